@@ -0,0 +1,325 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/app"
+	"github.com/clementhaon/sandbox-api-go/auth"
+	"github.com/clementhaon/sandbox-api-go/config"
+	"github.com/clementhaon/sandbox-api-go/database"
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/repository"
+	"github.com/clementhaon/sandbox-api-go/services"
+	"github.com/clementhaon/sandbox-api-go/tenant"
+)
+
+// adminEnv is what the admin subcommands work with: the configured
+// database, scoped to one tenant.
+type adminEnv struct {
+	ctx      context.Context
+	cfg      *config.Config
+	userRepo repository.UserRepository
+	taskRepo repository.TaskRepository
+	hookRepo repository.WebhookRepository
+	users    services.UserService
+	// close releases the database, or saves the in-memory snapshot
+	close func() error
+}
+
+// openAdminEnv loads the config, connects to the database and resolves the
+// tenant slug ("" for the default tenant). With STORAGE=memory it works on
+// MEMORY_SNAPSHOT_FILE instead, which close saves.
+func openAdminEnv(tenantSlug string) (*adminEnv, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+	policy, err := app.PasswordPolicy(cfg)
+	if err != nil {
+		return nil, err
+	}
+	hasher := auth.NewPasswordHasher(app.PasswordCost(cfg))
+	passwordScreen, err := app.PasswordScreen(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		tenants   repository.TenantRepository
+		userRepo  repository.UserRepository
+		taskRepo  repository.TaskRepository
+		hookRepo  repository.WebhookRepository
+		auditRepo repository.AuditRepository
+		closeEnv  func() error
+	)
+	if cfg.InMemory() {
+		if cfg.MemorySnapshotFile == "" {
+			return nil, fmt.Errorf("STORAGE=memory keeps nothing without MEMORY_SNAPSHOT_FILE")
+		}
+		store := repository.NewMemoryStore()
+		if _, err := store.Load(cfg.MemorySnapshotFile); err != nil {
+			return nil, fmt.Errorf("MEMORY_SNAPSHOT_FILE: %w", err)
+		}
+		tenants = repository.NewMemoryTenantRepository(store)
+		userRepo = repository.NewMemoryUserRepository(store)
+		taskRepo = repository.NewMemoryTaskRepository(store)
+		hookRepo = repository.NewMemoryWebhookRepository(store)
+		auditRepo = repository.NewMemoryAuditRepository(store)
+		closeEnv = func() error { return store.Save(cfg.MemorySnapshotFile) }
+	} else {
+		db, err := database.Open(cfg, nil)
+		if err != nil {
+			return nil, err
+		}
+		tenants = repository.NewPostgresTenantRepository(db)
+		userRepo = repository.NewPostgresUserRepository(db)
+		taskRepo = repository.NewPostgresTaskRepository(db)
+		hookRepo = repository.NewPostgresWebhookRepository(db)
+		auditRepo = repository.NewPostgresAuditRepository(db)
+		closeEnv = db.Close
+	}
+
+	ctx := context.Background()
+	if tenantSlug != "" {
+		t, err := tenants.GetBySlug(ctx, tenantSlug)
+		if err != nil {
+			closeEnv()
+			return nil, fmt.Errorf("tenant %q: %w", tenantSlug, err)
+		}
+		ctx = tenant.WithID(ctx, t.ID)
+	}
+
+	auditSvc := services.NewAuditService(auditRepo)
+	return &adminEnv{
+		ctx:      ctx,
+		cfg:      cfg,
+		userRepo: userRepo,
+		taskRepo: taskRepo,
+		hookRepo: hookRepo,
+		users:    services.NewUserService(userRepo, auditSvc, policy, hasher, passwordScreen),
+		close:    closeEnv,
+	}, nil
+}
+
+// lookupUser finds a user by ID or username.
+func (e *adminEnv) lookupUser(ref string) (models.User, error) {
+	if id, err := strconv.Atoi(ref); err == nil {
+		return e.userRepo.GetByID(e.ctx, id)
+	}
+	return e.userRepo.GetByUsername(e.ctx, ref)
+}
+
+// adminCommand parses flags, opens the admin environment and runs fn with
+// the remaining arguments. usage describes the positional arguments.
+func adminCommand(name, usage string, args []string, define func(fs *flag.FlagSet), fn func(e *adminEnv, args []string) error) int {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	tenantSlug := fs.String("tenant", "", "tenant slug (default tenant if empty)")
+	if define != nil {
+		define(fs)
+	}
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), strings.TrimSpace("Usage: sandbox-api "+name+" [flags] "+usage))
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	e, err := openAdminEnv(*tenantSlug)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+		return 1
+	}
+
+	err = fn(e, fs.Args())
+	if closeErr := e.close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+		return 1
+	}
+	return 0
+}
+
+// runUser implements `sandbox-api user create|promote|deactivate`.
+func runUser(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: sandbox-api user create|promote|deactivate [flags]")
+		return 2
+	}
+
+	switch args[0] {
+	case "create":
+		var req models.CreateUserRequest
+		return adminCommand("user create", "", args[1:], func(fs *flag.FlagSet) {
+			fs.StringVar(&req.Username, "username", "", "username (required)")
+			fs.StringVar(&req.Email, "email", "", "email (required)")
+			fs.StringVar(&req.Role, "role", models.RoleUser, "role: "+strings.Join(models.ValidRoles(), ", "))
+			fs.StringVar(&req.FirstName, "first-name", "", "first name")
+			fs.StringVar(&req.LastName, "last-name", "", "last name")
+		}, func(e *adminEnv, _ []string) error {
+			// Read from stdin so the password stays out of the shell history
+			password, err := readPassword()
+			if err != nil {
+				return err
+			}
+			req.Password = password
+
+			user, err := e.users.Create(e.ctx, req)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Created user %d (%s, role %s)\n", user.ID, user.Username, user.Role)
+			return nil
+		})
+
+	case "promote":
+		var role string
+		return adminCommand("user promote", "<id|username>", args[1:], func(fs *flag.FlagSet) {
+			fs.StringVar(&role, "role", models.RoleAdmin, "new role: "+strings.Join(models.ValidRoles(), ", "))
+		}, func(e *adminEnv, args []string) error {
+			if len(args) != 1 {
+				return fmt.Errorf("expected one user")
+			}
+			user, err := e.lookupUser(args[0])
+			if err != nil {
+				return err
+			}
+			updated, err := e.users.Update(e.ctx, user.ID, models.UpdateUserRequest{Role: role})
+			if err != nil {
+				return err
+			}
+			fmt.Printf("User %d (%s) now has role %s\n", updated.ID, updated.Username, updated.Role)
+			return nil
+		})
+
+	case "deactivate":
+		return adminCommand("user deactivate", "<id|username>", args[1:], nil, func(e *adminEnv, args []string) error {
+			if len(args) != 1 {
+				return fmt.Errorf("expected one user")
+			}
+			user, err := e.lookupUser(args[0])
+			if err != nil {
+				return err
+			}
+			if _, err := e.users.UpdateStatus(e.ctx, user.ID, "inactive"); err != nil {
+				return err
+			}
+			fmt.Printf("User %d (%s) deactivated\n", user.ID, user.Username)
+			return nil
+		})
+	}
+
+	fmt.Fprintf(os.Stderr, "user: unknown subcommand %q\n", args[0])
+	return 2
+}
+
+// runToken implements `sandbox-api token issue`.
+func runToken(args []string) int {
+	if len(args) == 0 || args[0] != "issue" {
+		fmt.Fprintln(os.Stderr, "Usage: sandbox-api token issue [flags] <id|username>")
+		return 2
+	}
+
+	var scopeList string
+	return adminCommand("token issue", "<id|username>", args[1:], func(fs *flag.FlagSet) {
+		fs.StringVar(&scopeList, "scopes", "", "comma-separated scopes restricting the token: "+strings.Join(models.ValidScopes(), ", "))
+	}, func(e *adminEnv, args []string) error {
+		if len(args) != 1 {
+			return fmt.Errorf("expected one user")
+		}
+		var scopes []string
+		if scopeList != "" {
+			for _, scope := range strings.Split(scopeList, ",") {
+				scope = strings.TrimSpace(scope)
+				if !slices.Contains(models.ValidScopes(), scope) {
+					return fmt.Errorf("unknown scope %q", scope)
+				}
+				scopes = append(scopes, scope)
+			}
+		}
+		user, err := e.lookupUser(args[0])
+		if err != nil {
+			return err
+		}
+		if !user.IsActive {
+			return fmt.Errorf("user %d is inactive", user.ID)
+		}
+
+		jwtManager, err := auth.NewJWTManager(e.cfg.JWTSecret)
+		if err != nil {
+			return err
+		}
+		token, err := jwtManager.GenerateToken(user)
+		if scopes != nil {
+			token, err = jwtManager.GenerateScopedToken(user, scopes, auth.TokenTTL)
+		}
+		if err != nil {
+			return err
+		}
+		// Only the token goes to stdout, so it can be captured by scripts
+		fmt.Println(token)
+		return nil
+	})
+}
+
+// runTask implements `sandbox-api task purge`.
+func runTask(args []string) int {
+	if len(args) == 0 || args[0] != "purge" {
+		fmt.Fprintln(os.Stderr, "Usage: sandbox-api task purge --older-than <duration>")
+		return 2
+	}
+
+	var olderThan time.Duration
+	return adminCommand("task purge", "", args[1:], func(fs *flag.FlagSet) {
+		fs.DurationVar(&olderThan, "older-than", 0, "delete completed tasks not updated for this long, e.g. 720h (required)")
+	}, func(e *adminEnv, _ []string) error {
+		// Purging touches neither columns nor quotas
+		taskSvc := services.NewTaskService(e.taskRepo, nil, nil, nil, nil)
+		n, err := taskSvc.PurgeCompleted(e.ctx, olderThan)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Purged %d completed tasks\n", n)
+		return nil
+	})
+}
+
+// runRotateKeys implements `sandbox-api rotate-keys`, which reseals the
+// stored secrets of every tenant under the first key of ENCRYPTION_KEYS.
+// The keys they are sealed under must still be listed after it.
+func runRotateKeys(args []string) int {
+	return adminCommand("rotate-keys", "", args, nil, func(e *adminEnv, _ []string) error {
+		keyring, err := app.Keyring(e.cfg)
+		if err != nil {
+			return err
+		}
+		if keyring == nil {
+			return fmt.Errorf("ENCRYPTION_KEYS or ENCRYPTION_KEYS_FILE is required")
+		}
+		n, err := services.RotateWebhookSecrets(e.ctx, e.hookRepo, keyring)
+		fmt.Printf("Resealed %d webhook secrets\n", n)
+		return err
+	})
+}
+
+// readPassword reads a password from the first line of stdin.
+func readPassword() (string, error) {
+	if stat, err := os.Stdin.Stat(); err == nil && stat.Mode()&os.ModeCharDevice != 0 {
+		fmt.Fprint(os.Stderr, "Password: ")
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("read password from stdin: %w", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
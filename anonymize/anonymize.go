@@ -0,0 +1,187 @@
+// Package anonymize scrubs personal data from a copy of the database, such
+// as a production dump loaded into a sandbox: user names, emails and
+// passwords, task contents, and the credentials and traces tied to real
+// people. Fake values come from a Faker, so that the same copy anonymized
+// with the same seed always looks the same.
+package anonymize
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/clementhaon/sandbox-api-go/database"
+
+	"github.com/lib/pq"
+)
+
+// batchSize is the number of rows rewritten per statement.
+const batchSize = 1000
+
+// Options configures an anonymization.
+type Options struct {
+	Faker *Faker
+	// PasswordHash is set as the password of every user, such as the
+	// bcrypt hash of a password shared by the sandbox
+	PasswordHash string
+}
+
+// Report counts the rows rewritten or deleted, by table.
+type Report map[string]int64
+
+// field is a column rewritten with fake values keyed by the row ID; NULLs
+// stay NULL.
+type field struct {
+	column string
+	fake   func(id int) string
+	array  bool // TEXT[], faked as comma-separated values
+}
+
+// Run anonymizes the database behind tx, which should be a transaction so
+// that a failure leaves it untouched.
+func Run(ctx context.Context, tx database.Querier, opts Options) (Report, error) {
+	f := opts.Faker
+	report := Report{}
+	exec := func(table, query string, args ...any) error {
+		res, err := tx.ExecContext(ctx, query, args...)
+		if err != nil {
+			return fmt.Errorf("anonymize %s: %w", table, err)
+		}
+		n, _ := res.RowsAffected()
+		report[table] = max(report[table], n)
+		return nil
+	}
+	rewrite := func(table string, fields ...field) error {
+		n, err := rewriteTable(ctx, tx, table, fields)
+		if err != nil {
+			return fmt.Errorf("anonymize %s: %w", table, err)
+		}
+		report[table] = max(report[table], n)
+		return nil
+	}
+
+	// Move the usernames and emails out of the way first, as the unique
+	// constraints are checked row by row and a fake value could be the
+	// real one of a user not rewritten yet
+	if err := exec("users", `UPDATE users SET username = '#' || id, email = '#' || id`); err != nil {
+		return nil, err
+	}
+	steps := []func() error{
+		func() error {
+			return rewrite("users",
+				field{column: "username", fake: f.Username},
+				field{column: "email", fake: f.Email},
+				field{column: "first_name", fake: f.FirstName},
+				field{column: "last_name", fake: f.LastName})
+		},
+		func() error {
+			return exec("users", `UPDATE users SET password = $1, avatar_url = NULL`, opts.PasswordHash)
+		},
+		func() error {
+			return rewrite("tasks",
+				field{column: "title", fake: func(id int) string { return f.Sentence("task_title", id, 2, 6) }},
+				field{column: "description", fake: func(id int) string { return f.Sentence("task_description", id, 8, 30) }},
+				field{column: "tags", fake: func(id int) string { return strings.Join(f.Tags(id), ",") }, array: true})
+		},
+		func() error {
+			return rewrite("checklist_items",
+				field{column: "title", fake: func(id int) string { return f.Sentence("checklist_title", id, 2, 6) }})
+		},
+		func() error {
+			return rewrite("time_entries",
+				field{column: "description", fake: func(id int) string { return f.Sentence("time_entry_description", id, 3, 10) }})
+		},
+		// Activities of existing tasks name them as the tasks now are
+		func() error {
+			return rewrite("activities",
+				field{column: "task_title", fake: func(id int) string { return f.Sentence("activity_task_title", id, 2, 6) }})
+		},
+		func() error {
+			return exec("activities", `UPDATE activities a SET task_title = t.title FROM tasks t WHERE a.task_id = t.id AND a.task_title IS NOT NULL`)
+		},
+		func() error {
+			return rewrite("notifications",
+				field{column: "message", fake: func(id int) string { return f.Sentence("notification_message", id, 4, 12) }})
+		},
+		func() error {
+			return exec("notifications", `UPDATE notifications SET data = data - 'taskTitle' - 'userName' WHERE data IS NOT NULL`)
+		},
+		// Media objects keep their keys, which point into the bucket
+		func() error {
+			return exec("media", `UPDATE media SET original_filename = 'file-' || id || COALESCE(substring(original_filename from '\.[A-Za-z0-9]{1,10}$'), '')`)
+		},
+		func() error {
+			return exec("audit_logs", `UPDATE audit_logs SET ip_address = NULL, user_agent = NULL, metadata = '{}'`)
+		},
+	}
+	// Sessions, tokens and links to outside accounts belong to real people
+	for _, table := range []string{"sessions", "personal_tokens", "email_changes", "slack_links", "inbound_hooks", "webhooks"} {
+		steps = append(steps, func() error { return exec(table, `DELETE FROM `+pq.QuoteIdentifier(table)) })
+	}
+
+	for _, step := range steps {
+		if err := step(); err != nil {
+			return nil, err
+		}
+	}
+	return report, nil
+}
+
+// rewriteTable sets the fields of every row of table to their fake values,
+// batchSize rows per UPDATE.
+func rewriteTable(ctx context.Context, tx database.Querier, table string, fields []field) (int64, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT id FROM `+pq.QuoteIdentifier(table)+` ORDER BY id`)
+	if err != nil {
+		return 0, err
+	}
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	query := rewriteQuery(table, fields)
+	for start := 0; start < len(ids); start += batchSize {
+		batch := ids[start:min(start+batchSize, len(ids))]
+		args := []any{pq.Array(batch)}
+		for _, fd := range fields {
+			values := make([]string, len(batch))
+			for i, id := range batch {
+				values[i] = fd.fake(id)
+			}
+			args = append(args, pq.Array(values))
+		}
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return 0, err
+		}
+	}
+	return int64(len(ids)), nil
+}
+
+// rewriteQuery returns the UPDATE setting the fields of table from arrays
+// of IDs and values, in that order.
+func rewriteQuery(table string, fields []field) string {
+	sets := make([]string, len(fields))
+	params := []string{"$1::int[]"}
+	names := []string{"id"}
+	for i, fd := range fields {
+		column := pq.QuoteIdentifier(fd.column)
+		value := fmt.Sprintf("v.f%d", i)
+		if fd.array {
+			value = fmt.Sprintf("string_to_array(%s, ',')", value)
+		}
+		sets[i] = fmt.Sprintf("%s = CASE WHEN t.%s IS NULL THEN NULL ELSE %s END", column, column, value)
+		params = append(params, fmt.Sprintf("$%d::text[]", i+2))
+		names = append(names, fmt.Sprintf("f%d", i))
+	}
+	return fmt.Sprintf("UPDATE %s t SET %s FROM unnest(%s) AS v(%s) WHERE t.id = v.id",
+		pq.QuoteIdentifier(table), strings.Join(sets, ", "), strings.Join(params, ", "), strings.Join(names, ", "))
+}
@@ -0,0 +1,51 @@
+package anonymize
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/clementhaon/sandbox-api-go/validation"
+)
+
+func TestFaker(t *testing.T) {
+	f := NewFaker("sandbox")
+	if f.Email(42) != NewFaker("sandbox").Email(42) || f.Sentence("task_title", 42, 2, 6) != NewFaker("sandbox").Sentence("task_title", 42, 2, 6) {
+		t.Error("expected the same values for the same seed and ID")
+	}
+
+	differs := false
+	for id := 1; id <= 20; id++ {
+		if f.Sentence("task_title", id, 2, 6) != NewFaker("other").Sentence("task_title", id, 2, 6) {
+			differs = true
+		}
+
+		if err := validation.Username()(f.Username(id)); err != nil {
+			t.Errorf("invalid username %q: %s", f.Username(id), err.Message)
+		}
+		if err := validation.Email()(f.Email(id)); err != nil {
+			t.Errorf("invalid email %q: %s", f.Email(id), err.Message)
+		}
+		if n := len(strings.Fields(f.Sentence("task_title", id, 2, 6))); n < 2 || n > 6 {
+			t.Errorf("expected 2 to 6 words, got %d", n)
+		}
+		if tags := f.Tags(id); len(tags) > 3 {
+			t.Errorf("expected at most 3 tags, got %v", tags)
+		}
+	}
+	if !differs {
+		t.Error("expected other values for another seed")
+	}
+	if f.Username(1) == f.Username(2) || f.Email(1) == f.Email(2) {
+		t.Error("expected distinct usernames and emails")
+	}
+}
+
+func TestRewriteQuery(t *testing.T) {
+	got := rewriteQuery("tasks", []field{{column: "title"}, {column: "tags", array: true}})
+	want := `UPDATE "tasks" t SET "title" = CASE WHEN t."title" IS NULL THEN NULL ELSE v.f0 END, ` +
+		`"tags" = CASE WHEN t."tags" IS NULL THEN NULL ELSE string_to_array(v.f1, ',') END ` +
+		`FROM unnest($1::int[], $2::text[], $3::text[]) AS v(id, f0, f1) WHERE t.id = v.id`
+	if got != want {
+		t.Errorf("unexpected query:\n%s", got)
+	}
+}
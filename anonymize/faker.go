@@ -0,0 +1,101 @@
+package anonymize
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var firstNames = []string{
+	"Alice", "Bruno", "Chloe", "David", "Emma", "Felix", "Grace", "Hugo", "Ines", "Jules",
+	"Karim", "Lea", "Manon", "Nathan", "Olivia", "Paul", "Quentin", "Rose", "Sacha", "Theo",
+	"Ugo", "Victor", "Wendy", "Xavier", "Yasmine", "Zoe", "Adam", "Camille", "Elise", "Louis",
+}
+
+var lastNames = []string{
+	"Martin", "Bernard", "Dubois", "Thomas", "Robert", "Richard", "Petit", "Durand", "Leroy", "Moreau",
+	"Simon", "Laurent", "Lefebvre", "Michel", "Garcia", "David", "Bertrand", "Roux", "Vincent", "Fournier",
+	"Morel", "Girard", "Andre", "Mercier", "Dupont", "Lambert", "Bonnet", "Francois", "Martinez", "Legrand",
+}
+
+var words = []string{
+	"review", "update", "deploy", "draft", "sync", "plan", "fix", "check", "prepare", "clean",
+	"release", "design", "test", "migrate", "document", "refine", "archive", "schedule", "share", "validate",
+	"report", "budget", "roadmap", "dashboard", "invoice", "meeting", "backlog", "sprint", "customer", "feedback",
+	"pipeline", "server", "database", "mockup", "onboarding", "survey", "contract", "newsletter", "workshop", "audit",
+	"quarterly", "weekly", "internal", "shared", "mobile", "public", "final", "initial", "pending", "urgent",
+	"the", "for", "with", "and", "before", "after", "from", "into", "about", "team",
+}
+
+// Faker derives fake values from a seed and the ID of the row they replace,
+// so the same database anonymized with the same seed always gets the same
+// values, and nothing of the original values leaks into them.
+type Faker struct {
+	key []byte
+}
+
+// NewFaker returns a Faker whose values depend on seed.
+func NewFaker(seed string) *Faker {
+	return &Faker{key: []byte(seed)}
+}
+
+// FirstName returns the fake first name of the user id.
+func (f *Faker) FirstName(id int) string {
+	return pick(firstNames, f.number("first_name", id, 0))
+}
+
+// LastName returns the fake last name of the user id.
+func (f *Faker) LastName(id int) string {
+	return pick(lastNames, f.number("last_name", id, 0))
+}
+
+// Username returns the fake username of the user id, unique as it ends with
+// the ID, and valid for registration.
+func (f *Faker) Username(id int) string {
+	return strings.ToLower(f.FirstName(id)) + "_" + strconv.Itoa(id)
+}
+
+// Email returns the fake email address of the user id, unique as it holds
+// the ID, at the reserved example.com domain.
+func (f *Faker) Email(id int) string {
+	return fmt.Sprintf("%s.%s.%d@example.com", strings.ToLower(f.FirstName(id)), strings.ToLower(f.LastName(id)), id)
+}
+
+// Sentence returns between minWords and maxWords words for the field of the
+// row id, starting with a capital letter.
+func (f *Faker) Sentence(field string, id, minWords, maxWords int) string {
+	n := minWords + int(f.number(field, id, 0)%uint64(maxWords-minWords+1))
+	s := make([]string, n)
+	for i := range s {
+		s[i] = pick(words, f.number(field, id, i+1))
+	}
+	s[0] = strings.ToUpper(s[0][:1]) + s[0][1:]
+	return strings.Join(s, " ")
+}
+
+// Tags returns up to three distinct tags for the task id.
+func (f *Faker) Tags(id int) []string {
+	var tags []string
+	for i := range int(f.number("tags", id, 0) % 4) {
+		tag := pick(words[:50], f.number("tags", id, i+1))
+		if !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// number returns the n-th pseudo-random number of the field of the row id.
+func (f *Faker) number(field string, id, n int) uint64 {
+	mac := hmac.New(sha256.New, f.key)
+	fmt.Fprintf(mac, "%s:%d:%d", field, id, n)
+	return binary.BigEndian.Uint64(mac.Sum(nil))
+}
+
+func pick(list []string, n uint64) string {
+	return list[n%uint64(len(list))]
+}
@@ -0,0 +1,817 @@
+// Package app wires the API: repositories, services, handlers, background
+// workers and the HTTP handler stack. Everything is built from the
+// dependencies passed to New, so several servers can run in one process.
+package app
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"os"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/auth"
+	"github.com/clementhaon/sandbox-api-go/config"
+	"github.com/clementhaon/sandbox-api-go/database"
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/errtrack"
+	"github.com/clementhaon/sandbox-api-go/events"
+	"github.com/clementhaon/sandbox-api-go/handlers"
+	"github.com/clementhaon/sandbox-api-go/jobs"
+	"github.com/clementhaon/sandbox-api-go/kvstore"
+	"github.com/clementhaon/sandbox-api-go/logger"
+	"github.com/clementhaon/sandbox-api-go/mailer"
+	"github.com/clementhaon/sandbox-api-go/metrics"
+	"github.com/clementhaon/sandbox-api-go/middleware"
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/oidc"
+	"github.com/clementhaon/sandbox-api-go/pwned"
+	"github.com/clementhaon/sandbox-api-go/ratelimit"
+	"github.com/clementhaon/sandbox-api-go/replay"
+	"github.com/clementhaon/sandbox-api-go/repository"
+	"github.com/clementhaon/sandbox-api-go/secretbox"
+	"github.com/clementhaon/sandbox-api-go/services"
+	"github.com/clementhaon/sandbox-api-go/signup"
+	"github.com/clementhaon/sandbox-api-go/spa"
+	"github.com/clementhaon/sandbox-api-go/storage"
+	"github.com/clementhaon/sandbox-api-go/validation"
+	"github.com/clementhaon/sandbox-api-go/webhook"
+	"github.com/clementhaon/sandbox-api-go/websocket"
+
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Deps are the resources a Server is built on. The caller owns them: closing
+// the Server does not close the database.
+type Deps struct {
+	Config *config.Config
+	DB     *sql.DB
+	// Memory keeps the data instead of DB when DB is nil, for STORAGE=memory.
+	Memory *repository.MemoryStore
+	// Logger receives the logs of every request. Nil means the global logger.
+	Logger *slog.Logger
+	// Metrics records what the Server does and its registry is served on
+	// /metrics. Nil means metrics built on the default Prometheus registry,
+	// which only one Server of the process can use.
+	Metrics *metrics.Metrics
+	// Storage holds uploaded media. Nil means the MinIO bucket from Config,
+	// or objects in memory along with Memory.
+	Storage storage.StorageClient
+	// Mailer sends emails. Nil means the MAIL_DRIVER from Config.
+	Mailer mailer.Mailer
+	// ErrorReporter receives panics and 5xx errors. Nil means the tracker of
+	// ERROR_REPORTING_DSN, if any.
+	ErrorReporter errtrack.Reporter
+	// PasswordPolicy is what new passwords must meet. Nil means the policy
+	// of Config.
+	PasswordPolicy *validation.PasswordPolicy
+}
+
+// Server is a fully wired API instance.
+type Server struct {
+	config  atomic.Pointer[config.Config]
+	tenants repository.TenantRepository
+	logger  *slog.Logger
+	metrics *metrics.Metrics
+	handler http.Handler
+	// routeList holds the routes registered on handler.
+	routeList []route
+	// errorReporter is nil when error reporting is off.
+	errorReporter errtrack.Reporter
+
+	// reloadMu serializes Reload calls.
+	reloadMu sync.Mutex
+
+	authMW          func(middleware.ErrorHandler) http.HandlerFunc
+	diagnosticsMW   func(middleware.ErrorHandler) http.HandlerFunc
+	rateLimiter     *middleware.RateLimiter
+	userRateLimiter *middleware.UserRateLimiter
+	// logLevels holds the log level set at runtime, which Reload updates
+	logLevels     services.LogLevelService
+	responseCache *middleware.ResponseCache
+	trackUsage    func(middleware.ErrorHandler) middleware.ErrorHandler
+
+	authHandler         *handlers.AuthHandler
+	userHandler         *handlers.UserHandler
+	profileHandler      *handlers.ProfileHandler
+	accountHandler      *handlers.AccountHandler
+	sessionHandler      *handlers.SessionHandler
+	columnHandler       *handlers.ColumnHandler
+	taskHandler         *handlers.TaskHandler
+	checklistHandler    *handlers.ChecklistHandler
+	activityHandler     *handlers.ActivityHandler
+	timeEntryHandler    *handlers.TimeEntryHandler
+	notificationHandler *handlers.NotificationHandler
+	announcementHandler *handlers.AnnouncementHandler
+	mediaHandler        *handlers.MediaHandler
+	exportHandler       *handlers.ExportHandler
+	auditHandler        *handlers.AuditHandler
+	quotaHandler        *handlers.QuotaHandler
+	logLevelHandler     *handlers.LogLevelHandler
+	inboundHandler      *handlers.InboundHandler
+	statsHandler        *handlers.StatsHandler
+	oidcHandler         *handlers.OIDCHandler    // nil when the provider is off
+	webhookHandler      *handlers.WebhookHandler // nil without encryption keys
+	scimHandler         *handlers.SCIMHandler
+	slackHandler        *handlers.SlackHandler
+	tokenHandler        *handlers.PersonalTokenHandler
+	usageHandler        *handlers.UsageHandler
+	sandboxHandler      *handlers.SandboxHandler
+	backupHandler       *handlers.BackupHandler
+	diagnosticsHandler  *handlers.DiagnosticsHandler
+	wsHandler           *handlers.WebSocketHandler
+	frontend            *spa.Handler       // nil when no frontend is served
+	recorder            *replay.Recorder   // nil unless RECORD_FILE is set
+	keyring             *secretbox.Keyring // nil unless ENCRYPTION_KEYS(_FILE) is set
+
+	// stops holds the Stop functions of background workers, in start order.
+	stops []func()
+}
+
+// New builds a Server and starts its background workers. Close stops them.
+func New(deps Deps) (*Server, error) {
+	cfg := deps.Config
+	if cfg == nil || (deps.DB == nil && deps.Memory == nil) {
+		return nil, fmt.Errorf("app: config and database are required")
+	}
+
+	// Initialize repositories and transaction manager
+	retry := database.DefaultRetryPolicy
+	retry.MaxAttempts = cfg.DBRetryMaxAttempts
+	repos := newRepositories(deps, retry)
+
+	s := &Server{
+		tenants: repos.tenants,
+		logger:  deps.Logger,
+		metrics: deps.Metrics,
+	}
+	s.config.Store(cfg)
+	if s.metrics == nil {
+		m, err := metrics.New(metrics.DefaultRegistry(), metrics.Options{
+			LatencyBuckets:      cfg.LatencyBuckets,
+			LatencyGroupBuckets: cfg.LatencyGroupBuckets,
+		})
+		if err != nil {
+			return nil, err
+		}
+		s.metrics = m
+	}
+
+	// Initialize JWT manager
+	jwtManager, err := auth.NewJWTManager(cfg.JWTSecret)
+	if err != nil {
+		return nil, fmt.Errorf("initialize JWT manager: %w", err)
+	}
+	jwtManager = jwtManager.WithRememberMeTTL(cfg.JWTRememberMeTTL)
+
+	// Keys sealing the stored secrets that are read back
+	if s.keyring, err = Keyring(cfg); err != nil {
+		return nil, err
+	}
+
+	// Initialize MinIO storage
+	mediaStorage := deps.Storage
+	if mediaStorage == nil && deps.DB == nil {
+		mediaStorage = storage.NewMemoryStorage()
+		logger.Warn("Media is kept in memory: uploads are lost on exit and presigned URLs are unavailable")
+	}
+	if mediaStorage == nil {
+		mediaStorage, err = storage.NewStorage(
+			cfg.MinioEndpoint,
+			cfg.MinioUser,
+			cfg.MinioPassword,
+			cfg.MinioBucket,
+			cfg.MinioUseSSL,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("initialize MinIO storage: %w", err)
+		}
+	}
+
+	// Outgoing email
+	mail := deps.Mailer
+	if mail == nil {
+		mail, err = newMailer(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("initialize mailer: %w", err)
+		}
+	}
+
+	// Error tracking
+	s.errorReporter = deps.ErrorReporter
+	if s.errorReporter == nil && cfg.ErrorReportingDSN != "" {
+		reporter, err := errtrack.NewSentryReporter(cfg.ErrorReportingDSN, errtrack.SentryOptions{
+			Environment: cfg.AppEnv,
+			Release:     cfg.ErrorReportingRelease,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("ERROR_REPORTING_DSN: %w", err)
+		}
+		s.errorReporter = reporter
+		s.stops = append(s.stops, reporter.Stop)
+		logger.Info("Error reporting enabled")
+	}
+
+	// Initialize WebSocket manager
+	wsManager := websocket.NewManager()
+
+	// Shared state lives in Redis when configured, so instances agree on
+	// revoked tokens and rate limits; otherwise it stays in memory
+	var redisClient *redis.Client
+	if cfg.RedisURL != "" {
+		opts, err := redis.ParseURL(cfg.RedisURL)
+		if err != nil {
+			return nil, fmt.Errorf("parse REDIS_URL: %w", err)
+		}
+		redisClient = redis.NewClient(opts)
+		s.stops = append(s.stops, func() { redisClient.Close() })
+	}
+
+	// Initialize token blacklist
+	kv := s.kvStore(redisClient)
+	blacklist := auth.NewTokenBlacklist(kv)
+
+	// Initialize background job queue
+	jobQueue := jobs.NewQueue(2)
+	s.stops = append(s.stops, jobQueue.Stop)
+
+	txManager := repos.txManager
+	userRepo := repos.users
+	taskRepo := repos.tasks
+	columnRepo := repos.columns
+	checklistRepo := repos.checklist
+	activityRepo := repos.activities
+	timeEntryRepo := repos.timeEntries
+	notifRepo := repos.notifications
+	mediaRepo := repos.media
+	auditRepo := repos.audit
+	emailChangeRepo := repos.emailChanges
+	quotaRepo := repos.quotas
+	announcementRepo := repos.announcements
+	inboundRepo := repos.inboundHooks
+	slackLinkRepo := repos.slackLinks
+	sessionRepo := repos.sessions
+	personalTokenRepo := repos.personalTokens
+	usageRepo := repos.usage
+	backupRepo := repos.backups
+
+	// Initialize services
+	auditSvc := services.NewAuditService(auditRepo)
+	quotaSvc := services.NewQuotaService(quotaRepo, userRepo, taskRepo, mediaRepo, usageRepo, models.QuotaLimits{
+		MaxTasks:         cfg.QuotaMaxTasks,
+		MaxMediaBytes:    cfg.QuotaMaxMediaBytes,
+		MaxDailyRequests: cfg.QuotaMaxDailyRequests,
+		MaxDailyBytes:    cfg.QuotaMaxDailyBytes,
+	}, auditSvc)
+	signupGuard, err := s.newSignupGuard(cfg, redisClient)
+	if err != nil {
+		return nil, err
+	}
+	policy, err := passwordPolicy(deps)
+	if err != nil {
+		return nil, err
+	}
+	hasher := auth.NewPasswordHasher(PasswordCost(cfg))
+	passwordScreen, err := PasswordScreen(cfg)
+	if err != nil {
+		return nil, err
+	}
+	var loginAlerter services.LoginAlerter
+	if cfg.LoginAlertURL != "" {
+		loginAlerter = services.NewMailLoginAlerter(mail, cfg.LoginAlertURL)
+	}
+	sessionSvc := services.NewSessionService(sessionRepo, blacklist, loginAlerter, auditSvc)
+	authSvc := services.NewAuthService(userRepo, jwtManager, auditSvc, signupGuard, policy, hasher, passwordScreen, sessionSvc)
+	userSvc := services.NewUserService(userRepo, auditSvc, policy, hasher, passwordScreen)
+	profileSvc := services.NewProfileService(userRepo, cfg.AvatarAllowedHosts)
+	accountSvc := services.NewAccountService(userRepo, emailChangeRepo, txManager, services.NewMailVerificationSender(mail), auditSvc)
+	columnSvc := services.NewColumnService(columnRepo, txManager)
+	notificationSvc := services.NewNotificationService(notifRepo, wsManager)
+	activitySvc := services.NewActivityService(activityRepo)
+	announcementSvc := services.NewAnnouncementService(announcementRepo)
+
+	// Side effects of task changes
+	bus := events.NewBus()
+	bus.Subscribe(events.TaskCreated, activitySvc.Record)
+	bus.Subscribe(events.TaskAssigned, activitySvc.Record)
+	bus.Subscribe(events.TaskAssigned, services.NotifyTaskAssigned(notificationSvc))
+	bus.Subscribe(events.TaskCompleted, activitySvc.Record)
+	bus.Subscribe(events.TaskCompleted, services.NotifyTaskCompleted(notificationSvc))
+
+	// Outgoing webhooks, which need the keyring to seal their secrets
+	if s.keyring != nil {
+		client := webhook.NewClient(webhook.DefaultTimeout, cfg.WebhookAllowPrivateNetworks)
+		webhookSvc := services.NewWebhookService(repos.webhooks, auditSvc, s.keyring, client)
+		for _, t := range []events.Type{events.TaskCreated, events.TaskAssigned, events.TaskCompleted} {
+			bus.Subscribe(t, webhookSvc.Notify)
+		}
+		s.stops = append(s.stops, webhookSvc.Stop)
+		s.webhookHandler = handlers.NewWebhookHandler(webhookSvc)
+	}
+
+	taskSvc := services.NewTaskService(taskRepo, columnRepo, checklistRepo, quotaSvc, bus)
+	checklistSvc := services.NewChecklistService(checklistRepo, taskRepo, txManager)
+	timeEntrySvc := services.NewTimeEntryService(timeEntryRepo, txManager)
+	mediaSvc := services.NewMediaService(mediaRepo, mediaStorage, quotaSvc)
+	exportSvc := services.NewExportService(userRepo, taskRepo, timeEntryRepo, notifRepo, jobQueue)
+	statsSvc := services.NewStatsService(userRepo, taskRepo, repos.health)
+	inboundSvc := services.NewInboundService(inboundRepo, columnRepo, taskSvc)
+	slackSvc := services.NewSlackService(slackLinkRepo, columnRepo, taskSvc, kv, auditSvc)
+	clockSvc := services.NewClockService(kv)
+	usageSvc := services.NewUsageService(usageRepo, quotaSvc)
+	personalTokenSvc := services.NewPersonalTokenService(personalTokenRepo, userRepo, auditSvc, clockSvc)
+	sandboxSvc := services.NewSandboxService(txManager, taskRepo, columnRepo, checklistRepo, timeEntryRepo, notifRepo, quotaSvc)
+	backupSvc := services.NewBackupService(backupRepo, txManager, mediaStorage, jobQueue, auditSvc, cfg.BackupRestoreEnabled)
+
+	// OpenID Connect provider, when an issuer is configured
+	if cfg.OIDCIssuer != "" {
+		oidcSvc, err := newOIDCService(cfg, userRepo, kv, auditSvc)
+		if err != nil {
+			return nil, fmt.Errorf("initialize OpenID Connect provider: %w", err)
+		}
+		s.oidcHandler = handlers.NewOIDCHandler(oidcSvc)
+	}
+
+	// Refresh business metrics (active users, tasks by status)
+	metricsCollector := services.StartMetricsCollector(userRepo, taskRepo, cfg.MetricsCollectInterval, s.metrics)
+	s.stops = append(s.stops, metricsCollector.Stop)
+
+	// Purge audit logs past the retention period
+	auditRetention := services.StartAuditRetention(auditSvc, cfg.AuditRetention, time.Hour)
+	s.stops = append(s.stops, auditRetention.Stop)
+
+	// Auth middleware with injected JWT manager, blacklist and
+	// personal access tokens, followed by the sandbox clock of the user and
+	// the per-user rate limit
+	authenticateUser := middleware.NewAuthMiddleware(jwtManager, blacklist, personalTokenSvc)
+	applyClock := middleware.ApplyClock(clockSvc)
+	// Set up even at 0 RPS, so a reload can turn it on
+	s.userRateLimiter = middleware.NewUserRateLimiter(s.rateLimitStore(redisClient), cfg.UserRateLimitRPS, cfg.UserRateLimitBurst)
+	authMW := func(handler middleware.ErrorHandler) http.HandlerFunc {
+		return authenticateUser(applyClock(s.userRateLimiter.Limit(handler)))
+	}
+
+	// Initialize rate limiter
+	s.rateLimiter = middleware.NewRateLimiter(cfg.RateLimitRequests, cfg.RateLimitWindow)
+	s.stops = append(s.stops, s.rateLimiter.Stop)
+
+	s.responseCache = middleware.NewResponseCache(cfg.ResponseCacheTTL, s.metrics)
+	s.trackUsage = middleware.NewUsageTracker(quotaSvc, usageSvc)
+
+	s.authMW = authMW
+	s.diagnosticsMW = middleware.NewDiagnosticsAuth(cfg.DiagnosticsToken, authMW)
+	s.authHandler = handlers.NewAuthHandler(authSvc, jwtManager, blacklist)
+	s.sessionHandler = handlers.NewSessionHandler(sessionSvc)
+	s.userHandler = handlers.NewUserHandler(userSvc)
+	s.scimHandler = handlers.NewSCIMHandler(services.NewSCIMService(userRepo, auditSvc, hasher, passwordScreen))
+	s.profileHandler = handlers.NewProfileHandler(profileSvc)
+	s.accountHandler = handlers.NewAccountHandler(accountSvc)
+	s.columnHandler = handlers.NewColumnHandler(columnSvc)
+	s.taskHandler = handlers.NewTaskHandler(taskSvc)
+	s.checklistHandler = handlers.NewChecklistHandler(checklistSvc)
+	s.activityHandler = handlers.NewActivityHandler(activitySvc)
+	s.timeEntryHandler = handlers.NewTimeEntryHandler(timeEntrySvc)
+	s.notificationHandler = handlers.NewNotificationHandler(notificationSvc)
+	s.announcementHandler = handlers.NewAnnouncementHandler(announcementSvc)
+	s.mediaHandler = handlers.NewMediaHandler(mediaSvc)
+	s.exportHandler = handlers.NewExportHandler(exportSvc)
+	s.auditHandler = handlers.NewAuditHandler(auditSvc)
+	s.quotaHandler = handlers.NewQuotaHandler(quotaSvc)
+	s.logLevels = services.NewLogLevelService(auditSvc)
+	s.stops = append(s.stops, s.logLevels.Stop)
+	s.logLevelHandler = handlers.NewLogLevelHandler(s.logLevels)
+	s.inboundHandler = handlers.NewInboundHandler(inboundSvc)
+	s.slackHandler = handlers.NewSlackHandler(slackSvc)
+	s.tokenHandler = handlers.NewPersonalTokenHandler(personalTokenSvc)
+	s.usageHandler = handlers.NewUsageHandler(usageSvc)
+	s.sandboxHandler = handlers.NewSandboxHandler(sandboxSvc, clockSvc)
+	s.backupHandler = handlers.NewBackupHandler(backupSvc)
+	s.statsHandler = handlers.NewStatsHandler(statsSvc)
+	s.diagnosticsHandler = handlers.NewDiagnosticsHandler()
+	s.wsHandler = handlers.NewWebSocketHandler(wsManager, jwtManager)
+
+	if s.frontend, err = newFrontend(cfg); err != nil {
+		return nil, err
+	}
+
+	// Requests changing data are logged for replay
+	if cfg.RecordFile != "" {
+		if s.recorder, err = replay.NewRecorder(cfg.RecordFile); err != nil {
+			return nil, fmt.Errorf("RECORD_FILE: %w", err)
+		}
+		s.stops = append(s.stops, func() { s.recorder.Close() })
+		logger.Warn("Recording the requests changing data for replay, with their bodies minus passwords and secrets", map[string]interface{}{
+			"file": cfg.RecordFile,
+		})
+	}
+
+	s.handler = s.buildHandler()
+	return s, nil
+}
+
+// repositories are the data access of a Server, in Postgres or in memory.
+type repositories struct {
+	txManager database.Transactor
+	health    services.DBHealthChecker
+
+	tenants        repository.TenantRepository
+	users          repository.UserRepository
+	tasks          repository.TaskRepository
+	columns        repository.ColumnRepository
+	checklist      repository.ChecklistRepository
+	activities     repository.ActivityRepository
+	timeEntries    repository.TimeEntryRepository
+	notifications  repository.NotificationRepository
+	media          repository.MediaRepository
+	audit          repository.AuditRepository
+	emailChanges   repository.EmailChangeRepository
+	quotas         repository.QuotaRepository
+	announcements  repository.AnnouncementRepository
+	inboundHooks   repository.InboundHookRepository
+	slackLinks     repository.SlackLinkRepository
+	sessions       repository.SessionRepository
+	personalTokens repository.PersonalTokenRepository
+	usage          repository.UsageRepository
+	backups        repository.BackupRepository
+	webhooks       repository.WebhookRepository
+}
+
+// newRepositories returns the repositories of deps.DB, or of deps.Memory
+// when there is no database.
+func newRepositories(deps Deps, retry database.RetryPolicy) repositories {
+	if db := deps.DB; db != nil {
+		return repositories{
+			txManager:      database.NewTxManager(db).WithRetryPolicy(retry),
+			health:         db,
+			tenants:        repository.NewPostgresTenantRepository(db),
+			users:          repository.NewPostgresUserRepository(db),
+			tasks:          repository.NewPostgresTaskRepository(db),
+			columns:        repository.NewPostgresColumnRepository(db),
+			checklist:      repository.NewPostgresChecklistRepository(db),
+			activities:     repository.NewPostgresActivityRepository(db),
+			timeEntries:    repository.NewPostgresTimeEntryRepository(db),
+			notifications:  repository.NewPostgresNotificationRepository(db),
+			media:          repository.NewPostgresMediaRepository(db),
+			audit:          repository.NewPostgresAuditRepository(db),
+			emailChanges:   repository.NewPostgresEmailChangeRepository(db),
+			quotas:         repository.NewPostgresQuotaRepository(db),
+			announcements:  repository.NewPostgresAnnouncementRepository(db),
+			inboundHooks:   repository.NewPostgresInboundHookRepository(db),
+			slackLinks:     repository.NewPostgresSlackLinkRepository(db),
+			sessions:       repository.NewPostgresSessionRepository(db),
+			personalTokens: repository.NewPostgresPersonalTokenRepository(db),
+			usage:          repository.NewPostgresUsageRepository(db),
+			backups:        repository.NewPostgresBackupRepository(db),
+			webhooks:       repository.NewPostgresWebhookRepository(db),
+		}
+	}
+
+	store := deps.Memory
+	return repositories{
+		txManager:      store,
+		health:         store,
+		tenants:        repository.NewMemoryTenantRepository(store),
+		users:          repository.NewMemoryUserRepository(store),
+		tasks:          repository.NewMemoryTaskRepository(store),
+		columns:        repository.NewMemoryColumnRepository(store),
+		checklist:      repository.NewMemoryChecklistRepository(store),
+		activities:     repository.NewMemoryActivityRepository(store),
+		timeEntries:    repository.NewMemoryTimeEntryRepository(store),
+		notifications:  repository.NewMemoryNotificationRepository(store),
+		media:          repository.NewMemoryMediaRepository(store),
+		audit:          repository.NewMemoryAuditRepository(store),
+		emailChanges:   repository.NewMemoryEmailChangeRepository(store),
+		quotas:         repository.NewMemoryQuotaRepository(store),
+		announcements:  repository.NewMemoryAnnouncementRepository(store),
+		inboundHooks:   repository.NewMemoryInboundHookRepository(store),
+		slackLinks:     repository.NewMemorySlackLinkRepository(store),
+		sessions:       repository.NewMemorySessionRepository(store),
+		personalTokens: repository.NewMemoryPersonalTokenRepository(store),
+		usage:          repository.NewMemoryUsageRepository(store),
+		backups:        repository.NewMemoryBackupRepository(store),
+		webhooks:       repository.NewMemoryWebhookRepository(store),
+	}
+}
+
+// kvStore returns a key-value store in Redis if client is set, else in
+// memory. Close releases it.
+func (s *Server) kvStore(client *redis.Client) kvstore.Store {
+	if client == nil {
+		store := kvstore.NewMemoryStore()
+		s.stops = append(s.stops, store.Stop)
+		return store
+	}
+	return kvstore.NewRedisStore(client, "sandbox:")
+}
+
+// rateLimitStore returns a rate limit store in Redis if client is set, else
+// in memory. Close releases it.
+func (s *Server) rateLimitStore(client *redis.Client) ratelimit.Store {
+	if client == nil {
+		store := ratelimit.NewMemoryStore()
+		s.stops = append(s.stops, store.Stop)
+		return store
+	}
+	return ratelimit.NewRedisStore(client, "sandbox:ratelimit:")
+}
+
+// passwordPolicy returns the password policy of deps, or else of its
+// configuration.
+func passwordPolicy(deps Deps) (validation.PasswordPolicy, error) {
+	if deps.PasswordPolicy != nil {
+		return *deps.PasswordPolicy, nil
+	}
+	return PasswordPolicy(deps.Config)
+}
+
+// PasswordPolicy builds the password policy of the configuration.
+func PasswordPolicy(cfg *config.Config) (validation.PasswordPolicy, error) {
+	policy := validation.PasswordPolicy{
+		MinLength: cfg.PasswordMinLength,
+		Classes:   cfg.PasswordRequiredClasses,
+	}
+	if cfg.PasswordBlockCommon {
+		policy.Banned = validation.CommonPasswords()
+		if cfg.PasswordBannedFile != "" {
+			extra, err := validation.LoadPasswordList(cfg.PasswordBannedFile)
+			if err != nil {
+				return policy, fmt.Errorf("PASSWORD_BANNED_FILE: %w", err)
+			}
+			maps.Copy(policy.Banned, extra)
+		}
+	}
+	return policy, nil
+}
+
+// PasswordCost returns the bcrypt cost of the configuration, tuned to this
+// machine when BCRYPT_MAX_HASH_MS is set.
+func PasswordCost(cfg *config.Config) int {
+	cost := cfg.BcryptCost
+	if cost == 0 {
+		cost = bcrypt.DefaultCost
+	}
+	if cfg.BcryptMaxHashTime <= 0 {
+		return cost
+	}
+
+	tuned, took := auth.TunePasswordCost(cost, cfg.BcryptMaxHashTime)
+	fields := map[string]interface{}{
+		"cost":        tuned,
+		"hash_ms":     took.Milliseconds(),
+		"max_hash_ms": cfg.BcryptMaxHashTime.Milliseconds(),
+	}
+	if took > cfg.BcryptMaxHashTime {
+		logger.Warn("Password hashes at BCRYPT_COST take longer than BCRYPT_MAX_HASH_MS", fields)
+	} else {
+		logger.Info("Password hash cost tuned", fields)
+	}
+	return tuned
+}
+
+// PasswordScreen builds the breached password check of the configuration,
+// nil when it is off.
+func PasswordScreen(cfg *config.Config) (*services.PasswordScreen, error) {
+	var checker pwned.Checker
+	switch cfg.PwnedCheck {
+	case "api":
+		checker = pwned.NewRangeChecker(cfg.PwnedAPIURL, cfg.PwnedTimeout)
+	case "bloom":
+		filter, err := pwned.LoadBloomFilter(cfg.PwnedBloomFile)
+		if err != nil {
+			return nil, fmt.Errorf("PWNED_BLOOM_FILE: %w", err)
+		}
+		checker = filter
+	default:
+		return nil, nil
+	}
+	return services.NewPasswordScreen(checker, cfg.PwnedAction != "warn"), nil
+}
+
+// newSignupGuard builds the registration checks enabled in the configuration.
+func (s *Server) newSignupGuard(cfg *config.Config, redisClient *redis.Client) (*signup.Guard, error) {
+	opts := signup.Options{Limit: cfg.SignupRateLimit, Window: cfg.SignupRateWindow}
+	if cfg.SignupRateLimit > 0 {
+		opts.Store = s.rateLimitStore(redisClient)
+	}
+	if cfg.SignupBlockDisposable {
+		domains := signup.DefaultDisposableDomains()
+		if cfg.SignupDisposableDomainsFile != "" {
+			extra, err := signup.LoadDomainList(cfg.SignupDisposableDomainsFile)
+			if err != nil {
+				return nil, fmt.Errorf("SIGNUP_DISPOSABLE_DOMAINS_FILE: %w", err)
+			}
+			for domain := range extra {
+				domains.Add(domain)
+			}
+		}
+		opts.Blocklist = domains
+	}
+	if cfg.CaptchaVerifyURL != "" {
+		opts.Captcha = signup.NewSiteVerifier(cfg.CaptchaVerifyURL, cfg.CaptchaSecret)
+	}
+	return signup.NewGuard(opts), nil
+}
+
+// newFrontend loads the frontend from FRONTEND_DIR, or the one embedded in
+// the binary. It returns nil when there is neither.
+func newFrontend(cfg *config.Config) (*spa.Handler, error) {
+	fsys := spa.Embedded()
+	if cfg.FrontendDir != "" {
+		fsys = os.DirFS(cfg.FrontendDir)
+	}
+	if fsys == nil {
+		return nil, nil
+	}
+
+	frontend, err := spa.New(fsys)
+	if err != nil {
+		return nil, fmt.Errorf("load frontend: %w", err)
+	}
+	logger.Info("Serving the frontend under /", map[string]interface{}{
+		"dir": cfg.FrontendDir,
+	})
+	return frontend, nil
+}
+
+// newOIDCService loads the clients and signing key of the OpenID Connect
+// provider.
+func newOIDCService(cfg *config.Config, userRepo repository.UserRepository, codes kvstore.Store, auditSvc services.AuditService) (services.OIDCService, error) {
+	clients, err := oidc.LoadClients(cfg.OIDCClientsFile)
+	if err != nil {
+		return nil, fmt.Errorf("OIDC_CLIENTS_FILE: %w", err)
+	}
+
+	var keys *oidc.KeySet
+	if cfg.OIDCSigningKeyFile != "" {
+		if keys, err = oidc.LoadKeySet(cfg.OIDCSigningKeyFile); err != nil {
+			return nil, fmt.Errorf("OIDC_SIGNING_KEY_FILE: %w", err)
+		}
+	} else {
+		logger.Warn("OIDC_SIGNING_KEY_FILE not set: using a generated key, tokens will not survive a restart")
+		if keys, err = oidc.GenerateKeySet(); err != nil {
+			return nil, err
+		}
+	}
+
+	return services.NewOIDCService(userRepo, codes, services.OIDCConfig{
+		Issuer:   cfg.OIDCIssuer,
+		LoginURL: cfg.OIDCLoginURL,
+		Keys:     keys,
+		Clients:  clients,
+	}, auditSvc), nil
+}
+
+// buildHandler wraps the routes in the server-wide middleware chain.
+func (s *Server) buildHandler() http.Handler {
+	cfg := s.Config()
+
+	handler := middleware.CSRFMiddleware(middleware.MaxBytesMiddleware(cfg.MaxBodySize)(s.routes()))
+	if cfg.MultiTenant {
+		handler = middleware.NewTenantMiddleware(s.tenants, cfg.TenantBaseDomain)(handler)
+		logger.Info("Multi-tenancy enabled")
+	}
+	// Outside the tenant middleware, so the tenant it resolves is recorded
+	if s.recorder != nil {
+		handler = middleware.NewRecordMiddleware(s.recorder)(handler)
+	}
+	if len(cfg.OpsIPAllowlist) > 0 || len(cfg.OpsIPDenylist) > 0 {
+		handler = middleware.NewIPFilter(cfg.OpsIPAllowlist, cfg.OpsIPDenylist, "/admin/", "/metrics", "/debug/")(handler)
+	}
+	if cfg.LogBodies {
+		handler = middleware.NewBodyLoggingMiddleware(cfg.LogBodyMaxBytes)(handler)
+		logger.Warn("Request/response body logging enabled (DEBUG level)")
+	}
+	handler = middleware.PanicRecoveryMiddleware(middleware.NewClientIPMiddleware(cfg.TrustedProxies)(middleware.RequestLoggingMiddleware(handler)))
+	// Outermost, so logs, metrics and routes all see paths without the prefix
+	handler = middleware.NewBasePath(cfg.BasePath)(handler)
+
+	problems := errors.ProblemFormat{Always: cfg.ErrorFormat == config.ErrorFormatProblem, TypeBase: cfg.ErrorTypeBaseURI}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := metrics.NewContext(r.Context(), s.metrics)
+		ctx = errors.WithProblemFormat(ctx, problems)
+		ctx = logger.WithSlowQueryThreshold(ctx, s.Config().SlowQueryThreshold)
+		if s.logger != nil {
+			ctx = logger.WithBase(ctx, s.logger)
+		}
+		if s.errorReporter != nil {
+			ctx = errtrack.NewContext(ctx, s.errorReporter)
+		}
+		handler.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// Handler returns the HTTP handler serving the API.
+func (s *Server) Handler() http.Handler {
+	return s.handler
+}
+
+// Config returns the configuration currently in effect.
+func (s *Server) Config() *config.Config {
+	return s.config.Load()
+}
+
+// reloadableFields are the Config fields Reload applies to a running server.
+var reloadableFields = map[string]bool{
+	"LogLevel":           true,
+	"SlowQueryThreshold": true,
+	"RateLimitRequests":  true,
+	"RateLimitWindow":    true,
+	"UserRateLimitRPS":   true,
+	"UserRateLimitBurst": true,
+}
+
+// Reload validates cfg and makes it the server's configuration. The log
+// level, slow query threshold and the per-IP and per-user rate limits take
+// effect immediately, but a log level changed for a while on PUT
+// /admin/loglevel is kept until it reverts to the reloaded one. Changes to
+// other fields are kept in Config but only apply after a restart, and are
+// logged at WARN as such.
+// An invalid cfg is rejected and the current configuration stays in place.
+func (s *Server) Reload(cfg *config.Config) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+	old := s.Config()
+
+	s.logLevels.Configure(cfg.LogLevel)
+	s.rateLimiter.SetLimit(cfg.RateLimitRequests, cfg.RateLimitWindow)
+	s.userRateLimiter.SetLimit(cfg.UserRateLimitRPS, cfg.UserRateLimitBurst)
+	s.config.Store(cfg)
+
+	logger.Info("Configuration reloaded", map[string]interface{}{
+		"log_level":             cfg.LogLevel.String(),
+		"slow_query_threshold":  cfg.SlowQueryThreshold.String(),
+		"rate_limit_requests":   cfg.RateLimitRequests,
+		"rate_limit_window":     cfg.RateLimitWindow.String(),
+		"user_rate_limit_rps":   cfg.UserRateLimitRPS,
+		"user_rate_limit_burst": cfg.UserRateLimitBurst,
+	})
+	if fields := changedFields(old, cfg); len(fields) > 0 {
+		logger.Warn("Configuration changes require a restart", map[string]interface{}{
+			"fields": fields,
+		})
+	}
+	return nil
+}
+
+// changedFields lists the fields that differ between a and b, other than
+// the reloadable ones.
+func changedFields(a, b *config.Config) []string {
+	va, vb := reflect.ValueOf(a).Elem(), reflect.ValueOf(b).Elem()
+	var fields []string
+	for i := 0; i < va.NumField(); i++ {
+		name := va.Type().Field(i).Name
+		if reloadableFields[name] {
+			continue
+		}
+		if !reflect.DeepEqual(va.Field(i).Interface(), vb.Field(i).Interface()) {
+			fields = append(fields, name)
+		}
+	}
+	return fields
+}
+
+// Close stops the background workers, most recently started first.
+func (s *Server) Close() {
+	for i := len(s.stops) - 1; i >= 0; i-- {
+		s.stops[i]()
+	}
+	s.stops = nil
+}
+
+// Keyring returns the keys of ENCRYPTION_KEYS or ENCRYPTION_KEYS_FILE,
+// or nil when neither is set.
+func Keyring(cfg *config.Config) (*secretbox.Keyring, error) {
+	var provider secretbox.KeyProvider = secretbox.StaticKeys(cfg.EncryptionKeys)
+	name := "ENCRYPTION_KEYS"
+	if cfg.EncryptionKeysFile != "" {
+		provider, name = secretbox.FileKeys(cfg.EncryptionKeysFile), "ENCRYPTION_KEYS_FILE"
+	}
+	keyring, err := secretbox.Load(context.Background(), provider)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return keyring, nil
+}
+
+// newMailer returns the Mailer for MAIL_DRIVER.
+func newMailer(cfg *config.Config) (mailer.Mailer, error) {
+	switch cfg.MailDriver {
+	case config.MailDriverSMTP:
+		return mailer.NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUser, cfg.SMTPPassword, cfg.MailFrom), nil
+	case config.MailDriverAPI:
+		return mailer.NewAPIMailer(cfg.MailAPIURL, cfg.MailAPIKey, cfg.MailFrom)
+	default:
+		return mailer.LogMailer{}, nil
+	}
+}
@@ -0,0 +1,412 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/config"
+	"github.com/clementhaon/sandbox-api-go/logger"
+	"github.com/clementhaon/sandbox-api-go/metrics"
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/repository"
+	"github.com/clementhaon/sandbox-api-go/secretbox"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func testConfig() *config.Config {
+	return &config.Config{
+		JWTSecret:              "at-least-sixteen-chars",
+		Port:                   8080,
+		DBPort:                 5432,
+		DBRetryMaxAttempts:     3,
+		JWTExpiryHours:         24,
+		JWTRememberMeTTL:       30 * 24 * time.Hour,
+		MaxBodySize:            1 << 20,
+		MetricsCollectInterval: time.Hour,
+		RateLimitRequests:      100,
+		RateLimitWindow:        time.Minute,
+		ErrorFormat:            config.ErrorFormatJSON,
+	}
+}
+
+// testMetrics returns metrics registered on a registry of their own.
+func testMetrics(t *testing.T) *metrics.Metrics {
+	t.Helper()
+	m, err := metrics.New(prometheus.NewRegistry(), metrics.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+// newTestServer returns a server on an in-memory store, with the test
+// config and metrics of its own unless deps sets them.
+func newTestServer(t *testing.T, deps Deps) *Server {
+	t.Helper()
+	if deps.Config == nil {
+		deps.Config = testConfig()
+	}
+	if deps.Metrics == nil {
+		deps.Metrics = testMetrics(t)
+	}
+	deps.Memory = repository.NewMemoryStore()
+	s, err := New(deps)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestNew_RequiresConfigAndDB(t *testing.T) {
+	if _, err := New(Deps{}); err == nil {
+		t.Error("expected an error without config and database")
+	}
+}
+
+func TestServer_Handler(t *testing.T) {
+	var logs bytes.Buffer
+	s := newTestServer(t, Deps{Logger: slog.New(slog.NewJSONHandler(&logs, nil))})
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"/", http.StatusOK},
+		{"/nope", http.StatusNotFound},
+		{"/tasks", http.StatusUnauthorized},
+		{"/metrics", http.StatusOK},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if rec.Code != tt.want {
+			t.Errorf("GET %s: expected %d, got %d", tt.path, tt.want, rec.Code)
+		}
+	}
+
+	if !strings.Contains(logs.String(), `"path":"/tasks"`) {
+		t.Errorf("expected request logs on the injected logger, got %s", logs.String())
+	}
+}
+
+func TestServer_Methods(t *testing.T) {
+	s := newTestServer(t, Deps{})
+
+	tests := []struct {
+		method, path string
+		want         int
+		allow        string
+	}{
+		{http.MethodOptions, "/tasks", http.StatusNoContent, "GET, HEAD, POST, OPTIONS"},
+		{http.MethodOptions, "/tasks/7", http.StatusNoContent, "GET, HEAD, PUT, DELETE, OPTIONS"},
+		{http.MethodOptions, "/", http.StatusNoContent, "GET, HEAD, OPTIONS"},
+		{http.MethodGet, "/auth/login", http.StatusMethodNotAllowed, "POST, OPTIONS"},
+		{http.MethodPut, "/auth/login", http.StatusMethodNotAllowed, "POST, OPTIONS"},
+		{http.MethodOptions, "/nope", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		s.Handler().ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+		if rec.Code != tt.want {
+			t.Errorf("%s %s: expected %d, got %d", tt.method, tt.path, tt.want, rec.Code)
+		}
+		if got := rec.Header().Get("Allow"); got != tt.allow {
+			t.Errorf("%s %s: expected Allow %q, got %q", tt.method, tt.path, tt.allow, got)
+		}
+		if tt.want == http.StatusMethodNotAllowed && !strings.Contains(rec.Body.String(), "METHOD_NOT_ALLOWED") {
+			t.Errorf("%s %s: expected a METHOD_NOT_ALLOWED error, got %s", tt.method, tt.path, rec.Body.String())
+		}
+	}
+}
+
+func TestServer_Head(t *testing.T) {
+	s := newTestServer(t, Deps{})
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	resp, err := http.Head(srv.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected 200, got %d", resp.StatusCode)
+	}
+	if resp.ContentLength <= 0 || resp.Header.Get("ETag") == "" {
+		t.Errorf("expected Content-Length and ETag, got %d and %q", resp.ContentLength, resp.Header.Get("ETag"))
+	}
+	if len(body) != 0 {
+		t.Errorf("expected no body, got %q", body)
+	}
+}
+
+func TestRouteTable(t *testing.T) {
+	s := newTestServer(t, Deps{})
+
+	// Routes changing an account are refused to impersonation tokens
+	accountRoutes := []string{
+		"PUT /profile/email", "PUT /profile/username",
+		"PUT /users/{id}", "PATCH /users/{id}/status", "DELETE /users/{id}",
+	}
+	for _, rt := range s.routeTable() {
+		path := rt.pattern[strings.Index(rt.pattern, "/"):]
+		if strings.HasPrefix(path, "/admin/") && !slices.Contains(rt.roles, models.RoleAdmin) {
+			t.Errorf("%s: admin routes must require the admin role", rt.pattern)
+		}
+		if slices.Contains(accountRoutes, rt.pattern) && !rt.noImpersonation {
+			t.Errorf("%s: account changes must be refused while impersonating", rt.pattern)
+		}
+	}
+
+	rt := route{pattern: "PUT /profile/email", noImpersonation: true, rateLimit: rateLimitIP, maxBody: smallBody}
+	if got, want := rt.String(), "PUT /profile/email auth no-impersonation rate-limit=ip max-body=16384"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestServer_Introspection(t *testing.T) {
+	s := newTestServer(t, Deps{})
+
+	rec := httptest.NewRecorder()
+	if err := s.handleRoutes(rec, httptest.NewRequest(http.MethodGet, "/admin/routes", nil)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var routes struct {
+		Data []routeInfo `json:"data"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&routes); err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, rt := range routes.Data {
+		if rt.Method == http.MethodGet && rt.Path == "/admin/config" {
+			found = rt.Access == "auth" && slices.Contains(rt.Middleware, "roles=admin")
+		}
+	}
+	if !found {
+		t.Errorf("expected GET /admin/config listed as admin only, got %+v", routes.Data)
+	}
+
+	rec = httptest.NewRecorder()
+	if err := s.handleConfig(rec, httptest.NewRequest(http.MethodGet, "/admin/config", nil)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if body := rec.Body.String(); strings.Contains(body, testConfig().JWTSecret) || !strings.Contains(body, `"RateLimitWindow":"1m0s"`) {
+		t.Errorf("expected the configuration without its secrets, got %s", body)
+	}
+
+	// Both are admin only
+	rec = httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/config", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without a token, got %d", rec.Code)
+	}
+}
+
+func TestServer_BasePath(t *testing.T) {
+	cfg := testConfig()
+	cfg.BasePath = "/sandbox-api"
+	s := newTestServer(t, Deps{Config: cfg})
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"/sandbox-api/", http.StatusOK},
+		{"/sandbox-api/tasks", http.StatusUnauthorized},
+		{"/tasks", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if rec.Code != tt.want {
+			t.Errorf("GET %s: expected %d, got %d", tt.path, tt.want, rec.Code)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/sandbox-api/", nil))
+	if !strings.Contains(rec.Body.String(), `"errors":"/sandbox-api/errors"`) {
+		t.Errorf("expected links under the base path, got %s", rec.Body.String())
+	}
+}
+
+func TestServer_Frontend(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "index.html"), []byte("<!doctype html>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := testConfig()
+	cfg.FrontendDir = dir
+	s := newTestServer(t, Deps{Config: cfg})
+
+	tests := []struct {
+		name     string
+		path     string
+		navigate bool
+		want     int
+		wantHTML bool
+	}{
+		{"root", "/", false, http.StatusOK, true},
+		{"client route", "/board/settings", false, http.StatusOK, true},
+		{"API fetch", "/tasks", false, http.StatusUnauthorized, false},
+		{"page load of an API path", "/tasks", true, http.StatusOK, true},
+		{"download", "/media/3/download", true, http.StatusUnauthorized, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.navigate {
+				req.Header.Set("Sec-Fetch-Mode", "navigate")
+				req.Header.Set("Sec-Fetch-Dest", "document")
+			}
+			rec := httptest.NewRecorder()
+			s.Handler().ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if html := strings.HasPrefix(rec.Header().Get("Content-Type"), "text/html"); html != tt.wantHTML {
+				t.Errorf("Content-Type = %q", rec.Header().Get("Content-Type"))
+			}
+		})
+	}
+
+	cfg = testConfig()
+	cfg.FrontendDir = t.TempDir()
+	if _, err := New(Deps{Config: cfg, Memory: repository.NewMemoryStore(), Metrics: testMetrics(t)}); err == nil {
+		t.Error("expected an error for a frontend without index.html")
+	}
+}
+
+func TestServer_Reload(t *testing.T) {
+	s := newTestServer(t, Deps{})
+
+	invalid := testConfig()
+	invalid.RateLimitRequests = 0
+	if err := s.Reload(invalid); err == nil {
+		t.Error("expected an invalid config to be rejected")
+	}
+	if s.Config().RateLimitRequests != 100 {
+		t.Errorf("expected the previous config to stay in place, got %d requests", s.Config().RateLimitRequests)
+	}
+
+	next := testConfig()
+	next.RateLimitRequests = 1
+	if err := s.Reload(next); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s.Config() != next {
+		t.Error("expected the reloaded config to be in effect")
+	}
+
+	// The new limit applies to the rate-limited routes right away
+	codes := make([]int, 2)
+	for i := range codes {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/auth/login", strings.NewReader("{}"))
+		req.RemoteAddr = "10.0.0.9:1234"
+		s.Handler().ServeHTTP(rec, req)
+		codes[i] = rec.Code
+	}
+	if codes[1] != http.StatusTooManyRequests {
+		t.Errorf("expected the second request to be throttled, got %v", codes)
+	}
+}
+
+func TestChangedFields(t *testing.T) {
+	a, b := testConfig(), testConfig()
+	b.UserRateLimitRPS, b.RateLimitRequests = 5, 10
+	if fields := changedFields(a, b); len(fields) != 0 {
+		t.Errorf("expected the rate limits to reload, got %v needing a restart", fields)
+	}
+	b.Port = 9090
+	if fields := changedFields(a, b); !slices.Equal(fields, []string{"Port"}) {
+		t.Errorf("expected Port to need a restart, got %v", fields)
+	}
+}
+
+func TestServer_ReloadDuringTemporaryLogLevel(t *testing.T) {
+	defer logger.SetLevel(slog.LevelInfo)
+	s := newTestServer(t, Deps{})
+
+	debug := "DEBUG"
+	if _, err := s.logLevels.Update(context.Background(), models.UpdateLogLevelRequest{Level: &debug, TTLSeconds: 600}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	next := testConfig()
+	next.LogLevel = slog.LevelError
+	if err := s.Reload(next); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The temporary level stays until its TTL, then gives way to the
+	// reloaded one
+	if logger.Level() != slog.LevelDebug {
+		t.Errorf("expected the temporary level kept over the reload, got %s", logger.Level())
+	}
+
+	s.Close()
+	if got := s.logLevels.Get(); got.RevertAt != nil {
+		t.Errorf("expected the pending revert canceled on Close, got %v", got.RevertAt)
+	}
+}
+
+func TestServer_ProblemFormatPerServer(t *testing.T) {
+	newServer := func(format string) *Server {
+		cfg := testConfig()
+		cfg.ErrorFormat = format
+		cfg.ErrorTypeBaseURI = "https://api.example.com/errors/"
+		return newTestServer(t, Deps{Config: cfg})
+	}
+	// Two servers of one process keep their own error format
+	problems, plain := newServer(config.ErrorFormatProblem), newServer(config.ErrorFormatJSON)
+
+	rec := httptest.NewRecorder()
+	problems.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/nope", nil))
+	var problem struct {
+		Type string `json:"type"`
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/problem+json" || json.Unmarshal(rec.Body.Bytes(), &problem) != nil {
+		t.Fatalf("expected a problem document, got %s: %s", ct, rec.Body)
+	}
+	if problem.Type != "https://api.example.com/errors/NOT_FOUND" {
+		t.Errorf("expected the problem typed under ERROR_TYPE_BASE_URI, got %q", problem.Type)
+	}
+
+	rec = httptest.NewRecorder()
+	plain.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/nope", nil))
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("expected plain JSON errors on the other server, got %s", ct)
+	}
+}
+
+func TestServer_WebhooksNeedEncryptionKeys(t *testing.T) {
+	hasWebhooks := func(cfg *config.Config) bool {
+		s := newTestServer(t, Deps{Config: cfg})
+		return slices.ContainsFunc(s.routeTable(), func(rt route) bool { return rt.pattern == "POST /webhooks" })
+	}
+
+	if hasWebhooks(testConfig()) {
+		t.Error("expected no webhook routes without encryption keys")
+	}
+	cfg := testConfig()
+	cfg.EncryptionKeys = "k1:" + base64.StdEncoding.EncodeToString(make([]byte, secretbox.KeySize))
+	if !hasWebhooks(cfg) {
+		t.Error("expected the webhook routes with encryption keys")
+	}
+}
@@ -0,0 +1,31 @@
+package app
+
+import (
+	"net/http"
+
+	"github.com/clementhaon/sandbox-api-go/respond"
+)
+
+// handleRoutes lists the registered routes with the middlewares each runs
+// behind, to compare deployments.
+func (s *Server) handleRoutes(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+
+	routes := make([]routeInfo, len(s.routeList))
+	for i, rt := range s.routeList {
+		routes[i] = rt.info()
+	}
+	respond.OK(w, routes)
+	return nil
+}
+
+// handleConfig returns the configuration in effect, reloads included, with
+// secrets masked.
+func (s *Server) handleConfig(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+
+	respond.OK(w, s.Config().Redacted())
+	return nil
+}
@@ -0,0 +1,194 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/logger"
+	"github.com/clementhaon/sandbox-api-go/middleware"
+)
+
+// access says who may call a route.
+type access int
+
+const (
+	public        access = iota
+	authenticated        // a valid JWT, under the per-user rate limit
+	diagnostics          // an admin JWT or the diagnostics token
+)
+
+// rateLimit is the rate limit class of a route, on top of the per-user
+// limit of authenticated routes.
+type rateLimit int
+
+const (
+	rateLimitNone rateLimit = iota
+	// rateLimitIP applies the per-IP limit of RATE_LIMIT_REQUESTS, for
+	// credential and abuse-prone routes.
+	rateLimitIP
+)
+
+// smallBody bounds the bodies of routes taking a few short fields, such as
+// credentials.
+const smallBody = 16 << 10
+
+// route declares an endpoint and the middlewares it runs behind. handlerFor
+// chains them in the same order for every route, so a route only says what
+// it needs.
+type route struct {
+	pattern string // "GET /tasks/{id}", as for http.ServeMux
+	handler middleware.ErrorHandler
+	// raw serves a route outside the API's error handling, such as the
+	// metrics and the WebSocket; the other fields do not apply to it.
+	raw http.Handler
+
+	access          access
+	roles           []string // any of them; implies authenticated
+	scopes          []string // any of them for scoped tokens, refused without; implies authenticated
+	noImpersonation bool     // refused with an impersonation token; implies authenticated
+	noSandbox       bool     // exempt from the sandbox settings of personal tokens
+	noUsage         bool     // neither counted in nor refused by the daily request quotas
+	rateLimit       rateLimit
+	maxBody         int64         // below the server-wide MAX_BODY_SIZE; 0 keeps it
+	timeout         time.Duration // deadline of the handler; 0 for none
+	cached          bool          // anonymous GETs served from the response cache
+	// checks run after authentication, for routes authenticating their
+	// callers themselves (signatures, SCIM token)
+	checks []func(middleware.ErrorHandler) middleware.ErrorHandler
+}
+
+func (rt route) needsAuth() bool {
+	return rt.access == authenticated || len(rt.roles) > 0 || len(rt.scopes) > 0 || rt.noImpersonation
+}
+
+// routeInfo describes a route for the route table, logged at startup and
+// served at /admin/routes.
+type routeInfo struct {
+	Method     string   `json:"method,omitempty"` // empty for any method
+	Path       string   `json:"path"`
+	Access     string   `json:"access"` // public, auth, diagnostics, or raw when the route handles it
+	Middleware []string `json:"middleware"`
+}
+
+func (rt route) info() routeInfo {
+	info := routeInfo{Path: rt.pattern, Middleware: []string{}}
+	if method, path, ok := strings.Cut(rt.pattern, " "); ok {
+		info.Method, info.Path = method, path
+	}
+
+	switch {
+	case rt.raw != nil:
+		info.Access = "raw"
+	case rt.access == diagnostics:
+		info.Access = "diagnostics"
+	case rt.needsAuth():
+		info.Access = "auth"
+	default:
+		info.Access = "public"
+	}
+
+	if len(rt.roles) > 0 {
+		info.Middleware = append(info.Middleware, "roles="+strings.Join(rt.roles, "|"))
+	}
+	if len(rt.scopes) > 0 {
+		info.Middleware = append(info.Middleware, "scopes="+strings.Join(rt.scopes, "|"))
+	}
+	if rt.noImpersonation {
+		info.Middleware = append(info.Middleware, "no-impersonation")
+	}
+	if rt.noSandbox {
+		info.Middleware = append(info.Middleware, "no-sandbox")
+	}
+	if rt.noUsage {
+		info.Middleware = append(info.Middleware, "no-usage")
+	}
+	if len(rt.checks) > 0 {
+		info.Middleware = append(info.Middleware, fmt.Sprintf("checks=%d", len(rt.checks)))
+	}
+	if rt.rateLimit == rateLimitIP {
+		info.Middleware = append(info.Middleware, "rate-limit=ip")
+	}
+	if rt.maxBody > 0 {
+		info.Middleware = append(info.Middleware, fmt.Sprintf("max-body=%d", rt.maxBody))
+	}
+	if rt.timeout > 0 {
+		info.Middleware = append(info.Middleware, "timeout="+rt.timeout.String())
+	}
+	if rt.cached {
+		info.Middleware = append(info.Middleware, "cached")
+	}
+	return info
+}
+
+// String describes the route and its middlewares on one line.
+func (rt route) String() string {
+	info := rt.info()
+	return strings.Join(append([]string{rt.pattern, info.Access}, info.Middleware...), " ")
+}
+
+// handlerFor chains the middlewares rt declares around its handler, from
+// the outermost: IP rate limit, response cache, authentication and error
+// handling, usage tracking, sandbox simulation, roles, scopes,
+// impersonation, checks, body limit, timeout.
+func (s *Server) handlerFor(rt route) http.Handler {
+	if rt.raw != nil {
+		return rt.raw
+	}
+
+	h := rt.handler
+	if rt.timeout > 0 {
+		h = middleware.Timeout(rt.timeout)(h)
+	}
+	if rt.maxBody > 0 {
+		h = middleware.MaxBytes(rt.maxBody)(h)
+	}
+	for i := len(rt.checks) - 1; i >= 0; i-- {
+		h = rt.checks[i](h)
+	}
+	if rt.noImpersonation {
+		h = middleware.RejectImpersonation(h)
+	}
+	if rt.needsAuth() {
+		h = middleware.RequireScope(rt.scopes...)(h)
+	}
+	if len(rt.roles) > 0 {
+		h = middleware.RequireRole(rt.roles...)(h)
+	}
+	if rt.needsAuth() && !rt.noSandbox {
+		h = middleware.SimulateSandbox(h)
+	}
+	if rt.needsAuth() && !rt.noUsage {
+		h = s.trackUsage(h)
+	}
+
+	var handler http.HandlerFunc
+	switch {
+	case rt.access == diagnostics:
+		handler = s.diagnosticsMW(h)
+	case rt.needsAuth():
+		handler = s.authMW(h)
+	default:
+		handler = middleware.ErrorMiddleware(h)
+	}
+	if rt.cached {
+		handler = s.responseCache.Cache(handler)
+	}
+	if rt.rateLimit == rateLimitIP {
+		handler = s.rateLimiter.Limit(handler)
+	}
+	return handler
+}
+
+// logRoutes logs the route table, one line per route.
+func logRoutes(table []route) {
+	lines := make([]string, len(table))
+	for i, rt := range table {
+		lines[i] = rt.String()
+	}
+	logger.Info("Routes registered", map[string]interface{}{
+		"count":  len(table),
+		"routes": lines,
+	})
+}
@@ -0,0 +1,349 @@
+package app
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/handlers"
+	"github.com/clementhaon/sandbox-api-go/logger"
+	"github.com/clementhaon/sandbox-api-go/middleware"
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/respond"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+func (s *Server) routes() http.Handler {
+	mux := http.NewServeMux()
+
+	// The home route takes the requests no other route matches, including
+	// OPTIONS and methods a path has no route for
+	home := s.responseCache.Cache(middleware.ErrorMiddleware(routeMethods(mux, handleHome)))
+	if s.frontend != nil {
+		home = frontendOr(s.frontend, home)
+	}
+	mux.HandleFunc("/", home)
+
+	s.routeList = s.routeTable()
+	for _, rt := range s.routeList {
+		mux.Handle(rt.pattern, s.handlerFor(rt))
+	}
+	logRoutes(s.routeList)
+
+	if s.frontend != nil {
+		return s.frontend.Navigations(mux, isAPINavigation)
+	}
+	return mux
+}
+
+// routeTable declares the routes of the API and what each runs behind.
+func (s *Server) routeTable() []route {
+	cfg := s.Config()
+	admin := []string{models.RoleAdmin}
+	tasksRead, tasksWrite := []string{models.ScopeTasksRead}, []string{models.ScopeTasksWrite}
+	profileRead, profileWrite := []string{models.ScopeProfileRead}, []string{models.ScopeProfileWrite}
+	notificationsRead, notificationsWrite := []string{models.ScopeNotificationsRead}, []string{models.ScopeNotificationsWrite}
+	anyScope := models.ValidScopes()
+
+	table := []route{
+		// Public routes (no authentication required)
+		{pattern: "POST /auth/register", handler: s.authHandler.HandleRegister, rateLimit: rateLimitIP, maxBody: smallBody},
+		{pattern: "POST /auth/login", handler: s.authHandler.HandleLogin, rateLimit: rateLimitIP, maxBody: smallBody},
+		{pattern: "POST /auth/logout", handler: s.authHandler.HandleLogout},
+		{pattern: "POST /auth/password-strength", handler: s.authHandler.HandlePasswordStrength, rateLimit: rateLimitIP, maxBody: smallBody},
+		{pattern: "POST /auth/verify-email", handler: s.accountHandler.HandleConfirmEmail, rateLimit: rateLimitIP, maxBody: smallBody},
+		{pattern: "POST /auth/sessions/revoke", handler: s.sessionHandler.HandleRevoke, rateLimit: rateLimitIP, maxBody: smallBody},
+		{pattern: "GET /errors", handler: handlers.HandleErrorCatalog, cached: true},
+		{pattern: "POST /inbound/tasks", handler: s.inboundHandler.ReceiveTask, rateLimit: rateLimitIP},
+	}
+
+	// OpenID Connect provider
+	if s.oidcHandler != nil {
+		table = append(table,
+			route{pattern: "GET /.well-known/openid-configuration", handler: s.oidcHandler.HandleDiscovery},
+			route{pattern: "GET /.well-known/jwks.json", handler: s.oidcHandler.HandleJWKS},
+			route{pattern: "GET /oauth/authorize", handler: s.oidcHandler.HandleStartAuthorize},
+			route{pattern: "POST /oauth/authorize", handler: s.oidcHandler.HandleAuthorize, noImpersonation: true},
+			route{pattern: "POST /oauth/token", handler: s.oidcHandler.HandleToken, rateLimit: rateLimitIP, maxBody: smallBody},
+			route{pattern: "GET /oauth/userinfo", handler: s.oidcHandler.HandleUserInfo},
+		)
+	}
+
+	// SCIM provisioning, for identity providers holding SCIM_TOKEN
+	if cfg.SCIMToken != "" {
+		scimAuth := middleware.NewSCIMAuth(cfg.SCIMToken)
+		scim := []func(middleware.ErrorHandler) middleware.ErrorHandler{scimAuth}
+		table = append(table,
+			route{pattern: "GET /scim/v2/Users", handler: s.scimHandler.ListUsers, checks: scim},
+			route{pattern: "POST /scim/v2/Users", handler: s.scimHandler.CreateUser, checks: scim},
+			route{pattern: "GET /scim/v2/Users/{id}", handler: s.scimHandler.GetUser, checks: scim},
+			route{pattern: "PATCH /scim/v2/Users/{id}", handler: s.scimHandler.PatchUser, checks: scim},
+			route{pattern: "DELETE /scim/v2/Users/{id}", handler: s.scimHandler.DeleteUser, checks: scim},
+		)
+	}
+
+	// Slack slash commands, signed with SLACK_SIGNING_SECRET
+	if cfg.SlackSigningSecret != "" {
+		slackSig := middleware.NewSlackSignature(cfg.SlackSigningSecret)
+		table = append(table, route{
+			pattern: "POST /integrations/slack/commands", handler: s.slackHandler.HandleCommand,
+			rateLimit: rateLimitIP, checks: []func(middleware.ErrorHandler) middleware.ErrorHandler{slackSig},
+		})
+	}
+
+	// Prometheus metrics endpoint
+	// OpenMetrics is required to expose latency exemplars
+	metricsAuth := middleware.NewMetricsAuth(cfg.MetricsToken, cfg.MetricsUser, cfg.MetricsPassword)
+	table = append(table,
+		route{pattern: "/metrics", raw: metricsAuth(promhttp.InstrumentMetricHandler(
+			s.metrics.Registry(),
+			promhttp.HandlerFor(s.metrics.Registry(), promhttp.HandlerOpts{EnableOpenMetrics: true}),
+		))},
+
+		// WebSocket endpoint (auth via query param)
+		route{pattern: "/ws", raw: http.HandlerFunc(s.wsHandler.HandleWebSocket)},
+	)
+
+	table = append(table, []route{
+		// Users Management Routes
+		{pattern: "GET /users", handler: s.userHandler.ListUsers, access: authenticated},
+		{pattern: "GET /users/search", handler: s.userHandler.SearchUsers, access: authenticated},
+		{pattern: "GET /users/{id}", handler: s.userHandler.GetUser, access: authenticated},
+		{pattern: "POST /users", handler: s.userHandler.CreateUser, roles: admin},
+		{pattern: "PUT /users/{id}", handler: s.userHandler.UpdateUser, roles: admin, noImpersonation: true},
+		{pattern: "PATCH /users/{id}/status", handler: s.userHandler.UpdateUserStatus, roles: admin, noImpersonation: true},
+		{pattern: "DELETE /users/{id}", handler: s.userHandler.DeleteUser, roles: admin, noImpersonation: true},
+
+		// Columns Management Routes
+		{pattern: "GET /columns", handler: s.columnHandler.ListColumns, scopes: tasksRead},
+		{pattern: "POST /columns", handler: s.columnHandler.CreateColumn, scopes: tasksWrite},
+		{pattern: "PUT /columns/{id}", handler: s.columnHandler.UpdateColumn, scopes: tasksWrite},
+		{pattern: "DELETE /columns/{id}", handler: s.columnHandler.DeleteColumn, scopes: tasksWrite},
+		{pattern: "PATCH /columns/reorder", handler: s.columnHandler.ReorderColumns, scopes: tasksWrite},
+
+		// Tasks Management Routes (Board)
+		{pattern: "GET /tasks/board", handler: s.taskHandler.GetBoard, scopes: tasksRead},
+		{pattern: "GET /tasks", handler: s.taskHandler.ListTasks, scopes: tasksRead},
+		{pattern: "GET /tasks/changes", handler: s.taskHandler.ListTaskChanges, scopes: tasksRead},
+		{pattern: "GET /tasks/export", handler: s.taskHandler.ExportTasks, scopes: tasksRead},
+		{pattern: "GET /tasks/{id}", handler: s.taskHandler.GetTask, scopes: tasksRead},
+		{pattern: "POST /tasks", handler: s.taskHandler.CreateTask, scopes: tasksWrite},
+		{pattern: "PUT /tasks/{id}", handler: s.taskHandler.UpdateTask, scopes: tasksWrite},
+		{pattern: "PATCH /tasks/{id}/move", handler: s.taskHandler.MoveTask, scopes: tasksWrite},
+		{pattern: "PATCH /tasks/{id}/status", handler: s.taskHandler.SetTaskStatus, scopes: tasksWrite},
+		{pattern: "POST /tasks/{id}/complete", handler: s.taskHandler.CompleteTask, scopes: tasksWrite},
+		{pattern: "POST /tasks/{id}/reopen", handler: s.taskHandler.ReopenTask, scopes: tasksWrite},
+		{pattern: "PATCH /tasks/reorder", handler: s.taskHandler.ReorderTasks, scopes: tasksWrite},
+		{pattern: "DELETE /tasks/{id}", handler: s.taskHandler.DeleteTask, scopes: tasksWrite},
+
+		// Inbound webhook creating tasks from other tools
+		{pattern: "GET /inbound", handler: s.inboundHandler.GetSettings, access: authenticated},
+		{pattern: "POST /inbound/secret", handler: s.inboundHandler.RotateSecret, noImpersonation: true},
+		{pattern: "PUT /inbound/mapping", handler: s.inboundHandler.UpdateMapping, access: authenticated},
+		{pattern: "DELETE /inbound", handler: s.inboundHandler.Disable, access: authenticated},
+
+		// Slack accounts linked to run slash commands as the user
+		{pattern: "GET /integrations/slack/links", handler: s.slackHandler.ListLinks, access: authenticated},
+		{pattern: "POST /integrations/slack/link-code", handler: s.slackHandler.CreateLinkCode, noImpersonation: true},
+		{pattern: "DELETE /integrations/slack/links", handler: s.slackHandler.Unlink, access: authenticated},
+
+		// Personal access tokens, which cannot manage tokens themselves
+		{pattern: "GET /tokens", handler: s.tokenHandler.ListTokens, access: authenticated},
+		{pattern: "POST /tokens", handler: s.tokenHandler.CreateToken, noImpersonation: true, maxBody: smallBody},
+		{pattern: "DELETE /tokens/{id}", handler: s.tokenHandler.RevokeToken, access: authenticated},
+
+		// Sandbox settings of the personal token making the request, for
+		// any of its scopes. They do not apply here, so a token failing
+		// every request can still be fixed
+		{pattern: "GET /sandbox/settings", handler: s.tokenHandler.GetSandboxSettings, scopes: anyScope, noSandbox: true},
+		{pattern: "PUT /sandbox/settings", handler: s.tokenHandler.UpdateSandboxSettings, scopes: anyScope, noSandbox: true, maxBody: smallBody},
+
+		// Sandbox fixtures, replacing the caller's tasks and notifications
+		{pattern: "POST /sandbox/reset", handler: s.sandboxHandler.Reset, scopes: tasksWrite, noImpersonation: true},
+		{pattern: "POST /sandbox/scenarios/{name}", handler: s.sandboxHandler.LoadScenario, scopes: tasksWrite, noImpersonation: true, timeout: 30 * time.Second},
+
+		// Sandbox clock of the caller, offsetting the time the server uses
+		// for their requests
+		{pattern: "GET /sandbox/clock", handler: s.sandboxHandler.GetClock, scopes: anyScope, noSandbox: true},
+		{pattern: "POST /sandbox/clock", handler: s.sandboxHandler.SetClock, scopes: anyScope, noSandbox: true, noImpersonation: true, maxBody: smallBody},
+
+		// API usage of the caller against their daily quotas. Not counted
+		// itself, so it can still be read once a quota is reached
+		{pattern: "GET /usage", handler: s.usageHandler.GetUsage, scopes: anyScope, noUsage: true},
+
+		// Task Checklist Routes
+		{pattern: "GET /tasks/{id}/checklist", handler: s.checklistHandler.ListItems, scopes: tasksRead},
+		{pattern: "POST /tasks/{id}/checklist", handler: s.checklistHandler.AddItem, scopes: tasksWrite},
+		{pattern: "PATCH /tasks/{id}/checklist/reorder", handler: s.checklistHandler.ReorderItems, scopes: tasksWrite},
+		{pattern: "PATCH /tasks/{id}/checklist/{itemId}", handler: s.checklistHandler.UpdateItem, scopes: tasksWrite},
+		{pattern: "DELETE /tasks/{id}/checklist/{itemId}", handler: s.checklistHandler.DeleteItem, scopes: tasksWrite},
+
+		// Activity Feed Routes
+		{pattern: "GET /activity", handler: s.activityHandler.ListActivity, scopes: tasksRead},
+
+		// Time Entries Routes
+		{pattern: "GET /time-entries", handler: s.timeEntryHandler.ListTimeEntries, scopes: tasksRead},
+		{pattern: "POST /time-entries", handler: s.timeEntryHandler.CreateTimeEntry, scopes: tasksWrite},
+		{pattern: "DELETE /time-entries/{id}", handler: s.timeEntryHandler.DeleteTimeEntry, scopes: tasksWrite},
+
+		// Notifications Routes
+		{pattern: "GET /notifications", handler: s.notificationHandler.ListNotifications, scopes: notificationsRead},
+		{pattern: "GET /notifications/unread-count", handler: s.notificationHandler.UnreadCount, scopes: notificationsRead},
+		{pattern: "POST /notifications/{id}/read", handler: s.notificationHandler.MarkNotificationRead, scopes: notificationsWrite},
+		{pattern: "PATCH /notifications/read", handler: s.notificationHandler.MarkNotificationsRead, scopes: notificationsWrite},
+		{pattern: "PATCH /notifications/read-all", handler: s.notificationHandler.MarkAllNotificationsRead, scopes: notificationsWrite},
+		{pattern: "DELETE /notifications/{id}", handler: s.notificationHandler.DeleteNotification, scopes: notificationsWrite},
+
+		// Announcements Routes
+		{pattern: "GET /announcements", handler: s.announcementHandler.ListActiveAnnouncements, access: authenticated},
+		{pattern: "POST /announcements/{id}/dismiss", handler: s.announcementHandler.DismissAnnouncement, access: authenticated},
+
+		// Auth & Profile Routes
+		{pattern: "GET /auth/user", handler: s.authHandler.HandleGetUser, scopes: profileRead},
+		{pattern: "GET /profile", handler: s.profileHandler.HandleGetProfile, scopes: profileRead},
+		{pattern: "PUT /profile", handler: s.profileHandler.HandleUpdateProfile, scopes: profileWrite},
+		{pattern: "GET /profile/preferences", handler: s.profileHandler.HandleGetPreferences, scopes: profileRead},
+		{pattern: "PUT /profile/preferences", handler: s.profileHandler.HandleUpdatePreferences, scopes: profileWrite},
+		{pattern: "PUT /profile/email", handler: s.accountHandler.HandleChangeEmail, noImpersonation: true, rateLimit: rateLimitIP},
+		{pattern: "PUT /profile/username", handler: s.accountHandler.HandleChangeUsername, noImpersonation: true, rateLimit: rateLimitIP},
+		{pattern: "GET /profile/export", handler: s.exportHandler.HandleRequestExport, noImpersonation: true},
+		{pattern: "GET /profile/export/{id}", handler: s.exportHandler.HandleGetExport, access: authenticated},
+		{pattern: "GET /profile/export/{id}/download", handler: s.exportHandler.HandleDownloadExport, access: authenticated},
+
+		// Media Routes
+		{pattern: "POST /media/upload", handler: s.mediaHandler.HandleGetPresignedUploadURL, access: authenticated},
+		{pattern: "POST /media/confirm", handler: s.mediaHandler.HandleConfirmUpload, access: authenticated},
+		{pattern: "GET /media", handler: s.mediaHandler.HandleGetUserMedia, access: authenticated},
+		{pattern: "GET /media/{id}", handler: s.mediaHandler.HandleGetMediaByID, access: authenticated},
+		{pattern: "GET /media/{id}/download", handler: s.mediaHandler.HandleGetPresignedDownloadURL, access: authenticated},
+		{pattern: "DELETE /media/{id}", handler: s.mediaHandler.HandleDeleteMedia, access: authenticated},
+
+		// Admin Routes
+		{pattern: "GET /admin/stats", handler: s.statsHandler.GetStats, roles: admin, timeout: 10 * time.Second},
+		{pattern: "POST /admin/impersonate/{userID}", handler: s.authHandler.HandleImpersonate, roles: admin, noImpersonation: true},
+		{pattern: "GET /admin/announcements", handler: s.announcementHandler.ListAnnouncements, roles: admin},
+		{pattern: "POST /admin/announcements", handler: s.announcementHandler.CreateAnnouncement, roles: admin},
+		{pattern: "PUT /admin/announcements/{id}", handler: s.announcementHandler.UpdateAnnouncement, roles: admin},
+		{pattern: "DELETE /admin/announcements/{id}", handler: s.announcementHandler.DeleteAnnouncement, roles: admin},
+		{pattern: "GET /admin/users/export", handler: s.userHandler.ExportUsers, roles: admin},
+		{pattern: "GET /admin/audit-logs", handler: s.auditHandler.ListAuditLogs, roles: admin, timeout: 10 * time.Second},
+		{pattern: "GET /admin/users/{id}/quota", handler: s.quotaHandler.GetUserQuota, roles: admin},
+		{pattern: "PUT /admin/users/{id}/quota", handler: s.quotaHandler.UpdateUserQuota, roles: admin},
+		{pattern: "GET /admin/routes", handler: s.handleRoutes, roles: admin},
+		{pattern: "GET /admin/config", handler: s.handleConfig, roles: admin},
+		{pattern: "GET /admin/loglevel", handler: s.logLevelHandler.GetLogLevel, roles: admin},
+		{pattern: "PUT /admin/loglevel", handler: s.logLevelHandler.UpdateLogLevel, roles: admin, maxBody: smallBody},
+		{pattern: "GET /admin/backups", handler: s.backupHandler.ListBackups, roles: admin},
+		{pattern: "POST /admin/backups", handler: s.backupHandler.CreateBackup, roles: admin, noImpersonation: true},
+		{pattern: "POST /admin/backups/{name}/restore", handler: s.backupHandler.RestoreBackup, roles: admin, noImpersonation: true},
+		{pattern: "GET /admin/backups/jobs/{id}", handler: s.backupHandler.GetBackupJob, roles: admin},
+
+		// Diagnostics Routes (admin or diagnostics token)
+		{pattern: "GET /debug/runtime", handler: s.diagnosticsHandler.HandleRuntimeStats, access: diagnostics},
+		{pattern: "/debug/pprof/", handler: s.diagnosticsHandler.HandlePprof, access: diagnostics},
+	}...)
+
+	// Outgoing webhooks, on with encryption keys to seal their secrets
+	if s.webhookHandler != nil {
+		table = append(table,
+			route{pattern: "GET /webhooks", handler: s.webhookHandler.ListWebhooks, access: authenticated},
+			route{pattern: "POST /webhooks", handler: s.webhookHandler.CreateWebhook, noImpersonation: true, maxBody: smallBody},
+			route{pattern: "DELETE /webhooks/{id}", handler: s.webhookHandler.DeleteWebhook, access: authenticated},
+			route{pattern: "POST /webhooks/{id}/test", handler: s.webhookHandler.TestWebhook, access: authenticated},
+		)
+	}
+
+	return table
+}
+
+// frontendOr lets the frontend take the GET requests no API route answers;
+// other methods still get the API's errors.
+func frontendOr(frontend http.Handler, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			frontend.ServeHTTP(w, r)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// isAPINavigation tells the API endpoints browsers load as pages, which the
+// frontend must not take over: OAuth and discovery, downloads, and the
+// operator endpoints.
+func isAPINavigation(r *http.Request) bool {
+	for _, prefix := range []string{"/oauth/", "/.well-known/", "/debug/", "/metrics", "/ws", "/errors", "/scim/", "/integrations/", "/inbound/"} {
+		if strings.HasPrefix(r.URL.Path, prefix) {
+			return true
+		}
+	}
+	return strings.HasSuffix(r.URL.Path, "/download")
+}
+
+// routeMethods answers OPTIONS with the methods the routes of the request
+// path allow, and refuses the other methods with a 405 listing them in the
+// Allow header. Paths without any route are left to next.
+func routeMethods(mux *http.ServeMux, next middleware.ErrorHandler) middleware.ErrorHandler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		allowed := allowedMethods(mux, r)
+		if len(allowed) == 0 {
+			return next(w, r)
+		}
+		if r.Method == http.MethodOptions {
+			w.Header().Set("Allow", strings.Join(allowed, ", "))
+			w.WriteHeader(http.StatusNoContent)
+			return nil
+		}
+		if r.URL.Path != "/" || !slices.Contains(allowed, r.Method) {
+			w.Header().Set("Allow", strings.Join(allowed, ", "))
+			return errors.NewMethodNotAllowedError()
+		}
+		return next(w, r)
+	}
+}
+
+// routedMethods are the methods tried to find the ones a path has routes for.
+var routedMethods = []string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+// allowedMethods lists the methods mux routes the path of r to a route
+// other than home, plus OPTIONS. It is empty for paths without a route.
+func allowedMethods(mux *http.ServeMux, r *http.Request) []string {
+	if r.URL.Path == "/" {
+		return []string{http.MethodGet, http.MethodHead, http.MethodOptions}
+	}
+	var allowed []string
+	probe := r.Clone(r.Context())
+	for _, method := range routedMethods {
+		probe.Method = method
+		if _, pattern := mux.Handler(probe); pattern != "" && pattern != "/" {
+			allowed = append(allowed, method)
+		}
+	}
+	if len(allowed) > 0 {
+		allowed = append(allowed, http.MethodOptions)
+	}
+	return allowed
+}
+
+func handleHome(w http.ResponseWriter, r *http.Request) error {
+	if r.URL.Path != "/" {
+		return errors.NewNotFoundError("Page")
+	}
+
+	base := middleware.BasePath(r.Context())
+	response := map[string]interface{}{
+		"message": "Welcome to the Go REST API with authentication! 🎉",
+		"version": "2.0.0",
+		"links": map[string]string{
+			"self":   base + "/",
+			"errors": base + "/errors",
+		},
+	}
+
+	logger.DebugContext(r.Context(), "Home endpoint accessed")
+	respond.OK(w, response)
+	return nil
+}
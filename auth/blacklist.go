@@ -1,63 +1,58 @@
 package auth
 
 import (
-	"sync"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"time"
+
+	"github.com/clementhaon/sandbox-api-go/kvstore"
 )
 
-// TokenBlacklist maintains a set of revoked JWT tokens until they expire.
+// TokenBlacklist keeps revoked JWT tokens in a shared store until they
+// expire, so a logout holds on every instance using the same store.
 type TokenBlacklist struct {
-	mu     sync.RWMutex
-	tokens map[string]time.Time // token -> expiry time
-	stopCh chan struct{}
+	store kvstore.Store
+}
+
+// NewTokenBlacklist creates a TokenBlacklist backed by store.
+func NewTokenBlacklist(store kvstore.Store) *TokenBlacklist {
+	return &TokenBlacklist{store: store}
 }
 
-// NewTokenBlacklist creates a new TokenBlacklist and starts a cleanup goroutine.
-func NewTokenBlacklist() *TokenBlacklist {
-	bl := &TokenBlacklist{
-		tokens: make(map[string]time.Time),
-		stopCh: make(chan struct{}),
+// Add revokes a token until its expiry time.
+func (bl *TokenBlacklist) Add(ctx context.Context, token string, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return nil
 	}
-	go bl.cleanup()
-	return bl
+	return bl.store.Set(ctx, revokedKey(TokenHash(token)), []byte{1}, ttl)
 }
 
-// Add adds a token to the blacklist with its expiry time.
-func (bl *TokenBlacklist) Add(token string, expiresAt time.Time) {
-	bl.mu.Lock()
-	defer bl.mu.Unlock()
-	bl.tokens[token] = expiresAt
+// AddHash revokes the token of hash TokenHash until its expiry time, for
+// callers which only kept the hash.
+func (bl *TokenBlacklist) AddHash(ctx context.Context, hash string, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+	return bl.store.Set(ctx, revokedKey(hash), []byte{1}, ttl)
 }
 
-// IsBlacklisted returns true if the token has been revoked.
-func (bl *TokenBlacklist) IsBlacklisted(token string) bool {
-	bl.mu.RLock()
-	defer bl.mu.RUnlock()
-	_, exists := bl.tokens[token]
-	return exists
+// IsBlacklisted reports whether the token has been revoked.
+func (bl *TokenBlacklist) IsBlacklisted(ctx context.Context, token string) (bool, error) {
+	_, revoked, err := bl.store.Get(ctx, revokedKey(TokenHash(token)))
+	return revoked, err
 }
 
-// Stop terminates the cleanup goroutine.
-func (bl *TokenBlacklist) Stop() {
-	close(bl.stopCh)
+// TokenHash identifies a token without holding it, so that stores never
+// keep usable tokens.
+func TokenHash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
 
-func (bl *TokenBlacklist) cleanup() {
-	ticker := time.NewTicker(5 * time.Minute)
-	defer ticker.Stop()
-	for {
-		select {
-		case <-ticker.C:
-			bl.mu.Lock()
-			now := time.Now()
-			for token, expiresAt := range bl.tokens {
-				if now.After(expiresAt) {
-					delete(bl.tokens, token)
-				}
-			}
-			bl.mu.Unlock()
-		case <-bl.stopCh:
-			return
-		}
-	}
+// revokedKey names a revoked token by its hash.
+func revokedKey(hash string) string {
+	return "revoked:" + hash
 }
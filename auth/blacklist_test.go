@@ -1,54 +1,78 @@
 package auth
 
 import (
+	"context"
 	"testing"
 	"time"
+
+	"github.com/clementhaon/sandbox-api-go/kvstore"
 )
 
 func TestTokenBlacklist(t *testing.T) {
+	ctx := context.Background()
+	newBlacklist := func(t *testing.T) *TokenBlacklist {
+		store := kvstore.NewMemoryStore()
+		t.Cleanup(store.Stop)
+		return NewTokenBlacklist(store)
+	}
+
 	t.Run("Add and IsBlacklisted returns true", func(t *testing.T) {
-		bl := NewTokenBlacklist()
-		defer bl.Stop()
+		bl := newBlacklist(t)
 
-		bl.Add("token-abc", time.Now().Add(1*time.Hour))
+		if err := bl.Add(ctx, "token-abc", time.Now().Add(1*time.Hour)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
 
-		if !bl.IsBlacklisted("token-abc") {
+		if revoked, _ := bl.IsBlacklisted(ctx, "token-abc"); !revoked {
 			t.Error("expected token to be blacklisted")
 		}
 	})
 
 	t.Run("non-blacklisted token returns false", func(t *testing.T) {
-		bl := NewTokenBlacklist()
-		defer bl.Stop()
+		bl := newBlacklist(t)
 
-		if bl.IsBlacklisted("never-added") {
+		if revoked, _ := bl.IsBlacklisted(ctx, "never-added"); revoked {
 			t.Error("expected token to not be blacklisted")
 		}
 	})
 
 	t.Run("multiple tokens can be blacklisted", func(t *testing.T) {
-		bl := NewTokenBlacklist()
-		defer bl.Stop()
+		bl := newBlacklist(t)
 
 		tokens := []string{"token-1", "token-2", "token-3"}
 		for _, tok := range tokens {
-			bl.Add(tok, time.Now().Add(1*time.Hour))
+			bl.Add(ctx, tok, time.Now().Add(1*time.Hour))
 		}
 
 		for _, tok := range tokens {
-			if !bl.IsBlacklisted(tok) {
+			if revoked, _ := bl.IsBlacklisted(ctx, tok); !revoked {
 				t.Errorf("expected %q to be blacklisted", tok)
 			}
 		}
 
-		if bl.IsBlacklisted("token-4") {
+		if revoked, _ := bl.IsBlacklisted(ctx, "token-4"); revoked {
 			t.Error("expected token-4 to not be blacklisted")
 		}
 	})
 
-	t.Run("Stop does not panic", func(t *testing.T) {
-		bl := NewTokenBlacklist()
-		// Should not panic
-		bl.Stop()
+	t.Run("expired token is not stored", func(t *testing.T) {
+		bl := newBlacklist(t)
+
+		bl.Add(ctx, "token-old", time.Now().Add(-time.Minute))
+
+		if revoked, _ := bl.IsBlacklisted(ctx, "token-old"); revoked {
+			t.Error("expected an already expired token to be ignored")
+		}
+	})
+	t.Run("token revoked by its hash", func(t *testing.T) {
+		bl := newBlacklist(t)
+
+		if err := bl.AddHash(ctx, TokenHash("token-abc"), time.Now().Add(1*time.Hour)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if revoked, _ := bl.IsBlacklisted(ctx, "token-abc"); !revoked {
+			t.Error("expected token to be blacklisted")
+		}
 	})
 }
@@ -1,16 +1,19 @@
 package auth
 
 import (
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/tenant"
 	"github.com/golang-jwt/jwt/v5"
 )
 
 // JWTManager handles JWT token generation and validation
 type JWTManager struct {
-	secret []byte
+	secret        []byte
+	rememberMeTTL time.Duration
 }
 
 // NewJWTManager creates a new JWTManager with the given secret
@@ -18,16 +21,70 @@ func NewJWTManager(secret string) (*JWTManager, error) {
 	if len(secret) < 16 {
 		return nil, fmt.Errorf("JWT secret must be at least 16 characters long")
 	}
-	return &JWTManager{secret: []byte(secret)}, nil
+	return &JWTManager{secret: []byte(secret), rememberMeTTL: DefaultRememberMeTTL}, nil
+}
+
+// TokenTTL is how long a token from GenerateToken is valid.
+const TokenTTL = 24 * time.Hour
+
+// DefaultRememberMeTTL is how long a token from GenerateRememberMeToken is
+// valid, unless changed with WithRememberMeTTL.
+const DefaultRememberMeTTL = 30 * 24 * time.Hour
+
+// WithRememberMeTTL returns a copy of m whose remember-me tokens are valid
+// for ttl.
+func (m *JWTManager) WithRememberMeTTL(ttl time.Duration) *JWTManager {
+	c := *m
+	c.rememberMeTTL = ttl
+	return &c
+}
+
+// RememberMeTTL is how long a token from GenerateRememberMeToken is valid.
+func (m *JWTManager) RememberMeTTL() time.Duration {
+	return m.rememberMeTTL
 }
 
 // GenerateToken generates a JWT token for a user
 func (m *JWTManager) GenerateToken(user models.User) (string, error) {
+	return m.sign(userClaims(user, time.Now().Add(TokenTTL)))
+}
+
+// GenerateRememberMeToken generates a token for a user who asked to stay
+// logged in, valid for RememberMeTTL and carrying the remember_me claim.
+func (m *JWTManager) GenerateRememberMeToken(user models.User) (string, error) {
+	claims := userClaims(user, time.Now().Add(m.rememberMeTTL))
+	claims["remember_me"] = true
+	return m.sign(claims)
+}
+
+// GenerateImpersonationToken generates a token acting as user on behalf of
+// the impersonator, valid for ttl. The impersonator is carried in the
+// impersonator_id claim.
+func (m *JWTManager) GenerateImpersonationToken(user models.User, impersonatorID int, ttl time.Duration) (string, error) {
+	claims := userClaims(user, time.Now().Add(ttl))
+	claims["impersonator_id"] = impersonatorID
+	return m.sign(claims)
+}
+
+// GenerateScopedToken generates a token for user restricted to scopes and
+// valid for ttl, for machines calling the API on the user's behalf. An
+// empty scopes list still restricts the token.
+func (m *JWTManager) GenerateScopedToken(user models.User, scopes []string, ttl time.Duration) (string, error) {
+	claims := userClaims(user, time.Now().Add(ttl))
+	if scopes == nil {
+		scopes = []string{}
+	}
+	claims["scopes"] = scopes
+	return m.sign(claims)
+}
+
+func userClaims(user models.User, expiresAt time.Time) jwt.MapClaims {
 	claims := jwt.MapClaims{
-		"user_id":  user.ID,
-		"username": user.Username,
-		"role":     user.Role,
-		"exp":      time.Now().Add(24 * time.Hour).Unix(),
+		"user_id":   user.ID,
+		"tenant_id": user.TenantID,
+		"username":  user.Username,
+		"role":      user.Role,
+		"exp":       expiresAt.Unix(),
 	}
 
 	if user.FirstName.Valid {
@@ -39,7 +96,10 @@ func (m *JWTManager) GenerateToken(user models.User) (string, error) {
 	if user.AvatarURL.Valid {
 		claims["avatar_url"] = user.AvatarURL.String
 	}
+	return claims
+}
 
+func (m *JWTManager) sign(claims jwt.MapClaims) (string, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString(m.secret)
 }
@@ -64,10 +124,16 @@ func (m *JWTManager) ValidateToken(tokenString string) (*models.Claims, error) {
 
 		result := &models.Claims{
 			UserID:    userID,
+			TenantID:  tenant.DefaultID,
 			Username:  username,
 			ExpiresAt: time.Unix(exp, 0),
 		}
 
+		// Tokens issued before multi-tenancy carry no tenant
+		if tenantID, ok := claims["tenant_id"].(float64); ok && tenantID > 0 {
+			result.TenantID = int(tenantID)
+		}
+
 		if role, ok := claims["role"].(string); ok {
 			result.Role = role
 		}
@@ -80,9 +146,50 @@ func (m *JWTManager) ValidateToken(tokenString string) (*models.Claims, error) {
 		if avatarURL, ok := claims["avatar_url"].(string); ok {
 			result.AvatarURL = avatarURL
 		}
+		if impersonatorID, ok := claims["impersonator_id"].(float64); ok {
+			result.ImpersonatorID = int(impersonatorID)
+		}
+		if rememberMe, ok := claims["remember_me"].(bool); ok {
+			result.RememberMe = rememberMe
+		}
+		// A scopes claim restricts the token, even when it lists nothing
+		// usable
+		if raw, ok := claims["scopes"]; ok {
+			result.Scopes = []string{}
+			list, _ := raw.([]interface{})
+			for _, scope := range list {
+				if scope, ok := scope.(string); ok {
+					result.Scopes = append(result.Scopes, scope)
+				}
+			}
+		}
 
 		return result, nil
 	}
 
 	return nil, fmt.Errorf("invalid token")
 }
+
+// Reasons a token is refused, as reported in metrics
+const (
+	ReasonMissingToken     = "missing_token"
+	ReasonMalformedToken   = "malformed_token"
+	ReasonExpiredToken     = "expired_token"
+	ReasonInvalidSignature = "invalid_signature"
+	ReasonInvalidToken     = "invalid_token"
+	ReasonRevokedToken     = "revoked_token"
+	ReasonWrongTenant      = "wrong_tenant"
+)
+
+// FailureReason tells why ValidateToken refused a token.
+func FailureReason(err error) string {
+	switch {
+	case errors.Is(err, jwt.ErrTokenMalformed):
+		return ReasonMalformedToken
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return ReasonExpiredToken
+	case errors.Is(err, jwt.ErrTokenSignatureInvalid):
+		return ReasonInvalidSignature
+	}
+	return ReasonInvalidToken
+}
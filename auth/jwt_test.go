@@ -1,12 +1,12 @@
 package auth
 
 import (
-	"database/sql"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/tenant"
 	"github.com/golang-jwt/jwt/v5"
 )
 
@@ -67,21 +67,12 @@ func TestNewJWTManager(t *testing.T) {
 
 func testUser() models.User {
 	return models.User{
-		ID:       42,
-		Username: "testuser",
-		Role:     "admin",
-		FirstName: sql.NullString{
-			String: "John",
-			Valid:  true,
-		},
-		LastName: sql.NullString{
-			String: "Doe",
-			Valid:  true,
-		},
-		AvatarURL: sql.NullString{
-			String: "https://example.com/avatar.png",
-			Valid:  true,
-		},
+		ID:        42,
+		Username:  "testuser",
+		Role:      "admin",
+		FirstName: models.NewNullString("John"),
+		LastName:  models.NewNullString("Doe"),
+		AvatarURL: models.NewNullString("https://example.com/avatar.png"),
 	}
 }
 
@@ -152,7 +143,123 @@ func TestGenerateToken(t *testing.T) {
 		if claims.AvatarURL != "" {
 			t.Errorf("AvatarURL = %q, want empty", claims.AvatarURL)
 		}
+		if claims.TenantID != tenant.DefaultID {
+			t.Errorf("TenantID = %d, want default %d", claims.TenantID, tenant.DefaultID)
+		}
 	})
+
+	t.Run("carries the user's tenant", func(t *testing.T) {
+		user := models.User{ID: 7, TenantID: 3, Username: "tenanted", Role: "user"}
+		tokenStr, err := mgr.GenerateToken(user)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		claims, err := mgr.ValidateToken(tokenStr)
+		if err != nil {
+			t.Fatalf("generated token should be valid: %v", err)
+		}
+		if claims.TenantID != 3 {
+			t.Errorf("TenantID = %d, want 3", claims.TenantID)
+		}
+		if claims.ImpersonatorID != 0 {
+			t.Errorf("ImpersonatorID = %d, want 0", claims.ImpersonatorID)
+		}
+	})
+}
+
+func TestGenerateImpersonationToken(t *testing.T) {
+	mgr, err := NewJWTManager("test-secret-at-least-16")
+	if err != nil {
+		t.Fatalf("failed to create JWTManager: %v", err)
+	}
+
+	tokenStr, err := mgr.GenerateImpersonationToken(testUser(), 99, 15*time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	claims, err := mgr.ValidateToken(tokenStr)
+	if err != nil {
+		t.Fatalf("generated token should be valid: %v", err)
+	}
+	if claims.UserID != testUser().ID {
+		t.Errorf("UserID = %d, want %d", claims.UserID, testUser().ID)
+	}
+	if claims.ImpersonatorID != 99 {
+		t.Errorf("ImpersonatorID = %d, want 99", claims.ImpersonatorID)
+	}
+	if ttl := time.Until(claims.ExpiresAt); ttl > 15*time.Minute || ttl < 14*time.Minute {
+		t.Errorf("expected the token to expire in 15 minutes, got %s", ttl)
+	}
+}
+
+func TestGenerateRememberMeToken(t *testing.T) {
+	mgr, err := NewJWTManager("test-secret-at-least-16")
+	if err != nil {
+		t.Fatalf("failed to create JWTManager: %v", err)
+	}
+
+	for _, tt := range []struct {
+		mgr *JWTManager
+		ttl time.Duration
+	}{
+		{mgr, DefaultRememberMeTTL},
+		{mgr.WithRememberMeTTL(7 * 24 * time.Hour), 7 * 24 * time.Hour},
+	} {
+		tokenStr, err := tt.mgr.GenerateRememberMeToken(testUser())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		claims, err := tt.mgr.ValidateToken(tokenStr)
+		if err != nil {
+			t.Fatalf("generated token should be valid: %v", err)
+		}
+		if !claims.RememberMe {
+			t.Error("expected the remember_me claim")
+		}
+		if ttl := time.Until(claims.ExpiresAt); ttl > tt.ttl || ttl < tt.ttl-time.Minute {
+			t.Errorf("expected the token to expire in %s, got %s", tt.ttl, ttl)
+		}
+	}
+
+	tokenStr, _ := mgr.GenerateToken(testUser())
+	if claims, _ := mgr.ValidateToken(tokenStr); claims.RememberMe {
+		t.Error("expected no remember_me claim on a regular token")
+	}
+}
+
+func TestGenerateScopedToken(t *testing.T) {
+	mgr, err := NewJWTManager("test-secret-at-least-16")
+	if err != nil {
+		t.Fatalf("failed to create JWTManager: %v", err)
+	}
+
+	tokenStr, err := mgr.GenerateScopedToken(testUser(), []string{"tasks:read"}, time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	claims, err := mgr.ValidateToken(tokenStr)
+	if err != nil {
+		t.Fatalf("generated token should be valid: %v", err)
+	}
+	if !claims.HasScope("tasks:read") || claims.HasScope("tasks:write") {
+		t.Errorf("expected only tasks:read, got %v", claims.Scopes)
+	}
+	if ttl := time.Until(claims.ExpiresAt); ttl > time.Hour || ttl < time.Hour-time.Minute {
+		t.Errorf("expected the token to expire in 1h, got %s", ttl)
+	}
+
+	// An empty list grants no scope rather than all of them
+	tokenStr, _ = mgr.GenerateScopedToken(testUser(), nil, time.Hour)
+	if claims, _ := mgr.ValidateToken(tokenStr); claims.Scopes == nil || claims.HasScope("tasks:read") {
+		t.Errorf("expected a token without scopes, got %v", claims.Scopes)
+	}
+
+	tokenStr, _ = mgr.GenerateToken(testUser())
+	if claims, _ := mgr.ValidateToken(tokenStr); claims.Scopes != nil || !claims.HasScope("tasks:write") {
+		t.Error("expected a regular token to be unrestricted")
+	}
 }
 
 func TestValidateToken(t *testing.T) {
@@ -196,6 +303,9 @@ func TestValidateToken(t *testing.T) {
 		if err == nil {
 			t.Fatal("expected error for expired token, got nil")
 		}
+		if reason := FailureReason(err); reason != ReasonExpiredToken {
+			t.Errorf("FailureReason = %q, want %q", reason, ReasonExpiredToken)
+		}
 	})
 
 	t.Run("rejects tampered token", func(t *testing.T) {
@@ -222,6 +332,19 @@ func TestValidateToken(t *testing.T) {
 		if err == nil {
 			t.Fatal("expected error for tampered token, got nil")
 		}
+		if reason := FailureReason(err); reason != ReasonInvalidSignature {
+			t.Errorf("FailureReason = %q, want %q", reason, ReasonInvalidSignature)
+		}
+	})
+
+	t.Run("rejects malformed token", func(t *testing.T) {
+		_, err := mgr.ValidateToken("not-a-jwt")
+		if err == nil {
+			t.Fatal("expected error for malformed token, got nil")
+		}
+		if reason := FailureReason(err); reason != ReasonMalformedToken {
+			t.Errorf("FailureReason = %q, want %q", reason, ReasonMalformedToken)
+		}
 	})
 
 	t.Run("rejects wrong signing method", func(t *testing.T) {
@@ -0,0 +1,115 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/metrics"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrHashDeadline is returned instead of hashing a password when the
+// deadline of the context leaves less time than a hash takes.
+var ErrHashDeadline = errors.New("not enough time left to hash the password")
+
+// PasswordHasher hashes passwords at a bcrypt cost and checks them. A nil
+// *PasswordHasher hashes at bcrypt's default cost.
+type PasswordHasher struct {
+	cost int
+	// estimate is how long the last hash took, in nanoseconds; 0 until one
+	// ran
+	estimate atomic.Int64
+}
+
+// NewPasswordHasher returns a PasswordHasher hashing at cost; 0 is bcrypt's
+// default. Passwords hashed at another cost still match, and are rehashed
+// at login.
+func NewPasswordHasher(cost int) *PasswordHasher {
+	if cost == 0 {
+		cost = bcrypt.DefaultCost
+	}
+	return &PasswordHasher{cost: cost}
+}
+
+// Cost returns the bcrypt cost passwords are hashed at.
+func (h *PasswordHasher) Cost() int {
+	if h == nil {
+		return bcrypt.DefaultCost
+	}
+	return h.cost
+}
+
+// TunePasswordCost returns the highest bcrypt cost, from minCost up, whose
+// hashes take at most maxDuration on this machine, and how long a hash at
+// that cost took. It returns minCost even when it is slower than
+// maxDuration.
+func TunePasswordCost(minCost int, maxDuration time.Duration) (int, time.Duration) {
+	cost, took := minCost, timeHash(minCost)
+	for cost < bcrypt.MaxCost && took*2 <= maxDuration {
+		next := timeHash(cost + 1)
+		if next > maxDuration {
+			break
+		}
+		cost, took = cost+1, next
+	}
+	return cost, took
+}
+
+func timeHash(cost int) time.Duration {
+	start := time.Now()
+	bcrypt.GenerateFromPassword([]byte("password cost benchmark"), cost)
+	return time.Since(start)
+}
+
+// Hash hashes password at the cost of h.
+func (h *PasswordHasher) Hash(ctx context.Context, password string) (string, error) {
+	cost := h.Cost()
+	if err := h.checkDeadline(ctx); err != nil {
+		return "", err
+	}
+	start := time.Now()
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), cost)
+	took := time.Since(start)
+	metrics.FromContext(ctx).RecordPasswordHash("hash", cost, took)
+	if h != nil {
+		h.estimate.Store(int64(took))
+	}
+	return string(hash), err
+}
+
+// Compare returns nil when password matches hash, and
+// bcrypt.ErrMismatchedHashAndPassword when it does not.
+func (h *PasswordHasher) Compare(ctx context.Context, hash, password string) error {
+	if err := h.checkDeadline(ctx); err != nil {
+		return err
+	}
+	cost, _ := bcrypt.Cost([]byte(hash))
+	start := time.Now()
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	metrics.FromContext(ctx).RecordPasswordHash("compare", cost, time.Since(start))
+	return err
+}
+
+// NeedsRehash tells whether hash is at another cost than the one of h.
+func (h *PasswordHasher) NeedsRehash(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err == nil && cost != h.Cost()
+}
+
+// checkDeadline refuses to start a hash that cannot finish before the
+// deadline of ctx, which only wastes CPU under load.
+func (h *PasswordHasher) checkDeadline(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if h == nil {
+		return nil
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < time.Duration(h.estimate.Load()) {
+		return ErrHashDeadline
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestPasswordHasher(t *testing.T) {
+	h := NewPasswordHasher(bcrypt.MinCost)
+
+	hash, err := h.Hash(context.Background(), "Password1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cost, _ := bcrypt.Cost([]byte(hash)); cost != bcrypt.MinCost {
+		t.Errorf("expected cost %d, got %d", bcrypt.MinCost, cost)
+	}
+	if err := h.Compare(context.Background(), hash, "Password1"); err != nil {
+		t.Errorf("expected the password to match, got %v", err)
+	}
+	if err := h.Compare(context.Background(), hash, "Password2"); !errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		t.Errorf("expected a mismatch, got %v", err)
+	}
+
+	if h.NeedsRehash(hash) {
+		t.Error("expected no rehash at the configured cost")
+	}
+	if !NewPasswordHasher(bcrypt.MinCost + 1).NeedsRehash(hash) {
+		t.Error("expected a rehash at another cost")
+	}
+	if !(*PasswordHasher)(nil).NeedsRehash(hash) || (*PasswordHasher)(nil).Cost() != bcrypt.DefaultCost {
+		t.Error("expected a nil hasher at the default cost")
+	}
+}
+
+func TestPasswordHasher_Deadline(t *testing.T) {
+	h := NewPasswordHasher(bcrypt.MinCost)
+
+	// No estimate yet: the first hash always runs
+	ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	time.Sleep(time.Millisecond)
+	if _, err := h.Hash(ctx, "Password1"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the expired context refused, got %v", err)
+	}
+
+	if _, err := h.Hash(context.Background(), "Password1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	h.estimate.Store(int64(time.Hour))
+	ctx, cancel = context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if _, err := h.Hash(ctx, "Password1"); !errors.Is(err, ErrHashDeadline) {
+		t.Errorf("expected ErrHashDeadline with less time left than a hash takes, got %v", err)
+	}
+	if err := h.Compare(ctx, "$2a$04$invalid", "Password1"); !errors.Is(err, ErrHashDeadline) {
+		t.Errorf("expected ErrHashDeadline on compare, got %v", err)
+	}
+}
+
+func TestTunePasswordCost(t *testing.T) {
+	cost, took := TunePasswordCost(bcrypt.MinCost, time.Nanosecond)
+	if cost != bcrypt.MinCost || took <= 0 {
+		t.Errorf("expected the minimum cost when every hash is too slow, got %d (%v)", cost, took)
+	}
+
+	cost, took = TunePasswordCost(bcrypt.MinCost, 20*time.Millisecond)
+	if cost < bcrypt.MinCost || took > 20*time.Millisecond {
+		t.Errorf("expected a cost whose hash fits 20ms, got %d (%v)", cost, took)
+	}
+}
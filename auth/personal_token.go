@@ -0,0 +1,5 @@
+package auth
+
+// PersonalTokenPrefix starts the personal access tokens users create,
+// telling them from JWTs.
+const PersonalTokenPrefix = "sap_"
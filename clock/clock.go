@@ -0,0 +1,27 @@
+// Package clock tells the time of a request. Sandbox users may move their
+// clock by an offset to test time-dependent flows; code working on their
+// behalf reads the time from Now rather than time.Now.
+package clock
+
+import (
+	"context"
+	"time"
+)
+
+type contextKey struct{}
+
+// WithOffset returns a copy of ctx whose clock is offset from the real time.
+func WithOffset(ctx context.Context, offset time.Duration) context.Context {
+	return context.WithValue(ctx, contextKey{}, offset)
+}
+
+// Offset returns the clock offset of ctx, or 0 if none was set.
+func Offset(ctx context.Context) time.Duration {
+	offset, _ := ctx.Value(contextKey{}).(time.Duration)
+	return offset
+}
+
+// Now returns the current time on the clock of ctx.
+func Now(ctx context.Context) time.Time {
+	return time.Now().Add(Offset(ctx))
+}
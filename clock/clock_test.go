@@ -0,0 +1,24 @@
+package clock
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestNow(t *testing.T) {
+	if got := Offset(context.Background()); got != 0 {
+		t.Errorf("expected no offset, got %v", got)
+	}
+	if got := time.Since(Now(context.Background())); got < 0 || got > time.Minute {
+		t.Errorf("expected the real time, got %v away", got)
+	}
+
+	ctx := WithOffset(context.Background(), 72*time.Hour)
+	if got := Offset(ctx); got != 72*time.Hour {
+		t.Errorf("expected 72h, got %v", got)
+	}
+	if got := time.Until(Now(ctx)); got < 71*time.Hour || got > 72*time.Hour {
+		t.Errorf("expected three days ahead, got %v", got)
+	}
+}
@@ -0,0 +1,407 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/anonymize"
+	"github.com/clementhaon/sandbox-api-go/auth"
+	"github.com/clementhaon/sandbox-api-go/config"
+	"github.com/clementhaon/sandbox-api-go/database"
+	"github.com/clementhaon/sandbox-api-go/loadtest"
+	"github.com/clementhaon/sandbox-api-go/pwned"
+	"github.com/clementhaon/sandbox-api-go/replay"
+	"github.com/clementhaon/sandbox-api-go/server"
+)
+
+// commands are the subcommands of the binary. Without one, it runs the API.
+var commands = map[string]func(args []string) int{
+	"loadtest":    runLoadtest,
+	"user":        runUser,
+	"token":       runToken,
+	"task":        runTask,
+	"pwned":       runPwned,
+	"migrate":     runMigrate,
+	"anonymize":   runAnonymize,
+	"replay":      runReplay,
+	"rotate-keys": runRotateKeys,
+}
+
+// runLoadtest implements `sandbox-api loadtest`.
+func runLoadtest(args []string) int {
+	fs := flag.NewFlagSet("loadtest", flag.ContinueOnError)
+	target := fs.String("url", "http://localhost:8080", "base URL of the API under test")
+	users := fs.Int("users", 10, "concurrent virtual users")
+	duration := fs.Duration("duration", 60*time.Second, "how long to generate load")
+	timeout := fs.Duration("timeout", 10*time.Second, "per-request timeout")
+	maxErrorRate := fs.Float64("max-error-rate", 0.01, "exit with status 1 above this share of failed requests")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Printf("Load testing %s with %d users for %s\n\n", *target, *users, *duration)
+	report, err := loadtest.Run(ctx, loadtest.Options{
+		TargetURL: *target,
+		Users:     *users,
+		Duration:  *duration,
+		Timeout:   *timeout,
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "loadtest:", err)
+		return 2
+	}
+	report.Write(os.Stdout)
+
+	if report.ErrorRate() > *maxErrorRate {
+		fmt.Fprintf(os.Stderr, "loadtest: error rate %.2f%% is above %.2f%%\n", report.ErrorRate()*100, *maxErrorRate*100)
+		return 1
+	}
+	return 0
+}
+
+// runPwned implements `sandbox-api pwned build`, which turns a list of
+// breached password hashes, such as the Pwned Passwords download, into the
+// Bloom filter read with PWNED_CHECK=bloom.
+func runPwned(args []string) int {
+	if len(args) == 0 || args[0] != "build" {
+		fmt.Fprintln(os.Stderr, "Usage: sandbox-api pwned build [-false-positive-rate 0.001] <hashes.txt> <filter>")
+		return 2
+	}
+	fs := flag.NewFlagSet("pwned build", flag.ContinueOnError)
+	rate := fs.Float64("false-positive-rate", 0.001, "share of unbreached passwords reported as breached")
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+	if fs.NArg() != 2 || *rate <= 0 || *rate >= 1 {
+		fmt.Fprintln(os.Stderr, "Usage: sandbox-api pwned build [-false-positive-rate 0.001] <hashes.txt> <filter>")
+		return 2
+	}
+
+	if err := buildPwnedFilter(fs.Arg(0), fs.Arg(1), *rate); err != nil {
+		fmt.Fprintln(os.Stderr, "pwned build:", err)
+		return 1
+	}
+	return 0
+}
+
+// buildPwnedFilter reads the hashes twice: to size the filter, then to
+// fill it.
+func buildPwnedFilter(hashesPath, filterPath string, rate float64) error {
+	readHashes := func(fn func(pwned.Hash)) error {
+		f, err := os.Open(hashesPath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return pwned.ReadHashes(f, fn)
+	}
+
+	n := 0
+	if err := readHashes(func(pwned.Hash) { n++ }); err != nil {
+		return err
+	}
+	filter := pwned.NewBloomFilter(n, rate)
+	if err := readHashes(filter.Add); err != nil {
+		return err
+	}
+
+	out, err := os.Create(filterPath)
+	if err != nil {
+		return err
+	}
+	size, err := filter.WriteTo(out)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %d hashes to %s (%d MB)\n", n, filterPath, size>>20)
+	return nil
+}
+
+const migrateUsage = "Usage: sandbox-api migrate status|up|down|force [-dry-run] [-lock-timeout 15s] [version|none]"
+
+// runMigrate implements `sandbox-api migrate status|up|down|force`. Unlike
+// the other commands, it connects without running the migrations.
+func runMigrate(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+	sub := args[0]
+	fs := flag.NewFlagSet("migrate "+sub, flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "print the SQL of up or down without running it")
+	lockTimeout := fs.Duration("lock-timeout", database.MigrationLockTimeout, "how long to wait for another migrator to finish")
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+	wantArgs := 0
+	if sub == "force" {
+		wantArgs = 1
+	}
+	if (sub != "status" && sub != "up" && sub != "down" && sub != "force") || fs.NArg() != wantArgs {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "migrate:", err)
+		return 1
+	}
+	if cfg.InMemory() {
+		fmt.Fprintln(os.Stderr, "migrate: STORAGE=memory has no database to migrate")
+		return 1
+	}
+	db, err := database.Connect(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "migrate:", err)
+		return 1
+	}
+	defer db.Close()
+
+	if sub == "force" {
+		version := -1
+		if fs.Arg(0) != "none" {
+			if version, err = strconv.Atoi(fs.Arg(0)); err != nil || version < 0 {
+				fmt.Fprintln(os.Stderr, "migrate force: expected a migration version or none")
+				return 2
+			}
+		}
+		if err := database.ForceMigrationVersion(db, version, *lockTimeout); err != nil {
+			fmt.Fprintln(os.Stderr, "migrate force:", err)
+			return 1
+		}
+		return 0
+	}
+
+	status, err := database.GetMigrationStatus(db)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "migrate:", err)
+		return 1
+	}
+	if status.Dirty {
+		fmt.Fprintf(os.Stderr, "Database is dirty at version %d.\n%s\n", status.Version, database.DirtyRecovery(int(status.Version)))
+		return 1
+	}
+
+	switch sub {
+	case "status":
+		fmt.Printf("Version: %d\n", status.Version)
+		if len(status.Pending) == 0 {
+			fmt.Println("No pending migrations")
+		}
+		for _, m := range status.Pending {
+			fmt.Printf("Pending: %06d %s\n", m.Version, m.Name)
+		}
+	case "up":
+		switch {
+		case len(status.Pending) == 0:
+			fmt.Println("No pending migrations")
+		case *dryRun:
+			for _, m := range status.Pending {
+				fmt.Printf("-- %06d_%s.up.sql\n%s\n", m.Version, m.Name, m.SQL)
+			}
+		default:
+			err = database.MigrateUp(db, *lockTimeout)
+		}
+	case "down":
+		switch {
+		case status.Version == 0:
+			fmt.Println("No migration to roll back")
+		case *dryRun:
+			var m database.Migration
+			if m, err = database.RollbackPlan(status.Version); err == nil {
+				fmt.Printf("-- %06d_%s.down.sql\n%s\n", m.Version, m.Name, m.SQL)
+			}
+		default:
+			err = database.RollbackMigration(db, *lockTimeout)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "migrate %s: %v\n", sub, err)
+		return 1
+	}
+	return 0
+}
+
+const anonymizeUsage = "Usage: sandbox-api anonymize -yes [-seed sandbox] [-password <shared password>]"
+
+// runAnonymize implements `sandbox-api anonymize`, which scrubs the personal
+// data of the configured database, a copy of production data meant for a
+// sandbox, in one transaction.
+func runAnonymize(args []string) int {
+	fs := flag.NewFlagSet("anonymize", flag.ContinueOnError)
+	yes := fs.Bool("yes", false, "confirm that the configured database is a copy to rewrite")
+	seed := fs.String("seed", "sandbox", "the same seed gives the same fake data")
+	password := fs.String("password", "", "password of every user; empty locks every account")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 0 {
+		fmt.Fprintln(os.Stderr, anonymizeUsage)
+		return 2
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "anonymize:", err)
+		return 1
+	}
+	if cfg.IsProduction() {
+		fmt.Fprintln(os.Stderr, "anonymize: refusing to run with APP_ENV=production")
+		return 1
+	}
+	if cfg.InMemory() {
+		fmt.Fprintln(os.Stderr, "anonymize: STORAGE=memory has no database to anonymize")
+		return 1
+	}
+	if !*yes {
+		fmt.Fprintf(os.Stderr, "This rewrites the users, tasks and sessions of database %s on %s.\n%s\n", cfg.DBName, cfg.DBHost, anonymizeUsage)
+		return 2
+	}
+
+	if *password == "" {
+		*password = rand.Text()
+	}
+	hash, err := auth.NewPasswordHasher(cfg.BcryptCost).Hash(context.Background(), *password)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "anonymize:", err)
+		return 1
+	}
+
+	db, err := database.Open(cfg, nil)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "anonymize:", err)
+		return 1
+	}
+	defer db.Close()
+
+	var report anonymize.Report
+	err = database.NewTxManager(db).WithTransaction(context.Background(), func(q database.Querier) error {
+		report, err = anonymize.Run(context.Background(), q, anonymize.Options{
+			Faker:        anonymize.NewFaker(*seed),
+			PasswordHash: hash,
+		})
+		return err
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "anonymize:", err)
+		return 1
+	}
+
+	tables := make([]string, 0, len(report))
+	for table := range report {
+		tables = append(tables, table)
+	}
+	slices.Sort(tables)
+	for _, table := range tables {
+		fmt.Printf("%-16s %d rows\n", table, report[table])
+	}
+	return 0
+}
+
+const replayUsage = "Usage: sandbox-api replay [-keep-going] <log>"
+
+// runReplay implements `sandbox-api replay`, which plays a log recorded with
+// RECORD_FILE against the configured database, or in memory with
+// STORAGE=memory and saved to MEMORY_SNAPSHOT_FILE. The database must be in
+// the state the log was recorded from, usually empty, for the requests to
+// find the same IDs.
+func runReplay(args []string) int {
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	keepGoing := fs.Bool("keep-going", false, "play the whole log even when a request gets another status than when recorded")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, replayUsage)
+		return 2
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "replay:", err)
+		return 1
+	}
+	entries, err := replay.ReadLog(f)
+	f.Close()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "replay: %s: %v\n", fs.Arg(0), err)
+		return 1
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "replay:", err)
+		return 1
+	}
+	replayConfig(cfg)
+	jwtManager, err := auth.NewJWTManager(cfg.JWTSecret)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "replay:", err)
+		return 1
+	}
+	srv, err := server.New(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "replay:", err)
+		return 1
+	}
+	player := replay.NewPlayer(srv.Handler(), jwtManager, cfg.BasePath)
+
+	played, mismatches := 0, 0
+	for i, e := range entries {
+		status, err := player.Play(e)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "replay: entry %d: %v\n", i+1, err)
+			mismatches++
+			break
+		}
+		played++
+		if status != e.Status {
+			fmt.Fprintf(os.Stderr, "replay: entry %d: %s %s got status %d, recorded %d\n", i+1, e.Method, e.Path, status, e.Status)
+			mismatches++
+			if !*keepGoing {
+				break
+			}
+		}
+	}
+
+	// Saves the in-memory data to MEMORY_SNAPSHOT_FILE, if set
+	if err := srv.Shutdown(context.Background()); err != nil {
+		fmt.Fprintln(os.Stderr, "replay:", err)
+		return 1
+	}
+	fmt.Printf("Replayed %d of %d requests, %d with another status than recorded\n", played, len(entries), mismatches)
+	if mismatches > 0 {
+		return 1
+	}
+	return 0
+}
+
+// replayConfig turns off what would refuse or side-track replayed requests:
+// rate limits, CAPTCHAs and the breached password check, emails, and the
+// recording of the replay itself.
+func replayConfig(cfg *config.Config) {
+	cfg.RateLimitRequests = math.MaxInt32
+	cfg.UserRateLimitRPS = 0
+	cfg.SignupRateLimit = 0
+	cfg.CaptchaVerifyURL, cfg.CaptchaSecret = "", ""
+	cfg.PwnedCheck = "off"
+	cfg.MailDriver = config.MailDriverLog
+	cfg.LoginAlertURL = ""
+	cfg.RecordFile = ""
+}
@@ -2,7 +2,11 @@ package config
 
 import (
 	"fmt"
+	"log/slog"
+	"net/netip"
+	"net/url"
 	"os"
+	"path"
 	"strconv"
 	"strings"
 	"time"
@@ -10,17 +14,46 @@ import (
 
 // Config holds all application configuration.
 type Config struct {
+	// Where data lives: "postgres", or "memory" for a throwaway sandbox
+	// without Postgres nor MinIO, whose data is lost on exit unless
+	// MEMORY_SNAPSHOT_FILE is set
+	Storage string // STORAGE
+	// JSON snapshot of the in-memory data, loaded at startup if it exists
+	// and written on shutdown
+	MemorySnapshotFile string // MEMORY_SNAPSHOT_FILE
+
 	// Database
-	DBHost     string
+	DBHost     string // comma-separated for failover, each optionally host:port
 	DBPort     int
 	DBUser     string
 	DBPassword string
 	DBName     string
 	DBSSLMode  string
 
+	// Hosts accepted when DB_HOST lists several: "any" or "read-write" (skip
+	// standbys), as libpq's target_session_attrs
+	DBTargetSessionAttrs string // DB_TARGET_SESSION_ATTRS
+	// How often every host is probed so a failed host is left for a healthy
+	// one; 0 only switches when connecting fails
+	DBHealthCheckInterval time.Duration // DB_HEALTH_CHECK_INTERVAL_SECONDS
+
+	// How long startup waits for the database to accept connections;
+	// 0 tries once
+	DBStartupTimeout time.Duration // DB_STARTUP_TIMEOUT_SECONDS
+
+	// Database operations taking at least this long are logged at WARN and
+	// counted; 0 disables slow query detection
+	SlowQueryThreshold time.Duration // SLOW_QUERY_THRESHOLD_MS
+
+	// Attempts for a transaction failing with a transient error (serialization
+	// failure, deadlock, lost connection); 1 disables retries
+	DBRetryMaxAttempts int // DB_RETRY_MAX_ATTEMPTS
+
 	// JWT
 	JWTSecret      string
 	JWTExpiryHours int
+	// Lifetime of the token and cookie of a login with remember_me
+	JWTRememberMeTTL time.Duration // JWT_REMEMBER_ME_DAYS
 
 	// MinIO
 	MinioEndpoint string
@@ -34,20 +67,211 @@ type Config struct {
 	MaxBodySize int64
 	AppEnv      string
 
+	// Unix domain socket to listen on instead of PORT; ignored when systemd
+	// passes listeners (LISTEN_FDS)
+	UnixSocket     string      // UNIX_SOCKET, path
+	UnixSocketMode os.FileMode // UNIX_SOCKET_MODE, octal permissions
+
+	// HTTP connections; a zero timeout means none
+	HTTPReadTimeout  time.Duration // HTTP_READ_TIMEOUT_SECONDS
+	HTTPWriteTimeout time.Duration // HTTP_WRITE_TIMEOUT_SECONDS
+	HTTPIdleTimeout  time.Duration // HTTP_IDLE_TIMEOUT_SECONDS, idle keep-alive connections
+	HTTPKeepAlives   bool          // HTTP_KEEP_ALIVES; false closes connections after each request
+
+	// HTTP/2 over cleartext (h2c with prior knowledge), as TLS ends at the proxy
+	HTTP2Enabled              bool          // HTTP2_ENABLED
+	HTTP2MaxConcurrentStreams int           // HTTP2_MAX_CONCURRENT_STREAMS per connection; 0 uses Go's default
+	HTTP2PingInterval         time.Duration // HTTP2_PING_INTERVAL_SECONDS without frames before a health-check ping; 0 disables
+
 	// WebSocket
 	AllowedOrigins    []string
 	WSReadBufferSize  int
 	WSWriteBufferSize int
 	WSReadLimit       int64
 
+	// Proxies allowed to set X-Forwarded-For / X-Real-IP; empty trusts none
+	TrustedProxies []netip.Prefix // TRUSTED_PROXIES, CIDRs or single IPs
+
+	// Client IPs allowed on operational routes (/admin, /metrics, /debug);
+	// an empty allowlist allows any IP not in the denylist
+	OpsIPAllowlist []netip.Prefix // OPS_IP_ALLOWLIST
+	OpsIPDenylist  []netip.Prefix // OPS_IP_DENYLIST
+
 	// Rate Limiting
 	RateLimitRequests int
 	RateLimitWindow   time.Duration
+
+	// Per-user limit on authenticated routes; a zero rate disables it
+	UserRateLimitRPS   float64 // USER_RATE_LIMIT_RPS
+	UserRateLimitBurst int     // USER_RATE_LIMIT_BURST
+
+	// Signups allowed per client IP within the window; 0 disables the limit
+	SignupRateLimit  int           // SIGNUP_RATE_LIMIT
+	SignupRateWindow time.Duration // SIGNUP_RATE_WINDOW_MINUTES
+
+	// Refuse email addresses of disposable providers (a built-in list plus
+	// the domains of an optional file, one per line)
+	SignupBlockDisposable       bool   // SIGNUP_BLOCK_DISPOSABLE
+	SignupDisposableDomainsFile string // SIGNUP_DISPOSABLE_DOMAINS_FILE
+
+	// Password policy of registrations and password changes: a minimum
+	// length, the character classes required (upper, lower, number,
+	// symbol), and refusal of common passwords (a built-in list plus the
+	// passwords of an optional file, one per line)
+	PasswordMinLength       int      // PASSWORD_MIN_LENGTH
+	PasswordRequiredClasses []string // PASSWORD_REQUIRED_CLASSES
+	PasswordBlockCommon     bool     // PASSWORD_BLOCK_COMMON
+	PasswordBannedFile      string   // PASSWORD_BANNED_FILE
+
+	// bcrypt cost of password hashes, 4 to 31 (0 is bcrypt's default, 10).
+	// With a maximum hash time, the highest cost from there whose hashes
+	// take at most that long is picked at startup.
+	BcryptCost        int           // BCRYPT_COST
+	BcryptMaxHashTime time.Duration // BCRYPT_MAX_HASH_MS; 0 keeps BCRYPT_COST
+
+	// Breached password check of registrations and admin-created accounts:
+	// off, api (k-anonymity range queries to PWNED_API_URL) or bloom (an
+	// offline filter built with `sandbox-api pwned build`). Breached
+	// passwords are refused, or only logged with PWNED_ACTION=warn.
+	PwnedCheck     string        // PWNED_CHECK
+	PwnedAPIURL    string        // PWNED_API_URL
+	PwnedBloomFile string        // PWNED_BLOOM_FILE
+	PwnedAction    string        // PWNED_ACTION: reject or warn
+	PwnedTimeout   time.Duration // PWNED_TIMEOUT_MS
+
+	// CAPTCHA on signup, checked at a siteverify endpoint (reCAPTCHA,
+	// hCaptcha, Turnstile); both empty disables it
+	CaptchaVerifyURL string // CAPTCHA_VERIFY_URL
+	CaptchaSecret    string // CAPTCHA_SECRET
+
+	// Redis shared by API instances (revoked tokens, rate limits); empty
+	// keeps that state in memory
+	RedisURL string // REDIS_URL, e.g. redis://:password@redis:6379/0
+
+	LogLevel slog.Level // LOG_LEVEL: DEBUG, INFO, WARN or ERROR
+
+	// ConfigWatchInterval is how often CONFIG_FILE is checked for changes
+	// (CONFIG_WATCH_INTERVAL_SECONDS); 0 reloads on SIGHUP only.
+	ConfigWatchInterval time.Duration
+
+	// In-memory cache of public responses (home, error catalog); 0 disables it
+	ResponseCacheTTL time.Duration // RESPONSE_CACHE_TTL_SECONDS
+
+	// Audit
+	AuditRetention time.Duration
+
+	// Metrics
+	MetricsCollectInterval time.Duration
+	LatencyBuckets         []float64            // HTTP_LATENCY_BUCKETS, seconds
+	LatencyGroupBuckets    map[string][]float64 // HTTP_LATENCY_BUCKETS_<GROUP>, keyed by lowercase group
+
+	// /metrics protection; all empty leaves the endpoint public
+	MetricsToken    string // METRICS_TOKEN, sent as "Authorization: Bearer <token>"
+	MetricsUser     string // METRICS_USER, basic auth
+	MetricsPassword string // METRICS_PASSWORD
+
+	// Diagnostics (pprof, runtime stats); empty means admin JWT only
+	DiagnosticsToken string
+
+	// SCIM provisioning (/scim/v2/Users) for identity providers; empty
+	// disables it
+	SCIMToken string // SCIM_TOKEN, sent as "Authorization: Bearer <token>"
+
+	// Slack slash commands (/integrations/slack/commands); empty disables
+	// them
+	SlackSigningSecret string // SLACK_SIGNING_SECRET, from the Slack app's settings
+
+	// Path prefix the API is served under behind a reverse proxy sharing
+	// the domain ("/sandbox-api"); empty serves it at the root
+	BasePath string // BASE_PATH
+
+	// Frontend served under /; empty serves the one embedded in the binary
+	// with the frontend build tag, if any
+	FrontendDir string // FRONTEND_DIR, the build output holding index.html
+
+	// Profile
+	AvatarAllowedHosts []string // AVATAR_ALLOWED_HOSTS; empty allows any host
+
+	// Errors
+	ErrorFormat      string // "json" or "problem" (RFC 7807); clients may still ask for problem+json
+	ErrorTypeBaseURI string
+
+	// Panics and 5xx errors are sent to a Sentry-compatible error tracker;
+	// an empty DSN disables it
+	ErrorReportingDSN     string // ERROR_REPORTING_DSN, https://<key>@<host>/<project id>
+	ErrorReportingRelease string // ERROR_REPORTING_RELEASE, e.g. the deployed git tag
+
+	// Default per-user quotas, overridable per user by admins; 0 means unlimited
+	QuotaMaxTasks         int   // QUOTA_MAX_TASKS
+	QuotaMaxMediaBytes    int64 // QUOTA_MAX_MEDIA_MB, in bytes
+	QuotaMaxDailyRequests int64 // QUOTA_MAX_DAILY_REQUESTS, per UTC day
+	QuotaMaxDailyBytes    int64 // QUOTA_MAX_DAILY_MB, in bytes received and sent per UTC day
+
+	// Debug body logging (DEBUG level only); secrets are redacted
+	LogBodies       bool // LOG_BODIES
+	LogBodyMaxBytes int  // LOG_BODY_MAX_BYTES, per body
+
+	// Outgoing email: "log" (development, nothing is sent), "smtp" or "api"
+	// (SendGrid's v3 mail send API or a compatible provider)
+	MailDriver   string // MAIL_DRIVER
+	MailFrom     string // MAIL_FROM, e.g. "Sandbox <no-reply@example.com>"
+	SMTPHost     string // SMTP_HOST
+	SMTPPort     int    // SMTP_PORT
+	SMTPUser     string // SMTP_USER; empty sends without authentication
+	SMTPPassword string // SMTP_PASSWORD
+	MailAPIURL   string // MAIL_API_URL
+	MailAPIKey   string // MAIL_API_KEY
+
+	// Page of the frontend signing out the session of a login alert, which
+	// posts its token parameter to /auth/sessions/revoke; empty disables
+	// the alerts of logins from new devices
+	LoginAlertURL string // LOGIN_ALERT_URL
+
+	// OpenID Connect provider, so other apps can log users in with their
+	// sandbox account; an empty issuer disables it
+	OIDCIssuer         string // OIDC_ISSUER, public base URL of the API
+	OIDCLoginURL       string // OIDC_LOGIN_URL, page where users log in and approve a client
+	OIDCClientsFile    string // OIDC_CLIENTS_FILE, JSON array of clients
+	OIDCSigningKeyFile string // OIDC_SIGNING_KEY_FILE, PEM RSA key; empty generates one per start
+
+	// Multi-tenancy; when off every request belongs to the default tenant
+	MultiTenant      bool   // MULTI_TENANT
+	TenantBaseDomain string // TENANT_BASE_DOMAIN; "acme.<domain>" resolves to tenant "acme"
+
+	// Admin backups go to the MinIO bucket; restores replace the data of
+	// every tenant, so they are refused unless enabled
+	BackupRestoreEnabled bool // BACKUP_RESTORE_ENABLED
+
+	// Append-only log of the successful requests changing data, with their
+	// bodies, which `sandbox-api replay` plays against a fresh database to
+	// reproduce a sandbox; empty disables recording
+	RecordFile string // RECORD_FILE
+
+	// Keys encrypting the secrets stored in the database that must be read
+	// back, as "id:base64-key" pairs with the current key first; empty
+	// disables the features storing such secrets
+	EncryptionKeys     string // ENCRYPTION_KEYS
+	EncryptionKeysFile string // ENCRYPTION_KEYS_FILE, holding the keys instead
+
+	// Outgoing webhooks, on when encryption keys are set; their URLs may not
+	// resolve to private or loopback addresses unless allowed, for local use
+	WebhookAllowPrivateNetworks bool // WEBHOOK_ALLOW_PRIVATE_NETWORKS
 }
 
 // Load reads configuration from environment variables and returns a validated Config.
+// CONFIG_FILE, if set, is applied to the environment first; Load can be
+// called again to reload.
 func Load() (*Config, error) {
+	if path := os.Getenv(ConfigFileEnv); path != "" {
+		if err := loadEnvFile(path); err != nil {
+			return nil, err
+		}
+	}
+
 	cfg := &Config{
+		Storage:            GetEnv("STORAGE", StoragePostgres),
+		MemorySnapshotFile: os.Getenv("MEMORY_SNAPSHOT_FILE"),
+
 		// Database
 		DBHost:     GetEnv("DB_HOST", "localhost"),
 		DBPort:     getEnvInt("DB_PORT", 5432),
@@ -56,8 +280,15 @@ func Load() (*Config, error) {
 		DBName:     GetEnv("DB_NAME", "sandbox_api"),
 		DBSSLMode:  GetEnv("DB_SSLMODE", "disable"),
 
+		DBTargetSessionAttrs:  GetEnv("DB_TARGET_SESSION_ATTRS", DBSessionAny),
+		DBHealthCheckInterval: time.Duration(getEnvInt("DB_HEALTH_CHECK_INTERVAL_SECONDS", 10)) * time.Second,
+		DBStartupTimeout:      time.Duration(getEnvInt("DB_STARTUP_TIMEOUT_SECONDS", 60)) * time.Second,
+		SlowQueryThreshold:    time.Duration(getEnvInt("SLOW_QUERY_THRESHOLD_MS", 200)) * time.Millisecond,
+		DBRetryMaxAttempts:    getEnvInt("DB_RETRY_MAX_ATTEMPTS", 3),
+
 		// JWT
-		JWTExpiryHours: getEnvInt("JWT_EXPIRY_HOURS", 24),
+		JWTExpiryHours:   getEnvInt("JWT_EXPIRY_HOURS", 24),
+		JWTRememberMeTTL: time.Duration(getEnvInt("JWT_REMEMBER_ME_DAYS", 30)) * 24 * time.Hour,
 
 		// MinIO
 		MinioEndpoint: GetEnv("MINIO_ENDPOINT", "minio:9000"),
@@ -70,6 +301,16 @@ func Load() (*Config, error) {
 		Port:        getEnvInt("PORT", 8080),
 		MaxBodySize: int64(getEnvInt("MAX_BODY_SIZE", 1<<20)),
 		AppEnv:      GetEnv("APP_ENV", "development"),
+		UnixSocket:  os.Getenv("UNIX_SOCKET"),
+
+		// HTTP connections
+		HTTPReadTimeout:           time.Duration(getEnvInt("HTTP_READ_TIMEOUT_SECONDS", 15)) * time.Second,
+		HTTPWriteTimeout:          time.Duration(getEnvInt("HTTP_WRITE_TIMEOUT_SECONDS", 15)) * time.Second,
+		HTTPIdleTimeout:           time.Duration(getEnvInt("HTTP_IDLE_TIMEOUT_SECONDS", 60)) * time.Second,
+		HTTPKeepAlives:            GetEnv("HTTP_KEEP_ALIVES", "true") == "true",
+		HTTP2Enabled:              GetEnv("HTTP2_ENABLED", "true") == "true",
+		HTTP2MaxConcurrentStreams: getEnvInt("HTTP2_MAX_CONCURRENT_STREAMS", 250),
+		HTTP2PingInterval:         time.Duration(getEnvInt("HTTP2_PING_INTERVAL_SECONDS", 0)) * time.Second,
 
 		// WebSocket
 		WSReadBufferSize:  getEnvInt("WS_READ_BUFFER_SIZE", 1024),
@@ -79,6 +320,110 @@ func Load() (*Config, error) {
 		// Rate Limiting
 		RateLimitRequests: getEnvInt("RATE_LIMIT_REQUESTS", 10),
 		RateLimitWindow:   time.Duration(getEnvInt("RATE_LIMIT_WINDOW_SECONDS", 60)) * time.Second,
+
+		UserRateLimitRPS:   getEnvFloat("USER_RATE_LIMIT_RPS", 10),
+		UserRateLimitBurst: getEnvInt("USER_RATE_LIMIT_BURST", 20),
+		RedisURL:           os.Getenv("REDIS_URL"),
+
+		// Signup controls
+		SignupRateLimit:             getEnvInt("SIGNUP_RATE_LIMIT", 5),
+		SignupRateWindow:            time.Duration(getEnvInt("SIGNUP_RATE_WINDOW_MINUTES", 60)) * time.Minute,
+		SignupBlockDisposable:       GetEnv("SIGNUP_BLOCK_DISPOSABLE", "true") == "true",
+		SignupDisposableDomainsFile: os.Getenv("SIGNUP_DISPOSABLE_DOMAINS_FILE"),
+		CaptchaVerifyURL:            os.Getenv("CAPTCHA_VERIFY_URL"),
+		PasswordMinLength:           getEnvInt("PASSWORD_MIN_LENGTH", 8),
+		PasswordBlockCommon:         GetEnv("PASSWORD_BLOCK_COMMON", "true") == "true",
+		PasswordBannedFile:          os.Getenv("PASSWORD_BANNED_FILE"),
+		BcryptCost:                  getEnvInt("BCRYPT_COST", 10),
+		BcryptMaxHashTime:           time.Duration(getEnvInt("BCRYPT_MAX_HASH_MS", 0)) * time.Millisecond,
+		PwnedCheck:                  GetEnv("PWNED_CHECK", "off"),
+		PwnedAPIURL:                 GetEnv("PWNED_API_URL", "https://api.pwnedpasswords.com"),
+		PwnedBloomFile:              os.Getenv("PWNED_BLOOM_FILE"),
+		PwnedAction:                 GetEnv("PWNED_ACTION", "reject"),
+		PwnedTimeout:                time.Duration(getEnvInt("PWNED_TIMEOUT_MS", 2000)) * time.Millisecond,
+		CaptchaSecret:               os.Getenv("CAPTCHA_SECRET"),
+
+		// Reloading
+		ConfigWatchInterval: time.Duration(getEnvInt("CONFIG_WATCH_INTERVAL_SECONDS", 0)) * time.Second,
+
+		// Response cache
+		ResponseCacheTTL: time.Duration(getEnvInt("RESPONSE_CACHE_TTL_SECONDS", 60)) * time.Second,
+
+		// Audit (0 keeps audit logs forever)
+		AuditRetention: time.Duration(getEnvInt("AUDIT_RETENTION_DAYS", 90)) * 24 * time.Hour,
+
+		// Metrics
+		MetricsCollectInterval: time.Duration(getEnvInt("METRICS_COLLECT_INTERVAL_SECONDS", 60)) * time.Second,
+		MetricsToken:           os.Getenv("METRICS_TOKEN"),
+		MetricsUser:            os.Getenv("METRICS_USER"),
+		MetricsPassword:        os.Getenv("METRICS_PASSWORD"),
+
+		// Diagnostics
+		DiagnosticsToken: os.Getenv("DIAGNOSTICS_TOKEN"),
+
+		// SCIM provisioning
+		SCIMToken: os.Getenv("SCIM_TOKEN"),
+
+		// Errors
+		ErrorFormat:      GetEnv("ERROR_FORMAT", ErrorFormatJSON),
+		ErrorTypeBaseURI: os.Getenv("ERROR_TYPE_BASE_URI"), // empty means "/errors#"
+
+		// Error reporting
+		ErrorReportingDSN:     os.Getenv("ERROR_REPORTING_DSN"),
+		ErrorReportingRelease: os.Getenv("ERROR_REPORTING_RELEASE"),
+
+		// Quotas
+		QuotaMaxTasks:         getEnvInt("QUOTA_MAX_TASKS", 0),
+		QuotaMaxMediaBytes:    int64(getEnvInt("QUOTA_MAX_MEDIA_MB", 0)) << 20,
+		QuotaMaxDailyRequests: int64(getEnvInt("QUOTA_MAX_DAILY_REQUESTS", 0)),
+		QuotaMaxDailyBytes:    int64(getEnvInt("QUOTA_MAX_DAILY_MB", 0)) << 20,
+
+		// Debug body logging
+		LogBodies:       GetEnv("LOG_BODIES", "false") == "true",
+		LogBodyMaxBytes: getEnvInt("LOG_BODY_MAX_BYTES", 4096),
+
+		// Email
+		MailDriver:   GetEnv("MAIL_DRIVER", MailDriverLog),
+		MailFrom:     os.Getenv("MAIL_FROM"),
+		SMTPHost:     os.Getenv("SMTP_HOST"),
+		SMTPPort:     getEnvInt("SMTP_PORT", 587),
+		SMTPUser:     os.Getenv("SMTP_USER"),
+		SMTPPassword: os.Getenv("SMTP_PASSWORD"),
+		MailAPIURL:   GetEnv("MAIL_API_URL", "https://api.sendgrid.com"),
+		MailAPIKey:   os.Getenv("MAIL_API_KEY"),
+
+		LoginAlertURL: os.Getenv("LOGIN_ALERT_URL"),
+
+		BasePath: normalizeBasePath(os.Getenv("BASE_PATH")),
+
+		// Frontend
+		FrontendDir: os.Getenv("FRONTEND_DIR"),
+
+		// Slack slash commands
+		SlackSigningSecret: os.Getenv("SLACK_SIGNING_SECRET"),
+
+		// OpenID Connect provider
+		OIDCIssuer:         os.Getenv("OIDC_ISSUER"),
+		OIDCLoginURL:       os.Getenv("OIDC_LOGIN_URL"),
+		OIDCClientsFile:    os.Getenv("OIDC_CLIENTS_FILE"),
+		OIDCSigningKeyFile: os.Getenv("OIDC_SIGNING_KEY_FILE"),
+
+		// Multi-tenancy
+		MultiTenant:      GetEnv("MULTI_TENANT", "false") == "true",
+		TenantBaseDomain: strings.ToLower(strings.TrimPrefix(os.Getenv("TENANT_BASE_DOMAIN"), ".")),
+
+		// Backups
+		BackupRestoreEnabled: GetEnv("BACKUP_RESTORE_ENABLED", "false") == "true",
+
+		// Record and replay
+		RecordFile: os.Getenv("RECORD_FILE"),
+
+		// Encryption of stored secrets
+		EncryptionKeys:     os.Getenv("ENCRYPTION_KEYS"),
+		EncryptionKeysFile: os.Getenv("ENCRYPTION_KEYS_FILE"),
+
+		// Webhooks
+		WebhookAllowPrivateNetworks: GetEnv("WEBHOOK_ALLOW_PRIVATE_NETWORKS", "false") == "true",
 	}
 
 	// JWT secret is required
@@ -88,6 +433,22 @@ func Load() (*Config, error) {
 	}
 	cfg.JWTSecret = jwtSecret
 
+	if err := cfg.LogLevel.UnmarshalText([]byte(GetEnv("LOG_LEVEL", "INFO"))); err != nil {
+		return nil, fmt.Errorf("LOG_LEVEL: %w", err)
+	}
+
+	mode, err := strconv.ParseUint(GetEnv("UNIX_SOCKET_MODE", "0660"), 8, 32)
+	if err != nil {
+		return nil, fmt.Errorf("UNIX_SOCKET_MODE: expected octal permissions like 0660")
+	}
+	cfg.UnixSocketMode = os.FileMode(mode)
+
+	for _, class := range strings.Split(GetEnv("PASSWORD_REQUIRED_CLASSES", "upper,lower,number"), ",") {
+		if class = strings.TrimSpace(class); class != "" {
+			cfg.PasswordRequiredClasses = append(cfg.PasswordRequiredClasses, class)
+		}
+	}
+
 	// Allowed origins
 	if origins := os.Getenv("ALLOWED_ORIGINS"); origins != "" {
 		for _, o := range strings.Split(origins, ",") {
@@ -95,6 +456,44 @@ func Load() (*Config, error) {
 		}
 	}
 
+	// Avatar hosts ("cdn.example.com" or "*.example.com")
+	if hosts := os.Getenv("AVATAR_ALLOWED_HOSTS"); hosts != "" {
+		for _, h := range strings.Split(hosts, ",") {
+			if h = strings.TrimSpace(h); h != "" {
+				cfg.AvatarAllowedHosts = append(cfg.AvatarAllowedHosts, h)
+			}
+		}
+	}
+
+	// Trusted proxies and operational route IP lists ("10.0.0.0/8" or "192.0.2.10")
+	if cfg.TrustedProxies, err = parsePrefixes(os.Getenv("TRUSTED_PROXIES")); err != nil {
+		return nil, fmt.Errorf("TRUSTED_PROXIES: %w", err)
+	}
+	if cfg.OpsIPAllowlist, err = parsePrefixes(os.Getenv("OPS_IP_ALLOWLIST")); err != nil {
+		return nil, fmt.Errorf("OPS_IP_ALLOWLIST: %w", err)
+	}
+	if cfg.OpsIPDenylist, err = parsePrefixes(os.Getenv("OPS_IP_DENYLIST")); err != nil {
+		return nil, fmt.Errorf("OPS_IP_DENYLIST: %w", err)
+	}
+
+	// Latency histogram buckets, globally and per endpoint group
+	if cfg.LatencyBuckets, err = parseBuckets(os.Getenv(latencyBucketsEnv)); err != nil {
+		return nil, fmt.Errorf("%s: %w", latencyBucketsEnv, err)
+	}
+	cfg.LatencyGroupBuckets = make(map[string][]float64)
+	for _, kv := range os.Environ() {
+		key, value, _ := strings.Cut(kv, "=")
+		group, ok := strings.CutPrefix(key, latencyBucketsEnv+"_")
+		if !ok || group == "" {
+			continue
+		}
+		buckets, err := parseBuckets(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		cfg.LatencyGroupBuckets[strings.ToLower(group)] = buckets
+	}
+
 	if err := cfg.Validate(); err != nil {
 		return nil, err
 	}
@@ -102,6 +501,84 @@ func Load() (*Config, error) {
 	return cfg, nil
 }
 
+const latencyBucketsEnv = "HTTP_LATENCY_BUCKETS"
+
+// Supported ERROR_FORMAT values.
+const (
+	ErrorFormatJSON    = "json"
+	ErrorFormatProblem = "problem"
+)
+
+// Supported MAIL_DRIVER values.
+const (
+	MailDriverLog  = "log"
+	MailDriverSMTP = "smtp"
+	MailDriverAPI  = "api"
+)
+
+// Supported STORAGE values.
+const (
+	StoragePostgres = "postgres"
+	StorageMemory   = "memory"
+)
+
+// Supported DB_TARGET_SESSION_ATTRS values.
+const (
+	DBSessionAny       = "any"
+	DBSessionReadWrite = "read-write"
+)
+
+// parseBuckets parses a comma-separated list of strictly increasing, positive
+// bucket bounds in seconds. An empty value returns nil (use the defaults).
+func parseBuckets(value string) ([]float64, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+	var buckets []float64
+	for _, part := range strings.Split(value, ",") {
+		b, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bucket %q", part)
+		}
+		if b <= 0 || (len(buckets) > 0 && b <= buckets[len(buckets)-1]) {
+			return nil, fmt.Errorf("buckets must be positive and strictly increasing")
+		}
+		buckets = append(buckets, b)
+	}
+	return buckets, nil
+}
+
+// parsePrefixes parses a comma-separated list of CIDRs or single IPs.
+func parsePrefixes(value string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if strings.Contains(part, "/") {
+			prefix, err := netip.ParsePrefix(part)
+			if err != nil {
+				return nil, fmt.Errorf("invalid CIDR %q", part)
+			}
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid IP %q", part)
+		}
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes, nil
+}
+
+// InMemory reports whether data is kept in memory instead of Postgres.
+func (c *Config) InMemory() bool {
+	return c.Storage == StorageMemory
+}
+
 // Validate checks that all configuration values are valid.
 func (c *Config) Validate() error {
 	if len(c.JWTSecret) < 16 {
@@ -110,18 +587,227 @@ func (c *Config) Validate() error {
 	if c.Port <= 0 || c.Port > 65535 {
 		return fmt.Errorf("PORT must be between 1 and 65535")
 	}
+	switch c.Storage {
+	case "", StoragePostgres:
+		if c.MemorySnapshotFile != "" {
+			return fmt.Errorf("MEMORY_SNAPSHOT_FILE requires STORAGE=%s", StorageMemory)
+		}
+	case StorageMemory:
+	default:
+		return fmt.Errorf("STORAGE must be %q or %q", StoragePostgres, StorageMemory)
+	}
 	if c.DBPort <= 0 || c.DBPort > 65535 {
 		return fmt.Errorf("DB_PORT must be between 1 and 65535")
 	}
+	if c.DBTargetSessionAttrs != "" && c.DBTargetSessionAttrs != DBSessionAny && c.DBTargetSessionAttrs != DBSessionReadWrite {
+		return fmt.Errorf("DB_TARGET_SESSION_ATTRS must be %q or %q", DBSessionAny, DBSessionReadWrite)
+	}
+	if c.DBHealthCheckInterval < 0 {
+		return fmt.Errorf("DB_HEALTH_CHECK_INTERVAL_SECONDS must not be negative")
+	}
+	if c.DBStartupTimeout < 0 {
+		return fmt.Errorf("DB_STARTUP_TIMEOUT_SECONDS must not be negative")
+	}
+	if c.SlowQueryThreshold < 0 {
+		return fmt.Errorf("SLOW_QUERY_THRESHOLD_MS must not be negative")
+	}
+	if c.DBRetryMaxAttempts < 1 {
+		return fmt.Errorf("DB_RETRY_MAX_ATTEMPTS must be at least 1")
+	}
+	if c.UnixSocketMode&^os.ModePerm != 0 {
+		return fmt.Errorf("UNIX_SOCKET_MODE must only set permission bits")
+	}
+	if c.HTTPReadTimeout < 0 || c.HTTPWriteTimeout < 0 || c.HTTPIdleTimeout < 0 {
+		return fmt.Errorf("HTTP_READ_TIMEOUT_SECONDS, HTTP_WRITE_TIMEOUT_SECONDS and HTTP_IDLE_TIMEOUT_SECONDS must not be negative")
+	}
+	if c.HTTP2MaxConcurrentStreams < 0 {
+		return fmt.Errorf("HTTP2_MAX_CONCURRENT_STREAMS must not be negative")
+	}
+	if c.HTTP2PingInterval < 0 {
+		return fmt.Errorf("HTTP2_PING_INTERVAL_SECONDS must not be negative")
+	}
 	if c.JWTExpiryHours <= 0 {
 		return fmt.Errorf("JWT_EXPIRY_HOURS must be positive")
 	}
+	if c.JWTRememberMeTTL < 24*time.Hour || c.JWTRememberMeTTL > 365*24*time.Hour {
+		return fmt.Errorf("JWT_REMEMBER_ME_DAYS must be between 1 and 365")
+	}
 	if c.MaxBodySize <= 0 {
 		return fmt.Errorf("MAX_BODY_SIZE must be positive")
 	}
+	if c.RateLimitRequests <= 0 {
+		return fmt.Errorf("RATE_LIMIT_REQUESTS must be positive")
+	}
+	if c.RateLimitWindow <= 0 {
+		return fmt.Errorf("RATE_LIMIT_WINDOW_SECONDS must be positive")
+	}
+	if c.UserRateLimitRPS < 0 {
+		return fmt.Errorf("USER_RATE_LIMIT_RPS must not be negative")
+	}
+	if c.UserRateLimitRPS > 0 && c.UserRateLimitBurst < 1 {
+		return fmt.Errorf("USER_RATE_LIMIT_BURST must be at least 1")
+	}
+	if c.SignupRateLimit < 0 {
+		return fmt.Errorf("SIGNUP_RATE_LIMIT must not be negative")
+	}
+	if c.SignupRateLimit > 0 && c.SignupRateWindow <= 0 {
+		return fmt.Errorf("SIGNUP_RATE_WINDOW_MINUTES must be positive")
+	}
+	if c.SignupDisposableDomainsFile != "" && !c.SignupBlockDisposable {
+		return fmt.Errorf("SIGNUP_DISPOSABLE_DOMAINS_FILE requires SIGNUP_BLOCK_DISPOSABLE=true")
+	}
+	if c.PasswordMinLength < 0 || c.PasswordMinLength > 128 {
+		return fmt.Errorf("PASSWORD_MIN_LENGTH must be between 0 and 128")
+	}
+	for _, class := range c.PasswordRequiredClasses {
+		switch class {
+		case "upper", "lower", "number", "symbol":
+		default:
+			return fmt.Errorf("PASSWORD_REQUIRED_CLASSES: unknown class %q, expected upper, lower, number or symbol", class)
+		}
+	}
+	if c.PasswordBannedFile != "" && !c.PasswordBlockCommon {
+		return fmt.Errorf("PASSWORD_BANNED_FILE requires PASSWORD_BLOCK_COMMON=true")
+	}
+	if c.BcryptCost != 0 && (c.BcryptCost < 4 || c.BcryptCost > 31) {
+		return fmt.Errorf("BCRYPT_COST must be between 4 and 31")
+	}
+	if c.BcryptMaxHashTime < 0 {
+		return fmt.Errorf("BCRYPT_MAX_HASH_MS must not be negative")
+	}
+	switch c.PwnedCheck {
+	case "", "off":
+	case "api":
+		if u, err := url.Parse(c.PwnedAPIURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("PWNED_API_URL must be an http or https URL")
+		}
+		if c.PwnedTimeout <= 0 {
+			return fmt.Errorf("PWNED_TIMEOUT_MS must be positive")
+		}
+	case "bloom":
+		if c.PwnedBloomFile == "" {
+			return fmt.Errorf("PWNED_CHECK=bloom requires PWNED_BLOOM_FILE")
+		}
+	default:
+		return fmt.Errorf("PWNED_CHECK must be off, api or bloom")
+	}
+	if c.PwnedAction != "" && c.PwnedAction != "reject" && c.PwnedAction != "warn" {
+		return fmt.Errorf("PWNED_ACTION must be reject or warn")
+	}
+	if (c.CaptchaVerifyURL == "") != (c.CaptchaSecret == "") {
+		return fmt.Errorf("CAPTCHA_VERIFY_URL and CAPTCHA_SECRET must be set together")
+	}
+	if c.ConfigWatchInterval < 0 {
+		return fmt.Errorf("CONFIG_WATCH_INTERVAL_SECONDS must not be negative")
+	}
+	if c.ResponseCacheTTL < 0 {
+		return fmt.Errorf("RESPONSE_CACHE_TTL_SECONDS must not be negative")
+	}
+	if c.AuditRetention < 0 {
+		return fmt.Errorf("AUDIT_RETENTION_DAYS must not be negative")
+	}
+	if c.MetricsCollectInterval <= 0 {
+		return fmt.Errorf("METRICS_COLLECT_INTERVAL_SECONDS must be positive")
+	}
+	if c.MetricsToken != "" && len(c.MetricsToken) < 16 {
+		return fmt.Errorf("METRICS_TOKEN must be at least 16 characters long")
+	}
+	if (c.MetricsUser == "") != (c.MetricsPassword == "") {
+		return fmt.Errorf("METRICS_USER and METRICS_PASSWORD must be set together")
+	}
+	if c.DiagnosticsToken != "" && len(c.DiagnosticsToken) < 16 {
+		return fmt.Errorf("DIAGNOSTICS_TOKEN must be at least 16 characters long")
+	}
+	if c.SCIMToken != "" && len(c.SCIMToken) < 32 {
+		return fmt.Errorf("SCIM_TOKEN must be at least 32 characters long")
+	}
+	if c.BasePath != "" && (c.BasePath != path.Clean(c.BasePath) || strings.ContainsAny(c.BasePath, "?#%")) {
+		return fmt.Errorf("BASE_PATH must be a plain path such as /sandbox-api")
+	}
+	if c.EncryptionKeys != "" && c.EncryptionKeysFile != "" {
+		return fmt.Errorf("ENCRYPTION_KEYS and ENCRYPTION_KEYS_FILE are exclusive")
+	}
+	if c.IsProduction() && c.WebhookAllowPrivateNetworks {
+		return fmt.Errorf("WEBHOOK_ALLOW_PRIVATE_NETWORKS cannot be used in production")
+	}
+	if c.SlackSigningSecret != "" && len(c.SlackSigningSecret) < 16 {
+		return fmt.Errorf("SLACK_SIGNING_SECRET must be at least 16 characters long")
+	}
+	if c.ErrorFormat != ErrorFormatJSON && c.ErrorFormat != ErrorFormatProblem {
+		return fmt.Errorf("ERROR_FORMAT must be %q or %q", ErrorFormatJSON, ErrorFormatProblem)
+	}
+	if c.LogBodies && c.LogBodyMaxBytes <= 0 {
+		return fmt.Errorf("LOG_BODY_MAX_BYTES must be positive")
+	}
+	if c.QuotaMaxTasks < 0 {
+		return fmt.Errorf("QUOTA_MAX_TASKS must not be negative")
+	}
+	if c.QuotaMaxMediaBytes < 0 {
+		return fmt.Errorf("QUOTA_MAX_MEDIA_MB must not be negative")
+	}
+	if c.QuotaMaxDailyRequests < 0 {
+		return fmt.Errorf("QUOTA_MAX_DAILY_REQUESTS must not be negative")
+	}
+	if c.QuotaMaxDailyBytes < 0 {
+		return fmt.Errorf("QUOTA_MAX_DAILY_MB must not be negative")
+	}
+	if c.LoginAlertURL != "" {
+		if u, err := url.Parse(c.LoginAlertURL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return fmt.Errorf("LOGIN_ALERT_URL must be an http(s) URL")
+		}
+	}
+	if c.OIDCIssuer != "" {
+		if u, err := url.Parse(c.OIDCIssuer); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" || u.RawQuery != "" || u.Fragment != "" {
+			return fmt.Errorf("OIDC_ISSUER must be an http(s) URL without query or fragment")
+		}
+		if u, _ := url.Parse(c.OIDCIssuer); !strings.HasSuffix(strings.TrimSuffix(u.Path, "/"), c.BasePath) {
+			return fmt.Errorf("OIDC_ISSUER must end with BASE_PATH")
+		}
+		if c.OIDCLoginURL == "" {
+			return fmt.Errorf("OIDC_LOGIN_URL is required with OIDC_ISSUER")
+		}
+		if c.OIDCClientsFile == "" {
+			return fmt.Errorf("OIDC_CLIENTS_FILE is required with OIDC_ISSUER")
+		}
+		if c.IsProduction() && c.OIDCSigningKeyFile == "" {
+			return fmt.Errorf("OIDC_SIGNING_KEY_FILE is required with OIDC_ISSUER in production")
+		}
+	}
+	switch c.MailDriver {
+	case "", MailDriverLog:
+	case MailDriverSMTP:
+		if c.MailFrom == "" {
+			return fmt.Errorf("MAIL_FROM is required with MAIL_DRIVER=smtp")
+		}
+		if c.SMTPHost == "" {
+			return fmt.Errorf("SMTP_HOST is required with MAIL_DRIVER=smtp")
+		}
+		if c.SMTPPort <= 0 || c.SMTPPort > 65535 {
+			return fmt.Errorf("SMTP_PORT must be between 1 and 65535")
+		}
+	case MailDriverAPI:
+		if c.MailFrom == "" {
+			return fmt.Errorf("MAIL_FROM is required with MAIL_DRIVER=api")
+		}
+		if c.MailAPIKey == "" {
+			return fmt.Errorf("MAIL_API_KEY is required with MAIL_DRIVER=api")
+		}
+	default:
+		return fmt.Errorf("MAIL_DRIVER must be %q, %q or %q", MailDriverLog, MailDriverSMTP, MailDriverAPI)
+	}
 	return nil
 }
 
+// normalizeBasePath returns p with a leading slash and no trailing one, and
+// "" for the root.
+func normalizeBasePath(p string) string {
+	p = strings.Trim(strings.TrimSpace(p), "/")
+	if p == "" {
+		return ""
+	}
+	return "/" + p
+}
+
 // IsProduction returns true if the app is running in production mode.
 func (c *Config) IsProduction() bool {
 	return c.AppEnv == "production"
@@ -152,3 +838,12 @@ func getEnvInt(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if floatVal, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatVal
+		}
+	}
+	return defaultValue
+}
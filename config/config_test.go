@@ -1,7 +1,9 @@
 package config
 
 import (
+	"os"
 	"testing"
+	"time"
 )
 
 func TestGetEnv(t *testing.T) {
@@ -44,11 +46,17 @@ func TestRequireEnv(t *testing.T) {
 func TestConfig_Validate(t *testing.T) {
 	validConfig := func() *Config {
 		return &Config{
-			JWTSecret:      "at-least-sixteen-chars",
-			Port:           8080,
-			DBPort:         5432,
-			JWTExpiryHours: 24,
-			MaxBodySize:    1 << 20,
+			JWTSecret:              "at-least-sixteen-chars",
+			Port:                   8080,
+			DBPort:                 5432,
+			DBRetryMaxAttempts:     3,
+			JWTExpiryHours:         24,
+			JWTRememberMeTTL:       30 * 24 * time.Hour,
+			MaxBodySize:            1 << 20,
+			MetricsCollectInterval: time.Minute,
+			RateLimitRequests:      10,
+			RateLimitWindow:        time.Minute,
+			ErrorFormat:            ErrorFormatJSON,
 		}
 	}
 
@@ -67,6 +75,14 @@ func TestConfig_Validate(t *testing.T) {
 		}
 	})
 
+	t.Run("rejects zero DB retry attempts", func(t *testing.T) {
+		cfg := validConfig()
+		cfg.DBRetryMaxAttempts = 0
+		if err := cfg.Validate(); err == nil {
+			t.Fatal("expected error for DB_RETRY_MAX_ATTEMPTS=0")
+		}
+	})
+
 	t.Run("rejects port zero", func(t *testing.T) {
 		cfg := validConfig()
 		cfg.Port = 0
@@ -99,6 +115,62 @@ func TestConfig_Validate(t *testing.T) {
 		}
 	})
 
+	t.Run("mail drivers require their settings", func(t *testing.T) {
+		cfg := validConfig()
+		cfg.MailDriver = MailDriverSMTP
+		cfg.MailFrom = "no-reply@example.com"
+		cfg.SMTPPort = 587
+		if err := cfg.Validate(); err == nil {
+			t.Error("expected error for MAIL_DRIVER=smtp without SMTP_HOST")
+		}
+		cfg.SMTPHost = "smtp.example.com"
+		if err := cfg.Validate(); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+
+		cfg = validConfig()
+		cfg.MailDriver = MailDriverAPI
+		cfg.MailAPIKey = "key"
+		if err := cfg.Validate(); err == nil {
+			t.Error("expected error for MAIL_DRIVER=api without MAIL_FROM")
+		}
+
+		cfg = validConfig()
+		cfg.MailDriver = "carrier-pigeon"
+		if err := cfg.Validate(); err == nil {
+			t.Error("expected error for an unknown MAIL_DRIVER")
+		}
+	})
+
+	t.Run("rejects non-permission bits in UnixSocketMode", func(t *testing.T) {
+		cfg := validConfig()
+		cfg.UnixSocketMode = os.ModeSetuid | 0o660
+		if err := cfg.Validate(); err == nil {
+			t.Fatal("expected error for setuid UnixSocketMode")
+		}
+	})
+
+	t.Run("rejects negative HTTP settings", func(t *testing.T) {
+		cfg := validConfig()
+		cfg.HTTPIdleTimeout = -time.Second
+		if err := cfg.Validate(); err == nil {
+			t.Fatal("expected error for negative HTTPIdleTimeout")
+		}
+		cfg = validConfig()
+		cfg.HTTP2MaxConcurrentStreams = -1
+		if err := cfg.Validate(); err == nil {
+			t.Fatal("expected error for negative HTTP2MaxConcurrentStreams")
+		}
+	})
+
+	t.Run("rejects a zero burst for the per-user limit", func(t *testing.T) {
+		cfg := validConfig()
+		cfg.UserRateLimitRPS = 5
+		if err := cfg.Validate(); err == nil {
+			t.Fatal("expected error for zero UserRateLimitBurst")
+		}
+	})
+
 	t.Run("rejects non-positive JWTExpiryHours", func(t *testing.T) {
 		cfg := validConfig()
 		cfg.JWTExpiryHours = 0
@@ -114,6 +186,237 @@ func TestConfig_Validate(t *testing.T) {
 			t.Fatal("expected error for zero MaxBodySize")
 		}
 	})
+
+	t.Run("rejects negative AuditRetention", func(t *testing.T) {
+		cfg := validConfig()
+		cfg.AuditRetention = -time.Hour
+		if err := cfg.Validate(); err == nil {
+			t.Fatal("expected error for negative AuditRetention")
+		}
+	})
+
+	t.Run("rejects short DiagnosticsToken", func(t *testing.T) {
+		cfg := validConfig()
+		cfg.DiagnosticsToken = "short"
+		if err := cfg.Validate(); err == nil {
+			t.Fatal("expected error for short DiagnosticsToken")
+		}
+	})
+
+	t.Run("rejects incomplete metrics credentials", func(t *testing.T) {
+		cfg := validConfig()
+		cfg.MetricsToken = "short"
+		if err := cfg.Validate(); err == nil {
+			t.Fatal("expected error for short MetricsToken")
+		}
+		cfg = validConfig()
+		cfg.MetricsUser = "prometheus"
+		if err := cfg.Validate(); err == nil {
+			t.Fatal("expected error for MetricsUser without MetricsPassword")
+		}
+	})
+
+	t.Run("rejects unknown ErrorFormat", func(t *testing.T) {
+		cfg := validConfig()
+		cfg.ErrorFormat = "xml"
+		if err := cfg.Validate(); err == nil {
+			t.Fatal("expected error for unknown ErrorFormat")
+		}
+	})
+
+	t.Run("rejects negative quotas", func(t *testing.T) {
+		cfg := validConfig()
+		cfg.QuotaMaxTasks = -1
+		if err := cfg.Validate(); err == nil {
+			t.Fatal("expected error for negative QuotaMaxTasks")
+		}
+		cfg = validConfig()
+		cfg.QuotaMaxMediaBytes = -1
+		if err := cfg.Validate(); err == nil {
+			t.Fatal("expected error for negative QuotaMaxMediaBytes")
+		}
+		cfg = validConfig()
+		cfg.QuotaMaxDailyRequests = -1
+		if err := cfg.Validate(); err == nil {
+			t.Fatal("expected error for negative QuotaMaxDailyRequests")
+		}
+	})
+
+	t.Run("rejects non-positive LogBodyMaxBytes when body logging is on", func(t *testing.T) {
+		cfg := validConfig()
+		cfg.LogBodies = true
+		cfg.LogBodyMaxBytes = 0
+		if err := cfg.Validate(); err == nil {
+			t.Fatal("expected error for zero LogBodyMaxBytes")
+		}
+	})
+
+	t.Run("rejects non-positive MetricsCollectInterval", func(t *testing.T) {
+		cfg := validConfig()
+		cfg.MetricsCollectInterval = 0
+		if err := cfg.Validate(); err == nil {
+			t.Fatal("expected error for zero MetricsCollectInterval")
+		}
+	})
+
+	t.Run("rejects non-positive rate limits", func(t *testing.T) {
+		cfg := validConfig()
+		cfg.RateLimitRequests = 0
+		if err := cfg.Validate(); err == nil {
+			t.Fatal("expected error for zero RateLimitRequests")
+		}
+		cfg = validConfig()
+		cfg.RateLimitWindow = 0
+		if err := cfg.Validate(); err == nil {
+			t.Fatal("expected error for zero RateLimitWindow")
+		}
+	})
+
+	t.Run("rejects signup limit without window", func(t *testing.T) {
+		cfg := validConfig()
+		cfg.SignupRateLimit = 5
+		if err := cfg.Validate(); err == nil {
+			t.Fatal("expected error for SignupRateLimit without SignupRateWindow")
+		}
+		cfg.SignupRateWindow = time.Hour
+		if err := cfg.Validate(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("requires a long SCIM token", func(t *testing.T) {
+		cfg := validConfig()
+		cfg.SCIMToken = "too-short-0123456789"
+		if err := cfg.Validate(); err == nil {
+			t.Fatal("expected error for a short SCIM_TOKEN")
+		}
+		cfg.SCIMToken = "scim-token-0123456789abcdef0123456789"
+		if err := cfg.Validate(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("rejects a short Slack signing secret", func(t *testing.T) {
+		cfg := validConfig()
+		cfg.SlackSigningSecret = "short"
+		if err := cfg.Validate(); err == nil {
+			t.Fatal("expected error for a short SLACK_SIGNING_SECRET")
+		}
+		cfg.SlackSigningSecret = "8f742231b10e8888abcd99yyyzzz85a5"
+		if err := cfg.Validate(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("rejects encryption keys given twice", func(t *testing.T) {
+		cfg := validConfig()
+		cfg.EncryptionKeys = "a:key"
+		cfg.EncryptionKeysFile = "/run/secrets/encryption_keys"
+		if err := cfg.Validate(); err == nil {
+			t.Fatal("expected error for both ENCRYPTION_KEYS and ENCRYPTION_KEYS_FILE")
+		}
+	})
+
+	t.Run("rejects private webhook targets in production", func(t *testing.T) {
+		cfg := validConfig()
+		cfg.AppEnv = "production"
+		cfg.WebhookAllowPrivateNetworks = true
+		if err := cfg.Validate(); err == nil {
+			t.Fatal("expected error for WEBHOOK_ALLOW_PRIVATE_NETWORKS in production")
+		}
+	})
+
+	t.Run("checks the base path", func(t *testing.T) {
+		cfg := validConfig()
+		cfg.BasePath = "/sandbox-api/../admin"
+		if err := cfg.Validate(); err == nil {
+			t.Fatal("expected error for a BASE_PATH that is not clean")
+		}
+		cfg.BasePath = "/sandbox-api"
+		cfg.OIDCIssuer = "https://example.com"
+		cfg.OIDCLoginURL = "https://app.example.com/oauth/consent"
+		cfg.OIDCClientsFile = "/etc/sandbox/oidc-clients.json"
+		if err := cfg.Validate(); err == nil {
+			t.Fatal("expected error for an OIDC_ISSUER outside of BASE_PATH")
+		}
+		cfg.OIDCIssuer = "https://example.com/sandbox-api"
+		if err := cfg.Validate(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("checks the OpenID Connect provider settings", func(t *testing.T) {
+		cfg := validConfig()
+		cfg.OIDCIssuer = "https://api.example.com"
+		if err := cfg.Validate(); err == nil {
+			t.Fatal("expected error for OIDC_ISSUER without login URL and clients")
+		}
+		cfg.OIDCLoginURL = "https://app.example.com/oauth/consent"
+		cfg.OIDCClientsFile = "/etc/sandbox/oidc-clients.json"
+		if err := cfg.Validate(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		cfg.AppEnv = "production"
+		if err := cfg.Validate(); err == nil {
+			t.Fatal("expected error for a generated signing key in production")
+		}
+		cfg.OIDCSigningKeyFile = "/etc/sandbox/oidc-key.pem"
+		cfg.OIDCIssuer = "api.example.com"
+		if err := cfg.Validate(); err == nil {
+			t.Fatal("expected error for an issuer that is not a URL")
+		}
+	})
+
+	t.Run("bounds the remember-me lifetime", func(t *testing.T) {
+		cfg := validConfig()
+		for _, ttl := range []time.Duration{0, 366 * 24 * time.Hour} {
+			cfg.JWTRememberMeTTL = ttl
+			if err := cfg.Validate(); err == nil {
+				t.Errorf("expected error for JWT_REMEMBER_ME_DAYS of %s", ttl)
+			}
+		}
+	})
+
+	t.Run("checks the login alert URL", func(t *testing.T) {
+		cfg := validConfig()
+		cfg.LoginAlertURL = "app.example.com/sessions/revoke"
+		if err := cfg.Validate(); err == nil {
+			t.Fatal("expected error for a LOGIN_ALERT_URL that is not a URL")
+		}
+		cfg.LoginAlertURL = "https://app.example.com/sessions/revoke"
+		if err := cfg.Validate(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("checks the storage", func(t *testing.T) {
+		cfg := validConfig()
+		cfg.Storage = "sqlite"
+		if err := cfg.Validate(); err == nil {
+			t.Fatal("expected error for an unknown STORAGE")
+		}
+		cfg.Storage = StoragePostgres
+		cfg.MemorySnapshotFile = "/var/lib/sandbox/snapshot.json"
+		if err := cfg.Validate(); err == nil {
+			t.Fatal("expected error for MEMORY_SNAPSHOT_FILE with Postgres")
+		}
+		cfg.Storage = StorageMemory
+		if err := cfg.Validate(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("requires CAPTCHA URL and secret together", func(t *testing.T) {
+		cfg := validConfig()
+		cfg.CaptchaVerifyURL = "https://hcaptcha.com/siteverify"
+		if err := cfg.Validate(); err == nil {
+			t.Fatal("expected error for CAPTCHA_VERIFY_URL without CAPTCHA_SECRET")
+		}
+		cfg.CaptchaSecret = "secret"
+		if err := cfg.Validate(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
 }
 
 func TestConfig_IsProduction(t *testing.T) {
@@ -153,3 +456,81 @@ func TestConfig_IsProduction(t *testing.T) {
 		})
 	}
 }
+
+func TestParseBuckets(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    []float64
+		wantErr bool
+	}{
+		{name: "empty uses defaults", value: "", want: nil},
+		{name: "valid list", value: "0.05, 0.1,1", want: []float64{0.05, 0.1, 1}},
+		{name: "not a number", value: "0.1,fast", wantErr: true},
+		{name: "not increasing", value: "0.5,0.1", wantErr: true},
+		{name: "negative", value: "-1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseBuckets(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("wantErr %v, got %v", tt.wantErr, err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("got %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestParsePrefixes(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    []string
+		wantErr bool
+	}{
+		{name: "empty trusts none", value: ""},
+		{name: "cidrs and ips", value: "10.0.0.0/8, 192.0.2.10,::1", want: []string{"10.0.0.0/8", "192.0.2.10/32", "::1/128"}},
+		{name: "host bits are masked", value: "172.16.5.4/12", want: []string{"172.16.0.0/12"}},
+		{name: "invalid ip", value: "10.0.0.256", wantErr: true},
+		{name: "invalid cidr", value: "10.0.0.0/33", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parsePrefixes(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("wantErr %v, got %v", tt.wantErr, err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i].String() != tt.want[i] {
+					t.Errorf("got %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestNormalizeBasePath(t *testing.T) {
+	for value, want := range map[string]string{
+		"":              "",
+		"/":             "",
+		"sandbox-api":   "/sandbox-api",
+		"/sandbox-api/": "/sandbox-api",
+		" /apps/tasks ": "/apps/tasks",
+	} {
+		if got := normalizeBasePath(value); got != want {
+			t.Errorf("normalizeBasePath(%q) = %q, want %q", value, got, want)
+		}
+	}
+}
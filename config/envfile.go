@@ -0,0 +1,117 @@
+package config
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ConfigFileEnv names an optional KEY=VALUE file whose entries override the
+// environment. Load reads it every time, so reloads pick up edits.
+const ConfigFileEnv = "CONFIG_FILE"
+
+var (
+	envFileMu sync.Mutex
+	// envFileOriginals holds, for each key set from the file, the value it
+	// had in the environment before (nil if it was unset), so a key removed
+	// from the file goes back to it.
+	envFileOriginals = map[string]*string{}
+)
+
+// loadEnvFile applies the entries of the file at path to the environment.
+func loadEnvFile(path string) error {
+	entries, err := readEnvFile(path)
+	if err != nil {
+		return err
+	}
+
+	envFileMu.Lock()
+	defer envFileMu.Unlock()
+
+	for key, original := range envFileOriginals {
+		if _, ok := entries[key]; ok {
+			continue
+		}
+		if original == nil {
+			os.Unsetenv(key)
+		} else {
+			os.Setenv(key, *original)
+		}
+		delete(envFileOriginals, key)
+	}
+	for key, value := range entries {
+		if _, tracked := envFileOriginals[key]; !tracked {
+			if original, ok := os.LookupEnv(key); ok {
+				envFileOriginals[key] = &original
+			} else {
+				envFileOriginals[key] = nil
+			}
+		}
+		os.Setenv(key, value)
+	}
+	return nil
+}
+
+// readEnvFile parses KEY=VALUE lines. Blank lines and lines starting with #
+// are skipped, an "export " prefix is allowed and values may be quoted.
+func readEnvFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", ConfigFileEnv, err)
+	}
+	defer f.Close()
+
+	entries := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" || strings.ContainsAny(key, " \t") {
+			return nil, fmt.Errorf("%s: line %d: expected KEY=VALUE", path, n)
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		entries[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return entries, nil
+}
+
+// WatchFile calls onChange whenever the modification time of the file at
+// path changes, checking every interval until ctx is done.
+func WatchFile(ctx context.Context, path string, interval time.Duration, onChange func()) {
+	modTime := func() time.Time {
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}
+		}
+		return info.ModTime()
+	}
+
+	last := modTime()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if current := modTime(); !current.Equal(last) {
+				last = current
+				onChange()
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package config
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoad_ConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sandbox.env")
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Setenv("JWT_SECRET", "at-least-sixteen-chars")
+	t.Setenv("RATE_LIMIT_REQUESTS", "10")
+	t.Setenv(ConfigFileEnv, path)
+	t.Cleanup(func() {
+		os.Unsetenv("LOG_LEVEL")
+	})
+
+	write("# comment\nexport RATE_LIMIT_REQUESTS=50\nLOG_LEVEL='debug'\n\n")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.RateLimitRequests != 50 || cfg.LogLevel != slog.LevelDebug {
+		t.Errorf("expected file values, got %d requests at %s", cfg.RateLimitRequests, cfg.LogLevel)
+	}
+
+	// Keys removed from the file fall back to the environment
+	write("LOG_LEVEL=warn\n")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.RateLimitRequests != 10 || cfg.LogLevel != slog.LevelWarn {
+		t.Errorf("expected environment values back, got %d requests at %s", cfg.RateLimitRequests, cfg.LogLevel)
+	}
+
+	write("not a valid line\n")
+	if _, err := Load(); err == nil {
+		t.Error("expected an error for a malformed file")
+	}
+	write("LOG_LEVEL=loud\n")
+	if _, err := Load(); err == nil {
+		t.Error("expected an error for an unknown log level")
+	}
+}
+
+func TestWatchFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sandbox.env")
+	if err := os.WriteFile(path, []byte("A=1\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	changed := make(chan struct{}, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go WatchFile(ctx, path, 10*time.Millisecond, func() { changed <- struct{}{} })
+
+	time.Sleep(30 * time.Millisecond)
+	future := time.Now().Add(time.Hour)
+	if err := os.Chtimes(path, future, future); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-changed:
+	case <-time.After(time.Second):
+		t.Fatal("expected a change notification")
+	}
+}
@@ -0,0 +1,86 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// masked replaces the value of a secret that is set.
+const masked = "********"
+
+// secretFields hold credentials, masked by Redacted.
+var secretFields = map[string]bool{
+	"DBPassword":         true,
+	"JWTSecret":          true,
+	"MinioPassword":      true,
+	"CaptchaSecret":      true,
+	"MetricsToken":       true,
+	"MetricsPassword":    true,
+	"DiagnosticsToken":   true,
+	"SCIMToken":          true,
+	"SlackSigningSecret": true,
+	"SMTPPassword":       true,
+	"MailAPIKey":         true,
+	"EncryptionKeys":     true,
+}
+
+// credentialURLFields are URLs which may carry credentials in their user
+// info, masked by Redacted.
+var credentialURLFields = map[string]bool{
+	"RedisURL":          true,
+	"ErrorReportingDSN": true,
+}
+
+// Redacted returns the configuration by field name, safe to show to
+// operators: secrets that are set read "********", as do the credentials
+// of URLs. Durations and levels are written as strings.
+func (c *Config) Redacted() map[string]interface{} {
+	v := reflect.ValueOf(c).Elem()
+	fields := make(map[string]interface{}, v.NumField())
+	for i := 0; i < v.NumField(); i++ {
+		name := v.Type().Field(i).Name
+		value := v.Field(i).Interface()
+
+		switch {
+		case secretFields[name]:
+			if v.Field(i).String() != "" {
+				value = masked
+			}
+		case credentialURLFields[name]:
+			value = redactURL(v.Field(i).String())
+		default:
+			switch x := value.(type) {
+			case time.Duration:
+				value = x.String()
+			case slog.Level:
+				value = x.String()
+			case os.FileMode:
+				value = fmt.Sprintf("%#o", uint32(x))
+			}
+		}
+		fields[name] = value
+	}
+	return fields
+}
+
+// redactURL masks the user info of a URL, or all of a URL that does not
+// parse.
+func redactURL(raw string) string {
+	if raw == "" {
+		return ""
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return masked
+	}
+	if u.User == nil {
+		return raw
+	}
+	u.User = nil
+	return u.Scheme + "://" + masked + "@" + strings.TrimPrefix(u.String(), u.Scheme+"://")
+}
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestConfig_Redacted(t *testing.T) {
+	cfg := &Config{
+		JWTSecret:         "a-very-long-jwt-secret",
+		DBPassword:        "postgres",
+		RedisURL:          "redis://:hunter2@redis:6379/0",
+		ErrorReportingDSN: "https://publickey@o1.ingest.sentry.io/42",
+		RateLimitWindow:   time.Minute,
+		Port:              8080,
+	}
+	got := cfg.Redacted()
+
+	for field, want := range map[string]interface{}{
+		"JWTSecret":         masked,
+		"DBPassword":        masked,
+		"SMTPPassword":      "",
+		"RedisURL":          "redis://********@redis:6379/0",
+		"ErrorReportingDSN": "https://********@o1.ingest.sentry.io/42",
+		"RateLimitWindow":   "1m0s",
+		"Port":              8080,
+	} {
+		if got[field] != want {
+			t.Errorf("%s = %v, want %v", field, got[field], want)
+		}
+	}
+}
+
+// New credentials must be added to secretFields
+func TestConfig_Redacted_CoversSecrets(t *testing.T) {
+	secretName := regexp.MustCompile(`Password|Secret|Token|APIKey`)
+	// settings named after what they configure
+	notSecret := regexp.MustCompile(`^Password(MinLength|RequiredClasses|BlockCommon|BannedFile)$`)
+	typ := reflect.TypeOf(Config{})
+	for i := 0; i < typ.NumField(); i++ {
+		name := typ.Field(i).Name
+		if secretName.MatchString(name) && !notSecret.MatchString(name) && !secretFields[name] {
+			t.Errorf("%s looks like a secret but is not masked", name)
+		}
+	}
+}
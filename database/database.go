@@ -3,61 +3,125 @@ package database
 import (
 	"database/sql"
 	"fmt"
+	"net"
 	"time"
 
 	"github.com/clementhaon/sandbox-api-go/config"
-	_ "github.com/lib/pq"
+	"github.com/clementhaon/sandbox-api-go/metrics"
+	"github.com/lib/pq"
 	"log"
 )
 
-var DB *sql.DB
-
-// InitDB initializes the database connection
-func InitDB() error {
-	// Load environment variables
-	dbHost := config.GetEnv("DB_HOST", "localhost")
-	dbPort := config.GetEnv("DB_PORT", "5432")
-	dbUser := config.GetEnv("DB_USER", "postgres")
-	dbPassword := config.GetEnv("DB_PASSWORD", "postgres123")
-	dbName := config.GetEnv("DB_NAME", "sandbox_api")
-	dbSSLMode := config.GetEnv("DB_SSLMODE", "disable")
-
-	// Build the connection string
-	connStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
-		dbHost, dbPort, dbUser, dbPassword, dbName, dbSSLMode)
-
-	// Connect to the database
-	var err error
-	DB, err = sql.Open("postgres", connStr)
+// Open connects to the database described by cfg, waiting up to
+// cfg.DBStartupTimeout for it to accept connections, configures the connection
+// pool and runs pending migrations. Host health checks and failovers are
+// recorded on m, which may be nil. The caller owns the returned *sql.DB and
+// must close it.
+func Open(cfg *config.Config, m *metrics.Metrics) (*sql.DB, error) {
+	db, err := connect(cfg, m)
 	if err != nil {
-		return fmt.Errorf("error opening database connection: %v", err)
+		return nil, err
 	}
 
-	// Test the connection
-	if err = DB.Ping(); err != nil {
-		return fmt.Errorf("error testing database connection: %v", err)
+	// Run migrations automatically
+	if err := RunMigrations(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error running migrations: %v", err)
+	}
+
+	return db, nil
+}
+
+// Connect is Open without the migrations, for the migrate command.
+func Connect(cfg *config.Config) (*sql.DB, error) {
+	return connect(cfg, nil)
+}
+
+func connect(cfg *config.Config, m *metrics.Metrics) (*sql.DB, error) {
+	db, err := openDB(cfg, m)
+	if err != nil {
+		return nil, fmt.Errorf("error opening database connection: %v", err)
+	}
+
+	// Wait for the database, which may still be starting (docker compose)
+	if err = waitForDB(db, cfg.DBStartupTimeout); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error testing database connection: %w", err)
 	}
 
 	// Configure the connection pool
-	DB.SetMaxOpenConns(25)
-	DB.SetMaxIdleConns(25)
-	DB.SetConnMaxLifetime(5 * time.Minute)
-	DB.SetConnMaxIdleTime(1 * time.Minute)
+	db.SetMaxOpenConns(25)
+	db.SetMaxIdleConns(25)
+	db.SetConnMaxLifetime(5 * time.Minute)
+	db.SetConnMaxIdleTime(1 * time.Minute)
 
 	log.Println("✅ PostgreSQL connection established successfully")
+	return db, nil
+}
 
-	// Run migrations automatically
-	if err := RunMigrations(DB); err != nil {
-		return fmt.Errorf("error running migrations: %v", err)
+// openDB returns a handle on the configured database. With several
+// comma-separated hosts in DB_HOST, or a read-write session required,
+// connections go through a failoverConnector whose hosts are checked every
+// DBHealthCheckInterval.
+func openDB(cfg *config.Config, m *metrics.Metrics) (*sql.DB, error) {
+	dsn := func(addr string) (string, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
+			host, port, cfg.DBUser, cfg.DBPassword, cfg.DBName, cfg.DBSSLMode), nil
+	}
+
+	addrs := splitHosts(cfg.DBHost, cfg.DBPort)
+	readWrite := cfg.DBTargetSessionAttrs == config.DBSessionReadWrite
+	if len(addrs) == 1 && !readWrite {
+		connStr, err := dsn(addrs[0])
+		if err != nil {
+			return nil, err
+		}
+		return sql.Open("postgres", connStr)
 	}
 
-	return nil
+	hosts := make([]dbHost, len(addrs))
+	for i, addr := range addrs {
+		connStr, err := dsn(addr)
+		if err != nil {
+			return nil, err
+		}
+		connector, err := pq.NewConnector(connStr)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", addr, err)
+		}
+		hosts[i] = dbHost{addr: addr, connector: connector}
+	}
+	connector := newFailoverConnector(hosts, readWrite, &pq.Driver{}, m)
+	if cfg.DBHealthCheckInterval > 0 {
+		connector.startHealthChecks(cfg.DBHealthCheckInterval, cfg.DBHealthCheckInterval)
+	}
+	log.Printf("🗄️  Database hosts %v (target session: %s)", addrs, cfg.DBTargetSessionAttrs)
+	return sql.OpenDB(connector), nil
 }
 
-// CloseDB closes the database connection
-func CloseDB() error {
-	if DB != nil {
-		return DB.Close()
+// startupRetry paces the connection attempts of waitForDB.
+var startupRetry = RetryPolicy{BaseDelay: 500 * time.Millisecond, MaxDelay: 5 * time.Second}
+
+// waitForDB pings db until it answers, backing off between attempts, and
+// gives up once the next attempt would start after maxWait. Each failed
+// attempt is logged so the wait is visible while the API is not serving yet.
+func waitForDB(db *sql.DB, maxWait time.Duration) error {
+	deadline := time.Now().Add(maxWait)
+	for attempt := 1; ; attempt++ {
+		err := db.Ping()
+		if err == nil {
+			return nil
+		}
+		delay := startupRetry.backoff(attempt)
+		if time.Now().Add(delay).After(deadline) {
+			return fmt.Errorf("database not reachable after %d attempt(s): %w", attempt, err)
+		}
+		log.Printf("⏳ Waiting for the database (attempt %d, retrying in %s, giving up in %s): %v",
+			attempt, delay.Round(time.Millisecond), time.Until(deadline).Round(time.Second), err)
+		time.Sleep(delay)
 	}
-	return nil
 }
@@ -0,0 +1,81 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// startingDriver refuses connections until it has been dialed failures times,
+// like a database that is still starting.
+type startingDriver struct {
+	failures int32
+	dials    atomic.Int32
+}
+
+func (d *startingDriver) Open(string) (driver.Conn, error) {
+	if d.dials.Add(1) <= d.failures {
+		return nil, errors.New("connection refused")
+	}
+	return fakeConn{}, nil
+}
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (fakeConn) Close() error                        { return nil }
+func (fakeConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+func openStarting(t *testing.T, failures int32) (*sql.DB, *startingDriver) {
+	t.Helper()
+	d := &startingDriver{failures: failures}
+	db := sql.OpenDB(connector{d})
+	t.Cleanup(func() { db.Close() })
+	return db, d
+}
+
+type connector struct{ d *startingDriver }
+
+func (c connector) Connect(context.Context) (driver.Conn, error) { return c.d.Open("") }
+func (c connector) Driver() driver.Driver                        { return c.d }
+
+func TestWaitForDB(t *testing.T) {
+	saved := startupRetry
+	startupRetry = RetryPolicy{BaseDelay: time.Millisecond, MaxDelay: 2 * time.Millisecond}
+	defer func() { startupRetry = saved }()
+
+	t.Run("waits until the database answers", func(t *testing.T) {
+		db, d := openStarting(t, 3)
+		if err := waitForDB(db, time.Second); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := d.dials.Load(); got != 4 {
+			t.Errorf("expected 4 connection attempts, got %d", got)
+		}
+	})
+
+	t.Run("gives up after the max wait", func(t *testing.T) {
+		db, _ := openStarting(t, 1000)
+		start := time.Now()
+		if err := waitForDB(db, 20*time.Millisecond); err == nil {
+			t.Fatal("expected an error")
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("expected to give up after about 20ms, took %s", elapsed)
+		}
+	})
+
+	t.Run("zero max wait tries once", func(t *testing.T) {
+		db, d := openStarting(t, 1000)
+		if err := waitForDB(db, 0); err == nil {
+			t.Fatal("expected an error")
+		}
+		if got := d.dials.Load(); got != 1 {
+			t.Errorf("expected 1 connection attempt, got %d", got)
+		}
+	})
+}
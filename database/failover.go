@@ -0,0 +1,258 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/metrics"
+)
+
+// errReadOnly rejects a host in recovery or otherwise read-only when a
+// read-write session is required.
+var errReadOnly = errors.New("server is read-only")
+
+// splitHosts parses a comma-separated list of host or host:port entries,
+// using port when an entry has none.
+func splitHosts(hosts string, port int) []string {
+	var addrs []string
+	for _, h := range strings.Split(hosts, ",") {
+		h = strings.TrimSpace(h)
+		if h == "" {
+			continue
+		}
+		if _, _, err := net.SplitHostPort(h); err != nil {
+			h = net.JoinHostPort(strings.Trim(h, "[]"), strconv.Itoa(port))
+		}
+		addrs = append(addrs, h)
+	}
+	return addrs
+}
+
+// dbHost is one server of a multi-host configuration.
+type dbHost struct {
+	addr      string // host:port, for logs and metrics
+	connector driver.Connector
+}
+
+// failoverConnector opens connections on the first host that accepts them
+// and, with readWrite, is not read-only, starting from the host currently in
+// use. Connections to other hosts are dropped from the pool when they are
+// returned to it, so the pool follows a switchover. A background check
+// probes every host and moves to another one when the current host fails.
+type failoverConnector struct {
+	hosts     []dbHost
+	readWrite bool
+	driver    driver.Driver
+	metrics   *metrics.Metrics
+
+	current atomic.Int32 // index in hosts of the host in use
+	stop    chan struct{}
+	done    chan struct{}
+	once    sync.Once
+}
+
+func newFailoverConnector(hosts []dbHost, readWrite bool, d driver.Driver, m *metrics.Metrics) *failoverConnector {
+	return &failoverConnector{hosts: hosts, readWrite: readWrite, driver: d, metrics: m}
+}
+
+// Connect implements driver.Connector.
+func (c *failoverConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	start := int(c.current.Load())
+	var errs []error
+	for i := range c.hosts {
+		idx := (start + i) % len(c.hosts)
+		conn, err := c.dial(ctx, idx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", c.hosts[idx].addr, err))
+			continue
+		}
+		c.use(idx)
+		return &hostConn{Conn: conn, connector: c, host: idx}, nil
+	}
+	return nil, errors.Join(errs...)
+}
+
+// Driver implements driver.Connector.
+func (c *failoverConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// dial connects to host i and checks that it accepts the session.
+func (c *failoverConnector) dial(ctx context.Context, i int) (driver.Conn, error) {
+	conn, err := c.hosts[i].connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if c.readWrite {
+		readOnly, err := isReadOnly(ctx, conn)
+		if err == nil && readOnly {
+			err = errReadOnly
+		}
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+// use makes host i the one new connections go to.
+func (c *failoverConnector) use(i int) {
+	if old := c.current.Swap(int32(i)); int(old) != i {
+		c.metrics.RecordDatabaseFailover()
+		log.Printf("🔀 Database switched from %s to %s", c.hosts[old].addr, c.hosts[i].addr)
+	}
+}
+
+// checkHosts probes every host, records which ones are up, and moves to the
+// first healthy host when the current one is not.
+func (c *failoverConnector) checkHosts(ctx context.Context) {
+	up := make([]bool, len(c.hosts))
+	for i, h := range c.hosts {
+		conn, err := c.dial(ctx, i)
+		if err == nil {
+			conn.Close()
+		}
+		up[i] = err == nil
+		c.metrics.SetDatabaseHostUp(h.addr, up[i])
+	}
+	if up[c.current.Load()] {
+		return
+	}
+	for i := range c.hosts {
+		if up[i] {
+			c.use(i)
+			return
+		}
+	}
+}
+
+// startHealthChecks runs checkHosts every interval until Close.
+func (c *failoverConnector) startHealthChecks(interval, timeout time.Duration) {
+	c.stop = make(chan struct{})
+	c.done = make(chan struct{})
+	go func() {
+		defer close(c.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-c.stop:
+				return
+			case <-ticker.C:
+				ctx, cancel := context.WithTimeout(context.Background(), timeout)
+				c.checkHosts(ctx)
+				cancel()
+			}
+		}
+	}()
+}
+
+// Close stops the health checks. sql.DB.Close calls it.
+func (c *failoverConnector) Close() error {
+	c.once.Do(func() {
+		if c.stop != nil {
+			close(c.stop)
+			<-c.done
+		}
+	})
+	return nil
+}
+
+// isReadOnly reports whether the server behind conn refuses writes, like a
+// standby or a primary demoted during a failover.
+func isReadOnly(ctx context.Context, conn driver.Conn) (bool, error) {
+	q, ok := conn.(driver.QueryerContext)
+	if !ok {
+		return false, errors.New("driver cannot check transaction_read_only")
+	}
+	rows, err := q.QueryContext(ctx, "SHOW transaction_read_only", nil)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+	dest := make([]driver.Value, 1)
+	if err := rows.Next(dest); err != nil {
+		return false, err
+	}
+	switch v := dest[0].(type) {
+	case []byte:
+		return string(v) == "on", nil
+	case string:
+		return v == "on", nil
+	}
+	return false, fmt.Errorf("unexpected transaction_read_only value %v", dest[0])
+}
+
+// hostConn is a connection to one host of a failoverConnector. It stops
+// being valid once the connector has moved to another host.
+type hostConn struct {
+	driver.Conn
+	connector *failoverConnector
+	host      int
+}
+
+func (hc *hostConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	q, ok := hc.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	return q.QueryContext(ctx, query, args)
+}
+
+func (hc *hostConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	e, ok := hc.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	return e.ExecContext(ctx, query, args)
+}
+
+func (hc *hostConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if p, ok := hc.Conn.(driver.ConnPrepareContext); ok {
+		return p.PrepareContext(ctx, query)
+	}
+	return hc.Conn.Prepare(query)
+}
+
+func (hc *hostConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if b, ok := hc.Conn.(driver.ConnBeginTx); ok {
+		return b.BeginTx(ctx, opts)
+	}
+	return hc.Conn.Begin()
+}
+
+func (hc *hostConn) Ping(ctx context.Context) error {
+	if p, ok := hc.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (hc *hostConn) ResetSession(ctx context.Context) error {
+	if r, ok := hc.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+// IsValid implements driver.Validator; sql.DB discards invalid connections
+// instead of returning them to the pool.
+func (hc *hostConn) IsValid() bool {
+	if int(hc.connector.current.Load()) != hc.host {
+		return false
+	}
+	if v, ok := hc.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
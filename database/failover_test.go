@@ -0,0 +1,180 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeHost is a database server that can go down or become read-only.
+type fakeHost struct {
+	down     atomic.Bool
+	readOnly atomic.Bool
+}
+
+func (h *fakeHost) Connect(context.Context) (driver.Conn, error) {
+	if h.down.Load() {
+		return nil, errors.New("connection refused")
+	}
+	return &fakeHostConn{host: h}, nil
+}
+
+func (h *fakeHost) Driver() driver.Driver { return nil }
+
+// fakeHostConn answers SHOW transaction_read_only for its host.
+type fakeHostConn struct {
+	fakeConn
+	host *fakeHost
+}
+
+func (c *fakeHostConn) QueryContext(context.Context, string, []driver.NamedValue) (driver.Rows, error) {
+	value := "off"
+	if c.host.readOnly.Load() {
+		value = "on"
+	}
+	return &fakeRows{values: []driver.Value{[]byte(value)}}, nil
+}
+
+type fakeRows struct {
+	values []driver.Value
+	read   bool
+}
+
+func (r *fakeRows) Columns() []string { return []string{"transaction_read_only"} }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.read {
+		return io.EOF
+	}
+	r.read = true
+	copy(dest, r.values)
+	return nil
+}
+
+func newFakeFailover(readWrite bool, n int) (*failoverConnector, []*fakeHost) {
+	fakes := make([]*fakeHost, n)
+	hosts := make([]dbHost, n)
+	for i := range fakes {
+		fakes[i] = &fakeHost{}
+		hosts[i] = dbHost{addr: "db" + string(rune('a'+i)) + ":5432", connector: fakes[i]}
+	}
+	return newFailoverConnector(hosts, readWrite, nil, nil), fakes
+}
+
+func TestSplitHosts(t *testing.T) {
+	got := splitHosts("db1, db2:6543,[::1],, ", 5432)
+	want := []string{"db1:5432", "db2:6543", "[::1]:5432"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestFailoverConnector_Connect(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("skips hosts that are down", func(t *testing.T) {
+		c, fakes := newFakeFailover(false, 3)
+		fakes[0].down.Store(true)
+
+		conn, err := c.Connect(ctx)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if host := conn.(*hostConn).host; host != 1 {
+			t.Errorf("expected a connection to host 1, got %d", host)
+		}
+	})
+
+	t.Run("skips read-only hosts for read-write sessions", func(t *testing.T) {
+		c, fakes := newFakeFailover(true, 2)
+		fakes[0].readOnly.Store(true)
+
+		conn, err := c.Connect(ctx)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if host := conn.(*hostConn).host; host != 1 {
+			t.Errorf("expected a connection to host 1, got %d", host)
+		}
+	})
+
+	t.Run("fails when every host is down", func(t *testing.T) {
+		c, fakes := newFakeFailover(false, 2)
+		fakes[0].down.Store(true)
+		fakes[1].down.Store(true)
+
+		if _, err := c.Connect(ctx); err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+
+	t.Run("invalidates connections to the previous host", func(t *testing.T) {
+		c, fakes := newFakeFailover(false, 2)
+		first, _ := c.Connect(ctx)
+
+		fakes[0].down.Store(true)
+		second, err := c.Connect(ctx)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if first.(driver.Validator).IsValid() {
+			t.Error("expected the connection to the old host to be invalid")
+		}
+		if !second.(driver.Validator).IsValid() {
+			t.Error("expected the connection to the new host to be valid")
+		}
+
+		// Stays on the new host when the old one comes back
+		fakes[0].down.Store(false)
+		third, _ := c.Connect(ctx)
+		if host := third.(*hostConn).host; host != 1 {
+			t.Errorf("expected to stay on host 1, got %d", host)
+		}
+	})
+}
+
+func TestFailoverConnector_CheckHosts(t *testing.T) {
+	ctx := context.Background()
+	c, fakes := newFakeFailover(true, 3)
+
+	c.checkHosts(ctx)
+	if c.current.Load() != 0 {
+		t.Fatalf("expected to stay on host 0, got %d", c.current.Load())
+	}
+
+	// The primary is demoted and host 2 promoted
+	fakes[0].readOnly.Store(true)
+	fakes[1].readOnly.Store(true)
+	c.checkHosts(ctx)
+	if c.current.Load() != 2 {
+		t.Errorf("expected a switch to host 2, got %d", c.current.Load())
+	}
+}
+
+func TestFailoverConnector_CloseStopsHealthChecks(t *testing.T) {
+	c, fakes := newFakeFailover(false, 2)
+	c.startHealthChecks(time.Millisecond, time.Second)
+	fakes[0].down.Store(true)
+
+	deadline := time.Now().Add(time.Second)
+	for c.current.Load() != 1 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if c.current.Load() != 1 {
+		t.Fatal("expected the health check to switch to host 1")
+	}
+
+	db := sql.OpenDB(c)
+	db.Close()
+	select {
+	case <-c.done:
+	default:
+		t.Error("expected closing the database to stop the health checks")
+	}
+}
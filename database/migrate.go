@@ -2,35 +2,71 @@ package database
 
 import (
 	"database/sql"
+	"embed"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"log"
+	"time"
 
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
 )
 
-// RunMigrations runs database migrations
-func RunMigrations(db *sql.DB) error {
-	// Create the postgres driver for migrate
+// migrationsFS embeds the migrations in the binary, so they run regardless
+// of the working directory.
+//
+//go:embed migrations/*.sql
+var migrationsFS embed.FS
+
+// newMigrate returns a migrator applying the embedded migrations to db.
+func newMigrate(db *sql.DB) (*migrate.Migrate, error) {
 	driver, err := postgres.WithInstance(db, &postgres.Config{})
 	if err != nil {
-		return fmt.Errorf("error creating postgres driver: %v", err)
+		return nil, fmt.Errorf("error creating postgres driver: %v", err)
+	}
+
+	source, err := iofs.New(migrationsFS, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("error reading migrations: %v", err)
+	}
+
+	m, err := migrate.NewWithInstance("iofs", source, "postgres", driver)
+	if err != nil {
+		return nil, fmt.Errorf("error initializing migrations: %v", err)
 	}
+	return m, nil
+}
+
+// MigrationLockTimeout is how long a migrator waits by default for the
+// advisory lock held by another one, such as an instance starting at the
+// same time.
+const MigrationLockTimeout = 15 * time.Second
 
-	// Create the migration instance
-	m, err := migrate.NewWithDatabaseInstance(
-		"file://database/migrations",
-		"postgres",
-		driver,
-	)
+// RunMigrations runs database migrations
+func RunMigrations(db *sql.DB) error {
+	return MigrateUp(db, MigrationLockTimeout)
+}
+
+// MigrateUp runs the pending migrations, waiting up to lockTimeout for
+// other migrators to finish. A migration failing part way leaves the
+// database dirty; the error then says how to repair it.
+func MigrateUp(db *sql.DB, lockTimeout time.Duration) error {
+	m, err := newMigrate(db)
 	if err != nil {
-		return fmt.Errorf("error initializing migrations: %v", err)
+		return err
 	}
+	m.LockTimeout = lockTimeout
 
 	// Run migrations
 	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
-		return fmt.Errorf("error running migrations: %v", err)
+		if version, dirty, verr := m.Version(); verr == nil && dirty && !errors.As(err, new(migrate.ErrDirty)) {
+			err = fmt.Errorf("%w\n%s", err, DirtyRecovery(int(version)))
+		}
+		return fmt.Errorf("error running migrations: %w", explainMigrateError(err))
 	}
 
 	version, dirty, err := m.Version()
@@ -47,24 +83,17 @@ func RunMigrations(db *sql.DB) error {
 	return nil
 }
 
-// RollbackMigration rolls back the last migration
-func RollbackMigration(db *sql.DB) error {
-	driver, err := postgres.WithInstance(db, &postgres.Config{})
-	if err != nil {
-		return fmt.Errorf("error creating postgres driver: %v", err)
-	}
-
-	m, err := migrate.NewWithDatabaseInstance(
-		"file://database/migrations",
-		"postgres",
-		driver,
-	)
+// RollbackMigration rolls back the last migration, waiting up to
+// lockTimeout for other migrators to finish.
+func RollbackMigration(db *sql.DB, lockTimeout time.Duration) error {
+	m, err := newMigrate(db)
 	if err != nil {
-		return fmt.Errorf("error initializing migrations: %v", err)
+		return err
 	}
+	m.LockTimeout = lockTimeout
 
-	if err := m.Down(); err != nil && err != migrate.ErrNoChange {
-		return fmt.Errorf("error rolling back migrations: %v", err)
+	if err := m.Steps(-1); err != nil && !errors.Is(err, migrate.ErrNoChange) && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("error rolling back migrations: %w", explainMigrateError(err))
 	}
 
 	log.Println("✅ Rollback completed successfully")
@@ -73,18 +102,9 @@ func RollbackMigration(db *sql.DB) error {
 
 // GetMigrationVersion returns the current migration version
 func GetMigrationVersion(db *sql.DB) (uint, bool, error) {
-	driver, err := postgres.WithInstance(db, &postgres.Config{})
-	if err != nil {
-		return 0, false, fmt.Errorf("error creating postgres driver: %v", err)
-	}
-
-	m, err := migrate.NewWithDatabaseInstance(
-		"file://database/migrations",
-		"postgres",
-		driver,
-	)
+	m, err := newMigrate(db)
 	if err != nil {
-		return 0, false, fmt.Errorf("error initializing migrations: %v", err)
+		return 0, false, err
 	}
 
 	version, dirty, err := m.Version()
@@ -94,3 +114,154 @@ func GetMigrationVersion(db *sql.DB) (uint, bool, error) {
 
 	return version, dirty, nil
 }
+
+// Migration is one of the embedded migrations, in one direction.
+type Migration struct {
+	Version uint
+	Name    string // such as "create_sessions"
+	SQL     string
+}
+
+// MigrationStatus is the state of the schema of a database.
+type MigrationStatus struct {
+	Version uint // 0 before the first migration
+	// Dirty is set when migration Version failed part way; nothing runs
+	// until the schema is repaired and the version forced
+	Dirty   bool
+	Pending []Migration // up migrations not applied yet, in order
+}
+
+// GetMigrationStatus returns the version of db and the migrations Up would
+// apply to it.
+func GetMigrationStatus(db *sql.DB) (MigrationStatus, error) {
+	m, err := newMigrate(db)
+	if err != nil {
+		return MigrationStatus{}, err
+	}
+	var status MigrationStatus
+	status.Version, status.Dirty, err = m.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return MigrationStatus{}, fmt.Errorf("error getting migration version: %v", err)
+	}
+
+	src, err := iofs.New(migrationsFS, "migrations")
+	if err != nil {
+		return MigrationStatus{}, fmt.Errorf("error reading migrations: %v", err)
+	}
+	defer src.Close()
+	if status.Pending, err = pendingMigrations(src, status.Version); err != nil {
+		return MigrationStatus{}, err
+	}
+	return status, nil
+}
+
+// LatestMigrationVersion returns the version of the last embedded
+// migration, the one of a database fully migrated.
+func LatestMigrationVersion() (uint, error) {
+	src, err := iofs.New(migrationsFS, "migrations")
+	if err != nil {
+		return 0, fmt.Errorf("error reading migrations: %v", err)
+	}
+	defer src.Close()
+	migrations, err := pendingMigrations(src, 0)
+	if err != nil || len(migrations) == 0 {
+		return 0, err
+	}
+	return migrations[len(migrations)-1].Version, nil
+}
+
+// RollbackPlan returns the down migration RollbackMigration would run on a
+// database at version, which must not be 0.
+func RollbackPlan(version uint) (Migration, error) {
+	src, err := iofs.New(migrationsFS, "migrations")
+	if err != nil {
+		return Migration{}, fmt.Errorf("error reading migrations: %v", err)
+	}
+	defer src.Close()
+	return readMigration(src.ReadDown, version)
+}
+
+// ForceMigrationVersion records version as applied and clears the dirty
+// flag, without running anything, once a failed migration was repaired by
+// hand. -1 records that no migration is applied.
+func ForceMigrationVersion(db *sql.DB, version int, lockTimeout time.Duration) error {
+	m, err := newMigrate(db)
+	if err != nil {
+		return err
+	}
+	m.LockTimeout = lockTimeout
+
+	if err := m.Force(version); err != nil {
+		return fmt.Errorf("error forcing migration version: %w", explainMigrateError(err))
+	}
+	log.Printf("✅ Migration version forced to %d\n", version)
+	return nil
+}
+
+// pendingMigrations reads the up migrations of src after version, all of
+// them for version 0.
+func pendingMigrations(src source.Driver, version uint) ([]Migration, error) {
+	var next uint
+	var err error
+	if version == 0 {
+		next, err = src.First()
+	} else {
+		next, err = src.Next(version)
+	}
+
+	var pending []Migration
+	for ; err == nil; next, err = src.Next(next) {
+		migration, err := readMigration(src.ReadUp, next)
+		if err != nil {
+			return nil, err
+		}
+		pending = append(pending, migration)
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("error reading migrations: %v", err)
+	}
+	return pending, nil
+}
+
+func readMigration(read func(uint) (io.ReadCloser, string, error), version uint) (Migration, error) {
+	r, name, err := read(version)
+	if err != nil {
+		return Migration{}, fmt.Errorf("error reading migration %d: %w", version, err)
+	}
+	defer r.Close()
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return Migration{}, fmt.Errorf("error reading migration %d: %w", version, err)
+	}
+	return Migration{Version: version, Name: name, SQL: string(body)}, nil
+}
+
+// explainMigrateError tells operators how to get out of the states
+// golang-migrate refuses to migrate from.
+func explainMigrateError(err error) error {
+	var dirty migrate.ErrDirty
+	switch {
+	case errors.As(err, &dirty):
+		return fmt.Errorf("%w\n%s", err, DirtyRecovery(dirty.Version))
+	case errors.Is(err, migrate.ErrLockTimeout):
+		return fmt.Errorf("%w: another migrator (a starting instance or a migrate command) holds the migration lock; try again once it is done", err)
+	}
+	return err
+}
+
+// DirtyRecovery explains how to repair a database left dirty by migration
+// version failing part way.
+func DirtyRecovery(version int) string {
+	previous := "none"
+	if src, err := iofs.New(migrationsFS, "migrations"); err == nil {
+		if prev, err := src.Prev(uint(version)); err == nil {
+			previous = fmt.Sprint(prev)
+		}
+		src.Close()
+	}
+	return fmt.Sprintf(`Migration %d failed part way and nothing will migrate until it is repaired:
+  1. Compare its statements (database/migrations/%06d_*.up.sql) with the schema to see which ran.
+  2. Either finish the migration by hand and run: sandbox-api migrate force %d
+     or undo what it changed and run:              sandbox-api migrate force %s
+  3. Run sandbox-api migrate up, or restart the API.`, version, version, version, previous)
+}
@@ -0,0 +1,94 @@
+package database
+
+import (
+	"io"
+	"io/fs"
+	"strings"
+	"testing"
+
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+func TestEmbeddedMigrations(t *testing.T) {
+	source, err := iofs.New(migrationsFS, "migrations")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer source.Close()
+
+	version, err := source.First()
+	if err != nil {
+		t.Fatalf("no migrations embedded: %v", err)
+	}
+	count := 0
+	for {
+		count++
+		if _, _, err := source.ReadUp(version); err != nil {
+			t.Errorf("migration %d has no up file: %v", version, err)
+		}
+		if _, _, err := source.ReadDown(version); err != nil {
+			t.Errorf("migration %d has no down file: %v", version, err)
+		}
+		next, err := source.Next(version)
+		if err != nil {
+			break
+		}
+		version = next
+	}
+
+	files, _ := fs.Glob(migrationsFS, "migrations/*.up.sql")
+	if count != len(files) {
+		t.Errorf("expected %d migrations, found %d", len(files), count)
+	}
+	if latest, err := LatestMigrationVersion(); err != nil || latest != version {
+		t.Errorf("expected latest migration version %d, got %d (%v)", version, latest, err)
+	}
+}
+
+func TestPendingMigrations(t *testing.T) {
+	source, err := iofs.New(migrationsFS, "migrations")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer source.Close()
+
+	files, _ := fs.Glob(migrationsFS, "migrations/*.up.sql")
+	all, err := pendingMigrations(source, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(all) != len(files) || all[0].Version != 1 {
+		t.Fatalf("expected every migration pending on an empty database, got %d", len(all))
+	}
+
+	last := all[len(all)-1]
+	pending, err := pendingMigrations(source, all[len(all)-2].Version)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	up, _, err := source.ReadUp(last.Version)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer up.Close()
+	sql, _ := io.ReadAll(up)
+	if len(pending) != 1 || pending[0].Version != last.Version || pending[0].SQL != string(sql) {
+		t.Errorf("expected only migration %d with its SQL pending, got %+v", last.Version, pending)
+	}
+
+	if pending, _ := pendingMigrations(source, last.Version); len(pending) != 0 {
+		t.Errorf("expected nothing pending at the last version, got %d", len(pending))
+	}
+}
+
+func TestDirtyRecovery(t *testing.T) {
+	guidance := DirtyRecovery(25)
+	for _, want := range []string{"000025_*.up.sql", "migrate force 25", "migrate force 24"} {
+		if !strings.Contains(guidance, want) {
+			t.Errorf("expected %q in:\n%s", want, guidance)
+		}
+	}
+	if guidance := DirtyRecovery(1); !strings.Contains(guidance, "migrate force none") {
+		t.Errorf("expected the first migration to be undone with force none, got:\n%s", guidance)
+	}
+}
@@ -0,0 +1,2 @@
+-- Index case-insensitive username prefix searches (LIKE 'abc%')
+CREATE INDEX idx_users_username_lower ON users (lower(username) text_pattern_ops);
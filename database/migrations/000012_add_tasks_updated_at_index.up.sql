@@ -0,0 +1,2 @@
+-- Index modification time for incremental sync (GET /tasks?updatedSince=)
+CREATE INDEX idx_tasks_updated_at ON tasks(updated_at);
@@ -0,0 +1,2 @@
+CREATE INDEX idx_tasks_updated_at ON tasks(updated_at);
+DROP INDEX IF EXISTS idx_tasks_updated_at_id;
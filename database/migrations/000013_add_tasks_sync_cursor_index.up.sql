@@ -0,0 +1,3 @@
+-- Keyset pagination for GET /tasks/changes walks (updated_at, id)
+CREATE INDEX idx_tasks_updated_at_id ON tasks(updated_at, id);
+DROP INDEX IF EXISTS idx_tasks_updated_at;
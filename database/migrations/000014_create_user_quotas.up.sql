@@ -0,0 +1,7 @@
+-- Per-user quota overrides; NULL limits use the configured defaults
+CREATE TABLE user_quotas (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    max_tasks INTEGER CHECK (max_tasks >= 0),
+    max_media_bytes BIGINT CHECK (max_media_bytes >= 0),
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,17 @@
+DROP INDEX IF EXISTS idx_audit_logs_tenant_id;
+DROP INDEX IF EXISTS idx_tasks_tenant_id;
+
+ALTER TABLE tasks DROP CONSTRAINT IF EXISTS tasks_tenant_id_column_id_fkey;
+ALTER TABLE columns DROP CONSTRAINT IF EXISTS columns_tenant_id_id_key;
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_tenant_id_email_key;
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_tenant_id_username_key;
+ALTER TABLE users ADD CONSTRAINT users_username_key UNIQUE (username);
+ALTER TABLE users ADD CONSTRAINT users_email_key UNIQUE (email);
+
+ALTER TABLE audit_logs DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE tasks DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE columns DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE users DROP COLUMN IF EXISTS tenant_id;
+
+DROP TABLE IF EXISTS tenants;
@@ -0,0 +1,36 @@
+-- Tenants isolate users, boards and audit logs when the API is shared
+CREATE TABLE tenants (
+    id SERIAL PRIMARY KEY,
+    slug VARCHAR(63) UNIQUE NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Existing data belongs to the default tenant (id 1)
+INSERT INTO tenants (id, slug, name) VALUES (1, 'default', 'Default');
+SELECT setval('tenants_id_seq', (SELECT MAX(id) FROM tenants));
+
+ALTER TABLE users ADD COLUMN tenant_id INTEGER NOT NULL DEFAULT 1 REFERENCES tenants(id) ON DELETE CASCADE;
+ALTER TABLE columns ADD COLUMN tenant_id INTEGER NOT NULL DEFAULT 1 REFERENCES tenants(id) ON DELETE CASCADE;
+ALTER TABLE tasks ADD COLUMN tenant_id INTEGER NOT NULL DEFAULT 1 REFERENCES tenants(id) ON DELETE CASCADE;
+ALTER TABLE audit_logs ADD COLUMN tenant_id INTEGER NOT NULL DEFAULT 1 REFERENCES tenants(id) ON DELETE CASCADE;
+
+-- Inserts must name their tenant from now on
+ALTER TABLE users ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE columns ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE tasks ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE audit_logs ALTER COLUMN tenant_id DROP DEFAULT;
+
+-- Usernames and emails are unique per tenant
+ALTER TABLE users DROP CONSTRAINT users_username_key;
+ALTER TABLE users DROP CONSTRAINT users_email_key;
+ALTER TABLE users ADD CONSTRAINT users_tenant_id_username_key UNIQUE (tenant_id, username);
+ALTER TABLE users ADD CONSTRAINT users_tenant_id_email_key UNIQUE (tenant_id, email);
+
+-- A task can only sit in a column of its own tenant
+ALTER TABLE columns ADD CONSTRAINT columns_tenant_id_id_key UNIQUE (tenant_id, id);
+ALTER TABLE tasks ADD CONSTRAINT tasks_tenant_id_column_id_fkey
+    FOREIGN KEY (tenant_id, column_id) REFERENCES columns(tenant_id, id);
+
+CREATE INDEX idx_tasks_tenant_id ON tasks(tenant_id);
+CREATE INDEX idx_audit_logs_tenant_id ON audit_logs(tenant_id);
@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS tasks_touch_task_list_modified ON tasks;
+DROP FUNCTION IF EXISTS touch_task_list_modified();
+DROP TABLE IF EXISTS task_list_modified;
@@ -0,0 +1,22 @@
+-- Last change to each tenant's task list, for conditional GET /tasks.
+-- Kept by a trigger so deletions count too, which max(updated_at) misses.
+CREATE TABLE task_list_modified (
+    tenant_id INTEGER PRIMARY KEY REFERENCES tenants(id) ON DELETE CASCADE,
+    modified_at TIMESTAMPTZ NOT NULL
+);
+
+INSERT INTO task_list_modified (tenant_id, modified_at)
+SELECT tenant_id, COALESCE(MAX(updated_at), CURRENT_TIMESTAMP) FROM tasks GROUP BY tenant_id;
+
+CREATE FUNCTION touch_task_list_modified() RETURNS trigger AS $$
+BEGIN
+    INSERT INTO task_list_modified (tenant_id, modified_at)
+    VALUES (COALESCE(NEW.tenant_id, OLD.tenant_id), clock_timestamp())
+    ON CONFLICT (tenant_id) DO UPDATE SET modified_at = EXCLUDED.modified_at;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER tasks_touch_task_list_modified
+    AFTER INSERT OR UPDATE OR DELETE ON tasks
+    FOR EACH ROW EXECUTE FUNCTION touch_task_list_modified();
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_tasks_tenant_id_completed_at;
+ALTER TABLE tasks ALTER COLUMN completed DROP NOT NULL;
+ALTER TABLE tasks DROP COLUMN IF EXISTS completed_at;
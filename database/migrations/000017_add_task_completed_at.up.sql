@@ -0,0 +1,10 @@
+-- When a task was completed, for completion reporting over time.
+ALTER TABLE tasks ADD COLUMN completed_at TIMESTAMP;
+
+UPDATE tasks SET completed = FALSE WHERE completed IS NULL;
+ALTER TABLE tasks ALTER COLUMN completed SET NOT NULL;
+
+-- Tasks completed before this column existed last changed when completed, at best
+UPDATE tasks SET completed_at = updated_at WHERE completed;
+
+CREATE INDEX idx_tasks_tenant_id_completed_at ON tasks(tenant_id, completed_at) WHERE completed_at IS NOT NULL;
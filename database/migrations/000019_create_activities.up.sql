@@ -0,0 +1,15 @@
+-- Activity feed: one row per event and user who sees it
+CREATE TABLE activities (
+    id SERIAL PRIMARY KEY,
+    tenant_id INTEGER NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    actor_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    type VARCHAR(30) NOT NULL,
+    -- The title is kept so the entry still reads right once the task is gone
+    task_id INTEGER REFERENCES tasks(id) ON DELETE SET NULL,
+    task_title VARCHAR(200) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- GET /activity walks a user's feed newest first
+CREATE INDEX idx_activities_user_id_created_at_id ON activities(user_id, created_at DESC, id DESC);
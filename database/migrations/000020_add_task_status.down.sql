@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_tasks_tenant_id_status;
+ALTER TABLE tasks DROP CONSTRAINT IF EXISTS tasks_completed_status_check;
+ALTER TABLE tasks DROP COLUMN IF EXISTS status;
@@ -0,0 +1,9 @@
+-- Board state of a task; completed is kept as status = 'done' for existing clients
+ALTER TABLE tasks ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'todo'
+    CHECK (status IN ('todo', 'in_progress', 'done', 'blocked'));
+
+UPDATE tasks SET status = 'done' WHERE completed;
+
+ALTER TABLE tasks ADD CONSTRAINT tasks_completed_status_check CHECK (completed = (status = 'done'));
+
+CREATE INDEX idx_tasks_tenant_id_status ON tasks(tenant_id, status);
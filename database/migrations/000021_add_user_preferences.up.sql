@@ -0,0 +1,2 @@
+-- Profile preferences (timezone, locale, task sort, notifications); missing keys take their default
+ALTER TABLE users ADD COLUMN preferences JSONB NOT NULL DEFAULT '{}';
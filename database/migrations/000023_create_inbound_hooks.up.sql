@@ -0,0 +1,9 @@
+-- Per-user webhook creating tasks from inbound payloads (Zapier, IFTTT,
+-- email-to-task gateways). Only a hash of the secret is kept.
+CREATE TABLE inbound_hooks (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    secret_hash VARCHAR(64) NOT NULL UNIQUE,
+    mapping JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,10 @@
+-- Slack users linked to an account, so their slash commands act as it.
+CREATE TABLE slack_links (
+    team_id VARCHAR(32) NOT NULL,
+    slack_user_id VARCHAR(32) NOT NULL,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (team_id, slack_user_id)
+);
+
+CREATE INDEX idx_slack_links_user_id ON slack_links(user_id);
@@ -0,0 +1,17 @@
+-- Sessions opened by logins, by the hash of their token. Rows outlive their
+-- token so that the devices (IP address and user agent) a user logs in from
+-- are recognized.
+CREATE TABLE sessions (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    -- Hash of the token of the "this wasn't me" link of a login alert
+    revoke_token_hash VARCHAR(64) UNIQUE,
+    ip_address VARCHAR(45) NOT NULL,
+    user_agent TEXT NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_sessions_user_id ON sessions(user_id);
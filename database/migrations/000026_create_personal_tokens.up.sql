@@ -0,0 +1,16 @@
+-- Personal access tokens users create for scripts and integrations, by the
+-- hash of their secret. Revoking a token deletes its row.
+CREATE TABLE personal_tokens (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    -- First characters of the token, shown to recognize it
+    prefix VARCHAR(16) NOT NULL,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    scopes TEXT[] NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    last_used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_personal_tokens_user_id ON personal_tokens(user_id);
@@ -0,0 +1 @@
+ALTER TABLE personal_tokens DROP COLUMN IF EXISTS sandbox;
@@ -0,0 +1,3 @@
+-- Sandbox settings of a personal token: the delay and failures simulated on
+-- the requests made with it; '{}' simulates nothing
+ALTER TABLE personal_tokens ADD COLUMN sandbox JSONB NOT NULL DEFAULT '{}';
@@ -0,0 +1,3 @@
+ALTER TABLE user_quotas DROP COLUMN IF EXISTS max_daily_bytes;
+ALTER TABLE user_quotas DROP COLUMN IF EXISTS max_daily_requests;
+DROP TABLE IF EXISTS api_usage;
@@ -0,0 +1,16 @@
+-- Requests and bytes transferred per user, key and UTC day. token_id is the
+-- personal token the requests were made with, 0 for login sessions; rows
+-- outlive revoked tokens.
+CREATE TABLE api_usage (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_id INTEGER NOT NULL DEFAULT 0,
+    day DATE NOT NULL,
+    requests BIGINT NOT NULL DEFAULT 0,
+    bytes_in BIGINT NOT NULL DEFAULT 0,
+    bytes_out BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (user_id, day, token_id)
+);
+
+-- Daily request and transfer quotas, overriding the configured defaults
+ALTER TABLE user_quotas ADD COLUMN max_daily_requests BIGINT CHECK (max_daily_requests >= 0);
+ALTER TABLE user_quotas ADD COLUMN max_daily_bytes BIGINT CHECK (max_daily_bytes >= 0);
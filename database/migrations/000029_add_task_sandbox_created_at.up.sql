@@ -0,0 +1,4 @@
+-- When a task was created on the sandbox clock of its creator, when it was
+-- moved. created_at and updated_at stay on the real time: the change feed
+-- pages on them across the tenant.
+ALTER TABLE tasks ADD COLUMN sandbox_created_at TIMESTAMP;
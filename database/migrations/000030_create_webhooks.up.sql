@@ -0,0 +1,12 @@
+-- Outgoing webhooks users register to receive the events of their tasks.
+-- The signing secret is read back to sign each delivery, so it is stored
+-- sealed with the keys of ENCRYPTION_KEYS rather than hashed.
+CREATE TABLE webhooks (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    url VARCHAR(2048) NOT NULL,
+    secret TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_webhooks_user_id ON webhooks(user_id);
@@ -3,7 +3,10 @@ package database
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+
+	"github.com/lib/pq"
 )
 
 // Querier is the common interface for *sql.DB and *sql.Tx.
@@ -20,16 +23,34 @@ type Transactor interface {
 
 // TxManager implements Transactor using a real *sql.DB.
 type TxManager struct {
-	db *sql.DB
+	db    *sql.DB
+	retry RetryPolicy
 }
 
-// NewTxManager creates a new TxManager.
+// NewTxManager creates a new TxManager retrying transactions with
+// DefaultRetryPolicy.
 func NewTxManager(db *sql.DB) *TxManager {
-	return &TxManager{db: db}
+	return &TxManager{db: db, retry: DefaultRetryPolicy}
+}
+
+// WithRetryPolicy sets how transactions failing with a transient error are
+// retried.
+func (tm *TxManager) WithRetryPolicy(policy RetryPolicy) *TxManager {
+	tm.retry = policy
+	return tm
 }
 
-// WithTransaction executes fn within a database transaction.
+// WithTransaction executes fn within a database transaction. A transaction
+// failing with a transient error (serialization failure, deadlock, lost
+// connection) is rolled back and run again, fn included, so fn must not have
+// side effects outside the transaction.
 func (tm *TxManager) WithTransaction(ctx context.Context, fn func(q Querier) error) error {
+	return Retry(ctx, tm.retry, "transaction", func() error {
+		return tm.run(ctx, fn)
+	})
+}
+
+func (tm *TxManager) run(ctx context.Context, fn func(q Querier) error) error {
 	tx, err := tm.db.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("begin transaction: %w", err)
@@ -43,7 +64,13 @@ func (tm *TxManager) WithTransaction(ctx context.Context, fn func(q Querier) err
 	}
 
 	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("commit transaction: %w", err)
+		err = fmt.Errorf("commit transaction: %w", err)
+		// Without an answer from the server the commit may have been applied
+		var pqErr *pq.Error
+		if !errors.As(err, &pqErr) {
+			return permanentError{err}
+		}
+		return err
 	}
 	return nil
 }
@@ -0,0 +1,104 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"math/rand/v2"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/metrics"
+
+	"github.com/lib/pq"
+)
+
+// RetryPolicy bounds how often and how quickly a failed operation is retried.
+type RetryPolicy struct {
+	MaxAttempts int           // total attempts, the first included; 1 disables retries
+	BaseDelay   time.Duration // backoff before the second attempt, doubled after each one
+	MaxDelay    time.Duration // cap on a single backoff
+}
+
+// DefaultRetryPolicy makes up to 3 attempts, backing off from 50ms up to 1s.
+var DefaultRetryPolicy = RetryPolicy{MaxAttempts: 3, BaseDelay: 50 * time.Millisecond, MaxDelay: time.Second}
+
+// transientCodes are the PostgreSQL error codes after which the statement
+// or transaction was rolled back and can be run again.
+var transientCodes = map[pq.ErrorCode]bool{
+	"40001": true, // serialization_failure
+	"40P01": true, // deadlock_detected
+	"53300": true, // too_many_connections
+	"57P01": true, // admin_shutdown
+	"57P02": true, // crash_shutdown
+	"57P03": true, // cannot_connect_now
+}
+
+// IsTransient reports whether err is a failure that may succeed when
+// retried: a serialization failure or deadlock, a server restarting or
+// failing over, or a dropped connection.
+func IsTransient(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return transientCodes[pqErr.Code] || pqErr.Code.Class() == "08" // connection_exception
+	}
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr)
+}
+
+// permanentError marks an error Retry must return without retrying, even if
+// its cause is transient.
+type permanentError struct{ err error }
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Retry runs fn until it succeeds, fails with an error that is not
+// transient, or the policy's attempts run out, sleeping with jittered
+// exponential backoff in between. operation labels the retry metrics.
+func Retry(ctx context.Context, policy RetryPolicy, operation string, fn func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		var permanent permanentError
+		if errors.As(err, &permanent) {
+			return permanent.err
+		}
+		if err == nil || !IsTransient(err) {
+			return err
+		}
+		if attempt >= policy.MaxAttempts {
+			metrics.FromContext(ctx).RecordDatabaseRetryExhausted(operation)
+			return err
+		}
+
+		metrics.FromContext(ctx).RecordDatabaseRetry(operation)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(policy.backoff(attempt)):
+		}
+	}
+}
+
+// backoff returns a random delay up to BaseDelay doubled attempt-1 times,
+// capped at MaxDelay.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.BaseDelay
+	for i := 1; i < attempt && d < p.MaxDelay; i++ {
+		d *= 2
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	return rand.N(d) + 1
+}
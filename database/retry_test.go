@@ -0,0 +1,114 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"serialization failure", &pq.Error{Code: "40001"}, true},
+		{"deadlock", fmt.Errorf("update: %w", &pq.Error{Code: "40P01"}), true},
+		{"connection failure", &pq.Error{Code: "08006"}, true},
+		{"server shutting down", &pq.Error{Code: "57P01"}, true},
+		{"unique violation", &pq.Error{Code: "23505"}, false},
+		{"bad connection", driver.ErrBadConn, true},
+		{"no rows", sql.ErrNoRows, false},
+		{"canceled", context.Canceled, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsTransient(tt.err); got != tt.want {
+				t.Errorf("IsTransient(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetry(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 2 * time.Millisecond}
+	serialization := &pq.Error{Code: "40001"}
+
+	t.Run("retries transient errors until success", func(t *testing.T) {
+		calls := 0
+		err := Retry(context.Background(), policy, "test", func() error {
+			calls++
+			if calls < 3 {
+				return serialization
+			}
+			return nil
+		})
+		if err != nil || calls != 3 {
+			t.Errorf("got err %v after %d calls, want success after 3", err, calls)
+		}
+	})
+
+	t.Run("gives up after the last attempt", func(t *testing.T) {
+		calls := 0
+		err := Retry(context.Background(), policy, "test", func() error {
+			calls++
+			return serialization
+		})
+		if !errors.Is(err, serialization) || calls != 3 {
+			t.Errorf("got err %v after %d calls, want the serialization failure after 3", err, calls)
+		}
+	})
+
+	t.Run("returns other errors at once", func(t *testing.T) {
+		calls := 0
+		err := Retry(context.Background(), policy, "test", func() error {
+			calls++
+			return sql.ErrNoRows
+		})
+		if err != sql.ErrNoRows || calls != 1 {
+			t.Errorf("got err %v after %d calls, want sql.ErrNoRows after 1", err, calls)
+		}
+	})
+
+	t.Run("does not retry permanent errors", func(t *testing.T) {
+		calls := 0
+		err := Retry(context.Background(), policy, "test", func() error {
+			calls++
+			return permanentError{driver.ErrBadConn}
+		})
+		if err != driver.ErrBadConn || calls != 1 {
+			t.Errorf("got err %v after %d calls, want driver.ErrBadConn after 1", err, calls)
+		}
+	})
+
+	t.Run("stops when the context is done", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		calls := 0
+		err := Retry(ctx, RetryPolicy{MaxAttempts: 5, BaseDelay: time.Hour, MaxDelay: time.Hour}, "test", func() error {
+			calls++
+			cancel()
+			return serialization
+		})
+		if !errors.Is(err, serialization) || calls != 1 {
+			t.Errorf("got err %v after %d calls, want the serialization failure after 1", err, calls)
+		}
+	})
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	p := RetryPolicy{BaseDelay: 10 * time.Millisecond, MaxDelay: 50 * time.Millisecond}
+	for attempt, limit := range map[int]time.Duration{1: 10 * time.Millisecond, 3: 40 * time.Millisecond, 10: 50 * time.Millisecond, 80: 50 * time.Millisecond} {
+		for range 20 {
+			if d := p.backoff(attempt); d <= 0 || d > limit {
+				t.Fatalf("backoff(%d) = %v, want in (0, %v]", attempt, d, limit)
+			}
+		}
+	}
+}
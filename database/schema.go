@@ -0,0 +1,73 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// SchemaDriftError reports the tables and columns expected by CheckSchema
+// that the database lacks.
+type SchemaDriftError struct {
+	Missing []string // "table users" or "column users.email", sorted
+}
+
+func (e *SchemaDriftError) Error() string {
+	return fmt.Sprintf("database schema drifted from the migrations, missing %d:\n  %s\n"+
+		"Check `sandbox-api migrate status` and the changes made to the schema by hand",
+		len(e.Missing), strings.Join(e.Missing, "\n  "))
+}
+
+// CheckSchema looks up the tables and columns of the current schema in
+// information_schema and returns a *SchemaDriftError listing those of
+// expected, columns by table, that are missing.
+func CheckSchema(ctx context.Context, db Querier, expected map[string][]string) error {
+	rows, err := db.QueryContext(ctx, `
+		SELECT table_name, column_name FROM information_schema.columns
+		WHERE table_schema = current_schema()
+	`)
+	if err != nil {
+		return fmt.Errorf("error reading the database schema: %w", err)
+	}
+	defer rows.Close()
+
+	actual := make(map[string]map[string]bool)
+	for rows.Next() {
+		var table, column string
+		if err := rows.Scan(&table, &column); err != nil {
+			return fmt.Errorf("error reading the database schema: %w", err)
+		}
+		if actual[table] == nil {
+			actual[table] = make(map[string]bool)
+		}
+		actual[table][column] = true
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error reading the database schema: %w", err)
+	}
+
+	if missing := schemaDrift(expected, actual); len(missing) > 0 {
+		return &SchemaDriftError{Missing: missing}
+	}
+	return nil
+}
+
+// schemaDrift lists what expected has that actual lacks. A missing table
+// is reported once rather than column by column.
+func schemaDrift(expected map[string][]string, actual map[string]map[string]bool) []string {
+	var missing []string
+	for table, columns := range expected {
+		if actual[table] == nil {
+			missing = append(missing, "table "+table)
+			continue
+		}
+		for _, column := range columns {
+			if !actual[table][column] {
+				missing = append(missing, "column "+table+"."+column)
+			}
+		}
+	}
+	slices.Sort(missing)
+	return missing
+}
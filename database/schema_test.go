@@ -0,0 +1,39 @@
+package database
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSchemaDrift(t *testing.T) {
+	expected := map[string][]string{
+		"users":    {"id", "email", "preferences"},
+		"sessions": {"id", "token_hash"},
+		"tasks":    {"id"},
+	}
+	actual := map[string]map[string]bool{
+		"users": {"id": true, "email": true},
+		"tasks": {"id": true, "title": true},
+		"extra": {"id": true},
+	}
+
+	missing := schemaDrift(expected, actual)
+	want := []string{"column users.preferences", "table sessions"}
+	if !slices.Equal(missing, want) {
+		t.Errorf("expected %v missing, got %v", want, missing)
+	}
+
+	err := &SchemaDriftError{Missing: missing}
+	if msg := err.Error(); !strings.Contains(msg, "missing 2") || !strings.Contains(msg, "  table sessions") {
+		t.Errorf("unexpected report:\n%s", msg)
+	}
+
+	if missing := schemaDrift(expected, map[string]map[string]bool{
+		"users":    {"id": true, "email": true, "preferences": true},
+		"sessions": {"id": true, "token_hash": true},
+		"tasks":    {"id": true},
+	}); len(missing) != 0 {
+		t.Errorf("expected no drift, got %v", missing)
+	}
+}
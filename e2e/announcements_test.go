@@ -0,0 +1,51 @@
+package e2e
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/testsupport"
+)
+
+func TestAnnouncements_ActiveAndDismissed(t *testing.T) {
+	env := testsupport.NewEnv(t)
+	admin, _ := env.Admin()
+	user, _ := env.Register("heidi")
+
+	var current, scheduled models.Announcement
+	admin.Do(http.MethodPost, "/admin/announcements", models.AnnouncementRequest{
+		Title: "Maintenance tonight", Body: "The board is read-only from 22:00", Level: models.AnnouncementLevelMaintenance,
+	}).Expect(http.StatusCreated).Decode(&current)
+	later := time.Now().Add(24 * time.Hour)
+	admin.Do(http.MethodPost, "/admin/announcements", models.AnnouncementRequest{
+		Title: "Checklists", Body: "Tasks now have checklists", Level: models.AnnouncementLevelFeature, StartsAt: &later,
+	}).Expect(http.StatusCreated).Decode(&scheduled)
+
+	var active []models.Announcement
+	user.Do(http.MethodGet, "/announcements", nil).Expect(http.StatusOK).Decode(&active)
+	if len(active) != 1 || active[0].ID != current.ID {
+		t.Fatalf("expected only announcement %d, got %+v", current.ID, active)
+	}
+
+	user.Do(http.MethodPost, fmt.Sprintf("/announcements/%d/dismiss", current.ID), nil).Expect(http.StatusNoContent)
+	user.Do(http.MethodGet, "/announcements", nil).Expect(http.StatusOK).Decode(&active)
+	if len(active) != 0 {
+		t.Errorf("expected no announcement after dismissal, got %+v", active)
+	}
+
+	// Dismissal is per user
+	admin.Do(http.MethodGet, "/announcements", nil).Expect(http.StatusOK).Decode(&active)
+	if len(active) != 1 {
+		t.Errorf("expected the admin to still see the announcement, got %+v", active)
+	}
+
+	var all []models.Announcement
+	admin.Do(http.MethodGet, "/admin/announcements", nil).Expect(http.StatusOK).Decode(&all)
+	if len(all) != 2 {
+		t.Errorf("expected 2 announcements for the admin, got %d", len(all))
+	}
+	user.Do(http.MethodPost, "/admin/announcements", models.AnnouncementRequest{}).Expect(http.StatusForbidden)
+}
@@ -0,0 +1,90 @@
+// Package e2e exercises the API end to end through testsupport, on an
+// in-memory store, or on the PostgreSQL server of TEST_DATABASE_URL.
+package e2e
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/testsupport"
+)
+
+func TestAuth_RegisterLoginLogout(t *testing.T) {
+	env := testsupport.NewEnv(t)
+
+	_, user := env.Register("alice")
+	if user.ID == 0 || user.Role != models.RoleUser {
+		t.Fatalf("unexpected registered user: %+v", user)
+	}
+
+	client, _ := env.Login(user.Email, testsupport.DefaultPassword)
+	var claims models.Claims
+	client.Do(http.MethodGet, "/auth/user", nil).Expect(http.StatusOK).Decode(&claims)
+	if claims.UserID != user.ID {
+		t.Errorf("expected claims for user %d, got %d", user.ID, claims.UserID)
+	}
+
+	client.Do(http.MethodPost, "/auth/logout", nil).Expect(http.StatusOK)
+	client.Do(http.MethodGet, "/auth/user", nil).Expect(http.StatusUnauthorized)
+}
+
+func TestAuth_RejectsBadCredentials(t *testing.T) {
+	env := testsupport.NewEnv(t)
+	_, user := env.Register("bob")
+
+	resp := env.Client().Do(http.MethodPost, "/auth/login", models.LoginRequest{
+		Email:    user.Email,
+		Password: "wrong-password",
+	}).Expect(http.StatusUnauthorized)
+	if resp.ErrorCode() != string(errors.ErrInvalidCredentials) {
+		t.Errorf("expected %s, got %s", errors.ErrInvalidCredentials, resp.ErrorCode())
+	}
+}
+
+func TestAuth_DuplicateEmail(t *testing.T) {
+	env := testsupport.NewEnv(t)
+	_, user := env.Register("carol")
+
+	env.Client().Do(http.MethodPost, "/auth/register", models.RegisterRequest{
+		Username: "carolagain",
+		Email:    user.Email,
+		Password: testsupport.DefaultPassword,
+	}).Expect(http.StatusConflict)
+}
+
+func TestAdminRoutes_RequireAdminRole(t *testing.T) {
+	env := testsupport.NewEnv(t)
+
+	user, _ := env.Register("dave")
+	user.Do(http.MethodGet, "/admin/audit-logs", nil).Expect(http.StatusForbidden)
+
+	admin, _ := env.Admin()
+	admin.Do(http.MethodGet, "/admin/audit-logs", nil).Expect(http.StatusOK)
+}
+
+func TestUsers_MutationsRequireAdminRole(t *testing.T) {
+	env := testsupport.NewEnv(t)
+
+	user, self := env.Register("erin")
+	_, other := env.Register("frank")
+
+	// Email changes go through the verified /profile/email flow
+	for _, id := range []int{self.ID, other.ID} {
+		resp := user.Do(http.MethodPut, fmt.Sprintf("/users/%d", id), models.UpdateUserRequest{Email: "taken@example.com"})
+		resp.Expect(http.StatusForbidden)
+	}
+	user.Do(http.MethodPut, fmt.Sprintf("/users/%d", self.ID), models.UpdateUserRequest{Role: models.RoleAdmin}).Expect(http.StatusForbidden)
+	user.Do(http.MethodPatch, fmt.Sprintf("/users/%d/status", other.ID), models.UpdateUserStatusRequest{Status: "inactive"}).Expect(http.StatusForbidden)
+	user.Do(http.MethodDelete, fmt.Sprintf("/users/%d", other.ID), nil).Expect(http.StatusForbidden)
+
+	var unchanged models.User
+	admin, _ := env.Admin()
+	admin.Do(http.MethodGet, fmt.Sprintf("/users/%d", self.ID), nil).Expect(http.StatusOK).Decode(&unchanged)
+	if unchanged.Email != self.Email || unchanged.Role != models.RoleUser {
+		t.Errorf("expected the user unchanged, got %+v", unchanged)
+	}
+	admin.Do(http.MethodPut, fmt.Sprintf("/users/%d", other.ID), models.UpdateUserRequest{FirstName: "Frank"}).Expect(http.StatusOK)
+}
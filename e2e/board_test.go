@@ -0,0 +1,121 @@
+package e2e
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/testsupport"
+)
+
+func TestBoard_ColumnsAndTasks(t *testing.T) {
+	env := testsupport.NewEnv(t)
+	client, _ := env.Register("erin")
+
+	var todo, done models.Column
+	client.Do(http.MethodPost, "/columns", models.CreateColumnRequest{Title: "To do"}).Expect(http.StatusCreated).Decode(&todo)
+	client.Do(http.MethodPost, "/columns", models.CreateColumnRequest{Title: "Done"}).Expect(http.StatusCreated).Decode(&done)
+
+	var task models.Task
+	client.Do(http.MethodPost, "/tasks", models.CreateTaskRequest{
+		Title:    "Write <b>tests</b>",
+		ColumnID: todo.ID,
+	}).Expect(http.StatusCreated).Decode(&task)
+	if task.Title != "Write tests" {
+		t.Errorf("expected the title to be sanitized, got %q", task.Title)
+	}
+
+	client.Do(http.MethodPatch, fmt.Sprintf("/tasks/%d/move", task.ID), models.MoveTaskRequest{
+		ColumnID: done.ID,
+	}).Expect(http.StatusOK)
+
+	var board models.BoardResponse
+	client.Do(http.MethodGet, "/tasks/board", nil).Expect(http.StatusOK).Decode(&board)
+	// The tenant starts with a Backlog column
+	if len(board.Columns) != 3 || len(board.Tasks) != 1 {
+		t.Fatalf("expected 3 columns and 1 task, got %+v", board)
+	}
+	if board.Tasks[0].ColumnID != done.ID {
+		t.Errorf("expected the task in column %d, got %d", done.ID, board.Tasks[0].ColumnID)
+	}
+
+	client.Do(http.MethodDelete, fmt.Sprintf("/tasks/%d", task.ID), nil).Expect(http.StatusNoContent)
+	client.Do(http.MethodGet, fmt.Sprintf("/tasks/%d", task.ID), nil).Expect(http.StatusNotFound)
+}
+
+func TestBoard_MoveTaskAfterAnother(t *testing.T) {
+	env := testsupport.NewEnv(t)
+	client, _ := env.Register("frank")
+
+	var column models.Column
+	client.Do(http.MethodPost, "/columns", models.CreateColumnRequest{Title: "To do"}).Expect(http.StatusCreated).Decode(&column)
+
+	tasks := make([]models.Task, 3)
+	for i := range tasks {
+		client.Do(http.MethodPost, "/tasks", models.CreateTaskRequest{
+			Title:    fmt.Sprintf("Task %d", i),
+			ColumnID: column.ID,
+		}).Expect(http.StatusCreated).Decode(&tasks[i])
+	}
+
+	// Squeeze the orders together so the next move has to renumber the column
+	client.Do(http.MethodPatch, "/tasks/reorder", models.ReorderTasksRequest{
+		ColumnID: column.ID,
+		TaskIDs:  []int{tasks[0].ID, tasks[1].ID, tasks[2].ID},
+	}).Expect(http.StatusOK)
+	for i := range 11 {
+		client.Do(http.MethodPatch, fmt.Sprintf("/tasks/%d/move", tasks[2-i%2].ID), models.MoveTaskRequest{
+			ColumnID: column.ID,
+			AfterID:  &tasks[0].ID,
+		}).Expect(http.StatusOK)
+	}
+
+	var board models.BoardResponse
+	client.Do(http.MethodGet, "/tasks/board", nil).Expect(http.StatusOK).Decode(&board)
+	var got []int
+	for _, task := range board.Tasks {
+		got = append(got, task.ID)
+	}
+	// The last move put task 2 right after task 0
+	want := []int{tasks[0].ID, tasks[2].ID, tasks[1].ID}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("expected tasks in order %v, got %v", want, got)
+	}
+}
+
+func TestBoard_TaskStatusTransitions(t *testing.T) {
+	env := testsupport.NewEnv(t)
+	client, _ := env.Register("grace")
+
+	var column models.Column
+	client.Do(http.MethodPost, "/columns", models.CreateColumnRequest{Title: "To do"}).Expect(http.StatusCreated).Decode(&column)
+	var task models.Task
+	client.Do(http.MethodPost, "/tasks", models.CreateTaskRequest{Title: "Ship it", ColumnID: column.ID}).Expect(http.StatusCreated).Decode(&task)
+	if task.Status != models.TaskStatusTodo {
+		t.Fatalf("expected a new task to be todo, got %s", task.Status)
+	}
+
+	statusPath := fmt.Sprintf("/tasks/%d/status", task.ID)
+	client.Do(http.MethodPatch, statusPath, models.SetTaskStatusRequest{Status: models.TaskStatusBlocked}).Expect(http.StatusOK)
+	// A blocked task has to be unblocked first
+	client.Do(http.MethodPost, fmt.Sprintf("/tasks/%d/complete", task.ID), nil).Expect(http.StatusConflict)
+	client.Do(http.MethodPatch, statusPath, models.SetTaskStatusRequest{Status: models.TaskStatusInProgress}).Expect(http.StatusOK)
+	client.Do(http.MethodPatch, statusPath, models.SetTaskStatusRequest{Status: models.TaskStatusDone}).Expect(http.StatusOK).Decode(&task)
+	if !task.Completed || task.CompletedAt == nil {
+		t.Errorf("expected a done task to be completed, got %+v", task)
+	}
+
+	var done []models.Task
+	client.Do(http.MethodGet, "/tasks?status=done", nil).Expect(http.StatusOK).Decode(&done)
+	if len(done) != 1 || done[0].ID != task.ID {
+		t.Errorf("expected only task %d to be done, got %+v", task.ID, done)
+	}
+}
+
+func TestBoard_StateChangesRequireCSRFToken(t *testing.T) {
+	env := testsupport.NewEnv(t)
+
+	// Without the CSRF cookie and header, the request is refused before authentication
+	env.Client().Do(http.MethodPost, "/columns", models.CreateColumnRequest{Title: "Nope"}).Expect(http.StatusForbidden)
+}
@@ -0,0 +1,44 @@
+package e2e
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/testsupport"
+)
+
+func TestChecklist_ProgressInTask(t *testing.T) {
+	env := testsupport.NewEnv(t)
+	client, _ := env.Register("grace")
+
+	var column models.Column
+	client.Do(http.MethodPost, "/columns", models.CreateColumnRequest{Title: "To do"}).Expect(http.StatusCreated).Decode(&column)
+	var task models.Task
+	client.Do(http.MethodPost, "/tasks", models.CreateTaskRequest{Title: "Release", ColumnID: column.ID}).Expect(http.StatusCreated).Decode(&task)
+
+	path := fmt.Sprintf("/tasks/%d/checklist", task.ID)
+	items := make([]models.ChecklistItem, 3)
+	for i, title := range []string{"Tag", "Build", "Announce"} {
+		client.Do(http.MethodPost, path, models.CreateChecklistItemRequest{Title: title}).Expect(http.StatusCreated).Decode(&items[i])
+	}
+
+	done := true
+	client.Do(http.MethodPatch, fmt.Sprintf("%s/%d", path, items[0].ID), models.UpdateChecklistItemRequest{Done: &done}).Expect(http.StatusOK)
+	client.Do(http.MethodDelete, fmt.Sprintf("%s/%d", path, items[2].ID), nil).Expect(http.StatusNoContent)
+
+	var reordered []models.ChecklistItem
+	client.Do(http.MethodPatch, path+"/reorder", models.ReorderChecklistRequest{
+		ItemIDs: []int{items[1].ID, items[0].ID},
+	}).Expect(http.StatusOK).Decode(&reordered)
+	if len(reordered) != 2 || reordered[0].ID != items[1].ID {
+		t.Errorf("expected item %d first, got %+v", items[1].ID, reordered)
+	}
+
+	client.Do(http.MethodGet, fmt.Sprintf("/tasks/%d", task.ID), nil).Expect(http.StatusOK).Decode(&task)
+	want := models.ChecklistSummary{Total: 2, Done: 1, Percent: 50}
+	if task.Checklist != want {
+		t.Errorf("expected checklist %+v, got %+v", want, task.Checklist)
+	}
+}
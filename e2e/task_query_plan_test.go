@@ -0,0 +1,136 @@
+package e2e
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/clementhaon/sandbox-api-go/database"
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/repository"
+	"github.com/clementhaon/sandbox-api-go/tenant"
+	"github.com/clementhaon/sandbox-api-go/testsupport"
+)
+
+// recordingQuerier passes queries to a database and remembers them.
+type recordingQuerier struct {
+	database.Querier
+	queries []recordedQuery
+}
+
+type recordedQuery struct {
+	sql  string
+	args []any
+}
+
+func (q *recordingQuerier) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	q.queries = append(q.queries, recordedQuery{query, args})
+	return q.Querier.QueryContext(ctx, query, args...)
+}
+
+func (q *recordingQuerier) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	q.queries = append(q.queries, recordedQuery{query, args})
+	return q.Querier.QueryRowContext(ctx, query, args...)
+}
+
+// TestTaskList_SingleQueryPlan guards task listings against per-task
+// lookups: each listing must be one statement whose plan has no subplan
+// evaluated per row.
+func TestTaskList_SingleQueryPlan(t *testing.T) {
+	env := testsupport.NewEnv(t)
+	env.RequirePostgres()
+	client, user := env.Register("gina")
+
+	var column models.Column
+	client.Do(http.MethodPost, "/columns", models.CreateColumnRequest{Title: "To do"}).Expect(http.StatusCreated).Decode(&column)
+	for _, title := range []string{"One", "Two", "Three"} {
+		client.Do(http.MethodPost, "/tasks", models.CreateTaskRequest{
+			Title:      title,
+			ColumnID:   column.ID,
+			AssigneeID: &user.ID,
+			Tags:       []string{"backend", "review"},
+		}).Expect(http.StatusCreated)
+	}
+
+	var tenantID int
+	if err := env.DB.QueryRow("SELECT tenant_id FROM users WHERE id = $1", user.ID).Scan(&tenantID); err != nil {
+		t.Fatalf("read tenant: %v", err)
+	}
+	ctx := tenant.WithID(context.Background(), tenantID)
+
+	listings := map[string]func(repository.TaskRepository) ([]models.Task, error){
+		"board": func(r repository.TaskRepository) ([]models.Task, error) {
+			return r.ListWithAssignee(ctx, nil)
+		},
+		"column": func(r repository.TaskRepository) ([]models.Task, error) {
+			return r.ListWithAssignee(ctx, &column.ID)
+		},
+		"list": func(r repository.TaskRepository) ([]models.Task, error) {
+			return r.List(ctx, models.TaskListParams{SortBy: "updatedAt"})
+		},
+		"changes": func(r repository.TaskRepository) ([]models.Task, error) {
+			return r.ListChangedAfter(ctx, nil, 100)
+		},
+		"user": func(r repository.TaskRepository) ([]models.Task, error) {
+			return r.ListByUser(ctx, user.ID)
+		},
+	}
+
+	for name, list := range listings {
+		t.Run(name, func(t *testing.T) {
+			rec := &recordingQuerier{Querier: env.DB}
+			tasks, err := list(repository.NewPostgresTaskRepository(env.DB).WithQuerier(rec))
+			if err != nil {
+				t.Fatalf("list tasks: %v", err)
+			}
+			if len(tasks) != 3 {
+				t.Fatalf("expected 3 tasks, got %d", len(tasks))
+			}
+			for _, task := range tasks {
+				if task.Assignee == nil || len(task.Tags) != 2 {
+					t.Errorf("expected assignee and tags on task %d, got %+v", task.ID, task)
+				}
+			}
+			if len(rec.queries) != 1 {
+				t.Fatalf("expected 1 query, got %d", len(rec.queries))
+			}
+
+			var raw []byte
+			q := rec.queries[0]
+			if err := env.DB.QueryRow("EXPLAIN (FORMAT JSON) "+q.sql, q.args...).Scan(&raw); err != nil {
+				t.Fatalf("explain: %v", err)
+			}
+			var plans []struct{ Plan planNode }
+			if err := json.Unmarshal(raw, &plans); err != nil {
+				t.Fatalf("decode plan: %v", err)
+			}
+			if node := plans[0].Plan.find("SubPlan"); node != nil {
+				t.Errorf("expected no per-row subplan, found %s %q", node.NodeType, node.SubplanName)
+			}
+		})
+	}
+}
+
+// planNode is a node of an EXPLAIN (FORMAT JSON) plan.
+type planNode struct {
+	NodeType           string     `json:"Node Type"`
+	ParentRelationship string     `json:"Parent Relationship"`
+	SubplanName        string     `json:"Subplan Name"`
+	Plans              []planNode `json:"Plans"`
+}
+
+// find returns the first node below n with the given parent relationship.
+func (n *planNode) find(relationship string) *planNode {
+	for i := range n.Plans {
+		child := &n.Plans[i]
+		if child.ParentRelationship == relationship {
+			return child
+		}
+		if found := child.find(relationship); found != nil {
+			return found
+		}
+	}
+	return nil
+}
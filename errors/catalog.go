@@ -0,0 +1,70 @@
+package errors
+
+import "net/http"
+
+// CatalogEntry documents one ErrorCode for API clients.
+type CatalogEntry struct {
+	Code        ErrorCode `json:"code"`
+	Status      int       `json:"status"`
+	Type        ErrorType `json:"type"`
+	Description string    `json:"description"`
+}
+
+// catalog lists every ErrorCode in declaration order. Status and Type are the
+// values used by the matching New*Error constructors.
+var catalog = []CatalogEntry{
+	// Authentication errors
+	{ErrAuthRequired, http.StatusUnauthorized, ErrorTypeClient, "The request has no valid authentication token or session."},
+	{ErrInvalidToken, http.StatusUnauthorized, ErrorTypeClient, "The token is malformed, has a bad signature or was revoked."},
+	{ErrTokenExpired, http.StatusUnauthorized, ErrorTypeClient, "The token has expired; log in again."},
+	{ErrInvalidCredentials, http.StatusUnauthorized, ErrorTypeClient, "The email or password is wrong."},
+	{ErrUserExists, http.StatusConflict, ErrorTypeClient, "A user with this username or email already exists."},
+
+	// Validation errors
+	{ErrValidationFailed, http.StatusBadRequest, ErrorTypeValidation, "The input is invalid; see the validation field for per-field messages."},
+	{ErrInvalidJSON, http.StatusBadRequest, ErrorTypeClient, "The request body is not valid JSON."},
+	{ErrMissingField, http.StatusBadRequest, ErrorTypeValidation, "A required field is missing."},
+	{ErrInvalidFormat, http.StatusBadRequest, ErrorTypeValidation, "A field or query parameter has the wrong format."},
+
+	// Resource errors
+	{ErrNotFound, http.StatusNotFound, ErrorTypeClient, "The requested resource does not exist."},
+	{ErrForbidden, http.StatusForbidden, ErrorTypeClient, "The authenticated user may not perform this action."},
+	{ErrInsufficientScope, http.StatusForbidden, ErrorTypeClient, "The token is restricted to scopes that do not include the one the route requires (details.requiredScopes); routes without a scope refuse restricted tokens."},
+	{ErrConflict, http.StatusConflict, ErrorTypeClient, "The request conflicts with the current state of the resource."},
+
+	// Server errors
+	{ErrInternal, http.StatusInternalServerError, ErrorTypeServer, "An unexpected server error occurred; quote the request_id when reporting it."},
+	{ErrDatabase, http.StatusInternalServerError, ErrorTypeServer, "A database operation failed."},
+	{ErrServiceUnavailable, http.StatusServiceUnavailable, ErrorTypeServer, "A dependency is temporarily unavailable; retry later."},
+
+	// Rate limiting errors
+	{ErrTooManyRequests, http.StatusTooManyRequests, ErrorTypeClient, "Too many requests; wait before retrying."},
+
+	// Method errors
+	{ErrMethodNotAllowed, http.StatusMethodNotAllowed, ErrorTypeClient, "The HTTP method is not supported for this route."},
+
+	// Payload errors
+	{ErrPayloadTooLarge, http.StatusRequestEntityTooLarge, ErrorTypeClient, "The request body exceeds the maximum allowed size."},
+
+	// Quota errors
+	{ErrQuotaExceeded, http.StatusForbidden, ErrorTypeClient, "A per-user quota is reached; details give the resource and limit. Tasks and media storage answer 403; daily requests and bytes answer 429 with Retry-After until the next UTC day."},
+
+	// Signup errors
+	{ErrSignupRateLimited, http.StatusTooManyRequests, ErrorTypeClient, "Too many accounts were created from the client's IP; wait for Retry-After."},
+	{ErrDisposableEmail, http.StatusBadRequest, ErrorTypeClient, "The email domain belongs to a disposable email provider."},
+	{ErrCaptchaRequired, http.StatusBadRequest, ErrorTypeClient, "Registration needs a CAPTCHA token (captcha_token) and none was sent."},
+	{ErrCaptchaFailed, http.StatusBadRequest, ErrorTypeClient, "The CAPTCHA token was rejected; solve the CAPTCHA again."},
+
+	// Password errors
+	{ErrPasswordBreached, http.StatusBadRequest, ErrorTypeValidation, "The password is listed in known data breaches (Pwned Passwords); choose another one."},
+
+	// Sandbox errors
+	{ErrSimulated, http.StatusInternalServerError, ErrorTypeClient, "The failure the sandbox settings of the personal token ask for, with their status code (500 by default); the request was not processed."},
+}
+
+// Catalog returns a copy of the documented error codes.
+func Catalog() []CatalogEntry {
+	entries := make([]CatalogEntry, len(catalog))
+	copy(entries, catalog)
+	return entries
+}
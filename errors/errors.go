@@ -2,9 +2,12 @@ package errors
 
 import (
 	"encoding/json"
+	goerrors "errors"
 	"fmt"
 	"net/http"
 	"time"
+
+	"github.com/clementhaon/sandbox-api-go/i18n"
 )
 
 // ErrorCode represents the type of error
@@ -27,7 +30,10 @@ const (
 	// Resource errors
 	ErrNotFound  ErrorCode = "NOT_FOUND"
 	ErrForbidden ErrorCode = "FORBIDDEN"
-	ErrConflict  ErrorCode = "CONFLICT"
+	// ErrInsufficientScope refuses a token restricted to scopes that do not
+	// cover the route
+	ErrInsufficientScope ErrorCode = "INSUFFICIENT_SCOPE"
+	ErrConflict          ErrorCode = "CONFLICT"
 
 	// Server errors
 	ErrInternal           ErrorCode = "INTERNAL_ERROR"
@@ -42,8 +48,29 @@ const (
 
 	// Payload errors
 	ErrPayloadTooLarge ErrorCode = "PAYLOAD_TOO_LARGE"
+
+	// Quota errors
+	ErrQuotaExceeded ErrorCode = "QUOTA_EXCEEDED"
+
+	// Signup errors
+	ErrSignupRateLimited ErrorCode = "SIGNUP_RATE_LIMITED"
+	ErrDisposableEmail   ErrorCode = "DISPOSABLE_EMAIL"
+	ErrCaptchaRequired   ErrorCode = "CAPTCHA_REQUIRED"
+	ErrCaptchaFailed     ErrorCode = "CAPTCHA_FAILED"
+
+	// Password errors
+	ErrPasswordBreached ErrorCode = "PASSWORD_BREACHED"
+
+	// Sandbox errors
+	ErrSimulated ErrorCode = "SIMULATED_ERROR"
 )
 
+// Error lets an ErrorCode be used as a sentinel, so that
+// errors.Is(err, errors.ErrNotFound) matches any AppError with that code.
+func (c ErrorCode) Error() string {
+	return string(c)
+}
+
 // ErrorType categorizes errors by their nature
 type ErrorType string
 
@@ -58,6 +85,10 @@ type ValidationError struct {
 	Field   string      `json:"field"`
 	Message string      `json:"message"`
 	Value   interface{} `json:"value,omitempty"`
+
+	// Key and Args identify Message in the i18n catalogs
+	Key  string        `json:"-"`
+	Args []interface{} `json:"-"`
 }
 
 // AppError represents a structured application error
@@ -70,7 +101,14 @@ type AppError struct {
 	StatusCode int               `json:"-"`
 	Timestamp  time.Time         `json:"timestamp"`
 	RequestID  string            `json:"request_id,omitempty"`
+	TraceID    string            `json:"trace_id,omitempty"`
+	SpanID     string            `json:"span_id,omitempty"`
 	Cause      error             `json:"-"`
+
+	// MessageKey and MessageArgs identify Message in the i18n catalogs; custom
+	// messages have no key and are never translated.
+	MessageKey  string        `json:"-"`
+	MessageArgs []interface{} `json:"-"`
 }
 
 // Error implements the error interface
@@ -81,6 +119,23 @@ func (e *AppError) Error() string {
 	return fmt.Sprintf("%s: %s", e.Code, e.Message)
 }
 
+// Unwrap returns the root cause so errors.Is and errors.As can inspect it
+func (e *AppError) Unwrap() error {
+	return e.Cause
+}
+
+// Is reports whether target is the same ErrorCode, or an AppError with the
+// same code. Messages and details are ignored.
+func (e *AppError) Is(target error) bool {
+	switch t := target.(type) {
+	case ErrorCode:
+		return e.Code == t
+	case *AppError:
+		return t != nil && e.Code == t.Code
+	}
+	return false
+}
+
 // WithCause adds a root cause to the error
 func (e *AppError) WithCause(cause error) *AppError {
 	e.Cause = cause
@@ -93,12 +148,46 @@ func (e *AppError) WithRequestID(requestID string) *AppError {
 	return e
 }
 
+// WithTrace adds the trace and span of the request to the error, so it can
+// be found in the tracing backend
+func (e *AppError) WithTrace(traceID, spanID string) *AppError {
+	e.TraceID = traceID
+	e.SpanID = spanID
+	return e
+}
+
 // WithDetails adds additional details to the error
 func (e *AppError) WithDetails(details interface{}) *AppError {
 	e.Details = details
 	return e
 }
 
+// withKey records the i18n catalog key of a constructor's default message
+func (e *AppError) withKey(key string, args ...interface{}) *AppError {
+	e.MessageKey = key
+	e.MessageArgs = args
+	return e
+}
+
+// Localize returns a copy of the error with its message and validation
+// messages translated to lang. Messages without a catalog key are kept.
+func (e *AppError) Localize(lang string) *AppError {
+	localized := *e
+	if e.MessageKey != "" {
+		localized.Message = i18n.T(lang, e.MessageKey, e.MessageArgs...)
+	}
+	if len(e.Validation) > 0 {
+		localized.Validation = make([]ValidationError, len(e.Validation))
+		for i, v := range e.Validation {
+			if v.Key != "" {
+				v.Message = i18n.T(lang, v.Key, v.Args...)
+			}
+			localized.Validation[i] = v
+		}
+	}
+	return &localized
+}
+
 // NewAppError creates a new application error
 func NewAppError(code ErrorCode, message string, statusCode int, errorType ErrorType) *AppError {
 	return &AppError{
@@ -112,65 +201,74 @@ func NewAppError(code ErrorCode, message string, statusCode int, errorType Error
 
 // Authentication Errors
 func NewAuthRequiredError() *AppError {
-	return NewAppError(ErrAuthRequired, "Authentication required", http.StatusUnauthorized, ErrorTypeClient)
+	return NewAppError(ErrAuthRequired, "Authentication required", http.StatusUnauthorized, ErrorTypeClient).withKey("error.AUTH_REQUIRED")
 }
 
 func NewUnauthorizedError(message string) *AppError {
 	if message == "" {
-		message = "Unauthorized access"
+		return NewAppError(ErrAuthRequired, "Unauthorized access", http.StatusUnauthorized, ErrorTypeClient).withKey("error.UNAUTHORIZED")
 	}
 	return NewAppError(ErrAuthRequired, message, http.StatusUnauthorized, ErrorTypeClient)
 }
 
 func NewInvalidTokenError() *AppError {
-	return NewAppError(ErrInvalidToken, "Invalid or malformed token", http.StatusUnauthorized, ErrorTypeClient)
+	return NewAppError(ErrInvalidToken, "Invalid or malformed token", http.StatusUnauthorized, ErrorTypeClient).withKey("error.INVALID_TOKEN")
 }
 
 func NewTokenExpiredError() *AppError {
-	return NewAppError(ErrTokenExpired, "Token has expired", http.StatusUnauthorized, ErrorTypeClient)
+	return NewAppError(ErrTokenExpired, "Token has expired", http.StatusUnauthorized, ErrorTypeClient).withKey("error.TOKEN_EXPIRED")
 }
 
 func NewInvalidCredentialsError() *AppError {
-	return NewAppError(ErrInvalidCredentials, "Invalid username or password", http.StatusUnauthorized, ErrorTypeClient)
+	return NewAppError(ErrInvalidCredentials, "Invalid username or password", http.StatusUnauthorized, ErrorTypeClient).withKey("error.INVALID_CREDENTIALS")
 }
 
 func NewUserExistsError() *AppError {
-	return NewAppError(ErrUserExists, "User already exists", http.StatusConflict, ErrorTypeClient)
+	return NewAppError(ErrUserExists, "User already exists", http.StatusConflict, ErrorTypeClient).withKey("error.USER_EXISTS")
 }
 
 // Validation Errors
 func NewValidationError(validationErrors []ValidationError) *AppError {
-	err := NewAppError(ErrValidationFailed, "Input validation failed", http.StatusBadRequest, ErrorTypeValidation)
+	err := NewAppError(ErrValidationFailed, "Input validation failed", http.StatusBadRequest, ErrorTypeValidation).withKey("error.VALIDATION_FAILED")
 	err.Validation = validationErrors
 	return err
 }
 
 func NewBadRequestError(message string) *AppError {
 	if message == "" {
-		message = "Bad request"
+		return NewAppError(ErrValidationFailed, "Bad request", http.StatusBadRequest, ErrorTypeClient).withKey("error.BAD_REQUEST")
 	}
 	return NewAppError(ErrValidationFailed, message, http.StatusBadRequest, ErrorTypeClient)
 }
 
 func NewInvalidJSONError() *AppError {
-	return NewAppError(ErrInvalidJSON, "Invalid JSON format", http.StatusBadRequest, ErrorTypeClient)
+	return NewAppError(ErrInvalidJSON, "Invalid JSON format", http.StatusBadRequest, ErrorTypeClient).withKey("error.INVALID_JSON")
 }
 
 func NewMissingFieldError(field string) *AppError {
-	return NewAppError(ErrMissingField, fmt.Sprintf("Missing required field: %s", field), http.StatusBadRequest, ErrorTypeValidation)
+	return NewAppError(ErrMissingField, fmt.Sprintf("Missing required field: %s", field), http.StatusBadRequest, ErrorTypeValidation).
+		withKey("error.MISSING_FIELD", field)
 }
 
 func NewInvalidFormatError(field, expected string) *AppError {
-	return NewAppError(ErrInvalidFormat, fmt.Sprintf("Invalid format for field '%s', expected: %s", field, expected), http.StatusBadRequest, ErrorTypeValidation)
+	return NewAppError(ErrInvalidFormat, fmt.Sprintf("Invalid format for field '%s', expected: %s", field, expected), http.StatusBadRequest, ErrorTypeValidation).
+		withKey("error.INVALID_FORMAT", field, expected)
 }
 
 // Resource Errors
 func NewNotFoundError(resource string) *AppError {
-	return NewAppError(ErrNotFound, fmt.Sprintf("%s not found", resource), http.StatusNotFound, ErrorTypeClient)
+	return NewAppError(ErrNotFound, fmt.Sprintf("%s not found", resource), http.StatusNotFound, ErrorTypeClient).
+		withKey("error.NOT_FOUND", resource)
 }
 
 func NewForbiddenError() *AppError {
-	return NewAppError(ErrForbidden, "Access forbidden", http.StatusForbidden, ErrorTypeClient)
+	return NewAppError(ErrForbidden, "Access forbidden", http.StatusForbidden, ErrorTypeClient).withKey("error.FORBIDDEN")
+}
+
+func NewInsufficientScopeError(required ...string) *AppError {
+	return NewAppError(ErrInsufficientScope, "The token's scopes do not allow this action", http.StatusForbidden, ErrorTypeClient).
+		withKey("error.INSUFFICIENT_SCOPE").
+		WithDetails(map[string]interface{}{"requiredScopes": required})
 }
 
 func NewConflictError(message string) *AppError {
@@ -179,37 +277,88 @@ func NewConflictError(message string) *AppError {
 
 // Server Errors
 func NewInternalError() *AppError {
-	return NewAppError(ErrInternal, "Internal server error", http.StatusInternalServerError, ErrorTypeServer)
+	return NewAppError(ErrInternal, "Internal server error", http.StatusInternalServerError, ErrorTypeServer).withKey("error.INTERNAL_ERROR")
 }
 
 func NewInternalServerError(message string) *AppError {
 	if message == "" {
-		message = "Internal server error"
+		return NewInternalError()
 	}
 	return NewAppError(ErrInternal, message, http.StatusInternalServerError, ErrorTypeServer)
 }
 
 func NewDatabaseError() *AppError {
-	return NewAppError(ErrDatabase, "Database operation failed", http.StatusInternalServerError, ErrorTypeServer)
+	return NewAppError(ErrDatabase, "Database operation failed", http.StatusInternalServerError, ErrorTypeServer).withKey("error.DATABASE_ERROR")
 }
 
 func NewServiceUnavailableError() *AppError {
-	return NewAppError(ErrServiceUnavailable, "Service temporarily unavailable", http.StatusServiceUnavailable, ErrorTypeServer)
+	return NewAppError(ErrServiceUnavailable, "Service temporarily unavailable", http.StatusServiceUnavailable, ErrorTypeServer).withKey("error.SERVICE_UNAVAILABLE")
 }
 
 // Rate Limiting Errors
 func NewTooManyRequestsError() *AppError {
-	return NewAppError(ErrTooManyRequests, "Too many requests, please try again later", http.StatusTooManyRequests, ErrorTypeClient)
+	return NewAppError(ErrTooManyRequests, "Too many requests, please try again later", http.StatusTooManyRequests, ErrorTypeClient).withKey("error.TOO_MANY_REQUESTS")
 }
 
 // Method Errors
 func NewMethodNotAllowedError() *AppError {
-	return NewAppError(ErrMethodNotAllowed, "Method not allowed", http.StatusMethodNotAllowed, ErrorTypeClient)
+	return NewAppError(ErrMethodNotAllowed, "Method not allowed", http.StatusMethodNotAllowed, ErrorTypeClient).withKey("error.METHOD_NOT_ALLOWED")
 }
 
 // Payload Errors
 func NewPayloadTooLargeError() *AppError {
-	return NewAppError(ErrPayloadTooLarge, "Request body too large", http.StatusRequestEntityTooLarge, ErrorTypeClient)
+	return NewAppError(ErrPayloadTooLarge, "Request body too large", http.StatusRequestEntityTooLarge, ErrorTypeClient).withKey("error.PAYLOAD_TOO_LARGE")
+}
+
+// Quota Errors
+func NewQuotaExceededError(resource string, limit int64) *AppError {
+	return NewAppError(ErrQuotaExceeded, fmt.Sprintf("Quota exceeded for %s (limit %d)", resource, limit), http.StatusForbidden, ErrorTypeClient).
+		withKey("error.QUOTA_EXCEEDED", resource, limit).
+		WithDetails(map[string]interface{}{"resource": resource, "limit": limit})
+}
+
+// NewRequestQuotaExceededError refuses a request once the user reached their
+// daily quota of resource; it is retried the next day.
+func NewRequestQuotaExceededError(resource string, limit int64) *AppError {
+	return NewAppError(ErrQuotaExceeded, fmt.Sprintf("Quota exceeded for %s (limit %d)", resource, limit), http.StatusTooManyRequests, ErrorTypeClient).
+		withKey("error.QUOTA_EXCEEDED", resource, limit).
+		WithDetails(map[string]interface{}{"resource": resource, "limit": limit})
+}
+
+// Signup Errors
+func NewSignupRateLimitedError() *AppError {
+	return NewAppError(ErrSignupRateLimited, "Too many accounts created from this address, please try again later", http.StatusTooManyRequests, ErrorTypeClient).
+		withKey("error.SIGNUP_RATE_LIMITED")
+}
+
+func NewDisposableEmailError() *AppError {
+	return NewAppError(ErrDisposableEmail, "Disposable email addresses are not accepted", http.StatusBadRequest, ErrorTypeClient).
+		withKey("error.DISPOSABLE_EMAIL")
+}
+
+func NewCaptchaRequiredError() *AppError {
+	return NewAppError(ErrCaptchaRequired, "CAPTCHA verification is required", http.StatusBadRequest, ErrorTypeClient).
+		withKey("error.CAPTCHA_REQUIRED")
+}
+
+func NewCaptchaFailedError() *AppError {
+	return NewAppError(ErrCaptchaFailed, "CAPTCHA verification failed", http.StatusBadRequest, ErrorTypeClient).
+		withKey("error.CAPTCHA_FAILED")
+}
+
+func NewPasswordBreachedError() *AppError {
+	return NewAppError(ErrPasswordBreached, "This password appeared in a data breach, please choose another one", http.StatusBadRequest, ErrorTypeValidation).
+		withKey("error.PASSWORD_BREACHED")
+}
+
+// Sandbox Errors
+
+// NewSimulatedError is the failure with statusCode the sandbox settings of
+// a personal token ask for. It is a client error whatever the status, as
+// the client chose it.
+func NewSimulatedError(statusCode int) *AppError {
+	return NewAppError(ErrSimulated, "Simulated error", statusCode, ErrorTypeClient).
+		withKey("error.SIMULATED_ERROR")
 }
 
 // ErrorResponse represents the standardized error response format
@@ -237,10 +386,53 @@ func WriteError(w http.ResponseWriter, err *AppError) {
 	json.NewEncoder(w).Encode(response)
 }
 
-// IsAppError checks if an error is an AppError
+// IsAppError returns the first AppError in err's chain, so errors wrapped
+// with fmt.Errorf("...: %w", appErr) are still classified correctly.
 func IsAppError(err error) (*AppError, bool) {
-	if appErr, ok := err.(*AppError); ok {
+	var appErr *AppError
+	if goerrors.As(err, &appErr) {
 		return appErr, true
 	}
 	return nil, false
 }
+
+// Is is the standard library errors.Is, re-exported because this package
+// shadows its name.
+func Is(err, target error) bool {
+	return goerrors.Is(err, target)
+}
+
+// As is the standard library errors.As, re-exported because this package
+// shadows its name.
+func As(err error, target interface{}) bool {
+	return goerrors.As(err, target)
+}
+
+// Wrap attaches cause to appErr. It returns nil if cause is nil, so it can
+// wrap the result of a call directly.
+func Wrap(cause error, appErr *AppError) error {
+	if cause == nil {
+		return nil
+	}
+	return appErr.WithCause(cause)
+}
+
+// WrapAs classifies cause as code, taking the status and type from the
+// catalog. An AppError already in cause's chain is returned unchanged so a
+// more specific classification is not lost. It returns nil if cause is nil.
+func WrapAs(cause error, code ErrorCode, message string) error {
+	if cause == nil {
+		return nil
+	}
+	if appErr, ok := IsAppError(cause); ok {
+		return appErr
+	}
+	status, errorType := http.StatusInternalServerError, ErrorTypeServer
+	for _, entry := range catalog {
+		if entry.Code == code {
+			status, errorType = entry.Status, entry.Type
+			break
+		}
+	}
+	return NewAppError(code, message, status, errorType).WithCause(cause)
+}
@@ -7,6 +7,8 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+
+	"github.com/clementhaon/sandbox-api-go/i18n"
 )
 
 func TestErrorConstructors(t *testing.T) {
@@ -199,6 +201,58 @@ func TestIsAppError(t *testing.T) {
 			t.Error("expected nil AppError for regular error")
 		}
 	})
+
+	t.Run("finds AppError wrapped with %w", func(t *testing.T) {
+		appErr := NewNotFoundError("Item")
+		got, ok := IsAppError(fmt.Errorf("loading item: %w", appErr))
+		if !ok || got != appErr {
+			t.Errorf("expected wrapped AppError, got %v", got)
+		}
+	})
+}
+
+func TestAppError_IsAndUnwrap(t *testing.T) {
+	cause := fmt.Errorf("connection reset")
+	err := fmt.Errorf("repo: %w", NewDatabaseError().WithCause(cause))
+
+	if !Is(err, ErrDatabase) {
+		t.Error("expected errors.Is to match the DATABASE_ERROR code")
+	}
+	if !Is(err, NewDatabaseError()) {
+		t.Error("expected errors.Is to match an AppError with the same code")
+	}
+	if Is(err, ErrNotFound) {
+		t.Error("expected errors.Is not to match a different code")
+	}
+	if !Is(err, cause) {
+		t.Error("expected errors.Is to reach the cause through Unwrap")
+	}
+}
+
+func TestWrapAs(t *testing.T) {
+	if WrapAs(nil, ErrConflict, "conflict") != nil {
+		t.Error("expected nil for a nil cause")
+	}
+	if Wrap(nil, NewInternalError()) != nil {
+		t.Error("expected nil for a nil cause")
+	}
+
+	cause := fmt.Errorf("duplicate key")
+	appErr, ok := IsAppError(WrapAs(cause, ErrConflict, "Column already exists"))
+	if !ok {
+		t.Fatal("expected AppError")
+	}
+	if appErr.StatusCode != http.StatusConflict || appErr.Type != ErrorTypeClient {
+		t.Errorf("expected 409 client_error from the catalog, got %d %s", appErr.StatusCode, appErr.Type)
+	}
+	if appErr.Cause != cause {
+		t.Error("expected cause to be kept")
+	}
+
+	existing := NewForbiddenError()
+	if got := WrapAs(fmt.Errorf("ctx: %w", existing), ErrInternal, "x"); got != existing {
+		t.Errorf("expected existing AppError to be kept, got %v", got)
+	}
 }
 
 func TestWriteError(t *testing.T) {
@@ -235,3 +289,138 @@ func TestWriteError(t *testing.T) {
 		}
 	})
 }
+
+func TestCatalog_MatchesConstructors(t *testing.T) {
+	byCode := make(map[ErrorCode]CatalogEntry)
+	for _, entry := range Catalog() {
+		if _, dup := byCode[entry.Code]; dup {
+			t.Errorf("duplicate catalog entry for %s", entry.Code)
+		}
+		if entry.Description == "" {
+			t.Errorf("missing description for %s", entry.Code)
+		}
+		byCode[entry.Code] = entry
+	}
+
+	constructors := []*AppError{
+		NewAuthRequiredError(),
+		NewInvalidTokenError(),
+		NewTokenExpiredError(),
+		NewInvalidCredentialsError(),
+		NewUserExistsError(),
+		NewValidationError(nil),
+		NewInvalidJSONError(),
+		NewMissingFieldError("f"),
+		NewInvalidFormatError("f", "x"),
+		NewNotFoundError("Thing"),
+		NewForbiddenError(),
+		NewConflictError("c"),
+		NewInternalError(),
+		NewDatabaseError(),
+		NewServiceUnavailableError(),
+		NewTooManyRequestsError(),
+		NewMethodNotAllowedError(),
+		NewPayloadTooLargeError(),
+		NewQuotaExceededError("tasks", 10),
+		NewSignupRateLimitedError(),
+		NewDisposableEmailError(),
+		NewCaptchaRequiredError(),
+		NewCaptchaFailedError(),
+		NewPasswordBreachedError(),
+		NewInsufficientScopeError("tasks:read"),
+		NewSimulatedError(http.StatusInternalServerError),
+	}
+	if len(constructors) != len(byCode) {
+		t.Errorf("catalog has %d codes, constructors cover %d", len(byCode), len(constructors))
+	}
+	for _, appErr := range constructors {
+		entry, ok := byCode[appErr.Code]
+		if !ok {
+			t.Errorf("%s is missing from the catalog", appErr.Code)
+			continue
+		}
+		if msg, ok := i18n.Lookup(i18n.DefaultLanguage, appErr.MessageKey, appErr.MessageArgs...); appErr.MessageKey != "" && (!ok || msg != appErr.Message) {
+			t.Errorf("%s: English catalog has %q for key %q, constructor says %q", appErr.Code, msg, appErr.MessageKey, appErr.Message)
+		}
+		if entry.Status != appErr.StatusCode || entry.Type != appErr.Type {
+			t.Errorf("%s: catalog has %d/%s, constructor returns %d/%s",
+				appErr.Code, entry.Status, entry.Type, appErr.StatusCode, appErr.Type)
+		}
+	}
+}
+
+func TestWriteErrorFor_Problem(t *testing.T) {
+	appErr := NewValidationError([]ValidationError{{Field: "email", Message: "required"}}).WithRequestID("req-1")
+
+	t.Run("negotiated from Accept header", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/users", nil)
+		req.Header.Set("Accept", "application/problem+json, application/json;q=0.9")
+		rec := httptest.NewRecorder()
+
+		WriteErrorFor(rec, req, appErr)
+
+		if ct := rec.Header().Get("Content-Type"); ct != ProblemContentType {
+			t.Fatalf("Content-Type = %q, want %q", ct, ProblemContentType)
+		}
+		var problem Problem
+		if err := json.NewDecoder(rec.Body).Decode(&problem); err != nil {
+			t.Fatalf("failed to decode problem: %v", err)
+		}
+		if problem.Type != "/errors#VALIDATION_FAILED" {
+			t.Errorf("type = %q", problem.Type)
+		}
+		if problem.Status != http.StatusBadRequest || problem.Title != "Bad Request" {
+			t.Errorf("status/title = %d %q", problem.Status, problem.Title)
+		}
+		if problem.Instance != "/users" || problem.RequestID != "req-1" || len(problem.Validation) != 1 {
+			t.Errorf("unexpected problem: %+v", problem)
+		}
+	})
+
+	t.Run("plain JSON by default", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		WriteErrorFor(rec, httptest.NewRequest(http.MethodGet, "/", nil), appErr)
+		if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Content-Type = %q, want application/json", ct)
+		}
+	})
+
+	t.Run("always when configured", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req = req.WithContext(WithProblemFormat(req.Context(), ProblemFormat{Always: true, TypeBase: "https://api.example.com/errors/"}))
+		rec := httptest.NewRecorder()
+		WriteErrorFor(rec, req, appErr)
+
+		var problem Problem
+		json.NewDecoder(rec.Body).Decode(&problem)
+		if problem.Type != "https://api.example.com/errors/VALIDATION_FAILED" {
+			t.Errorf("type = %q", problem.Type)
+		}
+	})
+}
+
+func TestAppError_Localize(t *testing.T) {
+	appErr := NewValidationError([]ValidationError{
+		{Field: "email", Message: "This field is required", Key: "validation.required"},
+		{Field: "title", Message: "custom"},
+	})
+
+	fr := appErr.Localize("fr")
+	if fr.Message != "La validation des données a échoué" {
+		t.Errorf("unexpected message %q", fr.Message)
+	}
+	if fr.Validation[0].Message != "Ce champ est obligatoire" {
+		t.Errorf("unexpected validation message %q", fr.Validation[0].Message)
+	}
+	if fr.Validation[1].Message != "custom" {
+		t.Errorf("expected message without key to be kept, got %q", fr.Validation[1].Message)
+	}
+	if appErr.Validation[0].Message != "This field is required" {
+		t.Error("expected the original error to be left unchanged")
+	}
+
+	custom := NewConflictError("Column title taken").Localize("fr")
+	if custom.Message != "Column title taken" {
+		t.Errorf("expected custom message to be kept, got %q", custom.Message)
+	}
+}
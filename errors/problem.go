@@ -0,0 +1,116 @@
+package errors
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/i18n"
+)
+
+// ProblemContentType is the media type of RFC 7807 problem documents.
+const ProblemContentType = "application/problem+json"
+
+// DefaultProblemTypeBase points problem types at the GET /errors catalog.
+const DefaultProblemTypeBase = "/errors#"
+
+// Problem is an RFC 7807 problem document. Code, RequestID, TraceID, SpanID,
+// Validation, Details and Timestamp are extension members carrying the
+// AppError fields.
+type Problem struct {
+	Type       string            `json:"type"`
+	Title      string            `json:"title"`
+	Status     int               `json:"status"`
+	Detail     string            `json:"detail,omitempty"`
+	Instance   string            `json:"instance,omitempty"`
+	Code       ErrorCode         `json:"code"`
+	RequestID  string            `json:"request_id,omitempty"`
+	TraceID    string            `json:"trace_id,omitempty"`
+	SpanID     string            `json:"span_id,omitempty"`
+	Validation []ValidationError `json:"validation,omitempty"`
+	Details    interface{}       `json:"details,omitempty"`
+	Timestamp  time.Time         `json:"timestamp"`
+}
+
+// ProblemFormat is how WriteErrorFor writes problem documents: Always emits
+// them instead of only when the client asks for them, and TypeBase is the
+// base URI that error codes are appended to in the type member ("" for
+// DefaultProblemTypeBase).
+type ProblemFormat struct {
+	Always   bool
+	TypeBase string
+}
+
+type problemFormatKey struct{}
+
+// WithProblemFormat returns a copy of ctx whose errors WriteErrorFor writes
+// in format. Servers use it to each apply their own.
+func WithProblemFormat(ctx context.Context, format ProblemFormat) context.Context {
+	return context.WithValue(ctx, problemFormatKey{}, format)
+}
+
+// problemFormatOf returns the ProblemFormat of ctx, the zero one by default.
+func problemFormatOf(ctx context.Context) ProblemFormat {
+	format, _ := ctx.Value(problemFormatKey{}).(ProblemFormat)
+	return format
+}
+
+// Type returns the type URI for an error code.
+func (f ProblemFormat) Type(code ErrorCode) string {
+	if f.TypeBase == "" {
+		return DefaultProblemTypeBase + string(code)
+	}
+	return f.TypeBase + string(code)
+}
+
+// NewProblem converts an AppError to a problem document, typed by format.
+// instance is usually the request path.
+func NewProblem(err *AppError, instance string, format ProblemFormat) *Problem {
+	return &Problem{
+		Type:       format.Type(err.Code),
+		Title:      http.StatusText(err.StatusCode),
+		Status:     err.StatusCode,
+		Detail:     err.Message,
+		Instance:   instance,
+		Code:       err.Code,
+		RequestID:  err.RequestID,
+		TraceID:    err.TraceID,
+		SpanID:     err.SpanID,
+		Validation: err.Validation,
+		Details:    err.Details,
+		Timestamp:  err.Timestamp,
+	}
+}
+
+// WriteProblem writes an error as application/problem+json.
+func WriteProblem(w http.ResponseWriter, err *AppError, instance string, format ProblemFormat) {
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(err.StatusCode)
+
+	json.NewEncoder(w).Encode(NewProblem(err, instance, format))
+}
+
+// WantsProblem reports whether the response to r should be a problem
+// document: either always, by the ProblemFormat of its context, or
+// requested in the Accept header.
+func WantsProblem(r *http.Request) bool {
+	if problemFormatOf(r.Context()).Always {
+		return true
+	}
+	return strings.Contains(r.Header.Get("Accept"), ProblemContentType)
+}
+
+// WriteErrorFor writes err in the format and language negotiated for r.
+func WriteErrorFor(w http.ResponseWriter, r *http.Request, err *AppError) {
+	lang := i18n.FromRequest(r)
+	err = err.Localize(lang)
+	w.Header().Set("Content-Language", lang)
+
+	if WantsProblem(r) {
+		WriteProblem(w, err, r.URL.Path, problemFormatOf(r.Context()))
+		return
+	}
+	WriteError(w, err)
+}
@@ -0,0 +1,128 @@
+// Package errtrack reports panics and server errors to an error-tracking
+// backend. Reporting is off unless a Reporter is attached to the request
+// context with NewContext.
+package errtrack
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// Frame is a function call of a stack trace.
+type Frame struct {
+	Function string // package path qualified, e.g. "github.com/x/y.(*T).M"
+	File     string
+	Line     int
+}
+
+// Event describes a panic or a server error, with the request it happened in.
+type Event struct {
+	Message    string
+	Panic      bool
+	StatusCode int
+	ErrorCode  string
+	Stack      []Frame // innermost call first
+	Time       time.Time
+
+	RequestID string
+	TraceID   string
+	SpanID    string
+	Method    string
+	Path      string
+	ClientIP  string
+	UserAgent string
+	UserID    int // 0 for anonymous requests
+	TenantID  int
+}
+
+// Reporter sends events to an error tracker. Report must not block the
+// request it is called from.
+type Reporter interface {
+	Report(ev Event)
+}
+
+type contextKey struct{}
+
+// scope holds the reporter of a request, and what the middlewares deeper
+// in the chain learn about the request (user, tenant).
+type scope struct {
+	reporter Reporter
+
+	mu       sync.Mutex
+	userID   int
+	tenantID int
+}
+
+// NewContext returns a context reporting to reporter, for one request.
+func NewContext(ctx context.Context, reporter Reporter) context.Context {
+	return context.WithValue(ctx, contextKey{}, &scope{reporter: reporter})
+}
+
+func scopeFrom(ctx context.Context) *scope {
+	s, _ := ctx.Value(contextKey{}).(*scope)
+	return s
+}
+
+// Enabled tells whether events reported with ctx are sent anywhere.
+func Enabled(ctx context.Context) bool {
+	return scopeFrom(ctx) != nil
+}
+
+// SetUser records the authenticated user of the request of ctx, so events
+// reported for the request name them even from outer middlewares.
+func SetUser(ctx context.Context, userID int) {
+	if s := scopeFrom(ctx); s != nil {
+		s.mu.Lock()
+		s.userID = userID
+		s.mu.Unlock()
+	}
+}
+
+// SetTenant records the tenant of the request of ctx.
+func SetTenant(ctx context.Context, tenantID int) {
+	if s := scopeFrom(ctx); s != nil {
+		s.mu.Lock()
+		s.tenantID = tenantID
+		s.mu.Unlock()
+	}
+}
+
+// Report sends ev to the reporter of ctx, filling in the user and tenant
+// of the request. It does nothing when reporting is off.
+func Report(ctx context.Context, ev Event) {
+	s := scopeFrom(ctx)
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if ev.UserID == 0 {
+		ev.UserID = s.userID
+	}
+	if ev.TenantID == 0 {
+		ev.TenantID = s.tenantID
+	}
+	s.mu.Unlock()
+	if ev.Time.IsZero() {
+		ev.Time = time.Now()
+	}
+	s.reporter.Report(ev)
+}
+
+// Callers returns the stack of the calling goroutine from the caller of
+// Callers, less skip more frames.
+func Callers(skip int) []Frame {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(skip+2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	var stack []Frame
+	for {
+		f, more := frames.Next()
+		stack = append(stack, Frame{Function: f.Function, File: f.File, Line: f.Line})
+		if !more {
+			return stack
+		}
+	}
+}
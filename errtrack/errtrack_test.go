@@ -0,0 +1,147 @@
+package errtrack
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type recorder struct{ events []Event }
+
+func (r *recorder) Report(ev Event) { r.events = append(r.events, ev) }
+
+func TestReport_FillsRequestScope(t *testing.T) {
+	rec := &recorder{}
+	ctx := NewContext(context.Background(), rec)
+
+	// Set deeper in the chain, on a derived context
+	inner := context.WithValue(ctx, struct{}{}, "x")
+	SetUser(inner, 7)
+	SetTenant(inner, 3)
+
+	Report(ctx, Event{Message: "boom"})
+	if len(rec.events) != 1 {
+		t.Fatalf("expected 1 event, got %d", len(rec.events))
+	}
+	ev := rec.events[0]
+	if ev.UserID != 7 || ev.TenantID != 3 || ev.Time.IsZero() {
+		t.Errorf("expected user, tenant and time filled in, got %+v", ev)
+	}
+}
+
+func TestReport_Disabled(t *testing.T) {
+	ctx := context.Background()
+	if Enabled(ctx) {
+		t.Error("expected reporting off without a reporter")
+	}
+	SetUser(ctx, 1)
+	Report(ctx, Event{Message: "ignored"})
+}
+
+func TestCallers(t *testing.T) {
+	stack := Callers(0)
+	if len(stack) == 0 || !strings.HasSuffix(stack[0].Function, "errtrack.TestCallers") {
+		t.Fatalf("expected the stack to start at the caller, got %+v", stack)
+	}
+}
+
+func TestParseDSN(t *testing.T) {
+	tests := []struct {
+		dsn      string
+		endpoint string
+		key      string
+	}{
+		{dsn: "https://abc@o1.ingest.sentry.io/42", endpoint: "https://o1.ingest.sentry.io/api/42/envelope/", key: "abc"},
+		{dsn: "http://abc@glitchtip.local:8000/tracker/7", endpoint: "http://glitchtip.local:8000/tracker/api/7/envelope/", key: "abc"},
+		{dsn: "https://o1.ingest.sentry.io/42"},
+		{dsn: "https://abc@o1.ingest.sentry.io/"},
+		{dsn: "ftp://abc@host/1"},
+		{dsn: "not a url"},
+	}
+
+	for _, tc := range tests {
+		endpoint, key, err := parseDSN(tc.dsn)
+		if tc.endpoint == "" {
+			if err == nil {
+				t.Errorf("parseDSN(%q): expected an error", tc.dsn)
+			}
+			continue
+		}
+		if err != nil || endpoint != tc.endpoint || key != tc.key {
+			t.Errorf("parseDSN(%q) = %q, %q, %v", tc.dsn, endpoint, key, err)
+		}
+	}
+}
+
+func TestSentryReporter(t *testing.T) {
+	type received struct {
+		path, auth string
+		lines      []string
+	}
+	got := make(chan received, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var lines []string
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		got <- received{path: r.URL.Path, auth: r.Header.Get("X-Sentry-Auth"), lines: lines}
+	}))
+	defer srv.Close()
+
+	dsn := strings.Replace(srv.URL, "http://", "http://pubkey@", 1) + "/5"
+	reporter, err := NewSentryReporter(dsn, SentryOptions{Environment: "test", Release: "v1.2.3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reporter.Report(Event{
+		Message:    "nil map",
+		Panic:      true,
+		StatusCode: 500,
+		ErrorCode:  "INTERNAL_ERROR",
+		Stack: []Frame{
+			{Function: "github.com/clementhaon/sandbox-api-go/handlers.(*TaskHandler).Get", File: "handlers/task.go", Line: 10},
+			{Function: "net/http.HandlerFunc.ServeHTTP", File: "server.go", Line: 2},
+		},
+		RequestID: "req-1",
+		TraceID:   "4bf92f3577b34da6a3ce929d0e0e4736",
+		SpanID:    "00f067aa0ba902b7",
+		Method:    "GET",
+		Path:      "/tasks/1",
+		UserID:    7,
+	})
+	reporter.Stop()
+
+	r := <-got
+	if r.path != "/api/5/envelope/" {
+		t.Errorf("unexpected path %q", r.path)
+	}
+	if !strings.Contains(r.auth, "sentry_key=pubkey") {
+		t.Errorf("unexpected auth header %q", r.auth)
+	}
+	if len(r.lines) != 3 || !strings.Contains(r.lines[1], `"type":"event"`) {
+		t.Fatalf("expected an envelope with one event, got %q", r.lines)
+	}
+
+	var ev sentryEvent
+	if err := json.Unmarshal([]byte(r.lines[2]), &ev); err != nil {
+		t.Fatal(err)
+	}
+	if ev.Level != "fatal" || ev.Environment != "test" || ev.Release != "v1.2.3" {
+		t.Errorf("unexpected event %+v", ev)
+	}
+	if ev.User["id"] != "7" || ev.Tags["request_id"] != "req-1" || ev.Contexts["trace"]["trace_id"] != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("expected request context in event, got user %v tags %v contexts %v", ev.User, ev.Tags, ev.Contexts)
+	}
+	frames := ev.Exception.Values[0].Stacktrace.Frames
+	if len(frames) != 2 || frames[1].Function != "(*TaskHandler).Get" || frames[1].Module != "github.com/clementhaon/sandbox-api-go/handlers" || !frames[1].InApp || frames[0].InApp {
+		t.Errorf("expected frames outermost first, got %+v", frames)
+	}
+
+	// Stopped reporters drop events
+	reporter.Report(Event{Message: "late"})
+}
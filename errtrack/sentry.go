@@ -0,0 +1,303 @@
+package errtrack
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/logger"
+)
+
+// SentryOptions describe the deployment events come from.
+type SentryOptions struct {
+	Environment string
+	Release     string
+	// BufferSize is how many events wait to be sent; more are dropped.
+	// 0 means 100.
+	BufferSize int
+}
+
+// SentryReporter sends events to Sentry, or to a tracker speaking its
+// envelope protocol (GlitchTip, self-hosted Sentry), from a background
+// goroutine.
+type SentryReporter struct {
+	dsn        string
+	endpoint   string
+	authHeader string
+	opts       SentryOptions
+	serverName string
+	client     *http.Client
+
+	events  chan Event
+	dropped atomic.Int64
+	done    chan struct{}
+	mu      sync.RWMutex // guards closed against sends on a closed channel
+	closed  bool
+}
+
+// NewSentryReporter starts a reporter for the project of dsn, such as
+// https://<key>@o1.ingest.sentry.io/<project id>.
+func NewSentryReporter(dsn string, opts SentryOptions) (*SentryReporter, error) {
+	endpoint, key, err := parseDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = 100
+	}
+	serverName, _ := os.Hostname()
+
+	s := &SentryReporter{
+		dsn:        dsn,
+		endpoint:   endpoint,
+		authHeader: "Sentry sentry_version=7, sentry_client=sandbox-api-go/1.0, sentry_key=" + key,
+		opts:       opts,
+		serverName: serverName,
+		client:     &http.Client{Timeout: 10 * time.Second},
+		events:     make(chan Event, opts.BufferSize),
+		done:       make(chan struct{}),
+	}
+	go s.run()
+	return s, nil
+}
+
+// parseDSN returns the envelope endpoint and the public key of a DSN.
+func parseDSN(dsn string) (endpoint, key string, err error) {
+	u, err := url.Parse(dsn)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", "", fmt.Errorf("invalid DSN: expected http(s)://<key>@<host>/<project id>")
+	}
+	key = u.User.Username()
+	if key == "" {
+		return "", "", fmt.Errorf("invalid DSN: missing public key")
+	}
+	prefix, project := "", strings.TrimPrefix(u.Path, "/")
+	if i := strings.LastIndex(project, "/"); i >= 0 {
+		prefix, project = "/"+project[:i], project[i+1:]
+	}
+	if _, err := strconv.Atoi(project); err != nil {
+		return "", "", fmt.Errorf("invalid DSN: missing project ID")
+	}
+	return u.Scheme + "://" + u.Host + prefix + "/api/" + project + "/envelope/", key, nil
+}
+
+// Report implements Reporter. It never blocks; ev is dropped if the buffer
+// is full.
+func (s *SentryReporter) Report(ev Event) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return
+	}
+	select {
+	case s.events <- ev:
+	default:
+		s.dropped.Add(1)
+	}
+}
+
+// Dropped returns the number of events dropped because the buffer was full.
+func (s *SentryReporter) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+// Stop sends the events still buffered, then stops the reporter.
+func (s *SentryReporter) Stop() {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.closed = true
+	close(s.events)
+	s.mu.Unlock()
+	<-s.done
+}
+
+func (s *SentryReporter) run() {
+	defer close(s.done)
+	for ev := range s.events {
+		if err := s.send(ev); err != nil {
+			logger.Warn("Failed to report error to the error tracker", map[string]interface{}{
+				"error":      err.Error(),
+				"request_id": ev.RequestID,
+			})
+		}
+	}
+}
+
+func (s *SentryReporter) send(ev Event) error {
+	body, err := s.envelope(ev)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-sentry-envelope")
+	req.Header.Set("X-Sentry-Auth", s.authHeader)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("error tracker responded %s", resp.Status)
+	}
+	return nil
+}
+
+type sentryFrame struct {
+	Function string `json:"function,omitempty"`
+	Module   string `json:"module,omitempty"`
+	AbsPath  string `json:"abs_path,omitempty"`
+	Lineno   int    `json:"lineno,omitempty"`
+	InApp    bool   `json:"in_app"`
+}
+
+type sentryException struct {
+	Type       string `json:"type"`
+	Value      string `json:"value"`
+	Stacktrace struct {
+		Frames []sentryFrame `json:"frames"`
+	} `json:"stacktrace"`
+}
+
+type sentryEvent struct {
+	EventID     string `json:"event_id"`
+	Timestamp   string `json:"timestamp"`
+	Platform    string `json:"platform"`
+	Level       string `json:"level"`
+	Logger      string `json:"logger"`
+	ServerName  string `json:"server_name,omitempty"`
+	Environment string `json:"environment,omitempty"`
+	Release     string `json:"release,omitempty"`
+	Exception   struct {
+		Values []sentryException `json:"values"`
+	} `json:"exception"`
+	Request struct {
+		Method  string            `json:"method,omitempty"`
+		URL     string            `json:"url,omitempty"`
+		Headers map[string]string `json:"headers,omitempty"`
+	} `json:"request"`
+	User     map[string]string         `json:"user,omitempty"`
+	Tags     map[string]string         `json:"tags"`
+	Contexts map[string]map[string]any `json:"contexts,omitempty"`
+}
+
+// envelope encodes ev as a Sentry envelope holding one event.
+func (s *SentryReporter) envelope(ev Event) ([]byte, error) {
+	id := make([]byte, 16)
+	rand.Read(id)
+
+	e := sentryEvent{
+		EventID:     hex.EncodeToString(id),
+		Timestamp:   ev.Time.UTC().Format(time.RFC3339Nano),
+		Platform:    "go",
+		Level:       "error",
+		Logger:      "http",
+		ServerName:  s.serverName,
+		Environment: s.opts.Environment,
+		Release:     s.opts.Release,
+		Tags:        map[string]string{},
+	}
+
+	exc := sentryException{Type: ev.ErrorCode, Value: ev.Message}
+	if ev.Panic {
+		e.Level = "fatal"
+		exc.Type = "panic"
+	}
+	// Sentry lists frames from the outermost call
+	exc.Stacktrace.Frames = make([]sentryFrame, 0, len(ev.Stack))
+	for i := len(ev.Stack) - 1; i >= 0; i-- {
+		exc.Stacktrace.Frames = append(exc.Stacktrace.Frames, toSentryFrame(ev.Stack[i]))
+	}
+	e.Exception.Values = []sentryException{exc}
+
+	e.Request.Method = ev.Method
+	e.Request.URL = ev.Path
+	if ev.UserAgent != "" {
+		e.Request.Headers = map[string]string{"User-Agent": ev.UserAgent}
+	}
+	if ev.UserID != 0 || ev.ClientIP != "" {
+		e.User = map[string]string{}
+		if ev.UserID != 0 {
+			e.User["id"] = strconv.Itoa(ev.UserID)
+		}
+		if ev.ClientIP != "" {
+			e.User["ip_address"] = ev.ClientIP
+		}
+	}
+
+	if ev.StatusCode != 0 {
+		e.Tags["status_code"] = strconv.Itoa(ev.StatusCode)
+	}
+	if ev.ErrorCode != "" {
+		e.Tags["error_code"] = ev.ErrorCode
+	}
+	if ev.RequestID != "" {
+		e.Tags["request_id"] = ev.RequestID
+	}
+	if ev.TenantID != 0 {
+		e.Tags["tenant_id"] = strconv.Itoa(ev.TenantID)
+	}
+	if ev.TraceID != "" {
+		e.Contexts = map[string]map[string]any{
+			"trace": {"trace_id": ev.TraceID, "span_id": ev.SpanID},
+		}
+	}
+
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+	header, err := json.Marshal(map[string]string{
+		"event_id": e.EventID,
+		"sent_at":  time.Now().UTC().Format(time.RFC3339Nano),
+		"dsn":      s.dsn,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.Write(header)
+	fmt.Fprintf(&buf, "\n{\"type\":\"event\",\"length\":%d}\n", len(payload))
+	buf.Write(payload)
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+// toSentryFrame splits the package path off the function name.
+func toSentryFrame(f Frame) sentryFrame {
+	module, function := "", f.Function
+	slash := strings.LastIndex(function, "/")
+	if dot := strings.Index(function[slash+1:], "."); dot >= 0 {
+		module, function = function[:slash+1+dot], function[slash+2+dot:]
+	}
+	return sentryFrame{
+		Function: function,
+		Module:   module,
+		AbsPath:  f.File,
+		Lineno:   f.Line,
+		InApp:    strings.HasPrefix(module, "github.com/clementhaon/sandbox-api-go"),
+	}
+}
@@ -0,0 +1,75 @@
+// Package events is an in-process publish/subscribe bus for domain events,
+// so that side effects of a change (notifications, activity feed) are
+// registered once in app.New instead of being called from every service
+// that makes the change.
+package events
+
+import (
+	"context"
+	"sync"
+
+	"github.com/clementhaon/sandbox-api-go/models"
+)
+
+// Type names a kind of domain event.
+type Type string
+
+const (
+	TaskCreated   Type = "task_created"
+	TaskAssigned  Type = "task_assigned" // Task.AssigneeID is the new assignee
+	TaskCompleted Type = "task_completed"
+)
+
+// Event is something that happened to a task.
+type Event struct {
+	Type    Type
+	ActorID int // user who caused the event
+	Task    models.Task
+}
+
+// Handler reacts to an event. It runs in the publisher's goroutine with the
+// publisher's context, which carries the request's tenant; a handler that
+// fails logs its error, the change that raised the event stands.
+type Handler func(ctx context.Context, e Event)
+
+// Bus delivers each published event to the handlers subscribed to its type,
+// in subscription order. A nil *Bus drops every event.
+type Bus struct {
+	mu       sync.RWMutex
+	handlers map[Type][]Handler
+}
+
+func NewBus() *Bus {
+	return &Bus{handlers: make(map[Type][]Handler)}
+}
+
+// Subscribe registers h for events of type t.
+func (b *Bus) Subscribe(t Type, h Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[t] = append(b.handlers[t], h)
+}
+
+// Publish runs the handlers of e.Type before returning.
+func (b *Bus) Publish(ctx context.Context, e Event) {
+	if b == nil {
+		return
+	}
+	b.mu.RLock()
+	handlers := b.handlers[e.Type]
+	b.mu.RUnlock()
+
+	for _, h := range handlers {
+		h(ctx, e)
+	}
+}
+
+// Watchers returns the users following the event's task: its owner and its
+// assignee, once each.
+func (e Event) Watchers() []int {
+	watchers := []int{e.Task.UserID}
+	if e.Task.AssigneeID != nil && *e.Task.AssigneeID != e.Task.UserID {
+		watchers = append(watchers, *e.Task.AssigneeID)
+	}
+	return watchers
+}
@@ -0,0 +1,24 @@
+package events
+
+import (
+	"context"
+	"testing"
+)
+
+func TestBus_Publish(t *testing.T) {
+	bus := NewBus()
+	var got []string
+	bus.Subscribe(TaskCompleted, func(ctx context.Context, e Event) { got = append(got, "first") })
+	bus.Subscribe(TaskCompleted, func(ctx context.Context, e Event) { got = append(got, "second") })
+	bus.Subscribe(TaskCreated, func(ctx context.Context, e Event) { got = append(got, "created") })
+
+	bus.Publish(context.Background(), Event{Type: TaskCompleted})
+	if len(got) != 2 || got[0] != "first" || got[1] != "second" {
+		t.Errorf("expected both completion handlers in order, got %v", got)
+	}
+}
+
+func TestBus_NilDropsEvents(t *testing.T) {
+	var bus *Bus
+	bus.Publish(context.Background(), Event{Type: TaskCreated})
+}
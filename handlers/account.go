@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/i18n"
+	"github.com/clementhaon/sandbox-api-go/middleware"
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/respond"
+	"github.com/clementhaon/sandbox-api-go/services"
+)
+
+type AccountHandler struct {
+	accountService services.AccountService
+}
+
+func NewAccountHandler(s services.AccountService) *AccountHandler {
+	return &AccountHandler{accountService: s}
+}
+
+// HandleChangeEmail sends a verification token to the requested address.
+// The email is switched by HandleConfirmEmail.
+func (h *AccountHandler) HandleChangeEmail(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*models.Claims)
+	if !ok {
+		return errors.NewInternalError().WithDetails(map[string]interface{}{
+			"issue": "user_context_missing",
+		})
+	}
+
+	var req models.ChangeEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return errors.NewInvalidJSONError()
+	}
+
+	if err := h.accountService.RequestEmailChange(r.Context(), claims.UserID, req); err != nil {
+		return err
+	}
+
+	respond.WriteJSON(w, http.StatusAccepted, map[string]interface{}{
+		"message": i18n.T(i18n.FromRequest(r), "profile.email_verification_sent"),
+	}, nil)
+	return nil
+}
+
+// HandleConfirmEmail completes an email change. It is public: the token
+// itself proves access to the new address.
+func (h *AccountHandler) HandleConfirmEmail(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req models.ConfirmEmailChangeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return errors.NewInvalidJSONError()
+	}
+
+	user, err := h.accountService.ConfirmEmailChange(r.Context(), req)
+	if err != nil {
+		return err
+	}
+
+	respond.OK(w, map[string]interface{}{
+		"message": i18n.T(i18n.FromRequest(r), "profile.email_changed"),
+		"user":    user,
+	})
+	return nil
+}
+
+func (h *AccountHandler) HandleChangeUsername(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*models.Claims)
+	if !ok {
+		return errors.NewInternalError().WithDetails(map[string]interface{}{
+			"issue": "user_context_missing",
+		})
+	}
+
+	var req models.ChangeUsernameRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return errors.NewInvalidJSONError()
+	}
+
+	user, err := h.accountService.ChangeUsername(r.Context(), claims.UserID, req)
+	if err != nil {
+		return err
+	}
+
+	respond.OK(w, map[string]interface{}{
+		"message": i18n.T(i18n.FromRequest(r), "profile.username_changed"),
+		"user":    user,
+	})
+	return nil
+}
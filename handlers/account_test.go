@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/mocks"
+	"github.com/clementhaon/sandbox-api-go/models"
+)
+
+func TestAccountHandler_ChangeEmail(t *testing.T) {
+	var gotUserID int
+	var gotEmail string
+	svc := &mocks.MockAccountService{
+		RequestEmailChangeFn: func(ctx context.Context, userID int, req models.ChangeEmailRequest) error {
+			gotUserID, gotEmail = userID, req.Email
+			return nil
+		},
+	}
+
+	handler := NewAccountHandler(svc)
+	body := strings.NewReader(`{"email":"new@test.com"}`)
+	req := withUserContext(httptest.NewRequest(http.MethodPut, "/profile/email", body), 7)
+	w := httptest.NewRecorder()
+
+	if err := handler.HandleChangeEmail(w, req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if w.Code != http.StatusAccepted {
+		t.Errorf("expected status 202, got %d", w.Code)
+	}
+	if gotUserID != 7 || gotEmail != "new@test.com" {
+		t.Errorf("unexpected call: user %d email %q", gotUserID, gotEmail)
+	}
+}
+
+func TestAccountHandler_ConfirmEmail_InvalidToken(t *testing.T) {
+	svc := &mocks.MockAccountService{
+		ConfirmEmailChangeFn: func(ctx context.Context, req models.ConfirmEmailChangeRequest) (models.User, error) {
+			return models.User{}, errors.NewInvalidTokenError()
+		},
+	}
+
+	handler := NewAccountHandler(svc)
+	req := httptest.NewRequest(http.MethodPost, "/auth/verify-email", strings.NewReader(`{"token":"nope"}`))
+	w := httptest.NewRecorder()
+
+	err := handler.HandleConfirmEmail(w, req)
+	if !errors.Is(err, errors.ErrInvalidToken) {
+		t.Errorf("expected invalid token, got %v", err)
+	}
+}
+
+func TestAccountHandler_ChangeUsername(t *testing.T) {
+	svc := &mocks.MockAccountService{
+		ChangeUsernameFn: func(ctx context.Context, userID int, req models.ChangeUsernameRequest) (models.User, error) {
+			return models.User{ID: userID, Username: req.Username}, nil
+		},
+	}
+
+	handler := NewAccountHandler(svc)
+	req := withUserContext(httptest.NewRequest(http.MethodPut, "/profile/username", strings.NewReader(`{"username":"bob"}`)), 1)
+	w := httptest.NewRecorder()
+
+	if err := handler.HandleChangeUsername(w, req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var data struct {
+		User models.User `json:"user"`
+	}
+	decodeData(t, w, &data)
+	if data.User.Username != "bob" {
+		t.Errorf("expected username bob, got %q", data.User.Username)
+	}
+}
+
+func TestAccountHandler_InvalidJSON(t *testing.T) {
+	handler := NewAccountHandler(&mocks.MockAccountService{})
+	req := withUserContext(httptest.NewRequest(http.MethodPut, "/profile/username", strings.NewReader(`{`)), 1)
+
+	err := handler.HandleChangeUsername(httptest.NewRecorder(), req)
+	if !errors.Is(err, errors.ErrInvalidJSON) {
+		t.Errorf("expected invalid JSON, got %v", err)
+	}
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/middleware"
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/respond"
+	"github.com/clementhaon/sandbox-api-go/services"
+)
+
+type ActivityHandler struct {
+	activityService services.ActivityService
+}
+
+func NewActivityHandler(s services.ActivityService) *ActivityHandler {
+	return &ActivityHandler{activityService: s}
+}
+
+// ListActivity returns the user's activity feed, newest first, from the
+// ?before= cursor, with the cursor of the next page in
+// meta.pagination.nextCursor.
+func (h *ActivityHandler) ListActivity(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*models.Claims)
+	if !ok {
+		return errors.NewInternalError().WithDetails(map[string]interface{}{
+			"issue": "user_context_missing",
+		})
+	}
+
+	var before *models.TaskCursor
+	if token := r.URL.Query().Get("before"); token != "" {
+		cursor, err := models.DecodeTaskCursor(token)
+		if err != nil {
+			return errors.NewInvalidFormatError("before", "cursor returned by a previous call")
+		}
+		before = &cursor
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	activities, page, err := h.activityService.Feed(r.Context(), claims.UserID, before, limit)
+	if err != nil {
+		return err
+	}
+
+	respond.Paginated(w, activities, page)
+	return nil
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/clementhaon/sandbox-api-go/mocks"
+	"github.com/clementhaon/sandbox-api-go/models"
+)
+
+func TestActivityHandler_ListActivity(t *testing.T) {
+	svc := &mocks.MockActivityService{
+		FeedFn: func(ctx context.Context, userID int, before *models.TaskCursor, limit int) ([]models.Activity, models.CursorPage, error) {
+			if userID != 42 || before != nil || limit != 10 {
+				t.Errorf("unexpected feed request: user %d, before %v, limit %d", userID, before, limit)
+			}
+			return []models.Activity{{ID: 1, Type: "task_created"}}, models.CursorPage{Limit: limit}, nil
+		},
+	}
+
+	handler := NewActivityHandler(svc)
+	req := httptest.NewRequest(http.MethodGet, "/activity?limit=10", nil)
+	req = withUserContext(req, 42)
+	w := httptest.NewRecorder()
+
+	if err := handler.ListActivity(w, req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var activities []models.Activity
+	decodeData(t, w, &activities)
+	if len(activities) != 1 {
+		t.Errorf("expected 1 activity, got %d", len(activities))
+	}
+}
+
+func TestActivityHandler_ListActivity_InvalidCursor(t *testing.T) {
+	handler := NewActivityHandler(&mocks.MockActivityService{})
+
+	req := httptest.NewRequest(http.MethodGet, "/activity?before=not*a*cursor", nil)
+	req = withUserContext(req, 42)
+	w := httptest.NewRecorder()
+
+	if err := handler.ListActivity(w, req); err == nil {
+		t.Fatal("expected error for an invalid cursor")
+	}
+}
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/middleware"
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/respond"
+	"github.com/clementhaon/sandbox-api-go/services"
+)
+
+type AnnouncementHandler struct {
+	announcementService services.AnnouncementService
+}
+
+func NewAnnouncementHandler(s services.AnnouncementService) *AnnouncementHandler {
+	return &AnnouncementHandler{announcementService: s}
+}
+
+// ListActiveAnnouncements returns the announcements active now that the
+// user has not dismissed.
+func (h *AnnouncementHandler) ListActiveAnnouncements(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*models.Claims)
+	if !ok {
+		return errors.NewInternalError().WithDetails(map[string]interface{}{
+			"issue": "user_context_missing",
+		})
+	}
+
+	announcements, err := h.announcementService.ListActive(r.Context(), claims.UserID)
+	if err != nil {
+		return err
+	}
+
+	respond.OK(w, announcements)
+	return nil
+}
+
+func (h *AnnouncementHandler) DismissAnnouncement(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*models.Claims)
+	if !ok {
+		return errors.NewInternalError().WithDetails(map[string]interface{}{
+			"issue": "user_context_missing",
+		})
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return errors.NewBadRequestError("Invalid announcement ID")
+	}
+
+	if err := h.announcementService.Dismiss(r.Context(), id, claims.UserID); err != nil {
+		return err
+	}
+
+	respond.NoContent(w)
+	return nil
+}
+
+// ListAnnouncements returns every announcement, past and scheduled ones
+// included, for admins.
+func (h *AnnouncementHandler) ListAnnouncements(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	announcements, err := h.announcementService.List(r.Context())
+	if err != nil {
+		return err
+	}
+
+	respond.OK(w, announcements)
+	return nil
+}
+
+func (h *AnnouncementHandler) CreateAnnouncement(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*models.Claims)
+	if !ok {
+		return errors.NewInternalError().WithDetails(map[string]interface{}{
+			"issue": "user_context_missing",
+		})
+	}
+
+	var req models.AnnouncementRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return errors.NewInvalidJSONError()
+	}
+
+	announcement, err := h.announcementService.Create(r.Context(), claims.UserID, req)
+	if err != nil {
+		return err
+	}
+
+	respond.Created(w, announcement)
+	return nil
+}
+
+func (h *AnnouncementHandler) UpdateAnnouncement(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return errors.NewBadRequestError("Invalid announcement ID")
+	}
+
+	var req models.AnnouncementRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return errors.NewInvalidJSONError()
+	}
+
+	announcement, err := h.announcementService.Update(r.Context(), id, req)
+	if err != nil {
+		return err
+	}
+
+	respond.OK(w, announcement)
+	return nil
+}
+
+func (h *AnnouncementHandler) DeleteAnnouncement(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return errors.NewBadRequestError("Invalid announcement ID")
+	}
+
+	if err := h.announcementService.Delete(r.Context(), id); err != nil {
+		return err
+	}
+
+	respond.NoContent(w)
+	return nil
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/clementhaon/sandbox-api-go/mocks"
+	"github.com/clementhaon/sandbox-api-go/models"
+)
+
+func TestAnnouncementHandler_DismissAnnouncement(t *testing.T) {
+	var dismissedID, userID int
+	svc := &mocks.MockAnnouncementService{
+		DismissFn: func(ctx context.Context, id int, uid int) error {
+			dismissedID, userID = id, uid
+			return nil
+		},
+	}
+
+	req := withUserContext(httptest.NewRequest(http.MethodPost, "/announcements/4/dismiss", nil), 9)
+	req.SetPathValue("id", "4")
+	w := httptest.NewRecorder()
+	if err := NewAnnouncementHandler(svc).DismissAnnouncement(w, req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if dismissedID != 4 || userID != 9 {
+		t.Errorf("expected announcement 4 dismissed by 9, got %d and %d", dismissedID, userID)
+	}
+	if w.Code != http.StatusNoContent {
+		t.Errorf("expected 204, got %d", w.Code)
+	}
+}
+
+func TestAnnouncementHandler_CreateAnnouncement(t *testing.T) {
+	var createdBy int
+	svc := &mocks.MockAnnouncementService{
+		CreateFn: func(ctx context.Context, userID int, req models.AnnouncementRequest) (models.Announcement, error) {
+			createdBy = userID
+			return models.Announcement{ID: 1, Title: req.Title, Level: req.Level}, nil
+		},
+	}
+
+	body := `{"title":"Maintenance","body":"Tonight","level":"maintenance"}`
+	req := withUserContext(httptest.NewRequest(http.MethodPost, "/admin/announcements", strings.NewReader(body)), 2)
+	w := httptest.NewRecorder()
+	if err := NewAnnouncementHandler(svc).CreateAnnouncement(w, req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if createdBy != 2 {
+		t.Errorf("expected the admin as author, got %d", createdBy)
+	}
+
+	var announcement models.Announcement
+	decodeData(t, w, &announcement)
+	if announcement.Title != "Maintenance" || announcement.Level != models.AnnouncementLevelMaintenance {
+		t.Errorf("unexpected announcement: %+v", announcement)
+	}
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/respond"
+	"github.com/clementhaon/sandbox-api-go/services"
+)
+
+type AuditHandler struct {
+	auditService services.AuditService
+}
+
+func NewAuditHandler(s services.AuditService) *AuditHandler {
+	return &AuditHandler{auditService: s}
+}
+
+func (h *AuditHandler) ListAuditLogs(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	q := r.URL.Query()
+	page, _ := strconv.Atoi(q.Get("page"))
+	pageSize, _ := strconv.Atoi(q.Get("pageSize"))
+
+	params := models.AuditLogListParams{
+		Page:       page,
+		PageSize:   pageSize,
+		Action:     q.Get("action"),
+		TargetType: q.Get("targetType"),
+	}
+
+	if v := q.Get("actorId"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			return errors.NewBadRequestError("Invalid actorId")
+		}
+		params.ActorID = &id
+	}
+	if v := q.Get("targetId"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			return errors.NewBadRequestError("Invalid targetId")
+		}
+		params.TargetID = &id
+	}
+	if v := q.Get("from"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return errors.NewInvalidFormatError("from", "RFC 3339 timestamp")
+		}
+		params.From = &t
+	}
+	if v := q.Get("to"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return errors.NewInvalidFormatError("to", "RFC 3339 timestamp")
+		}
+		params.To = &t
+	}
+
+	response, err := h.auditService.List(r.Context(), params)
+	if err != nil {
+		return err
+	}
+
+	respond.Paginated(w, response.Data, response.Pagination)
+	return nil
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/mocks"
+	"github.com/clementhaon/sandbox-api-go/models"
+)
+
+func TestAuditHandler_ListAuditLogs(t *testing.T) {
+	var received models.AuditLogListParams
+	svc := &mocks.MockAuditService{
+		ListFn: func(ctx context.Context, params models.AuditLogListParams) (models.AuditLogListResponse, error) {
+			received = params
+			return models.AuditLogListResponse{
+				Data:       []models.AuditLog{{ID: 1, Action: models.AuditActionLogin}},
+				Pagination: models.Pagination{Page: 1, PageSize: 20, Total: 1, TotalPages: 1},
+			}, nil
+		},
+	}
+
+	handler := NewAuditHandler(svc)
+	req := httptest.NewRequest(http.MethodGet, "/admin/audit-logs?action=auth.login&actorId=5&from=2024-01-01T00:00:00Z&pageSize=20", nil)
+	w := httptest.NewRecorder()
+
+	if err := handler.ListAuditLogs(w, req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if received.Action != models.AuditActionLogin {
+		t.Errorf("expected action filter, got %q", received.Action)
+	}
+	if received.ActorID == nil || *received.ActorID != 5 {
+		t.Errorf("expected actor 5, got %v", received.ActorID)
+	}
+	if received.From == nil || received.From.Year() != 2024 {
+		t.Errorf("expected from filter, got %v", received.From)
+	}
+	if received.PageSize != 20 {
+		t.Errorf("expected page size 20, got %d", received.PageSize)
+	}
+
+	var logs []models.AuditLog
+	envelope := decodeData(t, w, &logs)
+	if len(logs) != 1 {
+		t.Errorf("expected 1 log, got %d", len(logs))
+	}
+	if _, ok := envelope.Meta["pagination"]; !ok {
+		t.Error("expected pagination in meta")
+	}
+}
+
+func TestAuditHandler_ListAuditLogs_InvalidFilter(t *testing.T) {
+	handler := NewAuditHandler(&mocks.MockAuditService{})
+
+	for _, query := range []string{"actorId=abc", "targetId=x", "from=yesterday", "to=2024-13-01"} {
+		req := httptest.NewRequest(http.MethodGet, "/admin/audit-logs?"+query, nil)
+		err := handler.ListAuditLogs(httptest.NewRecorder(), req)
+		if _, ok := errors.IsAppError(err); !ok {
+			t.Errorf("%s: expected AppError, got %v", query, err)
+		}
+	}
+}
@@ -4,13 +4,16 @@ import (
 	"encoding/json"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 
 	"github.com/clementhaon/sandbox-api-go/auth"
 	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/i18n"
 	"github.com/clementhaon/sandbox-api-go/logger"
 	"github.com/clementhaon/sandbox-api-go/middleware"
 	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/respond"
 	"github.com/clementhaon/sandbox-api-go/services"
 )
 
@@ -37,6 +40,7 @@ func (h *AuthHandler) HandleRegister(w http.ResponseWriter, r *http.Request) err
 
 	user, token, err := h.authService.Register(r.Context(), req)
 	if err != nil {
+		setSignupRetryAfter(w, err)
 		return err
 	}
 
@@ -44,26 +48,39 @@ func (h *AuthHandler) HandleRegister(w http.ResponseWriter, r *http.Request) err
 	http.SetCookie(w, &http.Cookie{
 		Name:     "auth_token",
 		Value:    token,
-		Path:     "/",
+		Path:     middleware.CookiePath(r),
 		MaxAge:   24 * 60 * 60,
 		HttpOnly: true,
 		Secure:   isProduction,
 		SameSite: http.SameSiteStrictMode,
 	})
 
-	csrfToken := middleware.SetCSRFCookie(w, isProduction)
+	csrfToken := middleware.SetCSRFCookie(w, r, isProduction, 24*60*60)
 
 	response := models.AuthResponse{
 		User:    user,
-		Message: "Registration successful",
+		Message: i18n.T(i18n.FromRequest(r), "auth.registered"),
 	}
 
 	w.Header().Set("X-CSRF-Token", csrfToken)
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(response)
+	respond.Created(w, response)
 	return nil
 }
 
+// setSignupRetryAfter mirrors the retryAfter detail of a signup rate limit
+// error in the Retry-After header.
+func setSignupRetryAfter(w http.ResponseWriter, err error) {
+	appErr, ok := errors.IsAppError(err)
+	if !ok || appErr.Code != errors.ErrSignupRateLimited {
+		return
+	}
+	if details, ok := appErr.Details.(map[string]interface{}); ok {
+		if seconds, ok := details["retryAfter"].(int); ok {
+			w.Header().Set("Retry-After", strconv.Itoa(seconds))
+		}
+	}
+}
+
 func (h *AuthHandler) HandleLogin(w http.ResponseWriter, r *http.Request) error {
 	w.Header().Set("Content-Type", "application/json")
 
@@ -80,26 +97,53 @@ func (h *AuthHandler) HandleLogin(w http.ResponseWriter, r *http.Request) error
 		return err
 	}
 
+	// A remembered login outlives the browser; otherwise the cookies are
+	// dropped when it closes, and the token expires within a day
+	maxAge := 0
+	if req.RememberMe {
+		maxAge = int(h.jwtManager.RememberMeTTL().Seconds())
+	}
+
 	isProduction := os.Getenv("APP_ENV") == "production"
 	http.SetCookie(w, &http.Cookie{
 		Name:     "auth_token",
 		Value:    token,
-		Path:     "/",
-		MaxAge:   24 * 60 * 60,
+		Path:     middleware.CookiePath(r),
+		MaxAge:   maxAge,
 		HttpOnly: true,
 		Secure:   isProduction,
 		SameSite: http.SameSiteStrictMode,
 	})
 
-	csrfToken := middleware.SetCSRFCookie(w, isProduction)
+	csrfToken := middleware.SetCSRFCookie(w, r, isProduction, maxAge)
 	w.Header().Set("X-CSRF-Token", csrfToken)
 
 	response := models.AuthResponse{
 		User:    user,
-		Message: "Login successful",
+		Message: i18n.T(i18n.FromRequest(r), "auth.logged_in"),
+	}
+
+	respond.OK(w, response)
+	return nil
+}
+
+// HandlePasswordStrength scores a password for a signup or password change
+// form, without storing it.
+func (h *AuthHandler) HandlePasswordStrength(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+
+	var req models.PasswordStrengthRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return errors.NewInvalidJSONError()
+	}
+
+	strength, err := h.authService.PasswordStrength(r.Context(), req)
+	if err != nil {
+		return err
 	}
 
-	json.NewEncoder(w).Encode(response)
+	respond.OK(w, strength)
 	return nil
 }
 
@@ -111,7 +155,9 @@ func (h *AuthHandler) HandleLogout(w http.ResponseWriter, r *http.Request) error
 	// Extract and blacklist the current token
 	if token := h.extractToken(r); token != "" {
 		if claims, err := h.jwtManager.ValidateToken(token); err == nil {
-			h.blacklist.Add(token, claims.ExpiresAt)
+			if err := h.blacklist.Add(r.Context(), token, claims.ExpiresAt); err != nil {
+				return errors.NewServiceUnavailableError().WithCause(err)
+			}
 		}
 	}
 
@@ -119,16 +165,16 @@ func (h *AuthHandler) HandleLogout(w http.ResponseWriter, r *http.Request) error
 	http.SetCookie(w, &http.Cookie{
 		Name:     "auth_token",
 		Value:    "",
-		Path:     "/",
+		Path:     middleware.CookiePath(r),
 		MaxAge:   -1,
 		HttpOnly: true,
 		Secure:   isProduction,
 		SameSite: http.SameSiteStrictMode,
 	})
-	middleware.ClearCSRFCookie(w, isProduction)
+	middleware.ClearCSRFCookie(w, r, isProduction)
 
-	json.NewEncoder(w).Encode(map[string]string{
-		"message": "Logout successful",
+	respond.OK(w, map[string]string{
+		"message": i18n.T(i18n.FromRequest(r), "auth.logged_out"),
 	})
 	return nil
 }
@@ -144,7 +190,36 @@ func (h *AuthHandler) HandleGetUser(w http.ResponseWriter, r *http.Request) erro
 		})
 	}
 
-	json.NewEncoder(w).Encode(claims)
+	respond.OK(w, claims)
+	return nil
+}
+
+// HandleImpersonate issues a short-lived token acting as the user in the
+// path, for support staff to reproduce an issue. The token is returned in
+// the body, to be sent as a Bearer token; the admin's session cookie is left
+// untouched.
+func (h *AuthHandler) HandleImpersonate(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*models.Claims)
+	if !ok {
+		logger.ErrorContext(r.Context(), "Missing user context in authenticated request", nil)
+		return errors.NewInternalError().WithDetails(map[string]interface{}{
+			"issue": "user_context_missing",
+		})
+	}
+
+	targetID, err := strconv.Atoi(r.PathValue("userID"))
+	if err != nil {
+		return errors.NewBadRequestError("Invalid user ID")
+	}
+
+	response, err := h.authService.Impersonate(r.Context(), claims.UserID, targetID)
+	if err != nil {
+		return err
+	}
+
+	respond.Created(w, response)
 	return nil
 }
 
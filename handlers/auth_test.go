@@ -10,6 +10,7 @@ import (
 
 	"github.com/clementhaon/sandbox-api-go/auth"
 	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/kvstore"
 	"github.com/clementhaon/sandbox-api-go/mocks"
 	"github.com/clementhaon/sandbox-api-go/models"
 )
@@ -25,7 +26,7 @@ func newTestJWTManager(t *testing.T) *auth.JWTManager {
 
 func newTestAuthHandler(svc *mocks.MockAuthService) *AuthHandler {
 	jm, _ := auth.NewJWTManager("test-secret-key-minimum-16-chars")
-	bl := auth.NewTokenBlacklist()
+	bl := auth.NewTokenBlacklist(kvstore.NewMemoryStore())
 	return NewAuthHandler(svc, jm, bl)
 }
 
@@ -72,7 +73,7 @@ func TestAuthHandler_Register_Success(t *testing.T) {
 	}
 
 	var resp models.AuthResponse
-	json.NewDecoder(w.Body).Decode(&resp)
+	decodeData(t, w, &resp)
 	if resp.User.Username != "johndoe" {
 		t.Errorf("expected username johndoe, got %s", resp.User.Username)
 	}
@@ -93,6 +94,28 @@ func TestAuthHandler_Register_InvalidJSON(t *testing.T) {
 	}
 }
 
+func TestAuthHandler_Register_SignupRateLimited(t *testing.T) {
+	svc := &mocks.MockAuthService{
+		RegisterFn: func(ctx context.Context, req models.RegisterRequest) (models.User, string, error) {
+			return models.User{}, "", errors.NewSignupRateLimitedError().
+				WithDetails(map[string]interface{}{"retryAfter": 720})
+		},
+	}
+
+	handler := newTestAuthHandler(svc)
+	body, _ := json.Marshal(models.RegisterRequest{Username: "johndoe", Email: "john@example.com", Password: "Password1"})
+	req := httptest.NewRequest(http.MethodPost, "/auth/register", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	err := handler.HandleRegister(w, req)
+	if !errors.Is(err, errors.ErrSignupRateLimited) {
+		t.Fatalf("expected SIGNUP_RATE_LIMITED, got %v", err)
+	}
+	if got := w.Header().Get("Retry-After"); got != "720" {
+		t.Errorf("Retry-After = %q, want 720", got)
+	}
+}
+
 func TestAuthHandler_Login_Success(t *testing.T) {
 	svc := &mocks.MockAuthService{
 		LoginFn: func(ctx context.Context, req models.LoginRequest) (models.User, string, error) {
@@ -121,12 +144,49 @@ func TestAuthHandler_Login_Success(t *testing.T) {
 	}
 
 	var resp models.AuthResponse
-	json.NewDecoder(w.Body).Decode(&resp)
+	decodeData(t, w, &resp)
 	if resp.Message != "Login successful" {
 		t.Errorf("expected 'Login successful', got '%s'", resp.Message)
 	}
 }
 
+func TestAuthHandler_Login_RememberMe(t *testing.T) {
+	svc := &mocks.MockAuthService{
+		LoginFn: func(ctx context.Context, req models.LoginRequest) (models.User, string, error) {
+			return models.User{ID: 1, Email: req.Email}, "jwt-token", nil
+		},
+	}
+	handler := newTestAuthHandler(svc)
+
+	tests := []struct {
+		name       string
+		rememberMe bool
+		wantMaxAge int
+	}{
+		{"browser session", false, 0},
+		{"remembered", true, 30 * 24 * 60 * 60},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(models.LoginRequest{Email: "john@example.com", Password: "Password1", RememberMe: tt.rememberMe})
+			w := httptest.NewRecorder()
+			if err := handler.HandleLogin(w, httptest.NewRequest(http.MethodPost, "/auth/login", bytes.NewReader(body))); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			cookies := w.Result().Cookies()
+			if len(cookies) != 2 {
+				t.Fatalf("expected the auth and CSRF cookies, got %v", cookies)
+			}
+			for _, c := range cookies {
+				if c.MaxAge != tt.wantMaxAge {
+					t.Errorf("cookie %s: expected Max-Age %d, got %d", c.Name, tt.wantMaxAge, c.MaxAge)
+				}
+			}
+		})
+	}
+}
+
 func TestAuthHandler_Login_ServiceError(t *testing.T) {
 	svc := &mocks.MockAuthService{
 		LoginFn: func(ctx context.Context, req models.LoginRequest) (models.User, string, error) {
@@ -181,8 +241,9 @@ func TestAuthHandler_Logout(t *testing.T) {
 
 func TestAuthHandler_Logout_BlacklistsToken(t *testing.T) {
 	jm := newTestJWTManager(t)
-	bl := auth.NewTokenBlacklist()
-	defer bl.Stop()
+	store := kvstore.NewMemoryStore()
+	defer store.Stop()
+	bl := auth.NewTokenBlacklist(store)
 	handler := NewAuthHandler(&mocks.MockAuthService{}, jm, bl)
 
 	// Generate a valid token
@@ -203,7 +264,40 @@ func TestAuthHandler_Logout_BlacklistsToken(t *testing.T) {
 	}
 
 	// Token should now be blacklisted
-	if !bl.IsBlacklisted(token) {
+	if revoked, _ := bl.IsBlacklisted(context.Background(), token); !revoked {
 		t.Error("expected token to be blacklisted after logout")
 	}
 }
+
+func TestAuthHandler_Impersonate(t *testing.T) {
+	var adminID, targetID int
+	svc := &mocks.MockAuthService{
+		ImpersonateFn: func(ctx context.Context, a, target int) (models.ImpersonationResponse, error) {
+			adminID, targetID = a, target
+			return models.ImpersonationResponse{Token: "tok", User: models.User{ID: target}}, nil
+		},
+	}
+	handler := newTestAuthHandler(svc)
+
+	req := withUserContext(httptest.NewRequest(http.MethodPost, "/admin/impersonate/5", nil), 1)
+	req.SetPathValue("userID", "5")
+	w := httptest.NewRecorder()
+	if err := handler.HandleImpersonate(w, req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if adminID != 1 || targetID != 5 {
+		t.Errorf("expected admin 1 impersonating 5, got %d and %d", adminID, targetID)
+	}
+	if w.Code != http.StatusCreated {
+		t.Errorf("expected 201, got %d", w.Code)
+	}
+	if cookies := w.Result().Cookies(); len(cookies) != 0 {
+		t.Errorf("expected the session cookie to be left alone, got %v", cookies)
+	}
+	var resp models.ImpersonationResponse
+	decodeData(t, w, &resp)
+	if resp.Token != "tok" || resp.User.ID != 5 {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+}
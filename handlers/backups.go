@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/middleware"
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/respond"
+	"github.com/clementhaon/sandbox-api-go/services"
+)
+
+type BackupHandler struct {
+	backupService services.BackupService
+}
+
+func NewBackupHandler(s services.BackupService) *BackupHandler {
+	return &BackupHandler{backupService: s}
+}
+
+func (h *BackupHandler) ListBackups(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	backups, err := h.backupService.List(r.Context())
+	if err != nil {
+		return err
+	}
+
+	respond.OK(w, backups)
+	return nil
+}
+
+func (h *BackupHandler) CreateBackup(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*models.Claims)
+	if !ok {
+		return errors.NewInternalError().WithDetails(map[string]interface{}{"issue": "user_context_missing"})
+	}
+
+	job, err := h.backupService.Create(r.Context(), claims.UserID)
+	if err != nil {
+		return err
+	}
+
+	acceptBackupJob(w, r, job)
+	return nil
+}
+
+// RestoreBackup replaces all the data with that of the backup, once the
+// job runs.
+func (h *BackupHandler) RestoreBackup(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*models.Claims)
+	if !ok {
+		return errors.NewInternalError().WithDetails(map[string]interface{}{"issue": "user_context_missing"})
+	}
+
+	job, err := h.backupService.Restore(r.Context(), claims.UserID, r.PathValue("name"))
+	if err != nil {
+		return err
+	}
+
+	acceptBackupJob(w, r, job)
+	return nil
+}
+
+func (h *BackupHandler) GetBackupJob(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	job, err := h.backupService.GetJob(r.Context(), r.PathValue("id"))
+	if err != nil {
+		return err
+	}
+
+	respond.OK(w, job)
+	return nil
+}
+
+// acceptBackupJob answers 202 with the queued job, where to follow its
+// progress in the Location header.
+func acceptBackupJob(w http.ResponseWriter, r *http.Request, job models.BackupJob) {
+	w.Header().Set("Location", middleware.BasePath(r.Context())+"/admin/backups/jobs/"+job.ID)
+	respond.WriteJSON(w, http.StatusAccepted, job, nil)
+}
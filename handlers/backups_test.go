@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/mocks"
+	"github.com/clementhaon/sandbox-api-go/models"
+)
+
+func TestBackupHandler_RestoreBackup(t *testing.T) {
+	svc := &mocks.MockBackupService{
+		RestoreFn: func(ctx context.Context, userID int, name string) (models.BackupJob, error) {
+			if name != "20261016T120000Z" {
+				return models.BackupJob{}, errors.NewNotFoundError("Backup")
+			}
+			return models.BackupJob{ID: "job-1", Type: models.BackupJobRestore, Backup: name, Status: "pending"}, nil
+		},
+	}
+	handler := NewBackupHandler(svc)
+
+	w := httptest.NewRecorder()
+	req := withUserContext(httptest.NewRequest(http.MethodPost, "/admin/backups/20261016T120000Z/restore", nil), 1)
+	req.SetPathValue("name", "20261016T120000Z")
+	if err := handler.RestoreBackup(w, req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if w.Code != http.StatusAccepted {
+		t.Errorf("expected status 202, got %d", w.Code)
+	}
+	if loc := w.Header().Get("Location"); loc != "/admin/backups/jobs/job-1" {
+		t.Errorf("expected the job's location, got %q", loc)
+	}
+
+	req = withUserContext(httptest.NewRequest(http.MethodPost, "/admin/backups/missing/restore", nil), 1)
+	req.SetPathValue("name", "missing")
+	if err := handler.RestoreBackup(httptest.NewRecorder(), req); !errors.Is(err, errors.ErrNotFound) {
+		t.Errorf("expected NOT_FOUND, got %v", err)
+	}
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/respond"
+	"github.com/clementhaon/sandbox-api-go/services"
+)
+
+type ChecklistHandler struct {
+	checklistService services.ChecklistService
+}
+
+func NewChecklistHandler(s services.ChecklistService) *ChecklistHandler {
+	return &ChecklistHandler{checklistService: s}
+}
+
+// checklistPath parses the task ID and, when the route has one, the item ID.
+func checklistPath(r *http.Request) (taskID int, itemID int, err error) {
+	taskID, err = strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return 0, 0, errors.NewBadRequestError("Invalid task ID")
+	}
+	if s := r.PathValue("itemId"); s != "" {
+		itemID, err = strconv.Atoi(s)
+		if err != nil {
+			return 0, 0, errors.NewBadRequestError("Invalid checklist item ID")
+		}
+	}
+	return taskID, itemID, nil
+}
+
+func (h *ChecklistHandler) ListItems(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	taskID, _, err := checklistPath(r)
+	if err != nil {
+		return err
+	}
+
+	items, err := h.checklistService.List(r.Context(), taskID)
+	if err != nil {
+		return err
+	}
+
+	respond.OK(w, items)
+	return nil
+}
+
+func (h *ChecklistHandler) AddItem(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	taskID, _, err := checklistPath(r)
+	if err != nil {
+		return err
+	}
+
+	var req models.CreateChecklistItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return errors.NewInvalidJSONError()
+	}
+
+	item, err := h.checklistService.Add(r.Context(), taskID, req)
+	if err != nil {
+		return err
+	}
+
+	respond.Created(w, item)
+	return nil
+}
+
+func (h *ChecklistHandler) UpdateItem(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	taskID, itemID, err := checklistPath(r)
+	if err != nil {
+		return err
+	}
+
+	var req models.UpdateChecklistItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return errors.NewInvalidJSONError()
+	}
+
+	item, err := h.checklistService.Update(r.Context(), taskID, itemID, req)
+	if err != nil {
+		return err
+	}
+
+	respond.OK(w, item)
+	return nil
+}
+
+func (h *ChecklistHandler) DeleteItem(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	taskID, itemID, err := checklistPath(r)
+	if err != nil {
+		return err
+	}
+
+	if err := h.checklistService.Delete(r.Context(), taskID, itemID); err != nil {
+		return err
+	}
+
+	respond.NoContent(w)
+	return nil
+}
+
+func (h *ChecklistHandler) ReorderItems(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	taskID, _, err := checklistPath(r)
+	if err != nil {
+		return err
+	}
+
+	var req models.ReorderChecklistRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return errors.NewInvalidJSONError()
+	}
+
+	items, err := h.checklistService.Reorder(r.Context(), taskID, req.ItemIDs)
+	if err != nil {
+		return err
+	}
+
+	respond.OK(w, items)
+	return nil
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/clementhaon/sandbox-api-go/mocks"
+	"github.com/clementhaon/sandbox-api-go/models"
+)
+
+func TestChecklistHandler_UpdateItem(t *testing.T) {
+	svc := &mocks.MockChecklistService{
+		UpdateFn: func(ctx context.Context, taskID int, id int, req models.UpdateChecklistItemRequest) (models.ChecklistItem, error) {
+			if taskID != 3 || id != 8 {
+				t.Errorf("expected item 8 of task 3, got item %d of task %d", id, taskID)
+			}
+			return models.ChecklistItem{ID: id, TaskID: taskID, Done: *req.Done}, nil
+		},
+	}
+
+	handler := NewChecklistHandler(svc)
+	done := true
+	body, _ := json.Marshal(models.UpdateChecklistItemRequest{Done: &done})
+	req := httptest.NewRequest(http.MethodPatch, "/tasks/3/checklist/8", bytes.NewReader(body))
+	req.SetPathValue("id", "3")
+	req.SetPathValue("itemId", "8")
+	w := httptest.NewRecorder()
+
+	if err := handler.UpdateItem(w, req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var item models.ChecklistItem
+	decodeData(t, w, &item)
+	if !item.Done {
+		t.Error("expected the item to be done")
+	}
+}
+
+func TestChecklistHandler_DeleteItem_InvalidID(t *testing.T) {
+	handler := NewChecklistHandler(&mocks.MockChecklistService{})
+
+	req := httptest.NewRequest(http.MethodDelete, "/tasks/3/checklist/abc", nil)
+	req.SetPathValue("id", "3")
+	req.SetPathValue("itemId", "abc")
+	w := httptest.NewRecorder()
+
+	if err := handler.DeleteItem(w, req); err == nil {
+		t.Fatal("expected error for invalid item ID")
+	}
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/clementhaon/sandbox-api-go/errors"
 	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/respond"
 	"github.com/clementhaon/sandbox-api-go/services"
 )
 
@@ -26,7 +27,7 @@ func (h *ColumnHandler) ListColumns(w http.ResponseWriter, r *http.Request) erro
 		return err
 	}
 
-	json.NewEncoder(w).Encode(columns)
+	respond.OK(w, columns)
 	return nil
 }
 
@@ -47,8 +48,7 @@ func (h *ColumnHandler) CreateColumn(w http.ResponseWriter, r *http.Request) err
 		return err
 	}
 
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(column)
+	respond.Created(w, column)
 	return nil
 }
 
@@ -70,7 +70,7 @@ func (h *ColumnHandler) UpdateColumn(w http.ResponseWriter, r *http.Request) err
 		return err
 	}
 
-	json.NewEncoder(w).Encode(column)
+	respond.OK(w, column)
 	return nil
 }
 
@@ -86,7 +86,7 @@ func (h *ColumnHandler) DeleteColumn(w http.ResponseWriter, r *http.Request) err
 		return err
 	}
 
-	w.WriteHeader(http.StatusNoContent)
+	respond.NoContent(w)
 	return nil
 }
 
@@ -107,6 +107,6 @@ func (h *ColumnHandler) ReorderColumns(w http.ResponseWriter, r *http.Request) e
 		return err
 	}
 
-	json.NewEncoder(w).Encode(columns)
+	respond.OK(w, columns)
 	return nil
 }
@@ -61,7 +61,7 @@ func TestColumnHandler_ListColumns(t *testing.T) {
 			}
 
 			var cols []models.Column
-			json.NewDecoder(w.Body).Decode(&cols)
+			decodeData(t, w, &cols)
 			if len(cols) != tt.wantCount {
 				t.Errorf("expected %d columns, got %d", tt.wantCount, len(cols))
 			}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/respond"
+)
+
+type DiagnosticsHandler struct {
+	startTime time.Time
+}
+
+func NewDiagnosticsHandler() *DiagnosticsHandler {
+	return &DiagnosticsHandler{startTime: time.Now()}
+}
+
+// RuntimeStats is a snapshot of Go runtime health.
+type RuntimeStats struct {
+	Uptime        string    `json:"uptime"`
+	GoVersion     string    `json:"goVersion"`
+	NumCPU        int       `json:"numCpu"`
+	GOMAXPROCS    int       `json:"gomaxprocs"`
+	Goroutines    int       `json:"goroutines"`
+	HeapAlloc     uint64    `json:"heapAllocBytes"`
+	HeapInuse     uint64    `json:"heapInuseBytes"`
+	HeapSys       uint64    `json:"heapSysBytes"`
+	HeapObjects   uint64    `json:"heapObjects"`
+	TotalAlloc    uint64    `json:"totalAllocBytes"`
+	Sys           uint64    `json:"sysBytes"`
+	NumGC         uint32    `json:"numGc"`
+	LastGC        time.Time `json:"lastGc,omitempty"`
+	GCPauseTotal  string    `json:"gcPauseTotal"`
+	RecentPauses  []string  `json:"recentGcPauses"`
+	GCCPUFraction float64   `json:"gcCpuFraction"`
+}
+
+// maxRecentPauses is the number of most recent GC pauses reported.
+const maxRecentPauses = 10
+
+func (h *DiagnosticsHandler) HandleRuntimeStats(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+
+	stats := RuntimeStats{
+		Uptime:        time.Since(h.startTime).Round(time.Second).String(),
+		GoVersion:     runtime.Version(),
+		NumCPU:        runtime.NumCPU(),
+		GOMAXPROCS:    runtime.GOMAXPROCS(0),
+		Goroutines:    runtime.NumGoroutine(),
+		HeapAlloc:     m.HeapAlloc,
+		HeapInuse:     m.HeapInuse,
+		HeapSys:       m.HeapSys,
+		HeapObjects:   m.HeapObjects,
+		TotalAlloc:    m.TotalAlloc,
+		Sys:           m.Sys,
+		NumGC:         m.NumGC,
+		GCPauseTotal:  time.Duration(m.PauseTotalNs).String(),
+		RecentPauses:  []string{},
+		GCCPUFraction: m.GCCPUFraction,
+	}
+	if m.LastGC > 0 {
+		stats.LastGC = time.Unix(0, int64(m.LastGC)).UTC()
+	}
+
+	// PauseNs is a circular buffer; the most recent pause is at (NumGC+255)%256.
+	for i := uint32(0); i < m.NumGC && i < maxRecentPauses; i++ {
+		pause := m.PauseNs[(m.NumGC-i+255)%uint32(len(m.PauseNs))]
+		stats.RecentPauses = append(stats.RecentPauses, time.Duration(pause).String())
+	}
+
+	respond.OK(w, stats)
+	return nil
+}
+
+// HandlePprof serves the net/http/pprof index and named profiles under /debug/pprof/.
+// CPU profiles and traces are bounded by the server's WriteTimeout.
+func (h *DiagnosticsHandler) HandlePprof(w http.ResponseWriter, r *http.Request) error {
+	switch r.URL.Path {
+	case "/debug/pprof/cmdline":
+		pprof.Cmdline(w, r)
+	case "/debug/pprof/profile":
+		pprof.Profile(w, r)
+	case "/debug/pprof/symbol":
+		pprof.Symbol(w, r)
+	case "/debug/pprof/trace":
+		pprof.Trace(w, r)
+	default:
+		pprof.Index(w, r)
+	}
+	return nil
+}
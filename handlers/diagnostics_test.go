@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestDiagnosticsHandler_RuntimeStats(t *testing.T) {
+	runtime.GC()
+
+	handler := NewDiagnosticsHandler()
+	req := httptest.NewRequest(http.MethodGet, "/debug/runtime", nil)
+	w := httptest.NewRecorder()
+
+	if err := handler.HandleRuntimeStats(w, req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var stats RuntimeStats
+	decodeData(t, w, &stats)
+	if stats.Goroutines < 1 {
+		t.Errorf("expected at least one goroutine, got %d", stats.Goroutines)
+	}
+	if stats.NumGC < 1 || len(stats.RecentPauses) == 0 {
+		t.Errorf("expected GC stats after runtime.GC, got %d GCs and %v", stats.NumGC, stats.RecentPauses)
+	}
+	if stats.HeapAlloc == 0 {
+		t.Error("expected non-zero heap allocation")
+	}
+}
+
+func TestDiagnosticsHandler_PprofIndex(t *testing.T) {
+	handler := NewDiagnosticsHandler()
+	req := httptest.NewRequest(http.MethodGet, "/debug/pprof/", nil)
+	w := httptest.NewRecorder()
+
+	handler.HandlePprof(w, req)
+
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "goroutine") {
+		t.Errorf("expected pprof index listing profiles, got %d", w.Code)
+	}
+}
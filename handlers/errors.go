@@ -0,0 +1,18 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/respond"
+)
+
+// HandleErrorCatalog lists every error code the API can return with its HTTP
+// status, type and description, so clients can map codes without the source.
+func HandleErrorCatalog(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+
+	respond.OK(w, errors.Catalog())
+	return nil
+}
@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+)
+
+func TestHandleErrorCatalog(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/errors", nil)
+	w := httptest.NewRecorder()
+
+	if err := HandleErrorCatalog(w, req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var entries []errors.CatalogEntry
+	decodeData(t, w, &entries)
+	if len(entries) != len(errors.Catalog()) {
+		t.Fatalf("expected %d entries, got %d", len(errors.Catalog()), len(entries))
+	}
+	for _, entry := range entries {
+		if entry.Code == errors.ErrNotFound && entry.Status != http.StatusNotFound {
+			t.Errorf("expected NOT_FOUND to map to 404, got %d", entry.Status)
+		}
+	}
+}
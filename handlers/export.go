@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/middleware"
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/respond"
+	"github.com/clementhaon/sandbox-api-go/services"
+)
+
+type ExportHandler struct {
+	exportService services.ExportService
+}
+
+func NewExportHandler(s services.ExportService) *ExportHandler {
+	return &ExportHandler{exportService: s}
+}
+
+func (h *ExportHandler) HandleRequestExport(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*models.Claims)
+	if !ok {
+		return errors.NewInternalError().WithDetails(map[string]interface{}{
+			"issue": "user_context_missing",
+		})
+	}
+
+	job, err := h.exportService.RequestExport(r.Context(), claims.UserID, r.URL.Query().Get("format"))
+	if err != nil {
+		return err
+	}
+
+	job = withBasePath(r, job)
+	w.Header().Set("Location", middleware.BasePath(r.Context())+"/profile/export/"+job.ID)
+	respond.WriteJSON(w, http.StatusAccepted, job, nil)
+	return nil
+}
+
+func (h *ExportHandler) HandleGetExport(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*models.Claims)
+	if !ok {
+		return errors.NewInternalError().WithDetails(map[string]interface{}{
+			"issue": "user_context_missing",
+		})
+	}
+
+	job, err := h.exportService.GetExport(r.Context(), claims.UserID, r.PathValue("id"))
+	if err != nil {
+		return err
+	}
+
+	respond.OK(w, withBasePath(r, job))
+	return nil
+}
+
+func (h *ExportHandler) HandleDownloadExport(w http.ResponseWriter, r *http.Request) error {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*models.Claims)
+	if !ok {
+		return errors.NewInternalError().WithDetails(map[string]interface{}{
+			"issue": "user_context_missing",
+		})
+	}
+
+	file, err := h.exportService.DownloadExport(r.Context(), claims.UserID, r.PathValue("id"))
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", file.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", file.Filename))
+	w.Header().Set("Content-Length", strconv.Itoa(len(file.Data)))
+	w.Write(file.Data)
+	return nil
+}
+
+// withBasePath makes the download link of job start with the path the API
+// is served under.
+func withBasePath(r *http.Request, job models.ExportJob) models.ExportJob {
+	if job.DownloadURL != "" {
+		job.DownloadURL = middleware.BasePath(r.Context()) + job.DownloadURL
+	}
+	return job
+}
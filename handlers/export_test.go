@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/mocks"
+	"github.com/clementhaon/sandbox-api-go/models"
+)
+
+func TestExportHandler_RequestExport(t *testing.T) {
+	var receivedFormat string
+	svc := &mocks.MockExportService{
+		RequestExportFn: func(ctx context.Context, userID int, format string) (models.ExportJob, error) {
+			receivedFormat = format
+			return models.ExportJob{ID: "job-1", Status: "pending", Format: format}, nil
+		},
+	}
+
+	handler := NewExportHandler(svc)
+	req := withUserContext(httptest.NewRequest(http.MethodGet, "/profile/export?format=zip", nil), 1)
+	w := httptest.NewRecorder()
+
+	if err := handler.HandleRequestExport(w, req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if w.Code != http.StatusAccepted {
+		t.Errorf("expected status 202, got %d", w.Code)
+	}
+	if receivedFormat != "zip" {
+		t.Errorf("expected format 'zip', got %q", receivedFormat)
+	}
+	if loc := w.Header().Get("Location"); loc != "/profile/export/job-1" {
+		t.Errorf("unexpected Location header %q", loc)
+	}
+
+	var job models.ExportJob
+	decodeData(t, w, &job)
+	if job.ID != "job-1" {
+		t.Errorf("expected job ID 'job-1', got %q", job.ID)
+	}
+}
+
+func TestExportHandler_GetExport_NotFound(t *testing.T) {
+	svc := &mocks.MockExportService{
+		GetExportFn: func(ctx context.Context, userID int, jobID string) (models.ExportJob, error) {
+			return models.ExportJob{}, errors.NewNotFoundError("Export")
+		},
+	}
+
+	handler := NewExportHandler(svc)
+	req := withUserContext(httptest.NewRequest(http.MethodGet, "/profile/export/missing", nil), 1)
+	req.SetPathValue("id", "missing")
+	w := httptest.NewRecorder()
+
+	err := handler.HandleGetExport(w, req)
+	appErr, ok := errors.IsAppError(err)
+	if !ok || appErr.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 AppError, got %v", err)
+	}
+}
+
+func TestExportHandler_DownloadExport(t *testing.T) {
+	svc := &mocks.MockExportService{
+		DownloadExportFn: func(ctx context.Context, userID int, jobID string) (models.ExportFile, error) {
+			return models.ExportFile{
+				Filename:    "export-1.json",
+				ContentType: "application/json",
+				Data:        []byte(`{"profile":{}}`),
+			}, nil
+		},
+	}
+
+	handler := NewExportHandler(svc)
+	req := withUserContext(httptest.NewRequest(http.MethodGet, "/profile/export/job-1/download", nil), 1)
+	req.SetPathValue("id", "job-1")
+	w := httptest.NewRecorder()
+
+	if err := handler.HandleDownloadExport(w, req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cd := w.Header().Get("Content-Disposition"); cd != `attachment; filename="export-1.json"` {
+		t.Errorf("unexpected Content-Disposition %q", cd)
+	}
+	if w.Body.String() != `{"profile":{}}` {
+		t.Errorf("unexpected body %q", w.Body.String())
+	}
+}
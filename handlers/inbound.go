@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/middleware"
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/respond"
+	"github.com/clementhaon/sandbox-api-go/services"
+)
+
+type InboundHandler struct {
+	inboundService services.InboundService
+}
+
+func NewInboundHandler(s services.InboundService) *InboundHandler {
+	return &InboundHandler{inboundService: s}
+}
+
+func (h *InboundHandler) GetSettings(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*models.Claims)
+	if !ok {
+		return errors.NewInternalError().WithDetails(map[string]interface{}{"issue": "user_context_missing"})
+	}
+
+	settings, err := h.inboundService.Settings(r.Context(), claims.UserID)
+	if err != nil {
+		return err
+	}
+
+	respond.OK(w, settings)
+	return nil
+}
+
+// RotateSecret enables the webhook, or replaces its secret. The secret is
+// in this response only.
+func (h *InboundHandler) RotateSecret(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*models.Claims)
+	if !ok {
+		return errors.NewInternalError().WithDetails(map[string]interface{}{"issue": "user_context_missing"})
+	}
+
+	resp, err := h.inboundService.RotateSecret(r.Context(), claims.UserID)
+	if err != nil {
+		return err
+	}
+
+	respond.OK(w, resp)
+	return nil
+}
+
+func (h *InboundHandler) UpdateMapping(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*models.Claims)
+	if !ok {
+		return errors.NewInternalError().WithDetails(map[string]interface{}{"issue": "user_context_missing"})
+	}
+
+	var mapping models.InboundMapping
+	if err := json.NewDecoder(r.Body).Decode(&mapping); err != nil {
+		return errors.NewInvalidJSONError()
+	}
+
+	settings, err := h.inboundService.UpdateMapping(r.Context(), claims.UserID, mapping)
+	if err != nil {
+		return err
+	}
+
+	respond.OK(w, settings)
+	return nil
+}
+
+func (h *InboundHandler) Disable(w http.ResponseWriter, r *http.Request) error {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*models.Claims)
+	if !ok {
+		return errors.NewInternalError().WithDetails(map[string]interface{}{"issue": "user_context_missing"})
+	}
+
+	if err := h.inboundService.Disable(r.Context(), claims.UserID); err != nil {
+		return err
+	}
+
+	respond.NoContent(w)
+	return nil
+}
+
+// ReceiveTask creates a task from a JSON object or a form, for the user
+// whose secret is in the X-Inbound-Secret header or the secret parameter.
+func (h *InboundHandler) ReceiveTask(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	secret := r.Header.Get(models.InboundSecretHeader)
+	if secret == "" {
+		secret = r.URL.Query().Get("secret")
+	}
+
+	payload := map[string]interface{}{}
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "application/x-www-form-urlencoded", "multipart/form-data":
+		if err := r.ParseMultipartForm(1 << 20); err != nil && err != http.ErrNotMultipart {
+			return errors.NewBadRequestError("Invalid form")
+		}
+		for key, values := range r.PostForm {
+			payload[key] = values[0]
+		}
+	default:
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			return errors.NewInvalidJSONError()
+		}
+	}
+
+	task, err := h.inboundService.Receive(r.Context(), secret, payload)
+	if err != nil {
+		return err
+	}
+
+	respond.Created(w, task)
+	return nil
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/mocks"
+	"github.com/clementhaon/sandbox-api-go/models"
+)
+
+func TestInboundHandler_ReceiveTask(t *testing.T) {
+	var gotSecret string
+	var gotPayload map[string]interface{}
+	h := NewInboundHandler(&mocks.MockInboundService{
+		ReceiveFn: func(ctx context.Context, secret string, payload map[string]interface{}) (models.Task, error) {
+			gotSecret, gotPayload = secret, payload
+			if secret != "inb_good" {
+				return models.Task{}, errors.NewInvalidTokenError()
+			}
+			return models.Task{ID: 5, Title: "Renew the domain"}, nil
+		},
+	})
+
+	t.Run("JSON payload with the secret header", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/inbound/tasks", strings.NewReader(`{"title": "Renew the domain", "data": {"priority": "high"}}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(models.InboundSecretHeader, "inb_good")
+		w := httptest.NewRecorder()
+		if err := h.ReceiveTask(w, req); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if w.Code != http.StatusCreated {
+			t.Errorf("status = %d, want 201", w.Code)
+		}
+		var task models.Task
+		decodeData(t, w, &task)
+		if task.ID != 5 || gotPayload["title"] != "Renew the domain" {
+			t.Errorf("unexpected task %+v for payload %v", task, gotPayload)
+		}
+	})
+
+	t.Run("form payload with the secret parameter", func(t *testing.T) {
+		form := url.Values{"subject": {"Call the bank"}, "body-plain": {"Before noon"}}
+		req := httptest.NewRequest(http.MethodPost, "/inbound/tasks?secret=inb_good", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if err := h.ReceiveTask(httptest.NewRecorder(), req); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if gotSecret != "inb_good" || gotPayload["subject"] != "Call the bank" || gotPayload["body-plain"] != "Before noon" {
+			t.Errorf("unexpected secret %q or payload %v", gotSecret, gotPayload)
+		}
+	})
+
+	t.Run("invalid JSON", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/inbound/tasks", strings.NewReader(`{"title":`))
+		req.Header.Set(models.InboundSecretHeader, "inb_good")
+		if err := h.ReceiveTask(httptest.NewRecorder(), req); !errors.Is(err, errors.ErrInvalidJSON) {
+			t.Errorf("expected invalid JSON, got %v", err)
+		}
+	})
+
+	t.Run("unknown secret", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/inbound/tasks", strings.NewReader(`{"title": "x"}`))
+		req.Header.Set(models.InboundSecretHeader, "inb_bad")
+		if err := h.ReceiveTask(httptest.NewRecorder(), req); !errors.Is(err, errors.ErrInvalidToken) {
+			t.Errorf("expected an invalid token, got %v", err)
+		}
+	})
+}
+
+func TestInboundHandler_UpdateMapping(t *testing.T) {
+	var gotUser int
+	var got models.InboundMapping
+	h := NewInboundHandler(&mocks.MockInboundService{
+		UpdateMappingFn: func(ctx context.Context, userID int, mapping models.InboundMapping) (models.InboundSettings, error) {
+			gotUser, got = userID, mapping
+			return models.InboundSettings{Enabled: true, Mapping: mapping}, nil
+		},
+	})
+
+	body := `{"fields": {"title": "subject"}, "columnId": 3, "priority": "high", "tags": ["mail"]}`
+	req := withUserContext(httptest.NewRequest(http.MethodPut, "/inbound/mapping", strings.NewReader(body)), 7)
+	w := httptest.NewRecorder()
+	if err := h.UpdateMapping(w, req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gotUser != 7 || got.Fields.Title != "subject" || got.ColumnID != 3 || got.Priority != "high" {
+		t.Errorf("unexpected mapping %+v for user %d", got, gotUser)
+	}
+
+	var settings models.InboundSettings
+	decodeData(t, w, &settings)
+	if !settings.Enabled || settings.Mapping.Tags[0] != "mail" {
+		t.Errorf("unexpected settings %+v", settings)
+	}
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/respond"
+	"github.com/clementhaon/sandbox-api-go/services"
+)
+
+type LogLevelHandler struct {
+	logLevelService services.LogLevelService
+}
+
+func NewLogLevelHandler(s services.LogLevelService) *LogLevelHandler {
+	return &LogLevelHandler{logLevelService: s}
+}
+
+func (h *LogLevelHandler) GetLogLevel(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+
+	respond.OK(w, h.logLevelService.Get())
+	return nil
+}
+
+// UpdateLogLevel changes the global and per-package log levels, for
+// ttlSeconds when given.
+func (h *LogLevelHandler) UpdateLogLevel(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req models.UpdateLogLevelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return errors.NewInvalidJSONError()
+	}
+
+	levels, err := h.logLevelService.Update(r.Context(), req)
+	if err != nil {
+		return err
+	}
+
+	respond.OK(w, levels)
+	return nil
+}
@@ -2,13 +2,14 @@ package handlers
 
 import (
 	"encoding/json"
-	"fmt"
 	"net/http"
 	"strconv"
 
 	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/i18n"
 	"github.com/clementhaon/sandbox-api-go/middleware"
 	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/respond"
 	"github.com/clementhaon/sandbox-api-go/services"
 )
 
@@ -37,7 +38,7 @@ func (h *MediaHandler) HandleGetPresignedUploadURL(w http.ResponseWriter, r *htt
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	respond.OK(w, response)
 	return nil
 }
 
@@ -58,8 +59,7 @@ func (h *MediaHandler) HandleConfirmUpload(w http.ResponseWriter, r *http.Reques
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(media)
+	respond.Created(w, media)
 	return nil
 }
 
@@ -85,7 +85,12 @@ func (h *MediaHandler) HandleGetUserMedia(w http.ResponseWriter, r *http.Request
 		return err
 	}
 
-	json.NewEncoder(w).Encode(response)
+	respond.Paginated(w, response.Media, map[string]int{
+		"page":       response.Page,
+		"limit":      response.Limit,
+		"totalCount": response.TotalCount,
+		"totalPages": response.TotalPages,
+	})
 	return nil
 }
 
@@ -106,7 +111,7 @@ func (h *MediaHandler) HandleGetMediaByID(w http.ResponseWriter, r *http.Request
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(media)
+	respond.OK(w, media)
 	return nil
 }
 
@@ -127,7 +132,7 @@ func (h *MediaHandler) HandleGetPresignedDownloadURL(w http.ResponseWriter, r *h
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	respond.OK(w, response)
 	return nil
 }
 
@@ -147,8 +152,8 @@ func (h *MediaHandler) HandleDeleteMedia(w http.ResponseWriter, r *http.Request)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{
-		"message": fmt.Sprintf("Media %d deleted successfully", mediaID),
+	respond.OK(w, map[string]string{
+		"message": i18n.T(i18n.FromRequest(r), "media.deleted", mediaID),
 	})
 	return nil
 }
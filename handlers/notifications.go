@@ -8,6 +8,7 @@ import (
 	"github.com/clementhaon/sandbox-api-go/errors"
 	"github.com/clementhaon/sandbox-api-go/middleware"
 	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/respond"
 	"github.com/clementhaon/sandbox-api-go/services"
 )
 
@@ -34,7 +35,7 @@ func (h *NotificationHandler) ListNotifications(w http.ResponseWriter, r *http.R
 		return err
 	}
 
-	json.NewEncoder(w).Encode(notifications)
+	respond.OK(w, notifications)
 	return nil
 }
 
@@ -62,9 +63,52 @@ func (h *NotificationHandler) MarkNotificationsRead(w http.ResponseWriter, r *ht
 		return err
 	}
 
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"success": true,
-		"marked":  marked,
+	respond.OK(w, map[string]interface{}{
+		"marked": marked,
+	})
+	return nil
+}
+
+func (h *NotificationHandler) MarkNotificationRead(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*models.Claims)
+	if !ok {
+		return errors.NewInternalError().WithDetails(map[string]interface{}{
+			"issue": "user_context_missing",
+		})
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return errors.NewBadRequestError("Invalid notification ID")
+	}
+
+	if err := h.notificationService.MarkOneRead(r.Context(), claims.UserID, id); err != nil {
+		return err
+	}
+
+	respond.NoContent(w)
+	return nil
+}
+
+func (h *NotificationHandler) UnreadCount(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*models.Claims)
+	if !ok {
+		return errors.NewInternalError().WithDetails(map[string]interface{}{
+			"issue": "user_context_missing",
+		})
+	}
+
+	unread, err := h.notificationService.UnreadCount(r.Context(), claims.UserID)
+	if err != nil {
+		return err
+	}
+
+	respond.OK(w, map[string]interface{}{
+		"unread": unread,
 	})
 	return nil
 }
@@ -84,9 +128,8 @@ func (h *NotificationHandler) MarkAllNotificationsRead(w http.ResponseWriter, r
 		return err
 	}
 
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"success": true,
-		"marked":  marked,
+	respond.OK(w, map[string]interface{}{
+		"marked": marked,
 	})
 	return nil
 }
@@ -110,6 +153,6 @@ func (h *NotificationHandler) DeleteNotification(w http.ResponseWriter, r *http.
 		return err
 	}
 
-	w.WriteHeader(http.StatusNoContent)
+	respond.NoContent(w)
 	return nil
 }
@@ -288,3 +288,101 @@ func TestNotificationHandler_DeleteNotification(t *testing.T) {
 		})
 	}
 }
+
+func TestNotificationHandler_MarkNotificationRead(t *testing.T) {
+	tests := []struct {
+		name       string
+		withCtx    bool
+		pathID     string
+		markFn     func(ctx context.Context, userID int, id int) error
+		wantStatus int
+		wantErr    bool
+	}{
+		{
+			name:    "success",
+			withCtx: true,
+			pathID:  "5",
+			markFn: func(ctx context.Context, userID int, id int) error {
+				if userID != 1 || id != 5 {
+					t.Errorf("expected notification 5 of user 1, got %d of user %d", id, userID)
+				}
+				return nil
+			},
+			wantStatus: http.StatusNoContent,
+		},
+		{
+			name:    "no user context",
+			withCtx: false,
+			pathID:  "5",
+			wantErr: true,
+		},
+		{
+			name:    "invalid id",
+			withCtx: true,
+			pathID:  "abc",
+			wantErr: true,
+		},
+		{
+			name:    "not found",
+			withCtx: true,
+			pathID:  "999",
+			markFn: func(ctx context.Context, userID int, id int) error {
+				return errors.NewNotFoundError("Notification not found")
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &mocks.MockNotificationService{MarkOneReadFn: tt.markFn}
+			handler := NewNotificationHandler(svc)
+
+			req := httptest.NewRequest(http.MethodPost, "/notifications/"+tt.pathID+"/read", nil)
+			req.SetPathValue("id", tt.pathID)
+			if tt.withCtx {
+				req = withUserContext(req, 1)
+			}
+			w := httptest.NewRecorder()
+
+			err := handler.MarkNotificationRead(w, req)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if w.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+		})
+	}
+}
+
+func TestNotificationHandler_UnreadCount(t *testing.T) {
+	svc := &mocks.MockNotificationService{
+		UnreadCountFn: func(ctx context.Context, userID int) (int, error) {
+			return 4, nil
+		},
+	}
+	handler := NewNotificationHandler(svc)
+
+	req := withUserContext(httptest.NewRequest(http.MethodGet, "/notifications/unread-count", nil), 1)
+	w := httptest.NewRecorder()
+
+	if err := handler.UnreadCount(w, req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", w.Code)
+	}
+
+	var body map[string]int
+	decodeData(t, w, &body)
+	if body["unread"] != 4 {
+		t.Errorf("expected 4 unread, got %d", body["unread"])
+	}
+}
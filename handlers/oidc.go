@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/logger"
+	"github.com/clementhaon/sandbox-api-go/middleware"
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/oidc"
+	"github.com/clementhaon/sandbox-api-go/respond"
+	"github.com/clementhaon/sandbox-api-go/services"
+)
+
+// OIDCHandler serves the OpenID Connect provider. Discovery, JWKS, token and
+// userinfo responses are plain JSON as the protocol requires, not wrapped
+// in the API envelope.
+type OIDCHandler struct {
+	oidcService services.OIDCService
+}
+
+func NewOIDCHandler(s services.OIDCService) *OIDCHandler {
+	return &OIDCHandler{oidcService: s}
+}
+
+func (h *OIDCHandler) HandleDiscovery(w http.ResponseWriter, r *http.Request) error {
+	writeProtocolJSON(w, http.StatusOK, h.oidcService.Discovery())
+	return nil
+}
+
+func (h *OIDCHandler) HandleJWKS(w http.ResponseWriter, r *http.Request) error {
+	writeProtocolJSON(w, http.StatusOK, h.oidcService.JWKS())
+	return nil
+}
+
+// HandleStartAuthorize receives the browser sent by a client, and forwards
+// it to the login page, or back to the client with an error.
+func (h *OIDCHandler) HandleStartAuthorize(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	target, err := h.oidcService.Start(authorizeRequestFromQuery(r))
+	if err != nil {
+		return err
+	}
+	http.Redirect(w, r, target, http.StatusFound)
+	return nil
+}
+
+// HandleAuthorize is called by the login page once the user is logged in
+// and has approved the client. It returns where to send the browser.
+func (h *OIDCHandler) HandleAuthorize(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*models.Claims)
+	if !ok {
+		return errors.NewInternalError().WithDetails(map[string]interface{}{"issue": "user_context_missing"})
+	}
+
+	var req models.AuthorizeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.WarnContext(r.Context(), "Invalid JSON in authorize request", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return errors.NewInvalidJSONError()
+	}
+
+	target, err := h.oidcService.Authorize(r.Context(), claims.UserID, req)
+	if err != nil {
+		return err
+	}
+	respond.OK(w, models.AuthorizeResponse{RedirectTo: target})
+	return nil
+}
+
+// HandleToken exchanges an authorization code for tokens. Clients
+// authenticate with HTTP basic auth or client_id and client_secret in the
+// form.
+func (h *OIDCHandler) HandleToken(w http.ResponseWriter, r *http.Request) error {
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(w, oidc.NewError(oidc.ErrInvalidRequest, "malformed form"))
+		return nil
+	}
+	req := models.TokenRequest{
+		GrantType:    r.PostForm.Get("grant_type"),
+		Code:         r.PostForm.Get("code"),
+		RedirectURI:  r.PostForm.Get("redirect_uri"),
+		ClientID:     r.PostForm.Get("client_id"),
+		ClientSecret: r.PostForm.Get("client_secret"),
+		CodeVerifier: r.PostForm.Get("code_verifier"),
+	}
+	if id, secret, ok := r.BasicAuth(); ok {
+		req.ClientID, req.ClientSecret = id, secret
+	}
+
+	tokens, err := h.oidcService.Exchange(r.Context(), req)
+	var protocolErr *oidc.Error
+	if errors.As(err, &protocolErr) {
+		if protocolErr.Code == oidc.ErrInvalidClient {
+			w.Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+		}
+		writeOAuthError(w, protocolErr)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	writeProtocolJSON(w, http.StatusOK, tokens)
+	return nil
+}
+
+// HandleUserInfo returns the claims granted by the bearer access token.
+func (h *OIDCHandler) HandleUserInfo(w http.ResponseWriter, r *http.Request) error {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		w.Header().Set("WWW-Authenticate", `Bearer`)
+		writeOAuthError(w, oidc.NewError(oidc.ErrInvalidToken, "bearer token required"))
+		return nil
+	}
+
+	info, err := h.oidcService.UserInfo(r.Context(), token)
+	var protocolErr *oidc.Error
+	if errors.As(err, &protocolErr) {
+		w.Header().Set("WWW-Authenticate", `Bearer error="`+protocolErr.Code+`"`)
+		writeOAuthError(w, protocolErr)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	writeProtocolJSON(w, http.StatusOK, info)
+	return nil
+}
+
+func authorizeRequestFromQuery(r *http.Request) models.AuthorizeRequest {
+	q := r.URL.Query()
+	return models.AuthorizeRequest{
+		ResponseType:        q.Get("response_type"),
+		ClientID:            q.Get("client_id"),
+		RedirectURI:         q.Get("redirect_uri"),
+		Scope:               q.Get("scope"),
+		State:               q.Get("state"),
+		Nonce:               q.Get("nonce"),
+		CodeChallenge:       q.Get("code_challenge"),
+		CodeChallengeMethod: q.Get("code_challenge_method"),
+	}
+}
+
+func writeOAuthError(w http.ResponseWriter, err *oidc.Error) {
+	writeProtocolJSON(w, err.Status, err)
+}
+
+// writeProtocolJSON writes v as is, never cached, as tokens may be in it.
+func writeProtocolJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.Warn("Failed to encode response", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+}
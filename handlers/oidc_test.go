@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/mocks"
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/oidc"
+)
+
+func TestOIDCHandler_StartAuthorize(t *testing.T) {
+	var got models.AuthorizeRequest
+	h := NewOIDCHandler(&mocks.MockOIDCService{
+		StartFn: func(req models.AuthorizeRequest) (string, error) {
+			got = req
+			if req.ClientID == "unknown" {
+				return "", errors.NewBadRequestError("Unknown OAuth client")
+			}
+			return "https://app.sandbox.test/oauth/consent?client_id=wiki", nil
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/oauth/authorize?response_type=code&client_id=wiki&scope=openid&state=xyz", nil)
+	w := httptest.NewRecorder()
+	if err := h.HandleStartAuthorize(w, req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if w.Code != http.StatusFound || w.Header().Get("Location") != "https://app.sandbox.test/oauth/consent?client_id=wiki" {
+		t.Errorf("expected a redirect to the login page, got %d %q", w.Code, w.Header().Get("Location"))
+	}
+	if got.ResponseType != "code" || got.Scope != "openid" || got.State != "xyz" {
+		t.Errorf("unexpected request %+v", got)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/oauth/authorize?client_id=unknown", nil)
+	if err := h.HandleStartAuthorize(httptest.NewRecorder(), req); !errors.Is(err, errors.ErrValidationFailed) {
+		t.Errorf("expected a bad request, got %v", err)
+	}
+}
+
+func TestOIDCHandler_Authorize(t *testing.T) {
+	h := NewOIDCHandler(&mocks.MockOIDCService{
+		AuthorizeFn: func(ctx context.Context, userID int, req models.AuthorizeRequest) (string, error) {
+			return "https://wiki.test/callback?code=abc&state=" + req.State + "&user=" + strconv.Itoa(userID), nil
+		},
+	})
+
+	body, _ := json.Marshal(models.AuthorizeRequest{ClientID: "wiki", State: "xyz"})
+	req := withUserContext(httptest.NewRequest(http.MethodPost, "/oauth/authorize", bytes.NewReader(body)), 7)
+	w := httptest.NewRecorder()
+	if err := h.HandleAuthorize(w, req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var resp models.AuthorizeResponse
+	decodeData(t, w, &resp)
+	if resp.RedirectTo != "https://wiki.test/callback?code=abc&state=xyz&user=7" {
+		t.Errorf("RedirectTo = %q", resp.RedirectTo)
+	}
+}
+
+func TestOIDCHandler_Token(t *testing.T) {
+	var got models.TokenRequest
+	h := NewOIDCHandler(&mocks.MockOIDCService{
+		ExchangeFn: func(ctx context.Context, req models.TokenRequest) (models.TokenResponse, error) {
+			got = req
+			if req.Code == "used" {
+				return models.TokenResponse{}, oidc.NewError(oidc.ErrInvalidGrant, "invalid or expired code")
+			}
+			return models.TokenResponse{AccessToken: "at", TokenType: "Bearer", IDToken: "id"}, nil
+		},
+	})
+
+	post := func(code string) *httptest.ResponseRecorder {
+		form := url.Values{"grant_type": {"authorization_code"}, "code": {code}, "redirect_uri": {"https://wiki.test/callback"}}
+		req := httptest.NewRequest(http.MethodPost, "/oauth/token", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetBasicAuth("wiki", "s3cret")
+		w := httptest.NewRecorder()
+		if err := h.HandleToken(w, req); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return w
+	}
+
+	w := post("abc")
+	if w.Code != http.StatusOK || w.Header().Get("Cache-Control") != "no-store" {
+		t.Errorf("expected an uncached 200, got %d %q", w.Code, w.Header().Get("Cache-Control"))
+	}
+	if got.ClientID != "wiki" || got.ClientSecret != "s3cret" || got.Code != "abc" {
+		t.Errorf("unexpected token request %+v", got)
+	}
+	var tokens models.TokenResponse
+	if err := json.NewDecoder(w.Body).Decode(&tokens); err != nil || tokens.AccessToken != "at" {
+		t.Errorf("expected the tokens unwrapped, got %+v (%v)", tokens, err)
+	}
+
+	w = post("used")
+	var oauthErr oidc.Error
+	if err := json.NewDecoder(w.Body).Decode(&oauthErr); err != nil {
+		t.Fatalf("failed to decode error: %v", err)
+	}
+	if w.Code != http.StatusBadRequest || oauthErr.Code != oidc.ErrInvalidGrant {
+		t.Errorf("expected invalid_grant, got %d %+v", w.Code, oauthErr)
+	}
+}
+
+func TestOIDCHandler_UserInfo(t *testing.T) {
+	h := NewOIDCHandler(&mocks.MockOIDCService{
+		UserInfoFn: func(ctx context.Context, accessToken string) (models.UserInfo, error) {
+			if accessToken != "good" {
+				return models.UserInfo{}, oidc.NewError(oidc.ErrInvalidToken, "")
+			}
+			return models.UserInfo{Subject: "7", Email: "jane@example.com"}, nil
+		},
+	})
+
+	tests := []struct {
+		name       string
+		auth       string
+		wantStatus int
+	}{
+		{"valid token", "Bearer good", http.StatusOK},
+		{"invalid token", "Bearer bad", http.StatusUnauthorized},
+		{"no token", "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/oauth/userinfo", nil)
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			w := httptest.NewRecorder()
+			if err := h.HandleUserInfo(w, req); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusUnauthorized && !strings.HasPrefix(w.Header().Get("WWW-Authenticate"), "Bearer") {
+				t.Errorf("expected a Bearer challenge, got %q", w.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/middleware"
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/respond"
+	"github.com/clementhaon/sandbox-api-go/services"
+)
+
+type PersonalTokenHandler struct {
+	tokenService services.PersonalTokenService
+}
+
+func NewPersonalTokenHandler(s services.PersonalTokenService) *PersonalTokenHandler {
+	return &PersonalTokenHandler{tokenService: s}
+}
+
+func (h *PersonalTokenHandler) ListTokens(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*models.Claims)
+	if !ok {
+		return errors.NewInternalError().WithDetails(map[string]interface{}{"issue": "user_context_missing"})
+	}
+
+	tokens, err := h.tokenService.List(r.Context(), claims.UserID)
+	if err != nil {
+		return err
+	}
+
+	respond.OK(w, tokens)
+	return nil
+}
+
+// CreateToken returns the new token with its secret, shown this once.
+func (h *PersonalTokenHandler) CreateToken(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*models.Claims)
+	if !ok {
+		return errors.NewInternalError().WithDetails(map[string]interface{}{"issue": "user_context_missing"})
+	}
+
+	var req models.CreatePersonalTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return errors.NewInvalidJSONError()
+	}
+
+	resp, err := h.tokenService.Create(r.Context(), claims.UserID, req)
+	if err != nil {
+		return err
+	}
+
+	respond.Created(w, resp)
+	return nil
+}
+
+func (h *PersonalTokenHandler) RevokeToken(w http.ResponseWriter, r *http.Request) error {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*models.Claims)
+	if !ok {
+		return errors.NewInternalError().WithDetails(map[string]interface{}{"issue": "user_context_missing"})
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return errors.NewBadRequestError("Invalid token ID")
+	}
+
+	if err := h.tokenService.Revoke(r.Context(), claims.UserID, id); err != nil {
+		return err
+	}
+
+	respond.NoContent(w)
+	return nil
+}
+
+// GetSandboxSettings returns the sandbox settings of the personal token the
+// request is made with.
+func (h *PersonalTokenHandler) GetSandboxSettings(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*models.Claims)
+	if !ok {
+		return errors.NewInternalError().WithDetails(map[string]interface{}{"issue": "user_context_missing"})
+	}
+	if claims.PersonalTokenID == 0 || claims.Sandbox == nil {
+		return errNoPersonalToken()
+	}
+
+	respond.OK(w, claims.Sandbox)
+	return nil
+}
+
+// UpdateSandboxSettings replaces the sandbox settings of the personal token
+// the request is made with; they apply from its next request.
+func (h *PersonalTokenHandler) UpdateSandboxSettings(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*models.Claims)
+	if !ok {
+		return errors.NewInternalError().WithDetails(map[string]interface{}{"issue": "user_context_missing"})
+	}
+	if claims.PersonalTokenID == 0 {
+		return errNoPersonalToken()
+	}
+
+	var settings models.SandboxSettings
+	if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+		return errors.NewInvalidJSONError()
+	}
+
+	settings, err := h.tokenService.UpdateSandbox(r.Context(), claims.UserID, claims.PersonalTokenID, settings)
+	if err != nil {
+		return err
+	}
+
+	respond.OK(w, settings)
+	return nil
+}
+
+// errNoPersonalToken refuses the sandbox settings to requests made without
+// a personal access token, which they belong to.
+func errNoPersonalToken() error {
+	return errors.NewBadRequestError("Sandbox settings belong to a personal access token: send the request with one")
+}
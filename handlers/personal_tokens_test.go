@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/middleware"
+	"github.com/clementhaon/sandbox-api-go/mocks"
+	"github.com/clementhaon/sandbox-api-go/models"
+)
+
+func TestPersonalTokenHandler_CreateToken(t *testing.T) {
+	var gotUserID int
+	var gotReq models.CreatePersonalTokenRequest
+	svc := &mocks.MockPersonalTokenService{
+		CreateFn: func(ctx context.Context, userID int, req models.CreatePersonalTokenRequest) (models.CreatePersonalTokenResponse, error) {
+			gotUserID, gotReq = userID, req
+			return models.CreatePersonalTokenResponse{
+				PersonalToken: models.PersonalToken{ID: 5, Name: req.Name, Prefix: "sap_abcdef", Scopes: req.Scopes},
+				Token:         "sap_abcdefsecret",
+			}, nil
+		},
+	}
+	handler := NewPersonalTokenHandler(svc)
+
+	w := httptest.NewRecorder()
+	body := `{"name":"CI","scopes":["tasks:read"],"expiresInDays":30}`
+	req := withUserContext(httptest.NewRequest(http.MethodPost, "/tokens", strings.NewReader(body)), 3)
+	if err := handler.CreateToken(w, req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d", w.Code)
+	}
+	if gotUserID != 3 || gotReq.Name != "CI" || gotReq.ExpiresInDays != 30 || len(gotReq.Scopes) != 1 {
+		t.Errorf("unexpected request for user %d: %+v", gotUserID, gotReq)
+	}
+
+	var resp struct {
+		Data struct {
+			ID    int    `json:"id"`
+			Token string `json:"token"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if resp.Data.ID != 5 || resp.Data.Token != "sap_abcdefsecret" {
+		t.Errorf("expected token 5 with its secret, got %s", w.Body.String())
+	}
+
+	req = withUserContext(httptest.NewRequest(http.MethodPost, "/tokens", strings.NewReader(`{`)), 3)
+	if err := handler.CreateToken(httptest.NewRecorder(), req); !errors.Is(err, errors.ErrInvalidJSON) {
+		t.Errorf("expected INVALID_JSON, got %v", err)
+	}
+}
+
+func TestPersonalTokenHandler_RevokeToken(t *testing.T) {
+	svc := &mocks.MockPersonalTokenService{
+		RevokeFn: func(ctx context.Context, userID, id int) error {
+			if userID != 3 || id != 5 {
+				return errors.NewNotFoundError("Personal token")
+			}
+			return nil
+		},
+	}
+	handler := NewPersonalTokenHandler(svc)
+
+	w := httptest.NewRecorder()
+	req := withUserContext(httptest.NewRequest(http.MethodDelete, "/tokens/5", nil), 3)
+	req.SetPathValue("id", "5")
+	if err := handler.RevokeToken(w, req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if w.Code != http.StatusNoContent {
+		t.Errorf("expected status 204, got %d", w.Code)
+	}
+
+	req = withUserContext(httptest.NewRequest(http.MethodDelete, "/tokens/6", nil), 3)
+	req.SetPathValue("id", "6")
+	if err := handler.RevokeToken(httptest.NewRecorder(), req); !errors.Is(err, errors.ErrNotFound) {
+		t.Errorf("expected NOT_FOUND, got %v", err)
+	}
+
+	req = withUserContext(httptest.NewRequest(http.MethodDelete, "/tokens/abc", nil), 3)
+	req.SetPathValue("id", "abc")
+	if err := handler.RevokeToken(httptest.NewRecorder(), req); !errors.Is(err, errors.ErrValidationFailed) {
+		t.Errorf("expected VALIDATION_FAILED, got %v", err)
+	}
+}
+
+func TestPersonalTokenHandler_UpdateSandboxSettings(t *testing.T) {
+	var gotUserID, gotID int
+	svc := &mocks.MockPersonalTokenService{
+		UpdateSandboxFn: func(ctx context.Context, userID, id int, settings models.SandboxSettings) (models.SandboxSettings, error) {
+			gotUserID, gotID = userID, id
+			return settings, nil
+		},
+	}
+	handler := NewPersonalTokenHandler(svc)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPut, "/sandbox/settings", strings.NewReader(`{"delayMs":200,"statusCode":503}`))
+	req = req.WithContext(context.WithValue(req.Context(), middleware.UserContextKey, &models.Claims{UserID: 3, PersonalTokenID: 5}))
+	if err := handler.UpdateSandboxSettings(w, req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var settings models.SandboxSettings
+	decodeData(t, w, &settings)
+	if gotUserID != 3 || gotID != 5 || settings.DelayMS != 200 || settings.StatusCode != 503 {
+		t.Errorf("expected the settings of token 5 of user 3 updated, got %+v for %d/%d", settings, gotUserID, gotID)
+	}
+
+	// Settings belong to a personal token, not to a login
+	req = withUserContext(httptest.NewRequest(http.MethodPut, "/sandbox/settings", strings.NewReader(`{}`)), 3)
+	if err := handler.UpdateSandboxSettings(httptest.NewRecorder(), req); !errors.Is(err, errors.ErrValidationFailed) {
+		t.Errorf("expected VALIDATION_FAILED without a personal token, got %v", err)
+	}
+}
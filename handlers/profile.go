@@ -3,11 +3,14 @@ package handlers
 import (
 	"encoding/json"
 	"net/http"
+	"strings"
 
 	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/i18n"
 	"github.com/clementhaon/sandbox-api-go/logger"
 	"github.com/clementhaon/sandbox-api-go/middleware"
 	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/respond"
 	"github.com/clementhaon/sandbox-api-go/services"
 )
 
@@ -34,8 +37,12 @@ func (h *ProfileHandler) HandleGetProfile(w http.ResponseWriter, r *http.Request
 	if err != nil {
 		return err
 	}
+	data, err := selectFields(r, user)
+	if err != nil {
+		return err
+	}
 
-	json.NewEncoder(w).Encode(user)
+	respond.OK(w, data)
 	return nil
 }
 
@@ -64,10 +71,62 @@ func (h *ProfileHandler) HandleUpdateProfile(w http.ResponseWriter, r *http.Requ
 	}
 
 	response := map[string]interface{}{
-		"message": "Profile updated successfully",
+		"message": i18n.T(i18n.FromRequest(r), "profile.updated"),
 		"user":    updatedUser,
 	}
 
-	json.NewEncoder(w).Encode(response)
+	respond.OK(w, response)
+	return nil
+}
+
+func (h *ProfileHandler) HandleGetPreferences(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*models.Claims)
+	if !ok {
+		logger.ErrorContext(r.Context(), "Missing user context in authenticated request", nil)
+		return errors.NewInternalError().WithDetails(map[string]interface{}{
+			"issue": "user_context_missing",
+		})
+	}
+
+	prefs, err := h.profileService.GetPreferences(r.Context(), claims.UserID)
+	if err != nil {
+		return err
+	}
+
+	respond.OK(w, prefs)
+	return nil
+}
+
+// HandleUpdatePreferences replaces the preferences; keys left out of the
+// body are reset to their default, and unknown keys are rejected.
+func (h *ProfileHandler) HandleUpdatePreferences(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*models.Claims)
+	if !ok {
+		logger.ErrorContext(r.Context(), "Missing user context in authenticated request", nil)
+		return errors.NewInternalError().WithDetails(map[string]interface{}{
+			"issue": "user_context_missing",
+		})
+	}
+
+	prefs := models.DefaultPreferences()
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&prefs); err != nil {
+		if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+			return errors.NewBadRequestError("Unknown preference " + field)
+		}
+		return errors.NewInvalidJSONError()
+	}
+
+	updated, err := h.profileService.UpdatePreferences(r.Context(), claims.UserID, prefs)
+	if err != nil {
+		return err
+	}
+
+	respond.OK(w, updated)
 	return nil
 }
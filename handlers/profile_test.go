@@ -155,10 +155,41 @@ func TestProfileHandler_HandleUpdateProfile(t *testing.T) {
 			}
 
 			var resp map[string]interface{}
-			json.NewDecoder(w.Body).Decode(&resp)
+			decodeData(t, w, &resp)
 			if resp["message"] != "Profile updated successfully" {
 				t.Errorf("expected success message, got %v", resp["message"])
 			}
 		})
 	}
 }
+
+func TestProfileHandler_HandleUpdatePreferences(t *testing.T) {
+	var received models.Preferences
+	svc := &mocks.MockProfileService{
+		UpdatePreferencesFn: func(ctx context.Context, userID int, prefs models.Preferences) (models.Preferences, error) {
+			received = prefs
+			return prefs, nil
+		},
+	}
+	handler := NewProfileHandler(svc)
+
+	update := func(body string) error {
+		req := httptest.NewRequest(http.MethodPut, "/profile/preferences", bytes.NewBufferString(body))
+		req = withUserContext(req, 1)
+		return handler.HandleUpdatePreferences(httptest.NewRecorder(), req)
+	}
+
+	if err := update(`{"timezone":"Europe/Paris"}`); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Keys left out are reset to their default
+	if received.Timezone != "Europe/Paris" || received.Locale != "en" || !received.Notifications.Push {
+		t.Errorf("expected defaults for missing keys, got %+v", received)
+	}
+
+	err := update(`{"theme":"dark"}`)
+	appErr, ok := errors.IsAppError(err)
+	if !ok || appErr.Message != `Unknown preference "theme"` {
+		t.Errorf("expected an unknown preference error, got %v", err)
+	}
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/respond"
+	"github.com/clementhaon/sandbox-api-go/services"
+)
+
+type QuotaHandler struct {
+	quotaService services.QuotaService
+}
+
+func NewQuotaHandler(s services.QuotaService) *QuotaHandler {
+	return &QuotaHandler{quotaService: s}
+}
+
+func (h *QuotaHandler) GetUserQuota(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return errors.NewBadRequestError("Invalid user ID")
+	}
+
+	quota, err := h.quotaService.Get(r.Context(), id)
+	if err != nil {
+		return err
+	}
+
+	respond.OK(w, quota)
+	return nil
+}
+
+// UpdateUserQuota replaces a user's quota overrides. Omitted or null limits
+// fall back to the configured defaults.
+func (h *QuotaHandler) UpdateUserQuota(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return errors.NewBadRequestError("Invalid user ID")
+	}
+
+	var req models.UpdateQuotaRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return errors.NewInvalidJSONError()
+	}
+
+	quota, err := h.quotaService.Update(r.Context(), id, req)
+	if err != nil {
+		return err
+	}
+
+	respond.OK(w, quota)
+	return nil
+}
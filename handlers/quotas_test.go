@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/mocks"
+	"github.com/clementhaon/sandbox-api-go/models"
+)
+
+func TestQuotaHandler_UpdateUserQuota(t *testing.T) {
+	var receivedID int
+	var received models.UpdateQuotaRequest
+	svc := &mocks.MockQuotaService{
+		UpdateFn: func(ctx context.Context, userID int, req models.UpdateQuotaRequest) (models.UserQuota, error) {
+			receivedID, received = userID, req
+			return models.UserQuota{UserID: userID, Limits: models.QuotaLimits{MaxTasks: *req.MaxTasks}}, nil
+		},
+	}
+
+	handler := NewQuotaHandler(svc)
+	req := httptest.NewRequest(http.MethodPut, "/admin/users/7/quota", strings.NewReader(`{"maxTasks":25}`))
+	req.SetPathValue("id", "7")
+	w := httptest.NewRecorder()
+
+	if err := handler.UpdateUserQuota(w, req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if receivedID != 7 {
+		t.Errorf("expected user 7, got %d", receivedID)
+	}
+	if received.MaxTasks == nil || *received.MaxTasks != 25 || received.MaxMediaBytes != nil {
+		t.Errorf("unexpected request: %+v", received)
+	}
+
+	var quota models.UserQuota
+	decodeData(t, w, &quota)
+	if quota.Limits.MaxTasks != 25 {
+		t.Errorf("expected task limit 25, got %d", quota.Limits.MaxTasks)
+	}
+}
+
+func TestQuotaHandler_InvalidUserID(t *testing.T) {
+	handler := NewQuotaHandler(&mocks.MockQuotaService{})
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/users/abc/quota", nil)
+	req.SetPathValue("id", "abc")
+	err := handler.GetUserQuota(httptest.NewRecorder(), req)
+	if _, ok := errors.IsAppError(err); !ok {
+		t.Fatalf("expected AppError, got %v", err)
+	}
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/middleware"
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/respond"
+	"github.com/clementhaon/sandbox-api-go/services"
+)
+
+type SandboxHandler struct {
+	sandboxService services.SandboxService
+	clockService   services.ClockService
+}
+
+func NewSandboxHandler(s services.SandboxService, clocks services.ClockService) *SandboxHandler {
+	return &SandboxHandler{sandboxService: s, clockService: clocks}
+}
+
+// Reset deletes the tasks and notifications of the caller.
+func (h *SandboxHandler) Reset(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*models.Claims)
+	if !ok {
+		return errors.NewInternalError().WithDetails(map[string]interface{}{"issue": "user_context_missing"})
+	}
+
+	resp, err := h.sandboxService.Reset(r.Context(), claims.UserID)
+	if err != nil {
+		return err
+	}
+
+	respond.OK(w, resp)
+	return nil
+}
+
+// LoadScenario replaces the tasks and notifications of the caller with the
+// fixture dataset of the name in the path.
+func (h *SandboxHandler) LoadScenario(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*models.Claims)
+	if !ok {
+		return errors.NewInternalError().WithDetails(map[string]interface{}{"issue": "user_context_missing"})
+	}
+
+	resp, err := h.sandboxService.LoadScenario(r.Context(), claims.UserID, r.PathValue("name"))
+	if err != nil {
+		return err
+	}
+
+	respond.OK(w, resp)
+	return nil
+}
+
+// GetClock returns the sandbox clock of the caller.
+func (h *SandboxHandler) GetClock(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*models.Claims)
+	if !ok {
+		return errors.NewInternalError().WithDetails(map[string]interface{}{"issue": "user_context_missing"})
+	}
+
+	resp, err := h.clockService.Get(r.Context(), claims.UserID)
+	if err != nil {
+		return err
+	}
+
+	respond.OK(w, resp)
+	return nil
+}
+
+// SetClock moves the sandbox clock of the caller, from their next request.
+func (h *SandboxHandler) SetClock(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*models.Claims)
+	if !ok {
+		return errors.NewInternalError().WithDetails(map[string]interface{}{"issue": "user_context_missing"})
+	}
+
+	var req models.SetSandboxClockRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return errors.NewInvalidJSONError()
+	}
+
+	resp, err := h.clockService.Set(r.Context(), claims.UserID, req)
+	if err != nil {
+		return err
+	}
+
+	respond.OK(w, resp)
+	return nil
+}
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/mocks"
+	"github.com/clementhaon/sandbox-api-go/models"
+)
+
+func TestSandboxHandler_LoadScenario(t *testing.T) {
+	var gotUserID int
+	var gotName string
+	svc := &mocks.MockSandboxService{
+		LoadScenarioFn: func(ctx context.Context, userID int, name string) (models.SandboxScenarioResponse, error) {
+			if name != models.ScenarioSmall {
+				return models.SandboxScenarioResponse{}, errors.NewInvalidFormatError("name", "small")
+			}
+			gotUserID, gotName = userID, name
+			return models.SandboxScenarioResponse{Scenario: name, Tasks: 5}, nil
+		},
+	}
+	handler := NewSandboxHandler(svc, &mocks.MockClockService{})
+
+	w := httptest.NewRecorder()
+	req := withUserContext(httptest.NewRequest(http.MethodPost, "/sandbox/scenarios/small", nil), 3)
+	req.SetPathValue("name", "small")
+	if err := handler.LoadScenario(w, req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var resp models.SandboxScenarioResponse
+	decodeData(t, w, &resp)
+	if gotUserID != 3 || gotName != "small" || resp.Tasks != 5 {
+		t.Errorf("expected the small scenario loaded for user 3, got %+v", resp)
+	}
+
+	req = withUserContext(httptest.NewRequest(http.MethodPost, "/sandbox/scenarios/huge", nil), 3)
+	req.SetPathValue("name", "huge")
+	if err := handler.LoadScenario(httptest.NewRecorder(), req); !errors.Is(err, errors.ErrInvalidFormat) {
+		t.Errorf("expected INVALID_FORMAT, got %v", err)
+	}
+}
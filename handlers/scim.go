@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/clementhaon/sandbox-api-go/middleware"
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/scim"
+	"github.com/clementhaon/sandbox-api-go/services"
+)
+
+// SCIMHandler serves the SCIM 2.0 Users endpoint. Responses and errors are
+// in the SCIM format, not wrapped in the API envelope.
+type SCIMHandler struct {
+	scimService services.SCIMService
+}
+
+func NewSCIMHandler(s services.SCIMService) *SCIMHandler {
+	return &SCIMHandler{scimService: s}
+}
+
+// ListUsers searches users with ?filter=, ?startIndex= and ?count=.
+func (h *SCIMHandler) ListUsers(w http.ResponseWriter, r *http.Request) error {
+	startIndex, _ := strconv.Atoi(r.URL.Query().Get("startIndex"))
+	count, _ := strconv.Atoi(r.URL.Query().Get("count"))
+
+	list, err := h.scimService.List(r.Context(), models.SCIMListParams{
+		Filter:     r.URL.Query().Get("filter"),
+		StartIndex: startIndex,
+		Count:      count,
+	})
+	if err != nil {
+		scim.WriteError(w, scim.ErrorFrom(err))
+		return nil
+	}
+	for i := range list.Resources {
+		list.Resources[i] = withSCIMBasePath(r, list.Resources[i])
+	}
+	scim.Write(w, http.StatusOK, list)
+	return nil
+}
+
+func (h *SCIMHandler) GetUser(w http.ResponseWriter, r *http.Request) error {
+	user, err := h.scimService.Get(r.Context(), r.PathValue("id"))
+	if err != nil {
+		scim.WriteError(w, scim.ErrorFrom(err))
+		return nil
+	}
+	scim.Write(w, http.StatusOK, withSCIMBasePath(r, user))
+	return nil
+}
+
+func (h *SCIMHandler) CreateUser(w http.ResponseWriter, r *http.Request) error {
+	var req models.SCIMUser
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		scim.WriteError(w, scim.BadRequest(scim.ErrInvalidSyntax, "invalid JSON"))
+		return nil
+	}
+
+	user, err := h.scimService.Create(r.Context(), req)
+	if err != nil {
+		scim.WriteError(w, scim.ErrorFrom(err))
+		return nil
+	}
+	user = withSCIMBasePath(r, user)
+	w.Header().Set("Location", user.Meta.Location)
+	scim.Write(w, http.StatusCreated, user)
+	return nil
+}
+
+func (h *SCIMHandler) PatchUser(w http.ResponseWriter, r *http.Request) error {
+	var req models.SCIMPatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		scim.WriteError(w, scim.BadRequest(scim.ErrInvalidSyntax, "invalid JSON"))
+		return nil
+	}
+
+	user, err := h.scimService.Patch(r.Context(), r.PathValue("id"), req)
+	if err != nil {
+		scim.WriteError(w, scim.ErrorFrom(err))
+		return nil
+	}
+	scim.Write(w, http.StatusOK, withSCIMBasePath(r, user))
+	return nil
+}
+
+func (h *SCIMHandler) DeleteUser(w http.ResponseWriter, r *http.Request) error {
+	if err := h.scimService.Delete(r.Context(), r.PathValue("id")); err != nil {
+		scim.WriteError(w, scim.ErrorFrom(err))
+		return nil
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// withSCIMBasePath makes the location of user start with the path the API
+// is served under.
+func withSCIMBasePath(r *http.Request, user models.SCIMUser) models.SCIMUser {
+	if user.Meta != nil {
+		meta := *user.Meta
+		meta.Location = middleware.BasePath(r.Context()) + meta.Location
+		user.Meta = &meta
+	}
+	return user
+}
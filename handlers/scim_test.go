@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/mocks"
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/scim"
+)
+
+func TestSCIMHandler_ListUsers(t *testing.T) {
+	var got models.SCIMListParams
+	h := NewSCIMHandler(&mocks.MockSCIMService{
+		ListFn: func(ctx context.Context, params models.SCIMListParams) (models.SCIMListResponse, error) {
+			got = params
+			return models.SCIMListResponse{Schemas: []string{models.SCIMSchemaListResponse}, TotalResults: 1}, nil
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, `/scim/v2/Users?filter=userName+eq+%22jane%22&startIndex=11&count=10`, nil)
+	w := httptest.NewRecorder()
+	if err := h.ListUsers(w, req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Filter != `userName eq "jane"` || got.StartIndex != 11 || got.Count != 10 {
+		t.Errorf("unexpected params %+v", got)
+	}
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != scim.ContentType {
+		t.Errorf("unexpected response %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	var list models.SCIMListResponse
+	if err := json.NewDecoder(w.Body).Decode(&list); err != nil || list.TotalResults != 1 {
+		t.Errorf("expected the list unwrapped, got %+v (%v)", list, err)
+	}
+}
+
+func TestSCIMHandler_CreateUser(t *testing.T) {
+	h := NewSCIMHandler(&mocks.MockSCIMService{
+		CreateFn: func(ctx context.Context, user models.SCIMUser) (models.SCIMUser, error) {
+			if user.UserName == "taken" {
+				return models.SCIMUser{}, scim.NewError(http.StatusConflict, scim.ErrUniqueness, "userName or email already in use")
+			}
+			user.ID = "9"
+			user.Meta = &models.SCIMMeta{ResourceType: "User", Location: "/scim/v2/Users/9"}
+			return user, nil
+		},
+	})
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/scim/v2/Users", strings.NewReader(body))
+		req.Header.Set("Content-Type", scim.ContentType)
+		w := httptest.NewRecorder()
+		if err := h.CreateUser(w, req); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return w
+	}
+
+	w := post(`{"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"], "userName": "jane"}`)
+	if w.Code != http.StatusCreated || w.Header().Get("Location") != "/scim/v2/Users/9" {
+		t.Errorf("expected 201 with a Location, got %d %q", w.Code, w.Header().Get("Location"))
+	}
+
+	tests := []struct {
+		body     string
+		status   int
+		scimType string
+	}{
+		{`{"userName": "taken"}`, http.StatusConflict, scim.ErrUniqueness},
+		{`{"userName": `, http.StatusBadRequest, scim.ErrInvalidSyntax},
+	}
+	for _, tt := range tests {
+		w := post(tt.body)
+		var body scim.Error
+		if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+			t.Fatalf("failed to decode error: %v", err)
+		}
+		if w.Code != tt.status || body.ScimType != tt.scimType {
+			t.Errorf("%s: expected %d %s, got %d %+v", tt.body, tt.status, tt.scimType, w.Code, body)
+		}
+	}
+}
+
+func TestSCIMHandler_PatchAndDeleteUser(t *testing.T) {
+	var patched models.SCIMPatchRequest
+	h := NewSCIMHandler(&mocks.MockSCIMService{
+		PatchFn: func(ctx context.Context, id string, req models.SCIMPatchRequest) (models.SCIMUser, error) {
+			patched = req
+			return models.SCIMUser{ID: id}, nil
+		},
+		DeleteFn: func(ctx context.Context, id string) error {
+			if id != "7" {
+				return errors.NewNotFoundError("User")
+			}
+			return nil
+		},
+	})
+
+	body := `{"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"], "Operations": [{"op": "replace", "path": "active", "value": false}]}`
+	req := httptest.NewRequest(http.MethodPatch, "/scim/v2/Users/7", strings.NewReader(body))
+	req.SetPathValue("id", "7")
+	w := httptest.NewRecorder()
+	if err := h.PatchUser(w, req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if w.Code != http.StatusOK || len(patched.Operations) != 1 || string(patched.Operations[0].Value) != "false" {
+		t.Errorf("unexpected patch %d %+v", w.Code, patched)
+	}
+
+	for id, want := range map[string]int{"7": http.StatusNoContent, "8": http.StatusNotFound} {
+		req := httptest.NewRequest(http.MethodDelete, "/scim/v2/Users/"+id, nil)
+		req.SetPathValue("id", id)
+		w := httptest.NewRecorder()
+		if err := h.DeleteUser(w, req); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if w.Code != want {
+			t.Errorf("DELETE %s: status = %d, want %d", id, w.Code, want)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/i18n"
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/respond"
+	"github.com/clementhaon/sandbox-api-go/services"
+)
+
+type SessionHandler struct {
+	sessionService services.SessionService
+}
+
+func NewSessionHandler(s services.SessionService) *SessionHandler {
+	return &SessionHandler{sessionService: s}
+}
+
+// HandleRevoke signs out the session of a login alert. It is public: the
+// token of the "this wasn't me" link proves access to the account's email.
+func (h *SessionHandler) HandleRevoke(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req models.RevokeSessionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return errors.NewInvalidJSONError()
+	}
+
+	if err := h.sessionService.Revoke(r.Context(), req); err != nil {
+		return err
+	}
+
+	respond.OK(w, map[string]interface{}{
+		"message": i18n.T(i18n.FromRequest(r), "auth.session_revoked"),
+	})
+	return nil
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/mocks"
+	"github.com/clementhaon/sandbox-api-go/models"
+)
+
+func TestSessionHandler_Revoke(t *testing.T) {
+	var gotToken string
+	svc := &mocks.MockSessionService{
+		RevokeFn: func(ctx context.Context, req models.RevokeSessionRequest) error {
+			gotToken = req.Token
+			if req.Token != "abc123" {
+				return errors.NewInvalidTokenError()
+			}
+			return nil
+		},
+	}
+	handler := NewSessionHandler(svc)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/auth/sessions/revoke", strings.NewReader(`{"token":"abc123"}`))
+	if err := handler.HandleRevoke(w, req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if w.Code != http.StatusOK || gotToken != "abc123" {
+		t.Errorf("expected status 200 revoking abc123, got %d revoking %q", w.Code, gotToken)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/auth/sessions/revoke", strings.NewReader(`{"token":"stale"}`))
+	if err := handler.HandleRevoke(httptest.NewRecorder(), req); !errors.Is(err, errors.ErrInvalidToken) {
+		t.Errorf("expected INVALID_TOKEN, got %v", err)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/auth/sessions/revoke", strings.NewReader(`{`))
+	if err := handler.HandleRevoke(httptest.NewRecorder(), req); !errors.Is(err, errors.ErrInvalidJSON) {
+		t.Errorf("expected INVALID_JSON, got %v", err)
+	}
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/middleware"
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/respond"
+	"github.com/clementhaon/sandbox-api-go/services"
+	"github.com/clementhaon/sandbox-api-go/slack"
+)
+
+type SlackHandler struct {
+	slackService services.SlackService
+}
+
+func NewSlackHandler(s services.SlackService) *SlackHandler {
+	return &SlackHandler{slackService: s}
+}
+
+// HandleCommand answers a slash command posted by Slack, whose signature
+// was checked by middleware.NewSlackSignature.
+func (h *SlackHandler) HandleCommand(w http.ResponseWriter, r *http.Request) error {
+	if err := r.ParseForm(); err != nil {
+		return errors.NewBadRequestError("Invalid form")
+	}
+
+	msg, err := h.slackService.HandleCommand(r.Context(), slack.ParseCommand(r.PostForm))
+	if err != nil {
+		return err
+	}
+
+	slack.Write(w, msg)
+	return nil
+}
+
+// CreateLinkCode returns a code to send from Slack to link a Slack account.
+func (h *SlackHandler) CreateLinkCode(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*models.Claims)
+	if !ok {
+		return errors.NewInternalError().WithDetails(map[string]interface{}{"issue": "user_context_missing"})
+	}
+
+	resp, err := h.slackService.CreateLinkCode(r.Context(), claims.UserID)
+	if err != nil {
+		return err
+	}
+
+	respond.Created(w, resp)
+	return nil
+}
+
+func (h *SlackHandler) ListLinks(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*models.Claims)
+	if !ok {
+		return errors.NewInternalError().WithDetails(map[string]interface{}{"issue": "user_context_missing"})
+	}
+
+	links, err := h.slackService.ListLinks(r.Context(), claims.UserID)
+	if err != nil {
+		return err
+	}
+
+	respond.OK(w, links)
+	return nil
+}
+
+func (h *SlackHandler) Unlink(w http.ResponseWriter, r *http.Request) error {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*models.Claims)
+	if !ok {
+		return errors.NewInternalError().WithDetails(map[string]interface{}{"issue": "user_context_missing"})
+	}
+
+	if err := h.slackService.Unlink(r.Context(), claims.UserID); err != nil {
+		return err
+	}
+
+	respond.NoContent(w)
+	return nil
+}
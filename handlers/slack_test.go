@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/clementhaon/sandbox-api-go/mocks"
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/slack"
+)
+
+func TestSlackHandler_HandleCommand(t *testing.T) {
+	var got slack.Command
+	h := NewSlackHandler(&mocks.MockSlackService{
+		HandleCommandFn: func(ctx context.Context, cmd slack.Command) (slack.Message, error) {
+			got = cmd
+			return slack.Ephemeral("You have no open tasks."), nil
+		},
+	})
+
+	form := url.Values{"team_id": {"T1"}, "user_id": {"U1"}, "command": {"/tasks"}, "text": {"list"}}
+	req := httptest.NewRequest(http.MethodPost, "/integrations/slack/commands", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	if err := h.HandleCommand(w, req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got.TeamID != "T1" || got.UserID != "U1" || got.Text != "list" {
+		t.Errorf("unexpected command %+v", got)
+	}
+	if w.Code != http.StatusOK {
+		t.Errorf("status = %d, want 200", w.Code)
+	}
+	var msg slack.Message
+	if err := json.NewDecoder(w.Body).Decode(&msg); err != nil {
+		t.Fatalf("failed to decode message: %v", err)
+	}
+	if msg.ResponseType != slack.ResponseEphemeral || msg.Text != "You have no open tasks." {
+		t.Errorf("unexpected message %+v", msg)
+	}
+}
+
+func TestSlackHandler_CreateLinkCode(t *testing.T) {
+	h := NewSlackHandler(&mocks.MockSlackService{
+		CreateLinkCodeFn: func(ctx context.Context, userID int) (models.SlackLinkCodeResponse, error) {
+			if userID != 7 {
+				t.Errorf("userID = %d, want 7", userID)
+			}
+			return models.SlackLinkCodeResponse{Code: "ABCD2345", Command: "/tasks link ABCD2345"}, nil
+		},
+	})
+
+	req := withUserContext(httptest.NewRequest(http.MethodPost, "/integrations/slack/link-code", nil), 7)
+	w := httptest.NewRecorder()
+	if err := h.CreateLinkCode(w, req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if w.Code != http.StatusCreated {
+		t.Errorf("status = %d, want 201", w.Code)
+	}
+	var resp models.SlackLinkCodeResponse
+	decodeData(t, w, &resp)
+	if resp.Code != "ABCD2345" {
+		t.Errorf("unexpected response %+v", resp)
+	}
+}
@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/respond"
+	"github.com/clementhaon/sandbox-api-go/services"
+)
+
+type StatsHandler struct {
+	statsService services.StatsService
+}
+
+func NewStatsHandler(s services.StatsService) *StatsHandler {
+	return &StatsHandler{statsService: s}
+}
+
+// GetStats returns the ops dashboard summary. ?days sets how many days of
+// signups are included, up to services.MaxStatsDays.
+func (h *StatsHandler) GetStats(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	days := services.DefaultStatsDays
+	if v := r.URL.Query().Get("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > services.MaxStatsDays {
+			return errors.NewBadRequestError("days must be between 1 and " + strconv.Itoa(services.MaxStatsDays))
+		}
+		days = n
+	}
+
+	stats, err := h.statsService.Get(r.Context(), days)
+	if err != nil {
+		return err
+	}
+
+	respond.OK(w, stats)
+	return nil
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/mocks"
+	"github.com/clementhaon/sandbox-api-go/models"
+)
+
+func TestStatsHandler_GetStats(t *testing.T) {
+	var receivedDays int
+	svc := &mocks.MockStatsService{
+		GetFn: func(ctx context.Context, days int) (models.AdminStats, error) {
+			receivedDays = days
+			return models.AdminStats{Users: models.UserStats{Total: 12}}, nil
+		},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/stats?days=7", nil)
+	w := httptest.NewRecorder()
+	if err := NewStatsHandler(svc).GetStats(w, req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if receivedDays != 7 {
+		t.Errorf("expected 7 days, got %d", receivedDays)
+	}
+
+	var stats models.AdminStats
+	decodeData(t, w, &stats)
+	if stats.Users.Total != 12 {
+		t.Errorf("expected 12 users, got %d", stats.Users.Total)
+	}
+}
+
+func TestStatsHandler_InvalidDays(t *testing.T) {
+	handler := NewStatsHandler(&mocks.MockStatsService{})
+
+	for _, days := range []string{"abc", "0", "91"} {
+		req := httptest.NewRequest(http.MethodGet, "/admin/stats?days="+days, nil)
+		err := handler.GetStats(httptest.NewRecorder(), req)
+		if _, ok := errors.IsAppError(err); !ok {
+			t.Errorf("days=%s: expected AppError, got %v", days, err)
+		}
+	}
+}
@@ -2,12 +2,17 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/clementhaon/sandbox-api-go/errors"
 	"github.com/clementhaon/sandbox-api-go/middleware"
 	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/respond"
 	"github.com/clementhaon/sandbox-api-go/services"
 )
 
@@ -27,28 +32,208 @@ func (h *TaskHandler) GetBoard(w http.ResponseWriter, r *http.Request) error {
 		return err
 	}
 
-	json.NewEncoder(w).Encode(board)
+	respond.OK(w, board)
 	return nil
 }
 
-func (h *TaskHandler) ListTasks(w http.ResponseWriter, r *http.Request) error {
-	w.Header().Set("Content-Type", "application/json")
+// parseIDList parses comma-separated positive IDs.
+func parseIDList(raw string) ([]int, error) {
+	parts := strings.Split(raw, ",")
+	ids := make([]int, 0, len(parts))
+	for _, part := range parts {
+		id, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || id < 1 {
+			return nil, fmt.Errorf("invalid ID %q", part)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// selectFields trims data to the fields of ?fields=, for clients wanting
+// lighter responses.
+func selectFields(r *http.Request, data interface{}) (interface{}, error) {
+	projected, err := respond.Project(data, respond.Fields(r))
+	if err != nil {
+		return nil, errors.NewInternalError().WithCause(err)
+	}
+	return projected, nil
+}
+
+// taskIncludes reads the related resources asked for with ?include=.
+func taskIncludes(r *http.Request) ([]string, error) {
+	raw := r.URL.Query().Get("include")
+	if raw == "" {
+		return nil, nil
+	}
+	var include []string
+	for _, name := range strings.Split(raw, ",") {
+		name = strings.TrimSpace(name)
+		if !slices.Contains(models.ValidTaskIncludes(), name) {
+			return nil, errors.NewInvalidFormatError("include", strings.Join(models.ValidTaskIncludes(), ", "))
+		}
+		include = append(include, name)
+	}
+	return include, nil
+}
 
+// taskListParams reads the filters and sort order of task lists from the
+// query string.
+func taskListParams(r *http.Request) (models.TaskListParams, error) {
 	var columnID *int
 	if columnIDStr := r.URL.Query().Get("columnId"); columnIDStr != "" {
 		id, err := strconv.Atoi(columnIDStr)
 		if err != nil {
-			return errors.NewBadRequestError("Invalid columnId")
+			return models.TaskListParams{}, errors.NewBadRequestError("Invalid columnId")
 		}
 		columnID = &id
 	}
 
-	tasks, err := h.taskService.List(r.Context(), columnID)
+	params := models.TaskListParams{
+		ColumnID:  columnID,
+		SortBy:    r.URL.Query().Get("sortBy"),
+		SortOrder: r.URL.Query().Get("sortOrder"),
+	}
+	if statuses := r.URL.Query().Get("status"); statuses != "" {
+		for _, status := range strings.Split(statuses, ",") {
+			if !slices.Contains(models.ValidTaskStatuses(), status) {
+				return models.TaskListParams{}, errors.NewInvalidFormatError("status", strings.Join(models.ValidTaskStatuses(), ", "))
+			}
+			params.Statuses = append(params.Statuses, status)
+		}
+	}
+	if since := r.URL.Query().Get("updatedSince"); since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			return models.TaskListParams{}, errors.NewInvalidFormatError("updatedSince", "RFC 3339 timestamp")
+		}
+		params.UpdatedSince = &t
+	}
+	return params, nil
+}
+
+// include embeds the related resources of ?include= in tasks.
+func (h *TaskHandler) include(r *http.Request, tasks []models.Task, include []string) error {
+	if len(include) == 0 {
+		return nil
+	}
+	return h.taskService.Include(r.Context(), tasks, include)
+}
+
+func (h *TaskHandler) ListTasks(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	include, err := taskIncludes(r)
+	if err != nil {
+		return err
+	}
+
+	// ?ids= fetches those tasks in one query, ignoring the other filters;
+	// the IDs matching no task are listed in meta.missingIds
+	if raw := r.URL.Query().Get("ids"); raw != "" {
+		ids, err := parseIDList(raw)
+		if err != nil {
+			return errors.NewInvalidFormatError("ids", "comma-separated task IDs")
+		}
+		tasks, missing, err := h.taskService.GetByIDs(r.Context(), ids)
+		if err != nil {
+			return err
+		}
+		if err := h.include(r, tasks, include); err != nil {
+			return err
+		}
+		data, err := selectFields(r, tasks)
+		if err != nil {
+			return err
+		}
+		respond.WriteJSON(w, http.StatusOK, data, respond.Meta{"missingIds": missing})
+		return nil
+	}
+
+	params, err := taskListParams(r)
+	if err != nil {
+		return err
+	}
+
+	// Polling clients revalidate with If-Modified-Since and get 304 while
+	// no task of the tenant changed. Checklist changes touch their task,
+	// column changes do not: embedded columns are always sent again.
+	modified, err := h.taskService.LastModified(r.Context())
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Cache-Control", "private, no-cache")
+	if modified.Settled && !modified.At.IsZero() && !slices.Contains(include, models.TaskIncludeColumn) {
+		lastModified := modified.At.UTC().Truncate(time.Second)
+		w.Header().Set("Last-Modified", lastModified.Format(http.TimeFormat))
+		if since, err := http.ParseTime(r.Header.Get("If-Modified-Since")); err == nil && !lastModified.After(since) {
+			w.WriteHeader(http.StatusNotModified)
+			return nil
+		}
+	}
+
+	tasks, err := h.taskService.List(r.Context(), params)
+	if err != nil {
+		return err
+	}
+	if err := h.include(r, tasks, include); err != nil {
+		return err
+	}
+	data, err := selectFields(r, tasks)
+	if err != nil {
+		return err
+	}
+
+	respond.OK(w, data)
+	return nil
+}
+
+// ExportTasks streams every task matching the filters of ListTasks, as the
+// rows are read, in a JSON list or as NDJSON.
+func (h *TaskHandler) ExportTasks(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	params, err := taskListParams(r)
 	if err != nil {
 		return err
 	}
 
-	json.NewEncoder(w).Encode(tasks)
+	list := respond.NewListWriter(w, r)
+	err = h.taskService.Each(r.Context(), params, func(task models.Task) error {
+		return list.Write(task)
+	})
+	if err != nil {
+		return list.Fail(err)
+	}
+	list.Close()
+	return nil
+}
+
+// ListTaskChanges returns tasks modified after the ?since= cursor, with the
+// cursor for the next call in meta.pagination.nextCursor.
+func (h *TaskHandler) ListTaskChanges(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	var after *models.TaskCursor
+	if since := r.URL.Query().Get("since"); since != "" {
+		cursor, err := models.DecodeTaskCursor(since)
+		if err != nil {
+			return errors.NewInvalidFormatError("since", "cursor returned by a previous call")
+		}
+		after = &cursor
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	tasks, page, err := h.taskService.Changes(r.Context(), after, limit)
+	if err != nil {
+		return err
+	}
+	data, err := selectFields(r, tasks)
+	if err != nil {
+		return err
+	}
+
+	respond.Paginated(w, data, page)
 	return nil
 }
 
@@ -60,12 +245,25 @@ func (h *TaskHandler) GetTask(w http.ResponseWriter, r *http.Request) error {
 		return errors.NewBadRequestError("Invalid task ID")
 	}
 
+	include, err := taskIncludes(r)
+	if err != nil {
+		return err
+	}
+
 	task, err := h.taskService.GetByID(r.Context(), id)
 	if err != nil {
 		return err
 	}
+	tasks := []models.Task{task}
+	if err := h.include(r, tasks, include); err != nil {
+		return err
+	}
+	data, err := selectFields(r, tasks[0])
+	if err != nil {
+		return err
+	}
 
-	json.NewEncoder(w).Encode(task)
+	respond.OK(w, data)
 	return nil
 }
 
@@ -89,14 +287,20 @@ func (h *TaskHandler) CreateTask(w http.ResponseWriter, r *http.Request) error {
 		return err
 	}
 
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(task)
+	respond.Created(w, task)
 	return nil
 }
 
 func (h *TaskHandler) UpdateTask(w http.ResponseWriter, r *http.Request) error {
 	w.Header().Set("Content-Type", "application/json")
 
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*models.Claims)
+	if !ok {
+		return errors.NewInternalError().WithDetails(map[string]interface{}{
+			"issue": "user_context_missing",
+		})
+	}
+
 	id, err := strconv.Atoi(r.PathValue("id"))
 	if err != nil {
 		return errors.NewBadRequestError("Invalid task ID")
@@ -107,12 +311,12 @@ func (h *TaskHandler) UpdateTask(w http.ResponseWriter, r *http.Request) error {
 		return errors.NewInvalidJSONError()
 	}
 
-	task, err := h.taskService.Update(r.Context(), id, req)
+	task, err := h.taskService.Update(r.Context(), claims.UserID, id, req)
 	if err != nil {
 		return err
 	}
 
-	json.NewEncoder(w).Encode(task)
+	respond.OK(w, task)
 	return nil
 }
 
@@ -134,7 +338,77 @@ func (h *TaskHandler) MoveTask(w http.ResponseWriter, r *http.Request) error {
 		return err
 	}
 
-	json.NewEncoder(w).Encode(task)
+	respond.OK(w, task)
+	return nil
+}
+
+func (h *TaskHandler) SetTaskStatus(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*models.Claims)
+	if !ok {
+		return errors.NewInternalError().WithDetails(map[string]interface{}{
+			"issue": "user_context_missing",
+		})
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return errors.NewBadRequestError("Invalid task ID")
+	}
+
+	var req models.SetTaskStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return errors.NewInvalidJSONError()
+	}
+
+	task, err := h.taskService.SetStatus(r.Context(), claims.UserID, id, req)
+	if err != nil {
+		return err
+	}
+
+	respond.OK(w, task)
+	return nil
+}
+
+func (h *TaskHandler) CompleteTask(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*models.Claims)
+	if !ok {
+		return errors.NewInternalError().WithDetails(map[string]interface{}{
+			"issue": "user_context_missing",
+		})
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return errors.NewBadRequestError("Invalid task ID")
+	}
+
+	task, err := h.taskService.Complete(r.Context(), claims.UserID, id)
+	if err != nil {
+		return err
+	}
+
+	respond.OK(w, task)
+	return nil
+}
+
+func (h *TaskHandler) ReopenTask(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return errors.NewBadRequestError("Invalid task ID")
+	}
+
+	task, err := h.taskService.Reopen(r.Context(), id)
+	if err != nil {
+		return err
+	}
+
+	respond.OK(w, task)
 	return nil
 }
 
@@ -158,7 +432,7 @@ func (h *TaskHandler) ReorderTasks(w http.ResponseWriter, r *http.Request) error
 		return err
 	}
 
-	json.NewEncoder(w).Encode(tasks)
+	respond.OK(w, tasks)
 	return nil
 }
 
@@ -174,6 +448,6 @@ func (h *TaskHandler) DeleteTask(w http.ResponseWriter, r *http.Request) error {
 		return err
 	}
 
-	w.WriteHeader(http.StatusNoContent)
+	respond.NoContent(w)
 	return nil
 }
@@ -7,11 +7,13 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/clementhaon/sandbox-api-go/errors"
 	"github.com/clementhaon/sandbox-api-go/middleware"
 	"github.com/clementhaon/sandbox-api-go/mocks"
 	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/respond"
 )
 
 func withUserContext(r *http.Request, userID int) *http.Request {
@@ -20,6 +22,23 @@ func withUserContext(r *http.Request, userID int) *http.Request {
 	return r.WithContext(ctx)
 }
 
+// decodeData decodes the data field of a respond.Envelope into v.
+func decodeData(t *testing.T, w *httptest.ResponseRecorder, v interface{}) respond.Envelope {
+	t.Helper()
+	var raw json.RawMessage
+	envelope := respond.Envelope{Data: &raw}
+	if err := json.NewDecoder(w.Body).Decode(&envelope); err != nil {
+		t.Fatalf("failed to decode response envelope: %v", err)
+	}
+	if !envelope.Success {
+		t.Error("expected success envelope")
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		t.Fatalf("failed to decode response data: %v", err)
+	}
+	return envelope
+}
+
 func TestTaskHandler_GetBoard(t *testing.T) {
 	svc := &mocks.MockTaskService{
 		GetBoardFn: func(ctx context.Context) (models.BoardResponse, error) {
@@ -43,7 +62,7 @@ func TestTaskHandler_GetBoard(t *testing.T) {
 	}
 
 	var board models.BoardResponse
-	json.NewDecoder(w.Body).Decode(&board)
+	decodeData(t, w, &board)
 	if len(board.Columns) != 1 {
 		t.Errorf("expected 1 column, got %d", len(board.Columns))
 	}
@@ -52,9 +71,15 @@ func TestTaskHandler_GetBoard(t *testing.T) {
 	}
 }
 
+// neverModified reports a task list that has never changed.
+func neverModified(ctx context.Context) (models.TaskListModified, error) {
+	return models.TaskListModified{Settled: true}, nil
+}
+
 func TestTaskHandler_ListTasks(t *testing.T) {
 	svc := &mocks.MockTaskService{
-		ListFn: func(ctx context.Context, columnID *int) ([]models.Task, error) {
+		LastModifiedFn: neverModified,
+		ListFn: func(ctx context.Context, params models.TaskListParams) ([]models.Task, error) {
 			return []models.Task{
 				{ID: 1, Title: "Task 1"},
 				{ID: 2, Title: "Task 2"},
@@ -72,17 +97,159 @@ func TestTaskHandler_ListTasks(t *testing.T) {
 	}
 
 	var tasks []models.Task
-	json.NewDecoder(w.Body).Decode(&tasks)
+	decodeData(t, w, &tasks)
 	if len(tasks) != 2 {
 		t.Errorf("expected 2 tasks, got %d", len(tasks))
 	}
 }
 
+func TestTaskHandler_ListTasks_ByIDs(t *testing.T) {
+	var got []int
+	svc := &mocks.MockTaskService{
+		GetByIDsFn: func(ctx context.Context, ids []int) ([]models.Task, []int, error) {
+			got = ids
+			return []models.Task{{ID: 1, Title: "Task 1"}}, []int{9}, nil
+		},
+	}
+	handler := NewTaskHandler(svc)
+
+	req := httptest.NewRequest(http.MethodGet, "/tasks?ids=1,%209", nil)
+	w := httptest.NewRecorder()
+	if err := handler.ListTasks(w, req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(got) != 2 || got[0] != 1 || got[1] != 9 {
+		t.Errorf("expected IDs [1 9], got %v", got)
+	}
+	var tasks []models.Task
+	envelope := decodeData(t, w, &tasks)
+	if len(tasks) != 1 || tasks[0].ID != 1 {
+		t.Errorf("unexpected tasks %+v", tasks)
+	}
+	if missing, _ := envelope.Meta["missingIds"].([]interface{}); len(missing) != 1 || missing[0] != float64(9) {
+		t.Errorf("expected missingIds [9], got %v", envelope.Meta["missingIds"])
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/tasks?ids=1,x", nil)
+	if err := handler.ListTasks(httptest.NewRecorder(), req); err == nil {
+		t.Error("expected invalid IDs to be refused")
+	}
+}
+
+func TestTaskHandler_ExportTasks(t *testing.T) {
+	var got models.TaskListParams
+	svc := &mocks.MockTaskService{
+		EachFn: func(ctx context.Context, params models.TaskListParams, fn func(models.Task) error) error {
+			got = params
+			for i := 1; i <= 3; i++ {
+				if err := fn(models.Task{ID: i, Title: "Task"}); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+
+	handler := NewTaskHandler(svc)
+	req := httptest.NewRequest(http.MethodGet, "/tasks/export?status=done&format=ndjson", nil)
+	w := httptest.NewRecorder()
+
+	if err := handler.ExportTasks(w, req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got.Statuses) != 1 || got.Statuses[0] != "done" {
+		t.Errorf("expected the status filter, got %+v", got)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != respond.NDJSONContentType {
+		t.Errorf("expected NDJSON, got %q", ct)
+	}
+	lines := bytes.Split(bytes.TrimSpace(w.Body.Bytes()), []byte("\n"))
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines, got %q", w.Body.String())
+	}
+	var task models.Task
+	if err := json.Unmarshal(lines[2], &task); err != nil || task.ID != 3 {
+		t.Errorf("unexpected last line %q", lines[2])
+	}
+}
+
+func TestTaskHandler_ExportTasks_Errors(t *testing.T) {
+	handler := NewTaskHandler(&mocks.MockTaskService{
+		EachFn: func(ctx context.Context, params models.TaskListParams, fn func(models.Task) error) error {
+			return errors.NewDatabaseError()
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/tasks/export", nil)
+	if err := handler.ExportTasks(httptest.NewRecorder(), req); err == nil {
+		t.Error("expected the error before any task to be returned")
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/tasks/export?status=bogus", nil)
+	if err := handler.ExportTasks(httptest.NewRecorder(), req); err == nil {
+		t.Error("expected an invalid status to be refused")
+	}
+}
+
+func TestTaskHandler_ListTasks_IfModifiedSince(t *testing.T) {
+	modifiedAt := time.Date(2026, 3, 1, 10, 0, 0, 500_000_000, time.UTC)
+	modified := models.TaskListModified{At: modifiedAt, Settled: true}
+	listed := 0
+	svc := &mocks.MockTaskService{
+		LastModifiedFn: func(ctx context.Context) (models.TaskListModified, error) {
+			return modified, nil
+		},
+		ListFn: func(ctx context.Context, params models.TaskListParams) ([]models.Task, error) {
+			listed++
+			return []models.Task{{ID: 1, Title: "Task 1"}}, nil
+		},
+	}
+	handler := NewTaskHandler(svc)
+	list := func(ifModifiedSince string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/tasks", nil)
+		if ifModifiedSince != "" {
+			req.Header.Set("If-Modified-Since", ifModifiedSince)
+		}
+		w := httptest.NewRecorder()
+		if err := handler.ListTasks(w, req); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return w
+	}
+
+	w := list("")
+	lastModified := w.Header().Get("Last-Modified")
+	if lastModified != "Sun, 01 Mar 2026 10:00:00 GMT" {
+		t.Fatalf("got Last-Modified %q", lastModified)
+	}
+
+	if w := list(lastModified); w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Errorf("expected an empty 304, got %d with %q", w.Code, w.Body.String())
+	}
+	if listed != 1 {
+		t.Errorf("expected the tasks not to be queried for a 304, got %d queries", listed)
+	}
+
+	if w := list("Sun, 01 Mar 2026 09:59:59 GMT"); w.Code != http.StatusOK {
+		t.Errorf("expected 200 for an older date, got %d", w.Code)
+	}
+
+	// A change may still follow in the same second: no validator yet
+	modified.Settled = false
+	w = list(lastModified)
+	if w.Code != http.StatusOK || w.Header().Get("Last-Modified") != "" {
+		t.Errorf("expected 200 without Last-Modified, got %d %q", w.Code, w.Header().Get("Last-Modified"))
+	}
+}
+
 func TestTaskHandler_ListTasks_WithColumnFilter(t *testing.T) {
 	var receivedColumnID *int
 	svc := &mocks.MockTaskService{
-		ListFn: func(ctx context.Context, columnID *int) ([]models.Task, error) {
-			receivedColumnID = columnID
+		LastModifiedFn: neverModified,
+		ListFn: func(ctx context.Context, params models.TaskListParams) ([]models.Task, error) {
+			receivedColumnID = params.ColumnID
 			return []models.Task{}, nil
 		},
 	}
@@ -100,6 +267,31 @@ func TestTaskHandler_ListTasks_WithColumnFilter(t *testing.T) {
 	}
 }
 
+func TestTaskHandler_ListTasks_StatusFilter(t *testing.T) {
+	var received []string
+	svc := &mocks.MockTaskService{
+		LastModifiedFn: neverModified,
+		ListFn: func(ctx context.Context, params models.TaskListParams) ([]models.Task, error) {
+			received = params.Statuses
+			return []models.Task{}, nil
+		},
+	}
+	handler := NewTaskHandler(svc)
+
+	req := httptest.NewRequest(http.MethodGet, "/tasks?status=todo,blocked", nil)
+	if err := handler.ListTasks(httptest.NewRecorder(), req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(received) != 2 || received[0] != "todo" || received[1] != "blocked" {
+		t.Errorf("expected statuses [todo blocked], got %v", received)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/tasks?status=todo,archived", nil)
+	if err := handler.ListTasks(httptest.NewRecorder(), req); err == nil {
+		t.Error("expected error for an unknown status")
+	}
+}
+
 func TestTaskHandler_ListTasks_InvalidColumnId(t *testing.T) {
 	svc := &mocks.MockTaskService{}
 	handler := NewTaskHandler(svc)
@@ -113,6 +305,81 @@ func TestTaskHandler_ListTasks_InvalidColumnId(t *testing.T) {
 	}
 }
 
+func TestTaskHandler_ListTasks_SortAndUpdatedSince(t *testing.T) {
+	var received models.TaskListParams
+	svc := &mocks.MockTaskService{
+		LastModifiedFn: neverModified,
+		ListFn: func(ctx context.Context, params models.TaskListParams) ([]models.Task, error) {
+			received = params
+			return []models.Task{}, nil
+		},
+	}
+
+	handler := NewTaskHandler(svc)
+	req := httptest.NewRequest(http.MethodGet, "/tasks?sortBy=updatedAt&sortOrder=desc&updatedSince=2024-05-01T12:00:00Z", nil)
+	w := httptest.NewRecorder()
+
+	if err := handler.ListTasks(w, req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if received.SortBy != "updatedAt" || received.SortOrder != "desc" {
+		t.Errorf("unexpected sort %q %q", received.SortBy, received.SortOrder)
+	}
+	want := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	if received.UpdatedSince == nil || !received.UpdatedSince.Equal(want) {
+		t.Errorf("expected updatedSince %v, got %v", want, received.UpdatedSince)
+	}
+}
+
+func TestTaskHandler_ListTasks_InvalidUpdatedSince(t *testing.T) {
+	handler := NewTaskHandler(&mocks.MockTaskService{})
+
+	req := httptest.NewRequest(http.MethodGet, "/tasks?updatedSince=yesterday", nil)
+	err := handler.ListTasks(httptest.NewRecorder(), req)
+	if !errors.Is(err, errors.ErrInvalidFormat) {
+		t.Errorf("expected invalid format error, got %v", err)
+	}
+}
+
+func TestTaskHandler_ListTaskChanges(t *testing.T) {
+	cursor := models.TaskCursor{UpdatedAt: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC), ID: 7}
+	var received *models.TaskCursor
+	svc := &mocks.MockTaskService{
+		ChangesFn: func(ctx context.Context, after *models.TaskCursor, limit int) ([]models.Task, models.CursorPage, error) {
+			received = after
+			return []models.Task{{ID: 8}}, models.CursorPage{NextCursor: "next", Limit: limit}, nil
+		},
+	}
+
+	handler := NewTaskHandler(svc)
+	req := httptest.NewRequest(http.MethodGet, "/tasks/changes?since="+cursor.Encode()+"&limit=50", nil)
+	w := httptest.NewRecorder()
+
+	if err := handler.ListTaskChanges(w, req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if received == nil || received.ID != 7 {
+		t.Errorf("expected decoded cursor for task 7, got %+v", received)
+	}
+
+	var tasks []models.Task
+	envelope := decodeData(t, w, &tasks)
+	page, _ := envelope.Meta["pagination"].(map[string]interface{})
+	if len(tasks) != 1 || page["nextCursor"] != "next" {
+		t.Errorf("unexpected response: tasks=%v meta=%v", tasks, envelope.Meta)
+	}
+}
+
+func TestTaskHandler_ListTaskChanges_InvalidCursor(t *testing.T) {
+	handler := NewTaskHandler(&mocks.MockTaskService{})
+
+	req := httptest.NewRequest(http.MethodGet, "/tasks/changes?since=***", nil)
+	err := handler.ListTaskChanges(httptest.NewRecorder(), req)
+	if !errors.Is(err, errors.ErrInvalidFormat) {
+		t.Errorf("expected invalid format error, got %v", err)
+	}
+}
+
 func TestTaskHandler_CreateTask(t *testing.T) {
 	svc := &mocks.MockTaskService{
 		CreateFn: func(ctx context.Context, userID int, req models.CreateTaskRequest) (models.Task, error) {
@@ -138,7 +405,7 @@ func TestTaskHandler_CreateTask(t *testing.T) {
 	}
 
 	var task models.Task
-	json.NewDecoder(w.Body).Decode(&task)
+	decodeData(t, w, &task)
 	if task.Title != "New Task" {
 		t.Errorf("expected title 'New Task', got '%s'", task.Title)
 	}
@@ -173,6 +440,49 @@ func TestTaskHandler_CreateTask_InvalidJSON(t *testing.T) {
 	}
 }
 
+func TestTaskHandler_CompleteTask(t *testing.T) {
+	svc := &mocks.MockTaskService{
+		CompleteFn: func(ctx context.Context, userID int, id int) (models.Task, error) {
+			if userID != 42 {
+				t.Errorf("expected userID 42, got %d", userID)
+			}
+			return models.Task{ID: id, Completed: true}, nil
+		},
+	}
+
+	handler := NewTaskHandler(svc)
+	req := httptest.NewRequest(http.MethodPost, "/tasks/5/complete", nil)
+	req.SetPathValue("id", "5")
+	req = withUserContext(req, 42)
+	w := httptest.NewRecorder()
+
+	if err := handler.CompleteTask(w, req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", w.Code)
+	}
+
+	var task models.Task
+	decodeData(t, w, &task)
+	if task.ID != 5 || !task.Completed {
+		t.Errorf("expected task 5 completed, got %+v", task)
+	}
+}
+
+func TestTaskHandler_ReopenTask_InvalidID(t *testing.T) {
+	svc := &mocks.MockTaskService{}
+	handler := NewTaskHandler(svc)
+
+	req := httptest.NewRequest(http.MethodPost, "/tasks/abc/reopen", nil)
+	req.SetPathValue("id", "abc")
+	w := httptest.NewRecorder()
+
+	if err := handler.ReopenTask(w, req); err == nil {
+		t.Fatal("expected error for invalid ID")
+	}
+}
+
 func TestTaskHandler_DeleteTask(t *testing.T) {
 	deletedID := 0
 	svc := &mocks.MockTaskService{
@@ -213,6 +523,68 @@ func TestTaskHandler_DeleteTask_InvalidID(t *testing.T) {
 	}
 }
 
+func TestTaskHandler_ListTasks_Include(t *testing.T) {
+	var included []string
+	svc := &mocks.MockTaskService{
+		LastModifiedFn: func(ctx context.Context) (models.TaskListModified, error) {
+			return models.TaskListModified{}, nil
+		},
+		ListFn: func(ctx context.Context, params models.TaskListParams) ([]models.Task, error) {
+			return []models.Task{{ID: 1, ColumnID: 3}}, nil
+		},
+		IncludeFn: func(ctx context.Context, tasks []models.Task, include []string) error {
+			included = include
+			tasks[0].Column = &models.Column{ID: 3, Title: "Doing"}
+			return nil
+		},
+	}
+	handler := NewTaskHandler(svc)
+
+	req := httptest.NewRequest(http.MethodGet, "/tasks?include=column,checklistItems", nil)
+	w := httptest.NewRecorder()
+	if err := handler.ListTasks(w, req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(included) != 2 || included[0] != "column" || included[1] != "checklistItems" {
+		t.Errorf("expected column and checklistItems included, got %v", included)
+	}
+	var tasks []models.Task
+	decodeData(t, w, &tasks)
+	if len(tasks) != 1 || tasks[0].Column == nil || tasks[0].Column.Title != "Doing" {
+		t.Errorf("expected the column embedded, got %+v", tasks)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/tasks?include=comments", nil)
+	err := handler.ListTasks(httptest.NewRecorder(), req)
+	if appErr, ok := errors.IsAppError(err); !ok || appErr.Code != errors.ErrInvalidFormat {
+		t.Errorf("expected INVALID_FORMAT for an unknown include, got %v", err)
+	}
+}
+
+func TestTaskHandler_GetTask_Fields(t *testing.T) {
+	svc := &mocks.MockTaskService{
+		GetByIDFn: func(ctx context.Context, id int) (models.Task, error) {
+			return models.Task{ID: id, Title: "Task", Description: "Long text", Status: models.TaskStatusDone, Completed: true}, nil
+		},
+	}
+
+	handler := NewTaskHandler(svc)
+	req := httptest.NewRequest(http.MethodGet, "/tasks/7?fields=id,title,completed", nil)
+	req.SetPathValue("id", "7")
+	w := httptest.NewRecorder()
+
+	if err := handler.GetTask(w, req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var task map[string]interface{}
+	decodeData(t, w, &task)
+	if len(task) != 3 || task["id"] != float64(7) || task["title"] != "Task" || task["completed"] != true {
+		t.Errorf("expected only id, title and completed, got %v", task)
+	}
+}
+
 func TestTaskHandler_GetTask_NotFound(t *testing.T) {
 	svc := &mocks.MockTaskService{
 		GetByIDFn: func(ctx context.Context, id int) (models.Task, error) {
@@ -269,3 +641,29 @@ func TestTaskHandler_ReorderTasks_MissingColumnID(t *testing.T) {
 		t.Fatal("expected error for missing columnId")
 	}
 }
+
+func TestTaskHandler_SetTaskStatus(t *testing.T) {
+	svc := &mocks.MockTaskService{
+		SetStatusFn: func(ctx context.Context, userID int, id int, req models.SetTaskStatusRequest) (models.Task, error) {
+			if userID != 1 || id != 5 {
+				t.Errorf("expected task 5 by user 1, got task %d by user %d", id, userID)
+			}
+			return models.Task{ID: id, Status: req.Status}, nil
+		},
+	}
+	handler := NewTaskHandler(svc)
+
+	req := httptest.NewRequest(http.MethodPatch, "/tasks/5/status", bytes.NewBufferString(`{"status":"in_progress"}`))
+	req.SetPathValue("id", "5")
+	req = withUserContext(req, 1)
+	w := httptest.NewRecorder()
+
+	if err := handler.SetTaskStatus(w, req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var task models.Task
+	decodeData(t, w, &task)
+	if task.Status != models.TaskStatusInProgress {
+		t.Errorf("expected status in_progress, got %s", task.Status)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/clementhaon/sandbox-api-go/errors"
 	"github.com/clementhaon/sandbox-api-go/middleware"
 	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/respond"
 	"github.com/clementhaon/sandbox-api-go/services"
 )
 
@@ -37,7 +38,7 @@ func (h *TimeEntryHandler) ListTimeEntries(w http.ResponseWriter, r *http.Reques
 		return err
 	}
 
-	json.NewEncoder(w).Encode(entries)
+	respond.OK(w, entries)
 	return nil
 }
 
@@ -61,8 +62,7 @@ func (h *TimeEntryHandler) CreateTimeEntry(w http.ResponseWriter, r *http.Reques
 		return err
 	}
 
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(entry)
+	respond.Created(w, entry)
 	return nil
 }
 
@@ -78,6 +78,6 @@ func (h *TimeEntryHandler) DeleteTimeEntry(w http.ResponseWriter, r *http.Reques
 		return err
 	}
 
-	w.WriteHeader(http.StatusNoContent)
+	respond.NoContent(w)
 	return nil
 }
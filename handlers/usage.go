@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/middleware"
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/respond"
+	"github.com/clementhaon/sandbox-api-go/services"
+)
+
+type UsageHandler struct {
+	usageService services.UsageService
+}
+
+func NewUsageHandler(s services.UsageService) *UsageHandler {
+	return &UsageHandler{usageService: s}
+}
+
+// GetUsage returns the API usage of the caller against their daily quotas.
+// ?days sets how many days of history are included, up to
+// services.MaxUsageDays.
+func (h *UsageHandler) GetUsage(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*models.Claims)
+	if !ok {
+		return errors.NewInternalError().WithDetails(map[string]interface{}{"issue": "user_context_missing"})
+	}
+
+	days := services.DefaultUsageDays
+	if v := r.URL.Query().Get("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > services.MaxUsageDays {
+			return errors.NewBadRequestError("days must be between 1 and " + strconv.Itoa(services.MaxUsageDays))
+		}
+		days = n
+	}
+
+	report, err := h.usageService.Get(r.Context(), claims.UserID, days)
+	if err != nil {
+		return err
+	}
+
+	respond.OK(w, report)
+	return nil
+}
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/mocks"
+	"github.com/clementhaon/sandbox-api-go/models"
+)
+
+func TestUsageHandler_GetUsage(t *testing.T) {
+	var receivedUser, receivedDays int
+	svc := &mocks.MockUsageService{
+		GetFn: func(ctx context.Context, userID, days int) (models.UsageReport, error) {
+			receivedUser, receivedDays = userID, days
+			return models.UsageReport{Today: models.UsageCounts{Requests: 4}, MaxDailyRequests: 100}, nil
+		},
+	}
+	handler := NewUsageHandler(svc)
+
+	w := httptest.NewRecorder()
+	if err := handler.GetUsage(w, withUserContext(httptest.NewRequest(http.MethodGet, "/usage?days=30", nil), 3)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if receivedUser != 3 || receivedDays != 30 {
+		t.Errorf("expected 30 days of user 3, got %d days of user %d", receivedDays, receivedUser)
+	}
+	var report models.UsageReport
+	decodeData(t, w, &report)
+	if report.Today.Requests != 4 || report.MaxDailyRequests != 100 {
+		t.Errorf("expected the report of the service, got %+v", report)
+	}
+
+	for _, days := range []string{"abc", "0", "91"} {
+		req := withUserContext(httptest.NewRequest(http.MethodGet, "/usage?days="+days, nil), 3)
+		if _, ok := errors.IsAppError(handler.GetUsage(httptest.NewRecorder(), req)); !ok {
+			t.Errorf("days=%s: expected AppError", days)
+		}
+	}
+}
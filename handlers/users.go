@@ -7,6 +7,7 @@ import (
 
 	"github.com/clementhaon/sandbox-api-go/errors"
 	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/respond"
 	"github.com/clementhaon/sandbox-api-go/services"
 )
 
@@ -18,13 +19,13 @@ func NewUserHandler(s services.UserService) *UserHandler {
 	return &UserHandler{userService: s}
 }
 
-func (h *UserHandler) ListUsers(w http.ResponseWriter, r *http.Request) error {
-	w.Header().Set("Content-Type", "application/json")
-
+// userListParams reads the page, filters and sort order of user lists from
+// the query string.
+func userListParams(r *http.Request) models.UserListParams {
 	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
 	pageSize, _ := strconv.Atoi(r.URL.Query().Get("pageSize"))
 
-	params := models.UserListParams{
+	return models.UserListParams{
 		Page:      page,
 		PageSize:  pageSize,
 		SortBy:    r.URL.Query().Get("sortBy"),
@@ -33,22 +34,43 @@ func (h *UserHandler) ListUsers(w http.ResponseWriter, r *http.Request) error {
 		Role:      r.URL.Query().Get("role"),
 		Status:    r.URL.Query().Get("status"),
 	}
+}
 
-	response, err := h.userService.List(r.Context(), params)
+func (h *UserHandler) ListUsers(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	response, err := h.userService.List(r.Context(), userListParams(r))
 	if err != nil {
 		return err
 	}
 
-	json.NewEncoder(w).Encode(response)
+	respond.Paginated(w, response.Data, response.Pagination)
+	return nil
+}
+
+// ExportUsers streams every user matching the filters of ListUsers, without
+// pages, in a JSON list or as NDJSON.
+func (h *UserHandler) ExportUsers(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	list := respond.NewListWriter(w, r)
+	err := h.userService.Each(r.Context(), userListParams(r), func(user models.UserResponse) error {
+		return list.Write(user)
+	})
+	if err != nil {
+		return list.Fail(err)
+	}
+	list.Close()
 	return nil
 }
 
 func (h *UserHandler) GetUser(w http.ResponseWriter, r *http.Request) error {
 	w.Header().Set("Content-Type", "application/json")
 
+	// Usernames start with a letter, so a non-numeric segment is a username
 	id, err := strconv.Atoi(r.PathValue("id"))
 	if err != nil {
-		return errors.NewBadRequestError("Invalid user ID")
+		return h.GetPublicProfile(w, r)
 	}
 
 	user, err := h.userService.GetByID(r.Context(), id)
@@ -56,7 +78,35 @@ func (h *UserHandler) GetUser(w http.ResponseWriter, r *http.Request) error {
 		return err
 	}
 
-	json.NewEncoder(w).Encode(user)
+	respond.OK(w, user)
+	return nil
+}
+
+// GetPublicProfile returns the public profile of the user named in the path.
+// It is served from GET /users/{id} when the segment is not numeric.
+func (h *UserHandler) GetPublicProfile(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	profile, err := h.userService.GetPublicProfile(r.Context(), r.PathValue("id"))
+	if err != nil {
+		return err
+	}
+
+	respond.OK(w, profile)
+	return nil
+}
+
+// SearchUsers returns public profiles whose username starts with ?q=.
+func (h *UserHandler) SearchUsers(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	profiles, err := h.userService.Search(r.Context(), r.URL.Query().Get("q"), limit)
+	if err != nil {
+		return err
+	}
+
+	respond.OK(w, profiles)
 	return nil
 }
 
@@ -73,8 +123,7 @@ func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) error {
 		return err
 	}
 
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(user)
+	respond.Created(w, user)
 	return nil
 }
 
@@ -96,7 +145,7 @@ func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) error {
 		return err
 	}
 
-	json.NewEncoder(w).Encode(user)
+	respond.OK(w, user)
 	return nil
 }
 
@@ -118,7 +167,7 @@ func (h *UserHandler) UpdateUserStatus(w http.ResponseWriter, r *http.Request) e
 		return err
 	}
 
-	json.NewEncoder(w).Encode(user)
+	respond.OK(w, user)
 	return nil
 }
 
@@ -134,6 +183,6 @@ func (h *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) error {
 		return err
 	}
 
-	w.WriteHeader(http.StatusNoContent)
+	respond.NoContent(w)
 	return nil
 }
@@ -67,14 +67,46 @@ func TestUserHandler_ListUsers(t *testing.T) {
 	}
 }
 
+func TestUserHandler_ExportUsers(t *testing.T) {
+	var got models.UserListParams
+	svc := &mocks.MockUserService{
+		EachFn: func(ctx context.Context, params models.UserListParams, fn func(models.UserResponse) error) error {
+			got = params
+			for _, name := range []string{"alice", "bob"} {
+				if err := fn(models.UserResponse{Username: name}); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+	handler := NewUserHandler(svc)
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/users/export?role=admin&sortBy=email", nil)
+	w := httptest.NewRecorder()
+	if err := handler.ExportUsers(w, req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got.Role != "admin" || got.SortBy != "email" {
+		t.Errorf("expected the list filters, got %+v", got)
+	}
+	var users []models.UserResponse
+	decodeData(t, w, &users)
+	if len(users) != 2 || users[1].Username != "bob" {
+		t.Errorf("unexpected users %+v", users)
+	}
+}
+
 func TestUserHandler_GetUser(t *testing.T) {
 	tests := []struct {
-		name       string
-		pathID     string
-		getByIDFn  func(ctx context.Context, id int) (models.UserResponse, error)
-		wantStatus int
-		wantErr    bool
-		wantCode   errors.ErrorCode
+		name               string
+		pathID             string
+		getByIDFn          func(ctx context.Context, id int) (models.UserResponse, error)
+		getPublicProfileFn func(ctx context.Context, username string) (models.PublicProfile, error)
+		wantStatus         int
+		wantErr            bool
+		wantCode           errors.ErrorCode
 	}{
 		{
 			name:   "success",
@@ -85,9 +117,15 @@ func TestUserHandler_GetUser(t *testing.T) {
 			wantStatus: http.StatusOK,
 		},
 		{
-			name:    "invalid id",
-			pathID:  "abc",
-			wantErr: true,
+			name:   "username falls back to public profile",
+			pathID: "alice",
+			getPublicProfileFn: func(ctx context.Context, username string) (models.PublicProfile, error) {
+				if username != "alice" {
+					t.Errorf("expected username alice, got %s", username)
+				}
+				return models.PublicProfile{ID: 1, Username: "alice"}, nil
+			},
+			wantStatus: http.StatusOK,
 		},
 		{
 			name:   "not found",
@@ -102,7 +140,7 @@ func TestUserHandler_GetUser(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			svc := &mocks.MockUserService{GetByIDFn: tt.getByIDFn}
+			svc := &mocks.MockUserService{GetByIDFn: tt.getByIDFn, GetPublicProfileFn: tt.getPublicProfileFn}
 			handler := NewUserHandler(svc)
 
 			req := httptest.NewRequest(http.MethodGet, "/users/"+tt.pathID, nil)
@@ -135,6 +173,58 @@ func TestUserHandler_GetUser(t *testing.T) {
 	}
 }
 
+func TestUserHandler_GetPublicProfile_OmitsEmail(t *testing.T) {
+	svc := &mocks.MockUserService{
+		GetPublicProfileFn: func(ctx context.Context, username string) (models.PublicProfile, error) {
+			return models.PublicProfile{ID: 1, Username: username, FirstName: "Alice"}, nil
+		},
+	}
+	handler := NewUserHandler(svc)
+
+	req := httptest.NewRequest(http.MethodGet, "/users/alice", nil)
+	req.SetPathValue("id", "alice")
+	w := httptest.NewRecorder()
+
+	if err := handler.GetPublicProfile(w, req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var data map[string]interface{}
+	decodeData(t, w, &data)
+	if _, ok := data["email"]; ok {
+		t.Error("public profile must not include email")
+	}
+	if data["username"] != "alice" {
+		t.Errorf("expected username alice, got %v", data["username"])
+	}
+}
+
+func TestUserHandler_SearchUsers(t *testing.T) {
+	var gotQuery string
+	var gotLimit int
+	svc := &mocks.MockUserService{
+		SearchFn: func(ctx context.Context, query string, limit int) ([]models.PublicProfile, error) {
+			gotQuery, gotLimit = query, limit
+			return []models.PublicProfile{{ID: 1, Username: "alice"}, {ID: 2, Username: "alicia"}}, nil
+		},
+	}
+	handler := NewUserHandler(svc)
+
+	req := httptest.NewRequest(http.MethodGet, "/users/search?q=ali&limit=5", nil)
+	w := httptest.NewRecorder()
+
+	if err := handler.SearchUsers(w, req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gotQuery != "ali" || gotLimit != 5 {
+		t.Errorf("expected q=ali limit=5, got q=%s limit=%d", gotQuery, gotLimit)
+	}
+	var profiles []models.PublicProfile
+	decodeData(t, w, &profiles)
+	if len(profiles) != 2 {
+		t.Errorf("expected 2 profiles, got %d", len(profiles))
+	}
+}
+
 func TestUserHandler_CreateUser(t *testing.T) {
 	tests := []struct {
 		name       string
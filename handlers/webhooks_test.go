@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/clementhaon/sandbox-api-go/mocks"
+	"github.com/clementhaon/sandbox-api-go/models"
+)
+
+func TestWebhookHandler_TestWebhook(t *testing.T) {
+	var gotUserID, gotID int
+	svc := &mocks.MockWebhookService{
+		TestFn: func(ctx context.Context, userID, id int) (models.WebhookTestResult, error) {
+			gotUserID, gotID = userID, id
+			return models.WebhookTestResult{Status: http.StatusNoContent, LatencyMS: 42}, nil
+		},
+	}
+	handler := NewWebhookHandler(svc)
+
+	w := httptest.NewRecorder()
+	req := withUserContext(httptest.NewRequest(http.MethodPost, "/webhooks/8/test", nil), 3)
+	req.SetPathValue("id", "8")
+	if err := handler.TestWebhook(w, req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gotUserID != 3 || gotID != 8 {
+		t.Errorf("expected webhook 8 of user 3, got %d of %d", gotID, gotUserID)
+	}
+
+	var resp struct {
+		Data models.WebhookTestResult `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if w.Code != http.StatusOK || resp.Data.Status != http.StatusNoContent || resp.Data.LatencyMS != 42 {
+		t.Errorf("unexpected response %d %s", w.Code, w.Body.String())
+	}
+
+	req = withUserContext(httptest.NewRequest(http.MethodPost, "/webhooks/x/test", nil), 3)
+	req.SetPathValue("id", "x")
+	if err := handler.TestWebhook(httptest.NewRecorder(), req); err == nil {
+		t.Error("expected an error for a non-numeric ID")
+	}
+}
@@ -8,6 +8,8 @@ import (
 
 	"github.com/clementhaon/sandbox-api-go/auth"
 	"github.com/clementhaon/sandbox-api-go/logger"
+	"github.com/clementhaon/sandbox-api-go/metrics"
+	"github.com/clementhaon/sandbox-api-go/models"
 	"github.com/clementhaon/sandbox-api-go/websocket"
 
 	"github.com/google/uuid"
@@ -52,8 +54,10 @@ func NewWebSocketHandler(wsManager *websocket.Manager, jwtManager *auth.JWTManag
 }
 
 func (h *WebSocketHandler) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
+	m := metrics.FromContext(r.Context())
 	token := r.URL.Query().Get("token")
 	if token == "" {
+		m.RecordAuthFailure("token", auth.ReasonMissingToken)
 		http.Error(w, "Missing token", http.StatusUnauthorized)
 		return
 	}
@@ -63,9 +67,19 @@ func (h *WebSocketHandler) HandleWebSocket(w http.ResponseWriter, r *http.Reques
 		logger.Warn("WebSocket: Invalid token", map[string]interface{}{
 			"error": err.Error(),
 		})
+		reason := auth.FailureReason(err)
+		m.RecordTokenValidation(reason)
+		m.RecordAuthFailure("token", reason)
 		http.Error(w, "Invalid token", http.StatusUnauthorized)
 		return
 	}
+	m.RecordTokenValidation("valid")
+
+	// The socket only carries notifications
+	if !claims.HasScope(models.ScopeNotificationsRead) {
+		http.Error(w, "Insufficient scope", http.StatusForbidden)
+		return
+	}
 
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
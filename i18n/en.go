@@ -0,0 +1,85 @@
+package i18n
+
+var english = map[string]string{
+	// Errors, keyed by error code
+	"error.AUTH_REQUIRED":       "Authentication required",
+	"error.UNAUTHORIZED":        "Unauthorized access",
+	"error.INVALID_TOKEN":       "Invalid or malformed token",
+	"error.TOKEN_EXPIRED":       "Token has expired",
+	"error.INVALID_CREDENTIALS": "Invalid username or password",
+	"error.USER_EXISTS":         "User already exists",
+	"error.VALIDATION_FAILED":   "Input validation failed",
+	"error.BAD_REQUEST":         "Bad request",
+	"error.INVALID_JSON":        "Invalid JSON format",
+	"error.MISSING_FIELD":       "Missing required field: %s",
+	"error.INVALID_FORMAT":      "Invalid format for field '%s', expected: %s",
+	"error.NOT_FOUND":           "%s not found",
+	"error.FORBIDDEN":           "Access forbidden",
+	"error.INSUFFICIENT_SCOPE":  "The token's scopes do not allow this action",
+	"error.INTERNAL_ERROR":      "Internal server error",
+	"error.DATABASE_ERROR":      "Database operation failed",
+	"error.SERVICE_UNAVAILABLE": "Service temporarily unavailable",
+	"error.TOO_MANY_REQUESTS":   "Too many requests, please try again later",
+	"error.METHOD_NOT_ALLOWED":  "Method not allowed",
+	"error.PAYLOAD_TOO_LARGE":   "Request body too large",
+	"error.QUOTA_EXCEEDED":      "Quota exceeded for %s (limit %d)",
+	"error.SIGNUP_RATE_LIMITED": "Too many accounts created from this address, please try again later",
+	"error.DISPOSABLE_EMAIL":    "Disposable email addresses are not accepted",
+	"error.CAPTCHA_REQUIRED":    "CAPTCHA verification is required",
+	"error.CAPTCHA_FAILED":      "CAPTCHA verification failed",
+	"error.PASSWORD_BREACHED":   "This password appeared in a data breach, please choose another one",
+	"error.SIMULATED_ERROR":     "Simulated error",
+
+	// Validation rules
+	"validation.required":        "This field is required",
+	"validation.string":          "Value must be a string",
+	"validation.number":          "Value must be a number",
+	"validation.min_length":      "Must be at least %d characters long",
+	"validation.max_length":      "Must be no more than %d characters long",
+	"validation.email":           "Must be a valid email address",
+	"validation.username_length": "Username must be between 3 and 30 characters",
+	"validation.username_start":  "Username must start with a letter",
+	"validation.username_chars":  "Username can only contain letters, numbers, and underscores",
+	"validation.password_min":    "Password must be at least %d characters long",
+	"validation.password_max":    "Password must be no more than 128 characters long",
+	"validation.password_upper":  "Password must contain at least one uppercase letter",
+	"validation.password_lower":  "Password must contain at least one lowercase letter",
+	"validation.password_number": "Password must contain at least one number",
+	"validation.password_symbol": "Password must contain at least one symbol",
+	"validation.password_common": "Password is too common",
+	"validation.not_empty":       "This field cannot be empty",
+	"validation.range":           "Value must be between %d and %d",
+	"validation.min":             "Value must be at least %d",
+	"validation.max":             "Value must be at most %d",
+	"validation.one_of":          "Value must be one of: %v",
+	"validation.url":             "Must be a valid http or https URL",
+	"validation.url_host":        "Host %s is not allowed",
+	"validation.uuid":            "Must be a valid UUID",
+	"validation.date_format":     "Must be a date in the format %s",
+	"validation.timezone":        "Must be a valid time zone",
+	"validation.time":            "Value must be a time",
+	"validation.before":          "Must be before %s",
+	"validation.after":           "Must be after %s",
+	"validation.field_equals":    "Must match %s",
+
+	// Password strength feedback
+	"password_strength.common":         "This is a commonly used password",
+	"password_strength.personal":       "Passwords containing your username or email address are easy to guess",
+	"password_strength.repeats":        "Repeated characters like \"aaa\" are easy to guess",
+	"password_strength.sequences":      "Sequences like \"abc\" or \"qwerty\" are easy to guess",
+	"password_strength.uncommon":       "Pick a password nobody else would",
+	"password_strength.longer":         "Use a longer password, such as a few unrelated words",
+	"password_strength.mix":            "Mix uppercase and lowercase letters, numbers and symbols",
+	"password_strength.avoid_patterns": "Avoid repetitions, sequences and personal information",
+
+	// Success messages
+	"auth.registered":                 "Registration successful",
+	"auth.logged_in":                  "Login successful",
+	"auth.logged_out":                 "Logout successful",
+	"auth.session_revoked":            "The session was signed out",
+	"profile.updated":                 "Profile updated successfully",
+	"profile.email_verification_sent": "Verification email sent to the new address",
+	"profile.email_changed":           "Email updated successfully",
+	"profile.username_changed":        "Username updated successfully",
+	"media.deleted":                   "Media %d deleted successfully",
+}
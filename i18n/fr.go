@@ -0,0 +1,85 @@
+package i18n
+
+var french = map[string]string{
+	// Errors, keyed by error code
+	"error.AUTH_REQUIRED":       "Authentification requise",
+	"error.UNAUTHORIZED":        "Accès non autorisé",
+	"error.INVALID_TOKEN":       "Jeton invalide ou mal formé",
+	"error.TOKEN_EXPIRED":       "Le jeton a expiré",
+	"error.INVALID_CREDENTIALS": "Nom d'utilisateur ou mot de passe incorrect",
+	"error.USER_EXISTS":         "L'utilisateur existe déjà",
+	"error.VALIDATION_FAILED":   "La validation des données a échoué",
+	"error.BAD_REQUEST":         "Requête invalide",
+	"error.INVALID_JSON":        "Format JSON invalide",
+	"error.MISSING_FIELD":       "Champ obligatoire manquant : %s",
+	"error.INVALID_FORMAT":      "Format invalide pour le champ '%s', attendu : %s",
+	"error.NOT_FOUND":           "Ressource introuvable : %s",
+	"error.FORBIDDEN":           "Accès interdit",
+	"error.INSUFFICIENT_SCOPE":  "Les scopes du jeton ne permettent pas cette action",
+	"error.INTERNAL_ERROR":      "Erreur interne du serveur",
+	"error.DATABASE_ERROR":      "L'opération en base de données a échoué",
+	"error.SERVICE_UNAVAILABLE": "Service temporairement indisponible",
+	"error.TOO_MANY_REQUESTS":   "Trop de requêtes, veuillez réessayer plus tard",
+	"error.METHOD_NOT_ALLOWED":  "Méthode non autorisée",
+	"error.PAYLOAD_TOO_LARGE":   "Corps de la requête trop volumineux",
+	"error.QUOTA_EXCEEDED":      "Quota dépassé pour %s (limite %d)",
+	"error.SIGNUP_RATE_LIMITED": "Trop de comptes créés depuis cette adresse, veuillez réessayer plus tard",
+	"error.DISPOSABLE_EMAIL":    "Les adresses e-mail jetables ne sont pas acceptées",
+	"error.CAPTCHA_REQUIRED":    "La vérification CAPTCHA est requise",
+	"error.CAPTCHA_FAILED":      "La vérification CAPTCHA a échoué",
+	"error.PASSWORD_BREACHED":   "Ce mot de passe est apparu dans une fuite de données, veuillez en choisir un autre",
+	"error.SIMULATED_ERROR":     "Erreur simulée",
+
+	// Validation rules
+	"validation.required":        "Ce champ est obligatoire",
+	"validation.string":          "La valeur doit être une chaîne de caractères",
+	"validation.number":          "La valeur doit être un nombre",
+	"validation.min_length":      "Doit contenir au moins %d caractères",
+	"validation.max_length":      "Ne doit pas dépasser %d caractères",
+	"validation.email":           "Doit être une adresse e-mail valide",
+	"validation.username_length": "Le nom d'utilisateur doit contenir entre 3 et 30 caractères",
+	"validation.username_start":  "Le nom d'utilisateur doit commencer par une lettre",
+	"validation.username_chars":  "Le nom d'utilisateur ne peut contenir que des lettres, des chiffres et des underscores",
+	"validation.password_min":    "Le mot de passe doit contenir au moins %d caractères",
+	"validation.password_max":    "Le mot de passe ne doit pas dépasser 128 caractères",
+	"validation.password_upper":  "Le mot de passe doit contenir au moins une majuscule",
+	"validation.password_lower":  "Le mot de passe doit contenir au moins une minuscule",
+	"validation.password_number": "Le mot de passe doit contenir au moins un chiffre",
+	"validation.password_symbol": "Le mot de passe doit contenir au moins un symbole",
+	"validation.password_common": "Ce mot de passe est trop courant",
+	"validation.not_empty":       "Ce champ ne peut pas être vide",
+	"validation.range":           "La valeur doit être comprise entre %d et %d",
+	"validation.min":             "La valeur doit être supérieure ou égale à %d",
+	"validation.max":             "La valeur doit être inférieure ou égale à %d",
+	"validation.one_of":          "La valeur doit être l'une des suivantes : %v",
+	"validation.url":             "Doit être une URL http ou https valide",
+	"validation.url_host":        "L'hôte %s n'est pas autorisé",
+	"validation.uuid":            "Doit être un UUID valide",
+	"validation.date_format":     "Doit être une date au format %s",
+	"validation.timezone":        "Doit être un fuseau horaire valide",
+	"validation.time":            "La valeur doit être une date",
+	"validation.before":          "Doit être antérieur à %s",
+	"validation.after":           "Doit être postérieur à %s",
+	"validation.field_equals":    "Doit correspondre à %s",
+
+	// Password strength feedback
+	"password_strength.common":         "Ce mot de passe est très courant",
+	"password_strength.personal":       "Un mot de passe contenant votre nom d'utilisateur ou votre adresse email est facile à deviner",
+	"password_strength.repeats":        "Les caractères répétés comme « aaa » sont faciles à deviner",
+	"password_strength.sequences":      "Les suites comme « abc » ou « azerty » sont faciles à deviner",
+	"password_strength.uncommon":       "Choisissez un mot de passe que personne d'autre n'utiliserait",
+	"password_strength.longer":         "Utilisez un mot de passe plus long, par exemple quelques mots sans rapport",
+	"password_strength.mix":            "Mélangez majuscules, minuscules, chiffres et symboles",
+	"password_strength.avoid_patterns": "Évitez les répétitions, les suites et les informations personnelles",
+
+	// Success messages
+	"auth.registered":                 "Inscription réussie",
+	"auth.logged_in":                  "Connexion réussie",
+	"auth.logged_out":                 "Déconnexion réussie",
+	"auth.session_revoked":            "La session a été déconnectée",
+	"profile.updated":                 "Profil mis à jour",
+	"profile.email_verification_sent": "E-mail de vérification envoyé à la nouvelle adresse",
+	"profile.email_changed":           "Adresse e-mail mise à jour",
+	"profile.username_changed":        "Nom d'utilisateur mis à jour",
+	"media.deleted":                   "Média %d supprimé",
+}
@@ -0,0 +1,107 @@
+// Package i18n holds the message catalogs used to localize API messages and
+// negotiates the response language from the Accept-Language header.
+package i18n
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultLanguage is used when the client accepts none of the supported languages.
+const DefaultLanguage = "en"
+
+// catalogs maps a language to its messages. Every key must exist in English,
+// which is the fallback for missing translations.
+var catalogs = map[string]map[string]string{
+	"en": english,
+	"fr": french,
+}
+
+type contextKey string
+
+const languageKey contextKey = "language"
+
+// Supported returns the supported languages, sorted.
+func Supported() []string {
+	langs := make([]string, 0, len(catalogs))
+	for lang := range catalogs {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// T returns the message for key in lang formatted with args. It falls back to
+// English, then to the key itself.
+func T(lang, key string, args ...interface{}) string {
+	if msg, ok := Lookup(lang, key, args...); ok {
+		return msg
+	}
+	return key
+}
+
+// Lookup is like T but reports whether the key exists.
+func Lookup(lang, key string, args ...interface{}) (string, bool) {
+	format, ok := catalogs[lang][key]
+	if !ok {
+		if format, ok = catalogs[DefaultLanguage][key]; !ok {
+			return "", false
+		}
+	}
+	if len(args) == 0 {
+		return format, true
+	}
+	return fmt.Sprintf(format, args...), true
+}
+
+// Negotiate picks the supported language with the highest quality in an
+// Accept-Language header, matching on the primary subtag ("fr-CA" → "fr").
+func Negotiate(header string) string {
+	best, bestQ := DefaultLanguage, 0.0
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		lang, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+		if _, ok := catalogs[lang]; !ok {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q > bestQ {
+			best, bestQ = lang, q
+		}
+	}
+	return best
+}
+
+// WithLanguage stores the response language in ctx.
+func WithLanguage(ctx context.Context, lang string) context.Context {
+	return context.WithValue(ctx, languageKey, lang)
+}
+
+// FromContext returns the language stored in ctx, or DefaultLanguage.
+func FromContext(ctx context.Context) string {
+	if lang, ok := ctx.Value(languageKey).(string); ok {
+		return lang
+	}
+	return DefaultLanguage
+}
+
+// FromRequest returns the language stored in the request context, or
+// negotiates it from the Accept-Language header.
+func FromRequest(r *http.Request) string {
+	if lang, ok := r.Context().Value(languageKey).(string); ok {
+		return lang
+	}
+	return Negotiate(r.Header.Get("Accept-Language"))
+}
@@ -0,0 +1,67 @@
+package i18n
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCatalogs_HaveSameKeys(t *testing.T) {
+	for lang, messages := range catalogs {
+		for key := range messages {
+			if _, ok := english[key]; !ok {
+				t.Errorf("%s: key %q is missing from the English catalog", lang, key)
+			}
+		}
+		for key := range english {
+			if _, ok := messages[key]; !ok {
+				t.Errorf("%s: missing translation for %q", lang, key)
+			}
+		}
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", "en"},
+		{"fr", "fr"},
+		{"fr-CA,fr;q=0.9,en;q=0.8", "fr"},
+		{"de-DE,de;q=0.9", "en"},
+		{"en;q=0.5, fr;q=0.8", "fr"},
+		{"de, fr;q=0.3", "fr"},
+		{"fr;q=abc, en", "en"},
+	}
+	for _, tt := range tests {
+		if got := Negotiate(tt.header); got != tt.want {
+			t.Errorf("Negotiate(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestT(t *testing.T) {
+	if got := T("fr", "media.deleted", 7); got != "Média 7 supprimé" {
+		t.Errorf("unexpected French message %q", got)
+	}
+	if got := T("de", "auth.logged_in"); got != "Login successful" {
+		t.Errorf("expected English fallback, got %q", got)
+	}
+	if got := T("fr", "unknown.key"); got != "unknown.key" {
+		t.Errorf("expected key fallback, got %q", got)
+	}
+}
+
+func TestFromRequest(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept-Language", "fr")
+	if got := FromRequest(req); got != "fr" {
+		t.Errorf("expected negotiated fr, got %q", got)
+	}
+
+	req = req.WithContext(WithLanguage(context.Background(), "en"))
+	if got := FromRequest(req); got != "en" {
+		t.Errorf("expected language from context, got %q", got)
+	}
+}
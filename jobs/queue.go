@@ -0,0 +1,213 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/logger"
+	"github.com/google/uuid"
+)
+
+// Status represents the lifecycle state of a job.
+type Status string
+
+const (
+	StatusPending   Status = "pending"
+	StatusRunning   Status = "running"
+	StatusCompleted Status = "completed"
+	StatusFailed    Status = "failed"
+)
+
+// DefaultRetention is how long finished jobs are kept before cleanup.
+const DefaultRetention = time.Hour
+
+// Job is a unit of background work and its result.
+type Job struct {
+	ID          string
+	Type        string
+	UserID      int
+	Status      Status
+	Progress    int
+	Error       string
+	Result      []byte
+	ContentType string
+	CreatedAt   time.Time
+	CompletedAt *time.Time
+}
+
+// Output is what a job function returns on success.
+type Output struct {
+	Data        []byte
+	ContentType string
+}
+
+// Func performs the work of a job. report may be called to update progress (0-100).
+type Func func(ctx context.Context, report func(progress int)) (Output, error)
+
+type task struct {
+	ctx context.Context
+	id  string
+	fn  Func
+}
+
+// Queue runs jobs on a fixed pool of background workers and keeps their results in memory.
+type Queue struct {
+	mu        sync.RWMutex
+	jobs      map[string]*Job
+	work      chan task
+	retention time.Duration
+	wg        sync.WaitGroup
+	stopCh    chan struct{}
+	stopOnce  sync.Once
+}
+
+// NewQueue creates a queue with the given number of workers and starts them.
+func NewQueue(workers int) *Queue {
+	if workers <= 0 {
+		workers = 1
+	}
+	q := &Queue{
+		jobs:      make(map[string]*Job),
+		work:      make(chan task, 100),
+		retention: DefaultRetention,
+		stopCh:    make(chan struct{}),
+	}
+	for i := 0; i < workers; i++ {
+		q.wg.Add(1)
+		go q.worker()
+	}
+	go q.cleanup()
+	return q
+}
+
+// Enqueue schedules fn and returns a snapshot of the pending job.
+// The job runs detached from ctx cancellation but keeps its values (request ID, user ID).
+func (q *Queue) Enqueue(ctx context.Context, jobType string, userID int, fn Func) (Job, error) {
+	job := &Job{
+		ID:        uuid.New().String(),
+		Type:      jobType,
+		UserID:    userID,
+		Status:    StatusPending,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	q.mu.Lock()
+	q.jobs[job.ID] = job
+	snapshot := *job
+	q.mu.Unlock()
+
+	select {
+	case q.work <- task{ctx: context.WithoutCancel(ctx), id: job.ID, fn: fn}:
+		return snapshot, nil
+	default:
+		q.mu.Lock()
+		delete(q.jobs, job.ID)
+		q.mu.Unlock()
+		return Job{}, fmt.Errorf("job queue is full")
+	}
+}
+
+// Get returns a snapshot of the job with the given ID.
+func (q *Queue) Get(id string) (Job, bool) {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	job, ok := q.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return *job, true
+}
+
+// Stop stops accepting work, waits for running jobs and terminates the workers.
+func (q *Queue) Stop() {
+	q.stopOnce.Do(func() {
+		close(q.stopCh)
+		q.wg.Wait()
+	})
+}
+
+func (q *Queue) worker() {
+	defer q.wg.Done()
+	for {
+		select {
+		case t := <-q.work:
+			q.run(t)
+		case <-q.stopCh:
+			return
+		}
+	}
+}
+
+func (q *Queue) run(t task) {
+	q.update(t.id, func(j *Job) { j.Status = StatusRunning })
+
+	report := func(progress int) {
+		if progress < 0 {
+			progress = 0
+		}
+		if progress > 100 {
+			progress = 100
+		}
+		q.update(t.id, func(j *Job) { j.Progress = progress })
+	}
+
+	out, err := q.safeRun(t, report)
+	now := time.Now().UTC()
+
+	q.update(t.id, func(j *Job) {
+		j.CompletedAt = &now
+		if err != nil {
+			j.Status = StatusFailed
+			j.Error = err.Error()
+			return
+		}
+		j.Status = StatusCompleted
+		j.Progress = 100
+		j.Result = out.Data
+		j.ContentType = out.ContentType
+	})
+
+	if err != nil {
+		logger.ErrorContext(t.ctx, "Background job failed", err, map[string]interface{}{
+			"job_id": t.id,
+		})
+	}
+}
+
+func (q *Queue) safeRun(t task, report func(int)) (out Output, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("job panicked: %v", recovered)
+		}
+	}()
+	return t.fn(t.ctx, report)
+}
+
+func (q *Queue) update(id string, fn func(j *Job)) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if job, ok := q.jobs[id]; ok {
+		fn(job)
+	}
+}
+
+func (q *Queue) cleanup() {
+	ticker := time.NewTicker(5 * time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			q.mu.Lock()
+			for id, job := range q.jobs {
+				if job.CompletedAt != nil && time.Since(*job.CompletedAt) > q.retention {
+					delete(q.jobs, id)
+				}
+			}
+			q.mu.Unlock()
+		case <-q.stopCh:
+			return
+		}
+	}
+}
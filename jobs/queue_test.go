@@ -0,0 +1,103 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func waitForStatus(t *testing.T, q *Queue, id string, want Status) Job {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if job, ok := q.Get(id); ok && job.Status == want {
+			return job
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	job, _ := q.Get(id)
+	t.Fatalf("job %s did not reach status %q (current: %q)", id, want, job.Status)
+	return Job{}
+}
+
+func TestQueue_RunsJobToCompletion(t *testing.T) {
+	q := NewQueue(1)
+	defer q.Stop()
+
+	job, err := q.Enqueue(context.Background(), "test", 7, func(ctx context.Context, report func(int)) (Output, error) {
+		report(50)
+		return Output{Data: []byte("done"), ContentType: "text/plain"}, nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if job.Status != StatusPending {
+		t.Errorf("expected pending status, got %q", job.Status)
+	}
+	if job.UserID != 7 {
+		t.Errorf("expected user 7, got %d", job.UserID)
+	}
+
+	finished := waitForStatus(t, q, job.ID, StatusCompleted)
+	if string(finished.Result) != "done" {
+		t.Errorf("expected result 'done', got %q", finished.Result)
+	}
+	if finished.Progress != 100 {
+		t.Errorf("expected progress 100, got %d", finished.Progress)
+	}
+	if finished.CompletedAt == nil {
+		t.Error("expected CompletedAt to be set")
+	}
+}
+
+func TestQueue_RecordsFailure(t *testing.T) {
+	q := NewQueue(1)
+	defer q.Stop()
+
+	job, _ := q.Enqueue(context.Background(), "test", 1, func(ctx context.Context, report func(int)) (Output, error) {
+		return Output{}, fmt.Errorf("boom")
+	})
+
+	failed := waitForStatus(t, q, job.ID, StatusFailed)
+	if failed.Error != "boom" {
+		t.Errorf("expected error 'boom', got %q", failed.Error)
+	}
+}
+
+func TestQueue_RecoversPanic(t *testing.T) {
+	q := NewQueue(1)
+	defer q.Stop()
+
+	job, _ := q.Enqueue(context.Background(), "test", 1, func(ctx context.Context, report func(int)) (Output, error) {
+		panic("unexpected")
+	})
+
+	waitForStatus(t, q, job.ID, StatusFailed)
+}
+
+func TestQueue_DetachesFromRequestCancellation(t *testing.T) {
+	q := NewQueue(1)
+	defer q.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	job, _ := q.Enqueue(ctx, "test", 1, func(ctx context.Context, report func(int)) (Output, error) {
+		if err := ctx.Err(); err != nil {
+			return Output{}, err
+		}
+		return Output{}, nil
+	})
+
+	waitForStatus(t, q, job.ID, StatusCompleted)
+}
+
+func TestQueue_GetUnknown(t *testing.T) {
+	q := NewQueue(1)
+	defer q.Stop()
+
+	if _, ok := q.Get("missing"); ok {
+		t.Error("expected unknown job to be missing")
+	}
+}
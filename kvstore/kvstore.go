@@ -0,0 +1,112 @@
+// Package kvstore holds short-lived shared state, such as revoked tokens, in
+// memory for a single instance or in Redis so every instance sees it.
+package kvstore
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Store is a key-value store whose entries expire.
+type Store interface {
+	// Set stores value under key for ttl.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Get returns the value under key, and false if there is none or it expired.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Delete removes key, if present.
+	Delete(ctx context.Context, key string) error
+	// Take removes key and returns its value, like Get. Of concurrent calls
+	// for the same key, only one finds it.
+	Take(ctx context.Context, key string) ([]byte, bool, error)
+}
+
+type entry struct {
+	value   []byte
+	expires time.Time
+}
+
+// MemoryStore is a Store local to the process.
+type MemoryStore struct {
+	mu      sync.RWMutex
+	entries map[string]entry
+	stopCh  chan struct{}
+}
+
+// NewMemoryStore creates a MemoryStore and starts a goroutine dropping
+// expired entries. Stop ends it.
+func NewMemoryStore() *MemoryStore {
+	s := &MemoryStore{
+		entries: make(map[string]entry),
+		stopCh:  make(chan struct{}),
+	}
+	go s.cleanup()
+	return s
+}
+
+// Set implements Store.
+func (s *MemoryStore) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[key] = entry{value: value, expires: time.Now().Add(ttl)}
+	return nil
+}
+
+// Get implements Store.
+func (s *MemoryStore) Get(_ context.Context, key string) ([]byte, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	e, ok := s.entries[key]
+	if !ok || time.Now().After(e.expires) {
+		return nil, false, nil
+	}
+	return e.value, true, nil
+}
+
+// Delete implements Store.
+func (s *MemoryStore) Delete(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+	return nil
+}
+
+// Take implements Store.
+func (s *MemoryStore) Take(_ context.Context, key string) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	delete(s.entries, key)
+	if time.Now().After(e.expires) {
+		return nil, false, nil
+	}
+	return e.value, true, nil
+}
+
+// Stop terminates the cleanup goroutine.
+func (s *MemoryStore) Stop() {
+	close(s.stopCh)
+}
+
+func (s *MemoryStore) cleanup() {
+	ticker := time.NewTicker(5 * time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.mu.Lock()
+			now := time.Now()
+			for key, e := range s.entries {
+				if now.After(e.expires) {
+					delete(s.entries, key)
+				}
+			}
+			s.mu.Unlock()
+		case <-s.stopCh:
+			return
+		}
+	}
+}
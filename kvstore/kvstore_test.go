@@ -0,0 +1,67 @@
+package kvstore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestStores(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	memory := NewMemoryStore()
+	defer memory.Stop()
+
+	stores := map[string]Store{
+		"memory": memory,
+		"redis":  NewRedisStore(client, "test:"),
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			if _, ok, err := store.Get(ctx, "missing"); ok || err != nil {
+				t.Fatalf("expected no entry, got ok=%v err=%v", ok, err)
+			}
+
+			if err := store.Set(ctx, "key", []byte("value"), time.Hour); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			value, ok, err := store.Get(ctx, "key")
+			if err != nil || !ok || string(value) != "value" {
+				t.Fatalf("expected %q, got %q ok=%v err=%v", "value", value, ok, err)
+			}
+
+			if err := store.Delete(ctx, "key"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if _, ok, _ := store.Get(ctx, "key"); ok {
+				t.Error("expected the entry to be deleted")
+			}
+
+			if err := store.Set(ctx, "once", []byte("value"), time.Hour); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			value, ok, err = store.Take(ctx, "once")
+			if err != nil || !ok || string(value) != "value" {
+				t.Fatalf("expected %q, got %q ok=%v err=%v", "value", value, ok, err)
+			}
+			if _, ok, err := store.Take(ctx, "once"); ok || err != nil {
+				t.Errorf("expected the entry to be taken once, got ok=%v err=%v", ok, err)
+			}
+
+			if err := store.Set(ctx, "short", []byte("value"), time.Millisecond); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			time.Sleep(5 * time.Millisecond)
+			mr.FastForward(5 * time.Millisecond)
+			if _, ok, _ := store.Get(ctx, "short"); ok {
+				t.Error("expected the entry to expire")
+			}
+		})
+	}
+}
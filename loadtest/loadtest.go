@@ -0,0 +1,309 @@
+// Package loadtest drives a running API with concurrent virtual users, each
+// registering an account and then creating, reading, updating and deleting
+// tasks until the test ends. It reports latency percentiles and error rates
+// per operation.
+package loadtest
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/models"
+)
+
+// password is used by every virtual user; it satisfies the password rules.
+const password = "Loadtest-Passw0rd!"
+
+// Options configures a load test.
+type Options struct {
+	TargetURL string        // base URL of the API, e.g. http://localhost:8080
+	Users     int           // concurrent virtual users
+	Duration  time.Duration // how long users keep sending requests
+	Timeout   time.Duration // per-request timeout
+}
+
+// Run registers opts.Users users against opts.TargetURL and runs the task
+// scenario until opts.Duration has elapsed or ctx is cancelled.
+func Run(ctx context.Context, opts Options) (Report, error) {
+	if opts.TargetURL == "" {
+		return Report{}, fmt.Errorf("target URL is required")
+	}
+	if opts.Users < 1 {
+		return Report{}, fmt.Errorf("users must be at least 1")
+	}
+	if opts.Duration <= 0 {
+		return Report{}, fmt.Errorf("duration must be positive")
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+
+	runID := make([]byte, 4)
+	rand.Read(runID)
+
+	ctx, cancel := context.WithTimeout(ctx, opts.Duration)
+	defer cancel()
+
+	rec := newRecorder()
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < opts.Users; i++ {
+		jar, _ := cookiejar.New(nil)
+		u := &virtualUser{
+			base:     strings.TrimRight(opts.TargetURL, "/"),
+			http:     &http.Client{Jar: jar, Timeout: opts.Timeout},
+			rec:      rec,
+			username: fmt.Sprintf("load%s%d", hex.EncodeToString(runID), i),
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			u.run(ctx)
+		}()
+	}
+	wg.Wait()
+
+	return rec.report(time.Since(start)), nil
+}
+
+// virtualUser is one simulated client with its own session.
+type virtualUser struct {
+	base     string
+	http     *http.Client
+	rec      *recorder
+	username string
+	csrf     string
+}
+
+func (u *virtualUser) run(ctx context.Context) {
+	email := u.username + "@loadtest.example.com"
+	if !u.do(ctx, "register", http.MethodPost, "/auth/register", models.RegisterRequest{
+		Username: u.username, Email: email, Password: password,
+	}, nil) {
+		return
+	}
+	if !u.do(ctx, "login", http.MethodPost, "/auth/login", models.LoginRequest{
+		Email: email, Password: password,
+	}, nil) {
+		return
+	}
+
+	var column models.Column
+	if !u.do(ctx, "create_column", http.MethodPost, "/columns", models.CreateColumnRequest{Title: u.username}, &column) {
+		return
+	}
+
+	for n := 1; ctx.Err() == nil; n++ {
+		var task models.Task
+		if !u.do(ctx, "create_task", http.MethodPost, "/tasks", models.CreateTaskRequest{
+			Title: fmt.Sprintf("Load test task %d", n), ColumnID: column.ID,
+		}, &task) {
+			continue
+		}
+		path := fmt.Sprintf("/tasks/%d", task.ID)
+		u.do(ctx, "get_task", http.MethodGet, path, nil, nil)
+		u.do(ctx, "update_task", http.MethodPut, path, models.UpdateTaskRequest{Description: "updated"}, nil)
+		u.do(ctx, "get_board", http.MethodGet, "/tasks/board", nil, nil)
+		u.do(ctx, "delete_task", http.MethodDelete, path, nil, nil)
+	}
+
+	// Clean up with a fresh context: the run context is over by now
+	cleanupCtx, cancel := context.WithTimeout(context.Background(), u.http.Timeout)
+	defer cancel()
+	u.send(cleanupCtx, http.MethodDelete, fmt.Sprintf("/columns/%d", column.ID), nil)
+}
+
+// do sends a request, records its latency under op and decodes the data of
+// a successful response into out. It reports whether the request succeeded.
+// Requests cut short by the end of the test are not recorded.
+func (u *virtualUser) do(ctx context.Context, op, method, path string, body, out interface{}) bool {
+	start := time.Now()
+	resp, err := u.send(ctx, method, path, body)
+	elapsed := time.Since(start)
+	if ctx.Err() != nil {
+		return false
+	}
+
+	ok := err == nil && resp.StatusCode < 400
+	if ok && out != nil {
+		var envelope struct {
+			Data json.RawMessage `json:"data"`
+		}
+		ok = json.Unmarshal(resp.body, &envelope) == nil && json.Unmarshal(envelope.Data, out) == nil
+	}
+	u.rec.record(op, elapsed, ok)
+	return ok
+}
+
+type response struct {
+	StatusCode int
+	body       []byte
+}
+
+func (u *virtualUser) send(ctx context.Context, method, path string, body interface{}) (*response, error) {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.base+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if u.csrf != "" {
+		req.Header.Set("X-CSRF-Token", u.csrf)
+	}
+
+	resp, err := u.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if token := resp.Header.Get("X-CSRF-Token"); token != "" {
+		u.csrf = token
+	}
+	return &response{StatusCode: resp.StatusCode, body: data}, nil
+}
+
+// recorder collects latencies and failures per operation.
+type recorder struct {
+	mu  sync.Mutex
+	ops map[string]*samples
+}
+
+type samples struct {
+	latencies []time.Duration
+	errors    int
+}
+
+func newRecorder() *recorder {
+	return &recorder{ops: make(map[string]*samples)}
+}
+
+func (r *recorder) record(op string, latency time.Duration, ok bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s, found := r.ops[op]
+	if !found {
+		s = &samples{}
+		r.ops[op] = s
+	}
+	s.latencies = append(s.latencies, latency)
+	if !ok {
+		s.errors++
+	}
+}
+
+func (r *recorder) report(elapsed time.Duration) Report {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	report := Report{Elapsed: elapsed}
+	for name, s := range r.ops {
+		sort.Slice(s.latencies, func(i, j int) bool { return s.latencies[i] < s.latencies[j] })
+		report.Operations = append(report.Operations, OperationStats{
+			Name:     name,
+			Requests: len(s.latencies),
+			Errors:   s.errors,
+			P50:      percentile(s.latencies, 0.50),
+			P95:      percentile(s.latencies, 0.95),
+			P99:      percentile(s.latencies, 0.99),
+			Max:      s.latencies[len(s.latencies)-1],
+		})
+	}
+	sort.Slice(report.Operations, func(i, j int) bool { return report.Operations[i].Name < report.Operations[j].Name })
+	return report
+}
+
+// percentile returns the nearest-rank percentile of sorted latencies.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(p*float64(len(sorted))+0.999999) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return sorted[rank]
+}
+
+// Report summarizes a load test.
+type Report struct {
+	Elapsed    time.Duration
+	Operations []OperationStats
+}
+
+// OperationStats are the results of one kind of request.
+type OperationStats struct {
+	Name          string
+	Requests      int
+	Errors        int
+	P50, P95, P99 time.Duration
+	Max           time.Duration
+}
+
+// Totals returns the number of requests and failures across operations.
+func (r Report) Totals() (requests, errors int) {
+	for _, op := range r.Operations {
+		requests += op.Requests
+		errors += op.Errors
+	}
+	return requests, errors
+}
+
+// ErrorRate returns the share of failed requests, between 0 and 1.
+func (r Report) ErrorRate() float64 {
+	requests, errors := r.Totals()
+	if requests == 0 {
+		return 0
+	}
+	return float64(errors) / float64(requests)
+}
+
+// Write prints the report as a table.
+func (r Report) Write(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "operation\trequests\terrors\tp50\tp95\tp99\tmax\t")
+	for _, op := range r.Operations {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%s\t%s\t%s\t%s\t\n", op.Name, op.Requests, op.Errors,
+			round(op.P50), round(op.P95), round(op.P99), round(op.Max))
+	}
+	tw.Flush()
+
+	requests, errors := r.Totals()
+	throughput := 0.0
+	if r.Elapsed > 0 {
+		throughput = float64(requests) / r.Elapsed.Seconds()
+	}
+	fmt.Fprintf(w, "\n%d requests in %s (%.1f req/s), %d errors (%.2f%%)\n",
+		requests, round(r.Elapsed), throughput, errors, r.ErrorRate()*100)
+}
+
+func round(d time.Duration) time.Duration {
+	if d > time.Second {
+		return d.Round(time.Millisecond)
+	}
+	return d.Round(10 * time.Microsecond)
+}
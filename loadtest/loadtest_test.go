@@ -0,0 +1,118 @@
+package loadtest
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeAPI answers the scenario's requests; the board endpoint always fails.
+func fakeAPI(missingCSRF *atomic.Int32) *httptest.Server {
+	var nextID atomic.Int32
+	mux := http.NewServeMux()
+	auth := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-CSRF-Token", "csrf")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"success":true,"data":{}}`))
+	}
+	created := func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-CSRF-Token") != "csrf" {
+			missingCSRF.Add(1)
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"success":true,"data":{"id":` + strconv.Itoa(int(nextID.Add(1))) + `}}`))
+	}
+	mux.HandleFunc("POST /auth/register", auth)
+	mux.HandleFunc("POST /auth/login", auth)
+	mux.HandleFunc("POST /columns", created)
+	mux.HandleFunc("POST /tasks", created)
+	task := func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}
+	mux.HandleFunc("GET /tasks/{id}", task)
+	mux.HandleFunc("PUT /tasks/{id}", task)
+	mux.HandleFunc("DELETE /tasks/{id}", task)
+	mux.HandleFunc("GET /tasks/board", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	mux.HandleFunc("DELETE /columns/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestRun(t *testing.T) {
+	var missingCSRF atomic.Int32
+	srv := fakeAPI(&missingCSRF)
+	defer srv.Close()
+
+	report, err := Run(context.Background(), Options{TargetURL: srv.URL, Users: 3, Duration: 200 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	stats := map[string]OperationStats{}
+	for _, op := range report.Operations {
+		stats[op.Name] = op
+	}
+	if stats["register"].Requests != 3 || stats["login"].Requests != 3 || stats["create_column"].Requests != 3 {
+		t.Errorf("expected one setup per user, got %+v", report.Operations)
+	}
+	if stats["create_task"].Requests == 0 || stats["get_task"].Errors != 0 {
+		t.Errorf("expected successful task requests, got %+v", stats["create_task"])
+	}
+	if board := stats["get_board"]; board.Requests == 0 || board.Errors != board.Requests {
+		t.Errorf("expected every board request to fail, got %+v", board)
+	}
+	if get := stats["get_task"]; get.P50 < time.Millisecond || get.P99 < get.P50 || get.Max < get.P99 {
+		t.Errorf("unexpected percentiles %+v", get)
+	}
+	if missingCSRF.Load() != 0 {
+		t.Errorf("expected the CSRF token on every state change, %d requests lacked it", missingCSRF.Load())
+	}
+	if rate := report.ErrorRate(); rate <= 0 || rate >= 1 {
+		t.Errorf("expected a partial error rate, got %v", rate)
+	}
+
+	var out bytes.Buffer
+	report.Write(&out)
+	if !strings.Contains(out.String(), "get_board") || !strings.Contains(out.String(), "req/s") {
+		t.Errorf("unexpected report output:\n%s", out.String())
+	}
+}
+
+func TestRun_ValidatesOptions(t *testing.T) {
+	tests := []Options{
+		{Users: 1, Duration: time.Second},
+		{TargetURL: "http://localhost", Users: 0, Duration: time.Second},
+		{TargetURL: "http://localhost", Users: 1},
+	}
+	for _, opts := range tests {
+		if _, err := Run(context.Background(), opts); err == nil {
+			t.Errorf("expected an error for %+v", opts)
+		}
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	var latencies []time.Duration
+	for i := 1; i <= 100; i++ {
+		latencies = append(latencies, time.Duration(i)*time.Millisecond)
+	}
+	if got := percentile(latencies, 0.95); got != 95*time.Millisecond {
+		t.Errorf("expected p95 of 95ms, got %s", got)
+	}
+	if got := percentile(latencies[:1], 0.99); got != time.Millisecond {
+		t.Errorf("expected the only sample, got %s", got)
+	}
+	if got := percentile(nil, 0.5); got != 0 {
+		t.Errorf("expected 0 without samples, got %s", got)
+	}
+}
@@ -0,0 +1,109 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// AsyncWriter moves writes off the caller's goroutine. Entries are copied into
+// a buffered channel and written by a background goroutine. When the buffer is
+// full, entries are dropped rather than blocking the request path; the number
+// of dropped entries is reported to the sink once it catches up.
+type AsyncWriter struct {
+	w       io.Writer
+	entries chan asyncEntry
+	dropped atomic.Int64
+	done    chan struct{}
+	mu      sync.RWMutex // guards closed against sends on a closed channel
+	closed  bool
+}
+
+// asyncEntry is either data to write or a flush marker to close once reached.
+type asyncEntry struct {
+	data  []byte
+	flush chan struct{}
+}
+
+// NewAsyncWriter starts a background writer for w holding up to bufferSize entries.
+func NewAsyncWriter(w io.Writer, bufferSize int) *AsyncWriter {
+	if bufferSize <= 0 {
+		bufferSize = 1
+	}
+	aw := &AsyncWriter{
+		w:       w,
+		entries: make(chan asyncEntry, bufferSize),
+		done:    make(chan struct{}),
+	}
+	go aw.run()
+	return aw
+}
+
+// Write queues a copy of p. It never blocks; p is dropped if the buffer is full.
+func (aw *AsyncWriter) Write(p []byte) (int, error) {
+	aw.mu.RLock()
+	defer aw.mu.RUnlock()
+	if aw.closed {
+		return aw.w.Write(p)
+	}
+	data := make([]byte, len(p))
+	copy(data, p)
+	select {
+	case aw.entries <- asyncEntry{data: data}:
+	default:
+		aw.dropped.Add(1)
+	}
+	return len(p), nil
+}
+
+// Dropped returns the total number of entries dropped because the buffer was full.
+func (aw *AsyncWriter) Dropped() int64 {
+	return aw.dropped.Load()
+}
+
+// Flush blocks until every entry queued before the call has been written.
+func (aw *AsyncWriter) Flush() {
+	aw.mu.RLock()
+	if aw.closed {
+		aw.mu.RUnlock()
+		return
+	}
+	marker := make(chan struct{})
+	aw.entries <- asyncEntry{flush: marker}
+	aw.mu.RUnlock()
+	<-marker
+}
+
+// Close flushes pending entries and stops the background writer. Later writes
+// go straight to the underlying writer. The underlying writer is not closed.
+func (aw *AsyncWriter) Close() error {
+	aw.mu.Lock()
+	if aw.closed {
+		aw.mu.Unlock()
+		return nil
+	}
+	aw.closed = true
+	close(aw.entries)
+	aw.mu.Unlock()
+
+	// The writer drains everything still queued before exiting.
+	<-aw.done
+	return nil
+}
+
+func (aw *AsyncWriter) run() {
+	defer close(aw.done)
+	var reported int64
+	for entry := range aw.entries {
+		if entry.flush != nil {
+			close(entry.flush)
+			continue
+		}
+		if dropped := aw.dropped.Load(); dropped > reported {
+			fmt.Fprintf(aw.w, `{"level":"WARN","msg":"Log buffer full, entries dropped","dropped":%d}`+"\n", dropped-reported)
+			reported = dropped
+		}
+		aw.w.Write(entry.data)
+	}
+}
@@ -0,0 +1,41 @@
+package logger
+
+import (
+	"log/slog"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// moduleRoot is the source directory of this module, derived from the location
+// of this file (<root>/logger/caller.go). It is empty if it cannot be determined.
+var moduleRoot = func() string {
+	_, file, _, ok := runtime.Caller(0)
+	if !ok {
+		return ""
+	}
+	return filepath.Dir(filepath.Dir(file)) + string(filepath.Separator)
+}()
+
+// relativePath trims the module root from file so that callers are reported
+// as package-relative paths such as "handlers/tasks.go". Files outside the
+// module (standard library, dependencies) are returned unchanged.
+func relativePath(file string) string {
+	if moduleRoot != "" && strings.HasPrefix(file, moduleRoot) {
+		return filepath.ToSlash(strings.TrimPrefix(file, moduleRoot))
+	}
+	return file
+}
+
+// relativeSource is a slog ReplaceAttr func that rewrites the source file path.
+func relativeSource(groups []string, a slog.Attr) slog.Attr {
+	if a.Key != slog.SourceKey || len(groups) > 0 {
+		return a
+	}
+	if src, ok := a.Value.Any().(*slog.Source); ok && src != nil {
+		rel := *src
+		rel.File = relativePath(src.File)
+		return slog.Any(slog.SourceKey, &rel)
+	}
+	return a
+}
@@ -2,9 +2,14 @@ package logger
 
 import (
 	"context"
+	"errors"
+	"io"
 	"log/slog"
 	"os"
+	"runtime"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 )
 
@@ -13,32 +18,260 @@ type ContextKey string
 
 const (
 	RequestIDKey ContextKey = "request_id"
+	// TraceIDKey and SpanIDKey hold the W3C Trace Context of the request
+	TraceIDKey   ContextKey = "trace_id"
+	SpanIDKey    ContextKey = "span_id"
 	UserIDKey    ContextKey = "user_id"
+	ClientIPKey  ContextKey = "client_ip"
+	UserAgentKey ContextKey = "user_agent"
+	// ImpersonatorIDKey holds the admin behind an impersonation token
+	ImpersonatorIDKey ContextKey = "impersonator_id"
+
+	// loggerKey stores the request-scoped logger
+	loggerKey ContextKey = "logger"
+	// baseKey stores the logger that replaces the global one for a context
+	baseKey ContextKey = "base_logger"
+	// slowQueryKey stores the slow query threshold of a context
+	slowQueryKey ContextKey = "slow_query_threshold"
 )
 
 // Global slog logger
 var global *slog.Logger
 
-// Initialize sets up the global logger with a JSON handler.
+// closers holds sinks (async writers, log files) that must be closed on shutdown,
+// in the order they must be closed.
+var closers []io.Closer
+
+// asyncWriters are flushed by Flush.
+var asyncWriters []*AsyncWriter
+
+// level is the minimum level logged; SetLevel changes it at runtime.
+var level = new(slog.LevelVar)
+
+// slowQueries counts the operations reported as slow.
+var slowQueries atomic.Int64
+
+// callerLevel is the minimum level for which caller info is captured.
+var callerLevel = slog.LevelDebug
+
+// activeSampler is the sampler of the current logger, if sampling is enabled.
+var activeSampler *sampler
+
+// Output formats for stdout.
+const (
+	FormatJSON    = "json"
+	FormatConsole = "console"
+)
+
+// Options configures the global logger's sinks.
+type Options struct {
+	Level          slog.Level
+	Format         string // FormatJSON (default) or FormatConsole for stdout
+	FilePath       string // optional JSON log file, rotated by size
+	FileMaxSizeMB  int
+	FileMaxBackups int
+	Async          bool // write from a background goroutine instead of the caller's
+	BufferSize     int  // entries buffered per sink in async mode
+	SampleFirst    int  // identical WARN messages always logged per SampleWindow
+	SampleEvery    int  // after SampleFirst, log one in SampleEvery (0 disables sampling)
+	SampleWindow   time.Duration
+	CallerLevel    slog.Level // caller (source) info is captured at this level and above
+}
+
+// OptionsFromEnv reads LOG_LEVEL, LOG_FORMAT, LOG_FILE, LOG_FILE_MAX_SIZE_MB,
+// LOG_FILE_MAX_BACKUPS, LOG_ASYNC, LOG_BUFFER_SIZE, LOG_SAMPLE_FIRST,
+// LOG_SAMPLE_EVERY and LOG_CALLER_LEVEL.
+func OptionsFromEnv() Options {
+	opts := Options{
+		Level:          parseLevel(os.Getenv("LOG_LEVEL"), slog.LevelInfo),
+		Format:         strings.ToLower(os.Getenv("LOG_FORMAT")),
+		FilePath:       os.Getenv("LOG_FILE"),
+		FileMaxSizeMB:  envInt("LOG_FILE_MAX_SIZE_MB", 100),
+		FileMaxBackups: envInt("LOG_FILE_MAX_BACKUPS", 5),
+		Async:          os.Getenv("LOG_ASYNC") != "false",
+		BufferSize:     envInt("LOG_BUFFER_SIZE", 4096),
+		SampleFirst:    envInt("LOG_SAMPLE_FIRST", 10),
+		SampleEvery:    envInt("LOG_SAMPLE_EVERY", 100),
+		SampleWindow:   time.Second,
+		CallerLevel:    parseLevel(os.Getenv("LOG_CALLER_LEVEL"), slog.LevelDebug),
+	}
+	return opts
+}
+
+// parseLevel maps DEBUG, INFO, WARN and ERROR (case-insensitive) to slog levels.
+func parseLevel(value string, defaultLevel slog.Level) slog.Level {
+	switch strings.ToUpper(value) {
+	case "DEBUG":
+		return slog.LevelDebug
+	case "INFO":
+		return slog.LevelInfo
+	case "WARN":
+		return slog.LevelWarn
+	case "ERROR":
+		return slog.LevelError
+	}
+	return defaultLevel
+}
+
+// Initialize sets up the global logger from environment variables.
+// If the log file cannot be opened, it falls back to stdout only.
 func Initialize() {
-	level := slog.LevelInfo
-	if env := os.Getenv("LOG_LEVEL"); env != "" {
-		switch strings.ToUpper(env) {
-		case "DEBUG":
-			level = slog.LevelDebug
-		case "WARN":
-			level = slog.LevelWarn
-		case "ERROR":
-			level = slog.LevelError
+	opts := OptionsFromEnv()
+	if err := Setup(opts); err != nil {
+		opts.FilePath = ""
+		Setup(opts)
+		Error("Failed to open log file, logging to stdout only", err)
+	}
+}
+
+// Setup replaces the global logger with one writing to the configured sinks.
+func Setup(opts Options) error {
+	handlerOpts := &slog.HandlerOptions{
+		Level:       enabledLevel{},
+		AddSource:   true,
+		ReplaceAttr: relativeSource,
+	}
+
+	var newClosers []io.Closer
+	var newAsync []*AsyncWriter
+	wrap := func(w io.Writer) io.Writer {
+		if !opts.Async {
+			return w
 		}
+		aw := NewAsyncWriter(w, opts.BufferSize)
+		newAsync = append(newAsync, aw)
+		newClosers = append(newClosers, aw)
+		return aw
 	}
 
-	handler := slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
-		Level:     level,
-		AddSource: true,
-	})
+	var file *RotatingFile
+	if opts.FilePath != "" {
+		var err error
+		file, err = NewRotatingFile(opts.FilePath, int64(opts.FileMaxSizeMB)<<20, opts.FileMaxBackups)
+		if err != nil {
+			return err
+		}
+	}
+
+	var handlers []slog.Handler
+	if opts.Format == FormatConsole {
+		handlers = append(handlers, NewConsoleHandler(wrap(os.Stdout), handlerOpts))
+	} else {
+		handlers = append(handlers, slog.NewJSONHandler(wrap(os.Stdout), handlerOpts))
+	}
+	if file != nil {
+		handlers = append(handlers, slog.NewJSONHandler(wrap(file), handlerOpts))
+		// Close the file after its async writer has drained.
+		newClosers = append(newClosers, file)
+	}
+
+	var handler slog.Handler = newFanoutHandler(handlers...)
+	var newSampler *sampler
+	if opts.SampleEvery > 0 {
+		window := opts.SampleWindow
+		if window <= 0 {
+			window = time.Second
+		}
+		sh := newSamplingHandler(handler, opts.SampleFirst, opts.SampleEvery, window)
+		newSampler = sh.s
+		handler = sh
+	}
+	handler = &moduleHandler{next: handler}
+
+	Close()
+	closers = newClosers
+	asyncWriters = newAsync
+	activeSampler = newSampler
+	callerLevel = opts.CallerLevel
+	level.Set(opts.Level)
 	global = slog.New(handler)
 	slog.SetDefault(global)
+	return nil
+}
+
+// SetLevel changes the minimum level of the global logger without rebuilding
+// its sinks.
+func SetLevel(l slog.Level) {
+	level.Set(l)
+}
+
+// Level returns the minimum level of the global logger.
+func Level() slog.Level {
+	return level.Level()
+}
+
+// WithSlowQueryThreshold returns a copy of ctx whose database operations
+// are logged at WARN and counted as slow from d on. 0, like a context
+// without a threshold, disables slow query detection. Servers use it to
+// each apply their own.
+func WithSlowQueryThreshold(ctx context.Context, d time.Duration) context.Context {
+	return context.WithValue(ctx, slowQueryKey, d)
+}
+
+// SlowQueries returns the number of database operations reported as slow.
+func SlowQueries() int64 {
+	return slowQueries.Load()
+}
+
+// Suppressed returns the number of WARN entries dropped by sampling.
+func Suppressed() int64 {
+	if activeSampler == nil {
+		return 0
+	}
+	return activeSampler.suppressed.Load()
+}
+
+// Flush blocks until all buffered log entries have been written.
+func Flush() {
+	for _, aw := range asyncWriters {
+		aw.Flush()
+	}
+}
+
+// Close flushes buffered entries and releases file sinks. Call it during
+// shutdown; the stdout sink keeps working (synchronously) afterwards.
+func Close() error {
+	var firstErr error
+	for _, c := range closers {
+		if err := c.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	closers = nil
+	asyncWriters = nil
+	return firstErr
+}
+
+func envInt(key string, defaultValue int) int {
+	if v, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return v
+	}
+	return defaultValue
+}
+
+// write emits a record whose source is the caller of the exported logging
+// function, not this package. Caller capture is skipped below callerLevel,
+// unless module levels need it.
+func write(ctx context.Context, level slog.Level, message string, attrs ...slog.Attr) {
+	l := base(ctx)
+	if scoped, ok := scopedLogger(ctx); ok {
+		l = scoped
+	}
+	if !l.Enabled(ctx, level) {
+		return
+	}
+
+	var pc uintptr
+	if level >= callerLevel || moduleLevels.Load() != nil {
+		var pcs [1]uintptr
+		// Skip runtime.Callers, write and the exported logging function.
+		runtime.Callers(3, pcs[:])
+		pc = pcs[0]
+	}
+
+	r := slog.NewRecord(time.Now(), level, message, pc)
+	r.AddAttrs(attrs...)
+	l.Handler().Handle(ctx, r)
 }
 
 func get() *slog.Logger {
@@ -48,15 +281,71 @@ func get() *slog.Logger {
 	return global
 }
 
+// NewContext returns a copy of ctx carrying l as the request-scoped logger.
+// Every *Context logging function called with the returned context uses l.
+func NewContext(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey, l)
+}
+
+// WithBase returns a copy of ctx that logs through l instead of the global
+// logger. Unlike NewContext, request and user IDs are still taken from ctx, so
+// it can be set before they are known. Servers use it to give each instance
+// its own logger.
+func WithBase(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, baseKey, l)
+}
+
+// FromContext returns the request-scoped logger stored in ctx. Without one, it
+// returns the base (or global) logger with the request and user IDs found in ctx.
+func FromContext(ctx context.Context) *slog.Logger {
+	if l, ok := scopedLogger(ctx); ok {
+		return l
+	}
+	return base(ctx).With(toArgs(ctxAttrs(ctx))...)
+}
+
+// base returns the logger set by WithBase, or the global logger.
+func base(ctx context.Context) *slog.Logger {
+	if ctx != nil {
+		if l, ok := ctx.Value(baseKey).(*slog.Logger); ok {
+			return l
+		}
+	}
+	return get()
+}
+
+// With returns a copy of ctx whose request-scoped logger also carries args.
+func With(ctx context.Context, args ...any) context.Context {
+	return NewContext(ctx, FromContext(ctx).With(args...))
+}
+
+func scopedLogger(ctx context.Context) (*slog.Logger, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	l, ok := ctx.Value(loggerKey).(*slog.Logger)
+	return l, ok
+}
+
 // ctxAttrs extracts request_id and user_id from context as slog attributes.
+// It returns nothing when ctx has a request-scoped logger, which already carries them.
 func ctxAttrs(ctx context.Context) []slog.Attr {
 	var attrs []slog.Attr
 	if ctx == nil {
 		return attrs
 	}
+	if _, ok := scopedLogger(ctx); ok {
+		return attrs
+	}
 	if rid, ok := ctx.Value(RequestIDKey).(string); ok {
 		attrs = append(attrs, slog.String("request_id", rid))
 	}
+	if tid, ok := ctx.Value(TraceIDKey).(string); ok {
+		attrs = append(attrs, slog.String("trace_id", tid))
+	}
+	if sid, ok := ctx.Value(SpanIDKey).(string); ok {
+		attrs = append(attrs, slog.String("span_id", sid))
+	}
 	if uid, ok := ctx.Value(UserIDKey).(int); ok {
 		attrs = append(attrs, slog.Int("user_id", uid))
 	}
@@ -93,7 +382,7 @@ func Debug(message string, fields ...map[string]interface{}) {
 	if len(fields) > 0 {
 		f = fields[0]
 	}
-	get().LogAttrs(context.Background(), slog.LevelDebug, message, fieldsToAttrs(f)...)
+	write(context.Background(), slog.LevelDebug, message, fieldsToAttrs(f)...)
 }
 
 func DebugContext(ctx context.Context, message string, fields ...map[string]interface{}) {
@@ -101,7 +390,7 @@ func DebugContext(ctx context.Context, message string, fields ...map[string]inte
 	if len(fields) > 0 {
 		f = fields[0]
 	}
-	get().LogAttrs(ctx, slog.LevelDebug, message, append(ctxAttrs(ctx), fieldsToAttrs(f)...)...)
+	write(ctx, slog.LevelDebug, message, append(ctxAttrs(ctx), fieldsToAttrs(f)...)...)
 }
 
 func Info(message string, fields ...map[string]interface{}) {
@@ -109,7 +398,7 @@ func Info(message string, fields ...map[string]interface{}) {
 	if len(fields) > 0 {
 		f = fields[0]
 	}
-	get().LogAttrs(context.Background(), slog.LevelInfo, message, fieldsToAttrs(f)...)
+	write(context.Background(), slog.LevelInfo, message, fieldsToAttrs(f)...)
 }
 
 func InfoContext(ctx context.Context, message string, fields ...map[string]interface{}) {
@@ -117,7 +406,7 @@ func InfoContext(ctx context.Context, message string, fields ...map[string]inter
 	if len(fields) > 0 {
 		f = fields[0]
 	}
-	get().LogAttrs(ctx, slog.LevelInfo, message, append(ctxAttrs(ctx), fieldsToAttrs(f)...)...)
+	write(ctx, slog.LevelInfo, message, append(ctxAttrs(ctx), fieldsToAttrs(f)...)...)
 }
 
 func Warn(message string, fields ...map[string]interface{}) {
@@ -125,7 +414,7 @@ func Warn(message string, fields ...map[string]interface{}) {
 	if len(fields) > 0 {
 		f = fields[0]
 	}
-	get().LogAttrs(context.Background(), slog.LevelWarn, message, fieldsToAttrs(f)...)
+	write(context.Background(), slog.LevelWarn, message, fieldsToAttrs(f)...)
 }
 
 func WarnContext(ctx context.Context, message string, fields ...map[string]interface{}) {
@@ -133,7 +422,7 @@ func WarnContext(ctx context.Context, message string, fields ...map[string]inter
 	if len(fields) > 0 {
 		f = fields[0]
 	}
-	get().LogAttrs(ctx, slog.LevelWarn, message, append(ctxAttrs(ctx), fieldsToAttrs(f)...)...)
+	write(ctx, slog.LevelWarn, message, append(ctxAttrs(ctx), fieldsToAttrs(f)...)...)
 }
 
 func Error(message string, err error, fields ...map[string]interface{}) {
@@ -145,9 +434,12 @@ func Error(message string, err error, fields ...map[string]interface{}) {
 	if err != nil {
 		attrs = append(attrs, slog.String("error", err.Error()))
 	}
-	get().LogAttrs(context.Background(), slog.LevelError, message, attrs...)
+	write(context.Background(), slog.LevelError, message, attrs...)
 }
 
+// ErrorContext logs at ERROR, or at DEBUG once ctx is canceled: the client
+// of the request went away, and the failure is most likely the work it
+// abandoned being cut short.
 func ErrorContext(ctx context.Context, message string, err error, fields ...map[string]interface{}) {
 	var f map[string]interface{}
 	if len(fields) > 0 {
@@ -157,7 +449,16 @@ func ErrorContext(ctx context.Context, message string, err error, fields ...map[
 	if err != nil {
 		attrs = append(attrs, slog.String("error", err.Error()))
 	}
-	get().LogAttrs(ctx, slog.LevelError, message, attrs...)
+	write(ctx, errorLevel(ctx), message, attrs...)
+}
+
+// errorLevel is the level of failures in ctx: DEBUG if ctx was canceled,
+// ERROR otherwise. Deadlines are not cancellations and stay errors.
+func errorLevel(ctx context.Context) slog.Level {
+	if errors.Is(ctx.Err(), context.Canceled) {
+		return slog.LevelDebug
+	}
+	return slog.LevelError
 }
 
 func Fatal(message string, err error, fields ...map[string]interface{}) {
@@ -169,7 +470,8 @@ func Fatal(message string, err error, fields ...map[string]interface{}) {
 	if err != nil {
 		attrs = append(attrs, slog.String("error", err.Error()))
 	}
-	get().LogAttrs(context.Background(), slog.LevelError, message, attrs...)
+	write(context.Background(), slog.LevelError, message, attrs...)
+	Close()
 	os.Exit(1)
 }
 
@@ -181,10 +483,12 @@ func LogHTTPRequest(ctx context.Context, method, url string, statusCode int, dur
 		slog.Int("status_code", statusCode),
 		slog.String("duration", duration.String()),
 	)
-	get().LogAttrs(ctx, slog.LevelInfo, "HTTP Request", attrs...)
+	write(ctx, slog.LevelInfo, "HTTP Request", attrs...)
 }
 
-// LogDatabaseOperation logs database operation details.
+// LogDatabaseOperation logs database operation details. Operations reaching
+// the slow query threshold are logged at WARN and counted; failures at ERROR,
+// or DEBUG when canceled with the request.
 func LogDatabaseOperation(ctx context.Context, operation, table string, duration time.Duration, err error) {
 	attrs := append(ctxAttrs(ctx),
 		slog.String("operation", operation),
@@ -192,10 +496,20 @@ func LogDatabaseOperation(ctx context.Context, operation, table string, duration
 		slog.String("duration", duration.String()),
 	)
 
-	if err != nil {
+	threshold, _ := ctx.Value(slowQueryKey).(time.Duration)
+	slow := threshold > 0 && duration >= threshold
+	if slow {
+		slowQueries.Add(1)
+	}
+
+	switch {
+	case err != nil:
 		attrs = append(attrs, slog.String("error", err.Error()))
-		get().LogAttrs(ctx, slog.LevelError, "Database operation failed", attrs...)
-	} else {
-		get().LogAttrs(ctx, slog.LevelInfo, "Database operation completed", attrs...)
+		write(ctx, errorLevel(ctx), "Database operation failed", attrs...)
+	case slow:
+		attrs = append(attrs, slog.String("threshold", threshold.String()))
+		write(ctx, slog.LevelWarn, "Slow database operation", attrs...)
+	default:
+		write(ctx, slog.LevelInfo, "Database operation completed", attrs...)
 	}
 }
@@ -0,0 +1,427 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConsoleHandler(t *testing.T) {
+	var buf bytes.Buffer
+	log := slog.New(NewConsoleHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo}))
+
+	log.Debug("hidden")
+	log.With("service", "api").WithGroup("req").Info("Request done", "status", 200, "path", "/tasks 1")
+
+	out := buf.String()
+	if strings.Contains(out, "hidden") {
+		t.Error("expected debug record to be filtered out")
+	}
+	for _, want := range []string{"INFO  Request done", "service=api", "req.status=200", `req.path="/tasks 1"`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in output %q", want, out)
+		}
+	}
+}
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "app.log")
+	rf, err := NewRotatingFile(path, 10, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer rf.Close()
+
+	for _, line := range []string{"first-1\n", "second2\n", "third-3\n", "fourth4\n"} {
+		if _, err := rf.Write([]byte(line)); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+	}
+
+	assertContent := func(name, want string) {
+		t.Helper()
+		got, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatalf("read %s: %v", name, err)
+		}
+		if string(got) != want {
+			t.Errorf("%s: got %q, want %q", filepath.Base(name), got, want)
+		}
+	}
+	assertContent(path, "fourth4\n")
+	assertContent(path+".1", "third-3\n")
+	assertContent(path+".2", "second2\n")
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Error("expected backups beyond maxBackups to be removed")
+	}
+}
+
+func TestSetup_WritesJSONToFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	if err := Setup(Options{Level: slog.LevelInfo, FilePath: path, FileMaxSizeMB: 1}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer func() {
+		Close()
+		Setup(Options{Level: slog.LevelInfo})
+	}()
+
+	Info("file sink test", map[string]interface{}{"answer": 42})
+	Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read log file: %v", err)
+	}
+	var entry map[string]interface{}
+	if err := json.Unmarshal(bytes.TrimSpace(data), &entry); err != nil {
+		t.Fatalf("expected JSON log line, got %q", data)
+	}
+	if entry["msg"] != "file sink test" || entry["answer"] != float64(42) {
+		t.Errorf("unexpected entry %v", entry)
+	}
+}
+
+func TestSetLevel(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	if err := Setup(Options{Level: slog.LevelWarn, FilePath: path, FileMaxSizeMB: 1}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer func() {
+		Close()
+		Setup(Options{Level: slog.LevelInfo})
+	}()
+
+	Info("before")
+	SetLevel(slog.LevelInfo)
+	Info("after")
+	Close()
+
+	if Level() != slog.LevelInfo {
+		t.Errorf("expected level INFO, got %s", Level())
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read log file: %v", err)
+	}
+	if strings.Contains(string(data), "before") || !strings.Contains(string(data), "after") {
+		t.Errorf("expected only the entry logged after SetLevel, got %q", data)
+	}
+}
+
+// blockingWriter blocks every write until release is closed.
+type blockingWriter struct {
+	release chan struct{}
+	buf     bytes.Buffer
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	<-w.release
+	return w.buf.Write(p)
+}
+
+func TestAsyncWriter_FlushWritesEverything(t *testing.T) {
+	var buf bytes.Buffer
+	aw := NewAsyncWriter(&buf, 16)
+
+	for i := 0; i < 10; i++ {
+		aw.Write([]byte("line\n"))
+	}
+	aw.Flush()
+
+	if got := strings.Count(buf.String(), "line\n"); got != 10 {
+		t.Errorf("expected 10 lines after flush, got %d", got)
+	}
+	aw.Close()
+}
+
+func TestAsyncWriter_DropsWhenFull(t *testing.T) {
+	w := &blockingWriter{release: make(chan struct{})}
+	aw := NewAsyncWriter(w, 2)
+
+	// One entry is taken by the blocked writer, two fill the buffer, the rest are dropped.
+	for i := 0; i < 10; i++ {
+		if _, err := aw.Write([]byte("x\n")); err != nil {
+			t.Fatalf("write should never fail: %v", err)
+		}
+	}
+	if aw.Dropped() == 0 {
+		t.Fatal("expected entries to be dropped while the sink is blocked")
+	}
+
+	close(w.release)
+	aw.Close()
+
+	if !strings.Contains(w.buf.String(), "entries dropped") {
+		t.Errorf("expected a dropped entries notice, got %q", w.buf.String())
+	}
+}
+
+func TestAsyncWriter_WriteAfterClose(t *testing.T) {
+	var buf bytes.Buffer
+	aw := NewAsyncWriter(&buf, 4)
+	aw.Close()
+
+	aw.Write([]byte("late\n"))
+	if buf.String() != "late\n" {
+		t.Errorf("expected synchronous write after close, got %q", buf.String())
+	}
+}
+
+func TestSamplingHandler(t *testing.T) {
+	var buf bytes.Buffer
+	h := newSamplingHandler(slog.NewJSONHandler(&buf, nil), 2, 5, time.Minute)
+	log := slog.New(h)
+
+	for i := 0; i < 12; i++ {
+		log.Warn("database unavailable")
+		log.Error("query failed")
+	}
+	log.Info("request served")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	counts := map[string]int{}
+	var suppressedReported float64
+	for _, line := range lines {
+		var entry map[string]interface{}
+		json.Unmarshal([]byte(line), &entry)
+		counts[entry["msg"].(string)]++
+		if n, ok := entry["suppressed"].(float64); ok {
+			suppressedReported += n
+		}
+	}
+
+	// First 2, then the 7th and 12th.
+	if counts["database unavailable"] != 4 {
+		t.Errorf("expected 4 sampled warnings, got %d", counts["database unavailable"])
+	}
+	if counts["query failed"] != 12 {
+		t.Errorf("expected every error to be logged, got %d", counts["query failed"])
+	}
+	if counts["request served"] != 1 {
+		t.Errorf("expected info to be logged, got %d", counts["request served"])
+	}
+	if suppressedReported != 8 || h.s.suppressed.Load() != 8 {
+		t.Errorf("expected 8 suppressed entries, reported %v, counted %d", suppressedReported, h.s.suppressed.Load())
+	}
+}
+
+func TestSampler_ResetsEachWindow(t *testing.T) {
+	s := newSamplingHandler(slog.NewJSONHandler(&bytes.Buffer{}, nil), 1, 100, time.Second).s
+	start := time.Now()
+
+	if keep, _ := s.sample("msg", start); !keep {
+		t.Fatal("expected first entry to be kept")
+	}
+	if keep, _ := s.sample("msg", start.Add(10*time.Millisecond)); keep {
+		t.Fatal("expected second entry in window to be suppressed")
+	}
+	keep, suppressed := s.sample("msg", start.Add(2*time.Second))
+	if !keep || suppressed != 1 {
+		t.Errorf("expected entry kept in new window with 1 suppressed, got keep=%v suppressed=%d", keep, suppressed)
+	}
+}
+
+func TestCallerInfo(t *testing.T) {
+	var buf bytes.Buffer
+	global = slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{AddSource: true, ReplaceAttr: relativeSource}))
+	callerLevel = slog.LevelWarn
+	defer func() {
+		callerLevel = slog.LevelDebug
+		Setup(Options{Level: slog.LevelInfo})
+	}()
+
+	Warn("with caller") // the reported line must be this one
+	_, _, line, _ := runtime.Caller(0)
+	Info("without caller")
+
+	entries := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+
+	var warn struct {
+		Source struct {
+			File string `json:"file"`
+			Line int    `json:"line"`
+		} `json:"source"`
+	}
+	json.Unmarshal([]byte(entries[0]), &warn)
+	if warn.Source.File != "logger/logger_test.go" {
+		t.Errorf("expected package-relative caller file, got %q", warn.Source.File)
+	}
+	if warn.Source.Line != line-1 {
+		t.Errorf("expected caller line %d, got %d", line-1, warn.Source.Line)
+	}
+
+	var info map[string]interface{}
+	json.Unmarshal([]byte(entries[1]), &info)
+	if _, ok := info["source"]; ok {
+		t.Errorf("expected no caller below LOG_CALLER_LEVEL, got %v", info["source"])
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	var buf bytes.Buffer
+	global = slog.New(slog.NewJSONHandler(&buf, nil))
+	defer Setup(Options{Level: slog.LevelInfo})
+
+	ctx := context.WithValue(context.Background(), RequestIDKey, "req-1")
+	ctx = context.WithValue(ctx, TraceIDKey, "trace-1")
+	ctx = context.WithValue(ctx, SpanIDKey, "span-1")
+	ctx = With(ctx, "method", "GET")
+	ctx = With(ctx, "user_id", 7)
+
+	FromContext(ctx).Info("scoped", "task_id", 3)
+	InfoContext(ctx, "wrapper")
+
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry map[string]interface{}
+		json.Unmarshal([]byte(line), &entry)
+		if entry["request_id"] != "req-1" || entry["method"] != "GET" || entry["user_id"] != float64(7) {
+			t.Errorf("expected request-scoped fields in %s", line)
+		}
+		if strings.Count(line, `"request_id"`) != 1 {
+			t.Errorf("expected request_id once in %s", line)
+		}
+		if entry["trace_id"] != "trace-1" || entry["span_id"] != "span-1" {
+			t.Errorf("expected trace fields in %s", line)
+		}
+	}
+}
+
+func TestFromContext_WithoutScopedLogger(t *testing.T) {
+	var buf bytes.Buffer
+	global = slog.New(slog.NewJSONHandler(&buf, nil))
+	defer Setup(Options{Level: slog.LevelInfo})
+
+	ctx := context.WithValue(context.Background(), UserIDKey, 9)
+	FromContext(ctx).Info("fallback")
+
+	if !strings.Contains(buf.String(), `"user_id":9`) {
+		t.Errorf("expected user_id from context, got %s", buf.String())
+	}
+}
+
+func TestWithBase(t *testing.T) {
+	var globalBuf, baseBuf bytes.Buffer
+	global = slog.New(slog.NewJSONHandler(&globalBuf, nil))
+	defer Setup(Options{Level: slog.LevelInfo})
+
+	ctx := WithBase(context.Background(), slog.New(slog.NewJSONHandler(&baseBuf, nil)))
+	ctx = context.WithValue(ctx, RequestIDKey, "req-2")
+	ctx = With(ctx, "method", "GET")
+	InfoContext(ctx, "scoped")
+	InfoContext(WithBase(context.Background(), slog.New(slog.NewJSONHandler(&baseBuf, nil))), "plain")
+
+	if globalBuf.Len() != 0 {
+		t.Errorf("expected nothing on the global logger, got %s", globalBuf.String())
+	}
+	out := baseBuf.String()
+	if !strings.Contains(out, `"request_id":"req-2"`) || !strings.Contains(out, `"msg":"plain"`) {
+		t.Errorf("expected both records on the base logger, got %s", out)
+	}
+}
+
+func TestLogDatabaseOperation_SlowQuery(t *testing.T) {
+	var buf bytes.Buffer
+	global = slog.New(slog.NewJSONHandler(&buf, nil))
+	defer Setup(Options{Level: slog.LevelInfo})
+	ctx := WithSlowQueryThreshold(context.Background(), 100*time.Millisecond)
+
+	before := SlowQueries()
+	LogDatabaseOperation(ctx, "SELECT", "tasks", 10*time.Millisecond, nil)
+	LogDatabaseOperation(ctx, "SELECT", "users", 150*time.Millisecond, nil)
+	LogDatabaseOperation(context.Background(), "SELECT", "columns", 150*time.Millisecond, nil)
+
+	if got := SlowQueries() - before; got != 1 {
+		t.Errorf("expected 1 slow query, got %d", got)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 log lines, got %q", buf.String())
+	}
+	var entry map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[1]), &entry); err != nil {
+		t.Fatalf("decode %q: %v", lines[1], err)
+	}
+	if entry["level"] != "WARN" || entry["table"] != "users" || entry["threshold"] != "100ms" {
+		t.Errorf("unexpected slow query entry %v", entry)
+	}
+	if strings.Contains(lines[0], "WARN") {
+		t.Errorf("expected the fast query at INFO, got %s", lines[0])
+	}
+	if strings.Contains(lines[2], "WARN") {
+		t.Errorf("expected no slow query without a threshold, got %s", lines[2])
+	}
+}
+
+func TestErrorContext_CanceledRequest(t *testing.T) {
+	var buf bytes.Buffer
+	global = slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	defer Setup(Options{Level: slog.LevelInfo})
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	expired, cancelExpired := context.WithTimeout(context.Background(), 0)
+	defer cancelExpired()
+
+	ErrorContext(canceled, "canceled", context.Canceled)
+	LogDatabaseOperation(canceled, "SELECT", "tasks", time.Millisecond, context.Canceled)
+	ErrorContext(expired, "expired", context.DeadlineExceeded)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 log lines, got %q", buf.String())
+	}
+	for i, want := range []string{"DEBUG", "DEBUG", "ERROR"} {
+		var entry map[string]interface{}
+		json.Unmarshal([]byte(lines[i]), &entry)
+		if entry["level"] != want {
+			t.Errorf("line %d: expected %s, got %s", i, want, lines[i])
+		}
+	}
+}
+
+func TestModuleLevels(t *testing.T) {
+	var buf bytes.Buffer
+	global = slog.New(&moduleHandler{next: slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: enabledLevel{}})})
+	level.Set(slog.LevelInfo)
+	defer func() {
+		SetModuleLevels(nil)
+		Setup(Options{Level: slog.LevelInfo})
+	}()
+
+	SetModuleLevels(map[string]slog.Level{"handlers": slog.LevelDebug})
+	Debug("other module")
+	SetModuleLevels(map[string]slog.Level{"logger": slog.LevelDebug})
+	Debug("this module")
+	global.Debug("this module through slog")
+	SetModuleLevels(map[string]slog.Level{"logger": slog.LevelError})
+	Warn("raised above the global level")
+	SetModuleLevels(nil)
+	Debug("cleared")
+	Info("global level")
+
+	got := buf.String()
+	for _, want := range []string{"this module", "this module through slog", "global level"} {
+		if !strings.Contains(got, `"msg":"`+want+`"`) {
+			t.Errorf("expected %q to be logged, got %s", want, got)
+		}
+	}
+	for _, unwanted := range []string{"other module", "raised above the global level", "cleared"} {
+		if strings.Contains(got, unwanted) {
+			t.Errorf("expected %q to be dropped, got %s", unwanted, got)
+		}
+	}
+	if levels := ModuleLevels(); len(levels) != 0 {
+		t.Errorf("expected no module levels, got %v", levels)
+	}
+}
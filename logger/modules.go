@@ -0,0 +1,118 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"path"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// moduleLevels holds the minimum levels set per module with
+// SetModuleLevels, nil when there are none.
+var moduleLevels atomic.Pointer[moduleLevelSet]
+
+// modulesByPC caches the module of the call sites seen by moduleHandler.
+var modulesByPC sync.Map
+
+type moduleLevelSet struct {
+	levels map[string]slog.Level
+	min    slog.Level // the lowest of levels
+}
+
+// SetModuleLevels sets the minimum level of the entries logged from the
+// given modules, overriding the global level for them. A module is a
+// package directory of this module, such as "handlers" or "repository".
+// It replaces the module levels set before; nil or an empty map clears
+// them.
+//
+// While module levels are set, caller info is captured for every entry, as
+// the module of an entry is found from its caller.
+func SetModuleLevels(levels map[string]slog.Level) {
+	if len(levels) == 0 {
+		moduleLevels.Store(nil)
+		return
+	}
+	set := &moduleLevelSet{levels: maps.Clone(levels), min: slog.LevelError + 1}
+	for _, l := range levels {
+		set.min = min(set.min, l)
+	}
+	moduleLevels.Store(set)
+}
+
+// ModuleLevels returns the module levels set with SetModuleLevels.
+func ModuleLevels() map[string]slog.Level {
+	set := moduleLevels.Load()
+	if set == nil {
+		return map[string]slog.Level{}
+	}
+	return maps.Clone(set.levels)
+}
+
+// enabledLevel is the level from which the sinks handle entries: the
+// global level, or a module level below it. moduleHandler drops the entries
+// of the other modules below the global level.
+type enabledLevel struct{}
+
+func (enabledLevel) Level() slog.Level {
+	l := level.Level()
+	if set := moduleLevels.Load(); set != nil {
+		l = min(l, set.min)
+	}
+	return l
+}
+
+// moduleHandler applies the module levels to the records of next, by the
+// package of their caller. Records without caller info, or logged from
+// outside this module, are held to the global level.
+type moduleHandler struct {
+	next slog.Handler
+}
+
+func (h *moduleHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	return h.next.Enabled(ctx, l)
+}
+
+func (h *moduleHandler) Handle(ctx context.Context, r slog.Record) error {
+	if set := moduleLevels.Load(); set != nil {
+		minLevel := level.Level()
+		if l, ok := set.levels[moduleOf(r.PC)]; ok {
+			minLevel = l
+		}
+		if r.Level < minLevel {
+			return nil
+		}
+	}
+	return h.next.Handle(ctx, r)
+}
+
+func (h *moduleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &moduleHandler{next: h.next.WithAttrs(attrs)}
+}
+
+func (h *moduleHandler) WithGroup(name string) slog.Handler {
+	return &moduleHandler{next: h.next.WithGroup(name)}
+}
+
+// moduleOf returns the package directory of the function at pc, such as
+// "handlers", or "" if it is not in this module.
+func moduleOf(pc uintptr) string {
+	if pc == 0 {
+		return ""
+	}
+	if module, ok := modulesByPC.Load(pc); ok {
+		return module.(string)
+	}
+
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	module := ""
+	if rel := relativePath(frame.File); rel != frame.File {
+		if module = path.Dir(rel); module == "." {
+			module = ""
+		}
+	}
+	modulesByPC.Store(pc, module)
+	return module
+}
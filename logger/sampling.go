@@ -0,0 +1,103 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// sampler tracks how often each message has been seen in the current window.
+// It is shared by every handler derived from the same samplingHandler.
+type sampler struct {
+	first      int
+	every      int
+	window     time.Duration
+	mu         sync.Mutex
+	counters   map[string]*sampleCounter
+	suppressed atomic.Int64
+}
+
+type sampleCounter struct {
+	start      time.Time
+	seen       int
+	suppressed int
+}
+
+// samplingHandler rate limits identical WARN messages: within each window the
+// first entries for a given message are logged, then one in every. DEBUG,
+// INFO and ERROR records are never sampled. The next logged entry for a
+// message carries the number of entries suppressed before it.
+type samplingHandler struct {
+	next slog.Handler
+	s    *sampler
+}
+
+func newSamplingHandler(next slog.Handler, first, every int, window time.Duration) *samplingHandler {
+	return &samplingHandler{
+		next: next,
+		s: &sampler{
+			first:    first,
+			every:    every,
+			window:   window,
+			counters: make(map[string]*sampleCounter),
+		},
+	}
+}
+
+func (h *samplingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *samplingHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level < slog.LevelWarn || r.Level >= slog.LevelError {
+		return h.next.Handle(ctx, r)
+	}
+
+	keep, suppressed := h.s.sample(r.Message, r.Time)
+	if !keep {
+		return nil
+	}
+	if suppressed > 0 {
+		r.AddAttrs(slog.Int("suppressed", suppressed))
+	}
+	return h.next.Handle(ctx, r)
+}
+
+func (h *samplingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &samplingHandler{next: h.next.WithAttrs(attrs), s: h.s}
+}
+
+func (h *samplingHandler) WithGroup(name string) slog.Handler {
+	return &samplingHandler{next: h.next.WithGroup(name), s: h.s}
+}
+
+// sample reports whether an entry for key should be logged and, if so, how
+// many entries for the same key were suppressed since the last logged one.
+func (s *sampler) sample(key string, now time.Time) (bool, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.counters[key]
+	if !ok || now.Sub(c.start) >= s.window {
+		// Carry suppressed counts over so they are reported on the next entry.
+		carried := 0
+		if ok {
+			carried = c.suppressed
+		}
+		c = &sampleCounter{start: now, suppressed: carried}
+		s.counters[key] = c
+	}
+
+	c.seen++
+	if c.seen <= s.first || (c.seen-s.first)%s.every == 0 {
+		suppressed := c.suppressed
+		c.suppressed = 0
+		return true, suppressed
+	}
+
+	c.suppressed++
+	s.suppressed.Add(1)
+	return false, 0
+}
@@ -0,0 +1,93 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+// APIMailer sends messages through the v3 mail send API of SendGrid, or of
+// a provider compatible with it.
+type APIMailer struct {
+	url    string
+	key    string
+	from   mail.Address
+	client *http.Client
+}
+
+// NewAPIMailer sends from from through the API at baseURL, such as
+// https://api.sendgrid.com, authenticating with key.
+func NewAPIMailer(baseURL, key, from string) (*APIMailer, error) {
+	addr, err := mail.ParseAddress(from)
+	if err != nil {
+		return nil, fmt.Errorf("invalid from address %q: %w", from, err)
+	}
+	return &APIMailer{
+		url:    strings.TrimSuffix(baseURL, "/") + "/v3/mail/send",
+		key:    key,
+		from:   *addr,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+type apiAddress struct {
+	Email string `json:"email"`
+	Name  string `json:"name,omitempty"`
+}
+
+type apiContent struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type apiPersonalization struct {
+	To []apiAddress `json:"to"`
+}
+
+type apiRequest struct {
+	Personalizations []apiPersonalization `json:"personalizations"`
+	From             apiAddress           `json:"from"`
+	Subject          string               `json:"subject"`
+	Content          []apiContent         `json:"content"`
+}
+
+// Send implements Mailer.
+func (m *APIMailer) Send(ctx context.Context, msg Message) error {
+	req := apiRequest{
+		Personalizations: []apiPersonalization{{To: []apiAddress{{Email: msg.To}}}},
+		From:             apiAddress{Email: m.from.Address, Name: m.from.Name},
+		Subject:          msg.Subject,
+		Content:          []apiContent{{Type: "text/plain", Value: msg.Text}},
+	}
+	if msg.HTML != "" {
+		req.Content = append(req.Content, apiContent{Type: "text/html", Value: msg.HTML})
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, m.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Authorization", "Bearer "+m.key)
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := m.client.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("mail API: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("mail API: %s: %s", resp.Status, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
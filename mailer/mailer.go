@@ -0,0 +1,36 @@
+// Package mailer sends emails through SMTP or an HTTP provider API, built
+// from the templates under templates/.
+package mailer
+
+import (
+	"context"
+
+	"github.com/clementhaon/sandbox-api-go/logger"
+)
+
+// Message is an email with a plain text body and an optional HTML one.
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Mailer delivers messages.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// LogMailer logs messages instead of sending them. It is meant for
+// development, where no mail transport is configured.
+type LogMailer struct{}
+
+// Send implements Mailer.
+func (LogMailer) Send(ctx context.Context, msg Message) error {
+	logger.InfoContext(ctx, "Email not sent, logged instead", map[string]interface{}{
+		"to":      msg.To,
+		"subject": msg.Subject,
+		"text":    msg.Text,
+	})
+	return nil
+}
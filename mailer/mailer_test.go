@@ -0,0 +1,135 @@
+package mailer
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRender(t *testing.T) {
+	msg, err := Render("new@example.com", TemplateEmailChange, map[string]interface{}{
+		"Email":    "<b>new@example.com</b>",
+		"Token":    "abc123",
+		"ValidFor": "24 hours",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if msg.To != "new@example.com" || msg.Subject != "Confirm your new email address" {
+		t.Errorf("unexpected message %+v", msg)
+	}
+	if !strings.Contains(msg.Text, "abc123") || !strings.Contains(msg.HTML, "abc123") {
+		t.Error("expected the token in both bodies")
+	}
+	if !strings.Contains(msg.HTML, "&lt;b&gt;") {
+		t.Error("expected the HTML body to be escaped")
+	}
+
+	if _, err := Render("new@example.com", "missing", nil); err == nil {
+		t.Error("expected an error for an unknown template")
+	}
+}
+
+func TestRender_NewLogin(t *testing.T) {
+	msg, err := Render("john@example.com", TemplateNewLogin, map[string]interface{}{
+		"Username":  "johndoe",
+		"Time":      "2026-10-16 09:30 UTC",
+		"IPAddress": "203.0.113.7",
+		"Device":    "Mozilla/5.0 <script>",
+		"RevokeURL": "https://app.example.com/revoke?token=abc123",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if msg.Subject != "New login to your account" {
+		t.Errorf("unexpected subject %q", msg.Subject)
+	}
+	for _, body := range []string{msg.Text, msg.HTML} {
+		if !strings.Contains(body, "203.0.113.7") || !strings.Contains(body, "token=abc123") {
+			t.Errorf("expected the device and the revocation link in %q", body)
+		}
+	}
+	if strings.Contains(msg.HTML, "<script>") {
+		t.Error("expected the user agent to be escaped")
+	}
+}
+
+func TestBuildMIME(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	t.Run("plain text", func(t *testing.T) {
+		body, err := buildMIME("Sandbox <no-reply@example.com>", Message{To: "a@example.com", Subject: "Héllo", Text: "hi"}, now)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		s := string(body)
+		for _, want := range []string{
+			"To: a@example.com\r\n",
+			"Subject: =?utf-8?q?H=C3=A9llo?=\r\n",
+			"Content-Type: text/plain; charset=utf-8\r\n",
+			"@example.com>\r\n",
+		} {
+			if !strings.Contains(s, want) {
+				t.Errorf("expected %q in:\n%s", want, s)
+			}
+		}
+	})
+
+	t.Run("with HTML", func(t *testing.T) {
+		body, err := buildMIME("no-reply@example.com", Message{To: "a@example.com", Subject: "Hi", Text: "hi", HTML: "<p>hi</p>"}, now)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		s := string(body)
+		if !strings.Contains(s, "Content-Type: multipart/alternative; boundary=") ||
+			!strings.Contains(s, "text/html; charset=utf-8") {
+			t.Errorf("expected a multipart/alternative message:\n%s", s)
+		}
+	})
+
+	t.Run("rejects header injection", func(t *testing.T) {
+		if _, err := buildMIME("no-reply@example.com", Message{To: "a@example.com\r\nBcc: b@example.com"}, now); err == nil {
+			t.Error("expected an error")
+		}
+	})
+}
+
+func TestAPIMailer_Send(t *testing.T) {
+	var got apiRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v3/mail/send" || r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	m, err := NewAPIMailer(srv.URL+"/", "secret", "Sandbox <no-reply@example.com>")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err = m.Send(context.Background(), Message{To: "a@example.com", Subject: "Hi", Text: "hi", HTML: "<p>hi</p>"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.From.Email != "no-reply@example.com" || got.From.Name != "Sandbox" {
+		t.Errorf("unexpected from %+v", got.From)
+	}
+	if len(got.Personalizations) != 1 || got.Personalizations[0].To[0].Email != "a@example.com" {
+		t.Errorf("unexpected recipients %+v", got.Personalizations)
+	}
+	if len(got.Content) != 2 || got.Content[0].Type != "text/plain" {
+		t.Errorf("unexpected content %+v", got.Content)
+	}
+
+	bad, _ := NewAPIMailer(srv.URL, "wrong", "no-reply@example.com")
+	if err := bad.Send(context.Background(), Message{To: "a@example.com"}); err == nil {
+		t.Error("expected an error for a rejected request")
+	}
+}
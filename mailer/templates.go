@@ -0,0 +1,51 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	texttemplate "text/template"
+)
+
+//go:embed templates
+var templateFS embed.FS
+
+// Each email is a <name>.txt template, which also defines "<name>.subject",
+// and an optional <name>.html template.
+var (
+	textTemplates = texttemplate.Must(texttemplate.ParseFS(templateFS, "templates/*.txt"))
+	htmlTemplates = htmltemplate.Must(htmltemplate.ParseFS(templateFS, "templates/*.html"))
+)
+
+// Template names.
+const (
+	TemplateEmailChange = "email_change"
+	TemplateNewLogin    = "new_login"
+)
+
+// Render builds the message to send to to from template name.
+func Render(to, name string, data any) (Message, error) {
+	msg := Message{To: to}
+
+	var buf bytes.Buffer
+	if err := textTemplates.ExecuteTemplate(&buf, name+".subject", data); err != nil {
+		return Message{}, fmt.Errorf("render %s subject: %w", name, err)
+	}
+	msg.Subject = buf.String()
+
+	buf.Reset()
+	if err := textTemplates.ExecuteTemplate(&buf, name+".txt", data); err != nil {
+		return Message{}, fmt.Errorf("render %s text: %w", name, err)
+	}
+	msg.Text = buf.String()
+
+	if htmlTemplates.Lookup(name+".html") != nil {
+		buf.Reset()
+		if err := htmlTemplates.ExecuteTemplate(&buf, name+".html", data); err != nil {
+			return Message{}, fmt.Errorf("render %s html: %w", name, err)
+		}
+		msg.HTML = buf.String()
+	}
+	return msg, nil
+}
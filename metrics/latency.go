@@ -0,0 +1,96 @@
+package metrics
+
+import (
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Latency metrics are split by endpoint group: the first path segment of the
+// normalized endpoint ("/tasks/{id}" -> "tasks"). Groups can have their own
+// histogram buckets, e.g. wider ones for media uploads.
+
+// latencyCollector exposes all latency histograms as one metric family. It is
+// an unchecked collector (Describe sends nothing) because the registry refuses
+// descriptors of the same name that mix constant and variable "group" labels.
+type latencyCollector struct {
+	byDefault *prometheus.HistogramVec
+	groups    map[string]*prometheus.HistogramVec
+}
+
+// newLatencyCollector builds the http_request_duration_seconds histograms,
+// with optional per-group bucket overrides. Empty defaultBuckets means
+// prometheus.DefBuckets.
+func newLatencyCollector(defaultBuckets []float64, groupBuckets map[string][]float64) *latencyCollector {
+	if len(defaultBuckets) == 0 {
+		defaultBuckets = prometheus.DefBuckets
+	}
+
+	c := &latencyCollector{
+		byDefault: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "http_request_duration_seconds",
+				Help:    "HTTP request duration in seconds",
+				Buckets: defaultBuckets,
+			},
+			[]string{"group", "method", "endpoint", "status_code"},
+		),
+		groups: make(map[string]*prometheus.HistogramVec, len(groupBuckets)),
+	}
+
+	// Groups with custom buckets get their own vector; the group is a constant
+	// label so the series stay under the same metric name.
+	for group, buckets := range groupBuckets {
+		c.groups[group] = prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:        "http_request_duration_seconds",
+				Help:        "HTTP request duration in seconds",
+				Buckets:     buckets,
+				ConstLabels: prometheus.Labels{"group": group},
+			},
+			[]string{"method", "endpoint", "status_code"},
+		)
+	}
+	return c
+}
+
+func (c *latencyCollector) Describe(chan<- *prometheus.Desc) {}
+
+func (c *latencyCollector) Collect(ch chan<- prometheus.Metric) {
+	c.byDefault.Collect(ch)
+	for _, vec := range c.groups {
+		vec.Collect(ch)
+	}
+}
+
+func (m *Metrics) observeLatency(method, endpoint, status string, duration time.Duration, requestID string) {
+	group := EndpointGroup(endpoint)
+	var observer prometheus.Observer
+	if h, ok := m.latency.groups[group]; ok {
+		observer = h.WithLabelValues(method, endpoint, status)
+	} else {
+		observer = m.latency.byDefault.WithLabelValues(group, method, endpoint, status)
+	}
+
+	seconds := duration.Seconds()
+	if eo, ok := observer.(prometheus.ExemplarObserver); ok && requestID != "" {
+		eo.ObserveWithExemplar(seconds, prometheus.Labels{"request_id": requestID})
+	} else {
+		observer.Observe(seconds)
+	}
+
+	m.httpRequestQuantiles.WithLabelValues(group).Observe(seconds)
+}
+
+// EndpointGroup returns the first segment of a normalized endpoint ("root" for "/").
+func EndpointGroup(endpoint string) string {
+	trimmed := strings.TrimPrefix(endpoint, "/")
+	if i := strings.IndexByte(trimmed, '/'); i >= 0 {
+		trimmed = trimmed[:i]
+	}
+	if trimmed == "" {
+		return "root"
+	}
+	return trimmed
+}
@@ -1,148 +1,541 @@
 package metrics
 
 import (
+	"context"
+	"fmt"
 	"strconv"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promauto"
+	dto "github.com/prometheus/client_model/go"
 )
 
-// Prometheus metrics
-var (
+// Registry is where a Metrics registers its collectors, and what /metrics
+// serves. *prometheus.Registry is one.
+type Registry interface {
+	prometheus.Registerer
+	prometheus.Gatherer
+}
+
+// DefaultRegistry returns the default Prometheus registry
+func DefaultRegistry() Registry {
+	return prometheus.DefaultRegisterer.(*prometheus.Registry)
+}
+
+// Options adjust the metrics of New.
+type Options struct {
+	// LatencyBuckets are the http_request_duration_seconds buckets. Empty
+	// means prometheus.DefBuckets.
+	LatencyBuckets []float64
+	// LatencyGroupBuckets override LatencyBuckets for endpoint groups.
+	LatencyGroupBuckets map[string][]float64
+}
+
+// Metrics holds the Prometheus metrics of one server. Its methods do nothing
+// on a nil *Metrics, so code can record metrics without checking whether
+// any were set up.
+type Metrics struct {
+	registry Registry
+
 	// HTTP request metrics
-	httpRequestsTotal = promauto.NewCounterVec(
-		prometheus.CounterOpts{
-			Name: "http_requests_total",
-			Help: "Total number of HTTP requests",
-		},
-		[]string{"method", "endpoint", "status_code"},
-	)
+	httpRequestsTotal      *prometheus.CounterVec
+	httpResponseSize       *prometheus.HistogramVec
+	httpRequestsInFlight   prometheus.Gauge
+	httpRequestsByProtocol *prometheus.CounterVec
+	httpConnectionsOpen    prometheus.Gauge
+	latency                *latencyCollector
+	httpRequestQuantiles   *prometheus.SummaryVec
 
-	httpRequestDuration = promauto.NewHistogramVec(
-		prometheus.HistogramOpts{
-			Name:    "http_request_duration_seconds",
-			Help:    "HTTP request duration in seconds",
-			Buckets: prometheus.DefBuckets,
-		},
-		[]string{"method", "endpoint", "status_code"},
-	)
+	// Response cache metrics
+	responseCacheRequestsTotal *prometheus.CounterVec
+	responseCacheEntries       prometheus.Gauge
 
 	// Database metrics
-	dbOperationsTotal = promauto.NewCounterVec(
-		prometheus.CounterOpts{
-			Name: "database_operations_total",
-			Help: "Total number of database operations",
-		},
-		[]string{"operation", "table", "status"},
-	)
-
-	dbOperationDuration = promauto.NewHistogramVec(
-		prometheus.HistogramOpts{
-			Name:    "database_operation_duration_seconds",
-			Help:    "Database operation duration in seconds",
-			Buckets: []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
-		},
-		[]string{"operation", "table"},
-	)
+	dbOperationsTotal       *prometheus.CounterVec
+	dbOperationDuration     *prometheus.HistogramVec
+	dbRetriesTotal          *prometheus.CounterVec
+	dbRetriesExhaustedTotal *prometheus.CounterVec
+	dbHostUp                *prometheus.GaugeVec
+	dbFailoversTotal        prometheus.Counter
 
 	// Authentication metrics
-	authAttemptsTotal = promauto.NewCounterVec(
-		prometheus.CounterOpts{
-			Name: "auth_attempts_total",
-			Help: "Total number of authentication attempts",
-		},
-		[]string{"type", "status"},
-	)
+	authAttemptsTotal     *prometheus.CounterVec
+	authFailuresTotal     *prometheus.CounterVec
+	tokenValidationsTotal *prometheus.CounterVec
+	passwordHashDuration  *prometheus.HistogramVec
 
 	// Error metrics
-	errorsTotal = promauto.NewCounterVec(
-		prometheus.CounterOpts{
-			Name: "errors_total",
-			Help: "Total number of errors by type and code",
-		},
-		[]string{"error_type", "error_code"},
-	)
+	errorsTotal *prometheus.CounterVec
 
 	// Application metrics
-	activeUsers = promauto.NewGauge(
-		prometheus.GaugeOpts{
-			Name: "active_users_current",
-			Help: "Current number of active users",
-		},
-	)
+	activeUsers prometheus.Gauge
+	tasksTotal  *prometheus.GaugeVec
+}
 
-	tasksTotal = promauto.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "tasks_total",
-			Help: "Total number of tasks by status",
-		},
-		[]string{"status"},
-	)
+// New builds the metrics of a server and registers them on reg. Servers
+// sharing a registry would share their counters, so registering twice on
+// the same one fails.
+func New(reg Registry, opts Options) (*Metrics, error) {
+	m := &Metrics{
+		registry: reg,
 
-	// System metrics
-	goVersion = promauto.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "go_version_info",
-			Help: "Go version information",
-		},
-		[]string{"version"},
-	)
+		httpRequestsTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "http_requests_total",
+				Help: "Total number of HTTP requests",
+			},
+			[]string{"method", "endpoint", "status_code"},
+		),
+		httpResponseSize: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "http_response_size_bytes",
+				Help:    "HTTP response body size in bytes",
+				Buckets: prometheus.ExponentialBuckets(100, 10, 7), // 100B to 100MB
+			},
+			[]string{"method", "endpoint"},
+		),
+		httpRequestsInFlight: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "http_requests_in_flight",
+				Help: "Number of HTTP requests currently being served",
+			},
+		),
+		httpRequestsByProtocol: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "http_requests_by_protocol_total",
+				Help: "Total number of HTTP requests by protocol version",
+			},
+			[]string{"protocol"},
+		),
+		httpConnectionsOpen: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "http_connections_open",
+				Help: "Number of open client connections, idle keep-alive ones included",
+			},
+		),
+		latency: newLatencyCollector(opts.LatencyBuckets, opts.LatencyGroupBuckets),
+		// Quantiles for SLO alerting, computed per group over a sliding window
+		httpRequestQuantiles: prometheus.NewSummaryVec(
+			prometheus.SummaryOpts{
+				Name:       "http_request_duration_quantiles_seconds",
+				Help:       "HTTP request duration quantiles (p50, p95, p99) per endpoint group",
+				Objectives: map[float64]float64{0.5: 0.05, 0.95: 0.01, 0.99: 0.001},
+				MaxAge:     5 * time.Minute,
+			},
+			[]string{"group"},
+		),
 
-	appInfo = promauto.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "app_info",
-			Help: "Application information",
-		},
-		[]string{"version", "commit", "build_date"},
-	)
-)
+		responseCacheRequestsTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "response_cache_requests_total",
+				Help: "Total number of cacheable requests by result (hit, miss)",
+			},
+			[]string{"result"},
+		),
+		responseCacheEntries: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "response_cache_entries",
+				Help: "Number of responses held in the in-memory cache",
+			},
+		),
+
+		dbOperationsTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "database_operations_total",
+				Help: "Total number of database operations",
+			},
+			[]string{"operation", "table", "status"},
+		),
+		dbOperationDuration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "database_operation_duration_seconds",
+				Help:    "Database operation duration in seconds",
+				Buckets: []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+			},
+			[]string{"operation", "table"},
+		),
+		dbRetriesTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "database_retries_total",
+				Help: "Total number of database operations retried after a transient error",
+			},
+			[]string{"operation"},
+		),
+		dbRetriesExhaustedTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "database_retries_exhausted_total",
+				Help: "Total number of database operations that still failed with a transient error after the last attempt",
+			},
+			[]string{"operation"},
+		),
+		dbHostUp: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "database_host_up",
+				Help: "Whether a database host passed its last health check (1) or not (0)",
+			},
+			[]string{"host"},
+		),
+		dbFailoversTotal: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: "database_failovers_total",
+				Help: "Total number of switches from one database host to another",
+			},
+		),
+
+		authAttemptsTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "auth_attempts_total",
+				Help: "Total number of authentication attempts",
+			},
+			[]string{"type", "status"},
+		),
+		authFailuresTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "auth_failures_total",
+				Help: "Total number of refused logins and tokens by reason",
+			},
+			[]string{"type", "reason"},
+		),
+		tokenValidationsTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "jwt_validations_total",
+				Help: "Total number of JWT validations by outcome: valid, or the reason the token was refused",
+			},
+			[]string{"outcome"},
+		),
+		passwordHashDuration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "password_hash_duration_seconds",
+				Help:    "Duration of bcrypt password hashes and comparisons in seconds, by bcrypt cost",
+				Buckets: []float64{0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5},
+			},
+			[]string{"operation", "cost"},
+		),
+
+		errorsTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "errors_total",
+				Help: "Total number of errors by type and code",
+			},
+			[]string{"error_type", "error_code"},
+		),
+
+		activeUsers: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "active_users_current",
+				Help: "Current number of active users",
+			},
+		),
+		tasksTotal: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "tasks_total",
+				Help: "Total number of tasks by status",
+			},
+			[]string{"status"},
+		),
+	}
+
+	collectors := []prometheus.Collector{
+		m.httpRequestsTotal, m.httpResponseSize, m.httpRequestsInFlight, m.httpRequestsByProtocol,
+		m.httpConnectionsOpen, m.latency, m.httpRequestQuantiles,
+		m.responseCacheRequestsTotal, m.responseCacheEntries,
+		m.dbOperationsTotal, m.dbOperationDuration, m.dbRetriesTotal, m.dbRetriesExhaustedTotal,
+		m.dbHostUp, m.dbFailoversTotal,
+		m.authAttemptsTotal, m.authFailuresTotal, m.tokenValidationsTotal, m.passwordHashDuration,
+		m.errorsTotal,
+		m.activeUsers, m.tasksTotal,
+	}
+	for _, c := range collectors {
+		if err := reg.Register(c); err != nil {
+			return nil, fmt.Errorf("register metrics: %w", err)
+		}
+	}
+	return m, nil
+}
+
+// Registry returns the registry the metrics are registered on.
+func (m *Metrics) Registry() Registry {
+	if m == nil {
+		return nil
+	}
+	return m.registry
+}
 
-// RecordHTTPRequest records an HTTP request metric
-func RecordHTTPRequest(method, endpoint string, statusCode int, duration time.Duration) {
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying m. Servers use it to give the
+// requests they serve their own metrics.
+func NewContext(ctx context.Context, m *Metrics) context.Context {
+	return context.WithValue(ctx, contextKey{}, m)
+}
+
+// FromContext returns the metrics of ctx, or nil if none were set.
+func FromContext(ctx context.Context) *Metrics {
+	m, _ := ctx.Value(contextKey{}).(*Metrics)
+	return m
+}
+
+// RecordHTTPRequest records an HTTP request metric. A non-empty requestID is
+// attached as an exemplar so slow buckets can be traced back to request logs.
+func (m *Metrics) RecordHTTPRequest(method, endpoint string, statusCode int, duration time.Duration, requestID string) {
+	if m == nil {
+		return
+	}
 	status := strconv.Itoa(statusCode)
-	httpRequestsTotal.WithLabelValues(method, endpoint, status).Inc()
-	httpRequestDuration.WithLabelValues(method, endpoint, status).Observe(duration.Seconds())
+	m.httpRequestsTotal.WithLabelValues(method, endpoint, status).Inc()
+	m.observeLatency(method, endpoint, status, duration, requestID)
+}
+
+// HTTPRequestCounts returns the number of HTTP requests recorded since the
+// server started, and how many of them ended in a 4xx or a 5xx
+func (m *Metrics) HTTPRequestCounts() (total, clientErrors, serverErrors int) {
+	if m == nil {
+		return 0, 0, 0
+	}
+	ch := make(chan prometheus.Metric)
+	go func() {
+		m.httpRequestsTotal.Collect(ch)
+		close(ch)
+	}()
+	for metric := range ch {
+		var pb dto.Metric
+		if err := metric.Write(&pb); err != nil {
+			continue
+		}
+		n := int(pb.GetCounter().GetValue())
+		total += n
+		for _, lp := range pb.GetLabel() {
+			if lp.GetName() != "status_code" || lp.GetValue() == "" {
+				continue
+			}
+			switch lp.GetValue()[0] {
+			case '4':
+				clientErrors += n
+			case '5':
+				serverErrors += n
+			}
+		}
+	}
+	return total, clientErrors, serverErrors
+}
+
+// RecordHTTPResponseSize records the size of a response body
+func (m *Metrics) RecordHTTPResponseSize(method, endpoint string, size int) {
+	if m == nil {
+		return
+	}
+	m.httpResponseSize.WithLabelValues(method, endpoint).Observe(float64(size))
+}
+
+// IncRequestsInFlight marks the start of a request
+func (m *Metrics) IncRequestsInFlight() {
+	if m == nil {
+		return
+	}
+	m.httpRequestsInFlight.Inc()
+}
+
+// DecRequestsInFlight marks the end of a request
+func (m *Metrics) DecRequestsInFlight() {
+	if m == nil {
+		return
+	}
+	m.httpRequestsInFlight.Dec()
+}
+
+// RecordHTTPProtocol counts a request by protocol version ("HTTP/1.1", "HTTP/2.0")
+func (m *Metrics) RecordHTTPProtocol(proto string) {
+	if m == nil {
+		return
+	}
+	m.httpRequestsByProtocol.WithLabelValues(proto).Inc()
+}
+
+// IncConnectionsOpen marks a new client connection
+func (m *Metrics) IncConnectionsOpen() {
+	if m == nil {
+		return
+	}
+	m.httpConnectionsOpen.Inc()
+}
+
+// DecConnectionsOpen marks a closed or hijacked client connection
+func (m *Metrics) DecConnectionsOpen() {
+	if m == nil {
+		return
+	}
+	m.httpConnectionsOpen.Dec()
+}
+
+// RecordResponseCache counts a cacheable request as a "hit" or a "miss"
+func (m *Metrics) RecordResponseCache(result string) {
+	if m == nil {
+		return
+	}
+	m.responseCacheRequestsTotal.WithLabelValues(result).Inc()
+}
+
+// SetResponseCacheEntries sets the number of cached responses
+func (m *Metrics) SetResponseCacheEntries(count int) {
+	if m == nil {
+		return
+	}
+	m.responseCacheEntries.Set(float64(count))
 }
 
 // RecordDatabaseOperation records a database operation metric
-func RecordDatabaseOperation(operation, table string, duration time.Duration, err error) {
+func (m *Metrics) RecordDatabaseOperation(operation, table string, duration time.Duration, err error) {
+	if m == nil {
+		return
+	}
 	status := "success"
 	if err != nil {
 		status = "error"
 	}
 
-	dbOperationsTotal.WithLabelValues(operation, table, status).Inc()
-	dbOperationDuration.WithLabelValues(operation, table).Observe(duration.Seconds())
+	m.dbOperationsTotal.WithLabelValues(operation, table, status).Inc()
+	m.dbOperationDuration.WithLabelValues(operation, table).Observe(duration.Seconds())
+}
+
+// RecordDatabaseRetry records a retry of a database operation
+func (m *Metrics) RecordDatabaseRetry(operation string) {
+	if m == nil {
+		return
+	}
+	m.dbRetriesTotal.WithLabelValues(operation).Inc()
+}
+
+// RecordDatabaseRetryExhausted records a database operation given up after
+// its last attempt
+func (m *Metrics) RecordDatabaseRetryExhausted(operation string) {
+	if m == nil {
+		return
+	}
+	m.dbRetriesExhaustedTotal.WithLabelValues(operation).Inc()
+}
+
+// SetDatabaseHostUp records the result of a database host health check
+func (m *Metrics) SetDatabaseHostUp(host string, up bool) {
+	if m == nil {
+		return
+	}
+	v := 0.0
+	if up {
+		v = 1
+	}
+	m.dbHostUp.WithLabelValues(host).Set(v)
+}
+
+// RecordDatabaseFailover records a switch to another database host
+func (m *Metrics) RecordDatabaseFailover() {
+	if m == nil {
+		return
+	}
+	m.dbFailoversTotal.Inc()
 }
 
 // RecordAuthAttempt records an authentication attempt
-func RecordAuthAttempt(authType, status string) {
-	authAttemptsTotal.WithLabelValues(authType, status).Inc()
+func (m *Metrics) RecordAuthAttempt(authType, status string) {
+	if m == nil {
+		return
+	}
+	m.authAttemptsTotal.WithLabelValues(authType, status).Inc()
+}
+
+// RecordAuthFailure records a refused authentication: a login
+// (unknown_email, invalid_password) or a token (missing_token,
+// expired_token...).
+func (m *Metrics) RecordAuthFailure(authType, reason string) {
+	if m == nil {
+		return
+	}
+	m.authFailuresTotal.WithLabelValues(authType, reason).Inc()
+}
+
+// RecordTokenValidation records the outcome of the validation of a
+// presented JWT: valid, or the reason it was refused.
+func (m *Metrics) RecordTokenValidation(outcome string) {
+	if m == nil {
+		return
+	}
+	m.tokenValidationsTotal.WithLabelValues(outcome).Inc()
+}
+
+// RecordPasswordHash records how long a password hash or comparison
+// (operation) took at a bcrypt cost.
+func (m *Metrics) RecordPasswordHash(operation string, cost int, duration time.Duration) {
+	if m == nil {
+		return
+	}
+	m.passwordHashDuration.WithLabelValues(operation, strconv.Itoa(cost)).Observe(duration.Seconds())
 }
 
 // RecordError records an error occurrence
-func RecordError(errorType, errorCode string) {
-	errorsTotal.WithLabelValues(errorType, errorCode).Inc()
+func (m *Metrics) RecordError(errorType, errorCode string) {
+	if m == nil {
+		return
+	}
+	m.errorsTotal.WithLabelValues(errorType, errorCode).Inc()
 }
 
 // SetActiveUsers sets the current number of active users
-func SetActiveUsers(count float64) {
-	activeUsers.Set(count)
+func (m *Metrics) SetActiveUsers(count float64) {
+	if m == nil {
+		return
+	}
+	m.activeUsers.Set(count)
 }
 
 // SetTasksCount sets the total number of tasks by status
-func SetTasksCount(status string, count float64) {
-	tasksTotal.WithLabelValues(status).Set(count)
+func (m *Metrics) SetTasksCount(status string, count float64) {
+	if m == nil {
+		return
+	}
+	m.tasksTotal.WithLabelValues(status).Set(count)
 }
 
-// InitAppInfo initializes application information metrics
-func InitAppInfo(version, commit, buildDate, goVersionStr string) {
+// RegisterAppInfo exposes the version of the application and of Go on reg.
+// They describe the process, so only main registers them.
+func RegisterAppInfo(reg prometheus.Registerer, version, commit, buildDate, goVersionStr string) {
+	appInfo := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "app_info",
+			Help: "Application information",
+		},
+		[]string{"version", "commit", "build_date"},
+	)
+	goVersion := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "go_version_info",
+			Help: "Go version information",
+		},
+		[]string{"version"},
+	)
+	reg.MustRegister(appInfo, goVersion)
 	appInfo.WithLabelValues(version, commit, buildDate).Set(1)
 	goVersion.WithLabelValues(goVersionStr).Set(1)
 }
 
-// GetRegistry returns the default Prometheus registry
-func GetRegistry() *prometheus.Registry {
-	return prometheus.DefaultRegisterer.(*prometheus.Registry)
+// RegisterLogSuppressed exposes on reg the number of log entries dropped by
+// sampling
+func RegisterLogSuppressed(reg prometheus.Registerer, suppressed func() float64) {
+	reg.MustRegister(prometheus.NewCounterFunc(
+		prometheus.CounterOpts{
+			Name: "log_entries_suppressed_total",
+			Help: "Total number of repeated log entries suppressed by sampling",
+		},
+		suppressed,
+	))
+}
+
+// RegisterSlowQueries exposes on reg the number of database operations that
+// reached the slow query threshold
+func RegisterSlowQueries(reg prometheus.Registerer, slow func() float64) {
+	reg.MustRegister(prometheus.NewCounterFunc(
+		prometheus.CounterOpts{
+			Name: "database_slow_queries_total",
+			Help: "Total number of database operations slower than the slow query threshold",
+		},
+		slow,
+	))
 }
@@ -0,0 +1,139 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// newTestMetrics returns metrics registered on a registry of their own.
+func newTestMetrics(t *testing.T, opts Options) *Metrics {
+	t.Helper()
+	m, err := New(prometheus.NewRegistry(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestEndpointGroup(t *testing.T) {
+	tests := map[string]string{
+		"/":                 "root",
+		"/tasks":            "tasks",
+		"/tasks/{id}/move":  "tasks",
+		"/admin/audit-logs": "admin",
+		"":                  "root",
+	}
+	for endpoint, want := range tests {
+		if got := EndpointGroup(endpoint); got != want {
+			t.Errorf("EndpointGroup(%q) = %q, want %q", endpoint, got, want)
+		}
+	}
+}
+
+// latencyBuckets returns the number of latency buckets of each group
+// observed on m, and the request IDs of their exemplars.
+func latencyBuckets(t *testing.T, m *Metrics) (map[string]int, map[string]bool) {
+	t.Helper()
+	families, err := m.Registry().Gather()
+	if err != nil {
+		t.Fatalf("gather failed: %v", err)
+	}
+
+	buckets := map[string]int{}
+	exemplars := map[string]bool{}
+	for _, mf := range families {
+		if mf.GetName() != "http_request_duration_seconds" {
+			continue
+		}
+		for _, metric := range mf.GetMetric() {
+			var group string
+			for _, lp := range metric.GetLabel() {
+				if lp.GetName() == "group" {
+					group = lp.GetValue()
+				}
+			}
+			buckets[group] = len(metric.GetHistogram().GetBucket())
+			for _, b := range metric.GetHistogram().GetBucket() {
+				if ex := b.GetExemplar(); ex != nil {
+					exemplars[ex.GetLabel()[0].GetValue()] = true
+				}
+			}
+		}
+	}
+	return buckets, exemplars
+}
+
+func TestObserveLatency_GroupBucketsAndExemplars(t *testing.T) {
+	m := newTestMetrics(t, Options{
+		LatencyBuckets:      []float64{0.1, 1},
+		LatencyGroupBuckets: map[string][]float64{"media": {1, 10, 60}},
+	})
+
+	m.RecordHTTPRequest("GET", "/tasks/{id}", 200, 50*time.Millisecond, "req-tasks")
+	m.RecordHTTPRequest("POST", "/media/upload", 201, 5*time.Second, "req-media")
+
+	buckets, exemplars := latencyBuckets(t, m)
+	if buckets["tasks"] != 2 || buckets["media"] != 3 {
+		t.Errorf("expected 2 default and 3 media buckets, got %v", buckets)
+	}
+	if !exemplars["req-tasks"] || !exemplars["req-media"] {
+		t.Errorf("expected request ID exemplars, got %v", exemplars)
+	}
+}
+
+func TestHTTPRequestCounts(t *testing.T) {
+	m := newTestMetrics(t, Options{})
+
+	m.RecordHTTPRequest("GET", "/stats-test", 200, time.Millisecond, "")
+	m.RecordHTTPRequest("GET", "/stats-test", 404, time.Millisecond, "")
+	m.RecordHTTPRequest("POST", "/stats-test", 422, time.Millisecond, "")
+	m.RecordHTTPRequest("GET", "/stats-test", 503, time.Millisecond, "")
+
+	total, clientErrors, serverErrors := m.HTTPRequestCounts()
+	if total != 4 || clientErrors != 2 || serverErrors != 1 {
+		t.Errorf("expected 4 requests, 2 client and 1 server errors, got %d, %d and %d",
+			total, clientErrors, serverErrors)
+	}
+}
+
+func TestNew_Independent(t *testing.T) {
+	a := newTestMetrics(t, Options{LatencyBuckets: []float64{0.1, 1}})
+	b := newTestMetrics(t, Options{LatencyBuckets: []float64{0.1, 1, 10}})
+
+	a.RecordHTTPRequest("GET", "/tasks", 200, time.Millisecond, "")
+
+	if total, _, _ := a.HTTPRequestCounts(); total != 1 {
+		t.Errorf("expected 1 request on the first metrics, got %d", total)
+	}
+	if total, _, _ := b.HTTPRequestCounts(); total != 0 {
+		t.Errorf("expected no request on the second metrics, got %d", total)
+	}
+
+	b.RecordHTTPRequest("GET", "/tasks", 200, time.Millisecond, "")
+	if buckets, _ := latencyBuckets(t, b); buckets["tasks"] != 3 {
+		t.Errorf("expected the second metrics to keep their 3 buckets, got %v", buckets)
+	}
+}
+
+func TestNew_SameRegistry(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	if _, err := New(reg, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := New(reg, Options{}); err == nil {
+		t.Error("expected an error registering metrics twice on a registry")
+	}
+}
+
+func TestMetrics_Nil(t *testing.T) {
+	var m *Metrics
+	m.RecordHTTPRequest("GET", "/tasks", 200, time.Millisecond, "")
+	if total, _, _ := m.HTTPRequestCounts(); total != 0 {
+		t.Errorf("expected no request on nil metrics, got %d", total)
+	}
+	if FromContext(t.Context()) != nil {
+		t.Error("expected no metrics in an empty context")
+	}
+}
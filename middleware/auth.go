@@ -2,20 +2,36 @@ package middleware
 
 import (
 	"context"
+	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/clementhaon/sandbox-api-go/auth"
 	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/errtrack"
 	"github.com/clementhaon/sandbox-api-go/logger"
+	"github.com/clementhaon/sandbox-api-go/metrics"
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/replay"
+	"github.com/clementhaon/sandbox-api-go/tenant"
 )
 
 type contextKey string
 
 const UserContextKey contextKey = "user"
 
+// PersonalTokenAuthenticator resolves the personal access tokens users
+// create, which start with auth.PersonalTokenPrefix.
+type PersonalTokenAuthenticator interface {
+	// Authenticate returns the claims of the user of token, or an
+	// INVALID_TOKEN or TOKEN_EXPIRED error refusing it
+	Authenticate(ctx context.Context, token string) (*models.Claims, error)
+}
+
 // NewAuthMiddleware returns an AuthMiddleware function that uses the given JWTManager and TokenBlacklist.
-func NewAuthMiddleware(jwtManager *auth.JWTManager, blacklist *auth.TokenBlacklist) func(ErrorHandler) http.HandlerFunc {
+// Personal access tokens are checked by personalTokens, or refused when it is nil.
+func NewAuthMiddleware(jwtManager *auth.JWTManager, blacklist *auth.TokenBlacklist, personalTokens PersonalTokenAuthenticator) func(ErrorHandler) http.HandlerFunc {
 	return func(handler ErrorHandler) http.HandlerFunc {
 		return ErrorMiddleware(func(w http.ResponseWriter, r *http.Request) error {
 			var token string
@@ -28,6 +44,7 @@ func NewAuthMiddleware(jwtManager *auth.JWTManager, blacklist *auth.TokenBlackli
 				authHeader := r.Header.Get("Authorization")
 				if authHeader == "" {
 					logger.WarnContext(r.Context(), "Authentication attempt without token")
+					metrics.FromContext(r.Context()).RecordAuthFailure("token", auth.ReasonMissingToken)
 					return errors.NewAuthRequiredError().WithDetails(map[string]interface{}{
 						"message": "Token required in cookie or Authorization header",
 					})
@@ -37,6 +54,7 @@ func NewAuthMiddleware(jwtManager *auth.JWTManager, blacklist *auth.TokenBlackli
 				tokenParts := strings.Split(authHeader, " ")
 				if len(tokenParts) != 2 || tokenParts[0] != "Bearer" {
 					logger.WarnContext(r.Context(), "Invalid token format in Authorization header")
+					refuseToken(r, auth.ReasonMalformedToken)
 					return errors.NewInvalidTokenError().WithDetails(map[string]interface{}{
 						"expected_format": "Bearer <token>",
 					})
@@ -44,10 +62,22 @@ func NewAuthMiddleware(jwtManager *auth.JWTManager, blacklist *auth.TokenBlackli
 				token = tokenParts[1]
 			}
 
-			// Check if token has been revoked
-			if blacklist != nil && blacklist.IsBlacklisted(token) {
-				logger.WarnContext(r.Context(), "Revoked token used")
-				return errors.NewInvalidTokenError()
+			if strings.HasPrefix(token, auth.PersonalTokenPrefix) {
+				return authenticatePersonalToken(w, r, personalTokens, token, handler)
+			}
+
+			// Check if token has been revoked; without the answer the
+			// request is refused rather than risk honoring a revoked token
+			if blacklist != nil {
+				revoked, err := blacklist.IsBlacklisted(r.Context(), token)
+				if err != nil {
+					return errors.NewServiceUnavailableError().WithCause(err)
+				}
+				if revoked {
+					logger.WarnContext(r.Context(), "Revoked token used")
+					refuseToken(r, auth.ReasonRevokedToken)
+					return errors.NewInvalidTokenError()
+				}
 			}
 
 			// Validate the token
@@ -56,14 +86,149 @@ func NewAuthMiddleware(jwtManager *auth.JWTManager, blacklist *auth.TokenBlackli
 				logger.WarnContext(r.Context(), "Invalid or expired token", map[string]interface{}{
 					"error": err.Error(),
 				})
+				refuseToken(r, auth.FailureReason(err))
 				return errors.NewInvalidTokenError().WithCause(err)
 			}
 
-			// Add user information to context
-			ctx := context.WithValue(r.Context(), UserContextKey, claims)
-			ctx = context.WithValue(ctx, logger.UserIDKey, claims.UserID)
+			// A token only grants access to the tenant it was issued for
+			if claims.TenantID != tenant.FromContext(r.Context()) {
+				logger.WarnContext(r.Context(), "Token used outside its tenant", map[string]interface{}{
+					"token_tenant_id": claims.TenantID,
+				})
+				refuseToken(r, auth.ReasonWrongTenant)
+				return errors.NewInvalidTokenError()
+			}
 
-			return handler(w, r.WithContext(ctx))
+			metrics.FromContext(r.Context()).RecordTokenValidation("valid")
+			return handler(w, withClaims(w, r, claims))
 		})
 	}
 }
+
+// authenticatePersonalToken serves r, made with a personal access token,
+// as the token's user.
+func authenticatePersonalToken(w http.ResponseWriter, r *http.Request, personalTokens PersonalTokenAuthenticator, token string, handler ErrorHandler) error {
+	if personalTokens == nil {
+		refuseToken(r, auth.ReasonInvalidToken)
+		return errors.NewInvalidTokenError()
+	}
+	claims, err := personalTokens.Authenticate(r.Context(), token)
+	switch {
+	case errors.Is(err, errors.ErrTokenExpired):
+		logger.WarnContext(r.Context(), "Expired personal access token")
+		refuseToken(r, auth.ReasonExpiredToken)
+		return err
+	case errors.Is(err, errors.ErrInvalidToken):
+		logger.WarnContext(r.Context(), "Unknown personal access token")
+		refuseToken(r, auth.ReasonInvalidToken)
+		return err
+	case err != nil:
+		return err
+	}
+
+	metrics.FromContext(r.Context()).RecordTokenValidation("valid")
+	return handler(w, withClaims(w, r, claims))
+}
+
+// withClaims returns r with the user information of claims in its context.
+func withClaims(w http.ResponseWriter, r *http.Request, claims *models.Claims) *http.Request {
+	ctx := context.WithValue(r.Context(), UserContextKey, claims)
+	ctx = context.WithValue(ctx, logger.UserIDKey, claims.UserID)
+	ctx = logger.With(ctx, "user_id", claims.UserID)
+	errtrack.SetUser(ctx, claims.UserID)
+	replay.SetUser(ctx, claims)
+
+	// Requests made while impersonating are flagged in logs, audit
+	// entries and the response
+	if claims.ImpersonatorID != 0 {
+		ctx = context.WithValue(ctx, logger.ImpersonatorIDKey, claims.ImpersonatorID)
+		ctx = logger.With(ctx, "impersonator_id", claims.ImpersonatorID)
+		w.Header().Set("X-Impersonated-By", strconv.Itoa(claims.ImpersonatorID))
+	}
+	return r.WithContext(ctx)
+}
+
+// refuseToken records a token refused for reason.
+func refuseToken(r *http.Request, reason string) {
+	m := metrics.FromContext(r.Context())
+	m.RecordTokenValidation(reason)
+	m.RecordAuthFailure("token", reason)
+}
+
+// RequireRole returns a decorator that only lets users with one of the given roles through.
+// It must be used inside an authenticated handler chain.
+func RequireRole(roles ...string) func(ErrorHandler) ErrorHandler {
+	return func(handler ErrorHandler) ErrorHandler {
+		return func(w http.ResponseWriter, r *http.Request) error {
+			claims, ok := r.Context().Value(UserContextKey).(*models.Claims)
+			if !ok {
+				return errors.NewAuthRequiredError()
+			}
+
+			for _, role := range roles {
+				if claims.Role == role {
+					return handler(w, r)
+				}
+			}
+
+			logger.WarnContext(r.Context(), "Access denied: insufficient role", map[string]interface{}{
+				"role":           claims.Role,
+				"required_roles": roles,
+			})
+			return errors.NewForbiddenError()
+		}
+	}
+}
+
+// RequireScope returns a decorator that only lets through tokens allowed one
+// of the given scopes. Tokens without scopes, such as the ones of a login,
+// are not restricted; with no scopes given, every restricted token is
+// refused. It must be used inside an authenticated handler chain.
+func RequireScope(scopes ...string) func(ErrorHandler) ErrorHandler {
+	return func(handler ErrorHandler) ErrorHandler {
+		return func(w http.ResponseWriter, r *http.Request) error {
+			claims, ok := r.Context().Value(UserContextKey).(*models.Claims)
+			if !ok {
+				return errors.NewAuthRequiredError()
+			}
+			if claims.Scopes == nil {
+				return handler(w, r)
+			}
+			for _, scope := range scopes {
+				if claims.HasScope(scope) {
+					return handler(w, r)
+				}
+			}
+
+			logger.WarnContext(r.Context(), "Access denied: insufficient scope", map[string]interface{}{
+				"scopes":          claims.Scopes,
+				"required_scopes": scopes,
+			})
+			challenge := `Bearer error="insufficient_scope"`
+			if len(scopes) > 0 {
+				challenge += fmt.Sprintf(`, scope="%s"`, strings.Join(scopes, " "))
+			}
+			w.Header().Set("WWW-Authenticate", challenge)
+			return errors.NewInsufficientScopeError(scopes...)
+		}
+	}
+}
+
+// RejectImpersonation refuses requests made with an impersonation token, for
+// actions support staff must not take on a user's behalf. It must be used
+// inside an authenticated handler chain.
+func RejectImpersonation(handler ErrorHandler) ErrorHandler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		claims, ok := r.Context().Value(UserContextKey).(*models.Claims)
+		if !ok {
+			return errors.NewAuthRequiredError()
+		}
+		if claims.ImpersonatorID != 0 {
+			logger.WarnContext(r.Context(), "Action refused while impersonating")
+			return errors.NewForbiddenError().WithDetails(map[string]interface{}{
+				"reason": "impersonation",
+			})
+		}
+		return handler(w, r)
+	}
+}
@@ -1,14 +1,20 @@
 package middleware
 
 import (
+	"context"
 	"encoding/json"
+	goerrors "errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
 
 	"github.com/clementhaon/sandbox-api-go/auth"
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/kvstore"
+	"github.com/clementhaon/sandbox-api-go/logger"
 	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/tenant"
 )
 
 const testSecret = "test-secret-key-minimum-16-chars"
@@ -38,8 +44,6 @@ func generateTestToken(t *testing.T, mgr *auth.JWTManager) string {
 
 func TestNewAuthMiddleware(t *testing.T) {
 	jwtMgr := newTestJWTManager(t)
-	blacklist := auth.NewTokenBlacklist()
-	defer blacklist.Stop()
 
 	okHandler := func(w http.ResponseWriter, r *http.Request) error {
 		claims, ok := r.Context().Value(UserContextKey).(*models.Claims)
@@ -75,6 +79,14 @@ func TestNewAuthMiddleware(t *testing.T) {
 			wantStatus: http.StatusOK,
 			wantClaims: true,
 		},
+		{
+			name: "token from another tenant is rejected",
+			setup: func(r *http.Request, token string) *http.Request {
+				r.Header.Set("Authorization", "Bearer "+token)
+				return r.WithContext(tenant.WithID(r.Context(), 2))
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
 		{
 			name: "missing token returns auth error",
 			setup: func(r *http.Request, token string) *http.Request {
@@ -114,13 +126,14 @@ func TestNewAuthMiddleware(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			token := generateTestToken(t, jwtMgr)
 
-			bl := auth.NewTokenBlacklist()
-			defer bl.Stop()
+			store := kvstore.NewMemoryStore()
+			defer store.Stop()
+			bl := auth.NewTokenBlacklist(store)
 			if tc.blacklist {
-				bl.Add(token, time.Now().Add(time.Hour))
+				bl.Add(context.Background(), token, time.Now().Add(time.Hour))
 			}
 
-			middleware := NewAuthMiddleware(jwtMgr, bl)
+			middleware := NewAuthMiddleware(jwtMgr, bl, nil)
 			handler := middleware(okHandler)
 
 			req := httptest.NewRequest(http.MethodGet, "/test", nil)
@@ -160,7 +173,7 @@ func TestAuthMiddleware_ClaimsInContext(t *testing.T) {
 		return nil
 	}
 
-	middleware := NewAuthMiddleware(jwtMgr, nil)
+	middleware := NewAuthMiddleware(jwtMgr, nil, nil)
 	wrapped := middleware(handler)
 
 	token := generateTestToken(t, jwtMgr)
@@ -183,3 +196,214 @@ func TestAuthMiddleware_ClaimsInContext(t *testing.T) {
 		t.Errorf("got Username %q, want %q", capturedClaims.Username, "testuser")
 	}
 }
+
+func TestAuthMiddleware_FlagsImpersonation(t *testing.T) {
+	jwtMgr := newTestJWTManager(t)
+
+	var impersonatorID int
+	wrapped := NewAuthMiddleware(jwtMgr, nil, nil)(func(w http.ResponseWriter, r *http.Request) error {
+		impersonatorID, _ = r.Context().Value(logger.ImpersonatorIDKey).(int)
+		return nil
+	})
+
+	token, err := jwtMgr.GenerateImpersonationToken(models.User{ID: 42, Username: "testuser", Role: "user"}, 1, time.Minute)
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	wrapped.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got status %d, want 200", rec.Code)
+	}
+	if impersonatorID != 1 {
+		t.Errorf("expected impersonator 1 in context, got %d", impersonatorID)
+	}
+	if got := rec.Header().Get("X-Impersonated-By"); got != "1" {
+		t.Errorf("expected X-Impersonated-By 1, got %q", got)
+	}
+}
+
+// personalTokensFunc is a PersonalTokenAuthenticator.
+type personalTokensFunc func(ctx context.Context, token string) (*models.Claims, error)
+
+func (f personalTokensFunc) Authenticate(ctx context.Context, token string) (*models.Claims, error) {
+	return f(ctx, token)
+}
+
+func TestAuthMiddleware_PersonalToken(t *testing.T) {
+	jwtMgr := newTestJWTManager(t)
+	personalTokens := personalTokensFunc(func(ctx context.Context, token string) (*models.Claims, error) {
+		switch token {
+		case "sap_valid":
+			return &models.Claims{UserID: 7, Username: "bot", Scopes: []string{models.ScopeTasksRead}}, nil
+		case "sap_expired":
+			return nil, errors.NewTokenExpiredError()
+		}
+		return nil, errors.NewInvalidTokenError()
+	})
+
+	var captured *models.Claims
+	handler := func(w http.ResponseWriter, r *http.Request) error {
+		captured, _ = r.Context().Value(UserContextKey).(*models.Claims)
+		return nil
+	}
+
+	tests := []struct {
+		name           string
+		personalTokens PersonalTokenAuthenticator
+		token          string
+		wantStatus     int
+	}{
+		{"valid token", personalTokens, "sap_valid", http.StatusOK},
+		{"expired token", personalTokens, "sap_expired", http.StatusUnauthorized},
+		{"unknown token", personalTokens, "sap_unknown", http.StatusUnauthorized},
+		{"personal tokens disabled", nil, "sap_valid", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			captured = nil
+			wrapped := NewAuthMiddleware(jwtMgr, nil, tt.personalTokens)(handler)
+
+			req := httptest.NewRequest(http.MethodGet, "/tasks", nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			rec := httptest.NewRecorder()
+			wrapped.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("got status %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusOK && (captured == nil || captured.UserID != 7 || captured.HasScope(models.ScopeTasksWrite)) {
+				t.Errorf("expected the scoped claims of user 7, got %+v", captured)
+			}
+		})
+	}
+}
+
+// unavailableStore is a kvstore.Store whose backend is down.
+type unavailableStore struct{ kvstore.Store }
+
+func (unavailableStore) Get(context.Context, string) ([]byte, bool, error) {
+	return nil, false, goerrors.New("connection refused")
+}
+
+func TestAuthMiddleware_RevocationStoreDown(t *testing.T) {
+	jwtMgr := newTestJWTManager(t)
+	wrapped := NewAuthMiddleware(jwtMgr, auth.NewTokenBlacklist(unavailableStore{}), nil)(func(w http.ResponseWriter, r *http.Request) error {
+		t.Error("handler should not run when revocation cannot be checked")
+		return nil
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.Header.Set("Authorization", "Bearer "+generateTestToken(t, jwtMgr))
+	rec := httptest.NewRecorder()
+	wrapped.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("got status %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+}
+
+func TestRequireRole(t *testing.T) {
+	okHandler := func(w http.ResponseWriter, r *http.Request) error {
+		w.WriteHeader(http.StatusOK)
+		return nil
+	}
+	protected := RequireRole(models.RoleAdmin)(okHandler)
+
+	tests := []struct {
+		name    string
+		claims  *models.Claims
+		wantErr bool
+	}{
+		{"admin allowed", &models.Claims{UserID: 1, Role: models.RoleAdmin}, false},
+		{"user forbidden", &models.Claims{UserID: 2, Role: "user"}, true},
+		{"missing claims", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/admin/audit-logs", nil)
+			if tt.claims != nil {
+				req = req.WithContext(context.WithValue(req.Context(), UserContextKey, tt.claims))
+			}
+			err := protected(httptest.NewRecorder(), req)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("wantErr %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestRequireScope(t *testing.T) {
+	okHandler := func(w http.ResponseWriter, r *http.Request) error {
+		w.WriteHeader(http.StatusOK)
+		return nil
+	}
+	protected := RequireScope(models.ScopeTasksRead, models.ScopeTasksWrite)(okHandler)
+
+	tests := []struct {
+		name    string
+		claims  *models.Claims
+		wantErr bool
+	}{
+		{"unrestricted allowed", &models.Claims{UserID: 1}, false},
+		{"matching scope allowed", &models.Claims{UserID: 1, Scopes: []string{models.ScopeTasksRead}}, false},
+		{"other scope refused", &models.Claims{UserID: 1, Scopes: []string{models.ScopeProfileRead}}, true},
+		{"no scope refused", &models.Claims{UserID: 1, Scopes: []string{}}, true},
+		{"missing claims", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/tasks", nil)
+			if tt.claims != nil {
+				req = req.WithContext(context.WithValue(req.Context(), UserContextKey, tt.claims))
+			}
+			rec := httptest.NewRecorder()
+			err := protected(rec, req)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("wantErr %v, got %v", tt.wantErr, err)
+			}
+			if tt.wantErr && tt.claims != nil {
+				if want := `Bearer error="insufficient_scope", scope="tasks:read tasks:write"`; rec.Header().Get("WWW-Authenticate") != want {
+					t.Errorf("expected WWW-Authenticate %q, got %q", want, rec.Header().Get("WWW-Authenticate"))
+				}
+			}
+		})
+	}
+}
+
+func TestRejectImpersonation(t *testing.T) {
+	okHandler := func(w http.ResponseWriter, r *http.Request) error {
+		w.WriteHeader(http.StatusOK)
+		return nil
+	}
+	protected := RejectImpersonation(okHandler)
+
+	tests := []struct {
+		name    string
+		claims  *models.Claims
+		wantErr bool
+	}{
+		{"own session allowed", &models.Claims{UserID: 2}, false},
+		{"impersonation refused", &models.Claims{UserID: 2, ImpersonatorID: 1}, true},
+		{"missing claims", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/profile/email", nil)
+			if tt.claims != nil {
+				req = req.WithContext(context.WithValue(req.Context(), UserContextKey, tt.claims))
+			}
+			err := protected(httptest.NewRecorder(), req)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("wantErr %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+)
+
+const basePathKey contextKey = "base_path"
+
+// NewBasePath mounts the API under prefix ("/sandbox-api"), for a reverse
+// proxy sharing its domain with other apps. The prefix is removed before
+// routing, so routes are registered without it; requests outside of it are
+// not found. An empty prefix leaves requests as they are.
+func NewBasePath(prefix string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if prefix == "" {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rest, ok := strings.CutPrefix(r.URL.Path, prefix)
+			if !ok || (rest != "" && rest[0] != '/') {
+				errors.WriteErrorFor(w, r, errors.NewNotFoundError("Page").WithRequestID(requestIDFor(r)).WithTrace(traceFor(r)))
+				return
+			}
+			if rest == "" {
+				rest = "/"
+			}
+
+			u := *r.URL
+			u.Path = rest
+			u.RawPath = ""
+			if raw, ok := strings.CutPrefix(r.URL.RawPath, prefix); ok {
+				u.RawPath = raw
+			}
+			r2 := r.WithContext(context.WithValue(r.Context(), basePathKey, prefix))
+			r2.URL = &u
+			next.ServeHTTP(w, r2)
+		})
+	}
+}
+
+// BasePath returns the prefix the API is mounted under, or "" at the root.
+// Links and redirects returned to clients start with it.
+func BasePath(ctx context.Context) string {
+	prefix, _ := ctx.Value(basePathKey).(string)
+	return prefix
+}
+
+// CookiePath returns the Path of the cookies the API sets, so they are only
+// sent back to it.
+func CookiePath(r *http.Request) string {
+	if prefix := BasePath(r.Context()); prefix != "" {
+		return prefix
+	}
+	return "/"
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBasePath(t *testing.T) {
+	var gotPath, gotBase, gotCookiePath string
+	handler := NewBasePath("/sandbox-api")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotBase, gotCookiePath = r.URL.Path, BasePath(r.Context()), CookiePath(r)
+	}))
+
+	tests := []struct {
+		path     string
+		wantCode int
+		wantPath string
+	}{
+		{"/sandbox-api/tasks/3", http.StatusOK, "/tasks/3"},
+		{"/sandbox-api/", http.StatusOK, "/"},
+		{"/sandbox-api", http.StatusOK, "/"},
+		{"/sandbox-apiary/tasks", http.StatusNotFound, ""},
+		{"/tasks", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			gotPath, gotBase, gotCookiePath = "", "", ""
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			if w.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantCode)
+			}
+			if gotPath != tt.wantPath {
+				t.Errorf("routed path = %q, want %q", gotPath, tt.wantPath)
+			}
+			if tt.wantCode == http.StatusOK && (gotBase != "/sandbox-api" || gotCookiePath != "/sandbox-api") {
+				t.Errorf("base path = %q, cookie path = %q", gotBase, gotCookiePath)
+			}
+			if req.URL.Path != tt.path {
+				t.Errorf("expected the original request untouched, got %q", req.URL.Path)
+			}
+		})
+	}
+
+	root := NewBasePath("")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotCookiePath = r.URL.Path, CookiePath(r)
+	}))
+	root.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/tasks", nil))
+	if gotPath != "/tasks" || gotCookiePath != "/" {
+		t.Errorf("expected requests untouched without a prefix, got %q and cookie path %q", gotPath, gotCookiePath)
+	}
+}
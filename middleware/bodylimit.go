@@ -14,3 +14,14 @@ func MaxBytesMiddleware(maxBytes int64) func(http.Handler) http.Handler {
 		})
 	}
 }
+
+// MaxBytes limits the request body of one route below the server-wide
+// limit of MaxBytesMiddleware.
+func MaxBytes(maxBytes int64) func(ErrorHandler) ErrorHandler {
+	return func(next ErrorHandler) ErrorHandler {
+		return func(w http.ResponseWriter, r *http.Request) error {
+			r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+			return next(w, r)
+		}
+	}
+}
@@ -55,3 +55,23 @@ func TestMaxBytesMiddleware(t *testing.T) {
 		}
 	})
 }
+
+func TestMaxBytes(t *testing.T) {
+	read := func(w http.ResponseWriter, r *http.Request) error {
+		_, err := io.ReadAll(r.Body)
+		return err
+	}
+	handler := ErrorMiddleware(MaxBytes(4)(read))
+
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodPost, "/test", strings.NewReader("too long")))
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413 past the route's limit, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodPost, "/test", strings.NewReader("ok")))
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected 200 under the limit, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/clementhaon/sandbox-api-go/logger"
+)
+
+// redactedValue replaces the value of sensitive fields in logged bodies.
+const redactedValue = "[REDACTED]"
+
+// sensitiveJSONField matches string values of JSON fields whose name mentions
+// a password, token or secret. The closing quote is optional so values cut by
+// truncation are scrubbed too.
+var sensitiveJSONField = regexp.MustCompile(`(?i)("[^"]*(?:password|token|secret)[^"]*"\s*:\s*)"(?:[^"\\]|\\.)*"?`)
+
+// sensitiveFormField does the same for URL-encoded form fields.
+var sensitiveFormField = regexp.MustCompile(`(?i)((?:^|&)[^=&]*(?:password|token|secret)[^=&]*=)[^&]*`)
+
+// NewBodyLoggingMiddleware logs request and response bodies at DEBUG level,
+// keyed by request ID. Only textual bodies (JSON, text, forms) are logged, cut
+// to maxBytes, with password, token and secret fields redacted. Bodies are
+// captured as they stream, so nothing extra is buffered or read. It is a
+// no-op when DEBUG logging is disabled.
+func NewBodyLoggingMiddleware(maxBytes int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			if !logger.FromContext(ctx).Enabled(ctx, slog.LevelDebug) || r.Header.Get("Upgrade") != "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			reqBody := &limitedBuffer{max: maxBytes}
+			if r.Body != nil && r.Body != http.NoBody {
+				r.Body = &teeReadCloser{Reader: io.TeeReader(r.Body, reqBody), Closer: r.Body}
+			}
+			recorder := &bodyRecorder{ResponseWriter: w, body: limitedBuffer{max: maxBytes}}
+
+			next.ServeHTTP(recorder, r)
+
+			fields := map[string]interface{}{}
+			if body, ok := loggableBody(r.Header.Get("Content-Type"), reqBody); ok {
+				fields["request_body"] = body
+			}
+			if body, ok := loggableBody(recorder.Header().Get("Content-Type"), &recorder.body); ok {
+				fields["response_body"] = body
+			}
+			if len(fields) > 0 {
+				logger.DebugContext(ctx, "HTTP bodies", fields)
+			}
+		})
+	}
+}
+
+// loggableBody returns the redacted body, or false if there is nothing to
+// log or the content type is not textual.
+func loggableBody(contentType string, buf *limitedBuffer) (string, bool) {
+	if buf.total == 0 {
+		return "", false
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+
+	var body string
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		body = sensitiveJSONField.ReplaceAllString(buf.String(), `$1"`+redactedValue+`"`)
+	case mediaType == "application/x-www-form-urlencoded":
+		body = sensitiveFormField.ReplaceAllString(buf.String(), "${1}"+redactedValue)
+	case strings.HasPrefix(mediaType, "text/"):
+		body = buf.String()
+	default:
+		return "", false
+	}
+
+	if buf.total > buf.max {
+		body += fmt.Sprintf("... (%d bytes truncated)", buf.total-buf.max)
+	}
+	return body, true
+}
+
+// limitedBuffer keeps the first max bytes written and counts the rest.
+type limitedBuffer struct {
+	bytes.Buffer
+	max   int
+	total int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	b.total += len(p)
+	if room := b.max - b.Len(); room > 0 {
+		if len(p) > room {
+			b.Buffer.Write(p[:room])
+		} else {
+			b.Buffer.Write(p)
+		}
+	}
+	return len(p), nil
+}
+
+type teeReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// bodyRecorder copies the start of the response body into body.
+type bodyRecorder struct {
+	http.ResponseWriter
+	body limitedBuffer
+}
+
+func (w *bodyRecorder) Write(b []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(b)
+	w.body.Write(b[:n])
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer (Flush, deadlines).
+func (w *bodyRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Hijack is required by the WebSocket upgrade.
+func (w *bodyRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("response writer does not support hijacking")
+	}
+	return hijacker.Hijack()
+}
@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/clementhaon/sandbox-api-go/logger"
+)
+
+func TestBodyLoggingMiddleware(t *testing.T) {
+	var logs bytes.Buffer
+	debugLogger := slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	handler := NewBodyLoggingMiddleware(64)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if !strings.Contains(string(body), "hunter2") {
+			t.Error("handler should receive the unredacted body")
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"user":{"id":1},"token":"eyJhbGciOi","padding":"` + strings.Repeat("x", 100) + `"}`))
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/login", strings.NewReader(`{"email":"a@b.c","password":"hunter2"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req = req.WithContext(logger.NewContext(req.Context(), debugLogger))
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	out := logs.String()
+	if strings.Contains(out, "hunter2") || strings.Contains(out, "eyJhbGciOi") {
+		t.Errorf("secrets leaked into logs: %s", out)
+	}
+	if !strings.Contains(out, "a@b.c") {
+		t.Errorf("expected request body in logs: %s", out)
+	}
+	if !strings.Contains(out, "bytes truncated") {
+		t.Errorf("expected truncated response body: %s", out)
+	}
+}
+
+func TestBodyLoggingMiddleware_DisabledWithoutDebug(t *testing.T) {
+	var logs bytes.Buffer
+	infoLogger := slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelInfo}))
+
+	var gotWriter http.ResponseWriter
+	handler := NewBodyLoggingMiddleware(64)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotWriter = w
+		w.Write([]byte("ok"))
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req = req.WithContext(logger.NewContext(req.Context(), infoLogger))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if gotWriter != rec {
+		t.Error("expected the writer to pass through untouched")
+	}
+	if logs.Len() != 0 {
+		t.Errorf("expected no logs, got %s", logs.String())
+	}
+}
+
+func TestLoggableBody(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        string
+		wantOK      bool
+	}{
+		{name: "json", contentType: "application/json; charset=utf-8", body: `{"newPassword":"p","x":1}`, want: `{"newPassword":"[REDACTED]","x":1}`, wantOK: true},
+		{name: "escaped quote", contentType: "application/json", body: `{"token":"a\"b","x":1}`, want: `{"token":"[REDACTED]","x":1}`, wantOK: true},
+		{name: "form", contentType: "application/x-www-form-urlencoded", body: "user=a&client_secret=s&x=1", want: "user=a&client_secret=[REDACTED]&x=1", wantOK: true},
+		{name: "text", contentType: "text/plain", body: "hello", want: "hello", wantOK: true},
+		{name: "binary", contentType: "image/png", body: "\x89PNG"},
+		{name: "empty", contentType: "application/json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &limitedBuffer{max: 1024}
+			buf.Write([]byte(tt.body))
+			got, ok := loggableBody(tt.contentType, buf)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("got (%q, %v), want (%q, %v)", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/metrics"
+	"github.com/clementhaon/sandbox-api-go/respond"
+)
+
+// maxCachedResponses bounds the number of responses a ResponseCache keeps.
+const maxCachedResponses = 1000
+
+type cachedResponse struct {
+	header http.Header
+	body   []byte
+	stored time.Time
+}
+
+// ResponseCache keeps successful responses of public, idempotent endpoints in
+// memory for a short TTL and marks them cacheable by clients and proxies.
+type ResponseCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]*cachedResponse
+	metrics *metrics.Metrics
+}
+
+// NewResponseCache creates a cache holding responses for ttl and recording
+// hits and misses on m, which may be nil. A zero ttl disables caching.
+func NewResponseCache(ttl time.Duration, m *metrics.Metrics) *ResponseCache {
+	return &ResponseCache{
+		ttl:     ttl,
+		entries: make(map[string]*cachedResponse),
+		metrics: m,
+	}
+}
+
+// Cache wraps an http.HandlerFunc so GET and HEAD responses with status 200
+// are served from memory until they expire. Responses are keyed on the URL
+// and the Accept and Accept-Language headers, which Vary names for other
+// caches. JSON envelopes served from memory get the timestamp and request
+// ID of the request. Requests with an Authorization header and responses
+// setting cookies are never cached.
+func (c *ResponseCache) Cache(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if c.ttl <= 0 || (r.Method != http.MethodGet && r.Method != http.MethodHead) || r.Header.Get("Authorization") != "" {
+			next(w, r)
+			return
+		}
+
+		key := r.URL.RequestURI() + "\x00" + r.Header.Get("Accept") + "\x00" + r.Header.Get("Accept-Language")
+		if entry := c.get(key); entry != nil {
+			c.metrics.RecordResponseCache("hit")
+			for name, values := range entry.header {
+				if name == http.CanonicalHeaderKey(RequestIDHeader) {
+					continue
+				}
+				w.Header()[name] = values
+			}
+			body := entry.body
+			if rewrapped, ok := respond.Rewrap(body, w.Header().Get(RequestIDHeader)); ok {
+				body = rewrapped
+				w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+			}
+			w.Header().Set("Age", strconv.Itoa(int(time.Since(entry.stored).Seconds())))
+			w.WriteHeader(http.StatusOK)
+			if r.Method == http.MethodGet {
+				w.Write(body)
+			}
+			return
+		}
+		c.metrics.RecordResponseCache("miss")
+
+		w.Header().Add("Vary", "Accept, Accept-Language")
+		rec := &cacheRecorder{ResponseWriter: w, status: http.StatusOK, maxAge: c.ttl}
+		next(rec, r)
+		if rec.status != http.StatusOK || r.Method != http.MethodGet || w.Header().Get("Set-Cookie") != "" {
+			return
+		}
+		c.put(key, &cachedResponse{
+			header: w.Header().Clone(),
+			body:   rec.body.Bytes(),
+			stored: time.Now(),
+		})
+	}
+}
+
+// get returns the unexpired entry for key, or nil.
+func (c *ResponseCache) get(key string) *cachedResponse {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	if time.Since(entry.stored) >= c.ttl {
+		delete(c.entries, key)
+		c.metrics.SetResponseCacheEntries(len(c.entries))
+		return nil
+	}
+	return entry
+}
+
+// put stores entry, first dropping expired entries when the cache is full.
+// The entry is not stored if the cache is still full.
+func (c *ResponseCache) put(key string, entry *cachedResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) >= maxCachedResponses {
+		for k, e := range c.entries {
+			if time.Since(e.stored) >= c.ttl {
+				delete(c.entries, k)
+			}
+		}
+		if len(c.entries) >= maxCachedResponses {
+			return
+		}
+	}
+	c.entries[key] = entry
+	c.metrics.SetResponseCacheEntries(len(c.entries))
+}
+
+// cacheRecorder copies the status and body of a response while writing it,
+// and marks 200 responses cacheable unless the handler chose otherwise.
+type cacheRecorder struct {
+	http.ResponseWriter
+	status      int
+	body        bytes.Buffer
+	wroteHeader bool
+	maxAge      time.Duration
+}
+
+func (rec *cacheRecorder) WriteHeader(status int) {
+	if rec.wroteHeader {
+		return
+	}
+	rec.wroteHeader = true
+	rec.status = status
+	if status == http.StatusOK && rec.Header().Get("Cache-Control") == "" {
+		rec.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(int(rec.maxAge.Seconds())))
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *cacheRecorder) Write(b []byte) (int, error) {
+	if !rec.wroteHeader {
+		rec.WriteHeader(http.StatusOK)
+	}
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
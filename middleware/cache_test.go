@@ -0,0 +1,127 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/respond"
+)
+
+func TestResponseCache(t *testing.T) {
+	newHandler := func(ttl time.Duration) (http.HandlerFunc, *int) {
+		calls := 0
+		return NewResponseCache(ttl, nil).Cache(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			if r.URL.Path == "/missing" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Content-Language", r.Header.Get("Accept-Language"))
+			w.Write([]byte("hello " + r.Header.Get("Accept-Language")))
+		}), &calls
+	}
+	get := func(handler http.HandlerFunc, path, lang string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Accept-Language", lang)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("serves repeated requests from memory", func(t *testing.T) {
+		handler, calls := newHandler(time.Minute)
+
+		first := get(handler, "/", "en")
+		second := get(handler, "/", "en")
+		if *calls != 1 {
+			t.Errorf("expected the handler to run once, ran %d times", *calls)
+		}
+		if second.Body.String() != "hello en" || second.Header().Get("Content-Language") != "en" {
+			t.Errorf("unexpected cached response: %q %v", second.Body.String(), second.Header())
+		}
+		if got := first.Header().Get("Cache-Control"); got != "public, max-age=60" {
+			t.Errorf("got Cache-Control %q, want %q", got, "public, max-age=60")
+		}
+		if second.Header().Get("Age") == "" {
+			t.Error("expected an Age header on the cached response")
+		}
+		if got := second.Header().Get("Vary"); got != "Accept, Accept-Language" {
+			t.Errorf("got Vary %q, want %q", got, "Accept, Accept-Language")
+		}
+	})
+
+	t.Run("keys on Accept-Language", func(t *testing.T) {
+		handler, calls := newHandler(time.Minute)
+
+		get(handler, "/", "en")
+		if rec := get(handler, "/", "fr"); rec.Body.String() != "hello fr" {
+			t.Errorf("got body %q, want %q", rec.Body.String(), "hello fr")
+		}
+		if *calls != 2 {
+			t.Errorf("expected one handler call per language, got %d", *calls)
+		}
+	})
+
+	t.Run("does not cache errors or authenticated requests", func(t *testing.T) {
+		handler, calls := newHandler(time.Minute)
+
+		get(handler, "/missing", "en")
+		get(handler, "/missing", "en")
+		for range 2 {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Authorization", "Bearer token")
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+		}
+		if *calls != 4 {
+			t.Errorf("expected every request to reach the handler, got %d calls", *calls)
+		}
+	})
+
+	t.Run("zero ttl disables caching", func(t *testing.T) {
+		handler, calls := newHandler(0)
+
+		rec := get(handler, "/", "en")
+		get(handler, "/", "en")
+		if *calls != 2 {
+			t.Errorf("expected 2 handler calls, got %d", *calls)
+		}
+		if rec.Header().Get("Cache-Control") != "" {
+			t.Errorf("expected no Cache-Control header, got %q", rec.Header().Get("Cache-Control"))
+		}
+	})
+	t.Run("stamps cached envelopes with the current request", func(t *testing.T) {
+		handler := NewResponseCache(time.Minute, nil).Cache(func(w http.ResponseWriter, r *http.Request) {
+			respond.Paginated(w, []string{"a"}, map[string]int{"page": 1})
+		})
+		serve := func(requestID string) (*httptest.ResponseRecorder, respond.Envelope) {
+			rec := httptest.NewRecorder()
+			rec.Header().Set(RequestIDHeader, requestID)
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+			var env respond.Envelope
+			if err := json.Unmarshal(rec.Body.Bytes(), &env); err != nil {
+				t.Fatalf("decode %q: %v", rec.Body.String(), err)
+			}
+			return rec, env
+		}
+
+		_, first := serve("req-1")
+		time.Sleep(time.Millisecond)
+		rec, second := serve("req-2")
+		if rec.Header().Get("Age") == "" {
+			t.Fatal("expected the second response from the cache")
+		}
+		if second.RequestID != "req-2" || !second.Timestamp.After(first.Timestamp) {
+			t.Errorf("expected the request ID and time of the second request, got %q at %v (first at %v)", second.RequestID, second.Timestamp, first.Timestamp)
+		}
+		if second.Meta["pagination"] == nil || len(second.Data.([]interface{})) != 1 {
+			t.Errorf("expected the cached data and meta, got %+v", second)
+		}
+		if got := rec.Header().Get("Content-Length"); got != strconv.Itoa(rec.Body.Len()) {
+			t.Errorf("got Content-Length %s for a %d byte body", got, rec.Body.Len())
+		}
+	})
+}
@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/clementhaon/sandbox-api-go/logger"
+)
+
+// NewClientIPMiddleware resolves the real client IP and stores it in the
+// request context, where logs, audit entries and rate limiting pick it up.
+// Forwarding headers are only honoured when the peer is a trusted proxy:
+// X-Forwarded-For is walked from the right, skipping trusted hops, and
+// X-Real-IP is used when it is absent. Otherwise the peer address is used.
+func NewClientIPMiddleware(trustedProxies []netip.Prefix) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := resolveClientIP(r, trustedProxies)
+			ctx := context.WithValue(r.Context(), logger.ClientIPKey, ip)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// clientIP returns the IP resolved by NewClientIPMiddleware, or the address
+// of the peer if the middleware did not run.
+func clientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(logger.ClientIPKey).(string); ok && ip != "" {
+		return ip
+	}
+	return peerIP(r)
+}
+
+func peerIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return ip
+}
+
+func resolveClientIP(r *http.Request, trusted []netip.Prefix) string {
+	peer := peerIP(r)
+	if !isTrustedProxy(peer, trusted) {
+		return peer
+	}
+
+	// Each proxy appends the address it received the request from, so the
+	// rightmost untrusted entry is the first one not written by the client
+	if forwarded := r.Header.Values("X-Forwarded-For"); len(forwarded) > 0 {
+		hops := strings.Split(strings.Join(forwarded, ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+			if err != nil {
+				break
+			}
+			if i == 0 || !isTrustedProxy(addr.String(), trusted) {
+				return addr.Unmap().String()
+			}
+		}
+		return peer
+	}
+
+	if realIP, err := netip.ParseAddr(strings.TrimSpace(r.Header.Get("X-Real-IP"))); err == nil {
+		return realIP.Unmap().String()
+	}
+	return peer
+}
+
+func isTrustedProxy(ip string, trusted []netip.Prefix) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/clementhaon/sandbox-api-go/logger"
+)
+
+func TestClientIPMiddleware(t *testing.T) {
+	trusted := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  []string
+		realIP     string
+		want       string
+	}{
+		{name: "direct client", remoteAddr: "203.0.113.7:5000", want: "203.0.113.7"},
+		{name: "untrusted peer cannot spoof", remoteAddr: "203.0.113.7:5000", forwarded: []string{"198.51.100.1"}, want: "203.0.113.7"},
+		{name: "trusted proxy", remoteAddr: "10.0.0.2:5000", forwarded: []string{"198.51.100.1"}, want: "198.51.100.1"},
+		{name: "spoofed left entry is ignored", remoteAddr: "10.0.0.2:5000", forwarded: []string{"1.2.3.4, 198.51.100.1"}, want: "198.51.100.1"},
+		{name: "trusted hops are skipped", remoteAddr: "10.0.0.2:5000", forwarded: []string{"198.51.100.1, 10.0.0.9", "10.0.0.5"}, want: "198.51.100.1"},
+		{name: "all hops trusted", remoteAddr: "10.0.0.2:5000", forwarded: []string{"10.0.0.9"}, want: "10.0.0.9"},
+		{name: "malformed hop falls back to peer", remoteAddr: "10.0.0.2:5000", forwarded: []string{"nonsense"}, want: "10.0.0.2"},
+		{name: "x-real-ip", remoteAddr: "10.0.0.2:5000", realIP: "198.51.100.3", want: "198.51.100.3"},
+		{name: "ipv4-mapped", remoteAddr: "10.0.0.2:5000", forwarded: []string{"::ffff:198.51.100.1"}, want: "198.51.100.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got, gotRateKey string
+			handler := NewClientIPMiddleware(trusted)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got, _ = r.Context().Value(logger.ClientIPKey).(string)
+				gotRateKey = clientIP(r)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for _, v := range tt.forwarded {
+				req.Header.Add("X-Forwarded-For", v)
+			}
+			if tt.realIP != "" {
+				req.Header.Set("X-Real-IP", tt.realIP)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			if got != tt.want {
+				t.Errorf("client IP = %q, want %q", got, tt.want)
+			}
+			if gotRateKey != tt.want {
+				t.Errorf("rate limit key = %q, want %q", gotRateKey, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientIPMiddleware_LogsClientIP(t *testing.T) {
+	var logs bytes.Buffer
+	inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logger.FromContext(r.Context()).Info("handled")
+	})
+	handler := NewClientIPMiddleware(nil)(RequestLoggingMiddleware(inner))
+
+	req := httptest.NewRequest(http.MethodGet, "/tasks", nil)
+	req.RemoteAddr = "203.0.113.7:5000"
+	req.Header.Set(RequestIDHeader, "req-123")
+	req = req.WithContext(logger.NewContext(req.Context(), slog.New(slog.NewJSONHandler(&logs, nil))))
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	out := logs.String()
+	if !strings.Contains(out, `"client_ip":"203.0.113.7"`) {
+		t.Errorf("expected client_ip in logs: %s", out)
+	}
+}
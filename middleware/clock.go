@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/clock"
+	"github.com/clementhaon/sandbox-api-go/logger"
+	"github.com/clementhaon/sandbox-api-go/models"
+)
+
+// ClockOffsets tells the virtual clock offset sandbox users set.
+type ClockOffsets interface {
+	// Offset returns the clock offset of the user, 0 for the real time
+	Offset(ctx context.Context, userID int) (time.Duration, error)
+}
+
+// ApplyClock serves the requests of users who moved their sandbox clock
+// with the clock of their context offset, so clock.Now reads their time.
+// It must be used inside an authenticated handler chain.
+func ApplyClock(offsets ClockOffsets) func(ErrorHandler) ErrorHandler {
+	return func(handler ErrorHandler) ErrorHandler {
+		return func(w http.ResponseWriter, r *http.Request) error {
+			claims, ok := r.Context().Value(UserContextKey).(*models.Claims)
+			if !ok {
+				return handler(w, r)
+			}
+			offset, err := offsets.Offset(r.Context(), claims.UserID)
+			if err != nil {
+				return err
+			}
+			if offset == 0 {
+				return handler(w, r)
+			}
+
+			ctx := clock.WithOffset(r.Context(), offset)
+			ctx = logger.With(ctx, "clock_offset", offset.String())
+			return handler(w, r.WithContext(ctx))
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/clock"
+	"github.com/clementhaon/sandbox-api-go/models"
+)
+
+type clockOffsetsFunc func(ctx context.Context, userID int) (time.Duration, error)
+
+func (f clockOffsetsFunc) Offset(ctx context.Context, userID int) (time.Duration, error) {
+	return f(ctx, userID)
+}
+
+func TestApplyClock(t *testing.T) {
+	offsets := clockOffsetsFunc(func(ctx context.Context, userID int) (time.Duration, error) {
+		if userID == 2 {
+			return 48 * time.Hour, nil
+		}
+		return 0, nil
+	})
+	var got time.Duration
+	handler := ApplyClock(offsets)(func(w http.ResponseWriter, r *http.Request) error {
+		got = clock.Offset(r.Context())
+		return nil
+	})
+
+	for userID, want := range map[int]time.Duration{1: 0, 2: 48 * time.Hour} {
+		r := httptest.NewRequest(http.MethodGet, "/tasks", nil)
+		r = r.WithContext(context.WithValue(r.Context(), UserContextKey, &models.Claims{UserID: userID}))
+		if err := handler(httptest.NewRecorder(), r); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got != want {
+			t.Errorf("user %d: expected offset %v, got %v", userID, want, got)
+		}
+	}
+}
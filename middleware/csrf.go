@@ -4,6 +4,7 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"net/http"
+	"strings"
 
 	"github.com/clementhaon/sandbox-api-go/errors"
 )
@@ -28,7 +29,9 @@ func CSRFMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
-		// Skip public auth routes (login, register, logout)
+		// Skip public auth routes (login, register, logout, email verification),
+		// the OAuth token endpoint, SCIM and the inbound task webhook, where
+		// clients authenticate themselves
 		if isCSRFExemptPath(r.URL.Path) {
 			next.ServeHTTP(w, r)
 			return
@@ -39,7 +42,7 @@ func CSRFMiddleware(next http.Handler) http.Handler {
 		if err != nil || cookie.Value == "" {
 			appErr := errors.NewForbiddenError()
 			appErr.Message = "Missing CSRF token"
-			errors.WriteError(w, appErr)
+			errors.WriteErrorFor(w, r, appErr.WithRequestID(requestIDFor(r)).WithTrace(traceFor(r)))
 			return
 		}
 
@@ -47,7 +50,7 @@ func CSRFMiddleware(next http.Handler) http.Handler {
 		if headerToken == "" || headerToken != cookie.Value {
 			appErr := errors.NewForbiddenError()
 			appErr.Message = "Invalid CSRF token"
-			errors.WriteError(w, appErr)
+			errors.WriteErrorFor(w, r, appErr.WithRequestID(requestIDFor(r)).WithTrace(traceFor(r)))
 			return
 		}
 
@@ -56,17 +59,21 @@ func CSRFMiddleware(next http.Handler) http.Handler {
 }
 
 func isCSRFExemptPath(path string) bool {
-	return path == "/auth/login" || path == "/auth/register" || path == "/auth/logout"
+	return path == "/auth/login" || path == "/auth/register" || path == "/auth/logout" ||
+		path == "/auth/verify-email" || path == "/oauth/token" ||
+		path == "/inbound/tasks" || path == "/integrations/slack/commands" || strings.HasPrefix(path, "/scim/")
 }
 
-// SetCSRFCookie sets the csrf_token cookie (readable by JavaScript).
-func SetCSRFCookie(w http.ResponseWriter, isProduction bool) string {
+// SetCSRFCookie sets the csrf_token cookie (readable by JavaScript) for
+// the path the API is served under. It lasts maxAge seconds, as the
+// auth_token cookie it goes with; 0 makes it a browser session cookie.
+func SetCSRFCookie(w http.ResponseWriter, r *http.Request, isProduction bool, maxAge int) string {
 	token := GenerateCSRFToken()
 	http.SetCookie(w, &http.Cookie{
 		Name:     "csrf_token",
 		Value:    token,
-		Path:     "/",
-		MaxAge:   24 * 60 * 60,
+		Path:     CookiePath(r),
+		MaxAge:   maxAge,
 		HttpOnly: false, // Must be readable by JS
 		Secure:   isProduction,
 		SameSite: http.SameSiteStrictMode,
@@ -75,11 +82,11 @@ func SetCSRFCookie(w http.ResponseWriter, isProduction bool) string {
 }
 
 // ClearCSRFCookie clears the csrf_token cookie.
-func ClearCSRFCookie(w http.ResponseWriter, isProduction bool) {
+func ClearCSRFCookie(w http.ResponseWriter, r *http.Request, isProduction bool) {
 	http.SetCookie(w, &http.Cookie{
 		Name:     "csrf_token",
 		Value:    "",
-		Path:     "/",
+		Path:     CookiePath(r),
 		MaxAge:   -1,
 		HttpOnly: false,
 		Secure:   isProduction,
@@ -101,7 +101,7 @@ func TestGenerateCSRFToken(t *testing.T) {
 
 func TestSetCSRFCookie(t *testing.T) {
 	rec := httptest.NewRecorder()
-	token := SetCSRFCookie(rec, false)
+	token := SetCSRFCookie(rec, httptest.NewRequest(http.MethodPost, "/auth/login", nil), false, 24*60*60)
 
 	if token == "" {
 		t.Error("expected non-empty token from SetCSRFCookie")
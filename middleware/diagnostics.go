@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/clementhaon/sandbox-api-go/models"
+)
+
+// DiagnosticsTokenHeader carries the shared diagnostics token.
+const DiagnosticsTokenHeader = "X-Diagnostics-Token"
+
+// NewDiagnosticsAuth returns a decorator for diagnostics endpoints (pprof, runtime
+// stats). Requests presenting the configured diagnostics token are let through,
+// which lets profiling tools without a JWT reach them. Everyone else must be an
+// authenticated admin, with a token not restricted to scopes. An empty token
+// disables token access.
+func NewDiagnosticsAuth(token string, authMW func(ErrorHandler) http.HandlerFunc) func(ErrorHandler) http.HandlerFunc {
+	return func(handler ErrorHandler) http.HandlerFunc {
+		withToken := ErrorMiddleware(handler)
+		asAdmin := authMW(RequireRole(models.RoleAdmin)(RequireScope()(handler)))
+
+		return func(w http.ResponseWriter, r *http.Request) {
+			provided := r.Header.Get(DiagnosticsTokenHeader)
+			if token != "" && provided != "" && subtle.ConstantTimeCompare([]byte(provided), []byte(token)) == 1 {
+				withToken(w, r)
+				return
+			}
+			asAdmin(w, r)
+		}
+	}
+}
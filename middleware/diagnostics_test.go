@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+)
+
+func TestNewDiagnosticsAuth(t *testing.T) {
+	// Stand-in for the JWT middleware: rejects everyone, so only the token grants access.
+	denyAll := func(handler ErrorHandler) http.HandlerFunc {
+		return ErrorMiddleware(func(w http.ResponseWriter, r *http.Request) error {
+			return errors.NewAuthRequiredError()
+		})
+	}
+	okHandler := func(w http.ResponseWriter, r *http.Request) error {
+		w.WriteHeader(http.StatusOK)
+		return nil
+	}
+
+	tests := []struct {
+		name       string
+		configured string
+		provided   string
+		wantStatus int
+	}{
+		{"valid token", "diagnostics-token-123", "diagnostics-token-123", http.StatusOK},
+		{"wrong token falls back to admin auth", "diagnostics-token-123", "nope", http.StatusUnauthorized},
+		{"no token provided", "diagnostics-token-123", "", http.StatusUnauthorized},
+		{"token access disabled", "", "", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewDiagnosticsAuth(tt.configured, denyAll)(okHandler)
+			req := httptest.NewRequest(http.MethodGet, "/debug/runtime", nil)
+			if tt.provided != "" {
+				req.Header.Set(DiagnosticsTokenHeader, tt.provided)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("got status %d, want %d", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -1,21 +1,34 @@
 package middleware
 
 import (
+	"bufio"
 	"context"
-	"crypto/rand"
-	"encoding/hex"
 	goerrors "errors"
 	"fmt"
+	"net"
 	"net/http"
 	"regexp"
 	"runtime/debug"
 	"time"
 
 	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/errtrack"
+	"github.com/clementhaon/sandbox-api-go/i18n"
 	"github.com/clementhaon/sandbox-api-go/logger"
 	"github.com/clementhaon/sandbox-api-go/metrics"
+	"github.com/google/uuid"
 )
 
+// RequestIDHeader is the header used to receive and return request IDs.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds inbound request IDs so they cannot bloat logs.
+const maxRequestIDLength = 128
+
+// statusClientClosedRequest is recorded for requests whose client went away
+// before the response, after nginx's convention.
+const statusClientClosedRequest = 499
+
 // ErrorHandler is a custom handler type that can return errors
 type ErrorHandler func(http.ResponseWriter, *http.Request) error
 
@@ -23,17 +36,30 @@ type ErrorHandler func(http.ResponseWriter, *http.Request) error
 func ErrorMiddleware(handler ErrorHandler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Add request ID to context for tracking
-		requestID := generateRequestID()
+		requestID := requestIDFor(r)
 		ctx := context.WithValue(r.Context(), logger.RequestIDKey, requestID)
+		ctx = withTrace(ctx, r)
+		ctx = context.WithValue(ctx, logger.ClientIPKey, clientIP(r))
+		ctx = context.WithValue(ctx, logger.UserAgentKey, r.UserAgent())
+		ctx = withRequestLogger(ctx, r)
+
+		// Negotiate the language of response messages
+		lang := i18n.Negotiate(r.Header.Get("Accept-Language"))
+		ctx = i18n.WithLanguage(ctx, lang)
 		r = r.WithContext(ctx)
 
-		// Set request ID header for client reference
-		w.Header().Set("X-Request-ID", requestID)
+		// Set request ID and trace headers for client reference
+		w.Header().Set(RequestIDHeader, requestID)
+		traceID, spanID := traceFor(r)
+		setTraceResponse(w, traceID, spanID)
+		w.Header().Set("Content-Language", lang)
+		w.Header().Add("Vary", "Accept-Language")
 
 		// Record start time for duration logging
 		startTime := time.Now()
 
-		// Execute the handler
+		// Execute the handler, keeping ETags to the answers to reads
+		w = withoutETag(w, r)
 		err := handler(w, r)
 
 		// Calculate duration for metrics
@@ -51,10 +77,13 @@ func ErrorMiddleware(handler ErrorHandler) http.HandlerFunc {
 			} else {
 				statusCode = 500
 			}
+			if clientGone(r) {
+				statusCode = statusClientClosedRequest
+			}
 		}
 
 		endpoint := normalizeEndpoint(r.URL.Path)
-		metrics.RecordHTTPRequest(r.Method, endpoint, statusCode, duration)
+		metrics.FromContext(r.Context()).RecordHTTPRequest(r.Method, endpoint, statusCode, duration, requestID)
 	}
 }
 
@@ -62,22 +91,33 @@ func ErrorMiddleware(handler ErrorHandler) http.HandlerFunc {
 func handleError(w http.ResponseWriter, r *http.Request, err error, requestID string) {
 	ctx := r.Context()
 
+	// Nobody is left to read the response; the error is most likely the
+	// handler's work being canceled with the request
+	if clientGone(r) {
+		metrics.FromContext(ctx).RecordError("client_error", "client_closed_request")
+		logger.DebugContext(ctx, "Client disconnected before the response", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
 	// Check for MaxBytesError (request body too large)
 	var maxBytesErr *http.MaxBytesError
 	if goerrors.As(err, &maxBytesErr) {
-		payloadErr := errors.NewPayloadTooLargeError().WithRequestID(requestID)
-		metrics.RecordError(string(payloadErr.Type), string(payloadErr.Code))
-		errors.WriteError(w, payloadErr)
+		payloadErr := errors.NewPayloadTooLargeError().WithRequestID(requestID).WithTrace(traceFor(r))
+		metrics.FromContext(ctx).RecordError(string(payloadErr.Type), string(payloadErr.Code))
+		errors.WriteErrorFor(w, r, payloadErr)
 		return
 	}
 
 	// Check if it's already an AppError
 	if appErr, ok := errors.IsAppError(err); ok {
-		// Add request ID to the error
-		appErr.WithRequestID(requestID)
+		// Add request ID and trace to the error
+		appErr.WithRequestID(requestID).WithTrace(traceFor(r))
 
 		// Record error metrics
-		metrics.RecordError(string(appErr.Type), string(appErr.Code))
+		metrics.FromContext(ctx).RecordError(string(appErr.Type), string(appErr.Code))
+		reportServerError(r, appErr)
 
 		// Log the error with appropriate level
 		if appErr.Type == errors.ErrorTypeServer {
@@ -94,12 +134,12 @@ func handleError(w http.ResponseWriter, r *http.Request, err error, requestID st
 		}
 
 		// Write the structured error response
-		errors.WriteError(w, appErr)
+		errors.WriteErrorFor(w, r, appErr)
 		return
 	}
 
 	// Handle unexpected/unstructured errors
-	metrics.RecordError("server_error", "unhandled_error")
+	metrics.FromContext(ctx).RecordError("server_error", "unhandled_error")
 	logger.ErrorContext(ctx, "Unhandled error occurred", err, map[string]interface{}{
 		"stack_trace": string(debug.Stack()),
 	})
@@ -107,9 +147,43 @@ func handleError(w http.ResponseWriter, r *http.Request, err error, requestID st
 	// Convert to internal server error
 	internalErr := errors.NewInternalError().
 		WithCause(err).
-		WithRequestID(requestID)
+		WithRequestID(requestID).
+		WithTrace(traceFor(r))
+	reportServerError(r, internalErr)
+
+	errors.WriteErrorFor(w, r, internalErr)
+}
+
+// clientGone tells whether the client of r closed the connection, which
+// cancels the request context.
+func clientGone(r *http.Request) bool {
+	return goerrors.Is(r.Context().Err(), context.Canceled)
+}
+
+// reportServerError sends a 5xx error to the error tracker, if one is set up.
+func reportServerError(r *http.Request, appErr *errors.AppError) {
+	if appErr.StatusCode < http.StatusInternalServerError || appErr.Code == errors.ErrSimulated || !errtrack.Enabled(r.Context()) {
+		return
+	}
+	ev := requestEvent(r, appErr.RequestID, appErr.TraceID, appErr.SpanID)
+	ev.Message = appErr.Error()
+	ev.StatusCode = appErr.StatusCode
+	ev.ErrorCode = string(appErr.Code)
+	ev.Stack = errtrack.Callers(1)
+	errtrack.Report(r.Context(), ev)
+}
 
-	errors.WriteError(w, internalErr)
+// requestEvent returns an error tracker event describing r.
+func requestEvent(r *http.Request, requestID, traceID, spanID string) errtrack.Event {
+	return errtrack.Event{
+		RequestID: requestID,
+		TraceID:   traceID,
+		SpanID:    spanID,
+		Method:    r.Method,
+		Path:      r.URL.Path,
+		ClientIP:  clientIP(r),
+		UserAgent: r.UserAgent(),
+	}
 }
 
 // PanicRecoveryMiddleware recovers from panics and converts them to errors
@@ -117,12 +191,22 @@ func PanicRecoveryMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
 			if recovered := recover(); recovered != nil {
-				// Get request ID if it exists
-				var requestID string
-				if id, ok := r.Context().Value(logger.RequestIDKey).(string); ok {
-					requestID = id
-				} else {
-					requestID = generateRequestID()
+				// A handler aborting its response on purpose (a stream failing
+				// halfway): let the server drop the connection
+				if recovered == http.ErrAbortHandler {
+					panic(recovered)
+				}
+
+				// Reuse the request ID and trace already sent back to the client if there are
+				requestID := w.Header().Get(RequestIDHeader)
+				if requestID == "" {
+					requestID = requestIDFor(r)
+					w.Header().Set(RequestIDHeader, requestID)
+				}
+				traceID, spanID, ok := parseTraceHeader(w.Header().Get(TraceresponseHeader))
+				if !ok {
+					traceID, spanID = traceFor(r)
+					setTraceResponse(w, traceID, spanID)
 				}
 
 				// Log the panic
@@ -130,16 +214,30 @@ func PanicRecoveryMiddleware(next http.Handler) http.Handler {
 					"panic":       recovered,
 					"stack_trace": string(debug.Stack()),
 					"request_id":  requestID,
+					"trace_id":    traceID,
+					"span_id":     spanID,
 				})
 
 				// Create error response
 				panicErr := errors.NewInternalError().
 					WithRequestID(requestID).
+					WithTrace(traceID, spanID).
 					WithDetails(map[string]interface{}{
 						"panic_recovered": true,
 					})
 
-				errors.WriteError(w, panicErr)
+				errors.WriteErrorFor(w, r, panicErr)
+
+				if errtrack.Enabled(r.Context()) {
+					ev := requestEvent(r, requestID, traceID, spanID)
+					ev.Message = fmt.Sprint(recovered)
+					ev.Panic = true
+					ev.StatusCode = panicErr.StatusCode
+					ev.ErrorCode = string(panicErr.Code)
+					// Skip this function and runtime.gopanic to start at the panic
+					ev.Stack = errtrack.Callers(2)
+					errtrack.Report(r.Context(), ev)
+				}
 			}
 		}()
 
@@ -152,56 +250,128 @@ func RequestLoggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		startTime := time.Now()
 
-		// Create a response writer wrapper to capture status code
+		m := metrics.FromContext(r.Context())
+		m.IncRequestsInFlight()
+		defer m.DecRequestsInFlight()
+		m.RecordHTTPProtocol(r.Proto)
+
+		// Create a response writer wrapper to capture status code and size
 		wrapper := &responseWriterWrapper{
 			ResponseWriter: w,
 			statusCode:     http.StatusOK,
 		}
 
-		// Add request ID if not already present
-		var requestID string
-		if id, ok := r.Context().Value(logger.RequestIDKey).(string); ok {
-			requestID = id
-		} else {
-			requestID = generateRequestID()
-			ctx := context.WithValue(r.Context(), logger.RequestIDKey, requestID)
-			r = r.WithContext(ctx)
-		}
+		// Add request ID so every log entry for this request shares it
+		requestID := requestIDFor(r)
+		logCtx := context.WithValue(r.Context(), logger.RequestIDKey, requestID)
+		logCtx = withTrace(logCtx, r)
 
-		// Set request ID header
-		wrapper.Header().Set("X-Request-ID", requestID)
+		// Set request ID and trace headers
+		wrapper.Header().Set(RequestIDHeader, requestID)
+		traceID, spanID := traceFor(r.WithContext(logCtx))
+		setTraceResponse(wrapper, traceID, spanID)
 
-		// Execute next handler
-		next.ServeHTTP(wrapper, r)
+		// Execute next handler with a request-scoped logger
+		next.ServeHTTP(wrapper, r.WithContext(withRequestLogger(logCtx, r)))
+
+		m.RecordHTTPResponseSize(r.Method, normalizeEndpoint(r.URL.Path), wrapper.size)
+		if !wrapper.wroteHeader && clientGone(r) {
+			wrapper.statusCode = statusClientClosedRequest
+		}
 
 		// Log the completed request (skip metrics endpoint to reduce noise)
 		if r.URL.Path != "/metrics" {
 			duration := time.Since(startTime)
-			logger.LogHTTPRequest(r.Context(), r.Method, r.URL.Path, wrapper.statusCode, duration)
+			logger.LogHTTPRequest(logCtx, r.Method, r.URL.Path, wrapper.statusCode, duration)
 		}
 	})
 }
 
-// responseWriterWrapper wraps http.ResponseWriter to capture status code
+// responseWriterWrapper wraps http.ResponseWriter to capture status code and body size
 type responseWriterWrapper struct {
 	http.ResponseWriter
-	statusCode int
+	statusCode  int
+	size        int
+	wroteHeader bool
 }
 
 func (w *responseWriterWrapper) WriteHeader(code int) {
 	w.statusCode = code
+	w.wroteHeader = true
 	w.ResponseWriter.WriteHeader(code)
 }
 
-// generateRequestID generates a unique request ID using crypto/rand.
-func generateRequestID() string {
-	b := make([]byte, 8)
-	if _, err := rand.Read(b); err != nil {
-		// Fallback to time-based ID if crypto/rand fails
-		fallback := time.Now().UnixNano()
-		return fmt.Sprintf("%s-%x", time.Now().Format("20060102150405"), fallback)
+func (w *responseWriterWrapper) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	n, err := w.ResponseWriter.Write(b)
+	w.size += n
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer (Flush, deadlines).
+func (w *responseWriterWrapper) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Hijack is required by the WebSocket upgrade.
+func (w *responseWriterWrapper) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("response writer does not support hijacking")
 	}
-	return time.Now().Format("20060102150405") + "-" + hex.EncodeToString(b)
+	return hijacker.Hijack()
+}
+
+// requestLoggerKey marks contexts that already carry the request-scoped logger.
+const requestLoggerKey contextKey = "request_logger"
+
+// withRequestLogger stores a logger carrying the request ID, client IP, method and
+// path in ctx, so that handlers and services can log through logger.FromContext.
+// It is a no-op if an outer middleware already did so.
+func withRequestLogger(ctx context.Context, r *http.Request) context.Context {
+	if ctx.Value(requestLoggerKey) != nil {
+		return ctx
+	}
+	args := []any{"method", r.Method, "path", r.URL.Path}
+	if ip, ok := ctx.Value(logger.ClientIPKey).(string); ok {
+		args = append(args, "client_ip", ip)
+	}
+	ctx = logger.With(ctx, args...)
+	return context.WithValue(ctx, requestLoggerKey, true)
+}
+
+// requestIDFor returns the request ID already in the context, the inbound
+// X-Request-ID header if it is well formed, or a freshly generated one.
+func requestIDFor(r *http.Request) string {
+	if id, ok := r.Context().Value(logger.RequestIDKey).(string); ok && id != "" {
+		return id
+	}
+	if id := r.Header.Get(RequestIDHeader); validRequestID(id) {
+		return id
+	}
+	return generateRequestID()
+}
+
+// validRequestID accepts non-empty IDs made of letters, digits and "-_.:"
+// so that client-supplied values cannot inject content into logs or headers.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-' || c == '_' || c == '.' || c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// generateRequestID generates a random (version 4) UUID backed by crypto/rand.
+func generateRequestID() string {
+	return uuid.NewString()
 }
 
 var numericSegmentRe = regexp.MustCompile(`/\d+`)
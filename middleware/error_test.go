@@ -1,13 +1,20 @@
 package middleware
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/errtrack"
+	"github.com/clementhaon/sandbox-api-go/logger"
+	"github.com/google/uuid"
 )
 
 func TestErrorMiddleware(t *testing.T) {
@@ -98,6 +105,122 @@ func TestPanicRecoveryMiddleware(t *testing.T) {
 	}
 }
 
+type eventRecorder struct{ events []errtrack.Event }
+
+func (r *eventRecorder) Report(ev errtrack.Event) { r.events = append(r.events, ev) }
+
+func TestPanicRecoveryMiddleware_AbortHandler(t *testing.T) {
+	handler := PanicRecoveryMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+
+	defer func() {
+		if recovered := recover(); recovered != http.ErrAbortHandler {
+			t.Errorf("expected http.ErrAbortHandler to reach the server, got %v", recovered)
+		}
+	}()
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
+
+func TestPanicRecoveryMiddleware_ReportsPanic(t *testing.T) {
+	rec := &eventRecorder{}
+	handler := PanicRecoveryMiddleware(RequestLoggingMiddleware(ErrorMiddleware(func(w http.ResponseWriter, r *http.Request) error {
+		errtrack.SetUser(r.Context(), 7)
+		panic("nil map")
+	})))
+
+	req := httptest.NewRequest(http.MethodGet, "/tasks/1", nil)
+	req = req.WithContext(errtrack.NewContext(req.Context(), rec))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if len(rec.events) != 1 {
+		t.Fatalf("expected 1 reported event, got %d", len(rec.events))
+	}
+	ev := rec.events[0]
+	if !ev.Panic || ev.Message != "nil map" || ev.UserID != 7 || ev.Path != "/tasks/1" {
+		t.Errorf("unexpected event %+v", ev)
+	}
+	if ev.RequestID != w.Header().Get(RequestIDHeader) || ev.TraceID == "" {
+		t.Errorf("expected the request and trace IDs of the response, got %q and %q", ev.RequestID, ev.TraceID)
+	}
+	if len(ev.Stack) == 0 || !strings.Contains(ev.Stack[0].Function, "TestPanicRecoveryMiddleware_ReportsPanic") {
+		t.Errorf("expected the stack to start at the panic, got %+v", ev.Stack)
+	}
+}
+
+func TestErrorMiddleware_ReportsServerErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		report bool
+	}{
+		{name: "database error", err: errors.NewDatabaseError(), report: true},
+		{name: "unstructured error", err: fmt.Errorf("boom"), report: true},
+		{name: "service unavailable", err: errors.NewServiceUnavailableError(), report: true},
+		{name: "client error", err: errors.NewNotFoundError("Widget")},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := &eventRecorder{}
+			handler := ErrorMiddleware(func(w http.ResponseWriter, r *http.Request) error {
+				return tc.err
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/test", nil)
+			req = req.WithContext(errtrack.NewContext(req.Context(), rec))
+			handler(httptest.NewRecorder(), req)
+
+			if got := len(rec.events) == 1; got != tc.report {
+				t.Fatalf("reported %d events, want report %v", len(rec.events), tc.report)
+			}
+			if tc.report && (rec.events[0].StatusCode < 500 || rec.events[0].ErrorCode == "" || len(rec.events[0].Stack) == 0) {
+				t.Errorf("unexpected event %+v", rec.events[0])
+			}
+		})
+	}
+}
+
+func TestErrorMiddleware_ClientDisconnected(t *testing.T) {
+	rec := &eventRecorder{}
+	handler := ErrorMiddleware(func(w http.ResponseWriter, r *http.Request) error {
+		return errors.NewDatabaseError().WithCause(r.Context().Err())
+	})
+
+	ctx, cancel := context.WithCancel(errtrack.NewContext(context.Background(), rec))
+	cancel()
+	req := httptest.NewRequest(http.MethodGet, "/tasks", nil).WithContext(ctx)
+	w := httptest.NewRecorder()
+	handler(w, req)
+
+	if w.Body.Len() != 0 {
+		t.Errorf("expected no response body for a gone client, got %q", w.Body.String())
+	}
+	if len(rec.events) != 0 {
+		t.Errorf("expected no error reported, got %+v", rec.events)
+	}
+}
+
+func TestRequestLoggingMiddleware_ClientDisconnected(t *testing.T) {
+	var logs bytes.Buffer
+	handler := RequestLoggingMiddleware(ErrorMiddleware(func(w http.ResponseWriter, r *http.Request) error {
+		return fmt.Errorf("query canceled")
+	}))
+
+	ctx, cancel := context.WithCancel(logger.NewContext(context.Background(), slog.New(slog.NewJSONHandler(&logs, nil))))
+	cancel()
+	req := httptest.NewRequest(http.MethodGet, "/tasks", nil).WithContext(ctx)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if !strings.Contains(logs.String(), `"status_code":499`) {
+		t.Errorf("expected the request logged with status 499, got %s", logs.String())
+	}
+	if strings.Contains(logs.String(), `"level":"ERROR"`) {
+		t.Errorf("expected no error logged for a gone client, got %s", logs.String())
+	}
+}
+
 func TestErrorMiddleware_MaxBytesError(t *testing.T) {
 	handler := ErrorHandler(func(w http.ResponseWriter, r *http.Request) error {
 		return &http.MaxBytesError{Limit: 1024}
@@ -125,3 +248,155 @@ func TestErrorMiddleware_MaxBytesError(t *testing.T) {
 		t.Errorf("got error code %q, want %q", code, errors.ErrPayloadTooLarge)
 	}
 }
+
+func TestErrorMiddleware_RequestID(t *testing.T) {
+	var ctxRequestID string
+	handler := ErrorMiddleware(func(w http.ResponseWriter, r *http.Request) error {
+		ctxRequestID, _ = r.Context().Value(logger.RequestIDKey).(string)
+		return errors.NewNotFoundError("Widget")
+	})
+
+	tests := []struct {
+		name    string
+		inbound string
+		want    string
+	}{
+		{name: "honors valid inbound ID", inbound: "client-abc_123", want: "client-abc_123"},
+		{name: "generates UUID when missing", inbound: ""},
+		{name: "replaces malformed inbound ID", inbound: "bad id\nforged: header"},
+		{name: "replaces oversized inbound ID", inbound: strings.Repeat("a", maxRequestIDLength+1)},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/test", nil)
+			if tc.inbound != "" {
+				req.Header.Set(RequestIDHeader, tc.inbound)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			got := rec.Header().Get(RequestIDHeader)
+			if tc.want != "" {
+				if got != tc.want {
+					t.Errorf("got request ID %q, want %q", got, tc.want)
+				}
+			} else if _, err := uuid.Parse(got); err != nil {
+				t.Errorf("expected generated UUID, got %q", got)
+			}
+
+			if ctxRequestID != got {
+				t.Errorf("context request ID %q does not match header %q", ctxRequestID, got)
+			}
+
+			if rid := errorRequestID(t, rec); rid != got {
+				t.Errorf("error response request_id %q does not match header %q", rid, got)
+			}
+		})
+	}
+}
+
+func TestRequestLoggingMiddleware_SharesRequestIDWithErrorMiddleware(t *testing.T) {
+	inner := ErrorMiddleware(func(w http.ResponseWriter, r *http.Request) error {
+		return errors.NewNotFoundError("Widget")
+	})
+	handler := RequestLoggingMiddleware(inner)
+
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rid := errorRequestID(t, rec); rid != rec.Header().Get(RequestIDHeader) {
+		t.Errorf("error response request_id %q does not match header %q", rid, rec.Header().Get(RequestIDHeader))
+	}
+}
+
+func TestErrorMiddleware_ETagOnReads(t *testing.T) {
+	handler := ErrorMiddleware(func(w http.ResponseWriter, r *http.Request) error {
+		w.Header().Set("ETag", `W/"abc"`)
+		w.WriteHeader(http.StatusOK)
+		return nil
+	})
+
+	for method, want := range map[string]string{
+		http.MethodGet:    `W/"abc"`,
+		http.MethodHead:   `W/"abc"`,
+		http.MethodPost:   "",
+		http.MethodPut:    "",
+		http.MethodDelete: "",
+	} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(method, "/tasks/1", nil))
+		if got := rec.Header().Get("ETag"); got != want {
+			t.Errorf("%s: expected ETag %q, got %q", method, want, got)
+		}
+	}
+}
+
+func errorRequestID(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+	var body errors.ErrorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil || body.Error == nil {
+		t.Fatalf("failed to decode error response: %v", err)
+	}
+	return body.Error.RequestID
+}
+
+func TestErrorMiddleware_LocalizesMessages(t *testing.T) {
+	handler := ErrorMiddleware(func(w http.ResponseWriter, r *http.Request) error {
+		return errors.NewForbiddenError()
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Language", "fr-FR,fr;q=0.9,en;q=0.8")
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+
+	if lang := rec.Header().Get("Content-Language"); lang != "fr" {
+		t.Errorf("expected Content-Language fr, got %q", lang)
+	}
+	var body errors.ErrorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil || body.Error == nil {
+		t.Fatalf("failed to decode error response: %v", err)
+	}
+	if body.Error.Message != "Accès interdit" {
+		t.Errorf("expected French message, got %q", body.Error.Message)
+	}
+}
+
+func TestRequestLoggingMiddleware_ScopedLogger(t *testing.T) {
+	var scoped bool
+	inner := ErrorMiddleware(func(w http.ResponseWriter, r *http.Request) error {
+		scoped = r.Context().Value(requestLoggerKey) != nil
+		return nil
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/tasks", nil)
+	RequestLoggingMiddleware(inner).ServeHTTP(httptest.NewRecorder(), req)
+
+	if !scoped {
+		t.Error("expected handler context to carry the request-scoped logger")
+	}
+}
+
+func TestResponseWriterWrapper(t *testing.T) {
+	rec := httptest.NewRecorder()
+	wrapper := &responseWriterWrapper{ResponseWriter: rec, statusCode: http.StatusOK}
+
+	wrapper.WriteHeader(http.StatusCreated)
+	wrapper.Write([]byte("hello"))
+	wrapper.Write([]byte(" world"))
+
+	if wrapper.statusCode != http.StatusCreated {
+		t.Errorf("expected status 201, got %d", wrapper.statusCode)
+	}
+	if wrapper.size != 11 {
+		t.Errorf("expected size 11, got %d", wrapper.size)
+	}
+	if wrapper.Unwrap() != rec {
+		t.Error("expected Unwrap to return the underlying writer")
+	}
+	if _, _, err := wrapper.Hijack(); err == nil {
+		t.Error("expected error when the underlying writer cannot be hijacked")
+	}
+}
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// noETagWriter drops the ETag of the response it writes. respond.WriteJSON
+// sets one without seeing the request, but it only identifies a resource
+// in the answer to a GET or HEAD.
+type noETagWriter struct {
+	http.ResponseWriter
+}
+
+// withoutETag wraps w for requests other than GET and HEAD.
+func withoutETag(w http.ResponseWriter, r *http.Request) http.ResponseWriter {
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		return w
+	}
+	return &noETagWriter{ResponseWriter: w}
+}
+
+func (w *noETagWriter) WriteHeader(code int) {
+	w.Header().Del("ETag")
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *noETagWriter) Write(b []byte) (int, error) {
+	w.Header().Del("ETag")
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *noETagWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Hijack passes hijacking through, as responseWriterWrapper does.
+func (w *noETagWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("response writer does not support hijacking")
+	}
+	return hijacker.Hijack()
+}
@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/logger"
+)
+
+// NewIPFilter restricts requests under the given path prefixes to client IPs
+// in allow (any IP when allow is empty) and not in deny. Other paths pass
+// through. It relies on the client IP resolved by NewClientIPMiddleware, so
+// forwarded addresses are only trusted from trusted proxies.
+func NewIPFilter(allow, deny []netip.Prefix, prefixes ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !underPrefix(r.URL.Path, prefixes) || ipAllowed(clientIP(r), allow, deny) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			logger.WarnContext(r.Context(), "Request blocked by IP filter", map[string]interface{}{
+				"client_ip": clientIP(r),
+				"path":      r.URL.Path,
+			})
+			errors.WriteErrorFor(w, r, errors.NewForbiddenError().WithRequestID(requestIDFor(r)).WithTrace(traceFor(r)))
+		})
+	}
+}
+
+// underPrefix reports whether path is one of prefixes or below one of them.
+func underPrefix(path string, prefixes []string) bool {
+	for _, p := range prefixes {
+		p = strings.TrimSuffix(p, "/")
+		if path == p || strings.HasPrefix(path, p+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func ipAllowed(ip string, allow, deny []netip.Prefix) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range deny {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	if len(allow) == 0 {
+		return true
+	}
+	for _, prefix := range allow {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
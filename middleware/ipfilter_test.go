@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestIPFilter(t *testing.T) {
+	allow := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+	deny := []netip.Prefix{netip.MustParsePrefix("10.6.6.0/24")}
+	handler := NewIPFilter(allow, deny, "/admin/", "/metrics")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name       string
+		path       string
+		remoteAddr string
+		wantStatus int
+	}{
+		{name: "public route from anywhere", path: "/tasks", remoteAddr: "203.0.113.7:1", wantStatus: http.StatusOK},
+		{name: "admin from allowlist", path: "/admin/audit-logs", remoteAddr: "10.1.2.3:1", wantStatus: http.StatusOK},
+		{name: "admin from outside", path: "/admin/audit-logs", remoteAddr: "203.0.113.7:1", wantStatus: http.StatusForbidden},
+		{name: "metrics from outside", path: "/metrics", remoteAddr: "203.0.113.7:1", wantStatus: http.StatusForbidden},
+		{name: "denylist wins", path: "/metrics", remoteAddr: "10.6.6.6:1", wantStatus: http.StatusForbidden},
+		{name: "prefix match is per segment", path: "/metricsx", remoteAddr: "203.0.113.7:1", wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.RemoteAddr = tt.remoteAddr
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			if w.Code != tt.wantStatus {
+				t.Errorf("expected %d, got %d", tt.wantStatus, w.Code)
+			}
+		})
+	}
+}
+
+func TestIPFilter_EmptyAllowlistOnlyDenies(t *testing.T) {
+	deny := []netip.Prefix{netip.MustParsePrefix("203.0.113.0/24")}
+	handler := NewIPFilter(nil, deny, "/admin")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/audit-logs", nil)
+	req.RemoteAddr = "198.51.100.1:1"
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected 200, got %d", w.Code)
+	}
+}
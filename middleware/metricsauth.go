@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/clementhaon/sandbox-api-go/logger"
+)
+
+// NewMetricsAuth protects the metrics endpoint with a bearer token, basic
+// auth, or either when both are configured. With neither it is a no-op.
+// Failures answer a plain-text 401 with a WWW-Authenticate challenge rather
+// than the JSON error envelope, which is what scrapers expect.
+func NewMetricsAuth(token, user, password string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if token == "" && user == "" {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if token != "" {
+				if provided, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok && secureEqual(provided, token) {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+			if user != "" {
+				if u, p, ok := r.BasicAuth(); ok && secureEqual(u, user) && secureEqual(p, password) {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			logger.WarnContext(r.Context(), "Unauthorized metrics scrape")
+			if user != "" {
+				w.Header().Add("WWW-Authenticate", `Basic realm="metrics"`)
+			}
+			if token != "" {
+				w.Header().Add("WWW-Authenticate", `Bearer realm="metrics"`)
+			}
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+		})
+	}
+}
+
+func secureEqual(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
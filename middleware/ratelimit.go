@@ -1,7 +1,6 @@
 package middleware
 
 import (
-	"net"
 	"net/http"
 	"sync"
 	"time"
@@ -35,6 +34,15 @@ func NewRateLimiter(maxRequests int, window time.Duration) *RateLimiter {
 	return rl
 }
 
+// SetLimit changes the allowance to maxRequests per window. Visitors keep
+// their tokens, capped at the new burst on their next request.
+func (rl *RateLimiter) SetLimit(maxRequests int, window time.Duration) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	rl.rate = float64(maxRequests) / window.Seconds()
+	rl.burst = maxRequests
+}
+
 // Stop terminates the cleanup goroutine.
 func (rl *RateLimiter) Stop() {
 	close(rl.stopCh)
@@ -92,14 +100,9 @@ func (rl *RateLimiter) allow(ip string) bool {
 // Limit wraps an http.HandlerFunc with per-IP rate limiting.
 func (rl *RateLimiter) Limit(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		ip, _, err := net.SplitHostPort(r.RemoteAddr)
-		if err != nil {
-			ip = r.RemoteAddr
-		}
-
-		if !rl.allow(ip) {
-			appErr := errors.NewTooManyRequestsError()
-			errors.WriteError(w, appErr)
+		if !rl.allow(clientIP(r)) {
+			appErr := errors.NewTooManyRequestsError().WithRequestID(requestIDFor(r)).WithTrace(traceFor(r))
+			errors.WriteErrorFor(w, r, appErr)
 			return
 		}
 
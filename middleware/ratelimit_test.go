@@ -78,6 +78,31 @@ func TestRateLimiter(t *testing.T) {
 		}
 	})
 
+	t.Run("set limit applies to existing visitors", func(t *testing.T) {
+		rl := NewRateLimiter(5, time.Minute)
+		defer rl.Stop()
+
+		handler := rl.Limit(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		})
+		send := func() int {
+			req := httptest.NewRequest(http.MethodGet, "/test", nil)
+			req.RemoteAddr = "10.0.0.3:12345"
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			return rec.Code
+		}
+
+		send()
+		rl.SetLimit(1, time.Minute)
+		if code := send(); code != http.StatusOK {
+			t.Errorf("got status %d, want %d", code, http.StatusOK)
+		}
+		if code := send(); code != http.StatusTooManyRequests {
+			t.Errorf("got status %d, want %d", code, http.StatusTooManyRequests)
+		}
+	})
+
 	t.Run("stop does not panic", func(t *testing.T) {
 		rl := NewRateLimiter(10, time.Second)
 		rl.Stop()
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/logger"
+	"github.com/clementhaon/sandbox-api-go/replay"
+)
+
+// NewRecordMiddleware records the requests that may change data (POST, PUT,
+// PATCH and DELETE) and succeed to rec, with their body and the user they
+// were authenticated as, so `sandbox-api replay` can play them again. The
+// body is captured as the handler reads it, and the tenant is the one the
+// tenant middleware, deeper in the chain, resolved. Failed requests changed
+// nothing and are left out; a request that cannot be recorded is logged.
+func NewRecordMiddleware(rec *replay.Recorder) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !replay.Mutating(r.Method) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			started := time.Now()
+			var body bytes.Buffer
+			if r.Body != nil && r.Body != http.NoBody {
+				r.Body = &teeReadCloser{Reader: io.TeeReader(r.Body, &body), Closer: r.Body}
+			}
+			wrapper := &responseWriterWrapper{ResponseWriter: w, statusCode: http.StatusOK}
+			r = r.WithContext(replay.NewContext(r.Context()))
+
+			next.ServeHTTP(wrapper, r)
+
+			if wrapper.statusCode >= http.StatusBadRequest {
+				return
+			}
+			entry := replay.Entry{
+				Time:        started.UTC(),
+				Method:      r.Method,
+				Path:        r.URL.RequestURI(),
+				Host:        r.Host,
+				Tenant:      replay.Tenant(r.Context()),
+				ContentType: r.Header.Get("Content-Type"),
+				User:        replay.User(r.Context()),
+				Status:      wrapper.statusCode,
+			}
+			entry.SetBody(body.Bytes())
+			if err := rec.Record(entry); err != nil {
+				logger.ErrorContext(r.Context(), "Failed to record request for replay", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/replay"
+)
+
+func TestRecordMiddleware(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "requests.log")
+	rec, err := replay.NewRecorder(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := NewRecordMiddleware(rec)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.ReadAll(r.Body)
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		replay.SetUser(r.Context(), &models.Claims{UserID: 4})
+		replay.SetTenant(r.Context(), "acme")
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/tasks", nil),
+		httptest.NewRequest(http.MethodPost, "/fail", strings.NewReader(`{}`)),
+		httptest.NewRequest(http.MethodPost, "/tasks?x=1", strings.NewReader(`{"title":"Write docs"}`)),
+		httptest.NewRequest(http.MethodPost, "/hooks/tasks?secret=s3cr3t&x=1", strings.NewReader(`{"title":"Hooked"}`)),
+		httptest.NewRequest(http.MethodPost, "/auth/login", strings.NewReader(`{"username":"alice","password":"hunter2!"}`)),
+	} {
+		req.Header.Set("Content-Type", "application/json")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	rec.Close()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	entries, err := replay.ReadLog(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("expected only the successful POSTs recorded, got %+v", entries)
+	}
+	e := entries[0]
+	if e.Method != http.MethodPost || e.Path != "/tasks?x=1" || e.Body != `{"title":"Write docs"}` || e.ContentType != "application/json" ||
+		e.Status != http.StatusCreated || e.User == nil || e.User.UserID != 4 || e.Tenant != "acme" {
+		t.Errorf("unexpected entry %+v", e)
+	}
+
+	// Secrets and passwords are not written to the log
+	if path := entries[1].Path; path != "/hooks/tasks?secret=%5BREDACTED%5D&x=1" {
+		t.Errorf("expected the secret redacted, got %s", path)
+	}
+	if body := entries[2].Body; body != `{"username":"alice","password":"[REDACTED]"}` {
+		t.Errorf("expected the password redacted, got %s", body)
+	}
+}
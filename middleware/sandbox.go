@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"cmp"
+	"math/rand/v2"
+	"net/http"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/logger"
+	"github.com/clementhaon/sandbox-api-go/models"
+)
+
+// sandboxRoll draws the number compared to the error rate of sandbox
+// settings, in [0, 1).
+var sandboxRoll = rand.Float64
+
+// SimulateSandbox applies the sandbox settings of the personal token a
+// request is made with: it waits their delay, then fails the request with
+// their status code, or at random at their error rate, without running the
+// handler. Other requests are served as is. It must be used inside an
+// authenticated handler chain.
+func SimulateSandbox(handler ErrorHandler) ErrorHandler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		claims, ok := r.Context().Value(UserContextKey).(*models.Claims)
+		if !ok || claims.Sandbox == nil || !claims.Sandbox.Simulates() {
+			return handler(w, r)
+		}
+		settings := *claims.Sandbox
+
+		if settings.DelayMS > 0 {
+			timer := time.NewTimer(time.Duration(settings.DelayMS) * time.Millisecond)
+			defer timer.Stop()
+			select {
+			case <-timer.C:
+			case <-r.Context().Done():
+				return r.Context().Err()
+			}
+		}
+
+		status := settings.StatusCode
+		if status == 0 && settings.ErrorRate > 0 && sandboxRoll() < settings.ErrorRate {
+			status = cmp.Or(settings.ErrorStatusCode, http.StatusInternalServerError)
+		}
+		if status != 0 {
+			logger.DebugContext(r.Context(), "Simulated error", map[string]interface{}{
+				"token_id":    claims.PersonalTokenID,
+				"status_code": status,
+			})
+			return errors.NewSimulatedError(status)
+		}
+		return handler(w, r)
+	}
+}
@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/models"
+)
+
+func TestSimulateSandbox(t *testing.T) {
+	defer func(roll func() float64) { sandboxRoll = roll }(sandboxRoll)
+	sandboxRoll = func() float64 { return 0.5 }
+
+	served := false
+	handler := SimulateSandbox(func(w http.ResponseWriter, r *http.Request) error {
+		served = true
+		return nil
+	})
+	request := func(sandbox *models.SandboxSettings) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/tasks", nil)
+		claims := &models.Claims{UserID: 1, PersonalTokenID: 5, Sandbox: sandbox}
+		return r.WithContext(context.WithValue(r.Context(), UserContextKey, claims))
+	}
+
+	tests := []struct {
+		name       string
+		sandbox    *models.SandboxSettings
+		wantStatus int // 0 when the handler serves the request
+	}{
+		{"no token", nil, 0},
+		{"nothing simulated", &models.SandboxSettings{}, 0},
+		{"forced status", &models.SandboxSettings{StatusCode: http.StatusTooManyRequests}, http.StatusTooManyRequests},
+		{"error rate hit", &models.SandboxSettings{ErrorRate: 0.6}, http.StatusInternalServerError},
+		{"error rate hit with status", &models.SandboxSettings{ErrorRate: 0.6, ErrorStatusCode: http.StatusBadGateway}, http.StatusBadGateway},
+		{"error rate missed", &models.SandboxSettings{ErrorRate: 0.4}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			served = false
+			err := handler(httptest.NewRecorder(), request(tt.sandbox))
+			if tt.wantStatus == 0 {
+				if err != nil || !served {
+					t.Errorf("expected the request served, got %v", err)
+				}
+				return
+			}
+			appErr, ok := errors.IsAppError(err)
+			if !ok || appErr.Code != errors.ErrSimulated || appErr.StatusCode != tt.wantStatus {
+				t.Errorf("expected a simulated %d, got %v", tt.wantStatus, err)
+			}
+			if served {
+				t.Error("expected the handler not to run")
+			}
+		})
+	}
+
+	start := time.Now()
+	if err := handler(httptest.NewRecorder(), request(&models.SandboxSettings{DelayMS: 20})); err != nil || !served {
+		t.Fatalf("expected the request served, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("expected a 20ms delay, took %s", elapsed)
+	}
+
+	// A client going away stops the delay
+	r := request(&models.SandboxSettings{DelayMS: 10000})
+	ctx, cancel := context.WithCancel(r.Context())
+	cancel()
+	if err := handler(httptest.NewRecorder(), r.WithContext(ctx)); err != context.Canceled {
+		t.Errorf("expected the delay canceled, got %v", err)
+	}
+}
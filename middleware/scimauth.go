@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/clementhaon/sandbox-api-go/logger"
+	"github.com/clementhaon/sandbox-api-go/scim"
+)
+
+// NewSCIMAuth protects the SCIM endpoints with the bearer token given to
+// identity providers. Failures answer a SCIM error rather than the API's
+// envelope, which is what provisioning clients parse.
+func NewSCIMAuth(token string) func(ErrorHandler) ErrorHandler {
+	return func(next ErrorHandler) ErrorHandler {
+		return func(w http.ResponseWriter, r *http.Request) error {
+			provided, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || token == "" || !secureEqual(provided, token) {
+				logger.WarnContext(r.Context(), "Unauthorized SCIM request")
+				w.Header().Set("WWW-Authenticate", `Bearer realm="scim"`)
+				scim.WriteError(w, scim.NewError(http.StatusUnauthorized, "", "Invalid or missing bearer token"))
+				return nil
+			}
+			return next(w, r)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/clementhaon/sandbox-api-go/scim"
+)
+
+func TestSCIMAuth(t *testing.T) {
+	token := "scim-token-0123456789abcdef0123456789"
+	handler := NewSCIMAuth(token)(func(w http.ResponseWriter, r *http.Request) error {
+		w.WriteHeader(http.StatusOK)
+		return nil
+	})
+
+	tests := []struct {
+		name       string
+		auth       string
+		wantStatus int
+	}{
+		{"valid token", "Bearer " + token, http.StatusOK},
+		{"wrong token", "Bearer nope", http.StatusUnauthorized},
+		{"basic auth", "Basic c2NpbTpzY2lt", http.StatusUnauthorized},
+		{"missing token", "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/scim/v2/Users", nil)
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			w := httptest.NewRecorder()
+			if err := handler(w, req); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusUnauthorized {
+				return
+			}
+			var body scim.Error
+			if err := json.NewDecoder(w.Body).Decode(&body); err != nil || body.Status != "401" {
+				t.Errorf("expected a SCIM error, got %+v (%v)", body, err)
+			}
+			if w.Header().Get("Content-Type") != scim.ContentType {
+				t.Errorf("Content-Type = %q", w.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
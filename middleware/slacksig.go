@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/logger"
+	"github.com/clementhaon/sandbox-api-go/slack"
+)
+
+// maxSlackBody bounds the slash command forms read to check their signature.
+const maxSlackBody = 64 << 10
+
+// NewSlackSignature only lets through requests signed with the Slack app's
+// signing secret. The body is read to check it, then handed to next as is.
+func NewSlackSignature(secret string) func(ErrorHandler) ErrorHandler {
+	return func(next ErrorHandler) ErrorHandler {
+		return func(w http.ResponseWriter, r *http.Request) error {
+			body, err := io.ReadAll(io.LimitReader(r.Body, maxSlackBody+1))
+			if err != nil {
+				return errors.NewBadRequestError("Could not read request body")
+			}
+			if len(body) > maxSlackBody {
+				return errors.NewPayloadTooLargeError()
+			}
+			if err := slack.Verify(secret, r.Header, body, time.Now()); err != nil {
+				logger.WarnContext(r.Context(), "Rejected Slack request", map[string]interface{}{
+					"reason": err.Error(),
+				})
+				return errors.NewUnauthorizedError("Invalid Slack signature")
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			return next(w, r)
+		}
+	}
+}
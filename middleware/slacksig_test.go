@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/slack"
+)
+
+func TestSlackSignature(t *testing.T) {
+	secret := "slack-signing-secret"
+	body := "team_id=T1&user_id=U1&command=%2Ftasks&text=help"
+	var received string
+	handler := NewSlackSignature(secret)(func(w http.ResponseWriter, r *http.Request) error {
+		b, _ := io.ReadAll(r.Body)
+		received = string(b)
+		w.WriteHeader(http.StatusOK)
+		return nil
+	})
+
+	newRequest := func(sign string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/integrations/slack/commands", strings.NewReader(body))
+		ts := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(slack.TimestampHeader, ts)
+		req.Header.Set(slack.SignatureHeader, slack.Sign(sign, ts, []byte(body)))
+		return req
+	}
+
+	w := httptest.NewRecorder()
+	if err := handler(w, newRequest(secret)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if received != body {
+		t.Errorf("expected the body passed on, got %q", received)
+	}
+
+	received = ""
+	err := handler(httptest.NewRecorder(), newRequest("wrong-secret"))
+	var appErr *errors.AppError
+	if !errors.As(err, &appErr) || appErr.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected a 401 error, got %v", err)
+	}
+	if received != "" {
+		t.Error("expected the handler not to run")
+	}
+}
@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/errtrack"
+	"github.com/clementhaon/sandbox-api-go/logger"
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/replay"
+	"github.com/clementhaon/sandbox-api-go/tenant"
+)
+
+// TenantHeader names the tenant of a request explicitly.
+const TenantHeader = "X-Tenant"
+
+// tenantCacheTTL bounds how long a resolved slug is reused without a lookup.
+const tenantCacheTTL = time.Minute
+
+// TenantResolver looks tenants up by slug.
+type TenantResolver interface {
+	GetBySlug(ctx context.Context, slug string) (models.Tenant, error)
+}
+
+type cachedTenant struct {
+	id        int
+	expiresAt time.Time
+}
+
+// NewTenantMiddleware scopes each request to a tenant. The tenant comes from
+// the X-Tenant header or, failing that, from the first label of a host under
+// baseDomain ("acme.example.com" is tenant "acme" for base "example.com").
+// Requests naming neither use the default tenant; unknown tenants get a 404.
+func NewTenantMiddleware(resolver TenantResolver, baseDomain string) func(http.Handler) http.Handler {
+	var mu sync.Mutex
+	cache := make(map[string]cachedTenant)
+
+	lookup := func(ctx context.Context, slug string) (int, error) {
+		mu.Lock()
+		entry, ok := cache[slug]
+		mu.Unlock()
+		if ok && time.Now().Before(entry.expiresAt) {
+			return entry.id, nil
+		}
+
+		t, err := resolver.GetBySlug(ctx, slug)
+		if err != nil {
+			return 0, err
+		}
+
+		mu.Lock()
+		cache[slug] = cachedTenant{id: t.ID, expiresAt: time.Now().Add(tenantCacheTTL)}
+		mu.Unlock()
+		return t.ID, nil
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			slug := tenantSlug(r, baseDomain)
+			if slug == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			id, err := lookup(r.Context(), slug)
+			if err != nil {
+				appErr, ok := errors.IsAppError(err)
+				if !ok {
+					appErr = errors.NewInternalError().WithCause(err)
+				}
+				appErr.WithRequestID(requestIDFor(r)).WithTrace(traceFor(r))
+				reportServerError(r, appErr)
+				errors.WriteErrorFor(w, r, appErr)
+				return
+			}
+
+			errtrack.SetTenant(r.Context(), id)
+			replay.SetTenant(r.Context(), slug)
+			ctx := tenant.WithID(r.Context(), id)
+			ctx = logger.With(ctx, "tenant_id", id)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// tenantSlug returns the tenant named by the request, or "" for the default.
+func tenantSlug(r *http.Request, baseDomain string) string {
+	if slug := strings.TrimSpace(r.Header.Get(TenantHeader)); slug != "" {
+		return strings.ToLower(slug)
+	}
+	if baseDomain == "" {
+		return ""
+	}
+
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	sub, ok := strings.CutSuffix(strings.ToLower(host), "."+baseDomain)
+	if !ok || sub == "" || strings.Contains(sub, ".") {
+		return ""
+	}
+	return sub
+}
@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/tenant"
+)
+
+type stubTenantResolver struct {
+	tenants map[string]int
+	lookups int
+}
+
+func (s *stubTenantResolver) GetBySlug(ctx context.Context, slug string) (models.Tenant, error) {
+	s.lookups++
+	id, ok := s.tenants[slug]
+	if !ok {
+		return models.Tenant{}, errors.NewNotFoundError("Tenant")
+	}
+	return models.Tenant{ID: id, Slug: slug}, nil
+}
+
+func TestTenantMiddleware(t *testing.T) {
+	resolver := &stubTenantResolver{tenants: map[string]int{"acme": 2, "globex": 3}}
+
+	var gotTenant int
+	handler := NewTenantMiddleware(resolver, "sandbox.test")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotTenant = tenant.FromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name       string
+		host       string
+		header     string
+		wantStatus int
+		wantTenant int
+	}{
+		{name: "no tenant uses default", host: "api.example.com", wantStatus: http.StatusOK, wantTenant: tenant.DefaultID},
+		{name: "header", host: "api.example.com", header: "acme", wantStatus: http.StatusOK, wantTenant: 2},
+		{name: "subdomain", host: "globex.sandbox.test:8080", wantStatus: http.StatusOK, wantTenant: 3},
+		{name: "header wins over subdomain", host: "globex.sandbox.test", header: "ACME", wantStatus: http.StatusOK, wantTenant: 2},
+		{name: "nested subdomain is ignored", host: "a.globex.sandbox.test", wantStatus: http.StatusOK, wantTenant: tenant.DefaultID},
+		{name: "unknown tenant", host: "initech.sandbox.test", wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotTenant = 0
+			req := httptest.NewRequest(http.MethodGet, "/tasks", nil)
+			req.Host = tt.host
+			if tt.header != "" {
+				req.Header.Set(TenantHeader, tt.header)
+			}
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+			if tt.wantStatus == http.StatusOK && gotTenant != tt.wantTenant {
+				t.Errorf("expected tenant %d, got %d", tt.wantTenant, gotTenant)
+			}
+		})
+	}
+}
+
+func TestTenantMiddleware_CachesLookups(t *testing.T) {
+	resolver := &stubTenantResolver{tenants: map[string]int{"acme": 2}}
+	handler := NewTenantMiddleware(resolver, "")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for range 3 {
+		req := httptest.NewRequest(http.MethodGet, "/tasks", nil)
+		req.Header.Set(TenantHeader, "acme")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	if resolver.lookups != 1 {
+		t.Errorf("expected 1 lookup, got %d", resolver.lookups)
+	}
+}
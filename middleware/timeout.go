@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"context"
+	goerrors "errors"
+	"net/http"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/logger"
+)
+
+// Timeout gives handlers d to answer: their context is canceled past it, so
+// database queries stop, and the failure they return becomes a 503.
+func Timeout(d time.Duration) func(ErrorHandler) ErrorHandler {
+	return func(next ErrorHandler) ErrorHandler {
+		return func(w http.ResponseWriter, r *http.Request) error {
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+
+			err := next(w, r.WithContext(ctx))
+			if err != nil && goerrors.Is(ctx.Err(), context.DeadlineExceeded) && r.Context().Err() == nil {
+				logger.WarnContext(r.Context(), "Request timed out", map[string]interface{}{
+					"timeout": d.String(),
+				})
+				return errors.NewServiceUnavailableError().WithCause(err)
+			}
+			return err
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+)
+
+func TestTimeout(t *testing.T) {
+	slow := func(w http.ResponseWriter, r *http.Request) error {
+		<-r.Context().Done()
+		return errors.NewDatabaseError().WithCause(r.Context().Err())
+	}
+
+	err := Timeout(10*time.Millisecond)(slow)(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if appErr, ok := errors.IsAppError(err); !ok || appErr.Code != errors.ErrServiceUnavailable {
+		t.Errorf("expected SERVICE_UNAVAILABLE past the timeout, got %v", err)
+	}
+
+	// A client going away is not a timeout
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+	err = Timeout(time.Second)(slow)(httptest.NewRecorder(), req)
+	if appErr, ok := errors.IsAppError(err); !ok || appErr.Code != errors.ErrDatabase {
+		t.Errorf("expected the handler's error when the client is gone, got %v", err)
+	}
+
+	fast := func(w http.ResponseWriter, r *http.Request) error { return nil }
+	if err := Timeout(time.Second)(fast)(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil)); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"github.com/clementhaon/sandbox-api-go/logger"
+)
+
+// W3C Trace Context headers: traceparent is set by the proxies and clients
+// taking part in a trace, traceresponse tells them the span of the API.
+const (
+	TraceparentHeader   = "traceparent"
+	TraceresponseHeader = "traceresponse"
+)
+
+// traceFor returns the trace and span IDs of the request: those already in
+// the context, or a new span in the trace of the traceparent header, or in
+// a new trace when there is none.
+func traceFor(r *http.Request) (traceID, spanID string) {
+	if id, ok := r.Context().Value(logger.TraceIDKey).(string); ok && id != "" {
+		spanID, _ = r.Context().Value(logger.SpanIDKey).(string)
+		return id, spanID
+	}
+	if id, _, ok := parseTraceHeader(r.Header.Get(TraceparentHeader)); ok {
+		return id, randomHex(8)
+	}
+	return randomHex(16), randomHex(8)
+}
+
+// withTrace stores the trace and span IDs of r in ctx, for logs and error
+// responses.
+func withTrace(ctx context.Context, r *http.Request) context.Context {
+	traceID, spanID := traceFor(r)
+	ctx = context.WithValue(ctx, logger.TraceIDKey, traceID)
+	return context.WithValue(ctx, logger.SpanIDKey, spanID)
+}
+
+// setTraceResponse sends the trace and span of the request back in the
+// traceresponse header.
+func setTraceResponse(w http.ResponseWriter, traceID, spanID string) {
+	w.Header().Set(TraceresponseHeader, "00-"+traceID+"-"+spanID+"-00")
+}
+
+// parseTraceHeader reads a traceparent or traceresponse header
+// ("00-<trace id>-<span id>-<flags>"), and returns false if it is malformed.
+func parseTraceHeader(header string) (traceID, spanID string, ok bool) {
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	if len(parts) < 4 || !isHex(parts[0], 2) || parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return "", "", false
+	}
+	traceID, spanID = parts[1], parts[2]
+	if !isHex(traceID, 32) || !isHex(spanID, 16) || !isHex(parts[3], 2) {
+		return "", "", false
+	}
+	if strings.Trim(traceID, "0") == "" || strings.Trim(spanID, "0") == "" {
+		return "", "", false
+	}
+	return traceID, spanID, true
+}
+
+// isHex tells whether s is n lowercase hexadecimal digits.
+func isHex(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
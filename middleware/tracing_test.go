@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+)
+
+func TestParseTraceHeader(t *testing.T) {
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	const spanID = "00f067aa0ba902b7"
+
+	tests := []struct {
+		name   string
+		header string
+		ok     bool
+	}{
+		{name: "valid", header: "00-" + traceID + "-" + spanID + "-01", ok: true},
+		{name: "future version with extra fields", header: "01-" + traceID + "-" + spanID + "-01-extra", ok: true},
+		{name: "empty", header: ""},
+		{name: "extra fields in version 00", header: "00-" + traceID + "-" + spanID + "-01-extra"},
+		{name: "invalid version", header: "ff-" + traceID + "-" + spanID + "-01"},
+		{name: "uppercase trace ID", header: "00-4BF92F3577B34DA6A3CE929D0E0E4736-" + spanID + "-01"},
+		{name: "short span ID", header: "00-" + traceID + "-00f067aa-01"},
+		{name: "zero trace ID", header: "00-00000000000000000000000000000000-" + spanID + "-01"},
+		{name: "zero span ID", header: "00-" + traceID + "-0000000000000000-01"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			gotTrace, gotSpan, ok := parseTraceHeader(tc.header)
+			if ok != tc.ok {
+				t.Fatalf("parseTraceHeader(%q) ok = %v, want %v", tc.header, ok, tc.ok)
+			}
+			if ok && (gotTrace != traceID || gotSpan != spanID) {
+				t.Errorf("got trace %q span %q", gotTrace, gotSpan)
+			}
+		})
+	}
+}
+
+func TestErrorMiddleware_TraceInErrorResponse(t *testing.T) {
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	const parentID = "00f067aa0ba902b7"
+
+	inner := ErrorMiddleware(func(w http.ResponseWriter, r *http.Request) error {
+		return errors.NewInternalError()
+	})
+	handler := RequestLoggingMiddleware(inner)
+
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.Header.Set(TraceparentHeader, "00-"+traceID+"-"+parentID+"-01")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	var body errors.ErrorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil || body.Error == nil {
+		t.Fatalf("failed to decode error response: %v", err)
+	}
+	if body.Error.TraceID != traceID {
+		t.Errorf("expected trace_id %q, got %q", traceID, body.Error.TraceID)
+	}
+	if body.Error.SpanID == "" || body.Error.SpanID == parentID {
+		t.Errorf("expected a new span_id, got %q", body.Error.SpanID)
+	}
+
+	gotTrace, gotSpan, ok := parseTraceHeader(rec.Header().Get(TraceresponseHeader))
+	if !ok || gotTrace != traceID || gotSpan != body.Error.SpanID {
+		t.Errorf("traceresponse %q does not match the error response", rec.Header().Get(TraceresponseHeader))
+	}
+}
+
+func TestErrorMiddleware_GeneratesTrace(t *testing.T) {
+	handler := ErrorMiddleware(func(w http.ResponseWriter, r *http.Request) error {
+		return errors.NewNotFoundError("Widget")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.Header.Set(TraceparentHeader, "garbage")
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+
+	var body errors.ErrorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil || body.Error == nil {
+		t.Fatalf("failed to decode error response: %v", err)
+	}
+	if !isHex(body.Error.TraceID, 32) || !isHex(body.Error.SpanID, 16) {
+		t.Errorf("expected generated trace and span IDs, got %q and %q", body.Error.TraceID, body.Error.SpanID)
+	}
+}
+
+func TestPanicRecoveryMiddleware_ReusesTrace(t *testing.T) {
+	handler := PanicRecoveryMiddleware(RequestLoggingMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})))
+
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	var body errors.ErrorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil || body.Error == nil {
+		t.Fatalf("failed to decode error response: %v", err)
+	}
+	traceID, spanID, _ := parseTraceHeader(rec.Header().Get(TraceresponseHeader))
+	if body.Error.TraceID == "" || body.Error.TraceID != traceID || body.Error.SpanID != spanID {
+		t.Errorf("panic response trace %q/%q does not match header %q", body.Error.TraceID, body.Error.SpanID, rec.Header().Get(TraceresponseHeader))
+	}
+}
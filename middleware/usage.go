@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/logger"
+	"github.com/clementhaon/sandbox-api-go/models"
+)
+
+// RequestQuotas enforces the daily request quotas of users.
+type RequestQuotas interface {
+	// CheckRequestQuota fails with QUOTA_EXCEEDED once the user reached a
+	// daily quota
+	CheckRequestQuota(ctx context.Context, userID int) error
+}
+
+// UsageRecorder counts the API usage of users.
+type UsageRecorder interface {
+	// Record counts a request of the user made with personal token
+	// tokenID, 0 for a login session
+	Record(ctx context.Context, userID, tokenID int, counts models.UsageCounts) error
+}
+
+// NewUsageTracker returns a decorator refusing the requests of users over
+// their daily quotas, with a Retry-After until the next UTC day, and
+// counting the others in usage with the bytes of their request and
+// response bodies. It must be used inside an authenticated handler chain.
+// Error responses are written outside it, so their bodies are not counted.
+// If the quotas cannot be checked, or the usage recorded, the request is
+// let through and the failure logged.
+func NewUsageTracker(quotas RequestQuotas, usage UsageRecorder) func(ErrorHandler) ErrorHandler {
+	return func(handler ErrorHandler) ErrorHandler {
+		return func(w http.ResponseWriter, r *http.Request) error {
+			claims, ok := r.Context().Value(UserContextKey).(*models.Claims)
+			if !ok {
+				return handler(w, r)
+			}
+
+			err := quotas.CheckRequestQuota(r.Context(), claims.UserID)
+			if errors.Is(err, errors.ErrQuotaExceeded) {
+				nextDay := time.Now().UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(time.Until(nextDay).Seconds()))))
+				return err
+			}
+			if err != nil {
+				logger.WarnContext(r.Context(), "Request quota unavailable", map[string]interface{}{
+					"error": err.Error(),
+				})
+			}
+
+			var body *countingReadCloser
+			if r.Body != nil && r.Body != http.NoBody {
+				body = &countingReadCloser{ReadCloser: r.Body}
+				r.Body = body
+			}
+			wrapper := &responseWriterWrapper{ResponseWriter: w, statusCode: http.StatusOK}
+
+			err = handler(wrapper, r)
+
+			counts := models.UsageCounts{Requests: 1, BytesOut: int64(wrapper.size)}
+			if body != nil {
+				counts.BytesIn = body.n
+			}
+			// The request is counted even if the client went away
+			if err := usage.Record(context.WithoutCancel(r.Context()), claims.UserID, claims.PersonalTokenID, counts); err != nil {
+				logger.WarnContext(r.Context(), "Could not record API usage", map[string]interface{}{
+					"error": err.Error(),
+				})
+			}
+			return err
+		}
+	}
+}
+
+// countingReadCloser counts the bytes read from a request body.
+type countingReadCloser struct {
+	io.ReadCloser
+	n int64
+}
+
+func (c *countingReadCloser) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	c.n += int64(n)
+	return n, err
+}
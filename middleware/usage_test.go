@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/models"
+)
+
+type requestQuotasFunc func(ctx context.Context, userID int) error
+
+func (f requestQuotasFunc) CheckRequestQuota(ctx context.Context, userID int) error {
+	return f(ctx, userID)
+}
+
+type usageRecorderFunc func(ctx context.Context, userID, tokenID int, counts models.UsageCounts) error
+
+func (f usageRecorderFunc) Record(ctx context.Context, userID, tokenID int, counts models.UsageCounts) error {
+	return f(ctx, userID, tokenID, counts)
+}
+
+func TestUsageTracker(t *testing.T) {
+	quotas := requestQuotasFunc(func(ctx context.Context, userID int) error {
+		if userID == 2 {
+			return errors.NewRequestQuotaExceededError("daily_requests", 10)
+		}
+		return nil
+	})
+	var recorded []models.UsageCounts
+	var recordedToken int
+	usage := usageRecorderFunc(func(ctx context.Context, userID, tokenID int, counts models.UsageCounts) error {
+		recorded = append(recorded, counts)
+		recordedToken = tokenID
+		return nil
+	})
+	handler := NewUsageTracker(quotas, usage)(func(w http.ResponseWriter, r *http.Request) error {
+		io.Copy(io.Discard, r.Body)
+		w.Write([]byte(`{"ok":true}`))
+		return nil
+	})
+
+	r := httptest.NewRequest(http.MethodPost, "/tasks", strings.NewReader(`{"title":"x"}`))
+	r = r.WithContext(context.WithValue(r.Context(), UserContextKey, &models.Claims{UserID: 1, PersonalTokenID: 5}))
+	if err := handler(httptest.NewRecorder(), r); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := models.UsageCounts{Requests: 1, BytesIn: 13, BytesOut: 11}
+	if len(recorded) != 1 || recorded[0] != want || recordedToken != 5 {
+		t.Errorf("expected %+v recorded for token 5, got %+v for token %d", want, recorded, recordedToken)
+	}
+
+	r = httptest.NewRequest(http.MethodGet, "/tasks", nil)
+	r = r.WithContext(context.WithValue(r.Context(), UserContextKey, &models.Claims{UserID: 2}))
+	w := httptest.NewRecorder()
+	if err := handler(w, r); !errors.Is(err, errors.ErrQuotaExceeded) {
+		t.Fatalf("expected QUOTA_EXCEEDED, got %v", err)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("expected a Retry-After header")
+	}
+	if len(recorded) != 1 {
+		t.Errorf("expected the refused request not to be counted, got %+v", recorded)
+	}
+}
@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/logger"
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/ratelimit"
+)
+
+// UserRateLimiter allows each authenticated user a number of requests per
+// second, counted in a ratelimit.Store. If the store fails the request is
+// let through, so an outage of a shared store does not take the API down
+// with it.
+type UserRateLimiter struct {
+	store ratelimit.Store
+	mu    sync.RWMutex
+	rate  float64 // requests per second; 0 lets every request through
+	burst int
+}
+
+// NewUserRateLimiter allows rate requests per second, with bursts of up to
+// burst, counted in store. A rate of 0 disables the limit.
+func NewUserRateLimiter(store ratelimit.Store, rate float64, burst int) *UserRateLimiter {
+	return &UserRateLimiter{store: store, rate: rate, burst: burst}
+}
+
+// SetLimit changes the allowance to rate requests per second with bursts of
+// up to burst; a rate of 0 disables the limit. Users keep their tokens,
+// capped at the new burst on their next request.
+func (l *UserRateLimiter) SetLimit(rate float64, burst int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.rate, l.burst = rate, burst
+}
+
+func (l *UserRateLimiter) limit() (float64, int) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.rate, l.burst
+}
+
+// Limit decorates handler with the limit. It must be used inside an
+// authenticated handler chain.
+func (l *UserRateLimiter) Limit(handler ErrorHandler) ErrorHandler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		claims, ok := r.Context().Value(UserContextKey).(*models.Claims)
+		rate, burst := l.limit()
+		if !ok || rate <= 0 {
+			return handler(w, r)
+		}
+
+		allowed, wait, err := l.store.Take(r.Context(), "user:"+strconv.Itoa(claims.UserID), rate, burst)
+		if err != nil {
+			logger.WarnContext(r.Context(), "Per-user rate limit unavailable", map[string]interface{}{
+				"error": err.Error(),
+			})
+			return handler(w, r)
+		}
+		if !allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			return errors.NewTooManyRequestsError()
+		}
+		return handler(w, r)
+	}
+}
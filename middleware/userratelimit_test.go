@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"context"
+	goerrors "errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/ratelimit"
+)
+
+// failingStore is a ratelimit.Store whose backend is down.
+type failingStore struct{}
+
+func (failingStore) Take(context.Context, string, float64, int) (bool, time.Duration, error) {
+	return false, 0, goerrors.New("connection refused")
+}
+
+func TestUserRateLimiter(t *testing.T) {
+	okHandler := func(w http.ResponseWriter, r *http.Request) error {
+		w.WriteHeader(http.StatusOK)
+		return nil
+	}
+	call := func(handler ErrorHandler, userID int) (*httptest.ResponseRecorder, error) {
+		req := httptest.NewRequest(http.MethodGet, "/tasks", nil)
+		req = req.WithContext(context.WithValue(req.Context(), UserContextKey, &models.Claims{UserID: userID}))
+		rec := httptest.NewRecorder()
+		return rec, handler(rec, req)
+	}
+
+	t.Run("limits each user to its burst", func(t *testing.T) {
+		store := ratelimit.NewMemoryStore()
+		defer store.Stop()
+		limiter := NewUserRateLimiter(store, 1, 2)
+		limited := limiter.Limit(okHandler)
+
+		for i := 0; i < 2; i++ {
+			if _, err := call(limited, 1); err != nil {
+				t.Fatalf("request %d: unexpected error: %v", i+1, err)
+			}
+		}
+		rec, err := call(limited, 1)
+		var appErr *errors.AppError
+		if !goerrors.As(err, &appErr) || appErr.Code != errors.ErrTooManyRequests {
+			t.Fatalf("expected TOO_MANY_REQUESTS, got %v", err)
+		}
+		if rec.Header().Get("Retry-After") != "1" {
+			t.Errorf("got Retry-After %q, want %q", rec.Header().Get("Retry-After"), "1")
+		}
+		if _, err := call(limited, 2); err != nil {
+			t.Errorf("expected another user to be allowed, got %v", err)
+		}
+
+		// A reload lifting the limit lets the user through at once
+		limiter.SetLimit(0, 0)
+		if _, err := call(limited, 1); err != nil {
+			t.Errorf("expected no limit once disabled, got %v", err)
+		}
+	})
+
+	t.Run("lets requests through when the store fails", func(t *testing.T) {
+		limited := NewUserRateLimiter(failingStore{}, 1, 1).Limit(okHandler)
+		for i := 0; i < 3; i++ {
+			if _, err := call(limited, 1); err != nil {
+				t.Fatalf("request %d: unexpected error: %v", i+1, err)
+			}
+		}
+	})
+}
@@ -3,6 +3,8 @@ package mocks
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"time"
 
 	"github.com/clementhaon/sandbox-api-go/database"
 	"github.com/clementhaon/sandbox-api-go/models"
@@ -16,14 +18,22 @@ type MockUserRepository struct {
 	CreateAuthFn              func(ctx context.Context, username, email, hashedPassword string) (models.User, error)
 	FindByEmailWithPasswordFn func(ctx context.Context, email string) (models.User, string, error)
 	UpdateLastLoginFn         func(ctx context.Context, userID int) error
+	UpdatePasswordHashFn      func(ctx context.Context, userID int, hashedPassword string) error
+	CountActiveSinceFn        func(ctx context.Context, since time.Time) (int, error)
+	StatsFn                   func(ctx context.Context, activeSince, signupsSince time.Time) (models.UserStats, error)
 	ListFn                    func(ctx context.Context, params models.UserListParams) ([]models.User, int, error)
+	EachFn                    func(ctx context.Context, params models.UserListParams, fn func(models.User) error) error
 	GetByIDFn                 func(ctx context.Context, id int) (models.User, error)
+	GetByUsernameFn           func(ctx context.Context, username string) (models.User, error)
+	SearchByUsernamePrefixFn  func(ctx context.Context, prefix string, limit int) ([]models.User, error)
 	ExistsFn                  func(ctx context.Context, id int) (bool, error)
 	CreateFn                  func(ctx context.Context, username, email, hashedPassword, firstName, lastName, role string) (models.User, error)
 	UpdateFn                  func(ctx context.Context, id int, req models.UpdateUserRequest) (models.User, error)
 	UpdateStatusFn            func(ctx context.Context, id int, isActive bool) (models.User, error)
 	DeleteFn                  func(ctx context.Context, id int) error
 	UpdateProfileFn           func(ctx context.Context, userID int, firstName, lastName, avatarURL sql.NullString) error
+	GetPreferencesFn          func(ctx context.Context, userID int) ([]byte, error)
+	UpdatePreferencesFn       func(ctx context.Context, userID int, prefsJSON []byte) error
 }
 
 func (m *MockUserRepository) ExistsByUsernameOrEmail(ctx context.Context, username, email string) (bool, error) {
@@ -41,12 +51,33 @@ func (m *MockUserRepository) UpdateLastLogin(ctx context.Context, userID int) er
 	}
 	return nil
 }
+func (m *MockUserRepository) UpdatePasswordHash(ctx context.Context, userID int, hashedPassword string) error {
+	if m.UpdatePasswordHashFn != nil {
+		return m.UpdatePasswordHashFn(ctx, userID, hashedPassword)
+	}
+	return nil
+}
+func (m *MockUserRepository) CountActiveSince(ctx context.Context, since time.Time) (int, error) {
+	return m.CountActiveSinceFn(ctx, since)
+}
+func (m *MockUserRepository) Stats(ctx context.Context, activeSince, signupsSince time.Time) (models.UserStats, error) {
+	return m.StatsFn(ctx, activeSince, signupsSince)
+}
 func (m *MockUserRepository) List(ctx context.Context, params models.UserListParams) ([]models.User, int, error) {
 	return m.ListFn(ctx, params)
 }
+func (m *MockUserRepository) Each(ctx context.Context, params models.UserListParams, fn func(models.User) error) error {
+	return m.EachFn(ctx, params, fn)
+}
 func (m *MockUserRepository) GetByID(ctx context.Context, id int) (models.User, error) {
 	return m.GetByIDFn(ctx, id)
 }
+func (m *MockUserRepository) GetByUsername(ctx context.Context, username string) (models.User, error) {
+	return m.GetByUsernameFn(ctx, username)
+}
+func (m *MockUserRepository) SearchByUsernamePrefix(ctx context.Context, prefix string, limit int) ([]models.User, error) {
+	return m.SearchByUsernamePrefixFn(ctx, prefix, limit)
+}
 func (m *MockUserRepository) Exists(ctx context.Context, id int) (bool, error) {
 	return m.ExistsFn(ctx, id)
 }
@@ -65,6 +96,12 @@ func (m *MockUserRepository) Delete(ctx context.Context, id int) error {
 func (m *MockUserRepository) UpdateProfile(ctx context.Context, userID int, firstName, lastName, avatarURL sql.NullString) error {
 	return m.UpdateProfileFn(ctx, userID, firstName, lastName, avatarURL)
 }
+func (m *MockUserRepository) GetPreferences(ctx context.Context, userID int) ([]byte, error) {
+	return m.GetPreferencesFn(ctx, userID)
+}
+func (m *MockUserRepository) UpdatePreferences(ctx context.Context, userID int, prefsJSON []byte) error {
+	return m.UpdatePreferencesFn(ctx, userID, prefsJSON)
+}
 func (m *MockUserRepository) WithQuerier(_ database.Querier) repository.UserRepository {
 	return m
 }
@@ -73,22 +110,53 @@ func (m *MockUserRepository) WithQuerier(_ database.Querier) repository.UserRepo
 
 type MockTaskRepository struct {
 	ListWithAssigneeFn func(ctx context.Context, columnID *int) ([]models.Task, error)
+	ListFn             func(ctx context.Context, params models.TaskListParams) ([]models.Task, error)
+	EachFn             func(ctx context.Context, params models.TaskListParams, fn func(models.Task) error) error
+	ListChangedAfterFn func(ctx context.Context, after *models.TaskCursor, limit int) ([]models.Task, error)
+	LastModifiedFn     func(ctx context.Context) (models.TaskListModified, error)
+	ListByUserFn       func(ctx context.Context, userID int) ([]models.Task, error)
 	GetByIDFn          func(ctx context.Context, id int) (models.Task, error)
+	GetByIDsFn         func(ctx context.Context, ids []int) ([]models.Task, error)
 	GetMaxOrderFn      func(ctx context.Context, columnID int) (int, error)
 	CreateFn           func(ctx context.Context, req models.CreateTaskRequest, order int, userID int) (models.Task, error)
 	ExistsFn           func(ctx context.Context, id int) (bool, error)
 	UpdateFn           func(ctx context.Context, id int, req models.UpdateTaskRequest) (models.Task, error)
 	MoveFn             func(ctx context.Context, id int, columnID int, order int) (models.Task, error)
+	SetStatusFn        func(ctx context.Context, id int, status string, from []string) (models.Task, error)
+	MoveRelativeFn     func(ctx context.Context, id int, columnID int, anchorID int, after bool) (models.Task, error)
 	ReorderFn          func(ctx context.Context, columnID int, taskIDs []int) error
 	DeleteFn           func(ctx context.Context, id int) error
+	CountByStatusFn    func(ctx context.Context) (map[string]int, error)
+	CountByStatusInTenantFn func(ctx context.Context) (map[string]int, error)
+	CountByUserFn      func(ctx context.Context, userID int) (int, error)
+	DeleteCompletedBeforeFn func(ctx context.Context, before time.Time) (int, error)
+	DeleteByUserFn          func(ctx context.Context, userID int) (int, error)
 }
 
 func (m *MockTaskRepository) ListWithAssignee(ctx context.Context, columnID *int) ([]models.Task, error) {
 	return m.ListWithAssigneeFn(ctx, columnID)
 }
+func (m *MockTaskRepository) List(ctx context.Context, params models.TaskListParams) ([]models.Task, error) {
+	return m.ListFn(ctx, params)
+}
+func (m *MockTaskRepository) Each(ctx context.Context, params models.TaskListParams, fn func(models.Task) error) error {
+	return m.EachFn(ctx, params, fn)
+}
+func (m *MockTaskRepository) ListChangedAfter(ctx context.Context, after *models.TaskCursor, limit int) ([]models.Task, error) {
+	return m.ListChangedAfterFn(ctx, after, limit)
+}
+func (m *MockTaskRepository) LastModified(ctx context.Context) (models.TaskListModified, error) {
+	return m.LastModifiedFn(ctx)
+}
+func (m *MockTaskRepository) ListByUser(ctx context.Context, userID int) ([]models.Task, error) {
+	return m.ListByUserFn(ctx, userID)
+}
 func (m *MockTaskRepository) GetByID(ctx context.Context, id int) (models.Task, error) {
 	return m.GetByIDFn(ctx, id)
 }
+func (m *MockTaskRepository) GetByIDs(ctx context.Context, ids []int) ([]models.Task, error) {
+	return m.GetByIDsFn(ctx, ids)
+}
 func (m *MockTaskRepository) GetMaxOrder(ctx context.Context, columnID int) (int, error) {
 	return m.GetMaxOrderFn(ctx, columnID)
 }
@@ -104,12 +172,33 @@ func (m *MockTaskRepository) Update(ctx context.Context, id int, req models.Upda
 func (m *MockTaskRepository) Move(ctx context.Context, id int, columnID int, order int) (models.Task, error) {
 	return m.MoveFn(ctx, id, columnID, order)
 }
+func (m *MockTaskRepository) MoveRelative(ctx context.Context, id int, columnID int, anchorID int, after bool) (models.Task, error) {
+	return m.MoveRelativeFn(ctx, id, columnID, anchorID, after)
+}
+func (m *MockTaskRepository) SetStatus(ctx context.Context, id int, status string, from []string) (models.Task, error) {
+	return m.SetStatusFn(ctx, id, status, from)
+}
 func (m *MockTaskRepository) Reorder(ctx context.Context, columnID int, taskIDs []int) error {
 	return m.ReorderFn(ctx, columnID, taskIDs)
 }
 func (m *MockTaskRepository) Delete(ctx context.Context, id int) error {
 	return m.DeleteFn(ctx, id)
 }
+func (m *MockTaskRepository) CountByStatus(ctx context.Context) (map[string]int, error) {
+	return m.CountByStatusFn(ctx)
+}
+func (m *MockTaskRepository) CountByStatusInTenant(ctx context.Context) (map[string]int, error) {
+	return m.CountByStatusInTenantFn(ctx)
+}
+func (m *MockTaskRepository) CountByUser(ctx context.Context, userID int) (int, error) {
+	return m.CountByUserFn(ctx, userID)
+}
+func (m *MockTaskRepository) DeleteCompletedBefore(ctx context.Context, before time.Time) (int, error) {
+	return m.DeleteCompletedBeforeFn(ctx, before)
+}
+func (m *MockTaskRepository) DeleteByUser(ctx context.Context, userID int) (int, error) {
+	return m.DeleteByUserFn(ctx, userID)
+}
 func (m *MockTaskRepository) WithQuerier(_ database.Querier) repository.TaskRepository {
 	return m
 }
@@ -166,10 +255,61 @@ func (m *MockColumnRepository) WithQuerier(_ database.Querier) repository.Column
 	return m
 }
 
+// --- ChecklistRepository Mock ---
+
+type MockChecklistRepository struct {
+	ListFn        func(ctx context.Context, taskID int) ([]models.ChecklistItem, error)
+	ListByTasksFn func(ctx context.Context, taskIDs []int) (map[int][]models.ChecklistItem, error)
+	CreateFn      func(ctx context.Context, taskID int, title string) (models.ChecklistItem, error)
+	UpdateFn      func(ctx context.Context, taskID int, id int, req models.UpdateChecklistItemRequest) (models.ChecklistItem, error)
+	DeleteFn      func(ctx context.Context, taskID int, id int) error
+	ReorderFn     func(ctx context.Context, taskID int, itemIDs []int) error
+}
+
+func (m *MockChecklistRepository) List(ctx context.Context, taskID int) ([]models.ChecklistItem, error) {
+	return m.ListFn(ctx, taskID)
+}
+func (m *MockChecklistRepository) ListByTasks(ctx context.Context, taskIDs []int) (map[int][]models.ChecklistItem, error) {
+	return m.ListByTasksFn(ctx, taskIDs)
+}
+func (m *MockChecklistRepository) Create(ctx context.Context, taskID int, title string) (models.ChecklistItem, error) {
+	return m.CreateFn(ctx, taskID, title)
+}
+func (m *MockChecklistRepository) Update(ctx context.Context, taskID int, id int, req models.UpdateChecklistItemRequest) (models.ChecklistItem, error) {
+	return m.UpdateFn(ctx, taskID, id, req)
+}
+func (m *MockChecklistRepository) Delete(ctx context.Context, taskID int, id int) error {
+	return m.DeleteFn(ctx, taskID, id)
+}
+func (m *MockChecklistRepository) Reorder(ctx context.Context, taskID int, itemIDs []int) error {
+	return m.ReorderFn(ctx, taskID, itemIDs)
+}
+func (m *MockChecklistRepository) WithQuerier(_ database.Querier) repository.ChecklistRepository {
+	return m
+}
+
+// --- ActivityRepository Mock ---
+
+type MockActivityRepository struct {
+	CreateFn      func(ctx context.Context, userIDs []int, a models.Activity) error
+	ListForUserFn func(ctx context.Context, userID int, before *models.TaskCursor, limit int) ([]models.Activity, error)
+}
+
+func (m *MockActivityRepository) Create(ctx context.Context, userIDs []int, a models.Activity) error {
+	return m.CreateFn(ctx, userIDs, a)
+}
+func (m *MockActivityRepository) ListForUser(ctx context.Context, userID int, before *models.TaskCursor, limit int) ([]models.Activity, error) {
+	return m.ListForUserFn(ctx, userID, before, limit)
+}
+func (m *MockActivityRepository) WithQuerier(_ database.Querier) repository.ActivityRepository {
+	return m
+}
+
 // --- TimeEntryRepository Mock ---
 
 type MockTimeEntryRepository struct {
 	ListFn                func(ctx context.Context, taskID int) ([]models.TimeEntry, error)
+	ListByUserFn          func(ctx context.Context, userID int) ([]models.TimeEntry, error)
 	TaskExistsFn          func(ctx context.Context, taskID int) (bool, error)
 	CreateFn              func(ctx context.Context, userID int, req models.CreateTimeEntryRequest) (models.TimeEntry, error)
 	AddTrackedTimeFn      func(ctx context.Context, taskID int, durationMinutes int) error
@@ -181,6 +321,9 @@ type MockTimeEntryRepository struct {
 func (m *MockTimeEntryRepository) List(ctx context.Context, taskID int) ([]models.TimeEntry, error) {
 	return m.ListFn(ctx, taskID)
 }
+func (m *MockTimeEntryRepository) ListByUser(ctx context.Context, userID int) ([]models.TimeEntry, error) {
+	return m.ListByUserFn(ctx, userID)
+}
 func (m *MockTimeEntryRepository) TaskExists(ctx context.Context, taskID int) (bool, error) {
 	return m.TaskExistsFn(ctx, taskID)
 }
@@ -214,8 +357,11 @@ func (m *MockTimeEntryRepository) WithQuerier(_ database.Querier) repository.Tim
 type MockNotificationRepository struct {
 	ListFn        func(ctx context.Context, userID int) ([]models.Notification, error)
 	MarkReadFn    func(ctx context.Context, userID int, notificationIDs []int) error
+	MarkOneReadFn func(ctx context.Context, userID int, id int) error
 	MarkAllReadFn func(ctx context.Context, userID int) (int64, error)
+	CountUnreadFn func(ctx context.Context, userID int) (int, error)
 	DeleteFn      func(ctx context.Context, userID int, id int) error
+	DeleteAllFn   func(ctx context.Context, userID int) (int64, error)
 	CreateFn      func(ctx context.Context, userID int, notifType, title, message string, dataJSON []byte) error
 }
 
@@ -225,12 +371,21 @@ func (m *MockNotificationRepository) List(ctx context.Context, userID int) ([]mo
 func (m *MockNotificationRepository) MarkRead(ctx context.Context, userID int, notificationIDs []int) error {
 	return m.MarkReadFn(ctx, userID, notificationIDs)
 }
+func (m *MockNotificationRepository) MarkOneRead(ctx context.Context, userID int, id int) error {
+	return m.MarkOneReadFn(ctx, userID, id)
+}
 func (m *MockNotificationRepository) MarkAllRead(ctx context.Context, userID int) (int64, error) {
 	return m.MarkAllReadFn(ctx, userID)
 }
+func (m *MockNotificationRepository) CountUnread(ctx context.Context, userID int) (int, error) {
+	return m.CountUnreadFn(ctx, userID)
+}
 func (m *MockNotificationRepository) Delete(ctx context.Context, userID int, id int) error {
 	return m.DeleteFn(ctx, userID, id)
 }
+func (m *MockNotificationRepository) DeleteAll(ctx context.Context, userID int) (int64, error) {
+	return m.DeleteAllFn(ctx, userID)
+}
 func (m *MockNotificationRepository) Create(ctx context.Context, userID int, notifType, title, message string, dataJSON []byte) error {
 	return m.CreateFn(ctx, userID, notifType, title, message, dataJSON)
 }
@@ -243,6 +398,7 @@ func (m *MockNotificationRepository) WithQuerier(_ database.Querier) repository.
 type MockMediaRepository struct {
 	CreateFn       func(ctx context.Context, userID int, objectKey, bucketName, originalFilename, mimeType string, fileSize int64) (models.Media, error)
 	CountFn        func(ctx context.Context, userID int) (int, error)
+	TotalSizeFn    func(ctx context.Context, userID int) (int64, error)
 	ListFn         func(ctx context.Context, userID int, limit, offset int) ([]models.Media, error)
 	GetByIDFn      func(ctx context.Context, userID int, mediaID int) (models.Media, error)
 	GetObjectKeyFn func(ctx context.Context, userID int, mediaID int) (string, error)
@@ -255,6 +411,9 @@ func (m *MockMediaRepository) Create(ctx context.Context, userID int, objectKey,
 func (m *MockMediaRepository) Count(ctx context.Context, userID int) (int, error) {
 	return m.CountFn(ctx, userID)
 }
+func (m *MockMediaRepository) TotalSize(ctx context.Context, userID int) (int64, error) {
+	return m.TotalSizeFn(ctx, userID)
+}
 func (m *MockMediaRepository) List(ctx context.Context, userID int, limit, offset int) ([]models.Media, error) {
 	return m.ListFn(ctx, userID, limit, offset)
 }
@@ -270,3 +429,276 @@ func (m *MockMediaRepository) Delete(ctx context.Context, userID int, mediaID in
 func (m *MockMediaRepository) WithQuerier(_ database.Querier) repository.MediaRepository {
 	return m
 }
+
+// --- AuditRepository Mock ---
+
+type MockAuditRepository struct {
+	CreateFn          func(ctx context.Context, log models.AuditLog) error
+	ListFn            func(ctx context.Context, params models.AuditLogListParams) ([]models.AuditLog, int, error)
+	DeleteOlderThanFn func(ctx context.Context, before time.Time) (int64, error)
+}
+
+func (m *MockAuditRepository) Create(ctx context.Context, log models.AuditLog) error {
+	return m.CreateFn(ctx, log)
+}
+func (m *MockAuditRepository) List(ctx context.Context, params models.AuditLogListParams) ([]models.AuditLog, int, error) {
+	return m.ListFn(ctx, params)
+}
+func (m *MockAuditRepository) DeleteOlderThan(ctx context.Context, before time.Time) (int64, error) {
+	return m.DeleteOlderThanFn(ctx, before)
+}
+func (m *MockAuditRepository) WithQuerier(_ database.Querier) repository.AuditRepository {
+	return m
+}
+
+// --- EmailChangeRepository Mock ---
+
+type MockEmailChangeRepository struct {
+	UpsertFn         func(ctx context.Context, change models.EmailChange) error
+	GetByTokenHashFn func(ctx context.Context, tokenHash string) (models.EmailChange, error)
+	DeleteFn         func(ctx context.Context, userID int) error
+}
+
+func (m *MockEmailChangeRepository) Upsert(ctx context.Context, change models.EmailChange) error {
+	return m.UpsertFn(ctx, change)
+}
+func (m *MockEmailChangeRepository) GetByTokenHash(ctx context.Context, tokenHash string) (models.EmailChange, error) {
+	return m.GetByTokenHashFn(ctx, tokenHash)
+}
+func (m *MockEmailChangeRepository) Delete(ctx context.Context, userID int) error {
+	return m.DeleteFn(ctx, userID)
+}
+func (m *MockEmailChangeRepository) WithQuerier(_ database.Querier) repository.EmailChangeRepository {
+	return m
+}
+
+// --- QuotaRepository Mock ---
+
+type MockQuotaRepository struct {
+	GetFn    func(ctx context.Context, userID int) (models.QuotaOverride, error)
+	UpsertFn func(ctx context.Context, override models.QuotaOverride) error
+}
+
+func (m *MockQuotaRepository) Get(ctx context.Context, userID int) (models.QuotaOverride, error) {
+	if m.GetFn != nil {
+		return m.GetFn(ctx, userID)
+	}
+	return models.QuotaOverride{UserID: userID}, nil
+}
+func (m *MockQuotaRepository) Upsert(ctx context.Context, override models.QuotaOverride) error {
+	return m.UpsertFn(ctx, override)
+}
+func (m *MockQuotaRepository) WithQuerier(_ database.Querier) repository.QuotaRepository {
+	return m
+}
+
+// --- UsageRepository Mock ---
+
+type MockUsageRepository struct {
+	AddFn       func(ctx context.Context, userID, tokenID int, day time.Time, counts models.UsageCounts) error
+	TotalsFn    func(ctx context.Context, userID int, day time.Time) (models.UsageCounts, error)
+	ListSinceFn func(ctx context.Context, userID int, since time.Time) ([]models.UsageRecord, error)
+}
+
+func (m *MockUsageRepository) Add(ctx context.Context, userID, tokenID int, day time.Time, counts models.UsageCounts) error {
+	return m.AddFn(ctx, userID, tokenID, day, counts)
+}
+func (m *MockUsageRepository) Totals(ctx context.Context, userID int, day time.Time) (models.UsageCounts, error) {
+	return m.TotalsFn(ctx, userID, day)
+}
+func (m *MockUsageRepository) ListSince(ctx context.Context, userID int, since time.Time) ([]models.UsageRecord, error) {
+	return m.ListSinceFn(ctx, userID, since)
+}
+func (m *MockUsageRepository) WithQuerier(_ database.Querier) repository.UsageRepository {
+	return m
+}
+
+// --- AnnouncementRepository Mock ---
+
+type MockAnnouncementRepository struct {
+	ListFn       func(ctx context.Context) ([]models.Announcement, error)
+	ListActiveFn func(ctx context.Context, userID int) ([]models.Announcement, error)
+	CreateFn     func(ctx context.Context, createdBy int, req models.AnnouncementRequest) (models.Announcement, error)
+	UpdateFn     func(ctx context.Context, id int, req models.AnnouncementRequest) (models.Announcement, error)
+	DeleteFn     func(ctx context.Context, id int) error
+	DismissFn    func(ctx context.Context, id int, userID int) error
+}
+
+func (m *MockAnnouncementRepository) List(ctx context.Context) ([]models.Announcement, error) {
+	return m.ListFn(ctx)
+}
+func (m *MockAnnouncementRepository) ListActive(ctx context.Context, userID int) ([]models.Announcement, error) {
+	return m.ListActiveFn(ctx, userID)
+}
+func (m *MockAnnouncementRepository) Create(ctx context.Context, createdBy int, req models.AnnouncementRequest) (models.Announcement, error) {
+	return m.CreateFn(ctx, createdBy, req)
+}
+func (m *MockAnnouncementRepository) Update(ctx context.Context, id int, req models.AnnouncementRequest) (models.Announcement, error) {
+	return m.UpdateFn(ctx, id, req)
+}
+func (m *MockAnnouncementRepository) Delete(ctx context.Context, id int) error {
+	return m.DeleteFn(ctx, id)
+}
+func (m *MockAnnouncementRepository) Dismiss(ctx context.Context, id int, userID int) error {
+	return m.DismissFn(ctx, id, userID)
+}
+func (m *MockAnnouncementRepository) WithQuerier(_ database.Querier) repository.AnnouncementRepository {
+	return m
+}
+
+// --- InboundHookRepository Mock ---
+
+type MockInboundHookRepository struct {
+	GetFn             func(ctx context.Context, userID int) (models.InboundHook, error)
+	GetBySecretHashFn func(ctx context.Context, secretHash string) (models.InboundHook, error)
+	SetSecretFn       func(ctx context.Context, userID int, secretHash string) (models.InboundHook, error)
+	UpdateMappingFn   func(ctx context.Context, userID int, mapping models.InboundMapping) (models.InboundHook, error)
+	DeleteFn          func(ctx context.Context, userID int) error
+}
+
+func (m *MockInboundHookRepository) Get(ctx context.Context, userID int) (models.InboundHook, error) {
+	return m.GetFn(ctx, userID)
+}
+func (m *MockInboundHookRepository) GetBySecretHash(ctx context.Context, secretHash string) (models.InboundHook, error) {
+	return m.GetBySecretHashFn(ctx, secretHash)
+}
+func (m *MockInboundHookRepository) SetSecret(ctx context.Context, userID int, secretHash string) (models.InboundHook, error) {
+	return m.SetSecretFn(ctx, userID, secretHash)
+}
+func (m *MockInboundHookRepository) UpdateMapping(ctx context.Context, userID int, mapping models.InboundMapping) (models.InboundHook, error) {
+	return m.UpdateMappingFn(ctx, userID, mapping)
+}
+func (m *MockInboundHookRepository) Delete(ctx context.Context, userID int) error {
+	return m.DeleteFn(ctx, userID)
+}
+func (m *MockInboundHookRepository) WithQuerier(_ database.Querier) repository.InboundHookRepository {
+	return m
+}
+
+// --- SlackLinkRepository Mock ---
+
+type MockSlackLinkRepository struct {
+	LinkFn         func(ctx context.Context, teamID, slackUserID string, userID int) (models.SlackLink, error)
+	GetFn          func(ctx context.Context, teamID, slackUserID string) (models.SlackLink, error)
+	ListByUserFn   func(ctx context.Context, userID int) ([]models.SlackLink, error)
+	DeleteFn       func(ctx context.Context, teamID, slackUserID string) error
+	DeleteByUserFn func(ctx context.Context, userID int) error
+}
+
+func (m *MockSlackLinkRepository) Link(ctx context.Context, teamID, slackUserID string, userID int) (models.SlackLink, error) {
+	return m.LinkFn(ctx, teamID, slackUserID, userID)
+}
+func (m *MockSlackLinkRepository) Get(ctx context.Context, teamID, slackUserID string) (models.SlackLink, error) {
+	return m.GetFn(ctx, teamID, slackUserID)
+}
+func (m *MockSlackLinkRepository) ListByUser(ctx context.Context, userID int) ([]models.SlackLink, error) {
+	return m.ListByUserFn(ctx, userID)
+}
+func (m *MockSlackLinkRepository) Delete(ctx context.Context, teamID, slackUserID string) error {
+	return m.DeleteFn(ctx, teamID, slackUserID)
+}
+func (m *MockSlackLinkRepository) DeleteByUser(ctx context.Context, userID int) error {
+	return m.DeleteByUserFn(ctx, userID)
+}
+func (m *MockSlackLinkRepository) WithQuerier(_ database.Querier) repository.SlackLinkRepository {
+	return m
+}
+
+// --- SessionRepository Mock ---
+
+type MockSessionRepository struct {
+	CreateFn               func(ctx context.Context, session models.Session) (models.Session, error)
+	CountByDeviceFn        func(ctx context.Context, userID int, ipAddress, userAgent string) (int, int, error)
+	GetByRevokeTokenHashFn func(ctx context.Context, hash string) (models.Session, error)
+	RevokeFn               func(ctx context.Context, id int) error
+	DeleteExpiredFn        func(ctx context.Context, userID int, before time.Time) error
+}
+
+func (m *MockSessionRepository) Create(ctx context.Context, session models.Session) (models.Session, error) {
+	return m.CreateFn(ctx, session)
+}
+func (m *MockSessionRepository) CountByDevice(ctx context.Context, userID int, ipAddress, userAgent string) (int, int, error) {
+	return m.CountByDeviceFn(ctx, userID, ipAddress, userAgent)
+}
+func (m *MockSessionRepository) GetByRevokeTokenHash(ctx context.Context, hash string) (models.Session, error) {
+	return m.GetByRevokeTokenHashFn(ctx, hash)
+}
+func (m *MockSessionRepository) Revoke(ctx context.Context, id int) error {
+	return m.RevokeFn(ctx, id)
+}
+func (m *MockSessionRepository) DeleteExpired(ctx context.Context, userID int, before time.Time) error {
+	return m.DeleteExpiredFn(ctx, userID, before)
+}
+func (m *MockSessionRepository) WithQuerier(_ database.Querier) repository.SessionRepository {
+	return m
+}
+
+// --- PersonalTokenRepository Mock ---
+
+type MockPersonalTokenRepository struct {
+	CreateFn        func(ctx context.Context, token models.PersonalToken) (models.PersonalToken, error)
+	GetByHashFn     func(ctx context.Context, hash string) (models.PersonalToken, error)
+	ListByUserFn    func(ctx context.Context, userID int) ([]models.PersonalToken, error)
+	MarkUsedFn      func(ctx context.Context, id int) error
+	UpdateSandboxFn func(ctx context.Context, userID, id int, settings models.SandboxSettings) error
+	DeleteFn        func(ctx context.Context, userID, id int) error
+}
+
+func (m *MockPersonalTokenRepository) Create(ctx context.Context, token models.PersonalToken) (models.PersonalToken, error) {
+	return m.CreateFn(ctx, token)
+}
+func (m *MockPersonalTokenRepository) GetByHash(ctx context.Context, hash string) (models.PersonalToken, error) {
+	return m.GetByHashFn(ctx, hash)
+}
+func (m *MockPersonalTokenRepository) ListByUser(ctx context.Context, userID int) ([]models.PersonalToken, error) {
+	return m.ListByUserFn(ctx, userID)
+}
+func (m *MockPersonalTokenRepository) MarkUsed(ctx context.Context, id int) error {
+	return m.MarkUsedFn(ctx, id)
+}
+func (m *MockPersonalTokenRepository) UpdateSandbox(ctx context.Context, userID, id int, settings models.SandboxSettings) error {
+	return m.UpdateSandboxFn(ctx, userID, id, settings)
+}
+func (m *MockPersonalTokenRepository) Delete(ctx context.Context, userID, id int) error {
+	return m.DeleteFn(ctx, userID, id)
+}
+func (m *MockPersonalTokenRepository) WithQuerier(_ database.Querier) repository.PersonalTokenRepository {
+	return m
+}
+
+// --- BackupRepository Mock ---
+
+type MockBackupRepository struct {
+	BeginSnapshotFn  func(ctx context.Context) error
+	TablesFn         func(ctx context.Context) ([]string, error)
+	SchemaVersionFn  func(ctx context.Context) (int, error)
+	DumpFn           func(ctx context.Context, table string, fn func(row json.RawMessage) error) error
+	ClearFn          func(ctx context.Context, tables []string) error
+	InsertFn         func(ctx context.Context, table string, rows []json.RawMessage) error
+	ResetSequencesFn func(ctx context.Context, tables []string) error
+}
+
+func (m *MockBackupRepository) BeginSnapshot(ctx context.Context) error {
+	return m.BeginSnapshotFn(ctx)
+}
+func (m *MockBackupRepository) Tables(ctx context.Context) ([]string, error) {
+	return m.TablesFn(ctx)
+}
+func (m *MockBackupRepository) SchemaVersion(ctx context.Context) (int, error) {
+	return m.SchemaVersionFn(ctx)
+}
+func (m *MockBackupRepository) Dump(ctx context.Context, table string, fn func(row json.RawMessage) error) error {
+	return m.DumpFn(ctx, table, fn)
+}
+func (m *MockBackupRepository) Clear(ctx context.Context, tables []string) error {
+	return m.ClearFn(ctx, tables)
+}
+func (m *MockBackupRepository) Insert(ctx context.Context, table string, rows []json.RawMessage) error {
+	return m.InsertFn(ctx, table, rows)
+}
+func (m *MockBackupRepository) ResetSequences(ctx context.Context, tables []string) error {
+	return m.ResetSequencesFn(ctx, tables)
+}
+func (m *MockBackupRepository) WithQuerier(_ database.Querier) repository.BackupRepository {
+	return m
+}
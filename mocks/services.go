@@ -2,15 +2,21 @@ package mocks
 
 import (
 	"context"
+	"time"
 
+	"github.com/clementhaon/sandbox-api-go/events"
 	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/oidc"
+	"github.com/clementhaon/sandbox-api-go/slack"
 )
 
 // --- AuthService Mock ---
 
 type MockAuthService struct {
-	RegisterFn func(ctx context.Context, req models.RegisterRequest) (models.User, string, error)
-	LoginFn    func(ctx context.Context, req models.LoginRequest) (models.User, string, error)
+	RegisterFn         func(ctx context.Context, req models.RegisterRequest) (models.User, string, error)
+	LoginFn            func(ctx context.Context, req models.LoginRequest) (models.User, string, error)
+	ImpersonateFn      func(ctx context.Context, adminID, targetID int) (models.ImpersonationResponse, error)
+	PasswordStrengthFn func(ctx context.Context, req models.PasswordStrengthRequest) (models.PasswordStrength, error)
 }
 
 func (m *MockAuthService) Register(ctx context.Context, req models.RegisterRequest) (models.User, string, error) {
@@ -19,21 +25,33 @@ func (m *MockAuthService) Register(ctx context.Context, req models.RegisterReque
 func (m *MockAuthService) Login(ctx context.Context, req models.LoginRequest) (models.User, string, error) {
 	return m.LoginFn(ctx, req)
 }
+func (m *MockAuthService) Impersonate(ctx context.Context, adminID, targetID int) (models.ImpersonationResponse, error) {
+	return m.ImpersonateFn(ctx, adminID, targetID)
+}
+func (m *MockAuthService) PasswordStrength(ctx context.Context, req models.PasswordStrengthRequest) (models.PasswordStrength, error) {
+	return m.PasswordStrengthFn(ctx, req)
+}
 
 // --- UserService Mock ---
 
 type MockUserService struct {
-	ListFn         func(ctx context.Context, params models.UserListParams) (models.UsersListResponse, error)
-	GetByIDFn      func(ctx context.Context, id int) (models.UserResponse, error)
-	CreateFn       func(ctx context.Context, req models.CreateUserRequest) (models.UserResponse, error)
-	UpdateFn       func(ctx context.Context, id int, req models.UpdateUserRequest) (models.UserResponse, error)
-	UpdateStatusFn func(ctx context.Context, id int, status string) (models.UserResponse, error)
-	DeleteFn       func(ctx context.Context, id int) error
+	ListFn             func(ctx context.Context, params models.UserListParams) (models.UsersListResponse, error)
+	EachFn             func(ctx context.Context, params models.UserListParams, fn func(models.UserResponse) error) error
+	GetByIDFn          func(ctx context.Context, id int) (models.UserResponse, error)
+	CreateFn           func(ctx context.Context, req models.CreateUserRequest) (models.UserResponse, error)
+	UpdateFn           func(ctx context.Context, id int, req models.UpdateUserRequest) (models.UserResponse, error)
+	UpdateStatusFn     func(ctx context.Context, id int, status string) (models.UserResponse, error)
+	DeleteFn           func(ctx context.Context, id int) error
+	SearchFn           func(ctx context.Context, query string, limit int) ([]models.PublicProfile, error)
+	GetPublicProfileFn func(ctx context.Context, username string) (models.PublicProfile, error)
 }
 
 func (m *MockUserService) List(ctx context.Context, params models.UserListParams) (models.UsersListResponse, error) {
 	return m.ListFn(ctx, params)
 }
+func (m *MockUserService) Each(ctx context.Context, params models.UserListParams, fn func(models.UserResponse) error) error {
+	return m.EachFn(ctx, params, fn)
+}
 func (m *MockUserService) GetByID(ctx context.Context, id int) (models.UserResponse, error) {
 	return m.GetByIDFn(ctx, id)
 }
@@ -49,12 +67,20 @@ func (m *MockUserService) UpdateStatus(ctx context.Context, id int, status strin
 func (m *MockUserService) Delete(ctx context.Context, id int) error {
 	return m.DeleteFn(ctx, id)
 }
+func (m *MockUserService) Search(ctx context.Context, query string, limit int) ([]models.PublicProfile, error) {
+	return m.SearchFn(ctx, query, limit)
+}
+func (m *MockUserService) GetPublicProfile(ctx context.Context, username string) (models.PublicProfile, error) {
+	return m.GetPublicProfileFn(ctx, username)
+}
 
 // --- ProfileService Mock ---
 
 type MockProfileService struct {
-	GetProfileFn    func(ctx context.Context, userID int) (models.User, error)
-	UpdateProfileFn func(ctx context.Context, userID int, req models.UpdateProfileRequest) (models.User, error)
+	GetProfileFn        func(ctx context.Context, userID int) (models.User, error)
+	UpdateProfileFn     func(ctx context.Context, userID int, req models.UpdateProfileRequest) (models.User, error)
+	GetPreferencesFn    func(ctx context.Context, userID int) (models.Preferences, error)
+	UpdatePreferencesFn func(ctx context.Context, userID int, prefs models.Preferences) (models.Preferences, error)
 }
 
 func (m *MockProfileService) GetProfile(ctx context.Context, userID int) (models.User, error) {
@@ -63,44 +89,86 @@ func (m *MockProfileService) GetProfile(ctx context.Context, userID int) (models
 func (m *MockProfileService) UpdateProfile(ctx context.Context, userID int, req models.UpdateProfileRequest) (models.User, error) {
 	return m.UpdateProfileFn(ctx, userID, req)
 }
+func (m *MockProfileService) GetPreferences(ctx context.Context, userID int) (models.Preferences, error) {
+	return m.GetPreferencesFn(ctx, userID)
+}
+func (m *MockProfileService) UpdatePreferences(ctx context.Context, userID int, prefs models.Preferences) (models.Preferences, error) {
+	return m.UpdatePreferencesFn(ctx, userID, prefs)
+}
 
 // --- TaskService Mock ---
 
 type MockTaskService struct {
-	GetBoardFn func(ctx context.Context) (models.BoardResponse, error)
-	ListFn     func(ctx context.Context, columnID *int) ([]models.Task, error)
-	GetByIDFn  func(ctx context.Context, id int) (models.Task, error)
-	CreateFn   func(ctx context.Context, userID int, req models.CreateTaskRequest) (models.Task, error)
-	UpdateFn   func(ctx context.Context, id int, req models.UpdateTaskRequest) (models.Task, error)
-	MoveFn     func(ctx context.Context, id int, req models.MoveTaskRequest) (models.Task, error)
-	ReorderFn  func(ctx context.Context, columnID int, taskIDs []int) ([]models.Task, error)
-	DeleteFn   func(ctx context.Context, id int) error
+	GetBoardFn       func(ctx context.Context) (models.BoardResponse, error)
+	ListFn           func(ctx context.Context, params models.TaskListParams) ([]models.Task, error)
+	EachFn           func(ctx context.Context, params models.TaskListParams, fn func(models.Task) error) error
+	ChangesFn        func(ctx context.Context, after *models.TaskCursor, limit int) ([]models.Task, models.CursorPage, error)
+	LastModifiedFn   func(ctx context.Context) (models.TaskListModified, error)
+	GetByIDFn        func(ctx context.Context, id int) (models.Task, error)
+	GetByIDsFn       func(ctx context.Context, ids []int) ([]models.Task, []int, error)
+	IncludeFn        func(ctx context.Context, tasks []models.Task, include []string) error
+	CreateFn         func(ctx context.Context, userID int, req models.CreateTaskRequest) (models.Task, error)
+	UpdateFn         func(ctx context.Context, userID int, id int, req models.UpdateTaskRequest) (models.Task, error)
+	MoveFn           func(ctx context.Context, id int, req models.MoveTaskRequest) (models.Task, error)
+	SetStatusFn      func(ctx context.Context, userID int, id int, req models.SetTaskStatusRequest) (models.Task, error)
+	CompleteFn       func(ctx context.Context, userID int, id int) (models.Task, error)
+	ReopenFn         func(ctx context.Context, id int) (models.Task, error)
+	ReorderFn        func(ctx context.Context, columnID int, taskIDs []int) ([]models.Task, error)
+	DeleteFn         func(ctx context.Context, id int) error
+	PurgeCompletedFn func(ctx context.Context, olderThan time.Duration) (int, error)
 }
 
 func (m *MockTaskService) GetBoard(ctx context.Context) (models.BoardResponse, error) {
 	return m.GetBoardFn(ctx)
 }
-func (m *MockTaskService) List(ctx context.Context, columnID *int) ([]models.Task, error) {
-	return m.ListFn(ctx, columnID)
+func (m *MockTaskService) List(ctx context.Context, params models.TaskListParams) ([]models.Task, error) {
+	return m.ListFn(ctx, params)
+}
+func (m *MockTaskService) Each(ctx context.Context, params models.TaskListParams, fn func(models.Task) error) error {
+	return m.EachFn(ctx, params, fn)
+}
+func (m *MockTaskService) LastModified(ctx context.Context) (models.TaskListModified, error) {
+	return m.LastModifiedFn(ctx)
+}
+func (m *MockTaskService) Changes(ctx context.Context, after *models.TaskCursor, limit int) ([]models.Task, models.CursorPage, error) {
+	return m.ChangesFn(ctx, after, limit)
 }
 func (m *MockTaskService) GetByID(ctx context.Context, id int) (models.Task, error) {
 	return m.GetByIDFn(ctx, id)
 }
+func (m *MockTaskService) GetByIDs(ctx context.Context, ids []int) ([]models.Task, []int, error) {
+	return m.GetByIDsFn(ctx, ids)
+}
+func (m *MockTaskService) Include(ctx context.Context, tasks []models.Task, include []string) error {
+	return m.IncludeFn(ctx, tasks, include)
+}
 func (m *MockTaskService) Create(ctx context.Context, userID int, req models.CreateTaskRequest) (models.Task, error) {
 	return m.CreateFn(ctx, userID, req)
 }
-func (m *MockTaskService) Update(ctx context.Context, id int, req models.UpdateTaskRequest) (models.Task, error) {
-	return m.UpdateFn(ctx, id, req)
+func (m *MockTaskService) Update(ctx context.Context, userID int, id int, req models.UpdateTaskRequest) (models.Task, error) {
+	return m.UpdateFn(ctx, userID, id, req)
 }
 func (m *MockTaskService) Move(ctx context.Context, id int, req models.MoveTaskRequest) (models.Task, error) {
 	return m.MoveFn(ctx, id, req)
 }
+func (m *MockTaskService) SetStatus(ctx context.Context, userID int, id int, req models.SetTaskStatusRequest) (models.Task, error) {
+	return m.SetStatusFn(ctx, userID, id, req)
+}
+func (m *MockTaskService) Complete(ctx context.Context, userID int, id int) (models.Task, error) {
+	return m.CompleteFn(ctx, userID, id)
+}
+func (m *MockTaskService) Reopen(ctx context.Context, id int) (models.Task, error) {
+	return m.ReopenFn(ctx, id)
+}
 func (m *MockTaskService) Reorder(ctx context.Context, columnID int, taskIDs []int) ([]models.Task, error) {
 	return m.ReorderFn(ctx, columnID, taskIDs)
 }
 func (m *MockTaskService) Delete(ctx context.Context, id int) error {
 	return m.DeleteFn(ctx, id)
 }
+func (m *MockTaskService) PurgeCompleted(ctx context.Context, olderThan time.Duration) (int, error) {
+	return m.PurgeCompletedFn(ctx, olderThan)
+}
 
 // --- ColumnService Mock ---
 
@@ -128,6 +196,46 @@ func (m *MockColumnService) Reorder(ctx context.Context, columnIDs []int) ([]mod
 	return m.ReorderFn(ctx, columnIDs)
 }
 
+// --- ChecklistService Mock ---
+
+type MockChecklistService struct {
+	ListFn    func(ctx context.Context, taskID int) ([]models.ChecklistItem, error)
+	AddFn     func(ctx context.Context, taskID int, req models.CreateChecklistItemRequest) (models.ChecklistItem, error)
+	UpdateFn  func(ctx context.Context, taskID int, id int, req models.UpdateChecklistItemRequest) (models.ChecklistItem, error)
+	DeleteFn  func(ctx context.Context, taskID int, id int) error
+	ReorderFn func(ctx context.Context, taskID int, itemIDs []int) ([]models.ChecklistItem, error)
+}
+
+func (m *MockChecklistService) List(ctx context.Context, taskID int) ([]models.ChecklistItem, error) {
+	return m.ListFn(ctx, taskID)
+}
+func (m *MockChecklistService) Add(ctx context.Context, taskID int, req models.CreateChecklistItemRequest) (models.ChecklistItem, error) {
+	return m.AddFn(ctx, taskID, req)
+}
+func (m *MockChecklistService) Update(ctx context.Context, taskID int, id int, req models.UpdateChecklistItemRequest) (models.ChecklistItem, error) {
+	return m.UpdateFn(ctx, taskID, id, req)
+}
+func (m *MockChecklistService) Delete(ctx context.Context, taskID int, id int) error {
+	return m.DeleteFn(ctx, taskID, id)
+}
+func (m *MockChecklistService) Reorder(ctx context.Context, taskID int, itemIDs []int) ([]models.ChecklistItem, error) {
+	return m.ReorderFn(ctx, taskID, itemIDs)
+}
+
+// --- ActivityService Mock ---
+
+type MockActivityService struct {
+	FeedFn   func(ctx context.Context, userID int, before *models.TaskCursor, limit int) ([]models.Activity, models.CursorPage, error)
+	RecordFn func(ctx context.Context, e events.Event)
+}
+
+func (m *MockActivityService) Feed(ctx context.Context, userID int, before *models.TaskCursor, limit int) ([]models.Activity, models.CursorPage, error) {
+	return m.FeedFn(ctx, userID, before, limit)
+}
+func (m *MockActivityService) Record(ctx context.Context, e events.Event) {
+	m.RecordFn(ctx, e)
+}
+
 // --- TimeEntryService Mock ---
 
 type MockTimeEntryService struct {
@@ -151,7 +259,9 @@ func (m *MockTimeEntryService) Delete(ctx context.Context, id int) error {
 type MockNotificationService struct {
 	ListFn        func(ctx context.Context, userID int) ([]models.Notification, error)
 	MarkReadFn    func(ctx context.Context, userID int, notificationIDs []int) (int, error)
+	MarkOneReadFn func(ctx context.Context, userID int, id int) error
 	MarkAllReadFn func(ctx context.Context, userID int) (int64, error)
+	UnreadCountFn func(ctx context.Context, userID int) (int, error)
 	DeleteFn      func(ctx context.Context, userID int, id int) error
 	CreateFn      func(ctx context.Context, userID int, notifType, title, message string, data models.NotificationData) error
 }
@@ -162,9 +272,15 @@ func (m *MockNotificationService) List(ctx context.Context, userID int) ([]model
 func (m *MockNotificationService) MarkRead(ctx context.Context, userID int, notificationIDs []int) (int, error) {
 	return m.MarkReadFn(ctx, userID, notificationIDs)
 }
+func (m *MockNotificationService) MarkOneRead(ctx context.Context, userID int, id int) error {
+	return m.MarkOneReadFn(ctx, userID, id)
+}
 func (m *MockNotificationService) MarkAllRead(ctx context.Context, userID int) (int64, error) {
 	return m.MarkAllReadFn(ctx, userID)
 }
+func (m *MockNotificationService) UnreadCount(ctx context.Context, userID int) (int, error) {
+	return m.UnreadCountFn(ctx, userID)
+}
 func (m *MockNotificationService) Delete(ctx context.Context, userID int, id int) error {
 	return m.DeleteFn(ctx, userID, id)
 }
@@ -201,3 +317,387 @@ func (m *MockMediaService) GetPresignedDownloadURL(ctx context.Context, userID i
 func (m *MockMediaService) Delete(ctx context.Context, userID int, mediaID int) error {
 	return m.DeleteFn(ctx, userID, mediaID)
 }
+
+// --- ExportService Mock ---
+
+type MockExportService struct {
+	RequestExportFn  func(ctx context.Context, userID int, format string) (models.ExportJob, error)
+	GetExportFn      func(ctx context.Context, userID int, jobID string) (models.ExportJob, error)
+	DownloadExportFn func(ctx context.Context, userID int, jobID string) (models.ExportFile, error)
+}
+
+func (m *MockExportService) RequestExport(ctx context.Context, userID int, format string) (models.ExportJob, error) {
+	return m.RequestExportFn(ctx, userID, format)
+}
+func (m *MockExportService) GetExport(ctx context.Context, userID int, jobID string) (models.ExportJob, error) {
+	return m.GetExportFn(ctx, userID, jobID)
+}
+func (m *MockExportService) DownloadExport(ctx context.Context, userID int, jobID string) (models.ExportFile, error) {
+	return m.DownloadExportFn(ctx, userID, jobID)
+}
+
+// --- AuditService Mock ---
+
+type MockAuditService struct {
+	RecordFn func(ctx context.Context, entry models.AuditEntry)
+	ListFn   func(ctx context.Context, params models.AuditLogListParams) (models.AuditLogListResponse, error)
+	PurgeFn  func(ctx context.Context, retention time.Duration) (int64, error)
+}
+
+func (m *MockAuditService) Record(ctx context.Context, entry models.AuditEntry) {
+	if m.RecordFn != nil {
+		m.RecordFn(ctx, entry)
+	}
+}
+func (m *MockAuditService) List(ctx context.Context, params models.AuditLogListParams) (models.AuditLogListResponse, error) {
+	return m.ListFn(ctx, params)
+}
+func (m *MockAuditService) Purge(ctx context.Context, retention time.Duration) (int64, error) {
+	return m.PurgeFn(ctx, retention)
+}
+
+// --- AccountService Mock ---
+
+type MockAccountService struct {
+	RequestEmailChangeFn func(ctx context.Context, userID int, req models.ChangeEmailRequest) error
+	ConfirmEmailChangeFn func(ctx context.Context, req models.ConfirmEmailChangeRequest) (models.User, error)
+	ChangeUsernameFn     func(ctx context.Context, userID int, req models.ChangeUsernameRequest) (models.User, error)
+}
+
+func (m *MockAccountService) RequestEmailChange(ctx context.Context, userID int, req models.ChangeEmailRequest) error {
+	return m.RequestEmailChangeFn(ctx, userID, req)
+}
+func (m *MockAccountService) ConfirmEmailChange(ctx context.Context, req models.ConfirmEmailChangeRequest) (models.User, error) {
+	return m.ConfirmEmailChangeFn(ctx, req)
+}
+func (m *MockAccountService) ChangeUsername(ctx context.Context, userID int, req models.ChangeUsernameRequest) (models.User, error) {
+	return m.ChangeUsernameFn(ctx, userID, req)
+}
+
+// --- SessionService Mock ---
+
+type MockSessionService struct {
+	StartFn  func(ctx context.Context, user models.User, token string, expiresAt time.Time) error
+	RevokeFn func(ctx context.Context, req models.RevokeSessionRequest) error
+}
+
+func (m *MockSessionService) Start(ctx context.Context, user models.User, token string, expiresAt time.Time) error {
+	return m.StartFn(ctx, user, token, expiresAt)
+}
+func (m *MockSessionService) Revoke(ctx context.Context, req models.RevokeSessionRequest) error {
+	return m.RevokeFn(ctx, req)
+}
+
+// --- PersonalTokenService Mock ---
+
+type MockPersonalTokenService struct {
+	ListFn          func(ctx context.Context, userID int) ([]models.PersonalToken, error)
+	CreateFn        func(ctx context.Context, userID int, req models.CreatePersonalTokenRequest) (models.CreatePersonalTokenResponse, error)
+	RevokeFn        func(ctx context.Context, userID, id int) error
+	UpdateSandboxFn func(ctx context.Context, userID, id int, settings models.SandboxSettings) (models.SandboxSettings, error)
+	AuthenticateFn  func(ctx context.Context, token string) (*models.Claims, error)
+}
+
+func (m *MockPersonalTokenService) List(ctx context.Context, userID int) ([]models.PersonalToken, error) {
+	return m.ListFn(ctx, userID)
+}
+func (m *MockPersonalTokenService) Create(ctx context.Context, userID int, req models.CreatePersonalTokenRequest) (models.CreatePersonalTokenResponse, error) {
+	return m.CreateFn(ctx, userID, req)
+}
+func (m *MockPersonalTokenService) Revoke(ctx context.Context, userID, id int) error {
+	return m.RevokeFn(ctx, userID, id)
+}
+func (m *MockPersonalTokenService) UpdateSandbox(ctx context.Context, userID, id int, settings models.SandboxSettings) (models.SandboxSettings, error) {
+	return m.UpdateSandboxFn(ctx, userID, id, settings)
+}
+func (m *MockPersonalTokenService) Authenticate(ctx context.Context, token string) (*models.Claims, error) {
+	return m.AuthenticateFn(ctx, token)
+}
+
+// --- BackupService Mock ---
+
+type MockBackupService struct {
+	ListFn    func(ctx context.Context) ([]models.Backup, error)
+	CreateFn  func(ctx context.Context, userID int) (models.BackupJob, error)
+	RestoreFn func(ctx context.Context, userID int, name string) (models.BackupJob, error)
+	GetJobFn  func(ctx context.Context, jobID string) (models.BackupJob, error)
+}
+
+func (m *MockBackupService) List(ctx context.Context) ([]models.Backup, error) {
+	return m.ListFn(ctx)
+}
+func (m *MockBackupService) Create(ctx context.Context, userID int) (models.BackupJob, error) {
+	return m.CreateFn(ctx, userID)
+}
+func (m *MockBackupService) Restore(ctx context.Context, userID int, name string) (models.BackupJob, error) {
+	return m.RestoreFn(ctx, userID, name)
+}
+func (m *MockBackupService) GetJob(ctx context.Context, jobID string) (models.BackupJob, error) {
+	return m.GetJobFn(ctx, jobID)
+}
+
+// MockVerificationSender records the verification tokens it is asked to send.
+type MockVerificationSender struct {
+	SendFn func(ctx context.Context, to, token string) error
+}
+
+func (m *MockVerificationSender) SendEmailChangeVerification(ctx context.Context, to, token string) error {
+	if m.SendFn != nil {
+		return m.SendFn(ctx, to, token)
+	}
+	return nil
+}
+
+// --- StatsService Mock ---
+
+type MockStatsService struct {
+	GetFn func(ctx context.Context, days int) (models.AdminStats, error)
+}
+
+func (m *MockStatsService) Get(ctx context.Context, days int) (models.AdminStats, error) {
+	return m.GetFn(ctx, days)
+}
+
+// --- QuotaService Mock ---
+
+// MockQuotaService allows everything unless a Check function is set.
+type MockQuotaService struct {
+	GetFn               func(ctx context.Context, userID int) (models.UserQuota, error)
+	UpdateFn            func(ctx context.Context, userID int, req models.UpdateQuotaRequest) (models.UserQuota, error)
+	CheckTaskQuotaFn    func(ctx context.Context, userID int) error
+	CheckMediaQuotaFn   func(ctx context.Context, userID int, size int64) error
+	CheckRequestQuotaFn func(ctx context.Context, userID int) error
+}
+
+func (m *MockQuotaService) Get(ctx context.Context, userID int) (models.UserQuota, error) {
+	return m.GetFn(ctx, userID)
+}
+func (m *MockQuotaService) Update(ctx context.Context, userID int, req models.UpdateQuotaRequest) (models.UserQuota, error) {
+	return m.UpdateFn(ctx, userID, req)
+}
+func (m *MockQuotaService) CheckTaskQuota(ctx context.Context, userID int) error {
+	if m.CheckTaskQuotaFn != nil {
+		return m.CheckTaskQuotaFn(ctx, userID)
+	}
+	return nil
+}
+func (m *MockQuotaService) CheckMediaQuota(ctx context.Context, userID int, size int64) error {
+	if m.CheckMediaQuotaFn != nil {
+		return m.CheckMediaQuotaFn(ctx, userID, size)
+	}
+	return nil
+}
+func (m *MockQuotaService) CheckRequestQuota(ctx context.Context, userID int) error {
+	if m.CheckRequestQuotaFn != nil {
+		return m.CheckRequestQuotaFn(ctx, userID)
+	}
+	return nil
+}
+
+// --- UsageService Mock ---
+
+type MockUsageService struct {
+	RecordFn func(ctx context.Context, userID, tokenID int, counts models.UsageCounts) error
+	GetFn    func(ctx context.Context, userID, days int) (models.UsageReport, error)
+}
+
+func (m *MockUsageService) Record(ctx context.Context, userID, tokenID int, counts models.UsageCounts) error {
+	return m.RecordFn(ctx, userID, tokenID, counts)
+}
+func (m *MockUsageService) Get(ctx context.Context, userID, days int) (models.UsageReport, error) {
+	return m.GetFn(ctx, userID, days)
+}
+
+// --- ClockService Mock ---
+
+// MockClockService keeps the real time unless OffsetFn is set.
+type MockClockService struct {
+	GetFn    func(ctx context.Context, userID int) (models.SandboxClock, error)
+	SetFn    func(ctx context.Context, userID int, req models.SetSandboxClockRequest) (models.SandboxClock, error)
+	OffsetFn func(ctx context.Context, userID int) (time.Duration, error)
+}
+
+func (m *MockClockService) Get(ctx context.Context, userID int) (models.SandboxClock, error) {
+	return m.GetFn(ctx, userID)
+}
+func (m *MockClockService) Set(ctx context.Context, userID int, req models.SetSandboxClockRequest) (models.SandboxClock, error) {
+	return m.SetFn(ctx, userID, req)
+}
+func (m *MockClockService) Offset(ctx context.Context, userID int) (time.Duration, error) {
+	if m.OffsetFn != nil {
+		return m.OffsetFn(ctx, userID)
+	}
+	return 0, nil
+}
+
+// --- AnnouncementService Mock ---
+
+type MockAnnouncementService struct {
+	ListActiveFn func(ctx context.Context, userID int) ([]models.Announcement, error)
+	ListFn       func(ctx context.Context) ([]models.Announcement, error)
+	CreateFn     func(ctx context.Context, userID int, req models.AnnouncementRequest) (models.Announcement, error)
+	UpdateFn     func(ctx context.Context, id int, req models.AnnouncementRequest) (models.Announcement, error)
+	DeleteFn     func(ctx context.Context, id int) error
+	DismissFn    func(ctx context.Context, id int, userID int) error
+}
+
+func (m *MockAnnouncementService) ListActive(ctx context.Context, userID int) ([]models.Announcement, error) {
+	return m.ListActiveFn(ctx, userID)
+}
+func (m *MockAnnouncementService) List(ctx context.Context) ([]models.Announcement, error) {
+	return m.ListFn(ctx)
+}
+func (m *MockAnnouncementService) Create(ctx context.Context, userID int, req models.AnnouncementRequest) (models.Announcement, error) {
+	return m.CreateFn(ctx, userID, req)
+}
+func (m *MockAnnouncementService) Update(ctx context.Context, id int, req models.AnnouncementRequest) (models.Announcement, error) {
+	return m.UpdateFn(ctx, id, req)
+}
+func (m *MockAnnouncementService) Delete(ctx context.Context, id int) error {
+	return m.DeleteFn(ctx, id)
+}
+func (m *MockAnnouncementService) Dismiss(ctx context.Context, id int, userID int) error {
+	return m.DismissFn(ctx, id, userID)
+}
+
+// --- OIDCService Mock ---
+
+type MockOIDCService struct {
+	DiscoveryFn func() models.OIDCDiscovery
+	JWKSFn      func() oidc.JWKS
+	StartFn     func(req models.AuthorizeRequest) (string, error)
+	AuthorizeFn func(ctx context.Context, userID int, req models.AuthorizeRequest) (string, error)
+	ExchangeFn  func(ctx context.Context, req models.TokenRequest) (models.TokenResponse, error)
+	UserInfoFn  func(ctx context.Context, accessToken string) (models.UserInfo, error)
+}
+
+func (m *MockOIDCService) Discovery() models.OIDCDiscovery {
+	return m.DiscoveryFn()
+}
+func (m *MockOIDCService) JWKS() oidc.JWKS {
+	return m.JWKSFn()
+}
+func (m *MockOIDCService) Start(req models.AuthorizeRequest) (string, error) {
+	return m.StartFn(req)
+}
+func (m *MockOIDCService) Authorize(ctx context.Context, userID int, req models.AuthorizeRequest) (string, error) {
+	return m.AuthorizeFn(ctx, userID, req)
+}
+func (m *MockOIDCService) Exchange(ctx context.Context, req models.TokenRequest) (models.TokenResponse, error) {
+	return m.ExchangeFn(ctx, req)
+}
+func (m *MockOIDCService) UserInfo(ctx context.Context, accessToken string) (models.UserInfo, error) {
+	return m.UserInfoFn(ctx, accessToken)
+}
+
+// --- SCIMService Mock ---
+
+type MockSCIMService struct {
+	ListFn   func(ctx context.Context, params models.SCIMListParams) (models.SCIMListResponse, error)
+	GetFn    func(ctx context.Context, id string) (models.SCIMUser, error)
+	CreateFn func(ctx context.Context, user models.SCIMUser) (models.SCIMUser, error)
+	PatchFn  func(ctx context.Context, id string, req models.SCIMPatchRequest) (models.SCIMUser, error)
+	DeleteFn func(ctx context.Context, id string) error
+}
+
+func (m *MockSCIMService) List(ctx context.Context, params models.SCIMListParams) (models.SCIMListResponse, error) {
+	return m.ListFn(ctx, params)
+}
+func (m *MockSCIMService) Get(ctx context.Context, id string) (models.SCIMUser, error) {
+	return m.GetFn(ctx, id)
+}
+func (m *MockSCIMService) Create(ctx context.Context, user models.SCIMUser) (models.SCIMUser, error) {
+	return m.CreateFn(ctx, user)
+}
+func (m *MockSCIMService) Patch(ctx context.Context, id string, req models.SCIMPatchRequest) (models.SCIMUser, error) {
+	return m.PatchFn(ctx, id, req)
+}
+func (m *MockSCIMService) Delete(ctx context.Context, id string) error {
+	return m.DeleteFn(ctx, id)
+}
+
+// --- InboundService Mock ---
+
+type MockInboundService struct {
+	SettingsFn      func(ctx context.Context, userID int) (models.InboundSettings, error)
+	RotateSecretFn  func(ctx context.Context, userID int) (models.InboundSecretResponse, error)
+	UpdateMappingFn func(ctx context.Context, userID int, mapping models.InboundMapping) (models.InboundSettings, error)
+	DisableFn       func(ctx context.Context, userID int) error
+	ReceiveFn       func(ctx context.Context, secret string, payload map[string]interface{}) (models.Task, error)
+}
+
+func (m *MockInboundService) Settings(ctx context.Context, userID int) (models.InboundSettings, error) {
+	return m.SettingsFn(ctx, userID)
+}
+func (m *MockInboundService) RotateSecret(ctx context.Context, userID int) (models.InboundSecretResponse, error) {
+	return m.RotateSecretFn(ctx, userID)
+}
+func (m *MockInboundService) UpdateMapping(ctx context.Context, userID int, mapping models.InboundMapping) (models.InboundSettings, error) {
+	return m.UpdateMappingFn(ctx, userID, mapping)
+}
+func (m *MockInboundService) Disable(ctx context.Context, userID int) error {
+	return m.DisableFn(ctx, userID)
+}
+func (m *MockInboundService) Receive(ctx context.Context, secret string, payload map[string]interface{}) (models.Task, error) {
+	return m.ReceiveFn(ctx, secret, payload)
+}
+
+// --- SlackService Mock ---
+
+type MockSlackService struct {
+	CreateLinkCodeFn func(ctx context.Context, userID int) (models.SlackLinkCodeResponse, error)
+	ListLinksFn      func(ctx context.Context, userID int) ([]models.SlackLink, error)
+	UnlinkFn         func(ctx context.Context, userID int) error
+	HandleCommandFn  func(ctx context.Context, cmd slack.Command) (slack.Message, error)
+}
+
+func (m *MockSlackService) CreateLinkCode(ctx context.Context, userID int) (models.SlackLinkCodeResponse, error) {
+	return m.CreateLinkCodeFn(ctx, userID)
+}
+func (m *MockSlackService) ListLinks(ctx context.Context, userID int) ([]models.SlackLink, error) {
+	return m.ListLinksFn(ctx, userID)
+}
+func (m *MockSlackService) Unlink(ctx context.Context, userID int) error {
+	return m.UnlinkFn(ctx, userID)
+}
+func (m *MockSlackService) HandleCommand(ctx context.Context, cmd slack.Command) (slack.Message, error) {
+	return m.HandleCommandFn(ctx, cmd)
+}
+
+// --- SandboxService Mock ---
+
+type MockSandboxService struct {
+	ResetFn        func(ctx context.Context, userID int) (models.SandboxResetResponse, error)
+	LoadScenarioFn func(ctx context.Context, userID int, name string) (models.SandboxScenarioResponse, error)
+}
+
+func (m *MockSandboxService) Reset(ctx context.Context, userID int) (models.SandboxResetResponse, error) {
+	return m.ResetFn(ctx, userID)
+}
+func (m *MockSandboxService) LoadScenario(ctx context.Context, userID int, name string) (models.SandboxScenarioResponse, error) {
+	return m.LoadScenarioFn(ctx, userID, name)
+}
+
+// --- WebhookService Mock ---
+
+type MockWebhookService struct {
+	ListFn   func(ctx context.Context, userID int) ([]models.Webhook, error)
+	CreateFn func(ctx context.Context, userID int, req models.CreateWebhookRequest) (models.CreateWebhookResponse, error)
+	DeleteFn func(ctx context.Context, userID, id int) error
+	TestFn   func(ctx context.Context, userID, id int) (models.WebhookTestResult, error)
+}
+
+func (m *MockWebhookService) List(ctx context.Context, userID int) ([]models.Webhook, error) {
+	return m.ListFn(ctx, userID)
+}
+func (m *MockWebhookService) Create(ctx context.Context, userID int, req models.CreateWebhookRequest) (models.CreateWebhookResponse, error) {
+	return m.CreateFn(ctx, userID, req)
+}
+func (m *MockWebhookService) Delete(ctx context.Context, userID, id int) error {
+	return m.DeleteFn(ctx, userID, id)
+}
+func (m *MockWebhookService) Test(ctx context.Context, userID, id int) (models.WebhookTestResult, error) {
+	return m.TestFn(ctx, userID, id)
+}
+func (m *MockWebhookService) Notify(ctx context.Context, e events.Event) {}
+func (m *MockWebhookService) Stop()                                      {}
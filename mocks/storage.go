@@ -1,6 +1,8 @@
 package mocks
 
 import (
+	"io"
+
 	"github.com/minio/minio-go/v7"
 )
 
@@ -10,6 +12,9 @@ type MockStorage struct {
 	GeneratePresignedDownloadURLFn func(objectKey string) (string, error)
 	DeleteObjectFn                 func(objectKey string) error
 	GetObjectInfoFn                func(objectKey string) (*minio.ObjectInfo, error)
+	PutObjectFn                    func(objectKey string, r io.Reader, size int64, contentType string) error
+	GetObjectFn                    func(objectKey string) (io.ReadCloser, error)
+	ListObjectsFn                  func(prefix string) ([]minio.ObjectInfo, error)
 }
 
 func (m *MockStorage) GeneratePresignedUploadURL(filename, mimeType string, userID int) (string, string, error) {
@@ -27,3 +32,15 @@ func (m *MockStorage) DeleteObject(objectKey string) error {
 func (m *MockStorage) GetObjectInfo(objectKey string) (*minio.ObjectInfo, error) {
 	return m.GetObjectInfoFn(objectKey)
 }
+
+func (m *MockStorage) PutObject(objectKey string, r io.Reader, size int64, contentType string) error {
+	return m.PutObjectFn(objectKey, r, size, contentType)
+}
+
+func (m *MockStorage) GetObject(objectKey string) (io.ReadCloser, error) {
+	return m.GetObjectFn(objectKey)
+}
+
+func (m *MockStorage) ListObjects(prefix string) ([]minio.ObjectInfo, error) {
+	return m.ListObjectsFn(prefix)
+}
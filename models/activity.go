@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// Activity is an entry of a user's activity feed
+type Activity struct {
+	ID        int       `json:"id"`
+	Type      string    `json:"type"`              // task_created, task_assigned, task_completed
+	ActorID   *int      `json:"actorId,omitempty"` // nil once the actor's account is deleted
+	TaskID    *int      `json:"taskId,omitempty"`  // nil once the task is deleted
+	TaskTitle string    `json:"taskTitle"`
+	CreatedAt time.Time `json:"createdAt"`
+}
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// Announcement levels
+const (
+	AnnouncementLevelInfo        = "info"
+	AnnouncementLevelFeature     = "feature"
+	AnnouncementLevelMaintenance = "maintenance"
+)
+
+// Announcement is a message shown to every user of a tenant while it is
+// active, from StartsAt until EndsAt (open-ended if nil)
+type Announcement struct {
+	ID        int        `json:"id"`
+	Title     string     `json:"title"`
+	Body      string     `json:"body"`
+	Level     string     `json:"level"`
+	StartsAt  time.Time  `json:"startsAt"`
+	EndsAt    *time.Time `json:"endsAt"`
+	CreatedBy *int       `json:"createdBy,omitempty"` // nil once the author's account is deleted
+	CreatedAt time.Time  `json:"createdAt"`
+	UpdatedAt time.Time  `json:"updatedAt"`
+}
+
+// AnnouncementRequest creates or replaces an announcement. A missing
+// startsAt means now, and a missing endsAt leaves it open-ended.
+type AnnouncementRequest struct {
+	Title    string     `json:"title" sanitize:"text,strip_html" validate:"required,max=200"`
+	Body     string     `json:"body" sanitize:"multiline,strip_html" validate:"required,max=5000"`
+	Level    string     `json:"level" validate:"required,oneof=info feature maintenance"`
+	StartsAt *time.Time `json:"startsAt,omitempty"`
+	EndsAt   *time.Time `json:"endsAt,omitempty" validate:"afterfield=startsAt"`
+}
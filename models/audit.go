@@ -0,0 +1,78 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AuditAction constants
+const (
+	AuditActionRegister       = "auth.register"
+	AuditActionLogin          = "auth.login"
+	AuditActionLoginFailed    = "auth.login_failed"
+	AuditActionSessionRevoke  = "auth.session_revoke"
+	AuditActionTokenCreate    = "auth.token_create"
+	AuditActionTokenRevoke    = "auth.token_revoke"
+	AuditActionPasswordChange = "user.password_change"
+	AuditActionRoleChange     = "user.role_change"
+	AuditActionEmailChangeReq = "user.email_change_request"
+	AuditActionEmailChange    = "user.email_change"
+	AuditActionUsernameChange = "user.username_change"
+	AuditActionUserCreate     = "admin.user_create"
+	AuditActionUserUpdate     = "admin.user_update"
+	AuditActionUserStatus     = "admin.user_status_change"
+	AuditActionUserDelete     = "admin.user_delete"
+	AuditActionQuotaChange    = "admin.quota_change"
+	AuditActionImpersonate    = "admin.impersonate"
+	AuditActionLogLevel       = "admin.log_level_change"
+	AuditActionBackupCreate   = "admin.backup_create"
+	AuditActionBackupRestore  = "admin.backup_restore"
+	AuditActionOAuthAuthorize = "oauth.authorize"
+	AuditActionSCIMUserCreate = "scim.user_create"
+	AuditActionSCIMUserUpdate = "scim.user_update"
+	AuditActionSCIMUserDelete = "scim.user_delete"
+	AuditActionSlackLink      = "slack.link"
+	AuditActionSlackUnlink    = "slack.unlink"
+	AuditActionWebhookCreate  = "webhook.create"
+	AuditActionWebhookDelete  = "webhook.delete"
+)
+
+// AuditLog represents a recorded security event
+type AuditLog struct {
+	ID         int             `json:"id"`
+	ActorID    *int            `json:"actorId,omitempty"`
+	Action     string          `json:"action"`
+	TargetType string          `json:"targetType,omitempty"`
+	TargetID   *int            `json:"targetId,omitempty"`
+	IPAddress  string          `json:"ipAddress,omitempty"`
+	UserAgent  string          `json:"userAgent,omitempty"`
+	Metadata   json.RawMessage `json:"metadata,omitempty"`
+	CreatedAt  time.Time       `json:"createdAt"`
+}
+
+// AuditEntry describes an event to record (internal use)
+type AuditEntry struct {
+	ActorID    *int
+	Action     string
+	TargetType string
+	TargetID   *int
+	Metadata   map[string]interface{}
+}
+
+// AuditLogListParams represents query parameters for listing audit logs
+type AuditLogListParams struct {
+	Page       int
+	PageSize   int
+	Action     string
+	ActorID    *int
+	TargetType string
+	TargetID   *int
+	From       *time.Time
+	To         *time.Time
+}
+
+// AuditLogListResponse represents the paginated audit log response
+type AuditLogListResponse struct {
+	Data       []AuditLog `json:"data"`
+	Pagination Pagination `json:"pagination"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// Backup job types
+const (
+	BackupJobBackup  = "backup"
+	BackupJobRestore = "restore"
+)
+
+// Backup is a snapshot of the database stored in the bucket
+type Backup struct {
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// BackupJob represents the state of an asynchronous backup or restore
+type BackupJob struct {
+	ID          string     `json:"id"`
+	Type        string     `json:"type"`
+	Backup      string     `json:"backup"`
+	Status      string     `json:"status"`
+	Progress    int        `json:"progress"`
+	Error       string     `json:"error,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+}
@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// ChecklistItem is one step of a task's checklist
+type ChecklistItem struct {
+	ID        int       `json:"id"`
+	TaskID    int       `json:"taskId"`
+	Title     string    `json:"title"`
+	Done      bool      `json:"done"`
+	Order     int       `json:"order"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// ChecklistSummary is the progress of a task's checklist, sent with the task
+type ChecklistSummary struct {
+	Total   int `json:"total"`
+	Done    int `json:"done"`
+	Percent int `json:"percent"` // rounded down, 0 for an empty checklist
+}
+
+// NewChecklistSummary computes the completion percentage of done items out of total
+func NewChecklistSummary(total, done int) ChecklistSummary {
+	summary := ChecklistSummary{Total: total, Done: done}
+	if total > 0 {
+		summary.Percent = done * 100 / total
+	}
+	return summary
+}
+
+// CreateChecklistItemRequest represents the request to add a checklist item
+type CreateChecklistItemRequest struct {
+	Title string `json:"title" sanitize:"text,strip_html" validate:"required,max=200"`
+}
+
+// UpdateChecklistItemRequest represents the request to rename, check or uncheck a checklist item
+type UpdateChecklistItemRequest struct {
+	Title *string `json:"title,omitempty" sanitize:"text,strip_html" validate:"notempty,max=200"`
+	Done  *bool   `json:"done,omitempty"`
+}
+
+// ReorderChecklistRequest represents the request to reorder a task's checklist
+type ReorderChecklistRequest struct {
+	ItemIDs []int `json:"itemIds"`
+}
@@ -14,13 +14,13 @@ type Column struct {
 
 // CreateColumnRequest represents the request to create a column
 type CreateColumnRequest struct {
-	Title string `json:"title"`
+	Title string `json:"title" sanitize:"text,strip_html"`
 	Color string `json:"color,omitempty"`
 }
 
 // UpdateColumnRequest represents the request to update a column
 type UpdateColumnRequest struct {
-	Title string `json:"title,omitempty"`
+	Title string `json:"title,omitempty" sanitize:"text,strip_html"`
 	Color string `json:"color,omitempty"`
 }
 
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TaskCursor is a position in (updated_at, id) order, used for keyset
+// pagination of task changes and, with created_at, of the activity feed.
+type TaskCursor struct {
+	UpdatedAt time.Time
+	ID        int
+}
+
+// Encode returns the cursor as an opaque URL-safe token.
+func (c TaskCursor) Encode() string {
+	raw := fmt.Sprintf("%d:%d", c.UpdatedAt.UnixNano(), c.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeTaskCursor parses a token produced by TaskCursor.Encode.
+func DecodeTaskCursor(token string) (TaskCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return TaskCursor{}, fmt.Errorf("invalid cursor encoding: %w", err)
+	}
+	nanos, id, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return TaskCursor{}, fmt.Errorf("invalid cursor %q", raw)
+	}
+	n, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return TaskCursor{}, fmt.Errorf("invalid cursor time: %w", err)
+	}
+	taskID, err := strconv.Atoi(id)
+	if err != nil {
+		return TaskCursor{}, fmt.Errorf("invalid cursor id: %w", err)
+	}
+	return TaskCursor{UpdatedAt: time.Unix(0, n).UTC(), ID: taskID}, nil
+}
+
+// CursorPage represents cursor pagination info in responses
+type CursorPage struct {
+	NextCursor string `json:"nextCursor,omitempty"`
+	HasMore    bool   `json:"hasMore"`
+	Limit      int    `json:"limit"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTaskCursor_RoundTrip(t *testing.T) {
+	c := TaskCursor{UpdatedAt: time.Date(2024, 5, 1, 12, 0, 0, 123456000, time.UTC), ID: 42}
+
+	decoded, err := DecodeTaskCursor(c.Encode())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !decoded.UpdatedAt.Equal(c.UpdatedAt) || decoded.ID != c.ID {
+		t.Errorf("got %+v, want %+v", decoded, c)
+	}
+}
+
+func TestDecodeTaskCursor_Invalid(t *testing.T) {
+	for _, token := range []string{"!!!", "bm9jb2xvbg", "YWJjOjE", "MTIzOmFiYw"} {
+		if _, err := DecodeTaskCursor(token); err == nil {
+			t.Errorf("expected error for %q", token)
+		}
+	}
+}
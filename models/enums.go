@@ -7,6 +7,17 @@ const (
 	RoleUser    = "user"
 )
 
+// Scope constants restrict what a token may do. Tokens without scopes, such
+// as the ones of a login, are not restricted.
+const (
+	ScopeTasksRead          = "tasks:read"  // board, tasks, checklists, activity, time entries
+	ScopeTasksWrite         = "tasks:write" // does not imply tasks:read
+	ScopeProfileRead        = "profile:read"
+	ScopeProfileWrite       = "profile:write"
+	ScopeNotificationsRead  = "notifications:read" // including the WebSocket
+	ScopeNotificationsWrite = "notifications:write"
+)
+
 // UserStatus constants
 const (
 	StatusActive   = "active"
@@ -21,6 +32,21 @@ const (
 	PriorityUrgent = "urgent"
 )
 
+// TaskStatus constants
+const (
+	TaskStatusTodo       = "todo"
+	TaskStatusInProgress = "in_progress"
+	TaskStatusDone       = "done"
+	TaskStatusBlocked    = "blocked"
+)
+
+// TaskInclude constants name the related resources ?include= embeds in tasks
+const (
+	TaskIncludeTags           = "tags"
+	TaskIncludeColumn         = "column"
+	TaskIncludeChecklistItems = "checklistItems"
+)
+
 // NotificationType constants
 const (
 	NotifTaskAssigned  = "task_assigned"
@@ -32,11 +58,25 @@ const (
 	NotifSystem        = "system"
 )
 
+// Scenario constants name the fixture datasets sandbox consumers load to
+// test against a known state
+const (
+	ScenarioEmpty     = "empty"      // nothing, as after a reset
+	ScenarioSmall     = "small"      // a few tasks in each column, with a checklist, tracked time and notifications
+	ScenarioLarge     = "large"      // 500 generated tasks, for pagination and filters
+	ScenarioEdgeCases = "edge-cases" // values at the limits: longest title, overdue deadlines, Unicode, many tags
+)
+
 // ValidRoles returns all valid user roles
 func ValidRoles() []string {
 	return []string{RoleAdmin, RoleManager, RoleUser}
 }
 
+// ValidScopes returns all valid token scopes
+func ValidScopes() []string {
+	return []string{ScopeTasksRead, ScopeTasksWrite, ScopeProfileRead, ScopeProfileWrite, ScopeNotificationsRead, ScopeNotificationsWrite}
+}
+
 // ValidStatuses returns all valid user statuses
 func ValidStatuses() []string {
 	return []string{StatusActive, StatusInactive}
@@ -47,6 +87,22 @@ func ValidPriorities() []string {
 	return []string{PriorityLow, PriorityMedium, PriorityHigh, PriorityUrgent}
 }
 
+// ValidTaskStatuses returns all valid task statuses
+func ValidTaskStatuses() []string {
+	return []string{TaskStatusTodo, TaskStatusInProgress, TaskStatusDone, TaskStatusBlocked}
+}
+
+// ValidTaskIncludes returns the related resources tasks can embed. Tags are
+// always embedded, and accepted for clients asking for them anyway.
+func ValidTaskIncludes() []string {
+	return []string{TaskIncludeTags, TaskIncludeColumn, TaskIncludeChecklistItems}
+}
+
+// ValidScenarios returns all valid sandbox scenarios
+func ValidScenarios() []string {
+	return []string{ScenarioEmpty, ScenarioSmall, ScenarioLarge, ScenarioEdgeCases}
+}
+
 // ValidNotificationTypes returns all valid notification types
 func ValidNotificationTypes() []string {
 	return []string{
@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// Data export formats
+const (
+	ExportFormatJSON = "json"
+	ExportFormatZip  = "zip"
+)
+
+// UserDataExport is the machine-readable archive of everything stored for a user
+type UserDataExport struct {
+	ExportedAt    time.Time      `json:"exportedAt"`
+	Profile       UserResponse   `json:"profile"`
+	Tasks         []Task         `json:"tasks"`
+	TimeEntries   []TimeEntry    `json:"timeEntries"`
+	Notifications []Notification `json:"notifications"`
+}
+
+// ExportJob represents the state of an asynchronous data export
+type ExportJob struct {
+	ID          string     `json:"id"`
+	Status      string     `json:"status"`
+	Format      string     `json:"format"`
+	Progress    int        `json:"progress"`
+	Error       string     `json:"error,omitempty"`
+	DownloadURL string     `json:"downloadUrl,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+}
+
+// ExportFile is a finished export ready to be downloaded
+type ExportFile struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// InboundSecretHeader carries the secret of an inbound webhook; the secret
+// query parameter is accepted too, for senders that cannot set headers.
+const InboundSecretHeader = "X-Inbound-Secret"
+
+// InboundFields name the payload fields read for each task attribute, as
+// dot-separated paths into JSON payloads ("data.subject") or form field
+// names. Empty fields use the attribute name ("title", "description"...).
+type InboundFields struct {
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Priority    string `json:"priority,omitempty"`
+	Deadline    string `json:"deadline,omitempty"`
+	Tags        string `json:"tags,omitempty"`
+}
+
+// InboundMapping turns inbound payloads into tasks: which fields to read,
+// and the values used when the payload has none.
+type InboundMapping struct {
+	Fields InboundFields `json:"fields"`
+	// ColumnID is the column tasks are created in; 0 means the first one
+	ColumnID int      `json:"columnId,omitempty"`
+	Priority string   `json:"priority,omitempty" validate:"oneof=low medium high urgent"`
+	Tags     []string `json:"tags,omitempty" sanitize:"text,strip_html"`
+}
+
+// InboundHook is the inbound webhook of a user.
+type InboundHook struct {
+	UserID     int
+	TenantID   int
+	SecretHash string
+	Mapping    InboundMapping
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// InboundSettings describes the inbound webhook of a user. The secret is
+// only shown when generated.
+type InboundSettings struct {
+	Enabled   bool           `json:"enabled"`
+	Mapping   InboundMapping `json:"mapping"`
+	CreatedAt *time.Time     `json:"createdAt,omitempty"`
+	UpdatedAt *time.Time     `json:"updatedAt,omitempty"`
+}
+
+// InboundSecretResponse returns a new webhook secret, shown once.
+type InboundSecretResponse struct {
+	Secret string `json:"secret"`
+	InboundSettings
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// LogLevels are the minimum levels of the logger: a global one, and the
+// levels of the packages ("handlers", "repository"...) overriding it
+type LogLevels struct {
+	Level   string            `json:"level"`
+	Modules map[string]string `json:"modules"`
+	// RevertAt is when a temporary change is undone
+	RevertAt *time.Time `json:"revertAt,omitempty"`
+}
+
+// UpdateLogLevelRequest changes the levels of the logger. An omitted level
+// keeps the global level; modules, when present, replace the module levels.
+// With a TTL, the levels from before the change come back after it.
+type UpdateLogLevelRequest struct {
+	Level      *string           `json:"level"`
+	Modules    map[string]string `json:"modules"`
+	TTLSeconds int               `json:"ttlSeconds" validate:"min=0,max=86400"`
+}
@@ -0,0 +1,79 @@
+package models
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+var jsonNull = []byte("null")
+
+// NullString is a nullable string that scans like sql.NullString but
+// marshals to JSON as a plain string or null.
+type NullString struct {
+	sql.NullString
+}
+
+// NewNullString returns a valid NullString holding s.
+func NewNullString(s string) NullString {
+	return NullString{sql.NullString{String: s, Valid: true}}
+}
+
+func (n NullString) MarshalJSON() ([]byte, error) {
+	if !n.Valid {
+		return jsonNull, nil
+	}
+	return json.Marshal(n.String)
+}
+
+func (n *NullString) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, jsonNull) {
+		*n = NullString{}
+		return nil
+	}
+	if err := json.Unmarshal(data, &n.String); err != nil {
+		return err
+	}
+	n.Valid = true
+	return nil
+}
+
+// NullTime is a nullable time that scans like sql.NullTime but marshals to
+// JSON as an RFC 3339 string or null.
+type NullTime struct {
+	sql.NullTime
+}
+
+// NewNullTime returns a valid NullTime holding t.
+func NewNullTime(t time.Time) NullTime {
+	return NullTime{sql.NullTime{Time: t, Valid: true}}
+}
+
+// Ptr returns the time, or nil if it is null.
+func (n NullTime) Ptr() *time.Time {
+	if !n.Valid {
+		return nil
+	}
+	t := n.Time
+	return &t
+}
+
+func (n NullTime) MarshalJSON() ([]byte, error) {
+	if !n.Valid {
+		return jsonNull, nil
+	}
+	return json.Marshal(n.Time)
+}
+
+func (n *NullTime) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, jsonNull) {
+		*n = NullTime{}
+		return nil
+	}
+	if err := json.Unmarshal(data, &n.Time); err != nil {
+		return err
+	}
+	n.Valid = true
+	return nil
+}
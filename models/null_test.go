@@ -0,0 +1,53 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestUser_NullableFieldsMarshalAsPlainValues(t *testing.T) {
+	login := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	u := User{FirstName: NewNullString("Alice"), LastLoginAt: NewNullTime(login)}
+
+	data, err := json.Marshal(u)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if fields["first_name"] != "Alice" {
+		t.Errorf("first_name = %v, want \"Alice\"", fields["first_name"])
+	}
+	if fields["last_login_at"] != "2024-05-01T12:00:00Z" {
+		t.Errorf("last_login_at = %v, want RFC 3339 string", fields["last_login_at"])
+	}
+	for _, key := range []string{"last_name", "avatar_url"} {
+		if v, ok := fields[key]; !ok || v != nil {
+			t.Errorf("%s = %v, want null", key, v)
+		}
+	}
+}
+
+func TestNullString_UnmarshalJSON(t *testing.T) {
+	var n NullString
+	if err := json.Unmarshal([]byte(`"bob"`), &n); err != nil || !n.Valid || n.String != "bob" {
+		t.Errorf("got %+v, %v; want valid \"bob\"", n, err)
+	}
+	if err := json.Unmarshal([]byte(`null`), &n); err != nil || n.Valid {
+		t.Errorf("got %+v, %v; want null", n, err)
+	}
+}
+
+func TestNullTime_UnmarshalJSON(t *testing.T) {
+	var n NullTime
+	if err := json.Unmarshal([]byte(`"2024-05-01T12:00:00Z"`), &n); err != nil || !n.Valid {
+		t.Errorf("got %+v, %v; want valid time", n, err)
+	}
+	if err := json.Unmarshal([]byte(`null`), &n); err != nil || n.Valid || n.Ptr() != nil {
+		t.Errorf("got %+v, %v; want null", n, err)
+	}
+}
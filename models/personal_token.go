@@ -0,0 +1,55 @@
+package models
+
+import "time"
+
+// PersonalToken is an access token a user created for a script or an
+// integration, known by the hash of its secret. It acts as the user within
+// its scopes until it expires or is revoked.
+type PersonalToken struct {
+	ID         int        `json:"id"`
+	UserID     int        `json:"-"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"` // first characters of the token
+	TokenHash  string     `json:"-"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  time.Time  `json:"expiresAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	// Sandbox is what is simulated on the requests made with the token
+	Sandbox SandboxSettings `json:"sandbox"`
+}
+
+// CreatePersonalTokenRequest names a new token, with the Scope* constants
+// it is restricted to and the days until it expires.
+type CreatePersonalTokenRequest struct {
+	Name          string   `json:"name" validate:"required,max=100"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int      `json:"expiresInDays" validate:"required,min=1,max=365"`
+}
+
+// CreatePersonalTokenResponse returns a token created, the only time its
+// secret is shown.
+type CreatePersonalTokenResponse struct {
+	PersonalToken
+	Token string `json:"token"`
+}
+
+// SandboxSettings simulate conditions on the requests made with a personal
+// token, for its consumer to test how an integration copes with latency and
+// failures. Other tokens and sessions of the user are not affected. The zero
+// value simulates nothing.
+type SandboxSettings struct {
+	// DelayMS delays every request by this many milliseconds, up to 30s
+	DelayMS int `json:"delayMs" validate:"min=0,max=30000"`
+	// StatusCode, from 400 to 599, fails every request with this status
+	StatusCode int `json:"statusCode,omitempty"`
+	// ErrorRate, from 0 to 1, is the share of requests failed at random
+	// with ErrorStatusCode, or 500 when it is not set
+	ErrorRate       float64 `json:"errorRate"`
+	ErrorStatusCode int     `json:"errorStatusCode,omitempty"`
+}
+
+// Simulates tells whether s changes anything to requests.
+func (s SandboxSettings) Simulates() bool {
+	return s != SandboxSettings{}
+}
@@ -0,0 +1,37 @@
+package models
+
+// Preferences are a user's profile settings. Keys missing from the stored
+// document take their value from DefaultPreferences.
+type Preferences struct {
+	Timezone      string                  `json:"timezone"` // IANA name, e.g. Europe/Paris
+	Locale        string                  `json:"locale"`   // one of i18n.Supported()
+	TaskSort      TaskSortPreference      `json:"taskSort"`
+	Notifications NotificationPreferences `json:"notifications"`
+}
+
+// TaskSortPreference is the default sort of task lists, as the sortBy and
+// sortOrder parameters of GET /tasks.
+type TaskSortPreference struct {
+	SortBy    string `json:"sortBy"`    // order, createdAt or updatedAt
+	SortOrder string `json:"sortOrder"` // asc or desc
+}
+
+// NotificationPreferences say how a user wants to be notified.
+type NotificationPreferences struct {
+	Email bool     `json:"email"`
+	Push  bool     `json:"push"`  // over the WebSocket
+	Muted []string `json:"muted"` // notification types not to send
+}
+
+// DefaultPreferences returns the preferences of a user who set none.
+func DefaultPreferences() Preferences {
+	return Preferences{
+		Timezone: "UTC",
+		Locale:   "en",
+		TaskSort: TaskSortPreference{SortBy: "order", SortOrder: "asc"},
+		Notifications: NotificationPreferences{
+			Push:  true,
+			Muted: []string{},
+		},
+	}
+}
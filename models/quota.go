@@ -0,0 +1,37 @@
+package models
+
+// QuotaLimits are the effective limits for a user; 0 means unlimited
+type QuotaLimits struct {
+	MaxTasks         int   `json:"maxTasks"`
+	MaxMediaBytes    int64 `json:"maxMediaBytes"`
+	MaxDailyRequests int64 `json:"maxDailyRequests"`
+	MaxDailyBytes    int64 `json:"maxDailyBytes"` // received and sent
+}
+
+// QuotaOverride holds per-user limits set by an admin; nil fields fall back
+// to the configured defaults
+type QuotaOverride struct {
+	UserID           int    `json:"userId"`
+	MaxTasks         *int   `json:"maxTasks"`
+	MaxMediaBytes    *int64 `json:"maxMediaBytes"`
+	MaxDailyRequests *int64 `json:"maxDailyRequests"`
+	MaxDailyBytes    *int64 `json:"maxDailyBytes"`
+}
+
+// UserQuota represents a user's limits and current usage
+type UserQuota struct {
+	UserID         int           `json:"userId"`
+	Limits         QuotaLimits   `json:"limits"`
+	Override       QuotaOverride `json:"override"`
+	UsedTasks      int           `json:"usedTasks"`
+	UsedMediaBytes int64         `json:"usedMediaBytes"`
+}
+
+// UpdateQuotaRequest sets a user's quota overrides; null resets a limit to
+// the default
+type UpdateQuotaRequest struct {
+	MaxTasks         *int   `json:"maxTasks" validate:"min=0"`
+	MaxMediaBytes    *int64 `json:"maxMediaBytes" validate:"min=0"`
+	MaxDailyRequests *int64 `json:"maxDailyRequests" validate:"min=0"`
+	MaxDailyBytes    *int64 `json:"maxDailyBytes" validate:"min=0"`
+}
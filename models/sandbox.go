@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// SandboxResetResponse counts the data a sandbox reset deleted.
+type SandboxResetResponse struct {
+	DeletedTasks         int   `json:"deletedTasks"`
+	DeletedNotifications int64 `json:"deletedNotifications"`
+}
+
+// SandboxScenarioResponse counts the data a scenario loaded, after the
+// reset it starts with.
+type SandboxScenarioResponse struct {
+	Scenario       string               `json:"scenario"`
+	Reset          SandboxResetResponse `json:"reset"`
+	Tasks          int                  `json:"tasks"`
+	ChecklistItems int                  `json:"checklistItems"`
+	TimeEntries    int                  `json:"timeEntries"`
+	Notifications  int                  `json:"notifications"`
+}
+
+// SandboxClock is the virtual clock of a user: the server reads the time
+// offset by OffsetSeconds for their requests.
+type SandboxClock struct {
+	OffsetSeconds int64     `json:"offsetSeconds"`
+	Now           time.Time `json:"now"`
+}
+
+// SetSandboxClockRequest moves the clock of a user to the time At, or by
+// OffsetSeconds from the real time when At is not set. An offset of 0
+// brings the clock back to the real time.
+type SetSandboxClockRequest struct {
+	OffsetSeconds int64      `json:"offsetSeconds"`
+	At            *time.Time `json:"at,omitempty"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// Session is a login of a user, known by the hash of its token, from a
+// device identified by its IP address and user agent.
+type Session struct {
+	ID              int
+	UserID          int
+	TokenHash       string
+	RevokeTokenHash string // of the "this wasn't me" link, empty without login alert
+	IPAddress       string
+	UserAgent       string
+	ExpiresAt       time.Time
+	RevokedAt       *time.Time
+	CreatedAt       time.Time
+}
+
+// RevokeSessionRequest signs out the session of a login alert, from the
+// token of its "this wasn't me" link.
+type RevokeSessionRequest struct {
+	Token string `json:"token" validate:"required"`
+}
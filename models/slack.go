@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// SlackLink links a Slack user, in a Slack workspace, to a sandbox account,
+// so their slash commands act as that account.
+type SlackLink struct {
+	TeamID      string    `json:"teamId"`
+	SlackUserID string    `json:"slackUserId"`
+	UserID      int       `json:"-"`
+	TenantID    int       `json:"-"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// SlackLinkCodeResponse returns a one-time code linking the Slack account
+// that sends it with "/tasks link <code>".
+type SlackLinkCodeResponse struct {
+	Code      string    `json:"code"`
+	Command   string    `json:"command"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
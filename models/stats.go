@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// AdminStats is the ops dashboard summary. Users and tasks are those of the
+// tenant; requests and database are process-wide.
+type AdminStats struct {
+	Users       UserStats      `json:"users"`
+	Tasks       map[string]int `json:"tasks"`
+	Requests    RequestStats   `json:"requests"`
+	Database    DatabaseHealth `json:"database"`
+	GeneratedAt time.Time      `json:"generatedAt"`
+}
+
+// UserStats counts the users of a tenant. SignupsPerDay covers the last days
+// in date order, with 0 for days without signups.
+type UserStats struct {
+	Total         int          `json:"total"`
+	Active        int          `json:"active"`
+	SignupsPerDay []DailyCount `json:"signupsPerDay"`
+}
+
+// DailyCount is a count for one day; Date is YYYY-MM-DD
+type DailyCount struct {
+	Date  string `json:"date"`
+	Count int    `json:"count"`
+}
+
+// RequestStats counts the HTTP requests served since the process started
+type RequestStats struct {
+	Total           int     `json:"total"`
+	ClientErrors    int     `json:"clientErrors"`
+	ServerErrors    int     `json:"serverErrors"`
+	ClientErrorRate float64 `json:"clientErrorRate"`
+	ServerErrorRate float64 `json:"serverErrorRate"`
+}
+
+// DatabaseHealth is the result of a ping and the state of the connection pool
+type DatabaseHealth struct {
+	Up              bool   `json:"up"`
+	Latency         string `json:"latency"`
+	Error           string `json:"error,omitempty"`
+	OpenConnections int    `json:"openConnections"`
+	InUse           int    `json:"inUse"`
+	Idle            int    `json:"idle"`
+	WaitCount       int64  `json:"waitCount"`
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"slices"
 	"time"
 
 	"github.com/lib/pq"
@@ -15,22 +16,32 @@ type UserBrief struct {
 
 // Task represents a task in the board
 type Task struct {
-	ID            int        `json:"id"`
-	Title         string     `json:"title"`
-	Description   string     `json:"description"`
-	ColumnID      int        `json:"columnId"`
-	Order         int        `json:"order"`
-	Priority      string     `json:"priority"`
-	AssigneeID    *int       `json:"assigneeId,omitempty"`
-	Assignee      *UserBrief `json:"assignee,omitempty"`
-	Deadline      *time.Time `json:"deadline,omitempty"`
-	EstimatedTime int        `json:"estimatedTime"` // in minutes
-	TrackedTime   int        `json:"trackedTime"`   // in minutes
-	Tags          []string   `json:"tags"`
-	CreatedBy     int        `json:"createdBy"`
-	UserID        int        `json:"userId"` // owner of the task
-	CreatedAt     time.Time  `json:"createdAt"`
-	UpdatedAt     time.Time  `json:"updatedAt"`
+	ID            int              `json:"id"`
+	Title         string           `json:"title" sanitize:"text,strip_html"`
+	Description   string           `json:"description"`
+	ColumnID      int              `json:"columnId"`
+	Order         int              `json:"order"`
+	Priority      string           `json:"priority"`
+	AssigneeID    *int             `json:"assigneeId,omitempty"`
+	Assignee      *UserBrief       `json:"assignee,omitempty"`
+	Deadline      *time.Time       `json:"deadline,omitempty"`
+	EstimatedTime int              `json:"estimatedTime"` // in minutes
+	TrackedTime   int              `json:"trackedTime"`   // in minutes
+	Tags          []string         `json:"tags"`
+	Checklist     ChecklistSummary `json:"checklist"`
+	// Column and ChecklistItems are only set when asked for with ?include=
+	Column         *Column         `json:"column,omitempty"`
+	ChecklistItems []ChecklistItem `json:"checklistItems,omitzero"`
+	Status         string          `json:"status"`
+	Completed      bool            `json:"completed"` // status is done
+	CompletedAt    *time.Time      `json:"completedAt,omitempty"`
+	CreatedBy      int             `json:"createdBy"`
+	UserID         int             `json:"userId"` // owner of the task
+	CreatedAt      time.Time       `json:"createdAt"`
+	UpdatedAt      time.Time       `json:"updatedAt"`
+	// SandboxCreatedAt is when the task was created on the sandbox clock
+	// of its creator, if it was moved
+	SandboxCreatedAt *time.Time `json:"sandboxCreatedAt,omitempty"`
 }
 
 // TaskDB represents the task as stored in database (with pq.StringArray for tags)
@@ -46,10 +57,15 @@ type TaskDB struct {
 	EstimatedTime int
 	TrackedTime   int
 	Tags          pq.StringArray
+	Status        string
+	Completed     bool
+	CompletedAt   *time.Time
 	CreatedBy     *int
 	UserID        int
 	CreatedAt     time.Time
 	UpdatedAt     time.Time
+
+	SandboxCreatedAt *time.Time
 }
 
 // ToTask converts TaskDB to Task
@@ -66,9 +82,14 @@ func (t *TaskDB) ToTask() Task {
 		EstimatedTime: t.EstimatedTime,
 		TrackedTime:   t.TrackedTime,
 		Tags:          []string{},
+		Status:        t.Status,
+		Completed:     t.Completed,
+		CompletedAt:   t.CompletedAt,
 		UserID:        t.UserID,
 		CreatedAt:     t.CreatedAt,
 		UpdatedAt:     t.UpdatedAt,
+
+		SandboxCreatedAt: t.SandboxCreatedAt,
 	}
 	if t.CreatedBy != nil {
 		task.CreatedBy = *t.CreatedBy
@@ -81,32 +102,67 @@ func (t *TaskDB) ToTask() Task {
 
 // CreateTaskRequest represents the request to create a task
 type CreateTaskRequest struct {
-	Title         string     `json:"title"`
-	Description   string     `json:"description,omitempty"`
+	Title         string     `json:"title" sanitize:"text,strip_html"`
+	Description   string     `json:"description,omitempty" sanitize:"multiline,strip_html"`
 	ColumnID      int        `json:"columnId"`
 	Priority      string     `json:"priority,omitempty"`
 	AssigneeID    *int       `json:"assigneeId,omitempty"`
 	Deadline      *time.Time `json:"deadline,omitempty"`
 	EstimatedTime int        `json:"estimatedTime,omitempty"`
-	Tags          []string   `json:"tags,omitempty"`
+	Tags          []string   `json:"tags,omitempty" sanitize:"text,strip_html"`
 }
 
 // UpdateTaskRequest represents the request to update a task
 type UpdateTaskRequest struct {
-	Title         string     `json:"title,omitempty"`
-	Description   string     `json:"description,omitempty"`
+	Title         string     `json:"title,omitempty" sanitize:"text,strip_html"`
+	Description   string     `json:"description,omitempty" sanitize:"multiline,strip_html"`
 	ColumnID      int        `json:"columnId,omitempty"`
 	Priority      string     `json:"priority,omitempty"`
 	AssigneeID    *int       `json:"assigneeId,omitempty"`
 	Deadline      *time.Time `json:"deadline,omitempty"`
 	EstimatedTime int        `json:"estimatedTime,omitempty"`
-	Tags          []string   `json:"tags,omitempty"`
+	Tags          []string   `json:"tags,omitempty" sanitize:"text,strip_html"`
 }
 
-// MoveTaskRequest represents the request to move a task
+// MoveTaskRequest represents the request to move a task. The task goes
+// right after AfterID or right before BeforeID when one is set, otherwise
+// at Order.
 type MoveTaskRequest struct {
-	ColumnID int `json:"columnId"`
-	Order    int `json:"order"`
+	ColumnID int  `json:"columnId"`
+	Order    int  `json:"order"`
+	AfterID  *int `json:"afterId,omitempty"`
+	BeforeID *int `json:"beforeId,omitempty"`
+}
+
+// SetTaskStatusRequest represents the request to change the status of a task
+type SetTaskStatusRequest struct {
+	Status string `json:"status" validate:"required,oneof=todo in_progress done blocked"`
+}
+
+// taskStatusTransitions lists the statuses a task can move to from each
+// status. A blocked task has to be unblocked before it is done.
+var taskStatusTransitions = map[string][]string{
+	TaskStatusTodo:       {TaskStatusInProgress, TaskStatusDone, TaskStatusBlocked},
+	TaskStatusInProgress: {TaskStatusTodo, TaskStatusDone, TaskStatusBlocked},
+	TaskStatusBlocked:    {TaskStatusTodo, TaskStatusInProgress},
+	TaskStatusDone:       {TaskStatusTodo, TaskStatusInProgress},
+}
+
+// CanTransitionTaskStatus reports whether a task can move from one status
+// to another.
+func CanTransitionTaskStatus(from, to string) bool {
+	return slices.Contains(taskStatusTransitions[from], to)
+}
+
+// TaskStatusSources returns the statuses a task can move to status from.
+func TaskStatusSources(status string) []string {
+	var sources []string
+	for _, from := range ValidTaskStatuses() {
+		if CanTransitionTaskStatus(from, status) {
+			sources = append(sources, from)
+		}
+	}
+	return sources
 }
 
 // ReorderTasksRequest represents the request to reorder tasks in a column
@@ -115,6 +171,24 @@ type ReorderTasksRequest struct {
 	TaskIDs  []int `json:"taskIds"`
 }
 
+// TaskListParams represents query parameters for listing tasks
+type TaskListParams struct {
+	ColumnID     *int
+	Statuses     []string // any of these statuses; empty means all
+	SortBy       string   // order (default), createdAt or updatedAt
+	SortOrder    string   // asc or desc
+	UpdatedSince *time.Time
+}
+
+// TaskListModified is when a tenant's task list last changed, as a
+// Last-Modified validator for GET /tasks.
+type TaskListModified struct {
+	At time.Time // zero if the list never changed
+	// Settled is false while another change could still land in the same
+	// second as At, so a second-precision validator would miss it.
+	Settled bool
+}
+
 // BoardResponse represents the response for GET /tasks/board
 type BoardResponse struct {
 	Columns []Column `json:"columns"`
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestTaskStatusSources(t *testing.T) {
+	tests := map[string][]string{
+		TaskStatusDone:    {TaskStatusTodo, TaskStatusInProgress},
+		TaskStatusBlocked: {TaskStatusTodo, TaskStatusInProgress},
+		TaskStatusTodo:    {TaskStatusInProgress, TaskStatusDone, TaskStatusBlocked},
+	}
+	for status, want := range tests {
+		if got := TaskStatusSources(status); !slices.Equal(got, want) {
+			t.Errorf("TaskStatusSources(%s) = %v, want %v", status, got, want)
+		}
+	}
+	if CanTransitionTaskStatus(TaskStatusTodo, TaskStatusTodo) {
+		t.Error("expected no transition from a status to itself")
+	}
+}
@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// Tenant is an isolated workspace sharing the same API deployment
+type Tenant struct {
+	ID        int       `json:"id"`
+	Slug      string    `json:"slug"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"createdAt"`
+}
@@ -8,7 +8,7 @@ type TimeEntry struct {
 	TaskID      int        `json:"taskId"`
 	UserID      int        `json:"userId"`
 	StartTime   time.Time  `json:"startTime"`
-	EndTime     *time.Time `json:"endTime,omitempty"`
+	EndTime     *time.Time `json:"endTime,omitempty" validate:"afterfield=startTime"`
 	Duration    int        `json:"duration"` // in seconds
 	Description string     `json:"description,omitempty"`
 	CreatedAt   time.Time  `json:"createdAt"`
@@ -16,9 +16,9 @@ type TimeEntry struct {
 
 // CreateTimeEntryRequest represents the request to create a time entry
 type CreateTimeEntryRequest struct {
-	TaskID      int        `json:"taskId"`
-	StartTime   time.Time  `json:"startTime"`
+	TaskID      int        `json:"taskId" validate:"required"`
+	StartTime   time.Time  `json:"startTime" validate:"required"`
 	EndTime     *time.Time `json:"endTime,omitempty"`
-	Duration    int        `json:"duration"` // in seconds
+	Duration    int        `json:"duration" validate:"min=1"` // in seconds
 	Description string     `json:"description,omitempty"`
 }
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// UsageCounts counts API requests and the bytes of their bodies.
+type UsageCounts struct {
+	Requests int64 `json:"requests"`
+	BytesIn  int64 `json:"bytesIn"`
+	BytesOut int64 `json:"bytesOut"`
+}
+
+// Bytes is the transfer counted against the daily bytes quota.
+func (c UsageCounts) Bytes() int64 {
+	return c.BytesIn + c.BytesOut
+}
+
+// UsageRecord is the usage of one key of a user on one UTC day.
+type UsageRecord struct {
+	Day       string `json:"day"`                 // YYYY-MM-DD
+	TokenID   int    `json:"tokenId,omitempty"`   // 0 for login sessions
+	TokenName string `json:"tokenName,omitempty"` // empty once the token is revoked
+	UsageCounts
+}
+
+// UsageReport is the API consumption of a user: today's against their daily
+// quotas, and the last days by key.
+type UsageReport struct {
+	Today            UsageCounts   `json:"today"`
+	MaxDailyRequests int64         `json:"maxDailyRequests"` // 0 means unlimited
+	MaxDailyBytes    int64         `json:"maxDailyBytes"`
+	ResetsAt         time.Time     `json:"resetsAt"`
+	History          []UsageRecord `json:"history"` // most recent day first
+}
@@ -1,45 +1,113 @@
 package models
 
 import (
-	"database/sql"
+	"slices"
 	"time"
 )
 
 // User represents a user in the system
 type User struct {
-	ID          int            `json:"id"`
-	Username    string         `json:"username"`
-	Email       string         `json:"email"`
-	Password    string         `json:"-"` // Le "-" empêche l'export en JSON pour la sécurité
-	FirstName   sql.NullString `json:"first_name,omitempty"`
-	LastName    sql.NullString `json:"last_name,omitempty"`
-	AvatarURL   sql.NullString `json:"avatar_url,omitempty"`
-	IsActive    bool           `json:"is_active"`
-	LastLoginAt sql.NullTime   `json:"last_login_at,omitempty"`
-	Role        string         `json:"role"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
+	ID          int        `json:"id"`
+	TenantID    int        `json:"-"`
+	Username    string     `json:"username"`
+	Email       string     `json:"email"`
+	Password    string     `json:"-"` // Le "-" empêche l'export en JSON pour la sécurité
+	FirstName   NullString `json:"first_name"`
+	LastName    NullString `json:"last_name"`
+	AvatarURL   NullString `json:"avatar_url"`
+	IsActive    bool       `json:"is_active"`
+	LastLoginAt NullTime   `json:"last_login_at"`
+	Role        string     `json:"role"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
 }
 
 // LoginRequest represents login credentials
 type LoginRequest struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
+
+	// RememberMe keeps the user logged in across browser restarts, for
+	// JWT_REMEMBER_ME_DAYS; otherwise the session ends with the browser's
+	RememberMe bool `json:"remember_me,omitempty"`
 }
 
 // RegisterRequest represents registration data
 type RegisterRequest struct {
-	Username string `json:"username"`
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Username string `json:"username" validate:"required,username"`
+	Email    string `json:"email" validate:"required,email"`
+	// Checked against the password policy by the auth service
+	Password string `json:"password" validate:"required"`
+
+	// Response of the CAPTCHA widget, when signups require one
+	CaptchaToken string `json:"captcha_token,omitempty"`
+}
+
+// PasswordStrengthRequest asks how strong a password would be; the username
+// and email, when known, weaken passwords containing them
+type PasswordStrengthRequest struct {
+	Password string `json:"password" validate:"required,max=1024"`
+	Username string `json:"username,omitempty"`
+	Email    string `json:"email,omitempty"`
+}
+
+// PasswordStrength scores a password from 0 (too guessable) to 4 (very
+// unguessable). Valid tells whether the password policy accepts it; Errors
+// lists the requirements it fails. Breached passwords, when they are
+// checked, score 0.
+type PasswordStrength struct {
+	Score    int              `json:"score"`
+	Valid    bool             `json:"valid"`
+	Breached bool             `json:"breached"`
+	Errors   []string         `json:"errors"`
+	Feedback PasswordFeedback `json:"feedback"`
+}
+
+// PasswordFeedback explains a password strength score
+type PasswordFeedback struct {
+	Warning     string   `json:"warning,omitempty"`
+	Suggestions []string `json:"suggestions"`
 }
 
 // UpdateProfileRequest represents profile update data
 // Note: email and password cannot be updated through this endpoint
 type UpdateProfileRequest struct {
-	FirstName *string `json:"first_name,omitempty"`
-	LastName  *string `json:"last_name,omitempty"`
-	AvatarURL *string `json:"avatar_url,omitempty"`
+	FirstName *string `json:"first_name,omitempty" sanitize:"text,strip_html"`
+	LastName  *string `json:"last_name,omitempty" sanitize:"text,strip_html"`
+	AvatarURL *string `json:"avatar_url,omitempty" validate:"url,max=2048"`
+}
+
+// ChangeEmailRequest starts an email change; the new address must be verified
+type ChangeEmailRequest struct {
+	Email string `json:"email" validate:"required,email,max=255"`
+}
+
+// ConfirmEmailChangeRequest completes an email change with the emailed token
+type ConfirmEmailChangeRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// ChangeUsernameRequest represents a username change
+type ChangeUsernameRequest struct {
+	Username string `json:"username" validate:"required,username"`
+}
+
+// EmailChange is a pending email change awaiting verification
+type EmailChange struct {
+	UserID    int
+	NewEmail  string
+	TokenHash string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+// ImpersonationResponse carries a token acting as another user. It is
+// returned in the body rather than set as a cookie, so the admin's own
+// session is left alone.
+type ImpersonationResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	User      User      `json:"user"`
 }
 
 // AuthResponse represents the response after authentication
@@ -51,12 +119,31 @@ type AuthResponse struct {
 // Claims represents JWT claims
 type Claims struct {
 	UserID    int       `json:"user_id"`
+	TenantID  int       `json:"tenant_id"`
 	Username  string    `json:"username"`
 	Role      string    `json:"role,omitempty"`
 	FirstName string    `json:"first_name,omitempty"`
 	LastName  string    `json:"last_name,omitempty"`
 	AvatarURL string    `json:"avatar_url,omitempty"`
 	ExpiresAt time.Time `json:"exp"`
+	// ImpersonatorID is the admin acting as the user, for impersonation
+	// tokens
+	ImpersonatorID int `json:"impersonator_id,omitempty"`
+	// RememberMe is set on the long-lived tokens of a remembered login
+	RememberMe bool `json:"remember_me,omitempty"`
+	// Scopes restricts the token to the routes of these Scope* constants;
+	// nil means unrestricted, while an empty list allows no scoped route
+	Scopes []string `json:"scopes,omitempty"`
+	// PersonalTokenID is the personal access token the request was made
+	// with, and Sandbox its settings; they are never part of a JWT
+	PersonalTokenID int              `json:"-"`
+	Sandbox         *SandboxSettings `json:"-"`
+}
+
+// HasScope tells whether the token may be used for scope: it is not
+// restricted, or scope is one of its scopes.
+func (c *Claims) HasScope(scope string) bool {
+	return c.Scopes == nil || slices.Contains(c.Scopes, scope)
 }
 
 // UserResponse represents a user in API responses (with proper JSON formatting)
@@ -97,12 +184,31 @@ func UserFromDB(u User) UserResponse {
 	if u.AvatarURL.Valid {
 		resp.AvatarURL = u.AvatarURL.String
 	}
-	if u.LastLoginAt.Valid {
-		resp.LastLogin = &u.LastLoginAt.Time
-	}
+	resp.LastLogin = u.LastLoginAt.Ptr()
 	return resp
 }
 
+// PublicProfile is the subset of a user visible to other users, used to
+// pick collaborators. It never includes the email address.
+type PublicProfile struct {
+	ID        int    `json:"id"`
+	Username  string `json:"username"`
+	FirstName string `json:"firstName,omitempty"`
+	LastName  string `json:"lastName,omitempty"`
+	AvatarURL string `json:"avatarUrl,omitempty"`
+}
+
+// PublicProfileFromDB converts a User to PublicProfile
+func PublicProfileFromDB(u User) PublicProfile {
+	return PublicProfile{
+		ID:        u.ID,
+		Username:  u.Username,
+		FirstName: u.FirstName.String,
+		LastName:  u.LastName.String,
+		AvatarURL: u.AvatarURL.String,
+	}
+}
+
 // Pagination represents pagination info in responses
 type Pagination struct {
 	Page       int `json:"page"`
@@ -122,8 +228,8 @@ type CreateUserRequest struct {
 	Email     string `json:"email"`
 	Username  string `json:"username"`
 	Password  string `json:"password"`
-	FirstName string `json:"firstName,omitempty"`
-	LastName  string `json:"lastName,omitempty"`
+	FirstName string `json:"firstName,omitempty" sanitize:"text,strip_html"`
+	LastName  string `json:"lastName,omitempty" sanitize:"text,strip_html"`
 	Role      string `json:"role,omitempty"`
 }
 
@@ -131,9 +237,9 @@ type CreateUserRequest struct {
 type UpdateUserRequest struct {
 	Email     string `json:"email,omitempty"`
 	Username  string `json:"username,omitempty"`
-	FirstName string `json:"firstName,omitempty"`
-	LastName  string `json:"lastName,omitempty"`
-	AvatarURL string `json:"avatarUrl,omitempty"`
+	FirstName string `json:"firstName,omitempty" sanitize:"text,strip_html"`
+	LastName  string `json:"lastName,omitempty" sanitize:"text,strip_html"`
+	AvatarURL string `json:"avatarUrl,omitempty" validate:"url,max=2048"`
 	Role      string `json:"role,omitempty"`
 }
 
@@ -151,4 +257,7 @@ type UserListParams struct {
 	Search    string
 	Role      string
 	Status    string
+	// Username and Email match exactly, ignoring case
+	Username string
+	Email    string
 }
@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// Webhook is an outgoing webhook of a user: the events of the tasks they own
+// or are assigned to are posted to URL, signed with a secret only shown
+// when the webhook is created.
+type Webhook struct {
+	ID     int    `json:"id"`
+	UserID int    `json:"-"`
+	URL    string `json:"url"`
+	// Secret is the signing secret sealed with secretbox
+	Secret    string    `json:"-"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// CreateWebhookRequest registers the URL receiving the events.
+type CreateWebhookRequest struct {
+	URL string `json:"url" validate:"required,max=2048"`
+}
+
+// CreateWebhookResponse returns a webhook created, the only time its
+// signing secret is shown.
+type CreateWebhookResponse struct {
+	Webhook
+	Secret string `json:"secret"`
+}
+
+// WebhookTestResult is how the receiver of a webhook answered a signed
+// ping. Error is set instead of Status when no response came back.
+type WebhookTestResult struct {
+	Status      int    `json:"status,omitempty"`
+	LatencyMS   int64  `json:"latencyMs"`
+	BodyPreview string `json:"bodyPreview"`
+	Error       string `json:"error,omitempty"`
+}
+
+// Types of WebhookEvent besides the events.Type of the task events.
+const WebhookEventPing = "ping"
+
+// WebhookEvent is the body of a webhook request.
+type WebhookEvent struct {
+	ID        string      `json:"id"`
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"createdAt"`
+	Data      interface{} `json:"data"`
+}
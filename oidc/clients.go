@@ -0,0 +1,95 @@
+package oidc
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+)
+
+// Client is an application allowed to log users in with the provider.
+// A client without a secret is public (a SPA or mobile app) and must use
+// PKCE.
+type Client struct {
+	ID           string   `json:"id"`
+	Secret       string   `json:"secret,omitempty"`
+	Name         string   `json:"name"`
+	RedirectURIs []string `json:"redirectUris"`
+}
+
+// Public reports whether the client has no secret.
+func (c Client) Public() bool {
+	return c.Secret == ""
+}
+
+// AllowsRedirect reports whether uri is one of the registered redirect
+// URIs, compared exactly.
+func (c Client) AllowsRedirect(uri string) bool {
+	return slices.Contains(c.RedirectURIs, uri)
+}
+
+// Clients is the registry of client applications, by ID.
+type Clients map[string]Client
+
+// NewClients registers clients, checking each has an ID and absolute
+// redirect URIs without fragments.
+func NewClients(clients ...Client) (Clients, error) {
+	registry := make(Clients, len(clients))
+	for _, c := range clients {
+		if c.ID == "" {
+			return nil, fmt.Errorf("client without id")
+		}
+		if _, dup := registry[c.ID]; dup {
+			return nil, fmt.Errorf("client %q listed twice", c.ID)
+		}
+		if len(c.RedirectURIs) == 0 {
+			return nil, fmt.Errorf("client %q has no redirect URI", c.ID)
+		}
+		for _, uri := range c.RedirectURIs {
+			u, err := url.Parse(uri)
+			if err != nil || !u.IsAbs() || u.Fragment != "" {
+				return nil, fmt.Errorf("client %q: invalid redirect URI %q", c.ID, uri)
+			}
+		}
+		registry[c.ID] = c
+	}
+	return registry, nil
+}
+
+// LoadClients reads a JSON array of clients from the file at path.
+func LoadClients(path string) (Clients, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var clients []Client
+	if err := json.Unmarshal(data, &clients); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	registry, err := NewClients(clients...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return registry, nil
+}
+
+// Lookup returns the client with the given ID.
+func (c Clients) Lookup(id string) (Client, bool) {
+	client, ok := c[id]
+	return client, ok
+}
+
+// Authenticate returns the client with the given ID if secret is its
+// secret. A public client authenticates with an empty secret.
+func (c Clients) Authenticate(id, secret string) (Client, bool) {
+	client, ok := c[id]
+	if !ok {
+		return Client{}, false
+	}
+	if subtle.ConstantTimeCompare([]byte(client.Secret), []byte(secret)) != 1 {
+		return Client{}, false
+	}
+	return client, true
+}
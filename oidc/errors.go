@@ -0,0 +1,40 @@
+package oidc
+
+import "net/http"
+
+// OAuth 2.0 error codes (RFC 6749, RFC 6750)
+const (
+	ErrInvalidRequest          = "invalid_request"
+	ErrInvalidClient           = "invalid_client"
+	ErrInvalidGrant            = "invalid_grant"
+	ErrUnauthorizedClient      = "unauthorized_client"
+	ErrUnsupportedGrantType    = "unsupported_grant_type"
+	ErrUnsupportedResponseType = "unsupported_response_type"
+	ErrInvalidScope            = "invalid_scope"
+	ErrInvalidToken            = "invalid_token"
+)
+
+// Error is an OAuth error, sent to clients in the format of RFC 6749 rather
+// than the API's error envelope.
+type Error struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+	Status      int    `json:"-"`
+}
+
+func (e *Error) Error() string {
+	if e.Description == "" {
+		return e.Code
+	}
+	return e.Code + ": " + e.Description
+}
+
+// NewError returns an OAuth error answered with 400 Bad Request, or 401 for
+// invalid_client and invalid_token.
+func NewError(code, description string) *Error {
+	status := http.StatusBadRequest
+	if code == ErrInvalidClient || code == ErrInvalidToken {
+		status = http.StatusUnauthorized
+	}
+	return &Error{Code: code, Description: description, Status: status}
+}
@@ -0,0 +1,144 @@
+// Package oidc holds the pieces of the OpenID Connect provider: the RSA key
+// signing ID and access tokens, published as a JWKS, and the registry of
+// client applications.
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// accessTokenType is the typ header of access tokens (RFC 9068), so an ID
+// token cannot be used in their place.
+const accessTokenType = "at+jwt"
+
+// KeySet signs tokens with an RSA key and verifies them.
+type KeySet struct {
+	key *rsa.PrivateKey
+	kid string
+}
+
+// NewKeySet uses key, identified by its JWK thumbprint.
+func NewKeySet(key *rsa.PrivateKey) *KeySet {
+	return &KeySet{key: key, kid: thumbprint(&key.PublicKey)}
+}
+
+// GenerateKeySet creates a KeySet with a new 2048-bit key. Tokens it signs
+// are not valid after a restart.
+func GenerateKeySet() (*KeySet, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	return NewKeySet(key), nil
+}
+
+// LoadKeySet reads a PEM encoded RSA private key, in PKCS#1 or PKCS#8 form.
+func LoadKeySet(path string) (*KeySet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM data", path)
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return NewKeySet(key), nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s: not an RSA key", path)
+	}
+	return NewKeySet(key), nil
+}
+
+// PublicKey returns the key tokens are verified with.
+func (k *KeySet) PublicKey() *rsa.PublicKey {
+	return &k.key.PublicKey
+}
+
+// JWK is the public half of a signing key.
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// JWKS is the document published at the jwks_uri.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public keys clients verify tokens with.
+func (k *KeySet) JWKS() JWKS {
+	return JWKS{Keys: []JWK{{
+		Kty: "RSA",
+		Use: "sig",
+		Alg: "RS256",
+		Kid: k.kid,
+		N:   base64.RawURLEncoding.EncodeToString(k.key.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.key.E)).Bytes()),
+	}}}
+}
+
+// SignIDToken signs the claims of an ID token.
+func (k *KeySet) SignIDToken(claims jwt.MapClaims) (string, error) {
+	return k.sign(claims, "JWT")
+}
+
+// SignAccessToken signs the claims of an access token.
+func (k *KeySet) SignAccessToken(claims jwt.MapClaims) (string, error) {
+	return k.sign(claims, accessTokenType)
+}
+
+func (k *KeySet) sign(claims jwt.MapClaims, typ string) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = k.kid
+	token.Header["typ"] = typ
+	return token.SignedString(k.key)
+}
+
+// ParseAccessToken verifies an access token issued by issuer and returns
+// its claims.
+func (k *KeySet) ParseAccessToken(tokenString, issuer string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if typ, _ := token.Header["typ"].(string); typ != accessTokenType {
+			return nil, fmt.Errorf("not an access token")
+		}
+		return k.PublicKey(), nil
+	}, jwt.WithValidMethods([]string{"RS256"}), jwt.WithIssuer(issuer), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, fmt.Errorf("invalid token")
+	}
+	return claims, nil
+}
+
+// thumbprint is the RFC 7638 thumbprint of key.
+func thumbprint(key *rsa.PublicKey) string {
+	e := base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes())
+	n := base64.RawURLEncoding.EncodeToString(key.N.Bytes())
+	sum := sha256.Sum256([]byte(`{"e":"` + e + `","kty":"RSA","n":"` + n + `"}`))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,138 @@
+package oidc
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestKeySet(t *testing.T) {
+	keys, err := GenerateKeySet()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	now := time.Now()
+	claims := jwt.MapClaims{"iss": "https://sandbox.test", "sub": "1", "exp": now.Add(time.Hour).Unix()}
+
+	t.Run("access tokens round trip", func(t *testing.T) {
+		token, err := keys.SignAccessToken(claims)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		parsed, err := keys.ParseAccessToken(token, "https://sandbox.test")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if parsed["sub"] != "1" {
+			t.Errorf("sub = %v, want 1", parsed["sub"])
+		}
+		if _, err := keys.ParseAccessToken(token, "https://other.test"); err == nil {
+			t.Error("expected an error for another issuer")
+		}
+	})
+
+	t.Run("ID tokens are not access tokens", func(t *testing.T) {
+		token, err := keys.SignIDToken(claims)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := keys.ParseAccessToken(token, "https://sandbox.test"); err == nil {
+			t.Error("expected an ID token to be refused as access token")
+		}
+	})
+
+	t.Run("tokens of another key are refused", func(t *testing.T) {
+		other, _ := GenerateKeySet()
+		token, _ := other.SignAccessToken(claims)
+		if _, err := keys.ParseAccessToken(token, "https://sandbox.test"); err == nil {
+			t.Error("expected a token signed by another key to be refused")
+		}
+	})
+
+	t.Run("JWKS publishes the key", func(t *testing.T) {
+		set := keys.JWKS()
+		if len(set.Keys) != 1 {
+			t.Fatalf("expected one key, got %d", len(set.Keys))
+		}
+		jwk := set.Keys[0]
+		if jwk.Kty != "RSA" || jwk.Alg != "RS256" || jwk.E != "AQAB" || jwk.Kid != keys.kid || jwk.N == "" {
+			t.Errorf("unexpected JWK %+v", jwk)
+		}
+	})
+}
+
+func TestLoadKeySet(t *testing.T) {
+	generated, _ := GenerateKeySet()
+	der, err := x509.MarshalPKCS8PrivateKey(generated.key)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	loaded, err := LoadKeySet(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if loaded.kid != generated.kid {
+		t.Error("expected the loaded key to have the same key ID")
+	}
+
+	if err := os.WriteFile(path, []byte("not a key"), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := LoadKeySet(path); err == nil {
+		t.Error("expected an error for a file without a key")
+	}
+}
+
+func TestClients(t *testing.T) {
+	clients, err := NewClients(
+		Client{ID: "wiki", Secret: "s3cret", RedirectURIs: []string{"https://wiki.test/callback"}},
+		Client{ID: "spa", RedirectURIs: []string{"https://spa.test/callback"}},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, ok := clients.Authenticate("wiki", "s3cret"); !ok {
+		t.Error("expected the right secret to authenticate")
+	}
+	if _, ok := clients.Authenticate("wiki", "wrong"); ok {
+		t.Error("expected a wrong secret to fail")
+	}
+	if _, ok := clients.Authenticate("wiki", ""); ok {
+		t.Error("expected a confidential client without secret to fail")
+	}
+	if c, ok := clients.Authenticate("spa", ""); !ok || !c.Public() {
+		t.Error("expected a public client to authenticate without secret")
+	}
+	if _, ok := clients.Authenticate("unknown", ""); ok {
+		t.Error("expected an unknown client to fail")
+	}
+
+	c, _ := clients.Lookup("wiki")
+	if !c.AllowsRedirect("https://wiki.test/callback") || c.AllowsRedirect("https://wiki.test/callback/") {
+		t.Error("expected redirect URIs to be compared exactly")
+	}
+
+	invalid := [][]Client{
+		{{ID: "", RedirectURIs: []string{"https://a.test/cb"}}},
+		{{ID: "a"}},
+		{{ID: "a", RedirectURIs: []string{"/relative"}}},
+		{{ID: "a", RedirectURIs: []string{"https://a.test/cb#fragment"}}},
+		{{ID: "a", RedirectURIs: []string{"https://a.test/cb"}}, {ID: "a", RedirectURIs: []string{"https://a.test/cb"}}},
+	}
+	for _, list := range invalid {
+		if _, err := NewClients(list...); err == nil {
+			t.Errorf("expected an error for %+v", list)
+		}
+	}
+}
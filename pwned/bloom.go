@@ -0,0 +1,142 @@
+package pwned
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+)
+
+// bloomMagic starts the files written by BloomFilter.WriteTo.
+const bloomMagic = "PWBLOOM1"
+
+// BloomFilter is a set of password hashes in a fixed amount of memory. It
+// may wrongly hold a hash, at the rate it was sized for, but never misses
+// one it was given.
+type BloomFilter struct {
+	bits []uint64
+	m    uint64 // number of bits
+	k    uint32 // bits set per hash
+}
+
+// NewBloomFilter sizes a filter for n hashes and the given false positive
+// rate, such as 0.001.
+func NewBloomFilter(n int, falsePositiveRate float64) *BloomFilter {
+	n = max(n, 1)
+	m := uint64(math.Ceil(-float64(n) * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2)))
+	m = max(m, 64)
+	k := uint32(math.Round(float64(m) / float64(n) * math.Ln2))
+	return &BloomFilter{bits: make([]uint64, (m+63)/64), m: m, k: max(k, 1)}
+}
+
+// positions calls fn with the bits of hash. SHA-1 being uniform, two of its
+// words make the k positions by double hashing.
+func (f *BloomFilter) positions(hash Hash, fn func(bit uint64)) {
+	h1 := binary.BigEndian.Uint64(hash[0:8])
+	h2 := binary.BigEndian.Uint64(hash[8:16]) | 1
+	for i := uint64(0); i < uint64(f.k); i++ {
+		fn((h1 + i*h2) % f.m)
+	}
+}
+
+// Add puts hash in the filter.
+func (f *BloomFilter) Add(hash Hash) {
+	f.positions(hash, func(bit uint64) {
+		f.bits[bit/64] |= 1 << (bit % 64)
+	})
+}
+
+// Contains tells whether hash may have been added.
+func (f *BloomFilter) Contains(hash Hash) bool {
+	found := true
+	f.positions(hash, func(bit uint64) {
+		found = found && f.bits[bit/64]&(1<<(bit%64)) != 0
+	})
+	return found
+}
+
+// Breached implements Checker.
+func (f *BloomFilter) Breached(_ context.Context, password string) (bool, error) {
+	return f.Contains(HashPassword(password)), nil
+}
+
+// WriteTo writes the filter in the format ReadBloomFilter reads.
+func (f *BloomFilter) WriteTo(w io.Writer) (int64, error) {
+	bw := bufio.NewWriter(w)
+	header := make([]byte, len(bloomMagic)+12)
+	copy(header, bloomMagic)
+	binary.BigEndian.PutUint32(header[len(bloomMagic):], f.k)
+	binary.BigEndian.PutUint64(header[len(bloomMagic)+4:], f.m)
+	bw.Write(header)
+	if err := binary.Write(bw, binary.BigEndian, f.bits); err != nil {
+		return 0, err
+	}
+	if err := bw.Flush(); err != nil {
+		return 0, err
+	}
+	return int64(len(header) + 8*len(f.bits)), nil
+}
+
+// ReadBloomFilter reads a filter written by WriteTo.
+func ReadBloomFilter(r io.Reader) (*BloomFilter, error) {
+	br := bufio.NewReader(r)
+	header := make([]byte, len(bloomMagic)+12)
+	if _, err := io.ReadFull(br, header); err != nil || string(header[:len(bloomMagic)]) != bloomMagic {
+		return nil, fmt.Errorf("not a password Bloom filter")
+	}
+	f := &BloomFilter{
+		k: binary.BigEndian.Uint32(header[len(bloomMagic):]),
+		m: binary.BigEndian.Uint64(header[len(bloomMagic)+4:]),
+	}
+	if f.k == 0 || f.m == 0 {
+		return nil, fmt.Errorf("invalid password Bloom filter header")
+	}
+	f.bits = make([]uint64, (f.m+63)/64)
+	if err := binary.Read(br, binary.BigEndian, f.bits); err != nil {
+		return nil, fmt.Errorf("truncated password Bloom filter: %w", err)
+	}
+	return f, nil
+}
+
+// LoadBloomFilter reads a filter from the file at path.
+func LoadBloomFilter(path string) (*BloomFilter, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	f, err := ReadBloomFilter(file)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	return f, nil
+}
+
+// ReadHashes calls fn with the hashes of r, one hex SHA-1 per line as in the
+// Pwned Passwords downloads ("HASH:COUNT" or "HASH"). Blank lines are skipped.
+func ReadHashes(r io.Reader, fn func(Hash)) error {
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text, _, _ := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if text == "" {
+			continue
+		}
+		var hash Hash
+		if len(text) != 2*len(hash) {
+			return fmt.Errorf("line %d: not a SHA-1 hash", line)
+		}
+		if _, err := hex.Decode(hash[:], []byte(text)); err != nil {
+			return fmt.Errorf("line %d: not a SHA-1 hash", line)
+		}
+		fn(hash)
+	}
+	return scanner.Err()
+}
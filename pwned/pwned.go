@@ -0,0 +1,23 @@
+// Package pwned tells whether passwords appeared in known data breaches,
+// from the Pwned Passwords range API, which only ever sees the first five
+// characters of the SHA-1 of a password, or from an offline Bloom filter of
+// the breached password hashes.
+package pwned
+
+import (
+	"context"
+	"crypto/sha1"
+)
+
+// Checker tells whether a password is known to be breached.
+type Checker interface {
+	Breached(ctx context.Context, password string) (bool, error)
+}
+
+// Hash is the SHA-1 of a password, as breached password lists give them.
+type Hash [sha1.Size]byte
+
+// HashPassword returns the SHA-1 of password.
+func HashPassword(password string) Hash {
+	return sha1.Sum([]byte(password))
+}
@@ -0,0 +1,93 @@
+package pwned
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRangeChecker(t *testing.T) {
+	// SHA-1 of "password" is 5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8
+	var gotPath, gotPadding string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotPadding = r.URL.Path, r.Header.Get("Add-Padding")
+		fmt.Fprint(w, "003D68EB55068C33ACE09247EE4C639306B:3\r\n1E4C9B93F3F0682250B6CF8331B7EE68FD8:9659365\r\n0000000000000000000000000000000000A:0\r\n")
+	}))
+	defer srv.Close()
+	c := NewRangeChecker(srv.URL+"/", time.Second)
+
+	breached, err := c.Breached(context.Background(), "password")
+	if err != nil || !breached {
+		t.Fatalf("expected password breached, got %v, %v", breached, err)
+	}
+	if gotPath != "/range/5BAA6" || gotPadding != "true" {
+		t.Errorf("expected a padded query of the hash prefix, got %s (padding %q)", gotPath, gotPadding)
+	}
+
+	if breached, err := c.Breached(context.Background(), "not in the response"); err != nil || breached {
+		t.Errorf("expected another password not breached, got %v, %v", breached, err)
+	}
+}
+
+func TestRangeChecker_Unavailable(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	if _, err := NewRangeChecker(srv.URL, time.Second).Breached(context.Background(), "password"); err == nil {
+		t.Error("expected an error when the API fails")
+	}
+}
+
+func TestBloomFilter(t *testing.T) {
+	var list strings.Builder
+	for i := 0; i < 1000; i++ {
+		hash := HashPassword(fmt.Sprintf("breached-%d", i))
+		fmt.Fprintf(&list, "%s:%d\n", strings.ToUpper(hex.EncodeToString(hash[:])), i+1)
+	}
+
+	f := NewBloomFilter(1000, 0.001)
+	if err := ReadHashes(strings.NewReader(list.String()), f.Add); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if _, err := f.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := ReadBloomFilter(&buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i := 0; i < 1000; i++ {
+		if breached, _ := loaded.Breached(context.Background(), fmt.Sprintf("breached-%d", i)); !breached {
+			t.Fatalf("expected breached-%d in the filter", i)
+		}
+	}
+	falsePositives := 0
+	for i := 0; i < 10000; i++ {
+		if breached, _ := loaded.Breached(context.Background(), fmt.Sprintf("fine-%d", i)); breached {
+			falsePositives++
+		}
+	}
+	if falsePositives > 50 {
+		t.Errorf("expected about 0.1%% false positives, got %d in 10000", falsePositives)
+	}
+}
+
+func TestReadHashes_Invalid(t *testing.T) {
+	if err := ReadHashes(strings.NewReader("\nnot-a-hash:3\n"), func(Hash) {}); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("expected an error on line 2, got %v", err)
+	}
+	if _, err := ReadBloomFilter(strings.NewReader("garbage")); err == nil {
+		t.Error("expected an error for a file that is not a filter")
+	}
+}
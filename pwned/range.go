@@ -0,0 +1,68 @@
+package pwned
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultAPIURL is the Pwned Passwords API of haveibeenpwned.com.
+const DefaultAPIURL = "https://api.pwnedpasswords.com"
+
+// RangeChecker queries a Pwned Passwords range API: it sends the first five
+// hex characters of the SHA-1 of a password and looks for the others in the
+// suffixes sent back, padded so that the response size tells nothing.
+type RangeChecker struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewRangeChecker queries the API at baseURL, such as DefaultAPIURL.
+func NewRangeChecker(baseURL string, timeout time.Duration) *RangeChecker {
+	return &RangeChecker{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		client:  &http.Client{Timeout: timeout},
+	}
+}
+
+// Breached implements Checker.
+func (c *RangeChecker) Breached(ctx context.Context, password string) (bool, error) {
+	hash := HashPassword(password)
+	digest := strings.ToUpper(hex.EncodeToString(hash[:]))
+	prefix, suffix := digest[:5], digest[5:]
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/range/"+prefix, nil)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Add-Padding", "true")
+	req.Header.Set("User-Agent", "sandbox-api-go")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+		return false, fmt.Errorf("pwned passwords API responded %s", resp.Status)
+	}
+
+	// Lines read "SUFFIX:COUNT"; padding lines have a count of 0
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		candidate, count, ok := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if !ok || !strings.EqualFold(candidate, suffix) {
+			continue
+		}
+		n, err := strconv.Atoi(count)
+		return err == nil && n > 0, nil
+	}
+	return false, scanner.Err()
+}
@@ -0,0 +1,88 @@
+// Package ratelimit implements token buckets kept in a Store: in memory for
+// a single instance, or in Redis so limits hold across instances.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Store holds token buckets by key.
+type Store interface {
+	// Take removes a token from the bucket named key, which refills at rate
+	// tokens per second up to burst. When the bucket is empty it returns
+	// false and how long until a token is available.
+	Take(ctx context.Context, key string, rate float64, burst int) (bool, time.Duration, error)
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	full   time.Time // when the bucket is refilled to burst
+}
+
+// MemoryStore is a Store local to the process.
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	stopCh  chan struct{}
+}
+
+// NewMemoryStore creates a MemoryStore and starts a goroutine dropping
+// buckets that have been full for a while. Stop ends it.
+func NewMemoryStore() *MemoryStore {
+	s := &MemoryStore{
+		buckets: make(map[string]*bucket),
+		stopCh:  make(chan struct{}),
+	}
+	go s.cleanup()
+	return s
+}
+
+// Take implements Store.
+func (s *MemoryStore) Take(_ context.Context, key string, rate float64, burst int) (bool, time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(burst), last: now}
+		s.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(burst), b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		b.full = now.Add(time.Duration((float64(burst) - b.tokens) / rate * float64(time.Second)))
+		return true, 0, nil
+	}
+	return false, time.Duration((1 - b.tokens) / rate * float64(time.Second)), nil
+}
+
+// Stop terminates the cleanup goroutine.
+func (s *MemoryStore) Stop() {
+	close(s.stopCh)
+}
+
+func (s *MemoryStore) cleanup() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.mu.Lock()
+			for key, b := range s.buckets {
+				if time.Since(b.last) > 10*time.Minute && time.Now().After(b.full) {
+					delete(s.buckets, key)
+				}
+			}
+			s.mu.Unlock()
+		case <-s.stopCh:
+			return
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestStores(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	memory := NewMemoryStore()
+	defer memory.Stop()
+
+	stores := map[string]Store{
+		"memory": memory,
+		"redis":  NewRedisStore(client, "test:"),
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			for i := 0; i < 3; i++ {
+				allowed, _, err := store.Take(ctx, "user:1", 1, 3)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if !allowed {
+					t.Fatalf("request %d: expected to be allowed within the burst", i+1)
+				}
+			}
+
+			allowed, wait, err := store.Take(ctx, "user:1", 1, 3)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if allowed {
+				t.Error("expected the request past the burst to be rejected")
+			}
+			if wait <= 0 || wait > time.Second {
+				t.Errorf("expected a wait of at most 1s, got %s", wait)
+			}
+
+			// Buckets are independent
+			if allowed, _, _ := store.Take(ctx, "user:2", 1, 3); !allowed {
+				t.Error("expected another key to have its own bucket")
+			}
+		})
+	}
+
+	if ttl := mr.TTL("test:user:1"); ttl <= 0 || ttl > 3*time.Second {
+		t.Errorf("expected the Redis bucket to expire once full again, got TTL %s", ttl)
+	}
+}
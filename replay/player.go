@@ -0,0 +1,89 @@
+package replay
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/clementhaon/sandbox-api-go/auth"
+	"github.com/clementhaon/sandbox-api-go/models"
+)
+
+// playerCSRFToken is the CSRF token of replayed requests, sent as both the
+// cookie and the header.
+const playerCSRFToken = "replay"
+
+// tenantHeader names the tenant of a request, as middleware.TenantHeader.
+const tenantHeader = "X-Tenant"
+
+// Player sends recorded requests to a handler, as the users they were
+// recorded for.
+type Player struct {
+	handler  http.Handler
+	jwt      *auth.JWTManager
+	basePath string
+}
+
+// NewPlayer returns a Player sending requests to handler, served under
+// basePath, with tokens signed by jwtManager. The handler must accept the
+// tokens of jwtManager, and serve the same IDs as when the log was recorded:
+// the log must be played from the state it was recorded from, usually an
+// empty database.
+func NewPlayer(handler http.Handler, jwtManager *auth.JWTManager, basePath string) *Player {
+	return &Player{handler: handler, jwt: jwtManager, basePath: strings.TrimSuffix(basePath, "/")}
+}
+
+// Play sends the request of e, with Password as the password of its body,
+// and returns the status of the response.
+func (p *Player) Play(e Entry) (int, error) {
+	body := e.RequestBody()
+	if e.BodyBase64 == nil {
+		body = []byte(replacePasswords(e.Body, e.ContentType, Password))
+	}
+	req, err := http.NewRequest(e.Method, p.basePath+e.Path, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("%s %s: %w", e.Method, e.Path, err)
+	}
+	req.RemoteAddr = "127.0.0.1:0"
+	if e.Host != "" {
+		req.Host = e.Host
+	}
+	if e.Tenant != "" {
+		req.Header.Set(tenantHeader, e.Tenant)
+	}
+	if e.ContentType != "" {
+		req.Header.Set("Content-Type", e.ContentType)
+	}
+	req.AddCookie(&http.Cookie{Name: "csrf_token", Value: playerCSRFToken})
+	req.Header.Set("X-CSRF-Token", playerCSRFToken)
+	if e.User != nil {
+		token, err := p.token(e.User)
+		if err != nil {
+			return 0, fmt.Errorf("%s %s: %w", e.Method, e.Path, err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	rec := httptest.NewRecorder()
+	p.handler.ServeHTTP(rec, req)
+	return rec.Code, nil
+}
+
+// token signs a token with the claims of user.
+func (p *Player) token(claims *models.Claims) (string, error) {
+	user := models.User{
+		ID:       claims.UserID,
+		TenantID: claims.TenantID,
+		Username: claims.Username,
+		Role:     claims.Role,
+	}
+	switch {
+	case claims.ImpersonatorID != 0:
+		return p.jwt.GenerateImpersonationToken(user, claims.ImpersonatorID, auth.TokenTTL)
+	case claims.Scopes != nil:
+		return p.jwt.GenerateScopedToken(user, claims.Scopes, auth.TokenTTL)
+	}
+	return p.jwt.GenerateToken(user)
+}
@@ -0,0 +1,49 @@
+package replay
+
+import (
+	"mime"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// redactedValue replaces the passwords and secrets of recorded requests.
+const redactedValue = "[REDACTED]"
+
+// Password is sent by the player in place of each password of a recorded
+// body, so an account registered by a log logs in with it later in the
+// same log.
+const Password = "Replay-Password-1"
+
+// passwordJSONField matches string values of JSON fields whose name
+// mentions a password.
+var passwordJSONField = regexp.MustCompile(`(?i)("[^"]*password[^"]*"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+
+// passwordFormField does the same for URL-encoded form fields.
+var passwordFormField = regexp.MustCompile(`(?i)((?:^|&)[^=&]*password[^=&]*=)[^&]*`)
+
+// secretQueryParam matches the values of query parameters whose name
+// mentions a secret or a password, such as the one of inbound webhooks.
+var secretQueryParam = regexp.MustCompile(`(?i)((?:^|&)[^=&]*(?:secret|password)[^=&]*=)[^&]*`)
+
+// redact replaces the secrets of the query string and the passwords of the
+// body of e.
+func (e *Entry) redact() {
+	if path, query, ok := strings.Cut(e.Path, "?"); ok {
+		e.Path = path + "?" + secretQueryParam.ReplaceAllString(query, "${1}"+url.QueryEscape(redactedValue))
+	}
+	e.Body = replacePasswords(e.Body, e.ContentType, redactedValue)
+}
+
+// replacePasswords returns body with the value of every password field set
+// to password, for JSON and URL-encoded bodies.
+func replacePasswords(body, contentType, password string) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		return passwordJSONField.ReplaceAllString(body, `${1}"`+password+`"`)
+	case mediaType == "application/x-www-form-urlencoded":
+		return passwordFormField.ReplaceAllString(body, "${1}"+url.QueryEscape(password))
+	}
+	return body
+}
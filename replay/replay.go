@@ -0,0 +1,196 @@
+// Package replay records the requests changing the data of the API to an
+// append-only log, and plays such a log against another instance, such as
+// one with a fresh database, to reproduce the state it led to.
+//
+// Entries carry the claims of the user a request was authenticated as
+// rather than its token: the player signs a new token for each, so a log
+// keeps working after its tokens expire. Passwords in bodies and secrets in
+// query strings are redacted before an entry is written; the player sends
+// Password in place of each body password, so the accounts registered by
+// a log can still log in when it is played.
+package replay
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/clementhaon/sandbox-api-go/models"
+)
+
+// Entry is a recorded request, one JSON line of the log.
+type Entry struct {
+	Time   time.Time `json:"time"`
+	Method string    `json:"method"`
+	// Path is relative to BASE_PATH, with the query string if any
+	Path string `json:"path"`
+	Host string `json:"host,omitempty"`
+	// Tenant is the slug of the tenant the request was served for in
+	// multi-tenant mode, sent as the X-Tenant header on replay
+	Tenant      string `json:"tenant,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	// Body is the request body when it is UTF-8 text, else BodyBase64
+	Body       string `json:"body,omitempty"`
+	BodyBase64 []byte `json:"body_base64,omitempty"`
+	// User is who the request was authenticated as; nil for public routes
+	User   *models.Claims `json:"user,omitempty"`
+	Status int            `json:"status"`
+}
+
+// SetBody stores body in e, as text when it is valid UTF-8.
+func (e *Entry) SetBody(body []byte) {
+	e.Body, e.BodyBase64 = "", nil
+	if utf8.Valid(body) {
+		e.Body = string(body)
+	} else {
+		e.BodyBase64 = body
+	}
+}
+
+// RequestBody returns the body stored in e.
+func (e *Entry) RequestBody() []byte {
+	if e.BodyBase64 != nil {
+		return e.BodyBase64
+	}
+	return []byte(e.Body)
+}
+
+// Mutating tells whether requests of method may change data, and are
+// recorded.
+func Mutating(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// Recorder appends entries to a log file. It is safe for concurrent use.
+type Recorder struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewRecorder opens the log at path, creating it if needed. Entries are
+// added after the ones already there.
+func NewRecorder(path string) (*Recorder, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return &Recorder{file: file}, nil
+}
+
+// Record appends e to the log, redacted,, as a single write so a crash cannot leave
+// half an entry followed by another.
+func (r *Recorder) Record(e Entry) error {
+	e.redact()
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, err = r.file.Write(line)
+	return err
+}
+
+// Close closes the log file.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.file.Close()
+}
+
+// ReadLog reads the entries of a log, in order. A last line cut short, as
+// a crash while recording leaves it, is skipped.
+func ReadLog(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+	reader := bufio.NewReader(r)
+	for line := 1; ; line++ {
+		data, err := reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		last := err == io.EOF
+		var e Entry
+		if uerr := json.Unmarshal(data, &e); uerr != nil {
+			if last {
+				return entries, nil
+			}
+			return nil, fmt.Errorf("line %d: %w", line, uerr)
+		}
+		entries = append(entries, e)
+		if last {
+			return entries, nil
+		}
+	}
+}
+
+type contextKey struct{}
+
+// scope holds what the middlewares deeper in the chain learn about a
+// recorded request.
+type scope struct {
+	mu     sync.Mutex
+	user   *models.Claims
+	tenant string
+}
+
+// NewContext returns a context collecting the user and tenant of a
+// recorded request.
+func NewContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, contextKey{}, &scope{})
+}
+
+// SetUser records the user the request of ctx is authenticated as, when
+// the request is recorded.
+func SetUser(ctx context.Context, claims *models.Claims) {
+	if s, ok := ctx.Value(contextKey{}).(*scope); ok {
+		user := *claims
+		s.mu.Lock()
+		s.user = &user
+		s.mu.Unlock()
+	}
+}
+
+// User returns the user recorded with SetUser in ctx, or nil.
+func User(ctx context.Context) *models.Claims {
+	s, ok := ctx.Value(contextKey{}).(*scope)
+	if !ok {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.user
+}
+
+// SetTenant records the slug of the tenant the request of ctx is served
+// for, when the request is recorded.
+func SetTenant(ctx context.Context, slug string) {
+	if s, ok := ctx.Value(contextKey{}).(*scope); ok {
+		s.mu.Lock()
+		s.tenant = slug
+		s.mu.Unlock()
+	}
+}
+
+// Tenant returns the tenant recorded with SetTenant in ctx, or "".
+func Tenant(ctx context.Context) string {
+	s, ok := ctx.Value(contextKey{}).(*scope)
+	if !ok {
+		return ""
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tenant
+}
@@ -0,0 +1,148 @@
+package replay
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/clementhaon/sandbox-api-go/auth"
+	"github.com/clementhaon/sandbox-api-go/models"
+)
+
+func TestRecorder_ReadLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "requests.log")
+	rec, err := NewRecorder(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := Entry{Method: http.MethodPost, Path: "/tasks", Status: http.StatusCreated, User: &models.Claims{UserID: 1}}
+	text.SetBody([]byte(`{"title":"Write docs"}`))
+	binary := Entry{Method: http.MethodPost, Path: "/media", Status: http.StatusCreated}
+	binary.SetBody([]byte{0xff, 0x00, 0xfe})
+	for _, e := range []Entry{text, binary} {
+		if err := rec.Record(e); err != nil {
+			t.Fatalf("record: %v", err)
+		}
+	}
+	rec.Close()
+
+	// A crash while recording leaves a last line cut short
+	f, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	f.WriteString(`{"method":"DELETE","pa`)
+	f.Close()
+
+	data, _ := os.ReadFile(path)
+	entries, err := ReadLog(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+	if string(entries[0].RequestBody()) != `{"title":"Write docs"}` || entries[0].User.UserID != 1 {
+		t.Errorf("unexpected text entry: %+v", entries[0])
+	}
+	if !bytes.Equal(entries[1].RequestBody(), []byte{0xff, 0x00, 0xfe}) {
+		t.Errorf("expected the binary body back, got %v", entries[1].RequestBody())
+	}
+
+	if _, err := ReadLog(strings.NewReader("{}\nnot json\n{}\n")); err == nil {
+		t.Error("expected an error for a corrupt line in the middle of the log")
+	}
+}
+
+func TestSetUser(t *testing.T) {
+	// Without NewContext nothing is recorded
+	SetUser(context.Background(), &models.Claims{UserID: 1})
+
+	ctx := NewContext(context.Background())
+	if User(ctx) != nil {
+		t.Error("expected no user before SetUser")
+	}
+	SetUser(ctx, &models.Claims{UserID: 7, Role: "admin"})
+	if user := User(ctx); user == nil || user.UserID != 7 || user.Role != "admin" {
+		t.Errorf("unexpected user %+v", user)
+	}
+
+	SetTenant(ctx, "acme")
+	if Tenant(ctx) != "acme" {
+		t.Errorf("expected tenant acme, got %q", Tenant(ctx))
+	}
+}
+
+func TestPlayer_Play(t *testing.T) {
+	jwtManager, err := auth.NewJWTManager("at-least-sixteen-chars")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got *http.Request
+	var body string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+		w.WriteHeader(http.StatusCreated)
+	})
+	player := NewPlayer(handler, jwtManager, "/api/")
+
+	entry := Entry{
+		Method:      http.MethodPost,
+		Path:        "/tasks?notify=false",
+		Host:        "acme.example.com",
+		Tenant:      "acme",
+		ContentType: "application/json",
+		Body:        `{"title":"Write docs"}`,
+		User:        &models.Claims{UserID: 3, TenantID: 2, Username: "alice", Scopes: []string{models.ScopeTasksWrite}},
+	}
+	status, err := player.Play(entry)
+	if err != nil || status != http.StatusCreated {
+		t.Fatalf("expected 201, got %d (%v)", status, err)
+	}
+	if got.URL.RequestURI() != "/api/tasks?notify=false" || got.Host != "acme.example.com" || body != entry.Body {
+		t.Errorf("unexpected request %s %s on %s with %q", got.Method, got.URL.RequestURI(), got.Host, body)
+	}
+	if got.Header.Get("X-Tenant") != "acme" {
+		t.Errorf("expected the recorded tenant, got %q", got.Header.Get("X-Tenant"))
+	}
+	cookie, err := got.Cookie("csrf_token")
+	if err != nil || cookie.Value != got.Header.Get("X-CSRF-Token") {
+		t.Error("expected matching CSRF cookie and header")
+	}
+
+	claims, err := jwtManager.ValidateToken(strings.TrimPrefix(got.Header.Get("Authorization"), "Bearer "))
+	if err != nil {
+		t.Fatalf("expected a valid token: %v", err)
+	}
+	if claims.UserID != 3 || claims.TenantID != 2 || len(claims.Scopes) != 1 {
+		t.Errorf("expected the recorded user and scopes, got %+v", claims)
+	}
+
+	// Public requests are sent without a token
+	if _, err := player.Play(Entry{Method: http.MethodPost, Path: "/auth/register"}); err != nil {
+		t.Fatal(err)
+	}
+	if got.Header.Get("Authorization") != "" {
+		t.Error("expected no token for an entry without user")
+	}
+
+	// Redacted passwords are replaced by the replay one
+	register := Entry{Method: http.MethodPost, Path: "/auth/register", ContentType: "application/json", Body: `{"username":"bob","password":"[REDACTED]"}`}
+	if _, err := player.Play(register); err != nil {
+		t.Fatal(err)
+	}
+	if body != `{"username":"bob","password":"`+Password+`"}` {
+		t.Errorf("expected the replay password, got %s", body)
+	}
+	form := Entry{Method: http.MethodPost, Path: "/auth/login", ContentType: "application/x-www-form-urlencoded", Body: "username=bob&password=%5BREDACTED%5D"}
+	if _, err := player.Play(form); err != nil {
+		t.Fatal(err)
+	}
+	if body != "username=bob&password="+Password {
+		t.Errorf("expected the replay password, got %s", body)
+	}
+}
@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/database"
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/tenant"
+)
+
+// activityRow is a row of activities.
+type activityRow struct {
+	ID        int       `json:"id"`
+	TenantID  int       `json:"tenant_id"`
+	UserID    int       `json:"user_id"`
+	ActorID   *int      `json:"actor_id"`
+	Type      string    `json:"type"`
+	TaskID    *int      `json:"task_id"`
+	TaskTitle string    `json:"task_title"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type memoryActivityRepo struct {
+	memoryRepo
+}
+
+// NewMemoryActivityRepository returns an ActivityRepository keeping its
+// data in store.
+func NewMemoryActivityRepository(store *MemoryStore) ActivityRepository {
+	return &memoryActivityRepo{memoryRepo{store: store}}
+}
+
+func (r *memoryActivityRepo) WithQuerier(q database.Querier) ActivityRepository {
+	return &memoryActivityRepo{r.bind(q)}
+}
+
+func (r *memoryActivityRepo) Create(ctx context.Context, userIDs []int, a models.Activity) error {
+	return r.write(ctx, "INSERT", "activities", func(d *memoryData) error {
+		for _, userID := range userIDs {
+			if _, ok := d.users.get(userID); !ok {
+				return constraintError("insert or update on table activities violates foreign key constraint activities_user_id_fkey")
+			}
+		}
+		if a.TaskID != nil {
+			if _, ok := d.tasks.get(*a.TaskID); !ok {
+				return constraintError("insert or update on table activities violates foreign key constraint activities_task_id_fkey")
+			}
+		}
+		now := memoryNow()
+		for _, userID := range userIDs {
+			d.activities.put(activityRow{
+				ID: d.activities.nextID(), TenantID: tenant.FromContext(ctx), UserID: userID, ActorID: a.ActorID,
+				Type: a.Type, TaskID: a.TaskID, TaskTitle: a.TaskTitle, CreatedAt: now,
+			})
+		}
+		return nil
+	})
+}
+
+func (r *memoryActivityRepo) ListForUser(ctx context.Context, userID int, before *models.TaskCursor, limit int) ([]models.Activity, error) {
+	activities := []models.Activity{}
+	err := r.read(ctx, "SELECT", "activities", func(d *memoryData) error {
+		tenantID := tenant.FromContext(ctx)
+		rows := d.activities.filter(func(a activityRow) bool {
+			return a.UserID == userID && a.TenantID == tenantID && (before == nil ||
+				cmp.Or(a.CreatedAt.Compare(before.UpdatedAt), cmp.Compare(a.ID, before.ID)) < 0)
+		})
+		slices.SortFunc(rows, func(a, b activityRow) int {
+			return cmp.Or(b.CreatedAt.Compare(a.CreatedAt), cmp.Compare(b.ID, a.ID))
+		})
+		for _, a := range pageOf(rows, limit, 0) {
+			activities = append(activities, models.Activity{
+				ID: a.ID, Type: a.Type, ActorID: a.ActorID, TaskID: a.TaskID, TaskTitle: a.TaskTitle, CreatedAt: a.CreatedAt,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return activities, nil
+}
@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/database"
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/logger"
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/tenant"
+
+	"github.com/lib/pq"
+)
+
+type ActivityRepository interface {
+	// Create adds the activity to the feed of each of userIDs.
+	Create(ctx context.Context, userIDs []int, a models.Activity) error
+	// ListForUser returns up to limit entries of a user's feed, newest first,
+	// that come before the cursor, or from the newest if before is nil.
+	ListForUser(ctx context.Context, userID int, before *models.TaskCursor, limit int) ([]models.Activity, error)
+	WithQuerier(q database.Querier) ActivityRepository
+}
+
+type postgresActivityRepo struct {
+	db database.Querier
+}
+
+func NewPostgresActivityRepository(db *sql.DB) ActivityRepository {
+	return &postgresActivityRepo{db: db}
+}
+
+func (r *postgresActivityRepo) WithQuerier(q database.Querier) ActivityRepository {
+	return &postgresActivityRepo{db: q}
+}
+
+const (
+	activityID        column = "id"
+	activityType      column = "type"
+	activityActorID   column = "actor_id"
+	activityTaskID    column = "task_id"
+	activityTaskTitle column = "task_title"
+	activityCreatedAt column = "created_at"
+)
+
+func activityFields(a *models.Activity) []field {
+	return []field{
+		{activityID, &a.ID}, {activityType, &a.Type}, {activityActorID, &a.ActorID},
+		{activityTaskID, &a.TaskID}, {activityTaskTitle, &a.TaskTitle}, {activityCreatedAt, &a.CreatedAt},
+	}
+}
+
+func (r *postgresActivityRepo) Create(ctx context.Context, userIDs []int, a models.Activity) error {
+	startTime := time.Now()
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO activities (tenant_id, user_id, actor_id, type, task_id, task_title)
+		SELECT $1, u, $2, $3, $4, $5 FROM unnest($6::int[]) AS u`,
+		tenant.FromContext(ctx), a.ActorID, a.Type, a.TaskID, a.TaskTitle, pq.Array(userIDs))
+	logger.LogDatabaseOperation(ctx, "INSERT", "activities", time.Since(startTime), err)
+
+	if err != nil {
+		logger.ErrorContext(ctx, "Error creating activity", err)
+		return errors.NewDatabaseError().WithCause(err)
+	}
+	return nil
+}
+
+func (r *postgresActivityRepo) ListForUser(ctx context.Context, userID int, before *models.TaskCursor, limit int) ([]models.Activity, error) {
+	q := (&query{}).add(`SELECT `+columnList("", activityFields(new(models.Activity)))+`
+		FROM activities WHERE user_id = ? AND tenant_id = ?`, userID, tenant.FromContext(ctx))
+	if before != nil {
+		q.add(` AND (created_at, id) < (?, ?)`, before.UpdatedAt, before.ID)
+	}
+	q.add(` ORDER BY created_at DESC, id DESC LIMIT ?`, limit)
+
+	startTime := time.Now()
+	rows, err := r.db.QueryContext(ctx, q.String(), q.args...)
+	logger.LogDatabaseOperation(ctx, "SELECT", "activities", time.Since(startTime), err)
+	if err != nil {
+		logger.ErrorContext(ctx, "Error querying activities", err)
+		return nil, errors.NewDatabaseError().WithCause(err)
+	}
+	defer rows.Close()
+
+	activities := []models.Activity{}
+	for rows.Next() {
+		var a models.Activity
+		if err := rows.Scan(dests(activityFields(&a))...); err != nil {
+			logger.ErrorContext(ctx, "Error scanning activity row", err)
+			return nil, errors.NewDatabaseError().WithCause(err)
+		}
+		activities = append(activities, a)
+	}
+	return activities, nil
+}
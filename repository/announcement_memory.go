@@ -0,0 +1,190 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/database"
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/tenant"
+)
+
+// announcementRow is a row of announcements.
+type announcementRow struct {
+	ID        int        `json:"id"`
+	TenantID  int        `json:"tenant_id"`
+	Title     string     `json:"title"`
+	Body      string     `json:"body"`
+	Level     string     `json:"level"`
+	StartsAt  time.Time  `json:"starts_at"`
+	EndsAt    *time.Time `json:"ends_at"`
+	CreatedBy *int       `json:"created_by"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+func (r announcementRow) model() models.Announcement {
+	return models.Announcement{
+		ID: r.ID, Title: r.Title, Body: r.Body, Level: r.Level, StartsAt: r.StartsAt, EndsAt: clonePtr(r.EndsAt),
+		CreatedBy: clonePtr(r.CreatedBy), CreatedAt: r.CreatedAt, UpdatedAt: r.UpdatedAt,
+	}
+}
+
+// announcementDismissalRow is a row of announcement_dismissals.
+type announcementDismissalRow struct {
+	AnnouncementID int       `json:"announcement_id"`
+	UserID         int       `json:"user_id"`
+	DismissedAt    time.Time `json:"dismissed_at"`
+}
+
+func (r announcementDismissalRow) key() string {
+	return fmt.Sprintf("%d/%d", r.AnnouncementID, r.UserID)
+}
+
+// tenantAnnouncement returns announcement id, if it belongs to the tenant
+// of ctx.
+func (d *memoryData) tenantAnnouncement(ctx context.Context, id int) (announcementRow, bool) {
+	a, ok := d.announcements.get(id)
+	return a, ok && a.TenantID == tenant.FromContext(ctx)
+}
+
+// setAnnouncement applies req to a, with the checks of the table.
+func setAnnouncement(a *announcementRow, req models.AnnouncementRequest) error {
+	if !slices.Contains([]string{models.AnnouncementLevelInfo, models.AnnouncementLevelFeature, models.AnnouncementLevelMaintenance}, req.Level) {
+		return constraintError("new row for relation announcements violates check constraint announcements_level_check")
+	}
+	a.Title, a.Body, a.Level = req.Title, req.Body, req.Level
+	a.StartsAt = memoryNow()
+	if req.StartsAt != nil {
+		a.StartsAt = req.StartsAt.Truncate(time.Microsecond)
+	}
+	a.EndsAt = nil
+	if req.EndsAt != nil {
+		endsAt := req.EndsAt.Truncate(time.Microsecond)
+		if !endsAt.After(a.StartsAt) {
+			return constraintError("new row for relation announcements violates check constraint announcements_check")
+		}
+		a.EndsAt = &endsAt
+	}
+	return nil
+}
+
+func announcementList(rows []announcementRow) []models.Announcement {
+	slices.SortFunc(rows, func(a, b announcementRow) int {
+		return cmp.Or(b.StartsAt.Compare(a.StartsAt), cmp.Compare(b.ID, a.ID))
+	})
+	announcements := []models.Announcement{}
+	for _, a := range rows {
+		announcements = append(announcements, a.model())
+	}
+	return announcements
+}
+
+type memoryAnnouncementRepo struct {
+	memoryRepo
+}
+
+// NewMemoryAnnouncementRepository returns an AnnouncementRepository keeping
+// its data in store.
+func NewMemoryAnnouncementRepository(store *MemoryStore) AnnouncementRepository {
+	return &memoryAnnouncementRepo{memoryRepo{store: store}}
+}
+
+func (r *memoryAnnouncementRepo) WithQuerier(q database.Querier) AnnouncementRepository {
+	return &memoryAnnouncementRepo{r.bind(q)}
+}
+
+func (r *memoryAnnouncementRepo) List(ctx context.Context) ([]models.Announcement, error) {
+	var announcements []models.Announcement
+	err := r.read(ctx, "SELECT", "announcements", func(d *memoryData) error {
+		tenantID := tenant.FromContext(ctx)
+		announcements = announcementList(d.announcements.filter(func(a announcementRow) bool { return a.TenantID == tenantID }))
+		return nil
+	})
+	return announcements, err
+}
+
+func (r *memoryAnnouncementRepo) ListActive(ctx context.Context, userID int) ([]models.Announcement, error) {
+	var announcements []models.Announcement
+	err := r.read(ctx, "SELECT", "announcements", func(d *memoryData) error {
+		tenantID, now := tenant.FromContext(ctx), memoryNow()
+		announcements = announcementList(d.announcements.filter(func(a announcementRow) bool {
+			_, dismissed := d.announcementDismissals.get(announcementDismissalRow{AnnouncementID: a.ID, UserID: userID}.key())
+			return a.TenantID == tenantID && !a.StartsAt.After(now) && (a.EndsAt == nil || a.EndsAt.After(now)) && !dismissed
+		}))
+		return nil
+	})
+	return announcements, err
+}
+
+func (r *memoryAnnouncementRepo) Create(ctx context.Context, createdBy int, req models.AnnouncementRequest) (models.Announcement, error) {
+	var a announcementRow
+	err := r.write(ctx, "INSERT", "announcements", func(d *memoryData) error {
+		if _, ok := d.users.get(createdBy); !ok {
+			return constraintError("insert or update on table announcements violates foreign key constraint announcements_created_by_fkey")
+		}
+		now := memoryNow()
+		a = announcementRow{TenantID: tenant.FromContext(ctx), CreatedBy: &createdBy, CreatedAt: now, UpdatedAt: now}
+		if err := setAnnouncement(&a, req); err != nil {
+			return err
+		}
+		a.ID = d.announcements.nextID()
+		d.announcements.put(a)
+		return nil
+	})
+	if err != nil {
+		return models.Announcement{}, err
+	}
+	return a.model(), nil
+}
+
+func (r *memoryAnnouncementRepo) Update(ctx context.Context, id int, req models.AnnouncementRequest) (models.Announcement, error) {
+	var a announcementRow
+	err := r.write(ctx, "UPDATE", "announcements", func(d *memoryData) error {
+		var ok bool
+		if a, ok = d.tenantAnnouncement(ctx, id); !ok {
+			return errors.NewNotFoundError("Announcement not found")
+		}
+		if err := setAnnouncement(&a, req); err != nil {
+			return err
+		}
+		a.UpdatedAt = memoryNow()
+		d.announcements.put(a)
+		return nil
+	})
+	if err != nil {
+		return models.Announcement{}, err
+	}
+	return a.model(), nil
+}
+
+func (r *memoryAnnouncementRepo) Delete(ctx context.Context, id int) error {
+	return r.write(ctx, "DELETE", "announcements", func(d *memoryData) error {
+		if _, ok := d.tenantAnnouncement(ctx, id); !ok {
+			return errors.NewNotFoundError("Announcement not found")
+		}
+		d.announcements.delete(id)
+		d.announcementDismissals.deleteWhere(func(a announcementDismissalRow) bool { return a.AnnouncementID == id })
+		return nil
+	})
+}
+
+func (r *memoryAnnouncementRepo) Dismiss(ctx context.Context, id int, userID int) error {
+	return r.write(ctx, "INSERT", "announcement_dismissals", func(d *memoryData) error {
+		if _, ok := d.tenantAnnouncement(ctx, id); !ok {
+			return errors.NewNotFoundError("Announcement not found")
+		}
+		if _, ok := d.users.get(userID); !ok {
+			return constraintError("insert or update on table announcement_dismissals violates foreign key constraint announcement_dismissals_user_id_fkey")
+		}
+		dismissal := announcementDismissalRow{AnnouncementID: id, UserID: userID, DismissedAt: memoryNow()}
+		if _, ok := d.announcementDismissals.get(dismissal.key()); !ok {
+			d.announcementDismissals.put(dismissal)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,191 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/database"
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/logger"
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/tenant"
+)
+
+type AnnouncementRepository interface {
+	// List returns every announcement of the tenant, latest start first.
+	List(ctx context.Context) ([]models.Announcement, error)
+	// ListActive returns the announcements active now that the user has not
+	// dismissed, latest start first.
+	ListActive(ctx context.Context, userID int) ([]models.Announcement, error)
+	Create(ctx context.Context, createdBy int, req models.AnnouncementRequest) (models.Announcement, error)
+	Update(ctx context.Context, id int, req models.AnnouncementRequest) (models.Announcement, error)
+	Delete(ctx context.Context, id int) error
+	// Dismiss hides an announcement from a user. Dismissing it again is a
+	// no-op; it returns a NotFound error if the announcement does not exist.
+	Dismiss(ctx context.Context, id int, userID int) error
+	WithQuerier(q database.Querier) AnnouncementRepository
+}
+
+type postgresAnnouncementRepo struct {
+	db database.Querier
+}
+
+func NewPostgresAnnouncementRepository(db *sql.DB) AnnouncementRepository {
+	return &postgresAnnouncementRepo{db: db}
+}
+
+func (r *postgresAnnouncementRepo) WithQuerier(q database.Querier) AnnouncementRepository {
+	return &postgresAnnouncementRepo{db: q}
+}
+
+const (
+	announcementID        column = "id"
+	announcementTitle     column = "title"
+	announcementBody      column = "body"
+	announcementLevel     column = "level"
+	announcementStartsAt  column = "starts_at"
+	announcementEndsAt    column = "ends_at"
+	announcementCreatedBy column = "created_by"
+	announcementCreatedAt column = "created_at"
+	announcementUpdatedAt column = "updated_at"
+)
+
+func announcementFields(a *models.Announcement) []field {
+	return []field{
+		{announcementID, &a.ID}, {announcementTitle, &a.Title}, {announcementBody, &a.Body},
+		{announcementLevel, &a.Level}, {announcementStartsAt, &a.StartsAt}, {announcementEndsAt, &a.EndsAt},
+		{announcementCreatedBy, &a.CreatedBy}, {announcementCreatedAt, &a.CreatedAt}, {announcementUpdatedAt, &a.UpdatedAt},
+	}
+}
+
+var announcementColumns = columnList("", announcementFields(new(models.Announcement)))
+
+func (r *postgresAnnouncementRepo) List(ctx context.Context) ([]models.Announcement, error) {
+	startTime := time.Now()
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+announcementColumns+` FROM announcements WHERE tenant_id = $1 ORDER BY starts_at DESC, id DESC`,
+		tenant.FromContext(ctx))
+	logger.LogDatabaseOperation(ctx, "SELECT", "announcements", time.Since(startTime), err)
+	return r.scanAll(ctx, rows, err)
+}
+
+func (r *postgresAnnouncementRepo) ListActive(ctx context.Context, userID int) ([]models.Announcement, error) {
+	startTime := time.Now()
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+announcementColumns+` FROM announcements a
+		WHERE tenant_id = $1 AND starts_at <= NOW() AND (ends_at IS NULL OR ends_at > NOW())
+			AND NOT EXISTS (
+				SELECT 1 FROM announcement_dismissals d WHERE d.announcement_id = a.id AND d.user_id = $2
+			)
+		ORDER BY starts_at DESC, id DESC`,
+		tenant.FromContext(ctx), userID)
+	logger.LogDatabaseOperation(ctx, "SELECT", "announcements", time.Since(startTime), err)
+	return r.scanAll(ctx, rows, err)
+}
+
+func (r *postgresAnnouncementRepo) scanAll(ctx context.Context, rows *sql.Rows, err error) ([]models.Announcement, error) {
+	if err != nil {
+		logger.ErrorContext(ctx, "Error querying announcements", err)
+		return nil, errors.NewDatabaseError().WithCause(err)
+	}
+	defer rows.Close()
+
+	announcements := []models.Announcement{}
+	for rows.Next() {
+		var a models.Announcement
+		if err := rows.Scan(dests(announcementFields(&a))...); err != nil {
+			logger.ErrorContext(ctx, "Error scanning announcement row", err)
+			return nil, errors.NewDatabaseError().WithCause(err)
+		}
+		announcements = append(announcements, a)
+	}
+	return announcements, nil
+}
+
+func (r *postgresAnnouncementRepo) Create(ctx context.Context, createdBy int, req models.AnnouncementRequest) (models.Announcement, error) {
+	var a models.Announcement
+	startTime := time.Now()
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO announcements (tenant_id, title, body, level, starts_at, ends_at, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING `+announcementColumns,
+		tenant.FromContext(ctx), req.Title, req.Body, req.Level, req.StartsAt, req.EndsAt, createdBy,
+	).Scan(dests(announcementFields(&a))...)
+	logger.LogDatabaseOperation(ctx, "INSERT", "announcements", time.Since(startTime), err)
+
+	if err != nil {
+		logger.ErrorContext(ctx, "Error creating announcement", err)
+		return models.Announcement{}, errors.NewDatabaseError().WithCause(err)
+	}
+	return a, nil
+}
+
+func (r *postgresAnnouncementRepo) Update(ctx context.Context, id int, req models.AnnouncementRequest) (models.Announcement, error) {
+	var a models.Announcement
+	startTime := time.Now()
+	err := r.db.QueryRowContext(ctx, `
+		UPDATE announcements SET
+			title = $1, body = $2, level = $3, starts_at = $4, ends_at = $5, updated_at = NOW()
+		WHERE id = $6 AND tenant_id = $7
+		RETURNING `+announcementColumns,
+		req.Title, req.Body, req.Level, req.StartsAt, req.EndsAt, id, tenant.FromContext(ctx),
+	).Scan(dests(announcementFields(&a))...)
+	logger.LogDatabaseOperation(ctx, "UPDATE", "announcements", time.Since(startTime), err)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Announcement{}, errors.NewNotFoundError("Announcement not found")
+	}
+	if err != nil {
+		logger.ErrorContext(ctx, "Error updating announcement", err)
+		return models.Announcement{}, errors.NewDatabaseError().WithCause(err)
+	}
+	return a, nil
+}
+
+func (r *postgresAnnouncementRepo) Delete(ctx context.Context, id int) error {
+	startTime := time.Now()
+	result, err := r.db.ExecContext(ctx,
+		"DELETE FROM announcements WHERE id = $1 AND tenant_id = $2", id, tenant.FromContext(ctx))
+	logger.LogDatabaseOperation(ctx, "DELETE", "announcements", time.Since(startTime), err)
+
+	if err != nil {
+		logger.ErrorContext(ctx, "Error deleting announcement", err)
+		return errors.NewDatabaseError().WithCause(err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return errors.NewDatabaseError().WithCause(err)
+	}
+	if rowsAffected == 0 {
+		return errors.NewNotFoundError("Announcement not found")
+	}
+	return nil
+}
+
+func (r *postgresAnnouncementRepo) Dismiss(ctx context.Context, id int, userID int) error {
+	var found bool
+	startTime := time.Now()
+	err := r.db.QueryRowContext(ctx, `
+		WITH target AS (
+			SELECT id FROM announcements WHERE id = $1 AND tenant_id = $2
+		), dismissed AS (
+			INSERT INTO announcement_dismissals (announcement_id, user_id)
+			SELECT id, $3 FROM target
+			ON CONFLICT DO NOTHING
+		)
+		SELECT EXISTS (SELECT 1 FROM target)`,
+		id, tenant.FromContext(ctx), userID,
+	).Scan(&found)
+	logger.LogDatabaseOperation(ctx, "INSERT", "announcement_dismissals", time.Since(startTime), err)
+
+	if err != nil {
+		logger.ErrorContext(ctx, "Error dismissing announcement", err)
+		return errors.NewDatabaseError().WithCause(err)
+	}
+	if !found {
+		return errors.NewNotFoundError("Announcement not found")
+	}
+	return nil
+}
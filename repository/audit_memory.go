@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"slices"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/database"
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/tenant"
+)
+
+// auditLogRow is a row of audit_logs.
+type auditLogRow struct {
+	ID         int               `json:"id"`
+	TenantID   int               `json:"tenant_id"`
+	ActorID    *int              `json:"actor_id"`
+	Action     string            `json:"action"`
+	TargetType models.NullString `json:"target_type"`
+	TargetID   *int              `json:"target_id"`
+	IPAddress  models.NullString `json:"ip_address"`
+	UserAgent  models.NullString `json:"user_agent"`
+	Metadata   json.RawMessage   `json:"metadata"`
+	CreatedAt  time.Time         `json:"created_at"`
+}
+
+func (r auditLogRow) model() models.AuditLog {
+	return models.AuditLog{
+		ID: r.ID, ActorID: r.ActorID, Action: r.Action, TargetType: r.TargetType.String, TargetID: r.TargetID,
+		IPAddress: r.IPAddress.String, UserAgent: r.UserAgent.String, Metadata: slices.Clone(r.Metadata),
+		CreatedAt: r.CreatedAt,
+	}
+}
+
+type memoryAuditRepo struct {
+	memoryRepo
+}
+
+// NewMemoryAuditRepository returns an AuditRepository keeping its data in
+// store.
+func NewMemoryAuditRepository(store *MemoryStore) AuditRepository {
+	return &memoryAuditRepo{memoryRepo{store: store}}
+}
+
+func (r *memoryAuditRepo) WithQuerier(q database.Querier) AuditRepository {
+	return &memoryAuditRepo{r.bind(q)}
+}
+
+func (r *memoryAuditRepo) Create(ctx context.Context, log models.AuditLog) error {
+	metadata := log.Metadata
+	if len(metadata) == 0 {
+		metadata = []byte("{}")
+	}
+	if !json.Valid(metadata) {
+		return constraintError("invalid input syntax for type json")
+	}
+
+	return r.write(ctx, "INSERT", "audit_logs", func(d *memoryData) error {
+		if log.ActorID != nil {
+			if _, ok := d.users.get(*log.ActorID); !ok {
+				return constraintError("insert or update on table audit_logs violates foreign key constraint audit_logs_actor_id_fkey")
+			}
+		}
+		d.auditLogs.put(auditLogRow{
+			ID: d.auditLogs.nextID(), TenantID: tenant.FromContext(ctx), ActorID: log.ActorID, Action: log.Action,
+			TargetType: nullIfEmpty(log.TargetType), TargetID: log.TargetID, IPAddress: nullIfEmpty(log.IPAddress),
+			UserAgent: nullIfEmpty(log.UserAgent), Metadata: slices.Clone(metadata), CreatedAt: memoryNow(),
+		})
+		return nil
+	})
+}
+
+func (r *memoryAuditRepo) List(ctx context.Context, params models.AuditLogListParams) ([]models.AuditLog, int, error) {
+	logs := []models.AuditLog{}
+	var total int
+	err := r.read(ctx, "SELECT", "audit_logs", func(d *memoryData) error {
+		tenantID := tenant.FromContext(ctx)
+		rows := d.auditLogs.filter(func(l auditLogRow) bool {
+			return l.TenantID == tenantID &&
+				(params.Action == "" || l.Action == params.Action) &&
+				(params.ActorID == nil || l.ActorID != nil && *l.ActorID == *params.ActorID) &&
+				(params.TargetType == "" || l.TargetType.String == params.TargetType) &&
+				(params.TargetID == nil || l.TargetID != nil && *l.TargetID == *params.TargetID) &&
+				(params.From == nil || !l.CreatedAt.Before(*params.From)) &&
+				(params.To == nil || !l.CreatedAt.After(*params.To))
+		})
+		total = len(rows)
+
+		slices.SortFunc(rows, func(a, b auditLogRow) int {
+			return cmp.Or(b.CreatedAt.Compare(a.CreatedAt), cmp.Compare(b.ID, a.ID))
+		})
+		for _, l := range pageOf(rows, params.PageSize, (params.Page-1)*params.PageSize) {
+			logs = append(logs, l.model())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return logs, total, nil
+}
+
+func (r *memoryAuditRepo) DeleteOlderThan(ctx context.Context, before time.Time) (int64, error) {
+	var deleted int64
+	err := r.write(ctx, "DELETE", "audit_logs", func(d *memoryData) error {
+		deleted = int64(len(d.auditLogs.deleteWhere(func(l auditLogRow) bool { return l.CreatedAt.Before(before) })))
+		return nil
+	})
+	return deleted, err
+}
@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/database"
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/logger"
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/tenant"
+)
+
+type AuditRepository interface {
+	Create(ctx context.Context, log models.AuditLog) error
+	List(ctx context.Context, params models.AuditLogListParams) ([]models.AuditLog, int, error)
+	DeleteOlderThan(ctx context.Context, before time.Time) (int64, error)
+	WithQuerier(q database.Querier) AuditRepository
+}
+
+type postgresAuditRepo struct {
+	db database.Querier
+}
+
+func NewPostgresAuditRepository(db *sql.DB) AuditRepository {
+	return &postgresAuditRepo{db: db}
+}
+
+func (r *postgresAuditRepo) WithQuerier(q database.Querier) AuditRepository {
+	return &postgresAuditRepo{db: q}
+}
+
+func (r *postgresAuditRepo) Create(ctx context.Context, log models.AuditLog) error {
+	metadata := log.Metadata
+	if len(metadata) == 0 {
+		metadata = []byte("{}")
+	}
+
+	startTime := time.Now()
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO audit_logs (actor_id, action, target_type, target_id, ip_address, user_agent, metadata, tenant_id)
+		VALUES ($1, $2, NULLIF($3, ''), $4, NULLIF($5, ''), NULLIF($6, ''), $7, $8)
+	`, log.ActorID, log.Action, log.TargetType, log.TargetID, log.IPAddress, log.UserAgent, metadata, tenant.FromContext(ctx))
+	logger.LogDatabaseOperation(ctx, "INSERT", "audit_logs", time.Since(startTime), err)
+
+	if err != nil {
+		logger.ErrorContext(ctx, "Error creating audit log", err)
+		return errors.NewDatabaseError().WithCause(err)
+	}
+	return nil
+}
+
+func (r *postgresAuditRepo) List(ctx context.Context, params models.AuditLogListParams) ([]models.AuditLog, int, error) {
+	baseQuery := `FROM audit_logs WHERE tenant_id = $1`
+	args := []interface{}{tenant.FromContext(ctx)}
+	argIndex := 2
+
+	if params.Action != "" {
+		baseQuery += fmt.Sprintf(` AND action = $%d`, argIndex)
+		args = append(args, params.Action)
+		argIndex++
+	}
+	if params.ActorID != nil {
+		baseQuery += fmt.Sprintf(` AND actor_id = $%d`, argIndex)
+		args = append(args, *params.ActorID)
+		argIndex++
+	}
+	if params.TargetType != "" {
+		baseQuery += fmt.Sprintf(` AND target_type = $%d`, argIndex)
+		args = append(args, params.TargetType)
+		argIndex++
+	}
+	if params.TargetID != nil {
+		baseQuery += fmt.Sprintf(` AND target_id = $%d`, argIndex)
+		args = append(args, *params.TargetID)
+		argIndex++
+	}
+	if params.From != nil {
+		baseQuery += fmt.Sprintf(` AND created_at >= $%d`, argIndex)
+		args = append(args, *params.From)
+		argIndex++
+	}
+	if params.To != nil {
+		baseQuery += fmt.Sprintf(` AND created_at <= $%d`, argIndex)
+		args = append(args, *params.To)
+		argIndex++
+	}
+
+	var total int
+	startTime := time.Now()
+	err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) "+baseQuery, args...).Scan(&total)
+	logger.LogDatabaseOperation(ctx, "SELECT COUNT", "audit_logs", time.Since(startTime), err)
+	if err != nil {
+		logger.ErrorContext(ctx, "Error counting audit logs", err)
+		return nil, 0, errors.NewDatabaseError().WithCause(err)
+	}
+
+	offset := (params.Page - 1) * params.PageSize
+	selectQuery := fmt.Sprintf(`SELECT id, actor_id, action, COALESCE(target_type, ''), target_id,
+		COALESCE(ip_address, ''), COALESCE(user_agent, ''), metadata, created_at
+		%s ORDER BY created_at DESC, id DESC LIMIT $%d OFFSET $%d`, baseQuery, argIndex, argIndex+1)
+	args = append(args, params.PageSize, offset)
+
+	startTime = time.Now()
+	rows, err := r.db.QueryContext(ctx, selectQuery, args...)
+	logger.LogDatabaseOperation(ctx, "SELECT", "audit_logs", time.Since(startTime), err)
+	if err != nil {
+		logger.ErrorContext(ctx, "Error querying audit logs", err)
+		return nil, 0, errors.NewDatabaseError().WithCause(err)
+	}
+	defer rows.Close()
+
+	logs := []models.AuditLog{}
+	for rows.Next() {
+		var l models.AuditLog
+		if err := rows.Scan(&l.ID, &l.ActorID, &l.Action, &l.TargetType, &l.TargetID,
+			&l.IPAddress, &l.UserAgent, &l.Metadata, &l.CreatedAt); err != nil {
+			logger.ErrorContext(ctx, "Error scanning audit log row", err)
+			return nil, 0, errors.NewDatabaseError().WithCause(err)
+		}
+		logs = append(logs, l)
+	}
+	return logs, total, nil
+}
+
+// DeleteOlderThan purges across all tenants; retention is deployment-wide.
+func (r *postgresAuditRepo) DeleteOlderThan(ctx context.Context, before time.Time) (int64, error) {
+	startTime := time.Now()
+	result, err := r.db.ExecContext(ctx, `DELETE FROM audit_logs WHERE created_at < $1`, before)
+	logger.LogDatabaseOperation(ctx, "DELETE", "audit_logs", time.Since(startTime), err)
+
+	if err != nil {
+		logger.ErrorContext(ctx, "Error purging audit logs", err)
+		return 0, errors.NewDatabaseError().WithCause(err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, errors.NewDatabaseError().WithCause(err)
+	}
+	return rowsAffected, nil
+}
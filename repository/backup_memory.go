@@ -0,0 +1,165 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/clementhaon/sandbox-api-go/database"
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/tenant"
+)
+
+type memoryBackupRepo struct {
+	memoryRepo
+}
+
+// NewMemoryBackupRepository returns a BackupRepository copying the data of
+// store. Its backups restore into Postgres and the other way round.
+func NewMemoryBackupRepository(store *MemoryStore) BackupRepository {
+	return &memoryBackupRepo{memoryRepo{store: store}}
+}
+
+func (r *memoryBackupRepo) WithQuerier(q database.Querier) BackupRepository {
+	return &memoryBackupRepo{r.bind(q)}
+}
+
+// BeginSnapshot does nothing: a transaction of the store already excludes
+// every other operation.
+func (r *memoryBackupRepo) BeginSnapshot(ctx context.Context) error {
+	return nil
+}
+
+func (r *memoryBackupRepo) Tables(ctx context.Context) ([]string, error) {
+	var tables []string
+	err := r.read(ctx, "SELECT", "tables", func(d *memoryData) error {
+		for _, t := range d.tables() {
+			if t.tableName() != "tenants" {
+				tables = append(tables, t.tableName())
+			}
+		}
+		return nil
+	})
+	return tables, err
+}
+
+func (r *memoryBackupRepo) SchemaVersion(ctx context.Context) (int, error) {
+	return memorySchemaVersion(), nil
+}
+
+func (r *memoryBackupRepo) Dump(ctx context.Context, table string, fn func(row json.RawMessage) error) error {
+	var rows []json.RawMessage
+	err := r.read(ctx, "SELECT", table, func(d *memoryData) error {
+		t, err := memoryTableNamed(d, table)
+		if err != nil {
+			return err
+		}
+		ofTenant := memoryTenantScope(d, tenant.FromContext(ctx))
+		return t.dump(func(row json.RawMessage) error {
+			ok, err := ofTenant(row)
+			if ok {
+				rows = append(rows, row)
+			}
+			return err
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Clear deletes from the referencing tables first, so the users that scope
+// the rows without a tenant_id go last.
+func (r *memoryBackupRepo) Clear(ctx context.Context, tables []string) error {
+	return r.write(ctx, "DELETE", strings.Join(tables, ","), func(d *memoryData) error {
+		ofTenant := memoryTenantScope(d, tenant.FromContext(ctx))
+		for _, table := range slices.Backward(tables) {
+			t, err := memoryTableNamed(d, table)
+			if err != nil {
+				return err
+			}
+			if err := t.deleteRows(ofTenant); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Insert adds the rows as they are once they are known to be of the tenant:
+// like the Postgres one, it relies on the rows coming from a consistent
+// backup rather than checking them further.
+func (r *memoryBackupRepo) Insert(ctx context.Context, table string, rows []json.RawMessage) error {
+	return r.write(ctx, "INSERT", table, func(d *memoryData) error {
+		t, err := memoryTableNamed(d, table)
+		if err != nil {
+			return err
+		}
+		ofTenant := memoryTenantScope(d, tenant.FromContext(ctx))
+		for _, row := range rows {
+			ok, err := ofTenant(row)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return fmt.Errorf("a row of table %s is of another tenant", table)
+			}
+		}
+		if err := t.insert(rows); err != nil {
+			return errors.NewDatabaseError().WithCause(err)
+		}
+		return nil
+	})
+}
+
+func (r *memoryBackupRepo) ResetSequences(ctx context.Context, tables []string) error {
+	return r.write(ctx, "UPDATE", "sequences", func(d *memoryData) error {
+		for _, table := range tables {
+			t, err := memoryTableNamed(d, table)
+			if err != nil {
+				return err
+			}
+			t.resetSequence()
+		}
+		return nil
+	})
+}
+
+// memoryTenantScope returns whether a row belongs to tenantID, by its
+// tenant_id or else the tenant of its user_id.
+func memoryTenantScope(d *memoryData, tenantID int) func(row json.RawMessage) (bool, error) {
+	return func(row json.RawMessage) (bool, error) {
+		var owner struct {
+			TenantID *int `json:"tenant_id"`
+			UserID   *int `json:"user_id"`
+		}
+		if err := json.Unmarshal(row, &owner); err != nil {
+			return false, err
+		}
+		switch {
+		case owner.TenantID != nil:
+			return *owner.TenantID == tenantID, nil
+		case owner.UserID != nil:
+			user, ok := d.users.get(*owner.UserID)
+			return ok && user.TenantID == tenantID, nil
+		}
+		return false, constraintError("row without tenant_id or user_id")
+	}
+}
+
+func memoryTableNamed(d *memoryData, name string) (memoryTableData, error) {
+	t := d.table(name)
+	if t == nil {
+		return nil, constraintError("relation %q does not exist", name)
+	}
+	return t, nil
+}
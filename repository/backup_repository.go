@@ -0,0 +1,306 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/database"
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/logger"
+	"github.com/clementhaon/sandbox-api-go/tenant"
+
+	"github.com/lib/pq"
+)
+
+// BackupRepository copies the rows of the tenant of the context out of and
+// back into the database, for backups and restores. A row belongs to a
+// tenant by its tenant_id column or, without one, by the tenant of its
+// user_id; the tenants table itself is left out.
+type BackupRepository interface {
+	// BeginSnapshot makes the transaction it runs in read one snapshot of
+	// the database; it must be its first statement
+	BeginSnapshot(ctx context.Context) error
+	// Tables returns the tables of the application, the migrations and
+	// tenants tables aside, each after the tables it references
+	Tables(ctx context.Context) ([]string, error)
+	SchemaVersion(ctx context.Context) (int, error)
+	// Dump calls fn with each row of table, as a JSON object
+	Dump(ctx context.Context, table string, fn func(row json.RawMessage) error) error
+	// Clear deletes the rows of tables, which must include every table
+	// referencing them and be in the order of Tables
+	Clear(ctx context.Context, tables []string) error
+	// Insert adds rows, JSON objects as written by Dump, to table. Rows of
+	// another tenant are refused
+	Insert(ctx context.Context, table string, rows []json.RawMessage) error
+	// ResetSequences moves the sequences of the serial columns of tables
+	// past their greatest value
+	ResetSequences(ctx context.Context, tables []string) error
+	WithQuerier(q database.Querier) BackupRepository
+}
+
+type postgresBackupRepo struct {
+	db database.Querier
+}
+
+func NewPostgresBackupRepository(db *sql.DB) BackupRepository {
+	return &postgresBackupRepo{db: db}
+}
+
+func (r *postgresBackupRepo) WithQuerier(q database.Querier) BackupRepository {
+	return &postgresBackupRepo{db: q}
+}
+
+func (r *postgresBackupRepo) BeginSnapshot(ctx context.Context) error {
+	if _, err := r.db.ExecContext(ctx, `SET TRANSACTION ISOLATION LEVEL REPEATABLE READ, READ ONLY`); err != nil {
+		logger.ErrorContext(ctx, "Error starting backup snapshot", err)
+		return errors.NewDatabaseError().WithCause(err)
+	}
+	return nil
+}
+
+func (r *postgresBackupRepo) Tables(ctx context.Context) ([]string, error) {
+	startTime := time.Now()
+	tables, err := r.queryStrings(ctx, `
+		SELECT table_name FROM information_schema.tables
+		WHERE table_schema = current_schema() AND table_type = 'BASE TABLE'
+			AND table_name NOT IN ('schema_migrations', 'tenants')
+		ORDER BY table_name
+	`)
+	logger.LogDatabaseOperation(ctx, "SELECT", "information_schema.tables", time.Since(startTime), err)
+	if err != nil {
+		logger.ErrorContext(ctx, "Error listing tables", err)
+		return nil, errors.NewDatabaseError().WithCause(err)
+	}
+
+	startTime = time.Now()
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT DISTINCT child.relname, parent.relname
+		FROM pg_constraint con
+		JOIN pg_class child ON child.oid = con.conrelid
+		JOIN pg_class parent ON parent.oid = con.confrelid
+		WHERE con.contype = 'f' AND con.connamespace = current_schema()::regnamespace
+	`)
+	logger.LogDatabaseOperation(ctx, "SELECT", "pg_constraint", time.Since(startTime), err)
+	if err != nil {
+		logger.ErrorContext(ctx, "Error listing foreign keys", err)
+		return nil, errors.NewDatabaseError().WithCause(err)
+	}
+	defer rows.Close()
+
+	references := make(map[string][]string)
+	for rows.Next() {
+		var child, parent string
+		if err := rows.Scan(&child, &parent); err != nil {
+			return nil, errors.NewDatabaseError().WithCause(err)
+		}
+		references[child] = append(references[child], parent)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.NewDatabaseError().WithCause(err)
+	}
+	return dependencyOrder(tables, references), nil
+}
+
+// dependencyOrder sorts tables so that each comes after the tables it
+// references, and otherwise keeps their order. References to itself or to
+// tables not listed are ignored, and the tables of a cycle keep their order.
+func dependencyOrder(tables []string, references map[string][]string) []string {
+	ordered := make([]string, 0, len(tables))
+	state := make(map[string]int) // 1 while visiting, 2 once placed
+	var visit func(table string)
+	visit = func(table string) {
+		if state[table] != 0 {
+			return
+		}
+		state[table] = 1
+		for _, parent := range references[table] {
+			if slices.Contains(tables, parent) {
+				visit(parent)
+			}
+		}
+		state[table] = 2
+		ordered = append(ordered, table)
+	}
+	for _, table := range tables {
+		visit(table)
+	}
+	return ordered
+}
+
+func (r *postgresBackupRepo) SchemaVersion(ctx context.Context) (int, error) {
+	var version int
+	startTime := time.Now()
+	err := r.db.QueryRowContext(ctx, `SELECT version FROM schema_migrations`).Scan(&version)
+	logger.LogDatabaseOperation(ctx, "SELECT", "schema_migrations", time.Since(startTime), err)
+
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		logger.ErrorContext(ctx, "Error fetching schema version", err)
+		return 0, errors.NewDatabaseError().WithCause(err)
+	}
+	return version, nil
+}
+
+// tenantScope returns the condition on the rows of table, aliased t, of the
+// tenant passed as $1.
+func (r *postgresBackupRepo) tenantScope(ctx context.Context, table string) (string, error) {
+	startTime := time.Now()
+	columns, err := r.queryStrings(ctx, `
+		SELECT column_name FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = $1 AND column_name IN ('tenant_id', 'user_id')
+	`, table)
+	logger.LogDatabaseOperation(ctx, "SELECT", "information_schema.columns", time.Since(startTime), err)
+	if err != nil {
+		logger.ErrorContext(ctx, "Error listing tenant columns", err, map[string]interface{}{"table": table})
+		return "", errors.NewDatabaseError().WithCause(err)
+	}
+	switch {
+	case slices.Contains(columns, "tenant_id"):
+		return "t.tenant_id = $1", nil
+	case slices.Contains(columns, "user_id"):
+		return "t.user_id IN (SELECT id FROM users WHERE tenant_id = $1)", nil
+	}
+	return "", fmt.Errorf("table %s has no tenant_id or user_id column", table)
+}
+
+func (r *postgresBackupRepo) Dump(ctx context.Context, table string, fn func(row json.RawMessage) error) error {
+	scope, err := r.tenantScope(ctx, table)
+	if err != nil {
+		return err
+	}
+
+	startTime := time.Now()
+	rows, err := r.db.QueryContext(ctx, fmt.Sprintf(`SELECT row_to_json(t) FROM %s t WHERE %s`, pq.QuoteIdentifier(table), scope),
+		tenant.FromContext(ctx))
+	logger.LogDatabaseOperation(ctx, "SELECT", table, time.Since(startTime), err)
+	if err != nil {
+		logger.ErrorContext(ctx, "Error dumping table", err, map[string]interface{}{"table": table})
+		return errors.NewDatabaseError().WithCause(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row []byte
+		if err := rows.Scan(&row); err != nil {
+			return errors.NewDatabaseError().WithCause(err)
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return errors.NewDatabaseError().WithCause(err)
+	}
+	return nil
+}
+
+// Clear deletes from the referencing tables first, so the users that scope
+// the rows without a tenant_id go last.
+func (r *postgresBackupRepo) Clear(ctx context.Context, tables []string) error {
+	for _, table := range slices.Backward(tables) {
+		scope, err := r.tenantScope(ctx, table)
+		if err != nil {
+			return err
+		}
+
+		startTime := time.Now()
+		_, err = r.db.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s t WHERE %s`, pq.QuoteIdentifier(table), scope),
+			tenant.FromContext(ctx))
+		logger.LogDatabaseOperation(ctx, "DELETE", table, time.Since(startTime), err)
+		if err != nil {
+			logger.ErrorContext(ctx, "Error clearing table", err, map[string]interface{}{"table": table})
+			return errors.NewDatabaseError().WithCause(err)
+		}
+	}
+	return nil
+}
+
+func (r *postgresBackupRepo) Insert(ctx context.Context, table string, rows []json.RawMessage) error {
+	data, err := json.Marshal(rows)
+	if err != nil {
+		return err
+	}
+
+	scope, err := r.tenantScope(ctx, table)
+	if err != nil {
+		return err
+	}
+
+	// The record type of the table maps the JSON keys to its columns, in
+	// their order; rows out of the scope are left out, then refused
+	startTime := time.Now()
+	result, err := r.db.ExecContext(ctx, fmt.Sprintf(`INSERT INTO %[1]s SELECT * FROM json_populate_recordset(NULL::%[1]s, $2) t WHERE %[2]s`,
+		pq.QuoteIdentifier(table), scope), tenant.FromContext(ctx), string(data))
+	logger.LogDatabaseOperation(ctx, "INSERT", table, time.Since(startTime), err)
+	if err != nil {
+		logger.ErrorContext(ctx, "Error restoring rows", err, map[string]interface{}{"table": table})
+		return errors.NewDatabaseError().WithCause(err)
+	}
+	if inserted, err := result.RowsAffected(); err == nil && inserted != int64(len(rows)) {
+		return fmt.Errorf("%d rows of table %s are of another tenant", int64(len(rows))-inserted, table)
+	}
+	return nil
+}
+
+func (r *postgresBackupRepo) ResetSequences(ctx context.Context, tables []string) error {
+	startTime := time.Now()
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT table_name, column_name, pg_get_serial_sequence(quote_ident(table_name), column_name)
+		FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = ANY($1)
+			AND pg_get_serial_sequence(quote_ident(table_name), column_name) IS NOT NULL
+	`, pq.Array(tables))
+	logger.LogDatabaseOperation(ctx, "SELECT", "information_schema.columns", time.Since(startTime), err)
+	if err != nil {
+		logger.ErrorContext(ctx, "Error listing sequences", err)
+		return errors.NewDatabaseError().WithCause(err)
+	}
+
+	type serial struct{ table, column, sequence string }
+	var serials []serial
+	for rows.Next() {
+		var s serial
+		if err := rows.Scan(&s.table, &s.column, &s.sequence); err != nil {
+			rows.Close()
+			return errors.NewDatabaseError().WithCause(err)
+		}
+		serials = append(serials, s)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return errors.NewDatabaseError().WithCause(err)
+	}
+
+	for _, s := range serials {
+		startTime := time.Now()
+		_, err := r.db.ExecContext(ctx, fmt.Sprintf(`SELECT setval($1::regclass, COALESCE((SELECT MAX(%s) FROM %s), 0) + 1, false)`,
+			pq.QuoteIdentifier(s.column), pq.QuoteIdentifier(s.table)), s.sequence)
+		logger.LogDatabaseOperation(ctx, "SELECT", s.sequence, time.Since(startTime), err)
+		if err != nil {
+			logger.ErrorContext(ctx, "Error resetting sequence", err, map[string]interface{}{"sequence": s.sequence})
+			return errors.NewDatabaseError().WithCause(err)
+		}
+	}
+	return nil
+}
+
+func (r *postgresBackupRepo) queryStrings(ctx context.Context, query string, args ...any) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var values []string
+	for rows.Next() {
+		var v string
+		if err := rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, rows.Err()
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/clementhaon/sandbox-api-go/tenant"
+)
+
+func TestDependencyOrder(t *testing.T) {
+	tables := []string{"activities", "tasks", "tenants", "users"}
+	references := map[string][]string{
+		"activities": {"tasks", "users", "tenants"},
+		"tasks":      {"users", "tenants", "tasks", "columns"},
+		"users":      {"tenants"},
+	}
+	got := dependencyOrder(tables, references)
+	want := []string{"tenants", "users", "tasks", "activities"}
+	if !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	// Tables referencing each other are all kept
+	got = dependencyOrder([]string{"a", "b"}, map[string][]string{"a": {"b"}, "b": {"a"}})
+	if len(got) != 2 {
+		t.Errorf("expected both tables of a cycle, got %v", got)
+	}
+}
+
+func TestMemoryBackupRepository_TenantScope(t *testing.T) {
+	store := NewMemoryStore()
+	users := NewMemoryUserRepository(store)
+	notifications := NewMemoryNotificationRepository(store)
+	backups := NewMemoryBackupRepository(store)
+	ctxA := tenant.WithID(context.Background(), 1)
+	ctxB := tenant.WithID(context.Background(), 2)
+
+	alice, err := users.CreateAuth(ctxA, "alice", "alice@example.com", "hash-a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bob, err := users.CreateAuth(ctxB, "bob", "bob@example.com", "hash-b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []int{alice.ID, bob.ID} {
+		if err := notifications.Create(ctxA, id, "info", "Hello", "", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dump := func(ctx context.Context, table string) []string {
+		t.Helper()
+		var rows []string
+		if err := backups.Dump(ctx, table, func(row json.RawMessage) error {
+			rows = append(rows, string(row))
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		return rows
+	}
+
+	tables, err := backups.Tables(ctxA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if slices.Contains(tables, "tenants") {
+		t.Error("expected the tenants table left out of backups")
+	}
+	usersA, notificationsA := dump(ctxA, "users"), dump(ctxA, "notifications")
+	if len(usersA) != 1 || !strings.Contains(usersA[0], `"alice"`) || strings.Contains(strings.Join(usersA, ""), "bob") {
+		t.Errorf("expected only the users of tenant A, got %v", usersA)
+	}
+	if len(notificationsA) != 1 || !strings.Contains(notificationsA[0], fmt.Sprintf(`"user_id":%d`, alice.ID)) {
+		t.Errorf("expected only the notifications of the users of tenant A, got %v", notificationsA)
+	}
+
+	// Clearing and restoring tenant A leaves tenant B alone
+	if err := backups.Clear(ctxA, tables); err != nil {
+		t.Fatal(err)
+	}
+	if len(dump(ctxA, "users")) != 0 || len(dump(ctxB, "users")) != 1 || len(dump(ctxB, "notifications")) != 1 {
+		t.Error("expected only the rows of tenant A deleted")
+	}
+	if err := backups.Insert(ctxA, "users", []json.RawMessage{json.RawMessage(usersA[0])}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := backups.Insert(ctxA, "notifications", []json.RawMessage{json.RawMessage(notificationsA[0])}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(dump(ctxA, "users")) != 1 || len(dump(ctxA, "notifications")) != 1 {
+		t.Error("expected the rows of tenant A restored")
+	}
+
+	// Rows of another tenant are refused
+	if err := backups.Insert(ctxB, "users", []json.RawMessage{json.RawMessage(usersA[0])}); err == nil {
+		t.Error("expected a row of another tenant refused")
+	}
+}
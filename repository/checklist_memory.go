@@ -0,0 +1,178 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/database"
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/tenant"
+)
+
+// checklistItemRow is a row of checklist_items.
+type checklistItemRow struct {
+	ID        int       `json:"id"`
+	TaskID    int       `json:"task_id"`
+	TenantID  int       `json:"tenant_id"`
+	Title     string    `json:"title"`
+	Done      bool      `json:"done"`
+	Order     int       `json:"order"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (r checklistItemRow) model() models.ChecklistItem {
+	return models.ChecklistItem{
+		ID: r.ID, TaskID: r.TaskID, Title: r.Title, Done: r.Done, Order: r.Order,
+		CreatedAt: r.CreatedAt, UpdatedAt: r.UpdatedAt,
+	}
+}
+
+func compareChecklistItems(a, b checklistItemRow) int {
+	return cmp.Or(cmp.Compare(a.Order, b.Order), cmp.Compare(a.ID, b.ID))
+}
+
+// touchTask bumps the updated_at of a task whose checklist changed.
+func (d *memoryData) touchTask(taskID int) {
+	if t, ok := d.tasks.get(taskID); ok {
+		t.UpdatedAt = memoryNow()
+		d.tasks.put(t)
+		d.touchTaskList(t.TenantID)
+	}
+}
+
+// taskChecklistItem returns item id of a task of the tenant of ctx.
+func (d *memoryData) taskChecklistItem(ctx context.Context, taskID, id int) (checklistItemRow, bool) {
+	item, ok := d.checklistItems.get(id)
+	return item, ok && item.TaskID == taskID && item.TenantID == tenant.FromContext(ctx)
+}
+
+type memoryChecklistRepo struct {
+	memoryRepo
+}
+
+// NewMemoryChecklistRepository returns a ChecklistRepository keeping its
+// data in store.
+func NewMemoryChecklistRepository(store *MemoryStore) ChecklistRepository {
+	return &memoryChecklistRepo{memoryRepo{store: store}}
+}
+
+func (r *memoryChecklistRepo) WithQuerier(q database.Querier) ChecklistRepository {
+	return &memoryChecklistRepo{r.bind(q)}
+}
+
+func (r *memoryChecklistRepo) List(ctx context.Context, taskID int) ([]models.ChecklistItem, error) {
+	items := []models.ChecklistItem{}
+	err := r.read(ctx, "SELECT", "checklist_items", func(d *memoryData) error {
+		tenantID := tenant.FromContext(ctx)
+		rows := d.checklistItems.filter(func(i checklistItemRow) bool { return i.TaskID == taskID && i.TenantID == tenantID })
+		slices.SortFunc(rows, compareChecklistItems)
+		for _, i := range rows {
+			items = append(items, i.model())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (r *memoryChecklistRepo) ListByTasks(ctx context.Context, taskIDs []int) (map[int][]models.ChecklistItem, error) {
+	items := make(map[int][]models.ChecklistItem)
+	err := r.read(ctx, "SELECT", "checklist_items", func(d *memoryData) error {
+		tenantID := tenant.FromContext(ctx)
+		rows := d.checklistItems.filter(func(i checklistItemRow) bool {
+			return slices.Contains(taskIDs, i.TaskID) && i.TenantID == tenantID
+		})
+		slices.SortFunc(rows, compareChecklistItems)
+		for _, i := range rows {
+			items[i.TaskID] = append(items[i.TaskID], i.model())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (r *memoryChecklistRepo) Create(ctx context.Context, taskID int, title string) (models.ChecklistItem, error) {
+	var item checklistItemRow
+	err := r.write(ctx, "INSERT", "checklist_items", func(d *memoryData) error {
+		t, ok := d.tenantTask(ctx, taskID)
+		if !ok {
+			return errors.NewNotFoundError("Task not found")
+		}
+		order := -1
+		for _, i := range d.checklistItems.rows {
+			if i.TaskID == taskID {
+				order = max(order, i.Order)
+			}
+		}
+		now := memoryNow()
+		item = checklistItemRow{
+			ID: d.checklistItems.nextID(), TaskID: taskID, TenantID: t.TenantID, Title: title, Order: order + 1,
+			CreatedAt: now, UpdatedAt: now,
+		}
+		d.checklistItems.put(item)
+		d.touchTask(taskID)
+		return nil
+	})
+	return item.model(), err
+}
+
+func (r *memoryChecklistRepo) Update(ctx context.Context, taskID int, id int, req models.UpdateChecklistItemRequest) (models.ChecklistItem, error) {
+	var item checklistItemRow
+	err := r.write(ctx, "UPDATE", "checklist_items", func(d *memoryData) error {
+		var ok bool
+		if item, ok = d.taskChecklistItem(ctx, taskID, id); !ok {
+			return errors.NewNotFoundError("Checklist item not found")
+		}
+		if req.Title != nil {
+			item.Title = *req.Title
+		}
+		if req.Done != nil {
+			item.Done = *req.Done
+		}
+		item.UpdatedAt = memoryNow()
+		d.checklistItems.put(item)
+		d.touchTask(taskID)
+		return nil
+	})
+	return item.model(), err
+}
+
+func (r *memoryChecklistRepo) Delete(ctx context.Context, taskID int, id int) error {
+	return r.write(ctx, "DELETE", "checklist_items", func(d *memoryData) error {
+		if _, ok := d.taskChecklistItem(ctx, taskID, id); !ok {
+			return errors.NewNotFoundError("Checklist item not found")
+		}
+		d.checklistItems.delete(id)
+		d.touchTask(taskID)
+		return nil
+	})
+}
+
+func (r *memoryChecklistRepo) Reorder(ctx context.Context, taskID int, itemIDs []int) error {
+	return r.write(ctx, "UPDATE", "checklist_items", func(d *memoryData) error {
+		for _, id := range itemIDs {
+			if _, ok := d.taskChecklistItem(ctx, taskID, id); !ok {
+				return errors.NewNotFoundError("Checklist item not found")
+			}
+		}
+		now := memoryNow()
+		for i, id := range itemIDs {
+			item, _ := d.checklistItems.get(id)
+			item.Order, item.UpdatedAt = i, now
+			d.checklistItems.put(item)
+		}
+		if len(itemIDs) > 0 {
+			d.touchTask(taskID)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,229 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/database"
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/logger"
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/tenant"
+
+	"github.com/lib/pq"
+)
+
+// ChecklistRepository stores the checklist items of tasks. Every change also
+// bumps the task's updated_at, since task responses carry checklist progress.
+type ChecklistRepository interface {
+	List(ctx context.Context, taskID int) ([]models.ChecklistItem, error)
+	// ListByTasks returns the checklists of several tasks in one query, by
+	// task ID. Tasks without items are not in the map.
+	ListByTasks(ctx context.Context, taskIDs []int) (map[int][]models.ChecklistItem, error)
+	Create(ctx context.Context, taskID int, title string) (models.ChecklistItem, error)
+	Update(ctx context.Context, taskID int, id int, req models.UpdateChecklistItemRequest) (models.ChecklistItem, error)
+	Delete(ctx context.Context, taskID int, id int) error
+	// Reorder orders the given items of a task as listed. It returns a
+	// NotFound error if one of them is not on the task.
+	Reorder(ctx context.Context, taskID int, itemIDs []int) error
+	WithQuerier(q database.Querier) ChecklistRepository
+}
+
+type postgresChecklistRepo struct {
+	db database.Querier
+}
+
+func NewPostgresChecklistRepository(db *sql.DB) ChecklistRepository {
+	return &postgresChecklistRepo{db: db}
+}
+
+func (r *postgresChecklistRepo) WithQuerier(q database.Querier) ChecklistRepository {
+	return &postgresChecklistRepo{db: q}
+}
+
+const (
+	checklistID        column = "id"
+	checklistTaskID    column = "task_id"
+	checklistTitle     column = "title"
+	checklistDone      column = "done"
+	checklistOrder     column = `"order"`
+	checklistCreatedAt column = "created_at"
+	checklistUpdatedAt column = "updated_at"
+)
+
+func checklistItemFields(i *models.ChecklistItem) []field {
+	return []field{
+		{checklistID, &i.ID}, {checklistTaskID, &i.TaskID}, {checklistTitle, &i.Title}, {checklistDone, &i.Done},
+		{checklistOrder, &i.Order}, {checklistCreatedAt, &i.CreatedAt}, {checklistUpdatedAt, &i.UpdatedAt},
+	}
+}
+
+var checklistItemColumns = columnList("", checklistItemFields(new(models.ChecklistItem)))
+
+// touchTasks follows a CTE named changed that returns checklist items, and
+// bumps updated_at on their tasks.
+const touchTasks = `, touched AS (
+			UPDATE tasks SET updated_at = NOW() WHERE id IN (SELECT task_id FROM changed)
+		)`
+
+func (r *postgresChecklistRepo) List(ctx context.Context, taskID int) ([]models.ChecklistItem, error) {
+	startTime := time.Now()
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+checklistItemColumns+` FROM checklist_items WHERE task_id = $1 AND tenant_id = $2 ORDER BY "order", id`,
+		taskID, tenant.FromContext(ctx))
+	logger.LogDatabaseOperation(ctx, "SELECT", "checklist_items", time.Since(startTime), err)
+	if err != nil {
+		logger.ErrorContext(ctx, "Error querying checklist items", err)
+		return nil, errors.NewDatabaseError().WithCause(err)
+	}
+	defer rows.Close()
+
+	items := []models.ChecklistItem{}
+	for rows.Next() {
+		var item models.ChecklistItem
+		if err := rows.Scan(dests(checklistItemFields(&item))...); err != nil {
+			logger.ErrorContext(ctx, "Error scanning checklist item row", err)
+			return nil, errors.NewDatabaseError().WithCause(err)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func (r *postgresChecklistRepo) ListByTasks(ctx context.Context, taskIDs []int) (map[int][]models.ChecklistItem, error) {
+	startTime := time.Now()
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+checklistItemColumns+` FROM checklist_items WHERE task_id = ANY($1) AND tenant_id = $2 ORDER BY task_id, "order", id`,
+		pq.Array(taskIDs), tenant.FromContext(ctx))
+	logger.LogDatabaseOperation(ctx, "SELECT", "checklist_items", time.Since(startTime), err)
+	if err != nil {
+		logger.ErrorContext(ctx, "Error querying checklist items of tasks", err)
+		return nil, errors.NewDatabaseError().WithCause(err)
+	}
+	defer rows.Close()
+
+	items := map[int][]models.ChecklistItem{}
+	for rows.Next() {
+		var item models.ChecklistItem
+		if err := rows.Scan(dests(checklistItemFields(&item))...); err != nil {
+			logger.ErrorContext(ctx, "Error scanning checklist item row", err)
+			return nil, errors.NewDatabaseError().WithCause(err)
+		}
+		items[item.TaskID] = append(items[item.TaskID], item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.NewDatabaseError().WithCause(err)
+	}
+	return items, nil
+}
+
+// Create appends an item to the checklist. It returns a NotFound error when
+// the task does not exist.
+func (r *postgresChecklistRepo) Create(ctx context.Context, taskID int, title string) (models.ChecklistItem, error) {
+	var item models.ChecklistItem
+	startTime := time.Now()
+	err := r.db.QueryRowContext(ctx, `
+		WITH changed AS (
+			INSERT INTO checklist_items (task_id, tenant_id, title, "order")
+			SELECT t.id, t.tenant_id, $3,
+				(SELECT COALESCE(MAX("order"), -1) + 1 FROM checklist_items WHERE task_id = t.id)
+			FROM tasks t WHERE t.id = $1 AND t.tenant_id = $2
+			RETURNING `+checklistItemColumns+`
+		)`+touchTasks+`
+		SELECT `+checklistItemColumns+` FROM changed`,
+		taskID, tenant.FromContext(ctx), title,
+	).Scan(dests(checklistItemFields(&item))...)
+	logger.LogDatabaseOperation(ctx, "INSERT", "checklist_items", time.Since(startTime), err)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.ChecklistItem{}, errors.NewNotFoundError("Task not found")
+	}
+	if err != nil {
+		logger.ErrorContext(ctx, "Error creating checklist item", err)
+		return models.ChecklistItem{}, errors.NewDatabaseError().WithCause(err)
+	}
+	return item, nil
+}
+
+// Update renames, checks or unchecks an item; nil fields are left as they are.
+func (r *postgresChecklistRepo) Update(ctx context.Context, taskID int, id int, req models.UpdateChecklistItemRequest) (models.ChecklistItem, error) {
+	var item models.ChecklistItem
+	startTime := time.Now()
+	err := r.db.QueryRowContext(ctx, `
+		WITH changed AS (
+			UPDATE checklist_items SET
+				title = COALESCE($1, title),
+				done = COALESCE($2, done),
+				updated_at = NOW()
+			WHERE id = $3 AND task_id = $4 AND tenant_id = $5
+			RETURNING `+checklistItemColumns+`
+		)`+touchTasks+`
+		SELECT `+checklistItemColumns+` FROM changed`,
+		req.Title, req.Done, id, taskID, tenant.FromContext(ctx),
+	).Scan(dests(checklistItemFields(&item))...)
+	logger.LogDatabaseOperation(ctx, "UPDATE", "checklist_items", time.Since(startTime), err)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.ChecklistItem{}, errors.NewNotFoundError("Checklist item not found")
+	}
+	if err != nil {
+		logger.ErrorContext(ctx, "Error updating checklist item", err)
+		return models.ChecklistItem{}, errors.NewDatabaseError().WithCause(err)
+	}
+	return item, nil
+}
+
+func (r *postgresChecklistRepo) Delete(ctx context.Context, taskID int, id int) error {
+	startTime := time.Now()
+	result, err := r.db.ExecContext(ctx, `
+		WITH changed AS (
+			DELETE FROM checklist_items WHERE id = $1 AND task_id = $2 AND tenant_id = $3
+			RETURNING task_id
+		)`+touchTasks+`
+		SELECT 1 FROM changed`,
+		id, taskID, tenant.FromContext(ctx))
+	logger.LogDatabaseOperation(ctx, "DELETE", "checklist_items", time.Since(startTime), err)
+
+	if err != nil {
+		logger.ErrorContext(ctx, "Error deleting checklist item", err)
+		return errors.NewDatabaseError().WithCause(err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return errors.NewDatabaseError().WithCause(err)
+	}
+	if rowsAffected == 0 {
+		return errors.NewNotFoundError("Checklist item not found")
+	}
+	return nil
+}
+
+func (r *postgresChecklistRepo) Reorder(ctx context.Context, taskID int, itemIDs []int) error {
+	startTime := time.Now()
+	result, err := r.db.ExecContext(ctx, `
+		WITH changed AS (
+			UPDATE checklist_items SET "order" = v.ord - 1, updated_at = NOW()
+			FROM unnest($1::int[]) WITH ORDINALITY AS v(id, ord)
+			WHERE checklist_items.id = v.id AND task_id = $2 AND tenant_id = $3
+			RETURNING task_id
+		)`+touchTasks+`
+		SELECT 1 FROM changed`,
+		pq.Array(itemIDs), taskID, tenant.FromContext(ctx))
+	logger.LogDatabaseOperation(ctx, "UPDATE", "checklist_items", time.Since(startTime), err)
+
+	if err != nil {
+		logger.ErrorContext(ctx, "Error reordering checklist items", err)
+		return errors.NewDatabaseError().WithCause(err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return errors.NewDatabaseError().WithCause(err)
+	}
+	if int(rowsAffected) != len(itemIDs) {
+		return errors.NewNotFoundError("Checklist item not found")
+	}
+	return nil
+}
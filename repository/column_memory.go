@@ -0,0 +1,194 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/database"
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/tenant"
+)
+
+// columnRow is a row of columns.
+type columnRow struct {
+	ID        int       `json:"id"`
+	TenantID  int       `json:"tenant_id"`
+	Title     string    `json:"title"`
+	Order     int       `json:"order"`
+	Color     string    `json:"color"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (r columnRow) model() models.Column {
+	return models.Column{ID: r.ID, Title: r.Title, Order: r.Order, Color: r.Color, CreatedAt: r.CreatedAt, UpdatedAt: r.UpdatedAt}
+}
+
+// tenantColumns returns the columns of the tenant of ctx by order.
+func (d *memoryData) tenantColumns(ctx context.Context) []columnRow {
+	tenantID := tenant.FromContext(ctx)
+	rows := d.columns.filter(func(c columnRow) bool { return c.TenantID == tenantID })
+	slices.SortStableFunc(rows, func(a, b columnRow) int { return cmp.Compare(a.Order, b.Order) })
+	return rows
+}
+
+// tenantColumn returns column id, if it belongs to the tenant of ctx.
+func (d *memoryData) tenantColumn(ctx context.Context, id int) (columnRow, bool) {
+	c, ok := d.columns.get(id)
+	return c, ok && c.TenantID == tenant.FromContext(ctx)
+}
+
+type memoryColumnRepo struct {
+	memoryRepo
+}
+
+// NewMemoryColumnRepository returns a ColumnRepository keeping its data in
+// store.
+func NewMemoryColumnRepository(store *MemoryStore) ColumnRepository {
+	return &memoryColumnRepo{memoryRepo{store: store}}
+}
+
+func (r *memoryColumnRepo) WithQuerier(q database.Querier) ColumnRepository {
+	return &memoryColumnRepo{r.bind(q)}
+}
+
+func (r *memoryColumnRepo) List(ctx context.Context) ([]models.Column, error) {
+	columns := []models.Column{}
+	err := r.read(ctx, "SELECT", "columns", func(d *memoryData) error {
+		for _, c := range d.tenantColumns(ctx) {
+			columns = append(columns, c.model())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return columns, nil
+}
+
+func (r *memoryColumnRepo) GetByID(ctx context.Context, id int) (models.Column, error) {
+	var c columnRow
+	err := r.read(ctx, "SELECT", "columns", func(d *memoryData) error {
+		var ok bool
+		if c, ok = d.tenantColumn(ctx, id); !ok {
+			return errors.NewNotFoundError("Column not found")
+		}
+		return nil
+	})
+	return c.model(), err
+}
+
+func (r *memoryColumnRepo) GetMaxOrder(ctx context.Context) (int, error) {
+	maxOrder := -1
+	err := r.read(ctx, "SELECT MAX", "columns", func(d *memoryData) error {
+		for _, c := range d.tenantColumns(ctx) {
+			maxOrder = max(maxOrder, c.Order)
+		}
+		return nil
+	})
+	return maxOrder, err
+}
+
+func (r *memoryColumnRepo) Create(ctx context.Context, title, color string, order int) (models.Column, error) {
+	var c columnRow
+	err := r.write(ctx, "INSERT", "columns", func(d *memoryData) error {
+		now := memoryNow()
+		c = columnRow{
+			ID: d.columns.nextID(), TenantID: tenant.FromContext(ctx), Title: title, Order: order, Color: color,
+			CreatedAt: now, UpdatedAt: now,
+		}
+		d.columns.put(c)
+		return nil
+	})
+	return c.model(), err
+}
+
+func (r *memoryColumnRepo) Update(ctx context.Context, id int, title, color string) (models.Column, error) {
+	var c columnRow
+	err := r.write(ctx, "UPDATE", "columns", func(d *memoryData) error {
+		var ok bool
+		if c, ok = d.tenantColumn(ctx, id); !ok {
+			return errors.NewNotFoundError("Column not found")
+		}
+		c.Title, c.Color, c.UpdatedAt = title, color, memoryNow()
+		d.columns.put(c)
+		return nil
+	})
+	return c.model(), err
+}
+
+func (r *memoryColumnRepo) GetFirstOtherColumn(ctx context.Context, excludeID int) (int, error) {
+	var id int
+	err := r.read(ctx, "SELECT", "columns", func(d *memoryData) error {
+		for _, c := range d.tenantColumns(ctx) {
+			if c.ID != excludeID {
+				id = c.ID
+				return nil
+			}
+		}
+		return errors.NewBadRequestError("Cannot delete the last column")
+	})
+	return id, err
+}
+
+func (r *memoryColumnRepo) MoveTasksToColumn(ctx context.Context, fromColumnID, toColumnID int) error {
+	return r.write(ctx, "UPDATE", "tasks", func(d *memoryData) error {
+		tenantID := tenant.FromContext(ctx)
+		tasks := d.tasks.filter(func(t taskRow) bool { return t.ColumnID == fromColumnID && t.TenantID == tenantID })
+		if _, ok := d.tenantColumn(ctx, toColumnID); !ok && len(tasks) > 0 {
+			return constraintError("insert or update on table tasks violates foreign key constraint tasks_column_id_fkey")
+		}
+		now := memoryNow()
+		for _, t := range tasks {
+			t.ColumnID, t.UpdatedAt = toColumnID, now
+			d.tasks.put(t)
+			d.touchTaskList(t.TenantID)
+		}
+		return nil
+	})
+}
+
+func (r *memoryColumnRepo) Delete(ctx context.Context, id int) error {
+	return r.write(ctx, "DELETE", "columns", func(d *memoryData) error {
+		if _, ok := d.tenantColumn(ctx, id); !ok {
+			return errors.NewNotFoundError("Column not found")
+		}
+		for _, t := range d.tasks.rows {
+			if t.ColumnID == id {
+				return constraintError("update or delete on table columns violates foreign key constraint tasks_column_id_fkey")
+			}
+		}
+		d.columns.delete(id)
+		return nil
+	})
+}
+
+func (r *memoryColumnRepo) ReorderAfterDelete(ctx context.Context) error {
+	return r.write(ctx, "UPDATE", "columns", func(d *memoryData) error {
+		for i, c := range d.tenantColumns(ctx) {
+			c.Order = i
+			d.columns.put(c)
+		}
+		return nil
+	})
+}
+
+func (r *memoryColumnRepo) Reorder(ctx context.Context, columnIDs []int) error {
+	return r.write(ctx, "UPDATE", "columns", func(d *memoryData) error {
+		// Postgres applies the orders up to the first unknown column
+		now := memoryNow()
+		for i, columnID := range columnIDs {
+			c, ok := d.tenantColumn(ctx, columnID)
+			if !ok {
+				return errors.NewNotFoundError("Column not found: " + strconv.Itoa(columnID))
+			}
+			c.Order, c.UpdatedAt = i, now
+			d.columns.put(c)
+		}
+		return nil
+	})
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/clementhaon/sandbox-api-go/errors"
 	"github.com/clementhaon/sandbox-api-go/logger"
 	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/tenant"
 )
 
 type ColumnRepository interface {
@@ -46,7 +47,7 @@ func scanColumn(row interface{ Scan(...any) error }) (models.Column, error) {
 
 func (r *postgresColumnRepo) List(ctx context.Context) ([]models.Column, error) {
 	startTime := time.Now()
-	rows, err := r.db.QueryContext(ctx, `SELECT id, title, "order", color, created_at, updated_at FROM columns ORDER BY "order" ASC`)
+	rows, err := r.db.QueryContext(ctx, `SELECT id, title, "order", color, created_at, updated_at FROM columns WHERE tenant_id = $1 ORDER BY "order" ASC`, tenant.FromContext(ctx))
 	logger.LogDatabaseOperation(ctx, "SELECT", "columns", time.Since(startTime), err)
 
 	if err != nil {
@@ -70,10 +71,10 @@ func (r *postgresColumnRepo) List(ctx context.Context) ([]models.Column, error)
 func (r *postgresColumnRepo) GetByID(ctx context.Context, id int) (models.Column, error) {
 	startTime := time.Now()
 	c, err := scanColumn(r.db.QueryRowContext(ctx,
-		`SELECT id, title, "order", color, created_at, updated_at FROM columns WHERE id = $1`, id))
+		`SELECT id, title, "order", color, created_at, updated_at FROM columns WHERE id = $1 AND tenant_id = $2`, id, tenant.FromContext(ctx)))
 	logger.LogDatabaseOperation(ctx, "SELECT", "columns", time.Since(startTime), err)
 
-	if err == sql.ErrNoRows {
+	if errors.Is(err, sql.ErrNoRows) {
 		return models.Column{}, errors.NewNotFoundError("Column not found")
 	}
 	if err != nil {
@@ -86,7 +87,7 @@ func (r *postgresColumnRepo) GetByID(ctx context.Context, id int) (models.Column
 func (r *postgresColumnRepo) GetMaxOrder(ctx context.Context) (int, error) {
 	var maxOrder int
 	startTime := time.Now()
-	err := r.db.QueryRowContext(ctx, `SELECT COALESCE(MAX("order"), -1) FROM columns`).Scan(&maxOrder)
+	err := r.db.QueryRowContext(ctx, `SELECT COALESCE(MAX("order"), -1) FROM columns WHERE tenant_id = $1`, tenant.FromContext(ctx)).Scan(&maxOrder)
 	logger.LogDatabaseOperation(ctx, "SELECT MAX", "columns", time.Since(startTime), err)
 	if err != nil {
 		logger.ErrorContext(ctx, "Error getting max order", err)
@@ -98,9 +99,9 @@ func (r *postgresColumnRepo) GetMaxOrder(ctx context.Context) (int, error) {
 func (r *postgresColumnRepo) Create(ctx context.Context, title, color string, order int) (models.Column, error) {
 	startTime := time.Now()
 	c, err := scanColumn(r.db.QueryRowContext(ctx,
-		`INSERT INTO columns (title, "order", color) VALUES ($1, $2, $3)
+		`INSERT INTO columns (title, "order", color, tenant_id) VALUES ($1, $2, $3, $4)
 		RETURNING id, title, "order", color, created_at, updated_at`,
-		title, order, color,
+		title, order, color, tenant.FromContext(ctx),
 	))
 	logger.LogDatabaseOperation(ctx, "INSERT", "columns", time.Since(startTime), err)
 
@@ -114,9 +115,9 @@ func (r *postgresColumnRepo) Create(ctx context.Context, title, color string, or
 func (r *postgresColumnRepo) Update(ctx context.Context, id int, title, color string) (models.Column, error) {
 	startTime := time.Now()
 	c, err := scanColumn(r.db.QueryRowContext(ctx,
-		`UPDATE columns SET title = $1, color = $2, updated_at = NOW() WHERE id = $3
+		`UPDATE columns SET title = $1, color = $2, updated_at = NOW() WHERE id = $3 AND tenant_id = $4
 		RETURNING id, title, "order", color, created_at, updated_at`,
-		title, color, id,
+		title, color, id, tenant.FromContext(ctx),
 	))
 	logger.LogDatabaseOperation(ctx, "UPDATE", "columns", time.Since(startTime), err)
 
@@ -130,10 +131,10 @@ func (r *postgresColumnRepo) Update(ctx context.Context, id int, title, color st
 func (r *postgresColumnRepo) GetFirstOtherColumn(ctx context.Context, excludeID int) (int, error) {
 	var id int
 	startTime := time.Now()
-	err := r.db.QueryRowContext(ctx, `SELECT id FROM columns WHERE id != $1 ORDER BY "order" ASC LIMIT 1`, excludeID).Scan(&id)
+	err := r.db.QueryRowContext(ctx, `SELECT id FROM columns WHERE id != $1 AND tenant_id = $2 ORDER BY "order" ASC LIMIT 1`, excludeID, tenant.FromContext(ctx)).Scan(&id)
 	logger.LogDatabaseOperation(ctx, "SELECT", "columns", time.Since(startTime), err)
 
-	if err == sql.ErrNoRows {
+	if errors.Is(err, sql.ErrNoRows) {
 		return 0, errors.NewBadRequestError("Cannot delete the last column")
 	}
 	if err != nil {
@@ -145,7 +146,7 @@ func (r *postgresColumnRepo) GetFirstOtherColumn(ctx context.Context, excludeID
 
 func (r *postgresColumnRepo) MoveTasksToColumn(ctx context.Context, fromColumnID, toColumnID int) error {
 	startTime := time.Now()
-	_, err := r.db.ExecContext(ctx, `UPDATE tasks SET column_id = $1, updated_at = NOW() WHERE column_id = $2`, toColumnID, fromColumnID)
+	_, err := r.db.ExecContext(ctx, `UPDATE tasks SET column_id = $1, updated_at = NOW() WHERE column_id = $2 AND tenant_id = $3`, toColumnID, fromColumnID, tenant.FromContext(ctx))
 	logger.LogDatabaseOperation(ctx, "UPDATE", "tasks", time.Since(startTime), err)
 	if err != nil {
 		logger.ErrorContext(ctx, "Error moving tasks", err)
@@ -156,7 +157,7 @@ func (r *postgresColumnRepo) MoveTasksToColumn(ctx context.Context, fromColumnID
 
 func (r *postgresColumnRepo) Delete(ctx context.Context, id int) error {
 	startTime := time.Now()
-	result, err := r.db.ExecContext(ctx, `DELETE FROM columns WHERE id = $1`, id)
+	result, err := r.db.ExecContext(ctx, `DELETE FROM columns WHERE id = $1 AND tenant_id = $2`, id, tenant.FromContext(ctx))
 	logger.LogDatabaseOperation(ctx, "DELETE", "columns", time.Since(startTime), err)
 	if err != nil {
 		logger.ErrorContext(ctx, "Error deleting column", err)
@@ -178,11 +179,11 @@ func (r *postgresColumnRepo) ReorderAfterDelete(ctx context.Context) error {
 	_, err := r.db.ExecContext(ctx, `
 		WITH ordered AS (
 			SELECT id, ROW_NUMBER() OVER (ORDER BY "order") - 1 as new_order
-			FROM columns
+			FROM columns WHERE tenant_id = $1
 		)
 		UPDATE columns SET "order" = ordered.new_order
 		FROM ordered WHERE columns.id = ordered.id
-	`)
+	`, tenant.FromContext(ctx))
 	logger.LogDatabaseOperation(ctx, "UPDATE", "columns", time.Since(startTime), err)
 	if err != nil {
 		logger.WarnContext(ctx, "Error reordering columns after delete", map[string]interface{}{
@@ -195,7 +196,7 @@ func (r *postgresColumnRepo) ReorderAfterDelete(ctx context.Context) error {
 func (r *postgresColumnRepo) Reorder(ctx context.Context, columnIDs []int) error {
 	for i, columnID := range columnIDs {
 		startTime := time.Now()
-		result, err := r.db.ExecContext(ctx, `UPDATE columns SET "order" = $1, updated_at = NOW() WHERE id = $2`, i, columnID)
+		result, err := r.db.ExecContext(ctx, `UPDATE columns SET "order" = $1, updated_at = NOW() WHERE id = $2 AND tenant_id = $3`, i, columnID, tenant.FromContext(ctx))
 		logger.LogDatabaseOperation(ctx, "UPDATE", "columns", time.Since(startTime), err)
 
 		if err != nil {
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/database"
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/models"
+)
+
+// emailChangeRow is a row of email_changes.
+type emailChangeRow struct {
+	UserID    int       `json:"user_id"`
+	NewEmail  string    `json:"new_email"`
+	TokenHash string    `json:"token_hash"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type memoryEmailChangeRepo struct {
+	memoryRepo
+}
+
+// NewMemoryEmailChangeRepository returns an EmailChangeRepository keeping
+// its data in store.
+func NewMemoryEmailChangeRepository(store *MemoryStore) EmailChangeRepository {
+	return &memoryEmailChangeRepo{memoryRepo{store: store}}
+}
+
+func (r *memoryEmailChangeRepo) WithQuerier(q database.Querier) EmailChangeRepository {
+	return &memoryEmailChangeRepo{r.bind(q)}
+}
+
+func (r *memoryEmailChangeRepo) Upsert(ctx context.Context, change models.EmailChange) error {
+	return r.write(ctx, "UPSERT", "email_changes", func(d *memoryData) error {
+		if _, ok := d.users.get(change.UserID); !ok {
+			return constraintError("insert or update on table email_changes violates foreign key constraint email_changes_user_id_fkey")
+		}
+		for _, c := range d.emailChanges.rows {
+			if c.TokenHash == change.TokenHash && c.UserID != change.UserID {
+				return constraintError("duplicate key value violates unique constraint email_changes_token_hash_key")
+			}
+		}
+		d.emailChanges.put(emailChangeRow{
+			UserID: change.UserID, NewEmail: change.NewEmail, TokenHash: change.TokenHash,
+			ExpiresAt: change.ExpiresAt.Truncate(time.Microsecond), CreatedAt: memoryNow(),
+		})
+		return nil
+	})
+}
+
+func (r *memoryEmailChangeRepo) GetByTokenHash(ctx context.Context, tokenHash string) (models.EmailChange, error) {
+	var change models.EmailChange
+	err := r.read(ctx, "SELECT", "email_changes", func(d *memoryData) error {
+		for _, c := range d.emailChanges.rows {
+			if c.TokenHash == tokenHash {
+				change = models.EmailChange(c)
+				return nil
+			}
+		}
+		return errors.NewNotFoundError("Email change")
+	})
+	return change, err
+}
+
+func (r *memoryEmailChangeRepo) Delete(ctx context.Context, userID int) error {
+	return r.write(ctx, "DELETE", "email_changes", func(d *memoryData) error {
+		d.emailChanges.delete(userID)
+		return nil
+	})
+}
@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/database"
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/logger"
+	"github.com/clementhaon/sandbox-api-go/models"
+)
+
+type EmailChangeRepository interface {
+	// Upsert stores the pending change for a user, replacing any previous one
+	Upsert(ctx context.Context, change models.EmailChange) error
+	GetByTokenHash(ctx context.Context, tokenHash string) (models.EmailChange, error)
+	Delete(ctx context.Context, userID int) error
+	WithQuerier(q database.Querier) EmailChangeRepository
+}
+
+type postgresEmailChangeRepo struct {
+	db database.Querier
+}
+
+func NewPostgresEmailChangeRepository(db *sql.DB) EmailChangeRepository {
+	return &postgresEmailChangeRepo{db: db}
+}
+
+func (r *postgresEmailChangeRepo) WithQuerier(q database.Querier) EmailChangeRepository {
+	return &postgresEmailChangeRepo{db: q}
+}
+
+func (r *postgresEmailChangeRepo) Upsert(ctx context.Context, change models.EmailChange) error {
+	startTime := time.Now()
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO email_changes (user_id, new_email, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id) DO UPDATE
+		SET new_email = EXCLUDED.new_email, token_hash = EXCLUDED.token_hash,
+			expires_at = EXCLUDED.expires_at, created_at = CURRENT_TIMESTAMP
+	`, change.UserID, change.NewEmail, change.TokenHash, change.ExpiresAt)
+	logger.LogDatabaseOperation(ctx, "INSERT", "email_changes", time.Since(startTime), err)
+
+	if err != nil {
+		logger.ErrorContext(ctx, "Error storing email change", err)
+		return errors.NewDatabaseError().WithCause(err)
+	}
+	return nil
+}
+
+func (r *postgresEmailChangeRepo) GetByTokenHash(ctx context.Context, tokenHash string) (models.EmailChange, error) {
+	var change models.EmailChange
+	startTime := time.Now()
+	err := r.db.QueryRowContext(ctx, `
+		SELECT user_id, new_email, token_hash, expires_at, created_at
+		FROM email_changes WHERE token_hash = $1
+	`, tokenHash).Scan(&change.UserID, &change.NewEmail, &change.TokenHash, &change.ExpiresAt, &change.CreatedAt)
+	logger.LogDatabaseOperation(ctx, "SELECT", "email_changes", time.Since(startTime), err)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.EmailChange{}, errors.NewNotFoundError("Email change")
+	}
+	if err != nil {
+		logger.ErrorContext(ctx, "Error fetching email change", err)
+		return models.EmailChange{}, errors.NewDatabaseError().WithCause(err)
+	}
+	return change, nil
+}
+
+func (r *postgresEmailChangeRepo) Delete(ctx context.Context, userID int) error {
+	startTime := time.Now()
+	_, err := r.db.ExecContext(ctx, `DELETE FROM email_changes WHERE user_id = $1`, userID)
+	logger.LogDatabaseOperation(ctx, "DELETE", "email_changes", time.Since(startTime), err)
+
+	if err != nil {
+		logger.ErrorContext(ctx, "Error deleting email change", err)
+		return errors.NewDatabaseError().WithCause(err)
+	}
+	return nil
+}
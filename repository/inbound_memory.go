@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/database"
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/models"
+)
+
+// inboundHookRow is a row of inbound_hooks.
+type inboundHookRow struct {
+	UserID     int             `json:"user_id"`
+	SecretHash string          `json:"secret_hash"`
+	Mapping    json.RawMessage `json:"mapping"`
+	CreatedAt  time.Time       `json:"created_at"`
+	UpdatedAt  time.Time       `json:"updated_at"`
+}
+
+func (r inboundHookRow) model() (models.InboundHook, error) {
+	h := models.InboundHook{UserID: r.UserID, SecretHash: r.SecretHash, CreatedAt: r.CreatedAt, UpdatedAt: r.UpdatedAt}
+	if err := json.Unmarshal(r.Mapping, &h.Mapping); err != nil {
+		return models.InboundHook{}, errors.NewDatabaseError().WithCause(err)
+	}
+	return h, nil
+}
+
+type memoryInboundHookRepo struct {
+	memoryRepo
+}
+
+// NewMemoryInboundHookRepository returns an InboundHookRepository keeping
+// its data in store.
+func NewMemoryInboundHookRepository(store *MemoryStore) InboundHookRepository {
+	return &memoryInboundHookRepo{memoryRepo{store: store}}
+}
+
+func (r *memoryInboundHookRepo) WithQuerier(q database.Querier) InboundHookRepository {
+	return &memoryInboundHookRepo{r.bind(q)}
+}
+
+func (r *memoryInboundHookRepo) Get(ctx context.Context, userID int) (models.InboundHook, error) {
+	var h models.InboundHook
+	err := r.read(ctx, "SELECT", "inbound_hooks", func(d *memoryData) error {
+		row, ok := d.inboundHooks.get(userID)
+		if !ok {
+			return errors.NewNotFoundError("Inbound webhook")
+		}
+		var err error
+		h, err = row.model()
+		return err
+	})
+	return h, err
+}
+
+func (r *memoryInboundHookRepo) GetBySecretHash(ctx context.Context, secretHash string) (models.InboundHook, error) {
+	var h models.InboundHook
+	err := r.read(ctx, "SELECT", "inbound_hooks", func(d *memoryData) error {
+		for _, row := range d.inboundHooks.rows {
+			if u, ok := d.users.get(row.UserID); ok && row.SecretHash == secretHash && u.IsActive {
+				var err error
+				h, err = row.model()
+				h.TenantID = u.TenantID
+				return err
+			}
+		}
+		return errors.NewNotFoundError("Inbound webhook")
+	})
+	return h, err
+}
+
+func (r *memoryInboundHookRepo) SetSecret(ctx context.Context, userID int, secretHash string) (models.InboundHook, error) {
+	var h models.InboundHook
+	err := r.write(ctx, "UPSERT", "inbound_hooks", func(d *memoryData) error {
+		if _, ok := d.users.get(userID); !ok {
+			return constraintError("insert or update on table inbound_hooks violates foreign key constraint inbound_hooks_user_id_fkey")
+		}
+		for _, row := range d.inboundHooks.rows {
+			if row.SecretHash == secretHash && row.UserID != userID {
+				return constraintError("duplicate key value violates unique constraint inbound_hooks_secret_hash_key")
+			}
+		}
+		now := memoryNow()
+		row, ok := d.inboundHooks.get(userID)
+		if !ok {
+			row = inboundHookRow{UserID: userID, Mapping: json.RawMessage("{}"), CreatedAt: now}
+		}
+		row.SecretHash, row.UpdatedAt = secretHash, now
+		d.inboundHooks.put(row)
+
+		var err error
+		h, err = row.model()
+		return err
+	})
+	return h, err
+}
+
+func (r *memoryInboundHookRepo) UpdateMapping(ctx context.Context, userID int, mapping models.InboundMapping) (models.InboundHook, error) {
+	mappingJSON, err := json.Marshal(mapping)
+	if err != nil {
+		return models.InboundHook{}, errors.NewInternalError().WithCause(err)
+	}
+
+	var h models.InboundHook
+	err = r.write(ctx, "UPDATE", "inbound_hooks", func(d *memoryData) error {
+		row, ok := d.inboundHooks.get(userID)
+		if !ok {
+			return errors.NewNotFoundError("Inbound webhook")
+		}
+		row.Mapping, row.UpdatedAt = slices.Clone(mappingJSON), memoryNow()
+		d.inboundHooks.put(row)
+
+		var err error
+		h, err = row.model()
+		return err
+	})
+	return h, err
+}
+
+func (r *memoryInboundHookRepo) Delete(ctx context.Context, userID int) error {
+	return r.write(ctx, "DELETE", "inbound_hooks", func(d *memoryData) error {
+		if _, ok := d.inboundHooks.get(userID); !ok {
+			return errors.NewNotFoundError("Inbound webhook")
+		}
+		d.inboundHooks.delete(userID)
+		return nil
+	})
+}
@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/database"
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/logger"
+	"github.com/clementhaon/sandbox-api-go/models"
+)
+
+type InboundHookRepository interface {
+	// Get returns the webhook of a user, or a NotFound error
+	Get(ctx context.Context, userID int) (models.InboundHook, error)
+	// GetBySecretHash returns the webhook holding the secret, with the
+	// tenant of its user, in any tenant. Webhooks of inactive users are
+	// not found.
+	GetBySecretHash(ctx context.Context, secretHash string) (models.InboundHook, error)
+	// SetSecret creates the webhook of a user or replaces its secret,
+	// keeping its mapping
+	SetSecret(ctx context.Context, userID int, secretHash string) (models.InboundHook, error)
+	// UpdateMapping returns a NotFound error if the user has no webhook
+	UpdateMapping(ctx context.Context, userID int, mapping models.InboundMapping) (models.InboundHook, error)
+	Delete(ctx context.Context, userID int) error
+	WithQuerier(q database.Querier) InboundHookRepository
+}
+
+type postgresInboundHookRepo struct {
+	db database.Querier
+}
+
+func NewPostgresInboundHookRepository(db *sql.DB) InboundHookRepository {
+	return &postgresInboundHookRepo{db: db}
+}
+
+func (r *postgresInboundHookRepo) WithQuerier(q database.Querier) InboundHookRepository {
+	return &postgresInboundHookRepo{db: q}
+}
+
+const inboundHookColumns = `user_id, secret_hash, mapping, created_at, updated_at`
+
+func scanInboundHook(row interface{ Scan(...any) error }, extra ...any) (models.InboundHook, error) {
+	var h models.InboundHook
+	var mapping []byte
+	dest := append([]any{&h.UserID, &h.SecretHash, &mapping, &h.CreatedAt, &h.UpdatedAt}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return models.InboundHook{}, err
+	}
+	if err := json.Unmarshal(mapping, &h.Mapping); err != nil {
+		return models.InboundHook{}, err
+	}
+	return h, nil
+}
+
+func (r *postgresInboundHookRepo) Get(ctx context.Context, userID int) (models.InboundHook, error) {
+	startTime := time.Now()
+	h, err := scanInboundHook(r.db.QueryRowContext(ctx,
+		`SELECT `+inboundHookColumns+` FROM inbound_hooks WHERE user_id = $1`, userID))
+	logger.LogDatabaseOperation(ctx, "SELECT", "inbound_hooks", time.Since(startTime), err)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.InboundHook{}, errors.NewNotFoundError("Inbound webhook")
+	}
+	if err != nil {
+		logger.ErrorContext(ctx, "Error fetching inbound webhook", err)
+		return models.InboundHook{}, errors.NewDatabaseError().WithCause(err)
+	}
+	return h, nil
+}
+
+func (r *postgresInboundHookRepo) GetBySecretHash(ctx context.Context, secretHash string) (models.InboundHook, error) {
+	var tenantID int
+	startTime := time.Now()
+	h, err := scanInboundHook(r.db.QueryRowContext(ctx, `
+		SELECT h.user_id, h.secret_hash, h.mapping, h.created_at, h.updated_at, u.tenant_id
+		FROM inbound_hooks h JOIN users u ON u.id = h.user_id
+		WHERE h.secret_hash = $1 AND u.is_active = true
+	`, secretHash), &tenantID)
+	logger.LogDatabaseOperation(ctx, "SELECT", "inbound_hooks", time.Since(startTime), err)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.InboundHook{}, errors.NewNotFoundError("Inbound webhook")
+	}
+	if err != nil {
+		logger.ErrorContext(ctx, "Error fetching inbound webhook by secret", err)
+		return models.InboundHook{}, errors.NewDatabaseError().WithCause(err)
+	}
+	h.TenantID = tenantID
+	return h, nil
+}
+
+func (r *postgresInboundHookRepo) SetSecret(ctx context.Context, userID int, secretHash string) (models.InboundHook, error) {
+	startTime := time.Now()
+	h, err := scanInboundHook(r.db.QueryRowContext(ctx, `
+		INSERT INTO inbound_hooks (user_id, secret_hash)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret_hash = EXCLUDED.secret_hash, updated_at = CURRENT_TIMESTAMP
+		RETURNING `+inboundHookColumns,
+		userID, secretHash))
+	logger.LogDatabaseOperation(ctx, "UPSERT", "inbound_hooks", time.Since(startTime), err)
+
+	if err != nil {
+		logger.ErrorContext(ctx, "Error storing inbound webhook secret", err)
+		return models.InboundHook{}, errors.NewDatabaseError().WithCause(err)
+	}
+	return h, nil
+}
+
+func (r *postgresInboundHookRepo) UpdateMapping(ctx context.Context, userID int, mapping models.InboundMapping) (models.InboundHook, error) {
+	mappingJSON, err := json.Marshal(mapping)
+	if err != nil {
+		return models.InboundHook{}, errors.NewInternalError().WithCause(err)
+	}
+
+	startTime := time.Now()
+	h, err := scanInboundHook(r.db.QueryRowContext(ctx, `
+		UPDATE inbound_hooks SET mapping = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE user_id = $2
+		RETURNING `+inboundHookColumns,
+		mappingJSON, userID))
+	logger.LogDatabaseOperation(ctx, "UPDATE", "inbound_hooks", time.Since(startTime), err)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.InboundHook{}, errors.NewNotFoundError("Inbound webhook")
+	}
+	if err != nil {
+		logger.ErrorContext(ctx, "Error updating inbound webhook mapping", err)
+		return models.InboundHook{}, errors.NewDatabaseError().WithCause(err)
+	}
+	return h, nil
+}
+
+func (r *postgresInboundHookRepo) Delete(ctx context.Context, userID int) error {
+	startTime := time.Now()
+	result, err := r.db.ExecContext(ctx, `DELETE FROM inbound_hooks WHERE user_id = $1`, userID)
+	logger.LogDatabaseOperation(ctx, "DELETE", "inbound_hooks", time.Since(startTime), err)
+
+	if err != nil {
+		logger.ErrorContext(ctx, "Error deleting inbound webhook", err)
+		return errors.NewDatabaseError().WithCause(err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return errors.NewDatabaseError().WithCause(err)
+	}
+	if rowsAffected == 0 {
+		return errors.NewNotFoundError("Inbound webhook")
+	}
+	return nil
+}
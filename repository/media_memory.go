@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/database"
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/models"
+)
+
+// mediaRow is a row of media.
+type mediaRow struct {
+	ID               int       `json:"id"`
+	UserID           int       `json:"user_id"`
+	ObjectKey        string    `json:"object_key"`
+	BucketName       string    `json:"bucket_name"`
+	OriginalFilename string    `json:"original_filename"`
+	FileSize         int64     `json:"file_size"`
+	MimeType         string    `json:"mime_type"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+func (r mediaRow) model() models.Media {
+	return models.Media{
+		ID: r.ID, UserID: r.UserID, ObjectKey: r.ObjectKey, BucketName: r.BucketName,
+		OriginalFilename: r.OriginalFilename, FileSize: r.FileSize, MimeType: r.MimeType,
+		CreatedAt: r.CreatedAt, UpdatedAt: r.UpdatedAt,
+	}
+}
+
+// userMedia returns media id, if it belongs to userID.
+func (d *memoryData) userMedia(userID, id int) (mediaRow, bool) {
+	m, ok := d.media.get(id)
+	return m, ok && m.UserID == userID
+}
+
+type memoryMediaRepo struct {
+	memoryRepo
+}
+
+// NewMemoryMediaRepository returns a MediaRepository keeping its data in
+// store.
+func NewMemoryMediaRepository(store *MemoryStore) MediaRepository {
+	return &memoryMediaRepo{memoryRepo{store: store}}
+}
+
+func (r *memoryMediaRepo) WithQuerier(q database.Querier) MediaRepository {
+	return &memoryMediaRepo{r.bind(q)}
+}
+
+func (r *memoryMediaRepo) Create(ctx context.Context, userID int, objectKey, bucketName, originalFilename, mimeType string, fileSize int64) (models.Media, error) {
+	var m mediaRow
+	err := r.write(ctx, "INSERT", "media", func(d *memoryData) error {
+		if _, ok := d.users.get(userID); !ok {
+			return errors.NewInternalServerError("Failed to save media record")
+		}
+		now := memoryNow()
+		m = mediaRow{
+			ID: d.media.nextID(), UserID: userID, ObjectKey: objectKey, BucketName: bucketName,
+			OriginalFilename: originalFilename, FileSize: fileSize, MimeType: mimeType, CreatedAt: now, UpdatedAt: now,
+		}
+		d.media.put(m)
+		return nil
+	})
+	if err != nil {
+		return models.Media{}, err
+	}
+	return m.model(), nil
+}
+
+func (r *memoryMediaRepo) Count(ctx context.Context, userID int) (int, error) {
+	var count int
+	err := r.read(ctx, "SELECT COUNT", "media", func(d *memoryData) error {
+		for _, m := range d.media.rows {
+			if m.UserID == userID {
+				count++
+			}
+		}
+		return nil
+	})
+	return count, err
+}
+
+func (r *memoryMediaRepo) TotalSize(ctx context.Context, userID int) (int64, error) {
+	var total int64
+	err := r.read(ctx, "SELECT", "media", func(d *memoryData) error {
+		for _, m := range d.media.rows {
+			if m.UserID == userID {
+				total += m.FileSize
+			}
+		}
+		return nil
+	})
+	return total, err
+}
+
+func (r *memoryMediaRepo) List(ctx context.Context, userID int, limit, offset int) ([]models.Media, error) {
+	var mediaList []models.Media
+	err := r.read(ctx, "SELECT", "media", func(d *memoryData) error {
+		rows := d.media.filter(func(m mediaRow) bool { return m.UserID == userID })
+		slices.SortStableFunc(rows, func(a, b mediaRow) int { return b.CreatedAt.Compare(a.CreatedAt) })
+		for _, m := range pageOf(rows, limit, offset) {
+			mediaList = append(mediaList, m.model())
+		}
+		return nil
+	})
+	return mediaList, err
+}
+
+func (r *memoryMediaRepo) GetByID(ctx context.Context, userID int, mediaID int) (models.Media, error) {
+	var m mediaRow
+	err := r.read(ctx, "SELECT", "media", func(d *memoryData) error {
+		var ok bool
+		if m, ok = d.userMedia(userID, mediaID); !ok {
+			return errors.NewNotFoundError("Media")
+		}
+		return nil
+	})
+	if err != nil {
+		return models.Media{}, err
+	}
+	return m.model(), nil
+}
+
+func (r *memoryMediaRepo) GetObjectKey(ctx context.Context, userID int, mediaID int) (string, error) {
+	m, err := r.GetByID(ctx, userID, mediaID)
+	return m.ObjectKey, err
+}
+
+func (r *memoryMediaRepo) Delete(ctx context.Context, userID int, mediaID int) error {
+	return r.write(ctx, "DELETE", "media", func(d *memoryData) error {
+		if _, ok := d.userMedia(userID, mediaID); ok {
+			d.media.delete(mediaID)
+		}
+		return nil
+	})
+}
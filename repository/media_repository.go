@@ -13,6 +13,7 @@ import (
 type MediaRepository interface {
 	Create(ctx context.Context, userID int, objectKey, bucketName, originalFilename, mimeType string, fileSize int64) (models.Media, error)
 	Count(ctx context.Context, userID int) (int, error)
+	TotalSize(ctx context.Context, userID int) (int64, error)
 	List(ctx context.Context, userID int, limit, offset int) ([]models.Media, error)
 	GetByID(ctx context.Context, userID int, mediaID int) (models.Media, error)
 	GetObjectKey(ctx context.Context, userID int, mediaID int) (string, error)
@@ -65,6 +66,17 @@ func (r *postgresMediaRepo) Count(ctx context.Context, userID int) (int, error)
 	return count, nil
 }
 
+// TotalSize returns the combined size in bytes of a user's media.
+func (r *postgresMediaRepo) TotalSize(ctx context.Context, userID int) (int64, error) {
+	var total int64
+	err := r.db.QueryRowContext(ctx, `SELECT COALESCE(SUM(file_size), 0) FROM media WHERE user_id = $1`, userID).Scan(&total)
+	if err != nil {
+		logger.Error("Failed to sum media size", err)
+		return 0, errors.NewInternalServerError("Failed to retrieve media usage")
+	}
+	return total, nil
+}
+
 func (r *postgresMediaRepo) List(ctx context.Context, userID int, limit, offset int) ([]models.Media, error) {
 	rows, err := r.db.QueryContext(ctx, `
 		SELECT id, user_id, object_key, bucket_name, original_filename, file_size, mime_type, created_at, updated_at
@@ -99,7 +111,7 @@ func (r *postgresMediaRepo) GetByID(ctx context.Context, userID int, mediaID int
 		WHERE id = $1 AND user_id = $2
 	`, mediaID, userID).Scan(&m.ID, &m.UserID, &m.ObjectKey, &m.BucketName, &m.OriginalFilename, &m.FileSize, &m.MimeType, &m.CreatedAt, &m.UpdatedAt)
 
-	if err == sql.ErrNoRows {
+	if errors.Is(err, sql.ErrNoRows) {
 		return models.Media{}, errors.NewNotFoundError("Media")
 	}
 	if err != nil {
@@ -113,7 +125,7 @@ func (r *postgresMediaRepo) GetObjectKey(ctx context.Context, userID int, mediaI
 	var objectKey string
 	err := r.db.QueryRowContext(ctx, `SELECT object_key FROM media WHERE id = $1 AND user_id = $2`, mediaID, userID).Scan(&objectKey)
 
-	if err == sql.ErrNoRows {
+	if errors.Is(err, sql.ErrNoRows) {
 		return "", errors.NewNotFoundError("Media")
 	}
 	if err != nil {
@@ -0,0 +1,535 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/clock"
+	"github.com/clementhaon/sandbox-api-go/database"
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/logger"
+)
+
+// MemoryStore keeps the data of the memory repositories in the process, for
+// a sandbox or a demo running without Postgres. It enforces what the
+// services rely on from the schema: unique keys, foreign keys and their
+// cascades, and the defaults of the columns.
+//
+// Operations run one at a time. A transaction (MemoryStore is a
+// database.Transactor) excludes every other operation until it ends, and
+// leaves the data as it found it when it fails.
+type MemoryStore struct {
+	// gate is held shared by each operation run outside a transaction, and
+	// exclusively by a transaction for its whole length
+	gate sync.RWMutex
+	// mu guards data
+	mu   sync.RWMutex
+	data *memoryData
+}
+
+// NewMemoryStore returns a store holding the rows the migrations seed: the
+// default tenant and its Backlog column.
+func NewMemoryStore() *MemoryStore {
+	d := newMemoryData()
+	now := memoryNow()
+	d.tenants.put(tenantRow{ID: 1, Slug: "default", Name: "Default", CreatedAt: now})
+	d.columns.put(columnRow{ID: 1, TenantID: 1, Title: "Backlog", Order: 0, Color: "#9E9E9E", CreatedAt: now, UpdatedAt: now})
+	for _, t := range d.tables() {
+		t.resetSequence()
+	}
+	return &MemoryStore{data: d}
+}
+
+// memoryNow returns the current time at the precision of a Postgres
+// timestamp, so values read back compare equal to the ones written.
+func memoryNow() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+// memorySandboxTime is sandboxTime at the precision of memoryNow.
+func memorySandboxTime(ctx context.Context) *time.Time {
+	if clock.Offset(ctx) == 0 {
+		return nil
+	}
+	t := clock.Now(ctx).UTC().Truncate(time.Microsecond)
+	return &t
+}
+
+// WithTransaction runs fn alone on the store. fn must only use repositories
+// bound to q with WithQuerier; the data is restored if it returns an error.
+func (s *MemoryStore) WithTransaction(ctx context.Context, fn func(q database.Querier) error) error {
+	s.gate.Lock()
+	defer s.gate.Unlock()
+
+	s.mu.RLock()
+	saved := s.data.clone()
+	s.mu.RUnlock()
+
+	tx := &memoryTx{}
+	tx.active.Store(true)
+	defer tx.active.Store(false)
+	if err := fn(tx); err != nil {
+		s.mu.Lock()
+		s.data = saved
+		s.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// PingContext and Stats report the store as a database that is always up,
+// for the admin stats.
+func (s *MemoryStore) PingContext(ctx context.Context) error {
+	return ctx.Err()
+}
+
+func (s *MemoryStore) Stats() sql.DBStats {
+	return sql.DBStats{}
+}
+
+// memorySchemaVersion is the schema version of the memory store: always the
+// one of the latest migration.
+func memorySchemaVersion() int {
+	version, _ := database.LatestMigrationVersion()
+	return int(version)
+}
+
+// memorySnapshot is the JSON document a MemoryStore is saved as: the rows
+// of each table, as the memory BackupRepository dumps them.
+type memorySnapshot struct {
+	SchemaVersion int                          `json:"schema_version"`
+	SavedAt       time.Time                    `json:"saved_at"`
+	Tables        map[string][]json.RawMessage `json:"tables"`
+}
+
+// Save writes the data of the store to path, replacing the file only once
+// it is complete.
+func (s *MemoryStore) Save(path string) error {
+	s.gate.Lock()
+	defer s.gate.Unlock()
+
+	snapshot := memorySnapshot{
+		SchemaVersion: memorySchemaVersion(),
+		SavedAt:       memoryNow(),
+		Tables:        make(map[string][]json.RawMessage),
+	}
+	for _, t := range s.data.tables() {
+		rows := []json.RawMessage{}
+		if err := t.dump(func(row json.RawMessage) error {
+			rows = append(rows, row)
+			return nil
+		}); err != nil {
+			return fmt.Errorf("save %s: %w", t.tableName(), err)
+		}
+		snapshot.Tables[t.tableName()] = rows
+	}
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Load replaces the data of the store with the snapshot saved at path. A
+// missing file leaves the store as it is, and reports false.
+func (s *MemoryStore) Load(path string) (bool, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	var snapshot memorySnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return false, fmt.Errorf("read snapshot %s: %w", path, err)
+	}
+	if snapshot.SchemaVersion != memorySchemaVersion() {
+		return false, fmt.Errorf("snapshot %s is of schema version %d, not %d", path, snapshot.SchemaVersion, memorySchemaVersion())
+	}
+
+	d := newMemoryData()
+	for _, t := range d.tables() {
+		if err := t.insert(snapshot.Tables[t.tableName()]); err != nil {
+			return false, fmt.Errorf("read snapshot %s: %s: %w", path, t.tableName(), err)
+		}
+		t.resetSequence()
+	}
+
+	s.gate.Lock()
+	defer s.gate.Unlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data = d
+	return true, nil
+}
+
+// memoryTx is the Querier of a memory transaction. It runs no SQL: it only
+// binds memory repositories to the transaction.
+type memoryTx struct {
+	active atomic.Bool
+}
+
+var errMemorySQL = fmt.Errorf("repository: the memory store runs no SQL")
+
+func (tx *memoryTx) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return nil, errMemorySQL
+}
+
+func (tx *memoryTx) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return nil, errMemorySQL
+}
+
+// QueryRowContext panics, as a *sql.Row cannot carry an error of its own.
+func (tx *memoryTx) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	panic(errMemorySQL)
+}
+
+// memoryRepo is what the memory repositories share: the store, and the
+// transaction WithQuerier bound them to, if any.
+type memoryRepo struct {
+	store *MemoryStore
+	tx    *memoryTx
+}
+
+// bind returns the repository bound to q, a transaction of the store.
+func (r memoryRepo) bind(q database.Querier) memoryRepo {
+	tx, _ := q.(*memoryTx)
+	return memoryRepo{store: r.store, tx: tx}
+}
+
+// read runs fn on the data of the store, logged as a database operation.
+func (r memoryRepo) read(ctx context.Context, op, table string, fn func(d *memoryData) error) error {
+	return r.run(ctx, op, table, false, fn)
+}
+
+// write runs fn on the data of the store, alone. fn must check what can
+// fail before it changes anything, as a single operation is not rolled
+// back.
+func (r memoryRepo) write(ctx context.Context, op, table string, fn func(d *memoryData) error) error {
+	return r.run(ctx, op, table, true, fn)
+}
+
+func (r memoryRepo) run(ctx context.Context, op, table string, exclusive bool, fn func(d *memoryData) error) error {
+	// The transaction holds the gate for the operations bound to it
+	if r.tx == nil || !r.tx.active.Load() {
+		r.store.gate.RLock()
+		defer r.store.gate.RUnlock()
+	}
+	if exclusive {
+		r.store.mu.Lock()
+		defer r.store.mu.Unlock()
+	} else {
+		r.store.mu.RLock()
+		defer r.store.mu.RUnlock()
+	}
+
+	startTime := time.Now()
+	err := fn(r.store.data)
+	logger.LogDatabaseOperation(ctx, op, table, time.Since(startTime), err)
+	return err
+}
+
+// constraintError is what the memory store returns where Postgres would
+// reject a write for breaking a constraint.
+func constraintError(format string, args ...any) error {
+	return errors.NewDatabaseError().WithCause(fmt.Errorf(format, args...))
+}
+
+// pageOf returns the rows LIMIT limit OFFSET offset would; a limit of 0 or
+// less is no limit.
+func pageOf[R any](rows []R, limit, offset int) []R {
+	if offset >= len(rows) {
+		return nil
+	}
+	rows = rows[max(offset, 0):]
+	if limit > 0 && limit < len(rows) {
+		rows = rows[:limit]
+	}
+	return rows
+}
+
+// containsFold is ILIKE '%' || sub || '%'.
+func containsFold(s, sub string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(sub))
+}
+
+// clonePtr copies the value p points to, so a row shares nothing with its
+// callers.
+func clonePtr[T any](p *T) *T {
+	if p == nil {
+		return nil
+	}
+	v := *p
+	return &v
+}
+
+// compareBool orders false before true, as Postgres does.
+func compareBool(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case b:
+		return -1
+	}
+	return 1
+}
+
+// memoryTable holds the rows of a table by primary key.
+type memoryTable[K cmp.Ordered, R any] struct {
+	name string
+	key  func(R) K
+	// seq is the last value of the serial primary key, for tables that
+	// have one
+	seq    int
+	serial bool
+	rows   map[K]R
+}
+
+func newMemoryTable[K cmp.Ordered, R any](name string, serial bool, key func(R) K) *memoryTable[K, R] {
+	return &memoryTable[K, R]{name: name, key: key, serial: serial, rows: make(map[K]R)}
+}
+
+func (t *memoryTable[K, R]) nextID() int {
+	t.seq++
+	return t.seq
+}
+
+func (t *memoryTable[K, R]) get(k K) (R, bool) {
+	row, ok := t.rows[k]
+	return row, ok
+}
+
+func (t *memoryTable[K, R]) put(row R) {
+	t.rows[t.key(row)] = row
+}
+
+func (t *memoryTable[K, R]) delete(k K) {
+	delete(t.rows, k)
+}
+
+// all returns the rows in primary key order.
+func (t *memoryTable[K, R]) all() []R {
+	keys := slices.Sorted(maps.Keys(t.rows))
+	rows := make([]R, len(keys))
+	for i, k := range keys {
+		rows[i] = t.rows[k]
+	}
+	return rows
+}
+
+// filter returns the rows matching keep, in primary key order.
+func (t *memoryTable[K, R]) filter(keep func(R) bool) []R {
+	var rows []R
+	for _, row := range t.all() {
+		if keep(row) {
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+// deleteWhere deletes the rows matching match and returns them.
+func (t *memoryTable[K, R]) deleteWhere(match func(R) bool) []R {
+	deleted := t.filter(match)
+	for _, row := range deleted {
+		delete(t.rows, t.key(row))
+	}
+	return deleted
+}
+
+// clone copies the table. Rows are values, replaced rather than changed in
+// place, so the copy does not share anything that is later modified.
+func (t *memoryTable[K, R]) clone() *memoryTable[K, R] {
+	c := *t
+	c.rows = maps.Clone(t.rows)
+	return &c
+}
+
+// memoryTableData is a table, whatever its rows, for backups and snapshots.
+type memoryTableData interface {
+	tableName() string
+	dump(fn func(row json.RawMessage) error) error
+	insert(rows []json.RawMessage) error
+	deleteRows(match func(row json.RawMessage) (bool, error)) error
+	resetSequence()
+}
+
+func (t *memoryTable[K, R]) tableName() string {
+	return t.name
+}
+
+func (t *memoryTable[K, R]) dump(fn func(row json.RawMessage) error) error {
+	for _, row := range t.all() {
+		data, err := json.Marshal(row)
+		if err != nil {
+			return err
+		}
+		if err := fn(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *memoryTable[K, R]) insert(rows []json.RawMessage) error {
+	for _, data := range rows {
+		var row R
+		if err := json.Unmarshal(data, &row); err != nil {
+			return err
+		}
+		if _, ok := t.rows[t.key(row)]; ok {
+			return fmt.Errorf("duplicate key %v in %s", t.key(row), t.name)
+		}
+		t.put(row)
+	}
+	return nil
+}
+
+func (t *memoryTable[K, R]) deleteRows(match func(row json.RawMessage) (bool, error)) error {
+	for k, row := range t.rows {
+		data, err := json.Marshal(row)
+		if err != nil {
+			return err
+		}
+		ok, err := match(data)
+		if err != nil {
+			return err
+		}
+		if ok {
+			delete(t.rows, k)
+		}
+	}
+	return nil
+}
+
+func (t *memoryTable[K, R]) resetSequence() {
+	if !t.serial {
+		return
+	}
+	t.seq = 0
+	for k := range t.rows {
+		t.seq = max(t.seq, any(k).(int))
+	}
+}
+
+// memoryData holds the tables of a MemoryStore.
+type memoryData struct {
+	tenants                *memoryTable[int, tenantRow]
+	users                  *memoryTable[int, userRow]
+	columns                *memoryTable[int, columnRow]
+	tasks                  *memoryTable[int, taskRow]
+	taskListModified       *memoryTable[int, taskListModifiedRow]
+	checklistItems         *memoryTable[int, checklistItemRow]
+	timeEntries            *memoryTable[int, timeEntryRow]
+	activities             *memoryTable[int, activityRow]
+	notifications          *memoryTable[int, notificationRow]
+	media                  *memoryTable[int, mediaRow]
+	auditLogs              *memoryTable[int, auditLogRow]
+	emailChanges           *memoryTable[int, emailChangeRow]
+	userQuotas             *memoryTable[int, userQuotaRow]
+	announcements          *memoryTable[int, announcementRow]
+	announcementDismissals *memoryTable[string, announcementDismissalRow]
+	inboundHooks           *memoryTable[int, inboundHookRow]
+	slackLinks             *memoryTable[string, slackLinkRow]
+	sessions               *memoryTable[int, sessionRow]
+	personalTokens         *memoryTable[int, personalTokenRow]
+	apiUsage               *memoryTable[string, apiUsageRow]
+	webhooks               *memoryTable[int, webhookRow]
+}
+
+func newMemoryData() *memoryData {
+	return &memoryData{
+		tenants:                newMemoryTable("tenants", true, func(r tenantRow) int { return r.ID }),
+		users:                  newMemoryTable("users", true, func(r userRow) int { return r.ID }),
+		columns:                newMemoryTable("columns", true, func(r columnRow) int { return r.ID }),
+		tasks:                  newMemoryTable("tasks", true, func(r taskRow) int { return r.ID }),
+		taskListModified:       newMemoryTable("task_list_modified", false, func(r taskListModifiedRow) int { return r.TenantID }),
+		checklistItems:         newMemoryTable("checklist_items", true, func(r checklistItemRow) int { return r.ID }),
+		timeEntries:            newMemoryTable("time_entries", true, func(r timeEntryRow) int { return r.ID }),
+		activities:             newMemoryTable("activities", true, func(r activityRow) int { return r.ID }),
+		notifications:          newMemoryTable("notifications", true, func(r notificationRow) int { return r.ID }),
+		media:                  newMemoryTable("media", true, func(r mediaRow) int { return r.ID }),
+		auditLogs:              newMemoryTable("audit_logs", true, func(r auditLogRow) int { return r.ID }),
+		emailChanges:           newMemoryTable("email_changes", false, func(r emailChangeRow) int { return r.UserID }),
+		userQuotas:             newMemoryTable("user_quotas", false, func(r userQuotaRow) int { return r.UserID }),
+		announcements:          newMemoryTable("announcements", true, func(r announcementRow) int { return r.ID }),
+		announcementDismissals: newMemoryTable("announcement_dismissals", false, announcementDismissalRow.key),
+		inboundHooks:           newMemoryTable("inbound_hooks", false, func(r inboundHookRow) int { return r.UserID }),
+		slackLinks:             newMemoryTable("slack_links", false, slackLinkRow.key),
+		sessions:               newMemoryTable("sessions", true, func(r sessionRow) int { return r.ID }),
+		personalTokens:         newMemoryTable("personal_tokens", true, func(r personalTokenRow) int { return r.ID }),
+		apiUsage:               newMemoryTable("api_usage", false, apiUsageRow.key),
+		webhooks:               newMemoryTable("webhooks", true, func(r webhookRow) int { return r.ID }),
+	}
+}
+
+// tables returns the tables, each after the tables it references.
+func (d *memoryData) tables() []memoryTableData {
+	return []memoryTableData{
+		d.tenants, d.users, d.columns, d.tasks, d.taskListModified, d.checklistItems, d.timeEntries,
+		d.activities, d.notifications, d.media, d.auditLogs, d.emailChanges, d.userQuotas,
+		d.announcements, d.announcementDismissals, d.inboundHooks, d.slackLinks, d.sessions, d.personalTokens,
+		d.apiUsage, d.webhooks,
+	}
+}
+
+func (d *memoryData) clone() *memoryData {
+	return &memoryData{
+		tenants:                d.tenants.clone(),
+		users:                  d.users.clone(),
+		columns:                d.columns.clone(),
+		tasks:                  d.tasks.clone(),
+		taskListModified:       d.taskListModified.clone(),
+		checklistItems:         d.checklistItems.clone(),
+		timeEntries:            d.timeEntries.clone(),
+		activities:             d.activities.clone(),
+		notifications:          d.notifications.clone(),
+		media:                  d.media.clone(),
+		auditLogs:              d.auditLogs.clone(),
+		emailChanges:           d.emailChanges.clone(),
+		userQuotas:             d.userQuotas.clone(),
+		announcements:          d.announcements.clone(),
+		announcementDismissals: d.announcementDismissals.clone(),
+		inboundHooks:           d.inboundHooks.clone(),
+		slackLinks:             d.slackLinks.clone(),
+		sessions:               d.sessions.clone(),
+		personalTokens:         d.personalTokens.clone(),
+		apiUsage:               d.apiUsage.clone(),
+		webhooks:               d.webhooks.clone(),
+	}
+}
+
+// table returns the table called name, or nil.
+func (d *memoryData) table(name string) memoryTableData {
+	for _, t := range d.tables() {
+		if t.tableName() == name {
+			return t
+		}
+	}
+	return nil
+}
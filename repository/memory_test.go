@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/clementhaon/sandbox-api-go/database"
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/models"
+)
+
+// TestMemoryTablesMatchSchema keeps the rows of the memory tables in the
+// shape of the Postgres ones, so backups restore from one into the other.
+func TestMemoryTablesMatchSchema(t *testing.T) {
+	schema := Schema()
+	tables := newMemoryData().tables()
+	for _, table := range tables {
+		columns, ok := schema[table.tableName()]
+		if !ok {
+			t.Errorf("memory table %s is not in Schema", table.tableName())
+			continue
+		}
+		var tags []string
+		rows, _ := reflect.TypeOf(table).Elem().FieldByName("rows")
+		row := rows.Type.Elem()
+		for i := 0; i < row.NumField(); i++ {
+			tags = append(tags, strings.Split(row.Field(i).Tag.Get("json"), ",")[0])
+		}
+		for _, column := range columns {
+			if !slices.Contains(tags, column) {
+				t.Errorf("memory table %s has no column %s", table.tableName(), column)
+			}
+		}
+	}
+	if len(tables) != reflect.TypeOf(memoryData{}).NumField() {
+		t.Error("expected tables to list every memory table")
+	}
+}
+
+func TestMemoryStore_TransactionRollback(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	users := NewMemoryUserRepository(store)
+
+	err := store.WithTransaction(ctx, func(q database.Querier) error {
+		if _, err := users.WithQuerier(q).CreateAuth(ctx, "alice", "alice@example.com", "hash"); err != nil {
+			return err
+		}
+		return fmt.Errorf("abort")
+	})
+	if err == nil {
+		t.Fatal("expected the transaction error")
+	}
+	if _, _, err := users.FindByEmailWithPassword(ctx, "alice@example.com"); err == nil {
+		t.Error("expected the user to be rolled back")
+	}
+}
+
+func TestMemoryUserRepo_Unique(t *testing.T) {
+	ctx := context.Background()
+	users := NewMemoryUserRepository(NewMemoryStore())
+	if _, err := users.CreateAuth(ctx, "alice", "alice@example.com", "hash"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := users.CreateAuth(ctx, "alice", "other@example.com", "hash"); err == nil {
+		t.Error("expected an error for a taken username")
+	}
+}
+
+func TestMemoryUserRepo_DeleteCascades(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	users := NewMemoryUserRepository(store)
+	tasks := NewMemoryTaskRepository(store)
+	columns, err := NewMemoryColumnRepository(store).List(ctx)
+	if err != nil || len(columns) == 0 {
+		t.Fatalf("expected the default column, got %v (%v)", columns, err)
+	}
+
+	alice, err := users.CreateAuth(ctx, "alice", "alice@example.com", "hash")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	bob, err := users.CreateAuth(ctx, "bob", "bob@example.com", "hash")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	owned, err := tasks.Create(ctx, models.CreateTaskRequest{Title: "Owned", ColumnID: columns[0].ID, Priority: models.PriorityLow}, 1, alice.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assigned, err := tasks.Create(ctx, models.CreateTaskRequest{Title: "Assigned", ColumnID: columns[0].ID, Priority: models.PriorityLow, AssigneeID: &alice.ID}, 2, bob.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := users.Delete(ctx, alice.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := tasks.GetByID(ctx, owned.ID); !errors.Is(err, errors.ErrNotFound) {
+		t.Errorf("expected the owned task to be deleted, got %v", err)
+	}
+	task, err := tasks.GetByID(ctx, assigned.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if task.AssigneeID != nil {
+		t.Errorf("expected the assignee to be cleared, got %d", *task.AssigneeID)
+	}
+}
+
+func TestMemoryStore_SaveLoad(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+
+	if loaded, err := NewMemoryStore().Load(path); loaded || err != nil {
+		t.Fatalf("expected a missing snapshot to be skipped, got %t (%v)", loaded, err)
+	}
+
+	store := NewMemoryStore()
+	if _, err := NewMemoryUserRepository(store).CreateAuth(ctx, "alice", "alice@example.com", "hash"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := store.Save(path); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	restored := NewMemoryStore()
+	if loaded, err := restored.Load(path); !loaded || err != nil {
+		t.Fatalf("load: %t (%v)", loaded, err)
+	}
+	users := NewMemoryUserRepository(restored)
+	alice, _, err := users.FindByEmailWithPassword(ctx, "alice@example.com")
+	if err != nil {
+		t.Fatalf("expected the user in the snapshot, got %v", err)
+	}
+	// Sequences resume after the loaded rows
+	bob, err := users.CreateAuth(ctx, "bob", "bob@example.com", "hash")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if bob.ID <= alice.ID {
+		t.Errorf("expected a new ID after %d, got %d", alice.ID, bob.ID)
+	}
+}
@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/database"
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/models"
+)
+
+// notificationRow is a row of notifications.
+type notificationRow struct {
+	ID        int             `json:"id"`
+	UserID    int             `json:"user_id"`
+	Type      string          `json:"type"`
+	Title     string          `json:"title"`
+	Message   string          `json:"message"`
+	Read      bool            `json:"read"`
+	Data      json.RawMessage `json:"data"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// userNotification returns notification id, if it belongs to userID.
+func (d *memoryData) userNotification(userID, id int) (notificationRow, bool) {
+	n, ok := d.notifications.get(id)
+	return n, ok && n.UserID == userID
+}
+
+type memoryNotificationRepo struct {
+	memoryRepo
+}
+
+// NewMemoryNotificationRepository returns a NotificationRepository keeping
+// its data in store.
+func NewMemoryNotificationRepository(store *MemoryStore) NotificationRepository {
+	return &memoryNotificationRepo{memoryRepo{store: store}}
+}
+
+func (r *memoryNotificationRepo) WithQuerier(q database.Querier) NotificationRepository {
+	return &memoryNotificationRepo{r.bind(q)}
+}
+
+func (r *memoryNotificationRepo) List(ctx context.Context, userID int) ([]models.Notification, error) {
+	notifications := []models.Notification{}
+	err := r.read(ctx, "SELECT", "notifications", func(d *memoryData) error {
+		rows := d.notifications.filter(func(n notificationRow) bool { return n.UserID == userID })
+		slices.SortStableFunc(rows, func(a, b notificationRow) int { return b.CreatedAt.Compare(a.CreatedAt) })
+		for _, n := range rows {
+			notifications = append(notifications, models.Notification{
+				ID: n.ID, Type: n.Type, Title: n.Title, Message: n.Message, Read: n.Read,
+				Data: jsonOrNil(n.Data), CreatedAt: n.CreatedAt,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return notifications, nil
+}
+
+// jsonOrNil copies a JSON column, returning nil for NULL, which a snapshot
+// reads back as the JSON null.
+func jsonOrNil(data json.RawMessage) json.RawMessage {
+	if data == nil || string(data) == "null" {
+		return nil
+	}
+	return slices.Clone(data)
+}
+
+func (r *memoryNotificationRepo) MarkRead(ctx context.Context, userID int, notificationIDs []int) error {
+	return r.write(ctx, "UPDATE", "notifications", func(d *memoryData) error {
+		for _, id := range notificationIDs {
+			if n, ok := d.userNotification(userID, id); ok {
+				n.Read = true
+				d.notifications.put(n)
+			}
+		}
+		return nil
+	})
+}
+
+func (r *memoryNotificationRepo) MarkOneRead(ctx context.Context, userID int, id int) error {
+	return r.write(ctx, "UPDATE", "notifications", func(d *memoryData) error {
+		n, ok := d.userNotification(userID, id)
+		if !ok {
+			return errors.NewNotFoundError("Notification not found")
+		}
+		n.Read = true
+		d.notifications.put(n)
+		return nil
+	})
+}
+
+func (r *memoryNotificationRepo) CountUnread(ctx context.Context, userID int) (int, error) {
+	var count int
+	err := r.read(ctx, "SELECT COUNT", "notifications", func(d *memoryData) error {
+		for _, n := range d.notifications.rows {
+			if n.UserID == userID && !n.Read {
+				count++
+			}
+		}
+		return nil
+	})
+	return count, err
+}
+
+func (r *memoryNotificationRepo) MarkAllRead(ctx context.Context, userID int) (int64, error) {
+	var count int64
+	err := r.write(ctx, "UPDATE", "notifications", func(d *memoryData) error {
+		for _, n := range d.notifications.filter(func(n notificationRow) bool { return n.UserID == userID && !n.Read }) {
+			n.Read = true
+			d.notifications.put(n)
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+func (r *memoryNotificationRepo) DeleteAll(ctx context.Context, userID int) (int64, error) {
+	var count int64
+	err := r.write(ctx, "DELETE", "notifications", func(d *memoryData) error {
+		count = int64(len(d.notifications.deleteWhere(func(n notificationRow) bool { return n.UserID == userID })))
+		return nil
+	})
+	return count, err
+}
+
+func (r *memoryNotificationRepo) Delete(ctx context.Context, userID int, id int) error {
+	return r.write(ctx, "DELETE", "notifications", func(d *memoryData) error {
+		if _, ok := d.userNotification(userID, id); !ok {
+			return errors.NewNotFoundError("Notification not found")
+		}
+		d.notifications.delete(id)
+		return nil
+	})
+}
+
+func (r *memoryNotificationRepo) Create(ctx context.Context, userID int, notifType, title, message string, dataJSON []byte) error {
+	if dataJSON != nil && !json.Valid(dataJSON) {
+		return constraintError("invalid input syntax for type json")
+	}
+	return r.write(ctx, "INSERT", "notifications", func(d *memoryData) error {
+		if _, ok := d.users.get(userID); !ok {
+			return constraintError("insert or update on table notifications violates foreign key constraint notifications_user_id_fkey")
+		}
+		d.notifications.put(notificationRow{
+			ID: d.notifications.nextID(), UserID: userID, Type: notifType, Title: title, Message: message,
+			Data: slices.Clone(dataJSON), CreatedAt: memoryNow(),
+		})
+		return nil
+	})
+}
@@ -15,8 +15,15 @@ import (
 type NotificationRepository interface {
 	List(ctx context.Context, userID int) ([]models.Notification, error)
 	MarkRead(ctx context.Context, userID int, notificationIDs []int) error
+	// MarkOneRead marks a notification of the user read. It returns a
+	// NotFound error if the user has no such notification.
+	MarkOneRead(ctx context.Context, userID int, id int) error
 	MarkAllRead(ctx context.Context, userID int) (int64, error)
+	CountUnread(ctx context.Context, userID int) (int, error)
 	Delete(ctx context.Context, userID int, id int) error
+	// DeleteAll deletes every notification of the user and returns how many
+	// there were
+	DeleteAll(ctx context.Context, userID int) (int64, error)
 	Create(ctx context.Context, userID int, notifType, title, message string, dataJSON []byte) error
 	WithQuerier(q database.Querier) NotificationRepository
 }
@@ -73,6 +80,39 @@ func (r *postgresNotificationRepo) MarkRead(ctx context.Context, userID int, not
 	return nil
 }
 
+func (r *postgresNotificationRepo) MarkOneRead(ctx context.Context, userID int, id int) error {
+	startTime := time.Now()
+	result, err := r.db.ExecContext(ctx, `UPDATE notifications SET read = true WHERE id = $1 AND user_id = $2`, id, userID)
+	logger.LogDatabaseOperation(ctx, "UPDATE", "notifications", time.Since(startTime), err)
+
+	if err != nil {
+		logger.ErrorContext(ctx, "Error marking notification as read", err)
+		return errors.NewDatabaseError().WithCause(err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return errors.NewDatabaseError().WithCause(err)
+	}
+	if rowsAffected == 0 {
+		return errors.NewNotFoundError("Notification not found")
+	}
+	return nil
+}
+
+func (r *postgresNotificationRepo) CountUnread(ctx context.Context, userID int) (int, error) {
+	var count int
+	startTime := time.Now()
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND read = false`, userID).Scan(&count)
+	logger.LogDatabaseOperation(ctx, "SELECT COUNT", "notifications", time.Since(startTime), err)
+
+	if err != nil {
+		logger.ErrorContext(ctx, "Error counting unread notifications", err)
+		return 0, errors.NewDatabaseError().WithCause(err)
+	}
+	return count, nil
+}
+
 func (r *postgresNotificationRepo) MarkAllRead(ctx context.Context, userID int) (int64, error) {
 	startTime := time.Now()
 	result, err := r.db.ExecContext(ctx, `UPDATE notifications SET read = true WHERE user_id = $1 AND read = false`, userID)
@@ -90,6 +130,23 @@ func (r *postgresNotificationRepo) MarkAllRead(ctx context.Context, userID int)
 	return rowsAffected, nil
 }
 
+func (r *postgresNotificationRepo) DeleteAll(ctx context.Context, userID int) (int64, error) {
+	startTime := time.Now()
+	result, err := r.db.ExecContext(ctx, `DELETE FROM notifications WHERE user_id = $1`, userID)
+	logger.LogDatabaseOperation(ctx, "DELETE", "notifications", time.Since(startTime), err)
+
+	if err != nil {
+		logger.ErrorContext(ctx, "Error deleting all notifications", err)
+		return 0, errors.NewDatabaseError().WithCause(err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, errors.NewDatabaseError().WithCause(err)
+	}
+	return rowsAffected, nil
+}
+
 func (r *postgresNotificationRepo) Delete(ctx context.Context, userID int, id int) error {
 	startTime := time.Now()
 	result, err := r.db.ExecContext(ctx, "DELETE FROM notifications WHERE id = $1 AND user_id = $2", id, userID)
@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/database"
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/models"
+)
+
+// personalTokenRow is a row of personal_tokens.
+type personalTokenRow struct {
+	ID         int                    `json:"id"`
+	UserID     int                    `json:"user_id"`
+	Name       string                 `json:"name"`
+	Prefix     string                 `json:"prefix"`
+	TokenHash  string                 `json:"token_hash"`
+	Scopes     []string               `json:"scopes"`
+	ExpiresAt  time.Time              `json:"expires_at"`
+	LastUsedAt *time.Time             `json:"last_used_at"`
+	CreatedAt  time.Time              `json:"created_at"`
+	Sandbox    models.SandboxSettings `json:"sandbox"`
+}
+
+func (r personalTokenRow) model() models.PersonalToken {
+	return models.PersonalToken{
+		ID: r.ID, UserID: r.UserID, Name: r.Name, Prefix: r.Prefix, TokenHash: r.TokenHash,
+		Scopes: slices.Clone(r.Scopes), ExpiresAt: r.ExpiresAt, LastUsedAt: clonePtr(r.LastUsedAt), CreatedAt: r.CreatedAt,
+		Sandbox: r.Sandbox,
+	}
+}
+
+type memoryPersonalTokenRepo struct {
+	memoryRepo
+}
+
+// NewMemoryPersonalTokenRepository returns a PersonalTokenRepository keeping
+// its data in store.
+func NewMemoryPersonalTokenRepository(store *MemoryStore) PersonalTokenRepository {
+	return &memoryPersonalTokenRepo{memoryRepo{store: store}}
+}
+
+func (r *memoryPersonalTokenRepo) WithQuerier(q database.Querier) PersonalTokenRepository {
+	return &memoryPersonalTokenRepo{r.bind(q)}
+}
+
+func (r *memoryPersonalTokenRepo) Create(ctx context.Context, token models.PersonalToken) (models.PersonalToken, error) {
+	err := r.write(ctx, "INSERT", "personal_tokens", func(d *memoryData) error {
+		if _, ok := d.users.get(token.UserID); !ok {
+			return constraintError("insert or update on table personal_tokens violates foreign key constraint personal_tokens_user_id_fkey")
+		}
+		for _, t := range d.personalTokens.rows {
+			if t.TokenHash == token.TokenHash {
+				return constraintError("duplicate key value violates unique constraint personal_tokens_token_hash_key")
+			}
+		}
+		token.ID, token.CreatedAt = d.personalTokens.nextID(), memoryNow()
+		d.personalTokens.put(personalTokenRow{
+			ID: token.ID, UserID: token.UserID, Name: token.Name, Prefix: token.Prefix, TokenHash: token.TokenHash,
+			Scopes: slices.Clone(token.Scopes), ExpiresAt: token.ExpiresAt.Truncate(time.Microsecond), CreatedAt: token.CreatedAt,
+			Sandbox: token.Sandbox,
+		})
+		return nil
+	})
+	if err != nil {
+		return models.PersonalToken{}, err
+	}
+	return token, nil
+}
+
+func (r *memoryPersonalTokenRepo) GetByHash(ctx context.Context, hash string) (models.PersonalToken, error) {
+	var token models.PersonalToken
+	err := r.read(ctx, "SELECT", "personal_tokens", func(d *memoryData) error {
+		for _, t := range d.personalTokens.rows {
+			if t.TokenHash == hash {
+				token = t.model()
+				return nil
+			}
+		}
+		return errors.NewNotFoundError("Personal token")
+	})
+	return token, err
+}
+
+func (r *memoryPersonalTokenRepo) ListByUser(ctx context.Context, userID int) ([]models.PersonalToken, error) {
+	tokens := []models.PersonalToken{}
+	err := r.read(ctx, "SELECT", "personal_tokens", func(d *memoryData) error {
+		rows := d.personalTokens.filter(func(t personalTokenRow) bool { return t.UserID == userID })
+		slices.SortFunc(rows, func(a, b personalTokenRow) int {
+			return cmp.Or(b.CreatedAt.Compare(a.CreatedAt), cmp.Compare(b.ID, a.ID))
+		})
+		for _, t := range rows {
+			tokens = append(tokens, t.model())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+func (r *memoryPersonalTokenRepo) MarkUsed(ctx context.Context, id int) error {
+	return r.write(ctx, "UPDATE", "personal_tokens", func(d *memoryData) error {
+		now := memoryNow()
+		if t, ok := d.personalTokens.get(id); ok && (t.LastUsedAt == nil || t.LastUsedAt.Before(now.Add(-time.Minute))) {
+			t.LastUsedAt = &now
+			d.personalTokens.put(t)
+		}
+		return nil
+	})
+}
+
+func (r *memoryPersonalTokenRepo) UpdateSandbox(ctx context.Context, userID, id int, settings models.SandboxSettings) error {
+	return r.write(ctx, "UPDATE", "personal_tokens", func(d *memoryData) error {
+		t, ok := d.personalTokens.get(id)
+		if !ok || t.UserID != userID {
+			return errors.NewNotFoundError("Personal token")
+		}
+		t.Sandbox = settings
+		d.personalTokens.put(t)
+		return nil
+	})
+}
+
+func (r *memoryPersonalTokenRepo) Delete(ctx context.Context, userID, id int) error {
+	return r.write(ctx, "DELETE", "personal_tokens", func(d *memoryData) error {
+		if t, ok := d.personalTokens.get(id); !ok || t.UserID != userID {
+			return errors.NewNotFoundError("Personal token")
+		}
+		d.personalTokens.delete(id)
+		return nil
+	})
+}
@@ -0,0 +1,176 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/database"
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/logger"
+	"github.com/clementhaon/sandbox-api-go/models"
+
+	"github.com/lib/pq"
+)
+
+type PersonalTokenRepository interface {
+	Create(ctx context.Context, token models.PersonalToken) (models.PersonalToken, error)
+	// GetByHash returns the token with the hash of its secret, expired or
+	// not, for any user
+	GetByHash(ctx context.Context, hash string) (models.PersonalToken, error)
+	ListByUser(ctx context.Context, userID int) ([]models.PersonalToken, error)
+	// MarkUsed records that a token was used, at most once a minute
+	MarkUsed(ctx context.Context, id int) error
+	// UpdateSandbox replaces the sandbox settings of a token, and returns a
+	// NotFound error if userID has no token id
+	UpdateSandbox(ctx context.Context, userID, id int, settings models.SandboxSettings) error
+	// Delete returns a NotFound error if userID has no token id
+	Delete(ctx context.Context, userID, id int) error
+	WithQuerier(q database.Querier) PersonalTokenRepository
+}
+
+type postgresPersonalTokenRepo struct {
+	db database.Querier
+}
+
+func NewPostgresPersonalTokenRepository(db *sql.DB) PersonalTokenRepository {
+	return &postgresPersonalTokenRepo{db: db}
+}
+
+func (r *postgresPersonalTokenRepo) WithQuerier(q database.Querier) PersonalTokenRepository {
+	return &postgresPersonalTokenRepo{db: q}
+}
+
+const personalTokenColumns = `id, user_id, name, prefix, token_hash, scopes, expires_at, last_used_at, created_at, sandbox`
+
+func scanPersonalToken(row interface{ Scan(...interface{}) error }) (models.PersonalToken, error) {
+	var t models.PersonalToken
+	var lastUsedAt sql.NullTime
+	var sandbox []byte
+	err := row.Scan(&t.ID, &t.UserID, &t.Name, &t.Prefix, &t.TokenHash, pq.Array(&t.Scopes), &t.ExpiresAt, &lastUsedAt, &t.CreatedAt, &sandbox)
+	if err != nil {
+		return t, err
+	}
+	if lastUsedAt.Valid {
+		t.LastUsedAt = &lastUsedAt.Time
+	}
+	return t, json.Unmarshal(sandbox, &t.Sandbox)
+}
+
+func (r *postgresPersonalTokenRepo) Create(ctx context.Context, token models.PersonalToken) (models.PersonalToken, error) {
+	startTime := time.Now()
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO personal_tokens (user_id, name, prefix, token_hash, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`, token.UserID, token.Name, token.Prefix, token.TokenHash, pq.Array(token.Scopes), token.ExpiresAt,
+	).Scan(&token.ID, &token.CreatedAt)
+	logger.LogDatabaseOperation(ctx, "INSERT", "personal_tokens", time.Since(startTime), err)
+
+	if err != nil {
+		logger.ErrorContext(ctx, "Error creating personal token", err)
+		return models.PersonalToken{}, errors.NewDatabaseError().WithCause(err)
+	}
+	return token, nil
+}
+
+func (r *postgresPersonalTokenRepo) GetByHash(ctx context.Context, hash string) (models.PersonalToken, error) {
+	startTime := time.Now()
+	t, err := scanPersonalToken(r.db.QueryRowContext(ctx,
+		`SELECT `+personalTokenColumns+` FROM personal_tokens WHERE token_hash = $1`, hash))
+	logger.LogDatabaseOperation(ctx, "SELECT", "personal_tokens", time.Since(startTime), err)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.PersonalToken{}, errors.NewNotFoundError("Personal token")
+	}
+	if err != nil {
+		logger.ErrorContext(ctx, "Error fetching personal token", err)
+		return models.PersonalToken{}, errors.NewDatabaseError().WithCause(err)
+	}
+	return t, nil
+}
+
+func (r *postgresPersonalTokenRepo) ListByUser(ctx context.Context, userID int) ([]models.PersonalToken, error) {
+	startTime := time.Now()
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+personalTokenColumns+` FROM personal_tokens WHERE user_id = $1 ORDER BY created_at DESC, id DESC`, userID)
+	logger.LogDatabaseOperation(ctx, "SELECT", "personal_tokens", time.Since(startTime), err)
+
+	if err != nil {
+		logger.ErrorContext(ctx, "Error listing personal tokens", err)
+		return nil, errors.NewDatabaseError().WithCause(err)
+	}
+	defer rows.Close()
+
+	tokens := []models.PersonalToken{}
+	for rows.Next() {
+		t, err := scanPersonalToken(rows)
+		if err != nil {
+			return nil, errors.NewDatabaseError().WithCause(err)
+		}
+		tokens = append(tokens, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.NewDatabaseError().WithCause(err)
+	}
+	return tokens, nil
+}
+
+func (r *postgresPersonalTokenRepo) MarkUsed(ctx context.Context, id int) error {
+	startTime := time.Now()
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE personal_tokens SET last_used_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < CURRENT_TIMESTAMP - INTERVAL '1 minute')
+	`, id)
+	logger.LogDatabaseOperation(ctx, "UPDATE", "personal_tokens", time.Since(startTime), err)
+
+	if err != nil {
+		logger.ErrorContext(ctx, "Error marking personal token used", err)
+		return errors.NewDatabaseError().WithCause(err)
+	}
+	return nil
+}
+
+func (r *postgresPersonalTokenRepo) UpdateSandbox(ctx context.Context, userID, id int, settings models.SandboxSettings) error {
+	sandbox, err := json.Marshal(settings)
+	if err != nil {
+		return errors.NewInternalError().WithCause(err)
+	}
+
+	startTime := time.Now()
+	result, err := r.db.ExecContext(ctx, `UPDATE personal_tokens SET sandbox = $1 WHERE id = $2 AND user_id = $3`, sandbox, id, userID)
+	logger.LogDatabaseOperation(ctx, "UPDATE", "personal_tokens", time.Since(startTime), err)
+
+	if err != nil {
+		logger.ErrorContext(ctx, "Error updating personal token sandbox settings", err)
+		return errors.NewDatabaseError().WithCause(err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return errors.NewDatabaseError().WithCause(err)
+	}
+	if rowsAffected == 0 {
+		return errors.NewNotFoundError("Personal token")
+	}
+	return nil
+}
+
+func (r *postgresPersonalTokenRepo) Delete(ctx context.Context, userID, id int) error {
+	startTime := time.Now()
+	result, err := r.db.ExecContext(ctx, `DELETE FROM personal_tokens WHERE id = $1 AND user_id = $2`, id, userID)
+	logger.LogDatabaseOperation(ctx, "DELETE", "personal_tokens", time.Since(startTime), err)
+
+	if err != nil {
+		logger.ErrorContext(ctx, "Error deleting personal token", err)
+		return errors.NewDatabaseError().WithCause(err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return errors.NewDatabaseError().WithCause(err)
+	}
+	if rowsAffected == 0 {
+		return errors.NewNotFoundError("Personal token")
+	}
+	return nil
+}
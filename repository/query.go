@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/clementhaon/sandbox-api-go/clock"
+)
+
+// column is a column name. Tables declare theirs as constants so a misspelt
+// column fails to compile instead of failing at query time.
+type column string
+
+// field pairs a column with the value it is scanned into. A query selects
+// the columns of a field list and scans into its destinations, so the two
+// cannot drift apart.
+type field struct {
+	col  column
+	dest any
+}
+
+// columnList joins the columns of fields, qualified with alias if not empty.
+func columnList(alias string, fields []field) string {
+	names := make([]string, len(fields))
+	for i, f := range fields {
+		if alias != "" {
+			names[i] = alias + "." + string(f.col)
+		} else {
+			names[i] = string(f.col)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// dests returns the scan destinations of fields.
+func dests(fields []field) []any {
+	out := make([]any, len(fields))
+	for i, f := range fields {
+		out[i] = f.dest
+	}
+	return out
+}
+
+// query builds a statement whose positional placeholders are numbered in the
+// order arguments are bound, so clauses can be added conditionally.
+type query struct {
+	text strings.Builder
+	args []any
+}
+
+// bind adds an argument and returns its placeholder.
+func (q *query) bind(v any) string {
+	q.args = append(q.args, v)
+	return "$" + strconv.Itoa(len(q.args))
+}
+
+// add appends clause, replacing each ? with the placeholder of the next
+// argument. It panics if the number of ? and arguments differ.
+func (q *query) add(clause string, args ...any) *query {
+	if strings.Count(clause, "?") != len(args) {
+		panic("repository: placeholder count does not match arguments in " + strconv.Quote(clause))
+	}
+	for _, arg := range args {
+		i := strings.IndexByte(clause, '?')
+		q.text.WriteString(clause[:i])
+		q.text.WriteString(q.bind(arg))
+		clause = clause[i+1:]
+	}
+	q.text.WriteString(clause)
+	return q
+}
+
+// String returns the statement built so far.
+func (q *query) String() string {
+	return q.text.String()
+}
+
+// sandboxTime returns the time on the sandbox clock of ctx, or nil when the
+// clock of ctx is the real time. It is stored next to the real time, never in
+// its place: other users page and clean up on the real timestamps.
+func sandboxTime(ctx context.Context) any {
+	if clock.Offset(ctx) == 0 {
+		return nil
+	}
+	return clock.Now(ctx)
+}
@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestQuery_NumbersPlaceholdersInBindOrder(t *testing.T) {
+	q := &query{}
+	set := "email = " + q.bind("a@example.com")
+	q.add("UPDATE users SET "+set+" WHERE id = ? AND tenant_id = ?", 7, 1)
+
+	want := "UPDATE users SET email = $1 WHERE id = $2 AND tenant_id = $3"
+	if q.String() != want {
+		t.Errorf("got %q, want %q", q.String(), want)
+	}
+	if !reflect.DeepEqual(q.args, []any{"a@example.com", 7, 1}) {
+		t.Errorf("unexpected args %v", q.args)
+	}
+}
+
+func TestQuery_AddPanicsOnArgumentMismatch(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic")
+		}
+	}()
+	(&query{}).add("WHERE id = ? AND tenant_id = ?", 1)
+}
+
+func TestColumnList(t *testing.T) {
+	var id int
+	var name string
+	fields := []field{{userID, &id}, {userUsername, &name}}
+
+	if got := columnList("u", fields); got != "u.id, u.username" {
+		t.Errorf("got %q", got)
+	}
+	if got := columnList("", fields); got != "id, username" {
+		t.Errorf("got %q", got)
+	}
+	if d := dests(fields); d[0] != &id || d[1] != &name {
+		t.Errorf("unexpected destinations %v", d)
+	}
+}
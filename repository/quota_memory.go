@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/database"
+	"github.com/clementhaon/sandbox-api-go/models"
+)
+
+// userQuotaRow is a row of user_quotas.
+type userQuotaRow struct {
+	UserID           int       `json:"user_id"`
+	MaxTasks         *int      `json:"max_tasks"`
+	MaxMediaBytes    *int64    `json:"max_media_bytes"`
+	MaxDailyRequests *int64    `json:"max_daily_requests"`
+	MaxDailyBytes    *int64    `json:"max_daily_bytes"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+type memoryQuotaRepo struct {
+	memoryRepo
+}
+
+// NewMemoryQuotaRepository returns a QuotaRepository keeping its data in
+// store.
+func NewMemoryQuotaRepository(store *MemoryStore) QuotaRepository {
+	return &memoryQuotaRepo{memoryRepo{store: store}}
+}
+
+func (r *memoryQuotaRepo) WithQuerier(q database.Querier) QuotaRepository {
+	return &memoryQuotaRepo{r.bind(q)}
+}
+
+func (r *memoryQuotaRepo) Get(ctx context.Context, userID int) (models.QuotaOverride, error) {
+	override := models.QuotaOverride{UserID: userID}
+	err := r.read(ctx, "SELECT", "user_quotas", func(d *memoryData) error {
+		if q, ok := d.userQuotas.get(userID); ok {
+			override.MaxTasks, override.MaxMediaBytes = clonePtr(q.MaxTasks), clonePtr(q.MaxMediaBytes)
+			override.MaxDailyRequests, override.MaxDailyBytes = clonePtr(q.MaxDailyRequests), clonePtr(q.MaxDailyBytes)
+		}
+		return nil
+	})
+	if err != nil {
+		return models.QuotaOverride{}, err
+	}
+	return override, nil
+}
+
+func (r *memoryQuotaRepo) Upsert(ctx context.Context, override models.QuotaOverride) error {
+	return r.write(ctx, "UPSERT", "user_quotas", func(d *memoryData) error {
+		if _, ok := d.users.get(override.UserID); !ok {
+			return constraintError("insert or update on table user_quotas violates foreign key constraint user_quotas_user_id_fkey")
+		}
+		d.userQuotas.put(userQuotaRow{
+			UserID: override.UserID, MaxTasks: clonePtr(override.MaxTasks), MaxMediaBytes: clonePtr(override.MaxMediaBytes),
+			MaxDailyRequests: clonePtr(override.MaxDailyRequests), MaxDailyBytes: clonePtr(override.MaxDailyBytes),
+			UpdatedAt: memoryNow(),
+		})
+		return nil
+	})
+}
@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/database"
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/logger"
+	"github.com/clementhaon/sandbox-api-go/models"
+)
+
+type QuotaRepository interface {
+	// Get returns the user's overrides; a user without any has nil limits
+	Get(ctx context.Context, userID int) (models.QuotaOverride, error)
+	Upsert(ctx context.Context, override models.QuotaOverride) error
+	WithQuerier(q database.Querier) QuotaRepository
+}
+
+type postgresQuotaRepo struct {
+	db database.Querier
+}
+
+func NewPostgresQuotaRepository(db *sql.DB) QuotaRepository {
+	return &postgresQuotaRepo{db: db}
+}
+
+func (r *postgresQuotaRepo) WithQuerier(q database.Querier) QuotaRepository {
+	return &postgresQuotaRepo{db: q}
+}
+
+func (r *postgresQuotaRepo) Get(ctx context.Context, userID int) (models.QuotaOverride, error) {
+	override := models.QuotaOverride{UserID: userID}
+	var maxTasks sql.NullInt32
+	var maxMediaBytes, maxDailyRequests, maxDailyBytes sql.NullInt64
+
+	startTime := time.Now()
+	err := r.db.QueryRowContext(ctx,
+		`SELECT max_tasks, max_media_bytes, max_daily_requests, max_daily_bytes FROM user_quotas WHERE user_id = $1`, userID,
+	).Scan(&maxTasks, &maxMediaBytes, &maxDailyRequests, &maxDailyBytes)
+	logger.LogDatabaseOperation(ctx, "SELECT", "user_quotas", time.Since(startTime), err)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return override, nil
+	}
+	if err != nil {
+		logger.ErrorContext(ctx, "Error fetching user quota", err)
+		return models.QuotaOverride{}, errors.NewDatabaseError().WithCause(err)
+	}
+
+	if maxTasks.Valid {
+		v := int(maxTasks.Int32)
+		override.MaxTasks = &v
+	}
+	if maxMediaBytes.Valid {
+		override.MaxMediaBytes = &maxMediaBytes.Int64
+	}
+	if maxDailyRequests.Valid {
+		override.MaxDailyRequests = &maxDailyRequests.Int64
+	}
+	if maxDailyBytes.Valid {
+		override.MaxDailyBytes = &maxDailyBytes.Int64
+	}
+	return override, nil
+}
+
+func (r *postgresQuotaRepo) Upsert(ctx context.Context, override models.QuotaOverride) error {
+	startTime := time.Now()
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO user_quotas (user_id, max_tasks, max_media_bytes, max_daily_requests, max_daily_bytes)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id) DO UPDATE
+		SET max_tasks = EXCLUDED.max_tasks, max_media_bytes = EXCLUDED.max_media_bytes,
+			max_daily_requests = EXCLUDED.max_daily_requests, max_daily_bytes = EXCLUDED.max_daily_bytes,
+			updated_at = CURRENT_TIMESTAMP
+	`, override.UserID, override.MaxTasks, override.MaxMediaBytes, override.MaxDailyRequests, override.MaxDailyBytes)
+	logger.LogDatabaseOperation(ctx, "UPSERT", "user_quotas", time.Since(startTime), err)
+
+	if err != nil {
+		logger.ErrorContext(ctx, "Error storing user quota", err)
+		return errors.NewDatabaseError().WithCause(err)
+	}
+	return nil
+}
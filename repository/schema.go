@@ -0,0 +1,37 @@
+package repository
+
+// Schema returns the columns the repositories read and write, by table. It
+// is checked against the database at startup, so that a schema that drifted
+// from the migrations is reported at once rather than failing requests.
+func Schema() map[string][]string {
+	return map[string][]string{
+		"tenants": {"id", "slug", "name", "created_at"},
+		"users": {"id", "tenant_id", "username", "email", "password", "first_name", "last_name", "avatar_url",
+			"is_active", "last_login_at", "role", "preferences", "created_at", "updated_at"},
+		"columns": {"id", "tenant_id", "title", "order", "color", "created_at", "updated_at"},
+		"tasks": {"id", "tenant_id", "title", "description", "completed", "completed_at", "status", "user_id",
+			"column_id", "order", "priority", "assignee_id", "deadline", "estimated_time", "tracked_time", "tags",
+			"created_by", "created_at", "updated_at", "sandbox_created_at"},
+		"task_list_modified": {"tenant_id", "modified_at"},
+		"checklist_items":    {"id", "task_id", "tenant_id", "title", "done", "order", "created_at", "updated_at"},
+		"activities":         {"id", "tenant_id", "user_id", "actor_id", "type", "task_id", "task_title", "created_at"},
+		"time_entries":       {"id", "task_id", "user_id", "start_time", "end_time", "duration", "description", "created_at"},
+		"notifications":      {"id", "user_id", "type", "title", "message", "read", "data", "created_at"},
+		"media": {"id", "user_id", "object_key", "bucket_name", "original_filename", "file_size", "mime_type",
+			"created_at", "updated_at"},
+		"audit_logs": {"id", "tenant_id", "actor_id", "action", "target_type", "target_id", "ip_address", "user_agent",
+			"metadata", "created_at"},
+		"email_changes":           {"user_id", "new_email", "token_hash", "expires_at", "created_at"},
+		"user_quotas":             {"user_id", "max_tasks", "max_media_bytes", "max_daily_requests", "max_daily_bytes", "updated_at"},
+		"announcements":           {"id", "tenant_id", "title", "body", "level", "starts_at", "ends_at", "created_by", "created_at", "updated_at"},
+		"announcement_dismissals": {"announcement_id", "user_id"},
+		"inbound_hooks":           {"user_id", "secret_hash", "mapping", "created_at", "updated_at"},
+		"slack_links":             {"team_id", "slack_user_id", "user_id", "created_at"},
+		"sessions": {"id", "user_id", "token_hash", "revoke_token_hash", "ip_address", "user_agent", "expires_at",
+			"revoked_at", "created_at"},
+		"personal_tokens": {"id", "user_id", "name", "prefix", "token_hash", "scopes", "expires_at", "last_used_at",
+			"created_at", "sandbox"},
+		"api_usage": {"user_id", "token_id", "day", "requests", "bytes_in", "bytes_out"},
+		"webhooks":  {"id", "user_id", "url", "secret", "created_at"},
+	}
+}
@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// TestSchemaMatchesMigrations keeps Schema to tables the migrations create
+// and columns they mention, so that the startup check cannot fail on a
+// migrated database.
+func TestSchemaMatchesMigrations(t *testing.T) {
+	files, err := filepath.Glob("../database/migrations/*.up.sql")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no migrations found: %v", err)
+	}
+	var sql strings.Builder
+	for _, f := range files {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sql.Write(b)
+	}
+	migrations := sql.String()
+
+	for table, columns := range Schema() {
+		if !regexp.MustCompile(`CREATE TABLE (IF NOT EXISTS )?` + table + ` \(`).MatchString(migrations) {
+			t.Errorf("no migration creates table %s", table)
+		}
+		for _, column := range columns {
+			if !regexp.MustCompile(`\b"?` + column + `"?\b`).MatchString(migrations) {
+				t.Errorf("no migration mentions column %s.%s", table, column)
+			}
+		}
+	}
+}
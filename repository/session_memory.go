@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/database"
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/models"
+)
+
+// sessionRow is a row of sessions.
+type sessionRow struct {
+	ID              int               `json:"id"`
+	UserID          int               `json:"user_id"`
+	TokenHash       string            `json:"token_hash"`
+	RevokeTokenHash models.NullString `json:"revoke_token_hash"`
+	IPAddress       string            `json:"ip_address"`
+	UserAgent       string            `json:"user_agent"`
+	ExpiresAt       time.Time         `json:"expires_at"`
+	RevokedAt       *time.Time        `json:"revoked_at"`
+	CreatedAt       time.Time         `json:"created_at"`
+}
+
+type memorySessionRepo struct {
+	memoryRepo
+}
+
+// NewMemorySessionRepository returns a SessionRepository keeping its data
+// in store.
+func NewMemorySessionRepository(store *MemoryStore) SessionRepository {
+	return &memorySessionRepo{memoryRepo{store: store}}
+}
+
+func (r *memorySessionRepo) WithQuerier(q database.Querier) SessionRepository {
+	return &memorySessionRepo{r.bind(q)}
+}
+
+func (r *memorySessionRepo) Create(ctx context.Context, session models.Session) (models.Session, error) {
+	err := r.write(ctx, "INSERT", "sessions", func(d *memoryData) error {
+		if _, ok := d.users.get(session.UserID); !ok {
+			return constraintError("insert or update on table sessions violates foreign key constraint sessions_user_id_fkey")
+		}
+		for _, s := range d.sessions.rows {
+			if s.TokenHash == session.TokenHash {
+				return constraintError("duplicate key value violates unique constraint sessions_token_hash_key")
+			}
+			if s.RevokeTokenHash.Valid && s.RevokeTokenHash.String == session.RevokeTokenHash {
+				return constraintError("duplicate key value violates unique constraint sessions_revoke_token_hash_key")
+			}
+		}
+		session.ID, session.CreatedAt = d.sessions.nextID(), memoryNow()
+		d.sessions.put(sessionRow{
+			ID: session.ID, UserID: session.UserID, TokenHash: session.TokenHash,
+			RevokeTokenHash: nullIfEmpty(session.RevokeTokenHash), IPAddress: session.IPAddress, UserAgent: session.UserAgent,
+			ExpiresAt: session.ExpiresAt.Truncate(time.Microsecond), CreatedAt: session.CreatedAt,
+		})
+		return nil
+	})
+	if err != nil {
+		return models.Session{}, err
+	}
+	return session, nil
+}
+
+func (r *memorySessionRepo) CountByDevice(ctx context.Context, userID int, ipAddress, userAgent string) (int, int, error) {
+	var sessions, fromDevice int
+	err := r.read(ctx, "SELECT COUNT", "sessions", func(d *memoryData) error {
+		for _, s := range d.sessions.rows {
+			if s.UserID == userID {
+				sessions++
+				if s.IPAddress == ipAddress && s.UserAgent == userAgent {
+					fromDevice++
+				}
+			}
+		}
+		return nil
+	})
+	return sessions, fromDevice, err
+}
+
+func (r *memorySessionRepo) GetByRevokeTokenHash(ctx context.Context, hash string) (models.Session, error) {
+	var session models.Session
+	err := r.read(ctx, "SELECT", "sessions", func(d *memoryData) error {
+		for _, s := range d.sessions.rows {
+			if s.RevokeTokenHash.Valid && s.RevokeTokenHash.String == hash {
+				session = models.Session{
+					ID: s.ID, UserID: s.UserID, TokenHash: s.TokenHash, RevokeTokenHash: s.RevokeTokenHash.String,
+					IPAddress: s.IPAddress, UserAgent: s.UserAgent, ExpiresAt: s.ExpiresAt,
+					RevokedAt: clonePtr(s.RevokedAt), CreatedAt: s.CreatedAt,
+				}
+				return nil
+			}
+		}
+		return errors.NewNotFoundError("Session")
+	})
+	return session, err
+}
+
+func (r *memorySessionRepo) Revoke(ctx context.Context, id int) error {
+	return r.write(ctx, "UPDATE", "sessions", func(d *memoryData) error {
+		if s, ok := d.sessions.get(id); ok && s.RevokedAt == nil {
+			now := memoryNow()
+			s.RevokedAt = &now
+			d.sessions.put(s)
+		}
+		return nil
+	})
+}
+
+func (r *memorySessionRepo) DeleteExpired(ctx context.Context, userID int, before time.Time) error {
+	return r.write(ctx, "DELETE", "sessions", func(d *memoryData) error {
+		d.sessions.deleteWhere(func(s sessionRow) bool { return s.UserID == userID && s.ExpiresAt.Before(before) })
+		return nil
+	})
+}
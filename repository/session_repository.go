@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/database"
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/logger"
+	"github.com/clementhaon/sandbox-api-go/models"
+)
+
+type SessionRepository interface {
+	Create(ctx context.Context, session models.Session) (models.Session, error)
+	// CountByDevice returns how many sessions a user has, and how many of
+	// them come from the device of ipAddress and userAgent
+	CountByDevice(ctx context.Context, userID int, ipAddress, userAgent string) (sessions, fromDevice int, err error)
+	GetByRevokeTokenHash(ctx context.Context, hash string) (models.Session, error)
+	// Revoke marks a session revoked, unless it already is
+	Revoke(ctx context.Context, id int) error
+	// DeleteExpired forgets the sessions of a user which expired before
+	// before
+	DeleteExpired(ctx context.Context, userID int, before time.Time) error
+	WithQuerier(q database.Querier) SessionRepository
+}
+
+type postgresSessionRepo struct {
+	db database.Querier
+}
+
+func NewPostgresSessionRepository(db *sql.DB) SessionRepository {
+	return &postgresSessionRepo{db: db}
+}
+
+func (r *postgresSessionRepo) WithQuerier(q database.Querier) SessionRepository {
+	return &postgresSessionRepo{db: q}
+}
+
+func (r *postgresSessionRepo) Create(ctx context.Context, session models.Session) (models.Session, error) {
+	startTime := time.Now()
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO sessions (user_id, token_hash, revoke_token_hash, ip_address, user_agent, expires_at)
+		VALUES ($1, $2, NULLIF($3, ''), $4, $5, $6)
+		RETURNING id, created_at
+	`, session.UserID, session.TokenHash, session.RevokeTokenHash, session.IPAddress, session.UserAgent, session.ExpiresAt,
+	).Scan(&session.ID, &session.CreatedAt)
+	logger.LogDatabaseOperation(ctx, "INSERT", "sessions", time.Since(startTime), err)
+
+	if err != nil {
+		logger.ErrorContext(ctx, "Error creating session", err)
+		return models.Session{}, errors.NewDatabaseError().WithCause(err)
+	}
+	return session, nil
+}
+
+func (r *postgresSessionRepo) CountByDevice(ctx context.Context, userID int, ipAddress, userAgent string) (int, int, error) {
+	var sessions, fromDevice int
+	startTime := time.Now()
+	err := r.db.QueryRowContext(ctx, `
+		SELECT COUNT(*), COUNT(*) FILTER (WHERE ip_address = $2 AND user_agent = $3)
+		FROM sessions WHERE user_id = $1
+	`, userID, ipAddress, userAgent).Scan(&sessions, &fromDevice)
+	logger.LogDatabaseOperation(ctx, "SELECT", "sessions", time.Since(startTime), err)
+
+	if err != nil {
+		logger.ErrorContext(ctx, "Error counting sessions", err)
+		return 0, 0, errors.NewDatabaseError().WithCause(err)
+	}
+	return sessions, fromDevice, nil
+}
+
+func (r *postgresSessionRepo) GetByRevokeTokenHash(ctx context.Context, hash string) (models.Session, error) {
+	var s models.Session
+	var revokeTokenHash sql.NullString
+	var revokedAt sql.NullTime
+	startTime := time.Now()
+	err := r.db.QueryRowContext(ctx, `
+		SELECT id, user_id, token_hash, revoke_token_hash, ip_address, user_agent, expires_at, revoked_at, created_at
+		FROM sessions WHERE revoke_token_hash = $1
+	`, hash).Scan(&s.ID, &s.UserID, &s.TokenHash, &revokeTokenHash, &s.IPAddress, &s.UserAgent, &s.ExpiresAt, &revokedAt, &s.CreatedAt)
+	logger.LogDatabaseOperation(ctx, "SELECT", "sessions", time.Since(startTime), err)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Session{}, errors.NewNotFoundError("Session")
+	}
+	if err != nil {
+		logger.ErrorContext(ctx, "Error fetching session", err)
+		return models.Session{}, errors.NewDatabaseError().WithCause(err)
+	}
+	s.RevokeTokenHash = revokeTokenHash.String
+	if revokedAt.Valid {
+		s.RevokedAt = &revokedAt.Time
+	}
+	return s, nil
+}
+
+func (r *postgresSessionRepo) Revoke(ctx context.Context, id int) error {
+	startTime := time.Now()
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE sessions SET revoked_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND revoked_at IS NULL
+	`, id)
+	logger.LogDatabaseOperation(ctx, "UPDATE", "sessions", time.Since(startTime), err)
+
+	if err != nil {
+		logger.ErrorContext(ctx, "Error revoking session", err)
+		return errors.NewDatabaseError().WithCause(err)
+	}
+	return nil
+}
+
+func (r *postgresSessionRepo) DeleteExpired(ctx context.Context, userID int, before time.Time) error {
+	startTime := time.Now()
+	_, err := r.db.ExecContext(ctx, `DELETE FROM sessions WHERE user_id = $1 AND expires_at < $2`, userID, before)
+	logger.LogDatabaseOperation(ctx, "DELETE", "sessions", time.Since(startTime), err)
+
+	if err != nil {
+		logger.ErrorContext(ctx, "Error deleting expired sessions", err)
+		return errors.NewDatabaseError().WithCause(err)
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/database"
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/models"
+)
+
+// slackLinkRow is a row of slack_links.
+type slackLinkRow struct {
+	TeamID      string    `json:"team_id"`
+	SlackUserID string    `json:"slack_user_id"`
+	UserID      int       `json:"user_id"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func (r slackLinkRow) key() string {
+	return r.TeamID + "/" + r.SlackUserID
+}
+
+type memorySlackLinkRepo struct {
+	memoryRepo
+}
+
+// NewMemorySlackLinkRepository returns a SlackLinkRepository keeping its
+// data in store.
+func NewMemorySlackLinkRepository(store *MemoryStore) SlackLinkRepository {
+	return &memorySlackLinkRepo{memoryRepo{store: store}}
+}
+
+func (r *memorySlackLinkRepo) WithQuerier(q database.Querier) SlackLinkRepository {
+	return &memorySlackLinkRepo{r.bind(q)}
+}
+
+func (r *memorySlackLinkRepo) Link(ctx context.Context, teamID, slackUserID string, userID int) (models.SlackLink, error) {
+	l := slackLinkRow{TeamID: teamID, SlackUserID: slackUserID, UserID: userID}
+	err := r.write(ctx, "UPSERT", "slack_links", func(d *memoryData) error {
+		if _, ok := d.users.get(userID); !ok {
+			return constraintError("insert or update on table slack_links violates foreign key constraint slack_links_user_id_fkey")
+		}
+		l.CreatedAt = memoryNow()
+		d.slackLinks.put(l)
+		return nil
+	})
+	if err != nil {
+		return models.SlackLink{}, err
+	}
+	return models.SlackLink{TeamID: teamID, SlackUserID: slackUserID, UserID: userID, CreatedAt: l.CreatedAt}, nil
+}
+
+func (r *memorySlackLinkRepo) Get(ctx context.Context, teamID, slackUserID string) (models.SlackLink, error) {
+	var link models.SlackLink
+	err := r.read(ctx, "SELECT", "slack_links", func(d *memoryData) error {
+		l, ok := d.slackLinks.get(slackLinkRow{TeamID: teamID, SlackUserID: slackUserID}.key())
+		if !ok {
+			return errors.NewNotFoundError("Slack link")
+		}
+		u, ok := d.users.get(l.UserID)
+		if !ok || !u.IsActive {
+			return errors.NewNotFoundError("Slack link")
+		}
+		link = models.SlackLink{TeamID: teamID, SlackUserID: slackUserID, UserID: l.UserID, TenantID: u.TenantID, CreatedAt: l.CreatedAt}
+		return nil
+	})
+	return link, err
+}
+
+func (r *memorySlackLinkRepo) ListByUser(ctx context.Context, userID int) ([]models.SlackLink, error) {
+	links := []models.SlackLink{}
+	err := r.read(ctx, "SELECT", "slack_links", func(d *memoryData) error {
+		rows := d.slackLinks.filter(func(l slackLinkRow) bool { return l.UserID == userID })
+		slices.SortStableFunc(rows, func(a, b slackLinkRow) int { return a.CreatedAt.Compare(b.CreatedAt) })
+		for _, l := range rows {
+			links = append(links, models.SlackLink{TeamID: l.TeamID, SlackUserID: l.SlackUserID, UserID: l.UserID, CreatedAt: l.CreatedAt})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+func (r *memorySlackLinkRepo) Delete(ctx context.Context, teamID, slackUserID string) error {
+	return r.write(ctx, "DELETE", "slack_links", func(d *memoryData) error {
+		d.slackLinks.delete(slackLinkRow{TeamID: teamID, SlackUserID: slackUserID}.key())
+		return nil
+	})
+}
+
+func (r *memorySlackLinkRepo) DeleteByUser(ctx context.Context, userID int) error {
+	return r.write(ctx, "DELETE", "slack_links", func(d *memoryData) error {
+		if len(d.slackLinks.deleteWhere(func(l slackLinkRow) bool { return l.UserID == userID })) == 0 {
+			return errors.NewNotFoundError("Slack link")
+		}
+		return nil
+	})
+}
@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/database"
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/logger"
+	"github.com/clementhaon/sandbox-api-go/models"
+)
+
+type SlackLinkRepository interface {
+	// Link links a Slack user to userID, replacing any account it was
+	// linked to
+	Link(ctx context.Context, teamID, slackUserID string, userID int) (models.SlackLink, error)
+	// Get returns the link of a Slack user, with the tenant of the account,
+	// in any tenant. Links to inactive users are not found.
+	Get(ctx context.Context, teamID, slackUserID string) (models.SlackLink, error)
+	ListByUser(ctx context.Context, userID int) ([]models.SlackLink, error)
+	Delete(ctx context.Context, teamID, slackUserID string) error
+	// DeleteByUser returns a NotFound error if the user has no link
+	DeleteByUser(ctx context.Context, userID int) error
+	WithQuerier(q database.Querier) SlackLinkRepository
+}
+
+type postgresSlackLinkRepo struct {
+	db database.Querier
+}
+
+func NewPostgresSlackLinkRepository(db *sql.DB) SlackLinkRepository {
+	return &postgresSlackLinkRepo{db: db}
+}
+
+func (r *postgresSlackLinkRepo) WithQuerier(q database.Querier) SlackLinkRepository {
+	return &postgresSlackLinkRepo{db: q}
+}
+
+func (r *postgresSlackLinkRepo) Link(ctx context.Context, teamID, slackUserID string, userID int) (models.SlackLink, error) {
+	l := models.SlackLink{TeamID: teamID, SlackUserID: slackUserID, UserID: userID}
+	startTime := time.Now()
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO slack_links (team_id, slack_user_id, user_id)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (team_id, slack_user_id) DO UPDATE
+		SET user_id = EXCLUDED.user_id, created_at = CURRENT_TIMESTAMP
+		RETURNING created_at
+	`, teamID, slackUserID, userID).Scan(&l.CreatedAt)
+	logger.LogDatabaseOperation(ctx, "UPSERT", "slack_links", time.Since(startTime), err)
+
+	if err != nil {
+		logger.ErrorContext(ctx, "Error linking Slack user", err)
+		return models.SlackLink{}, errors.NewDatabaseError().WithCause(err)
+	}
+	return l, nil
+}
+
+func (r *postgresSlackLinkRepo) Get(ctx context.Context, teamID, slackUserID string) (models.SlackLink, error) {
+	l := models.SlackLink{TeamID: teamID, SlackUserID: slackUserID}
+	startTime := time.Now()
+	err := r.db.QueryRowContext(ctx, `
+		SELECT l.user_id, u.tenant_id, l.created_at
+		FROM slack_links l JOIN users u ON u.id = l.user_id
+		WHERE l.team_id = $1 AND l.slack_user_id = $2 AND u.is_active = true
+	`, teamID, slackUserID).Scan(&l.UserID, &l.TenantID, &l.CreatedAt)
+	logger.LogDatabaseOperation(ctx, "SELECT", "slack_links", time.Since(startTime), err)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.SlackLink{}, errors.NewNotFoundError("Slack link")
+	}
+	if err != nil {
+		logger.ErrorContext(ctx, "Error fetching Slack link", err)
+		return models.SlackLink{}, errors.NewDatabaseError().WithCause(err)
+	}
+	return l, nil
+}
+
+func (r *postgresSlackLinkRepo) ListByUser(ctx context.Context, userID int) ([]models.SlackLink, error) {
+	startTime := time.Now()
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT team_id, slack_user_id, user_id, created_at
+		FROM slack_links WHERE user_id = $1
+		ORDER BY created_at
+	`, userID)
+	logger.LogDatabaseOperation(ctx, "SELECT", "slack_links", time.Since(startTime), err)
+
+	if err != nil {
+		logger.ErrorContext(ctx, "Error listing Slack links", err)
+		return nil, errors.NewDatabaseError().WithCause(err)
+	}
+	defer rows.Close()
+
+	links := []models.SlackLink{}
+	for rows.Next() {
+		var l models.SlackLink
+		if err := rows.Scan(&l.TeamID, &l.SlackUserID, &l.UserID, &l.CreatedAt); err != nil {
+			return nil, errors.NewDatabaseError().WithCause(err)
+		}
+		links = append(links, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.NewDatabaseError().WithCause(err)
+	}
+	return links, nil
+}
+
+func (r *postgresSlackLinkRepo) Delete(ctx context.Context, teamID, slackUserID string) error {
+	startTime := time.Now()
+	_, err := r.db.ExecContext(ctx, `DELETE FROM slack_links WHERE team_id = $1 AND slack_user_id = $2`, teamID, slackUserID)
+	logger.LogDatabaseOperation(ctx, "DELETE", "slack_links", time.Since(startTime), err)
+
+	if err != nil {
+		logger.ErrorContext(ctx, "Error deleting Slack link", err)
+		return errors.NewDatabaseError().WithCause(err)
+	}
+	return nil
+}
+
+func (r *postgresSlackLinkRepo) DeleteByUser(ctx context.Context, userID int) error {
+	startTime := time.Now()
+	result, err := r.db.ExecContext(ctx, `DELETE FROM slack_links WHERE user_id = $1`, userID)
+	logger.LogDatabaseOperation(ctx, "DELETE", "slack_links", time.Since(startTime), err)
+
+	if err != nil {
+		logger.ErrorContext(ctx, "Error deleting Slack links", err)
+		return errors.NewDatabaseError().WithCause(err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return errors.NewDatabaseError().WithCause(err)
+	}
+	if rowsAffected == 0 {
+		return errors.NewNotFoundError("Slack link")
+	}
+	return nil
+}
@@ -0,0 +1,557 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/database"
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/tenant"
+)
+
+// taskRow is a row of tasks.
+type taskRow struct {
+	ID            int        `json:"id"`
+	TenantID      int        `json:"tenant_id"`
+	Title         string     `json:"title"`
+	Description   string     `json:"description"`
+	Completed     bool       `json:"completed"`
+	CompletedAt   *time.Time `json:"completed_at"`
+	Status        string     `json:"status"`
+	UserID        int        `json:"user_id"`
+	ColumnID      int        `json:"column_id"`
+	Order         int        `json:"order"`
+	Priority      string     `json:"priority"`
+	AssigneeID    *int       `json:"assignee_id"`
+	Deadline      *time.Time `json:"deadline"`
+	EstimatedTime int        `json:"estimated_time"`
+	TrackedTime   int        `json:"tracked_time"`
+	Tags          []string   `json:"tags"`
+	CreatedBy     *int       `json:"created_by"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+
+	SandboxCreatedAt *time.Time `json:"sandbox_created_at"`
+}
+
+// taskListModifiedRow is a row of task_list_modified.
+type taskListModifiedRow struct {
+	TenantID   int       `json:"tenant_id"`
+	ModifiedAt time.Time `json:"modified_at"`
+}
+
+// touchTaskList records a change to the tasks of a tenant, as the
+// tasks_touch_task_list_modified trigger does.
+func (d *memoryData) touchTaskList(tenantID int) {
+	d.taskListModified.put(taskListModifiedRow{TenantID: tenantID, ModifiedAt: memoryNow()})
+}
+
+// putTask writes a task that changed, checking its foreign keys.
+func (d *memoryData) putTask(t taskRow) error {
+	if c, ok := d.columns.get(t.ColumnID); !ok || c.TenantID != t.TenantID {
+		return constraintError("insert or update on table tasks violates foreign key constraint tasks_column_id_fkey")
+	}
+	if t.AssigneeID != nil {
+		if _, ok := d.users.get(*t.AssigneeID); !ok {
+			return constraintError("insert or update on table tasks violates foreign key constraint tasks_assignee_id_fkey")
+		}
+	}
+	if !slices.Contains(models.ValidPriorities(), t.Priority) {
+		return constraintError("new row for relation tasks violates check constraint tasks_priority_check")
+	}
+	t.Tags = slices.Clone(t.Tags)
+	d.tasks.put(t)
+	d.touchTaskList(t.TenantID)
+	return nil
+}
+
+// deleteTask deletes a task with the rows that reference it.
+func (d *memoryData) deleteTask(t taskRow) {
+	d.tasks.delete(t.ID)
+	d.checklistItems.deleteWhere(func(c checklistItemRow) bool { return c.TaskID == t.ID })
+	d.timeEntries.deleteWhere(func(e timeEntryRow) bool { return e.TaskID == t.ID })
+	for _, a := range d.activities.filter(func(a activityRow) bool { return a.TaskID != nil && *a.TaskID == t.ID }) {
+		a.TaskID = nil
+		d.activities.put(a)
+	}
+	d.touchTaskList(t.TenantID)
+}
+
+// tenantTask returns task id, if it belongs to the tenant of ctx.
+func (d *memoryData) tenantTask(ctx context.Context, id int) (taskRow, bool) {
+	t, ok := d.tasks.get(id)
+	return t, ok && t.TenantID == tenant.FromContext(ctx)
+}
+
+// task returns t as the task listings return it, with its assignee and
+// checklist.
+func (d *memoryData) task(t taskRow) models.Task {
+	db := models.TaskDB{
+		ID: t.ID, Title: t.Title, Description: t.Description, ColumnID: t.ColumnID, Order: t.Order,
+		Priority: t.Priority, AssigneeID: t.AssigneeID, Deadline: t.Deadline, EstimatedTime: t.EstimatedTime,
+		TrackedTime: t.TrackedTime, Tags: slices.Clone(t.Tags), Status: t.Status, Completed: t.Completed,
+		CompletedAt: t.CompletedAt, CreatedBy: t.CreatedBy, UserID: t.UserID, CreatedAt: t.CreatedAt, UpdatedAt: t.UpdatedAt,
+		SandboxCreatedAt: t.SandboxCreatedAt,
+	}
+	task := db.ToTask()
+
+	var total, done int
+	for _, c := range d.checklistItems.rows {
+		if c.TaskID == t.ID {
+			total++
+			if c.Done {
+				done++
+			}
+		}
+	}
+	task.Checklist = models.NewChecklistSummary(total, done)
+
+	if t.AssigneeID != nil {
+		if u, ok := d.users.get(*t.AssigneeID); ok && u.TenantID == t.TenantID {
+			task.Assignee = &models.UserBrief{ID: u.ID, Username: u.Username}
+			if u.AvatarURL.Valid {
+				task.Assignee.AvatarURL = u.AvatarURL.String
+			}
+		}
+	}
+	return task
+}
+
+func (d *memoryData) taskList(rows []taskRow) []models.Task {
+	tasks := []models.Task{}
+	for _, t := range rows {
+		tasks = append(tasks, d.task(t))
+	}
+	return tasks
+}
+
+type memoryTaskRepo struct {
+	memoryRepo
+}
+
+// NewMemoryTaskRepository returns a TaskRepository keeping its data in
+// store.
+func NewMemoryTaskRepository(store *MemoryStore) TaskRepository {
+	return &memoryTaskRepo{memoryRepo{store: store}}
+}
+
+func (r *memoryTaskRepo) WithQuerier(q database.Querier) TaskRepository {
+	return &memoryTaskRepo{r.bind(q)}
+}
+
+func (r *memoryTaskRepo) ListWithAssignee(ctx context.Context, columnID *int) ([]models.Task, error) {
+	var tasks []models.Task
+	err := r.read(ctx, "SELECT", "tasks", func(d *memoryData) error {
+		tenantID := tenant.FromContext(ctx)
+		rows := d.tasks.filter(func(t taskRow) bool {
+			return t.TenantID == tenantID && (columnID == nil || t.ColumnID == *columnID)
+		})
+		slices.SortStableFunc(rows, func(a, b taskRow) int {
+			return cmp.Or(cmp.Compare(a.ColumnID, b.ColumnID), cmp.Compare(a.Order, b.Order))
+		})
+		tasks = d.taskList(rows)
+		return nil
+	})
+	return tasks, err
+}
+
+func (r *memoryTaskRepo) List(ctx context.Context, params models.TaskListParams) ([]models.Task, error) {
+	tasks := []models.Task{}
+	err := r.Each(ctx, params, func(task models.Task) error {
+		tasks = append(tasks, task)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+func (r *memoryTaskRepo) Each(ctx context.Context, params models.TaskListParams, fn func(models.Task) error) error {
+	var tasks []models.Task
+	err := r.read(ctx, "SELECT", "tasks", func(d *memoryData) error {
+		tenantID := tenant.FromContext(ctx)
+		rows := d.tasks.filter(func(t taskRow) bool {
+			return t.TenantID == tenantID &&
+				(params.ColumnID == nil || t.ColumnID == *params.ColumnID) &&
+				(len(params.Statuses) == 0 || slices.Contains(params.Statuses, t.Status)) &&
+				(params.UpdatedSince == nil || t.UpdatedAt.After(*params.UpdatedSince))
+		})
+
+		compare := map[string]func(a, b taskRow) int{
+			"createdAt": func(a, b taskRow) int { return a.CreatedAt.Compare(b.CreatedAt) },
+			"updatedAt": func(a, b taskRow) int { return a.UpdatedAt.Compare(b.UpdatedAt) },
+		}[params.SortBy]
+		if compare == nil {
+			compare = func(a, b taskRow) int {
+				return cmp.Or(cmp.Compare(a.ColumnID, b.ColumnID), cmp.Compare(a.Order, b.Order))
+			}
+		}
+		desc := strings.EqualFold(params.SortOrder, "DESC")
+		slices.SortFunc(rows, func(a, b taskRow) int {
+			c := cmp.Or(compare(a, b), cmp.Compare(a.ID, b.ID))
+			if desc {
+				return -c
+			}
+			return c
+		})
+		tasks = d.taskList(rows)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, task := range tasks {
+		if err := fn(task); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *memoryTaskRepo) LastModified(ctx context.Context) (models.TaskListModified, error) {
+	modified := models.TaskListModified{Settled: true}
+	err := r.read(ctx, "SELECT", "task_list_modified", func(d *memoryData) error {
+		if row, ok := d.taskListModified.get(tenant.FromContext(ctx)); ok {
+			modified = models.TaskListModified{
+				At:      row.ModifiedAt,
+				Settled: row.ModifiedAt.Before(time.Now().Truncate(time.Second)),
+			}
+		}
+		return nil
+	})
+	return modified, err
+}
+
+func (r *memoryTaskRepo) ListChangedAfter(ctx context.Context, after *models.TaskCursor, limit int) ([]models.Task, error) {
+	var tasks []models.Task
+	err := r.read(ctx, "SELECT", "tasks", func(d *memoryData) error {
+		tenantID := tenant.FromContext(ctx)
+		rows := d.tasks.filter(func(t taskRow) bool {
+			return t.TenantID == tenantID && (after == nil ||
+				cmp.Or(t.UpdatedAt.Compare(after.UpdatedAt), cmp.Compare(t.ID, after.ID)) > 0)
+		})
+		slices.SortFunc(rows, func(a, b taskRow) int {
+			return cmp.Or(a.UpdatedAt.Compare(b.UpdatedAt), cmp.Compare(a.ID, b.ID))
+		})
+		tasks = d.taskList(pageOf(rows, limit, 0))
+		return nil
+	})
+	return tasks, err
+}
+
+func (r *memoryTaskRepo) ListByUser(ctx context.Context, userID int) ([]models.Task, error) {
+	var tasks []models.Task
+	err := r.read(ctx, "SELECT", "tasks", func(d *memoryData) error {
+		tenantID := tenant.FromContext(ctx)
+		rows := d.tasks.filter(func(t taskRow) bool { return t.UserID == userID && t.TenantID == tenantID })
+		slices.SortStableFunc(rows, func(a, b taskRow) int { return a.CreatedAt.Compare(b.CreatedAt) })
+		tasks = d.taskList(rows)
+		return nil
+	})
+	return tasks, err
+}
+
+func (r *memoryTaskRepo) GetByID(ctx context.Context, id int) (models.Task, error) {
+	var task models.Task
+	err := r.read(ctx, "SELECT", "tasks", func(d *memoryData) error {
+		t, ok := d.tenantTask(ctx, id)
+		if !ok {
+			return errors.NewNotFoundError("Task not found")
+		}
+		task = d.task(t)
+		return nil
+	})
+	return task, err
+}
+
+func (r *memoryTaskRepo) GetByIDs(ctx context.Context, ids []int) ([]models.Task, error) {
+	var tasks []models.Task
+	err := r.read(ctx, "SELECT", "tasks", func(d *memoryData) error {
+		tenantID := tenant.FromContext(ctx)
+		rows := d.tasks.filter(func(t taskRow) bool { return slices.Contains(ids, t.ID) && t.TenantID == tenantID })
+		tasks = d.taskList(rows)
+		return nil
+	})
+	return tasks, err
+}
+
+func (r *memoryTaskRepo) GetMaxOrder(ctx context.Context, columnID int) (int, error) {
+	maxOrder := -1
+	err := r.read(ctx, "SELECT MAX", "tasks", func(d *memoryData) error {
+		tenantID := tenant.FromContext(ctx)
+		for _, t := range d.tasks.rows {
+			if t.ColumnID == columnID && t.TenantID == tenantID {
+				maxOrder = max(maxOrder, t.Order)
+			}
+		}
+		return nil
+	})
+	return maxOrder, err
+}
+
+func (r *memoryTaskRepo) Create(ctx context.Context, req models.CreateTaskRequest, order int, userID int) (models.Task, error) {
+	var task models.Task
+	err := r.write(ctx, "INSERT", "tasks", func(d *memoryData) error {
+		if _, ok := d.users.get(userID); !ok {
+			return constraintError("insert or update on table tasks violates foreign key constraint tasks_user_id_fkey")
+		}
+		now := memoryNow()
+		t := taskRow{
+			TenantID: tenant.FromContext(ctx), Title: req.Title, Description: req.Description, Status: models.TaskStatusTodo,
+			UserID: userID, ColumnID: req.ColumnID, Order: order, Priority: req.Priority, AssigneeID: req.AssigneeID,
+			Deadline: req.Deadline, EstimatedTime: req.EstimatedTime, Tags: req.Tags, CreatedBy: &userID,
+			CreatedAt: now, UpdatedAt: now, SandboxCreatedAt: memorySandboxTime(ctx),
+		}
+		if t.Tags == nil {
+			t.Tags = []string{}
+		}
+		t.ID = d.tasks.nextID()
+		if err := d.putTask(t); err != nil {
+			return err
+		}
+		task = d.task(t)
+		return nil
+	})
+	return task, err
+}
+
+func (r *memoryTaskRepo) Exists(ctx context.Context, id int) (bool, error) {
+	var exists bool
+	err := r.read(ctx, "SELECT", "tasks", func(d *memoryData) error {
+		_, exists = d.tenantTask(ctx, id)
+		return nil
+	})
+	return exists, err
+}
+
+func (r *memoryTaskRepo) CountByUser(ctx context.Context, userID int) (int, error) {
+	var count int
+	err := r.read(ctx, "SELECT COUNT", "tasks", func(d *memoryData) error {
+		tenantID := tenant.FromContext(ctx)
+		for _, t := range d.tasks.rows {
+			if t.UserID == userID && t.TenantID == tenantID {
+				count++
+			}
+		}
+		return nil
+	})
+	return count, err
+}
+
+func (r *memoryTaskRepo) DeleteCompletedBefore(ctx context.Context, before time.Time) (int, error) {
+	var count int
+	err := r.write(ctx, "DELETE", "tasks", func(d *memoryData) error {
+		tenantID := tenant.FromContext(ctx)
+		for _, t := range d.tasks.filter(func(t taskRow) bool {
+			return t.Completed && t.UpdatedAt.Before(before) && t.TenantID == tenantID
+		}) {
+			d.deleteTask(t)
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+func (r *memoryTaskRepo) DeleteByUser(ctx context.Context, userID int) (int, error) {
+	var count int
+	err := r.write(ctx, "DELETE", "tasks", func(d *memoryData) error {
+		tenantID := tenant.FromContext(ctx)
+		for _, t := range d.tasks.filter(func(t taskRow) bool {
+			return t.UserID == userID && t.TenantID == tenantID
+		}) {
+			d.deleteTask(t)
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+func (r *memoryTaskRepo) CountByStatus(ctx context.Context) (map[string]int, error) {
+	return r.countByStatus(ctx, func(taskRow) bool { return true })
+}
+
+func (r *memoryTaskRepo) CountByStatusInTenant(ctx context.Context) (map[string]int, error) {
+	tenantID := tenant.FromContext(ctx)
+	return r.countByStatus(ctx, func(t taskRow) bool { return t.TenantID == tenantID })
+}
+
+// countByStatus counts the tasks matching keep in a map holding every
+// status.
+func (r *memoryTaskRepo) countByStatus(ctx context.Context, keep func(taskRow) bool) (map[string]int, error) {
+	counts := make(map[string]int)
+	for _, status := range models.ValidTaskStatuses() {
+		counts[status] = 0
+	}
+	err := r.read(ctx, "SELECT COUNT", "tasks", func(d *memoryData) error {
+		for _, t := range d.tasks.rows {
+			if keep(t) {
+				counts[t.Status]++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+func (r *memoryTaskRepo) Update(ctx context.Context, id int, req models.UpdateTaskRequest) (models.Task, error) {
+	var task models.Task
+	err := r.write(ctx, "UPDATE", "tasks", func(d *memoryData) error {
+		t, ok := d.tenantTask(ctx, id)
+		if !ok {
+			return errors.NewNotFoundError("Task not found")
+		}
+		if req.Title != "" {
+			t.Title = req.Title
+		}
+		t.Description = req.Description
+		if req.ColumnID > 0 {
+			t.ColumnID = req.ColumnID
+		}
+		if req.Priority != "" {
+			t.Priority = req.Priority
+		}
+		t.AssigneeID, t.Deadline = req.AssigneeID, req.Deadline
+		if req.EstimatedTime > 0 {
+			t.EstimatedTime = req.EstimatedTime
+		}
+		if req.Tags != nil {
+			t.Tags = req.Tags
+		}
+		t.UpdatedAt = memoryNow()
+		if err := d.putTask(t); err != nil {
+			return err
+		}
+		task = d.task(t)
+		return nil
+	})
+	return task, err
+}
+
+func (r *memoryTaskRepo) Move(ctx context.Context, id int, columnID int, order int) (models.Task, error) {
+	var task models.Task
+	err := r.write(ctx, "UPDATE", "tasks", func(d *memoryData) error {
+		var err error
+		task, err = d.moveTask(ctx, id, columnID, order)
+		return err
+	})
+	return task, err
+}
+
+func (d *memoryData) moveTask(ctx context.Context, id int, columnID int, order int) (models.Task, error) {
+	t, ok := d.tenantTask(ctx, id)
+	if !ok {
+		return models.Task{}, errors.NewNotFoundError("Task not found")
+	}
+	t.ColumnID, t.Order, t.UpdatedAt = columnID, order, memoryNow()
+	if err := d.putTask(t); err != nil {
+		return models.Task{}, err
+	}
+	return d.task(t), nil
+}
+
+func (r *memoryTaskRepo) SetStatus(ctx context.Context, id int, status string, from []string) (models.Task, error) {
+	var task models.Task
+	err := r.write(ctx, "UPDATE", "tasks", func(d *memoryData) error {
+		t, ok := d.tenantTask(ctx, id)
+		if !ok || !slices.Contains(from, t.Status) {
+			return errors.NewNotFoundError("Task not found")
+		}
+		now := memoryNow()
+		t.Status, t.Completed, t.CompletedAt, t.UpdatedAt = status, status == models.TaskStatusDone, nil, now
+		if t.Completed {
+			t.CompletedAt = &now
+		}
+		if err := d.putTask(t); err != nil {
+			return err
+		}
+		task = d.task(t)
+		return nil
+	})
+	return task, err
+}
+
+func (r *memoryTaskRepo) Reorder(ctx context.Context, columnID int, taskIDs []int) error {
+	return r.write(ctx, "UPDATE", "tasks", func(d *memoryData) error {
+		for _, taskID := range taskIDs {
+			if t, ok := d.tenantTask(ctx, taskID); !ok || t.ColumnID != columnID {
+				return errors.NewNotFoundError("Task not found in column: " + strconv.Itoa(taskID))
+			}
+		}
+		now := memoryNow()
+		for i, taskID := range taskIDs {
+			t, _ := d.tasks.get(taskID)
+			t.Order, t.UpdatedAt = (i+1)*TaskOrderGap, now
+			d.tasks.put(t)
+			d.touchTaskList(t.TenantID)
+		}
+		return nil
+	})
+}
+
+func (r *memoryTaskRepo) MoveRelative(ctx context.Context, id int, columnID int, anchorID int, after bool) (models.Task, error) {
+	var task models.Task
+	err := r.write(ctx, "UPDATE", "tasks", func(d *memoryData) error {
+		if _, ok := d.tenantTask(ctx, id); !ok {
+			return errors.NewNotFoundError("Task not found")
+		}
+		tenantID := tenant.FromContext(ctx)
+		rows := d.tasks.filter(func(t taskRow) bool {
+			return t.ColumnID == columnID && t.TenantID == tenantID && t.ID != id
+		})
+		slices.SortStableFunc(rows, func(a, b taskRow) int { return cmp.Compare(a.Order, b.Order) })
+		tasks := make([]orderedTask, len(rows))
+		for i, t := range rows {
+			tasks[i] = orderedTask{id: t.ID, order: t.Order}
+		}
+
+		i := slices.IndexFunc(tasks, func(t orderedTask) bool { return t.id == anchorID })
+		if i < 0 {
+			return errors.NewNotFoundError("Task not found in column: " + strconv.Itoa(anchorID))
+		}
+		if after {
+			i++
+		}
+
+		order, ok := insertionOrder(tasks, i)
+		if !ok {
+			now := memoryNow()
+			for j, t := range rows {
+				slot := j
+				if j >= i {
+					slot++
+				}
+				t.Order, t.UpdatedAt = (slot+1)*TaskOrderGap, now
+				d.tasks.put(t)
+			}
+			order = (i + 1) * TaskOrderGap
+		}
+
+		var err error
+		task, err = d.moveTask(ctx, id, columnID, order)
+		return err
+	})
+	return task, err
+}
+
+func (r *memoryTaskRepo) Delete(ctx context.Context, id int) error {
+	return r.write(ctx, "DELETE", "tasks", func(d *memoryData) error {
+		t, ok := d.tenantTask(ctx, id)
+		if !ok {
+			return errors.NewNotFoundError("Task not found")
+		}
+		d.deleteTask(t)
+		return nil
+	})
+}
@@ -0,0 +1,40 @@
+package repository
+
+// TaskOrderGap spaces the "order" of consecutive tasks in a column, so a
+// task can be moved between two others by giving it the order halfway
+// between theirs, without renumbering the column.
+const TaskOrderGap = 1024
+
+// orderBetween returns an order strictly between prev and next. hasPrev and
+// hasNext tell whether there is a task on either side; the top of a column
+// is bounded by 0. It returns false when the neighbours are too close and
+// the column must be renumbered first.
+func orderBetween(prev int, hasPrev bool, next int, hasNext bool) (int, bool) {
+	if !hasPrev {
+		prev = 0
+	}
+	if !hasNext {
+		return prev + TaskOrderGap, true
+	}
+	mid := prev + (next-prev)/2
+	return mid, prev < mid && mid < next
+}
+
+// orderedTask is a task's place in its column.
+type orderedTask struct {
+	id    int
+	order int
+}
+
+// insertionOrder returns the order for a task inserted at index i of the
+// column tasks, which are sorted by order and exclude the inserted task.
+func insertionOrder(tasks []orderedTask, i int) (int, bool) {
+	var prev, next int
+	if i > 0 {
+		prev = tasks[i-1].order
+	}
+	if i < len(tasks) {
+		next = tasks[i].order
+	}
+	return orderBetween(prev, i > 0, next, i < len(tasks))
+}
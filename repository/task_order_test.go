@@ -0,0 +1,30 @@
+package repository
+
+import "testing"
+
+func TestInsertionOrder(t *testing.T) {
+	column := []orderedTask{{1, 1024}, {2, 2048}, {3, 2049}}
+
+	tests := []struct {
+		name   string
+		tasks  []orderedTask
+		index  int
+		want   int
+		wantOK bool
+	}{
+		{"empty column", nil, 0, TaskOrderGap, true},
+		{"top", column, 0, 512, true},
+		{"between", column, 1, 1536, true},
+		{"bottom", column, 3, 2049 + TaskOrderGap, true},
+		{"no room between", column, 2, 0, false},
+		{"no room at the top", []orderedTask{{1, 1}}, 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := insertionOrder(tt.tasks, tt.index)
+			if ok != tt.wantOK || (ok && got != tt.want) {
+				t.Errorf("insertionOrder(%d) = %d, %v, want %d, %v", tt.index, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
@@ -3,27 +3,57 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/clementhaon/sandbox-api-go/database"
 	"github.com/clementhaon/sandbox-api-go/errors"
 	"github.com/clementhaon/sandbox-api-go/logger"
 	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/tenant"
 
 	"github.com/lib/pq"
 )
 
 type TaskRepository interface {
 	ListWithAssignee(ctx context.Context, columnID *int) ([]models.Task, error)
+	List(ctx context.Context, params models.TaskListParams) ([]models.Task, error)
+	// Each calls fn for the tasks List returns, one row at a time, and
+	// stops at the first error fn returns. The query holds a connection
+	// until it is done.
+	Each(ctx context.Context, params models.TaskListParams, fn func(models.Task) error) error
+	ListChangedAfter(ctx context.Context, after *models.TaskCursor, limit int) ([]models.Task, error)
+	LastModified(ctx context.Context) (models.TaskListModified, error)
+	ListByUser(ctx context.Context, userID int) ([]models.Task, error)
 	GetByID(ctx context.Context, id int) (models.Task, error)
+	// GetByIDs returns the tasks of ids that exist, in one query, in no
+	// particular order.
+	GetByIDs(ctx context.Context, ids []int) ([]models.Task, error)
 	GetMaxOrder(ctx context.Context, columnID int) (int, error)
 	Create(ctx context.Context, req models.CreateTaskRequest, order int, userID int) (models.Task, error)
 	Exists(ctx context.Context, id int) (bool, error)
 	Update(ctx context.Context, id int, req models.UpdateTaskRequest) (models.Task, error)
 	Move(ctx context.Context, id int, columnID int, order int) (models.Task, error)
+	// SetStatus moves a task to status if its current status is one of from,
+	// completing it when status is done. It returns a NotFound error when the
+	// task does not exist or is in another status.
+	SetStatus(ctx context.Context, id int, status string, from []string) (models.Task, error)
+	// MoveRelative moves a task into columnID right after the anchor task,
+	// or right before it when after is false.
+	MoveRelative(ctx context.Context, id int, columnID int, anchorID int, after bool) (models.Task, error)
 	Reorder(ctx context.Context, columnID int, taskIDs []int) error
 	Delete(ctx context.Context, id int) error
+	CountByStatus(ctx context.Context) (map[string]int, error)
+	// CountByStatusInTenant is CountByStatus for the tasks of the tenant.
+	CountByStatusInTenant(ctx context.Context) (map[string]int, error)
+	CountByUser(ctx context.Context, userID int) (int, error)
+	DeleteCompletedBefore(ctx context.Context, before time.Time) (int, error)
+	// DeleteByUser deletes the tasks userID created in the tenant, with
+	// their checklists and time entries, and returns how many there were
+	DeleteByUser(ctx context.Context, userID int) (int, error)
 	WithQuerier(q database.Querier) TaskRepository
 }
 
@@ -39,29 +69,83 @@ func (r *postgresTaskRepo) WithQuerier(q database.Querier) TaskRepository {
 	return &postgresTaskRepo{db: q}
 }
 
+const (
+	taskID            column = "id"
+	taskTitle         column = "title"
+	taskDescription   column = "description"
+	taskColumnID      column = "column_id"
+	taskOrder         column = `"order"`
+	taskPriority      column = "priority"
+	taskAssigneeID    column = "assignee_id"
+	taskDeadline      column = "deadline"
+	taskEstimatedTime column = "estimated_time"
+	taskTrackedTime   column = "tracked_time"
+	taskTags          column = "tags"
+	taskStatus        column = "status"
+	taskCompleted     column = "completed"
+	taskCompletedAt   column = "completed_at"
+	taskCreatedBy     column = "created_by"
+	taskUserID        column = "user_id"
+	taskCreatedAt     column = "created_at"
+	taskUpdatedAt     column = "updated_at"
+
+	taskSandboxCreatedAt column = "sandbox_created_at"
+)
+
+// taskFields lists the columns returned for a task, scanned into t.
+func taskFields(t *models.TaskDB) []field {
+	return []field{
+		{taskID, &t.ID}, {taskTitle, &t.Title}, {taskDescription, &t.Description},
+		{taskColumnID, &t.ColumnID}, {taskOrder, &t.Order}, {taskPriority, &t.Priority},
+		{taskAssigneeID, &t.AssigneeID}, {taskDeadline, &t.Deadline}, {taskEstimatedTime, &t.EstimatedTime},
+		{taskTrackedTime, &t.TrackedTime}, {taskTags, &t.Tags}, {taskStatus, &t.Status}, {taskCompleted, &t.Completed},
+		{taskCompletedAt, &t.CompletedAt}, {taskCreatedBy, &t.CreatedBy}, {taskUserID, &t.UserID},
+		{taskCreatedAt, &t.CreatedAt}, {taskUpdatedAt, &t.UpdatedAt}, {taskSandboxCreatedAt, &t.SandboxCreatedAt},
+	}
+}
+
+// assigneeRow holds the user joined to a task; it is all NULL when the task
+// is unassigned.
+type assigneeRow struct {
+	id        sql.NullInt64
+	username  sql.NullString
+	avatarURL sql.NullString
+}
+
+func assigneeFields(a *assigneeRow) []field {
+	return []field{{userID, &a.id}, {userUsername, &a.username}, {userAvatarURL, &a.avatarURL}}
+}
+
+// checklistCounts holds the size and progress of the checklist joined to a
+// task.
+type checklistCounts struct {
+	total int
+	done  int
+}
+
+func checklistCountFields(c *checklistCounts) []field {
+	return []field{{"total", &c.total}, {"done", &c.done}}
+}
+
 func scanTaskRow(row interface{ Scan(...any) error }) (models.Task, error) {
 	var t models.TaskDB
-	var assigneeID sql.NullInt64
-	var assigneeUsername, assigneeAvatarURL sql.NullString
-
-	err := row.Scan(
-		&t.ID, &t.Title, &t.Description, &t.ColumnID, &t.Order, &t.Priority,
-		&t.AssigneeID, &t.Deadline, &t.EstimatedTime, &t.TrackedTime, &t.Tags,
-		&t.CreatedBy, &t.UserID, &t.CreatedAt, &t.UpdatedAt,
-		&assigneeID, &assigneeUsername, &assigneeAvatarURL,
-	)
-	if err != nil {
+	var assignee assigneeRow
+	var checklist checklistCounts
+
+	fields := append(taskFields(&t), assigneeFields(&assignee)...)
+	if err := row.Scan(dests(append(fields, checklistCountFields(&checklist)...))...); err != nil {
 		return models.Task{}, err
 	}
 
 	task := t.ToTask()
-	if assigneeID.Valid {
+	task.Checklist = models.NewChecklistSummary(checklist.total, checklist.done)
+	if assignee.id.Valid {
 		task.Assignee = &models.UserBrief{
-			ID:       int(assigneeID.Int64),
-			Username: assigneeUsername.String,
+			ID:       int(assignee.id.Int64),
+			Username: assignee.username.String,
 		}
-		if assigneeAvatarURL.Valid {
-			task.Assignee.AvatarURL = assigneeAvatarURL.String
+		if assignee.avatarURL.Valid {
+			task.Assignee.AvatarURL = assignee.avatarURL.String
 		}
 	}
 	return task, nil
@@ -80,27 +164,36 @@ func scanTaskRows(ctx context.Context, rows *sql.Rows) ([]models.Task, error) {
 	return tasks, nil
 }
 
-const taskSelectWithAssignee = `
-	SELECT t.id, t.title, t.description, t.column_id, t."order", t.priority,
-		t.assignee_id, t.deadline, t.estimated_time, t.tracked_time, t.tags,
-		t.created_by, t.user_id, t.created_at, t.updated_at,
-		u.id, u.username, u.avatar_url
-	FROM tasks t
-	LEFT JOIN users u ON t.assignee_id = u.id`
+// selectTaskWithAssignee selects the tasks of source, aliased t, joined to
+// their assignee and checklist counts. source is a table or a CTE returning
+// task rows.
+func selectTaskWithAssignee(source string) string {
+	return `SELECT ` + columnList("t", taskFields(new(models.TaskDB))) + `, ` + columnList("u", assigneeFields(new(assigneeRow))) +
+		`, ` + columnList("cl", checklistCountFields(new(checklistCounts))) + `
+	FROM ` + source + ` t
+	LEFT JOIN users u ON t.assignee_id = u.id AND u.tenant_id = t.tenant_id
+	LEFT JOIN LATERAL (
+		SELECT COUNT(*) AS total, COUNT(*) FILTER (WHERE done) AS done
+		FROM checklist_items WHERE task_id = t.id
+	) cl ON true`
+}
+
+// taskSelectWithAssignee is the base of every task listing. Related data is
+// joined here so a list is always one query: tags live on the row, and counts
+// over related tables belong in a LEFT JOIN LATERAL aggregate rather than a
+// lookup per task. e2e/task_query_plan_test.go guards this.
+var taskSelectWithAssignee = selectTaskWithAssignee("tasks")
 
 func (r *postgresTaskRepo) ListWithAssignee(ctx context.Context, columnID *int) ([]models.Task, error) {
-	var query string
-	var args []interface{}
-
+	q := (&query{}).add(taskSelectWithAssignee+` WHERE t.tenant_id = ?`, tenant.FromContext(ctx))
 	if columnID != nil {
-		query = taskSelectWithAssignee + ` WHERE t.column_id = $1 ORDER BY t."order" ASC`
-		args = append(args, *columnID)
+		q.add(` AND t.column_id = ? ORDER BY t."order" ASC`, *columnID)
 	} else {
-		query = taskSelectWithAssignee + ` ORDER BY t.column_id, t."order" ASC`
+		q.add(` ORDER BY t.column_id, t."order" ASC`)
 	}
 
 	startTime := time.Now()
-	rows, err := r.db.QueryContext(ctx, query, args...)
+	rows, err := r.db.QueryContext(ctx, q.String(), q.args...)
 	logger.LogDatabaseOperation(ctx, "SELECT", "tasks", time.Since(startTime), err)
 	if err != nil {
 		logger.ErrorContext(ctx, "Error querying tasks", err)
@@ -111,12 +204,138 @@ func (r *postgresTaskRepo) ListWithAssignee(ctx context.Context, columnID *int)
 	return scanTaskRows(ctx, rows)
 }
 
-func (r *postgresTaskRepo) GetByID(ctx context.Context, id int) (models.Task, error) {
+// List returns tasks filtered by column and modification time. Unknown sort
+// fields fall back to board order (column, then position).
+func (r *postgresTaskRepo) List(ctx context.Context, params models.TaskListParams) ([]models.Task, error) {
+	tasks := []models.Task{}
+	err := r.Each(ctx, params, func(task models.Task) error {
+		tasks = append(tasks, task)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+func (r *postgresTaskRepo) Each(ctx context.Context, params models.TaskListParams, fn func(models.Task) error) error {
+	validSortFields := map[string]string{
+		"order":     `t.column_id, t."order"`,
+		"createdAt": "t.created_at",
+		"updatedAt": "t.updated_at",
+	}
+	sortField, ok := validSortFields[params.SortBy]
+	if !ok {
+		sortField = validSortFields["order"]
+	}
+
+	sortOrder := strings.ToUpper(params.SortOrder)
+	if sortOrder != "ASC" && sortOrder != "DESC" {
+		sortOrder = "ASC"
+	}
+
+	q := (&query{}).add(taskSelectWithAssignee+` WHERE t.tenant_id = ?`, tenant.FromContext(ctx))
+	if params.ColumnID != nil {
+		q.add(` AND t.column_id = ?`, *params.ColumnID)
+	}
+	if len(params.Statuses) > 0 {
+		q.add(` AND t.status = ANY(?)`, pq.Array(params.Statuses))
+	}
+	if params.UpdatedSince != nil {
+		q.add(` AND t.updated_at > ?`, *params.UpdatedSince)
+	}
+
+	// Every sort key applies the direction; id keeps ties stable
+	orderBy := strings.ReplaceAll(sortField, ",", " "+sortOrder+",")
+	q.add(fmt.Sprintf(` ORDER BY %s %s, t.id %s`, orderBy, sortOrder, sortOrder))
+
 	startTime := time.Now()
-	task, err := scanTaskRow(r.db.QueryRowContext(ctx, taskSelectWithAssignee+` WHERE t.id = $1`, id))
+	rows, err := r.db.QueryContext(ctx, q.String(), q.args...)
 	logger.LogDatabaseOperation(ctx, "SELECT", "tasks", time.Since(startTime), err)
+	if err != nil {
+		logger.ErrorContext(ctx, "Error querying tasks", err)
+		return errors.NewDatabaseError().WithCause(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		task, err := scanTaskRow(rows)
+		if err != nil {
+			logger.ErrorContext(ctx, "Error scanning task row", err)
+			return errors.NewDatabaseError().WithCause(err)
+		}
+		if err := fn(task); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		logger.ErrorContext(ctx, "Error reading task rows", err)
+		return errors.NewDatabaseError().WithCause(err)
+	}
+	return nil
+}
+
+// LastModified returns when a task of the tenant was last created, updated
+// or deleted, as recorded by the tasks_touch_task_list_modified trigger.
+func (r *postgresTaskRepo) LastModified(ctx context.Context) (models.TaskListModified, error) {
+	var modified models.TaskListModified
+	startTime := time.Now()
+	err := r.db.QueryRowContext(ctx,
+		`SELECT modified_at, modified_at < date_trunc('second', clock_timestamp())
+		 FROM task_list_modified WHERE tenant_id = $1`,
+		tenant.FromContext(ctx)).Scan(&modified.At, &modified.Settled)
+	logger.LogDatabaseOperation(ctx, "SELECT", "task_list_modified", time.Since(startTime), err)
 
 	if err == sql.ErrNoRows {
+		return models.TaskListModified{Settled: true}, nil
+	}
+	if err != nil {
+		logger.ErrorContext(ctx, "Error reading task list modification time", err)
+		return models.TaskListModified{}, errors.NewDatabaseError().WithCause(err)
+	}
+	return modified, nil
+}
+
+// ListChangedAfter returns up to limit tasks in (updated_at, id) order that
+// come after the cursor, or from the start if after is nil.
+func (r *postgresTaskRepo) ListChangedAfter(ctx context.Context, after *models.TaskCursor, limit int) ([]models.Task, error) {
+	q := (&query{}).add(taskSelectWithAssignee+` WHERE t.tenant_id = ?`, tenant.FromContext(ctx))
+	if after != nil {
+		q.add(` AND (t.updated_at, t.id) > (?, ?)`, after.UpdatedAt, after.ID)
+	}
+	q.add(` ORDER BY t.updated_at, t.id LIMIT ?`, limit)
+
+	startTime := time.Now()
+	rows, err := r.db.QueryContext(ctx, q.String(), q.args...)
+	logger.LogDatabaseOperation(ctx, "SELECT", "tasks", time.Since(startTime), err)
+	if err != nil {
+		logger.ErrorContext(ctx, "Error querying task changes", err)
+		return nil, errors.NewDatabaseError().WithCause(err)
+	}
+	defer rows.Close()
+
+	return scanTaskRows(ctx, rows)
+}
+
+func (r *postgresTaskRepo) ListByUser(ctx context.Context, userID int) ([]models.Task, error) {
+	startTime := time.Now()
+	rows, err := r.db.QueryContext(ctx, taskSelectWithAssignee+` WHERE t.user_id = $1 AND t.tenant_id = $2 ORDER BY t.created_at ASC`, userID, tenant.FromContext(ctx))
+	logger.LogDatabaseOperation(ctx, "SELECT", "tasks", time.Since(startTime), err)
+	if err != nil {
+		logger.ErrorContext(ctx, "Error querying user tasks", err)
+		return nil, errors.NewDatabaseError().WithCause(err)
+	}
+	defer rows.Close()
+
+	return scanTaskRows(ctx, rows)
+}
+
+func (r *postgresTaskRepo) GetByID(ctx context.Context, id int) (models.Task, error) {
+	startTime := time.Now()
+	task, err := scanTaskRow(r.db.QueryRowContext(ctx, taskSelectWithAssignee+` WHERE t.id = $1 AND t.tenant_id = $2`, id, tenant.FromContext(ctx)))
+	logger.LogDatabaseOperation(ctx, "SELECT", "tasks", time.Since(startTime), err)
+
+	if errors.Is(err, sql.ErrNoRows) {
 		return models.Task{}, errors.NewNotFoundError("Task not found")
 	}
 	if err != nil {
@@ -126,10 +345,23 @@ func (r *postgresTaskRepo) GetByID(ctx context.Context, id int) (models.Task, er
 	return task, nil
 }
 
+func (r *postgresTaskRepo) GetByIDs(ctx context.Context, ids []int) ([]models.Task, error) {
+	startTime := time.Now()
+	rows, err := r.db.QueryContext(ctx, taskSelectWithAssignee+` WHERE t.id = ANY($1) AND t.tenant_id = $2`, pq.Array(ids), tenant.FromContext(ctx))
+	logger.LogDatabaseOperation(ctx, "SELECT", "tasks", time.Since(startTime), err)
+	if err != nil {
+		logger.ErrorContext(ctx, "Error fetching tasks", err)
+		return nil, errors.NewDatabaseError().WithCause(err)
+	}
+	defer rows.Close()
+
+	return scanTaskRows(ctx, rows)
+}
+
 func (r *postgresTaskRepo) GetMaxOrder(ctx context.Context, columnID int) (int, error) {
 	var maxOrder int
 	startTime := time.Now()
-	err := r.db.QueryRowContext(ctx, `SELECT COALESCE(MAX("order"), -1) FROM tasks WHERE column_id = $1`, columnID).Scan(&maxOrder)
+	err := r.db.QueryRowContext(ctx, `SELECT COALESCE(MAX("order"), -1) FROM tasks WHERE column_id = $1 AND tenant_id = $2`, columnID, tenant.FromContext(ctx)).Scan(&maxOrder)
 	logger.LogDatabaseOperation(ctx, "SELECT MAX", "tasks", time.Since(startTime), err)
 	if err != nil {
 		logger.ErrorContext(ctx, "Error getting max order", err)
@@ -142,18 +374,13 @@ func (r *postgresTaskRepo) Create(ctx context.Context, req models.CreateTaskRequ
 	startTime := time.Now()
 	task, err := scanTaskRow(r.db.QueryRowContext(ctx, `
 		WITH inserted AS (
-			INSERT INTO tasks (title, description, column_id, "order", priority, assignee_id, deadline, estimated_time, tags, created_by, user_id)
-			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $10)
+			INSERT INTO tasks (title, description, column_id, "order", priority, assignee_id, deadline, estimated_time, tags, created_by, user_id, tenant_id, sandbox_created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $10, $11, $12)
 			RETURNING *
 		)
-		SELECT i.id, i.title, i.description, i.column_id, i."order", i.priority,
-			i.assignee_id, i.deadline, i.estimated_time, i.tracked_time, i.tags,
-			i.created_by, i.user_id, i.created_at, i.updated_at,
-			u.id, u.username, u.avatar_url
-		FROM inserted i
-		LEFT JOIN users u ON i.assignee_id = u.id`,
+		`+selectTaskWithAssignee("inserted"),
 		req.Title, req.Description, req.ColumnID, order, req.Priority,
-		req.AssigneeID, req.Deadline, req.EstimatedTime, pq.Array(req.Tags), userID,
+		req.AssigneeID, req.Deadline, req.EstimatedTime, pq.Array(req.Tags), userID, tenant.FromContext(ctx), sandboxTime(ctx),
 	))
 	logger.LogDatabaseOperation(ctx, "INSERT", "tasks", time.Since(startTime), err)
 
@@ -167,10 +394,10 @@ func (r *postgresTaskRepo) Create(ctx context.Context, req models.CreateTaskRequ
 func (r *postgresTaskRepo) Exists(ctx context.Context, id int) (bool, error) {
 	var existingID int
 	startTime := time.Now()
-	err := r.db.QueryRowContext(ctx, "SELECT id FROM tasks WHERE id = $1", id).Scan(&existingID)
+	err := r.db.QueryRowContext(ctx, "SELECT id FROM tasks WHERE id = $1 AND tenant_id = $2", id, tenant.FromContext(ctx)).Scan(&existingID)
 	logger.LogDatabaseOperation(ctx, "SELECT", "tasks", time.Since(startTime), err)
 
-	if err == sql.ErrNoRows {
+	if errors.Is(err, sql.ErrNoRows) {
 		return false, nil
 	}
 	if err != nil {
@@ -180,6 +407,101 @@ func (r *postgresTaskRepo) Exists(ctx context.Context, id int) (bool, error) {
 	return true, nil
 }
 
+// CountByUser returns the number of tasks owned by a user.
+func (r *postgresTaskRepo) CountByUser(ctx context.Context, userID int) (int, error) {
+	var count int
+	startTime := time.Now()
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM tasks WHERE user_id = $1 AND tenant_id = $2`, userID, tenant.FromContext(ctx)).Scan(&count)
+	logger.LogDatabaseOperation(ctx, "SELECT COUNT", "tasks", time.Since(startTime), err)
+
+	if err != nil {
+		logger.ErrorContext(ctx, "Error counting user tasks", err)
+		return 0, errors.NewDatabaseError().WithCause(err)
+	}
+	return count, nil
+}
+
+// DeleteCompletedBefore deletes the completed tasks last updated before the
+// given time and returns how many were deleted.
+func (r *postgresTaskRepo) DeleteCompletedBefore(ctx context.Context, before time.Time) (int, error) {
+	startTime := time.Now()
+	result, err := r.db.ExecContext(ctx,
+		"DELETE FROM tasks WHERE completed AND updated_at < $1 AND tenant_id = $2",
+		before, tenant.FromContext(ctx))
+	logger.LogDatabaseOperation(ctx, "DELETE", "tasks", time.Since(startTime), err)
+
+	if err != nil {
+		logger.ErrorContext(ctx, "Error purging completed tasks", err)
+		return 0, errors.NewDatabaseError().WithCause(err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, errors.NewDatabaseError().WithCause(err)
+	}
+	return int(rowsAffected), nil
+}
+
+func (r *postgresTaskRepo) DeleteByUser(ctx context.Context, userID int) (int, error) {
+	startTime := time.Now()
+	result, err := r.db.ExecContext(ctx,
+		"DELETE FROM tasks WHERE user_id = $1 AND tenant_id = $2",
+		userID, tenant.FromContext(ctx))
+	logger.LogDatabaseOperation(ctx, "DELETE", "tasks", time.Since(startTime), err)
+
+	if err != nil {
+		logger.ErrorContext(ctx, "Error deleting the tasks of a user", err)
+		return 0, errors.NewDatabaseError().WithCause(err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, errors.NewDatabaseError().WithCause(err)
+	}
+	return int(rowsAffected), nil
+}
+
+// CountByStatus counts across all tenants; it feeds process-wide metrics.
+// Every status is present, with 0 when no task has it.
+func (r *postgresTaskRepo) CountByStatus(ctx context.Context) (map[string]int, error) {
+	startTime := time.Now()
+	rows, err := r.db.QueryContext(ctx, `SELECT status, COUNT(*) FROM tasks GROUP BY status`)
+	logger.LogDatabaseOperation(ctx, "SELECT COUNT", "tasks", time.Since(startTime), err)
+	return scanStatusCounts(ctx, rows, err)
+}
+
+func (r *postgresTaskRepo) CountByStatusInTenant(ctx context.Context) (map[string]int, error) {
+	startTime := time.Now()
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT status, COUNT(*) FROM tasks WHERE tenant_id = $1 GROUP BY status`, tenant.FromContext(ctx))
+	logger.LogDatabaseOperation(ctx, "SELECT COUNT", "tasks", time.Since(startTime), err)
+	return scanStatusCounts(ctx, rows, err)
+}
+
+// scanStatusCounts reads status, count rows into a map holding every status.
+func scanStatusCounts(ctx context.Context, rows *sql.Rows, err error) (map[string]int, error) {
+	if err != nil {
+		logger.ErrorContext(ctx, "Error counting tasks", err)
+		return nil, errors.NewDatabaseError().WithCause(err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for _, status := range models.ValidTaskStatuses() {
+		counts[status] = 0
+	}
+	for rows.Next() {
+		var status string
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			logger.ErrorContext(ctx, "Error scanning task count row", err)
+			return nil, errors.NewDatabaseError().WithCause(err)
+		}
+		counts[status] = count
+	}
+	return counts, nil
+}
+
 func (r *postgresTaskRepo) Update(ctx context.Context, id int, req models.UpdateTaskRequest) (models.Task, error) {
 	startTime := time.Now()
 	task, err := scanTaskRow(r.db.QueryRowContext(ctx, `
@@ -194,17 +516,12 @@ func (r *postgresTaskRepo) Update(ctx context.Context, id int, req models.Update
 				estimated_time = CASE WHEN $7 > 0 THEN $7 ELSE estimated_time END,
 				tags = COALESCE($8, tags),
 				updated_at = NOW()
-			WHERE id = $9
+			WHERE id = $9 AND tenant_id = $10
 			RETURNING *
 		)
-		SELECT u2.id, u2.title, u2.description, u2.column_id, u2."order", u2.priority,
-			u2.assignee_id, u2.deadline, u2.estimated_time, u2.tracked_time, u2.tags,
-			u2.created_by, u2.user_id, u2.created_at, u2.updated_at,
-			usr.id, usr.username, usr.avatar_url
-		FROM updated u2
-		LEFT JOIN users usr ON u2.assignee_id = usr.id`,
+		`+selectTaskWithAssignee("updated"),
 		req.Title, req.Description, req.ColumnID, req.Priority,
-		req.AssigneeID, req.Deadline, req.EstimatedTime, pq.Array(req.Tags), id,
+		req.AssigneeID, req.Deadline, req.EstimatedTime, pq.Array(req.Tags), id, tenant.FromContext(ctx),
 	))
 	logger.LogDatabaseOperation(ctx, "UPDATE", "tasks", time.Since(startTime), err)
 
@@ -220,20 +537,15 @@ func (r *postgresTaskRepo) Move(ctx context.Context, id int, columnID int, order
 	task, err := scanTaskRow(r.db.QueryRowContext(ctx, `
 		WITH moved AS (
 			UPDATE tasks SET column_id = $1, "order" = $2, updated_at = NOW()
-			WHERE id = $3
+			WHERE id = $3 AND tenant_id = $4
 			RETURNING *
 		)
-		SELECT m.id, m.title, m.description, m.column_id, m."order", m.priority,
-			m.assignee_id, m.deadline, m.estimated_time, m.tracked_time, m.tags,
-			m.created_by, m.user_id, m.created_at, m.updated_at,
-			u.id, u.username, u.avatar_url
-		FROM moved m
-		LEFT JOIN users u ON m.assignee_id = u.id`,
-		columnID, order, id,
+		`+selectTaskWithAssignee("moved"),
+		columnID, order, id, tenant.FromContext(ctx),
 	))
 	logger.LogDatabaseOperation(ctx, "UPDATE", "tasks", time.Since(startTime), err)
 
-	if err == sql.ErrNoRows {
+	if errors.Is(err, sql.ErrNoRows) {
 		return models.Task{}, errors.NewNotFoundError("Task not found")
 	}
 	if err != nil {
@@ -243,47 +555,169 @@ func (r *postgresTaskRepo) Move(ctx context.Context, id int, columnID int, order
 	return task, nil
 }
 
+// SetStatus keeps completed and completed_at in step with the status.
+func (r *postgresTaskRepo) SetStatus(ctx context.Context, id int, status string, from []string) (models.Task, error) {
+	startTime := time.Now()
+	task, err := scanTaskRow(r.db.QueryRowContext(ctx, `
+		WITH changed AS (
+			UPDATE tasks SET
+				status = $1,
+				completed = ($1 = 'done'),
+				completed_at = CASE WHEN $1 = 'done' THEN NOW() END,
+				updated_at = NOW()
+			WHERE id = $2 AND tenant_id = $3 AND status = ANY($4)
+			RETURNING *
+		)
+		`+selectTaskWithAssignee("changed"),
+		status, id, tenant.FromContext(ctx), pq.Array(from),
+	))
+	logger.LogDatabaseOperation(ctx, "UPDATE", "tasks", time.Since(startTime), err)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Task{}, errors.NewNotFoundError("Task not found")
+	}
+	if err != nil {
+		logger.ErrorContext(ctx, "Error updating task status", err)
+		return models.Task{}, errors.NewDatabaseError().WithCause(err)
+	}
+	return task, nil
+}
+
+// Reorder gives the tasks of a column the order of taskIDs, TaskOrderGap
+// apart.
 func (r *postgresTaskRepo) Reorder(ctx context.Context, columnID int, taskIDs []int) error {
-	// If the querier is already a transaction, use it directly.
-	// Otherwise, start a new transaction.
-	var querier database.Querier
-	var commitFn func() error
+	return r.inTx(ctx, func(q database.Querier) error {
+		for i, taskID := range taskIDs {
+			startTime := time.Now()
+			result, err := q.ExecContext(ctx, `UPDATE tasks SET "order" = $1, updated_at = NOW() WHERE id = $2 AND column_id = $3 AND tenant_id = $4`, (i+1)*TaskOrderGap, taskID, columnID, tenant.FromContext(ctx))
+			logger.LogDatabaseOperation(ctx, "UPDATE", "tasks", time.Since(startTime), err)
+
+			if err != nil {
+				logger.ErrorContext(ctx, "Error updating task order", err)
+				return errors.NewDatabaseError().WithCause(err)
+			}
+
+			rowsAffected, err := result.RowsAffected()
+			if err != nil {
+				return errors.NewDatabaseError().WithCause(err)
+			}
+			if rowsAffected == 0 {
+				return errors.NewNotFoundError("Task not found in column: " + strconv.Itoa(taskID))
+			}
+		}
+		return nil
+	})
+}
 
-	if db, ok := r.db.(*sql.DB); ok {
-		tx, err := db.BeginTx(ctx, nil)
+// MoveRelative moves a task into a column right after or before the anchor
+// task, halfway between the anchor and its neighbour. The column is
+// renumbered TaskOrderGap apart first when the two are adjacent.
+func (r *postgresTaskRepo) MoveRelative(ctx context.Context, id int, columnID int, anchorID int, after bool) (models.Task, error) {
+	var task models.Task
+	err := r.inTx(ctx, func(q database.Querier) error {
+		tasks, err := lockColumnOrder(ctx, q, columnID, id)
 		if err != nil {
-			logger.ErrorContext(ctx, "Error starting transaction for reorder", err)
-			return errors.NewDatabaseError().WithCause(err)
+			return err
 		}
-		defer tx.Rollback()
-		querier = tx
-		commitFn = tx.Commit
-	} else {
-		querier = r.db
-		commitFn = func() error { return nil }
-	}
 
-	for i, taskID := range taskIDs {
-		startTime := time.Now()
-		result, err := querier.ExecContext(ctx, `UPDATE tasks SET "order" = $1, updated_at = NOW() WHERE id = $2 AND column_id = $3`, i, taskID, columnID)
-		logger.LogDatabaseOperation(ctx, "UPDATE", "tasks", time.Since(startTime), err)
+		i := slices.IndexFunc(tasks, func(t orderedTask) bool { return t.id == anchorID })
+		if i < 0 {
+			return errors.NewNotFoundError("Task not found in column: " + strconv.Itoa(anchorID))
+		}
+		if after {
+			i++
+		}
 
-		if err != nil {
-			logger.ErrorContext(ctx, "Error updating task order", err)
-			return errors.NewDatabaseError().WithCause(err)
+		order, ok := insertionOrder(tasks, i)
+		if !ok {
+			if err := renumberColumn(ctx, q, tasks, i); err != nil {
+				return err
+			}
+			order = (i + 1) * TaskOrderGap
 		}
 
-		rowsAffected, err := result.RowsAffected()
-		if err != nil {
-			return errors.NewDatabaseError().WithCause(err)
+		task, err = (&postgresTaskRepo{db: q}).Move(ctx, id, columnID, order)
+		return err
+	})
+	return task, err
+}
+
+// lockColumnOrder returns the tasks of a column but the one being moved, in
+// order, locked until the end of the transaction so concurrent moves into
+// the column do not pick the same order.
+func lockColumnOrder(ctx context.Context, q database.Querier, columnID int, movedID int) ([]orderedTask, error) {
+	startTime := time.Now()
+	rows, err := q.QueryContext(ctx,
+		`SELECT id, "order" FROM tasks WHERE column_id = $1 AND tenant_id = $2 AND id <> $3 ORDER BY "order", id FOR UPDATE`,
+		columnID, tenant.FromContext(ctx), movedID)
+	logger.LogDatabaseOperation(ctx, "SELECT", "tasks", time.Since(startTime), err)
+	if err != nil {
+		logger.ErrorContext(ctx, "Error locking column tasks", err)
+		return nil, errors.NewDatabaseError().WithCause(err)
+	}
+	defer rows.Close()
+
+	var tasks []orderedTask
+	for rows.Next() {
+		var t orderedTask
+		if err := rows.Scan(&t.id, &t.order); err != nil {
+			return nil, errors.NewDatabaseError().WithCause(err)
 		}
-		if rowsAffected == 0 {
-			return errors.NewNotFoundError("Task not found in column: " + strconv.Itoa(taskID))
+		tasks = append(tasks, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.NewDatabaseError().WithCause(err)
+	}
+	return tasks, nil
+}
+
+// renumberColumn spaces tasks TaskOrderGap apart, leaving the slot at index
+// free for the task being moved.
+func renumberColumn(ctx context.Context, q database.Querier, tasks []orderedTask, free int) error {
+	ids := make([]int, len(tasks))
+	orders := make([]int, len(tasks))
+	for i, t := range tasks {
+		slot := i
+		if i >= free {
+			slot++
 		}
+		ids[i], orders[i] = t.id, (slot+1)*TaskOrderGap
+	}
+
+	startTime := time.Now()
+	_, err := q.ExecContext(ctx, `
+		UPDATE tasks SET "order" = v.ord, updated_at = NOW()
+		FROM unnest($1::int[], $2::int[]) AS v(id, ord)
+		WHERE tasks.id = v.id AND tasks.tenant_id = $3`,
+		pq.Array(ids), pq.Array(orders), tenant.FromContext(ctx))
+	logger.LogDatabaseOperation(ctx, "UPDATE", "tasks", time.Since(startTime), err)
+	if err != nil {
+		logger.ErrorContext(ctx, "Error renumbering column tasks", err)
+		return errors.NewDatabaseError().WithCause(err)
 	}
+	return nil
+}
 
-	if err := commitFn(); err != nil {
-		logger.ErrorContext(ctx, "Error committing reorder transaction", err)
+// inTx runs fn in a new transaction, or in the repository's own querier
+// when it is already a transaction.
+func (r *postgresTaskRepo) inTx(ctx context.Context, fn func(q database.Querier) error) error {
+	db, ok := r.db.(*sql.DB)
+	if !ok {
+		return fn(r.db)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		logger.ErrorContext(ctx, "Error starting transaction", err)
+		return errors.NewDatabaseError().WithCause(err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		logger.ErrorContext(ctx, "Error committing transaction", err)
 		return errors.NewDatabaseError().WithCause(err)
 	}
 	return nil
@@ -291,7 +725,7 @@ func (r *postgresTaskRepo) Reorder(ctx context.Context, columnID int, taskIDs []
 
 func (r *postgresTaskRepo) Delete(ctx context.Context, id int) error {
 	startTime := time.Now()
-	result, err := r.db.ExecContext(ctx, "DELETE FROM tasks WHERE id = $1", id)
+	result, err := r.db.ExecContext(ctx, "DELETE FROM tasks WHERE id = $1 AND tenant_id = $2", id, tenant.FromContext(ctx))
 	logger.LogDatabaseOperation(ctx, "DELETE", "tasks", time.Since(startTime), err)
 
 	if err != nil {
@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/database"
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/models"
+)
+
+// tenantRow is a row of tenants.
+type tenantRow struct {
+	ID        int       `json:"id"`
+	Slug      string    `json:"slug"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type memoryTenantRepo struct {
+	memoryRepo
+}
+
+// NewMemoryTenantRepository returns a TenantRepository keeping its data in
+// store.
+func NewMemoryTenantRepository(store *MemoryStore) TenantRepository {
+	return &memoryTenantRepo{memoryRepo{store: store}}
+}
+
+func (r *memoryTenantRepo) WithQuerier(q database.Querier) TenantRepository {
+	return &memoryTenantRepo{r.bind(q)}
+}
+
+func (r *memoryTenantRepo) GetBySlug(ctx context.Context, slug string) (models.Tenant, error) {
+	var t models.Tenant
+	err := r.read(ctx, "SELECT", "tenants", func(d *memoryData) error {
+		for _, row := range d.tenants.rows {
+			if row.Slug == slug {
+				t = models.Tenant{ID: row.ID, Slug: row.Slug, Name: row.Name, CreatedAt: row.CreatedAt}
+				return nil
+			}
+		}
+		return errors.NewNotFoundError("Tenant")
+	})
+	return t, err
+}
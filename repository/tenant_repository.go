@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/database"
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/logger"
+	"github.com/clementhaon/sandbox-api-go/models"
+)
+
+type TenantRepository interface {
+	GetBySlug(ctx context.Context, slug string) (models.Tenant, error)
+	WithQuerier(q database.Querier) TenantRepository
+}
+
+type postgresTenantRepo struct {
+	db database.Querier
+}
+
+func NewPostgresTenantRepository(db *sql.DB) TenantRepository {
+	return &postgresTenantRepo{db: db}
+}
+
+func (r *postgresTenantRepo) WithQuerier(q database.Querier) TenantRepository {
+	return &postgresTenantRepo{db: q}
+}
+
+func (r *postgresTenantRepo) GetBySlug(ctx context.Context, slug string) (models.Tenant, error) {
+	var t models.Tenant
+	startTime := time.Now()
+	err := r.db.QueryRowContext(ctx,
+		`SELECT id, slug, name, created_at FROM tenants WHERE slug = $1`, slug,
+	).Scan(&t.ID, &t.Slug, &t.Name, &t.CreatedAt)
+	logger.LogDatabaseOperation(ctx, "SELECT", "tenants", time.Since(startTime), err)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Tenant{}, errors.NewNotFoundError("Tenant")
+	}
+	if err != nil {
+		logger.ErrorContext(ctx, "Error fetching tenant", err)
+		return models.Tenant{}, errors.NewDatabaseError().WithCause(err)
+	}
+	return t, nil
+}
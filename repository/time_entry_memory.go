@@ -0,0 +1,163 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/database"
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/models"
+)
+
+// timeEntryRow is a row of time_entries.
+type timeEntryRow struct {
+	ID          int        `json:"id"`
+	TaskID      int        `json:"task_id"`
+	UserID      int        `json:"user_id"`
+	StartTime   time.Time  `json:"start_time"`
+	EndTime     *time.Time `json:"end_time"`
+	Duration    int        `json:"duration"`
+	Description string     `json:"description"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+func (r timeEntryRow) model() models.TimeEntry {
+	return models.TimeEntry{
+		ID: r.ID, TaskID: r.TaskID, UserID: r.UserID, StartTime: r.StartTime, EndTime: r.EndTime,
+		Duration: r.Duration, Description: r.Description, CreatedAt: r.CreatedAt,
+	}
+}
+
+func timeEntryList(rows []timeEntryRow) []models.TimeEntry {
+	slices.SortStableFunc(rows, func(a, b timeEntryRow) int { return b.StartTime.Compare(a.StartTime) })
+	entries := []models.TimeEntry{}
+	for _, e := range rows {
+		entries = append(entries, e.model())
+	}
+	return entries
+}
+
+// tenantTimeEntry returns time entry id, if its task belongs to the tenant
+// of ctx.
+func (d *memoryData) tenantTimeEntry(ctx context.Context, id int) (timeEntryRow, bool) {
+	e, ok := d.timeEntries.get(id)
+	if !ok {
+		return timeEntryRow{}, false
+	}
+	_, ok = d.tenantTask(ctx, e.TaskID)
+	return e, ok
+}
+
+type memoryTimeEntryRepo struct {
+	memoryRepo
+}
+
+// NewMemoryTimeEntryRepository returns a TimeEntryRepository keeping its
+// data in store.
+func NewMemoryTimeEntryRepository(store *MemoryStore) TimeEntryRepository {
+	return &memoryTimeEntryRepo{memoryRepo{store: store}}
+}
+
+func (r *memoryTimeEntryRepo) WithQuerier(q database.Querier) TimeEntryRepository {
+	return &memoryTimeEntryRepo{r.bind(q)}
+}
+
+func (r *memoryTimeEntryRepo) List(ctx context.Context, taskID int) ([]models.TimeEntry, error) {
+	var entries []models.TimeEntry
+	err := r.read(ctx, "SELECT", "time_entries", func(d *memoryData) error {
+		var rows []timeEntryRow
+		if _, ok := d.tenantTask(ctx, taskID); ok {
+			rows = d.timeEntries.filter(func(e timeEntryRow) bool { return e.TaskID == taskID })
+		}
+		entries = timeEntryList(rows)
+		return nil
+	})
+	return entries, err
+}
+
+func (r *memoryTimeEntryRepo) ListByUser(ctx context.Context, userID int) ([]models.TimeEntry, error) {
+	var entries []models.TimeEntry
+	err := r.read(ctx, "SELECT", "time_entries", func(d *memoryData) error {
+		entries = timeEntryList(d.timeEntries.filter(func(e timeEntryRow) bool { return e.UserID == userID }))
+		return nil
+	})
+	return entries, err
+}
+
+func (r *memoryTimeEntryRepo) TaskExists(ctx context.Context, taskID int) (bool, error) {
+	var exists bool
+	err := r.read(ctx, "SELECT", "tasks", func(d *memoryData) error {
+		_, exists = d.tenantTask(ctx, taskID)
+		return nil
+	})
+	return exists, err
+}
+
+func (r *memoryTimeEntryRepo) Create(ctx context.Context, userID int, req models.CreateTimeEntryRequest) (models.TimeEntry, error) {
+	var e timeEntryRow
+	err := r.write(ctx, "INSERT", "time_entries", func(d *memoryData) error {
+		if _, ok := d.tasks.get(req.TaskID); !ok {
+			return constraintError("insert or update on table time_entries violates foreign key constraint time_entries_task_id_fkey")
+		}
+		if _, ok := d.users.get(userID); !ok {
+			return constraintError("insert or update on table time_entries violates foreign key constraint time_entries_user_id_fkey")
+		}
+		e = timeEntryRow{
+			ID: d.timeEntries.nextID(), TaskID: req.TaskID, UserID: userID, StartTime: req.StartTime.Truncate(time.Microsecond),
+			EndTime: req.EndTime, Duration: req.Duration, Description: req.Description, CreatedAt: memoryNow(),
+		}
+		if e.EndTime != nil {
+			end := e.EndTime.Truncate(time.Microsecond)
+			e.EndTime = &end
+		}
+		d.timeEntries.put(e)
+		return nil
+	})
+	return e.model(), err
+}
+
+func (r *memoryTimeEntryRepo) AddTrackedTime(ctx context.Context, taskID int, durationMinutes int) error {
+	return r.trackTime(ctx, taskID, func(tracked int) int { return tracked + durationMinutes })
+}
+
+func (r *memoryTimeEntryRepo) SubtractTrackedTime(ctx context.Context, taskID int, durationMinutes int) error {
+	return r.trackTime(ctx, taskID, func(tracked int) int { return max(0, tracked-durationMinutes) })
+}
+
+// trackTime sets the tracked time of a task to update of it.
+func (r *memoryTimeEntryRepo) trackTime(ctx context.Context, taskID int, update func(tracked int) int) error {
+	return r.write(ctx, "UPDATE", "tasks", func(d *memoryData) error {
+		if t, ok := d.tenantTask(ctx, taskID); ok {
+			t.TrackedTime, t.UpdatedAt = update(t.TrackedTime), memoryNow()
+			d.tasks.put(t)
+			d.touchTaskList(t.TenantID)
+		}
+		return nil
+	})
+}
+
+func (r *memoryTimeEntryRepo) GetTaskIDAndDuration(ctx context.Context, id int) (int, int, error) {
+	var e timeEntryRow
+	err := r.read(ctx, "SELECT", "time_entries", func(d *memoryData) error {
+		var ok bool
+		if e, ok = d.tenantTimeEntry(ctx, id); !ok {
+			return errors.NewNotFoundError("Time entry not found")
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	return e.TaskID, e.Duration, nil
+}
+
+func (r *memoryTimeEntryRepo) Delete(ctx context.Context, id int) error {
+	return r.write(ctx, "DELETE", "time_entries", func(d *memoryData) error {
+		if _, ok := d.tenantTimeEntry(ctx, id); !ok {
+			return errors.NewNotFoundError("Time entry not found")
+		}
+		d.timeEntries.delete(id)
+		return nil
+	})
+}
@@ -9,10 +9,12 @@ import (
 	"github.com/clementhaon/sandbox-api-go/errors"
 	"github.com/clementhaon/sandbox-api-go/logger"
 	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/tenant"
 )
 
 type TimeEntryRepository interface {
 	List(ctx context.Context, taskID int) ([]models.TimeEntry, error)
+	ListByUser(ctx context.Context, userID int) ([]models.TimeEntry, error)
 	TaskExists(ctx context.Context, taskID int) (bool, error)
 	Create(ctx context.Context, userID int, req models.CreateTimeEntryRequest) (models.TimeEntry, error)
 	AddTrackedTime(ctx context.Context, taskID int, durationMinutes int) error
@@ -39,9 +41,9 @@ func (r *postgresTimeEntryRepo) List(ctx context.Context, taskID int) ([]models.
 	rows, err := r.db.QueryContext(ctx, `
 		SELECT id, task_id, user_id, start_time, end_time, duration, description, created_at
 		FROM time_entries
-		WHERE task_id = $1
+		WHERE task_id = $1 AND task_id IN (SELECT id FROM tasks WHERE tenant_id = $2)
 		ORDER BY start_time DESC
-	`, taskID)
+	`, taskID, tenant.FromContext(ctx))
 	logger.LogDatabaseOperation(ctx, "SELECT", "time_entries", time.Since(startTime), err)
 
 	if err != nil {
@@ -62,13 +64,41 @@ func (r *postgresTimeEntryRepo) List(ctx context.Context, taskID int) ([]models.
 	return entries, nil
 }
 
+func (r *postgresTimeEntryRepo) ListByUser(ctx context.Context, userID int) ([]models.TimeEntry, error) {
+	startTime := time.Now()
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, task_id, user_id, start_time, end_time, duration, description, created_at
+		FROM time_entries
+		WHERE user_id = $1
+		ORDER BY start_time DESC
+	`, userID)
+	logger.LogDatabaseOperation(ctx, "SELECT", "time_entries", time.Since(startTime), err)
+
+	if err != nil {
+		logger.ErrorContext(ctx, "Error querying user time entries", err)
+		return nil, errors.NewDatabaseError().WithCause(err)
+	}
+	defer rows.Close()
+
+	entries := []models.TimeEntry{}
+	for rows.Next() {
+		var e models.TimeEntry
+		if err := rows.Scan(&e.ID, &e.TaskID, &e.UserID, &e.StartTime, &e.EndTime, &e.Duration, &e.Description, &e.CreatedAt); err != nil {
+			logger.ErrorContext(ctx, "Error scanning time entry row", err)
+			return nil, errors.NewDatabaseError().WithCause(err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
 func (r *postgresTimeEntryRepo) TaskExists(ctx context.Context, taskID int) (bool, error) {
 	var id int
 	startTime := time.Now()
-	err := r.db.QueryRowContext(ctx, "SELECT id FROM tasks WHERE id = $1", taskID).Scan(&id)
+	err := r.db.QueryRowContext(ctx, "SELECT id FROM tasks WHERE id = $1 AND tenant_id = $2", taskID, tenant.FromContext(ctx)).Scan(&id)
 	logger.LogDatabaseOperation(ctx, "SELECT", "tasks", time.Since(startTime), err)
 
-	if err == sql.ErrNoRows {
+	if errors.Is(err, sql.ErrNoRows) {
 		return false, nil
 	}
 	if err != nil {
@@ -98,7 +128,7 @@ func (r *postgresTimeEntryRepo) Create(ctx context.Context, userID int, req mode
 
 func (r *postgresTimeEntryRepo) AddTrackedTime(ctx context.Context, taskID int, durationMinutes int) error {
 	startTime := time.Now()
-	_, err := r.db.ExecContext(ctx, `UPDATE tasks SET tracked_time = tracked_time + $1, updated_at = NOW() WHERE id = $2`, durationMinutes, taskID)
+	_, err := r.db.ExecContext(ctx, `UPDATE tasks SET tracked_time = tracked_time + $1, updated_at = NOW() WHERE id = $2 AND tenant_id = $3`, durationMinutes, taskID, tenant.FromContext(ctx))
 	logger.LogDatabaseOperation(ctx, "UPDATE", "tasks", time.Since(startTime), err)
 	return err
 }
@@ -106,10 +136,13 @@ func (r *postgresTimeEntryRepo) AddTrackedTime(ctx context.Context, taskID int,
 func (r *postgresTimeEntryRepo) GetTaskIDAndDuration(ctx context.Context, id int) (int, int, error) {
 	var taskID, duration int
 	startTime := time.Now()
-	err := r.db.QueryRowContext(ctx, "SELECT task_id, duration FROM time_entries WHERE id = $1", id).Scan(&taskID, &duration)
+	err := r.db.QueryRowContext(ctx, `
+		SELECT te.task_id, te.duration FROM time_entries te
+		JOIN tasks t ON t.id = te.task_id
+		WHERE te.id = $1 AND t.tenant_id = $2`, id, tenant.FromContext(ctx)).Scan(&taskID, &duration)
 	logger.LogDatabaseOperation(ctx, "SELECT", "time_entries", time.Since(startTime), err)
 
-	if err == sql.ErrNoRows {
+	if errors.Is(err, sql.ErrNoRows) {
 		return 0, 0, errors.NewNotFoundError("Time entry not found")
 	}
 	if err != nil {
@@ -121,7 +154,9 @@ func (r *postgresTimeEntryRepo) GetTaskIDAndDuration(ctx context.Context, id int
 
 func (r *postgresTimeEntryRepo) Delete(ctx context.Context, id int) error {
 	startTime := time.Now()
-	result, err := r.db.ExecContext(ctx, "DELETE FROM time_entries WHERE id = $1", id)
+	result, err := r.db.ExecContext(ctx, `
+		DELETE FROM time_entries
+		WHERE id = $1 AND task_id IN (SELECT id FROM tasks WHERE tenant_id = $2)`, id, tenant.FromContext(ctx))
 	logger.LogDatabaseOperation(ctx, "DELETE", "time_entries", time.Since(startTime), err)
 
 	if err != nil {
@@ -141,7 +176,7 @@ func (r *postgresTimeEntryRepo) Delete(ctx context.Context, id int) error {
 
 func (r *postgresTimeEntryRepo) SubtractTrackedTime(ctx context.Context, taskID int, durationMinutes int) error {
 	startTime := time.Now()
-	_, err := r.db.ExecContext(ctx, `UPDATE tasks SET tracked_time = GREATEST(0, tracked_time - $1), updated_at = NOW() WHERE id = $2`, durationMinutes, taskID)
+	_, err := r.db.ExecContext(ctx, `UPDATE tasks SET tracked_time = GREATEST(0, tracked_time - $1), updated_at = NOW() WHERE id = $2 AND tenant_id = $3`, durationMinutes, taskID, tenant.FromContext(ctx))
 	logger.LogDatabaseOperation(ctx, "UPDATE", "tasks", time.Since(startTime), err)
 	return err
 }
@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/database"
+	"github.com/clementhaon/sandbox-api-go/models"
+)
+
+// apiUsageRow is a row of api_usage.
+type apiUsageRow struct {
+	UserID   int    `json:"user_id"`
+	TokenID  int    `json:"token_id"`
+	Day      string `json:"day"`
+	Requests int64  `json:"requests"`
+	BytesIn  int64  `json:"bytes_in"`
+	BytesOut int64  `json:"bytes_out"`
+}
+
+func (r apiUsageRow) key() string {
+	return fmt.Sprintf("%d/%s/%d", r.UserID, r.Day, r.TokenID)
+}
+
+type memoryUsageRepo struct {
+	memoryRepo
+}
+
+// NewMemoryUsageRepository returns a UsageRepository keeping its data in
+// store.
+func NewMemoryUsageRepository(store *MemoryStore) UsageRepository {
+	return &memoryUsageRepo{memoryRepo{store: store}}
+}
+
+func (r *memoryUsageRepo) WithQuerier(q database.Querier) UsageRepository {
+	return &memoryUsageRepo{r.bind(q)}
+}
+
+func (r *memoryUsageRepo) Add(ctx context.Context, userID, tokenID int, day time.Time, counts models.UsageCounts) error {
+	return r.write(ctx, "UPSERT", "api_usage", func(d *memoryData) error {
+		if _, ok := d.users.get(userID); !ok {
+			return constraintError("insert or update on table api_usage violates foreign key constraint api_usage_user_id_fkey")
+		}
+		u := apiUsageRow{UserID: userID, TokenID: tokenID, Day: usageDay(day)}
+		if existing, ok := d.apiUsage.get(u.key()); ok {
+			u = existing
+		}
+		u.Requests += counts.Requests
+		u.BytesIn += counts.BytesIn
+		u.BytesOut += counts.BytesOut
+		d.apiUsage.put(u)
+		return nil
+	})
+}
+
+func (r *memoryUsageRepo) Totals(ctx context.Context, userID int, day time.Time) (models.UsageCounts, error) {
+	var c models.UsageCounts
+	err := r.read(ctx, "SELECT", "api_usage", func(d *memoryData) error {
+		for _, u := range d.apiUsage.filter(func(u apiUsageRow) bool { return u.UserID == userID && u.Day == usageDay(day) }) {
+			c.Requests += u.Requests
+			c.BytesIn += u.BytesIn
+			c.BytesOut += u.BytesOut
+		}
+		return nil
+	})
+	if err != nil {
+		return models.UsageCounts{}, err
+	}
+	return c, nil
+}
+
+func (r *memoryUsageRepo) ListSince(ctx context.Context, userID int, since time.Time) ([]models.UsageRecord, error) {
+	records := []models.UsageRecord{}
+	err := r.read(ctx, "SELECT", "api_usage", func(d *memoryData) error {
+		rows := d.apiUsage.filter(func(u apiUsageRow) bool { return u.UserID == userID && u.Day >= usageDay(since) })
+		slices.SortFunc(rows, func(a, b apiUsageRow) int {
+			return cmp.Or(cmp.Compare(b.Day, a.Day), cmp.Compare(a.TokenID, b.TokenID))
+		})
+		for _, u := range rows {
+			record := models.UsageRecord{
+				Day: u.Day, TokenID: u.TokenID,
+				UsageCounts: models.UsageCounts{Requests: u.Requests, BytesIn: u.BytesIn, BytesOut: u.BytesOut},
+			}
+			if t, ok := d.personalTokens.get(u.TokenID); ok {
+				record.TokenName = t.Name
+			}
+			records = append(records, record)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
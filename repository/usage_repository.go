@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/database"
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/logger"
+	"github.com/clementhaon/sandbox-api-go/models"
+)
+
+// UsageRepository counts the API usage of users by UTC day and key: the
+// personal token the requests were made with, 0 for login sessions.
+type UsageRepository interface {
+	// Add adds counts to the usage of key tokenID of the user on the day of
+	// day
+	Add(ctx context.Context, userID, tokenID int, day time.Time, counts models.UsageCounts) error
+	// Totals returns the usage of the user on the day of day, all keys
+	// together
+	Totals(ctx context.Context, userID int, day time.Time) (models.UsageCounts, error)
+	// ListSince returns the usage of the user by day and key from the day
+	// of since, the most recent day first
+	ListSince(ctx context.Context, userID int, since time.Time) ([]models.UsageRecord, error)
+	WithQuerier(q database.Querier) UsageRepository
+}
+
+// usageDay is the api_usage day of t.
+func usageDay(t time.Time) string {
+	return t.UTC().Format(time.DateOnly)
+}
+
+type postgresUsageRepo struct {
+	db database.Querier
+}
+
+func NewPostgresUsageRepository(db *sql.DB) UsageRepository {
+	return &postgresUsageRepo{db: db}
+}
+
+func (r *postgresUsageRepo) WithQuerier(q database.Querier) UsageRepository {
+	return &postgresUsageRepo{db: q}
+}
+
+func (r *postgresUsageRepo) Add(ctx context.Context, userID, tokenID int, day time.Time, counts models.UsageCounts) error {
+	startTime := time.Now()
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO api_usage (user_id, token_id, day, requests, bytes_in, bytes_out)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (user_id, day, token_id) DO UPDATE
+		SET requests = api_usage.requests + EXCLUDED.requests,
+			bytes_in = api_usage.bytes_in + EXCLUDED.bytes_in,
+			bytes_out = api_usage.bytes_out + EXCLUDED.bytes_out
+	`, userID, tokenID, usageDay(day), counts.Requests, counts.BytesIn, counts.BytesOut)
+	logger.LogDatabaseOperation(ctx, "UPSERT", "api_usage", time.Since(startTime), err)
+
+	if err != nil {
+		logger.ErrorContext(ctx, "Error recording API usage", err)
+		return errors.NewDatabaseError().WithCause(err)
+	}
+	return nil
+}
+
+func (r *postgresUsageRepo) Totals(ctx context.Context, userID int, day time.Time) (models.UsageCounts, error) {
+	var c models.UsageCounts
+	startTime := time.Now()
+	err := r.db.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(requests), 0), COALESCE(SUM(bytes_in), 0), COALESCE(SUM(bytes_out), 0)
+		FROM api_usage WHERE user_id = $1 AND day = $2
+	`, userID, usageDay(day)).Scan(&c.Requests, &c.BytesIn, &c.BytesOut)
+	logger.LogDatabaseOperation(ctx, "SELECT", "api_usage", time.Since(startTime), err)
+
+	if err != nil {
+		logger.ErrorContext(ctx, "Error fetching API usage", err)
+		return models.UsageCounts{}, errors.NewDatabaseError().WithCause(err)
+	}
+	return c, nil
+}
+
+func (r *postgresUsageRepo) ListSince(ctx context.Context, userID int, since time.Time) ([]models.UsageRecord, error) {
+	startTime := time.Now()
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT to_char(u.day, 'YYYY-MM-DD'), u.token_id, COALESCE(t.name, ''), u.requests, u.bytes_in, u.bytes_out
+		FROM api_usage u LEFT JOIN personal_tokens t ON t.id = u.token_id
+		WHERE u.user_id = $1 AND u.day >= $2
+		ORDER BY u.day DESC, u.token_id
+	`, userID, usageDay(since))
+	logger.LogDatabaseOperation(ctx, "SELECT", "api_usage", time.Since(startTime), err)
+
+	if err != nil {
+		logger.ErrorContext(ctx, "Error listing API usage", err)
+		return nil, errors.NewDatabaseError().WithCause(err)
+	}
+	defer rows.Close()
+
+	records := []models.UsageRecord{}
+	for rows.Next() {
+		var u models.UsageRecord
+		if err := rows.Scan(&u.Day, &u.TokenID, &u.TokenName, &u.Requests, &u.BytesIn, &u.BytesOut); err != nil {
+			return nil, errors.NewDatabaseError().WithCause(err)
+		}
+		records = append(records, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.NewDatabaseError().WithCause(err)
+	}
+	return records, nil
+}
@@ -0,0 +1,550 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/database"
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/tenant"
+)
+
+// userRow is a row of users.
+type userRow struct {
+	ID          int               `json:"id"`
+	TenantID    int               `json:"tenant_id"`
+	Username    string            `json:"username"`
+	Email       string            `json:"email"`
+	Password    string            `json:"password"`
+	FirstName   models.NullString `json:"first_name"`
+	LastName    models.NullString `json:"last_name"`
+	AvatarURL   models.NullString `json:"avatar_url"`
+	IsActive    bool              `json:"is_active"`
+	LastLoginAt models.NullTime   `json:"last_login_at"`
+	Role        string            `json:"role"`
+	Preferences json.RawMessage   `json:"preferences"`
+	CreatedAt   time.Time         `json:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+}
+
+func (r userRow) model() models.User {
+	return models.User{
+		ID: r.ID, TenantID: r.TenantID, Username: r.Username, Email: r.Email,
+		FirstName: r.FirstName, LastName: r.LastName, AvatarURL: r.AvatarURL,
+		IsActive: r.IsActive, LastLoginAt: r.LastLoginAt, Role: r.Role,
+		CreatedAt: r.CreatedAt, UpdatedAt: r.UpdatedAt,
+	}
+}
+
+// nullIfEmpty turns an empty s into NULL, as NULLIF does.
+func nullIfEmpty(s string) models.NullString {
+	if s == "" {
+		return models.NullString{}
+	}
+	return models.NewNullString(s)
+}
+
+// checkUserUnique enforces the unique usernames and emails of a tenant,
+// ignoring the user exceptID.
+func (d *memoryData) checkUserUnique(tenantID int, username, email string, exceptID int) error {
+	for _, u := range d.users.rows {
+		if u.ID == exceptID || u.TenantID != tenantID {
+			continue
+		}
+		if u.Username == username {
+			return constraintError("duplicate key value violates unique constraint users_tenant_id_username_key")
+		}
+		if u.Email == email {
+			return constraintError("duplicate key value violates unique constraint users_tenant_id_email_key")
+		}
+	}
+	return nil
+}
+
+// tenantUser returns user id, if it belongs to the tenant of ctx.
+func (d *memoryData) tenantUser(ctx context.Context, id int) (userRow, bool) {
+	u, ok := d.users.get(id)
+	return u, ok && u.TenantID == tenant.FromContext(ctx)
+}
+
+// insertUser adds a user with the defaults of the columns.
+func (d *memoryData) insertUser(u userRow) (userRow, error) {
+	if err := d.checkUserUnique(u.TenantID, u.Username, u.Email, 0); err != nil {
+		return userRow{}, err
+	}
+	now := memoryNow()
+	u.ID = d.users.nextID()
+	u.Preferences = json.RawMessage("{}")
+	u.CreatedAt, u.UpdatedAt = now, now
+	d.users.put(u)
+	return u, nil
+}
+
+// deleteUser deletes a user with the rows that reference it, as the
+// foreign keys of the schema do.
+func (d *memoryData) deleteUser(id int) error {
+	// created_by has no ON DELETE action
+	for _, t := range d.tasks.rows {
+		if t.CreatedBy != nil && *t.CreatedBy == id && t.UserID != id {
+			return constraintError("update or delete on table users violates foreign key constraint tasks_created_by_fkey")
+		}
+	}
+
+	d.users.delete(id)
+	for _, t := range d.tasks.filter(func(t taskRow) bool { return t.UserID == id }) {
+		d.deleteTask(t)
+	}
+	for _, t := range d.tasks.filter(func(t taskRow) bool { return t.AssigneeID != nil && *t.AssigneeID == id }) {
+		t.AssigneeID = nil
+		d.tasks.put(t)
+		d.touchTaskList(t.TenantID)
+	}
+	d.timeEntries.deleteWhere(func(e timeEntryRow) bool { return e.UserID == id })
+	d.activities.deleteWhere(func(a activityRow) bool { return a.UserID == id })
+	for _, a := range d.activities.filter(func(a activityRow) bool { return a.ActorID != nil && *a.ActorID == id }) {
+		a.ActorID = nil
+		d.activities.put(a)
+	}
+	for _, l := range d.auditLogs.filter(func(l auditLogRow) bool { return l.ActorID != nil && *l.ActorID == id }) {
+		l.ActorID = nil
+		d.auditLogs.put(l)
+	}
+	for _, a := range d.announcements.filter(func(a announcementRow) bool { return a.CreatedBy != nil && *a.CreatedBy == id }) {
+		a.CreatedBy = nil
+		d.announcements.put(a)
+	}
+	d.notifications.deleteWhere(func(n notificationRow) bool { return n.UserID == id })
+	d.media.deleteWhere(func(m mediaRow) bool { return m.UserID == id })
+	d.emailChanges.delete(id)
+	d.userQuotas.delete(id)
+	d.inboundHooks.delete(id)
+	d.announcementDismissals.deleteWhere(func(a announcementDismissalRow) bool { return a.UserID == id })
+	d.slackLinks.deleteWhere(func(l slackLinkRow) bool { return l.UserID == id })
+	d.sessions.deleteWhere(func(s sessionRow) bool { return s.UserID == id })
+	d.personalTokens.deleteWhere(func(t personalTokenRow) bool { return t.UserID == id })
+	d.apiUsage.deleteWhere(func(u apiUsageRow) bool { return u.UserID == id })
+	d.webhooks.deleteWhere(func(h webhookRow) bool { return h.UserID == id })
+	return nil
+}
+
+type memoryUserRepo struct {
+	memoryRepo
+}
+
+// NewMemoryUserRepository returns a UserRepository keeping its data in
+// store.
+func NewMemoryUserRepository(store *MemoryStore) UserRepository {
+	return &memoryUserRepo{memoryRepo{store: store}}
+}
+
+func (r *memoryUserRepo) WithQuerier(q database.Querier) UserRepository {
+	return &memoryUserRepo{r.bind(q)}
+}
+
+// --- Auth operations ---
+
+func (r *memoryUserRepo) ExistsByUsernameOrEmail(ctx context.Context, username, email string) (bool, error) {
+	var exists bool
+	err := r.read(ctx, "SELECT", "users", func(d *memoryData) error {
+		tenantID := tenant.FromContext(ctx)
+		for _, u := range d.users.rows {
+			if u.TenantID == tenantID && (u.Username == username || u.Email == email) {
+				exists = true
+			}
+		}
+		return nil
+	})
+	return exists, err
+}
+
+func (r *memoryUserRepo) CreateAuth(ctx context.Context, username, email, hashedPassword string) (models.User, error) {
+	var u userRow
+	err := r.write(ctx, "INSERT", "users", func(d *memoryData) error {
+		var err error
+		u, err = d.insertUser(userRow{
+			TenantID: tenant.FromContext(ctx), Username: username, Email: email, Password: hashedPassword,
+			IsActive: true, Role: "user",
+		})
+		return err
+	})
+	return u.model(), err
+}
+
+func (r *memoryUserRepo) FindByEmailWithPassword(ctx context.Context, email string) (models.User, string, error) {
+	var found userRow
+	err := r.read(ctx, "SELECT", "users", func(d *memoryData) error {
+		tenantID := tenant.FromContext(ctx)
+		for _, u := range d.users.rows {
+			if u.Email == email && u.TenantID == tenantID {
+				found = u
+				return nil
+			}
+		}
+		return errors.NewInvalidCredentialsError()
+	})
+	if err != nil {
+		return models.User{}, "", err
+	}
+	return found.model(), found.Password, nil
+}
+
+func (r *memoryUserRepo) UpdateLastLogin(ctx context.Context, userID int) error {
+	return r.write(ctx, "UPDATE", "users", func(d *memoryData) error {
+		if u, ok := d.tenantUser(ctx, userID); ok {
+			u.LastLoginAt = models.NewNullTime(memoryNow())
+			d.users.put(u)
+		}
+		return nil
+	})
+}
+
+func (r *memoryUserRepo) UpdatePasswordHash(ctx context.Context, userID int, hashedPassword string) error {
+	return r.write(ctx, "UPDATE", "users", func(d *memoryData) error {
+		if u, ok := d.tenantUser(ctx, userID); ok {
+			u.Password = hashedPassword
+			d.users.put(u)
+		}
+		return nil
+	})
+}
+
+// --- Stats ---
+
+// CountActiveSince counts across all tenants; it feeds process-wide metrics.
+func (r *memoryUserRepo) CountActiveSince(ctx context.Context, since time.Time) (int, error) {
+	var count int
+	err := r.read(ctx, "SELECT COUNT", "users", func(d *memoryData) error {
+		for _, u := range d.users.rows {
+			if u.IsActive && u.LastLoginAt.Valid && !u.LastLoginAt.Time.Before(since) {
+				count++
+			}
+		}
+		return nil
+	})
+	return count, err
+}
+
+func (r *memoryUserRepo) Stats(ctx context.Context, activeSince, signupsSince time.Time) (models.UserStats, error) {
+	stats := models.UserStats{SignupsPerDay: []models.DailyCount{}}
+	err := r.read(ctx, "SELECT COUNT", "users", func(d *memoryData) error {
+		tenantID := tenant.FromContext(ctx)
+		signups := make(map[string]int)
+		for _, u := range d.users.rows {
+			if u.TenantID != tenantID {
+				continue
+			}
+			stats.Total++
+			if u.IsActive && u.LastLoginAt.Valid && !u.LastLoginAt.Time.Before(activeSince) {
+				stats.Active++
+			}
+			signups[u.CreatedAt.Format(time.DateOnly)]++
+		}
+
+		today := memoryNow().Truncate(24 * time.Hour)
+		for day := signupsSince.UTC().Truncate(24 * time.Hour); !day.After(today); day = day.AddDate(0, 0, 1) {
+			date := day.Format(time.DateOnly)
+			stats.SignupsPerDay = append(stats.SignupsPerDay, models.DailyCount{Date: date, Count: signups[date]})
+		}
+		return nil
+	})
+	if err != nil {
+		return models.UserStats{}, err
+	}
+	return stats, nil
+}
+
+// --- User CRUD ---
+
+func (r *memoryUserRepo) List(ctx context.Context, params models.UserListParams) ([]models.User, int, error) {
+	if params.Page < 1 {
+		params.Page = 1
+	}
+	if params.PageSize < 1 || params.PageSize > 100 {
+		params.PageSize = 20
+	}
+
+	var rows []userRow
+	err := r.read(ctx, "SELECT", "users", func(d *memoryData) error {
+		rows = matchingUsers(ctx, d, params)
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	users := []models.User{}
+	for _, u := range pageOf(rows, params.PageSize, (params.Page-1)*params.PageSize) {
+		users = append(users, u.model())
+	}
+	return users, len(rows), nil
+}
+
+func (r *memoryUserRepo) Each(ctx context.Context, params models.UserListParams, fn func(models.User) error) error {
+	var rows []userRow
+	err := r.read(ctx, "SELECT", "users", func(d *memoryData) error {
+		rows = matchingUsers(ctx, d, params)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// fn runs once the store is released, as it may use it
+	for _, u := range rows {
+		if err := fn(u.model()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// matchingUsers returns the users of the tenant matching params, sorted as
+// userListOrder sorts them, then by id.
+func matchingUsers(ctx context.Context, d *memoryData, params models.UserListParams) []userRow {
+	tenantID := tenant.FromContext(ctx)
+	rows := d.users.filter(func(u userRow) bool {
+		if u.TenantID != tenantID {
+			return false
+		}
+		if params.Search != "" && !containsFold(u.Email, params.Search) && !containsFold(u.Username, params.Search) &&
+			!(u.FirstName.Valid && containsFold(u.FirstName.String, params.Search)) &&
+			!(u.LastName.Valid && containsFold(u.LastName.String, params.Search)) {
+			return false
+		}
+		if params.Role != "" && u.Role != params.Role {
+			return false
+		}
+		if params.Status != "" && u.IsActive != (params.Status == "active") {
+			return false
+		}
+		if params.Username != "" && !strings.EqualFold(u.Username, params.Username) {
+			return false
+		}
+		return params.Email == "" || strings.EqualFold(u.Email, params.Email)
+	})
+
+	compare := map[string]func(a, b userRow) int{
+		"email":     func(a, b userRow) int { return strings.Compare(a.Email, b.Email) },
+		"username":  func(a, b userRow) int { return strings.Compare(a.Username, b.Username) },
+		"role":      func(a, b userRow) int { return strings.Compare(a.Role, b.Role) },
+		"status":    func(a, b userRow) int { return compareBool(a.IsActive, b.IsActive) },
+		"createdAt": func(a, b userRow) int { return a.CreatedAt.Compare(b.CreatedAt) },
+	}[params.SortBy]
+	if compare == nil {
+		compare = func(a, b userRow) int { return cmp.Compare(a.ID, b.ID) }
+	}
+	desc := strings.EqualFold(params.SortOrder, "DESC")
+	// filter returns the rows by id, which keeps ties in id order
+	slices.SortStableFunc(rows, func(a, b userRow) int {
+		if desc {
+			return compare(b, a)
+		}
+		return compare(a, b)
+	})
+	return rows
+}
+
+func (r *memoryUserRepo) GetByID(ctx context.Context, id int) (models.User, error) {
+	var u userRow
+	err := r.read(ctx, "SELECT", "users", func(d *memoryData) error {
+		var ok bool
+		if u, ok = d.tenantUser(ctx, id); !ok {
+			return errors.NewNotFoundError("User")
+		}
+		return nil
+	})
+	if err != nil {
+		return models.User{}, err
+	}
+	return u.model(), nil
+}
+
+// GetByUsername returns the active user with the given username
+// (case-insensitive).
+func (r *memoryUserRepo) GetByUsername(ctx context.Context, username string) (models.User, error) {
+	var found userRow
+	err := r.read(ctx, "SELECT", "users", func(d *memoryData) error {
+		tenantID := tenant.FromContext(ctx)
+		for _, u := range d.users.rows {
+			if strings.EqualFold(u.Username, username) && u.IsActive && u.TenantID == tenantID {
+				found = u
+				return nil
+			}
+		}
+		return errors.NewNotFoundError("User")
+	})
+	if err != nil {
+		return models.User{}, err
+	}
+	return found.model(), nil
+}
+
+// SearchByUsernamePrefix returns up to limit active users whose username
+// starts with prefix (case-insensitive), ordered by username.
+func (r *memoryUserRepo) SearchByUsernamePrefix(ctx context.Context, prefix string, limit int) ([]models.User, error) {
+	var rows []userRow
+	err := r.read(ctx, "SELECT", "users", func(d *memoryData) error {
+		tenantID := tenant.FromContext(ctx)
+		prefix = strings.ToLower(prefix)
+		rows = d.users.filter(func(u userRow) bool {
+			return strings.HasPrefix(strings.ToLower(u.Username), prefix) && u.IsActive && u.TenantID == tenantID
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortStableFunc(rows, func(a, b userRow) int {
+		return strings.Compare(strings.ToLower(a.Username), strings.ToLower(b.Username))
+	})
+	users := []models.User{}
+	for _, u := range pageOf(rows, limit, 0) {
+		users = append(users, u.model())
+	}
+	return users, nil
+}
+
+func (r *memoryUserRepo) Exists(ctx context.Context, id int) (bool, error) {
+	var exists bool
+	err := r.read(ctx, "SELECT", "users", func(d *memoryData) error {
+		_, exists = d.tenantUser(ctx, id)
+		return nil
+	})
+	return exists, err
+}
+
+func (r *memoryUserRepo) Create(ctx context.Context, username, email, hashedPassword, firstName, lastName, role string) (models.User, error) {
+	var u userRow
+	err := r.write(ctx, "INSERT", "users", func(d *memoryData) error {
+		var err error
+		u, err = d.insertUser(userRow{
+			TenantID: tenant.FromContext(ctx), Username: username, Email: email, Password: hashedPassword,
+			FirstName: nullIfEmpty(firstName), LastName: nullIfEmpty(lastName), IsActive: true, Role: role,
+		})
+		return err
+	})
+	return u.model(), err
+}
+
+func (r *memoryUserRepo) Update(ctx context.Context, id int, req models.UpdateUserRequest) (models.User, error) {
+	if req.Email == "" && req.Username == "" && req.FirstName == "" && req.LastName == "" && req.AvatarURL == "" && req.Role == "" {
+		return models.User{}, errors.NewBadRequestError("No fields to update")
+	}
+
+	var u userRow
+	err := r.write(ctx, "UPDATE", "users", func(d *memoryData) error {
+		var ok bool
+		if u, ok = d.tenantUser(ctx, id); !ok {
+			return errors.NewNotFoundError("User")
+		}
+		assign := func(dst *string, value string) {
+			if value != "" {
+				*dst = value
+			}
+		}
+		assignNull := func(dst *models.NullString, value string) {
+			if value != "" {
+				*dst = models.NewNullString(value)
+			}
+		}
+		assign(&u.Email, req.Email)
+		assign(&u.Username, req.Username)
+		assignNull(&u.FirstName, req.FirstName)
+		assignNull(&u.LastName, req.LastName)
+		assignNull(&u.AvatarURL, req.AvatarURL)
+		assign(&u.Role, req.Role)
+		if err := d.checkUserUnique(u.TenantID, u.Username, u.Email, u.ID); err != nil {
+			return err
+		}
+		u.UpdatedAt = memoryNow()
+		d.users.put(u)
+		return nil
+	})
+	if err != nil {
+		return models.User{}, err
+	}
+	return u.model(), nil
+}
+
+func (r *memoryUserRepo) UpdateStatus(ctx context.Context, id int, isActive bool) (models.User, error) {
+	var u userRow
+	err := r.write(ctx, "UPDATE", "users", func(d *memoryData) error {
+		var ok bool
+		if u, ok = d.tenantUser(ctx, id); !ok {
+			return errors.NewNotFoundError("User not found")
+		}
+		u.IsActive, u.UpdatedAt = isActive, memoryNow()
+		d.users.put(u)
+		return nil
+	})
+	if err != nil {
+		return models.User{}, err
+	}
+	return u.model(), nil
+}
+
+func (r *memoryUserRepo) Delete(ctx context.Context, id int) error {
+	return r.write(ctx, "DELETE", "users", func(d *memoryData) error {
+		if _, ok := d.tenantUser(ctx, id); !ok {
+			return errors.NewNotFoundError("User not found")
+		}
+		return d.deleteUser(id)
+	})
+}
+
+// --- Profile operations ---
+
+func (r *memoryUserRepo) UpdateProfile(ctx context.Context, userID int, firstName, lastName, avatarURL sql.NullString) error {
+	return r.write(ctx, "UPDATE", "users", func(d *memoryData) error {
+		u, ok := d.tenantUser(ctx, userID)
+		if !ok {
+			return nil
+		}
+		for _, f := range []struct {
+			dst   *models.NullString
+			value sql.NullString
+		}{{&u.FirstName, firstName}, {&u.LastName, lastName}, {&u.AvatarURL, avatarURL}} {
+			if f.value.Valid {
+				*f.dst = models.NullString{NullString: f.value}
+			}
+		}
+		u.UpdatedAt = memoryNow()
+		d.users.put(u)
+		return nil
+	})
+}
+
+func (r *memoryUserRepo) GetPreferences(ctx context.Context, userID int) ([]byte, error) {
+	var prefsJSON []byte
+	err := r.read(ctx, "SELECT", "users", func(d *memoryData) error {
+		u, ok := d.tenantUser(ctx, userID)
+		if !ok {
+			return errors.NewNotFoundError("User not found")
+		}
+		prefsJSON = slices.Clone(u.Preferences)
+		return nil
+	})
+	return prefsJSON, err
+}
+
+func (r *memoryUserRepo) UpdatePreferences(ctx context.Context, userID int, prefsJSON []byte) error {
+	if !json.Valid(prefsJSON) {
+		return constraintError("invalid input syntax for type json")
+	}
+	return r.write(ctx, "UPDATE", "users", func(d *memoryData) error {
+		u, ok := d.tenantUser(ctx, userID)
+		if !ok {
+			return errors.NewNotFoundError("User not found")
+		}
+		u.Preferences, u.UpdatedAt = slices.Clone(prefsJSON), memoryNow()
+		d.users.put(u)
+		return nil
+	})
+}
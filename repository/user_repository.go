@@ -11,6 +11,7 @@ import (
 	"github.com/clementhaon/sandbox-api-go/errors"
 	"github.com/clementhaon/sandbox-api-go/logger"
 	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/tenant"
 )
 
 type UserRepository interface {
@@ -19,10 +20,22 @@ type UserRepository interface {
 	CreateAuth(ctx context.Context, username, email, hashedPassword string) (models.User, error)
 	FindByEmailWithPassword(ctx context.Context, email string) (models.User, string, error)
 	UpdateLastLogin(ctx context.Context, userID int) error
+	UpdatePasswordHash(ctx context.Context, userID int, hashedPassword string) error
+
+	// Stats
+	CountActiveSince(ctx context.Context, since time.Time) (int, error)
+	// Stats counts the users of the tenant, active ones having logged in
+	// since activeSince, and their signups per day from signupsSince.
+	Stats(ctx context.Context, activeSince, signupsSince time.Time) (models.UserStats, error)
 
 	// User CRUD
 	List(ctx context.Context, params models.UserListParams) ([]models.User, int, error)
+	// Each calls fn for every user matching params, ignoring the page, one
+	// row at a time, and stops at the first error fn returns.
+	Each(ctx context.Context, params models.UserListParams, fn func(models.User) error) error
 	GetByID(ctx context.Context, id int) (models.User, error)
+	GetByUsername(ctx context.Context, username string) (models.User, error)
+	SearchByUsernamePrefix(ctx context.Context, prefix string, limit int) ([]models.User, error)
 	Exists(ctx context.Context, id int) (bool, error)
 	Create(ctx context.Context, username, email, hashedPassword, firstName, lastName, role string) (models.User, error)
 	Update(ctx context.Context, id int, req models.UpdateUserRequest) (models.User, error)
@@ -31,6 +44,9 @@ type UserRepository interface {
 
 	// Profile operations
 	UpdateProfile(ctx context.Context, userID int, firstName, lastName, avatarURL sql.NullString) error
+	// GetPreferences returns the preferences document of a user, as stored.
+	GetPreferences(ctx context.Context, userID int) ([]byte, error)
+	UpdatePreferences(ctx context.Context, userID int, prefsJSON []byte) error
 
 	WithQuerier(q database.Querier) UserRepository
 }
@@ -47,12 +63,37 @@ func (r *postgresUserRepo) WithQuerier(q database.Querier) UserRepository {
 	return &postgresUserRepo{db: q}
 }
 
-const userColumns = `id, username, email, first_name, last_name, avatar_url, is_active, last_login_at, role, created_at, updated_at`
+const (
+	userID          column = "id"
+	userTenantID    column = "tenant_id"
+	userUsername    column = "username"
+	userEmail       column = "email"
+	userPassword    column = "password"
+	userFirstName   column = "first_name"
+	userLastName    column = "last_name"
+	userAvatarURL   column = "avatar_url"
+	userIsActive    column = "is_active"
+	userLastLoginAt column = "last_login_at"
+	userRole        column = "role"
+	userCreatedAt   column = "created_at"
+	userUpdatedAt   column = "updated_at"
+)
+
+// userFields lists the columns returned for a user, scanned into u.
+func userFields(u *models.User) []field {
+	return []field{
+		{userID, &u.ID}, {userTenantID, &u.TenantID}, {userUsername, &u.Username},
+		{userEmail, &u.Email}, {userFirstName, &u.FirstName}, {userLastName, &u.LastName},
+		{userAvatarURL, &u.AvatarURL}, {userIsActive, &u.IsActive}, {userLastLoginAt, &u.LastLoginAt},
+		{userRole, &u.Role}, {userCreatedAt, &u.CreatedAt}, {userUpdatedAt, &u.UpdatedAt},
+	}
+}
+
+var userColumns = columnList("", userFields(new(models.User)))
 
 func scanUser(row interface{ Scan(...any) error }) (models.User, error) {
 	var u models.User
-	err := row.Scan(&u.ID, &u.Username, &u.Email, &u.FirstName, &u.LastName,
-		&u.AvatarURL, &u.IsActive, &u.LastLoginAt, &u.Role, &u.CreatedAt, &u.UpdatedAt)
+	err := row.Scan(dests(userFields(&u))...)
 	return u, err
 }
 
@@ -61,10 +102,10 @@ func scanUser(row interface{ Scan(...any) error }) (models.User, error) {
 func (r *postgresUserRepo) ExistsByUsernameOrEmail(ctx context.Context, username, email string) (bool, error) {
 	var id int
 	startTime := time.Now()
-	err := r.db.QueryRowContext(ctx, "SELECT id FROM users WHERE username = $1 OR email = $2", username, email).Scan(&id)
+	err := r.db.QueryRowContext(ctx, "SELECT id FROM users WHERE tenant_id = $3 AND (username = $1 OR email = $2)", username, email, tenant.FromContext(ctx)).Scan(&id)
 	logger.LogDatabaseOperation(ctx, "SELECT", "users", time.Since(startTime), err)
 
-	if err == sql.ErrNoRows {
+	if errors.Is(err, sql.ErrNoRows) {
 		return false, nil
 	}
 	if err != nil {
@@ -77,10 +118,10 @@ func (r *postgresUserRepo) ExistsByUsernameOrEmail(ctx context.Context, username
 func (r *postgresUserRepo) CreateAuth(ctx context.Context, username, email, hashedPassword string) (models.User, error) {
 	startTime := time.Now()
 	u, err := scanUser(r.db.QueryRowContext(ctx,
-		`INSERT INTO users (username, email, password, is_active, role)
-		VALUES ($1, $2, $3, true, 'user')
+		`INSERT INTO users (tenant_id, username, email, password, is_active, role)
+		VALUES ($1, $2, $3, $4, true, 'user')
 		RETURNING `+userColumns,
-		username, email, hashedPassword,
+		tenant.FromContext(ctx), username, email, hashedPassword,
 	))
 	logger.LogDatabaseOperation(ctx, "INSERT", "users", time.Since(startTime), err)
 
@@ -94,16 +135,15 @@ func (r *postgresUserRepo) CreateAuth(ctx context.Context, username, email, hash
 func (r *postgresUserRepo) FindByEmailWithPassword(ctx context.Context, email string) (models.User, string, error) {
 	var u models.User
 	var hashedPassword string
+	fields := append(userFields(&u), field{userPassword, &hashedPassword})
 	startTime := time.Now()
 	err := r.db.QueryRowContext(ctx,
-		`SELECT id, username, email, password, first_name, last_name, avatar_url, is_active, last_login_at, role, created_at, updated_at
-		FROM users WHERE email = $1`, email,
-	).Scan(&u.ID, &u.Username, &u.Email, &hashedPassword, &u.FirstName,
-		&u.LastName, &u.AvatarURL, &u.IsActive, &u.LastLoginAt,
-		&u.Role, &u.CreatedAt, &u.UpdatedAt)
+		`SELECT `+columnList("", fields)+` FROM users WHERE email = $1 AND tenant_id = $2`,
+		email, tenant.FromContext(ctx),
+	).Scan(dests(fields)...)
 	logger.LogDatabaseOperation(ctx, "SELECT", "users", time.Since(startTime), err)
 
-	if err == sql.ErrNoRows {
+	if errors.Is(err, sql.ErrNoRows) {
 		return models.User{}, "", errors.NewInvalidCredentialsError()
 	}
 	if err != nil {
@@ -115,59 +155,90 @@ func (r *postgresUserRepo) FindByEmailWithPassword(ctx context.Context, email st
 
 func (r *postgresUserRepo) UpdateLastLogin(ctx context.Context, userID int) error {
 	startTime := time.Now()
-	_, err := r.db.ExecContext(ctx, "UPDATE users SET last_login_at = NOW() WHERE id = $1", userID)
+	_, err := r.db.ExecContext(ctx, "UPDATE users SET last_login_at = NOW() WHERE id = $1 AND tenant_id = $2", userID, tenant.FromContext(ctx))
 	logger.LogDatabaseOperation(ctx, "UPDATE", "users", time.Since(startTime), err)
 	return err
 }
 
-// --- User CRUD ---
+func (r *postgresUserRepo) UpdatePasswordHash(ctx context.Context, userID int, hashedPassword string) error {
+	startTime := time.Now()
+	_, err := r.db.ExecContext(ctx, "UPDATE users SET password = $1 WHERE id = $2 AND tenant_id = $3", hashedPassword, userID, tenant.FromContext(ctx))
+	logger.LogDatabaseOperation(ctx, "UPDATE", "users", time.Since(startTime), err)
+	return err
+}
 
-func (r *postgresUserRepo) List(ctx context.Context, params models.UserListParams) ([]models.User, int, error) {
-	if params.Page < 1 {
-		params.Page = 1
-	}
-	if params.PageSize < 1 || params.PageSize > 100 {
-		params.PageSize = 20
+// --- Stats ---
+
+// CountActiveSince counts across all tenants; it feeds process-wide metrics.
+func (r *postgresUserRepo) CountActiveSince(ctx context.Context, since time.Time) (int, error) {
+	var count int
+	startTime := time.Now()
+	err := r.db.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM users WHERE is_active = true AND last_login_at >= $1", since).Scan(&count)
+	logger.LogDatabaseOperation(ctx, "SELECT COUNT", "users", time.Since(startTime), err)
+
+	if err != nil {
+		logger.ErrorContext(ctx, "Error counting active users", err)
+		return 0, errors.NewDatabaseError().WithCause(err)
 	}
+	return count, nil
+}
+
+func (r *postgresUserRepo) Stats(ctx context.Context, activeSince, signupsSince time.Time) (models.UserStats, error) {
+	stats := models.UserStats{SignupsPerDay: []models.DailyCount{}}
+	tenantID := tenant.FromContext(ctx)
 
-	validSortFields := map[string]string{
-		"id": "id", "email": "email", "username": "username",
-		"role": "role", "status": "is_active", "createdAt": "created_at",
+	startTime := time.Now()
+	err := r.db.QueryRowContext(ctx, `
+		SELECT COUNT(*), COUNT(*) FILTER (WHERE is_active = true AND last_login_at >= $1)
+		FROM users WHERE tenant_id = $2`,
+		activeSince, tenantID).Scan(&stats.Total, &stats.Active)
+	logger.LogDatabaseOperation(ctx, "SELECT COUNT", "users", time.Since(startTime), err)
+	if err != nil {
+		logger.ErrorContext(ctx, "Error counting users", err)
+		return models.UserStats{}, errors.NewDatabaseError().WithCause(err)
 	}
-	sortField, ok := validSortFields[params.SortBy]
-	if !ok {
-		sortField = "id"
+
+	startTime = time.Now()
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT to_char(d.day, 'YYYY-MM-DD'), COUNT(u.id)
+		FROM generate_series(date_trunc('day', $1::timestamp), date_trunc('day', LOCALTIMESTAMP), interval '1 day') AS d(day)
+		LEFT JOIN users u ON u.tenant_id = $2 AND date_trunc('day', u.created_at) = d.day
+		GROUP BY d.day ORDER BY d.day`,
+		signupsSince, tenantID)
+	logger.LogDatabaseOperation(ctx, "SELECT COUNT", "users", time.Since(startTime), err)
+	if err != nil {
+		logger.ErrorContext(ctx, "Error counting signups", err)
+		return models.UserStats{}, errors.NewDatabaseError().WithCause(err)
 	}
+	defer rows.Close()
 
-	sortOrder := strings.ToUpper(params.SortOrder)
-	if sortOrder != "ASC" && sortOrder != "DESC" {
-		sortOrder = "ASC"
+	for rows.Next() {
+		var day models.DailyCount
+		if err := rows.Scan(&day.Date, &day.Count); err != nil {
+			logger.ErrorContext(ctx, "Error scanning signup count row", err)
+			return models.UserStats{}, errors.NewDatabaseError().WithCause(err)
+		}
+		stats.SignupsPerDay = append(stats.SignupsPerDay, day)
 	}
+	return stats, nil
+}
 
-	baseQuery := `FROM users WHERE 1=1`
-	args := []interface{}{}
-	argIndex := 1
+// --- User CRUD ---
 
-	if params.Search != "" {
-		baseQuery += fmt.Sprintf(` AND (email ILIKE $%d OR username ILIKE $%d OR first_name ILIKE $%d OR last_name ILIKE $%d)`, argIndex, argIndex, argIndex, argIndex)
-		args = append(args, "%"+params.Search+"%")
-		argIndex++
-	}
-	if params.Role != "" {
-		baseQuery += fmt.Sprintf(` AND role = $%d`, argIndex)
-		args = append(args, params.Role)
-		argIndex++
+func (r *postgresUserRepo) List(ctx context.Context, params models.UserListParams) ([]models.User, int, error) {
+	if params.Page < 1 {
+		params.Page = 1
 	}
-	if params.Status != "" {
-		isActive := params.Status == "active"
-		baseQuery += fmt.Sprintf(` AND is_active = $%d`, argIndex)
-		args = append(args, isActive)
-		argIndex++
+	if params.PageSize < 1 || params.PageSize > 100 {
+		params.PageSize = 20
 	}
 
+	q := userListQuery(ctx, params)
+
 	var total int
 	startTime := time.Now()
-	err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) "+baseQuery, args...).Scan(&total)
+	err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) "+q.String(), q.args...).Scan(&total)
 	logger.LogDatabaseOperation(ctx, "SELECT COUNT", "users", time.Since(startTime), err)
 	if err != nil {
 		logger.ErrorContext(ctx, "Error counting users", err)
@@ -175,12 +246,10 @@ func (r *postgresUserRepo) List(ctx context.Context, params models.UserListParam
 	}
 
 	offset := (params.Page - 1) * params.PageSize
-	selectQuery := fmt.Sprintf(`SELECT %s %s ORDER BY %s %s LIMIT $%d OFFSET $%d`,
-		userColumns, baseQuery, sortField, sortOrder, argIndex, argIndex+1)
-	args = append(args, params.PageSize, offset)
+	q.add(` ORDER BY `+userListOrder(params)+` LIMIT ? OFFSET ?`, params.PageSize, offset)
 
 	startTime = time.Now()
-	rows, err := r.db.QueryContext(ctx, selectQuery, args...)
+	rows, err := r.db.QueryContext(ctx, "SELECT "+userColumns+" "+q.String(), q.args...)
 	logger.LogDatabaseOperation(ctx, "SELECT", "users", time.Since(startTime), err)
 	if err != nil {
 		logger.ErrorContext(ctx, "Error querying users", err)
@@ -201,13 +270,86 @@ func (r *postgresUserRepo) List(ctx context.Context, params models.UserListParam
 	return users, total, nil
 }
 
+func (r *postgresUserRepo) Each(ctx context.Context, params models.UserListParams, fn func(models.User) error) error {
+	q := userListQuery(ctx, params)
+	// id keeps ties stable
+	q.add(` ORDER BY ` + userListOrder(params) + `, id`)
+
+	startTime := time.Now()
+	rows, err := r.db.QueryContext(ctx, "SELECT "+userColumns+" "+q.String(), q.args...)
+	logger.LogDatabaseOperation(ctx, "SELECT", "users", time.Since(startTime), err)
+	if err != nil {
+		logger.ErrorContext(ctx, "Error querying users", err)
+		return errors.NewDatabaseError().WithCause(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			logger.ErrorContext(ctx, "Error scanning user row", err)
+			return errors.NewDatabaseError().WithCause(err)
+		}
+		if err := fn(u); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		logger.ErrorContext(ctx, "Error reading user rows", err)
+		return errors.NewDatabaseError().WithCause(err)
+	}
+	return nil
+}
+
+// userListQuery returns the FROM and WHERE clauses selecting the users of
+// the tenant matching params.
+func userListQuery(ctx context.Context, params models.UserListParams) *query {
+	q := (&query{}).add(`FROM users WHERE tenant_id = ?`, tenant.FromContext(ctx))
+	if params.Search != "" {
+		pattern := "%" + params.Search + "%"
+		q.add(` AND (email ILIKE ? OR username ILIKE ? OR first_name ILIKE ? OR last_name ILIKE ?)`, pattern, pattern, pattern, pattern)
+	}
+	if params.Role != "" {
+		q.add(` AND role = ?`, params.Role)
+	}
+	if params.Status != "" {
+		q.add(` AND is_active = ?`, params.Status == "active")
+	}
+	if params.Username != "" {
+		q.add(` AND lower(username) = lower(?)`, params.Username)
+	}
+	if params.Email != "" {
+		q.add(` AND lower(email) = lower(?)`, params.Email)
+	}
+	return q
+}
+
+// userListOrder returns the ORDER BY expression of params. Unknown sort
+// fields fall back to id.
+func userListOrder(params models.UserListParams) string {
+	validSortFields := map[string]column{
+		"id": userID, "email": userEmail, "username": userUsername,
+		"role": userRole, "status": userIsActive, "createdAt": userCreatedAt,
+	}
+	sortField, ok := validSortFields[params.SortBy]
+	if !ok {
+		sortField = userID
+	}
+
+	sortOrder := strings.ToUpper(params.SortOrder)
+	if sortOrder != "ASC" && sortOrder != "DESC" {
+		sortOrder = "ASC"
+	}
+	return fmt.Sprintf("%s %s", sortField, sortOrder)
+}
+
 func (r *postgresUserRepo) GetByID(ctx context.Context, id int) (models.User, error) {
 	startTime := time.Now()
 	u, err := scanUser(r.db.QueryRowContext(ctx,
-		`SELECT `+userColumns+` FROM users WHERE id = $1`, id))
+		`SELECT `+userColumns+` FROM users WHERE id = $1 AND tenant_id = $2`, id, tenant.FromContext(ctx)))
 	logger.LogDatabaseOperation(ctx, "SELECT", "users", time.Since(startTime), err)
 
-	if err == sql.ErrNoRows {
+	if errors.Is(err, sql.ErrNoRows) {
 		return models.User{}, errors.NewNotFoundError("User")
 	}
 	if err != nil {
@@ -217,13 +359,66 @@ func (r *postgresUserRepo) GetByID(ctx context.Context, id int) (models.User, er
 	return u, nil
 }
 
+// GetByUsername returns the active user with the given username
+// (case-insensitive).
+func (r *postgresUserRepo) GetByUsername(ctx context.Context, username string) (models.User, error) {
+	startTime := time.Now()
+	u, err := scanUser(r.db.QueryRowContext(ctx,
+		`SELECT `+userColumns+` FROM users WHERE lower(username) = lower($1) AND is_active = true AND tenant_id = $2`,
+		username, tenant.FromContext(ctx)))
+	logger.LogDatabaseOperation(ctx, "SELECT", "users", time.Since(startTime), err)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.User{}, errors.NewNotFoundError("User")
+	}
+	if err != nil {
+		logger.ErrorContext(ctx, "Error fetching user by username", err)
+		return models.User{}, errors.NewDatabaseError().WithCause(err)
+	}
+	return u, nil
+}
+
+// likeEscaper escapes LIKE wildcards so user input matches literally.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// SearchByUsernamePrefix returns up to limit active users whose username
+// starts with prefix (case-insensitive), ordered by username.
+func (r *postgresUserRepo) SearchByUsernamePrefix(ctx context.Context, prefix string, limit int) ([]models.User, error) {
+	startTime := time.Now()
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+userColumns+` FROM users
+		WHERE lower(username) LIKE lower($1) || '%' ESCAPE '\' AND is_active = true AND tenant_id = $3
+		ORDER BY lower(username) LIMIT $2`,
+		likeEscaper.Replace(prefix), limit, tenant.FromContext(ctx))
+	logger.LogDatabaseOperation(ctx, "SELECT", "users", time.Since(startTime), err)
+	if err != nil {
+		logger.ErrorContext(ctx, "Error searching users", err)
+		return nil, errors.NewDatabaseError().WithCause(err)
+	}
+	defer rows.Close()
+
+	users := []models.User{}
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			logger.ErrorContext(ctx, "Error scanning user", err)
+			return nil, errors.NewDatabaseError().WithCause(err)
+		}
+		users = append(users, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.NewDatabaseError().WithCause(err)
+	}
+	return users, nil
+}
+
 func (r *postgresUserRepo) Exists(ctx context.Context, id int) (bool, error) {
 	var existingID int
 	startTime := time.Now()
-	err := r.db.QueryRowContext(ctx, "SELECT id FROM users WHERE id = $1", id).Scan(&existingID)
+	err := r.db.QueryRowContext(ctx, "SELECT id FROM users WHERE id = $1 AND tenant_id = $2", id, tenant.FromContext(ctx)).Scan(&existingID)
 	logger.LogDatabaseOperation(ctx, "SELECT", "users", time.Since(startTime), err)
 
-	if err == sql.ErrNoRows {
+	if errors.Is(err, sql.ErrNoRows) {
 		return false, nil
 	}
 	if err != nil {
@@ -236,10 +431,10 @@ func (r *postgresUserRepo) Exists(ctx context.Context, id int) (bool, error) {
 func (r *postgresUserRepo) Create(ctx context.Context, username, email, hashedPassword, firstName, lastName, role string) (models.User, error) {
 	startTime := time.Now()
 	u, err := scanUser(r.db.QueryRowContext(ctx,
-		`INSERT INTO users (username, email, password, first_name, last_name, is_active, role)
-		VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), true, $6)
+		`INSERT INTO users (tenant_id, username, email, password, first_name, last_name, is_active, role)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, ''), true, $7)
 		RETURNING `+userColumns,
-		username, email, hashedPassword, firstName, lastName, role,
+		tenant.FromContext(ctx), username, email, hashedPassword, firstName, lastName, role,
 	))
 	logger.LogDatabaseOperation(ctx, "INSERT", "users", time.Since(startTime), err)
 
@@ -251,53 +446,30 @@ func (r *postgresUserRepo) Create(ctx context.Context, username, email, hashedPa
 }
 
 func (r *postgresUserRepo) Update(ctx context.Context, id int, req models.UpdateUserRequest) (models.User, error) {
-	setParts := []string{}
-	args := []interface{}{}
-	argIndex := 1
-
-	if req.Email != "" {
-		setParts = append(setParts, fmt.Sprintf("email = $%d", argIndex))
-		args = append(args, req.Email)
-		argIndex++
-	}
-	if req.Username != "" {
-		setParts = append(setParts, fmt.Sprintf("username = $%d", argIndex))
-		args = append(args, req.Username)
-		argIndex++
-	}
-	if req.FirstName != "" {
-		setParts = append(setParts, fmt.Sprintf("first_name = $%d", argIndex))
-		args = append(args, req.FirstName)
-		argIndex++
-	}
-	if req.LastName != "" {
-		setParts = append(setParts, fmt.Sprintf("last_name = $%d", argIndex))
-		args = append(args, req.LastName)
-		argIndex++
-	}
-	if req.AvatarURL != "" {
-		setParts = append(setParts, fmt.Sprintf("avatar_url = $%d", argIndex))
-		args = append(args, req.AvatarURL)
-		argIndex++
-	}
-	if req.Role != "" {
-		setParts = append(setParts, fmt.Sprintf("role = $%d", argIndex))
-		args = append(args, req.Role)
-		argIndex++
-	}
-
-	if len(setParts) == 0 {
-		return models.User{}, errors.NewBadRequestError("No fields to update")
+	q := &query{}
+	var set []string
+	assign := func(col column, value string) {
+		if value != "" {
+			set = append(set, string(col)+" = "+q.bind(value))
+		}
 	}
+	assign(userEmail, req.Email)
+	assign(userUsername, req.Username)
+	assign(userFirstName, req.FirstName)
+	assign(userLastName, req.LastName)
+	assign(userAvatarURL, req.AvatarURL)
+	assign(userRole, req.Role)
 
-	setParts = append(setParts, "updated_at = NOW()")
-	args = append(args, id)
+	if len(set) == 0 {
+		return models.User{}, errors.NewBadRequestError("No fields to update")
+	}
 
-	query := fmt.Sprintf(`UPDATE users SET %s WHERE id = $%d RETURNING %s`,
-		strings.Join(setParts, ", "), argIndex, userColumns)
+	set = append(set, string(userUpdatedAt)+" = NOW()")
+	q.add(`UPDATE users SET `+strings.Join(set, ", ")+` WHERE id = ? AND tenant_id = ? RETURNING `+userColumns,
+		id, tenant.FromContext(ctx))
 
 	startTime := time.Now()
-	u, err := scanUser(r.db.QueryRowContext(ctx, query, args...))
+	u, err := scanUser(r.db.QueryRowContext(ctx, q.String(), q.args...))
 	logger.LogDatabaseOperation(ctx, "UPDATE", "users", time.Since(startTime), err)
 
 	if err != nil {
@@ -310,12 +482,12 @@ func (r *postgresUserRepo) Update(ctx context.Context, id int, req models.Update
 func (r *postgresUserRepo) UpdateStatus(ctx context.Context, id int, isActive bool) (models.User, error) {
 	startTime := time.Now()
 	u, err := scanUser(r.db.QueryRowContext(ctx,
-		`UPDATE users SET is_active = $1, updated_at = NOW() WHERE id = $2 RETURNING `+userColumns,
-		isActive, id,
+		`UPDATE users SET is_active = $1, updated_at = NOW() WHERE id = $2 AND tenant_id = $3 RETURNING `+userColumns,
+		isActive, id, tenant.FromContext(ctx),
 	))
 	logger.LogDatabaseOperation(ctx, "UPDATE", "users", time.Since(startTime), err)
 
-	if err == sql.ErrNoRows {
+	if errors.Is(err, sql.ErrNoRows) {
 		return models.User{}, errors.NewNotFoundError("User not found")
 	}
 	if err != nil {
@@ -327,7 +499,7 @@ func (r *postgresUserRepo) UpdateStatus(ctx context.Context, id int, isActive bo
 
 func (r *postgresUserRepo) Delete(ctx context.Context, id int) error {
 	startTime := time.Now()
-	result, err := r.db.ExecContext(ctx, "DELETE FROM users WHERE id = $1", id)
+	result, err := r.db.ExecContext(ctx, "DELETE FROM users WHERE id = $1 AND tenant_id = $2", id, tenant.FromContext(ctx))
 	logger.LogDatabaseOperation(ctx, "DELETE", "users", time.Since(startTime), err)
 
 	if err != nil {
@@ -355,8 +527,8 @@ func (r *postgresUserRepo) UpdateProfile(ctx context.Context, userID int, firstN
 		    last_name = COALESCE($2, last_name),
 		    avatar_url = COALESCE($3, avatar_url),
 		    updated_at = NOW()
-		WHERE id = $4`,
-		firstName, lastName, avatarURL, userID,
+		WHERE id = $4 AND tenant_id = $5`,
+		firstName, lastName, avatarURL, userID, tenant.FromContext(ctx),
 	)
 	logger.LogDatabaseOperation(ctx, "UPDATE", "users", time.Since(startTime), err)
 
@@ -366,3 +538,45 @@ func (r *postgresUserRepo) UpdateProfile(ctx context.Context, userID int, firstN
 	}
 	return nil
 }
+
+func (r *postgresUserRepo) GetPreferences(ctx context.Context, userID int) ([]byte, error) {
+	var prefsJSON []byte
+	startTime := time.Now()
+	err := r.db.QueryRowContext(ctx,
+		`SELECT preferences FROM users WHERE id = $1 AND tenant_id = $2`,
+		userID, tenant.FromContext(ctx),
+	).Scan(&prefsJSON)
+	logger.LogDatabaseOperation(ctx, "SELECT", "users", time.Since(startTime), err)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errors.NewNotFoundError("User not found")
+	}
+	if err != nil {
+		logger.ErrorContext(ctx, "Database error getting user preferences", err)
+		return nil, errors.NewDatabaseError().WithCause(err)
+	}
+	return prefsJSON, nil
+}
+
+func (r *postgresUserRepo) UpdatePreferences(ctx context.Context, userID int, prefsJSON []byte) error {
+	startTime := time.Now()
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE users SET preferences = $1, updated_at = NOW() WHERE id = $2 AND tenant_id = $3`,
+		prefsJSON, userID, tenant.FromContext(ctx),
+	)
+	logger.LogDatabaseOperation(ctx, "UPDATE", "users", time.Since(startTime), err)
+
+	if err != nil {
+		logger.ErrorContext(ctx, "Database error updating user preferences", err)
+		return errors.NewDatabaseError().WithCause(err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return errors.NewDatabaseError().WithCause(err)
+	}
+	if rowsAffected == 0 {
+		return errors.NewNotFoundError("User not found")
+	}
+	return nil
+}
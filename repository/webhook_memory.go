@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/database"
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/models"
+)
+
+// webhookRow is a row of webhooks.
+type webhookRow struct {
+	ID        int       `json:"id"`
+	UserID    int       `json:"user_id"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"secret"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (r webhookRow) model() models.Webhook {
+	return models.Webhook{ID: r.ID, UserID: r.UserID, URL: r.URL, Secret: r.Secret, CreatedAt: r.CreatedAt}
+}
+
+type memoryWebhookRepo struct {
+	memoryRepo
+}
+
+// NewMemoryWebhookRepository returns a WebhookRepository keeping its data in
+// store.
+func NewMemoryWebhookRepository(store *MemoryStore) WebhookRepository {
+	return &memoryWebhookRepo{memoryRepo{store: store}}
+}
+
+func (r *memoryWebhookRepo) WithQuerier(q database.Querier) WebhookRepository {
+	return &memoryWebhookRepo{r.bind(q)}
+}
+
+func (r *memoryWebhookRepo) Create(ctx context.Context, hook models.Webhook) (models.Webhook, error) {
+	err := r.write(ctx, "INSERT", "webhooks", func(d *memoryData) error {
+		if _, ok := d.users.get(hook.UserID); !ok {
+			return constraintError("insert or update on table webhooks violates foreign key constraint webhooks_user_id_fkey")
+		}
+		hook.ID, hook.CreatedAt = d.webhooks.nextID(), memoryNow()
+		d.webhooks.put(webhookRow{ID: hook.ID, UserID: hook.UserID, URL: hook.URL, Secret: hook.Secret, CreatedAt: hook.CreatedAt})
+		return nil
+	})
+	if err != nil {
+		return models.Webhook{}, err
+	}
+	return hook, nil
+}
+
+func (r *memoryWebhookRepo) Get(ctx context.Context, userID, id int) (models.Webhook, error) {
+	var hook models.Webhook
+	err := r.read(ctx, "SELECT", "webhooks", func(d *memoryData) error {
+		h, ok := d.webhooks.get(id)
+		if !ok || h.UserID != userID {
+			return errors.NewNotFoundError("Webhook")
+		}
+		hook = h.model()
+		return nil
+	})
+	return hook, err
+}
+
+func (r *memoryWebhookRepo) ListByUser(ctx context.Context, userID int) ([]models.Webhook, error) {
+	return r.list(ctx, func(h webhookRow) bool { return h.UserID == userID })
+}
+
+func (r *memoryWebhookRepo) ListAll(ctx context.Context) ([]models.Webhook, error) {
+	return r.list(ctx, func(webhookRow) bool { return true })
+}
+
+func (r *memoryWebhookRepo) list(ctx context.Context, keep func(webhookRow) bool) ([]models.Webhook, error) {
+	hooks := []models.Webhook{}
+	err := r.read(ctx, "SELECT", "webhooks", func(d *memoryData) error {
+		for _, h := range d.webhooks.filter(keep) {
+			hooks = append(hooks, h.model())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return hooks, nil
+}
+
+func (r *memoryWebhookRepo) UpdateSecret(ctx context.Context, id int, secret string) error {
+	return r.write(ctx, "UPDATE", "webhooks", func(d *memoryData) error {
+		if h, ok := d.webhooks.get(id); ok {
+			h.Secret = secret
+			d.webhooks.put(h)
+		}
+		return nil
+	})
+}
+
+func (r *memoryWebhookRepo) Delete(ctx context.Context, userID, id int) error {
+	return r.write(ctx, "DELETE", "webhooks", func(d *memoryData) error {
+		if h, ok := d.webhooks.get(id); !ok || h.UserID != userID {
+			return errors.NewNotFoundError("Webhook")
+		}
+		d.webhooks.delete(id)
+		return nil
+	})
+}
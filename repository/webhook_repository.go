@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/database"
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/logger"
+	"github.com/clementhaon/sandbox-api-go/models"
+)
+
+type WebhookRepository interface {
+	Create(ctx context.Context, hook models.Webhook) (models.Webhook, error)
+	// Get returns a NotFound error if userID has no webhook id
+	Get(ctx context.Context, userID, id int) (models.Webhook, error)
+	ListByUser(ctx context.Context, userID int) ([]models.Webhook, error)
+	// ListAll returns the webhooks of every user, for resealing their
+	// secrets under a new key
+	ListAll(ctx context.Context) ([]models.Webhook, error)
+	UpdateSecret(ctx context.Context, id int, secret string) error
+	// Delete returns a NotFound error if userID has no webhook id
+	Delete(ctx context.Context, userID, id int) error
+	WithQuerier(q database.Querier) WebhookRepository
+}
+
+type postgresWebhookRepo struct {
+	db database.Querier
+}
+
+func NewPostgresWebhookRepository(db *sql.DB) WebhookRepository {
+	return &postgresWebhookRepo{db: db}
+}
+
+func (r *postgresWebhookRepo) WithQuerier(q database.Querier) WebhookRepository {
+	return &postgresWebhookRepo{db: q}
+}
+
+const webhookColumns = `id, user_id, url, secret, created_at`
+
+func scanWebhook(row interface{ Scan(...interface{}) error }) (models.Webhook, error) {
+	var h models.Webhook
+	err := row.Scan(&h.ID, &h.UserID, &h.URL, &h.Secret, &h.CreatedAt)
+	return h, err
+}
+
+func (r *postgresWebhookRepo) Create(ctx context.Context, hook models.Webhook) (models.Webhook, error) {
+	startTime := time.Now()
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO webhooks (user_id, url, secret)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at
+	`, hook.UserID, hook.URL, hook.Secret).Scan(&hook.ID, &hook.CreatedAt)
+	logger.LogDatabaseOperation(ctx, "INSERT", "webhooks", time.Since(startTime), err)
+
+	if err != nil {
+		logger.ErrorContext(ctx, "Error creating webhook", err)
+		return models.Webhook{}, errors.NewDatabaseError().WithCause(err)
+	}
+	return hook, nil
+}
+
+func (r *postgresWebhookRepo) Get(ctx context.Context, userID, id int) (models.Webhook, error) {
+	startTime := time.Now()
+	h, err := scanWebhook(r.db.QueryRowContext(ctx,
+		`SELECT `+webhookColumns+` FROM webhooks WHERE id = $1 AND user_id = $2`, id, userID))
+	logger.LogDatabaseOperation(ctx, "SELECT", "webhooks", time.Since(startTime), err)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Webhook{}, errors.NewNotFoundError("Webhook")
+	}
+	if err != nil {
+		logger.ErrorContext(ctx, "Error fetching webhook", err)
+		return models.Webhook{}, errors.NewDatabaseError().WithCause(err)
+	}
+	return h, nil
+}
+
+func (r *postgresWebhookRepo) ListByUser(ctx context.Context, userID int) ([]models.Webhook, error) {
+	return r.list(ctx, `SELECT `+webhookColumns+` FROM webhooks WHERE user_id = $1 ORDER BY id`, userID)
+}
+
+func (r *postgresWebhookRepo) ListAll(ctx context.Context) ([]models.Webhook, error) {
+	return r.list(ctx, `SELECT `+webhookColumns+` FROM webhooks ORDER BY id`)
+}
+
+func (r *postgresWebhookRepo) list(ctx context.Context, query string, args ...interface{}) ([]models.Webhook, error) {
+	startTime := time.Now()
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	logger.LogDatabaseOperation(ctx, "SELECT", "webhooks", time.Since(startTime), err)
+
+	if err != nil {
+		logger.ErrorContext(ctx, "Error listing webhooks", err)
+		return nil, errors.NewDatabaseError().WithCause(err)
+	}
+	defer rows.Close()
+
+	hooks := []models.Webhook{}
+	for rows.Next() {
+		h, err := scanWebhook(rows)
+		if err != nil {
+			return nil, errors.NewDatabaseError().WithCause(err)
+		}
+		hooks = append(hooks, h)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.NewDatabaseError().WithCause(err)
+	}
+	return hooks, nil
+}
+
+func (r *postgresWebhookRepo) UpdateSecret(ctx context.Context, id int, secret string) error {
+	startTime := time.Now()
+	_, err := r.db.ExecContext(ctx, `UPDATE webhooks SET secret = $1 WHERE id = $2`, secret, id)
+	logger.LogDatabaseOperation(ctx, "UPDATE", "webhooks", time.Since(startTime), err)
+
+	if err != nil {
+		logger.ErrorContext(ctx, "Error updating webhook secret", err)
+		return errors.NewDatabaseError().WithCause(err)
+	}
+	return nil
+}
+
+func (r *postgresWebhookRepo) Delete(ctx context.Context, userID, id int) error {
+	startTime := time.Now()
+	result, err := r.db.ExecContext(ctx, `DELETE FROM webhooks WHERE id = $1 AND user_id = $2`, id, userID)
+	logger.LogDatabaseOperation(ctx, "DELETE", "webhooks", time.Since(startTime), err)
+
+	if err != nil {
+		logger.ErrorContext(ctx, "Error deleting webhook", err)
+		return errors.NewDatabaseError().WithCause(err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return errors.NewDatabaseError().WithCause(err)
+	}
+	if rowsAffected == 0 {
+		return errors.NewNotFoundError("Webhook")
+	}
+	return nil
+}
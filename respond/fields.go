@@ -0,0 +1,63 @@
+package respond
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// Fields returns the top-level fields asked for with ?fields=id,title, or
+// nil when the whole resource is wanted.
+func Fields(r *http.Request) []string {
+	var fields []string
+	for _, field := range strings.Split(r.URL.Query().Get("fields"), ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// Project trims data, an object or a list of objects, to the given JSON
+// fields. Fields the objects do not have are ignored; with no fields, data
+// is returned as is.
+func Project(data interface{}, fields []string) (interface{}, error) {
+	if len(fields) == 0 {
+		return data, nil
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.Equal(raw, []byte("null")) {
+		return data, nil
+	}
+
+	if bytes.HasPrefix(raw, []byte("[")) {
+		var items []map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &items); err != nil {
+			return nil, err
+		}
+		for i, item := range items {
+			items[i] = pick(item, fields)
+		}
+		return items, nil
+	}
+
+	var item map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &item); err != nil {
+		return nil, err
+	}
+	return pick(item, fields), nil
+}
+
+func pick(item map[string]json.RawMessage, fields []string) map[string]json.RawMessage {
+	picked := make(map[string]json.RawMessage, len(fields))
+	for _, field := range fields {
+		if value, ok := item[field]; ok {
+			picked[field] = value
+		}
+	}
+	return picked
+}
@@ -0,0 +1,48 @@
+package respond
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+)
+
+type projected struct {
+	ID    int    `json:"id"`
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+func TestFields(t *testing.T) {
+	r := httptest.NewRequest("GET", "/tasks?fields=id,%20title,,", nil)
+	if got := Fields(r); len(got) != 2 || got[0] != "id" || got[1] != "title" {
+		t.Errorf("expected [id title], got %v", got)
+	}
+	if got := Fields(httptest.NewRequest("GET", "/tasks", nil)); got != nil {
+		t.Errorf("expected no fields, got %v", got)
+	}
+}
+
+func TestProject(t *testing.T) {
+	data, err := Project(projected{ID: 1, Title: "a", Body: "long"}, []string{"id", "title", "unknown"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out, _ := json.Marshal(data)
+	if string(out) != `{"id":1,"title":"a"}` {
+		t.Errorf("unexpected projection %s", out)
+	}
+
+	data, err = Project([]projected{{ID: 1, Body: "x"}, {ID: 2, Body: "y"}}, []string{"id"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out, _ = json.Marshal(data)
+	if string(out) != `[{"id":1},{"id":2}]` {
+		t.Errorf("unexpected list projection %s", out)
+	}
+
+	whole := projected{ID: 3}
+	if data, _ := Project(whole, nil); data != whole {
+		t.Errorf("expected data unchanged without fields, got %v", data)
+	}
+}
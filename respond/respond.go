@@ -0,0 +1,144 @@
+// Package respond writes successful API responses in a consistent envelope:
+//
+//	{"success": true, "data": ..., "meta": ..., "timestamp": "...", "request_id": "..."}
+//
+// Error responses use errors.WriteError, which shares the success, timestamp
+// and request_id fields.
+package respond
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/logger"
+)
+
+// requestIDHeader is set by the middleware before handlers run.
+const requestIDHeader = "X-Request-ID"
+
+// Meta holds response metadata such as pagination.
+type Meta map[string]interface{}
+
+// Envelope is the body of every successful JSON response.
+type Envelope struct {
+	Success   bool        `json:"success"`
+	Data      interface{} `json:"data"`
+	Meta      Meta        `json:"meta,omitempty"`
+	Timestamp time.Time   `json:"timestamp"`
+	RequestID string      `json:"request_id,omitempty"`
+}
+
+// WriteJSON writes data wrapped in an Envelope with the given status code.
+// meta may be nil.
+//
+// The body is encoded before the headers are sent, to set Content-Length
+// and, on a 200, a weak ETag of data and meta, so HEAD requests get both
+// without a body: net/http drops the body of responses to HEAD. Other
+// statuses answer writes, whose responses carry no ETag; the error
+// middleware drops it from 200s to methods other than GET and HEAD.
+func WriteJSON(w http.ResponseWriter, status int, data interface{}, meta Meta) {
+	w.Header().Set("Content-Type", "application/json")
+
+	requestID := w.Header().Get(requestIDHeader)
+	body, etag, err := encodeEnvelope(data, meta, requestID)
+	if err != nil {
+		logger.Warn("Failed to encode response", map[string]interface{}{
+			"error":      err.Error(),
+			"request_id": requestID,
+		})
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	if status == http.StatusOK && w.Header().Get("ETag") == "" {
+		w.Header().Set("ETag", etag)
+	}
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+// encodeEnvelope returns the body of a response, and its ETag. The ETag
+// leaves out the timestamp and request ID, which change on every response,
+// hence weak.
+func encodeEnvelope(data interface{}, meta Meta, requestID string) ([]byte, string, error) {
+	dataJSON, err := json.Marshal(data)
+	if err != nil {
+		return nil, "", err
+	}
+	var metaJSON []byte
+	if len(meta) > 0 {
+		if metaJSON, err = json.Marshal(meta); err != nil {
+			return nil, "", err
+		}
+	}
+
+	var buf bytes.Buffer
+	err = json.NewEncoder(&buf).Encode(Envelope{
+		Success:   true,
+		Data:      json.RawMessage(dataJSON),
+		Meta:      meta,
+		Timestamp: time.Now().UTC(),
+		RequestID: requestID,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	hash := sha256.New()
+	hash.Write(dataJSON)
+	hash.Write(metaJSON)
+	return buf.Bytes(), `W/"` + hex.EncodeToString(hash.Sum(nil)[:8]) + `"`, nil
+}
+
+// rawEnvelope is an Envelope whose data and meta are already encoded.
+type rawEnvelope struct {
+	Success   bool            `json:"success"`
+	Data      json.RawMessage `json:"data"`
+	Meta      json.RawMessage `json:"meta,omitempty"`
+	Timestamp time.Time       `json:"timestamp"`
+	RequestID string          `json:"request_id,omitempty"`
+}
+
+// Rewrap returns body, a successful response written by WriteJSON, with the
+// current timestamp and requestID, for a response served again from a
+// cache. ok is false when body is not such a response.
+func Rewrap(body []byte, requestID string) (rewrapped []byte, ok bool) {
+	var env rawEnvelope
+	if err := json.Unmarshal(body, &env); err != nil || !env.Success || env.Data == nil {
+		return nil, false
+	}
+	env.Timestamp = time.Now().UTC()
+	env.RequestID = requestID
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(env); err != nil {
+		return nil, false
+	}
+	return buf.Bytes(), true
+}
+
+// OK writes data with status 200.
+func OK(w http.ResponseWriter, data interface{}) {
+	WriteJSON(w, http.StatusOK, data, nil)
+}
+
+// Created writes data with status 201.
+func Created(w http.ResponseWriter, data interface{}) {
+	WriteJSON(w, http.StatusCreated, data, nil)
+}
+
+// NoContent writes an empty 204 response.
+func NoContent(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Paginated writes a page of items with the pagination in meta.
+func Paginated(w http.ResponseWriter, items interface{}, pagination interface{}) {
+	WriteJSON(w, http.StatusOK, items, Meta{"pagination": pagination})
+}
@@ -0,0 +1,138 @@
+package respond
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteJSON_Envelope(t *testing.T) {
+	w := httptest.NewRecorder()
+	w.Header().Set(requestIDHeader, "req-123")
+
+	Created(w, map[string]string{"name": "task"})
+
+	if w.Code != http.StatusCreated {
+		t.Errorf("expected status 201, got %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("expected application/json, got %q", ct)
+	}
+
+	var body map[string]interface{}
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatalf("failed to decode body: %v", err)
+	}
+	if body["success"] != true {
+		t.Errorf("expected success true, got %v", body["success"])
+	}
+	if body["request_id"] != "req-123" {
+		t.Errorf("expected request_id req-123, got %v", body["request_id"])
+	}
+	if data, _ := body["data"].(map[string]interface{}); data["name"] != "task" {
+		t.Errorf("expected data to be wrapped, got %v", body["data"])
+	}
+	if _, ok := body["meta"]; ok {
+		t.Error("expected meta to be omitted")
+	}
+	if _, ok := body["timestamp"]; !ok {
+		t.Error("expected timestamp")
+	}
+}
+
+func TestWriteJSON_LengthAndETag(t *testing.T) {
+	write := func(data interface{}) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		OK(w, data)
+		return w
+	}
+
+	first := write(map[string]int{"id": 1})
+	if got := first.Header().Get("Content-Length"); got != strconv.Itoa(first.Body.Len()) {
+		t.Errorf("expected Content-Length %d, got %s", first.Body.Len(), got)
+	}
+	etag := first.Header().Get("ETag")
+	if !strings.HasPrefix(etag, `W/"`) {
+		t.Fatalf("expected a weak ETag, got %q", etag)
+	}
+
+	time.Sleep(time.Millisecond) // a new timestamp must not change the ETag
+	if again := write(map[string]int{"id": 1}).Header().Get("ETag"); again != etag {
+		t.Errorf("expected the same ETag for the same data, got %q and %q", etag, again)
+	}
+	if other := write(map[string]int{"id": 2}).Header().Get("ETag"); other == etag {
+		t.Error("expected another ETag for other data")
+	}
+
+	created := httptest.NewRecorder()
+	Created(created, map[string]int{"id": 1})
+	if got := created.Header().Get("ETag"); got != "" {
+		t.Errorf("expected no ETag on a 201, got %q", got)
+	}
+}
+
+func TestRewrap(t *testing.T) {
+	w := httptest.NewRecorder()
+	w.Header().Set(requestIDHeader, "req-1")
+	Paginated(w, []int{1, 2}, map[string]int{"total": 2})
+
+	rewrapped, ok := Rewrap(w.Body.Bytes(), "req-2")
+	if !ok {
+		t.Fatalf("expected %s rewrapped", w.Body)
+	}
+	var body map[string]interface{}
+	if err := json.Unmarshal(rewrapped, &body); err != nil {
+		t.Fatalf("failed to decode body: %v", err)
+	}
+	if body["request_id"] != "req-2" || body["success"] != true {
+		t.Errorf("expected the envelope of request req-2, got %v", body)
+	}
+	if !strings.Contains(string(rewrapped), `"data":[1,2],"meta":{"pagination":{"total":2}}`) {
+		t.Errorf("expected data and meta kept as they were, got %s", rewrapped)
+	}
+
+	for _, body := range []string{"hello", `{"success":false,"error":{}}`, `{"success":true}`} {
+		if _, ok := Rewrap([]byte(body), "req-2"); ok {
+			t.Errorf("expected %s left alone", body)
+		}
+	}
+}
+
+func TestPaginated(t *testing.T) {
+	w := httptest.NewRecorder()
+
+	Paginated(w, []int{1, 2}, map[string]int{"page": 2})
+
+	var body struct {
+		Data []int `json:"data"`
+		Meta struct {
+			Pagination map[string]int `json:"pagination"`
+		} `json:"meta"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatalf("failed to decode body: %v", err)
+	}
+	if len(body.Data) != 2 {
+		t.Errorf("expected 2 items, got %d", len(body.Data))
+	}
+	if body.Meta.Pagination["page"] != 2 {
+		t.Errorf("expected page 2 in meta, got %v", body.Meta.Pagination)
+	}
+}
+
+func TestNoContent(t *testing.T) {
+	w := httptest.NewRecorder()
+
+	NoContent(w)
+
+	if w.Code != http.StatusNoContent {
+		t.Errorf("expected status 204, got %d", w.Code)
+	}
+	if w.Body.Len() != 0 {
+		t.Errorf("expected empty body, got %q", w.Body.String())
+	}
+}
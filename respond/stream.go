@@ -0,0 +1,111 @@
+package respond
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/logger"
+)
+
+// NDJSONContentType is the media type of newline-delimited JSON, one item
+// per line.
+const NDJSONContentType = "application/x-ndjson"
+
+// ListWriter writes a list response one item at a time, so lists of any
+// length are sent without being held in memory. Items go in the data array
+// of an Envelope, or one per line for clients asking for NDJSON.
+//
+// Nothing is sent before the first item: until then an error can still be
+// returned as an error response.
+type ListWriter struct {
+	w       http.ResponseWriter
+	ctx     context.Context
+	ndjson  bool
+	started bool
+	buf     bytes.Buffer
+	enc     *json.Encoder
+}
+
+// NewListWriter returns a ListWriter for r, writing NDJSON when r accepts
+// application/x-ndjson or has ?format=ndjson.
+func NewListWriter(w http.ResponseWriter, r *http.Request) *ListWriter {
+	lw := &ListWriter{w: w, ctx: r.Context(), ndjson: wantsNDJSON(r)}
+	lw.enc = json.NewEncoder(&lw.buf)
+	return lw
+}
+
+func wantsNDJSON(r *http.Request) bool {
+	if r.URL.Query().Get("format") == "ndjson" {
+		return true
+	}
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		if mediaType, _, err := mime.ParseMediaType(accept); err == nil && mediaType == NDJSONContentType {
+			return true
+		}
+	}
+	return false
+}
+
+// Write sends item. Its signature fits the callbacks of repositories
+// walking their rows.
+func (lw *ListWriter) Write(item interface{}) error {
+	lw.buf.Reset()
+	if !lw.started {
+		lw.start()
+	} else if !lw.ndjson {
+		lw.buf.WriteByte(',')
+	}
+	if err := lw.enc.Encode(item); err != nil {
+		return err
+	}
+	_, err := lw.w.Write(lw.buf.Bytes())
+	return err
+}
+
+// Close ends the list. A list with no item is sent in full here. A failed
+// write only means the client is gone, so it is not reported.
+func (lw *ListWriter) Close() {
+	lw.buf.Reset()
+	if !lw.started {
+		lw.start()
+	}
+	if !lw.ndjson {
+		requestID, _ := json.Marshal(lw.w.Header().Get(requestIDHeader))
+		lw.buf.WriteString(`],"timestamp":"` + time.Now().UTC().Format(time.RFC3339Nano) + `","request_id":`)
+		lw.buf.Write(requestID)
+		lw.buf.WriteString("}\n")
+	}
+	lw.w.Write(lw.buf.Bytes())
+}
+
+// start writes the headers, and the envelope up to the data array.
+func (lw *ListWriter) start() {
+	lw.started = true
+	if lw.ndjson {
+		lw.w.Header().Set("Content-Type", NDJSONContentType)
+	} else {
+		lw.w.Header().Set("Content-Type", "application/json")
+	}
+	lw.w.WriteHeader(http.StatusOK)
+	if !lw.ndjson {
+		lw.buf.WriteString(`{"success":true,"data":[`)
+	}
+}
+
+// Fail handles err from producing the list. Before the first item, it
+// returns err for the error middleware to write. Once items are sent the
+// status cannot change: Fail logs err and aborts the response with
+// http.ErrAbortHandler, so the client sees a truncated response rather than
+// a list that looks complete.
+func (lw *ListWriter) Fail(err error) error {
+	if !lw.started {
+		return err
+	}
+	logger.ErrorContext(lw.ctx, "List response aborted", err)
+	panic(http.ErrAbortHandler)
+}
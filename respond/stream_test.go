@@ -0,0 +1,103 @@
+package respond
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestListWriter_JSON(t *testing.T) {
+	w := httptest.NewRecorder()
+	w.Header().Set(requestIDHeader, "req-123")
+	r := httptest.NewRequest(http.MethodGet, "/tasks/export", nil)
+
+	list := NewListWriter(w, r)
+	for i := 1; i <= 3; i++ {
+		if err := list.Write(map[string]int{"id": i}); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+	list.Close()
+
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("expected application/json, got %q", ct)
+	}
+	var body struct {
+		Success   bool             `json:"success"`
+		Data      []map[string]int `json:"data"`
+		RequestID string           `json:"request_id"`
+		Timestamp string           `json:"timestamp"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatalf("failed to decode body: %v", err)
+	}
+	if !body.Success || len(body.Data) != 3 || body.Data[2]["id"] != 3 {
+		t.Errorf("unexpected body %+v", body)
+	}
+	if body.RequestID != "req-123" || body.Timestamp == "" {
+		t.Errorf("expected request_id and timestamp, got %+v", body)
+	}
+}
+
+func TestListWriter_Empty(t *testing.T) {
+	w := httptest.NewRecorder()
+	list := NewListWriter(w, httptest.NewRequest(http.MethodGet, "/tasks/export", nil))
+	list.Close()
+
+	var body Envelope
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatalf("failed to decode body: %v", err)
+	}
+	if data, ok := body.Data.([]interface{}); !ok || len(data) != 0 {
+		t.Errorf("expected an empty list, got %v", body.Data)
+	}
+}
+
+func TestListWriter_NDJSON(t *testing.T) {
+	for _, r := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/tasks/export?format=ndjson", nil),
+		func() *http.Request {
+			r := httptest.NewRequest(http.MethodGet, "/tasks/export", nil)
+			r.Header.Set("Accept", "application/json;q=0.5, application/x-ndjson")
+			return r
+		}(),
+	} {
+		w := httptest.NewRecorder()
+		list := NewListWriter(w, r)
+		list.Write(map[string]int{"id": 1})
+		list.Write(map[string]int{"id": 2})
+		list.Close()
+
+		if ct := w.Header().Get("Content-Type"); ct != NDJSONContentType {
+			t.Errorf("expected %s, got %q", NDJSONContentType, ct)
+		}
+		if got := w.Body.String(); got != "{\"id\":1}\n{\"id\":2}\n" {
+			t.Errorf("unexpected NDJSON body %q", got)
+		}
+	}
+}
+
+func TestListWriter_Fail(t *testing.T) {
+	errList := errors.New("query failed")
+
+	list := NewListWriter(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if err := list.Fail(errList); err != errList {
+		t.Errorf("expected the error back before any item, got %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	list = NewListWriter(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	list.Write(1)
+	defer func() {
+		if recovered := recover(); recovered != http.ErrAbortHandler {
+			t.Errorf("expected http.ErrAbortHandler, got %v", recovered)
+		}
+		if strings.HasSuffix(w.Body.String(), "}\n") {
+			t.Errorf("expected a truncated body, got %q", w.Body.String())
+		}
+	}()
+	list.Fail(errList)
+}
@@ -0,0 +1,188 @@
+// Package sanitize cleans user-supplied text before it is persisted, so that
+// control characters and stored-XSS payloads never reach clients.
+//
+// Fields opt in with a `sanitize` tag listing comma-separated options:
+//
+//	sanitize:"text"                  strip control characters (including newlines)
+//	sanitize:"multiline"             strip control characters, keep \n and \t
+//	sanitize:"text,strip_html"       also remove HTML tags
+//	sanitize:"multiline,escape_html" also escape <, >, &, ' and "
+//
+// Tags apply to string, *string and []string fields.
+package sanitize
+
+import (
+	"fmt"
+	"html"
+	"reflect"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Options controls how one value is sanitized.
+type Options struct {
+	Multiline  bool // keep \n and \t
+	StripHTML  bool // remove HTML tags
+	EscapeHTML bool // escape HTML special characters
+}
+
+// String sanitizes s according to opts. Control characters are always
+// removed, \r\n is normalized to \n, and the result is trimmed.
+func String(s string, opts Options) string {
+	s = ControlChars(strings.ReplaceAll(s, "\r\n", "\n"), opts.Multiline)
+	if opts.StripHTML {
+		s = StripHTML(s)
+	}
+	if opts.EscapeHTML {
+		s = html.EscapeString(s)
+	}
+	return strings.TrimSpace(s)
+}
+
+// ControlChars removes control and invisible formatting characters (such as
+// bidi overrides), keeping newlines and tabs if multiline is set.
+func ControlChars(s string, multiline bool) string {
+	return strings.Map(func(r rune) rune {
+		if multiline && (r == '\n' || r == '\t') {
+			return r
+		}
+		if unicode.IsControl(r) || unicode.Is(unicode.Cf, r) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// StripHTML removes HTML tags, comments and the content of script and style
+// elements. A "<" that does not start a tag, as in "a < b", is kept.
+func StripHTML(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); {
+		if s[i] != '<' || !startsTag(s[i+1:]) {
+			b.WriteByte(s[i])
+			i++
+			continue
+		}
+
+		if strings.HasPrefix(s[i:], "<!--") {
+			end := strings.Index(s[i:], "-->")
+			if end < 0 {
+				break
+			}
+			i += end + len("-->")
+			continue
+		}
+
+		end := strings.IndexByte(s[i:], '>')
+		if end < 0 {
+			// Unterminated tag: drop the rest rather than emit a partial tag
+			break
+		}
+		tag := strings.ToLower(s[i+1 : i+end])
+		i += end + 1
+
+		for _, element := range []string{"script", "style"} {
+			if strings.HasPrefix(tag, element) {
+				closing := strings.Index(strings.ToLower(s[i:]), "</"+element)
+				if closing < 0 {
+					i = len(s)
+				} else {
+					i += closing
+				}
+			}
+		}
+	}
+	return b.String()
+}
+
+// startsTag reports whether the text after a "<" looks like markup.
+func startsTag(rest string) bool {
+	if rest == "" {
+		return false
+	}
+	c := rest[0]
+	return c == '/' || c == '!' || c == '?' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// Struct sanitizes the tagged fields of the struct pointed to by v in place.
+// An unknown option is a programming error and panics.
+func Struct(v interface{}) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.Elem().Kind() != reflect.Struct {
+		panic(fmt.Sprintf("sanitize: Struct needs a pointer to a struct, got %T", v))
+	}
+	rv = rv.Elem()
+
+	for _, field := range fieldsOf(rv.Type()) {
+		fv := rv.Field(field.index)
+		switch fv.Kind() {
+		case reflect.String:
+			fv.SetString(String(fv.String(), field.opts))
+		case reflect.Pointer:
+			if !fv.IsNil() {
+				fv.Elem().SetString(String(fv.Elem().String(), field.opts))
+			}
+		case reflect.Slice:
+			for i := 0; i < fv.Len(); i++ {
+				fv.Index(i).SetString(String(fv.Index(i).String(), field.opts))
+			}
+		}
+	}
+}
+
+type taggedField struct {
+	index int
+	opts  Options
+}
+
+// fieldCache maps a struct type to its []taggedField.
+var fieldCache sync.Map
+
+func fieldsOf(t reflect.Type) []taggedField {
+	if cached, ok := fieldCache.Load(t); ok {
+		return cached.([]taggedField)
+	}
+
+	var fields []taggedField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag, ok := f.Tag.Lookup("sanitize")
+		if !ok || tag == "" || tag == "-" {
+			continue
+		}
+		if !isStringField(f.Type) {
+			panic(fmt.Sprintf("sanitize: %s.%s: tag on non-string field", t, f.Name))
+		}
+
+		field := taggedField{index: i}
+		for _, option := range strings.Split(tag, ",") {
+			switch strings.TrimSpace(option) {
+			case "text":
+			case "multiline":
+				field.opts.Multiline = true
+			case "strip_html":
+				field.opts.StripHTML = true
+			case "escape_html":
+				field.opts.EscapeHTML = true
+			default:
+				panic(fmt.Sprintf("sanitize: %s.%s: unknown option %q", t, f.Name, option))
+			}
+		}
+		fields = append(fields, field)
+	}
+
+	fieldCache.Store(t, fields)
+	return fields
+}
+
+func isStringField(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.String:
+		return true
+	case reflect.Pointer, reflect.Slice:
+		return t.Elem().Kind() == reflect.String
+	}
+	return false
+}
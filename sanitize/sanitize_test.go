@@ -0,0 +1,81 @@
+package sanitize
+
+import "testing"
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		opts Options
+		want string
+	}{
+		{"control characters", "a\x00b\x1bc‮d", Options{}, "abcd"},
+		{"newlines removed from text", "line1\r\nline2\tx", Options{}, "line1line2x"},
+		{"newlines kept when multiline", "line1\r\nline2\tx", Options{Multiline: true}, "line1\nline2\tx"},
+		{"trimmed", "  title  ", Options{}, "title"},
+		{"strip tags", `<b>bold</b> <img src=x onerror=alert(1)>text`, Options{StripHTML: true}, "bold text"},
+		{"strip script content", `hi<script>alert("x")</script> there`, Options{StripHTML: true}, "hi there"},
+		{"strip comments", `a<!-- <b> -->b`, Options{StripHTML: true}, "ab"},
+		{"keep comparisons", "a < b and c > d", Options{StripHTML: true}, "a < b and c > d"},
+		{"unterminated tag", "safe <img src=x onerror=alert(1)", Options{StripHTML: true}, "safe"},
+		{"escape", `<a href="x">'&'</a>`, Options{EscapeHTML: true}, "&lt;a href=&#34;x&#34;&gt;&#39;&amp;&#39;&lt;/a&gt;"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := String(tt.in, tt.opts); got != tt.want {
+				t.Errorf("String(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+type request struct {
+	Title       string   `sanitize:"text,strip_html"`
+	Description string   `sanitize:"multiline,escape_html"`
+	Name        *string  `sanitize:"text"`
+	Tags        []string `sanitize:"text,strip_html"`
+	Raw         string
+}
+
+func TestStruct(t *testing.T) {
+	name := " Jane\x00 "
+	req := request{
+		Title:       "<b>Fix</b> login\n",
+		Description: "Line 1\n<script>",
+		Name:        &name,
+		Tags:        []string{"<i>ui</i>", "bug\x07"},
+		Raw:         "<b>untouched</b>",
+	}
+
+	Struct(&req)
+
+	if req.Title != "Fix login" {
+		t.Errorf("Title = %q", req.Title)
+	}
+	if req.Description != "Line 1\n&lt;script&gt;" {
+		t.Errorf("Description = %q", req.Description)
+	}
+	if *req.Name != "Jane" {
+		t.Errorf("Name = %q", *req.Name)
+	}
+	if req.Tags[0] != "ui" || req.Tags[1] != "bug" {
+		t.Errorf("Tags = %q", req.Tags)
+	}
+	if req.Raw != "<b>untouched</b>" {
+		t.Errorf("untagged field changed: %q", req.Raw)
+	}
+
+	var nilName request
+	Struct(&nilName) // nil pointer and nil slice must not panic
+}
+
+func TestStruct_UnknownOptionPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for unknown option")
+		}
+	}()
+	Struct(&struct {
+		A string `sanitize:"shout"`
+	}{})
+}
@@ -0,0 +1,79 @@
+// Package scim holds the protocol pieces of the SCIM 2.0 provisioning API:
+// its error responses and the subset of filters the API understands.
+package scim
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+)
+
+// SchemaError is the schema URN of error responses.
+const SchemaError = "urn:ietf:params:scim:api:messages:2.0:Error"
+
+// SCIM error types (RFC 7644 section 3.12)
+const (
+	ErrInvalidFilter = "invalidFilter"
+	ErrInvalidSyntax = "invalidSyntax"
+	ErrInvalidPath   = "invalidPath"
+	ErrInvalidValue  = "invalidValue"
+	ErrMutability    = "mutability"
+	ErrUniqueness    = "uniqueness"
+)
+
+// Error is a SCIM error, sent to identity providers in the format of
+// RFC 7644 rather than the API's error envelope.
+type Error struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail,omitempty"`
+	code     int
+}
+
+func (e *Error) Error() string {
+	if e.ScimType == "" {
+		return e.Detail
+	}
+	return e.ScimType + ": " + e.Detail
+}
+
+// StatusCode is the HTTP status the error is answered with.
+func (e *Error) StatusCode() int {
+	return e.code
+}
+
+// NewError returns an error answered with status.
+func NewError(status int, scimType, detail string) *Error {
+	return &Error{
+		Schemas:  []string{SchemaError},
+		Status:   strconv.Itoa(status),
+		ScimType: scimType,
+		Detail:   detail,
+		code:     status,
+	}
+}
+
+// BadRequest returns a 400 error of the given type.
+func BadRequest(scimType, detail string) *Error {
+	return NewError(http.StatusBadRequest, scimType, detail)
+}
+
+// ErrorFrom converts err to a SCIM error. Application errors keep their
+// status and message, except server errors whose cause is not disclosed.
+func ErrorFrom(err error) *Error {
+	var scimErr *Error
+	if errors.As(err, &scimErr) {
+		return scimErr
+	}
+	appErr, ok := errors.IsAppError(err)
+	if !ok || appErr.StatusCode >= http.StatusInternalServerError {
+		return NewError(http.StatusInternalServerError, "", "Internal server error")
+	}
+	scimType := ""
+	if appErr.StatusCode == http.StatusConflict {
+		scimType = ErrUniqueness
+	}
+	return NewError(appErr.StatusCode, scimType, appErr.Message)
+}
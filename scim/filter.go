@@ -0,0 +1,39 @@
+package scim
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Filter is an equality filter on one attribute, the only form identity
+// providers use to look up an account before provisioning it.
+type Filter struct {
+	// Attribute is the attribute path as written, e.g. "userName"
+	Attribute string
+	Value     string
+}
+
+// ParseFilter parses a filter of the form `attribute eq "value"`. Other
+// operators and logical expressions are an invalidFilter error.
+func ParseFilter(filter string) (Filter, error) {
+	attr, rest, ok := strings.Cut(strings.TrimSpace(filter), " ")
+	if !ok {
+		return Filter{}, BadRequest(ErrInvalidFilter, "expected: attribute eq \"value\"")
+	}
+	op, value, ok := strings.Cut(strings.TrimSpace(rest), " ")
+	if !ok || !strings.EqualFold(op, "eq") {
+		return Filter{}, BadRequest(ErrInvalidFilter, "only the eq operator is supported")
+	}
+	value = strings.TrimSpace(value)
+	unquoted, err := strconv.Unquote(value)
+	if err != nil || !strings.HasPrefix(value, `"`) {
+		return Filter{}, BadRequest(ErrInvalidFilter, "the value must be a quoted string")
+	}
+	return Filter{Attribute: attr, Value: unquoted}, nil
+}
+
+// Is reports whether the filter is on attribute. Attribute names are
+// case-insensitive.
+func (f Filter) Is(attribute string) bool {
+	return strings.EqualFold(f.Attribute, attribute)
+}
@@ -0,0 +1,60 @@
+package scim
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+)
+
+func TestParseFilter(t *testing.T) {
+	f, err := ParseFilter(`userName eq "jane@acme.test"`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !f.Is("USERNAME") || f.Value != "jane@acme.test" {
+		t.Errorf("unexpected filter %+v", f)
+	}
+
+	f, err = ParseFilter(`emails.value EQ "a \"quoted\" value"`)
+	if err != nil || !f.Is("emails.value") || f.Value != `a "quoted" value` {
+		t.Errorf("unexpected filter %+v (%v)", f, err)
+	}
+
+	for _, filter := range []string{
+		`userName`,
+		`userName sw "ja"`,
+		`userName eq jane`,
+		`userName eq "a" and active eq true`,
+		"userName eq `jane`",
+	} {
+		if _, err := ParseFilter(filter); err == nil {
+			t.Errorf("expected an error for %s", filter)
+		}
+	}
+}
+
+func TestErrorFrom(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		status   int
+		scimType string
+	}{
+		{"SCIM error", BadRequest(ErrInvalidPath, "bad path"), http.StatusBadRequest, ErrInvalidPath},
+		{"not found", errors.NewNotFoundError("User"), http.StatusNotFound, ""},
+		{"conflict", errors.NewUserExistsError(), http.StatusConflict, ErrUniqueness},
+		{"database error", errors.NewDatabaseError(), http.StatusInternalServerError, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ErrorFrom(tt.err)
+			if got.StatusCode() != tt.status || got.ScimType != tt.scimType || got.Schemas[0] != SchemaError {
+				t.Errorf("unexpected error %+v", got)
+			}
+		})
+	}
+	if got := ErrorFrom(errors.NewDatabaseError()); got.Detail != "Internal server error" {
+		t.Errorf("expected the cause of server errors hidden, got %q", got.Detail)
+	}
+}
@@ -0,0 +1,27 @@
+package scim
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/clementhaon/sandbox-api-go/logger"
+)
+
+// ContentType is the media type of SCIM requests and responses.
+const ContentType = "application/scim+json"
+
+// Write sends v as a SCIM response.
+func Write(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.Warn("Failed to encode SCIM response", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+}
+
+// WriteError sends err as a SCIM error response.
+func WriteError(w http.ResponseWriter, err *Error) {
+	Write(w, err.StatusCode(), err)
+}
@@ -0,0 +1,167 @@
+// Package secretbox encrypts the secrets the API must read back, such as
+// the signing secrets of webhooks, before they are stored.
+//
+// Values are sealed with AES-256-GCM under the current key of a Keyring
+// and prefixed with the ID of that key, so a Keyring holding older keys
+// still opens what they sealed. Rotating keys is adding a new current key,
+// resealing the stored values with Rotate, then dropping the old key.
+package secretbox
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// KeySize is the size of keys, for AES-256.
+const KeySize = 32
+
+// ErrNoKeys is returned by a nil *Keyring, when no keys are configured.
+var ErrNoKeys = errors.New("secretbox: no encryption keys configured")
+
+// ErrUnknownKey is returned for values sealed under a key the Keyring does
+// not hold.
+var ErrUnknownKey = errors.New("secretbox: value sealed under an unknown key")
+
+// KeyProvider returns keys in the format of ParseKeys. It lets them come
+// from the environment, a file written by a secrets manager, or a KMS.
+type KeyProvider interface {
+	Keys(ctx context.Context) (string, error)
+}
+
+// StaticKeys provides the keys it holds.
+type StaticKeys string
+
+func (k StaticKeys) Keys(ctx context.Context) (string, error) {
+	return string(k), nil
+}
+
+// FileKeys provides the keys of the file at its path, read on each call.
+type FileKeys string
+
+func (f FileKeys) Keys(ctx context.Context) (string, error) {
+	data, err := os.ReadFile(string(f))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// Keyring seals values under its current key and opens those of any of
+// its keys. A nil *Keyring holds no key and fails with ErrNoKeys.
+type Keyring struct {
+	current string
+	keys    map[string]cipher.AEAD
+}
+
+// Load returns the Keyring of the keys of p, or nil if it provides none.
+func Load(ctx context.Context, p KeyProvider) (*Keyring, error) {
+	spec, err := p.Keys(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("secretbox: %w", err)
+	}
+	return ParseKeys(spec)
+}
+
+// ParseKeys reads comma-separated "id:key" pairs, the key being 32 bytes
+// in standard base64, such as "2024b:...,2024a:...". The first key is the
+// current one. An empty spec returns a nil Keyring.
+func ParseKeys(spec string) (*Keyring, error) {
+	if strings.TrimSpace(spec) == "" {
+		return nil, nil
+	}
+	k := &Keyring{keys: make(map[string]cipher.AEAD)}
+	for _, pair := range strings.Split(spec, ",") {
+		id, encoded, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok || id == "" || strings.ContainsAny(id, ".:") {
+			return nil, fmt.Errorf("secretbox: expected id:base64-key pairs")
+		}
+		if _, dup := k.keys[id]; dup {
+			return nil, fmt.Errorf("secretbox: key %q given twice", id)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(key) != KeySize {
+			return nil, fmt.Errorf("secretbox: key %q must be %d bytes in base64", id, KeySize)
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		k.keys[id] = aead
+		if k.current == "" {
+			k.current = id
+		}
+	}
+	return k, nil
+}
+
+// Seal encrypts plaintext under the current key. additionalData, such as
+// the name of the column, is authenticated but not stored: Open needs the
+// same, so a value copied to another column does not open.
+func (k *Keyring) Seal(plaintext, additionalData []byte) (string, error) {
+	if k == nil {
+		return "", ErrNoKeys
+	}
+	aead := k.keys[k.current]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, plaintext, additionalData)
+	return k.current + "." + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Open decrypts a value returned by Seal with the same additionalData.
+func (k *Keyring) Open(value string, additionalData []byte) ([]byte, error) {
+	if k == nil {
+		return nil, ErrNoKeys
+	}
+	id, encoded, ok := strings.Cut(value, ".")
+	if !ok {
+		return nil, fmt.Errorf("secretbox: malformed value")
+	}
+	aead, ok := k.keys[id]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return nil, fmt.Errorf("secretbox: malformed value")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, additionalData)
+	if err != nil {
+		return nil, fmt.Errorf("secretbox: value does not open: %w", err)
+	}
+	return plaintext, nil
+}
+
+// Rotate reseals value under the current key. It returns value and false
+// when it is sealed under the current key already.
+func (k *Keyring) Rotate(value string, additionalData []byte) (string, bool, error) {
+	if k == nil {
+		return "", false, ErrNoKeys
+	}
+	if id, _, _ := strings.Cut(value, "."); id == k.current {
+		return value, false, nil
+	}
+	plaintext, err := k.Open(value, additionalData)
+	if err != nil {
+		return "", false, err
+	}
+	sealed, err := k.Seal(plaintext, additionalData)
+	if err != nil {
+		return "", false, err
+	}
+	return sealed, true, nil
+}
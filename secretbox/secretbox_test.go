@@ -0,0 +1,111 @@
+package secretbox
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testKey(b byte) string {
+	return base64.StdEncoding.EncodeToString([]byte(strings.Repeat(string(b), KeySize)))
+}
+
+func TestKeyring_SealOpen(t *testing.T) {
+	k, err := ParseKeys("a:" + testKey('a'))
+	if err != nil {
+		t.Fatal(err)
+	}
+	column := []byte("webhooks.secret")
+
+	sealed, err := k.Seal([]byte("whsec_123"), column)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(sealed, "a.") || strings.Contains(sealed, "whsec_123") {
+		t.Errorf("unexpected sealed value %q", sealed)
+	}
+	again, _ := k.Seal([]byte("whsec_123"), column)
+	if again == sealed {
+		t.Error("expected a fresh nonce for each seal")
+	}
+
+	got, err := k.Open(sealed, column)
+	if err != nil || string(got) != "whsec_123" {
+		t.Fatalf("expected the plaintext back, got %q (%v)", got, err)
+	}
+	if _, err := k.Open(sealed, []byte("other.column")); err == nil {
+		t.Error("expected a value moved to another column not to open")
+	}
+	tampered := sealed[:len(sealed)-2] + "AA"
+	if _, err := k.Open(tampered, column); err == nil {
+		t.Error("expected a tampered value not to open")
+	}
+	if _, err := k.Open("b."+strings.TrimPrefix(sealed, "a."), column); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("expected ErrUnknownKey, got %v", err)
+	}
+}
+
+func TestKeyring_Rotate(t *testing.T) {
+	old, _ := ParseKeys("a:" + testKey('a'))
+	sealed, _ := old.Seal([]byte("secret"), nil)
+
+	k, err := ParseKeys("b:" + testKey('b') + ", a:" + testKey('a'))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := k.Open(sealed, nil); err != nil || string(got) != "secret" {
+		t.Fatalf("expected an old key to still open, got %q (%v)", got, err)
+	}
+
+	rotated, changed, err := k.Rotate(sealed, nil)
+	if err != nil || !changed || !strings.HasPrefix(rotated, "b.") {
+		t.Fatalf("expected a value sealed under b, got %q %v (%v)", rotated, changed, err)
+	}
+	if _, changed, _ := k.Rotate(rotated, nil); changed {
+		t.Error("expected a value under the current key left as is")
+	}
+
+	current, _ := ParseKeys("b:" + testKey('b'))
+	if got, err := current.Open(rotated, nil); err != nil || string(got) != "secret" {
+		t.Errorf("expected the rotated value to open without the old key, got %q (%v)", got, err)
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	if k, err := ParseKeys(" "); k != nil || err != nil {
+		t.Errorf("expected no keyring for an empty spec, got %v (%v)", k, err)
+	}
+	for _, spec := range []string{
+		"nokey",
+		"a:" + base64.StdEncoding.EncodeToString([]byte("short")),
+		"a:not base64!",
+		"a:" + testKey('a') + ",a:" + testKey('b'),
+		"a.b:" + testKey('a'),
+	} {
+		if _, err := ParseKeys(spec); err == nil {
+			t.Errorf("expected an error for %q", spec)
+		}
+	}
+
+	var k *Keyring
+	if _, err := k.Seal([]byte("x"), nil); !errors.Is(err, ErrNoKeys) {
+		t.Errorf("expected ErrNoKeys from a nil keyring, got %v", err)
+	}
+}
+
+func TestLoad_FileKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys")
+	os.WriteFile(path, []byte("a:"+testKey('a')+"\n"), 0o600)
+
+	k, err := Load(context.Background(), FileKeys(path))
+	if err != nil || k == nil {
+		t.Fatalf("expected a keyring, got %v (%v)", k, err)
+	}
+	if _, err := Load(context.Background(), FileKeys(filepath.Join(t.TempDir(), "missing"))); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
@@ -0,0 +1,307 @@
+// Package server runs the sandbox API inside another Go program: it opens the
+// database (or keeps the data in memory with STORAGE=memory), builds the
+// handler stack and serves it until Shutdown.
+//
+//	srv, err := server.New(cfg)
+//	if err != nil { ... }
+//	if err := srv.Start(); err != nil { ... }
+//	defer srv.Shutdown(context.Background())
+package server
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/clementhaon/sandbox-api-go/app"
+	"github.com/clementhaon/sandbox-api-go/config"
+	"github.com/clementhaon/sandbox-api-go/database"
+	"github.com/clementhaon/sandbox-api-go/logger"
+	"github.com/clementhaon/sandbox-api-go/metrics"
+	"github.com/clementhaon/sandbox-api-go/repository"
+	"github.com/clementhaon/sandbox-api-go/storage"
+)
+
+// Option customizes a Server built by New.
+type Option func(*options)
+
+type options struct {
+	addr    string
+	db      *sql.DB
+	memory  *repository.MemoryStore
+	logger  *slog.Logger
+	metrics metrics.Registry
+	storage storage.StorageClient
+}
+
+// WithAddr sets the TCP listen address instead of ":<cfg.Port>", and takes
+// precedence over UNIX_SOCKET and systemd socket activation. Use
+// "127.0.0.1:0" to pick a free port, then read it back with Addr.
+func WithAddr(addr string) Option {
+	return func(o *options) { o.addr = addr }
+}
+
+// WithDB uses an existing database instead of opening one from the config.
+// Migrations are not run and Shutdown leaves the database open.
+func WithDB(db *sql.DB) Option {
+	return func(o *options) { o.db = db }
+}
+
+// WithMemory keeps the data in an existing store, as STORAGE=memory does.
+// MEMORY_SNAPSHOT_FILE is neither loaded nor saved; the caller owns store.
+func WithMemory(store *repository.MemoryStore) Option {
+	return func(o *options) { o.memory = store }
+}
+
+// WithLogger sends the request logs to l instead of the global logger.
+func WithLogger(l *slog.Logger) Option {
+	return func(o *options) { o.logger = l }
+}
+
+// WithMetrics registers the metrics of the server on reg, and serves it on
+// /metrics, instead of the default Prometheus registry. Servers running in
+// the same process each need their own registry.
+func WithMetrics(reg metrics.Registry) Option {
+	return func(o *options) { o.metrics = reg }
+}
+
+// WithStorage stores media in s instead of the MinIO bucket from the config.
+func WithStorage(s storage.StorageClient) Option {
+	return func(o *options) { o.storage = s }
+}
+
+// Server is an embeddable sandbox API.
+type Server struct {
+	app      *app.Server
+	db       *sql.DB
+	ownsDB   bool
+	memory   *repository.MemoryStore // nil unless STORAGE=memory
+	snapshot string                  // where memory is saved on Shutdown, if set
+	http     *http.Server
+	listen   func() ([]net.Listener, error)
+	mu       sync.Mutex
+	addr     net.Addr
+	stopped  bool
+}
+
+// New builds a Server from cfg. Unless WithDB is given it connects to the
+// configured database, runs the migrations and checks that the schema has
+// the tables and columns of repository.Schema; with STORAGE=memory it
+// loads MEMORY_SNAPSHOT_FILE, if any, instead. Nothing is served until Start.
+func New(cfg *config.Config, opts ...Option) (*Server, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("server: config is required")
+	}
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.metrics == nil {
+		o.metrics = metrics.DefaultRegistry()
+	}
+	m, err := metrics.New(o.metrics, metrics.Options{
+		LatencyBuckets:      cfg.LatencyBuckets,
+		LatencyGroupBuckets: cfg.LatencyGroupBuckets,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{db: o.db, memory: o.memory, listen: listeners(cfg, o.addr)}
+	if s.db == nil && s.memory == nil && cfg.InMemory() {
+		memory, err := openMemory(cfg.MemorySnapshotFile)
+		if err != nil {
+			return nil, err
+		}
+		s.memory, s.snapshot = memory, cfg.MemorySnapshotFile
+	} else if s.db == nil && s.memory == nil {
+		db, err := database.Open(cfg, m)
+		if err != nil {
+			return nil, err
+		}
+		// Fail fast on a schema that no longer matches the repositories,
+		// rather than on the requests using it
+		if err := database.CheckSchema(context.Background(), db, repository.Schema()); err != nil {
+			db.Close()
+			return nil, err
+		}
+		s.db, s.ownsDB = db, true
+	}
+
+	a, err := app.New(app.Deps{
+		Config:  cfg,
+		DB:      s.db,
+		Memory:  s.memory,
+		Logger:  o.logger,
+		Metrics: m,
+		Storage: o.storage,
+	})
+	if err != nil {
+		if s.ownsDB {
+			s.db.Close()
+		}
+		return nil, err
+	}
+	s.app = a
+
+	s.http = newHTTPServer(cfg, a.Handler(), m)
+	return s, nil
+}
+
+// openMemory returns a MemoryStore holding the snapshot saved at path, or
+// an empty one when there is none yet.
+func openMemory(path string) (*repository.MemoryStore, error) {
+	store := repository.NewMemoryStore()
+	if path == "" {
+		logger.Warn("Data is kept in memory and lost on exit: set MEMORY_SNAPSHOT_FILE to keep it")
+		return store, nil
+	}
+	loaded, err := store.Load(path)
+	if err != nil {
+		return nil, fmt.Errorf("MEMORY_SNAPSHOT_FILE: %w", err)
+	}
+	logger.Info("Data is kept in memory", map[string]interface{}{
+		"snapshot": path,
+		"loaded":   loaded,
+	})
+	return store, nil
+}
+
+// newHTTPServer applies the connection settings of cfg to an http.Server
+// counting its connections on m.
+func newHTTPServer(cfg *config.Config, handler http.Handler, m *metrics.Metrics) *http.Server {
+	var protocols http.Protocols
+	protocols.SetHTTP1(true)
+	protocols.SetUnencryptedHTTP2(cfg.HTTP2Enabled)
+
+	srv := &http.Server{
+		Handler:      handler,
+		ReadTimeout:  cfg.HTTPReadTimeout,
+		WriteTimeout: cfg.HTTPWriteTimeout,
+		IdleTimeout:  cfg.HTTPIdleTimeout,
+		Protocols:    &protocols,
+		HTTP2: &http.HTTP2Config{
+			MaxConcurrentStreams: cfg.HTTP2MaxConcurrentStreams,
+			SendPingTimeout:      cfg.HTTP2PingInterval,
+		},
+		ConnState: func(_ net.Conn, state http.ConnState) {
+			switch state {
+			case http.StateNew:
+				m.IncConnectionsOpen()
+			case http.StateHijacked, http.StateClosed:
+				m.DecConnectionsOpen()
+			}
+		},
+	}
+	srv.SetKeepAlivesEnabled(cfg.HTTPKeepAlives)
+	return srv
+}
+
+// Handler returns the API handler, e.g. to mount it in another mux or to
+// drive it with httptest without listening on a port.
+func (s *Server) Handler() http.Handler {
+	return s.app.Handler()
+}
+
+// Reload validates cfg and applies it to the running server; see
+// app.Server.Reload for what takes effect without a restart.
+func (s *Server) Reload(cfg *config.Config) error {
+	return s.app.Reload(cfg)
+}
+
+// listeners returns how Start opens its listeners: on addr if set, else on
+// the sockets passed by systemd, else on cfg.UnixSocket or cfg.Port.
+func listeners(cfg *config.Config, addr string) func() ([]net.Listener, error) {
+	return func() ([]net.Listener, error) {
+		addr := addr
+		if addr == "" {
+			if lns, err := systemdListeners(); err != nil || len(lns) > 0 {
+				return lns, err
+			}
+			if cfg.UnixSocket != "" {
+				ln, err := listenUnix(cfg.UnixSocket, cfg.UnixSocketMode)
+				if err != nil {
+					return nil, err
+				}
+				return []net.Listener{ln}, nil
+			}
+			addr = fmt.Sprintf(":%d", cfg.Port)
+		}
+		ln, err := net.Listen("tcp", addr)
+		if err != nil {
+			return nil, fmt.Errorf("listen on %s: %w", addr, err)
+		}
+		return []net.Listener{ln}, nil
+	}
+}
+
+// Start opens the listeners and serves in the background. It returns once
+// they are open, so requests can be sent right away.
+func (s *Server) Start() error {
+	lns, err := s.listen()
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.addr = lns[0].Addr()
+	s.mu.Unlock()
+
+	for _, ln := range lns {
+		go func() {
+			if err := s.http.Serve(ln); err != nil && err != http.ErrServerClosed {
+				logger.Error("HTTP server stopped", err)
+			}
+		}()
+	}
+	return nil
+}
+
+// Addr returns the address the server listens on (the first one when systemd
+// passed several), or "" before Start. It is a path for Unix sockets.
+func (s *Server) Addr() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.addr == nil {
+		return ""
+	}
+	return s.addr.String()
+}
+
+// Shutdown stops accepting connections, waits for in-flight requests until
+// ctx is done, then stops the background workers and closes the database if
+// New opened it, or saves the in-memory data to MEMORY_SNAPSHOT_FILE. It is
+// safe to call more than once.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	if s.stopped {
+		s.mu.Unlock()
+		return nil
+	}
+	s.stopped = true
+	s.mu.Unlock()
+
+	err := s.http.Shutdown(ctx)
+	s.app.Close()
+	if s.snapshot != "" {
+		if saveErr := s.memory.Save(s.snapshot); saveErr != nil {
+			saveErr = fmt.Errorf("save MEMORY_SNAPSHOT_FILE: %w", saveErr)
+			if err == nil {
+				err = saveErr
+			}
+		} else {
+			logger.Info("In-memory data saved", map[string]interface{}{"snapshot": s.snapshot})
+		}
+	}
+	if s.ownsDB {
+		if closeErr := s.db.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
@@ -0,0 +1,398 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/auth"
+	"github.com/clementhaon/sandbox-api-go/config"
+	"github.com/clementhaon/sandbox-api-go/mocks"
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/replay"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func testConfig() *config.Config {
+	return &config.Config{
+		JWTSecret:              "at-least-sixteen-chars",
+		Port:                   8080,
+		JWTExpiryHours:         24,
+		JWTRememberMeTTL:       30 * 24 * time.Hour,
+		MaxBodySize:            1 << 20,
+		MetricsCollectInterval: time.Hour,
+		RateLimitRequests:      100,
+		RateLimitWindow:        time.Minute,
+		ErrorFormat:            config.ErrorFormatJSON,
+	}
+}
+
+// memoryServer is a server on the in-memory store, serving requests
+// through its whole handler chain.
+type memoryServer struct {
+	*Server
+}
+
+// newMemoryServer starts cfg on the in-memory store, and shuts it down at
+// the end of the test.
+func newMemoryServer(t *testing.T, cfg *config.Config) memoryServer {
+	t.Helper()
+	cfg.Storage = config.StorageMemory
+	srv, err := New(cfg, WithMetrics(prometheus.NewRegistry()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { srv.Shutdown(context.Background()) })
+	return memoryServer{srv}
+}
+
+// serve sends a JSON request with a CSRF token, authenticated with bearer
+// unless it is empty.
+func (s memoryServer) serve(method, path, bearer, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+bearer)
+	}
+	req.AddCookie(&http.Cookie{Name: "csrf_token", Value: "csrf"})
+	req.Header.Set("X-CSRF-Token", "csrf")
+	resp := httptest.NewRecorder()
+	s.Handler().ServeHTTP(resp, req)
+	return resp
+}
+
+// register signs up alice and returns her login token.
+func register(t *testing.T, s memoryServer) string {
+	t.Helper()
+	resp := s.serve(http.MethodPost, "/auth/register", "", `{"username":"alice","email":"alice@example.com","password":"Sandbox-Passw0rd"}`)
+	for _, c := range resp.Result().Cookies() {
+		if c.Name == "auth_token" && resp.Code == http.StatusCreated {
+			return c.Value
+		}
+	}
+	t.Fatalf("expected 201 with a token on register, got %d: %s", resp.Code, resp.Body)
+	return ""
+}
+
+// registerWithToken signs up alice and returns her login token and a
+// personal token of hers restricted to tasks:read.
+func registerWithToken(t *testing.T, s memoryServer) (login, token string) {
+	t.Helper()
+	login = register(t, s)
+	resp := s.serve(http.MethodPost, "/tokens", login, `{"name":"CI","scopes":["tasks:read"],"expiresInDays":30}`)
+	var created struct {
+		Data struct {
+			Token string `json:"token"`
+		} `json:"data"`
+	}
+	if resp.Code != http.StatusCreated || json.Unmarshal(resp.Body.Bytes(), &created) != nil {
+		t.Fatalf("expected 201 on token creation, got %d: %s", resp.Code, resp.Body)
+	}
+	return login, created.Data.Token
+}
+
+func TestServer_StartAndShutdown(t *testing.T) {
+	// sql.Open does not connect; GET / never reaches the database
+	db, err := sql.Open("postgres", "host=127.0.0.1 port=1 sslmode=disable connect_timeout=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	cfg := testConfig()
+	srv, err := New(cfg,
+		WithAddr("127.0.0.1:0"),
+		WithDB(db),
+		WithMetrics(prometheus.NewRegistry()),
+		WithStorage(&mocks.MockStorage{}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if srv.Addr() != "" {
+		t.Errorf("expected no address before Start, got %q", srv.Addr())
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+
+	resp, err := http.Get("http://" + srv.Addr() + "/")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected 200, got %d", resp.StatusCode)
+	}
+
+	if err := srv.Shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+	if err := srv.Shutdown(context.Background()); err != nil {
+		t.Errorf("second shutdown: %v", err)
+	}
+	// The injected database is left open
+	if err := db.Ping(); err != nil && strings.Contains(err.Error(), "database is closed") {
+		t.Error("expected the injected database to stay open")
+	}
+	if _, err := http.Get("http://" + srv.Addr() + "/"); err == nil {
+		t.Error("expected requests to fail after shutdown")
+	}
+}
+
+func TestNew_RequiresConfig(t *testing.T) {
+	if _, err := New(nil); err == nil {
+		t.Error("expected an error without config")
+	}
+}
+
+func TestServer_HTTP2Cleartext(t *testing.T) {
+	db, err := sql.Open("postgres", "host=127.0.0.1 port=1 sslmode=disable connect_timeout=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	cfg := testConfig()
+	cfg.HTTP2Enabled = true
+	cfg.HTTPKeepAlives = true
+	srv, err := New(cfg,
+		WithAddr("127.0.0.1:0"),
+		WithDB(db),
+		WithMetrics(prometheus.NewRegistry()),
+		WithStorage(&mocks.MockStorage{}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	defer srv.Shutdown(context.Background())
+
+	var protocols http.Protocols
+	protocols.SetUnencryptedHTTP2(true)
+	client := &http.Client{Transport: &http.Transport{Protocols: &protocols}}
+	resp, err := client.Get("http://" + srv.Addr() + "/")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.ProtoMajor != 2 {
+		t.Errorf("expected HTTP/2, got %s", resp.Proto)
+	}
+}
+
+func TestServer_InMemory(t *testing.T) {
+	cfg := testConfig()
+	cfg.Storage = config.StorageMemory
+	cfg.MemorySnapshotFile = filepath.Join(t.TempDir(), "snapshot.json")
+	start := func() *Server {
+		srv, err := New(cfg, WithAddr("127.0.0.1:0"), WithMetrics(prometheus.NewRegistry()))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := srv.Start(); err != nil {
+			t.Fatalf("start: %v", err)
+		}
+		return srv
+	}
+	post := func(srv *Server, path, body string) int {
+		resp, err := http.Post("http://"+srv.Addr()+path, "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	srv := start()
+	if status := post(srv, "/auth/register", `{"username":"alice","email":"alice@example.com","password":"Sandbox-Passw0rd"}`); status != http.StatusCreated {
+		t.Fatalf("expected 201 on register, got %d", status)
+	}
+	if err := srv.Shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+
+	// The account survives the restart through the snapshot
+	srv = start()
+	defer srv.Shutdown(context.Background())
+	if status := post(srv, "/auth/login", `{"email":"alice@example.com","password":"Sandbox-Passw0rd"}`); status != http.StatusOK {
+		t.Errorf("expected 200 on login after a restart, got %d", status)
+	}
+}
+
+func TestServer_RecordAndReplay(t *testing.T) {
+	cfg := testConfig()
+	cfg.Storage = config.StorageMemory
+	cfg.RecordFile = filepath.Join(t.TempDir(), "requests.log")
+	srv, err := New(cfg, WithMetrics(prometheus.NewRegistry()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	register := httptest.NewRequest(http.MethodPost, "/auth/register", strings.NewReader(`{"username":"alice","email":"alice@example.com","password":"Sandbox-Passw0rd"}`))
+	register.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(resp, register)
+	if resp.Code != http.StatusCreated {
+		t.Fatalf("expected 201 on register, got %d: %s", resp.Code, resp.Body)
+	}
+	create := httptest.NewRequest(http.MethodPost, "/tasks", strings.NewReader(`{"title":"Write docs","columnId":1}`))
+	create.Header.Set("Content-Type", "application/json")
+	for _, c := range resp.Result().Cookies() {
+		create.AddCookie(c)
+		if c.Name == "csrf_token" {
+			create.Header.Set("X-CSRF-Token", c.Value)
+		}
+	}
+	resp = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(resp, create)
+	if resp.Code != http.StatusCreated {
+		t.Fatalf("expected 201 on task creation, got %d: %s", resp.Code, resp.Body)
+	}
+	srv.Shutdown(context.Background())
+
+	// Play the log against a fresh instance
+	f, err := os.Open(cfg.RecordFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := replay.ReadLog(f)
+	f.Close()
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("expected 2 recorded requests, got %d (%v)", len(entries), err)
+	}
+
+	fresh := testConfig()
+	fresh.Storage = config.StorageMemory
+	replayed, err := New(fresh, WithMetrics(prometheus.NewRegistry()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer replayed.Shutdown(context.Background())
+	jwtManager, _ := auth.NewJWTManager(fresh.JWTSecret)
+	player := replay.NewPlayer(replayed.Handler(), jwtManager, "")
+	for _, e := range entries {
+		if status, err := player.Play(e); err != nil || status != e.Status {
+			t.Fatalf("%s %s: expected status %d, got %d (%v)", e.Method, e.Path, e.Status, status, err)
+		}
+	}
+
+	token, _ := jwtManager.GenerateToken(models.User{ID: entries[1].User.UserID, TenantID: entries[1].User.TenantID, Username: "alice"})
+	list := httptest.NewRequest(http.MethodGet, "/tasks", nil)
+	list.Header.Set("Authorization", "Bearer "+token)
+	resp = httptest.NewRecorder()
+	replayed.Handler().ServeHTTP(resp, list)
+	if resp.Code != http.StatusOK || !strings.Contains(resp.Body.String(), "Write docs") {
+		t.Errorf("expected the replayed task, got %d: %s", resp.Code, resp.Body)
+	}
+}
+
+func TestServer_SandboxSettings(t *testing.T) {
+	srv := newMemoryServer(t, testConfig())
+	login, token := registerWithToken(t, srv)
+
+	if resp := srv.serve(http.MethodPut, "/sandbox/settings", token, `{"statusCode":503}`); resp.Code != http.StatusOK {
+		t.Fatalf("expected 200 on the settings update, got %d: %s", resp.Code, resp.Body)
+	}
+	if resp := srv.serve(http.MethodGet, "/tasks", token, ""); resp.Code != http.StatusServiceUnavailable || !strings.Contains(resp.Body.String(), "SIMULATED_ERROR") {
+		t.Errorf("expected a simulated 503 with the token, got %d: %s", resp.Code, resp.Body)
+	}
+	if resp := srv.serve(http.MethodGet, "/tasks", login, ""); resp.Code != http.StatusOK {
+		t.Errorf("expected the login to be unaffected, got %d: %s", resp.Code, resp.Body)
+	}
+	if resp := srv.serve(http.MethodGet, "/sandbox/settings", token, ""); resp.Code != http.StatusOK || !strings.Contains(resp.Body.String(), `"statusCode":503`) {
+		t.Errorf("expected the settings served to the failing token, got %d: %s", resp.Code, resp.Body)
+	}
+	if resp := srv.serve(http.MethodGet, "/sandbox/settings", login, ""); resp.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for settings without a personal token, got %d: %s", resp.Code, resp.Body)
+	}
+}
+
+func TestServer_SandboxClock(t *testing.T) {
+	srv := newMemoryServer(t, testConfig())
+	login, token := registerWithToken(t, srv)
+
+	// Forty days later, past the expiry of the token on the moved clock
+	const offset = 40 * 24 * time.Hour
+	if resp := srv.serve(http.MethodPost, "/sandbox/clock", login, fmt.Sprintf(`{"offsetSeconds":%d}`, int64(offset/time.Second))); resp.Code != http.StatusOK {
+		t.Fatalf("expected 200 on the clock update, got %d: %s", resp.Code, resp.Body)
+	}
+	if resp := srv.serve(http.MethodGet, "/tasks", token, ""); resp.Code != http.StatusUnauthorized || !strings.Contains(resp.Body.String(), "TOKEN_EXPIRED") {
+		t.Errorf("expected the token expired on the moved clock, got %d: %s", resp.Code, resp.Body)
+	}
+
+	resp := srv.serve(http.MethodPost, "/tasks", login, `{"title":"Later","columnId":1}`)
+	var task struct {
+		Data models.Task `json:"data"`
+	}
+	if resp.Code != http.StatusCreated || json.Unmarshal(resp.Body.Bytes(), &task) != nil {
+		t.Fatalf("expected 201 on task creation, got %d: %s", resp.Code, resp.Body)
+	}
+	if task.Data.SandboxCreatedAt == nil {
+		t.Fatal("expected the task creation time on the sandbox clock")
+	}
+	if ahead := time.Until(*task.Data.SandboxCreatedAt); ahead < offset-time.Minute || ahead > offset {
+		t.Errorf("expected the task created forty days ahead on the sandbox clock, got %v", ahead)
+	}
+	// The real timestamps stay real, so the change feed of other users is not
+	// moved ahead
+	if since := time.Since(task.Data.UpdatedAt); since < 0 || since > time.Minute {
+		t.Errorf("expected the task updated now, got %v", task.Data.UpdatedAt)
+	}
+
+	// Back to the real time
+	if resp := srv.serve(http.MethodPost, "/sandbox/clock", login, `{"offsetSeconds":0}`); resp.Code != http.StatusOK {
+		t.Fatalf("expected 200 on the clock reset, got %d: %s", resp.Code, resp.Body)
+	}
+	if resp := srv.serve(http.MethodGet, "/tasks", token, ""); resp.Code != http.StatusOK {
+		t.Errorf("expected the token valid again, got %d: %s", resp.Code, resp.Body)
+	}
+	resp = srv.serve(http.MethodPost, "/tasks", login, `{"title":"Now","columnId":1}`)
+	task.Data = models.Task{}
+	if resp.Code != http.StatusCreated || json.Unmarshal(resp.Body.Bytes(), &task) != nil {
+		t.Fatalf("expected 201 on task creation, got %d: %s", resp.Code, resp.Body)
+	}
+	if task.Data.SandboxCreatedAt != nil {
+		t.Errorf("expected no sandbox creation time on the real clock, got %v", task.Data.SandboxCreatedAt)
+	}
+}
+
+func TestServer_DailyRequestQuota(t *testing.T) {
+	cfg := testConfig()
+	cfg.QuotaMaxDailyRequests = 3
+	srv := newMemoryServer(t, cfg)
+	login := register(t, srv)
+
+	for i := 0; i < 3; i++ {
+		if resp := srv.serve(http.MethodGet, "/tasks", login, ""); resp.Code != http.StatusOK {
+			t.Fatalf("request %d: expected 200 under the quota, got %d: %s", i+1, resp.Code, resp.Body)
+		}
+	}
+	resp := srv.serve(http.MethodGet, "/tasks", login, "")
+	if resp.Code != http.StatusTooManyRequests || !strings.Contains(resp.Body.String(), "QUOTA_EXCEEDED") || resp.Header().Get("Retry-After") == "" {
+		t.Errorf("expected a 429 with a Retry-After once the quota is reached, got %d: %s", resp.Code, resp.Body)
+	}
+
+	// The usage report stays readable, and the refused request is not counted
+	resp = srv.serve(http.MethodGet, "/usage", login, "")
+	var usage struct {
+		Data models.UsageReport `json:"data"`
+	}
+	if resp.Code != http.StatusOK || json.Unmarshal(resp.Body.Bytes(), &usage) != nil {
+		t.Fatalf("expected 200 on the usage report, got %d: %s", resp.Code, resp.Body)
+	}
+	if usage.Data.Today.Requests != 3 || usage.Data.MaxDailyRequests != 3 || usage.Data.Today.BytesOut == 0 {
+		t.Errorf("expected 3 requests counted against a quota of 3, got %+v", usage.Data)
+	}
+}
@@ -0,0 +1,213 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/database"
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/logger"
+	"github.com/clementhaon/sandbox-api-go/mailer"
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/repository"
+	"github.com/clementhaon/sandbox-api-go/validation"
+)
+
+// emailChangeTTL is how long an email verification token stays valid.
+const emailChangeTTL = 24 * time.Hour
+
+// AccountService changes the identifiers of an account (email and username),
+// which are otherwise fixed at registration.
+type AccountService interface {
+	// RequestEmailChange sends a verification token to the new address. The
+	// email is only switched once ConfirmEmailChange is called with it.
+	RequestEmailChange(ctx context.Context, userID int, req models.ChangeEmailRequest) error
+	ConfirmEmailChange(ctx context.Context, req models.ConfirmEmailChangeRequest) (models.User, error)
+	ChangeUsername(ctx context.Context, userID int, req models.ChangeUsernameRequest) (models.User, error)
+}
+
+// VerificationSender delivers email verification tokens.
+type VerificationSender interface {
+	SendEmailChangeVerification(ctx context.Context, to, token string) error
+}
+
+type accountService struct {
+	userRepo        repository.UserRepository
+	emailChangeRepo repository.EmailChangeRepository
+	txManager       database.Transactor
+	sender          VerificationSender
+	auditSvc        AuditService
+}
+
+func NewAccountService(userRepo repository.UserRepository, emailChangeRepo repository.EmailChangeRepository, txManager database.Transactor, sender VerificationSender, auditSvc AuditService) AccountService {
+	return &accountService{
+		userRepo:        userRepo,
+		emailChangeRepo: emailChangeRepo,
+		txManager:       txManager,
+		sender:          sender,
+		auditSvc:        auditSvc,
+	}
+}
+
+func (s *accountService) RequestEmailChange(ctx context.Context, userID int, req models.ChangeEmailRequest) error {
+	req.Email = strings.TrimSpace(req.Email)
+	if err := validation.Struct(req); err != nil {
+		return err
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if strings.EqualFold(user.Email, req.Email) {
+		return errors.NewBadRequestError("New email is the same as the current one")
+	}
+	exists, err := s.userRepo.ExistsByUsernameOrEmail(ctx, "", req.Email)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return errors.NewConflictError("Email is already in use")
+	}
+
+	token, err := newVerificationToken()
+	if err != nil {
+		logger.ErrorContext(ctx, "Error generating verification token", err)
+		return errors.NewInternalError().WithCause(err)
+	}
+	if err := s.emailChangeRepo.Upsert(ctx, models.EmailChange{
+		UserID:    userID,
+		NewEmail:  req.Email,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(emailChangeTTL),
+	}); err != nil {
+		return err
+	}
+
+	if err := s.sender.SendEmailChangeVerification(ctx, req.Email, token); err != nil {
+		logger.ErrorContext(ctx, "Error sending email verification", err)
+		return errors.NewServiceUnavailableError().WithCause(err)
+	}
+
+	s.auditSvc.Record(ctx, models.AuditEntry{
+		Action:     models.AuditActionEmailChangeReq,
+		TargetType: "user",
+		TargetID:   &userID,
+	})
+	return nil
+}
+
+func (s *accountService) ConfirmEmailChange(ctx context.Context, req models.ConfirmEmailChangeRequest) (models.User, error) {
+	if err := validation.Struct(req); err != nil {
+		return models.User{}, err
+	}
+
+	change, err := s.emailChangeRepo.GetByTokenHash(ctx, hashToken(req.Token))
+	if errors.Is(err, errors.ErrNotFound) {
+		return models.User{}, errors.NewInvalidTokenError()
+	}
+	if err != nil {
+		return models.User{}, err
+	}
+	if time.Now().After(change.ExpiresAt) {
+		return models.User{}, errors.NewTokenExpiredError()
+	}
+
+	// The address may have been taken since the change was requested
+	exists, err := s.userRepo.ExistsByUsernameOrEmail(ctx, "", change.NewEmail)
+	if err != nil {
+		return models.User{}, err
+	}
+	if exists {
+		return models.User{}, errors.NewConflictError("Email is already in use")
+	}
+
+	var user models.User
+	err = s.txManager.WithTransaction(ctx, func(q database.Querier) error {
+		var updateErr error
+		user, updateErr = s.userRepo.WithQuerier(q).Update(ctx, change.UserID, models.UpdateUserRequest{Email: change.NewEmail})
+		if updateErr != nil {
+			return updateErr
+		}
+		return s.emailChangeRepo.WithQuerier(q).Delete(ctx, change.UserID)
+	})
+	if err != nil {
+		return models.User{}, err
+	}
+
+	s.auditSvc.Record(ctx, models.AuditEntry{
+		ActorID:    &user.ID,
+		Action:     models.AuditActionEmailChange,
+		TargetType: "user",
+		TargetID:   &user.ID,
+	})
+	return user, nil
+}
+
+func (s *accountService) ChangeUsername(ctx context.Context, userID int, req models.ChangeUsernameRequest) (models.User, error) {
+	req.Username = strings.TrimSpace(req.Username)
+	if err := validation.Struct(req); err != nil {
+		return models.User{}, err
+	}
+
+	exists, err := s.userRepo.ExistsByUsernameOrEmail(ctx, req.Username, "")
+	if err != nil {
+		return models.User{}, err
+	}
+	if exists {
+		return models.User{}, errors.NewConflictError("Username is already taken")
+	}
+
+	user, err := s.userRepo.Update(ctx, userID, models.UpdateUserRequest{Username: req.Username})
+	if err != nil {
+		return models.User{}, err
+	}
+
+	s.auditSvc.Record(ctx, models.AuditEntry{
+		Action:     models.AuditActionUsernameChange,
+		TargetType: "user",
+		TargetID:   &userID,
+		Metadata:   map[string]interface{}{"username": user.Username},
+	})
+	return user, nil
+}
+
+// newVerificationToken returns a random token; only its hash is stored.
+func newVerificationToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// mailVerificationSender emails verification tokens.
+type mailVerificationSender struct {
+	mailer mailer.Mailer
+}
+
+func NewMailVerificationSender(m mailer.Mailer) VerificationSender {
+	return &mailVerificationSender{mailer: m}
+}
+
+func (s *mailVerificationSender) SendEmailChangeVerification(ctx context.Context, to, token string) error {
+	msg, err := mailer.Render(to, mailer.TemplateEmailChange, map[string]interface{}{
+		"Email":    to,
+		"Token":    token,
+		"ValidFor": fmt.Sprintf("%d hours", int(emailChangeTTL.Hours())),
+	})
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(ctx, msg)
+}
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/mocks"
+	"github.com/clementhaon/sandbox-api-go/models"
+)
+
+func TestAccountService_EmailChange_RequestThenConfirm(t *testing.T) {
+	var stored models.EmailChange
+	var sentTo, sentToken string
+	var updated models.UpdateUserRequest
+	deleted := false
+
+	userRepo := &mocks.MockUserRepository{
+		GetByIDFn: func(ctx context.Context, id int) (models.User, error) {
+			return models.User{ID: id, Email: "old@test.com"}, nil
+		},
+		ExistsByUsernameOrEmailFn: func(ctx context.Context, username, email string) (bool, error) {
+			return false, nil
+		},
+		UpdateFn: func(ctx context.Context, id int, req models.UpdateUserRequest) (models.User, error) {
+			updated = req
+			return models.User{ID: id, Email: req.Email}, nil
+		},
+	}
+	changeRepo := &mocks.MockEmailChangeRepository{
+		UpsertFn: func(ctx context.Context, change models.EmailChange) error {
+			stored = change
+			return nil
+		},
+		GetByTokenHashFn: func(ctx context.Context, tokenHash string) (models.EmailChange, error) {
+			if tokenHash != stored.TokenHash {
+				return models.EmailChange{}, errors.NewNotFoundError("Email change")
+			}
+			return stored, nil
+		},
+		DeleteFn: func(ctx context.Context, userID int) error {
+			deleted = true
+			return nil
+		},
+	}
+	sender := &mocks.MockVerificationSender{
+		SendFn: func(ctx context.Context, to, token string) error {
+			sentTo, sentToken = to, token
+			return nil
+		},
+	}
+
+	svc := NewAccountService(userRepo, changeRepo, &mocks.MockTransactor{}, sender, &mocks.MockAuditService{})
+	if err := svc.RequestEmailChange(context.Background(), 1, models.ChangeEmailRequest{Email: "new@test.com"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sentTo != "new@test.com" || sentToken == "" {
+		t.Fatalf("expected verification sent to new address, got to=%q", sentTo)
+	}
+	if stored.TokenHash == sentToken {
+		t.Error("token must be stored hashed")
+	}
+	if updated.Email != "" {
+		t.Error("email must not change before confirmation")
+	}
+
+	if _, err := svc.ConfirmEmailChange(context.Background(), models.ConfirmEmailChangeRequest{Token: "wrong"}); !errors.Is(err, errors.ErrInvalidToken) {
+		t.Errorf("expected invalid token, got %v", err)
+	}
+
+	user, err := svc.ConfirmEmailChange(context.Background(), models.ConfirmEmailChangeRequest{Token: sentToken})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if user.Email != "new@test.com" || !deleted {
+		t.Errorf("expected email switched and pending change removed, got %q deleted=%v", user.Email, deleted)
+	}
+}
+
+func TestAccountService_RequestEmailChange_Rejected(t *testing.T) {
+	tests := []struct {
+		name     string
+		email    string
+		exists   bool
+		wantCode errors.ErrorCode
+	}{
+		{"invalid email", "nope", false, errors.ErrValidationFailed},
+		{"same email", "OLD@test.com", false, errors.ErrValidationFailed},
+		{"email taken", "taken@test.com", true, errors.ErrConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userRepo := &mocks.MockUserRepository{
+				GetByIDFn: func(ctx context.Context, id int) (models.User, error) {
+					return models.User{ID: id, Email: "old@test.com"}, nil
+				},
+				ExistsByUsernameOrEmailFn: func(ctx context.Context, username, email string) (bool, error) {
+					return tt.exists, nil
+				},
+			}
+			svc := NewAccountService(userRepo, &mocks.MockEmailChangeRepository{}, &mocks.MockTransactor{}, &mocks.MockVerificationSender{}, &mocks.MockAuditService{})
+
+			err := svc.RequestEmailChange(context.Background(), 1, models.ChangeEmailRequest{Email: tt.email})
+			if !errors.Is(err, tt.wantCode) {
+				t.Errorf("expected %s, got %v", tt.wantCode, err)
+			}
+		})
+	}
+}
+
+func TestAccountService_ConfirmEmailChange_Expired(t *testing.T) {
+	changeRepo := &mocks.MockEmailChangeRepository{
+		GetByTokenHashFn: func(ctx context.Context, tokenHash string) (models.EmailChange, error) {
+			return models.EmailChange{UserID: 1, NewEmail: "new@test.com", ExpiresAt: time.Now().Add(-time.Minute)}, nil
+		},
+	}
+	svc := NewAccountService(&mocks.MockUserRepository{}, changeRepo, &mocks.MockTransactor{}, &mocks.MockVerificationSender{}, &mocks.MockAuditService{})
+
+	_, err := svc.ConfirmEmailChange(context.Background(), models.ConfirmEmailChangeRequest{Token: "abc"})
+	if !errors.Is(err, errors.ErrTokenExpired) {
+		t.Errorf("expected token expired, got %v", err)
+	}
+}
+
+func TestAccountService_ChangeUsername(t *testing.T) {
+	tests := []struct {
+		name     string
+		username string
+		exists   bool
+		wantCode errors.ErrorCode
+	}{
+		{"success", "new_name", false, ""},
+		{"invalid format", "1abc", false, errors.ErrValidationFailed},
+		{"taken", "alice", true, errors.ErrConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userRepo := &mocks.MockUserRepository{
+				ExistsByUsernameOrEmailFn: func(ctx context.Context, username, email string) (bool, error) {
+					return tt.exists, nil
+				},
+				UpdateFn: func(ctx context.Context, id int, req models.UpdateUserRequest) (models.User, error) {
+					return models.User{ID: id, Username: req.Username}, nil
+				},
+			}
+			svc := NewAccountService(userRepo, &mocks.MockEmailChangeRepository{}, &mocks.MockTransactor{}, &mocks.MockVerificationSender{}, &mocks.MockAuditService{})
+
+			user, err := svc.ChangeUsername(context.Background(), 1, models.ChangeUsernameRequest{Username: tt.username})
+			if tt.wantCode != "" {
+				if !errors.Is(err, tt.wantCode) {
+					t.Errorf("expected %s, got %v", tt.wantCode, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if user.Username != tt.username {
+				t.Errorf("expected username %q, got %q", tt.username, user.Username)
+			}
+		})
+	}
+}
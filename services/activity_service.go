@@ -0,0 +1,76 @@
+package services
+
+import (
+	"context"
+	"slices"
+
+	"github.com/clementhaon/sandbox-api-go/events"
+	"github.com/clementhaon/sandbox-api-go/logger"
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/repository"
+)
+
+type ActivityService interface {
+	// Feed returns a page of a user's activity feed, newest first, starting
+	// before the cursor.
+	Feed(ctx context.Context, userID int, before *models.TaskCursor, limit int) ([]models.Activity, models.CursorPage, error)
+	// Record is an events.Handler adding an event to the feeds of the user
+	// who caused it and of the task's watchers.
+	Record(ctx context.Context, e events.Event)
+}
+
+type activityService struct {
+	activityRepo repository.ActivityRepository
+}
+
+func NewActivityService(activityRepo repository.ActivityRepository) ActivityService {
+	return &activityService{activityRepo: activityRepo}
+}
+
+const (
+	defaultActivityLimit = 50
+	maxActivityLimit     = 200
+)
+
+func (s *activityService) Feed(ctx context.Context, userID int, before *models.TaskCursor, limit int) ([]models.Activity, models.CursorPage, error) {
+	if limit < 1 {
+		limit = defaultActivityLimit
+	}
+	if limit > maxActivityLimit {
+		limit = maxActivityLimit
+	}
+
+	// Fetch one extra row to know whether another page follows
+	activities, err := s.activityRepo.ListForUser(ctx, userID, before, limit+1)
+	if err != nil {
+		return nil, models.CursorPage{}, err
+	}
+
+	page := models.CursorPage{Limit: limit}
+	if len(activities) > limit {
+		activities = activities[:limit]
+		page.HasMore = true
+		last := activities[len(activities)-1]
+		page.NextCursor = models.TaskCursor{UpdatedAt: last.CreatedAt, ID: last.ID}.Encode()
+	}
+	return activities, page, nil
+}
+
+func (s *activityService) Record(ctx context.Context, e events.Event) {
+	users := e.Watchers()
+	if !slices.Contains(users, e.ActorID) {
+		users = append(users, e.ActorID)
+	}
+
+	taskID := e.Task.ID
+	actorID := e.ActorID
+	err := s.activityRepo.Create(ctx, users, models.Activity{
+		Type:      string(e.Type),
+		ActorID:   &actorID,
+		TaskID:    &taskID,
+		TaskTitle: e.Task.Title,
+	})
+	if err != nil {
+		logger.ErrorContext(ctx, "Error recording activity", err)
+	}
+}
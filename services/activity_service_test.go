@@ -0,0 +1,61 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/events"
+	"github.com/clementhaon/sandbox-api-go/mocks"
+	"github.com/clementhaon/sandbox-api-go/models"
+)
+
+func TestActivityService_Record(t *testing.T) {
+	var users []int
+	var recorded models.Activity
+	repo := &mocks.MockActivityRepository{
+		CreateFn: func(ctx context.Context, userIDs []int, a models.Activity) error {
+			users, recorded = userIDs, a
+			return nil
+		},
+	}
+	svc := NewActivityService(repo)
+
+	assignee := 3 // the owner, listed once
+	svc.Record(context.Background(), events.Event{
+		Type:    events.TaskCreated,
+		ActorID: 5,
+		Task:    models.Task{ID: 9, Title: "Plan", UserID: 3, AssigneeID: &assignee},
+	})
+	if len(users) != 2 || users[0] != 3 || users[1] != 5 {
+		t.Errorf("expected the feeds of users 3 and 5, got %v", users)
+	}
+	if recorded.Type != "task_created" || *recorded.TaskID != 9 || *recorded.ActorID != 5 {
+		t.Errorf("unexpected activity %+v", recorded)
+	}
+}
+
+func TestActivityService_Feed_Pages(t *testing.T) {
+	now := time.Now()
+	repo := &mocks.MockActivityRepository{
+		ListForUserFn: func(ctx context.Context, userID int, before *models.TaskCursor, limit int) ([]models.Activity, error) {
+			if limit != 3 {
+				t.Errorf("expected one extra row to be fetched, got limit %d", limit)
+			}
+			return []models.Activity{{ID: 4, CreatedAt: now}, {ID: 3, CreatedAt: now}, {ID: 2, CreatedAt: now}}, nil
+		},
+	}
+	svc := NewActivityService(repo)
+
+	activities, page, err := svc.Feed(context.Background(), 1, nil, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(activities) != 2 || !page.HasMore {
+		t.Fatalf("expected 2 activities and more to come, got %d, %+v", len(activities), page)
+	}
+	cursor, err := models.DecodeTaskCursor(page.NextCursor)
+	if err != nil || cursor.ID != 3 {
+		t.Errorf("expected the next page to start before activity 3, got %+v, %v", cursor, err)
+	}
+}
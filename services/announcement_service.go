@@ -0,0 +1,73 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/repository"
+	"github.com/clementhaon/sandbox-api-go/sanitize"
+	"github.com/clementhaon/sandbox-api-go/validation"
+)
+
+type AnnouncementService interface {
+	// ListActive returns the announcements to show to a user.
+	ListActive(ctx context.Context, userID int) ([]models.Announcement, error)
+	List(ctx context.Context) ([]models.Announcement, error)
+	Create(ctx context.Context, userID int, req models.AnnouncementRequest) (models.Announcement, error)
+	Update(ctx context.Context, id int, req models.AnnouncementRequest) (models.Announcement, error)
+	Delete(ctx context.Context, id int) error
+	Dismiss(ctx context.Context, id int, userID int) error
+}
+
+type announcementService struct {
+	announcementRepo repository.AnnouncementRepository
+}
+
+func NewAnnouncementService(announcementRepo repository.AnnouncementRepository) AnnouncementService {
+	return &announcementService{announcementRepo: announcementRepo}
+}
+
+func (s *announcementService) ListActive(ctx context.Context, userID int) ([]models.Announcement, error) {
+	return s.announcementRepo.ListActive(ctx, userID)
+}
+
+func (s *announcementService) List(ctx context.Context) ([]models.Announcement, error) {
+	return s.announcementRepo.List(ctx)
+}
+
+func (s *announcementService) Create(ctx context.Context, userID int, req models.AnnouncementRequest) (models.Announcement, error) {
+	if err := prepareAnnouncement(&req); err != nil {
+		return models.Announcement{}, err
+	}
+	return s.announcementRepo.Create(ctx, userID, req)
+}
+
+func (s *announcementService) Update(ctx context.Context, id int, req models.AnnouncementRequest) (models.Announcement, error) {
+	if err := prepareAnnouncement(&req); err != nil {
+		return models.Announcement{}, err
+	}
+	return s.announcementRepo.Update(ctx, id, req)
+}
+
+func (s *announcementService) Delete(ctx context.Context, id int) error {
+	return s.announcementRepo.Delete(ctx, id)
+}
+
+func (s *announcementService) Dismiss(ctx context.Context, id int, userID int) error {
+	return s.announcementRepo.Dismiss(ctx, id, userID)
+}
+
+// prepareAnnouncement sanitizes and validates req, starting it now if it
+// has no start.
+func prepareAnnouncement(req *models.AnnouncementRequest) error {
+	if req.StartsAt == nil {
+		now := time.Now().UTC()
+		req.StartsAt = &now
+	}
+	sanitize.Struct(req)
+	if err := validation.Struct(req); err != nil {
+		return err
+	}
+	return nil
+}
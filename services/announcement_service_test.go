@@ -0,0 +1,71 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/mocks"
+	"github.com/clementhaon/sandbox-api-go/models"
+)
+
+func TestAnnouncementService_Create_StartsNow(t *testing.T) {
+	var received models.AnnouncementRequest
+	repo := &mocks.MockAnnouncementRepository{
+		CreateFn: func(ctx context.Context, createdBy int, req models.AnnouncementRequest) (models.Announcement, error) {
+			received = req
+			return models.Announcement{ID: 1}, nil
+		},
+	}
+
+	_, err := NewAnnouncementService(repo).Create(context.Background(), 1, models.AnnouncementRequest{
+		Title: "Hello", Body: "World", Level: models.AnnouncementLevelInfo,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if received.StartsAt == nil || time.Since(*received.StartsAt) > time.Minute {
+		t.Errorf("expected the announcement to start now, got %v", received.StartsAt)
+	}
+}
+
+func TestAnnouncementService_Create_KeepsBodyLines(t *testing.T) {
+	var received models.AnnouncementRequest
+	repo := &mocks.MockAnnouncementRepository{
+		CreateFn: func(ctx context.Context, createdBy int, req models.AnnouncementRequest) (models.Announcement, error) {
+			received = req
+			return models.Announcement{ID: 1}, nil
+		},
+	}
+
+	_, err := NewAnnouncementService(repo).Create(context.Background(), 1, models.AnnouncementRequest{
+		Title: "Maintenance", Body: "Tonight 22:00-23:00\n<b>Exports</b> are paused", Level: models.AnnouncementLevelMaintenance,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "Tonight 22:00-23:00\nExports are paused"; received.Body != want {
+		t.Errorf("expected body %q, got %q", want, received.Body)
+	}
+}
+
+func TestAnnouncementService_Create_Validation(t *testing.T) {
+	start := time.Now()
+	before := start.Add(-time.Hour)
+	tests := map[string]models.AnnouncementRequest{
+		"missing title": {Body: "b", Level: models.AnnouncementLevelInfo},
+		"unknown level": {Title: "t", Body: "b", Level: "urgent"},
+		"ends first":    {Title: "t", Body: "b", Level: models.AnnouncementLevelInfo, StartsAt: &start, EndsAt: &before},
+	}
+
+	svc := NewAnnouncementService(&mocks.MockAnnouncementRepository{})
+	for name, req := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := svc.Create(context.Background(), 1, req)
+			if !errors.Is(err, errors.ErrValidationFailed) {
+				t.Errorf("expected a validation error, got %v", err)
+			}
+		})
+	}
+}
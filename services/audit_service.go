@@ -0,0 +1,157 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/logger"
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/repository"
+)
+
+type AuditService interface {
+	Record(ctx context.Context, entry models.AuditEntry)
+	List(ctx context.Context, params models.AuditLogListParams) (models.AuditLogListResponse, error)
+	Purge(ctx context.Context, retention time.Duration) (int64, error)
+}
+
+type auditService struct {
+	auditRepo repository.AuditRepository
+}
+
+func NewAuditService(auditRepo repository.AuditRepository) AuditService {
+	return &auditService{auditRepo: auditRepo}
+}
+
+// Record stores an audit entry. Failures are logged but never returned so that
+// auditing cannot break the operation being audited.
+func (s *auditService) Record(ctx context.Context, entry models.AuditEntry) {
+	log := models.AuditLog{
+		ActorID:    entry.ActorID,
+		Action:     entry.Action,
+		TargetType: entry.TargetType,
+		TargetID:   entry.TargetID,
+	}
+
+	if log.ActorID == nil {
+		if uid, ok := ctx.Value(logger.UserIDKey).(int); ok {
+			log.ActorID = &uid
+		}
+	}
+	if ip, ok := ctx.Value(logger.ClientIPKey).(string); ok {
+		log.IPAddress = ip
+	}
+	if ua, ok := ctx.Value(logger.UserAgentKey).(string); ok {
+		log.UserAgent = ua
+	}
+
+	// Actions taken with an impersonation token name the admin behind them
+	if impersonatorID, ok := ctx.Value(logger.ImpersonatorIDKey).(int); ok {
+		entry.Metadata = maps.Clone(entry.Metadata)
+		if entry.Metadata == nil {
+			entry.Metadata = map[string]interface{}{}
+		}
+		entry.Metadata["impersonatorId"] = impersonatorID
+	}
+
+	if len(entry.Metadata) > 0 {
+		metadata, err := json.Marshal(entry.Metadata)
+		if err != nil {
+			logger.WarnContext(ctx, "Failed to encode audit metadata", map[string]interface{}{
+				"action": entry.Action,
+				"error":  err.Error(),
+			})
+		} else {
+			log.Metadata = metadata
+		}
+	}
+
+	if err := s.auditRepo.Create(ctx, log); err != nil {
+		logger.ErrorContext(ctx, "Failed to record audit log", err, map[string]interface{}{
+			"action": entry.Action,
+		})
+	}
+}
+
+func (s *auditService) List(ctx context.Context, params models.AuditLogListParams) (models.AuditLogListResponse, error) {
+	if params.Page < 1 {
+		params.Page = 1
+	}
+	if params.PageSize < 1 || params.PageSize > 100 {
+		params.PageSize = 50
+	}
+
+	logs, total, err := s.auditRepo.List(ctx, params)
+	if err != nil {
+		return models.AuditLogListResponse{}, err
+	}
+
+	totalPages := (total + params.PageSize - 1) / params.PageSize
+	return models.AuditLogListResponse{
+		Data: logs,
+		Pagination: models.Pagination{
+			Page:       params.Page,
+			PageSize:   params.PageSize,
+			Total:      total,
+			TotalPages: totalPages,
+		},
+	}, nil
+}
+
+func (s *auditService) Purge(ctx context.Context, retention time.Duration) (int64, error) {
+	deleted, err := s.auditRepo.DeleteOlderThan(ctx, time.Now().Add(-retention))
+	if err != nil {
+		return 0, err
+	}
+	if deleted > 0 {
+		logger.InfoContext(ctx, "Purged expired audit logs", map[string]interface{}{
+			"deleted":   deleted,
+			"retention": retention.String(),
+		})
+	}
+	return deleted, nil
+}
+
+// AuditRetention periodically deletes audit logs older than the retention period.
+type AuditRetention struct {
+	svc       AuditService
+	retention time.Duration
+	stopCh    chan struct{}
+	stopOnce  sync.Once
+}
+
+// StartAuditRetention starts the purge loop. A non-positive retention keeps logs forever.
+func StartAuditRetention(svc AuditService, retention, interval time.Duration) *AuditRetention {
+	ar := &AuditRetention{
+		svc:       svc,
+		retention: retention,
+		stopCh:    make(chan struct{}),
+	}
+	if retention > 0 {
+		go ar.run(interval)
+	}
+	return ar
+}
+
+// Stop terminates the purge loop.
+func (ar *AuditRetention) Stop() {
+	ar.stopOnce.Do(func() { close(ar.stopCh) })
+}
+
+func (ar *AuditRetention) run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := ar.svc.Purge(context.Background(), ar.retention); err != nil {
+				logger.Error("Audit log retention purge failed", err)
+			}
+		case <-ar.stopCh:
+			return
+		}
+	}
+}
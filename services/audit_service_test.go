@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/logger"
+	"github.com/clementhaon/sandbox-api-go/mocks"
+	"github.com/clementhaon/sandbox-api-go/models"
+)
+
+func TestAuditService_Record_FillsRequestContext(t *testing.T) {
+	var created models.AuditLog
+	repo := &mocks.MockAuditRepository{
+		CreateFn: func(ctx context.Context, log models.AuditLog) error {
+			created = log
+			return nil
+		},
+	}
+
+	ctx := context.WithValue(context.Background(), logger.UserIDKey, 7)
+	ctx = context.WithValue(ctx, logger.ClientIPKey, "203.0.113.5")
+	ctx = context.WithValue(ctx, logger.UserAgentKey, "curl/8.0")
+
+	targetID := 3
+	svc := NewAuditService(repo)
+	svc.Record(ctx, models.AuditEntry{
+		Action:     models.AuditActionUserDelete,
+		TargetType: "user",
+		TargetID:   &targetID,
+		Metadata:   map[string]interface{}{"reason": "cleanup"},
+	})
+
+	if created.ActorID == nil || *created.ActorID != 7 {
+		t.Errorf("expected actor 7 from context, got %v", created.ActorID)
+	}
+	if created.IPAddress != "203.0.113.5" {
+		t.Errorf("expected IP from context, got %q", created.IPAddress)
+	}
+	if created.UserAgent != "curl/8.0" {
+		t.Errorf("expected user agent from context, got %q", created.UserAgent)
+	}
+	var metadata map[string]interface{}
+	if err := json.Unmarshal(created.Metadata, &metadata); err != nil || metadata["reason"] != "cleanup" {
+		t.Errorf("unexpected metadata %s", created.Metadata)
+	}
+}
+
+func TestAuditService_Record_KeepsExplicitActor(t *testing.T) {
+	var created models.AuditLog
+	repo := &mocks.MockAuditRepository{
+		CreateFn: func(ctx context.Context, log models.AuditLog) error {
+			created = log
+			return nil
+		},
+	}
+
+	actorID := 1
+	ctx := context.WithValue(context.Background(), logger.UserIDKey, 7)
+	NewAuditService(repo).Record(ctx, models.AuditEntry{ActorID: &actorID, Action: models.AuditActionLogin})
+
+	if created.ActorID == nil || *created.ActorID != 1 {
+		t.Errorf("expected explicit actor 1, got %v", created.ActorID)
+	}
+}
+
+func TestAuditService_Record_NamesImpersonator(t *testing.T) {
+	var created models.AuditLog
+	repo := &mocks.MockAuditRepository{
+		CreateFn: func(ctx context.Context, log models.AuditLog) error {
+			created = log
+			return nil
+		},
+	}
+
+	ctx := context.WithValue(context.Background(), logger.UserIDKey, 7)
+	ctx = context.WithValue(ctx, logger.ImpersonatorIDKey, 1)
+	NewAuditService(repo).Record(ctx, models.AuditEntry{Action: models.AuditActionUsernameChange})
+
+	var metadata map[string]interface{}
+	if err := json.Unmarshal(created.Metadata, &metadata); err != nil || metadata["impersonatorId"] != float64(1) {
+		t.Errorf("expected the impersonator in metadata, got %s", created.Metadata)
+	}
+}
+
+func TestAuditService_List_AppliesDefaults(t *testing.T) {
+	var received models.AuditLogListParams
+	repo := &mocks.MockAuditRepository{
+		ListFn: func(ctx context.Context, params models.AuditLogListParams) ([]models.AuditLog, int, error) {
+			received = params
+			return []models.AuditLog{{ID: 1}}, 120, nil
+		},
+	}
+
+	resp, err := NewAuditService(repo).List(context.Background(), models.AuditLogListParams{PageSize: 500})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if received.Page != 1 || received.PageSize != 50 {
+		t.Errorf("expected page 1 size 50, got page %d size %d", received.Page, received.PageSize)
+	}
+	if resp.Pagination.TotalPages != 3 {
+		t.Errorf("expected 3 pages, got %d", resp.Pagination.TotalPages)
+	}
+}
+
+func TestAuditService_Purge(t *testing.T) {
+	var cutoff time.Time
+	repo := &mocks.MockAuditRepository{
+		DeleteOlderThanFn: func(ctx context.Context, before time.Time) (int64, error) {
+			cutoff = before
+			return 4, nil
+		},
+	}
+
+	deleted, err := NewAuditService(repo).Purge(context.Background(), 24*time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if deleted != 4 {
+		t.Errorf("expected 4 deleted, got %d", deleted)
+	}
+	if since := time.Since(cutoff); since < 24*time.Hour || since > 25*time.Hour {
+		t.Errorf("unexpected cutoff %v", cutoff)
+	}
+}
@@ -2,9 +2,12 @@ package services
 
 import (
 	"context"
+	"strings"
+	"time"
 
 	"github.com/clementhaon/sandbox-api-go/auth"
 	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/i18n"
 	"github.com/clementhaon/sandbox-api-go/logger"
 	"github.com/clementhaon/sandbox-api-go/metrics"
 	"github.com/clementhaon/sandbox-api-go/models"
@@ -17,22 +20,54 @@ import (
 type AuthService interface {
 	Register(ctx context.Context, req models.RegisterRequest) (models.User, string, error)
 	Login(ctx context.Context, req models.LoginRequest) (models.User, string, error)
+	// Impersonate issues a token acting as the target user on behalf of an
+	// admin, valid for ImpersonationTTL. Admins cannot be impersonated.
+	Impersonate(ctx context.Context, adminID, targetID int) (models.ImpersonationResponse, error)
+	// PasswordStrength scores a password and checks it against the password
+	// policy, with feedback in the language of ctx.
+	PasswordStrength(ctx context.Context, req models.PasswordStrengthRequest) (models.PasswordStrength, error)
+}
+
+// ImpersonationTTL is how long an impersonation token is valid.
+const ImpersonationTTL = 15 * time.Minute
+
+// SignupGuard vets a registration before the account is created, such as
+// *signup.Guard.
+type SignupGuard interface {
+	Check(ctx context.Context, email, captchaToken string) error
 }
 
 type authService struct {
-	userRepo   repository.UserRepository
-	jwtManager *auth.JWTManager
+	userRepo    repository.UserRepository
+	jwtManager  *auth.JWTManager
+	auditSvc    AuditService
+	signupGuard SignupGuard
+	policy      validation.PasswordPolicy
+	hasher      *auth.PasswordHasher
+	passwords   *PasswordScreen
+	sessions    SessionService
 }
 
-func NewAuthService(userRepo repository.UserRepository, jwtManager *auth.JWTManager) AuthService {
-	return &authService{userRepo: userRepo, jwtManager: jwtManager}
+// NewAuthService creates the auth service, holding new passwords to policy
+// and hashing them with hasher; a nil signupGuard accepts every valid
+// registration, a nil passwords screen every valid password, and without
+// sessions the tokens issued are not recorded.
+func NewAuthService(userRepo repository.UserRepository, jwtManager *auth.JWTManager, auditSvc AuditService, signupGuard SignupGuard, policy validation.PasswordPolicy, hasher *auth.PasswordHasher, passwords *PasswordScreen, sessions SessionService) AuthService {
+	return &authService{userRepo: userRepo, jwtManager: jwtManager, auditSvc: auditSvc, signupGuard: signupGuard, policy: policy, hasher: hasher, passwords: passwords, sessions: sessions}
 }
 
 func (s *authService) Register(ctx context.Context, req models.RegisterRequest) (models.User, string, error) {
-	if validationErr := validation.ValidateRegisterRequest(req.Username, req.Email, req.Password); validationErr != nil {
+	if validationErr := validation.ValidateRegisterRequest(req.Username, req.Email, req.Password, s.policy); validationErr != nil {
 		return models.User{}, "", validationErr
 	}
 
+	if s.signupGuard != nil {
+		if err := s.signupGuard.Check(ctx, req.Email, req.CaptchaToken); err != nil {
+			metrics.FromContext(ctx).RecordAuthAttempt("register", "blocked")
+			return models.User{}, "", err
+		}
+	}
+
 	exists, err := s.userRepo.ExistsByUsernameOrEmail(ctx, req.Username, req.Email)
 	if err != nil {
 		return models.User{}, "", err
@@ -41,13 +76,16 @@ func (s *authService) Register(ctx context.Context, req models.RegisterRequest)
 		return models.User{}, "", errors.NewUserExistsError()
 	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err := s.passwords.Check(ctx, "register", req.Password); err != nil {
+		return models.User{}, "", err
+	}
+
+	hashedPassword, err := s.hasher.Hash(ctx, req.Password)
 	if err != nil {
-		logger.ErrorContext(ctx, "Error hashing password", err)
-		return models.User{}, "", errors.NewInternalError().WithCause(err)
+		return models.User{}, "", passwordHashError(ctx, err)
 	}
 
-	newUser, err := s.userRepo.CreateAuth(ctx, req.Username, req.Email, string(hashedPassword))
+	newUser, err := s.userRepo.CreateAuth(ctx, req.Username, req.Email, hashedPassword)
 	if err != nil {
 		return models.User{}, "", err
 	}
@@ -57,39 +95,65 @@ func (s *authService) Register(ctx context.Context, req models.RegisterRequest)
 		logger.ErrorContext(ctx, "Error generating JWT token", err)
 		return models.User{}, "", errors.NewInternalError().WithCause(err)
 	}
+	s.startSession(ctx, newUser, token, time.Now().Add(auth.TokenTTL))
 
 	logger.InfoContext(ctx, "User registered successfully", map[string]interface{}{
 		"user_id":  newUser.ID,
 		"username": newUser.Username,
 	})
-	metrics.RecordAuthAttempt("register", "success")
+	metrics.FromContext(ctx).RecordAuthAttempt("register", "success")
+	s.auditSvc.Record(ctx, models.AuditEntry{
+		ActorID:    &newUser.ID,
+		Action:     models.AuditActionRegister,
+		TargetType: "user",
+		TargetID:   &newUser.ID,
+	})
 
 	return newUser, token, nil
 }
 
 func (s *authService) Login(ctx context.Context, req models.LoginRequest) (models.User, string, error) {
-	if validationErr := validation.ValidateLoginRequest(req.Email, req.Password); validationErr != nil {
+	if validationErr := validation.Struct(req); validationErr != nil {
 		return models.User{}, "", validationErr
 	}
 
 	foundUser, hashedPassword, err := s.userRepo.FindByEmailWithPassword(ctx, req.Email)
 	if err != nil {
-		if _, ok := errors.IsAppError(err); ok {
+		if errors.Is(err, errors.ErrInvalidCredentials) {
 			logger.WarnContext(ctx, "Login attempt with non-existent email", map[string]interface{}{
 				"email": req.Email,
 			})
+			s.auditSvc.Record(ctx, models.AuditEntry{
+				Action:   models.AuditActionLoginFailed,
+				Metadata: map[string]interface{}{"email": req.Email, "reason": "unknown_email"},
+			})
+			recordLoginFailure(ctx, "unknown_email")
 		}
 		return models.User{}, "", err
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(req.Password)); err != nil {
+	if err := s.hasher.Compare(ctx, hashedPassword, req.Password); err != nil {
+		if !errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return models.User{}, "", passwordHashError(ctx, err)
+		}
 		logger.WarnContext(ctx, "Login attempt with invalid password", map[string]interface{}{
 			"user_id": foundUser.ID,
 			"email":   req.Email,
 		})
+		s.auditSvc.Record(ctx, models.AuditEntry{
+			Action:     models.AuditActionLoginFailed,
+			TargetType: "user",
+			TargetID:   &foundUser.ID,
+			Metadata:   map[string]interface{}{"email": req.Email, "reason": "invalid_password"},
+		})
+		recordLoginFailure(ctx, "invalid_password")
 		return models.User{}, "", errors.NewInvalidCredentialsError()
 	}
 
+	if s.hasher.NeedsRehash(hashedPassword) {
+		s.rehashPassword(ctx, foundUser.ID, req.Password)
+	}
+
 	if err := s.userRepo.UpdateLastLogin(ctx, foundUser.ID); err != nil {
 		logger.WarnContext(ctx, "Failed to update last_login_at", map[string]interface{}{
 			"user_id": foundUser.ID,
@@ -97,18 +161,155 @@ func (s *authService) Login(ctx context.Context, req models.LoginRequest) (model
 		})
 	}
 
-	token, err := s.jwtManager.GenerateToken(foundUser)
+	generate, ttl := s.jwtManager.GenerateToken, auth.TokenTTL
+	if req.RememberMe {
+		generate, ttl = s.jwtManager.GenerateRememberMeToken, s.jwtManager.RememberMeTTL()
+	}
+	token, err := generate(foundUser)
 	if err != nil {
 		logger.ErrorContext(ctx, "Error generating JWT token for login", err)
 		return models.User{}, "", errors.NewInternalError().WithCause(err)
 	}
+	s.startSession(ctx, foundUser, token, time.Now().Add(ttl))
 
 	logger.InfoContext(ctx, "User logged in successfully", map[string]interface{}{
-		"user_id":  foundUser.ID,
-		"username": foundUser.Username,
-		"email":    foundUser.Email,
+		"user_id":     foundUser.ID,
+		"username":    foundUser.Username,
+		"email":       foundUser.Email,
+		"remember_me": req.RememberMe,
+	})
+	metrics.FromContext(ctx).RecordAuthAttempt("login", "success")
+	s.auditSvc.Record(ctx, models.AuditEntry{
+		ActorID:    &foundUser.ID,
+		Action:     models.AuditActionLogin,
+		TargetType: "user",
+		TargetID:   &foundUser.ID,
 	})
-	metrics.RecordAuthAttempt("login", "success")
 
 	return foundUser, token, nil
 }
+
+func (s *authService) Impersonate(ctx context.Context, adminID, targetID int) (models.ImpersonationResponse, error) {
+	if targetID == adminID {
+		return models.ImpersonationResponse{}, errors.NewBadRequestError("Cannot impersonate yourself")
+	}
+
+	target, err := s.userRepo.GetByID(ctx, targetID)
+	if err != nil {
+		return models.ImpersonationResponse{}, err
+	}
+	if target.Role == models.RoleAdmin {
+		logger.WarnContext(ctx, "Impersonation of an admin refused", map[string]interface{}{
+			"target_id": targetID,
+		})
+		return models.ImpersonationResponse{}, errors.NewForbiddenError()
+	}
+
+	expiresAt := time.Now().Add(ImpersonationTTL)
+	token, err := s.jwtManager.GenerateImpersonationToken(target, adminID, ImpersonationTTL)
+	if err != nil {
+		logger.ErrorContext(ctx, "Error generating impersonation token", err)
+		return models.ImpersonationResponse{}, errors.NewInternalError().WithCause(err)
+	}
+
+	logger.InfoContext(ctx, "Impersonation token issued", map[string]interface{}{
+		"target_id": targetID,
+		"expires":   expiresAt,
+	})
+	s.auditSvc.Record(ctx, models.AuditEntry{
+		ActorID:    &adminID,
+		Action:     models.AuditActionImpersonate,
+		TargetType: "user",
+		TargetID:   &target.ID,
+		Metadata:   map[string]interface{}{"expiresAt": expiresAt.UTC()},
+	})
+
+	return models.ImpersonationResponse{Token: token, ExpiresAt: expiresAt.UTC(), User: target}, nil
+}
+
+func (s *authService) PasswordStrength(ctx context.Context, req models.PasswordStrengthRequest) (models.PasswordStrength, error) {
+	if validationErr := validation.Struct(req); validationErr != nil {
+		return models.PasswordStrength{}, validationErr
+	}
+
+	lang := i18n.FromContext(ctx)
+	userInputs := []string{req.Username}
+	if local, _, ok := strings.Cut(req.Email, "@"); ok {
+		userInputs = append(userInputs, local)
+	}
+	strength := s.policy.Strength(req.Password, userInputs...)
+
+	result := models.PasswordStrength{
+		Errors: []string{},
+		Feedback: models.PasswordFeedback{
+			Suggestions: make([]string, 0, len(strength.Suggestions)),
+		},
+	}
+	for _, v := range s.policy.Violations(req.Password) {
+		result.Errors = append(result.Errors, i18n.T(lang, v.Key, v.Args...))
+	}
+	if s.passwords.Breached(ctx, req.Password) {
+		result.Breached = true
+		strength.Score, strength.Warning = 0, "error.PASSWORD_BREACHED"
+		if s.passwords.Rejects() {
+			result.Errors = append(result.Errors, i18n.T(lang, "error.PASSWORD_BREACHED"))
+		}
+	}
+	result.Score = strength.Score
+	result.Valid = len(result.Errors) == 0
+	if strength.Warning != "" {
+		result.Feedback.Warning = i18n.T(lang, strength.Warning)
+	}
+	for _, key := range strength.Suggestions {
+		result.Feedback.Suggestions = append(result.Feedback.Suggestions, i18n.T(lang, key))
+	}
+	return result, nil
+}
+
+// startSession records the session of a token just issued, valid until
+// expiresAt. A failure only costs the login alert, so it does not fail the
+// login.
+func (s *authService) startSession(ctx context.Context, user models.User, token string, expiresAt time.Time) {
+	if s.sessions == nil {
+		return
+	}
+	if err := s.sessions.Start(ctx, user, token, expiresAt); err != nil {
+		logger.WarnContext(ctx, "Failed to record session", map[string]interface{}{
+			"user_id": user.ID,
+			"error":   err.Error(),
+		})
+	}
+}
+
+// recordLoginFailure counts a refused login as a failed attempt, by reason.
+func recordLoginFailure(ctx context.Context, reason string) {
+	m := metrics.FromContext(ctx)
+	m.RecordAuthAttempt("login", "failure")
+	m.RecordAuthFailure("login", reason)
+}
+
+// rehashPassword stores the password of a user hashed at the current cost,
+// once the login proved it. A failure keeps the old hash, which still works.
+func (s *authService) rehashPassword(ctx context.Context, userID int, password string) {
+	hashedPassword, err := s.hasher.Hash(ctx, password)
+	if err == nil {
+		err = s.userRepo.UpdatePasswordHash(ctx, userID, hashedPassword)
+	}
+	if err != nil {
+		logger.WarnContext(ctx, "Failed to rehash password", map[string]interface{}{
+			"user_id": userID,
+			"error":   err.Error(),
+		})
+	}
+}
+
+// passwordHashError maps an error from hashing or checking a password: when
+// the request has no time left for it, the client may retry later.
+func passwordHashError(ctx context.Context, err error) error {
+	if errors.Is(err, auth.ErrHashDeadline) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		logger.WarnContext(ctx, "Password hash skipped", map[string]interface{}{"error": err.Error()})
+		return errors.NewServiceUnavailableError().WithCause(err)
+	}
+	logger.ErrorContext(ctx, "Error hashing password", err)
+	return errors.NewInternalError().WithCause(err)
+}
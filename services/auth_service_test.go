@@ -3,11 +3,14 @@ package services
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/clementhaon/sandbox-api-go/auth"
 	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/i18n"
 	"github.com/clementhaon/sandbox-api-go/mocks"
 	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/validation"
 
 	"golang.org/x/crypto/bcrypt"
 )
@@ -37,7 +40,7 @@ func TestAuthService_Register_Success(t *testing.T) {
 		},
 	}
 
-	svc := NewAuthService(userRepo, newJWTManager(t))
+	svc := NewAuthService(userRepo, newJWTManager(t), &mocks.MockAuditService{}, nil, validation.DefaultPasswordPolicy(), nil, nil, nil)
 	user, token, err := svc.Register(context.Background(), models.RegisterRequest{
 		Username: "johndoe",
 		Email:    "john@example.com",
@@ -55,6 +58,28 @@ func TestAuthService_Register_Success(t *testing.T) {
 	}
 }
 
+func TestAuthService_Register_PasswordPolicy(t *testing.T) {
+	userRepo := &mocks.MockUserRepository{
+		ExistsByUsernameOrEmailFn: func(ctx context.Context, username, email string) (bool, error) {
+			return false, nil
+		},
+		CreateAuthFn: func(ctx context.Context, username, email, hashedPassword string) (models.User, error) {
+			return models.User{ID: 1, Username: username, Email: email, IsActive: true, Role: "user"}, nil
+		},
+	}
+	req := models.RegisterRequest{Username: "johndoe", Email: "john@example.com", Password: "Password1"}
+
+	// Each service holds its own policy
+	strict := NewAuthService(userRepo, newJWTManager(t), &mocks.MockAuditService{}, nil, validation.PasswordPolicy{MinLength: 12}, nil, nil, nil)
+	lenient := NewAuthService(userRepo, newJWTManager(t), &mocks.MockAuditService{}, nil, validation.PasswordPolicy{MinLength: 6}, nil, nil, nil)
+	if _, _, err := strict.Register(context.Background(), req); !errors.Is(err, errors.ErrValidationFailed) {
+		t.Errorf("expected a password short of the strict policy refused, got %v", err)
+	}
+	if _, _, err := lenient.Register(context.Background(), req); err != nil {
+		t.Errorf("expected the lenient policy to accept the password, got %v", err)
+	}
+}
+
 func TestAuthService_Register_UserExists(t *testing.T) {
 	userRepo := &mocks.MockUserRepository{
 		ExistsByUsernameOrEmailFn: func(ctx context.Context, username, email string) (bool, error) {
@@ -62,7 +87,7 @@ func TestAuthService_Register_UserExists(t *testing.T) {
 		},
 	}
 
-	svc := NewAuthService(userRepo, newJWTManager(t))
+	svc := NewAuthService(userRepo, newJWTManager(t), &mocks.MockAuditService{}, nil, validation.DefaultPasswordPolicy(), nil, nil, nil)
 	_, _, err := svc.Register(context.Background(), models.RegisterRequest{
 		Username: "johndoe",
 		Email:    "john@example.com",
@@ -81,9 +106,44 @@ func TestAuthService_Register_UserExists(t *testing.T) {
 	}
 }
 
+type signupGuardFunc func(ctx context.Context, email, captchaToken string) error
+
+func (f signupGuardFunc) Check(ctx context.Context, email, captchaToken string) error {
+	return f(ctx, email, captchaToken)
+}
+
+func TestAuthService_Register_SignupGuard(t *testing.T) {
+	userRepo := &mocks.MockUserRepository{
+		ExistsByUsernameOrEmailFn: func(ctx context.Context, username, email string) (bool, error) {
+			t.Fatal("a refused signup must not reach the repository")
+			return false, nil
+		},
+	}
+	var gotEmail, gotToken string
+	guard := signupGuardFunc(func(ctx context.Context, email, captchaToken string) error {
+		gotEmail, gotToken = email, captchaToken
+		return errors.NewDisposableEmailError()
+	})
+
+	svc := NewAuthService(userRepo, newJWTManager(t), &mocks.MockAuditService{}, guard, validation.DefaultPasswordPolicy(), nil, nil, nil)
+	_, _, err := svc.Register(context.Background(), models.RegisterRequest{
+		Username:     "johndoe",
+		Email:        "john@mailinator.com",
+		Password:     "Password1",
+		CaptchaToken: "captcha",
+	})
+
+	if !errors.Is(err, errors.ErrDisposableEmail) {
+		t.Fatalf("expected DISPOSABLE_EMAIL, got %v", err)
+	}
+	if gotEmail != "john@mailinator.com" || gotToken != "captcha" {
+		t.Errorf("guard got (%q, %q)", gotEmail, gotToken)
+	}
+}
+
 func TestAuthService_Register_ValidationError(t *testing.T) {
 	userRepo := &mocks.MockUserRepository{}
-	svc := NewAuthService(userRepo, newJWTManager(t))
+	svc := NewAuthService(userRepo, newJWTManager(t), &mocks.MockAuditService{}, nil, validation.DefaultPasswordPolicy(), nil, nil, nil)
 
 	tests := []struct {
 		name string
@@ -123,7 +183,7 @@ func TestAuthService_Login_Success(t *testing.T) {
 		},
 	}
 
-	svc := NewAuthService(userRepo, newJWTManager(t))
+	svc := NewAuthService(userRepo, newJWTManager(t), &mocks.MockAuditService{}, nil, validation.DefaultPasswordPolicy(), nil, nil, nil)
 	user, token, err := svc.Login(context.Background(), models.LoginRequest{
 		Email:    "john@example.com",
 		Password: "Password1",
@@ -140,6 +200,72 @@ func TestAuthService_Login_Success(t *testing.T) {
 	}
 }
 
+func TestAuthService_Login_StartsSession(t *testing.T) {
+	hashedPwd, _ := bcrypt.GenerateFromPassword([]byte("Password1"), bcrypt.MinCost)
+	userRepo := &mocks.MockUserRepository{
+		FindByEmailWithPasswordFn: func(ctx context.Context, email string) (models.User, string, error) {
+			return models.User{ID: 1, Username: "johndoe", Email: email, IsActive: true}, string(hashedPwd), nil
+		},
+		UpdateLastLoginFn: func(ctx context.Context, userID int) error {
+			return nil
+		},
+	}
+	var gotToken string
+	sessions := &mocks.MockSessionService{
+		StartFn: func(ctx context.Context, user models.User, token string, expiresAt time.Time) error {
+			gotToken = token
+			return errors.NewDatabaseError()
+		},
+	}
+
+	svc := NewAuthService(userRepo, newJWTManager(t), &mocks.MockAuditService{}, nil, validation.DefaultPasswordPolicy(), nil, nil, sessions)
+	_, token, err := svc.Login(context.Background(), models.LoginRequest{Email: "john@example.com", Password: "Password1"})
+
+	// A session that cannot be recorded does not fail the login
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gotToken == "" || gotToken != token {
+		t.Error("expected the session of the issued token to be started")
+	}
+}
+
+func TestAuthService_Login_RememberMe(t *testing.T) {
+	hashedPwd, _ := bcrypt.GenerateFromPassword([]byte("Password1"), bcrypt.MinCost)
+	userRepo := &mocks.MockUserRepository{
+		FindByEmailWithPasswordFn: func(ctx context.Context, email string) (models.User, string, error) {
+			return models.User{ID: 1, Username: "johndoe", Email: email, IsActive: true}, string(hashedPwd), nil
+		},
+		UpdateLastLoginFn: func(ctx context.Context, userID int) error {
+			return nil
+		},
+	}
+	var sessionExpiry time.Time
+	sessions := &mocks.MockSessionService{
+		StartFn: func(ctx context.Context, user models.User, token string, expiresAt time.Time) error {
+			sessionExpiry = expiresAt
+			return nil
+		},
+	}
+	jm := newJWTManager(t).WithRememberMeTTL(7 * 24 * time.Hour)
+	svc := NewAuthService(userRepo, jm, &mocks.MockAuditService{}, nil, validation.DefaultPasswordPolicy(), nil, nil, sessions)
+
+	_, token, err := svc.Login(context.Background(), models.LoginRequest{Email: "john@example.com", Password: "Password1", RememberMe: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	claims, err := jm.ValidateToken(token)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !claims.RememberMe || time.Until(claims.ExpiresAt) < 6*24*time.Hour {
+		t.Errorf("expected a remember-me token valid for a week, got %+v", claims)
+	}
+	if d := sessionExpiry.Sub(claims.ExpiresAt); d > time.Second || d < -time.Second {
+		t.Errorf("expected the session to expire with the token, got %v and %v", sessionExpiry, claims.ExpiresAt)
+	}
+}
+
 func TestAuthService_Login_WrongPassword(t *testing.T) {
 	hashedPwd, _ := bcrypt.GenerateFromPassword([]byte("Password1"), bcrypt.MinCost)
 
@@ -149,7 +275,14 @@ func TestAuthService_Login_WrongPassword(t *testing.T) {
 		},
 	}
 
-	svc := NewAuthService(userRepo, newJWTManager(t))
+	var recorded models.AuditEntry
+	auditSvc := &mocks.MockAuditService{
+		RecordFn: func(ctx context.Context, entry models.AuditEntry) {
+			recorded = entry
+		},
+	}
+
+	svc := NewAuthService(userRepo, newJWTManager(t), auditSvc, nil, validation.DefaultPasswordPolicy(), nil, nil, nil)
 	_, _, err := svc.Login(context.Background(), models.LoginRequest{
 		Email:    "john@example.com",
 		Password: "WrongPassword1",
@@ -165,6 +298,54 @@ func TestAuthService_Login_WrongPassword(t *testing.T) {
 	if appErr.Code != errors.ErrInvalidCredentials {
 		t.Errorf("expected INVALID_CREDENTIALS, got %s", appErr.Code)
 	}
+	if recorded.Action != models.AuditActionLoginFailed {
+		t.Errorf("expected %s audit entry, got %q", models.AuditActionLoginFailed, recorded.Action)
+	}
+	if recorded.TargetID == nil || *recorded.TargetID != 1 {
+		t.Error("expected failed login to target user 1")
+	}
+}
+
+func TestAuthService_Login_RehashesPassword(t *testing.T) {
+	hashedPwd, _ := bcrypt.GenerateFromPassword([]byte("Password1"), bcrypt.MinCost)
+
+	var rehashed string
+	userRepo := &mocks.MockUserRepository{
+		FindByEmailWithPasswordFn: func(ctx context.Context, email string) (models.User, string, error) {
+			return models.User{ID: 1, Email: email, IsActive: true, Role: "user"}, string(hashedPwd), nil
+		},
+		UpdatePasswordHashFn: func(ctx context.Context, userID int, hashedPassword string) error {
+			rehashed = hashedPassword
+			return nil
+		},
+	}
+
+	svc := NewAuthService(userRepo, newJWTManager(t), &mocks.MockAuditService{}, nil, validation.DefaultPasswordPolicy(), auth.NewPasswordHasher(bcrypt.MinCost+1), nil, nil)
+	if _, _, err := svc.Login(context.Background(), models.LoginRequest{Email: "john@example.com", Password: "Password1"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cost, _ := bcrypt.Cost([]byte(rehashed)); cost != bcrypt.MinCost+1 {
+		t.Fatalf("expected the password rehashed at the new cost, got %q", rehashed)
+	}
+	if bcrypt.CompareHashAndPassword([]byte(rehashed), []byte("Password1")) != nil {
+		t.Error("expected the new hash to match the password")
+	}
+}
+
+func TestAuthService_Login_NoTimeToHash(t *testing.T) {
+	hashedPwd, _ := bcrypt.GenerateFromPassword([]byte("Password1"), bcrypt.MinCost)
+	userRepo := &mocks.MockUserRepository{
+		FindByEmailWithPasswordFn: func(ctx context.Context, email string) (models.User, string, error) {
+			return models.User{ID: 1, Email: email, IsActive: true}, string(hashedPwd), nil
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	svc := NewAuthService(userRepo, newJWTManager(t), &mocks.MockAuditService{}, nil, validation.DefaultPasswordPolicy(), nil, nil, nil)
+	if _, _, err := svc.Login(ctx, models.LoginRequest{Email: "john@example.com", Password: "Password1"}); !errors.Is(err, errors.ErrServiceUnavailable) {
+		t.Errorf("expected SERVICE_UNAVAILABLE, got %v", err)
+	}
 }
 
 func TestAuthService_Login_UserNotFound(t *testing.T) {
@@ -174,7 +355,7 @@ func TestAuthService_Login_UserNotFound(t *testing.T) {
 		},
 	}
 
-	svc := NewAuthService(userRepo, newJWTManager(t))
+	svc := NewAuthService(userRepo, newJWTManager(t), &mocks.MockAuditService{}, nil, validation.DefaultPasswordPolicy(), nil, nil, nil)
 	_, _, err := svc.Login(context.Background(), models.LoginRequest{
 		Email:    "unknown@example.com",
 		Password: "Password1",
@@ -187,7 +368,7 @@ func TestAuthService_Login_UserNotFound(t *testing.T) {
 
 func TestAuthService_Login_ValidationError(t *testing.T) {
 	userRepo := &mocks.MockUserRepository{}
-	svc := NewAuthService(userRepo, newJWTManager(t))
+	svc := NewAuthService(userRepo, newJWTManager(t), &mocks.MockAuditService{}, nil, validation.DefaultPasswordPolicy(), nil, nil, nil)
 
 	_, _, err := svc.Login(context.Background(), models.LoginRequest{
 		Email:    "",
@@ -197,3 +378,78 @@ func TestAuthService_Login_ValidationError(t *testing.T) {
 		t.Error("expected validation error for empty email")
 	}
 }
+
+func TestAuthService_Impersonate(t *testing.T) {
+	users := map[int]models.User{
+		2: {ID: 2, Username: "jane", Role: models.RoleUser},
+		3: {ID: 3, Username: "root", Role: models.RoleAdmin},
+	}
+	userRepo := &mocks.MockUserRepository{
+		GetByIDFn: func(ctx context.Context, id int) (models.User, error) {
+			if u, ok := users[id]; ok {
+				return u, nil
+			}
+			return models.User{}, errors.NewNotFoundError("User not found")
+		},
+	}
+
+	var recorded []models.AuditEntry
+	auditSvc := &mocks.MockAuditService{
+		RecordFn: func(ctx context.Context, entry models.AuditEntry) { recorded = append(recorded, entry) },
+	}
+	jm := newJWTManager(t)
+	svc := NewAuthService(userRepo, jm, auditSvc, nil, validation.DefaultPasswordPolicy(), nil, nil, nil)
+
+	t.Run("issues a flagged token", func(t *testing.T) {
+		resp, err := svc.Impersonate(context.Background(), 1, 2)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		claims, err := jm.ValidateToken(resp.Token)
+		if err != nil {
+			t.Fatalf("expected a valid token: %v", err)
+		}
+		if claims.UserID != 2 || claims.ImpersonatorID != 1 {
+			t.Errorf("expected user 2 impersonated by 1, got %+v", claims)
+		}
+		if len(recorded) != 1 || recorded[0].Action != models.AuditActionImpersonate || *recorded[0].ActorID != 1 {
+			t.Errorf("expected an impersonation audit entry, got %+v", recorded)
+		}
+	})
+
+	for name, targetID := range map[string]int{"self": 1, "admin": 3, "unknown user": 9} {
+		t.Run("refuses "+name, func(t *testing.T) {
+			if _, err := svc.Impersonate(context.Background(), 1, targetID); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestAuthService_PasswordStrength(t *testing.T) {
+	svc := NewAuthService(&mocks.MockUserRepository{}, newJWTManager(t), &mocks.MockAuditService{}, nil, validation.DefaultPasswordPolicy(), nil, nil, nil)
+	ctx := i18n.WithLanguage(context.Background(), "fr")
+
+	got, err := svc.PasswordStrength(ctx, models.PasswordStrengthRequest{Password: "jdupont", Email: "jdupont@example.com"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Valid || len(got.Errors) != 3 || got.Errors[0] != i18n.T("fr", "validation.password_min", 8) {
+		t.Errorf("expected the length, uppercase and number requirements in French, got %v", got.Errors)
+	}
+	if got.Score > 1 || got.Feedback.Warning != i18n.T("fr", "password_strength.personal") || len(got.Feedback.Suggestions) == 0 {
+		t.Errorf("expected a weak password containing the email, got %+v", got)
+	}
+
+	got, err = svc.PasswordStrength(ctx, models.PasswordStrengthRequest{Password: "Correct-Horse-Battery-9"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !got.Valid || got.Score != 4 || got.Feedback.Warning != "" {
+		t.Errorf("expected a strong valid password, got %+v", got)
+	}
+
+	if _, err := svc.PasswordStrength(ctx, models.PasswordStrengthRequest{}); !errors.Is(err, errors.ErrValidationFailed) {
+		t.Errorf("expected a validation error without password, got %v", err)
+	}
+}
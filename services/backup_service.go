@@ -0,0 +1,395 @@
+package services
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/database"
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/jobs"
+	"github.com/clementhaon/sandbox-api-go/logger"
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/repository"
+	"github.com/clementhaon/sandbox-api-go/storage"
+	"github.com/clementhaon/sandbox-api-go/tenant"
+)
+
+// Backups are gzipped JSON lines in the bucket, under the ID of their
+// tenant: a backupHeader, then a backupRow per row, the tables following
+// each other in the order they can be restored in.
+const (
+	backupKeyPrefix = "backups/"
+	backupKeySuffix = ".jsonl.gz"
+	backupFormat    = "sandbox-api-backup"
+	backupVersion   = 2
+	// restoreBatch is the number of rows inserted per statement on restore
+	restoreBatch = 500
+)
+
+// backupNamePattern matches the names of backups, their creation time.
+var backupNamePattern = regexp.MustCompile(`^\d{8}T\d{6}Z$`)
+
+type backupHeader struct {
+	Format        string    `json:"format"`
+	Version       int       `json:"version"`
+	SchemaVersion int       `json:"schemaVersion"`
+	TenantID      int       `json:"tenantId"`
+	CreatedAt     time.Time `json:"createdAt"`
+	Tables        []string  `json:"tables"`
+}
+
+type backupRow struct {
+	Table string          `json:"table"`
+	Row   json.RawMessage `json:"row"`
+}
+
+// BackupService snapshots the data of the tenant of the context to the
+// bucket and restores it, replacing every row of the tenant, in background
+// jobs. One backup or restore runs at a time.
+type BackupService interface {
+	List(ctx context.Context) ([]models.Backup, error)
+	Create(ctx context.Context, userID int) (models.BackupJob, error)
+	Restore(ctx context.Context, userID int, name string) (models.BackupJob, error)
+	GetJob(ctx context.Context, jobID string) (models.BackupJob, error)
+}
+
+type backupService struct {
+	repo           repository.BackupRepository
+	txManager      database.Transactor
+	storage        storage.StorageClient
+	queue          *jobs.Queue
+	auditSvc       AuditService
+	restoreEnabled bool
+	running        atomic.Bool
+}
+
+func NewBackupService(repo repository.BackupRepository, txManager database.Transactor, storage storage.StorageClient, queue *jobs.Queue, auditSvc AuditService, restoreEnabled bool) BackupService {
+	return &backupService{
+		repo:           repo,
+		txManager:      txManager,
+		storage:        storage,
+		queue:          queue,
+		auditSvc:       auditSvc,
+		restoreEnabled: restoreEnabled,
+	}
+}
+
+func (s *backupService) List(ctx context.Context) ([]models.Backup, error) {
+	prefix := backupPrefix(ctx)
+	objects, err := s.storage.ListObjects(prefix)
+	if err != nil {
+		logger.ErrorContext(ctx, "Failed to list backups", err)
+		return nil, errors.NewServiceUnavailableError().WithCause(err)
+	}
+
+	backups := []models.Backup{}
+	for _, obj := range objects {
+		name := strings.TrimSuffix(strings.TrimPrefix(obj.Key, prefix), backupKeySuffix)
+		if !backupNamePattern.MatchString(name) {
+			continue
+		}
+		backups = append(backups, models.Backup{Name: name, Size: obj.Size, CreatedAt: obj.LastModified})
+	}
+	slices.SortFunc(backups, func(a, b models.Backup) int { return strings.Compare(b.Name, a.Name) })
+	return backups, nil
+}
+
+func (s *backupService) Create(ctx context.Context, userID int) (models.BackupJob, error) {
+	name := time.Now().UTC().Format("20060102T150405Z")
+	return s.enqueue(ctx, userID, models.BackupJobBackup, name, func(ctx context.Context, report func(int)) (jobs.Output, error) {
+		return jobs.Output{}, s.backup(ctx, name, report)
+	})
+}
+
+func (s *backupService) Restore(ctx context.Context, userID int, name string) (models.BackupJob, error) {
+	if !s.restoreEnabled {
+		return models.BackupJob{}, errors.NewForbiddenError().WithDetails(map[string]interface{}{
+			"issue": "restore_disabled",
+		})
+	}
+	if !backupNamePattern.MatchString(name) {
+		return models.BackupJob{}, errors.NewNotFoundError("Backup")
+	}
+	info, err := s.storage.GetObjectInfo(backupKey(ctx, name))
+	if err != nil {
+		logger.ErrorContext(ctx, "Failed to get backup info", err)
+		return models.BackupJob{}, errors.NewNotFoundError("Backup")
+	}
+
+	return s.enqueue(ctx, userID, models.BackupJobRestore, name, func(ctx context.Context, report func(int)) (jobs.Output, error) {
+		return jobs.Output{}, s.restore(ctx, name, info.Size, report)
+	})
+}
+
+func (s *backupService) GetJob(ctx context.Context, jobID string) (models.BackupJob, error) {
+	job, ok := s.queue.Get(jobID)
+	if !ok {
+		return models.BackupJob{}, errors.NewNotFoundError("Backup job")
+	}
+	resp, ok := toBackupJob(job)
+	if !ok {
+		return models.BackupJob{}, errors.NewNotFoundError("Backup job")
+	}
+	return resp, nil
+}
+
+// enqueue queues fn unless a backup or restore is already queued or
+// running.
+func (s *backupService) enqueue(ctx context.Context, userID int, jobType, name string, fn jobs.Func) (models.BackupJob, error) {
+	if !s.running.CompareAndSwap(false, true) {
+		return models.BackupJob{}, errors.NewConflictError("A backup or restore is already running")
+	}
+	job, err := s.queue.Enqueue(ctx, jobType+":"+name, userID, func(ctx context.Context, report func(int)) (jobs.Output, error) {
+		defer s.running.Store(false)
+		return fn(ctx, report)
+	})
+	if err != nil {
+		s.running.Store(false)
+		logger.ErrorContext(ctx, "Failed to enqueue backup job", err)
+		return models.BackupJob{}, errors.NewServiceUnavailableError().WithCause(err)
+	}
+
+	logger.InfoContext(ctx, "Backup job requested", map[string]interface{}{
+		"job_id": job.ID,
+		"type":   jobType,
+		"backup": name,
+	})
+	resp, _ := toBackupJob(job)
+	return resp, nil
+}
+
+// backup writes the backup to a temporary file, from one snapshot of the
+// database, then uploads it.
+func (s *backupService) backup(ctx context.Context, name string, report func(int)) error {
+	f, err := os.CreateTemp("", "backup-*"+backupKeySuffix)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	err = s.txManager.WithTransaction(ctx, func(q database.Querier) error {
+		// A retried transaction writes the file again
+		if err := f.Truncate(0); err != nil {
+			return err
+		}
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		return dumpBackup(ctx, s.repo.WithQuerier(q), f, report)
+	})
+	if err != nil {
+		return err
+	}
+
+	size, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if err := s.storage.PutObject(backupKey(ctx, name), f, size, "application/gzip"); err != nil {
+		return err
+	}
+
+	s.auditSvc.Record(ctx, models.AuditEntry{
+		Action:     models.AuditActionBackupCreate,
+		TargetType: "backup",
+		Metadata:   map[string]interface{}{"backup": name, "size": size},
+	})
+	return nil
+}
+
+// dumpBackup writes every row of the tables of repo to w; report goes up to
+// 90, the rest being the upload.
+func dumpBackup(ctx context.Context, repo repository.BackupRepository, w io.Writer, report func(int)) error {
+	if err := repo.BeginSnapshot(ctx); err != nil {
+		return err
+	}
+	version, err := repo.SchemaVersion(ctx)
+	if err != nil {
+		return err
+	}
+	tables, err := repo.Tables(ctx)
+	if err != nil {
+		return err
+	}
+
+	zw := gzip.NewWriter(w)
+	enc := json.NewEncoder(zw)
+	enc.SetEscapeHTML(false)
+	header := backupHeader{
+		Format:        backupFormat,
+		Version:       backupVersion,
+		SchemaVersion: version,
+		TenantID:      tenant.FromContext(ctx),
+		CreatedAt:     time.Now().UTC(),
+		Tables:        tables,
+	}
+	if err := enc.Encode(header); err != nil {
+		return err
+	}
+	for i, table := range tables {
+		err := repo.Dump(ctx, table, func(row json.RawMessage) error {
+			return enc.Encode(backupRow{Table: table, Row: row})
+		})
+		if err != nil {
+			return err
+		}
+		report((i + 1) * 90 / len(tables))
+	}
+	return zw.Close()
+}
+
+// restore replaces the rows of the tenant with those of the backup, in one
+// transaction.
+func (s *backupService) restore(ctx context.Context, name string, size int64, report func(int)) error {
+	err := s.txManager.WithTransaction(ctx, func(q database.Querier) error {
+		obj, err := s.storage.GetObject(backupKey(ctx, name))
+		if err != nil {
+			return err
+		}
+		defer obj.Close()
+		return loadBackup(ctx, s.repo.WithQuerier(q), &progressReader{r: obj, size: size, report: report})
+	})
+	if err != nil {
+		return err
+	}
+
+	s.auditSvc.Record(ctx, models.AuditEntry{
+		Action:     models.AuditActionBackupRestore,
+		TargetType: "backup",
+		Metadata:   map[string]interface{}{"backup": name},
+	})
+	return nil
+}
+
+// loadBackup deletes the rows of the tenant from the tables of repo and
+// inserts the rows read from r.
+func loadBackup(ctx context.Context, repo repository.BackupRepository, r io.Reader) error {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("read backup: %w", err)
+	}
+	dec := json.NewDecoder(zr)
+
+	var header backupHeader
+	if err := dec.Decode(&header); err != nil || header.Format != backupFormat {
+		return fmt.Errorf("not a backup of this API")
+	}
+	if header.Version != backupVersion {
+		return fmt.Errorf("unsupported backup version %d", header.Version)
+	}
+	version, err := repo.SchemaVersion(ctx)
+	if err != nil {
+		return err
+	}
+	if header.SchemaVersion != version {
+		return fmt.Errorf("the backup is of schema version %d but the database is at version %d; migrate the database to version %d first",
+			header.SchemaVersion, version, header.SchemaVersion)
+	}
+	if header.TenantID != tenant.FromContext(ctx) {
+		return fmt.Errorf("the backup is of another tenant")
+	}
+
+	tables, err := repo.Tables(ctx)
+	if err != nil {
+		return err
+	}
+	if err := repo.Clear(ctx, tables); err != nil {
+		return err
+	}
+
+	var table string
+	var batch []json.RawMessage
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		err := repo.Insert(ctx, table, batch)
+		batch = batch[:0]
+		return err
+	}
+	for {
+		var row backupRow
+		if err := dec.Decode(&row); err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("read backup: %w", err)
+		}
+		if row.Table != table {
+			if err := flush(); err != nil {
+				return err
+			}
+			if !slices.Contains(tables, row.Table) {
+				return fmt.Errorf("the backup has a table %q the database does not", row.Table)
+			}
+			table = row.Table
+		}
+		batch = append(batch, row.Row)
+		if len(batch) == restoreBatch {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+	return repo.ResetSequences(ctx, tables)
+}
+
+// backupPrefix returns where the backups of the tenant of ctx are kept.
+func backupPrefix(ctx context.Context) string {
+	return backupKeyPrefix + strconv.Itoa(tenant.FromContext(ctx)) + "/"
+}
+
+func backupKey(ctx context.Context, name string) string {
+	return backupPrefix(ctx) + name + backupKeySuffix
+}
+
+// progressReader reports the share of size read, up to 99 until the
+// restore commits.
+type progressReader struct {
+	r      io.Reader
+	size   int64
+	read   int64
+	report func(int)
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.read += int64(n)
+	if p.size > 0 {
+		p.report(int(min(p.read*100/p.size, 99)))
+	}
+	return n, err
+}
+
+func toBackupJob(job jobs.Job) (models.BackupJob, bool) {
+	jobType, name, ok := strings.Cut(job.Type, ":")
+	if !ok || (jobType != models.BackupJobBackup && jobType != models.BackupJobRestore) {
+		return models.BackupJob{}, false
+	}
+	return models.BackupJob{
+		ID:          job.ID,
+		Type:        jobType,
+		Backup:      name,
+		Status:      string(job.Status),
+		Progress:    job.Progress,
+		Error:       job.Error,
+		CreatedAt:   job.CreatedAt,
+		CompletedAt: job.CompletedAt,
+	}, true
+}
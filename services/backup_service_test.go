@@ -0,0 +1,219 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/jobs"
+	"github.com/clementhaon/sandbox-api-go/mocks"
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/tenant"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// memoryBucket is a MockStorage keeping objects in memory.
+func memoryBucket() (*mocks.MockStorage, map[string][]byte) {
+	objects := make(map[string][]byte)
+	return &mocks.MockStorage{
+		PutObjectFn: func(key string, r io.Reader, size int64, contentType string) error {
+			data, err := io.ReadAll(r)
+			if err != nil {
+				return err
+			}
+			if int64(len(data)) != size {
+				return io.ErrShortWrite
+			}
+			objects[key] = data
+			return nil
+		},
+		GetObjectFn: func(key string) (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(objects[key])), nil
+		},
+		GetObjectInfoFn: func(key string) (*minio.ObjectInfo, error) {
+			data, ok := objects[key]
+			if !ok {
+				return nil, io.EOF
+			}
+			return &minio.ObjectInfo{Key: key, Size: int64(len(data))}, nil
+		},
+		ListObjectsFn: func(prefix string) ([]minio.ObjectInfo, error) {
+			var infos []minio.ObjectInfo
+			for key, data := range objects {
+				if strings.HasPrefix(key, prefix) {
+					infos = append(infos, minio.ObjectInfo{Key: key, Size: int64(len(data))})
+				}
+			}
+			return infos, nil
+		},
+	}, objects
+}
+
+func waitForBackupJob(t *testing.T, svc BackupService, jobID string) models.BackupJob {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		job, err := svc.GetJob(context.Background(), jobID)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if job.Status == string(jobs.StatusCompleted) || job.Status == string(jobs.StatusFailed) {
+			return job
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("backup job did not finish in time")
+	return models.BackupJob{}
+}
+
+func TestBackupService_BackupAndRestore(t *testing.T) {
+	queue := jobs.NewQueue(1)
+	t.Cleanup(queue.Stop)
+	bucket, objects := memoryBucket()
+	tables := []string{"users", "tasks"}
+	dumped := map[string][]string{
+		"users": {`{"id":1,"username":"johndoe"}`, `{"id":2,"username":"<jane>"}`},
+		"tasks": {`{"id":7,"user_id":1,"title":"Write docs"}`},
+	}
+
+	snapshot := false
+	source := &mocks.MockBackupRepository{
+		BeginSnapshotFn: func(ctx context.Context) error { snapshot = true; return nil },
+		SchemaVersionFn: func(ctx context.Context) (int, error) { return 26, nil },
+		TablesFn:        func(ctx context.Context) ([]string, error) { return tables, nil },
+		DumpFn: func(ctx context.Context, table string, fn func(row json.RawMessage) error) error {
+			for _, row := range dumped[table] {
+				if err := fn(json.RawMessage(row)); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+	var recorded []string
+	audit := &mocks.MockAuditService{RecordFn: func(ctx context.Context, e models.AuditEntry) { recorded = append(recorded, e.Action) }}
+	svc := NewBackupService(source, &mocks.MockTransactor{}, bucket, queue, audit, true)
+
+	job, err := svc.Create(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if job.Type != models.BackupJobBackup || job.Backup == "" {
+		t.Fatalf("expected a backup job naming its backup, got %+v", job)
+	}
+	if finished := waitForBackupJob(t, svc, job.ID); finished.Status != string(jobs.StatusCompleted) {
+		t.Fatalf("expected completed backup, got %q (%s)", finished.Status, finished.Error)
+	}
+	if !snapshot {
+		t.Error("expected the tables dumped from one snapshot")
+	}
+	if _, ok := objects["backups/1/"+job.Backup+".jsonl.gz"]; !ok {
+		t.Fatalf("expected the backup uploaded, got %d objects", len(objects))
+	}
+
+	backups, err := svc.List(context.Background())
+	if err != nil || len(backups) != 1 || backups[0].Name != job.Backup {
+		t.Fatalf("expected backup %s listed, got %+v (%v)", job.Backup, backups, err)
+	}
+
+	// Other tenants neither see nor restore it
+	other := tenant.WithID(context.Background(), 2)
+	if backups, err := svc.List(other); err != nil || len(backups) != 0 {
+		t.Errorf("expected no backup listed for another tenant, got %+v (%v)", backups, err)
+	}
+	if _, err := svc.Restore(other, 1, job.Backup); !errors.Is(err, errors.ErrNotFound) {
+		t.Errorf("expected NOT_FOUND restoring the backup of another tenant, got %v", err)
+	}
+
+	var cleared []string
+	inserted := map[string][]string{}
+	reset := false
+	target := &mocks.MockBackupRepository{
+		SchemaVersionFn: func(ctx context.Context) (int, error) { return 26, nil },
+		TablesFn:        func(ctx context.Context) ([]string, error) { return tables, nil },
+		ClearFn:         func(ctx context.Context, t []string) error { cleared = t; return nil },
+		InsertFn: func(ctx context.Context, table string, rows []json.RawMessage) error {
+			for _, row := range rows {
+				inserted[table] = append(inserted[table], string(row))
+			}
+			return nil
+		},
+		ResetSequencesFn: func(ctx context.Context, t []string) error { reset = true; return nil },
+	}
+	svc = NewBackupService(target, &mocks.MockTransactor{}, bucket, queue, audit, true)
+
+	job, err = svc.Restore(context.Background(), 1, job.Backup)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if finished := waitForBackupJob(t, svc, job.ID); finished.Status != string(jobs.StatusCompleted) {
+		t.Fatalf("expected completed restore, got %q (%s)", finished.Status, finished.Error)
+	}
+	if len(cleared) != 2 || !reset {
+		t.Errorf("expected every table cleared and the sequences reset, got %v", cleared)
+	}
+	for table, rows := range dumped {
+		if strings.Join(inserted[table], "\n") != strings.Join(rows, "\n") {
+			t.Errorf("expected %s restored as %v, got %v", table, rows, inserted[table])
+		}
+	}
+	if strings.Join(recorded, ",") != models.AuditActionBackupCreate+","+models.AuditActionBackupRestore {
+		t.Errorf("expected the backup and the restore audited, got %v", recorded)
+	}
+
+	// A backup of another schema version is refused before anything is
+	// emptied
+	cleared = nil
+	target.SchemaVersionFn = func(ctx context.Context) (int, error) { return 27, nil }
+	job, err = svc.Restore(context.Background(), 1, job.Backup)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	finished := waitForBackupJob(t, svc, job.ID)
+	if finished.Status != string(jobs.StatusFailed) || !strings.Contains(finished.Error, "schema version 26") || cleared != nil {
+		t.Errorf("expected the restore to fail on the schema version, got %+v", finished)
+	}
+}
+
+func TestBackupService_Restore_Refused(t *testing.T) {
+	queue := jobs.NewQueue(1)
+	t.Cleanup(queue.Stop)
+	bucket, objects := memoryBucket()
+	objects["backups/1/20261016T120000Z.jsonl.gz"] = []byte("backup")
+
+	svc := NewBackupService(&mocks.MockBackupRepository{}, &mocks.MockTransactor{}, bucket, queue, &mocks.MockAuditService{}, false)
+	if _, err := svc.Restore(context.Background(), 1, "20261016T120000Z"); !errors.Is(err, errors.ErrForbidden) {
+		t.Errorf("expected FORBIDDEN with restores disabled, got %v", err)
+	}
+
+	svc = NewBackupService(&mocks.MockBackupRepository{}, &mocks.MockTransactor{}, bucket, queue, &mocks.MockAuditService{}, true)
+	for _, name := range []string{"20261016T130000Z", "../users/1/avatar"} {
+		if _, err := svc.Restore(context.Background(), 1, name); !errors.Is(err, errors.ErrNotFound) {
+			t.Errorf("expected NOT_FOUND for backup %q, got %v", name, err)
+		}
+	}
+
+	// Only one backup or restore runs at a time
+	release := make(chan struct{})
+	svc = NewBackupService(&mocks.MockBackupRepository{
+		BeginSnapshotFn: func(ctx context.Context) error { <-release; return io.EOF },
+	}, &mocks.MockTransactor{}, bucket, queue, &mocks.MockAuditService{}, true)
+	job, err := svc.Create(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := svc.Restore(context.Background(), 1, "20261016T120000Z"); !errors.Is(err, errors.ErrConflict) {
+		t.Errorf("expected CONFLICT while a backup runs, got %v", err)
+	}
+	close(release)
+	waitForBackupJob(t, svc, job.ID)
+	if _, err := svc.Create(context.Background(), 1); err != nil {
+		t.Errorf("expected a backup to start once the previous one finished, got %v", err)
+	}
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"context"
+
+	"github.com/clementhaon/sandbox-api-go/database"
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/repository"
+	"github.com/clementhaon/sandbox-api-go/sanitize"
+	"github.com/clementhaon/sandbox-api-go/validation"
+)
+
+type ChecklistService interface {
+	List(ctx context.Context, taskID int) ([]models.ChecklistItem, error)
+	Add(ctx context.Context, taskID int, req models.CreateChecklistItemRequest) (models.ChecklistItem, error)
+	Update(ctx context.Context, taskID int, id int, req models.UpdateChecklistItemRequest) (models.ChecklistItem, error)
+	Delete(ctx context.Context, taskID int, id int) error
+	Reorder(ctx context.Context, taskID int, itemIDs []int) ([]models.ChecklistItem, error)
+}
+
+type checklistService struct {
+	checklistRepo repository.ChecklistRepository
+	taskRepo      repository.TaskRepository
+	txManager     database.Transactor
+}
+
+func NewChecklistService(checklistRepo repository.ChecklistRepository, taskRepo repository.TaskRepository, txManager database.Transactor) ChecklistService {
+	return &checklistService{checklistRepo: checklistRepo, taskRepo: taskRepo, txManager: txManager}
+}
+
+func (s *checklistService) List(ctx context.Context, taskID int) ([]models.ChecklistItem, error) {
+	exists, err := s.taskRepo.Exists(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.NewNotFoundError("Task not found")
+	}
+	return s.checklistRepo.List(ctx, taskID)
+}
+
+func (s *checklistService) Add(ctx context.Context, taskID int, req models.CreateChecklistItemRequest) (models.ChecklistItem, error) {
+	sanitize.Struct(&req)
+	if err := validation.Struct(req); err != nil {
+		return models.ChecklistItem{}, err
+	}
+	return s.checklistRepo.Create(ctx, taskID, req.Title)
+}
+
+func (s *checklistService) Update(ctx context.Context, taskID int, id int, req models.UpdateChecklistItemRequest) (models.ChecklistItem, error) {
+	sanitize.Struct(&req)
+	if err := validation.Struct(req); err != nil {
+		return models.ChecklistItem{}, err
+	}
+	return s.checklistRepo.Update(ctx, taskID, id, req)
+}
+
+func (s *checklistService) Delete(ctx context.Context, taskID int, id int) error {
+	return s.checklistRepo.Delete(ctx, taskID, id)
+}
+
+func (s *checklistService) Reorder(ctx context.Context, taskID int, itemIDs []int) ([]models.ChecklistItem, error) {
+	if len(itemIDs) == 0 {
+		return nil, errors.NewBadRequestError("ItemIDs are required")
+	}
+	seen := make(map[int]bool, len(itemIDs))
+	for _, id := range itemIDs {
+		if seen[id] {
+			return nil, errors.NewBadRequestError("ItemIDs must not repeat")
+		}
+		seen[id] = true
+	}
+
+	// A missing item rolls back the whole reorder
+	err := s.txManager.WithTransaction(ctx, func(q database.Querier) error {
+		return s.checklistRepo.WithQuerier(q).Reorder(ctx, taskID, itemIDs)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.checklistRepo.List(ctx, taskID)
+}
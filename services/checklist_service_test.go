@@ -0,0 +1,68 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/mocks"
+	"github.com/clementhaon/sandbox-api-go/models"
+)
+
+func TestChecklistService_List_TaskNotFound(t *testing.T) {
+	taskRepo := &mocks.MockTaskRepository{
+		ExistsFn: func(ctx context.Context, id int) (bool, error) { return false, nil },
+	}
+	svc := NewChecklistService(&mocks.MockChecklistRepository{}, taskRepo, &mocks.MockTransactor{})
+
+	if _, err := svc.List(context.Background(), 1); !errors.Is(err, errors.ErrNotFound) {
+		t.Errorf("expected a not found error, got %v", err)
+	}
+}
+
+func TestChecklistService_Add(t *testing.T) {
+	repo := &mocks.MockChecklistRepository{
+		CreateFn: func(ctx context.Context, taskID int, title string) (models.ChecklistItem, error) {
+			if title != "Write docs" {
+				t.Errorf("expected the title to be sanitized, got %q", title)
+			}
+			return models.ChecklistItem{ID: 1, TaskID: taskID, Title: title}, nil
+		},
+	}
+	svc := NewChecklistService(repo, &mocks.MockTaskRepository{}, &mocks.MockTransactor{})
+
+	if _, err := svc.Add(context.Background(), 3, models.CreateChecklistItemRequest{Title: "Write <i>docs</i>"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := svc.Add(context.Background(), 3, models.CreateChecklistItemRequest{Title: "  "}); err == nil {
+		t.Error("expected a validation error for a blank title")
+	}
+}
+
+func TestChecklistService_Reorder(t *testing.T) {
+	var reordered []int
+	repo := &mocks.MockChecklistRepository{
+		ReorderFn: func(ctx context.Context, taskID int, itemIDs []int) error {
+			reordered = itemIDs
+			return nil
+		},
+		ListFn: func(ctx context.Context, taskID int) ([]models.ChecklistItem, error) {
+			return []models.ChecklistItem{{ID: 2}, {ID: 1}}, nil
+		},
+	}
+	svc := NewChecklistService(repo, &mocks.MockTaskRepository{}, &mocks.MockTransactor{})
+
+	items, err := svc.Reorder(context.Background(), 3, []int{2, 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(reordered) != 2 || len(items) != 2 {
+		t.Errorf("expected 2 items reordered and listed, got %v and %v", reordered, items)
+	}
+
+	for _, ids := range [][]int{nil, {1, 1}} {
+		if _, err := svc.Reorder(context.Background(), 3, ids); err == nil {
+			t.Errorf("expected an error for item IDs %v", ids)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/kvstore"
+	"github.com/clementhaon/sandbox-api-go/logger"
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/tenant"
+)
+
+// MaxClockOffset bounds how far a sandbox clock moves from the real time,
+// either way.
+const MaxClockOffset = 10 * 365 * 24 * time.Hour
+
+// ClockOffsetTTL is how long a clock offset is kept after it was last set;
+// the clock of the user then goes back to the real time.
+const ClockOffsetTTL = 30 * 24 * time.Hour
+
+// ClockService keeps the virtual clocks of sandbox users, which the server
+// uses for the data of their requests: the sandbox creation time of tasks
+// and deadlines of loaded scenarios. Personal tokens also expire on it when it
+// is ahead of the real time, never later than they really do; login sessions
+// keep the real time.
+type ClockService interface {
+	Get(ctx context.Context, userID int) (models.SandboxClock, error)
+	Set(ctx context.Context, userID int, req models.SetSandboxClockRequest) (models.SandboxClock, error)
+	// Offset returns the clock offset of the user, 0 for the real time
+	Offset(ctx context.Context, userID int) (time.Duration, error)
+}
+
+type clockService struct {
+	offsets kvstore.Store
+}
+
+// NewClockService returns a ClockService keeping the offsets in offsets, so
+// every instance sharing it sees them.
+func NewClockService(offsets kvstore.Store) ClockService {
+	return &clockService{offsets: offsets}
+}
+
+func (s *clockService) Get(ctx context.Context, userID int) (models.SandboxClock, error) {
+	offset, err := s.Offset(ctx, userID)
+	if err != nil {
+		return models.SandboxClock{}, err
+	}
+	return sandboxClock(offset), nil
+}
+
+func (s *clockService) Set(ctx context.Context, userID int, req models.SetSandboxClockRequest) (models.SandboxClock, error) {
+	maxSeconds := int64(MaxClockOffset / time.Second)
+	if req.OffsetSeconds > maxSeconds || req.OffsetSeconds < -maxSeconds {
+		return models.SandboxClock{}, errors.NewInvalidFormatError("offsetSeconds", fmt.Sprintf("from -%d to %d", maxSeconds, maxSeconds))
+	}
+	offset := time.Duration(req.OffsetSeconds) * time.Second
+	if req.At != nil {
+		offset = time.Until(*req.At).Round(time.Second)
+		if offset > MaxClockOffset || offset < -MaxClockOffset {
+			return models.SandboxClock{}, errors.NewInvalidFormatError("at", "a time within ten years of now")
+		}
+	}
+
+	key := clockOffsetKey(ctx, userID)
+	var err error
+	if offset == 0 {
+		err = s.offsets.Delete(ctx, key)
+	} else {
+		err = s.offsets.Set(ctx, key, []byte(strconv.FormatInt(int64(offset/time.Second), 10)), ClockOffsetTTL)
+	}
+	if err != nil {
+		return models.SandboxClock{}, errors.NewServiceUnavailableError().WithCause(err)
+	}
+
+	logger.InfoContext(ctx, "Sandbox clock set", map[string]interface{}{
+		"offset_seconds": int64(offset / time.Second),
+	})
+	return sandboxClock(offset), nil
+}
+
+func (s *clockService) Offset(ctx context.Context, userID int) (time.Duration, error) {
+	data, found, err := s.offsets.Get(ctx, clockOffsetKey(ctx, userID))
+	if err != nil {
+		return 0, errors.NewServiceUnavailableError().WithCause(err)
+	}
+	if !found {
+		return 0, nil
+	}
+	seconds, err := strconv.ParseInt(string(data), 10, 64)
+	if err != nil {
+		return 0, errors.NewInternalError().WithCause(err)
+	}
+	return time.Duration(seconds) * time.Second, nil
+}
+
+func sandboxClock(offset time.Duration) models.SandboxClock {
+	return models.SandboxClock{
+		OffsetSeconds: int64(offset / time.Second),
+		Now:           time.Now().Add(offset).UTC(),
+	}
+}
+
+// clockOffsetKey is the key of the clock offset of a user of the tenant of
+// ctx.
+func clockOffsetKey(ctx context.Context, userID int) string {
+	return fmt.Sprintf("clock:%d:%d", tenant.FromContext(ctx), userID)
+}
@@ -0,0 +1,60 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/kvstore"
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/tenant"
+)
+
+func TestClockService_Set(t *testing.T) {
+	ctx := context.Background()
+	store := kvstore.NewMemoryStore()
+	defer store.Stop()
+	svc := NewClockService(store)
+
+	resp, err := svc.Set(ctx, 3, models.SetSandboxClockRequest{OffsetSeconds: 3600})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.OffsetSeconds != 3600 || time.Until(resp.Now) < 59*time.Minute {
+		t.Errorf("expected the clock an hour ahead, got %+v", resp)
+	}
+	if offset, _ := svc.Offset(ctx, 3); offset != time.Hour {
+		t.Errorf("expected a 1h offset, got %v", offset)
+	}
+	// Other users, and the same user ID in another tenant, keep the real time
+	if offset, _ := svc.Offset(ctx, 4); offset != 0 {
+		t.Errorf("expected no offset for another user, got %v", offset)
+	}
+	if offset, _ := svc.Offset(tenant.WithID(ctx, 2), 3); offset != 0 {
+		t.Errorf("expected no offset in another tenant, got %v", offset)
+	}
+
+	at := time.Now().AddDate(0, 0, -7)
+	if resp, err := svc.Set(ctx, 3, models.SetSandboxClockRequest{At: &at}); err != nil || resp.OffsetSeconds != -7*24*3600 {
+		t.Errorf("expected the clock a week back, got %+v, %v", resp, err)
+	}
+
+	if _, err := svc.Set(ctx, 3, models.SetSandboxClockRequest{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp, _ := svc.Get(ctx, 3); resp.OffsetSeconds != 0 {
+		t.Errorf("expected the clock back to the real time, got %+v", resp)
+	}
+
+	far := time.Now().AddDate(20, 0, 0)
+	for _, bad := range []models.SetSandboxClockRequest{
+		{OffsetSeconds: 11 * 365 * 24 * 3600},
+		{OffsetSeconds: -11 * 365 * 24 * 3600},
+		{At: &far},
+	} {
+		if _, err := svc.Set(ctx, 3, bad); !errors.Is(err, errors.ErrInvalidFormat) {
+			t.Errorf("expected INVALID_FORMAT for %+v, got %v", bad, err)
+		}
+	}
+}
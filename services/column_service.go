@@ -6,6 +6,7 @@ import (
 	"github.com/clementhaon/sandbox-api-go/database"
 	"github.com/clementhaon/sandbox-api-go/models"
 	"github.com/clementhaon/sandbox-api-go/repository"
+	"github.com/clementhaon/sandbox-api-go/sanitize"
 )
 
 type ColumnService interface {
@@ -30,6 +31,8 @@ func (s *columnService) List(ctx context.Context) ([]models.Column, error) {
 }
 
 func (s *columnService) Create(ctx context.Context, req models.CreateColumnRequest) (models.Column, error) {
+	sanitize.Struct(&req)
+
 	if req.Color == "" {
 		req.Color = "#2196F3"
 	}
@@ -43,6 +46,8 @@ func (s *columnService) Create(ctx context.Context, req models.CreateColumnReque
 }
 
 func (s *columnService) Update(ctx context.Context, id int, req models.UpdateColumnRequest) (models.Column, error) {
+	sanitize.Struct(&req)
+
 	existing, err := s.columnRepo.GetByID(ctx, id)
 	if err != nil {
 		return models.Column{}, err
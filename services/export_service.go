@@ -0,0 +1,196 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/jobs"
+	"github.com/clementhaon/sandbox-api-go/logger"
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/repository"
+)
+
+const exportJobPrefix = "data_export_"
+
+type ExportService interface {
+	RequestExport(ctx context.Context, userID int, format string) (models.ExportJob, error)
+	GetExport(ctx context.Context, userID int, jobID string) (models.ExportJob, error)
+	DownloadExport(ctx context.Context, userID int, jobID string) (models.ExportFile, error)
+}
+
+type exportService struct {
+	userRepo      repository.UserRepository
+	taskRepo      repository.TaskRepository
+	timeEntryRepo repository.TimeEntryRepository
+	notifRepo     repository.NotificationRepository
+	queue         *jobs.Queue
+}
+
+func NewExportService(userRepo repository.UserRepository, taskRepo repository.TaskRepository, timeEntryRepo repository.TimeEntryRepository, notifRepo repository.NotificationRepository, queue *jobs.Queue) ExportService {
+	return &exportService{
+		userRepo:      userRepo,
+		taskRepo:      taskRepo,
+		timeEntryRepo: timeEntryRepo,
+		notifRepo:     notifRepo,
+		queue:         queue,
+	}
+}
+
+func (s *exportService) RequestExport(ctx context.Context, userID int, format string) (models.ExportJob, error) {
+	if format == "" {
+		format = models.ExportFormatJSON
+	}
+	if format != models.ExportFormatJSON && format != models.ExportFormatZip {
+		return models.ExportJob{}, errors.NewBadRequestError("format must be 'json' or 'zip'")
+	}
+
+	job, err := s.queue.Enqueue(ctx, exportJobPrefix+format, userID, func(ctx context.Context, report func(int)) (jobs.Output, error) {
+		return s.build(ctx, userID, format, report)
+	})
+	if err != nil {
+		logger.ErrorContext(ctx, "Failed to enqueue data export", err)
+		return models.ExportJob{}, errors.NewServiceUnavailableError().WithCause(err)
+	}
+
+	logger.InfoContext(ctx, "Data export requested", map[string]interface{}{
+		"job_id": job.ID,
+		"format": format,
+	})
+	return toExportJob(job), nil
+}
+
+func (s *exportService) GetExport(ctx context.Context, userID int, jobID string) (models.ExportJob, error) {
+	job, err := s.findJob(userID, jobID)
+	if err != nil {
+		return models.ExportJob{}, err
+	}
+	return toExportJob(job), nil
+}
+
+func (s *exportService) DownloadExport(ctx context.Context, userID int, jobID string) (models.ExportFile, error) {
+	job, err := s.findJob(userID, jobID)
+	if err != nil {
+		return models.ExportFile{}, err
+	}
+	if job.Status != jobs.StatusCompleted {
+		return models.ExportFile{}, errors.NewConflictError("Export is not ready yet")
+	}
+
+	format := strings.TrimPrefix(job.Type, exportJobPrefix)
+	return models.ExportFile{
+		Filename:    fmt.Sprintf("export-%d-%s.%s", userID, job.CreatedAt.Format("20060102"), format),
+		ContentType: job.ContentType,
+		Data:        job.Result,
+	}, nil
+}
+
+func (s *exportService) findJob(userID int, jobID string) (jobs.Job, error) {
+	job, ok := s.queue.Get(jobID)
+	if !ok || job.UserID != userID || !strings.HasPrefix(job.Type, exportJobPrefix) {
+		return jobs.Job{}, errors.NewNotFoundError("Export")
+	}
+	return job, nil
+}
+
+func (s *exportService) build(ctx context.Context, userID int, format string, report func(int)) (jobs.Output, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return jobs.Output{}, err
+	}
+	report(25)
+
+	tasks, err := s.taskRepo.ListByUser(ctx, userID)
+	if err != nil {
+		return jobs.Output{}, err
+	}
+	report(50)
+
+	entries, err := s.timeEntryRepo.ListByUser(ctx, userID)
+	if err != nil {
+		return jobs.Output{}, err
+	}
+	report(75)
+
+	notifications, err := s.notifRepo.List(ctx, userID)
+	if err != nil {
+		return jobs.Output{}, err
+	}
+
+	export := models.UserDataExport{
+		ExportedAt:    time.Now().UTC(),
+		Profile:       models.UserFromDB(user),
+		Tasks:         tasks,
+		TimeEntries:   entries,
+		Notifications: notifications,
+	}
+
+	if format == models.ExportFormatZip {
+		data, err := zipExport(export)
+		if err != nil {
+			return jobs.Output{}, err
+		}
+		return jobs.Output{Data: data, ContentType: "application/zip"}, nil
+	}
+
+	data, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		return jobs.Output{}, err
+	}
+	return jobs.Output{Data: data, ContentType: "application/json"}, nil
+}
+
+// zipExport writes each section of the export as its own JSON file in a zip archive.
+func zipExport(export models.UserDataExport) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+
+	files := []struct {
+		name    string
+		content interface{}
+	}{
+		{"profile.json", export.Profile},
+		{"tasks.json", export.Tasks},
+		{"time_entries.json", export.TimeEntries},
+		{"notifications.json", export.Notifications},
+		{"manifest.json", map[string]interface{}{"exportedAt": export.ExportedAt}},
+	}
+
+	for _, f := range files {
+		w, err := zw.Create(f.name)
+		if err != nil {
+			return nil, err
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(f.content); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func toExportJob(job jobs.Job) models.ExportJob {
+	resp := models.ExportJob{
+		ID:          job.ID,
+		Status:      string(job.Status),
+		Format:      strings.TrimPrefix(job.Type, exportJobPrefix),
+		Progress:    job.Progress,
+		Error:       job.Error,
+		CreatedAt:   job.CreatedAt,
+		CompletedAt: job.CompletedAt,
+	}
+	if job.Status == jobs.StatusCompleted {
+		resp.DownloadURL = "/profile/export/" + job.ID + "/download"
+	}
+	return resp
+}
@@ -0,0 +1,173 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/jobs"
+	"github.com/clementhaon/sandbox-api-go/mocks"
+	"github.com/clementhaon/sandbox-api-go/models"
+)
+
+func newTestExportService(t *testing.T) (ExportService, *jobs.Queue) {
+	t.Helper()
+	queue := jobs.NewQueue(1)
+	t.Cleanup(queue.Stop)
+
+	userRepo := &mocks.MockUserRepository{
+		GetByIDFn: func(ctx context.Context, id int) (models.User, error) {
+			return models.User{ID: id, Username: "johndoe", Email: "john@example.com", IsActive: true}, nil
+		},
+	}
+	taskRepo := &mocks.MockTaskRepository{
+		ListByUserFn: func(ctx context.Context, userID int) ([]models.Task, error) {
+			return []models.Task{{ID: 1, Title: "Task 1", UserID: userID}}, nil
+		},
+	}
+	timeEntryRepo := &mocks.MockTimeEntryRepository{
+		ListByUserFn: func(ctx context.Context, userID int) ([]models.TimeEntry, error) {
+			return []models.TimeEntry{{ID: 1, TaskID: 1, UserID: userID, Duration: 60}}, nil
+		},
+	}
+	notifRepo := &mocks.MockNotificationRepository{
+		ListFn: func(ctx context.Context, userID int) ([]models.Notification, error) {
+			return []models.Notification{}, nil
+		},
+	}
+
+	return NewExportService(userRepo, taskRepo, timeEntryRepo, notifRepo, queue), queue
+}
+
+func waitForExport(t *testing.T, svc ExportService, userID int, jobID string) models.ExportJob {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		job, err := svc.GetExport(context.Background(), userID, jobID)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if job.Status == string(jobs.StatusCompleted) || job.Status == string(jobs.StatusFailed) {
+			return job
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("export did not finish in time")
+	return models.ExportJob{}
+}
+
+func TestExportService_JSONExport(t *testing.T) {
+	svc, _ := newTestExportService(t)
+
+	job, err := svc.RequestExport(context.Background(), 42, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if job.Format != models.ExportFormatJSON {
+		t.Errorf("expected default format json, got %q", job.Format)
+	}
+
+	finished := waitForExport(t, svc, 42, job.ID)
+	if finished.Status != string(jobs.StatusCompleted) {
+		t.Fatalf("expected completed export, got %q (%s)", finished.Status, finished.Error)
+	}
+	if finished.DownloadURL != "/profile/export/"+job.ID+"/download" {
+		t.Errorf("unexpected download URL %q", finished.DownloadURL)
+	}
+
+	file, err := svc.DownloadExport(context.Background(), 42, job.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if file.ContentType != "application/json" {
+		t.Errorf("expected application/json, got %q", file.ContentType)
+	}
+
+	var export models.UserDataExport
+	if err := json.Unmarshal(file.Data, &export); err != nil {
+		t.Fatalf("export is not valid JSON: %v", err)
+	}
+	if export.Profile.Email != "john@example.com" {
+		t.Errorf("expected profile email in export, got %q", export.Profile.Email)
+	}
+	if len(export.Tasks) != 1 || len(export.TimeEntries) != 1 {
+		t.Errorf("expected 1 task and 1 time entry, got %d and %d", len(export.Tasks), len(export.TimeEntries))
+	}
+}
+
+func TestExportService_ZipExport(t *testing.T) {
+	svc, _ := newTestExportService(t)
+
+	job, err := svc.RequestExport(context.Background(), 42, "zip")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	waitForExport(t, svc, 42, job.ID)
+
+	file, err := svc.DownloadExport(context.Background(), 42, job.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(file.Data), int64(len(file.Data)))
+	if err != nil {
+		t.Fatalf("export is not a valid zip: %v", err)
+	}
+	names := map[string]bool{}
+	for _, f := range zr.File {
+		names[f.Name] = true
+	}
+	for _, want := range []string{"profile.json", "tasks.json", "time_entries.json", "notifications.json"} {
+		if !names[want] {
+			t.Errorf("expected %s in archive", want)
+		}
+	}
+}
+
+func TestExportService_InvalidFormat(t *testing.T) {
+	svc, _ := newTestExportService(t)
+
+	_, err := svc.RequestExport(context.Background(), 42, "xml")
+	if err == nil {
+		t.Fatal("expected error for invalid format")
+	}
+}
+
+func TestExportService_OtherUsersJobNotFound(t *testing.T) {
+	svc, _ := newTestExportService(t)
+
+	job, _ := svc.RequestExport(context.Background(), 42, "json")
+
+	_, err := svc.GetExport(context.Background(), 7, job.ID)
+	appErr, ok := errors.IsAppError(err)
+	if !ok || appErr.Code != errors.ErrNotFound {
+		t.Fatalf("expected NOT_FOUND for another user's export, got %v", err)
+	}
+}
+
+func TestExportService_DownloadNotReady(t *testing.T) {
+	queue := jobs.NewQueue(1)
+	defer queue.Stop()
+
+	release := make(chan struct{})
+	defer close(release)
+	userRepo := &mocks.MockUserRepository{
+		GetByIDFn: func(ctx context.Context, id int) (models.User, error) {
+			<-release
+			return models.User{ID: id}, nil
+		},
+	}
+	svc := NewExportService(userRepo, &mocks.MockTaskRepository{}, &mocks.MockTimeEntryRepository{}, &mocks.MockNotificationRepository{}, queue)
+
+	job, _ := svc.RequestExport(context.Background(), 42, "json")
+
+	_, err := svc.DownloadExport(context.Background(), 42, job.ID)
+	appErr, ok := errors.IsAppError(err)
+	if !ok || appErr.Code != errors.ErrConflict {
+		t.Fatalf("expected CONFLICT for unfinished export, got %v", err)
+	}
+}
@@ -0,0 +1,244 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/logger"
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/repository"
+	"github.com/clementhaon/sandbox-api-go/sanitize"
+	"github.com/clementhaon/sandbox-api-go/tenant"
+	"github.com/clementhaon/sandbox-api-go/validation"
+)
+
+// inboundSecretPrefix marks inbound webhook secrets, so secret scanners can
+// spot them.
+const inboundSecretPrefix = "inb_"
+
+// InboundService manages the inbound webhook each user can enable to
+// create tasks from other tools, and turns the payloads it receives into
+// tasks.
+type InboundService interface {
+	Settings(ctx context.Context, userID int) (models.InboundSettings, error)
+	// RotateSecret enables the webhook with a new secret, or replaces the
+	// secret of an enabled one. The secret is only returned here.
+	RotateSecret(ctx context.Context, userID int) (models.InboundSecretResponse, error)
+	UpdateMapping(ctx context.Context, userID int, mapping models.InboundMapping) (models.InboundSettings, error)
+	Disable(ctx context.Context, userID int) error
+	// Receive creates a task for the owner of secret from payload, a JSON
+	// object or the fields of a form.
+	Receive(ctx context.Context, secret string, payload map[string]interface{}) (models.Task, error)
+}
+
+type inboundService struct {
+	hookRepo   repository.InboundHookRepository
+	columnRepo repository.ColumnRepository
+	taskSvc    TaskService
+}
+
+func NewInboundService(hookRepo repository.InboundHookRepository, columnRepo repository.ColumnRepository, taskSvc TaskService) InboundService {
+	return &inboundService{hookRepo: hookRepo, columnRepo: columnRepo, taskSvc: taskSvc}
+}
+
+func (s *inboundService) Settings(ctx context.Context, userID int) (models.InboundSettings, error) {
+	hook, err := s.hookRepo.Get(ctx, userID)
+	if errors.Is(err, errors.ErrNotFound) {
+		return models.InboundSettings{}, nil
+	}
+	if err != nil {
+		return models.InboundSettings{}, err
+	}
+	return inboundSettings(hook), nil
+}
+
+func (s *inboundService) RotateSecret(ctx context.Context, userID int) (models.InboundSecretResponse, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return models.InboundSecretResponse{}, errors.NewInternalError().WithCause(err)
+	}
+	secret := inboundSecretPrefix + hex.EncodeToString(b)
+
+	hook, err := s.hookRepo.SetSecret(ctx, userID, hashToken(secret))
+	if err != nil {
+		return models.InboundSecretResponse{}, err
+	}
+	return models.InboundSecretResponse{Secret: secret, InboundSettings: inboundSettings(hook)}, nil
+}
+
+func (s *inboundService) UpdateMapping(ctx context.Context, userID int, mapping models.InboundMapping) (models.InboundSettings, error) {
+	sanitize.Struct(&mapping)
+	if err := validation.Struct(mapping); err != nil {
+		return models.InboundSettings{}, err
+	}
+	if mapping.ColumnID != 0 {
+		if _, err := s.columnRepo.GetByID(ctx, mapping.ColumnID); err != nil {
+			if errors.Is(err, errors.ErrNotFound) {
+				return models.InboundSettings{}, errors.NewBadRequestError("Column not found")
+			}
+			return models.InboundSettings{}, err
+		}
+	}
+
+	hook, err := s.hookRepo.UpdateMapping(ctx, userID, mapping)
+	if err != nil {
+		return models.InboundSettings{}, err
+	}
+	return inboundSettings(hook), nil
+}
+
+func (s *inboundService) Disable(ctx context.Context, userID int) error {
+	return s.hookRepo.Delete(ctx, userID)
+}
+
+func (s *inboundService) Receive(ctx context.Context, secret string, payload map[string]interface{}) (models.Task, error) {
+	if secret == "" {
+		return models.Task{}, errors.NewAuthRequiredError()
+	}
+	hook, err := s.hookRepo.GetBySecretHash(ctx, hashToken(secret))
+	if errors.Is(err, errors.ErrNotFound) {
+		return models.Task{}, errors.NewInvalidTokenError()
+	}
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	// The secret names the user, and so the tenant
+	ctx = tenant.WithID(ctx, hook.TenantID)
+	ctx = logger.With(ctx, "tenant_id", hook.TenantID, "user_id", hook.UserID)
+
+	req, err := inboundTaskRequest(hook.Mapping, payload)
+	if err != nil {
+		return models.Task{}, err
+	}
+	if req.ColumnID == 0 {
+		columns, err := s.columnRepo.List(ctx)
+		if err != nil {
+			return models.Task{}, err
+		}
+		if len(columns) == 0 {
+			return models.Task{}, errors.NewBadRequestError("No column to create the task in")
+		}
+		req.ColumnID = columns[0].ID
+	}
+
+	return s.taskSvc.Create(ctx, hook.UserID, req)
+}
+
+// inboundTaskRequest maps a payload to a task. A priority the board does
+// not know falls back to the mapping's; a deadline that cannot be read is
+// an error.
+func inboundTaskRequest(mapping models.InboundMapping, payload map[string]interface{}) (models.CreateTaskRequest, error) {
+	fields := mapping.Fields
+	req := models.CreateTaskRequest{
+		Title:       inboundString(payload, fieldOr(fields.Title, "title")),
+		Description: inboundString(payload, fieldOr(fields.Description, "description")),
+		ColumnID:    mapping.ColumnID,
+		Priority:    mapping.Priority,
+	}
+	if req.Title == "" {
+		return models.CreateTaskRequest{}, errors.NewBadRequestError(
+			fmt.Sprintf("Payload has no %q field for the title", fieldOr(fields.Title, "title")))
+	}
+
+	priority := strings.ToLower(inboundString(payload, fieldOr(fields.Priority, "priority")))
+	if slices.Contains(models.ValidPriorities(), priority) {
+		req.Priority = priority
+	}
+
+	if deadline := inboundString(payload, fieldOr(fields.Deadline, "deadline")); deadline != "" {
+		t, err := parseInboundTime(deadline)
+		if err != nil {
+			return models.CreateTaskRequest{}, errors.NewInvalidFormatError(fieldOr(fields.Deadline, "deadline"), "RFC 3339 time or YYYY-MM-DD date")
+		}
+		req.Deadline = &t
+	}
+
+	req.Tags = slices.Clone(mapping.Tags)
+	for _, tag := range inboundList(payload, fieldOr(fields.Tags, "tags")) {
+		if !slices.Contains(req.Tags, tag) {
+			req.Tags = append(req.Tags, tag)
+		}
+	}
+	return req, nil
+}
+
+func fieldOr(field, fallback string) string {
+	if field == "" {
+		return fallback
+	}
+	return field
+}
+
+// inboundValue follows a dot-separated path through nested objects.
+func inboundValue(payload map[string]interface{}, path string) interface{} {
+	var value interface{} = payload
+	for _, key := range strings.Split(path, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = object[key]
+	}
+	return value
+}
+
+// inboundString returns the value at path as a trimmed string; numbers and
+// booleans are formatted, anything else is empty.
+func inboundString(payload map[string]interface{}, path string) string {
+	switch v := inboundValue(payload, path).(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return ""
+}
+
+// inboundList returns the strings of a list, or of a comma-separated string.
+func inboundList(payload map[string]interface{}, path string) []string {
+	var items []string
+	switch v := inboundValue(payload, path).(type) {
+	case string:
+		items = strings.Split(v, ",")
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				items = append(items, s)
+			}
+		}
+	}
+
+	list := make([]string, 0, len(items))
+	for _, item := range items {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func parseInboundTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, s)
+}
+
+func inboundSettings(hook models.InboundHook) models.InboundSettings {
+	return models.InboundSettings{
+		Enabled:   true,
+		Mapping:   hook.Mapping,
+		CreatedAt: &hook.CreatedAt,
+		UpdatedAt: &hook.UpdatedAt,
+	}
+}
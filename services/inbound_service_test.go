@@ -0,0 +1,147 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/mocks"
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/tenant"
+)
+
+func TestInboundService_RotateSecret(t *testing.T) {
+	var storedHash string
+	svc := NewInboundService(&mocks.MockInboundHookRepository{
+		SetSecretFn: func(ctx context.Context, userID int, secretHash string) (models.InboundHook, error) {
+			storedHash = secretHash
+			return models.InboundHook{UserID: userID, SecretHash: secretHash}, nil
+		},
+	}, &mocks.MockColumnRepository{}, &mocks.MockTaskService{})
+
+	resp, err := svc.RotateSecret(context.Background(), 7)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(resp.Secret, inboundSecretPrefix) || !resp.Enabled {
+		t.Errorf("unexpected response %+v", resp)
+	}
+	if storedHash != hashToken(resp.Secret) {
+		t.Error("expected only the hash of the secret to be stored")
+	}
+}
+
+func TestInboundService_UpdateMapping(t *testing.T) {
+	svc := NewInboundService(&mocks.MockInboundHookRepository{
+		UpdateMappingFn: func(ctx context.Context, userID int, mapping models.InboundMapping) (models.InboundHook, error) {
+			return models.InboundHook{UserID: userID, Mapping: mapping}, nil
+		},
+	}, &mocks.MockColumnRepository{
+		GetByIDFn: func(ctx context.Context, id int) (models.Column, error) {
+			if id != 3 {
+				return models.Column{}, errors.NewNotFoundError("Column")
+			}
+			return models.Column{ID: 3}, nil
+		},
+	}, &mocks.MockTaskService{})
+	ctx := context.Background()
+
+	settings, err := svc.UpdateMapping(ctx, 7, models.InboundMapping{ColumnID: 3, Priority: "high", Tags: []string{"<b>mail</b>"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if settings.Mapping.Tags[0] != "mail" {
+		t.Errorf("expected tags sanitized, got %v", settings.Mapping.Tags)
+	}
+
+	if _, err := svc.UpdateMapping(ctx, 7, models.InboundMapping{ColumnID: 4}); !errors.Is(err, errors.ErrValidationFailed) {
+		t.Errorf("expected a bad request for an unknown column, got %v", err)
+	}
+	if _, err := svc.UpdateMapping(ctx, 7, models.InboundMapping{Priority: "asap"}); !errors.Is(err, errors.ErrValidationFailed) {
+		t.Errorf("expected a validation error for an unknown priority, got %v", err)
+	}
+}
+
+func TestInboundService_Receive(t *testing.T) {
+	mapping := models.InboundMapping{
+		Fields:   models.InboundFields{Title: "subject", Description: "body-plain", Tags: "labels"},
+		Priority: models.PriorityHigh,
+		Tags:     []string{"inbox"},
+	}
+	var created models.CreateTaskRequest
+	var createdFor, createdTenant int
+	svc := NewInboundService(&mocks.MockInboundHookRepository{
+		GetBySecretHashFn: func(ctx context.Context, secretHash string) (models.InboundHook, error) {
+			if secretHash != hashToken("inb_good") {
+				return models.InboundHook{}, errors.NewNotFoundError("Inbound webhook")
+			}
+			return models.InboundHook{UserID: 7, TenantID: 2, Mapping: mapping}, nil
+		},
+	}, &mocks.MockColumnRepository{
+		ListFn: func(ctx context.Context) ([]models.Column, error) {
+			return []models.Column{{ID: 11}, {ID: 12}}, nil
+		},
+	}, &mocks.MockTaskService{
+		CreateFn: func(ctx context.Context, userID int, req models.CreateTaskRequest) (models.Task, error) {
+			created, createdFor, createdTenant = req, userID, tenant.FromContext(ctx)
+			return models.Task{ID: 1, Title: req.Title}, nil
+		},
+	})
+	ctx := context.Background()
+
+	t.Run("maps the payload", func(t *testing.T) {
+		payload := map[string]interface{}{
+			"subject":    "Renew the domain",
+			"body-plain": "Expires next week",
+			"priority":   "Urgent",
+			"deadline":   "2026-11-01",
+			"labels":     "ops, inbox",
+		}
+		if _, err := svc.Receive(ctx, "inb_good", payload); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if createdFor != 7 || createdTenant != 2 {
+			t.Errorf("expected a task of user 7 in tenant 2, got user %d tenant %d", createdFor, createdTenant)
+		}
+		if created.Title != "Renew the domain" || created.Description != "Expires next week" || created.ColumnID != 11 {
+			t.Errorf("unexpected task %+v", created)
+		}
+		if created.Priority != models.PriorityUrgent || !created.Deadline.Equal(time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)) {
+			t.Errorf("unexpected priority or deadline %+v", created)
+		}
+		if strings.Join(created.Tags, ",") != "inbox,ops" {
+			t.Errorf("tags = %v, want inbox,ops", created.Tags)
+		}
+	})
+
+	t.Run("reads nested fields and falls back to the mapping", func(t *testing.T) {
+		nested := mapping
+		nested.Fields.Title = "data.title"
+		payload := map[string]interface{}{"data": map[string]interface{}{"title": "From Zapier"}, "priority": "P1"}
+		task, err := inboundTaskRequest(nested, payload)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if task.Title != "From Zapier" || task.Priority != models.PriorityHigh {
+			t.Errorf("unexpected task %+v", task)
+		}
+	})
+
+	t.Run("refuses bad secrets and payloads", func(t *testing.T) {
+		if _, err := svc.Receive(ctx, "", map[string]interface{}{}); !errors.Is(err, errors.ErrAuthRequired) {
+			t.Errorf("expected auth required, got %v", err)
+		}
+		if _, err := svc.Receive(ctx, "inb_bad", map[string]interface{}{"subject": "x"}); !errors.Is(err, errors.ErrInvalidToken) {
+			t.Errorf("expected an invalid token, got %v", err)
+		}
+		if _, err := svc.Receive(ctx, "inb_good", map[string]interface{}{"title": "x"}); !errors.Is(err, errors.ErrValidationFailed) {
+			t.Errorf("expected a bad request without the mapped title, got %v", err)
+		}
+		_, err := svc.Receive(ctx, "inb_good", map[string]interface{}{"subject": "x", "deadline": "next week"})
+		if !errors.Is(err, errors.ErrInvalidFormat) {
+			t.Errorf("expected an invalid format for the deadline, got %v", err)
+		}
+	})
+}
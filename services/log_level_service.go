@@ -0,0 +1,216 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/logger"
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/validation"
+)
+
+// LogLevelService changes the levels of the process logger at runtime.
+type LogLevelService interface {
+	Get() models.LogLevels
+	// Update applies req and records it in the audit log. With a TTL, the
+	// levels from before the first of the pending temporary changes are
+	// restored when it expires; a change without TTL cancels the restore.
+	Update(ctx context.Context, req models.UpdateLogLevelRequest) (models.LogLevels, error)
+	// Configure applies the global level of a reloaded configuration. While
+	// a temporary change is pending, it becomes the level restored when the
+	// TTL expires instead.
+	Configure(level slog.Level)
+	// Stop cancels the pending restore, leaving the levels as they are.
+	Stop()
+}
+
+// logLevels is a state of the logger, as set by logger.SetLevel and
+// logger.SetModuleLevels.
+type logLevels struct {
+	level   slog.Level
+	modules map[string]slog.Level
+}
+
+type logLevelService struct {
+	auditSvc  AuditService
+	afterFunc func(time.Duration, func()) *time.Timer
+
+	mu       sync.Mutex
+	baseline *logLevels // to restore when the TTL expires
+	timer    *time.Timer
+	revertAt time.Time
+	changes  int // tells a stale timer from the current one
+}
+
+func NewLogLevelService(auditSvc AuditService) LogLevelService {
+	return &logLevelService{auditSvc: auditSvc, afterFunc: time.AfterFunc}
+}
+
+func (s *logLevelService) Get() models.LogLevels {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.levels()
+}
+
+func (s *logLevelService) Update(ctx context.Context, req models.UpdateLogLevelRequest) (models.LogLevels, error) {
+	if err := validation.Struct(req); err != nil {
+		return models.LogLevels{}, err
+	}
+	var level *slog.Level
+	if req.Level != nil {
+		l, err := parseLogLevel(*req.Level)
+		if err != nil {
+			return models.LogLevels{}, errors.NewInvalidFormatError("level", "DEBUG, INFO, WARN or ERROR")
+		}
+		level = &l
+	}
+	var modules map[string]slog.Level
+	if req.Modules != nil {
+		modules = make(map[string]slog.Level, len(req.Modules))
+		for module, value := range req.Modules {
+			l, err := parseLogLevel(value)
+			if err != nil || strings.TrimSpace(module) == "" {
+				return models.LogLevels{}, errors.NewInvalidFormatError("modules", "package names mapped to DEBUG, INFO, WARN or ERROR")
+			}
+			modules[module] = l
+		}
+	}
+
+	s.mu.Lock()
+	previous := currentLogLevels()
+	next := previous
+	if level != nil {
+		next.level = *level
+	}
+	if modules != nil {
+		next.modules = modules
+	}
+	s.changes++
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	if req.TTLSeconds > 0 {
+		if s.baseline == nil {
+			s.baseline = &previous
+		}
+		ttl := time.Duration(req.TTLSeconds) * time.Second
+		change := s.changes
+		s.timer = s.afterFunc(ttl, func() { s.revert(change) })
+		s.revertAt = time.Now().Add(ttl)
+	} else {
+		s.baseline = nil
+		s.revertAt = time.Time{}
+	}
+	next.apply()
+	levels := s.levels()
+	s.mu.Unlock()
+
+	s.auditSvc.Record(ctx, models.AuditEntry{
+		Action:     models.AuditActionLogLevel,
+		TargetType: "logger",
+		Metadata: map[string]interface{}{
+			"level":           levels.Level,
+			"modules":         levels.Modules,
+			"previousLevel":   previous.level.String(),
+			"previousModules": levelNames(previous.modules),
+			"ttlSeconds":      req.TTLSeconds,
+		},
+	})
+	logger.InfoContext(ctx, "Log level changed", map[string]interface{}{
+		"level":       levels.Level,
+		"modules":     levels.Modules,
+		"ttl_seconds": req.TTLSeconds,
+	})
+	return levels, nil
+}
+
+func (s *logLevelService) Configure(level slog.Level) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.baseline != nil {
+		s.baseline.level = level
+		return
+	}
+	logger.SetLevel(level)
+}
+
+func (s *logLevelService) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.changes++
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+	s.baseline, s.timer, s.revertAt = nil, nil, time.Time{}
+}
+
+// revert restores the levels from before the temporary changes, unless
+// another change came after the one whose TTL expired.
+func (s *logLevelService) revert(change int) {
+	s.mu.Lock()
+	if change != s.changes || s.baseline == nil {
+		s.mu.Unlock()
+		return
+	}
+	s.baseline.apply()
+	s.baseline, s.timer, s.revertAt = nil, nil, time.Time{}
+	levels := s.levels()
+	s.mu.Unlock()
+
+	s.auditSvc.Record(context.Background(), models.AuditEntry{
+		Action:     models.AuditActionLogLevel,
+		TargetType: "logger",
+		Metadata: map[string]interface{}{
+			"level":    levels.Level,
+			"modules":  levels.Modules,
+			"reverted": true,
+		},
+	})
+	logger.Info("Log level reverted", map[string]interface{}{
+		"level":   levels.Level,
+		"modules": levels.Modules,
+	})
+}
+
+// levels describes the logger's levels. s.mu must be held.
+func (s *logLevelService) levels() models.LogLevels {
+	current := currentLogLevels()
+	levels := models.LogLevels{
+		Level:   current.level.String(),
+		Modules: levelNames(current.modules),
+	}
+	if !s.revertAt.IsZero() {
+		revertAt := s.revertAt
+		levels.RevertAt = &revertAt
+	}
+	return levels
+}
+
+func currentLogLevels() logLevels {
+	return logLevels{level: logger.Level(), modules: logger.ModuleLevels()}
+}
+
+func (l logLevels) apply() {
+	logger.SetLevel(l.level)
+	logger.SetModuleLevels(l.modules)
+}
+
+func levelNames(levels map[string]slog.Level) map[string]string {
+	names := make(map[string]string, len(levels))
+	for module, l := range levels {
+		names[module] = l.String()
+	}
+	return names
+}
+
+// parseLogLevel reads DEBUG, INFO, WARN or ERROR, in any case.
+func parseLogLevel(value string) (slog.Level, error) {
+	var l slog.Level
+	err := l.UnmarshalText([]byte(strings.TrimSpace(value)))
+	return l, err
+}
@@ -0,0 +1,145 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/logger"
+	"github.com/clementhaon/sandbox-api-go/mocks"
+	"github.com/clementhaon/sandbox-api-go/models"
+)
+
+func strPtr(v string) *string { return &v }
+
+func TestLogLevelService_Update(t *testing.T) {
+	logger.Setup(logger.Options{Level: slog.LevelInfo})
+	defer func() {
+		logger.SetLevel(slog.LevelInfo)
+		logger.SetModuleLevels(nil)
+	}()
+
+	var audited []models.AuditEntry
+	audit := &mocks.MockAuditService{
+		RecordFn: func(ctx context.Context, entry models.AuditEntry) {
+			audited = append(audited, entry)
+		},
+	}
+	svc := NewLogLevelService(audit).(*logLevelService)
+	var reverts []func()
+	svc.afterFunc = func(d time.Duration, f func()) *time.Timer {
+		reverts = append(reverts, f)
+		return time.NewTimer(time.Hour)
+	}
+
+	levels, err := svc.Update(context.Background(), models.UpdateLogLevelRequest{
+		Level:      strPtr("warn"),
+		Modules:    map[string]string{"repository": "DEBUG"},
+		TTLSeconds: 60,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if logger.Level() != slog.LevelWarn || logger.ModuleLevels()["repository"] != slog.LevelDebug {
+		t.Errorf("expected WARN with repository at DEBUG, got %s %v", logger.Level(), logger.ModuleLevels())
+	}
+	if levels.Level != "WARN" || levels.Modules["repository"] != "DEBUG" || levels.RevertAt == nil {
+		t.Errorf("unexpected levels %+v", levels)
+	}
+	if len(audited) != 1 || audited[0].Action != models.AuditActionLogLevel || audited[0].Metadata["previousLevel"] != "INFO" {
+		t.Errorf("expected the change audited, got %+v", audited)
+	}
+
+	// A second temporary change keeps the levels to restore
+	if _, err := svc.Update(context.Background(), models.UpdateLogLevelRequest{Level: strPtr("DEBUG"), TTLSeconds: 60}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	reverts[0]() // stale
+	if logger.Level() != slog.LevelDebug {
+		t.Errorf("expected the stale timer to be ignored, got %s", logger.Level())
+	}
+	reverts[1]()
+	if logger.Level() != slog.LevelInfo || len(logger.ModuleLevels()) != 0 {
+		t.Errorf("expected INFO without module levels back, got %s %v", logger.Level(), logger.ModuleLevels())
+	}
+	if got := svc.Get(); got.RevertAt != nil {
+		t.Errorf("expected no pending revert, got %v", got.RevertAt)
+	}
+	if len(audited) != 3 || audited[2].Metadata["reverted"] != true {
+		t.Errorf("expected the revert audited, got %+v", audited)
+	}
+
+	// A change without TTL cancels the revert
+	svc.Update(context.Background(), models.UpdateLogLevelRequest{Level: strPtr("ERROR"), TTLSeconds: 60})
+	svc.Update(context.Background(), models.UpdateLogLevelRequest{Level: strPtr("WARN")})
+	reverts[2]()
+	if logger.Level() != slog.LevelWarn {
+		t.Errorf("expected the permanent change to stay, got %s", logger.Level())
+	}
+}
+
+func TestLogLevelService_ConfigureAndStop(t *testing.T) {
+	logger.Setup(logger.Options{Level: slog.LevelInfo})
+	defer logger.SetLevel(slog.LevelInfo)
+
+	svc := NewLogLevelService(&mocks.MockAuditService{}).(*logLevelService)
+	var reverts []func()
+	var timers []*time.Timer
+	svc.afterFunc = func(d time.Duration, f func()) *time.Timer {
+		reverts = append(reverts, f)
+		timers = append(timers, time.NewTimer(time.Hour))
+		return timers[len(timers)-1]
+	}
+
+	// Without a pending change, the reloaded level applies at once
+	svc.Configure(slog.LevelWarn)
+	if logger.Level() != slog.LevelWarn {
+		t.Errorf("expected the configured level applied, got %s", logger.Level())
+	}
+
+	// During a temporary change, it is the level the change reverts to
+	svc.Update(context.Background(), models.UpdateLogLevelRequest{Level: strPtr("DEBUG"), TTLSeconds: 60})
+	svc.Configure(slog.LevelError)
+	if logger.Level() != slog.LevelDebug {
+		t.Errorf("expected the temporary level kept, got %s", logger.Level())
+	}
+	reverts[0]()
+	if logger.Level() != slog.LevelError {
+		t.Errorf("expected the reloaded level restored, got %s", logger.Level())
+	}
+
+	// Stop cancels the pending revert
+	svc.Update(context.Background(), models.UpdateLogLevelRequest{Level: strPtr("DEBUG"), TTLSeconds: 60})
+	svc.Stop()
+	if timers[1].Stop() {
+		t.Error("expected the revert timer stopped")
+	}
+	reverts[1]()
+	if logger.Level() != slog.LevelDebug {
+		t.Errorf("expected no revert once stopped, got %s", logger.Level())
+	}
+	if got := svc.Get(); got.RevertAt != nil {
+		t.Errorf("expected no pending revert, got %v", got.RevertAt)
+	}
+}
+
+func TestLogLevelService_Update_Invalid(t *testing.T) {
+	svc := NewLogLevelService(&mocks.MockAuditService{})
+
+	for name, req := range map[string]models.UpdateLogLevelRequest{
+		"level":    {Level: strPtr("verbose")},
+		"module":   {Modules: map[string]string{"handlers": "loud"}},
+		"ttl":      {Level: strPtr("DEBUG"), TTLSeconds: -1},
+		"long ttl": {Level: strPtr("DEBUG"), TTLSeconds: 7 * 86400},
+	} {
+		_, err := svc.Update(context.Background(), req)
+		if !errors.Is(err, errors.ErrInvalidFormat) && !errors.Is(err, errors.ErrValidationFailed) {
+			t.Errorf("%s: expected a validation error, got %v", name, err)
+		}
+	}
+	if logger.Level() != slog.LevelInfo {
+		t.Errorf("expected the level unchanged, got %s", logger.Level())
+	}
+}
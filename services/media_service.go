@@ -22,10 +22,11 @@ type MediaService interface {
 type mediaService struct {
 	mediaRepo repository.MediaRepository
 	storage   storage.StorageClient
+	quotaSvc  QuotaService
 }
 
-func NewMediaService(mediaRepo repository.MediaRepository, storage storage.StorageClient) MediaService {
-	return &mediaService{mediaRepo: mediaRepo, storage: storage}
+func NewMediaService(mediaRepo repository.MediaRepository, storage storage.StorageClient, quotaSvc QuotaService) MediaService {
+	return &mediaService{mediaRepo: mediaRepo, storage: storage, quotaSvc: quotaSvc}
 }
 
 func (s *mediaService) GetPresignedUploadURL(ctx context.Context, userID int, filename, mimeType string) (models.PresignedUploadURLResponse, error) {
@@ -60,6 +61,14 @@ func (s *mediaService) ConfirmUpload(ctx context.Context, userID int, objectKey,
 		return models.Media{}, errors.NewBadRequestError("Object not found or upload incomplete")
 	}
 
+	// The object is already stored, so remove it if it does not fit the quota
+	if err := s.quotaSvc.CheckMediaQuota(ctx, userID, objInfo.Size); err != nil {
+		if delErr := s.storage.DeleteObject(objectKey); delErr != nil {
+			logger.Error("Failed to delete over-quota upload", delErr)
+		}
+		return models.Media{}, err
+	}
+
 	return s.mediaRepo.Create(ctx, userID, objectKey, bucketName, originalFilename, mimeType, objInfo.Size)
 }
 
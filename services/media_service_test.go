@@ -53,7 +53,7 @@ func TestMediaService_GetPresignedUploadURL(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			storage := &mocks.MockStorage{GeneratePresignedUploadURLFn: tt.uploadFn}
 			repo := &mocks.MockMediaRepository{}
-			svc := NewMediaService(repo, storage)
+			svc := NewMediaService(repo, storage, &mocks.MockQuotaService{})
 
 			resp, err := svc.GetPresignedUploadURL(context.Background(), 1, tt.filename, tt.mimeType)
 			if tt.wantErr {
@@ -124,7 +124,7 @@ func TestMediaService_ConfirmUpload(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			storage := &mocks.MockStorage{GetObjectInfoFn: tt.getInfoFn}
 			repo := &mocks.MockMediaRepository{CreateFn: tt.createFn}
-			svc := NewMediaService(repo, storage)
+			svc := NewMediaService(repo, storage, &mocks.MockQuotaService{})
 
 			media, err := svc.ConfirmUpload(context.Background(), 1, tt.objectKey, tt.origFile, tt.mimeType, tt.bucket)
 			if tt.wantErr {
@@ -206,7 +206,7 @@ func TestMediaService_ListUserMedia(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			storage := &mocks.MockStorage{GeneratePresignedDownloadURLFn: tt.downloadFn}
 			repo := &mocks.MockMediaRepository{CountFn: tt.countFn, ListFn: tt.listFn}
-			svc := NewMediaService(repo, storage)
+			svc := NewMediaService(repo, storage, &mocks.MockQuotaService{})
 
 			resp, err := svc.ListUserMedia(context.Background(), 1, tt.page)
 			if tt.wantErr {
@@ -266,7 +266,7 @@ func TestMediaService_GetByID(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			storage := &mocks.MockStorage{GeneratePresignedDownloadURLFn: tt.downloadFn}
 			repo := &mocks.MockMediaRepository{GetByIDFn: tt.getByIDFn}
-			svc := NewMediaService(repo, storage)
+			svc := NewMediaService(repo, storage, &mocks.MockQuotaService{})
 
 			media, err := svc.GetByID(context.Background(), 1, 5)
 			if tt.wantErr {
@@ -324,7 +324,7 @@ func TestMediaService_GetPresignedDownloadURL(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			storage := &mocks.MockStorage{GeneratePresignedDownloadURLFn: tt.downloadFn}
 			repo := &mocks.MockMediaRepository{GetObjectKeyFn: tt.getObjectKeyFn}
-			svc := NewMediaService(repo, storage)
+			svc := NewMediaService(repo, storage, &mocks.MockQuotaService{})
 
 			resp, err := svc.GetPresignedDownloadURL(context.Background(), 1, 5)
 			if tt.wantErr {
@@ -407,7 +407,7 @@ func TestMediaService_Delete(t *testing.T) {
 				GetObjectKeyFn: tt.getObjectKeyFn,
 				DeleteFn:       tt.deleteRepoFn,
 			}
-			svc := NewMediaService(repo, storage)
+			svc := NewMediaService(repo, storage, &mocks.MockQuotaService{})
 
 			err := svc.Delete(context.Background(), 1, 5)
 			if tt.wantErr {
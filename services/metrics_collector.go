@@ -0,0 +1,72 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/logger"
+	"github.com/clementhaon/sandbox-api-go/metrics"
+	"github.com/clementhaon/sandbox-api-go/repository"
+)
+
+// activeUserWindow is how recently a user must have logged in to count as active.
+const activeUserWindow = 24 * time.Hour
+
+// MetricsCollector periodically refreshes the business gauges (active users, tasks by status).
+type MetricsCollector struct {
+	userRepo repository.UserRepository
+	taskRepo repository.TaskRepository
+	metrics  *metrics.Metrics
+	stopCh   chan struct{}
+	stopOnce sync.Once
+}
+
+// StartMetricsCollector collects into m once immediately, then every interval.
+func StartMetricsCollector(userRepo repository.UserRepository, taskRepo repository.TaskRepository, interval time.Duration, m *metrics.Metrics) *MetricsCollector {
+	mc := &MetricsCollector{
+		userRepo: userRepo,
+		taskRepo: taskRepo,
+		metrics:  m,
+		stopCh:   make(chan struct{}),
+	}
+	go mc.run(interval)
+	return mc
+}
+
+// Stop terminates the collection loop.
+func (mc *MetricsCollector) Stop() {
+	mc.stopOnce.Do(func() { close(mc.stopCh) })
+}
+
+func (mc *MetricsCollector) run(interval time.Duration) {
+	mc.collect(context.Background())
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			mc.collect(context.Background())
+		case <-mc.stopCh:
+			return
+		}
+	}
+}
+
+// collect updates each gauge independently so one failing query does not block the others.
+func (mc *MetricsCollector) collect(ctx context.Context) {
+	if active, err := mc.userRepo.CountActiveSince(ctx, time.Now().Add(-activeUserWindow)); err != nil {
+		logger.Warn("Failed to collect active users metric", map[string]interface{}{"error": err.Error()})
+	} else {
+		mc.metrics.SetActiveUsers(float64(active))
+	}
+
+	if counts, err := mc.taskRepo.CountByStatus(ctx); err != nil {
+		logger.Warn("Failed to collect tasks metric", map[string]interface{}{"error": err.Error()})
+	} else {
+		for status, count := range counts {
+			mc.metrics.SetTasksCount(status, float64(count))
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/mocks"
+)
+
+func TestMetricsCollector_Collect(t *testing.T) {
+	var since time.Time
+	taskCounted := false
+	userRepo := &mocks.MockUserRepository{
+		CountActiveSinceFn: func(ctx context.Context, s time.Time) (int, error) {
+			since = s
+			return 0, errors.NewDatabaseError()
+		},
+	}
+	taskRepo := &mocks.MockTaskRepository{
+		CountByStatusFn: func(ctx context.Context) (map[string]int, error) {
+			taskCounted = true
+			return map[string]int{"todo": 3, "done": 5}, nil
+		},
+	}
+
+	mc := &MetricsCollector{userRepo: userRepo, taskRepo: taskRepo}
+	mc.collect(context.Background())
+
+	if ago := time.Since(since); ago < activeUserWindow || ago > activeUserWindow+time.Minute {
+		t.Errorf("expected active users window of 24h, got %v", ago)
+	}
+	if !taskCounted {
+		t.Error("expected tasks to be counted even when the user count fails")
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 
+	"github.com/clementhaon/sandbox-api-go/events"
+	"github.com/clementhaon/sandbox-api-go/logger"
 	"github.com/clementhaon/sandbox-api-go/models"
 	"github.com/clementhaon/sandbox-api-go/repository"
 	"github.com/clementhaon/sandbox-api-go/websocket"
@@ -12,7 +14,9 @@ import (
 type NotificationService interface {
 	List(ctx context.Context, userID int) ([]models.Notification, error)
 	MarkRead(ctx context.Context, userID int, notificationIDs []int) (int, error)
+	MarkOneRead(ctx context.Context, userID int, id int) error
 	MarkAllRead(ctx context.Context, userID int) (int64, error)
+	UnreadCount(ctx context.Context, userID int) (int, error)
 	Delete(ctx context.Context, userID int, id int) error
 	Create(ctx context.Context, userID int, notifType, title, message string, data models.NotificationData) error
 }
@@ -37,10 +41,18 @@ func (s *notificationService) MarkRead(ctx context.Context, userID int, notifica
 	return len(notificationIDs), nil
 }
 
+func (s *notificationService) MarkOneRead(ctx context.Context, userID int, id int) error {
+	return s.notifRepo.MarkOneRead(ctx, userID, id)
+}
+
 func (s *notificationService) MarkAllRead(ctx context.Context, userID int) (int64, error) {
 	return s.notifRepo.MarkAllRead(ctx, userID)
 }
 
+func (s *notificationService) UnreadCount(ctx context.Context, userID int) (int, error) {
+	return s.notifRepo.CountUnread(ctx, userID)
+}
+
 func (s *notificationService) Delete(ctx context.Context, userID int, id int) error {
 	return s.notifRepo.Delete(ctx, userID, id)
 }
@@ -56,16 +68,50 @@ func (s *notificationService) Create(ctx context.Context, userID int, notifType,
 	}
 
 	if s.wsManager != nil {
-		s.wsManager.SendToUser(userID, &websocket.Message{
-			Type: "notification",
-			Payload: map[string]interface{}{
-				"type":    notifType,
-				"title":   title,
-				"message": message,
-				"data":    data,
-			},
-		})
+		payload := map[string]interface{}{
+			"type":    notifType,
+			"title":   title,
+			"message": message,
+			"data":    data,
+		}
+		// Lets clients update their badge without another request
+		if unread, err := s.notifRepo.CountUnread(ctx, userID); err == nil {
+			payload["unreadCount"] = unread
+		}
+		s.wsManager.SendToUser(userID, &websocket.Message{Type: "notification", Payload: payload})
 	}
 
 	return nil
 }
+
+// NotifyTaskAssigned returns an events handler sending a task_assigned
+// notification to the new assignee of a task, unless they assigned it to
+// themselves.
+func NotifyTaskAssigned(notifSvc NotificationService) events.Handler {
+	return func(ctx context.Context, e events.Event) {
+		if e.Task.AssigneeID == nil || *e.Task.AssigneeID == e.ActorID {
+			return
+		}
+		data := models.NotificationData{TaskID: e.Task.ID, TaskTitle: e.Task.Title, UserID: e.ActorID}
+		if err := notifSvc.Create(ctx, *e.Task.AssigneeID, models.NotifTaskAssigned, "Task assigned to you", e.Task.Title, data); err != nil {
+			logger.ErrorContext(ctx, "Error sending task assigned notification", err)
+		}
+	}
+}
+
+// NotifyTaskCompleted returns an events handler sending a task_completed
+// notification to the watchers of a completed task, except to the user who
+// completed it.
+func NotifyTaskCompleted(notifSvc NotificationService) events.Handler {
+	return func(ctx context.Context, e events.Event) {
+		data := models.NotificationData{TaskID: e.Task.ID, TaskTitle: e.Task.Title, UserID: e.ActorID}
+		for _, recipient := range e.Watchers() {
+			if recipient == e.ActorID {
+				continue
+			}
+			if err := notifSvc.Create(ctx, recipient, models.NotifTaskCompleted, "Task completed", e.Task.Title, data); err != nil {
+				logger.ErrorContext(ctx, "Error sending task completed notification", err)
+			}
+		}
+	}
+}
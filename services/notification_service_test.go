@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/clementhaon/sandbox-api-go/events"
 	"github.com/clementhaon/sandbox-api-go/mocks"
 	"github.com/clementhaon/sandbox-api-go/models"
 )
@@ -245,3 +246,51 @@ func TestNotificationService_Create(t *testing.T) {
 		})
 	}
 }
+
+func TestNotifyTaskCompleted(t *testing.T) {
+	assignee := 7
+	var notified []int
+	notifSvc := &mocks.MockNotificationService{
+		CreateFn: func(ctx context.Context, userID int, notifType, title, message string, data models.NotificationData) error {
+			if notifType != models.NotifTaskCompleted || data.TaskID != 1 || data.UserID != 7 {
+				t.Errorf("unexpected notification %s %+v", notifType, data)
+			}
+			notified = append(notified, userID)
+			return nil
+		},
+	}
+
+	// The assignee completes the task: only the owner hears about it
+	NotifyTaskCompleted(notifSvc)(context.Background(), events.Event{
+		Type:    events.TaskCompleted,
+		ActorID: 7,
+		Task:    models.Task{ID: 1, Title: "Ship it", UserID: 3, AssigneeID: &assignee},
+	})
+	if len(notified) != 1 || notified[0] != 3 {
+		t.Errorf("expected a notification to user 3 only, got %v", notified)
+	}
+}
+
+func TestNotifyTaskAssigned(t *testing.T) {
+	assignee := 7
+	var notified []int
+	notifSvc := &mocks.MockNotificationService{
+		CreateFn: func(ctx context.Context, userID int, notifType, title, message string, data models.NotificationData) error {
+			if notifType != models.NotifTaskAssigned || data.TaskID != 1 {
+				t.Errorf("unexpected notification %s %+v", notifType, data)
+			}
+			notified = append(notified, userID)
+			return nil
+		},
+	}
+	notify := NotifyTaskAssigned(notifSvc)
+	task := models.Task{ID: 1, Title: "Ship it", UserID: 3, AssigneeID: &assignee}
+
+	notify(context.Background(), events.Event{Type: events.TaskAssigned, ActorID: 3, Task: task})
+	// Taking a task for oneself notifies nobody
+	notify(context.Background(), events.Event{Type: events.TaskAssigned, ActorID: 7, Task: task})
+
+	if len(notified) != 1 || notified[0] != 7 {
+		t.Errorf("expected a single notification to user 7, got %v", notified)
+	}
+}
@@ -0,0 +1,441 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/kvstore"
+	"github.com/clementhaon/sandbox-api-go/logger"
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/oidc"
+	"github.com/clementhaon/sandbox-api-go/repository"
+	"github.com/clementhaon/sandbox-api-go/tenant"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Lifetimes of what the OpenID Connect provider issues
+const (
+	OIDCCodeTTL  = 2 * time.Minute
+	OIDCTokenTTL = time.Hour
+)
+
+// oidcScopes are the scopes the provider knows; others are ignored.
+var oidcScopes = []string{models.ScopeOpenID, models.ScopeProfile, models.ScopeEmail}
+
+// OIDCConfig configures the OpenID Connect provider.
+type OIDCConfig struct {
+	// Issuer is the public base URL of the API, e.g. https://api.example.com
+	Issuer string
+	// LoginURL is the page where users log in and approve a client; it
+	// receives the parameters of the authorization request
+	LoginURL string
+	Keys     *oidc.KeySet
+	Clients  oidc.Clients
+}
+
+// OIDCService lets other applications log users in with their sandbox
+// account, using the authorization code flow of OpenID Connect.
+type OIDCService interface {
+	Discovery() models.OIDCDiscovery
+	JWKS() oidc.JWKS
+	// Start checks an authorization request from a client and returns where
+	// to send the browser: the login page, or back to the client with an
+	// OAuth error. An unknown client or redirect URI is a BadRequest error,
+	// since the browser must not be sent to an unverified address.
+	Start(req models.AuthorizeRequest) (string, error)
+	// Authorize grants an authorization request for a logged-in user and
+	// returns the redirect URI of the client with a code, or with an OAuth
+	// error. Errors are as for Start.
+	Authorize(ctx context.Context, userID int, req models.AuthorizeRequest) (string, error)
+	// Exchange trades an authorization code for an ID token and an access
+	// token. Protocol failures are *oidc.Error.
+	Exchange(ctx context.Context, req models.TokenRequest) (models.TokenResponse, error)
+	// UserInfo returns the claims an access token grants. Protocol failures
+	// are *oidc.Error.
+	UserInfo(ctx context.Context, accessToken string) (models.UserInfo, error)
+}
+
+type oidcService struct {
+	userRepo repository.UserRepository
+	codes    kvstore.Store
+	cfg      OIDCConfig
+	auditSvc AuditService
+}
+
+// NewOIDCService creates the provider; authorization codes are kept in codes
+// until used or expired.
+func NewOIDCService(userRepo repository.UserRepository, codes kvstore.Store, cfg OIDCConfig, auditSvc AuditService) OIDCService {
+	cfg.Issuer = strings.TrimSuffix(cfg.Issuer, "/")
+	return &oidcService{userRepo: userRepo, codes: codes, cfg: cfg, auditSvc: auditSvc}
+}
+
+// authCode is what an authorization code stands for.
+type authCode struct {
+	ClientID      string `json:"clientId"`
+	RedirectURI   string `json:"redirectUri"`
+	UserID        int    `json:"userId"`
+	TenantID      int    `json:"tenantId"`
+	Scope         string `json:"scope"`
+	Nonce         string `json:"nonce,omitempty"`
+	CodeChallenge string `json:"codeChallenge,omitempty"`
+	AuthTime      int64  `json:"authTime"`
+}
+
+func (s *oidcService) Discovery() models.OIDCDiscovery {
+	return models.OIDCDiscovery{
+		Issuer:                            s.cfg.Issuer,
+		AuthorizationEndpoint:             s.cfg.Issuer + "/oauth/authorize",
+		TokenEndpoint:                     s.cfg.Issuer + "/oauth/token",
+		UserinfoEndpoint:                  s.cfg.Issuer + "/oauth/userinfo",
+		JWKSURI:                           s.cfg.Issuer + "/.well-known/jwks.json",
+		ScopesSupported:                   oidcScopes,
+		ResponseTypesSupported:            []string{"code"},
+		GrantTypesSupported:               []string{"authorization_code"},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  []string{"RS256"},
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		CodeChallengeMethodsSupported:     []string{"S256"},
+		ClaimsSupported: []string{
+			"sub", "iss", "aud", "exp", "iat", "auth_time", "nonce",
+			"preferred_username", "name", "given_name", "family_name", "picture", "email",
+		},
+	}
+}
+
+func (s *oidcService) JWKS() oidc.JWKS {
+	return s.cfg.Keys.JWKS()
+}
+
+func (s *oidcService) Start(req models.AuthorizeRequest) (string, error) {
+	_, protocolErr, err := s.checkAuthorize(&req)
+	if err != nil {
+		return "", err
+	}
+	if protocolErr != nil {
+		return redirectWith(req.RedirectURI, errorParams(protocolErr, req.State)), nil
+	}
+
+	params := url.Values{}
+	for name, value := range map[string]string{
+		"response_type":         req.ResponseType,
+		"client_id":             req.ClientID,
+		"redirect_uri":          req.RedirectURI,
+		"scope":                 req.Scope,
+		"state":                 req.State,
+		"nonce":                 req.Nonce,
+		"code_challenge":        req.CodeChallenge,
+		"code_challenge_method": req.CodeChallengeMethod,
+	} {
+		if value != "" {
+			params.Set(name, value)
+		}
+	}
+	return redirectWith(s.cfg.LoginURL, params), nil
+}
+
+func (s *oidcService) Authorize(ctx context.Context, userID int, req models.AuthorizeRequest) (string, error) {
+	client, protocolErr, err := s.checkAuthorize(&req)
+	if err != nil {
+		return "", err
+	}
+	if protocolErr != nil {
+		return redirectWith(req.RedirectURI, errorParams(protocolErr, req.State)), nil
+	}
+
+	code, err := randomToken()
+	if err != nil {
+		return "", errors.NewInternalError().WithCause(err)
+	}
+	data, err := json.Marshal(authCode{
+		ClientID:      client.ID,
+		RedirectURI:   req.RedirectURI,
+		UserID:        userID,
+		TenantID:      tenant.FromContext(ctx),
+		Scope:         req.Scope,
+		Nonce:         req.Nonce,
+		CodeChallenge: req.CodeChallenge,
+		AuthTime:      time.Now().Unix(),
+	})
+	if err != nil {
+		return "", errors.NewInternalError().WithCause(err)
+	}
+	if err := s.codes.Set(ctx, codeKey(code), data, OIDCCodeTTL); err != nil {
+		return "", errors.NewServiceUnavailableError().WithCause(err)
+	}
+
+	logger.InfoContext(ctx, "OAuth client authorized", map[string]interface{}{
+		"client_id": client.ID,
+		"scope":     req.Scope,
+	})
+	s.auditSvc.Record(ctx, models.AuditEntry{
+		Action:     models.AuditActionOAuthAuthorize,
+		TargetType: "user",
+		TargetID:   &userID,
+		Metadata:   map[string]interface{}{"clientId": client.ID, "scope": req.Scope},
+	})
+
+	params := url.Values{"code": {code}}
+	if req.State != "" {
+		params.Set("state", req.State)
+	}
+	return redirectWith(req.RedirectURI, params), nil
+}
+
+// checkAuthorize validates req and narrows its scope to the known scopes.
+// An unknown client or redirect URI is returned as err, other problems as
+// an OAuth error for the client.
+func (s *oidcService) checkAuthorize(req *models.AuthorizeRequest) (oidc.Client, *oidc.Error, error) {
+	client, ok := s.cfg.Clients.Lookup(req.ClientID)
+	if !ok {
+		return oidc.Client{}, nil, errors.NewBadRequestError("Unknown OAuth client")
+	}
+	if !client.AllowsRedirect(req.RedirectURI) {
+		return oidc.Client{}, nil, errors.NewBadRequestError("Redirect URI not registered for this client")
+	}
+
+	if req.ResponseType != "code" {
+		return client, oidc.NewError(oidc.ErrUnsupportedResponseType, "only the code response type is supported"), nil
+	}
+	scopes := parseScopes(req.Scope)
+	if !slices.Contains(scopes, models.ScopeOpenID) {
+		return client, oidc.NewError(oidc.ErrInvalidScope, "the openid scope is required"), nil
+	}
+	req.Scope = strings.Join(scopes, " ")
+
+	switch {
+	case req.CodeChallenge == "" && client.Public():
+		return client, oidc.NewError(oidc.ErrInvalidRequest, "public clients must use PKCE"), nil
+	case req.CodeChallenge != "" && req.CodeChallengeMethod != "S256":
+		return client, oidc.NewError(oidc.ErrInvalidRequest, "code_challenge_method must be S256"), nil
+	}
+	return client, nil, nil
+}
+
+func (s *oidcService) Exchange(ctx context.Context, req models.TokenRequest) (models.TokenResponse, error) {
+	if req.GrantType != "authorization_code" {
+		return models.TokenResponse{}, oidc.NewError(oidc.ErrUnsupportedGrantType, "")
+	}
+	client, ok := s.cfg.Clients.Authenticate(req.ClientID, req.ClientSecret)
+	if !ok {
+		logger.WarnContext(ctx, "OAuth client authentication failed", map[string]interface{}{
+			"client_id": req.ClientID,
+		})
+		return models.TokenResponse{}, oidc.NewError(oidc.ErrInvalidClient, "")
+	}
+	if req.Code == "" {
+		return models.TokenResponse{}, oidc.NewError(oidc.ErrInvalidRequest, "code is required")
+	}
+
+	// A code is used once: it is taken out of the store before tokens are
+	// issued, so of concurrent redemptions only one gets it
+	data, found, err := s.codes.Take(ctx, codeKey(req.Code))
+	if err != nil {
+		return models.TokenResponse{}, errors.NewServiceUnavailableError().WithCause(err)
+	}
+	if !found {
+		return models.TokenResponse{}, oidc.NewError(oidc.ErrInvalidGrant, "invalid or expired code")
+	}
+	var grant authCode
+	if err := json.Unmarshal(data, &grant); err != nil {
+		return models.TokenResponse{}, errors.NewInternalError().WithCause(err)
+	}
+
+	if grant.ClientID != client.ID || grant.RedirectURI != req.RedirectURI {
+		return models.TokenResponse{}, oidc.NewError(oidc.ErrInvalidGrant, "code was issued to another client or redirect URI")
+	}
+	if grant.CodeChallenge != "" && pkceChallenge(req.CodeVerifier) != grant.CodeChallenge {
+		return models.TokenResponse{}, oidc.NewError(oidc.ErrInvalidGrant, "code_verifier does not match")
+	}
+
+	user, err := s.activeUser(tenant.WithID(ctx, grant.TenantID), grant.UserID)
+	if err != nil {
+		return models.TokenResponse{}, err
+	}
+	if user == nil {
+		return models.TokenResponse{}, oidc.NewError(oidc.ErrInvalidGrant, "user no longer active")
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(OIDCTokenTTL)
+	subject := strconv.Itoa(user.ID)
+
+	accessToken, err := s.cfg.Keys.SignAccessToken(jwt.MapClaims{
+		"iss":       s.cfg.Issuer,
+		"sub":       subject,
+		"aud":       s.cfg.Issuer,
+		"client_id": client.ID,
+		"scope":     grant.Scope,
+		"tid":       grant.TenantID,
+		"iat":       now.Unix(),
+		"exp":       expiresAt.Unix(),
+	})
+	if err != nil {
+		return models.TokenResponse{}, errors.NewInternalError().WithCause(err)
+	}
+
+	idClaims := jwt.MapClaims{
+		"iss":       s.cfg.Issuer,
+		"sub":       subject,
+		"aud":       client.ID,
+		"iat":       now.Unix(),
+		"exp":       expiresAt.Unix(),
+		"auth_time": grant.AuthTime,
+	}
+	if grant.Nonce != "" {
+		idClaims["nonce"] = grant.Nonce
+	}
+	addUserInfoClaims(idClaims, userInfo(*user, parseScopes(grant.Scope)))
+	idToken, err := s.cfg.Keys.SignIDToken(idClaims)
+	if err != nil {
+		return models.TokenResponse{}, errors.NewInternalError().WithCause(err)
+	}
+
+	logger.InfoContext(ctx, "OAuth tokens issued", map[string]interface{}{
+		"client_id": client.ID,
+		"user_id":   user.ID,
+	})
+	return models.TokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(OIDCTokenTTL.Seconds()),
+		IDToken:     idToken,
+		Scope:       grant.Scope,
+	}, nil
+}
+
+func (s *oidcService) UserInfo(ctx context.Context, accessToken string) (models.UserInfo, error) {
+	claims, err := s.cfg.Keys.ParseAccessToken(accessToken, s.cfg.Issuer)
+	if err != nil {
+		return models.UserInfo{}, oidc.NewError(oidc.ErrInvalidToken, "")
+	}
+	subject, _ := claims["sub"].(string)
+	userID, err := strconv.Atoi(subject)
+	if err != nil {
+		return models.UserInfo{}, oidc.NewError(oidc.ErrInvalidToken, "")
+	}
+	tenantID, _ := claims["tid"].(float64)
+	scope, _ := claims["scope"].(string)
+
+	user, err := s.activeUser(tenant.WithID(ctx, int(tenantID)), userID)
+	if err != nil {
+		return models.UserInfo{}, err
+	}
+	if user == nil {
+		return models.UserInfo{}, oidc.NewError(oidc.ErrInvalidToken, "user no longer active")
+	}
+	return userInfo(*user, parseScopes(scope)), nil
+}
+
+// activeUser returns the user, or nil if it was deleted or deactivated.
+func (s *oidcService) activeUser(ctx context.Context, id int) (*models.User, error) {
+	user, err := s.userRepo.GetByID(ctx, id)
+	if errors.Is(err, errors.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !user.IsActive {
+		return nil, nil
+	}
+	return &user, nil
+}
+
+// userInfo releases the claims of the granted scopes.
+func userInfo(user models.User, scopes []string) models.UserInfo {
+	info := models.UserInfo{Subject: strconv.Itoa(user.ID)}
+	if slices.Contains(scopes, models.ScopeProfile) {
+		info.PreferredUsername = user.Username
+		info.GivenName = user.FirstName.String
+		info.FamilyName = user.LastName.String
+		info.Name = strings.TrimSpace(info.GivenName + " " + info.FamilyName)
+		info.Picture = user.AvatarURL.String
+	}
+	if slices.Contains(scopes, models.ScopeEmail) {
+		info.Email = user.Email
+	}
+	return info
+}
+
+func addUserInfoClaims(claims jwt.MapClaims, info models.UserInfo) {
+	for name, value := range map[string]string{
+		"preferred_username": info.PreferredUsername,
+		"name":               info.Name,
+		"given_name":         info.GivenName,
+		"family_name":        info.FamilyName,
+		"picture":            info.Picture,
+		"email":              info.Email,
+	} {
+		if value != "" {
+			claims[name] = value
+		}
+	}
+}
+
+// parseScopes returns the known scopes of a space-separated list, once each.
+func parseScopes(scope string) []string {
+	var scopes []string
+	for _, s := range strings.Fields(scope) {
+		if slices.Contains(oidcScopes, s) && !slices.Contains(scopes, s) {
+			scopes = append(scopes, s)
+		}
+	}
+	return scopes
+}
+
+func errorParams(protocolErr *oidc.Error, state string) url.Values {
+	params := url.Values{"error": {protocolErr.Code}}
+	if protocolErr.Description != "" {
+		params.Set("error_description", protocolErr.Description)
+	}
+	if state != "" {
+		params.Set("state", state)
+	}
+	return params
+}
+
+// redirectWith adds params to the query of target.
+func redirectWith(target string, params url.Values) string {
+	u, err := url.Parse(target)
+	if err != nil {
+		return target
+	}
+	query := u.Query()
+	for name, values := range params {
+		query[name] = values
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// pkceChallenge is the S256 code challenge of verifier (RFC 7636).
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// codeKey names an authorization code by its hash, so the store never holds
+// usable codes.
+func codeKey(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return "oidc_code:" + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,323 @@
+package services
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/kvstore"
+	"github.com/clementhaon/sandbox-api-go/mocks"
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/oidc"
+	"github.com/clementhaon/sandbox-api-go/tenant"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testIssuer = "https://sandbox.test"
+
+func newTestOIDCService(t *testing.T, userRepo *mocks.MockUserRepository) (OIDCService, *oidc.KeySet) {
+	t.Helper()
+	codes := kvstore.NewMemoryStore()
+	t.Cleanup(codes.Stop)
+	return newTestOIDCServiceOn(t, userRepo, codes)
+}
+
+// newTestOIDCServiceOn is newTestOIDCService keeping codes in codes.
+func newTestOIDCServiceOn(t *testing.T, userRepo *mocks.MockUserRepository, codes kvstore.Store) (OIDCService, *oidc.KeySet) {
+	t.Helper()
+	keys, err := oidc.GenerateKeySet()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	clients, err := oidc.NewClients(
+		oidc.Client{ID: "wiki", Secret: "s3cret", RedirectURIs: []string{"https://wiki.test/callback"}},
+		oidc.Client{ID: "spa", RedirectURIs: []string{"https://spa.test/callback"}},
+	)
+	if err != nil {
+		t.Fatalf("failed to register clients: %v", err)
+	}
+
+	svc := NewOIDCService(userRepo, codes, OIDCConfig{
+		Issuer:   testIssuer + "/",
+		LoginURL: "https://app.sandbox.test/oauth/consent",
+		Keys:     keys,
+		Clients:  clients,
+	}, &mocks.MockAuditService{})
+	return svc, keys
+}
+
+func oidcTestUser() *mocks.MockUserRepository {
+	return &mocks.MockUserRepository{
+		GetByIDFn: func(ctx context.Context, id int) (models.User, error) {
+			if id != 7 || tenant.FromContext(ctx) != 3 {
+				return models.User{}, errors.NewNotFoundError("User")
+			}
+			return models.User{
+				ID: 7, Username: "jane", Email: "jane@example.com", IsActive: true,
+				FirstName: models.NewNullString("Jane"), LastName: models.NewNullString("Doe"),
+			}, nil
+		},
+	}
+}
+
+// authorizeCode runs the authorization step for user 7 of tenant 3 and
+// returns the code sent to the client.
+func authorizeCode(t *testing.T, svc OIDCService, req models.AuthorizeRequest) string {
+	t.Helper()
+	target, err := svc.Authorize(tenant.WithID(context.Background(), 3), 7, req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	u, _ := url.Parse(target)
+	if u.Query().Get("error") != "" {
+		t.Fatalf("unexpected error redirect %s", target)
+	}
+	if u.Query().Get("state") != req.State {
+		t.Errorf("state = %q, want %q", u.Query().Get("state"), req.State)
+	}
+	return u.Query().Get("code")
+}
+
+func wikiRequest() models.AuthorizeRequest {
+	return models.AuthorizeRequest{
+		ResponseType: "code",
+		ClientID:     "wiki",
+		RedirectURI:  "https://wiki.test/callback",
+		Scope:        "openid profile email offline_access",
+		State:        "xyz",
+		Nonce:        "n-0S6",
+	}
+}
+
+func TestOIDCService_Start(t *testing.T) {
+	svc, _ := newTestOIDCService(t, oidcTestUser())
+
+	t.Run("sends the browser to the login page", func(t *testing.T) {
+		target, err := svc.Start(wikiRequest())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		u, _ := url.Parse(target)
+		if u.Host != "app.sandbox.test" || u.Query().Get("client_id") != "wiki" || u.Query().Get("nonce") != "n-0S6" {
+			t.Errorf("unexpected login redirect %s", target)
+		}
+		if u.Query().Get("scope") != "openid profile email" {
+			t.Errorf("scope = %q, want unknown scopes dropped", u.Query().Get("scope"))
+		}
+	})
+
+	t.Run("refuses unknown clients and redirect URIs", func(t *testing.T) {
+		req := wikiRequest()
+		req.ClientID = "unknown"
+		if _, err := svc.Start(req); !errors.Is(err, errors.ErrValidationFailed) {
+			t.Errorf("expected a bad request for an unknown client, got %v", err)
+		}
+		req = wikiRequest()
+		req.RedirectURI = "https://evil.test/callback"
+		if _, err := svc.Start(req); !errors.Is(err, errors.ErrValidationFailed) {
+			t.Errorf("expected a bad request for an unregistered redirect URI, got %v", err)
+		}
+	})
+
+	t.Run("returns protocol errors to the client", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			change func(*models.AuthorizeRequest)
+			want   string
+		}{
+			{"token response type", func(r *models.AuthorizeRequest) { r.ResponseType = "token" }, oidc.ErrUnsupportedResponseType},
+			{"missing openid scope", func(r *models.AuthorizeRequest) { r.Scope = "profile" }, oidc.ErrInvalidScope},
+			{"plain PKCE", func(r *models.AuthorizeRequest) { r.CodeChallenge, r.CodeChallengeMethod = "abc", "plain" }, oidc.ErrInvalidRequest},
+			{"public client without PKCE", func(r *models.AuthorizeRequest) {
+				r.ClientID, r.RedirectURI = "spa", "https://spa.test/callback"
+			}, oidc.ErrInvalidRequest},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				req := wikiRequest()
+				tt.change(&req)
+				target, err := svc.Start(req)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				u, _ := url.Parse(target)
+				if !strings.HasPrefix(target, req.RedirectURI) || u.Query().Get("error") != tt.want || u.Query().Get("state") != "xyz" {
+					t.Errorf("unexpected redirect %s", target)
+				}
+			})
+		}
+	})
+}
+
+func TestOIDCService_Exchange(t *testing.T) {
+	svc, keys := newTestOIDCService(t, oidcTestUser())
+	ctx := context.Background()
+
+	code := authorizeCode(t, svc, wikiRequest())
+	tokenReq := models.TokenRequest{
+		GrantType:    "authorization_code",
+		Code:         code,
+		RedirectURI:  "https://wiki.test/callback",
+		ClientID:     "wiki",
+		ClientSecret: "s3cret",
+	}
+
+	tokens, err := svc.Exchange(ctx, tokenReq)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tokens.TokenType != "Bearer" || tokens.Scope != "openid profile email" || tokens.ExpiresIn != int(OIDCTokenTTL.Seconds()) {
+		t.Errorf("unexpected token response %+v", tokens)
+	}
+
+	idClaims := jwt.MapClaims{}
+	if _, err := jwt.ParseWithClaims(tokens.IDToken, idClaims, func(*jwt.Token) (interface{}, error) {
+		return keys.PublicKey(), nil
+	}); err != nil {
+		t.Fatalf("invalid ID token: %v", err)
+	}
+	if idClaims["iss"] != testIssuer || idClaims["aud"] != "wiki" || idClaims["sub"] != "7" ||
+		idClaims["nonce"] != "n-0S6" || idClaims["email"] != "jane@example.com" || idClaims["name"] != "Jane Doe" {
+		t.Errorf("unexpected ID token claims %v", idClaims)
+	}
+
+	t.Run("codes are single use", func(t *testing.T) {
+		_, err := svc.Exchange(ctx, tokenReq)
+		assertOAuthError(t, err, oidc.ErrInvalidGrant)
+	})
+
+	t.Run("refuses a wrong client secret", func(t *testing.T) {
+		req := tokenReq
+		req.Code = authorizeCode(t, svc, wikiRequest())
+		req.ClientSecret = "wrong"
+		_, err := svc.Exchange(ctx, req)
+		assertOAuthError(t, err, oidc.ErrInvalidClient)
+	})
+
+	t.Run("refuses another redirect URI", func(t *testing.T) {
+		req := tokenReq
+		req.Code = authorizeCode(t, svc, wikiRequest())
+		req.RedirectURI = "https://wiki.test/other"
+		_, err := svc.Exchange(ctx, req)
+		assertOAuthError(t, err, oidc.ErrInvalidGrant)
+	})
+
+	t.Run("refuses other grant types", func(t *testing.T) {
+		req := tokenReq
+		req.GrantType = "password"
+		_, err := svc.Exchange(ctx, req)
+		assertOAuthError(t, err, oidc.ErrUnsupportedGrantType)
+	})
+
+	t.Run("checks the PKCE verifier", func(t *testing.T) {
+		verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+		authReq := models.AuthorizeRequest{
+			ResponseType: "code", ClientID: "spa", RedirectURI: "https://spa.test/callback", Scope: "openid",
+			CodeChallenge: "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM", CodeChallengeMethod: "S256",
+		}
+		req := models.TokenRequest{GrantType: "authorization_code", RedirectURI: "https://spa.test/callback", ClientID: "spa"}
+
+		req.Code, req.CodeVerifier = authorizeCode(t, svc, authReq), "wrong"
+		_, err := svc.Exchange(ctx, req)
+		assertOAuthError(t, err, oidc.ErrInvalidGrant)
+
+		req.Code, req.CodeVerifier = authorizeCode(t, svc, authReq), verifier
+		if _, err := svc.Exchange(ctx, req); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
+
+// slowStore pauses after reading, so concurrent readers of a key all read
+// it before any of them goes on to remove it.
+type slowStore struct{ kvstore.Store }
+
+func (s slowStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	value, found, err := s.Store.Get(ctx, key)
+	time.Sleep(20 * time.Millisecond)
+	return value, found, err
+}
+
+func (s slowStore) Take(ctx context.Context, key string) ([]byte, bool, error) {
+	value, found, err := s.Store.Take(ctx, key)
+	time.Sleep(20 * time.Millisecond)
+	return value, found, err
+}
+
+func TestOIDCService_Exchange_ConcurrentRedemptions(t *testing.T) {
+	codes := kvstore.NewMemoryStore()
+	t.Cleanup(codes.Stop)
+	svc, _ := newTestOIDCServiceOn(t, oidcTestUser(), slowStore{codes})
+
+	req := models.TokenRequest{
+		GrantType:    "authorization_code",
+		Code:         authorizeCode(t, svc, wikiRequest()),
+		RedirectURI:  "https://wiki.test/callback",
+		ClientID:     "wiki",
+		ClientSecret: "s3cret",
+	}
+
+	const redemptions = 10
+	errs := make(chan error, redemptions)
+	var wg sync.WaitGroup
+	for range redemptions {
+		wg.Go(func() {
+			_, err := svc.Exchange(context.Background(), req)
+			errs <- err
+		})
+	}
+	wg.Wait()
+	close(errs)
+
+	succeeded := 0
+	for err := range errs {
+		if err == nil {
+			succeeded++
+			continue
+		}
+		assertOAuthError(t, err, oidc.ErrInvalidGrant)
+	}
+	if succeeded != 1 {
+		t.Errorf("expected exactly one redemption to succeed, got %d", succeeded)
+	}
+}
+
+func TestOIDCService_UserInfo(t *testing.T) {
+	svc, _ := newTestOIDCService(t, oidcTestUser())
+	ctx := context.Background()
+
+	req := wikiRequest()
+	req.Scope = "openid email"
+	tokens, err := svc.Exchange(ctx, models.TokenRequest{
+		GrantType: "authorization_code", Code: authorizeCode(t, svc, req),
+		RedirectURI: req.RedirectURI, ClientID: "wiki", ClientSecret: "s3cret",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	info, err := svc.UserInfo(ctx, tokens.AccessToken)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := models.UserInfo{Subject: "7", Email: "jane@example.com"}
+	if info != want {
+		t.Errorf("UserInfo = %+v, want %+v (profile claims need the profile scope)", info, want)
+	}
+
+	_, err = svc.UserInfo(ctx, tokens.IDToken)
+	assertOAuthError(t, err, oidc.ErrInvalidToken)
+}
+
+func assertOAuthError(t *testing.T, err error, code string) {
+	t.Helper()
+	var protocolErr *oidc.Error
+	if !errors.As(err, &protocolErr) || protocolErr.Code != code {
+		t.Fatalf("expected OAuth error %s, got %v", code, err)
+	}
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"context"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/logger"
+	"github.com/clementhaon/sandbox-api-go/metrics"
+	"github.com/clementhaon/sandbox-api-go/pwned"
+)
+
+// PasswordScreen checks new passwords against known data breaches. A nil
+// *PasswordScreen lets every password through.
+type PasswordScreen struct {
+	checker pwned.Checker
+	reject  bool
+}
+
+// NewPasswordScreen checks passwords with checker, refusing breached ones
+// when reject is set and only logging them otherwise.
+func NewPasswordScreen(checker pwned.Checker, reject bool) *PasswordScreen {
+	return &PasswordScreen{checker: checker, reject: reject}
+}
+
+// Breached tells whether password is known to be breached. When the check
+// fails, the password is taken as not breached.
+func (s *PasswordScreen) Breached(ctx context.Context, password string) bool {
+	if s == nil || password == "" {
+		return false
+	}
+	breached, err := s.checker.Breached(ctx, password)
+	if err != nil {
+		logger.WarnContext(ctx, "Breached password check failed, password accepted", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return false
+	}
+	return breached
+}
+
+// Rejects tells whether breached passwords are refused.
+func (s *PasswordScreen) Rejects() bool {
+	return s != nil && s.reject
+}
+
+// Check returns PASSWORD_BREACHED for a breached password when the screen
+// rejects them; otherwise a breached password is logged and accepted.
+func (s *PasswordScreen) Check(ctx context.Context, flow, password string) error {
+	if !s.Breached(ctx, password) {
+		return nil
+	}
+	if s.reject {
+		logger.WarnContext(ctx, "Breached password refused", map[string]interface{}{"flow": flow})
+		metrics.FromContext(ctx).RecordAuthFailure(flow, "breached_password")
+		return errors.NewPasswordBreachedError()
+	}
+	logger.WarnContext(ctx, "Breached password accepted", map[string]interface{}{"flow": flow})
+	return nil
+}
@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/mocks"
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/validation"
+)
+
+// breachedPasswords is a pwned.Checker knowing a fixed set of passwords,
+// or failing when err is set.
+type breachedPasswords struct {
+	passwords map[string]bool
+	err       error
+}
+
+func (b breachedPasswords) Breached(_ context.Context, password string) (bool, error) {
+	return b.passwords[password], b.err
+}
+
+func TestAuthService_Register_BreachedPassword(t *testing.T) {
+	checker := breachedPasswords{passwords: map[string]bool{"Password1": true}}
+	created := 0
+	userRepo := &mocks.MockUserRepository{
+		ExistsByUsernameOrEmailFn: func(ctx context.Context, username, email string) (bool, error) {
+			return false, nil
+		},
+		CreateAuthFn: func(ctx context.Context, username, email, hashedPassword string) (models.User, error) {
+			created++
+			return models.User{ID: created, Username: username}, nil
+		},
+	}
+	req := models.RegisterRequest{Username: "johndoe", Email: "john@example.com", Password: "Password1"}
+
+	svc := NewAuthService(userRepo, newJWTManager(t), &mocks.MockAuditService{}, nil, validation.DefaultPasswordPolicy(), nil, NewPasswordScreen(checker, true), nil)
+	if _, _, err := svc.Register(context.Background(), req); !errors.Is(err, errors.ErrPasswordBreached) {
+		t.Fatalf("expected PASSWORD_BREACHED, got %v", err)
+	}
+	if created != 0 {
+		t.Error("expected no account created")
+	}
+
+	// Warned about only
+	svc = NewAuthService(userRepo, newJWTManager(t), &mocks.MockAuditService{}, nil, validation.DefaultPasswordPolicy(), nil, NewPasswordScreen(checker, false), nil)
+	if _, _, err := svc.Register(context.Background(), req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// A failing check lets the password through
+	failing := breachedPasswords{err: fmt.Errorf("API down")}
+	svc = NewAuthService(userRepo, newJWTManager(t), &mocks.MockAuditService{}, nil, validation.DefaultPasswordPolicy(), nil, NewPasswordScreen(failing, true), nil)
+	if _, _, err := svc.Register(context.Background(), req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestAuthService_PasswordStrength_Breached(t *testing.T) {
+	checker := breachedPasswords{passwords: map[string]bool{"Correct-Horse-Battery-9": true}}
+	req := models.PasswordStrengthRequest{Password: "Correct-Horse-Battery-9"}
+
+	for _, reject := range []bool{true, false} {
+		svc := NewAuthService(&mocks.MockUserRepository{}, newJWTManager(t), &mocks.MockAuditService{}, nil, validation.DefaultPasswordPolicy(), nil, NewPasswordScreen(checker, reject), nil)
+		got, err := svc.PasswordStrength(context.Background(), req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !got.Breached || got.Score != 0 || got.Feedback.Warning == "" {
+			t.Errorf("reject=%v: expected a breached password scored 0 with a warning, got %+v", reject, got)
+		}
+		if got.Valid == reject {
+			t.Errorf("reject=%v: expected valid=%v, got %+v", reject, !reject, got)
+		}
+	}
+}
+
+func TestUserService_Create_BreachedPassword(t *testing.T) {
+	userRepo := &mocks.MockUserRepository{
+		ExistsByUsernameOrEmailFn: func(ctx context.Context, username, email string) (bool, error) {
+			return false, nil
+		},
+	}
+	svc := NewUserService(userRepo, &mocks.MockAuditService{}, validation.PasswordPolicy{}, nil, NewPasswordScreen(breachedPasswords{passwords: map[string]bool{"123456": true}}, true))
+
+	_, err := svc.Create(context.Background(), models.CreateUserRequest{Username: "jane", Email: "jane@example.com", Password: "123456"})
+	if !errors.Is(err, errors.ErrPasswordBreached) {
+		t.Fatalf("expected PASSWORD_BREACHED, got %v", err)
+	}
+}
+
+func TestSCIMService_Create_BreachedPassword(t *testing.T) {
+	created := false
+	userRepo := &mocks.MockUserRepository{
+		ExistsByUsernameOrEmailFn: func(ctx context.Context, username, email string) (bool, error) {
+			return false, nil
+		},
+		CreateFn: func(ctx context.Context, username, email, hashedPassword, firstName, lastName, role string) (models.User, error) {
+			created = true
+			return models.User{ID: 1, Username: username, Email: email, IsActive: true}, nil
+		},
+	}
+	svc := NewSCIMService(userRepo, &mocks.MockAuditService{}, nil, NewPasswordScreen(breachedPasswords{passwords: map[string]bool{"123456": true}}, true))
+	user := models.SCIMUser{UserName: "jane", Emails: []models.SCIMEmail{{Value: "jane@example.com"}}, Password: "123456"}
+
+	if _, err := svc.Create(context.Background(), user); !errors.Is(err, errors.ErrPasswordBreached) || created {
+		t.Fatalf("expected PASSWORD_BREACHED and no account, got %v", err)
+	}
+
+	// Accounts provisioned without a password get a random one, not screened
+	user.Password = ""
+	if _, err := svc.Create(context.Background(), user); err != nil || !created {
+		t.Errorf("expected the account created, got %v", err)
+	}
+}
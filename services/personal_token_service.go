@@ -0,0 +1,200 @@
+package services
+
+import (
+	"context"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/auth"
+	"github.com/clementhaon/sandbox-api-go/clock"
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/logger"
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/repository"
+	"github.com/clementhaon/sandbox-api-go/validation"
+)
+
+// personalTokenPrefixLength is how much of a token is kept to recognize it
+// in the list, past auth.PersonalTokenPrefix.
+const personalTokenPrefixLength = 6
+
+// PersonalTokenService manages the personal access tokens of users and
+// authenticates the requests made with them.
+type PersonalTokenService interface {
+	List(ctx context.Context, userID int) ([]models.PersonalToken, error)
+	// Create returns the new token with its secret, which is not kept
+	Create(ctx context.Context, userID int, req models.CreatePersonalTokenRequest) (models.CreatePersonalTokenResponse, error)
+	Revoke(ctx context.Context, userID, id int) error
+	// UpdateSandbox validates and stores the sandbox settings of token id
+	UpdateSandbox(ctx context.Context, userID, id int, settings models.SandboxSettings) (models.SandboxSettings, error)
+	// Authenticate returns the claims of the user of token, restricted to
+	// its scopes and with its sandbox settings. Unknown tokens, tokens
+	// expired on the sandbox clock of their user or on the real time, and
+	// those of inactive users or of another tenant than the one of ctx, are
+	// refused.
+	Authenticate(ctx context.Context, token string) (*models.Claims, error)
+}
+
+type personalTokenService struct {
+	tokenRepo repository.PersonalTokenRepository
+	userRepo  repository.UserRepository
+	auditSvc  AuditService
+	clocks    ClockService
+}
+
+// NewPersonalTokenService returns a PersonalTokenService checking the expiry
+// of tokens on the sandbox clock of their user, from clocks.
+func NewPersonalTokenService(tokenRepo repository.PersonalTokenRepository, userRepo repository.UserRepository, auditSvc AuditService, clocks ClockService) PersonalTokenService {
+	return &personalTokenService{tokenRepo: tokenRepo, userRepo: userRepo, auditSvc: auditSvc, clocks: clocks}
+}
+
+func (s *personalTokenService) List(ctx context.Context, userID int) ([]models.PersonalToken, error) {
+	return s.tokenRepo.ListByUser(ctx, userID)
+}
+
+func (s *personalTokenService) Create(ctx context.Context, userID int, req models.CreatePersonalTokenRequest) (models.CreatePersonalTokenResponse, error) {
+	req.Name = strings.TrimSpace(req.Name)
+	if err := validation.Struct(req); err != nil {
+		return models.CreatePersonalTokenResponse{}, err
+	}
+	if len(req.Scopes) == 0 {
+		return models.CreatePersonalTokenResponse{}, errors.NewMissingFieldError("scopes")
+	}
+	var scopes []string
+	for _, scope := range req.Scopes {
+		if !slices.Contains(models.ValidScopes(), scope) {
+			return models.CreatePersonalTokenResponse{}, errors.NewInvalidFormatError("scopes", strings.Join(models.ValidScopes(), ", "))
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	secret, err := randomToken()
+	if err != nil {
+		logger.ErrorContext(ctx, "Error generating personal token", err)
+		return models.CreatePersonalTokenResponse{}, errors.NewInternalError().WithCause(err)
+	}
+	token := auth.PersonalTokenPrefix + secret
+
+	created, err := s.tokenRepo.Create(ctx, models.PersonalToken{
+		UserID:    userID,
+		Name:      req.Name,
+		Prefix:    token[:len(auth.PersonalTokenPrefix)+personalTokenPrefixLength],
+		TokenHash: auth.TokenHash(token),
+		Scopes:    scopes,
+		ExpiresAt: time.Now().Add(time.Duration(req.ExpiresInDays) * 24 * time.Hour),
+	})
+	if err != nil {
+		return models.CreatePersonalTokenResponse{}, err
+	}
+
+	s.auditSvc.Record(ctx, models.AuditEntry{
+		Action:     models.AuditActionTokenCreate,
+		TargetType: "personal_token",
+		TargetID:   &created.ID,
+		Metadata: map[string]interface{}{
+			"name":      created.Name,
+			"scopes":    created.Scopes,
+			"expiresAt": created.ExpiresAt,
+		},
+	})
+	return models.CreatePersonalTokenResponse{PersonalToken: created, Token: token}, nil
+}
+
+func (s *personalTokenService) Revoke(ctx context.Context, userID, id int) error {
+	if err := s.tokenRepo.Delete(ctx, userID, id); err != nil {
+		return err
+	}
+	s.auditSvc.Record(ctx, models.AuditEntry{
+		Action:     models.AuditActionTokenRevoke,
+		TargetType: "personal_token",
+		TargetID:   &id,
+	})
+	return nil
+}
+
+func (s *personalTokenService) UpdateSandbox(ctx context.Context, userID, id int, settings models.SandboxSettings) (models.SandboxSettings, error) {
+	if err := validation.Struct(settings); err != nil {
+		return models.SandboxSettings{}, err
+	}
+	if settings.StatusCode != 0 && !isErrorStatus(settings.StatusCode) {
+		return models.SandboxSettings{}, errors.NewInvalidFormatError("statusCode", "a status from 400 to 599")
+	}
+	if settings.ErrorStatusCode != 0 && !isErrorStatus(settings.ErrorStatusCode) {
+		return models.SandboxSettings{}, errors.NewInvalidFormatError("errorStatusCode", "a status from 400 to 599")
+	}
+	if math.IsNaN(settings.ErrorRate) || settings.ErrorRate < 0 || settings.ErrorRate > 1 {
+		return models.SandboxSettings{}, errors.NewInvalidFormatError("errorRate", "a number from 0 to 1")
+	}
+
+	if err := s.tokenRepo.UpdateSandbox(ctx, userID, id, settings); err != nil {
+		return models.SandboxSettings{}, err
+	}
+	return settings, nil
+}
+
+// isErrorStatus tells whether code is a client or server error status.
+func isErrorStatus(code int) bool {
+	return code >= 400 && code <= 599
+}
+
+func (s *personalTokenService) Authenticate(ctx context.Context, token string) (*models.Claims, error) {
+	t, err := s.tokenRepo.GetByHash(ctx, auth.TokenHash(token))
+	if errors.Is(err, errors.ErrNotFound) {
+		return nil, errors.NewInvalidTokenError()
+	}
+	if err != nil {
+		return nil, err
+	}
+	// A clock moved forward expires the token early, but one moved back
+	// does not revive it
+	offset, err := s.clocks.Offset(ctx, t.UserID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if sandbox := clock.Now(clock.WithOffset(ctx, offset)); sandbox.After(now) {
+		now = sandbox
+	}
+	if !now.Before(t.ExpiresAt) {
+		return nil, errors.NewTokenExpiredError()
+	}
+
+	// The user is looked up in the tenant of the request, which the token
+	// is then only valid in
+	user, err := s.userRepo.GetByID(ctx, t.UserID)
+	if errors.Is(err, errors.ErrNotFound) {
+		return nil, errors.NewInvalidTokenError()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !user.IsActive {
+		return nil, errors.NewInvalidTokenError()
+	}
+
+	if err := s.tokenRepo.MarkUsed(ctx, t.ID); err != nil {
+		logger.WarnContext(ctx, "Could not record personal token use", map[string]interface{}{
+			"token_id": t.ID,
+			"error":    err.Error(),
+		})
+	}
+
+	return &models.Claims{
+		UserID:    user.ID,
+		TenantID:  user.TenantID,
+		Username:  user.Username,
+		Role:      user.Role,
+		FirstName: user.FirstName.String,
+		LastName:  user.LastName.String,
+		AvatarURL: user.AvatarURL.String,
+		ExpiresAt: t.ExpiresAt,
+		Scopes:    t.Scopes,
+
+		PersonalTokenID: t.ID,
+		Sandbox:         &t.Sandbox,
+	}, nil
+}
@@ -0,0 +1,176 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/auth"
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/mocks"
+	"github.com/clementhaon/sandbox-api-go/models"
+)
+
+func TestPersonalTokenService_Create(t *testing.T) {
+	var created models.PersonalToken
+	repo := &mocks.MockPersonalTokenRepository{
+		CreateFn: func(ctx context.Context, token models.PersonalToken) (models.PersonalToken, error) {
+			created = token
+			token.ID = 5
+			return token, nil
+		},
+	}
+	var recorded []models.AuditEntry
+	svc := NewPersonalTokenService(repo, &mocks.MockUserRepository{}, &mocks.MockAuditService{
+		RecordFn: func(ctx context.Context, entry models.AuditEntry) { recorded = append(recorded, entry) },
+	}, &mocks.MockClockService{})
+
+	resp, err := svc.Create(context.Background(), 3, models.CreatePersonalTokenRequest{
+		Name:          " CI deploys ",
+		Scopes:        []string{models.ScopeTasksRead, models.ScopeTasksWrite, models.ScopeTasksRead},
+		ExpiresInDays: 30,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(resp.Token, auth.PersonalTokenPrefix) || !strings.HasPrefix(resp.Token, resp.Prefix) {
+		t.Errorf("expected a %s token starting with %q, got %q", auth.PersonalTokenPrefix, resp.Prefix, resp.Token)
+	}
+	if created.TokenHash != auth.TokenHash(resp.Token) || created.UserID != 3 || created.Name != "CI deploys" {
+		t.Errorf("expected the hash of the token stored for user 3, got %+v", created)
+	}
+	if len(created.Scopes) != 2 {
+		t.Errorf("expected duplicate scopes dropped, got %v", created.Scopes)
+	}
+	if ttl := time.Until(created.ExpiresAt); ttl > 30*24*time.Hour || ttl < 30*24*time.Hour-time.Minute {
+		t.Errorf("expected the token to expire in 30 days, got %s", ttl)
+	}
+	if len(recorded) != 1 || recorded[0].Action != models.AuditActionTokenCreate {
+		t.Errorf("expected a token creation audited, got %+v", recorded)
+	}
+
+	for _, scopes := range [][]string{nil, {"admin"}} {
+		_, err := svc.Create(context.Background(), 3, models.CreatePersonalTokenRequest{Name: "bad", Scopes: scopes, ExpiresInDays: 30})
+		if err == nil {
+			t.Errorf("expected scopes %v to be refused", scopes)
+		}
+	}
+	_, err = svc.Create(context.Background(), 3, models.CreatePersonalTokenRequest{Name: "forever", Scopes: []string{models.ScopeTasksRead}})
+	if !errors.Is(err, errors.ErrValidationFailed) {
+		t.Errorf("expected a token without expiry to be refused, got %v", err)
+	}
+}
+
+func TestPersonalTokenService_Authenticate(t *testing.T) {
+	const token = "sap_secret"
+	active := models.User{ID: 3, TenantID: 1, Username: "johndoe", Role: models.RoleUser, IsActive: true}
+
+	tests := []struct {
+		name        string
+		expiresAt   time.Time
+		clockOffset time.Duration
+		user        models.User
+		userErr     error
+		wantErr     errors.ErrorCode
+	}{
+		{"valid", time.Now().Add(time.Hour), 0, active, nil, ""},
+		{"expired", time.Now().Add(-time.Hour), 0, active, nil, errors.ErrTokenExpired},
+		{"expired on the sandbox clock", time.Now().Add(time.Hour), 2 * time.Hour, active, nil, errors.ErrTokenExpired},
+		{"not revived by a clock moved back", time.Now().Add(-time.Hour), -2 * time.Hour, active, nil, errors.ErrTokenExpired},
+		{"inactive user", time.Now().Add(time.Hour), 0, models.User{ID: 3, IsActive: false}, nil, errors.ErrInvalidToken},
+		{"other tenant", time.Now().Add(time.Hour), 0, models.User{}, errors.NewNotFoundError("User"), errors.ErrInvalidToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			used := false
+			repo := &mocks.MockPersonalTokenRepository{
+				GetByHashFn: func(ctx context.Context, hash string) (models.PersonalToken, error) {
+					if hash != auth.TokenHash(token) {
+						return models.PersonalToken{}, errors.NewNotFoundError("Personal token")
+					}
+					return models.PersonalToken{ID: 5, UserID: 3, Scopes: []string{models.ScopeTasksRead}, ExpiresAt: tt.expiresAt,
+						Sandbox: models.SandboxSettings{DelayMS: 100}}, nil
+				},
+				MarkUsedFn: func(ctx context.Context, id int) error {
+					used = true
+					return nil
+				},
+			}
+			userRepo := &mocks.MockUserRepository{
+				GetByIDFn: func(ctx context.Context, id int) (models.User, error) { return tt.user, tt.userErr },
+			}
+			clocks := &mocks.MockClockService{
+				OffsetFn: func(ctx context.Context, userID int) (time.Duration, error) { return tt.clockOffset, nil },
+			}
+			svc := NewPersonalTokenService(repo, userRepo, &mocks.MockAuditService{}, clocks)
+
+			claims, err := svc.Authenticate(context.Background(), token)
+			if tt.wantErr != "" {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("expected %s, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if claims.UserID != 3 || claims.Role != models.RoleUser || !claims.HasScope(models.ScopeTasksRead) || claims.HasScope(models.ScopeTasksWrite) {
+				t.Errorf("expected the claims of user 3 restricted to tasks:read, got %+v", claims)
+			}
+			if claims.PersonalTokenID != 5 || claims.Sandbox == nil || claims.Sandbox.DelayMS != 100 {
+				t.Errorf("expected the token and its sandbox settings in the claims, got %+v", claims)
+			}
+			if !used {
+				t.Error("expected the token use to be recorded")
+			}
+		})
+	}
+
+	svc := NewPersonalTokenService(&mocks.MockPersonalTokenRepository{
+		GetByHashFn: func(ctx context.Context, hash string) (models.PersonalToken, error) {
+			return models.PersonalToken{}, errors.NewNotFoundError("Personal token")
+		},
+	}, &mocks.MockUserRepository{}, &mocks.MockAuditService{}, &mocks.MockClockService{})
+	if _, err := svc.Authenticate(context.Background(), "sap_unknown"); !errors.Is(err, errors.ErrInvalidToken) {
+		t.Errorf("expected INVALID_TOKEN for an unknown token, got %v", err)
+	}
+}
+
+func TestPersonalTokenService_UpdateSandbox(t *testing.T) {
+	var stored models.SandboxSettings
+	repo := &mocks.MockPersonalTokenRepository{
+		UpdateSandboxFn: func(ctx context.Context, userID, id int, settings models.SandboxSettings) error {
+			if userID != 3 || id != 5 {
+				return errors.NewNotFoundError("Personal token")
+			}
+			stored = settings
+			return nil
+		},
+	}
+	svc := NewPersonalTokenService(repo, &mocks.MockUserRepository{}, &mocks.MockAuditService{}, &mocks.MockClockService{})
+
+	settings := models.SandboxSettings{DelayMS: 250, ErrorRate: 0.1, ErrorStatusCode: 503}
+	if _, err := svc.UpdateSandbox(context.Background(), 3, 5, settings); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stored != settings {
+		t.Errorf("expected %+v stored, got %+v", settings, stored)
+	}
+	if _, err := svc.UpdateSandbox(context.Background(), 4, 5, settings); !errors.Is(err, errors.ErrNotFound) {
+		t.Errorf("expected NOT_FOUND for the token of another user, got %v", err)
+	}
+
+	for _, bad := range []models.SandboxSettings{
+		{DelayMS: -1},
+		{DelayMS: 60000},
+		{StatusCode: 200},
+		{StatusCode: 600},
+		{ErrorRate: 1.5},
+		{ErrorRate: 0.5, ErrorStatusCode: 302},
+	} {
+		if _, err := svc.UpdateSandbox(context.Background(), 3, 5, bad); err == nil {
+			t.Errorf("expected %+v to be refused", bad)
+		}
+	}
+}
@@ -3,23 +3,34 @@ package services
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"fmt"
 
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/i18n"
 	"github.com/clementhaon/sandbox-api-go/logger"
 	"github.com/clementhaon/sandbox-api-go/models"
 	"github.com/clementhaon/sandbox-api-go/repository"
+	"github.com/clementhaon/sandbox-api-go/sanitize"
+	"github.com/clementhaon/sandbox-api-go/validation"
 )
 
 type ProfileService interface {
 	GetProfile(ctx context.Context, userID int) (models.User, error)
 	UpdateProfile(ctx context.Context, userID int, req models.UpdateProfileRequest) (models.User, error)
+	GetPreferences(ctx context.Context, userID int) (models.Preferences, error)
+	// UpdatePreferences replaces the preferences of a user after validating
+	// them.
+	UpdatePreferences(ctx context.Context, userID int, prefs models.Preferences) (models.Preferences, error)
 }
 
 type profileService struct {
-	userRepo repository.UserRepository
+	userRepo    repository.UserRepository
+	avatarHosts []string // empty allows any host
 }
 
-func NewProfileService(userRepo repository.UserRepository) ProfileService {
-	return &profileService{userRepo: userRepo}
+func NewProfileService(userRepo repository.UserRepository, avatarHosts []string) ProfileService {
+	return &profileService{userRepo: userRepo, avatarHosts: avatarHosts}
 }
 
 func (s *profileService) GetProfile(ctx context.Context, userID int) (models.User, error) {
@@ -28,13 +39,21 @@ func (s *profileService) GetProfile(ctx context.Context, userID int) (models.Use
 		return models.User{}, err
 	}
 
-	logger.InfoContext(ctx, "User profile retrieved", map[string]interface{}{
-		"user_id": user.ID,
-	})
+	logger.FromContext(ctx).Info("User profile retrieved")
 	return user, nil
 }
 
 func (s *profileService) UpdateProfile(ctx context.Context, userID int, req models.UpdateProfileRequest) (models.User, error) {
+	sanitize.Struct(&req)
+	if err := validation.Struct(req); err != nil {
+		return models.User{}, err
+	}
+	if req.AvatarURL != nil && len(s.avatarHosts) > 0 {
+		if err := validation.NewValidator().ValidateField("avatar_url", *req.AvatarURL, validation.URLHost(s.avatarHosts...)).GetError(); err != nil {
+			return models.User{}, err
+		}
+	}
+
 	var firstName, lastName, avatarURL sql.NullString
 
 	if req.FirstName != nil {
@@ -56,8 +75,54 @@ func (s *profileService) UpdateProfile(ctx context.Context, userID int, req mode
 		return models.User{}, err
 	}
 
-	logger.InfoContext(ctx, "User profile updated successfully", map[string]interface{}{
-		"user_id": updatedUser.ID,
-	})
+	logger.FromContext(ctx).Info("User profile updated successfully")
 	return updatedUser, nil
 }
+
+func (s *profileService) GetPreferences(ctx context.Context, userID int) (models.Preferences, error) {
+	prefsJSON, err := s.userRepo.GetPreferences(ctx, userID)
+	if err != nil {
+		return models.Preferences{}, err
+	}
+
+	prefs := models.DefaultPreferences()
+	if err := json.Unmarshal(prefsJSON, &prefs); err != nil {
+		logger.ErrorContext(ctx, "Error decoding user preferences", err)
+		return models.Preferences{}, errors.NewInternalError().WithCause(err)
+	}
+	return prefs, nil
+}
+
+func (s *profileService) UpdatePreferences(ctx context.Context, userID int, prefs models.Preferences) (models.Preferences, error) {
+	if prefs.Notifications.Muted == nil {
+		prefs.Notifications.Muted = []string{}
+	}
+	if err := validatePreferences(prefs); err != nil {
+		return models.Preferences{}, err
+	}
+
+	prefsJSON, err := json.Marshal(prefs)
+	if err != nil {
+		return models.Preferences{}, errors.NewInternalError().WithCause(err)
+	}
+	if err := s.userRepo.UpdatePreferences(ctx, userID, prefsJSON); err != nil {
+		return models.Preferences{}, err
+	}
+
+	logger.FromContext(ctx).Info("User preferences updated")
+	return prefs, nil
+}
+
+// validatePreferences checks every key against the values it accepts.
+// Nested keys are reported with dotted names, like taskSort.sortBy.
+func validatePreferences(prefs models.Preferences) *errors.AppError {
+	v := validation.NewValidator().
+		ValidateField("timezone", prefs.Timezone, validation.Required(), validation.Timezone()).
+		ValidateField("locale", prefs.Locale, validation.Required(), validation.Enum(i18n.Supported()...)).
+		ValidateField("taskSort.sortBy", prefs.TaskSort.SortBy, validation.Required(), validation.Enum("order", "createdAt", "updatedAt")).
+		ValidateField("taskSort.sortOrder", prefs.TaskSort.SortOrder, validation.Required(), validation.Enum("asc", "desc"))
+	for i, notifType := range prefs.Notifications.Muted {
+		v.ValidateField(fmt.Sprintf("notifications.muted[%d]", i), notifType, validation.Enum(models.ValidNotificationTypes()...))
+	}
+	return v.GetError()
+}
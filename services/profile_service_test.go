@@ -4,8 +4,10 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strings"
 	"testing"
 
+	"github.com/clementhaon/sandbox-api-go/errors"
 	"github.com/clementhaon/sandbox-api-go/mocks"
 	"github.com/clementhaon/sandbox-api-go/models"
 )
@@ -39,7 +41,7 @@ func TestProfileService_GetProfile(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			repo := &mocks.MockUserRepository{GetByIDFn: tt.getByIDFn}
-			svc := NewProfileService(repo)
+			svc := NewProfileService(repo, nil)
 
 			user, err := svc.GetProfile(context.Background(), tt.userID)
 			if tt.wantErr {
@@ -79,7 +81,7 @@ func TestProfileService_UpdateProfile(t *testing.T) {
 				return nil
 			},
 			getByIDFn: func(ctx context.Context, id int) (models.User, error) {
-				return models.User{ID: id, Username: "alice", FirstName: sql.NullString{String: "Alice", Valid: true}}, nil
+				return models.User{ID: id, Username: "alice", FirstName: models.NewNullString("Alice")}, nil
 			},
 		},
 		{
@@ -111,7 +113,7 @@ func TestProfileService_UpdateProfile(t *testing.T) {
 				UpdateProfileFn: tt.updateProfileFn,
 				GetByIDFn:       tt.getByIDFn,
 			}
-			svc := NewProfileService(repo)
+			svc := NewProfileService(repo, nil)
 
 			user, err := svc.UpdateProfile(context.Background(), tt.userID, tt.req)
 			if tt.wantErr {
@@ -129,3 +131,118 @@ func TestProfileService_UpdateProfile(t *testing.T) {
 		})
 	}
 }
+
+func TestProfileService_UpdateProfile_AvatarURL(t *testing.T) {
+	repo := &mocks.MockUserRepository{
+		UpdateProfileFn: func(ctx context.Context, userID int, firstName, lastName, avatarURL sql.NullString) error {
+			return nil
+		},
+		GetByIDFn: func(ctx context.Context, id int) (models.User, error) {
+			return models.User{ID: id}, nil
+		},
+	}
+	svc := NewProfileService(repo, []string{"cdn.example.com", "*.gravatar.com"})
+
+	tests := []struct {
+		name      string
+		avatarURL string
+		wantKey   string
+	}{
+		{"allowed host", "https://cdn.example.com/a.png", ""},
+		{"allowed subdomain", "https://secure.gravatar.com/avatar/1", ""},
+		{"empty clears avatar", "", ""},
+		{"relative URL", "/uploads/a.png", "validation.url"},
+		{"javascript scheme", "javascript:alert(1)", "validation.url"},
+		{"too long", "https://cdn.example.com/" + strings.Repeat("a", 2048), "validation.max_length"},
+		{"host not allowed", "https://evil.example.net/a.png", "validation.url_host"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			url := tt.avatarURL
+			_, err := svc.UpdateProfile(context.Background(), 1, models.UpdateProfileRequest{AvatarURL: &url})
+			if tt.wantKey == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			appErr, ok := errors.IsAppError(err)
+			if !ok || len(appErr.Validation) != 1 || appErr.Validation[0].Key != tt.wantKey {
+				t.Fatalf("expected %s validation error, got %v", tt.wantKey, err)
+			}
+			if appErr.Validation[0].Field != "avatar_url" {
+				t.Errorf("expected field avatar_url, got %s", appErr.Validation[0].Field)
+			}
+		})
+	}
+}
+
+func TestProfileService_GetPreferences_FillsDefaults(t *testing.T) {
+	repo := &mocks.MockUserRepository{
+		GetPreferencesFn: func(ctx context.Context, userID int) ([]byte, error) {
+			return []byte(`{"timezone":"Europe/Paris","notifications":{"muted":["mention"]}}`), nil
+		},
+	}
+	svc := NewProfileService(repo, nil)
+
+	prefs, err := svc.GetPreferences(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if prefs.Timezone != "Europe/Paris" || prefs.Locale != "en" || prefs.TaskSort.SortBy != "order" {
+		t.Errorf("expected stored keys over the defaults, got %+v", prefs)
+	}
+	if !prefs.Notifications.Push || len(prefs.Notifications.Muted) != 1 {
+		t.Errorf("unexpected notification preferences %+v", prefs.Notifications)
+	}
+}
+
+func TestProfileService_UpdatePreferences(t *testing.T) {
+	var stored []byte
+	repo := &mocks.MockUserRepository{
+		UpdatePreferencesFn: func(ctx context.Context, userID int, prefsJSON []byte) error {
+			stored = prefsJSON
+			return nil
+		},
+	}
+	svc := NewProfileService(repo, nil)
+
+	tests := []struct {
+		name      string
+		change    func(p *models.Preferences)
+		wantField string
+	}{
+		{"defaults", func(p *models.Preferences) {}, ""},
+		{"valid", func(p *models.Preferences) { p.Timezone = "America/New_York"; p.Locale = "fr" }, ""},
+		{"unknown timezone", func(p *models.Preferences) { p.Timezone = "Mars/Olympus" }, "timezone"},
+		{"server timezone", func(p *models.Preferences) { p.Timezone = "Local" }, "timezone"},
+		{"unsupported locale", func(p *models.Preferences) { p.Locale = "xx" }, "locale"},
+		{"unknown sort", func(p *models.Preferences) { p.TaskSort.SortBy = "title" }, "taskSort.sortBy"},
+		{"unknown muted type", func(p *models.Preferences) { p.Notifications.Muted = []string{"spam"} }, "notifications.muted[0]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stored = nil
+			prefs := models.DefaultPreferences()
+			tt.change(&prefs)
+
+			_, err := svc.UpdatePreferences(context.Background(), 1, prefs)
+			if tt.wantField == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if stored == nil {
+					t.Error("expected the preferences to be stored")
+				}
+				return
+			}
+			appErr, ok := errors.IsAppError(err)
+			if !ok || len(appErr.Validation) != 1 || appErr.Validation[0].Field != tt.wantField {
+				t.Fatalf("expected a validation error on %s, got %v", tt.wantField, err)
+			}
+			if stored != nil {
+				t.Error("expected invalid preferences not to be stored")
+			}
+		})
+	}
+}
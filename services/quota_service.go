@@ -0,0 +1,196 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/repository"
+	"github.com/clementhaon/sandbox-api-go/validation"
+)
+
+// Quota resources reported in QUOTA_EXCEEDED details
+const (
+	QuotaResourceTasks         = "tasks"
+	QuotaResourceMediaBytes    = "media_bytes"
+	QuotaResourceDailyRequests = "daily_requests"
+	QuotaResourceDailyBytes    = "daily_bytes"
+)
+
+// QuotaService enforces per-user limits. Limits come from per-user overrides
+// when set, otherwise from the configured defaults; 0 means unlimited.
+type QuotaService interface {
+	Get(ctx context.Context, userID int) (models.UserQuota, error)
+	Update(ctx context.Context, userID int, req models.UpdateQuotaRequest) (models.UserQuota, error)
+	// CheckTaskQuota fails with QUOTA_EXCEEDED if the user cannot create another task
+	CheckTaskQuota(ctx context.Context, userID int) error
+	// CheckMediaQuota fails with QUOTA_EXCEEDED if adding size bytes would exceed the limit
+	CheckMediaQuota(ctx context.Context, userID int, size int64) error
+	// CheckRequestQuota fails with a 429 QUOTA_EXCEEDED once the user made
+	// their daily requests, or transferred their daily bytes, this UTC day
+	CheckRequestQuota(ctx context.Context, userID int) error
+}
+
+type quotaService struct {
+	quotaRepo repository.QuotaRepository
+	userRepo  repository.UserRepository
+	taskRepo  repository.TaskRepository
+	mediaRepo repository.MediaRepository
+	usageRepo repository.UsageRepository
+	defaults  models.QuotaLimits
+	auditSvc  AuditService
+}
+
+func NewQuotaService(quotaRepo repository.QuotaRepository, userRepo repository.UserRepository, taskRepo repository.TaskRepository, mediaRepo repository.MediaRepository, usageRepo repository.UsageRepository, defaults models.QuotaLimits, auditSvc AuditService) QuotaService {
+	return &quotaService{
+		quotaRepo: quotaRepo,
+		userRepo:  userRepo,
+		taskRepo:  taskRepo,
+		mediaRepo: mediaRepo,
+		usageRepo: usageRepo,
+		defaults:  defaults,
+		auditSvc:  auditSvc,
+	}
+}
+
+func (s *quotaService) limits(ctx context.Context, userID int) (models.QuotaLimits, models.QuotaOverride, error) {
+	override, err := s.quotaRepo.Get(ctx, userID)
+	if err != nil {
+		return models.QuotaLimits{}, models.QuotaOverride{}, err
+	}
+
+	limits := s.defaults
+	if override.MaxTasks != nil {
+		limits.MaxTasks = *override.MaxTasks
+	}
+	if override.MaxMediaBytes != nil {
+		limits.MaxMediaBytes = *override.MaxMediaBytes
+	}
+	if override.MaxDailyRequests != nil {
+		limits.MaxDailyRequests = *override.MaxDailyRequests
+	}
+	if override.MaxDailyBytes != nil {
+		limits.MaxDailyBytes = *override.MaxDailyBytes
+	}
+	return limits, override, nil
+}
+
+func (s *quotaService) Get(ctx context.Context, userID int) (models.UserQuota, error) {
+	exists, err := s.userRepo.Exists(ctx, userID)
+	if err != nil {
+		return models.UserQuota{}, err
+	}
+	if !exists {
+		return models.UserQuota{}, errors.NewNotFoundError("User")
+	}
+
+	limits, override, err := s.limits(ctx, userID)
+	if err != nil {
+		return models.UserQuota{}, err
+	}
+	usedTasks, err := s.taskRepo.CountByUser(ctx, userID)
+	if err != nil {
+		return models.UserQuota{}, err
+	}
+	usedMedia, err := s.mediaRepo.TotalSize(ctx, userID)
+	if err != nil {
+		return models.UserQuota{}, err
+	}
+
+	return models.UserQuota{
+		UserID:         userID,
+		Limits:         limits,
+		Override:       override,
+		UsedTasks:      usedTasks,
+		UsedMediaBytes: usedMedia,
+	}, nil
+}
+
+func (s *quotaService) Update(ctx context.Context, userID int, req models.UpdateQuotaRequest) (models.UserQuota, error) {
+	if err := validation.Struct(req); err != nil {
+		return models.UserQuota{}, err
+	}
+	exists, err := s.userRepo.Exists(ctx, userID)
+	if err != nil {
+		return models.UserQuota{}, err
+	}
+	if !exists {
+		return models.UserQuota{}, errors.NewNotFoundError("User")
+	}
+
+	override := models.QuotaOverride{
+		UserID:           userID,
+		MaxTasks:         req.MaxTasks,
+		MaxMediaBytes:    req.MaxMediaBytes,
+		MaxDailyRequests: req.MaxDailyRequests,
+		MaxDailyBytes:    req.MaxDailyBytes,
+	}
+	if err := s.quotaRepo.Upsert(ctx, override); err != nil {
+		return models.UserQuota{}, err
+	}
+
+	s.auditSvc.Record(ctx, models.AuditEntry{
+		Action:     models.AuditActionQuotaChange,
+		TargetType: "user",
+		TargetID:   &userID,
+		Metadata: map[string]interface{}{
+			"maxTasks":         req.MaxTasks,
+			"maxMediaBytes":    req.MaxMediaBytes,
+			"maxDailyRequests": req.MaxDailyRequests,
+			"maxDailyBytes":    req.MaxDailyBytes,
+		},
+	})
+	return s.Get(ctx, userID)
+}
+
+func (s *quotaService) CheckTaskQuota(ctx context.Context, userID int) error {
+	limits, _, err := s.limits(ctx, userID)
+	if err != nil || limits.MaxTasks == 0 {
+		return err
+	}
+
+	count, err := s.taskRepo.CountByUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if count >= limits.MaxTasks {
+		return errors.NewQuotaExceededError(QuotaResourceTasks, int64(limits.MaxTasks))
+	}
+	return nil
+}
+
+func (s *quotaService) CheckMediaQuota(ctx context.Context, userID int, size int64) error {
+	limits, _, err := s.limits(ctx, userID)
+	if err != nil || limits.MaxMediaBytes == 0 {
+		return err
+	}
+
+	used, err := s.mediaRepo.TotalSize(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if used+size > limits.MaxMediaBytes {
+		return errors.NewQuotaExceededError(QuotaResourceMediaBytes, limits.MaxMediaBytes)
+	}
+	return nil
+}
+
+func (s *quotaService) CheckRequestQuota(ctx context.Context, userID int) error {
+	limits, _, err := s.limits(ctx, userID)
+	if err != nil || (limits.MaxDailyRequests == 0 && limits.MaxDailyBytes == 0) {
+		return err
+	}
+
+	used, err := s.usageRepo.Totals(ctx, userID, time.Now())
+	if err != nil {
+		return err
+	}
+	if limits.MaxDailyRequests > 0 && used.Requests >= limits.MaxDailyRequests {
+		return errors.NewRequestQuotaExceededError(QuotaResourceDailyRequests, limits.MaxDailyRequests)
+	}
+	if limits.MaxDailyBytes > 0 && used.Bytes() >= limits.MaxDailyBytes {
+		return errors.NewRequestQuotaExceededError(QuotaResourceDailyBytes, limits.MaxDailyBytes)
+	}
+	return nil
+}
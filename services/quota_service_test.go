@@ -0,0 +1,168 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/mocks"
+	"github.com/clementhaon/sandbox-api-go/models"
+)
+
+func intPtr(v int) *int { return &v }
+
+func TestQuotaService_CheckTaskQuota(t *testing.T) {
+	tests := []struct {
+		name     string
+		defaults models.QuotaLimits
+		override models.QuotaOverride
+		count    int
+		wantErr  bool
+	}{
+		{name: "unlimited", count: 1000},
+		{name: "under default", defaults: models.QuotaLimits{MaxTasks: 10}, count: 9},
+		{name: "at default", defaults: models.QuotaLimits{MaxTasks: 10}, count: 10, wantErr: true},
+		{name: "override raises limit", defaults: models.QuotaLimits{MaxTasks: 10}, override: models.QuotaOverride{MaxTasks: intPtr(20)}, count: 10},
+		{name: "override of zero is unlimited", defaults: models.QuotaLimits{MaxTasks: 10}, override: models.QuotaOverride{MaxTasks: intPtr(0)}, count: 50},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quotaRepo := &mocks.MockQuotaRepository{
+				GetFn: func(ctx context.Context, userID int) (models.QuotaOverride, error) {
+					return tt.override, nil
+				},
+			}
+			taskRepo := &mocks.MockTaskRepository{
+				CountByUserFn: func(ctx context.Context, userID int) (int, error) {
+					return tt.count, nil
+				},
+			}
+			svc := NewQuotaService(quotaRepo, &mocks.MockUserRepository{}, taskRepo, &mocks.MockMediaRepository{}, &mocks.MockUsageRepository{}, tt.defaults, &mocks.MockAuditService{})
+
+			err := svc.CheckTaskQuota(context.Background(), 1)
+			if tt.wantErr && !errors.Is(err, errors.ErrQuotaExceeded) {
+				t.Fatalf("expected QUOTA_EXCEEDED, got %v", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestQuotaService_CheckMediaQuota(t *testing.T) {
+	mediaRepo := &mocks.MockMediaRepository{
+		TotalSizeFn: func(ctx context.Context, userID int) (int64, error) {
+			return 900, nil
+		},
+	}
+	svc := NewQuotaService(&mocks.MockQuotaRepository{}, &mocks.MockUserRepository{}, &mocks.MockTaskRepository{}, mediaRepo, &mocks.MockUsageRepository{}, models.QuotaLimits{MaxMediaBytes: 1000}, &mocks.MockAuditService{})
+
+	if err := svc.CheckMediaQuota(context.Background(), 1, 100); err != nil {
+		t.Fatalf("expected upload filling the quota to pass, got %v", err)
+	}
+	err := svc.CheckMediaQuota(context.Background(), 1, 101)
+	appErr, ok := errors.IsAppError(err)
+	if !ok || appErr.Code != errors.ErrQuotaExceeded {
+		t.Fatalf("expected QUOTA_EXCEEDED, got %v", err)
+	}
+	details, _ := appErr.Details.(map[string]interface{})
+	if details["resource"] != QuotaResourceMediaBytes {
+		t.Errorf("expected resource %q, got %v", QuotaResourceMediaBytes, details["resource"])
+	}
+}
+
+func TestQuotaService_CheckRequestQuota(t *testing.T) {
+	usageRepo := &mocks.MockUsageRepository{
+		TotalsFn: func(ctx context.Context, userID int, day time.Time) (models.UsageCounts, error) {
+			return models.UsageCounts{Requests: 10, BytesIn: 400, BytesOut: 600}, nil
+		},
+	}
+	tests := []struct {
+		name     string
+		defaults models.QuotaLimits
+		resource string
+	}{
+		{name: "unlimited"},
+		{name: "under limits", defaults: models.QuotaLimits{MaxDailyRequests: 11, MaxDailyBytes: 1001}},
+		{name: "requests reached", defaults: models.QuotaLimits{MaxDailyRequests: 10}, resource: QuotaResourceDailyRequests},
+		{name: "bytes reached", defaults: models.QuotaLimits{MaxDailyBytes: 1000}, resource: QuotaResourceDailyBytes},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := NewQuotaService(&mocks.MockQuotaRepository{}, &mocks.MockUserRepository{}, &mocks.MockTaskRepository{}, &mocks.MockMediaRepository{}, usageRepo, tt.defaults, &mocks.MockAuditService{})
+
+			err := svc.CheckRequestQuota(context.Background(), 1)
+			if tt.resource == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			appErr, ok := errors.IsAppError(err)
+			if !ok || appErr.Code != errors.ErrQuotaExceeded || appErr.StatusCode != http.StatusTooManyRequests {
+				t.Fatalf("expected a 429 QUOTA_EXCEEDED, got %v", err)
+			}
+			details, _ := appErr.Details.(map[string]interface{})
+			if details["resource"] != tt.resource {
+				t.Errorf("expected resource %q, got %v", tt.resource, details["resource"])
+			}
+		})
+	}
+}
+
+func TestQuotaService_Update(t *testing.T) {
+	var stored models.QuotaOverride
+	quotaRepo := &mocks.MockQuotaRepository{
+		UpsertFn: func(ctx context.Context, override models.QuotaOverride) error {
+			stored = override
+			return nil
+		},
+		GetFn: func(ctx context.Context, userID int) (models.QuotaOverride, error) {
+			return stored, nil
+		},
+	}
+	userRepo := &mocks.MockUserRepository{
+		ExistsFn: func(ctx context.Context, id int) (bool, error) { return true, nil },
+	}
+	taskRepo := &mocks.MockTaskRepository{
+		CountByUserFn: func(ctx context.Context, userID int) (int, error) { return 3, nil },
+	}
+	mediaRepo := &mocks.MockMediaRepository{
+		TotalSizeFn: func(ctx context.Context, userID int) (int64, error) { return 0, nil },
+	}
+	var audited models.AuditEntry
+	auditSvc := &mocks.MockAuditService{
+		RecordFn: func(ctx context.Context, entry models.AuditEntry) { audited = entry },
+	}
+	svc := NewQuotaService(quotaRepo, userRepo, taskRepo, mediaRepo, &mocks.MockUsageRepository{}, models.QuotaLimits{MaxTasks: 10, MaxMediaBytes: 1 << 20}, auditSvc)
+
+	quota, err := svc.Update(context.Background(), 7, models.UpdateQuotaRequest{MaxTasks: intPtr(50)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if quota.Limits.MaxTasks != 50 {
+		t.Errorf("expected overridden task limit 50, got %d", quota.Limits.MaxTasks)
+	}
+	if quota.Limits.MaxMediaBytes != 1<<20 {
+		t.Errorf("expected default media limit, got %d", quota.Limits.MaxMediaBytes)
+	}
+	if quota.UsedTasks != 3 {
+		t.Errorf("expected 3 used tasks, got %d", quota.UsedTasks)
+	}
+	if audited.Action != models.AuditActionQuotaChange {
+		t.Errorf("expected quota change audit, got %q", audited.Action)
+	}
+}
+
+func TestQuotaService_Update_Invalid(t *testing.T) {
+	svc := NewQuotaService(&mocks.MockQuotaRepository{}, &mocks.MockUserRepository{}, &mocks.MockTaskRepository{}, &mocks.MockMediaRepository{}, &mocks.MockUsageRepository{}, models.QuotaLimits{}, &mocks.MockAuditService{})
+
+	_, err := svc.Update(context.Background(), 7, models.UpdateQuotaRequest{MaxTasks: intPtr(-1)})
+	if _, ok := errors.IsAppError(err); !ok {
+		t.Fatalf("expected validation error, got %v", err)
+	}
+}
@@ -0,0 +1,156 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/anonymize"
+	"github.com/clementhaon/sandbox-api-go/models"
+)
+
+// largeScenarioTasks is how many tasks the large scenario generates.
+const largeScenarioTasks = 500
+
+// scenario is a fixture dataset loaded for a user.
+type scenario struct {
+	tasks         []fixtureTask
+	notifications []fixtureNotification
+}
+
+// fixtureTask is a task of a scenario, created by the user loading it.
+type fixtureTask struct {
+	title       string
+	description string
+	column      int            // index in the board's columns, wrapping around
+	priority    string         // medium when empty
+	status      string         // set after creation; empty leaves the task to do
+	deadline    *time.Duration // from the time the scenario is loaded
+	estimate    int            // in minutes
+	tags        []string
+	assigned    bool     // to the user loading the scenario
+	checklist   []string // item titles
+	checked     int      // how many of the first items are done
+	tracked     int      // minutes of a time entry of the user, if any
+}
+
+// fixtureNotification is a notification of the user, about the task of a
+// scenario at index task, or none when it is negative.
+type fixtureNotification struct {
+	notifType string
+	title     string
+	message   string
+	task      int
+}
+
+// deadlineIn returns a deadline d from the time a scenario is loaded.
+func deadlineIn(d time.Duration) *time.Duration {
+	return &d
+}
+
+// ofLength repeats s up to n bytes.
+func ofLength(s string, n int) string {
+	return strings.Repeat(s, n/len(s)+1)[:n]
+}
+
+// scenarioNamed returns the scenario of a models.Scenario* name.
+func scenarioNamed(name string) (scenario, bool) {
+	switch name {
+	case models.ScenarioEmpty:
+		return scenario{}, true
+	case models.ScenarioSmall:
+		return smallScenario(), true
+	case models.ScenarioLarge:
+		return largeScenario(), true
+	case models.ScenarioEdgeCases:
+		return edgeCasesScenario(), true
+	}
+	return scenario{}, false
+}
+
+func smallScenario() scenario {
+	return scenario{
+		tasks: []fixtureTask{
+			{
+				title: "Write the onboarding guide", description: "Cover signup, the first board and inviting the team.",
+				priority: models.PriorityHigh, deadline: deadlineIn(7 * 24 * time.Hour), estimate: 240, tags: []string{"docs"},
+				checklist: []string{"Outline", "First draft", "Review"}, checked: 1,
+			},
+			{
+				title: "Fix the login redirect", description: "Users land on the home page instead of the page they asked for.",
+				column: 1, priority: models.PriorityUrgent, status: models.TaskStatusInProgress, deadline: deadlineIn(24 * time.Hour),
+				estimate: 60, tags: []string{"bug", "auth"}, assigned: true, tracked: 45,
+			},
+			{
+				title: "Plan the quarterly review", column: 1, priority: models.PriorityMedium,
+				status: models.TaskStatusBlocked, tags: []string{"planning"},
+			},
+			{
+				title: "Set up continuous integration", column: 2, priority: models.PriorityMedium,
+				status: models.TaskStatusDone, estimate: 120, tracked: 90,
+			},
+			{title: "Order new laptops", priority: models.PriorityLow},
+		},
+		notifications: []fixtureNotification{
+			{models.NotifTaskAssigned, "Task assigned", "Fix the login redirect was assigned to you", 1},
+			{models.NotifTaskDeadline, "Deadline tomorrow", "Fix the login redirect is due tomorrow", 1},
+			{models.NotifSystem, "Welcome", "Your sandbox is ready", -1},
+		},
+	}
+}
+
+// largeScenario generates its tasks with a Faker, so they are the same at
+// every load.
+func largeScenario() scenario {
+	faker := anonymize.NewFaker(models.ScenarioLarge)
+	priorities, statuses := models.ValidPriorities(), models.ValidTaskStatuses()
+
+	var s scenario
+	for i := range largeScenarioTasks {
+		t := fixtureTask{
+			title:    faker.Sentence("title", i, 2, 6),
+			column:   i,
+			priority: priorities[i%len(priorities)],
+			status:   statuses[i%len(statuses)],
+			estimate: 30 * (i % 9),
+			tags:     faker.Tags(i),
+		}
+		if i%3 == 0 {
+			t.description = faker.Sentence("description", i, 8, 20)
+		}
+		if i%5 == 0 {
+			t.deadline = deadlineIn(time.Duration(i-largeScenarioTasks/2) * 24 * time.Hour)
+		}
+		s.tasks = append(s.tasks, t)
+	}
+	return s
+}
+
+func edgeCasesScenario() scenario {
+	var longChecklist []string
+	for i := range 50 {
+		longChecklist = append(longChecklist, fmt.Sprintf("Step %d", i+1))
+	}
+	manyTags := strings.Fields("alpha beta gamma delta epsilon zeta eta theta iota kappa lambda mu nu xi omicron pi rho sigma tau upsilon")
+
+	return scenario{
+		tasks: []fixtureTask{
+			{title: ofLength("Longest title ", 200), description: ofLength("Longest description. ", 1000)},
+			{title: "X"},
+			{title: "Überprüfung der Straße 🚧 — 東京 & Zürich", description: "Line one\nLine two\n\n\tIndented line", tags: []string{"ünïcödé", "emoji-🚀"}},
+			{title: `Quotes "double" 'single', a back\slash & ampersand; DROP TABLE tasks;--`, priority: models.PriorityUrgent},
+			{title: "Overdue by a year", deadline: deadlineIn(-365 * 24 * time.Hour), column: 1, status: models.TaskStatusInProgress},
+			{title: "Overdue by a minute", deadline: deadlineIn(-time.Minute), priority: models.PriorityHigh, assigned: true},
+			{title: "Due in ten years", deadline: deadlineIn(10 * 365 * 24 * time.Hour), priority: models.PriorityLow},
+			{title: "Done with time tracked over the estimate", column: 2, status: models.TaskStatusDone, estimate: 1, tracked: 600},
+			{title: "Huge estimate", estimate: 1_000_000},
+			{title: "Many tags", tags: manyTags},
+			{title: "Long checklist, all done", checklist: longChecklist, checked: len(longChecklist)},
+			{title: "Blocked without anything else", status: models.TaskStatusBlocked},
+		},
+		notifications: []fixtureNotification{
+			{models.NotifTaskOverdue, "Overdue", "Overdue by a year is overdue", 4},
+			{models.NotifMention, "Überprüfung 🚧", "Unicode in a notification: 東京", 2},
+		},
+	}
+}
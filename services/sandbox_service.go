@@ -0,0 +1,223 @@
+package services
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/clock"
+	"github.com/clementhaon/sandbox-api-go/database"
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/logger"
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/repository"
+)
+
+// SandboxService puts the data of a user in a known state, for sandbox
+// consumers testing an integration.
+type SandboxService interface {
+	// Reset deletes the tasks the user created, with their checklists and
+	// time entries, and the user's notifications. The account, its
+	// preferences, tokens and media are kept.
+	Reset(ctx context.Context, userID int) (models.SandboxResetResponse, error)
+	// LoadScenario resets the data of the user, then loads the fixture
+	// dataset name, one of models.ValidScenarios, in the same transaction.
+	LoadScenario(ctx context.Context, userID int, name string) (models.SandboxScenarioResponse, error)
+}
+
+type sandboxService struct {
+	txManager     database.Transactor
+	taskRepo      repository.TaskRepository
+	columnRepo    repository.ColumnRepository
+	checklistRepo repository.ChecklistRepository
+	timeEntryRepo repository.TimeEntryRepository
+	notifRepo     repository.NotificationRepository
+	quotaSvc      QuotaService
+}
+
+func NewSandboxService(txManager database.Transactor, taskRepo repository.TaskRepository, columnRepo repository.ColumnRepository, checklistRepo repository.ChecklistRepository, timeEntryRepo repository.TimeEntryRepository, notifRepo repository.NotificationRepository, quotaSvc QuotaService) SandboxService {
+	return &sandboxService{
+		txManager:     txManager,
+		taskRepo:      taskRepo,
+		columnRepo:    columnRepo,
+		checklistRepo: checklistRepo,
+		timeEntryRepo: timeEntryRepo,
+		notifRepo:     notifRepo,
+		quotaSvc:      quotaSvc,
+	}
+}
+
+func (s *sandboxService) Reset(ctx context.Context, userID int) (models.SandboxResetResponse, error) {
+	var resp models.SandboxResetResponse
+	err := s.txManager.WithTransaction(ctx, func(q database.Querier) error {
+		var err error
+		resp, err = s.reset(ctx, q, userID)
+		return err
+	})
+	if err != nil {
+		return models.SandboxResetResponse{}, err
+	}
+
+	logger.FromContext(ctx).Info("Sandbox reset",
+		"deleted_tasks", resp.DeletedTasks,
+		"deleted_notifications", resp.DeletedNotifications,
+	)
+	return resp, nil
+}
+
+func (s *sandboxService) reset(ctx context.Context, q database.Querier, userID int) (models.SandboxResetResponse, error) {
+	tasks, err := s.taskRepo.WithQuerier(q).DeleteByUser(ctx, userID)
+	if err != nil {
+		return models.SandboxResetResponse{}, err
+	}
+	notifications, err := s.notifRepo.WithQuerier(q).DeleteAll(ctx, userID)
+	if err != nil {
+		return models.SandboxResetResponse{}, err
+	}
+	return models.SandboxResetResponse{DeletedTasks: tasks, DeletedNotifications: notifications}, nil
+}
+
+func (s *sandboxService) LoadScenario(ctx context.Context, userID int, name string) (models.SandboxScenarioResponse, error) {
+	sc, ok := scenarioNamed(name)
+	if !ok {
+		return models.SandboxScenarioResponse{}, errors.NewInvalidFormatError("name", strings.Join(models.ValidScenarios(), ", "))
+	}
+
+	// The user has no task left after the reset, so the scenario only has
+	// to fit in the quota
+	quota, err := s.quotaSvc.Get(ctx, userID)
+	if err != nil {
+		return models.SandboxScenarioResponse{}, err
+	}
+	if quota.Limits.MaxTasks > 0 && len(sc.tasks) > quota.Limits.MaxTasks {
+		return models.SandboxScenarioResponse{}, errors.NewQuotaExceededError(QuotaResourceTasks, int64(quota.Limits.MaxTasks))
+	}
+
+	columns, err := s.columnRepo.List(ctx)
+	if err != nil {
+		return models.SandboxScenarioResponse{}, err
+	}
+	if len(columns) == 0 && len(sc.tasks) > 0 {
+		return models.SandboxScenarioResponse{}, errors.NewConflictError("The board has no columns to load tasks into")
+	}
+
+	resp := models.SandboxScenarioResponse{Scenario: name}
+	err = s.txManager.WithTransaction(ctx, func(q database.Querier) error {
+		if resp.Reset, err = s.reset(ctx, q, userID); err != nil {
+			return err
+		}
+		return s.load(ctx, q, userID, sc, columns, &resp)
+	})
+	if err != nil {
+		return models.SandboxScenarioResponse{}, err
+	}
+
+	logger.FromContext(ctx).Info("Sandbox scenario loaded",
+		"scenario", name,
+		"tasks", resp.Tasks,
+	)
+	return resp, nil
+}
+
+// load creates the data of sc for userID with q, counting it in resp.
+func (s *sandboxService) load(ctx context.Context, q database.Querier, userID int, sc scenario, columns []models.Column, resp *models.SandboxScenarioResponse) error {
+	taskRepo, checklistRepo := s.taskRepo.WithQuerier(q), s.checklistRepo.WithQuerier(q)
+	timeEntryRepo, notifRepo := s.timeEntryRepo.WithQuerier(q), s.notifRepo.WithQuerier(q)
+	now := clock.Now(ctx)
+
+	// Tasks go after the ones of other users in their column
+	orders := make(map[int]int)
+	tasks := make([]models.Task, len(sc.tasks))
+	for i, f := range sc.tasks {
+		req := models.CreateTaskRequest{
+			Title:         f.title,
+			Description:   f.description,
+			ColumnID:      columns[f.column%len(columns)].ID,
+			Priority:      cmp.Or(f.priority, models.PriorityMedium),
+			EstimatedTime: f.estimate,
+			Tags:          f.tags,
+		}
+		if req.Tags == nil {
+			req.Tags = []string{}
+		}
+		if f.deadline != nil {
+			deadline := now.Add(*f.deadline)
+			req.Deadline = &deadline
+		}
+		if f.assigned {
+			req.AssigneeID = &userID
+		}
+
+		order, ok := orders[req.ColumnID]
+		if !ok {
+			maxOrder, err := taskRepo.GetMaxOrder(ctx, req.ColumnID)
+			if err != nil {
+				return err
+			}
+			order = maxOrder
+		}
+		order += repository.TaskOrderGap
+		orders[req.ColumnID] = order
+
+		task, err := taskRepo.Create(ctx, req, order, userID)
+		if err != nil {
+			return err
+		}
+		if f.status != "" && f.status != task.Status {
+			if task, err = taskRepo.SetStatus(ctx, task.ID, f.status, []string{task.Status}); err != nil {
+				return err
+			}
+		}
+		tasks[i] = task
+		resp.Tasks++
+
+		for j, title := range f.checklist {
+			item, err := checklistRepo.Create(ctx, task.ID, title)
+			if err != nil {
+				return err
+			}
+			if j < f.checked {
+				done := true
+				if _, err := checklistRepo.Update(ctx, task.ID, item.ID, models.UpdateChecklistItemRequest{Done: &done}); err != nil {
+					return err
+				}
+			}
+			resp.ChecklistItems++
+		}
+
+		if f.tracked > 0 {
+			end := now
+			_, err := timeEntryRepo.Create(ctx, userID, models.CreateTimeEntryRequest{
+				TaskID:    task.ID,
+				StartTime: end.Add(-time.Duration(f.tracked) * time.Minute),
+				EndTime:   &end,
+				Duration:  f.tracked * 60,
+			})
+			if err != nil {
+				return err
+			}
+			if err := timeEntryRepo.AddTrackedTime(ctx, task.ID, f.tracked); err != nil {
+				return err
+			}
+			resp.TimeEntries++
+		}
+	}
+
+	for _, n := range sc.notifications {
+		var data models.NotificationData
+		if n.task >= 0 {
+			data = models.NotificationData{TaskID: tasks[n.task].ID, TaskTitle: tasks[n.task].Title}
+		}
+		dataJSON, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		if err := notifRepo.Create(ctx, userID, n.notifType, n.title, n.message, dataJSON); err != nil {
+			return err
+		}
+		resp.Notifications++
+	}
+	return nil
+}
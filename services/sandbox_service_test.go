@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/mocks"
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/repository"
+	"github.com/clementhaon/sandbox-api-go/validation"
+)
+
+// newMemorySandboxService returns a SandboxService over an in-memory store
+// holding two users, and the task repository of the store.
+func newMemorySandboxService(t *testing.T, maxTasks int) (SandboxService, repository.TaskRepository, [2]int) {
+	t.Helper()
+	ctx := context.Background()
+	store := repository.NewMemoryStore()
+	users := repository.NewMemoryUserRepository(store)
+	var ids [2]int
+	for i, name := range []string{"alice", "bob"} {
+		u, err := users.CreateAuth(ctx, name, name+"@example.com", "hash")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		ids[i] = u.ID
+	}
+
+	tasks := repository.NewMemoryTaskRepository(store)
+	quota := &mocks.MockQuotaService{
+		GetFn: func(ctx context.Context, userID int) (models.UserQuota, error) {
+			return models.UserQuota{UserID: userID, Limits: models.QuotaLimits{MaxTasks: maxTasks}}, nil
+		},
+	}
+	svc := NewSandboxService(store, tasks, repository.NewMemoryColumnRepository(store), repository.NewMemoryChecklistRepository(store),
+		repository.NewMemoryTimeEntryRepository(store), repository.NewMemoryNotificationRepository(store), quota)
+	return svc, tasks, ids
+}
+
+func TestSandboxService_LoadScenario(t *testing.T) {
+	ctx := context.Background()
+	svc, tasks, users := newMemorySandboxService(t, 0)
+	alice, bob := users[0], users[1]
+
+	if _, err := svc.LoadScenario(ctx, bob, models.ScenarioSmall); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, name := range models.ValidScenarios() {
+		t.Run(name, func(t *testing.T) {
+			resp, err := svc.LoadScenario(ctx, alice, name)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			sc, _ := scenarioNamed(name)
+			if resp.Tasks != len(sc.tasks) || resp.Notifications != len(sc.notifications) {
+				t.Errorf("expected %d tasks and %d notifications, got %+v", len(sc.tasks), len(sc.notifications), resp)
+			}
+			if count, _ := tasks.CountByUser(ctx, alice); count != len(sc.tasks) {
+				t.Errorf("expected only the %d tasks of the scenario, got %d", len(sc.tasks), count)
+			}
+		})
+	}
+
+	// The data of other users is kept
+	if count, _ := tasks.CountByUser(ctx, bob); count != len(smallScenario().tasks) {
+		t.Errorf("expected the tasks of bob kept, got %d", count)
+	}
+
+	resp, err := svc.Reset(ctx, alice)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.DeletedTasks != len(edgeCasesScenario().tasks) || resp.DeletedNotifications != int64(len(edgeCasesScenario().notifications)) {
+		t.Errorf("expected the edge cases deleted, got %+v", resp)
+	}
+
+	if _, err := svc.LoadScenario(ctx, alice, "huge"); !errors.Is(err, errors.ErrInvalidFormat) {
+		t.Errorf("expected INVALID_FORMAT for an unknown scenario, got %v", err)
+	}
+}
+
+func TestSandboxService_LoadScenarioQuota(t *testing.T) {
+	ctx := context.Background()
+	svc, tasks, users := newMemorySandboxService(t, 100)
+
+	if _, err := svc.LoadScenario(ctx, users[0], models.ScenarioSmall); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := svc.LoadScenario(ctx, users[0], models.ScenarioLarge); !errors.Is(err, errors.ErrQuotaExceeded) {
+		t.Errorf("expected QUOTA_EXCEEDED, got %v", err)
+	}
+	// Nothing was reset
+	if count, _ := tasks.CountByUser(ctx, users[0]); count != len(smallScenario().tasks) {
+		t.Errorf("expected the small scenario kept, got %d tasks", count)
+	}
+}
+
+func TestScenarios_FitValidation(t *testing.T) {
+	for _, name := range models.ValidScenarios() {
+		sc, ok := scenarioNamed(name)
+		if !ok {
+			t.Fatalf("scenario %s is not defined", name)
+		}
+		for i, task := range sc.tasks {
+			if err := validation.ValidateTaskInput(task.title, task.description); err != nil {
+				t.Errorf("%s: task %d: %v", name, i, err)
+			}
+			if task.checked > len(task.checklist) {
+				t.Errorf("%s: task %d checks more items than it has", name, i)
+			}
+		}
+		for i, n := range sc.notifications {
+			if n.task >= len(sc.tasks) {
+				t.Errorf("%s: notification %d is about a task out of range", name, i)
+			}
+		}
+	}
+}
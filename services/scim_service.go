@@ -0,0 +1,526 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/clementhaon/sandbox-api-go/auth"
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/repository"
+	"github.com/clementhaon/sandbox-api-go/sanitize"
+	"github.com/clementhaon/sandbox-api-go/scim"
+	"github.com/clementhaon/sandbox-api-go/validation"
+)
+
+// SCIMUsersPath is where user resources are served, used for their
+// meta.location.
+const SCIMUsersPath = "/scim/v2/Users"
+
+// scimMaxCount bounds the page size of user searches
+const scimMaxCount = 100
+
+// SCIMService lets identity providers provision and deprovision accounts
+// through the SCIM 2.0 Users endpoint. Protocol failures are *scim.Error;
+// unknown users are NotFound errors.
+type SCIMService interface {
+	List(ctx context.Context, params models.SCIMListParams) (models.SCIMListResponse, error)
+	Get(ctx context.Context, id string) (models.SCIMUser, error)
+	Create(ctx context.Context, user models.SCIMUser) (models.SCIMUser, error)
+	Patch(ctx context.Context, id string, req models.SCIMPatchRequest) (models.SCIMUser, error)
+	Delete(ctx context.Context, id string) error
+}
+
+type scimService struct {
+	userRepo  repository.UserRepository
+	auditSvc  AuditService
+	hasher    *auth.PasswordHasher
+	passwords *PasswordScreen
+}
+
+// NewSCIMService returns a SCIMService hashing the passwords of the accounts
+// it creates with hasher, once passwords lets them through.
+func NewSCIMService(userRepo repository.UserRepository, auditSvc AuditService, hasher *auth.PasswordHasher, passwords *PasswordScreen) SCIMService {
+	return &scimService{userRepo: userRepo, auditSvc: auditSvc, hasher: hasher, passwords: passwords}
+}
+
+// List returns a page of users, optionally filtered on userName or
+// emails.value. startIndex is rounded down to the start of a page.
+func (s *scimService) List(ctx context.Context, params models.SCIMListParams) (models.SCIMListResponse, error) {
+	if params.StartIndex < 1 {
+		params.StartIndex = 1
+	}
+	if params.Count < 1 || params.Count > scimMaxCount {
+		params.Count = scimMaxCount
+	}
+	listParams := models.UserListParams{
+		Page:     (params.StartIndex-1)/params.Count + 1,
+		PageSize: params.Count,
+		SortBy:   "id",
+	}
+
+	if params.Filter != "" {
+		filter, err := scim.ParseFilter(params.Filter)
+		if err != nil {
+			return models.SCIMListResponse{}, err
+		}
+		switch {
+		case filter.Is("userName"):
+			listParams.Username = filter.Value
+		case filter.Is("emails.value"), filter.Is("emails"):
+			listParams.Email = filter.Value
+		default:
+			return models.SCIMListResponse{}, scim.BadRequest(scim.ErrInvalidFilter, "filters are supported on userName and emails.value")
+		}
+		if filter.Value == "" {
+			return scimListResponse(nil, 0, params.StartIndex), nil
+		}
+	}
+
+	users, total, err := s.userRepo.List(ctx, listParams)
+	if err != nil {
+		return models.SCIMListResponse{}, err
+	}
+	return scimListResponse(users, total, (listParams.Page-1)*listParams.PageSize+1), nil
+}
+
+func (s *scimService) Get(ctx context.Context, id string) (models.SCIMUser, error) {
+	userID, err := parseSCIMID(id)
+	if err != nil {
+		return models.SCIMUser{}, err
+	}
+	u, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return models.SCIMUser{}, err
+	}
+	return toSCIMUser(u), nil
+}
+
+// Create provisions an account with the user role. A password given is
+// screened for breaches; without one the account gets a random one.
+func (s *scimService) Create(ctx context.Context, user models.SCIMUser) (models.SCIMUser, error) {
+	userName := strings.TrimSpace(user.UserName)
+	if err := checkSCIMUserName(userName); err != nil {
+		return models.SCIMUser{}, err
+	}
+	email := primaryEmail(user.Emails)
+	if err := checkSCIMEmail(email); err != nil {
+		return models.SCIMUser{}, err
+	}
+
+	var firstName, lastName string
+	if user.Name != nil {
+		firstName, lastName = sanitizeName(user.Name.GivenName), sanitizeName(user.Name.FamilyName)
+	}
+
+	exists, err := s.userRepo.ExistsByUsernameOrEmail(ctx, userName, email)
+	if err != nil {
+		return models.SCIMUser{}, err
+	}
+	if exists {
+		return models.SCIMUser{}, scim.NewError(http.StatusConflict, scim.ErrUniqueness, "userName or email already in use")
+	}
+
+	password := user.Password
+	if err := s.passwords.Check(ctx, "scim_create", password); err != nil {
+		return models.SCIMUser{}, err
+	}
+	if password == "" {
+		if password, err = randomSCIMPassword(); err != nil {
+			return models.SCIMUser{}, errors.NewInternalError().WithCause(err)
+		}
+	}
+	hashedPassword, err := s.hasher.Hash(ctx, password)
+	if err != nil {
+		return models.SCIMUser{}, passwordHashError(ctx, err)
+	}
+
+	u, err := s.userRepo.Create(ctx, userName, email, hashedPassword, firstName, lastName, models.RoleUser)
+	if err != nil {
+		return models.SCIMUser{}, err
+	}
+	if user.Active != nil && !*user.Active {
+		if u, err = s.userRepo.UpdateStatus(ctx, u.ID, false); err != nil {
+			return models.SCIMUser{}, err
+		}
+	}
+
+	s.auditSvc.Record(ctx, models.AuditEntry{
+		Action:     models.AuditActionSCIMUserCreate,
+		TargetType: "user",
+		TargetID:   &u.ID,
+		Metadata:   map[string]interface{}{"active": u.IsActive},
+	})
+	return toSCIMUser(u), nil
+}
+
+// scimChange collects the attributes set by the operations of a patch.
+type scimChange struct {
+	userName  *string
+	email     *string
+	firstName *string
+	lastName  *string
+	active    *bool
+	// clearName is set when a name part is removed, which Update cannot do
+	clearName bool
+}
+
+// Patch applies add, replace and remove operations to a user. Attributes
+// that are not backed by the users table are ignored.
+func (s *scimService) Patch(ctx context.Context, id string, req models.SCIMPatchRequest) (models.SCIMUser, error) {
+	userID, err := parseSCIMID(id)
+	if err != nil {
+		return models.SCIMUser{}, err
+	}
+	if len(req.Operations) == 0 {
+		return models.SCIMUser{}, scim.BadRequest(scim.ErrInvalidSyntax, "no operations")
+	}
+
+	var change scimChange
+	for _, op := range req.Operations {
+		if err := change.apply(op); err != nil {
+			return models.SCIMUser{}, err
+		}
+	}
+
+	current, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return models.SCIMUser{}, err
+	}
+	if err := s.checkChange(ctx, current, change); err != nil {
+		return models.SCIMUser{}, err
+	}
+
+	u := current
+	var update models.UpdateUserRequest
+	if change.userName != nil && *change.userName != current.Username {
+		update.Username = *change.userName
+	}
+	if change.email != nil && !strings.EqualFold(*change.email, current.Email) {
+		update.Email = *change.email
+	}
+	if !change.clearName {
+		update.FirstName = derefString(change.firstName)
+		update.LastName = derefString(change.lastName)
+	}
+	if update != (models.UpdateUserRequest{}) {
+		if u, err = s.userRepo.Update(ctx, userID, update); err != nil {
+			return models.SCIMUser{}, err
+		}
+	}
+
+	if change.clearName {
+		firstName, lastName := u.FirstName.NullString, u.LastName.NullString
+		if change.firstName != nil {
+			firstName = sql.NullString{String: *change.firstName, Valid: *change.firstName != ""}
+		}
+		if change.lastName != nil {
+			lastName = sql.NullString{String: *change.lastName, Valid: *change.lastName != ""}
+		}
+		if err := s.userRepo.UpdateProfile(ctx, userID, firstName, lastName, u.AvatarURL.NullString); err != nil {
+			return models.SCIMUser{}, err
+		}
+		u.FirstName, u.LastName = models.NullString{NullString: firstName}, models.NullString{NullString: lastName}
+	}
+
+	if change.active != nil && *change.active != u.IsActive {
+		if u, err = s.userRepo.UpdateStatus(ctx, userID, *change.active); err != nil {
+			return models.SCIMUser{}, err
+		}
+	}
+
+	s.auditSvc.Record(ctx, models.AuditEntry{
+		Action:     models.AuditActionSCIMUserUpdate,
+		TargetType: "user",
+		TargetID:   &u.ID,
+		Metadata:   map[string]interface{}{"active": u.IsActive},
+	})
+	return toSCIMUser(u), nil
+}
+
+// checkChange validates the new username and email of a patch, and that
+// they are not used by another account.
+func (s *scimService) checkChange(ctx context.Context, current models.User, change scimChange) error {
+	var userName, email string
+	if change.userName != nil {
+		if err := checkSCIMUserName(*change.userName); err != nil {
+			return err
+		}
+		if *change.userName != current.Username {
+			userName = *change.userName
+		}
+	}
+	if change.email != nil {
+		if err := checkSCIMEmail(*change.email); err != nil {
+			return err
+		}
+		if !strings.EqualFold(*change.email, current.Email) {
+			email = *change.email
+		}
+	}
+	if userName == "" && email == "" {
+		return nil
+	}
+
+	exists, err := s.userRepo.ExistsByUsernameOrEmail(ctx, userName, email)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return scim.NewError(http.StatusConflict, scim.ErrUniqueness, "userName or email already in use")
+	}
+	return nil
+}
+
+// Delete removes the account and everything it owns.
+func (s *scimService) Delete(ctx context.Context, id string) error {
+	userID, err := parseSCIMID(id)
+	if err != nil {
+		return err
+	}
+	if err := s.userRepo.Delete(ctx, userID); err != nil {
+		return err
+	}
+
+	s.auditSvc.Record(ctx, models.AuditEntry{
+		Action:     models.AuditActionSCIMUserDelete,
+		TargetType: "user",
+		TargetID:   &userID,
+	})
+	return nil
+}
+
+// apply records the attributes set by op.
+func (c *scimChange) apply(op models.SCIMPatchOperation) error {
+	path := strings.TrimPrefix(op.Path, models.SCIMSchemaUser+":")
+
+	switch strings.ToLower(op.Op) {
+	case "add", "replace":
+		if path != "" {
+			return c.set(path, op.Value)
+		}
+		var attrs map[string]json.RawMessage
+		if err := json.Unmarshal(op.Value, &attrs); err != nil {
+			return scim.BadRequest(scim.ErrInvalidValue, "an operation without path needs an object value")
+		}
+		for attr, value := range attrs {
+			if err := c.set(strings.TrimPrefix(attr, models.SCIMSchemaUser+":"), value); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "remove":
+		switch strings.ToLower(path) {
+		case "":
+			return scim.BadRequest(scim.ErrInvalidPath, "remove needs a path")
+		case "name.givenname":
+			c.firstName, c.clearName = new(string), true
+		case "name.familyname":
+			c.lastName, c.clearName = new(string), true
+		case "name":
+			c.firstName, c.lastName, c.clearName = new(string), new(string), true
+		case "username", "active", "emails":
+			return scim.BadRequest(scim.ErrMutability, path+" cannot be removed")
+		}
+		return nil
+	default:
+		return scim.BadRequest(scim.ErrInvalidSyntax, "unknown operation "+op.Op)
+	}
+}
+
+// set records the value of the attribute at path. Attributes without a
+// column are ignored.
+func (c *scimChange) set(path string, value json.RawMessage) error {
+	lower := strings.ToLower(path)
+	switch {
+	case lower == "username":
+		s, err := scimString(path, value)
+		if err != nil {
+			return err
+		}
+		c.userName = &s
+	case lower == "name.givenname", lower == "name.familyname":
+		s, err := scimString(path, value)
+		if err != nil {
+			return err
+		}
+		s = sanitizeName(s)
+		if lower == "name.givenname" {
+			c.firstName = &s
+		} else {
+			c.lastName = &s
+		}
+		c.clearName = c.clearName || s == ""
+	case lower == "name":
+		var name models.SCIMName
+		if err := json.Unmarshal(value, &name); err != nil {
+			return scim.BadRequest(scim.ErrInvalidValue, "name must be an object")
+		}
+		if name.GivenName != "" {
+			s := sanitizeName(name.GivenName)
+			c.firstName = &s
+		}
+		if name.FamilyName != "" {
+			s := sanitizeName(name.FamilyName)
+			c.lastName = &s
+		}
+	case lower == "emails":
+		var emails []models.SCIMEmail
+		if err := json.Unmarshal(value, &emails); err != nil {
+			return scim.BadRequest(scim.ErrInvalidValue, "emails must be a list")
+		}
+		if email := primaryEmail(emails); email != "" {
+			c.email = &email
+		}
+	case strings.HasPrefix(lower, "emails[") && strings.HasSuffix(lower, "].value"):
+		// The users table holds one address, whatever its type
+		s, err := scimString(path, value)
+		if err != nil {
+			return err
+		}
+		c.email = &s
+	case lower == "active":
+		active, err := scimBool(value)
+		if err != nil {
+			return err
+		}
+		c.active = &active
+	}
+	return nil
+}
+
+// scimString decodes a string value, trimmed.
+func scimString(path string, value json.RawMessage) (string, error) {
+	var s string
+	if err := json.Unmarshal(value, &s); err != nil {
+		return "", scim.BadRequest(scim.ErrInvalidValue, path+" must be a string")
+	}
+	return strings.TrimSpace(s), nil
+}
+
+// scimBool decodes a boolean, also accepting "True" and "False" strings as
+// some identity providers send them.
+func scimBool(value json.RawMessage) (bool, error) {
+	var b bool
+	if err := json.Unmarshal(value, &b); err == nil {
+		return b, nil
+	}
+	var s string
+	if err := json.Unmarshal(value, &s); err == nil {
+		if b, err := strconv.ParseBool(s); err == nil {
+			return b, nil
+		}
+	}
+	return false, scim.BadRequest(scim.ErrInvalidValue, "active must be a boolean")
+}
+
+// checkSCIMUserName applies the username rules of registration, which
+// GET /users/{id} relies on to tell usernames from IDs.
+func checkSCIMUserName(userName string) error {
+	if userName == "" {
+		return scim.BadRequest(scim.ErrInvalidValue, "userName is required")
+	}
+	if verr := validation.Username()(userName); verr != nil {
+		return scim.BadRequest(scim.ErrInvalidValue, "userName: "+verr.Message)
+	}
+	return nil
+}
+
+func checkSCIMEmail(email string) error {
+	if email == "" {
+		return scim.BadRequest(scim.ErrInvalidValue, "an email is required")
+	}
+	if validation.Email()(email) != nil {
+		return scim.BadRequest(scim.ErrInvalidValue, "invalid email "+email)
+	}
+	return nil
+}
+
+// primaryEmail returns the primary address, or the first one.
+func primaryEmail(emails []models.SCIMEmail) string {
+	for _, e := range emails {
+		if e.Primary {
+			return strings.TrimSpace(e.Value)
+		}
+	}
+	if len(emails) > 0 {
+		return strings.TrimSpace(emails[0].Value)
+	}
+	return ""
+}
+
+func sanitizeName(s string) string {
+	return sanitize.String(s, sanitize.Options{StripHTML: true})
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// parseSCIMID returns the user ID of a resource id; ids that are not
+// numbers name no user.
+func parseSCIMID(id string) (int, error) {
+	userID, err := strconv.Atoi(id)
+	if err != nil || userID < 1 {
+		return 0, errors.NewNotFoundError("User")
+	}
+	return userID, nil
+}
+
+// randomSCIMPassword returns a password nobody knows, for accounts created
+// without one.
+func randomSCIMPassword() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func toSCIMUser(u models.User) models.SCIMUser {
+	id := strconv.Itoa(u.ID)
+	user := models.SCIMUser{
+		Schemas:  []string{models.SCIMSchemaUser},
+		ID:       id,
+		UserName: u.Username,
+		Emails:   []models.SCIMEmail{{Value: u.Email, Type: "work", Primary: true}},
+		Active:   &u.IsActive,
+		Meta: &models.SCIMMeta{
+			ResourceType: "User",
+			Created:      u.CreatedAt,
+			LastModified: u.UpdatedAt,
+			Location:     SCIMUsersPath + "/" + id,
+		},
+	}
+	if u.FirstName.String != "" || u.LastName.String != "" {
+		user.Name = &models.SCIMName{
+			GivenName:  u.FirstName.String,
+			FamilyName: u.LastName.String,
+			Formatted:  strings.TrimSpace(u.FirstName.String + " " + u.LastName.String),
+		}
+	}
+	return user
+}
+
+func scimListResponse(users []models.User, total, startIndex int) models.SCIMListResponse {
+	resources := make([]models.SCIMUser, len(users))
+	for i, u := range users {
+		resources[i] = toSCIMUser(u)
+	}
+	return models.SCIMListResponse{
+		Schemas:      []string{models.SCIMSchemaListResponse},
+		TotalResults: total,
+		StartIndex:   startIndex,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	}
+}
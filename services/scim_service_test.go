@@ -0,0 +1,279 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/mocks"
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/scim"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func assertSCIMError(t *testing.T, err error, status int, scimType string) {
+	t.Helper()
+	var scimErr *scim.Error
+	if !errors.As(err, &scimErr) || scimErr.StatusCode() != status || scimErr.ScimType != scimType {
+		t.Fatalf("expected SCIM error %d %s, got %v", status, scimType, err)
+	}
+}
+
+func TestSCIMService_List(t *testing.T) {
+	var got models.UserListParams
+	repo := &mocks.MockUserRepository{
+		ListFn: func(ctx context.Context, params models.UserListParams) ([]models.User, int, error) {
+			got = params
+			return []models.User{{ID: 7, Username: "jane", Email: "jane@example.com", IsActive: true}}, 1, nil
+		},
+	}
+	svc := NewSCIMService(repo, &mocks.MockAuditService{}, nil, nil)
+	ctx := context.Background()
+
+	t.Run("filters on userName", func(t *testing.T) {
+		list, err := svc.List(ctx, models.SCIMListParams{Filter: `userName eq "Jane"`, StartIndex: 1, Count: 10})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got.Username != "Jane" || got.Page != 1 || got.PageSize != 10 {
+			t.Errorf("unexpected list params %+v", got)
+		}
+		if list.TotalResults != 1 || list.StartIndex != 1 || len(list.Resources) != 1 || list.Resources[0].ID != "7" {
+			t.Errorf("unexpected list %+v", list)
+		}
+	})
+
+	t.Run("filters on emails", func(t *testing.T) {
+		if _, err := svc.List(ctx, models.SCIMListParams{Filter: `emails.value eq "jane@example.com"`}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got.Email != "jane@example.com" || got.PageSize != scimMaxCount {
+			t.Errorf("unexpected list params %+v", got)
+		}
+	})
+
+	t.Run("maps startIndex to pages", func(t *testing.T) {
+		list, err := svc.List(ctx, models.SCIMListParams{StartIndex: 21, Count: 10})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got.Page != 3 || list.StartIndex != 21 {
+			t.Errorf("page = %d, startIndex = %d", got.Page, list.StartIndex)
+		}
+	})
+
+	t.Run("refuses other filters", func(t *testing.T) {
+		_, err := svc.List(ctx, models.SCIMListParams{Filter: `externalId eq "abc"`})
+		assertSCIMError(t, err, http.StatusBadRequest, scim.ErrInvalidFilter)
+		_, err = svc.List(ctx, models.SCIMListParams{Filter: `userName co "ja"`})
+		assertSCIMError(t, err, http.StatusBadRequest, scim.ErrInvalidFilter)
+	})
+}
+
+func TestSCIMService_Create(t *testing.T) {
+	var created struct {
+		username, email, hash, firstName, role string
+	}
+	var deactivated bool
+	var audited []string
+	repo := &mocks.MockUserRepository{
+		ExistsByUsernameOrEmailFn: func(ctx context.Context, username, email string) (bool, error) {
+			return username == "taken", nil
+		},
+		CreateFn: func(ctx context.Context, username, email, hashedPassword, firstName, lastName, role string) (models.User, error) {
+			created.username, created.email, created.hash, created.firstName, created.role = username, email, hashedPassword, firstName, role
+			return models.User{ID: 9, Username: username, Email: email, FirstName: models.NewNullString(firstName), IsActive: true}, nil
+		},
+		UpdateStatusFn: func(ctx context.Context, id int, isActive bool) (models.User, error) {
+			deactivated = !isActive
+			return models.User{ID: id, Username: created.username, Email: created.email, IsActive: isActive}, nil
+		},
+	}
+	svc := NewSCIMService(repo, &mocks.MockAuditService{
+		RecordFn: func(ctx context.Context, entry models.AuditEntry) { audited = append(audited, entry.Action) },
+	}, nil, nil)
+	ctx := context.Background()
+
+	t.Run("provisions an account", func(t *testing.T) {
+		user, err := svc.Create(ctx, models.SCIMUser{
+			UserName: "jane_doe",
+			Name:     &models.SCIMName{GivenName: "<b>Jane</b>"},
+			Emails:   []models.SCIMEmail{{Value: "other@acme.test"}, {Value: "jane@acme.test", Primary: true}},
+			Password: "Secret123!",
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if created.email != "jane@acme.test" || created.firstName != "Jane" || created.role != models.RoleUser {
+			t.Errorf("unexpected account %+v", created)
+		}
+		if bcrypt.CompareHashAndPassword([]byte(created.hash), []byte("Secret123!")) != nil {
+			t.Error("expected the given password to be hashed")
+		}
+		if user.ID != "9" || user.Meta.Location != "/scim/v2/Users/9" || !*user.Active {
+			t.Errorf("unexpected resource %+v", user)
+		}
+		if len(audited) != 1 || audited[0] != models.AuditActionSCIMUserCreate {
+			t.Errorf("audited = %v", audited)
+		}
+	})
+
+	t.Run("deactivates", func(t *testing.T) {
+		inactive := false
+		user, err := svc.Create(ctx, models.SCIMUser{UserName: "bob", Emails: []models.SCIMEmail{{Value: "bob@acme.test"}}, Active: &inactive})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if created.email != "bob@acme.test" || !deactivated || *user.Active {
+			t.Errorf("expected an inactive account for bob@acme.test, got %+v", user)
+		}
+		if bcrypt.CompareHashAndPassword([]byte(created.hash), []byte("")) == nil {
+			t.Error("expected a random password")
+		}
+	})
+
+	t.Run("refuses invalid and duplicate accounts", func(t *testing.T) {
+		_, err := svc.Create(ctx, models.SCIMUser{UserName: "bob"})
+		assertSCIMError(t, err, http.StatusBadRequest, scim.ErrInvalidValue)
+		_, err = svc.Create(ctx, models.SCIMUser{Emails: []models.SCIMEmail{{Value: "bob@acme.test"}}})
+		assertSCIMError(t, err, http.StatusBadRequest, scim.ErrInvalidValue)
+		for _, userName := range []string{"1bob", "bob@acme.test", "b"} {
+			_, err = svc.Create(ctx, models.SCIMUser{UserName: userName, Emails: []models.SCIMEmail{{Value: "bob@acme.test"}}})
+			assertSCIMError(t, err, http.StatusBadRequest, scim.ErrInvalidValue)
+		}
+		_, err = svc.Create(ctx, models.SCIMUser{UserName: "taken", Emails: []models.SCIMEmail{{Value: "t@acme.test"}}})
+		assertSCIMError(t, err, http.StatusConflict, scim.ErrUniqueness)
+	})
+}
+
+func TestSCIMService_Patch(t *testing.T) {
+	var updated models.UpdateUserRequest
+	var status *bool
+	var profile []sql.NullString
+	newRepo := func() *mocks.MockUserRepository {
+		updated, status, profile = models.UpdateUserRequest{}, nil, nil
+		user := models.User{ID: 7, Username: "jane", Email: "jane@acme.test", IsActive: true,
+			FirstName: models.NewNullString("Jane"), LastName: models.NewNullString("Doe")}
+		return &mocks.MockUserRepository{
+			GetByIDFn: func(ctx context.Context, id int) (models.User, error) {
+				if id != 7 {
+					return models.User{}, errors.NewNotFoundError("User")
+				}
+				return user, nil
+			},
+			ExistsByUsernameOrEmailFn: func(ctx context.Context, username, email string) (bool, error) {
+				return username == "taken", nil
+			},
+			UpdateFn: func(ctx context.Context, id int, req models.UpdateUserRequest) (models.User, error) {
+				updated = req
+				if req.Username != "" {
+					user.Username = req.Username
+				}
+				return user, nil
+			},
+			UpdateStatusFn: func(ctx context.Context, id int, isActive bool) (models.User, error) {
+				status = &isActive
+				user.IsActive = isActive
+				return user, nil
+			},
+			UpdateProfileFn: func(ctx context.Context, userID int, firstName, lastName, avatarURL sql.NullString) error {
+				profile = []sql.NullString{firstName, lastName}
+				return nil
+			},
+		}
+	}
+	ops := func(list ...models.SCIMPatchOperation) models.SCIMPatchRequest {
+		return models.SCIMPatchRequest{Schemas: []string{models.SCIMSchemaPatchOp}, Operations: list}
+	}
+	ctx := context.Background()
+
+	t.Run("deactivates with a string boolean", func(t *testing.T) {
+		svc := NewSCIMService(newRepo(), &mocks.MockAuditService{}, nil, nil)
+		user, err := svc.Patch(ctx, "7", ops(models.SCIMPatchOperation{Op: "Replace", Path: "active", Value: json.RawMessage(`"False"`)}))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if status == nil || *status || *user.Active {
+			t.Errorf("expected the user to be deactivated, got %+v", user)
+		}
+		if updated != (models.UpdateUserRequest{}) {
+			t.Errorf("expected no other update, got %+v", updated)
+		}
+	})
+
+	t.Run("replaces attributes of an object value", func(t *testing.T) {
+		svc := NewSCIMService(newRepo(), &mocks.MockAuditService{}, nil, nil)
+		value := json.RawMessage(`{"userName": "jdoe", "name.givenName": "Janet", "emails": [{"value": "janet@acme.test", "primary": true}], "title": "ignored"}`)
+		user, err := svc.Patch(ctx, "7", ops(models.SCIMPatchOperation{Op: "replace", Value: value}))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		want := models.UpdateUserRequest{Username: "jdoe", Email: "janet@acme.test", FirstName: "Janet"}
+		if updated != want {
+			t.Errorf("update = %+v, want %+v", updated, want)
+		}
+		if user.UserName != "jdoe" || status != nil {
+			t.Errorf("unexpected result %+v", user)
+		}
+	})
+
+	t.Run("removes a name part", func(t *testing.T) {
+		svc := NewSCIMService(newRepo(), &mocks.MockAuditService{}, nil, nil)
+		if _, err := svc.Patch(ctx, "7", ops(models.SCIMPatchOperation{Op: "remove", Path: "name.familyName"})); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(profile) != 2 || profile[0].String != "Jane" || profile[1].Valid {
+			t.Errorf("expected only the family name cleared, got %+v", profile)
+		}
+	})
+
+	t.Run("refuses invalid patches", func(t *testing.T) {
+		svc := NewSCIMService(newRepo(), &mocks.MockAuditService{}, nil, nil)
+		_, err := svc.Patch(ctx, "7", ops(models.SCIMPatchOperation{Op: "remove", Path: "userName"}))
+		assertSCIMError(t, err, http.StatusBadRequest, scim.ErrMutability)
+		_, err = svc.Patch(ctx, "7", ops(models.SCIMPatchOperation{Op: "move", Path: "userName"}))
+		assertSCIMError(t, err, http.StatusBadRequest, scim.ErrInvalidSyntax)
+		_, err = svc.Patch(ctx, "7", ops(models.SCIMPatchOperation{Op: "replace", Path: "active", Value: json.RawMessage(`"maybe"`)}))
+		assertSCIMError(t, err, http.StatusBadRequest, scim.ErrInvalidValue)
+		_, err = svc.Patch(ctx, "7", ops(models.SCIMPatchOperation{Op: "replace", Path: "userName", Value: json.RawMessage(`"42"`)}))
+		assertSCIMError(t, err, http.StatusBadRequest, scim.ErrInvalidValue)
+		_, err = svc.Patch(ctx, "7", ops(models.SCIMPatchOperation{Op: "replace", Path: "userName", Value: json.RawMessage(`"taken"`)}))
+		assertSCIMError(t, err, http.StatusConflict, scim.ErrUniqueness)
+		_, err = svc.Patch(ctx, "7", ops())
+		assertSCIMError(t, err, http.StatusBadRequest, scim.ErrInvalidSyntax)
+	})
+
+	t.Run("unknown users are not found", func(t *testing.T) {
+		svc := NewSCIMService(newRepo(), &mocks.MockAuditService{}, nil, nil)
+		op := models.SCIMPatchOperation{Op: "replace", Path: "active", Value: json.RawMessage(`false`)}
+		for _, id := range []string{"8", "abc"} {
+			if _, err := svc.Patch(ctx, id, ops(op)); !errors.Is(err, errors.ErrNotFound) {
+				t.Errorf("expected not found for %q, got %v", id, err)
+			}
+		}
+	})
+}
+
+func TestSCIMService_Delete(t *testing.T) {
+	var deleted int
+	svc := NewSCIMService(&mocks.MockUserRepository{
+		DeleteFn: func(ctx context.Context, id int) error {
+			deleted = id
+			return nil
+		},
+	}, &mocks.MockAuditService{}, nil, nil)
+
+	if err := svc.Delete(context.Background(), "7"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if deleted != 7 {
+		t.Errorf("deleted = %d, want 7", deleted)
+	}
+	if err := svc.Delete(context.Background(), "0"); !errors.Is(err, errors.ErrNotFound) {
+		t.Errorf("expected not found, got %v", err)
+	}
+}
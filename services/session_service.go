@@ -0,0 +1,179 @@
+package services
+
+import (
+	"cmp"
+	"context"
+	"net/url"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/auth"
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/logger"
+	"github.com/clementhaon/sandbox-api-go/mailer"
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/repository"
+	"github.com/clementhaon/sandbox-api-go/validation"
+)
+
+// sessionHistory is how long sessions are remembered once expired; a device
+// unused for longer is unfamiliar again.
+const sessionHistory = 90 * 24 * time.Hour
+
+// SessionService records the sessions opened by logins, alerts users to
+// logins from devices they never used, and lets them sign such a session
+// out.
+type SessionService interface {
+	// Start records the session of token, issued to user until expiresAt,
+	// from the client IP and user agent of ctx. A session from a device the
+	// user has no other session from is alerted, unless it is their first.
+	Start(ctx context.Context, user models.User, token string, expiresAt time.Time) error
+	// Revoke signs out the session of a login alert, from the token of its
+	// "this wasn't me" link. Revoking a session twice succeeds.
+	Revoke(ctx context.Context, req models.RevokeSessionRequest) error
+}
+
+// LoginAlerter tells a user of a login from a new device, with the token
+// revoking its session.
+type LoginAlerter interface {
+	SendLoginAlert(ctx context.Context, user models.User, session models.Session, revokeToken string) error
+}
+
+type sessionService struct {
+	sessionRepo repository.SessionRepository
+	blacklist   *auth.TokenBlacklist
+	alerter     LoginAlerter
+	auditSvc    AuditService
+}
+
+// NewSessionService creates the session service; a nil alerter records
+// sessions without alerting anyone.
+func NewSessionService(sessionRepo repository.SessionRepository, blacklist *auth.TokenBlacklist, alerter LoginAlerter, auditSvc AuditService) SessionService {
+	return &sessionService{sessionRepo: sessionRepo, blacklist: blacklist, alerter: alerter, auditSvc: auditSvc}
+}
+
+func (s *sessionService) Start(ctx context.Context, user models.User, token string, expiresAt time.Time) error {
+	ip, _ := ctx.Value(logger.ClientIPKey).(string)
+	userAgent, _ := ctx.Value(logger.UserAgentKey).(string)
+
+	sessions, fromDevice, err := s.sessionRepo.CountByDevice(ctx, user.ID, ip, userAgent)
+	if err != nil {
+		return err
+	}
+	session := models.Session{
+		UserID:    user.ID,
+		TokenHash: auth.TokenHash(token),
+		IPAddress: ip,
+		UserAgent: userAgent,
+		ExpiresAt: expiresAt,
+	}
+
+	alert := s.alerter != nil && sessions > 0 && fromDevice == 0
+	var revokeToken string
+	if alert {
+		if revokeToken, err = newVerificationToken(); err != nil {
+			logger.ErrorContext(ctx, "Error generating session revocation token", err)
+			return errors.NewInternalError().WithCause(err)
+		}
+		session.RevokeTokenHash = hashToken(revokeToken)
+	}
+
+	if session, err = s.sessionRepo.Create(ctx, session); err != nil {
+		return err
+	}
+	if err := s.sessionRepo.DeleteExpired(ctx, user.ID, time.Now().Add(-sessionHistory)); err != nil {
+		logger.WarnContext(ctx, "Failed to delete expired sessions", map[string]interface{}{
+			"user_id": user.ID,
+			"error":   err.Error(),
+		})
+	}
+
+	if alert {
+		logger.InfoContext(ctx, "Login from a new device", map[string]interface{}{
+			"user_id":    user.ID,
+			"session_id": session.ID,
+		})
+		// The alert is sent in the background, so a slow mail transport
+		// does not hold the login up
+		ctx := context.WithoutCancel(ctx)
+		go func() {
+			if err := s.alerter.SendLoginAlert(ctx, user, session, revokeToken); err != nil {
+				logger.ErrorContext(ctx, "Error sending login alert", err)
+			}
+		}()
+	}
+	return nil
+}
+
+func (s *sessionService) Revoke(ctx context.Context, req models.RevokeSessionRequest) error {
+	if err := validation.Struct(req); err != nil {
+		return err
+	}
+
+	session, err := s.sessionRepo.GetByRevokeTokenHash(ctx, hashToken(req.Token))
+	if errors.Is(err, errors.ErrNotFound) {
+		return errors.NewInvalidTokenError()
+	}
+	if err != nil {
+		return err
+	}
+	if session.RevokedAt != nil {
+		return nil
+	}
+
+	if err := s.blacklist.AddHash(ctx, session.TokenHash, session.ExpiresAt); err != nil {
+		return errors.NewServiceUnavailableError().WithCause(err)
+	}
+	if err := s.sessionRepo.Revoke(ctx, session.ID); err != nil {
+		return err
+	}
+
+	logger.WarnContext(ctx, "Session revoked from a login alert", map[string]interface{}{
+		"user_id":    session.UserID,
+		"session_id": session.ID,
+	})
+	s.auditSvc.Record(ctx, models.AuditEntry{
+		ActorID:    &session.UserID,
+		Action:     models.AuditActionSessionRevoke,
+		TargetType: "session",
+		TargetID:   &session.ID,
+		Metadata: map[string]interface{}{
+			"sessionIp":        session.IPAddress,
+			"sessionUserAgent": session.UserAgent,
+			"sessionCreatedAt": session.CreatedAt.UTC(),
+		},
+	})
+	return nil
+}
+
+// mailLoginAlerter emails login alerts, linking to the page at revokeURL
+// with the revocation token in its token parameter.
+type mailLoginAlerter struct {
+	mailer    mailer.Mailer
+	revokeURL string
+}
+
+func NewMailLoginAlerter(m mailer.Mailer, revokeURL string) LoginAlerter {
+	return &mailLoginAlerter{mailer: m, revokeURL: revokeURL}
+}
+
+func (a *mailLoginAlerter) SendLoginAlert(ctx context.Context, user models.User, session models.Session, revokeToken string) error {
+	link, err := url.Parse(a.revokeURL)
+	if err != nil {
+		return err
+	}
+	query := link.Query()
+	query.Set("token", revokeToken)
+	link.RawQuery = query.Encode()
+
+	msg, err := mailer.Render(user.Email, mailer.TemplateNewLogin, map[string]interface{}{
+		"Username":  user.Username,
+		"Time":      session.CreatedAt.UTC().Format("2006-01-02 15:04 MST"),
+		"IPAddress": cmp.Or(session.IPAddress, "unknown"),
+		"Device":    cmp.Or(session.UserAgent, "unknown"),
+		"RevokeURL": link.String(),
+	})
+	if err != nil {
+		return err
+	}
+	return a.mailer.Send(ctx, msg)
+}
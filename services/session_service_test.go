@@ -0,0 +1,182 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/auth"
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/kvstore"
+	"github.com/clementhaon/sandbox-api-go/logger"
+	"github.com/clementhaon/sandbox-api-go/mailer"
+	"github.com/clementhaon/sandbox-api-go/mocks"
+	"github.com/clementhaon/sandbox-api-go/models"
+)
+
+// loginAlerts is a LoginAlerter passing the revocation tokens it is asked
+// to send on a channel.
+type loginAlerts chan string
+
+func (a loginAlerts) SendLoginAlert(_ context.Context, _ models.User, _ models.Session, revokeToken string) error {
+	a <- revokeToken
+	return nil
+}
+
+// lastMail is a mailer.Mailer keeping the last message sent.
+type lastMail struct {
+	msg mailer.Message
+}
+
+func (m *lastMail) Send(_ context.Context, msg mailer.Message) error {
+	m.msg = msg
+	return nil
+}
+
+func newBlacklist(t *testing.T) *auth.TokenBlacklist {
+	store := kvstore.NewMemoryStore()
+	t.Cleanup(store.Stop)
+	return auth.NewTokenBlacklist(store)
+}
+
+func TestSessionService_Start(t *testing.T) {
+	ctx := context.WithValue(context.Background(), logger.ClientIPKey, "203.0.113.7")
+	ctx = context.WithValue(ctx, logger.UserAgentKey, "Mozilla/5.0 (X11; Linux x86_64) Firefox/130.0")
+	user := models.User{ID: 3, Username: "johndoe", Email: "john@example.com"}
+
+	tests := []struct {
+		name                 string
+		sessions, fromDevice int
+		wantAlert            bool
+	}{
+		{"first session", 0, 0, false},
+		{"known device", 4, 1, false},
+		{"new device", 4, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var created models.Session
+			repo := &mocks.MockSessionRepository{
+				CountByDeviceFn: func(ctx context.Context, userID int, ipAddress, userAgent string) (int, int, error) {
+					if userID != 3 || ipAddress != "203.0.113.7" || !strings.Contains(userAgent, "Firefox") {
+						t.Errorf("unexpected device lookup %d %q %q", userID, ipAddress, userAgent)
+					}
+					return tt.sessions, tt.fromDevice, nil
+				},
+				CreateFn: func(ctx context.Context, session models.Session) (models.Session, error) {
+					created = session
+					session.ID = 11
+					return session, nil
+				},
+				DeleteExpiredFn: func(ctx context.Context, userID int, before time.Time) error {
+					if time.Since(before) < 30*24*time.Hour {
+						t.Errorf("expected sessions kept for months, purged before %v", before)
+					}
+					return nil
+				},
+			}
+			alerts := make(loginAlerts, 1)
+			svc := NewSessionService(repo, newBlacklist(t), alerts, &mocks.MockAuditService{})
+
+			if err := svc.Start(ctx, user, "jwt", time.Now().Add(time.Hour)); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if created.TokenHash != auth.TokenHash("jwt") || created.IPAddress != "203.0.113.7" {
+				t.Errorf("unexpected session %+v", created)
+			}
+
+			if !tt.wantAlert {
+				if created.RevokeTokenHash != "" {
+					t.Error("expected no revocation token")
+				}
+				select {
+				case <-alerts:
+					t.Error("expected no alert")
+				case <-time.After(50 * time.Millisecond):
+				}
+				return
+			}
+			select {
+			case token := <-alerts:
+				if hashToken(token) != created.RevokeTokenHash {
+					t.Error("expected the hash of the alerted token to be stored")
+				}
+			case <-time.After(time.Second):
+				t.Fatal("expected an alert")
+			}
+		})
+	}
+}
+
+func TestSessionService_Revoke(t *testing.T) {
+	ctx := context.Background()
+	session := models.Session{ID: 11, UserID: 3, TokenHash: auth.TokenHash("jwt"), ExpiresAt: time.Now().Add(time.Hour)}
+	revoked := 0
+	repo := &mocks.MockSessionRepository{
+		GetByRevokeTokenHashFn: func(ctx context.Context, hash string) (models.Session, error) {
+			if hash != hashToken("link-token") {
+				return models.Session{}, errors.NewNotFoundError("Session")
+			}
+			return session, nil
+		},
+		RevokeFn: func(ctx context.Context, id int) error {
+			revoked++
+			return nil
+		},
+	}
+	var recorded []models.AuditEntry
+	auditSvc := &mocks.MockAuditService{
+		RecordFn: func(ctx context.Context, entry models.AuditEntry) {
+			recorded = append(recorded, entry)
+		},
+	}
+	blacklist := newBlacklist(t)
+	svc := NewSessionService(repo, blacklist, nil, auditSvc)
+
+	err := svc.Revoke(ctx, models.RevokeSessionRequest{Token: "other"})
+	if !errors.Is(err, errors.ErrInvalidToken) {
+		t.Fatalf("expected INVALID_TOKEN, got %v", err)
+	}
+
+	if err := svc.Revoke(ctx, models.RevokeSessionRequest{Token: "link-token"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if isRevoked, _ := blacklist.IsBlacklisted(ctx, "jwt"); !isRevoked {
+		t.Error("expected the session token to be revoked")
+	}
+	if revoked != 1 || len(recorded) != 1 || recorded[0].Action != models.AuditActionSessionRevoke {
+		t.Errorf("expected the session marked revoked and audited, got %d revocations, audit %+v", revoked, recorded)
+	}
+
+	// A second click finds the session revoked already
+	now := time.Now()
+	session.RevokedAt = &now
+	if err := svc.Revoke(ctx, models.RevokeSessionRequest{Token: "link-token"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if revoked != 1 {
+		t.Error("expected a revoked session to be left alone")
+	}
+}
+
+func TestMailLoginAlerter(t *testing.T) {
+	m := &lastMail{}
+	alerter := NewMailLoginAlerter(m, "https://app.example.com/sessions/revoke?lang=fr")
+
+	err := alerter.SendLoginAlert(context.Background(), models.User{Username: "johndoe", Email: "john@example.com"},
+		models.Session{IPAddress: "203.0.113.7", CreatedAt: time.Now()}, "abc123")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sent := m.msg
+	if sent.To != "john@example.com" {
+		t.Errorf("unexpected recipient %q", sent.To)
+	}
+	if !strings.Contains(sent.Text, "https://app.example.com/sessions/revoke?lang=fr&token=abc123") {
+		t.Errorf("expected the revocation link in the email, got %q", sent.Text)
+	}
+	if !strings.Contains(sent.Text, "203.0.113.7") || !strings.Contains(sent.Text, "unknown") {
+		t.Errorf("expected the device details in the email, got %q", sent.Text)
+	}
+}
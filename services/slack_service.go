@@ -0,0 +1,285 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/kvstore"
+	"github.com/clementhaon/sandbox-api-go/logger"
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/repository"
+	"github.com/clementhaon/sandbox-api-go/slack"
+	"github.com/clementhaon/sandbox-api-go/tenant"
+)
+
+const (
+	// SlackLinkCodeTTL is how long a link code can be sent from Slack.
+	SlackLinkCodeTTL = 10 * time.Minute
+	// slackListLimit caps the tasks shown by "/tasks list".
+	slackListLimit = 10
+	// slackCodeAlphabet leaves out letters and digits easily confused.
+	slackCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	slackCodeLength   = 8
+)
+
+// SlackService links Slack users to accounts and runs the slash commands
+// they send.
+type SlackService interface {
+	// CreateLinkCode returns a one-time code which, sent from Slack with
+	// "/tasks link <code>", links that Slack user to userID.
+	CreateLinkCode(ctx context.Context, userID int) (models.SlackLinkCodeResponse, error)
+	ListLinks(ctx context.Context, userID int) ([]models.SlackLink, error)
+	// Unlink removes every Slack user linked to userID.
+	Unlink(ctx context.Context, userID int) error
+	// HandleCommand runs a slash command. Mistakes of the user are answered
+	// with a message; only failures of the API are returned as errors.
+	HandleCommand(ctx context.Context, cmd slack.Command) (slack.Message, error)
+}
+
+type slackService struct {
+	linkRepo   repository.SlackLinkRepository
+	columnRepo repository.ColumnRepository
+	taskSvc    TaskService
+	codes      kvstore.Store
+	auditSvc   AuditService
+}
+
+func NewSlackService(linkRepo repository.SlackLinkRepository, columnRepo repository.ColumnRepository, taskSvc TaskService, codes kvstore.Store, auditSvc AuditService) SlackService {
+	return &slackService{linkRepo: linkRepo, columnRepo: columnRepo, taskSvc: taskSvc, codes: codes, auditSvc: auditSvc}
+}
+
+// slackLinkCode is what a link code stands for in the store.
+type slackLinkCode struct {
+	UserID   int `json:"userId"`
+	TenantID int `json:"tenantId"`
+}
+
+func (s *slackService) CreateLinkCode(ctx context.Context, userID int) (models.SlackLinkCodeResponse, error) {
+	b := make([]byte, slackCodeLength)
+	if _, err := rand.Read(b); err != nil {
+		return models.SlackLinkCodeResponse{}, errors.NewInternalError().WithCause(err)
+	}
+	for i := range b {
+		b[i] = slackCodeAlphabet[int(b[i])%len(slackCodeAlphabet)]
+	}
+	code := string(b)
+
+	data, err := json.Marshal(slackLinkCode{UserID: userID, TenantID: tenant.FromContext(ctx)})
+	if err != nil {
+		return models.SlackLinkCodeResponse{}, errors.NewInternalError().WithCause(err)
+	}
+	if err := s.codes.Set(ctx, slackCodeKey(code), data, SlackLinkCodeTTL); err != nil {
+		return models.SlackLinkCodeResponse{}, errors.NewServiceUnavailableError().WithCause(err)
+	}
+	return models.SlackLinkCodeResponse{
+		Code:      code,
+		Command:   "/tasks link " + code,
+		ExpiresAt: time.Now().Add(SlackLinkCodeTTL),
+	}, nil
+}
+
+func (s *slackService) ListLinks(ctx context.Context, userID int) ([]models.SlackLink, error) {
+	return s.linkRepo.ListByUser(ctx, userID)
+}
+
+func (s *slackService) Unlink(ctx context.Context, userID int) error {
+	if err := s.linkRepo.DeleteByUser(ctx, userID); err != nil {
+		return err
+	}
+	s.auditSvc.Record(ctx, models.AuditEntry{
+		Action:     models.AuditActionSlackUnlink,
+		TargetType: "user",
+		TargetID:   &userID,
+	})
+	return nil
+}
+
+func (s *slackService) HandleCommand(ctx context.Context, cmd slack.Command) (slack.Message, error) {
+	if cmd.TeamID == "" || cmd.UserID == "" {
+		return slack.Ephemeral("This command is missing the Slack team or user."), nil
+	}
+	ctx = logger.With(ctx, "slack_team_id", cmd.TeamID, "slack_user_id", cmd.UserID)
+
+	sub, rest := cmd.Args()
+	switch sub {
+	case "", "help":
+		return slack.Ephemeral(slackHelp(cmd.Command)), nil
+	case "link":
+		return s.link(ctx, cmd, rest)
+	}
+
+	link, err := s.linkRepo.Get(ctx, cmd.TeamID, cmd.UserID)
+	if errors.Is(err, errors.ErrNotFound) {
+		return slack.Ephemeral("Your Slack account is not linked yet. Create a link code in the app, then send `" +
+			slackCommandName(cmd.Command) + " link <code>`."), nil
+	}
+	if err != nil {
+		return slack.Message{}, err
+	}
+
+	// The link names the account, and so the tenant
+	ctx = tenant.WithID(ctx, link.TenantID)
+	ctx = logger.With(ctx, "tenant_id", link.TenantID, "user_id", link.UserID)
+
+	switch sub {
+	case "add":
+		return s.addTask(ctx, link.UserID, rest)
+	case "list":
+		return s.listTasks(ctx, link.UserID)
+	case "unlink":
+		if err := s.linkRepo.Delete(ctx, cmd.TeamID, cmd.UserID); err != nil {
+			return slack.Message{}, err
+		}
+		s.auditSvc.Record(ctx, models.AuditEntry{
+			ActorID:    &link.UserID,
+			Action:     models.AuditActionSlackUnlink,
+			TargetType: "user",
+			TargetID:   &link.UserID,
+			Metadata:   map[string]interface{}{"slackTeamId": cmd.TeamID, "slackUserId": cmd.UserID},
+		})
+		return slack.Ephemeral("Your Slack account is no longer linked."), nil
+	}
+	return slack.Ephemeral(fmt.Sprintf("Unknown command `%s`.\n\n%s", slack.Escape(sub), slackHelp(cmd.Command))), nil
+}
+
+// link consumes a link code, which is used once.
+func (s *slackService) link(ctx context.Context, cmd slack.Command, code string) (slack.Message, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == "" {
+		return slack.Ephemeral("Send the code created in the app: `" + slackCommandName(cmd.Command) + " link <code>`."), nil
+	}
+
+	key := slackCodeKey(code)
+	data, found, err := s.codes.Get(ctx, key)
+	if err != nil {
+		return slack.Message{}, errors.NewServiceUnavailableError().WithCause(err)
+	}
+	if !found {
+		return slack.Ephemeral("This link code is invalid or has expired. Create a new one in the app."), nil
+	}
+	if err := s.codes.Delete(ctx, key); err != nil {
+		return slack.Message{}, errors.NewServiceUnavailableError().WithCause(err)
+	}
+	var grant slackLinkCode
+	if err := json.Unmarshal(data, &grant); err != nil {
+		return slack.Message{}, errors.NewInternalError().WithCause(err)
+	}
+
+	ctx = tenant.WithID(ctx, grant.TenantID)
+	if _, err := s.linkRepo.Link(ctx, cmd.TeamID, cmd.UserID, grant.UserID); err != nil {
+		return slack.Message{}, err
+	}
+
+	logger.InfoContext(ctx, "Slack user linked", map[string]interface{}{
+		"user_id": grant.UserID,
+	})
+	s.auditSvc.Record(ctx, models.AuditEntry{
+		ActorID:    &grant.UserID,
+		Action:     models.AuditActionSlackLink,
+		TargetType: "user",
+		TargetID:   &grant.UserID,
+		Metadata:   map[string]interface{}{"slackTeamId": cmd.TeamID, "slackUserId": cmd.UserID},
+	})
+	return slack.Ephemeral(":white_check_mark: Your Slack account is linked. Try `" + slackCommandName(cmd.Command) + " list`."), nil
+}
+
+func (s *slackService) addTask(ctx context.Context, userID int, title string) (slack.Message, error) {
+	if title == "" {
+		return slack.Ephemeral("Give the task a title."), nil
+	}
+
+	columns, err := s.columnRepo.List(ctx)
+	if err != nil {
+		return slack.Message{}, err
+	}
+	if len(columns) == 0 {
+		return slack.Ephemeral("The board has no column to create the task in."), nil
+	}
+
+	task, err := s.taskSvc.Create(ctx, userID, models.CreateTaskRequest{Title: title, ColumnID: columns[0].ID})
+	if err != nil {
+		return slackUserError(err)
+	}
+	return slack.Ephemeral(fmt.Sprintf(":white_check_mark: Created task #%d *%s* in %s.",
+		task.ID, slack.Escape(task.Title), slack.Escape(columns[0].Title))), nil
+}
+
+// listTasks lists the open tasks the user owns or is assigned.
+func (s *slackService) listTasks(ctx context.Context, userID int) (slack.Message, error) {
+	tasks, err := s.taskSvc.List(ctx, models.TaskListParams{
+		Statuses: []string{models.TaskStatusTodo, models.TaskStatusInProgress, models.TaskStatusBlocked},
+	})
+	if err != nil {
+		return slack.Message{}, err
+	}
+
+	var lines []string
+	mine := 0
+	for _, t := range tasks {
+		if t.UserID != userID && (t.AssigneeID == nil || *t.AssigneeID != userID) {
+			continue
+		}
+		mine++
+		if len(lines) < slackListLimit {
+			lines = append(lines, slackTaskLine(t))
+		}
+	}
+	if mine == 0 {
+		return slack.Ephemeral("You have no open tasks."), nil
+	}
+
+	text := fmt.Sprintf("*Your open tasks* (%d)\n%s", mine, strings.Join(lines, "\n"))
+	if more := mine - len(lines); more > 0 {
+		text += fmt.Sprintf("\n…and %d more in the app.", more)
+	}
+	return slack.Ephemeral(text), nil
+}
+
+func slackTaskLine(t models.Task) string {
+	line := fmt.Sprintf("• #%d *%s* · %s", t.ID, slack.Escape(t.Title), strings.ReplaceAll(t.Status, "_", " "))
+	if t.Priority != "" {
+		line += " · " + t.Priority
+	}
+	if t.Deadline != nil {
+		line += " · due " + t.Deadline.Format(time.DateOnly)
+	}
+	return line
+}
+
+// slackUserError answers client errors, such as a rejected title or a
+// reached quota, with a message, and returns the others.
+func slackUserError(err error) (slack.Message, error) {
+	var appErr *errors.AppError
+	if errors.As(err, &appErr) && appErr.StatusCode < 500 {
+		return slack.Ephemeral(":warning: " + slack.Escape(appErr.Message)), nil
+	}
+	return slack.Message{}, err
+}
+
+func slackCommandName(command string) string {
+	if command == "" {
+		return "/tasks"
+	}
+	return command
+}
+
+func slackHelp(command string) string {
+	name := slackCommandName(command)
+	return fmt.Sprintf("*%[1]s* commands:\n"+
+		"• `%[1]s add <title>` creates a task\n"+
+		"• `%[1]s list` lists your open tasks\n"+
+		"• `%[1]s link <code>` links your Slack account, with a code created in the app\n"+
+		"• `%[1]s unlink` unlinks it", name)
+}
+
+func slackCodeKey(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return "slack_link:" + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,136 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/kvstore"
+	"github.com/clementhaon/sandbox-api-go/mocks"
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/slack"
+	"github.com/clementhaon/sandbox-api-go/tenant"
+)
+
+func newSlackTestService(t *testing.T, links *mocks.MockSlackLinkRepository, taskSvc *mocks.MockTaskService) SlackService {
+	t.Helper()
+	codes := kvstore.NewMemoryStore()
+	t.Cleanup(codes.Stop)
+	columns := &mocks.MockColumnRepository{
+		ListFn: func(ctx context.Context) ([]models.Column, error) {
+			return []models.Column{{ID: 4, Title: "To do"}, {ID: 5, Title: "Done"}}, nil
+		},
+	}
+	return NewSlackService(links, columns, taskSvc, codes, &mocks.MockAuditService{})
+}
+
+func slackCommand(text string) slack.Command {
+	return slack.Command{TeamID: "T1", UserID: "U1", Command: "/tasks", Text: text}
+}
+
+func TestSlackService_Link(t *testing.T) {
+	var linkedUser, linkedTenant int
+	links := &mocks.MockSlackLinkRepository{
+		LinkFn: func(ctx context.Context, teamID, slackUserID string, userID int) (models.SlackLink, error) {
+			if teamID != "T1" || slackUserID != "U1" {
+				t.Errorf("unexpected Slack user %s/%s", teamID, slackUserID)
+			}
+			linkedUser, linkedTenant = userID, tenant.FromContext(ctx)
+			return models.SlackLink{TeamID: teamID, SlackUserID: slackUserID, UserID: userID}, nil
+		},
+	}
+	svc := newSlackTestService(t, links, &mocks.MockTaskService{})
+
+	code, err := svc.CreateLinkCode(tenant.WithID(context.Background(), 2), 7)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(code.Code) != slackCodeLength || code.Command != "/tasks link "+code.Code {
+		t.Errorf("unexpected code %+v", code)
+	}
+
+	msg, err := svc.HandleCommand(context.Background(), slackCommand("link "+strings.ToLower(code.Code)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if linkedUser != 7 || linkedTenant != 2 {
+		t.Errorf("linked user %d in tenant %d, want 7 in 2", linkedUser, linkedTenant)
+	}
+	if msg.ResponseType != slack.ResponseEphemeral || !strings.Contains(msg.Text, "linked") {
+		t.Errorf("unexpected message %+v", msg)
+	}
+
+	// A code links once
+	linkedUser = 0
+	msg, err = svc.HandleCommand(context.Background(), slackCommand("link "+code.Code))
+	if err != nil || linkedUser != 0 || !strings.Contains(msg.Text, "invalid or has expired") {
+		t.Errorf("expected a used code refused, got %+v (%v)", msg, err)
+	}
+}
+
+func TestSlackService_Commands(t *testing.T) {
+	assignee := 7
+	var created models.CreateTaskRequest
+	var createdTenant int
+	links := &mocks.MockSlackLinkRepository{
+		GetFn: func(ctx context.Context, teamID, slackUserID string) (models.SlackLink, error) {
+			if slackUserID != "U1" {
+				return models.SlackLink{}, errors.NewNotFoundError("Slack link")
+			}
+			return models.SlackLink{TeamID: teamID, SlackUserID: slackUserID, UserID: 7, TenantID: 2}, nil
+		},
+	}
+	taskSvc := &mocks.MockTaskService{
+		CreateFn: func(ctx context.Context, userID int, req models.CreateTaskRequest) (models.Task, error) {
+			if len(req.Title) > 10 {
+				return models.Task{}, errors.NewBadRequestError("Title is too long")
+			}
+			created, createdTenant = req, tenant.FromContext(ctx)
+			return models.Task{ID: 12, Title: req.Title, ColumnID: req.ColumnID, UserID: userID}, nil
+		},
+		ListFn: func(ctx context.Context, params models.TaskListParams) ([]models.Task, error) {
+			return []models.Task{
+				{ID: 1, Title: "Mine <b>", Status: models.TaskStatusTodo, Priority: models.PriorityHigh, UserID: 7},
+				{ID: 2, Title: "Someone else's", Status: models.TaskStatusTodo, UserID: 8},
+				{ID: 3, Title: "Assigned", Status: models.TaskStatusInProgress, UserID: 8, AssigneeID: &assignee},
+			}, nil
+		},
+	}
+	svc := newSlackTestService(t, links, taskSvc)
+	ctx := context.Background()
+
+	msg, err := svc.HandleCommand(ctx, slackCommand("add Ship it"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if created.Title != "Ship it" || created.ColumnID != 4 || createdTenant != 2 {
+		t.Errorf("unexpected task %+v in tenant %d", created, createdTenant)
+	}
+	if !strings.Contains(msg.Text, "#12") {
+		t.Errorf("unexpected message %q", msg.Text)
+	}
+
+	msg, err = svc.HandleCommand(ctx, slackCommand("add A title far too long"))
+	if err != nil || !strings.Contains(msg.Text, "Title is too long") {
+		t.Errorf("expected the error answered as a message, got %+v (%v)", msg, err)
+	}
+
+	msg, err = svc.HandleCommand(ctx, slackCommand("list"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(msg.Text, "Mine &lt;b&gt;") || !strings.Contains(msg.Text, "Assigned") || strings.Contains(msg.Text, "Someone else") {
+		t.Errorf("unexpected list %q", msg.Text)
+	}
+
+	msg, err = svc.HandleCommand(ctx, slack.Command{TeamID: "T1", UserID: "U2", Text: "list"})
+	if err != nil || !strings.Contains(msg.Text, "not linked") {
+		t.Errorf("expected an unlinked user told to link, got %+v (%v)", msg, err)
+	}
+
+	msg, err = svc.HandleCommand(ctx, slackCommand("frobnicate"))
+	if err != nil || !strings.Contains(msg.Text, "Unknown command") {
+		t.Errorf("expected the help for an unknown command, got %+v (%v)", msg, err)
+	}
+}
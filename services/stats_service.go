@@ -0,0 +1,100 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/metrics"
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/repository"
+)
+
+// Signup history bounds for the admin stats
+const (
+	DefaultStatsDays = 30
+	MaxStatsDays     = 90
+)
+
+// dbPingTimeout bounds the database ping of the admin stats.
+const dbPingTimeout = 2 * time.Second
+
+// DBHealthChecker is the part of *sql.DB the stats read.
+type DBHealthChecker interface {
+	PingContext(ctx context.Context) error
+	Stats() sql.DBStats
+}
+
+type StatsService interface {
+	// Get returns the dashboard summary, with signups over the last days
+	// (today included).
+	Get(ctx context.Context, days int) (models.AdminStats, error)
+}
+
+type statsService struct {
+	userRepo repository.UserRepository
+	taskRepo repository.TaskRepository
+	db       DBHealthChecker
+}
+
+func NewStatsService(userRepo repository.UserRepository, taskRepo repository.TaskRepository, db DBHealthChecker) StatsService {
+	return &statsService{userRepo: userRepo, taskRepo: taskRepo, db: db}
+}
+
+func (s *statsService) Get(ctx context.Context, days int) (models.AdminStats, error) {
+	if days < 1 || days > MaxStatsDays {
+		days = DefaultStatsDays
+	}
+	now := time.Now()
+
+	users, err := s.userRepo.Stats(ctx, now.Add(-activeUserWindow), now.AddDate(0, 0, 1-days))
+	if err != nil {
+		return models.AdminStats{}, err
+	}
+	tasks, err := s.taskRepo.CountByStatusInTenant(ctx)
+	if err != nil {
+		return models.AdminStats{}, err
+	}
+
+	return models.AdminStats{
+		Users:       users,
+		Tasks:       tasks,
+		Requests:    requestStats(ctx),
+		Database:    s.databaseHealth(ctx),
+		GeneratedAt: now.UTC(),
+	}, nil
+}
+
+func requestStats(ctx context.Context) models.RequestStats {
+	total, clientErrors, serverErrors := metrics.FromContext(ctx).HTTPRequestCounts()
+	stats := models.RequestStats{Total: total, ClientErrors: clientErrors, ServerErrors: serverErrors}
+	if total > 0 {
+		stats.ClientErrorRate = float64(clientErrors) / float64(total)
+		stats.ServerErrorRate = float64(serverErrors) / float64(total)
+	}
+	return stats
+}
+
+// databaseHealth pings the database and reads the pool counters. A failed
+// ping is reported in the result rather than returned.
+func (s *statsService) databaseHealth(ctx context.Context) models.DatabaseHealth {
+	ctx, cancel := context.WithTimeout(ctx, dbPingTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := s.db.PingContext(ctx)
+	pool := s.db.Stats()
+
+	health := models.DatabaseHealth{
+		Up:              err == nil,
+		Latency:         time.Since(start).Round(time.Microsecond).String(),
+		OpenConnections: pool.OpenConnections,
+		InUse:           pool.InUse,
+		Idle:            pool.Idle,
+		WaitCount:       pool.WaitCount,
+	}
+	if err != nil {
+		health.Error = err.Error()
+	}
+	return health
+}
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	stderrors "errors"
+	"testing"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/mocks"
+	"github.com/clementhaon/sandbox-api-go/models"
+)
+
+type fakeDB struct {
+	pingErr error
+	stats   sql.DBStats
+}
+
+func (db fakeDB) PingContext(context.Context) error { return db.pingErr }
+func (db fakeDB) Stats() sql.DBStats                { return db.stats }
+
+func TestStatsService_Get(t *testing.T) {
+	var signupsSince time.Time
+	userRepo := &mocks.MockUserRepository{
+		StatsFn: func(ctx context.Context, activeSince, since time.Time) (models.UserStats, error) {
+			signupsSince = since
+			return models.UserStats{Total: 12, Active: 3}, nil
+		},
+	}
+	taskRepo := &mocks.MockTaskRepository{
+		CountByStatusInTenantFn: func(ctx context.Context) (map[string]int, error) {
+			return map[string]int{models.TaskStatusTodo: 4, models.TaskStatusDone: 2}, nil
+		},
+	}
+	db := fakeDB{pingErr: stderrors.New("connection refused"), stats: sql.DBStats{OpenConnections: 5, InUse: 2, Idle: 3}}
+
+	stats, err := NewStatsService(userRepo, taskRepo, db).Get(context.Background(), 7)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if days := time.Since(signupsSince).Hours() / 24; days < 5.9 || days > 6.1 {
+		t.Errorf("expected signups from 6 days ago, got %.1f days", days)
+	}
+	if stats.Users.Total != 12 || stats.Tasks[models.TaskStatusTodo] != 4 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+	if stats.Database.Up || stats.Database.Error != "connection refused" || stats.Database.OpenConnections != 5 {
+		t.Errorf("expected the failed ping to be reported, got %+v", stats.Database)
+	}
+}
+
+func TestStatsService_Get_ClampsDays(t *testing.T) {
+	var signupsSince time.Time
+	userRepo := &mocks.MockUserRepository{
+		StatsFn: func(ctx context.Context, activeSince, since time.Time) (models.UserStats, error) {
+			signupsSince = since
+			return models.UserStats{}, nil
+		},
+	}
+	taskRepo := &mocks.MockTaskRepository{
+		CountByStatusInTenantFn: func(ctx context.Context) (map[string]int, error) { return nil, nil },
+	}
+
+	if _, err := NewStatsService(userRepo, taskRepo, fakeDB{}).Get(context.Background(), 0); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if days := time.Since(signupsSince).Hours() / 24; days < DefaultStatsDays-1.1 || days > DefaultStatsDays-0.9 {
+		t.Errorf("expected the default of %d days, got %.1f", DefaultStatsDays, days+1)
+	}
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"context"
+	"slices"
+
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/repository"
+)
+
+// taskLoader fills in the related resources of a list of tasks, with one
+// query per resource whatever the length of the list.
+type taskLoader struct {
+	columnRepo    repository.ColumnRepository
+	checklistRepo repository.ChecklistRepository
+}
+
+func (l *taskLoader) load(ctx context.Context, tasks []models.Task, include []string) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+	if slices.Contains(include, models.TaskIncludeColumn) {
+		if err := l.loadColumns(ctx, tasks); err != nil {
+			return err
+		}
+	}
+	if slices.Contains(include, models.TaskIncludeChecklistItems) {
+		if err := l.loadChecklists(ctx, tasks); err != nil {
+			return err
+		}
+	}
+	// Tags are read with the tasks
+	return nil
+}
+
+// loadColumns reads the columns of the board, fewer than its tasks.
+func (l *taskLoader) loadColumns(ctx context.Context, tasks []models.Task) error {
+	columns, err := l.columnRepo.List(ctx)
+	if err != nil {
+		return err
+	}
+	byID := make(map[int]*models.Column, len(columns))
+	for i := range columns {
+		byID[columns[i].ID] = &columns[i]
+	}
+	for i := range tasks {
+		tasks[i].Column = byID[tasks[i].ColumnID]
+	}
+	return nil
+}
+
+func (l *taskLoader) loadChecklists(ctx context.Context, tasks []models.Task) error {
+	ids := make([]int, len(tasks))
+	for i, task := range tasks {
+		ids[i] = task.ID
+	}
+	items, err := l.checklistRepo.ListByTasks(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for i := range tasks {
+		tasks[i].ChecklistItems = items[tasks[i].ID]
+		if tasks[i].ChecklistItems == nil {
+			tasks[i].ChecklistItems = []models.ChecklistItem{}
+		}
+	}
+	return nil
+}
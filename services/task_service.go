@@ -2,32 +2,73 @@ package services
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/events"
 	"github.com/clementhaon/sandbox-api-go/logger"
 	"github.com/clementhaon/sandbox-api-go/models"
 	"github.com/clementhaon/sandbox-api-go/repository"
+	"github.com/clementhaon/sandbox-api-go/sanitize"
 	"github.com/clementhaon/sandbox-api-go/validation"
 )
 
 type TaskService interface {
 	GetBoard(ctx context.Context) (models.BoardResponse, error)
-	List(ctx context.Context, columnID *int) ([]models.Task, error)
+	List(ctx context.Context, params models.TaskListParams) ([]models.Task, error)
+	// Each calls fn for the tasks List returns, as the rows are read.
+	Each(ctx context.Context, params models.TaskListParams, fn func(models.Task) error) error
+	// LastModified returns when the task list last changed, for conditional
+	// requests.
+	LastModified(ctx context.Context) (models.TaskListModified, error)
+	// Changes returns tasks modified after the cursor, oldest first, for
+	// incremental sync. Deleted tasks are not reported.
+	Changes(ctx context.Context, after *models.TaskCursor, limit int) ([]models.Task, models.CursorPage, error)
 	GetByID(ctx context.Context, id int) (models.Task, error)
+	// GetByIDs returns the tasks of ids in the order asked, and the IDs
+	// that match no task. Repeated IDs are fetched once.
+	GetByIDs(ctx context.Context, ids []int) ([]models.Task, []int, error)
+	// Include embeds the related resources named by include (see
+	// models.ValidTaskIncludes) in tasks, with one query per resource.
+	Include(ctx context.Context, tasks []models.Task, include []string) error
 	Create(ctx context.Context, userID int, req models.CreateTaskRequest) (models.Task, error)
-	Update(ctx context.Context, id int, req models.UpdateTaskRequest) (models.Task, error)
+	Update(ctx context.Context, userID int, id int, req models.UpdateTaskRequest) (models.Task, error)
 	Move(ctx context.Context, id int, req models.MoveTaskRequest) (models.Task, error)
+	// SetStatus moves a task to status on behalf of userID, if the current
+	// status allows it. Setting the status a task already has returns it
+	// unchanged and publishes no event.
+	SetStatus(ctx context.Context, userID int, id int, req models.SetTaskStatusRequest) (models.Task, error)
+	// Complete moves a task to done, as SetStatus.
+	Complete(ctx context.Context, userID int, id int) (models.Task, error)
+	// Reopen moves a done task back to todo. Reopening a task that is not
+	// done returns it unchanged.
+	Reopen(ctx context.Context, id int) (models.Task, error)
 	Reorder(ctx context.Context, columnID int, taskIDs []int) ([]models.Task, error)
 	Delete(ctx context.Context, id int) error
+	// PurgeCompleted deletes completed tasks untouched for olderThan and
+	// returns how many were deleted.
+	PurgeCompleted(ctx context.Context, olderThan time.Duration) (int, error)
 }
 
 type taskService struct {
 	taskRepo   repository.TaskRepository
 	columnRepo repository.ColumnRepository
+	loader     *taskLoader
+	quotaSvc   QuotaService
+	bus        *events.Bus
 }
 
-func NewTaskService(taskRepo repository.TaskRepository, columnRepo repository.ColumnRepository) TaskService {
-	return &taskService{taskRepo: taskRepo, columnRepo: columnRepo}
+// NewTaskService returns a TaskService publishing TaskCreated and
+// TaskCompleted events on bus, which may be nil.
+func NewTaskService(taskRepo repository.TaskRepository, columnRepo repository.ColumnRepository, checklistRepo repository.ChecklistRepository, quotaSvc QuotaService, bus *events.Bus) TaskService {
+	return &taskService{
+		taskRepo:   taskRepo,
+		columnRepo: columnRepo,
+		loader:     &taskLoader{columnRepo: columnRepo, checklistRepo: checklistRepo},
+		quotaSvc:   quotaSvc,
+		bus:        bus,
+	}
 }
 
 func (s *taskService) GetBoard(ctx context.Context) (models.BoardResponse, error) {
@@ -44,15 +85,103 @@ func (s *taskService) GetBoard(ctx context.Context) (models.BoardResponse, error
 	return models.BoardResponse{Columns: columns, Tasks: tasks}, nil
 }
 
-func (s *taskService) List(ctx context.Context, columnID *int) ([]models.Task, error) {
-	return s.taskRepo.ListWithAssignee(ctx, columnID)
+func (s *taskService) List(ctx context.Context, params models.TaskListParams) ([]models.Task, error) {
+	return s.taskRepo.List(ctx, params)
+}
+
+func (s *taskService) Each(ctx context.Context, params models.TaskListParams, fn func(models.Task) error) error {
+	return s.taskRepo.Each(ctx, params, fn)
+}
+
+func (s *taskService) LastModified(ctx context.Context) (models.TaskListModified, error) {
+	return s.taskRepo.LastModified(ctx)
+}
+
+const (
+	defaultChangesLimit = 100
+	maxChangesLimit     = 500
+
+	// maxBatchIDs bounds the tasks fetched by one GetByIDs call
+	maxBatchIDs = 100
+)
+
+func (s *taskService) Changes(ctx context.Context, after *models.TaskCursor, limit int) ([]models.Task, models.CursorPage, error) {
+	if limit < 1 {
+		limit = defaultChangesLimit
+	}
+	if limit > maxChangesLimit {
+		limit = maxChangesLimit
+	}
+
+	// Fetch one extra row to know whether another page follows
+	tasks, err := s.taskRepo.ListChangedAfter(ctx, after, limit+1)
+	if err != nil {
+		return nil, models.CursorPage{}, err
+	}
+
+	page := models.CursorPage{Limit: limit}
+	if len(tasks) > limit {
+		tasks = tasks[:limit]
+		page.HasMore = true
+	}
+	// With no new changes the client keeps polling from the same position
+	if len(tasks) > 0 {
+		last := tasks[len(tasks)-1]
+		page.NextCursor = models.TaskCursor{UpdatedAt: last.UpdatedAt, ID: last.ID}.Encode()
+	} else if after != nil {
+		page.NextCursor = after.Encode()
+	}
+	return tasks, page, nil
 }
 
 func (s *taskService) GetByID(ctx context.Context, id int) (models.Task, error) {
 	return s.taskRepo.GetByID(ctx, id)
 }
 
+func (s *taskService) Include(ctx context.Context, tasks []models.Task, include []string) error {
+	return s.loader.load(ctx, tasks, include)
+}
+
+func (s *taskService) GetByIDs(ctx context.Context, ids []int) ([]models.Task, []int, error) {
+	unique := make([]int, 0, len(ids))
+	seen := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	if len(unique) == 0 {
+		return nil, nil, errors.NewBadRequestError("At least one task ID is required")
+	}
+	if len(unique) > maxBatchIDs {
+		return nil, nil, errors.NewBadRequestError(fmt.Sprintf("At most %d task IDs can be fetched at once", maxBatchIDs))
+	}
+
+	found, err := s.taskRepo.GetByIDs(ctx, unique)
+	if err != nil {
+		return nil, nil, err
+	}
+	byID := make(map[int]models.Task, len(found))
+	for _, task := range found {
+		byID[task.ID] = task
+	}
+
+	tasks := make([]models.Task, 0, len(found))
+	missing := []int{}
+	for _, id := range unique {
+		if task, ok := byID[id]; ok {
+			tasks = append(tasks, task)
+		} else {
+			missing = append(missing, id)
+		}
+	}
+	return tasks, missing, nil
+}
+
 func (s *taskService) Create(ctx context.Context, userID int, req models.CreateTaskRequest) (models.Task, error) {
+	sanitize.Struct(&req)
+
 	if err := validation.ValidateTaskInput(req.Title, req.Description); err != nil {
 		return models.Task{}, err
 	}
@@ -65,40 +194,120 @@ func (s *taskService) Create(ctx context.Context, userID int, req models.CreateT
 	if req.Tags == nil {
 		req.Tags = []string{}
 	}
+	if err := s.quotaSvc.CheckTaskQuota(ctx, userID); err != nil {
+		return models.Task{}, err
+	}
 
 	maxOrder, err := s.taskRepo.GetMaxOrder(ctx, req.ColumnID)
 	if err != nil {
 		return models.Task{}, err
 	}
 
-	task, err := s.taskRepo.Create(ctx, req, maxOrder+1, userID)
+	task, err := s.taskRepo.Create(ctx, req, maxOrder+repository.TaskOrderGap, userID)
 	if err != nil {
 		return models.Task{}, err
 	}
 
-	logger.InfoContext(ctx, "Task created", map[string]interface{}{
-		"task_id":   task.ID,
-		"column_id": task.ColumnID,
-		"user_id":   userID,
-	})
+	logger.FromContext(ctx).Info("Task created",
+		"task_id", task.ID,
+		"column_id", task.ColumnID,
+	)
+	s.bus.Publish(ctx, events.Event{Type: events.TaskCreated, ActorID: userID, Task: task})
+	if task.AssigneeID != nil {
+		s.bus.Publish(ctx, events.Event{Type: events.TaskAssigned, ActorID: userID, Task: task})
+	}
 
 	return task, nil
 }
 
-func (s *taskService) Update(ctx context.Context, id int, req models.UpdateTaskRequest) (models.Task, error) {
-	exists, err := s.taskRepo.Exists(ctx, id)
+func (s *taskService) Update(ctx context.Context, userID int, id int, req models.UpdateTaskRequest) (models.Task, error) {
+	sanitize.Struct(&req)
+
+	previous, err := s.taskRepo.GetByID(ctx, id)
 	if err != nil {
 		return models.Task{}, err
 	}
-	if !exists {
-		return models.Task{}, errors.NewNotFoundError("Task not found")
+
+	task, err := s.taskRepo.Update(ctx, id, req)
+	if err != nil {
+		return models.Task{}, err
 	}
 
-	return s.taskRepo.Update(ctx, id, req)
+	if task.AssigneeID != nil && (previous.AssigneeID == nil || *previous.AssigneeID != *task.AssigneeID) {
+		s.bus.Publish(ctx, events.Event{Type: events.TaskAssigned, ActorID: userID, Task: task})
+	}
+	return task, nil
 }
 
 func (s *taskService) Move(ctx context.Context, id int, req models.MoveTaskRequest) (models.Task, error) {
-	return s.taskRepo.Move(ctx, id, req.ColumnID, req.Order)
+	if req.AfterID != nil && req.BeforeID != nil {
+		return models.Task{}, errors.NewBadRequestError("Only one of afterId and beforeId can be set")
+	}
+	anchor, after := req.BeforeID, false
+	if req.AfterID != nil {
+		anchor, after = req.AfterID, true
+	}
+	if anchor == nil {
+		return s.taskRepo.Move(ctx, id, req.ColumnID, req.Order)
+	}
+	if *anchor == id {
+		return models.Task{}, errors.NewBadRequestError("A task cannot be moved next to itself")
+	}
+	return s.taskRepo.MoveRelative(ctx, id, req.ColumnID, *anchor, after)
+}
+
+func (s *taskService) SetStatus(ctx context.Context, userID int, id int, req models.SetTaskStatusRequest) (models.Task, error) {
+	if err := validation.Struct(req); err != nil {
+		return models.Task{}, err
+	}
+	return s.moveToStatus(ctx, userID, id, req.Status)
+}
+
+func (s *taskService) Complete(ctx context.Context, userID int, id int) (models.Task, error) {
+	return s.moveToStatus(ctx, userID, id, models.TaskStatusDone)
+}
+
+// moveToStatus moves a task to status if its transitions allow it,
+// publishing TaskCompleted when it becomes done.
+func (s *taskService) moveToStatus(ctx context.Context, userID int, id int, status string) (models.Task, error) {
+	task, changed, err := s.setStatus(ctx, id, status, models.TaskStatusSources(status))
+	if err != nil {
+		return models.Task{}, err
+	}
+	if !changed {
+		if task.Status != status {
+			return models.Task{}, errors.NewConflictError(fmt.Sprintf("A %s task cannot be moved to %s", task.Status, status))
+		}
+		return task, nil
+	}
+
+	logger.FromContext(ctx).Info("Task status changed", "task_id", task.ID, "status", task.Status)
+	if status == models.TaskStatusDone {
+		s.bus.Publish(ctx, events.Event{Type: events.TaskCompleted, ActorID: userID, Task: task})
+	}
+	return task, nil
+}
+
+func (s *taskService) Reopen(ctx context.Context, id int) (models.Task, error) {
+	task, changed, err := s.setStatus(ctx, id, models.TaskStatusTodo, []string{models.TaskStatusDone})
+	if err == nil && changed {
+		logger.FromContext(ctx).Info("Task reopened", "task_id", task.ID)
+	}
+	return task, err
+}
+
+// setStatus reports whether the task changed status; when it was not in one
+// of the from statuses, the task is returned as is.
+func (s *taskService) setStatus(ctx context.Context, id int, status string, from []string) (models.Task, bool, error) {
+	task, err := s.taskRepo.SetStatus(ctx, id, status, from)
+	if err == nil {
+		return task, true, nil
+	}
+	if !errors.Is(err, errors.ErrNotFound) {
+		return models.Task{}, false, err
+	}
+	task, err = s.taskRepo.GetByID(ctx, id)
+	return task, false, err
 }
 
 func (s *taskService) Reorder(ctx context.Context, columnID int, taskIDs []int) ([]models.Task, error) {
@@ -111,3 +320,10 @@ func (s *taskService) Reorder(ctx context.Context, columnID int, taskIDs []int)
 func (s *taskService) Delete(ctx context.Context, id int) error {
 	return s.taskRepo.Delete(ctx, id)
 }
+
+func (s *taskService) PurgeCompleted(ctx context.Context, olderThan time.Duration) (int, error) {
+	if olderThan <= 0 {
+		return 0, errors.NewBadRequestError("Purge age must be positive")
+	}
+	return s.taskRepo.DeleteCompletedBefore(ctx, time.Now().Add(-olderThan))
+}
@@ -2,16 +2,20 @@ package services
 
 import (
 	"context"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/events"
 	"github.com/clementhaon/sandbox-api-go/mocks"
 	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/repository"
 )
 
 func newTestTaskService(taskRepo *mocks.MockTaskRepository, columnRepo *mocks.MockColumnRepository) TaskService {
-	return NewTaskService(taskRepo, columnRepo)
+	return NewTaskService(taskRepo, columnRepo, &mocks.MockChecklistRepository{}, &mocks.MockQuotaService{}, nil)
 }
 
 func TestTaskService_Create_Success(t *testing.T) {
@@ -20,8 +24,8 @@ func TestTaskService_Create_Success(t *testing.T) {
 			return 2, nil
 		},
 		CreateFn: func(ctx context.Context, req models.CreateTaskRequest, order int, userID int) (models.Task, error) {
-			if order != 3 {
-				t.Errorf("expected order 3, got %d", order)
+			if order != 2+repository.TaskOrderGap {
+				t.Errorf("expected order %d, got %d", 2+repository.TaskOrderGap, order)
 			}
 			if userID != 42 {
 				t.Errorf("expected userID 42, got %d", userID)
@@ -91,14 +95,14 @@ func TestTaskService_Create_MissingColumnID(t *testing.T) {
 
 func TestTaskService_Update_NotFound(t *testing.T) {
 	taskRepo := &mocks.MockTaskRepository{
-		ExistsFn: func(ctx context.Context, id int) (bool, error) {
-			return false, nil
+		GetByIDFn: func(ctx context.Context, id int) (models.Task, error) {
+			return models.Task{}, errors.NewNotFoundError("Task not found")
 		},
 	}
 	columnRepo := &mocks.MockColumnRepository{}
 	svc := newTestTaskService(taskRepo, columnRepo)
 
-	_, err := svc.Update(context.Background(), 999, models.UpdateTaskRequest{Title: "New"})
+	_, err := svc.Update(context.Background(), 7, 999, models.UpdateTaskRequest{Title: "New"})
 	if err == nil {
 		t.Fatal("expected not found error")
 	}
@@ -113,8 +117,8 @@ func TestTaskService_Update_NotFound(t *testing.T) {
 
 func TestTaskService_Update_Success(t *testing.T) {
 	taskRepo := &mocks.MockTaskRepository{
-		ExistsFn: func(ctx context.Context, id int) (bool, error) {
-			return true, nil
+		GetByIDFn: func(ctx context.Context, id int) (models.Task, error) {
+			return models.Task{ID: id, Title: "Old"}, nil
 		},
 		UpdateFn: func(ctx context.Context, id int, req models.UpdateTaskRequest) (models.Task, error) {
 			return models.Task{ID: id, Title: req.Title}, nil
@@ -123,7 +127,7 @@ func TestTaskService_Update_Success(t *testing.T) {
 	columnRepo := &mocks.MockColumnRepository{}
 	svc := newTestTaskService(taskRepo, columnRepo)
 
-	task, err := svc.Update(context.Background(), 1, models.UpdateTaskRequest{Title: "Updated"})
+	task, err := svc.Update(context.Background(), 7, 1, models.UpdateTaskRequest{Title: "Updated"})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -166,6 +170,78 @@ func TestTaskService_GetBoard(t *testing.T) {
 	}
 }
 
+func TestTaskService_GetByIDs(t *testing.T) {
+	var queried []int
+	taskRepo := &mocks.MockTaskRepository{
+		GetByIDsFn: func(ctx context.Context, ids []int) ([]models.Task, error) {
+			queried = ids
+			return []models.Task{{ID: 3, Title: "C"}, {ID: 1, Title: "A"}}, nil
+		},
+	}
+	svc := newTestTaskService(taskRepo, &mocks.MockColumnRepository{})
+
+	tasks, missing, err := svc.GetByIDs(context.Background(), []int{1, 2, 3, 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Equal(queried, []int{1, 2, 3}) {
+		t.Errorf("expected each ID queried once, got %v", queried)
+	}
+	if len(tasks) != 2 || tasks[0].ID != 1 || tasks[1].ID != 3 {
+		t.Errorf("expected tasks in the order asked, got %+v", tasks)
+	}
+	if !slices.Equal(missing, []int{2}) {
+		t.Errorf("expected ID 2 missing, got %v", missing)
+	}
+}
+
+func TestTaskService_Include(t *testing.T) {
+	columnCalls, checklistCalls := 0, 0
+	columnRepo := &mocks.MockColumnRepository{
+		ListFn: func(ctx context.Context) ([]models.Column, error) {
+			columnCalls++
+			return []models.Column{{ID: 10, Title: "Todo"}, {ID: 20, Title: "Done"}}, nil
+		},
+	}
+	checklistRepo := &mocks.MockChecklistRepository{
+		ListByTasksFn: func(ctx context.Context, taskIDs []int) (map[int][]models.ChecklistItem, error) {
+			checklistCalls++
+			if !slices.Equal(taskIDs, []int{1, 2}) {
+				t.Errorf("expected checklists of tasks 1 and 2, got %v", taskIDs)
+			}
+			return map[int][]models.ChecklistItem{1: {{ID: 5, TaskID: 1, Title: "Step"}}}, nil
+		},
+	}
+	svc := NewTaskService(&mocks.MockTaskRepository{}, columnRepo, checklistRepo, &mocks.MockQuotaService{}, nil)
+
+	tasks := []models.Task{{ID: 1, ColumnID: 20}, {ID: 2, ColumnID: 10}}
+	err := svc.Include(context.Background(), tasks, []string{models.TaskIncludeColumn, models.TaskIncludeChecklistItems})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if columnCalls != 1 || checklistCalls != 1 {
+		t.Errorf("expected one query per resource, got %d and %d", columnCalls, checklistCalls)
+	}
+	if tasks[0].Column == nil || tasks[0].Column.Title != "Done" || tasks[1].Column == nil || tasks[1].Column.Title != "Todo" {
+		t.Errorf("expected the columns of the tasks, got %+v and %+v", tasks[0].Column, tasks[1].Column)
+	}
+	if len(tasks[0].ChecklistItems) != 1 || tasks[1].ChecklistItems == nil || len(tasks[1].ChecklistItems) != 0 {
+		t.Errorf("unexpected checklists %+v and %+v", tasks[0].ChecklistItems, tasks[1].ChecklistItems)
+	}
+}
+
+func TestTaskService_GetByIDs_TooMany(t *testing.T) {
+	svc := newTestTaskService(&mocks.MockTaskRepository{}, &mocks.MockColumnRepository{})
+
+	ids := make([]int, maxBatchIDs+1)
+	for i := range ids {
+		ids[i] = i + 1
+	}
+	if _, _, err := svc.GetByIDs(context.Background(), ids); !errors.Is(err, errors.ErrValidationFailed) {
+		t.Errorf("expected a bad request error, got %v", err)
+	}
+}
+
 func TestTaskService_Move(t *testing.T) {
 	taskRepo := &mocks.MockTaskRepository{
 		MoveFn: func(ctx context.Context, id int, columnID int, order int) (models.Task, error) {
@@ -184,6 +260,157 @@ func TestTaskService_Move(t *testing.T) {
 	}
 }
 
+// publishedEvents returns a bus and the events of type typ published on it.
+func publishedEvents(typ events.Type) (*events.Bus, *[]events.Event) {
+	bus := events.NewBus()
+	published := &[]events.Event{}
+	bus.Subscribe(typ, func(ctx context.Context, e events.Event) {
+		*published = append(*published, e)
+	})
+	return bus, published
+}
+
+func TestTaskService_Complete_PublishesEvent(t *testing.T) {
+	taskRepo := &mocks.MockTaskRepository{
+		SetStatusFn: func(ctx context.Context, id int, status string, from []string) (models.Task, error) {
+			if status != models.TaskStatusDone {
+				t.Errorf("expected the task to move to done, got %s", status)
+			}
+			return models.Task{ID: id, Title: "Ship it", UserID: 3, Status: status, Completed: true}, nil
+		},
+	}
+	bus, published := publishedEvents(events.TaskCompleted)
+	svc := NewTaskService(taskRepo, &mocks.MockColumnRepository{}, &mocks.MockChecklistRepository{}, &mocks.MockQuotaService{}, bus)
+
+	task, err := svc.Complete(context.Background(), 7, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !task.Completed {
+		t.Error("expected the task to be completed")
+	}
+	if len(*published) != 1 || (*published)[0].ActorID != 7 || (*published)[0].Task.ID != 1 {
+		t.Errorf("expected one TaskCompleted event for task 1 by user 7, got %+v", *published)
+	}
+}
+
+func TestTaskService_Complete_AlreadyCompleted(t *testing.T) {
+	taskRepo := &mocks.MockTaskRepository{
+		SetStatusFn: func(ctx context.Context, id int, status string, from []string) (models.Task, error) {
+			return models.Task{}, errors.NewNotFoundError("Task not found")
+		},
+		GetByIDFn: func(ctx context.Context, id int) (models.Task, error) {
+			return models.Task{ID: id, UserID: 3, Status: models.TaskStatusDone, Completed: true}, nil
+		},
+	}
+	bus, published := publishedEvents(events.TaskCompleted)
+	svc := NewTaskService(taskRepo, &mocks.MockColumnRepository{}, &mocks.MockChecklistRepository{}, &mocks.MockQuotaService{}, bus)
+
+	task, err := svc.Complete(context.Background(), 7, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if task.ID != 1 || !task.Completed {
+		t.Errorf("expected completed task 1, got %+v", task)
+	}
+	if len(*published) != 0 {
+		t.Errorf("expected no event, got %+v", *published)
+	}
+}
+
+func TestTaskService_Reopen_NotFound(t *testing.T) {
+	notFound := errors.NewNotFoundError("Task not found")
+	taskRepo := &mocks.MockTaskRepository{
+		SetStatusFn: func(ctx context.Context, id int, status string, from []string) (models.Task, error) {
+			return models.Task{}, notFound
+		},
+		GetByIDFn: func(ctx context.Context, id int) (models.Task, error) {
+			return models.Task{}, notFound
+		},
+	}
+	svc := newTestTaskService(taskRepo, &mocks.MockColumnRepository{})
+
+	if _, err := svc.Reopen(context.Background(), 1); !errors.Is(err, errors.ErrNotFound) {
+		t.Errorf("expected a not found error, got %v", err)
+	}
+}
+
+func TestTaskService_SetStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		current  string
+		status   string
+		wantCode errors.ErrorCode
+	}{
+		{"start", models.TaskStatusTodo, models.TaskStatusInProgress, ""},
+		{"unchanged", models.TaskStatusBlocked, models.TaskStatusBlocked, ""},
+		{"blocked to done", models.TaskStatusBlocked, models.TaskStatusDone, errors.ErrConflict},
+		{"unknown status", models.TaskStatusTodo, "archived", errors.ErrValidationFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			taskRepo := &mocks.MockTaskRepository{
+				SetStatusFn: func(ctx context.Context, id int, status string, from []string) (models.Task, error) {
+					if !slices.Contains(from, tt.current) {
+						return models.Task{}, errors.NewNotFoundError("Task not found")
+					}
+					return models.Task{ID: id, Status: status}, nil
+				},
+				GetByIDFn: func(ctx context.Context, id int) (models.Task, error) {
+					return models.Task{ID: id, Status: tt.current}, nil
+				},
+			}
+			svc := newTestTaskService(taskRepo, &mocks.MockColumnRepository{})
+
+			task, err := svc.SetStatus(context.Background(), 7, 1, models.SetTaskStatusRequest{Status: tt.status})
+			if tt.wantCode != "" {
+				appErr, ok := errors.IsAppError(err)
+				if !ok || appErr.Code != tt.wantCode {
+					t.Fatalf("expected %s, got %v", tt.wantCode, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if task.Status != tt.status {
+				t.Errorf("expected status %s, got %s", tt.status, task.Status)
+			}
+		})
+	}
+}
+
+func TestTaskService_Move_AfterTask(t *testing.T) {
+	taskRepo := &mocks.MockTaskRepository{
+		MoveRelativeFn: func(ctx context.Context, id int, columnID int, anchorID int, after bool) (models.Task, error) {
+			if anchorID != 4 || !after {
+				t.Errorf("expected a move after task 4, got anchor %d after %v", anchorID, after)
+			}
+			return models.Task{ID: id, ColumnID: columnID}, nil
+		},
+	}
+	svc := newTestTaskService(taskRepo, &mocks.MockColumnRepository{})
+
+	anchor := 4
+	if _, err := svc.Move(context.Background(), 1, models.MoveTaskRequest{ColumnID: 2, AfterID: &anchor}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestTaskService_Move_InvalidAnchor(t *testing.T) {
+	svc := newTestTaskService(&mocks.MockTaskRepository{}, &mocks.MockColumnRepository{})
+	self, other := 1, 2
+
+	for name, req := range map[string]models.MoveTaskRequest{
+		"both anchors": {ColumnID: 2, AfterID: &other, BeforeID: &other},
+		"itself":       {ColumnID: 2, BeforeID: &self},
+	} {
+		if _, err := svc.Move(context.Background(), 1, req); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
 func TestTaskService_Reorder(t *testing.T) {
 	reorderCalled := false
 	taskRepo := &mocks.MockTaskRepository{
@@ -230,6 +457,32 @@ func TestTaskService_Delete(t *testing.T) {
 	}
 }
 
+func TestTaskService_PurgeCompleted(t *testing.T) {
+	var cutoff time.Time
+	taskRepo := &mocks.MockTaskRepository{
+		DeleteCompletedBeforeFn: func(ctx context.Context, before time.Time) (int, error) {
+			cutoff = before
+			return 3, nil
+		},
+	}
+	svc := newTestTaskService(taskRepo, &mocks.MockColumnRepository{})
+
+	n, err := svc.PurgeCompleted(context.Background(), 30*24*time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n != 3 {
+		t.Errorf("expected 3 purged tasks, got %d", n)
+	}
+	if age := time.Since(cutoff); age < 30*24*time.Hour || age > 30*24*time.Hour+time.Minute {
+		t.Errorf("expected a cutoff 30 days ago, got %s", cutoff)
+	}
+
+	if _, err := svc.PurgeCompleted(context.Background(), 0); err == nil {
+		t.Error("expected an error for a zero age")
+	}
+}
+
 func TestTaskService_Create_DescriptionTooLong(t *testing.T) {
 	taskRepo := &mocks.MockTaskRepository{}
 	columnRepo := &mocks.MockColumnRepository{}
@@ -238,7 +491,7 @@ func TestTaskService_Create_DescriptionTooLong(t *testing.T) {
 	_, err := svc.Create(context.Background(), 1, models.CreateTaskRequest{
 		Title:       "Valid",
 		ColumnID:    1,
-		Description: string(make([]byte, 1001)),
+		Description: strings.Repeat("a", 1001),
 	})
 	if err == nil {
 		t.Fatal("expected validation error for description too long")
@@ -310,3 +563,98 @@ func TestTaskService_Create_WithDeadline(t *testing.T) {
 		t.Fatal("expected deadline to be set")
 	}
 }
+
+func TestTaskService_Changes_Pages(t *testing.T) {
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	var gotAfter *models.TaskCursor
+	var gotLimit int
+	taskRepo := &mocks.MockTaskRepository{
+		ListChangedAfterFn: func(ctx context.Context, after *models.TaskCursor, limit int) ([]models.Task, error) {
+			gotAfter, gotLimit = after, limit
+			return []models.Task{
+				{ID: 1, UpdatedAt: base},
+				{ID: 2, UpdatedAt: base.Add(time.Minute)},
+				{ID: 3, UpdatedAt: base.Add(2 * time.Minute)},
+			}, nil
+		},
+	}
+	svc := newTestTaskService(taskRepo, &mocks.MockColumnRepository{})
+
+	tasks, page, err := svc.Changes(context.Background(), nil, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gotAfter != nil || gotLimit != 3 {
+		t.Errorf("expected first page with limit+1, got after=%v limit=%d", gotAfter, gotLimit)
+	}
+	if len(tasks) != 2 || !page.HasMore {
+		t.Fatalf("expected 2 tasks and more pages, got %d hasMore=%v", len(tasks), page.HasMore)
+	}
+	next, err := models.DecodeTaskCursor(page.NextCursor)
+	if err != nil {
+		t.Fatalf("invalid next cursor: %v", err)
+	}
+	if next.ID != 2 || !next.UpdatedAt.Equal(base.Add(time.Minute)) {
+		t.Errorf("expected cursor at task 2, got %+v", next)
+	}
+}
+
+func TestTaskService_Changes_NoNewChangesKeepsCursor(t *testing.T) {
+	taskRepo := &mocks.MockTaskRepository{
+		ListChangedAfterFn: func(ctx context.Context, after *models.TaskCursor, limit int) ([]models.Task, error) {
+			return []models.Task{}, nil
+		},
+	}
+	svc := newTestTaskService(taskRepo, &mocks.MockColumnRepository{})
+
+	after := models.TaskCursor{UpdatedAt: time.Now().UTC(), ID: 9}
+	tasks, page, err := svc.Changes(context.Background(), &after, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(tasks) != 0 || page.HasMore {
+		t.Errorf("expected empty final page, got %d hasMore=%v", len(tasks), page.HasMore)
+	}
+	if page.NextCursor != after.Encode() {
+		t.Error("expected the cursor to be returned unchanged")
+	}
+	if page.Limit != defaultChangesLimit {
+		t.Errorf("expected default limit %d, got %d", defaultChangesLimit, page.Limit)
+	}
+}
+
+func TestTaskService_Update_PublishesAssignment(t *testing.T) {
+	one, two := 1, 2
+	tests := []struct {
+		name     string
+		previous *int
+		assignee *int
+		want     int
+	}{
+		{"newly assigned", nil, &one, 1},
+		{"reassigned", &one, &two, 1},
+		{"same assignee", &one, &one, 0},
+		{"unassigned", &one, nil, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			taskRepo := &mocks.MockTaskRepository{
+				GetByIDFn: func(ctx context.Context, id int) (models.Task, error) {
+					return models.Task{ID: id, AssigneeID: tt.previous}, nil
+				},
+				UpdateFn: func(ctx context.Context, id int, req models.UpdateTaskRequest) (models.Task, error) {
+					return models.Task{ID: id, AssigneeID: req.AssigneeID}, nil
+				},
+			}
+			bus, published := publishedEvents(events.TaskAssigned)
+			svc := NewTaskService(taskRepo, &mocks.MockColumnRepository{}, &mocks.MockChecklistRepository{}, &mocks.MockQuotaService{}, bus)
+
+			if _, err := svc.Update(context.Background(), 7, 1, models.UpdateTaskRequest{AssigneeID: tt.assignee}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(*published) != tt.want {
+				t.Errorf("expected %d TaskAssigned events, got %d", tt.want, len(*published))
+			}
+		})
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/clementhaon/sandbox-api-go/logger"
 	"github.com/clementhaon/sandbox-api-go/models"
 	"github.com/clementhaon/sandbox-api-go/repository"
+	"github.com/clementhaon/sandbox-api-go/validation"
 )
 
 type TimeEntryService interface {
@@ -30,14 +31,8 @@ func (s *timeEntryService) List(ctx context.Context, taskID int) ([]models.TimeE
 }
 
 func (s *timeEntryService) Create(ctx context.Context, userID int, req models.CreateTimeEntryRequest) (models.TimeEntry, error) {
-	if req.TaskID == 0 {
-		return models.TimeEntry{}, errors.NewBadRequestError("taskId is required")
-	}
-	if req.StartTime.IsZero() {
-		return models.TimeEntry{}, errors.NewBadRequestError("startTime is required")
-	}
-	if req.Duration <= 0 {
-		return models.TimeEntry{}, errors.NewBadRequestError("duration must be positive")
+	if err := validation.Struct(req); err != nil {
+		return models.TimeEntry{}, err
 	}
 
 	exists, err := s.timeEntryRepo.TaskExists(ctx, req.TaskID)
@@ -65,12 +60,11 @@ func (s *timeEntryService) Create(ctx context.Context, userID int, req models.Cr
 		return models.TimeEntry{}, err
 	}
 
-	logger.InfoContext(ctx, "Time entry created", map[string]interface{}{
-		"entry_id": entry.ID,
-		"task_id":  entry.TaskID,
-		"duration": entry.Duration,
-		"user_id":  userID,
-	})
+	logger.FromContext(ctx).Info("Time entry created",
+		"entry_id", entry.ID,
+		"task_id", entry.TaskID,
+		"duration", entry.Duration,
+	)
 
 	return entry, nil
 }
@@ -0,0 +1,78 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/repository"
+)
+
+// Days of usage GET /usage reports
+const (
+	DefaultUsageDays = 7
+	MaxUsageDays     = 90
+)
+
+// UsageService counts the API requests of users and the bytes they
+// transfer, by UTC day and key, and reports them.
+type UsageService interface {
+	// Record counts a request of the user made with personal token
+	// tokenID, 0 for a login session
+	Record(ctx context.Context, userID, tokenID int, counts models.UsageCounts) error
+	// Get reports the usage of the user today and over the last days,
+	// today included
+	Get(ctx context.Context, userID, days int) (models.UsageReport, error)
+}
+
+type usageService struct {
+	usageRepo repository.UsageRepository
+	quotaSvc  QuotaService
+}
+
+func NewUsageService(usageRepo repository.UsageRepository, quotaSvc QuotaService) UsageService {
+	return &usageService{usageRepo: usageRepo, quotaSvc: quotaSvc}
+}
+
+func (s *usageService) Record(ctx context.Context, userID, tokenID int, counts models.UsageCounts) error {
+	return s.usageRepo.Add(ctx, userID, tokenID, time.Now(), counts)
+}
+
+func (s *usageService) Get(ctx context.Context, userID, days int) (models.UsageReport, error) {
+	if days < 1 || days > MaxUsageDays {
+		days = DefaultUsageDays
+	}
+	now := time.Now().UTC()
+
+	quota, err := s.quotaSvc.Get(ctx, userID)
+	if err != nil {
+		return models.UsageReport{}, err
+	}
+	history, err := s.usageRepo.ListSince(ctx, userID, now.AddDate(0, 0, 1-days))
+	if err != nil {
+		return models.UsageReport{}, err
+	}
+
+	report := models.UsageReport{
+		MaxDailyRequests: quota.Limits.MaxDailyRequests,
+		MaxDailyBytes:    quota.Limits.MaxDailyBytes,
+		ResetsAt:         nextUsageDay(now),
+		History:          history,
+	}
+	today := now.Format(time.DateOnly)
+	for _, u := range history {
+		if u.Day == today {
+			report.Today.Requests += u.Requests
+			report.Today.BytesIn += u.BytesIn
+			report.Today.BytesOut += u.BytesOut
+		}
+	}
+	return report, nil
+}
+
+// nextUsageDay returns when the UTC day after t starts, and with it new
+// daily quotas.
+func nextUsageDay(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d+1, 0, 0, 0, 0, time.UTC)
+}
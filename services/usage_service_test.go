@@ -0,0 +1,48 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/mocks"
+	"github.com/clementhaon/sandbox-api-go/models"
+)
+
+func TestUsageService_Get(t *testing.T) {
+	today := time.Now().UTC().Format(time.DateOnly)
+	var since time.Time
+	usageRepo := &mocks.MockUsageRepository{
+		ListSinceFn: func(ctx context.Context, userID int, s time.Time) ([]models.UsageRecord, error) {
+			since = s
+			return []models.UsageRecord{
+				{Day: today, UsageCounts: models.UsageCounts{Requests: 3, BytesIn: 10, BytesOut: 20}},
+				{Day: today, TokenID: 5, TokenName: "CI", UsageCounts: models.UsageCounts{Requests: 2, BytesOut: 5}},
+				{Day: "2000-01-01", UsageCounts: models.UsageCounts{Requests: 40}},
+			}, nil
+		},
+	}
+	quotaSvc := &mocks.MockQuotaService{
+		GetFn: func(ctx context.Context, userID int) (models.UserQuota, error) {
+			return models.UserQuota{Limits: models.QuotaLimits{MaxDailyRequests: 100}}, nil
+		},
+	}
+	svc := NewUsageService(usageRepo, quotaSvc)
+
+	report, err := svc.Get(context.Background(), 3, 7)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := (models.UsageCounts{Requests: 5, BytesIn: 10, BytesOut: 25}); report.Today != want {
+		t.Errorf("expected today's usage %+v, got %+v", want, report.Today)
+	}
+	if report.MaxDailyRequests != 100 || len(report.History) != 3 {
+		t.Errorf("expected the limits and history in the report, got %+v", report)
+	}
+	if got := since.Format(time.DateOnly); got != time.Now().UTC().AddDate(0, 0, -6).Format(time.DateOnly) {
+		t.Errorf("expected the history of the last 7 days, got since %s", got)
+	}
+	if !report.ResetsAt.After(time.Now()) || report.ResetsAt.Sub(time.Now()) > 24*time.Hour {
+		t.Errorf("expected the quotas to reset within a day, got %v", report.ResetsAt)
+	}
+}
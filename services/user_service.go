@@ -2,30 +2,51 @@ package services
 
 import (
 	"context"
+	"fmt"
+	"strings"
 
+	"github.com/clementhaon/sandbox-api-go/auth"
 	"github.com/clementhaon/sandbox-api-go/errors"
-	"github.com/clementhaon/sandbox-api-go/logger"
 	"github.com/clementhaon/sandbox-api-go/models"
 	"github.com/clementhaon/sandbox-api-go/repository"
-
-	"golang.org/x/crypto/bcrypt"
+	"github.com/clementhaon/sandbox-api-go/sanitize"
+	"github.com/clementhaon/sandbox-api-go/validation"
 )
 
 type UserService interface {
 	List(ctx context.Context, params models.UserListParams) (models.UsersListResponse, error)
+	// Each calls fn for every user matching params, across all pages, as
+	// the rows are read.
+	Each(ctx context.Context, params models.UserListParams, fn func(models.UserResponse) error) error
 	GetByID(ctx context.Context, id int) (models.UserResponse, error)
 	Create(ctx context.Context, req models.CreateUserRequest) (models.UserResponse, error)
 	Update(ctx context.Context, id int, req models.UpdateUserRequest) (models.UserResponse, error)
 	UpdateStatus(ctx context.Context, id int, status string) (models.UserResponse, error)
 	Delete(ctx context.Context, id int) error
+	Search(ctx context.Context, query string, limit int) ([]models.PublicProfile, error)
+	GetPublicProfile(ctx context.Context, username string) (models.PublicProfile, error)
 }
 
+const (
+	// defaultSearchLimit and maxSearchLimit bound user search results
+	defaultSearchLimit = 10
+	maxSearchLimit     = 50
+	maxSearchQueryLen  = 30
+)
+
 type userService struct {
-	userRepo repository.UserRepository
+	userRepo  repository.UserRepository
+	auditSvc  AuditService
+	policy    validation.PasswordPolicy
+	hasher    *auth.PasswordHasher
+	passwords *PasswordScreen
 }
 
-func NewUserService(userRepo repository.UserRepository) UserService {
-	return &userService{userRepo: userRepo}
+// NewUserService creates the user service, holding the passwords of the
+// accounts admins create to policy and hashing them with hasher; a nil
+// passwords screen accepts every password the policy does.
+func NewUserService(userRepo repository.UserRepository, auditSvc AuditService, policy validation.PasswordPolicy, hasher *auth.PasswordHasher, passwords *PasswordScreen) UserService {
+	return &userService{userRepo: userRepo, auditSvc: auditSvc, policy: policy, hasher: hasher, passwords: passwords}
 }
 
 func (s *userService) List(ctx context.Context, params models.UserListParams) (models.UsersListResponse, error) {
@@ -58,6 +79,12 @@ func (s *userService) List(ctx context.Context, params models.UserListParams) (m
 	}, nil
 }
 
+func (s *userService) Each(ctx context.Context, params models.UserListParams, fn func(models.UserResponse) error) error {
+	return s.userRepo.Each(ctx, params, func(u models.User) error {
+		return fn(models.UserFromDB(u))
+	})
+}
+
 func (s *userService) GetByID(ctx context.Context, id int) (models.UserResponse, error) {
 	u, err := s.userRepo.GetByID(ctx, id)
 	if err != nil {
@@ -67,9 +94,14 @@ func (s *userService) GetByID(ctx context.Context, id int) (models.UserResponse,
 }
 
 func (s *userService) Create(ctx context.Context, req models.CreateUserRequest) (models.UserResponse, error) {
+	sanitize.Struct(&req)
+
 	if req.Email == "" || req.Username == "" || req.Password == "" {
 		return models.UserResponse{}, errors.NewBadRequestError("Email, username and password are required")
 	}
+	if err := validation.NewValidator().ValidateField("password", req.Password, validation.Password(s.policy)).GetError(); err != nil {
+		return models.UserResponse{}, err
+	}
 
 	if req.Role == "" {
 		req.Role = models.RoleUser
@@ -94,20 +126,35 @@ func (s *userService) Create(ctx context.Context, req models.CreateUserRequest)
 		return models.UserResponse{}, errors.NewUserExistsError()
 	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err := s.passwords.Check(ctx, "admin_create", req.Password); err != nil {
+		return models.UserResponse{}, err
+	}
+
+	hashedPassword, err := s.hasher.Hash(ctx, req.Password)
 	if err != nil {
-		logger.ErrorContext(ctx, "Error hashing password", err)
-		return models.UserResponse{}, errors.NewInternalError().WithCause(err)
+		return models.UserResponse{}, passwordHashError(ctx, err)
 	}
 
-	u, err := s.userRepo.Create(ctx, req.Username, req.Email, string(hashedPassword), req.FirstName, req.LastName, req.Role)
+	u, err := s.userRepo.Create(ctx, req.Username, req.Email, hashedPassword, req.FirstName, req.LastName, req.Role)
 	if err != nil {
 		return models.UserResponse{}, err
 	}
+
+	s.auditSvc.Record(ctx, models.AuditEntry{
+		Action:     models.AuditActionUserCreate,
+		TargetType: "user",
+		TargetID:   &u.ID,
+		Metadata:   map[string]interface{}{"role": u.Role},
+	})
 	return models.UserFromDB(u), nil
 }
 
 func (s *userService) Update(ctx context.Context, id int, req models.UpdateUserRequest) (models.UserResponse, error) {
+	sanitize.Struct(&req)
+	if err := validation.Struct(req); err != nil {
+		return models.UserResponse{}, err
+	}
+
 	exists, err := s.userRepo.Exists(ctx, id)
 	if err != nil {
 		return models.UserResponse{}, err
@@ -133,6 +180,20 @@ func (s *userService) Update(ctx context.Context, id int, req models.UpdateUserR
 	if err != nil {
 		return models.UserResponse{}, err
 	}
+
+	s.auditSvc.Record(ctx, models.AuditEntry{
+		Action:     models.AuditActionUserUpdate,
+		TargetType: "user",
+		TargetID:   &u.ID,
+	})
+	if req.Role != "" {
+		s.auditSvc.Record(ctx, models.AuditEntry{
+			Action:     models.AuditActionRoleChange,
+			TargetType: "user",
+			TargetID:   &u.ID,
+			Metadata:   map[string]interface{}{"role": req.Role},
+		})
+	}
 	return models.UserFromDB(u), nil
 }
 
@@ -146,9 +207,62 @@ func (s *userService) UpdateStatus(ctx context.Context, id int, status string) (
 	if err != nil {
 		return models.UserResponse{}, err
 	}
+
+	s.auditSvc.Record(ctx, models.AuditEntry{
+		Action:     models.AuditActionUserStatus,
+		TargetType: "user",
+		TargetID:   &u.ID,
+		Metadata:   map[string]interface{}{"status": status},
+	})
 	return models.UserFromDB(u), nil
 }
 
 func (s *userService) Delete(ctx context.Context, id int) error {
-	return s.userRepo.Delete(ctx, id)
+	if err := s.userRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	s.auditSvc.Record(ctx, models.AuditEntry{
+		Action:     models.AuditActionUserDelete,
+		TargetType: "user",
+		TargetID:   &id,
+	})
+	return nil
+}
+
+// Search returns the public profiles of active users whose username starts
+// with query.
+func (s *userService) Search(ctx context.Context, query string, limit int) ([]models.PublicProfile, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, errors.NewBadRequestError("Search query is required")
+	}
+	if len(query) > maxSearchQueryLen {
+		return nil, errors.NewBadRequestError(fmt.Sprintf("Search query must be at most %d characters", maxSearchQueryLen))
+	}
+	if limit < 1 {
+		limit = defaultSearchLimit
+	}
+	if limit > maxSearchLimit {
+		limit = maxSearchLimit
+	}
+
+	users, err := s.userRepo.SearchByUsernamePrefix(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	profiles := make([]models.PublicProfile, len(users))
+	for i, u := range users {
+		profiles[i] = models.PublicProfileFromDB(u)
+	}
+	return profiles, nil
+}
+
+func (s *userService) GetPublicProfile(ctx context.Context, username string) (models.PublicProfile, error) {
+	u, err := s.userRepo.GetByUsername(ctx, username)
+	if err != nil {
+		return models.PublicProfile{}, err
+	}
+	return models.PublicProfileFromDB(u), nil
 }
@@ -2,11 +2,14 @@ package services
 
 import (
 	"context"
+	"net/http"
+	"strings"
 	"testing"
 
 	"github.com/clementhaon/sandbox-api-go/errors"
 	"github.com/clementhaon/sandbox-api-go/mocks"
 	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/validation"
 )
 
 func TestUserService_List(t *testing.T) {
@@ -19,7 +22,7 @@ func TestUserService_List(t *testing.T) {
 		},
 	}
 
-	svc := NewUserService(repo)
+	svc := NewUserService(repo, &mocks.MockAuditService{}, validation.DefaultPasswordPolicy(), nil, nil)
 	resp, err := svc.List(context.Background(), models.UserListParams{Page: 1, PageSize: 10})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -39,7 +42,7 @@ func TestUserService_List_DefaultPagination(t *testing.T) {
 		},
 	}
 
-	svc := NewUserService(repo)
+	svc := NewUserService(repo, &mocks.MockAuditService{}, validation.DefaultPasswordPolicy(), nil, nil)
 	resp, err := svc.List(context.Background(), models.UserListParams{})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -59,7 +62,7 @@ func TestUserService_GetByID_Success(t *testing.T) {
 		},
 	}
 
-	svc := NewUserService(repo)
+	svc := NewUserService(repo, &mocks.MockAuditService{}, validation.DefaultPasswordPolicy(), nil, nil)
 	user, err := svc.GetByID(context.Background(), 1)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -76,7 +79,7 @@ func TestUserService_GetByID_NotFound(t *testing.T) {
 		},
 	}
 
-	svc := NewUserService(repo)
+	svc := NewUserService(repo, &mocks.MockAuditService{}, validation.DefaultPasswordPolicy(), nil, nil)
 	_, err := svc.GetByID(context.Background(), 999)
 	if err == nil {
 		t.Fatal("expected error")
@@ -93,11 +96,11 @@ func TestUserService_Create_Success(t *testing.T) {
 		},
 	}
 
-	svc := NewUserService(repo)
+	svc := NewUserService(repo, &mocks.MockAuditService{}, validation.DefaultPasswordPolicy(), nil, nil)
 	user, err := svc.Create(context.Background(), models.CreateUserRequest{
 		Username: "newuser",
 		Email:    "new@test.com",
-		Password: "password",
+		Password: "Password1",
 	})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -110,9 +113,31 @@ func TestUserService_Create_Success(t *testing.T) {
 	}
 }
 
+func TestUserService_Create_PasswordPolicy(t *testing.T) {
+	repo := &mocks.MockUserRepository{
+		ExistsByUsernameOrEmailFn: func(ctx context.Context, username, email string) (bool, error) {
+			return false, nil
+		},
+		CreateFn: func(ctx context.Context, username, email, hashedPassword, firstName, lastName, role string) (models.User, error) {
+			return models.User{ID: 1, Username: username, Email: email, Role: role, IsActive: true}, nil
+		},
+	}
+	policy := validation.PasswordPolicy{MinLength: 12, Classes: []string{validation.PasswordClassSymbol}}
+	svc := NewUserService(repo, &mocks.MockAuditService{}, policy, nil, nil)
+
+	req := models.CreateUserRequest{Username: "newuser", Email: "new@test.com", Password: "Password1"}
+	if _, err := svc.Create(context.Background(), req); !errors.Is(err, errors.ErrValidationFailed) {
+		t.Fatalf("expected a password short of the policy refused, got %v", err)
+	}
+	req.Password = "correct horse battery"
+	if _, err := svc.Create(context.Background(), req); err != nil {
+		t.Fatalf("expected a password meeting the policy accepted, got %v", err)
+	}
+}
+
 func TestUserService_Create_MissingFields(t *testing.T) {
 	repo := &mocks.MockUserRepository{}
-	svc := NewUserService(repo)
+	svc := NewUserService(repo, &mocks.MockAuditService{}, validation.DefaultPasswordPolicy(), nil, nil)
 
 	_, err := svc.Create(context.Background(), models.CreateUserRequest{Username: "a", Email: ""})
 	if err == nil {
@@ -122,7 +147,7 @@ func TestUserService_Create_MissingFields(t *testing.T) {
 
 func TestUserService_Create_InvalidRole(t *testing.T) {
 	repo := &mocks.MockUserRepository{}
-	svc := NewUserService(repo)
+	svc := NewUserService(repo, &mocks.MockAuditService{}, validation.DefaultPasswordPolicy(), nil, nil)
 
 	_, err := svc.Create(context.Background(), models.CreateUserRequest{
 		Username: "test",
@@ -142,11 +167,11 @@ func TestUserService_Create_UserExists(t *testing.T) {
 		},
 	}
 
-	svc := NewUserService(repo)
+	svc := NewUserService(repo, &mocks.MockAuditService{}, validation.DefaultPasswordPolicy(), nil, nil)
 	_, err := svc.Create(context.Background(), models.CreateUserRequest{
 		Username: "existing",
 		Email:    "existing@test.com",
-		Password: "pass",
+		Password: "Password1",
 	})
 	if err == nil {
 		t.Fatal("expected error for existing user")
@@ -167,7 +192,7 @@ func TestUserService_Update_NotFound(t *testing.T) {
 		},
 	}
 
-	svc := NewUserService(repo)
+	svc := NewUserService(repo, &mocks.MockAuditService{}, validation.DefaultPasswordPolicy(), nil, nil)
 	_, err := svc.Update(context.Background(), 999, models.UpdateUserRequest{Email: "new@test.com"})
 	if err == nil {
 		t.Fatal("expected not found error")
@@ -181,7 +206,7 @@ func TestUserService_Update_InvalidRole(t *testing.T) {
 		},
 	}
 
-	svc := NewUserService(repo)
+	svc := NewUserService(repo, &mocks.MockAuditService{}, validation.DefaultPasswordPolicy(), nil, nil)
 	_, err := svc.Update(context.Background(), 1, models.UpdateUserRequest{Role: "superadmin"})
 	if err == nil {
 		t.Fatal("expected error for invalid role")
@@ -190,7 +215,7 @@ func TestUserService_Update_InvalidRole(t *testing.T) {
 
 func TestUserService_UpdateStatus_InvalidStatus(t *testing.T) {
 	repo := &mocks.MockUserRepository{}
-	svc := NewUserService(repo)
+	svc := NewUserService(repo, &mocks.MockAuditService{}, validation.DefaultPasswordPolicy(), nil, nil)
 
 	_, err := svc.UpdateStatus(context.Background(), 1, "unknown")
 	if err == nil {
@@ -205,7 +230,7 @@ func TestUserService_UpdateStatus_Success(t *testing.T) {
 		},
 	}
 
-	svc := NewUserService(repo)
+	svc := NewUserService(repo, &mocks.MockAuditService{}, validation.DefaultPasswordPolicy(), nil, nil)
 	user, err := svc.UpdateStatus(context.Background(), 1, "inactive")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -224,7 +249,7 @@ func TestUserService_Delete(t *testing.T) {
 		},
 	}
 
-	svc := NewUserService(repo)
+	svc := NewUserService(repo, &mocks.MockAuditService{}, validation.DefaultPasswordPolicy(), nil, nil)
 	err := svc.Delete(context.Background(), 5)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -233,3 +258,62 @@ func TestUserService_Delete(t *testing.T) {
 		t.Errorf("expected delete ID 5, got %d", deletedID)
 	}
 }
+
+func TestUserService_Search(t *testing.T) {
+	var gotPrefix string
+	var gotLimit int
+	repo := &mocks.MockUserRepository{
+		SearchByUsernamePrefixFn: func(ctx context.Context, prefix string, limit int) ([]models.User, error) {
+			gotPrefix, gotLimit = prefix, limit
+			return []models.User{{ID: 1, Username: "alice", Email: "alice@test.com", IsActive: true}}, nil
+		},
+	}
+
+	svc := NewUserService(repo, &mocks.MockAuditService{}, validation.DefaultPasswordPolicy(), nil, nil)
+	profiles, err := svc.Search(context.Background(), "  ali ", 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gotPrefix != "ali" {
+		t.Errorf("expected trimmed prefix ali, got %q", gotPrefix)
+	}
+	if gotLimit != defaultSearchLimit {
+		t.Errorf("expected default limit %d, got %d", defaultSearchLimit, gotLimit)
+	}
+	if len(profiles) != 1 || profiles[0].Username != "alice" {
+		t.Errorf("unexpected profiles: %+v", profiles)
+	}
+
+	if _, err := svc.Search(context.Background(), "ali", 1000); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gotLimit != maxSearchLimit {
+		t.Errorf("expected limit capped at %d, got %d", maxSearchLimit, gotLimit)
+	}
+}
+
+func TestUserService_Search_InvalidQuery(t *testing.T) {
+	svc := NewUserService(&mocks.MockUserRepository{}, &mocks.MockAuditService{}, validation.DefaultPasswordPolicy(), nil, nil)
+
+	for _, q := range []string{"", "   ", strings.Repeat("a", maxSearchQueryLen+1)} {
+		_, err := svc.Search(context.Background(), q, 10)
+		appErr, ok := errors.IsAppError(err)
+		if !ok || appErr.StatusCode != http.StatusBadRequest {
+			t.Errorf("query %q: expected bad request, got %v", q, err)
+		}
+	}
+}
+
+func TestUserService_GetPublicProfile_NotFound(t *testing.T) {
+	repo := &mocks.MockUserRepository{
+		GetByUsernameFn: func(ctx context.Context, username string) (models.User, error) {
+			return models.User{}, errors.NewNotFoundError("User")
+		},
+	}
+
+	svc := NewUserService(repo, &mocks.MockAuditService{}, validation.DefaultPasswordPolicy(), nil, nil)
+	_, err := svc.GetPublicProfile(context.Background(), "ghost")
+	if !errors.Is(err, errors.ErrNotFound) {
+		t.Errorf("expected not found, got %v", err)
+	}
+}
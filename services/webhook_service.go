@@ -0,0 +1,270 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/events"
+	"github.com/clementhaon/sandbox-api-go/logger"
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/repository"
+	"github.com/clementhaon/sandbox-api-go/secretbox"
+	"github.com/clementhaon/sandbox-api-go/validation"
+	"github.com/clementhaon/sandbox-api-go/webhook"
+)
+
+const (
+	// webhookSecretPrefix marks webhook signing secrets, so secret scanners
+	// can spot them.
+	webhookSecretPrefix = "whsec_"
+	// webhookSecretColumn is what the secrets of webhooks are sealed with,
+	// so they only open from that column.
+	webhookSecretColumn = "webhooks.secret"
+	// webhookPreviewBytes bounds the body returned by a test.
+	webhookPreviewBytes = 1024
+)
+
+// WebhookService manages the outgoing webhooks of users and delivers the
+// events of their tasks to them.
+type WebhookService interface {
+	List(ctx context.Context, userID int) ([]models.Webhook, error)
+	// Create returns the new webhook with its signing secret, which is only
+	// shown here
+	Create(ctx context.Context, userID int, req models.CreateWebhookRequest) (models.CreateWebhookResponse, error)
+	Delete(ctx context.Context, userID, id int) error
+	// Test sends a signed ping to webhook id and reports how its receiver
+	// answered. A receiver that cannot be reached is reported in the
+	// result, not as an error.
+	Test(ctx context.Context, userID, id int) (models.WebhookTestResult, error)
+	// Notify is an events.Handler sending e to the webhooks of the owner
+	// and assignee of its task, in the background
+	Notify(ctx context.Context, e events.Event)
+	// Stop waits for the deliveries in progress.
+	Stop()
+}
+
+type webhookService struct {
+	hookRepo repository.WebhookRepository
+	auditSvc AuditService
+	keyring  *secretbox.Keyring
+	client   *http.Client
+
+	deliveries sync.WaitGroup
+}
+
+// NewWebhookService returns a WebhookService sealing the signing secrets
+// with keyring and sending requests with client, such as one from
+// webhook.NewClient.
+func NewWebhookService(hookRepo repository.WebhookRepository, auditSvc AuditService, keyring *secretbox.Keyring, client *http.Client) WebhookService {
+	return &webhookService{hookRepo: hookRepo, auditSvc: auditSvc, keyring: keyring, client: client}
+}
+
+func (s *webhookService) List(ctx context.Context, userID int) ([]models.Webhook, error) {
+	return s.hookRepo.ListByUser(ctx, userID)
+}
+
+func (s *webhookService) Create(ctx context.Context, userID int, req models.CreateWebhookRequest) (models.CreateWebhookResponse, error) {
+	req.URL = strings.TrimSpace(req.URL)
+	if err := validation.Struct(req); err != nil {
+		return models.CreateWebhookResponse{}, err
+	}
+	if u, err := url.Parse(req.URL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" || u.User != nil {
+		return models.CreateWebhookResponse{}, errors.NewInvalidFormatError("url", "an http(s) URL without credentials")
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return models.CreateWebhookResponse{}, errors.NewInternalError().WithCause(err)
+	}
+	secret := webhookSecretPrefix + hex.EncodeToString(b)
+	sealed, err := s.keyring.Seal([]byte(secret), []byte(webhookSecretColumn))
+	if err != nil {
+		logger.ErrorContext(ctx, "Error sealing webhook secret", err)
+		return models.CreateWebhookResponse{}, errors.NewInternalError().WithCause(err)
+	}
+
+	created, err := s.hookRepo.Create(ctx, models.Webhook{UserID: userID, URL: req.URL, Secret: sealed})
+	if err != nil {
+		return models.CreateWebhookResponse{}, err
+	}
+
+	s.auditSvc.Record(ctx, models.AuditEntry{
+		Action:     models.AuditActionWebhookCreate,
+		TargetType: "webhook",
+		TargetID:   &created.ID,
+		Metadata:   map[string]interface{}{"url": created.URL},
+	})
+	return models.CreateWebhookResponse{Webhook: created, Secret: secret}, nil
+}
+
+func (s *webhookService) Delete(ctx context.Context, userID, id int) error {
+	if err := s.hookRepo.Delete(ctx, userID, id); err != nil {
+		return err
+	}
+	s.auditSvc.Record(ctx, models.AuditEntry{
+		Action:     models.AuditActionWebhookDelete,
+		TargetType: "webhook",
+		TargetID:   &id,
+	})
+	return nil
+}
+
+func (s *webhookService) Test(ctx context.Context, userID, id int) (models.WebhookTestResult, error) {
+	hook, err := s.hookRepo.Get(ctx, userID, id)
+	if err != nil {
+		return models.WebhookTestResult{}, err
+	}
+	event, err := newWebhookEvent(models.WebhookEventPing, map[string]interface{}{
+		"webhookId": hook.ID,
+		"task":      sampleTask(),
+	})
+	if err != nil {
+		return models.WebhookTestResult{}, errors.NewInternalError().WithCause(err)
+	}
+
+	started := time.Now()
+	resp, err := s.send(ctx, hook, event)
+	result := models.WebhookTestResult{LatencyMS: time.Since(started).Milliseconds()}
+	if err != nil {
+		if appErr, ok := errors.IsAppError(err); ok {
+			return models.WebhookTestResult{}, appErr
+		}
+		result.Error = err.Error()
+		return result, nil
+	}
+	defer resp.Body.Close()
+
+	preview, _ := io.ReadAll(io.LimitReader(resp.Body, webhookPreviewBytes))
+	result.Status = resp.StatusCode
+	result.BodyPreview = strings.ToValidUTF8(string(preview), "�")
+	return result, nil
+}
+
+func (s *webhookService) Notify(ctx context.Context, e events.Event) {
+	event, err := newWebhookEvent(string(e.Type), map[string]interface{}{
+		"actorId": e.ActorID,
+		"task":    e.Task,
+	})
+	if err != nil {
+		logger.ErrorContext(ctx, "Error building webhook event", err)
+		return
+	}
+	for _, userID := range e.Watchers() {
+		hooks, err := s.hookRepo.ListByUser(ctx, userID)
+		if err != nil {
+			logger.ErrorContext(ctx, "Error listing webhooks", err)
+			continue
+		}
+		for _, hook := range hooks {
+			s.deliver(ctx, hook, event)
+		}
+	}
+}
+
+// deliver sends event to hook in the background, logging failures. The
+// delivery outlives the request that raised the event.
+func (s *webhookService) deliver(ctx context.Context, hook models.Webhook, event models.WebhookEvent) {
+	ctx = context.WithoutCancel(ctx)
+	s.deliveries.Add(1)
+	go func() {
+		defer s.deliveries.Done()
+		fields := map[string]interface{}{"webhook_id": hook.ID, "event": event.Type}
+		resp, err := s.send(ctx, hook, event)
+		if err != nil {
+			fields["error"] = err.Error()
+			logger.WarnContext(ctx, "Webhook delivery failed", fields)
+			return
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= http.StatusMultipleChoices {
+			fields["status"] = resp.StatusCode
+			logger.WarnContext(ctx, "Webhook receiver refused the delivery", fields)
+		}
+	}()
+}
+
+func (s *webhookService) Stop() {
+	s.deliveries.Wait()
+}
+
+// send posts event to hook, signed with its secret.
+func (s *webhookService) send(ctx context.Context, hook models.Webhook, event models.WebhookEvent) (*http.Response, error) {
+	secret, err := s.keyring.Open(hook.Secret, []byte(webhookSecretColumn))
+	if err != nil {
+		logger.ErrorContext(ctx, "Error opening webhook secret", err)
+		return nil, errors.NewInternalError().WithCause(err)
+	}
+	body, err := json.Marshal(event)
+	if err != nil {
+		return nil, errors.NewInternalError().WithCause(err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "sandbox-api-webhook")
+	req.Header.Set(webhook.EventHeader, event.Type)
+	req.Header.Set(webhook.DeliveryHeader, event.ID)
+	req.Header.Set(webhook.SignatureHeader, webhook.Sign(string(secret), time.Now(), body))
+	return s.client.Do(req)
+}
+
+// newWebhookEvent returns an event of type with a new delivery ID.
+// RotateWebhookSecrets reseals the signing secrets of every webhook under
+// the current key of keyring, and returns how many it changed. keyring must
+// still hold the keys they are sealed under.
+func RotateWebhookSecrets(ctx context.Context, hookRepo repository.WebhookRepository, keyring *secretbox.Keyring) (int, error) {
+	hooks, err := hookRepo.ListAll(ctx)
+	if err != nil {
+		return 0, err
+	}
+	rotated := 0
+	for _, hook := range hooks {
+		secret, changed, err := keyring.Rotate(hook.Secret, []byte(webhookSecretColumn))
+		if err != nil {
+			return rotated, fmt.Errorf("webhook %d: %w", hook.ID, err)
+		}
+		if !changed {
+			continue
+		}
+		if err := hookRepo.UpdateSecret(ctx, hook.ID, secret); err != nil {
+			return rotated, err
+		}
+		rotated++
+	}
+	return rotated, nil
+}
+
+func newWebhookEvent(typ string, data interface{}) (models.WebhookEvent, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return models.WebhookEvent{}, err
+	}
+	return models.WebhookEvent{ID: "evt_" + hex.EncodeToString(b), Type: typ, CreatedAt: time.Now().UTC(), Data: data}, nil
+}
+
+// sampleTask is the task of test pings, shaped like those of real events.
+func sampleTask() models.Task {
+	now := time.Now().UTC()
+	return models.Task{
+		Title:     "Sample task",
+		Priority:  models.PriorityMedium,
+		Status:    models.TaskStatusTodo,
+		Tags:      []string{},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
@@ -0,0 +1,101 @@
+package signup
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+//go:embed disposable_domains.txt
+var disposableDomains string
+
+// Blocklist tells whether the addresses of an email domain are refused.
+type Blocklist interface {
+	Blocked(domain string) bool
+}
+
+// DomainList is a Blocklist of domains. A listed domain also blocks its
+// subdomains, so "mailinator.com" blocks "eu.mailinator.com".
+type DomainList map[string]struct{}
+
+// NewDomainList lists domains, ignoring case and a leading dot.
+func NewDomainList(domains ...string) DomainList {
+	l := make(DomainList, len(domains))
+	l.Add(domains...)
+	return l
+}
+
+// DefaultDisposableDomains returns the built-in list of disposable email
+// providers.
+func DefaultDisposableDomains() DomainList {
+	l, err := ReadDomainList(strings.NewReader(disposableDomains))
+	if err != nil {
+		panic(err)
+	}
+	return l
+}
+
+// ReadDomainList reads one domain per line; blank lines and lines starting
+// with # are skipped.
+func ReadDomainList(r io.Reader) (DomainList, error) {
+	l := make(DomainList)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		l.Add(line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// LoadDomainList reads a domain list from the file at path.
+func LoadDomainList(path string) (DomainList, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	l, err := ReadDomainList(f)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	return l, nil
+}
+
+// Add lists more domains.
+func (l DomainList) Add(domains ...string) {
+	for _, d := range domains {
+		if d = normalizeDomain(d); d != "" {
+			l[d] = struct{}{}
+		}
+	}
+}
+
+// Blocked implements Blocklist.
+func (l DomainList) Blocked(domain string) bool {
+	domain = normalizeDomain(domain)
+	for domain != "" {
+		if _, ok := l[domain]; ok {
+			return true
+		}
+		_, parent, found := strings.Cut(domain, ".")
+		if !found {
+			return false
+		}
+		domain = parent
+	}
+	return false
+}
+
+func normalizeDomain(domain string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(strings.ToLower(strings.TrimSpace(domain)), "."), ".")
+}
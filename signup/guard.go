@@ -0,0 +1,89 @@
+// Package signup guards registration against abuse: a limit on accounts
+// created per client IP, a blocklist of disposable email domains and an
+// optional CAPTCHA check.
+package signup
+
+import (
+	"context"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/logger"
+	"github.com/clementhaon/sandbox-api-go/ratelimit"
+)
+
+// Options configures a Guard; zero values turn the matching check off.
+type Options struct {
+	// Limit signups per client IP within Window, counted in Store
+	Store  ratelimit.Store
+	Limit  int
+	Window time.Duration
+
+	// Blocklist of email domains; nil accepts every domain
+	Blocklist Blocklist
+
+	// Captcha verifies the token sent with the signup; nil skips the check
+	Captcha CaptchaVerifier
+}
+
+// Guard runs the signup checks.
+type Guard struct {
+	opts Options
+}
+
+func NewGuard(opts Options) *Guard {
+	return &Guard{opts: opts}
+}
+
+// Check vets a signup for email from the client IP in ctx. It returns a
+// DISPOSABLE_EMAIL, CAPTCHA_REQUIRED, CAPTCHA_FAILED or SIGNUP_RATE_LIMITED
+// error, in that order, and counts the signup against the IP only once the
+// other checks pass. If the rate limit store fails the signup is let
+// through; if the CAPTCHA provider fails it is refused as unavailable.
+func (g *Guard) Check(ctx context.Context, email, captchaToken string) error {
+	ip, _ := ctx.Value(logger.ClientIPKey).(string)
+
+	if g.opts.Blocklist != nil {
+		_, domain, _ := strings.Cut(email, "@")
+		if g.opts.Blocklist.Blocked(domain) {
+			logger.WarnContext(ctx, "Signup refused: disposable email domain", map[string]interface{}{
+				"domain": domain,
+			})
+			return errors.NewDisposableEmailError()
+		}
+	}
+
+	if g.opts.Captcha != nil {
+		if captchaToken == "" {
+			return errors.NewCaptchaRequiredError()
+		}
+		ok, err := g.opts.Captcha.Verify(ctx, captchaToken, ip)
+		if err != nil {
+			logger.ErrorContext(ctx, "CAPTCHA verification unavailable", err)
+			return errors.NewServiceUnavailableError().WithCause(err)
+		}
+		if !ok {
+			logger.WarnContext(ctx, "Signup refused: CAPTCHA failed", nil)
+			return errors.NewCaptchaFailedError()
+		}
+	}
+
+	if g.opts.Store != nil && g.opts.Limit > 0 && ip != "" {
+		rate := float64(g.opts.Limit) / g.opts.Window.Seconds()
+		allowed, wait, err := g.opts.Store.Take(ctx, "signup:"+ip, rate, g.opts.Limit)
+		if err != nil {
+			logger.WarnContext(ctx, "Signup rate limit unavailable", map[string]interface{}{
+				"error": err.Error(),
+			})
+			return nil
+		}
+		if !allowed {
+			logger.WarnContext(ctx, "Signup refused: rate limited", nil)
+			return errors.NewSignupRateLimitedError().
+				WithDetails(map[string]interface{}{"retryAfter": int(math.Ceil(wait.Seconds()))})
+		}
+	}
+	return nil
+}
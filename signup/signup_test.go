@@ -0,0 +1,167 @@
+package signup
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/logger"
+	"github.com/clementhaon/sandbox-api-go/ratelimit"
+)
+
+func TestDomainList(t *testing.T) {
+	list, err := ReadDomainList(strings.NewReader("# comment\n\nMailinator.com\n.trashmail.de\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		domain string
+		want   bool
+	}{
+		{"mailinator.com", true},
+		{"MAILINATOR.COM", true},
+		{"eu.mailinator.com", true},
+		{"trashmail.de", true},
+		{"notmailinator.com", false},
+		{"mailinator.com.example.org", false},
+		{"example.com", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := list.Blocked(tt.domain); got != tt.want {
+			t.Errorf("Blocked(%q) = %v, want %v", tt.domain, got, tt.want)
+		}
+	}
+
+	if !DefaultDisposableDomains().Blocked("yopmail.com") {
+		t.Error("expected the built-in list to block yopmail.com")
+	}
+}
+
+type captchaFunc func(ctx context.Context, token, remoteIP string) (bool, error)
+
+func (f captchaFunc) Verify(ctx context.Context, token, remoteIP string) (bool, error) {
+	return f(ctx, token, remoteIP)
+}
+
+func TestGuard_Check(t *testing.T) {
+	ctx := context.WithValue(context.Background(), logger.ClientIPKey, "192.0.2.10")
+
+	t.Run("refuses disposable domains", func(t *testing.T) {
+		g := NewGuard(Options{Blocklist: NewDomainList("mailinator.com")})
+		if err := g.Check(ctx, "john@mailinator.com", ""); !errors.Is(err, errors.ErrDisposableEmail) {
+			t.Fatalf("expected DISPOSABLE_EMAIL, got %v", err)
+		}
+		if err := g.Check(ctx, "john@example.com", ""); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("checks the CAPTCHA", func(t *testing.T) {
+		var gotIP string
+		g := NewGuard(Options{Captcha: captchaFunc(func(ctx context.Context, token, remoteIP string) (bool, error) {
+			gotIP = remoteIP
+			return token == "good", nil
+		})})
+
+		if err := g.Check(ctx, "john@example.com", ""); !errors.Is(err, errors.ErrCaptchaRequired) {
+			t.Fatalf("expected CAPTCHA_REQUIRED, got %v", err)
+		}
+		if err := g.Check(ctx, "john@example.com", "bad"); !errors.Is(err, errors.ErrCaptchaFailed) {
+			t.Fatalf("expected CAPTCHA_FAILED, got %v", err)
+		}
+		if err := g.Check(ctx, "john@example.com", "good"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if gotIP != "192.0.2.10" {
+			t.Errorf("remote IP = %q, want 192.0.2.10", gotIP)
+		}
+	})
+
+	t.Run("refuses when the CAPTCHA provider fails", func(t *testing.T) {
+		g := NewGuard(Options{Captcha: captchaFunc(func(ctx context.Context, token, remoteIP string) (bool, error) {
+			return false, context.DeadlineExceeded
+		})})
+		if err := g.Check(ctx, "john@example.com", "token"); !errors.Is(err, errors.ErrServiceUnavailable) {
+			t.Fatalf("expected SERVICE_UNAVAILABLE, got %v", err)
+		}
+	})
+
+	t.Run("limits signups per IP", func(t *testing.T) {
+		store := ratelimit.NewMemoryStore()
+		defer store.Stop()
+		g := NewGuard(Options{Store: store, Limit: 2, Window: time.Hour})
+
+		for i := 0; i < 2; i++ {
+			if err := g.Check(ctx, "john@example.com", ""); err != nil {
+				t.Fatalf("signup %d: unexpected error: %v", i+1, err)
+			}
+		}
+		err := g.Check(ctx, "john@example.com", "")
+		if !errors.Is(err, errors.ErrSignupRateLimited) {
+			t.Fatalf("expected SIGNUP_RATE_LIMITED, got %v", err)
+		}
+		appErr, _ := errors.IsAppError(err)
+		if retry := appErr.Details.(map[string]interface{})["retryAfter"].(int); retry < 1700 || retry > 1800 {
+			t.Errorf("retryAfter = %d, want about half an hour", retry)
+		}
+
+		other := context.WithValue(context.Background(), logger.ClientIPKey, "192.0.2.11")
+		if err := g.Check(other, "john@example.com", ""); err != nil {
+			t.Fatalf("expected another IP to be allowed, got %v", err)
+		}
+	})
+
+	t.Run("refused signups are not counted", func(t *testing.T) {
+		store := ratelimit.NewMemoryStore()
+		defer store.Stop()
+		g := NewGuard(Options{Store: store, Limit: 1, Window: time.Hour, Blocklist: NewDomainList("mailinator.com")})
+
+		if err := g.Check(ctx, "john@mailinator.com", ""); !errors.Is(err, errors.ErrDisposableEmail) {
+			t.Fatalf("expected DISPOSABLE_EMAIL, got %v", err)
+		}
+		if err := g.Check(ctx, "john@example.com", ""); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
+
+func TestSiteVerifier(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("parse form: %v", err)
+		}
+		if r.PostForm.Get("secret") != "site-secret" || r.PostForm.Get("remoteip") != "192.0.2.10" {
+			t.Errorf("unexpected form %v", r.PostForm)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if r.PostForm.Get("response") == "good" {
+			w.Write([]byte(`{"success": true}`))
+			return
+		}
+		w.Write([]byte(`{"success": false, "error-codes": ["invalid-input-response"]}`))
+	}))
+	defer srv.Close()
+
+	v := NewSiteVerifier(srv.URL, "site-secret")
+	ok, err := v.Verify(context.Background(), "good", "192.0.2.10")
+	if err != nil || !ok {
+		t.Fatalf("Verify(good) = %v, %v; want true", ok, err)
+	}
+	ok, err = v.Verify(context.Background(), "bad", "192.0.2.10")
+	if err != nil || ok {
+		t.Fatalf("Verify(bad) = %v, %v; want false", ok, err)
+	}
+
+	failing := httptest.NewServer(http.NotFoundHandler())
+	defer failing.Close()
+	down := NewSiteVerifier(failing.URL, "site-secret")
+	if _, err := down.Verify(context.Background(), "good", "192.0.2.10"); err == nil {
+		t.Error("expected an error when the provider fails")
+	}
+}
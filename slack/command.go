@@ -0,0 +1,37 @@
+package slack
+
+import (
+	"net/url"
+	"strings"
+	"unicode"
+)
+
+// Command is a slash command invocation, from the form Slack posts.
+type Command struct {
+	TeamID   string // team_id
+	UserID   string // user_id, the Slack user who ran the command
+	UserName string // user_name
+	Command  string // command, e.g. "/tasks"
+	Text     string // text following the command
+}
+
+// ParseCommand reads a slash command from a parsed form.
+func ParseCommand(form url.Values) Command {
+	return Command{
+		TeamID:   form.Get("team_id"),
+		UserID:   form.Get("user_id"),
+		UserName: form.Get("user_name"),
+		Command:  form.Get("command"),
+		Text:     strings.TrimSpace(form.Get("text")),
+	}
+}
+
+// Args splits the text of the command into its subcommand, lowercased, and
+// the rest of the text.
+func (c Command) Args() (string, string) {
+	i := strings.IndexFunc(c.Text, unicode.IsSpace)
+	if i < 0 {
+		return strings.ToLower(c.Text), ""
+	}
+	return strings.ToLower(c.Text[:i]), strings.TrimSpace(c.Text[i:])
+}
@@ -0,0 +1,47 @@
+package slack
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/clementhaon/sandbox-api-go/logger"
+)
+
+// Response types of a message: ephemeral ones are only shown to the user
+// who ran the command.
+const (
+	ResponseEphemeral = "ephemeral"
+	ResponseInChannel = "in_channel"
+)
+
+// Message answers a slash command. Text is formatted with Slack's mrkdwn.
+type Message struct {
+	ResponseType string `json:"response_type"`
+	Text         string `json:"text"`
+}
+
+// Ephemeral returns a message only the user who ran the command sees.
+func Ephemeral(text string) Message {
+	return Message{ResponseType: ResponseEphemeral, Text: text}
+}
+
+var escaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// Escape makes user content safe to put in mrkdwn text, so it cannot
+// mention users or channels or inject links.
+func Escape(s string) string {
+	return escaper.Replace(s)
+}
+
+// Write sends msg. Slack shows the body of any non-200 response as an
+// error, so messages are always sent with 200.
+func Write(w http.ResponseWriter, msg Message) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(msg); err != nil {
+		logger.Warn("Failed to encode Slack response", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+}
@@ -0,0 +1,61 @@
+// Package slack verifies and answers Slack slash command requests.
+package slack
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers Slack signs its requests with.
+const (
+	SignatureHeader = "X-Slack-Signature"
+	TimestampHeader = "X-Slack-Request-Timestamp"
+)
+
+// MaxClockSkew is how old, or how far in the future, a signed request may
+// be; older ones are rejected so a captured request cannot be replayed.
+const MaxClockSkew = 5 * time.Minute
+
+var (
+	ErrMissingSignature = errors.New("missing Slack signature")
+	ErrStaleTimestamp   = errors.New("Slack request timestamp is too old")
+	ErrInvalidSignature = errors.New("invalid Slack signature")
+)
+
+// Sign returns the signature of body sent at timestamp, in the form of the
+// X-Slack-Signature header.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + timestamp + ":"))
+	mac.Write(body)
+	return "v0=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks that body was signed with secret, as Slack describes in
+// "Verifying requests from Slack", and was sent within MaxClockSkew of now.
+func Verify(secret string, header http.Header, body []byte, now time.Time) error {
+	timestamp := header.Get(TimestampHeader)
+	signature := header.Get(SignatureHeader)
+	if secret == "" || timestamp == "" || !strings.HasPrefix(signature, "v0=") {
+		return ErrMissingSignature
+	}
+
+	sec, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	if skew := now.Sub(time.Unix(sec, 0)); skew > MaxClockSkew || skew < -MaxClockSkew {
+		return ErrStaleTimestamp
+	}
+
+	if !hmac.Equal([]byte(signature), []byte(Sign(secret, timestamp, body))) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package slack
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestVerify(t *testing.T) {
+	secret := "8f742231b10e8888abcd99yyyzzz85a5"
+	body := []byte("token=x&team_id=T1&user_id=U1&command=%2Ftasks&text=list")
+	now := time.Unix(1700000000, 0)
+	signed := func(ts int64, sig string) http.Header {
+		h := http.Header{}
+		h.Set(TimestampHeader, strconv.FormatInt(ts, 10))
+		h.Set(SignatureHeader, sig)
+		return h
+	}
+	valid := Sign(secret, "1700000000", body)
+
+	tests := []struct {
+		name   string
+		header http.Header
+		body   []byte
+		want   error
+	}{
+		{"valid", signed(now.Unix(), valid), body, nil},
+		{"missing headers", http.Header{}, body, ErrMissingSignature},
+		{"tampered body", signed(now.Unix(), valid), []byte("text=add"), ErrInvalidSignature},
+		{"wrong secret", signed(now.Unix(), Sign("other", "1700000000", body)), body, ErrInvalidSignature},
+		{"replayed", signed(now.Add(-6*time.Minute).Unix(), Sign(secret, strconv.FormatInt(now.Add(-6*time.Minute).Unix(), 10), body)), body, ErrStaleTimestamp},
+		{"from the future", signed(now.Add(6*time.Minute).Unix(), valid), body, ErrStaleTimestamp},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Verify(secret, tt.header, tt.body, now); err != tt.want {
+				t.Errorf("Verify() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	if err := Verify("", signed(now.Unix(), valid), body, now); err != ErrMissingSignature {
+		t.Errorf("expected an empty secret to verify nothing, got %v", err)
+	}
+}
+
+func TestParseCommand(t *testing.T) {
+	cmd := ParseCommand(url.Values{
+		"team_id": {"T1"},
+		"user_id": {"U1"},
+		"command": {"/tasks"},
+		"text":    {"  ADD  Write the\nrelease notes "},
+	})
+	if cmd.TeamID != "T1" || cmd.UserID != "U1" || cmd.Command != "/tasks" {
+		t.Errorf("unexpected command %+v", cmd)
+	}
+	sub, rest := cmd.Args()
+	if sub != "add" || rest != "Write the\nrelease notes" {
+		t.Errorf("Args() = %q, %q", sub, rest)
+	}
+
+	if sub, rest := (Command{}).Args(); sub != "" || rest != "" {
+		t.Errorf("expected no arguments, got %q, %q", sub, rest)
+	}
+}
+
+func TestEscape(t *testing.T) {
+	if got := Escape("<!channel> & <@U1>"); got != "&lt;!channel&gt; &amp; &lt;@U1&gt;" {
+		t.Errorf("Escape() = %q", got)
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !frontend
+
+package spa
+
+import "io/fs"
+
+// Embedded returns nil: the binary was built without the frontend build
+// tag, so no frontend is embedded.
+func Embedded() fs.FS {
+	return nil
+}
@@ -0,0 +1,148 @@
+// Package spa serves a single-page application: its static files, and its
+// index.html for every client-side route.
+package spa
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Cache-Control values. Fingerprinted files never change under their name;
+// everything else, index.html first, is revalidated with its ETag so a new
+// release shows up on the next load.
+const (
+	cacheImmutable  = "public, max-age=31536000, immutable"
+	cacheRevalidate = "no-cache"
+	indexFile       = "index.html"
+)
+
+// fingerprinted matches file names carrying a content hash, as bundlers
+// write them: "index-BdX3k2aQ.js", "main.3f2a9c1b.css".
+var fingerprinted = regexp.MustCompile(`[.-]([A-Za-z0-9_]{8,})\.[A-Za-z0-9]+$`)
+
+type file struct {
+	content []byte
+	etag    string
+	cache   string
+}
+
+// Handler serves the files of an application, and index.html for paths
+// without a file extension that match no file, which are client routes.
+type Handler struct {
+	files map[string]file
+	index file
+}
+
+// New loads the files of fsys, which must hold an index.html at its root.
+// They are read once: a new release needs a restart.
+func New(fsys fs.FS) (*Handler, error) {
+	h := &Handler{files: map[string]file{}}
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		content, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(content)
+		h.files["/"+name] = file{
+			content: content,
+			etag:    `"` + hex.EncodeToString(sum[:8]) + `"`,
+			cache:   cacheControl(name),
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	index, ok := h.files["/"+indexFile]
+	if !ok {
+		return nil, fmt.Errorf("no %s in the frontend files", indexFile)
+	}
+	h.index = index
+	return h, nil
+}
+
+func cacheControl(name string) string {
+	base := path.Base(name)
+	if base == indexFile {
+		return cacheRevalidate
+	}
+	if m := fingerprinted.FindStringSubmatch(base); m != nil && isHash(m[1]) {
+		return cacheImmutable
+	}
+	return cacheRevalidate
+}
+
+// isHash tells hashes from words: a hash mixes letters and digits.
+func isHash(s string) bool {
+	return strings.ContainsAny(s, "0123456789") && strings.IndexFunc(s, func(r rune) bool {
+		return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+	}) >= 0
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := path.Clean("/" + r.URL.Path)
+	f, ok := h.files[name]
+	if !ok && name != "/" && path.Ext(name) != "" {
+		// A missing asset, not a client route: index.html would be
+		// parsed as a script or a stylesheet
+		http.NotFound(w, r)
+		return
+	}
+	if !ok || name == "/"+indexFile {
+		h.ServeIndex(w, r)
+		return
+	}
+	serve(w, r, name, f)
+}
+
+// ServeIndex answers with index.html, leaving the route to the client.
+func (h *Handler) ServeIndex(w http.ResponseWriter, r *http.Request) {
+	serve(w, r, indexFile, h.index)
+}
+
+func serve(w http.ResponseWriter, r *http.Request, name string, f file) {
+	w.Header().Set("Cache-Control", f.cache)
+	w.Header().Set("ETag", f.etag)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	http.ServeContent(w, r, name, time.Time{}, bytes.NewReader(f.content))
+}
+
+// Navigations sends the page loads of browsers to the application, so a
+// client route sharing its path with an API endpoint (/tasks) still loads
+// the page on a reload. Other requests, and the navigations for which
+// passthrough is true, go to next.
+func (h *Handler) Navigations(next http.Handler, passthrough func(*http.Request) bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isNavigation(r) && !passthrough(r) {
+			h.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isNavigation tells page loads from fetches, with the Fetch Metadata
+// headers browsers send.
+func isNavigation(r *http.Request) bool {
+	return (r.Method == http.MethodGet || r.Method == http.MethodHead) &&
+		r.Header.Get("Sec-Fetch-Mode") == "navigate" &&
+		r.Header.Get("Sec-Fetch-Dest") == "document"
+}
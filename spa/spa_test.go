@@ -0,0 +1,126 @@
+package spa
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func newTestHandler(t *testing.T) *Handler {
+	t.Helper()
+	h, err := New(fstest.MapFS{
+		"index.html":               {Data: []byte("<!doctype html><div id=app></div>")},
+		"favicon.ico":              {Data: []byte("icon")},
+		"assets/index-Bd3k2aQx.js": {Data: []byte("console.log(1)")},
+		"assets/app-settings.css":  {Data: []byte("body{}")},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return h
+}
+
+func TestHandler(t *testing.T) {
+	h := newTestHandler(t)
+
+	tests := []struct {
+		name      string
+		path      string
+		wantCode  int
+		wantBody  string
+		wantCache string
+	}{
+		{"root", "/", http.StatusOK, "<!doctype html>", cacheRevalidate},
+		{"client route", "/tasks/12/edit", http.StatusOK, "<!doctype html>", cacheRevalidate},
+		{"index.html", "/index.html", http.StatusOK, "<!doctype html>", cacheRevalidate},
+		{"fingerprinted asset", "/assets/index-Bd3k2aQx.js", http.StatusOK, "console.log", cacheImmutable},
+		{"plain asset", "/assets/app-settings.css", http.StatusOK, "body{}", cacheRevalidate},
+		{"root file", "/favicon.ico", http.StatusOK, "icon", cacheRevalidate},
+		{"missing asset", "/assets/gone-Xy12ab34.js", http.StatusNotFound, "", ""},
+		{"path traversal", "/../../etc/passwd", http.StatusOK, "<!doctype html>", cacheRevalidate},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if w.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantCode)
+			}
+			if !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Errorf("body = %q, want %q", w.Body.String(), tt.wantBody)
+			}
+			if got := w.Header().Get("Cache-Control"); got != tt.wantCache {
+				t.Errorf("Cache-Control = %q, want %q", got, tt.wantCache)
+			}
+		})
+	}
+
+	t.Run("revalidation", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		etag := w.Header().Get("ETag")
+		if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+			t.Errorf("Content-Type = %q", ct)
+		}
+
+		req := httptest.NewRequest(http.MethodGet, "/board", nil)
+		req.Header.Set("If-None-Match", etag)
+		w = httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != http.StatusNotModified {
+			t.Errorf("status = %d, want 304", w.Code)
+		}
+	})
+
+	t.Run("other methods", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", nil))
+		if w.Code != http.StatusMethodNotAllowed {
+			t.Errorf("status = %d, want 405", w.Code)
+		}
+	})
+}
+
+func TestNew_RequiresIndex(t *testing.T) {
+	if _, err := New(fstest.MapFS{"app.js": {Data: []byte("")}}); err == nil {
+		t.Fatal("expected an error without index.html")
+	}
+}
+
+func TestNavigations(t *testing.T) {
+	h := newTestHandler(t)
+	api := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data":[]}`))
+	})
+	handler := h.Navigations(api, func(r *http.Request) bool {
+		return strings.HasPrefix(r.URL.Path, "/oauth/")
+	})
+
+	navigate := func(path string) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Sec-Fetch-Mode", "navigate")
+		req.Header.Set("Sec-Fetch-Dest", "document")
+		return req
+	}
+
+	tests := []struct {
+		name string
+		req  *http.Request
+		want string
+	}{
+		{"page load", navigate("/tasks"), "<!doctype html>"},
+		{"fetch", httptest.NewRequest(http.MethodGet, "/tasks", nil), `{"data":[]}`},
+		{"passthrough", navigate("/oauth/authorize"), `{"data":[]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, tt.req)
+			if !strings.Contains(w.Body.String(), tt.want) {
+				t.Errorf("body = %q, want %q", w.Body.String(), tt.want)
+			}
+		})
+	}
+}
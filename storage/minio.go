@@ -3,6 +3,7 @@ package storage
 import (
 	"context"
 	"fmt"
+	"io"
 	"net/url"
 	"path/filepath"
 	"strings"
@@ -20,6 +21,9 @@ type StorageClient interface {
 	GeneratePresignedDownloadURL(objectKey string) (string, error)
 	DeleteObject(objectKey string) error
 	GetObjectInfo(objectKey string) (*minio.ObjectInfo, error)
+	PutObject(objectKey string, r io.Reader, size int64, contentType string) error
+	GetObject(objectKey string) (io.ReadCloser, error)
+	ListObjects(prefix string) ([]minio.ObjectInfo, error)
 }
 
 // Storage wraps a MinIO client with a default bucket.
@@ -131,3 +135,39 @@ func (s *Storage) GetObjectInfo(objectKey string) (*minio.ObjectInfo, error) {
 
 	return &objInfo, nil
 }
+
+// PutObject uploads size bytes of r under objectKey; a size of -1 uploads r
+// until EOF in parts.
+func (s *Storage) PutObject(objectKey string, r io.Reader, size int64, contentType string) error {
+	ctx := context.Background()
+
+	if _, err := s.client.PutObject(ctx, s.bucketName, objectKey, r, size, minio.PutObjectOptions{ContentType: contentType}); err != nil {
+		return fmt.Errorf("failed to put object: %w", err)
+	}
+	return nil
+}
+
+// GetObject returns the content of objectKey, which the caller must close.
+func (s *Storage) GetObject(objectKey string) (io.ReadCloser, error) {
+	ctx := context.Background()
+
+	obj, err := s.client.GetObject(ctx, s.bucketName, objectKey, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get object: %w", err)
+	}
+	return obj, nil
+}
+
+// ListObjects returns the objects whose key starts with prefix.
+func (s *Storage) ListObjects(prefix string) ([]minio.ObjectInfo, error) {
+	ctx := context.Background()
+
+	var objects []minio.ObjectInfo
+	for obj := range s.client.ListObjects(ctx, s.bucketName, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+		if obj.Err != nil {
+			return nil, fmt.Errorf("failed to list objects: %w", obj.Err)
+		}
+		objects = append(objects, obj)
+	}
+	return objects, nil
+}
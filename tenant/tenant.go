@@ -0,0 +1,24 @@
+// Package tenant carries the tenant of a request through its context so
+// repositories can scope every query without threading an extra argument.
+package tenant
+
+import "context"
+
+// DefaultID is the tenant that owns all data of a single-tenant deployment.
+const DefaultID = 1
+
+type contextKey struct{}
+
+// WithID returns a copy of ctx scoped to the given tenant.
+func WithID(ctx context.Context, id int) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the tenant of ctx, or DefaultID if none was set
+// (background jobs started outside a request, single-tenant mode).
+func FromContext(ctx context.Context) int {
+	if id, ok := ctx.Value(contextKey{}).(int); ok && id > 0 {
+		return id
+	}
+	return DefaultID
+}
@@ -0,0 +1,18 @@
+package tenant
+
+import (
+	"context"
+	"testing"
+)
+
+func TestFromContext(t *testing.T) {
+	if got := FromContext(context.Background()); got != DefaultID {
+		t.Errorf("expected default tenant, got %d", got)
+	}
+	if got := FromContext(WithID(context.Background(), 7)); got != 7 {
+		t.Errorf("expected tenant 7, got %d", got)
+	}
+	if got := FromContext(WithID(context.Background(), 0)); got != DefaultID {
+		t.Errorf("expected invalid id to fall back to default, got %d", got)
+	}
+}
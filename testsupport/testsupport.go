@@ -0,0 +1,302 @@
+// Package testsupport runs the full API in tests: a fresh store, the real
+// handler stack behind an httptest server, and clients that register, log in
+// and carry their session.
+//
+// The store is a PostgreSQL database with migrations applied, created on the
+// server TEST_DATABASE_URL points to (a postgres:// URL whose user may create
+// databases). Without it, the data is kept in memory, as with STORAGE=memory,
+// so the tests still run; those needing PostgreSQL itself call
+// RequirePostgres.
+package testsupport
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/config"
+	"github.com/clementhaon/sandbox-api-go/database"
+	"github.com/clementhaon/sandbox-api-go/mocks"
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/clementhaon/sandbox-api-go/repository"
+	"github.com/clementhaon/sandbox-api-go/server"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DatabaseURLEnv names the variable holding the PostgreSQL server URL.
+const DatabaseURLEnv = "TEST_DATABASE_URL"
+
+// DefaultPassword satisfies the password rules; Register uses it.
+const DefaultPassword = "Sandbox-Passw0rd!"
+
+// Env is a running API backed by its own store, dropped on cleanup.
+type Env struct {
+	URL string
+	// DB is the database of the API, or nil when it keeps its data in
+	// memory
+	DB     *sql.DB
+	Config *config.Config
+
+	t     testing.TB
+	seq   int
+	users repository.UserRepository
+}
+
+// NewEnv creates a database, applies the migrations and serves the API
+// until the test ends; without TEST_DATABASE_URL the API keeps its data in
+// memory instead. configure may adjust the config before the server is
+// built.
+func NewEnv(t testing.TB, configure ...func(*config.Config)) *Env {
+	t.Helper()
+
+	cfg := &config.Config{
+		Port:                   8080,
+		JWTSecret:              "testsupport-jwt-secret",
+		JWTExpiryHours:         24,
+		JWTRememberMeTTL:       30 * 24 * time.Hour,
+		DBRetryMaxAttempts:     3,
+		MaxBodySize:            1 << 20,
+		RateLimitRequests:      10000,
+		RateLimitWindow:        time.Minute,
+		MetricsCollectInterval: time.Hour,
+		ErrorFormat:            config.ErrorFormatJSON,
+	}
+	for _, fn := range configure {
+		fn(cfg)
+	}
+
+	opts := []server.Option{
+		server.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		server.WithMetrics(prometheus.NewRegistry()),
+		server.WithStorage(&mocks.MockStorage{}),
+	}
+	var db *sql.DB
+	var users repository.UserRepository
+	if serverURL := os.Getenv(DatabaseURLEnv); serverURL != "" {
+		db = createDatabase(t, serverURL)
+		if err := database.RunMigrations(db); err != nil {
+			t.Fatalf("run migrations: %v", err)
+		}
+		opts = append(opts, server.WithDB(db))
+		users = repository.NewPostgresUserRepository(db)
+	} else {
+		store := repository.NewMemoryStore()
+		cfg.Storage = config.StorageMemory
+		opts = append(opts, server.WithMemory(store))
+		users = repository.NewMemoryUserRepository(store)
+	}
+
+	srv, err := server.New(cfg, opts...)
+	if err != nil {
+		t.Fatalf("build server: %v", err)
+	}
+	ts := httptest.NewServer(srv.Handler())
+	t.Cleanup(func() {
+		ts.Close()
+		srv.Shutdown(context.Background())
+	})
+
+	return &Env{URL: ts.URL, DB: db, Config: cfg, t: t, users: users}
+}
+
+// RequirePostgres skips the test unless the API runs on PostgreSQL, for
+// tests about the database itself rather than the API.
+func (e *Env) RequirePostgres() {
+	e.t.Helper()
+	if e.DB == nil {
+		e.t.Skipf("needs PostgreSQL: %s is not set", DatabaseURLEnv)
+	}
+}
+
+// createDatabase creates a uniquely named database on the server at
+// serverURL and drops it when the test ends.
+func createDatabase(t testing.TB, serverURL string) *sql.DB {
+	t.Helper()
+
+	u, err := url.Parse(serverURL)
+	if err != nil {
+		t.Fatalf("parse %s: %v", DatabaseURLEnv, err)
+	}
+	admin, err := sql.Open("postgres", serverURL)
+	if err != nil {
+		t.Fatalf("connect to %s: %v", DatabaseURLEnv, err)
+	}
+
+	suffix := make([]byte, 6)
+	rand.Read(suffix)
+	name := "sandbox_test_" + hex.EncodeToString(suffix)
+	if _, err := admin.Exec("CREATE DATABASE " + name); err != nil {
+		admin.Close()
+		t.Fatalf("create database: %v", err)
+	}
+
+	u.Path = "/" + name
+	db, err := sql.Open("postgres", u.String())
+	if err != nil {
+		t.Fatalf("connect to %s: %v", name, err)
+	}
+
+	t.Cleanup(func() {
+		db.Close()
+		if _, err := admin.Exec("DROP DATABASE IF EXISTS " + name + " WITH (FORCE)"); err != nil {
+			t.Logf("drop database %s: %v", name, err)
+		}
+		admin.Close()
+	})
+	return db
+}
+
+// Client returns a client without a session.
+func (e *Env) Client() *Client {
+	jar, _ := cookiejar.New(nil)
+	return &Client{t: e.t, base: e.URL, http: &http.Client{Jar: jar, Timeout: 10 * time.Second}}
+}
+
+// Register creates a user with a unique username and email and
+// DefaultPassword, and returns a client logged in as that user.
+func (e *Env) Register(username string) (*Client, models.User) {
+	e.t.Helper()
+	e.seq++
+	username = fmt.Sprintf("%s%d", username, e.seq)
+
+	c := e.Client()
+	var resp models.AuthResponse
+	c.Do(http.MethodPost, "/auth/register", models.RegisterRequest{
+		Username: username,
+		Email:    username + "@example.com",
+		Password: DefaultPassword,
+	}).Expect(http.StatusCreated).Decode(&resp)
+	return c, resp.User
+}
+
+// Login returns a client logged in with the given credentials.
+func (e *Env) Login(email, password string) (*Client, models.User) {
+	e.t.Helper()
+
+	c := e.Client()
+	var resp models.AuthResponse
+	c.Do(http.MethodPost, "/auth/login", models.LoginRequest{
+		Email:    email,
+		Password: password,
+	}).Expect(http.StatusOK).Decode(&resp)
+	return c, resp.User
+}
+
+// Admin registers a user, promotes it to admin and returns a client logged
+// in with the new role.
+func (e *Env) Admin() (*Client, models.User) {
+	e.t.Helper()
+
+	_, user := e.Register("admin")
+	if _, err := e.users.Update(context.Background(), user.ID, models.UpdateUserRequest{Role: models.RoleAdmin}); err != nil {
+		e.t.Fatalf("promote user %d: %v", user.ID, err)
+	}
+	return e.Login(user.Email, DefaultPassword)
+}
+
+// Client sends requests to an Env, keeping cookies and the CSRF token
+// between requests like a browser would.
+type Client struct {
+	t    testing.TB
+	base string
+	http *http.Client
+	csrf string
+}
+
+// Do sends a request with body encoded as JSON (nil for none) and fails
+// the test if it cannot be sent.
+func (c *Client) Do(method, path string, body interface{}) *Response {
+	c.t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			c.t.Fatalf("encode %s %s body: %v", method, path, err)
+		}
+		reader = bytes.NewReader(b)
+	}
+	req, err := http.NewRequest(method, c.base+path, reader)
+	if err != nil {
+		c.t.Fatalf("build %s %s: %v", method, path, err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.csrf != "" {
+		req.Header.Set("X-CSRF-Token", c.csrf)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		c.t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		c.t.Fatalf("read %s %s response: %v", method, path, err)
+	}
+	if token := resp.Header.Get("X-CSRF-Token"); token != "" {
+		c.csrf = token
+	}
+
+	return &Response{t: c.t, Request: method + " " + path, StatusCode: resp.StatusCode, Header: resp.Header, Body: data}
+}
+
+// Response is a fully read response.
+type Response struct {
+	Request    string
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+
+	t testing.TB
+}
+
+// Expect fails the test unless the response has the given status.
+func (r *Response) Expect(status int) *Response {
+	r.t.Helper()
+	if r.StatusCode != status {
+		r.t.Fatalf("%s: expected status %d, got %d: %s", r.Request, status, r.StatusCode, r.Body)
+	}
+	return r
+}
+
+// Decode unmarshals the data field of the success envelope into v.
+func (r *Response) Decode(v interface{}) {
+	r.t.Helper()
+	var envelope struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(r.Body, &envelope); err != nil {
+		r.t.Fatalf("%s: decode envelope: %v: %s", r.Request, err, r.Body)
+	}
+	if err := json.Unmarshal(envelope.Data, v); err != nil {
+		r.t.Fatalf("%s: decode data: %v: %s", r.Request, err, envelope.Data)
+	}
+}
+
+// ErrorCode returns the code of an error response, or "" if there is none.
+func (r *Response) ErrorCode() string {
+	var body struct {
+		Error struct {
+			Code string `json:"code"`
+		} `json:"error"`
+	}
+	json.Unmarshal(r.Body, &body)
+	return body.Error.Code
+}
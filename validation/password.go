@@ -0,0 +1,159 @@
+package validation
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+)
+
+//go:embed common_passwords.txt
+var commonPasswords string
+
+// Character classes a PasswordPolicy can require
+const (
+	PasswordClassUpper  = "upper"
+	PasswordClassLower  = "lower"
+	PasswordClassNumber = "number"
+	PasswordClassSymbol = "symbol"
+)
+
+// PasswordMaxLength bounds passwords whatever the policy, as bcrypt only
+// reads 72 bytes of them.
+const PasswordMaxLength = 128
+
+// PasswordPolicy is what the Password rule requires of passwords.
+type PasswordPolicy struct {
+	MinLength int
+	Classes   []string            // PasswordClass* constants, all required
+	Banned    map[string]struct{} // refused passwords, lower case
+}
+
+// DefaultPasswordPolicy requires 8 characters with an uppercase letter, a
+// lowercase letter and a number, and bans nothing.
+func DefaultPasswordPolicy() PasswordPolicy {
+	return PasswordPolicy{
+		MinLength: 8,
+		Classes:   []string{PasswordClassUpper, PasswordClassLower, PasswordClassNumber},
+	}
+}
+
+// CommonPasswords returns the built-in list of common passwords.
+func CommonPasswords() map[string]struct{} {
+	banned, err := ReadPasswordList(strings.NewReader(commonPasswords))
+	if err != nil {
+		panic(err)
+	}
+	return banned
+}
+
+// ReadPasswordList reads one password per line, lower-cased; blank lines
+// and lines starting with # are skipped.
+func ReadPasswordList(r io.Reader) (map[string]struct{}, error) {
+	banned := make(map[string]struct{})
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		banned[strings.ToLower(line)] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return banned, nil
+}
+
+// LoadPasswordList reads a password list from the file at path.
+func LoadPasswordList(path string) (map[string]struct{}, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	banned, err := ReadPasswordList(f)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	return banned, nil
+}
+
+// Violations returns the requirements password fails, in the order the
+// password rule reports them.
+func (p PasswordPolicy) Violations(password string) []*errors.ValidationError {
+	var violations []*errors.ValidationError
+	if n := len([]rune(password)); n < p.MinLength {
+		violations = append(violations, &errors.ValidationError{
+			Message: fmt.Sprintf("Password must be at least %d characters long", p.MinLength),
+			Key:     "validation.password_min",
+			Args:    []interface{}{p.MinLength},
+		})
+	} else if n > PasswordMaxLength {
+		violations = append(violations, &errors.ValidationError{
+			Message: "Password must be no more than 128 characters long",
+			Key:     "validation.password_max",
+		})
+	}
+
+	classes := passwordClasses(password)
+	for _, class := range p.Classes {
+		if classes[class] {
+			continue
+		}
+		switch class {
+		case PasswordClassUpper:
+			violations = append(violations, &errors.ValidationError{
+				Message: "Password must contain at least one uppercase letter",
+				Key:     "validation.password_upper",
+			})
+		case PasswordClassLower:
+			violations = append(violations, &errors.ValidationError{
+				Message: "Password must contain at least one lowercase letter",
+				Key:     "validation.password_lower",
+			})
+		case PasswordClassNumber:
+			violations = append(violations, &errors.ValidationError{
+				Message: "Password must contain at least one number",
+				Key:     "validation.password_number",
+			})
+		case PasswordClassSymbol:
+			violations = append(violations, &errors.ValidationError{
+				Message: "Password must contain at least one symbol",
+				Key:     "validation.password_symbol",
+			})
+		}
+	}
+
+	if _, banned := p.Banned[strings.ToLower(password)]; banned {
+		violations = append(violations, &errors.ValidationError{
+			Message: "Password is too common",
+			Key:     "validation.password_common",
+		})
+	}
+	return violations
+}
+
+// passwordClasses tells which character classes password has.
+func passwordClasses(password string) map[string]bool {
+	classes := make(map[string]bool, 4)
+	for _, char := range password {
+		switch {
+		case unicode.IsUpper(char):
+			classes[PasswordClassUpper] = true
+		case unicode.IsLower(char):
+			classes[PasswordClassLower] = true
+		case unicode.IsNumber(char):
+			classes[PasswordClassNumber] = true
+		case unicode.IsPunct(char) || unicode.IsSymbol(char) || unicode.IsSpace(char):
+			classes[PasswordClassSymbol] = true
+		}
+	}
+	return classes
+}
@@ -0,0 +1,153 @@
+package validation
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// keyboardRows are the rows walked by passwords such as "qwerty" or "asdf".
+var keyboardRows = []string{"1234567890", "qwertyuiop", "asdfghjkl", "zxcvbnm", "azertyuiop", "qsdfghjklm", "wxcvbn"}
+
+// Strength estimates how guessable a password is, after zxcvbn: a score
+// from 0 (too guessable) to 4 (very unguessable), and the i18n keys of a
+// warning and suggestions explaining it.
+type Strength struct {
+	Score       int
+	Warning     string
+	Suggestions []string
+}
+
+// Strength scores password. userInputs, such as the username and
+// the email address, make a password that contains them weaker.
+//
+// Guesses are estimated from the character classes used and the length,
+// where characters repeating or following the previous one (abc, 321,
+// qwerty) count for a quarter. Passwords banned by p, even followed by
+// digits or symbols, score 0.
+func (p PasswordPolicy) Strength(password string, userInputs ...string) Strength {
+	var s Strength
+	if password == "" {
+		s.Suggestions = []string{"password_strength.longer"}
+		return s
+	}
+
+	lower := strings.ToLower(password)
+	if p.isBanned(lower) {
+		s.Warning = "password_strength.common"
+		s.Suggestions = []string{"password_strength.uncommon"}
+		return s
+	}
+
+	effective := 0.0
+	repeats, sequences := 0, 0
+	runes := []rune(lower)
+	for i, r := range runes {
+		switch {
+		case i == 0:
+			effective++
+		case r == runes[i-1]:
+			effective += 0.25
+			repeats++
+		case adjacent(runes[i-1], r):
+			effective += 0.25
+			sequences++
+		default:
+			effective++
+		}
+	}
+
+	personal := false
+	for _, input := range userInputs {
+		input = strings.ToLower(strings.TrimSpace(input))
+		if len([]rune(input)) >= 3 && strings.Contains(lower, input) {
+			// The input is guessed as one token
+			effective -= float64(len([]rune(input))) - 1
+			personal = true
+		}
+	}
+
+	charset := 0
+	for class := range passwordClasses(password) {
+		switch class {
+		case PasswordClassUpper, PasswordClassLower:
+			charset += 26
+		case PasswordClassNumber:
+			charset += 10
+		case PasswordClassSymbol:
+			charset += 33
+		}
+	}
+	if charset == 0 {
+		charset = 100 // letters of other scripts
+	}
+
+	guesses := math.Max(effective, 1) * math.Log10(float64(charset))
+	switch {
+	case guesses < 3:
+		s.Score = 0
+	case guesses < 6:
+		s.Score = 1
+	case guesses < 8:
+		s.Score = 2
+	case guesses < 10:
+		s.Score = 3
+	default:
+		s.Score = 4
+	}
+
+	switch {
+	case personal:
+		s.Score = min(s.Score, 1)
+		s.Warning = "password_strength.personal"
+	case repeats > len(runes)/3:
+		s.Warning = "password_strength.repeats"
+	case sequences > len(runes)/3:
+		s.Warning = "password_strength.sequences"
+	}
+	if s.Score < 3 {
+		if len(runes) < 12 {
+			s.Suggestions = append(s.Suggestions, "password_strength.longer")
+		}
+		if len(passwordClasses(password)) < 3 {
+			s.Suggestions = append(s.Suggestions, "password_strength.mix")
+		}
+		if s.Warning != "" {
+			s.Suggestions = append(s.Suggestions, "password_strength.avoid_patterns")
+		}
+	}
+	return s
+}
+
+// isBanned tells whether lower, or lower without trailing digits and
+// symbols ("password123!"), is banned by p.
+func (p PasswordPolicy) isBanned(lower string) bool {
+	if _, ok := p.Banned[lower]; ok {
+		return true
+	}
+	trimmed := strings.TrimRightFunc(lower, func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	_, ok := p.Banned[trimmed]
+	return ok && trimmed != ""
+}
+
+// adjacent tells whether b follows a in the alphabet, the digits or a
+// keyboard row, either way.
+func adjacent(a, b rune) bool {
+	if (unicode.IsLetter(a) && unicode.IsLetter(b)) || (unicode.IsDigit(a) && unicode.IsDigit(b)) {
+		if b == a+1 || b == a-1 {
+			return true
+		}
+	}
+	for _, row := range keyboardRows {
+		i := strings.IndexRune(row, a)
+		if i < 0 {
+			continue
+		}
+		if (i > 0 && rune(row[i-1]) == b) || (i < len(row)-1 && rune(row[i+1]) == b) {
+			return true
+		}
+	}
+	return false
+}
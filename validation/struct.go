@@ -0,0 +1,250 @@
+package validation
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/clementhaon/sandbox-api-go/errors"
+)
+
+// Struct validates the exported fields of a struct (or pointer to struct)
+// using their `validate` tags and returns a validation AppError, or nil.
+//
+// Rules are comma-separated and run in order:
+//
+//	required       non-zero value (non-blank for strings)
+//	notempty       non-blank string
+//	min=N, max=N   string length, or numeric bounds for integers
+//	email          valid email address
+//	username       username format
+//	oneof=a b c    one of the space-separated values
+//	url            absolute http or https URL
+//	uuid           UUID in canonical form
+//	date=LAYOUT    string in the given time layout, e.g. date=2006-01-02
+//	eqfield=F      equal to the sibling field with JSON name F
+//	afterfield=F   time after the sibling time field with JSON name F
+//
+// Nil pointers skip every rule except required, and a missing required value
+// skips the field's other rules. Fields are reported by their JSON name. An unknown rule is a programming error and panics.
+func Struct(v interface{}) *errors.AppError {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("validation: Struct called with %s", rv.Type()))
+	}
+
+	validator := NewValidator()
+	info := structInfoOf(rv.Type())
+	sibling := func(name string) interface{} {
+		fv := rv.Field(info.byName[name])
+		if fv.Kind() == reflect.Pointer {
+			if fv.IsNil() {
+				return nil
+			}
+			fv = fv.Elem()
+		}
+		return fv.Interface()
+	}
+
+	for _, field := range info.fields {
+		fv := rv.Field(field.index)
+		if fv.Kind() == reflect.Pointer {
+			if fv.IsNil() {
+				if field.required {
+					validator.ValidateField(field.name, nil, Required())
+				}
+				continue
+			}
+			fv = fv.Elem()
+		}
+
+		// A missing required value skips the field's other rules
+		value := fv.Interface()
+		if field.required && (fv.IsZero() || Required()(value) != nil) {
+			validator.ValidateField(field.name, nil, Required())
+			continue
+		}
+		validator.ValidateField(field.name, value, field.rules...)
+		for _, rule := range field.crossRules {
+			validator.ValidateField(field.name, value, func(value interface{}) *errors.ValidationError {
+				return rule(value, sibling)
+			})
+		}
+	}
+	return validator.GetError()
+}
+
+// CrossFieldRule validates a field against a sibling field of the same
+// struct. sibling returns the sibling's value by JSON name (nil for a nil
+// pointer). Cross-field rules are only applied by Struct.
+type CrossFieldRule func(value interface{}, sibling func(name string) interface{}) *errors.ValidationError
+
+// FieldEquals validates that a value equals the sibling field, e.g. a
+// password confirmation (tag: eqfield=password).
+func FieldEquals(field string) CrossFieldRule {
+	return func(value interface{}, sibling func(string) interface{}) *errors.ValidationError {
+		if !reflect.DeepEqual(value, sibling(field)) {
+			return &errors.ValidationError{
+				Message: fmt.Sprintf("Must match %s", field),
+				Key:     "validation.field_equals",
+				Args:    []interface{}{field},
+			}
+		}
+		return nil
+	}
+}
+
+// FieldAfter validates that a time is after the sibling time field
+// (tag: afterfield=startTime). Zero or missing times are not compared.
+func FieldAfter(field string) CrossFieldRule {
+	return func(value interface{}, sibling func(string) interface{}) *errors.ValidationError {
+		v, ok := toTime(value)
+		if !ok {
+			return &errors.ValidationError{
+				Message: "Value must be a time",
+				Key:     "validation.time",
+			}
+		}
+		other, ok := toTime(sibling(field))
+		if !ok || v.IsZero() || other.IsZero() || v.After(other) {
+			return nil
+		}
+		return &errors.ValidationError{
+			Message: fmt.Sprintf("Must be after %s", field),
+			Key:     "validation.after",
+			Args:    []interface{}{field},
+		}
+	}
+}
+
+// structInfo is the parsed `validate` tags of a struct type.
+type structInfo struct {
+	fields []structField
+	byName map[string]int // JSON name to field index, for cross-field rules
+}
+
+// structField is the parsed `validate` tag of one field.
+type structField struct {
+	index      int
+	name       string
+	required   bool
+	rules      []ValidationRule
+	crossRules []CrossFieldRule
+}
+
+// structCache maps a struct type to its *structInfo.
+var structCache sync.Map
+
+func structInfoOf(t reflect.Type) *structInfo {
+	if cached, ok := structCache.Load(t); ok {
+		return cached.(*structInfo)
+	}
+
+	info := &structInfo{byName: make(map[string]int)}
+	for i := 0; i < t.NumField(); i++ {
+		if f := t.Field(i); f.IsExported() {
+			info.byName[jsonName(f)] = i
+		}
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag, ok := f.Tag.Lookup("validate")
+		if !ok || tag == "" || tag == "-" || !f.IsExported() {
+			continue
+		}
+
+		kind := f.Type.Kind()
+		if kind == reflect.Pointer {
+			kind = f.Type.Elem().Kind()
+		}
+
+		field := structField{index: i, name: jsonName(f)}
+		for _, rule := range strings.Split(tag, ",") {
+			name, arg, _ := strings.Cut(strings.TrimSpace(rule), "=")
+			switch name {
+			case "required":
+				field.required = true
+			case "eqfield", "afterfield":
+				if _, ok := info.byName[arg]; !ok {
+					panic(fmt.Sprintf("validation: %s.%s: %s refers to unknown field %q", t, f.Name, name, arg))
+				}
+				if name == "eqfield" {
+					field.crossRules = append(field.crossRules, FieldEquals(arg))
+				} else {
+					field.crossRules = append(field.crossRules, FieldAfter(arg))
+				}
+			default:
+				field.rules = append(field.rules, parseRule(t, f.Name, kind, name, arg))
+			}
+		}
+		info.fields = append(info.fields, field)
+	}
+
+	structCache.Store(t, info)
+	return info
+}
+
+// parseRule maps one tag rule to the matching rule function.
+func parseRule(t reflect.Type, field string, kind reflect.Kind, name, arg string) ValidationRule {
+	intArg := func() int {
+		n, err := strconv.Atoi(arg)
+		if err != nil {
+			panic(fmt.Sprintf("validation: %s.%s: %s needs an integer, got %q", t, field, name, arg))
+		}
+		return n
+	}
+	isInt := kind >= reflect.Int && kind <= reflect.Int64
+
+	switch name {
+	case "notempty":
+		return NotEmpty()
+	case "email":
+		return Email()
+	case "username":
+		return Username()
+	case "min":
+		if isInt {
+			return Min(intArg())
+		}
+		return MinLength(intArg())
+	case "max":
+		if isInt {
+			return Max(intArg())
+		}
+		return MaxLength(intArg())
+	case "url":
+		return URL()
+	case "uuid":
+		return UUID()
+	case "date":
+		return DateFormat(arg)
+	case "oneof":
+		if kind == reflect.String {
+			return Enum(strings.Fields(arg)...)
+		}
+		var allowed []interface{}
+		for _, value := range strings.Fields(arg) {
+			allowed = append(allowed, value)
+		}
+		return OneOf(allowed...)
+	}
+	panic(fmt.Sprintf("validation: %s.%s: unknown rule %q", t, field, name))
+}
+
+// jsonName returns the name a field has in request bodies.
+func jsonName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return f.Name
+	}
+	return name
+}
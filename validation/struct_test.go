@@ -0,0 +1,148 @@
+package validation
+
+import (
+	"testing"
+	"time"
+)
+
+type taggedRequest struct {
+	Name     string     `json:"name" validate:"required,min=3,max=10"`
+	Email    *string    `json:"email,omitempty" validate:"email"`
+	Count    int        `json:"count" validate:"min=1,max=5"`
+	Priority string     `json:"priority" validate:"oneof=low high"`
+	When     time.Time  `json:"when" validate:"required"`
+	Owner    *int       `json:"ownerId" validate:"required"`
+	Ignored  string     `json:"ignored"`
+	Optional *time.Time `json:"optional,omitempty"`
+}
+
+func TestStruct(t *testing.T) {
+	owner := 1
+	valid := func() taggedRequest {
+		return taggedRequest{Name: "alice", Count: 2, Priority: "low", When: time.Now(), Owner: &owner}
+	}
+
+	if err := Struct(valid()); err != nil {
+		t.Fatalf("unexpected error: %v", err.Validation)
+	}
+	v := valid()
+	if err := Struct(&v); err != nil {
+		t.Fatalf("unexpected error for pointer: %v", err.Validation)
+	}
+
+	badEmail := "nope"
+	tests := []struct {
+		name      string
+		mutate    func(r *taggedRequest)
+		wantField string
+	}{
+		{"blank required string", func(r *taggedRequest) { r.Name = "  " }, "name"},
+		{"short string", func(r *taggedRequest) { r.Name = "al" }, "name"},
+		{"long string", func(r *taggedRequest) { r.Name = "alice-in-wonderland" }, "name"},
+		{"invalid pointer email", func(r *taggedRequest) { r.Email = &badEmail }, "email"},
+		{"integer below min", func(r *taggedRequest) { r.Count = 0 }, "count"},
+		{"integer above max", func(r *taggedRequest) { r.Count = 6 }, "count"},
+		{"not one of", func(r *taggedRequest) { r.Priority = "urgent" }, "priority"},
+		{"zero required time", func(r *taggedRequest) { r.When = time.Time{} }, "when"},
+		{"nil required pointer", func(r *taggedRequest) { r.Owner = nil }, "ownerId"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := valid()
+			tt.mutate(&r)
+			err := Struct(r)
+			if err == nil {
+				t.Fatal("expected validation error")
+			}
+			if len(err.Validation) != 1 || err.Validation[0].Field != tt.wantField {
+				t.Errorf("expected one error on %s, got %+v", tt.wantField, err.Validation)
+			}
+			if err.Validation[0].Key == "" {
+				t.Error("expected a catalog key on the validation error")
+			}
+		})
+	}
+}
+
+func TestStruct_UnknownRulePanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for unknown rule")
+		}
+	}()
+	Struct(struct {
+		Name string `validate:"shiny"`
+	}{})
+}
+
+type signupRequest struct {
+	Password        string     `json:"password" validate:"required"`
+	PasswordConfirm string     `json:"password_confirm" validate:"eqfield=password"`
+	Avatar          string     `json:"avatar" validate:"url"`
+	Birthday        string     `json:"birthday" validate:"date=2006-01-02"`
+	Invite          string     `json:"invite" validate:"uuid"`
+	Start           time.Time  `json:"start"`
+	End             *time.Time `json:"end" validate:"afterfield=start"`
+}
+
+func TestStruct_CrossFieldAndFormats(t *testing.T) {
+	start := time.Now()
+	end := start.Add(time.Hour)
+	req := signupRequest{
+		Password:        "secret",
+		PasswordConfirm: "secret",
+		Avatar:          "https://example.com/me.png",
+		Birthday:        "1990-05-17",
+		Invite:          "6f1c2b9e-7d4a-4b8e-9c3f-1a2b3c4d5e6f",
+		Start:           start,
+		End:             &end,
+	}
+	if err := Struct(req); err != nil {
+		t.Fatalf("unexpected error: %+v", err.Validation)
+	}
+
+	before := start.Add(-time.Minute)
+	req.PasswordConfirm = "other"
+	req.Avatar = "not a url"
+	req.Birthday = "17/05/1990"
+	req.Invite = "nope"
+	req.End = &before
+	err := Struct(req)
+	if err == nil {
+		t.Fatal("expected validation errors")
+	}
+	got := map[string]string{}
+	for _, v := range err.Validation {
+		got[v.Field] = v.Key
+	}
+	want := map[string]string{
+		"password_confirm": "validation.field_equals",
+		"avatar":           "validation.url",
+		"birthday":         "validation.date_format",
+		"invite":           "validation.uuid",
+		"end":              "validation.after",
+	}
+	for field, key := range want {
+		if got[field] != key {
+			t.Errorf("%s: expected %s, got %q", field, key, got[field])
+		}
+	}
+
+	req.End = nil
+	req.PasswordConfirm = "secret"
+	req.Avatar, req.Birthday, req.Invite = "", "", ""
+	if err := Struct(req); err != nil {
+		t.Errorf("expected optional fields to be skipped, got %+v", err.Validation)
+	}
+}
+
+func TestStruct_UnknownSiblingPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for unknown sibling field")
+		}
+	}()
+	Struct(struct {
+		A string `json:"a" validate:"eqfield=missing"`
+	}{})
+}
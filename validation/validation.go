@@ -3,9 +3,14 @@ package validation
 import (
 	"fmt"
 	"github.com/clementhaon/sandbox-api-go/errors"
+	"github.com/clementhaon/sandbox-api-go/models"
+	"github.com/google/uuid"
 	"net/mail"
+	"net/url"
+	"reflect"
 	"regexp"
 	"strings"
+	"time"
 	"unicode"
 )
 
@@ -64,6 +69,7 @@ func Required() ValidationRule {
 		if value == nil {
 			return &errors.ValidationError{
 				Message: "This field is required",
+				Key:     "validation.required",
 			}
 		}
 
@@ -72,12 +78,14 @@ func Required() ValidationRule {
 			if strings.TrimSpace(v) == "" {
 				return &errors.ValidationError{
 					Message: "This field is required",
+					Key:     "validation.required",
 				}
 			}
 		case *string:
 			if v == nil || strings.TrimSpace(*v) == "" {
 				return &errors.ValidationError{
 					Message: "This field is required",
+					Key:     "validation.required",
 				}
 			}
 		}
@@ -93,12 +101,15 @@ func MinLength(min int) ValidationRule {
 		if !ok {
 			return &errors.ValidationError{
 				Message: "Value must be a string",
+				Key:     "validation.string",
 			}
 		}
 
 		if len(strings.TrimSpace(str)) < min {
 			return &errors.ValidationError{
 				Message: fmt.Sprintf("Must be at least %d characters long", min),
+				Key:     "validation.min_length",
+				Args:    []interface{}{min},
 			}
 		}
 
@@ -113,12 +124,15 @@ func MaxLength(max int) ValidationRule {
 		if !ok {
 			return &errors.ValidationError{
 				Message: "Value must be a string",
+				Key:     "validation.string",
 			}
 		}
 
 		if len(str) > max {
 			return &errors.ValidationError{
 				Message: fmt.Sprintf("Must be no more than %d characters long", max),
+				Key:     "validation.max_length",
+				Args:    []interface{}{max},
 			}
 		}
 
@@ -133,6 +147,7 @@ func Email() ValidationRule {
 		if !ok {
 			return &errors.ValidationError{
 				Message: "Value must be a string",
+				Key:     "validation.string",
 			}
 		}
 
@@ -145,6 +160,7 @@ func Email() ValidationRule {
 		if err != nil {
 			return &errors.ValidationError{
 				Message: "Must be a valid email address",
+				Key:     "validation.email",
 			}
 		}
 
@@ -159,6 +175,7 @@ func Username() ValidationRule {
 		if !ok {
 			return &errors.ValidationError{
 				Message: "Value must be a string",
+				Key:     "validation.string",
 			}
 		}
 
@@ -171,6 +188,7 @@ func Username() ValidationRule {
 		if len(str) < 3 || len(str) > 30 {
 			return &errors.ValidationError{
 				Message: "Username must be between 3 and 30 characters",
+				Key:     "validation.username_length",
 			}
 		}
 
@@ -178,6 +196,7 @@ func Username() ValidationRule {
 		if !unicode.IsLetter(rune(str[0])) {
 			return &errors.ValidationError{
 				Message: "Username must start with a letter",
+				Key:     "validation.username_start",
 			}
 		}
 
@@ -186,6 +205,7 @@ func Username() ValidationRule {
 		if !validUsername.MatchString(str) {
 			return &errors.ValidationError{
 				Message: "Username can only contain letters, numbers, and underscores",
+				Key:     "validation.username_chars",
 			}
 		}
 
@@ -193,13 +213,15 @@ func Username() ValidationRule {
 	}
 }
 
-// Password validates password strength
-func Password() ValidationRule {
+// Password validates a password against policy, reporting its first
+// violation.
+func Password(policy PasswordPolicy) ValidationRule {
 	return func(value interface{}) *errors.ValidationError {
 		str, ok := value.(string)
 		if !ok {
 			return &errors.ValidationError{
 				Message: "Value must be a string",
+				Key:     "validation.string",
 			}
 		}
 
@@ -207,49 +229,9 @@ func Password() ValidationRule {
 			return nil // Let Required() handle empty values
 		}
 
-		// Password strength rules
-		if len(str) < 8 {
-			return &errors.ValidationError{
-				Message: "Password must be at least 8 characters long",
-			}
-		}
-
-		if len(str) > 128 {
-			return &errors.ValidationError{
-				Message: "Password must be no more than 128 characters long",
-			}
-		}
-
-		var hasUpper, hasLower, hasNumber bool
-		for _, char := range str {
-			switch {
-			case unicode.IsUpper(char):
-				hasUpper = true
-			case unicode.IsLower(char):
-				hasLower = true
-			case unicode.IsNumber(char):
-				hasNumber = true
-			}
+		if violations := policy.Violations(str); len(violations) > 0 {
+			return violations[0]
 		}
-
-		if !hasUpper {
-			return &errors.ValidationError{
-				Message: "Password must contain at least one uppercase letter",
-			}
-		}
-
-		if !hasLower {
-			return &errors.ValidationError{
-				Message: "Password must contain at least one lowercase letter",
-			}
-		}
-
-		if !hasNumber {
-			return &errors.ValidationError{
-				Message: "Password must contain at least one number",
-			}
-		}
-
 		return nil
 	}
 }
@@ -261,12 +243,14 @@ func NotEmpty() ValidationRule {
 		if !ok {
 			return &errors.ValidationError{
 				Message: "Value must be a string",
+				Key:     "validation.string",
 			}
 		}
 
 		if strings.TrimSpace(str) == "" {
 			return &errors.ValidationError{
 				Message: "This field cannot be empty",
+				Key:     "validation.not_empty",
 			}
 		}
 
@@ -289,12 +273,15 @@ func Range(min, max int) ValidationRule {
 		default:
 			return &errors.ValidationError{
 				Message: "Value must be a number",
+				Key:     "validation.number",
 			}
 		}
 
 		if num < min || num > max {
 			return &errors.ValidationError{
 				Message: fmt.Sprintf("Value must be between %d and %d", min, max),
+				Key:     "validation.range",
+				Args:    []interface{}{min, max},
 			}
 		}
 
@@ -302,6 +289,62 @@ func Range(min, max int) ValidationRule {
 	}
 }
 
+// Min validates that an integer is at least min
+func Min(min int) ValidationRule {
+	return func(value interface{}) *errors.ValidationError {
+		num, ok := toInt(value)
+		if !ok {
+			return &errors.ValidationError{
+				Message: "Value must be a number",
+				Key:     "validation.number",
+			}
+		}
+
+		if num < min {
+			return &errors.ValidationError{
+				Message: fmt.Sprintf("Value must be at least %d", min),
+				Key:     "validation.min",
+				Args:    []interface{}{min},
+			}
+		}
+
+		return nil
+	}
+}
+
+// Max validates that an integer is at most max
+func Max(max int) ValidationRule {
+	return func(value interface{}) *errors.ValidationError {
+		num, ok := toInt(value)
+		if !ok {
+			return &errors.ValidationError{
+				Message: "Value must be a number",
+				Key:     "validation.number",
+			}
+		}
+
+		if num > max {
+			return &errors.ValidationError{
+				Message: fmt.Sprintf("Value must be at most %d", max),
+				Key:     "validation.max",
+				Args:    []interface{}{max},
+			}
+		}
+
+		return nil
+	}
+}
+
+// toInt converts any signed integer to int
+func toInt(value interface{}) (int, bool) {
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return int(rv.Int()), true
+	}
+	return 0, false
+}
+
 // OneOf validates that a value is one of the allowed values
 func OneOf(allowed ...interface{}) ValidationRule {
 	return func(value interface{}) *errors.ValidationError {
@@ -313,31 +356,256 @@ func OneOf(allowed ...interface{}) ValidationRule {
 
 		return &errors.ValidationError{
 			Message: fmt.Sprintf("Value must be one of: %v", allowed),
+			Key:     "validation.one_of",
+			Args:    []interface{}{allowed},
 		}
 	}
 }
 
-// Custom validation functions for models
+// URL validates an absolute http or https URL with a host
+func URL() ValidationRule {
+	return func(value interface{}) *errors.ValidationError {
+		str, ok := value.(string)
+		if !ok {
+			return &errors.ValidationError{
+				Message: "Value must be a string",
+				Key:     "validation.string",
+			}
+		}
 
-// ValidateRegisterRequest validates user registration input
-func ValidateRegisterRequest(username, email, password string) *errors.AppError {
-	validator := NewValidator()
+		if str == "" {
+			return nil // Let Required() handle empty values
+		}
 
-	validator.ValidateField("username", username, Required(), Username())
-	validator.ValidateField("email", email, Required(), Email())
-	validator.ValidateField("password", password, Required(), Password())
+		u, err := url.Parse(str)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return &errors.ValidationError{
+				Message: "Must be a valid http or https URL",
+				Key:     "validation.url",
+			}
+		}
 
-	return validator.GetError()
+		return nil
+	}
 }
 
-// ValidateLoginRequest validates user login input
-func ValidateLoginRequest(email, password string) *errors.AppError {
-	validator := NewValidator()
+// URLHost validates that a URL's host is in allowed. An entry "*.example.com"
+// matches any subdomain of example.com. Unparseable URLs are left to URL().
+func URLHost(allowed ...string) ValidationRule {
+	return func(value interface{}) *errors.ValidationError {
+		str, ok := value.(string)
+		if !ok {
+			return &errors.ValidationError{
+				Message: "Value must be a string",
+				Key:     "validation.string",
+			}
+		}
 
-	validator.ValidateField("email", email, Required(), Email())
-	validator.ValidateField("password", password, Required())
+		u, err := url.Parse(str)
+		if str == "" || err != nil || u.Host == "" {
+			return nil
+		}
 
-	return validator.GetError()
+		host := strings.ToLower(u.Hostname())
+		for _, a := range allowed {
+			a = strings.ToLower(a)
+			if host == a || (strings.HasPrefix(a, "*.") && strings.HasSuffix(host, a[1:])) {
+				return nil
+			}
+		}
+
+		return &errors.ValidationError{
+			Message: fmt.Sprintf("Host %s is not allowed", host),
+			Key:     "validation.url_host",
+			Args:    []interface{}{host},
+		}
+	}
+}
+
+// UUID validates a UUID in its canonical form
+func UUID() ValidationRule {
+	return func(value interface{}) *errors.ValidationError {
+		str, ok := value.(string)
+		if !ok {
+			return &errors.ValidationError{
+				Message: "Value must be a string",
+				Key:     "validation.string",
+			}
+		}
+
+		if str == "" {
+			return nil // Let Required() handle empty values
+		}
+
+		if len(str) != 36 || uuid.Validate(str) != nil {
+			return &errors.ValidationError{
+				Message: "Must be a valid UUID",
+				Key:     "validation.uuid",
+			}
+		}
+
+		return nil
+	}
+}
+
+// DateFormat validates that a string parses with the given time layout
+func DateFormat(layout string) ValidationRule {
+	return func(value interface{}) *errors.ValidationError {
+		str, ok := value.(string)
+		if !ok {
+			return &errors.ValidationError{
+				Message: "Value must be a string",
+				Key:     "validation.string",
+			}
+		}
+
+		if str == "" {
+			return nil // Let Required() handle empty values
+		}
+
+		if _, err := time.Parse(layout, str); err != nil {
+			return &errors.ValidationError{
+				Message: fmt.Sprintf("Must be a date in the format %s", layout),
+				Key:     "validation.date_format",
+				Args:    []interface{}{layout},
+			}
+		}
+
+		return nil
+	}
+}
+
+// Timezone validates that a string is an IANA time zone name, such as
+// Europe/Paris or UTC
+func Timezone() ValidationRule {
+	return func(value interface{}) *errors.ValidationError {
+		str, ok := value.(string)
+		if !ok {
+			return &errors.ValidationError{
+				Message: "Value must be a string",
+				Key:     "validation.string",
+			}
+		}
+
+		if str == "" {
+			return nil // Let Required() handle empty values
+		}
+
+		// LoadLocation also accepts "Local", the zone of the server
+		if _, err := time.LoadLocation(str); err != nil || str == "Local" {
+			return &errors.ValidationError{
+				Message: "Must be a valid time zone",
+				Key:     "validation.timezone",
+			}
+		}
+
+		return nil
+	}
+}
+
+// Before validates that a time is strictly before t
+func Before(t time.Time) ValidationRule {
+	return func(value interface{}) *errors.ValidationError {
+		v, ok := toTime(value)
+		if !ok {
+			return &errors.ValidationError{
+				Message: "Value must be a time",
+				Key:     "validation.time",
+			}
+		}
+
+		if !v.IsZero() && !v.Before(t) {
+			return &errors.ValidationError{
+				Message: fmt.Sprintf("Must be before %s", t.Format(time.RFC3339)),
+				Key:     "validation.before",
+				Args:    []interface{}{t.Format(time.RFC3339)},
+			}
+		}
+
+		return nil
+	}
+}
+
+// After validates that a time is strictly after t
+func After(t time.Time) ValidationRule {
+	return func(value interface{}) *errors.ValidationError {
+		v, ok := toTime(value)
+		if !ok {
+			return &errors.ValidationError{
+				Message: "Value must be a time",
+				Key:     "validation.time",
+			}
+		}
+
+		if !v.IsZero() && !v.After(t) {
+			return &errors.ValidationError{
+				Message: fmt.Sprintf("Must be after %s", t.Format(time.RFC3339)),
+				Key:     "validation.after",
+				Args:    []interface{}{t.Format(time.RFC3339)},
+			}
+		}
+
+		return nil
+	}
+}
+
+// toTime accepts time.Time and *time.Time; a nil pointer is the zero time
+func toTime(value interface{}) (time.Time, bool) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, true
+	case *time.Time:
+		if v == nil {
+			return time.Time{}, true
+		}
+		return *v, true
+	}
+	return time.Time{}, false
+}
+
+// Enum validates that a string, or a named string type, is one of allowed
+func Enum(allowed ...string) ValidationRule {
+	return func(value interface{}) *errors.ValidationError {
+		rv := reflect.ValueOf(value)
+		if rv.Kind() != reflect.String {
+			return &errors.ValidationError{
+				Message: "Value must be a string",
+				Key:     "validation.string",
+			}
+		}
+
+		str := rv.String()
+		if str == "" {
+			return nil // Let Required() handle empty values
+		}
+		for _, a := range allowed {
+			if str == a {
+				return nil
+			}
+		}
+
+		return &errors.ValidationError{
+			Message: fmt.Sprintf("Value must be one of: %v", allowed),
+			Key:     "validation.one_of",
+			Args:    []interface{}{allowed},
+		}
+	}
+}
+
+// Custom validation functions for models
+
+// ValidateRegisterRequest validates user registration input, with the
+// password checked against policy
+func ValidateRegisterRequest(username, email, password string, policy PasswordPolicy) *errors.AppError {
+	if err := Struct(models.RegisterRequest{Username: username, Email: email, Password: password}); err != nil {
+		return err
+	}
+	return NewValidator().ValidateField("password", password, Password(policy)).GetError()
+}
+
+// ValidateLoginRequest validates user login input
+func ValidateLoginRequest(email, password string) *errors.AppError {
+	return Struct(models.LoginRequest{Email: email, Password: password})
 }
 
 // ValidateTaskInput validates task creation/update input
@@ -2,6 +2,9 @@ package validation
 
 import (
 	"testing"
+	"time"
+
+	"github.com/clementhaon/sandbox-api-go/i18n"
 )
 
 func TestValidateRegisterRequest(t *testing.T) {
@@ -27,7 +30,7 @@ func TestValidateRegisterRequest(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := ValidateRegisterRequest(tt.username, tt.email, tt.password)
+			err := ValidateRegisterRequest(tt.username, tt.email, tt.password, DefaultPasswordPolicy())
 			if (err != nil) != tt.wantErr {
 				t.Errorf("ValidateRegisterRequest() error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -143,7 +146,7 @@ func TestEmail(t *testing.T) {
 }
 
 func TestPassword(t *testing.T) {
-	rule := Password()
+	rule := Password(DefaultPasswordPolicy())
 
 	if rule("") != nil {
 		t.Error("Expected no error for empty string (Required handles that)")
@@ -214,3 +217,199 @@ func TestRange(t *testing.T) {
 		t.Error("Expected no error for nil pointer")
 	}
 }
+
+func TestURL(t *testing.T) {
+	rule := URL()
+
+	for _, valid := range []string{"", "https://example.com/a.png", "http://localhost:9000/x"} {
+		if rule(valid) != nil {
+			t.Errorf("Expected no error for %q", valid)
+		}
+	}
+	for _, invalid := range []string{"example.com", "ftp://example.com", "https://", "javascript:alert(1)"} {
+		if rule(invalid) == nil {
+			t.Errorf("Expected error for %q", invalid)
+		}
+	}
+}
+
+func TestURLHost(t *testing.T) {
+	rule := URLHost("cdn.example.com", "*.gravatar.com")
+
+	for _, valid := range []string{"", "https://CDN.example.com:8443/a.png", "https://secure.gravatar.com/x", "not a url"} {
+		if rule(valid) != nil {
+			t.Errorf("Expected no error for %q", valid)
+		}
+	}
+	for _, invalid := range []string{"https://example.com/a.png", "https://gravatar.com.evil.io/x", "https://evilgravatar.com/x"} {
+		if rule(invalid) == nil {
+			t.Errorf("Expected error for %q", invalid)
+		}
+	}
+}
+
+func TestUUID(t *testing.T) {
+	rule := UUID()
+
+	if rule("6f1c2b9e-7d4a-4b8e-9c3f-1a2b3c4d5e6f") != nil {
+		t.Error("Expected no error for valid UUID")
+	}
+	for _, invalid := range []string{"not-a-uuid", "6f1c2b9e7d4a4b8e9c3f1a2b3c4d5e6f", "{6f1c2b9e-7d4a-4b8e-9c3f-1a2b3c4d5e6f}"} {
+		if rule(invalid) == nil {
+			t.Errorf("Expected error for %q", invalid)
+		}
+	}
+}
+
+func TestDateFormat(t *testing.T) {
+	rule := DateFormat("2006-01-02")
+
+	if rule("2024-02-29") != nil {
+		t.Error("Expected no error for valid date")
+	}
+	if rule("2023-02-29") == nil {
+		t.Error("Expected error for invalid day")
+	}
+	if rule("29/02/2024") == nil {
+		t.Error("Expected error for wrong layout")
+	}
+}
+
+func TestBeforeAfter(t *testing.T) {
+	now := time.Now()
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+
+	if Before(now)(past) != nil || Before(now)(future) == nil {
+		t.Error("Before did not compare times correctly")
+	}
+	if After(now)(&future) != nil || After(now)(&past) == nil {
+		t.Error("After did not compare time pointers correctly")
+	}
+	var nilTime *time.Time
+	if After(now)(nilTime) != nil {
+		t.Error("Expected no error for nil time")
+	}
+	if Before(now)("yesterday") == nil {
+		t.Error("Expected error for non-time value")
+	}
+}
+
+func TestEnum(t *testing.T) {
+	type priority string
+	rule := Enum("low", "high")
+
+	if rule(priority("low")) != nil {
+		t.Error("Expected no error for named string type")
+	}
+	if rule("urgent") == nil {
+		t.Error("Expected error for value outside the enum")
+	}
+}
+
+func TestRuleMessagesMatchCatalog(t *testing.T) {
+	cases := []struct {
+		rule  ValidationRule
+		value interface{}
+	}{
+		{Required(), ""},
+		{MinLength(3), "a"},
+		{MaxLength(2), "abc"},
+		{Email(), "nope"},
+		{Username(), "1abc"},
+		{Password(DefaultPasswordPolicy()), "short"},
+		{Password(DefaultPasswordPolicy()), "password1"},
+		{NotEmpty(), " "},
+		{Range(1, 5), 9},
+		{OneOf("a", "b"), "c"},
+		{MinLength(1), 3},
+		{URL(), "nope"},
+		{URLHost("a.com"), "https://b.com"},
+		{UUID(), "nope"},
+		{DateFormat("2006-01-02"), "nope"},
+		{Before(time.Unix(0, 0)), time.Now()},
+		{After(time.Now()), time.Unix(0, 0)},
+		{After(time.Now()), "nope"},
+		{Enum("a"), "b"},
+		{Min(2), 1},
+		{Max(2), 3},
+	}
+	for _, c := range cases {
+		err := c.rule(c.value)
+		if err == nil {
+			t.Fatalf("expected error for %v", c.value)
+		}
+		if msg, ok := i18n.Lookup(i18n.DefaultLanguage, err.Key, err.Args...); !ok || msg != err.Message {
+			t.Errorf("key %q: catalog has %q, rule says %q", err.Key, msg, err.Message)
+		}
+	}
+}
+
+func TestPasswordPolicy(t *testing.T) {
+	rule := Password(PasswordPolicy{
+		MinLength: 12,
+		Classes:   []string{PasswordClassLower, PasswordClassSymbol},
+		Banned:    CommonPasswords(),
+	})
+
+	tests := []struct {
+		password string
+		wantKey  string
+	}{
+		{"short-one", "validation.password_min"},
+		{"no symbols here", ""}, // spaces count as symbols
+		{"nosymbolsatall", "validation.password_symbol"},
+		{"correct-horse-battery", ""},
+	}
+	for _, tt := range tests {
+		err := rule(tt.password)
+		switch {
+		case tt.wantKey == "" && err != nil:
+			t.Errorf("%q: unexpected error %q", tt.password, err.Message)
+		case tt.wantKey != "" && (err == nil || err.Key != tt.wantKey):
+			t.Errorf("%q: expected %s, got %+v", tt.password, tt.wantKey, err)
+		}
+		if err != nil {
+			if msg, ok := i18n.Lookup(i18n.DefaultLanguage, err.Key, err.Args...); !ok || msg != err.Message {
+				t.Errorf("key %q: catalog has %q, rule says %q", err.Key, msg, err.Message)
+			}
+		}
+	}
+
+	rule = Password(PasswordPolicy{Banned: CommonPasswords()})
+	if err := rule("PassWord123"); err == nil || err.Key != "validation.password_common" {
+		t.Errorf("expected a common password refused, got %+v", err)
+	}
+}
+
+func TestPasswordPolicy_Strength(t *testing.T) {
+	policy := PasswordPolicy{Banned: CommonPasswords()}
+
+	tests := []struct {
+		password   string
+		userInputs []string
+		wantMax    int
+		wantMin    int
+		warning    string
+	}{
+		{password: "password123!", wantMax: 0, warning: "password_strength.common"},
+		{password: "aaaaaaaa", wantMax: 1, warning: "password_strength.repeats"},
+		{password: "abcdefgh", wantMax: 1, warning: "password_strength.sequences"},
+		{password: "jdupont1987!", userInputs: []string{"jdupont"}, wantMax: 1, warning: "password_strength.personal"},
+		{password: "Tr0ub4dor&3", wantMin: 3, wantMax: 4},
+		{password: "correct horse battery staple", wantMin: 4, wantMax: 4},
+	}
+	for _, tt := range tests {
+		s := policy.Strength(tt.password, tt.userInputs...)
+		if s.Score < tt.wantMin || s.Score > tt.wantMax {
+			t.Errorf("%q: score %d, want %d to %d", tt.password, s.Score, tt.wantMin, tt.wantMax)
+		}
+		if s.Warning != tt.warning {
+			t.Errorf("%q: warning %q, want %q", tt.password, s.Warning, tt.warning)
+		}
+		for _, key := range append([]string{s.Warning}, s.Suggestions...) {
+			if _, ok := i18n.Lookup(i18n.DefaultLanguage, key); key != "" && !ok {
+				t.Errorf("%q: key %q missing from the catalog", tt.password, key)
+			}
+		}
+	}
+}
@@ -0,0 +1,80 @@
+// Package webhook signs the requests of outgoing webhooks and sends them
+// with a client that cannot be pointed at the server's own network.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+// Headers of webhook requests. SignatureHeader reads "t=<unix time>,v1=<hex
+// HMAC-SHA256>", the HMAC being of "<unix time>." followed by the body,
+// keyed with the secret of the webhook.
+const (
+	SignatureHeader = "X-Webhook-Signature"
+	EventHeader     = "X-Webhook-Event"
+	DeliveryHeader  = "X-Webhook-Delivery"
+)
+
+// DefaultTimeout is how long a receiver has to answer a delivery.
+const DefaultTimeout = 10 * time.Second
+
+// ErrPrivateAddress is returned when a webhook URL leads to an address that
+// is not public.
+var ErrPrivateAddress = errors.New("webhook: address is not public")
+
+// sharedAddressSpace is 100.64.0.0/10, used by carrier-grade NAT.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// Sign returns the SignatureHeader of body sent at timestamp.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	unix := timestamp.Unix()
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", unix)
+	mac.Write(body)
+	return fmt.Sprintf("t=%d,v1=%s", unix, hex.EncodeToString(mac.Sum(nil)))
+}
+
+// NewClient returns a client giving up after timeout and returning
+// redirects rather than following them. Unless allowPrivate is set, it
+// refuses to connect to loopback, private, link-local and other non-public
+// addresses. The check runs on the address dialed, after DNS resolution,
+// so a name resolving to such an address is refused as well.
+func NewClient(timeout time.Duration, allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivate {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil || !isPublic(addrPort.Addr()) {
+				return ErrPrivateAddress
+			}
+			return nil
+		}
+	}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+			MaxIdleConnsPerHost: 2,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// isPublic tells whether addr may be reached on the internet.
+func isPublic(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsGlobalUnicast() && !addr.IsPrivate() && !sharedAddressSpace.Contains(addr)
+}